@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/audit"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// auditLogEnvVar sets the audit log when --audit-log is not given, so it can be enforced for every
+// run on a machine
+const auditLogEnvVar = "IRR_AUDIT_LOG"
+
+// auditLogPath is set by the global --audit-log flag
+var auditLogPath string
+
+// auditedCommands are the commands recorded in the audit log: those that produce or check the
+// deployment inputs
+var auditedCommands = map[string]bool{
+	"override": true,
+	"validate": true,
+}
+
+// auditedCommand and auditedArgs are the command being audited and its arguments; auditedCommand
+// is nil when the run is not audited
+var (
+	auditedCommand *cobra.Command
+	auditedArgs    []string
+)
+
+// startAudit remembers cmd for the audit log when it is enabled and cmd is audited. It is called
+// once flags are parsed.
+func startAudit(cmd *cobra.Command, args []string) {
+	if auditLogPath == "" {
+		auditLogPath = os.Getenv(auditLogEnvVar)
+	}
+	if auditLogPath == "" || !auditedCommands[cmd.Name()] {
+		return
+	}
+	auditedCommand = cmd
+	auditedArgs = args
+}
+
+// finishAudit appends the record of the audited command, which failed with err if not nil, to the
+// audit log. Failures are logged rather than returned so they do not replace the outcome of the
+// command.
+func finishAudit(err error) {
+	if auditedCommand == nil {
+		return
+	}
+	defer func() {
+		auditedCommand = nil
+		auditedArgs = nil
+	}()
+	record := newAuditRecord(auditedCommand, auditedArgs, err)
+	if appendErr := audit.Append(AppFs, auditLogPath, record); appendErr != nil {
+		log.Error("Failed to write audit log", "error", appendErr)
+		return
+	}
+	log.Debug("Audit record written", "file", auditLogPath)
+}
+
+// newAuditRecord describes a run of cmd with args that failed with err if not nil. Flags the
+// command does not have are left empty.
+func newAuditRecord(cmd *cobra.Command, args []string, err error) *audit.Record {
+	record := &audit.Record{
+		Time:      time.Now().UTC(),
+		Command:   cmd.CommandPath(),
+		User:      audit.CurrentUser(),
+		Chart:     auditStringFlag(cmd, "chart-path"),
+		Release:   auditStringFlag(cmd, "release-name"),
+		Namespace: auditStringFlag(cmd, "namespace"),
+	}
+	if len(args) > 0 {
+		record.Release = args[0]
+	}
+	if !cmd.Flags().Changed("namespace") {
+		if namespace := os.Getenv("HELM_NAMESPACE"); namespace != "" {
+			record.Namespace = namespace
+		}
+	}
+
+	configFiles := auditStringSliceFlag(cmd, "registry-file")
+	if deprecatedConfig := auditStringFlag(cmd, "config"); len(configFiles) == 0 && deprecatedConfig != "" {
+		configFiles = []string{deprecatedConfig}
+	}
+	record.ConfigFiles = audit.Digests(AppFs, configFiles)
+	record.ValuesFiles = audit.Digests(AppFs, auditStringSliceFlag(cmd, "values"))
+	if outputFile := auditStringFlag(cmd, "output-file"); outputFile != "" {
+		digest := audit.Digest(AppFs, outputFile)
+		record.OutputFile = &digest
+	}
+
+	if err != nil {
+		info := exitcodes.Classify(err)
+		record.ExitCode = info.ExitCode
+		record.Error = info.Message
+	}
+	return record
+}
+
+// auditStringFlag returns the value of a string flag of cmd, or "" if cmd has no such flag
+func auditStringFlag(cmd *cobra.Command, name string) string {
+	if cmd.Flags().Lookup(name) == nil {
+		return ""
+	}
+	value, err := cmd.Flags().GetString(name)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// auditStringSliceFlag returns the values of a string slice flag of cmd, or nil if cmd has no such
+// flag
+func auditStringSliceFlag(cmd *cobra.Command, name string) []string {
+	if cmd.Flags().Lookup(name) == nil {
+		return nil
+	}
+	values, err := cmd.Flags().GetStringSlice(name)
+	if err != nil {
+		return nil
+	}
+	return values
+}
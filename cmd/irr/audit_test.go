@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/audit"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	originalFs := AppFs
+	AppFs = afero.NewMemMapFs()
+	t.Cleanup(func() {
+		AppFs = originalFs
+		auditLogPath = ""
+	})
+	require.NoError(t, afero.WriteFile(AppFs, "mappings.yaml", []byte("mappings: []\n"), 0o644))
+	require.NoError(t, afero.WriteFile(AppFs, "overrides.yaml", []byte("image: {}\n"), 0o644))
+
+	cmd := &cobra.Command{Use: "override"}
+	cmd.Flags().String("chart-path", "", "")
+	cmd.Flags().String("namespace", "default", "")
+	cmd.Flags().StringSlice("registry-file", nil, "")
+	cmd.Flags().String("output-file", "", "")
+	require.NoError(t, cmd.ParseFlags([]string{"--chart-path", "./nginx", "--registry-file", "mappings.yaml", "--output-file", "overrides.yaml"}))
+
+	auditLogPath = "audit.jsonl"
+	startAudit(cmd, []string{"web"})
+	finishAudit(&exitcodes.ExitCodeError{Code: exitcodes.ExitUnsupportedStructure, Err: assert.AnError})
+
+	data, err := afero.ReadFile(AppFs, "audit.jsonl")
+	require.NoError(t, err)
+	var record audit.Record
+	require.NoError(t, json.Unmarshal(data, &record))
+	assert.Equal(t, "override", record.Command)
+	assert.Equal(t, "./nginx", record.Chart)
+	assert.Equal(t, "web", record.Release)
+	assert.Equal(t, "default", record.Namespace)
+	require.Len(t, record.ConfigFiles, 1)
+	assert.Equal(t, audit.Digest(AppFs, "mappings.yaml"), record.ConfigFiles[0])
+	require.NotNil(t, record.OutputFile)
+	assert.NotEmpty(t, record.OutputFile.SHA256)
+	assert.Equal(t, exitcodes.ExitUnsupportedStructure, record.ExitCode)
+	assert.Equal(t, assert.AnError.Error(), record.Error)
+}
+
+func TestAuditLogSkipsOtherCommands(t *testing.T) {
+	originalFs := AppFs
+	AppFs = afero.NewMemMapFs()
+	t.Cleanup(func() {
+		AppFs = originalFs
+		auditLogPath = ""
+	})
+
+	auditLogPath = "audit.jsonl"
+	startAudit(&cobra.Command{Use: "inspect"}, nil)
+	finishAudit(nil)
+
+	exists, err := afero.Exists(AppFs, "audit.jsonl")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
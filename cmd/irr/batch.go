@@ -0,0 +1,656 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lucas-albers-lz4/irr/internal/helm"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/progress"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// BatchSettings are the override settings of a batch job. Settings under defaults apply to every
+// job that does not set them itself.
+type BatchSettings struct {
+	TargetRegistry    string   `yaml:"targetRegistry,omitempty"`
+	SourceRegistries  []string `yaml:"sourceRegistries,omitempty"`
+	ExcludeRegistries []string `yaml:"excludeRegistries,omitempty"`
+	RegistryFile      string   `yaml:"registryFile,omitempty"`
+	RegistryFiles     []string `yaml:"registryFiles,omitempty"`
+	PathStrategy      string   `yaml:"pathStrategy,omitempty"`
+	TargetFlavor      string   `yaml:"targetFlavor,omitempty"`
+	StrictMode        string   `yaml:"strictMode,omitempty"`
+	ContextAware      *bool    `yaml:"contextAware,omitempty"`
+	Namespace         string   `yaml:"namespace,omitempty"`
+	Values            []string `yaml:"values,omitempty"`
+	Set               []string `yaml:"set,omitempty"`
+	OutputFormat      string   `yaml:"outputFormat,omitempty"`
+}
+
+// BatchJob is one chart or release whose overrides a batch run generates
+type BatchJob struct {
+	// Name identifies the job in the report; defaults to the release name or chart directory name
+	Name string `yaml:"name,omitempty"`
+	// ChartPath is the chart to generate overrides for; exclusive with Release
+	ChartPath string `yaml:"chartPath,omitempty"`
+	// Release is the installed release to generate overrides for (Helm plugin mode only)
+	Release string `yaml:"release,omitempty"`
+	// OutputFile is where the overrides are written
+	OutputFile    string `yaml:"outputFile,omitempty"`
+	BatchSettings `yaml:",inline"`
+}
+
+// BatchSpec is the file read by 'irr run -f'
+type BatchSpec struct {
+	Defaults BatchSettings `yaml:"defaults,omitempty"`
+	Jobs     []BatchJob    `yaml:"jobs"`
+}
+
+// BatchJobResult describes the outcome of a single batch job
+type BatchJobResult struct {
+	Name       string             `json:"name" yaml:"name"`
+	ChartPath  string             `json:"chartPath,omitempty" yaml:"chartPath,omitempty"`
+	Release    string             `json:"release,omitempty" yaml:"release,omitempty"`
+	Namespace  string             `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	OutputFile string             `json:"outputFile,omitempty" yaml:"outputFile,omitempty"`
+	Error      string             `json:"error,omitempty" yaml:"error,omitempty"`
+	ErrorCode  string             `json:"errorCode,omitempty" yaml:"errorCode,omitempty"`
+	Category   exitcodes.Category `json:"category,omitempty" yaml:"category,omitempty"`
+}
+
+// BatchReport is the consolidated result of a batch run
+type BatchReport struct {
+	Spec      string           `json:"spec" yaml:"spec"`
+	Succeeded int              `json:"succeeded" yaml:"succeeded"`
+	Failed    int              `json:"failed" yaml:"failed"`
+	Jobs      []BatchJobResult `json:"jobs" yaml:"jobs"`
+}
+
+// batchCache shares work between the jobs of a batch run: each registry mappings file is loaded
+// once, and release jobs share one Helm client.
+type batchCache struct {
+	mu      sync.Mutex
+	configs map[string]*cachedRegistryConfig
+
+	adapterOnce    sync.Once
+	adapter        *helm.Adapter
+	adapterErr     error
+	adapterFactory func() (*helm.Adapter, error)
+}
+
+// cachedRegistryConfig is a registry mappings file loaded once for all jobs of a batch run
+type cachedRegistryConfig struct {
+	once   sync.Once
+	config *registry.Config
+	err    error
+}
+
+// batchRunCache is the cache of the batch run in progress, or nil outside 'irr run'
+var batchRunCache *batchCache
+
+func newBatchCache(adapterFactory func() (*helm.Adapter, error)) *batchCache {
+	return &batchCache{configs: make(map[string]*cachedRegistryConfig), adapterFactory: adapterFactory}
+}
+
+// registryConfig returns the registry mappings file at path, loading it with load the first time
+func (c *batchCache) registryConfig(path string, skipCWDRestriction bool, load func(string, bool) (*registry.Config, error)) (*registry.Config, error) {
+	key := path
+	if abs, err := filepath.Abs(path); err == nil {
+		key = abs
+	}
+	c.mu.Lock()
+	entry, ok := c.configs[key]
+	if !ok {
+		entry = &cachedRegistryConfig{}
+		c.configs[key] = entry
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.config, entry.err = load(path, skipCWDRestriction)
+	})
+	return entry.config, entry.err
+}
+
+// helmAdapter returns the Helm adapter shared by the release jobs, creating it on first use
+func (c *batchCache) helmAdapter() (*helm.Adapter, error) {
+	c.adapterOnce.Do(func() {
+		c.adapter, c.adapterErr = c.adapterFactory()
+	})
+	return c.adapter, c.adapterErr
+}
+
+// newRunCmd creates the run command
+func newRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Generate the overrides of many charts and releases from a batch spec",
+		Long: `Generates image overrides for every job listed in a batch spec file, as 'irr override'
+would for each of them, and reports the outcome of all jobs together.
+
+Each job names a chart (chartPath) or, when running as a Helm plugin, an installed release
+(release and namespace), and the file its overrides are written to (outputFile). Settings
+such as targetRegistry, registryFile or values are given per job or once under defaults.
+Relative paths in the spec are resolved against the directory of the spec file.
+
+Jobs run concurrently and share work: each registry mappings file is loaded once, and
+release jobs share one connection to the cluster. Failed jobs do not stop the others; irr
+exits with code 15 if any job failed. With --validate, the overrides of each chart job are
+checked with helm template, up to --validate-workers charts at a time.`,
+		Example: `  irr run -f batch.yaml
+  irr run -f batch.yaml --overwrite --report batch-report.yaml
+  irr run -f batch.yaml --workers 16 --validate --validate-workers 4
+  helm irr run -f releases.yaml --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: runBatch,
+	}
+
+	cmd.Flags().StringP("file", "f", "", "Path to the batch spec file (required)")
+	cmd.Flags().Int("workers", defaultChartWorkers, "Number of jobs run concurrently")
+	cmd.Flags().Bool("validate", false, "Run helm template to validate the generated overrides of every chart job")
+	addValidateWorkersFlag(cmd, "Number of Helm template validations run concurrently with --validate; 0 runs as many as --workers")
+	cmd.Flags().String("report", "", "Write the consolidated report to this file instead of stdout")
+	cmd.Flags().String("output-format", outputFormatYAML, "Format of the report (yaml or json)")
+	cmd.Flags().Bool("overwrite", false, "Replace existing output files, e.g. to regenerate all overrides")
+	cmd.Flags().Bool("dry-run", false, "Generate the overrides of every job without writing them")
+	return cmd
+}
+
+// runBatch implements 'irr run'
+func runBatch(cmd *cobra.Command, _ []string) error {
+	specFile, err := getStringFlag(cmd, "file")
+	if err != nil {
+		return err
+	}
+	if specFile == "" {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitMissingRequiredFlag,
+			Err:  errors.New("required flag(s) \"file\" not set"),
+		}
+	}
+	workers, err := cmd.Flags().GetInt("workers")
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get workers flag: %w", err),
+		}
+	}
+	if workers < 1 {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("--workers must be at least 1, got %d", workers),
+		}
+	}
+	reportFile, err := getStringFlag(cmd, "report")
+	if err != nil {
+		return err
+	}
+	reportFormat, err := getStringFlag(cmd, "output-format")
+	if err != nil {
+		return err
+	}
+	overwrite, err := getBoolFlag(cmd, "overwrite")
+	if err != nil {
+		return err
+	}
+	dryRun, err := getBoolFlag(cmd, "dry-run")
+	if err != nil {
+		return err
+	}
+	validateWorkers, err := getValidateWorkersFlag(cmd)
+	if err != nil {
+		return err
+	}
+	defer limitTemplateValidations(validateWorkers)()
+
+	spec, err := loadBatchSpec(specFile)
+	if err != nil {
+		return err
+	}
+	jobs, err := resolveBatchJobs(spec, filepath.Dir(specFile), dryRun)
+	if err != nil {
+		return err
+	}
+
+	results, err := runBatchJobs(cmd, jobs, workers, overwrite, dryRun)
+	if err != nil {
+		return err
+	}
+
+	report := newBatchReport(specFile, results)
+	logBatchReport(report)
+	if err := outputBatchReport(cmd, report, reportFile, reportFormat); err != nil {
+		return err
+	}
+	return batchError(report)
+}
+
+// runBatchJobs runs the jobs concurrently and returns their results in job order
+func runBatchJobs(cmd *cobra.Command, jobs []BatchJob, workers int, overwrite, dryRun bool) ([]BatchJobResult, error) {
+	// Job commands are created up front: creating override commands binds package-level flag
+	// variables, which must not happen while jobs run.
+	jobCmds := make([]*cobra.Command, len(jobs))
+	for i := range jobs {
+		var err error
+		jobCmds[i], err = newBatchJobCmd(cmd, &jobs[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	originalFactory := helmAdapterFactory
+	batchRunCache = newBatchCache(originalFactory)
+	helmAdapterFactory = batchRunCache.helmAdapter
+	defer func() {
+		helmAdapterFactory = originalFactory
+		batchRunCache = nil
+	}()
+
+	log.Info("Running batch jobs", "jobs", len(jobs), "workers", workers)
+	indexes := make([]int, len(jobs))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	bar := progress.Start(os.Stderr, "Running batch jobs", len(jobs))
+	results, err := processConcurrently(getCommandContext(cmd), indexes, workers, func(i int) BatchJobResult {
+		defer bar.Add(1)
+		return runBatchJob(jobCmds[i], &jobs[i], overwrite, dryRun)
+	})
+	bar.Finish()
+	return results, err
+}
+
+// loadBatchSpec reads and parses a batch spec file, rejecting unknown fields so typos in setting
+// names do not silently fall back to defaults
+func loadBatchSpec(path string) (*BatchSpec, error) {
+	data, err := afero.ReadFile(AppFs, path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  fmt.Errorf("batch spec file '%s' does not exist", path),
+			}
+		}
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to read batch spec file '%s': %w", path, err),
+		}
+	}
+	spec := &BatchSpec{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(spec); err != nil && !errors.Is(err, io.EOF) {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to parse batch spec file '%s': %w", path, err),
+		}
+	}
+	return spec, nil
+}
+
+// resolveBatchJobs applies the defaults of the spec to its jobs, resolves relative paths against
+// baseDir and validates the jobs
+func resolveBatchJobs(spec *BatchSpec, baseDir string, dryRun bool) ([]BatchJob, error) {
+	if len(spec.Jobs) == 0 {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("batch spec has no jobs"),
+		}
+	}
+
+	jobs := make([]BatchJob, 0, len(spec.Jobs))
+	names := make(map[string]bool, len(spec.Jobs))
+	outputFiles := make(map[string]string, len(spec.Jobs))
+	for i, job := range spec.Jobs {
+		job.BatchSettings = mergeBatchSettings(job.BatchSettings, spec.Defaults)
+		if job.Name == "" {
+			job.Name = job.Release
+			if job.Name == "" {
+				job.Name = filepath.Base(job.ChartPath)
+			}
+		}
+		invalid := func(format string, args ...interface{}) error {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  fmt.Errorf("batch job %d (%s): %s", i+1, job.Name, fmt.Sprintf(format, args...)),
+			}
+		}
+
+		switch {
+		case job.ChartPath == "" && job.Release == "":
+			return nil, invalid("one of chartPath or release is required")
+		case job.ChartPath != "" && job.Release != "":
+			return nil, invalid("chartPath and release cannot both be set")
+		case job.Release != "" && !isRunningAsHelmPlugin():
+			return nil, invalid("release jobs require running as a Helm plugin ('helm irr run')")
+		case job.OutputFile == "" && !dryRun:
+			return nil, invalid("outputFile is required unless --dry-run is set")
+		case job.OutputFormat != "" && job.OutputFormat != outputFormatYAML && job.OutputFormat != outputFormatJSON:
+			return nil, invalid("unsupported outputFormat %q (use yaml or json)", job.OutputFormat)
+		case names[job.Name]:
+			return nil, invalid("duplicate job name; set a unique name")
+		}
+		names[job.Name] = true
+
+		job.ChartPath = resolveBatchPath(baseDir, job.ChartPath)
+		job.OutputFile = resolveBatchPath(baseDir, job.OutputFile)
+		job.RegistryFile = resolveBatchPath(baseDir, job.RegistryFile)
+		registryFiles := make([]string, len(job.RegistryFiles))
+		for j, registryFile := range job.RegistryFiles {
+			registryFiles[j] = resolveBatchPath(baseDir, registryFile)
+		}
+		job.RegistryFiles = registryFiles
+		values := make([]string, len(job.Values))
+		for j, valuesFile := range job.Values {
+			values[j] = resolveBatchPath(baseDir, valuesFile)
+		}
+		job.Values = values
+
+		if job.OutputFile != "" {
+			if other, ok := outputFiles[job.OutputFile]; ok {
+				return nil, invalid("outputFile %s is also written by job %s", job.OutputFile, other)
+			}
+			outputFiles[job.OutputFile] = job.Name
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// mergeBatchSettings returns settings with every unset field taken from defaults
+func mergeBatchSettings(settings, defaults BatchSettings) BatchSettings {
+	pick := func(value, fallback string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	}
+	pickSlice := func(value, fallback []string) []string {
+		if len(value) == 0 {
+			return fallback
+		}
+		return value
+	}
+	merged := BatchSettings{
+		TargetRegistry:    pick(settings.TargetRegistry, defaults.TargetRegistry),
+		SourceRegistries:  pickSlice(settings.SourceRegistries, defaults.SourceRegistries),
+		ExcludeRegistries: pickSlice(settings.ExcludeRegistries, defaults.ExcludeRegistries),
+		RegistryFile:      settings.RegistryFile,
+		RegistryFiles:     settings.RegistryFiles,
+		PathStrategy:      pick(settings.PathStrategy, defaults.PathStrategy),
+		TargetFlavor:      pick(settings.TargetFlavor, defaults.TargetFlavor),
+		StrictMode:        pick(settings.StrictMode, defaults.StrictMode),
+		ContextAware:      settings.ContextAware,
+		Namespace:         pick(settings.Namespace, defaults.Namespace),
+		Values:            pickSlice(settings.Values, defaults.Values),
+		Set:               pickSlice(settings.Set, defaults.Set),
+		OutputFormat:      pick(settings.OutputFormat, defaults.OutputFormat),
+	}
+	if merged.ContextAware == nil {
+		merged.ContextAware = defaults.ContextAware
+	}
+	// registryFile and registryFiles are layered together, so a job setting either replaces both defaults
+	if len(merged.registryFiles()) == 0 {
+		merged.RegistryFile, merged.RegistryFiles = defaults.RegistryFile, defaults.RegistryFiles
+	}
+	return merged
+}
+
+// registryFiles returns the registry mappings files of the settings in the order they are
+// layered: registryFile, then registryFiles
+func (s *BatchSettings) registryFiles() []string {
+	if s.RegistryFile == "" {
+		return s.RegistryFiles
+	}
+	return append([]string{s.RegistryFile}, s.RegistryFiles...)
+}
+
+// resolveBatchPath resolves a path of the spec relative to the directory of the spec file
+func resolveBatchPath(baseDir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// newBatchJobCmd creates the override command a job is generated with, its flags set from the
+// job's settings, so jobs are configured and validated exactly like 'irr override'
+func newBatchJobCmd(parent *cobra.Command, job *BatchJob) (*cobra.Command, error) {
+	cmd := newOverrideCmd()
+	cmd.SetContext(parent.Context())
+	cmd.SetOut(parent.OutOrStdout())
+	cmd.SetErr(parent.ErrOrStderr())
+
+	scalars := []struct{ name, value string }{
+		{"chart-path", job.ChartPath},
+		{"target-registry", job.TargetRegistry},
+		{"path-strategy", job.PathStrategy},
+		{"target-flavor", job.TargetFlavor},
+		{"strict-mode", job.StrictMode},
+		{"namespace", job.Namespace},
+		{"output-format", job.OutputFormat},
+	}
+	if job.ContextAware != nil {
+		scalars = append(scalars, struct{ name, value string }{"context-aware", strconv.FormatBool(*job.ContextAware)})
+	}
+	// --validate of irr run applies to every job
+	if validate := parent.Flags().Lookup("validate"); validate != nil && validate.Changed {
+		scalars = append(scalars, struct{ name, value string }{"validate", validate.Value.String()})
+	}
+	for _, flag := range scalars {
+		if flag.value == "" {
+			continue
+		}
+		if err := cmd.Flags().Set(flag.name, flag.value); err != nil {
+			return nil, batchJobFlagError(job, flag.name, err)
+		}
+	}
+
+	// Slices are replaced rather than parsed, so --set values may contain commas
+	slicesToSet := []struct {
+		name   string
+		values []string
+	}{
+		{"source-registries", job.SourceRegistries},
+		{"exclude-registries", job.ExcludeRegistries},
+		{"registry-file", job.registryFiles()},
+		{"values", job.Values},
+		{"set", job.Set},
+	}
+	for _, flag := range slicesToSet {
+		if len(flag.values) == 0 {
+			continue
+		}
+		pf := cmd.Flags().Lookup(flag.name)
+		sliceValue, ok := pf.Value.(interface{ Replace([]string) error })
+		if !ok {
+			return nil, batchJobFlagError(job, flag.name, errors.New("not a list flag"))
+		}
+		if err := sliceValue.Replace(slices.Clone(flag.values)); err != nil {
+			return nil, batchJobFlagError(job, flag.name, err)
+		}
+		pf.Changed = true
+	}
+	return cmd, nil
+}
+
+// batchJobFlagError reports a job setting that could not be applied to the job's command
+func batchJobFlagError(job *BatchJob, flagName string, err error) error {
+	return &exitcodes.ExitCodeError{
+		Code: exitcodes.ExitInputConfigurationError,
+		Err:  fmt.Errorf("batch job %s: invalid value for --%s: %w", job.Name, flagName, err),
+	}
+}
+
+// runBatchJob generates and writes the overrides of a single job
+func runBatchJob(cmd *cobra.Command, job *BatchJob, overwrite, dryRun bool) BatchJobResult {
+	result := BatchJobResult{Name: job.Name, ChartPath: job.ChartPath, Release: job.Release}
+	// Jobs run concurrently, so their records name the job rather than relying on log.AddFields
+	logFields := []any{"job", job.Name}
+	if job.Release != "" {
+		logFields = append(logFields, "release", job.Release)
+	} else {
+		logFields = append(logFields, "chart", job.ChartPath)
+	}
+	log.Info("Running batch job", logFields...)
+	defer func() {
+		if result.Error != "" {
+			log.Warn("Batch job failed", append(logFields, "error", result.Error)...)
+		}
+	}()
+
+	var yamlBytes []byte
+	var config *GeneratorConfig
+	var err error
+	if job.Release != "" {
+		result.Namespace = job.Namespace
+		if result.Namespace == "" {
+			result.Namespace = os.Getenv("HELM_NAMESPACE")
+		}
+		if result.Namespace == "" {
+			result.Namespace = defaultNamespace
+		}
+		if err = requireNetwork(fmt.Sprintf("generating overrides for release %s", job.Release)); err == nil {
+			yamlBytes, config, err = generateReleaseOverrides(cmd, job.Release, result.Namespace, true)
+		}
+	} else {
+		yamlBytes, config, err = generateStandaloneOverrides(cmd, false)
+	}
+	if err != nil {
+		result.Error, result.ErrorCode, result.Category = reportedError(err)
+		return result
+	}
+
+	outputFormat := job.OutputFormat
+	if outputFormat == "" {
+		outputFormat = outputFormatYAML
+	}
+	output, err := formatOverrides(yamlBytes, outputFormat)
+	if err != nil {
+		result.Error, result.ErrorCode, result.Category = reportedError(err)
+		return result
+	}
+	if dryRun {
+		return result
+	}
+	// The encryption section of the job's registry config applies as it does to 'irr override'
+	if output, err = encryptOverrides(cmd, output, config.Encryption, outputFormat); err != nil {
+		result.Error, result.ErrorCode, result.Category = reportedError(err)
+		return result
+	}
+	if overwrite {
+		err = replaceOutputFile(job.OutputFile, output)
+	} else {
+		err = writeOutputFile(job.OutputFile, output, "Override values written")
+	}
+	if err != nil {
+		result.Error, result.ErrorCode, result.Category = reportedError(err)
+		return result
+	}
+	result.OutputFile = job.OutputFile
+	return result
+}
+
+// replaceOutputFile writes content to outputFile, replacing an existing file
+func replaceOutputFile(outputFile string, content []byte) error {
+	if dir := filepath.Dir(outputFile); dir != "" && dir != "." {
+		if err := AppFs.MkdirAll(dir, fileutil.ReadWriteExecuteUserReadExecuteOthers); err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to create output directory: %w", err),
+			}
+		}
+	}
+	if err := afero.WriteFile(AppFs, outputFile, content, fileutil.ReadWriteUserReadOthers); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write output file '%s': %w", outputFile, err),
+		}
+	}
+	log.Info("Output file written", "file", outputFile)
+	return nil
+}
+
+// newBatchReport builds the consolidated report of a batch run
+func newBatchReport(specFile string, results []BatchJobResult) *BatchReport {
+	report := &BatchReport{Spec: specFile, Jobs: results}
+	for _, result := range results {
+		if result.Error != "" {
+			report.Failed++
+		} else {
+			report.Succeeded++
+		}
+	}
+	return report
+}
+
+// logBatchReport logs the outcome of every job of a batch run
+func logBatchReport(report *BatchReport) {
+	for _, result := range report.Jobs {
+		if result.Error != "" {
+			log.Error("Batch job failed", "job", result.Name, "error", result.Error)
+			continue
+		}
+		log.Info("Batch job completed", "job", result.Name, "output", result.OutputFile)
+	}
+	log.Info("Batch run finished", "spec", report.Spec, "succeeded", report.Succeeded, "failed", report.Failed)
+}
+
+// outputBatchReport writes the report to reportFile, or to stdout when it is empty
+func outputBatchReport(cmd *cobra.Command, report *BatchReport, reportFile, reportFormat string) error {
+	var output []byte
+	var err error
+	if strings.EqualFold(reportFormat, outputFormatJSON) {
+		output, err = json.MarshalIndent(report, "", "  ")
+	} else {
+		output, err = yaml.Marshal(report)
+	}
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to marshal batch report: %w", err),
+		}
+	}
+	if reportFile != "" {
+		return replaceOutputFile(reportFile, output)
+	}
+	if _, err := fmt.Fprintln(cmd.OutOrStdout(), strings.TrimRight(string(output), "\n")); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write batch report to stdout: %w", err),
+		}
+	}
+	return nil
+}
+
+// batchError returns an error naming the failed jobs when any job of the report failed
+func batchError(report *BatchReport) error {
+	if report.Failed == 0 {
+		return nil
+	}
+	failed := make([]string, 0, report.Failed)
+	for _, result := range report.Jobs {
+		if result.Error != "" {
+			failed = append(failed, result.Name)
+		}
+	}
+	return &exitcodes.ExitCodeError{
+		Code: exitcodes.ExitChartProcessingFailed,
+		Err:  fmt.Errorf("%d of %d batch jobs failed: %s", report.Failed, len(report.Jobs), strings.Join(failed, ", ")),
+	}
+}
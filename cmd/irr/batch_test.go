@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestResolveBatchJobs(t *testing.T) {
+	contextAware := true
+	spec := &BatchSpec{
+		Defaults: BatchSettings{
+			TargetRegistry: "harbor.example.com",
+			RegistryFile:   "registry-mappings.yaml",
+			Values:         []string{"values/common.yaml"},
+			ContextAware:   &contextAware,
+		},
+		Jobs: []BatchJob{
+			{ChartPath: "charts/nginx", OutputFile: "out/nginx.yaml"},
+			{
+				Name:          "redis-prod",
+				ChartPath:     "/srv/charts/redis",
+				OutputFile:    "out/redis.yaml",
+				BatchSettings: BatchSettings{TargetRegistry: "ecr.example.com", Set: []string{"a=b,c"}},
+			},
+			{
+				ChartPath:     "charts/api",
+				OutputFile:    "out/api.yaml",
+				BatchSettings: BatchSettings{RegistryFiles: []string{"mappings.d/", "/etc/irr/eu.yaml"}},
+			},
+		},
+	}
+
+	jobs, err := resolveBatchJobs(spec, "/work", false)
+	require.NoError(t, err)
+	require.Len(t, jobs, 3)
+	assert.Equal(t, "nginx", jobs[0].Name)
+	assert.Equal(t, filepath.Join("/work", "charts/nginx"), jobs[0].ChartPath)
+	assert.Equal(t, filepath.Join("/work", "out/nginx.yaml"), jobs[0].OutputFile)
+	assert.Equal(t, filepath.Join("/work", "registry-mappings.yaml"), jobs[0].RegistryFile)
+	assert.Equal(t, []string{filepath.Join("/work", "mappings.d"), "/etc/irr/eu.yaml"}, jobs[2].registryFiles(),
+		"registryFiles of a job replace the registryFile of the defaults")
+	assert.Equal(t, []string{filepath.Join("/work", "values/common.yaml")}, jobs[0].Values)
+	assert.Equal(t, "harbor.example.com", jobs[0].TargetRegistry)
+	assert.Equal(t, "ecr.example.com", jobs[1].TargetRegistry, "job settings take precedence over defaults")
+	assert.Equal(t, "/srv/charts/redis", jobs[1].ChartPath)
+	require.NotNil(t, jobs[1].ContextAware)
+	assert.True(t, *jobs[1].ContextAware)
+	assert.Equal(t, []string{"values/common.yaml"}, spec.Defaults.Values, "defaults are not modified")
+
+	invalidSpecs := map[string][]BatchJob{
+		"no chart or release":  {{OutputFile: "out.yaml"}},
+		"chart and release":    {{ChartPath: "nginx", Release: "web", OutputFile: "out.yaml"}},
+		"missing output file":  {{ChartPath: "nginx"}},
+		"duplicate name":       {{ChartPath: "a/nginx", OutputFile: "a.yaml"}, {ChartPath: "b/nginx", OutputFile: "b.yaml"}},
+		"shared output file":   {{ChartPath: "nginx", OutputFile: "out.yaml"}, {ChartPath: "redis", OutputFile: "out.yaml"}},
+		"invalid outputFormat": {{ChartPath: "nginx", OutputFile: "out.yaml", BatchSettings: BatchSettings{OutputFormat: "toml"}}},
+	}
+	for name, invalidJobs := range invalidSpecs {
+		t.Run(name, func(t *testing.T) {
+			_, err := resolveBatchJobs(&BatchSpec{Jobs: invalidJobs}, ".", false)
+			var exitErr *exitcodes.ExitCodeError
+			require.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+		})
+	}
+
+	_, err = resolveBatchJobs(&BatchSpec{Jobs: []BatchJob{{ChartPath: "nginx"}}}, ".", true)
+	assert.NoError(t, err, "output files are optional for dry runs")
+}
+
+func TestNewBatchJobCmd(t *testing.T) {
+	contextAware := true
+	job := &BatchJob{
+		Name:      "nginx",
+		ChartPath: "charts/nginx",
+		BatchSettings: BatchSettings{
+			TargetRegistry:   "harbor.example.com",
+			SourceRegistries: []string{"docker.io", "quay.io"},
+			Set:              []string{"tolerations={a,b}"},
+			RegistryFile:     "base.yaml",
+			RegistryFiles:    []string{"mappings.d/", "eu,west.yaml"},
+			PathStrategy:     "flat",
+			ContextAware:     &contextAware,
+		},
+	}
+
+	cmd, err := newBatchJobCmd(newRunCmd(), job)
+	require.NoError(t, err)
+	chartPath, err := getStringFlag(cmd, "chart-path")
+	require.NoError(t, err)
+	assert.Equal(t, "charts/nginx", chartPath)
+	sources, err := getStringSliceFlag(cmd, "source-registries")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docker.io", "quay.io"}, sources)
+	set, err := getStringSliceFlag(cmd, "set")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tolerations={a,b}"}, set, "set values are not split at commas")
+	registryFiles, err := getStringSliceFlag(cmd, "registry-file")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"base.yaml", "mappings.d/", "eu,west.yaml"}, registryFiles)
+	strategyName, err := getStringFlag(cmd, "path-strategy")
+	require.NoError(t, err)
+	assert.Equal(t, "flat", strategyName)
+	enabled, err := getBoolFlag(cmd, "context-aware")
+	require.NoError(t, err)
+	assert.True(t, enabled)
+	validate, err := getBoolFlag(cmd, "validate")
+	require.NoError(t, err)
+	assert.False(t, validate)
+
+	runCmd := newRunCmd()
+	require.NoError(t, runCmd.ParseFlags([]string{"--validate"}))
+	cmd, err = newBatchJobCmd(runCmd, job)
+	require.NoError(t, err)
+	validate, err = getBoolFlag(cmd, "validate")
+	require.NoError(t, err)
+	assert.True(t, validate, "--validate of irr run applies to every job")
+}
+
+func TestBatchCacheRegistryConfig(t *testing.T) {
+	cache := newBatchCache(nil)
+	loads := 0
+	load := func(string, bool) (*registry.Config, error) {
+		loads++
+		return &registry.Config{}, nil
+	}
+
+	first, err := cache.registryConfig("registry-mappings.yaml", false, load)
+	require.NoError(t, err)
+	abs, err := filepath.Abs("registry-mappings.yaml")
+	require.NoError(t, err)
+	second, err := cache.registryConfig(abs, false, load)
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.Equal(t, 1, loads)
+}
+
+func TestRunBatch(t *testing.T) {
+	chartPath, err := filepath.Abs(helmExecTestChart)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	spec := BatchSpec{
+		Defaults: BatchSettings{TargetRegistry: "harbor.local", SourceRegistries: []string{"docker.io"}},
+		Jobs: []BatchJob{
+			{Name: "git", ChartPath: chartPath, OutputFile: "out/git.yaml"},
+			{Name: "git-json", ChartPath: chartPath, OutputFile: "out/git.json", BatchSettings: BatchSettings{OutputFormat: outputFormatJSON}},
+			{Name: "missing", ChartPath: "charts/missing", OutputFile: "out/missing.yaml"},
+		},
+	}
+	data, err := yaml.Marshal(spec)
+	require.NoError(t, err)
+	specFile := filepath.Join(dir, "batch.yaml")
+	require.NoError(t, os.WriteFile(specFile, data, 0o600))
+
+	runBatchCmd := func(extraArgs ...string) (*BatchReport, error) {
+		cmd := newRunCmd()
+		out := new(bytes.Buffer)
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"-f", specFile}, extraArgs...))
+		err := cmd.Execute()
+		report := &BatchReport{}
+		require.NoError(t, yaml.Unmarshal(out.Bytes(), report))
+		return report, err
+	}
+	var exitErr *exitcodes.ExitCodeError
+
+	report, err := runBatchCmd()
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitChartProcessingFailed, exitErr.Code)
+	assert.Contains(t, err.Error(), "1 of 3 batch jobs failed: missing")
+	assert.Equal(t, 2, report.Succeeded)
+	require.Len(t, report.Jobs, 3)
+	assert.Equal(t, filepath.Join(dir, "out/git.yaml"), report.Jobs[0].OutputFile)
+	assert.NotEmpty(t, report.Jobs[2].Error)
+	assert.FileExists(t, filepath.Join(dir, "out/git.yaml"))
+	jsonOutput, err := os.ReadFile(filepath.Join(dir, "out/git.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(jsonOutput), "harbor.local")
+
+	report, err = runBatchCmd()
+	require.Error(t, err)
+	assert.Contains(t, report.Jobs[0].Error, "already exists", "existing output files are kept without --overwrite")
+
+	report, err = runBatchCmd("--overwrite")
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 2, report.Succeeded)
+}
+
+func TestRunBatchConfigEncryption(t *testing.T) {
+	replaceEncryption(t)
+	chartPath, err := filepath.Abs(helmExecTestChart)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "registry.yaml"), []byte(`registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+encryption:
+  format: age
+  recipients:
+    - age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
+`), 0o600))
+	spec := BatchSpec{
+		Defaults: BatchSettings{TargetRegistry: "harbor.local", SourceRegistries: []string{"docker.io"}, RegistryFile: "registry.yaml"},
+		Jobs:     []BatchJob{{Name: "git", ChartPath: chartPath, OutputFile: "out/git.yaml"}},
+	}
+	data, err := yaml.Marshal(spec)
+	require.NoError(t, err)
+	specFile := filepath.Join(dir, "batch.yaml")
+	require.NoError(t, os.WriteFile(specFile, data, 0o600))
+
+	cmd := newRunCmd()
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetArgs([]string{"-f", specFile})
+	require.NoError(t, cmd.Execute())
+	output, err := os.ReadFile(filepath.Join(dir, "out/git.yaml"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(output), fakeAgeArmor), "jobs are encrypted with the encryption section of their registry config")
+}
+
+func TestRunBatchLogFields(t *testing.T) {
+	chartPath, err := filepath.Abs(helmExecTestChart)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	spec := BatchSpec{
+		Defaults: BatchSettings{TargetRegistry: "harbor.local", SourceRegistries: []string{"docker.io"}},
+		Jobs: []BatchJob{
+			{Name: "first", ChartPath: chartPath, OutputFile: "out/first.yaml"},
+			{Name: "second", ChartPath: chartPath, OutputFile: "out/second.yaml"},
+		},
+	}
+	data, err := yaml.Marshal(spec)
+	require.NoError(t, err)
+	specFile := filepath.Join(dir, "batch.yaml")
+	require.NoError(t, os.WriteFile(specFile, data, 0o600))
+	var logs bytes.Buffer
+	restoreLog := log.SetOutput(&logs)
+	t.Cleanup(restoreLog)
+	t.Cleanup(log.ResetFields)
+
+	cmd := newRunCmd()
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetArgs([]string{"-f", specFile})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, logs.String(), `"msg":"Running batch job","job":"first","chart":"`+chartPath+`"`)
+	assert.Contains(t, logs.String(), `"msg":"Running batch job","job":"second","chart":"`+chartPath+`"`)
+
+	logs.Reset()
+	log.Info("after batch")
+	assert.NotContains(t, logs.String(), `"chart"`, "jobs do not scope the chart to every log record")
+}
+
+func TestLoadBatchSpecUnknownField(t *testing.T) {
+	specFile := filepath.Join(t.TempDir(), "batch.yaml")
+	require.NoError(t, os.WriteFile(specFile, []byte("jobs:\n  - chartPath: nginx\n    targetRegistyr: harbor.local\n"), 0o600))
+
+	_, err := loadBatchSpec(specFile)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	assert.Contains(t, err.Error(), "targetRegistyr")
+}
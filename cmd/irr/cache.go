@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/cache"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	"github.com/lucas-albers-lz4/irr/pkg/limits"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/spf13/cobra"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/cli/values"
+)
+
+// cacheKeyLength is the number of key characters 'irr cache ls' shows in its table
+const cacheKeyLength = 12
+
+// newCacheCmd creates the cache command and its subcommands
+func newCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the chart analysis cache",
+		Long: `irr override caches the result of analyzing a chart, keyed by a digest of the chart
+(the archive, or every file of a chart directory) and of the values it was analyzed with.
+Later runs on the same chart contents and values reuse the result instead of analyzing the
+chart again. Changing any chart file, values file or --set value changes the key, as do
+--offline, the analysis limits, --default-registry and --rules-file. Analyses of charts with
+missing dependencies are not cached.
+
+The cache is stored in irr/analysis below the user cache directory (e.g. ~/.cache on Linux),
+or in the directory set by IRR_CACHE_DIR. Use 'irr override --no-cache' to skip it for a run.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.AddCommand(newCacheLsCmd(), newCacheClearCmd())
+	return cmd
+}
+
+// newCacheLsCmd creates the cache ls command
+func newCacheLsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List cached chart analyses",
+		Args:    cobra.NoArgs,
+		RunE:    runCacheLs,
+	}
+	cmd.Flags().String("output-format", outputFormatTable, "Output format (table or json)")
+	if err := cmd.RegisterFlagCompletionFunc("output-format",
+		cobra.FixedCompletions([]string{outputFormatTable, outputFormatJSON}, cobra.ShellCompDirectiveNoFileComp)); err != nil {
+		log.Debug("Failed to register output-format completion", "error", err)
+	}
+	return cmd
+}
+
+// newCacheClearCmd creates the cache clear command
+func newCacheClearCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear",
+		Short: "Remove all cached chart analyses",
+		Args:  cobra.NoArgs,
+		RunE:  runCacheClear,
+	}
+}
+
+// runCacheLs implements the cache ls command
+func runCacheLs(cmd *cobra.Command, _ []string) error {
+	outputFormat, err := getStringFlag(cmd, "output-format")
+	if err != nil {
+		return err
+	}
+	if outputFormat != outputFormatTable && outputFormat != outputFormatJSON {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("unsupported output format %q; supported formats: table, json", outputFormat),
+		}
+	}
+	analysisCache, err := openAnalysisCache()
+	if err != nil {
+		return err
+	}
+	infos, err := analysisCache.List()
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitIOError, Err: err}
+	}
+
+	if outputFormat == outputFormatJSON {
+		if infos == nil {
+			infos = []cache.Info{}
+		}
+		output, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			return &exitcodes.ExitCodeError{Code: exitcodes.ExitGeneralRuntimeError, Err: fmt.Errorf("failed to marshal cache entries to JSON: %w", err)}
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), string(output))
+		return err
+	}
+
+	if len(infos) == 0 {
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "No cached analyses in %s\n", analysisCache.Dir())
+		return err
+	}
+	var buf strings.Builder
+	writer := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(writer, "KEY\tCHART\tVERSION\tSIZE\tCREATED\tPATH"); err != nil {
+		return fmt.Errorf("failed to render cache table: %w", err)
+	}
+	for _, info := range infos {
+		key := info.Key
+		if len(key) > cacheKeyLength {
+			key = key[:cacheKeyLength]
+		}
+		if _, err := fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%s\t%s\n", key, dashIfEmpty(info.ChartName),
+			dashIfEmpty(info.ChartVersion), info.Size, info.Created.Local().Format("2006-01-02 15:04:05"), dashIfEmpty(info.Chart)); err != nil {
+			return fmt.Errorf("failed to render cache table: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to render cache table: %w", err)
+	}
+	_, err = fmt.Fprint(cmd.OutOrStdout(), buf.String())
+	return err
+}
+
+// runCacheClear implements the cache clear command
+func runCacheClear(cmd *cobra.Command, _ []string) error {
+	analysisCache, err := openAnalysisCache()
+	if err != nil {
+		return err
+	}
+	removed, err := analysisCache.Clear()
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitIOError, Err: err}
+	}
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "Removed %d cached analyses from %s\n", removed, analysisCache.Dir())
+	return err
+}
+
+// dashIfEmpty returns value, or "-" for an empty table cell
+func dashIfEmpty(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// openAnalysisCache returns the analysis cache in its default directory
+func openAnalysisCache() (*cache.Cache, error) {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	return cache.New(AppFs, dir), nil
+}
+
+// analysisCacheKey returns the analysis cache key of a chart analyzed with the given values, or an
+// empty string when the chart or a values file cannot be read, in which case the cache is not used.
+// The key covers the irr version, so upgrades never reuse results of an older analyzer, whether
+// missing dependencies are downloaded, the analysis limits in effect, the registry unqualified
+// images resolve to, and the contents of the --rules-file, if any.
+func analysisCacheKey(chartPath string, valueOpts *values.Options, contextAware, downloadDependencies bool, rulesFile string) string {
+	digest, err := cache.ChartDigest(AppFs, chartPath)
+	if err != nil {
+		log.Debug("Not using the analysis cache: failed to hash chart", "chartPath", chartPath, "error", err)
+		return ""
+	}
+	mode := "legacy"
+	if contextAware {
+		mode = "context-aware"
+	}
+	dependencies := "offline"
+	if downloadDependencies {
+		dependencies = "download"
+	}
+	current := limits.Current()
+	inputs := []string{
+		BinaryVersion, mode, "dependencies=" + dependencies,
+		fmt.Sprintf("limits=%d,%d,%d", current.MaxValuesSize, current.MaxDepth, current.MaxImagePatterns),
+		"default-registry=" + image.UnqualifiedRegistry(),
+	}
+	if rulesFile != "" {
+		contentDigest, err := cache.FileDigest(AppFs, rulesFile)
+		if err != nil {
+			log.Debug("Not using the analysis cache: failed to read rules file", "file", rulesFile, "error", err)
+			return ""
+		}
+		inputs = append(inputs, "rules-file="+rulesFile, contentDigest)
+	}
+	for _, file := range valueOpts.ValueFiles {
+		contentDigest, err := cache.FileDigest(AppFs, file)
+		if err != nil {
+			log.Debug("Not using the analysis cache: failed to read values file", "file", file, "error", err)
+			return ""
+		}
+		inputs = append(inputs, "values="+file, contentDigest)
+	}
+	for _, file := range valueOpts.FileValues {
+		_, path, _ := strings.Cut(file, "=")
+		contentDigest, err := cache.FileDigest(AppFs, path)
+		if err != nil {
+			log.Debug("Not using the analysis cache: failed to read --set-file file", "file", path, "error", err)
+			return ""
+		}
+		inputs = append(inputs, "set-file="+file, contentDigest)
+	}
+	for _, flag := range []struct {
+		name   string
+		values []string
+	}{
+		{"set", valueOpts.Values},
+		{"set-string", valueOpts.StringValues},
+		{"set-json", valueOpts.JSONValues},
+		{"set-literal", valueOpts.LiteralValues},
+	} {
+		for _, value := range flag.values {
+			inputs = append(inputs, flag.name+"="+value)
+		}
+	}
+	return cache.Key(digest, inputs...)
+}
+
+// cachedAnalysis returns the cache entry for key, or nil when key is empty or has no entry.
+// Cache errors are logged and treated as a miss.
+func cachedAnalysis(key string) *cache.Entry {
+	if key == "" {
+		return nil
+	}
+	analysisCache, err := openAnalysisCache()
+	if err != nil {
+		log.Debug("Not using the analysis cache", "error", err)
+		return nil
+	}
+	entry, ok, err := analysisCache.Get(key)
+	if err != nil {
+		log.Warn("Ignoring unreadable analysis cache entry", "key", key, "error", err)
+		return nil
+	}
+	if !ok {
+		log.Debug("No cached analysis for chart", "key", key)
+		return nil
+	}
+	return entry
+}
+
+// storeAnalysis writes an analysis result to the cache. It must be called before the result is
+// changed by later processing. Failures are logged; they never fail the run.
+func storeAnalysis(key, chartPath string, loadedChart *helmchart.Chart, result *analysis.ChartAnalysis, analyzedValues map[string]interface{}) {
+	analysisCache, err := openAnalysisCache()
+	if err != nil {
+		log.Debug("Not caching chart analysis", "error", err)
+		return
+	}
+	entry := &cache.Entry{Key: key, Chart: chartPath, Analysis: result, Values: analyzedValues}
+	if loadedChart != nil && loadedChart.Metadata != nil {
+		entry.ChartName = loadedChart.Metadata.Name
+		entry.ChartVersion = loadedChart.Metadata.Version
+	}
+	if err := analysisCache.Put(entry); err != nil {
+		log.Warn("Failed to cache chart analysis", "dir", analysisCache.Dir(), "error", err)
+		return
+	}
+	log.Debug("Cached chart analysis", "key", key, "dir", analysisCache.Dir())
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/cache"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	"github.com/lucas-albers-lz4/irr/pkg/limits"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/cli/values"
+)
+
+func TestAnalysisCacheKey(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	restoreFs := SetFs(fs)
+	t.Cleanup(restoreFs)
+	require.NoError(t, afero.WriteFile(fs, "/charts/web/Chart.yaml", []byte("name: web\nversion: 1.0.0\n"), 0o600))
+	require.NoError(t, afero.WriteFile(fs, "/values.yaml", []byte("replicaCount: 2\n"), 0o600))
+
+	opts := &values.Options{ValueFiles: []string{"/values.yaml"}, Values: []string{"image.tag=1.25"}}
+	key := analysisCacheKey("/charts/web", opts, true, true, "")
+	require.NotEmpty(t, key)
+	assert.Equal(t, key, analysisCacheKey("/charts/web", opts, true, true, ""))
+	assert.NotEqual(t, key, analysisCacheKey("/charts/web", opts, false, true, ""), "the analysis mode is part of the key")
+	assert.NotEqual(t, key, analysisCacheKey("/charts/web", &values.Options{ValueFiles: opts.ValueFiles}, true, true, ""))
+	assert.NotEqual(t, key, analysisCacheKey("/charts/web", opts, true, false, ""), "downloading missing dependencies is part of the key")
+	restoreLimits := limits.Set(limits.Limits{MaxDepth: 20})
+	assert.NotEqual(t, key, analysisCacheKey("/charts/web", opts, true, true, ""), "the analysis limits are part of the key")
+	restoreLimits()
+	restoreRegistry := image.SetDefaultRegistry("mirror.local")
+	assert.NotEqual(t, key, analysisCacheKey("/charts/web", opts, true, true, ""), "the default registry is part of the key")
+	restoreRegistry()
+	require.NoError(t, afero.WriteFile(fs, "/rules.yaml", []byte("conventions: []\n"), 0o600))
+	rulesKey := analysisCacheKey("/charts/web", opts, true, true, "/rules.yaml")
+	assert.NotEqual(t, key, rulesKey, "the rules file is part of the key")
+	require.NoError(t, afero.WriteFile(fs, "/rules.yaml", []byte("conventions:\n  - name: web\n"), 0o600))
+	assert.NotEqual(t, rulesKey, analysisCacheKey("/charts/web", opts, true, true, "/rules.yaml"), "rules file contents are part of the key")
+	assert.Empty(t, analysisCacheKey("/charts/web", opts, true, true, "/missing-rules.yaml"))
+
+	require.NoError(t, afero.WriteFile(fs, "/values.yaml", []byte("replicaCount: 3\n"), 0o600))
+	assert.NotEqual(t, key, analysisCacheKey("/charts/web", opts, true, true, ""), "values file contents are part of the key")
+
+	assert.Empty(t, analysisCacheKey("/charts/web", &values.Options{ValueFiles: []string{"/missing.yaml"}}, true, true, ""))
+	assert.Empty(t, analysisCacheKey("/charts/missing", opts, true, true, ""))
+}
+
+func TestAnalysisCacheCommands(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	restoreFs := SetFs(fs)
+	t.Cleanup(restoreFs)
+	t.Setenv(cache.DirEnvVar, "/cache")
+
+	assert.Nil(t, cachedAnalysis(""))
+	assert.Nil(t, cachedAnalysis("web"))
+	loadedChart := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "web", Version: "1.0.0"}}
+	result := &analysis.ChartAnalysis{ImagePatterns: []analysis.ImagePattern{{Path: "image", Type: analysis.PatternTypeString, Value: "nginx:1.25", Count: 1}}}
+	storeAnalysis("web", "charts/web", loadedChart, result, map[string]interface{}{"image": "nginx:1.25"})
+
+	entry := cachedAnalysis("web")
+	require.NotNil(t, entry)
+	assert.Equal(t, result, entry.Analysis)
+	assert.Equal(t, map[string]interface{}{"image": "nginx:1.25"}, entry.Values)
+
+	runCacheCmd := func(args ...string) string {
+		cmd := newCacheCmd()
+		out := new(bytes.Buffer)
+		cmd.SetOut(out)
+		cmd.SetArgs(args)
+		require.NoError(t, cmd.Execute())
+		return out.String()
+	}
+
+	table := runCacheCmd("ls")
+	assert.Contains(t, table, "KEY")
+	assert.Contains(t, table, "web")
+	assert.Contains(t, table, "1.0.0")
+	assert.Contains(t, table, "charts/web")
+
+	var infos []cache.Info
+	require.NoError(t, json.Unmarshal([]byte(runCacheCmd("ls", "--output-format", "json")), &infos))
+	require.Len(t, infos, 1)
+	assert.Equal(t, "web", infos[0].ChartName)
+
+	assert.Equal(t, "Removed 1 cached analyses from /cache\n", runCacheCmd("clear"))
+	assert.Equal(t, "No cached analyses in /cache\n", runCacheCmd("ls"))
+	assert.Nil(t, cachedAnalysis("web"))
+}
+
+func TestAnalysisCacheSkipsMissingDependencies(t *testing.T) {
+	t.Setenv(cache.DirEnvVar, t.TempDir())
+	t.Setenv(offlineEnvVar, trueString)
+	chartPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(chartPath, "values.yaml"), []byte("image:\n  repository: docker.io/library/nginx\n  tag: \"1.25\"\n"), 0o600))
+	writeChart := func(dependencies string) {
+		require.NoError(t, os.WriteFile(filepath.Join(chartPath, "Chart.yaml"),
+			[]byte("apiVersion: v2\nname: web\nversion: 1.0.0\n"+dependencies), 0o600))
+	}
+	runOverrideCmd := func() []cache.Info {
+		cmd := newOverrideCmd()
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetArgs([]string{"--chart-path", chartPath, "--target-registry", "harbor.local", "--source-registries", "docker.io", "--dry-run"})
+		require.NoError(t, cmd.Execute())
+		analysisCache, err := openAnalysisCache()
+		require.NoError(t, err)
+		infos, err := analysisCache.List()
+		require.NoError(t, err)
+		return infos
+	}
+
+	writeChart("dependencies:\n  - name: redis\n    version: 1.0.0\n    repository: https://charts.example.com\n")
+	assert.Empty(t, runOverrideCmd(), "analyses of charts with missing dependencies are not cached")
+	writeChart("")
+	assert.Len(t, runOverrideCmd(), 1)
+}
+
+func TestAnalysisCacheDefaultRegistry(t *testing.T) {
+	t.Setenv(cache.DirEnvVar, t.TempDir())
+	chartPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(chartPath, "Chart.yaml"), []byte("apiVersion: v2\nname: web\nversion: 1.0.0\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(chartPath, "values.yaml"), []byte("image: redis:7.2\n"), 0o600))
+	runOverrideCmd := func() string {
+		cmd := newOverrideCmd()
+		out := new(bytes.Buffer)
+		cmd.SetOut(out)
+		cmd.SetArgs([]string{"--chart-path", chartPath, "--target-registry", "harbor.local", "--source-registries", "docker.io,mirror.local", "--dry-run"})
+		require.NoError(t, cmd.Execute())
+		return out.String()
+	}
+
+	assert.Contains(t, runOverrideCmd(), "repository: docker.io/library/redis")
+	restoreRegistry := image.SetDefaultRegistry("mirror.local")
+	t.Cleanup(restoreRegistry)
+	assert.Contains(t, runOverrideCmd(), "repository: mirror.local/redis", "a different default registry misses the cache")
+	analysisCache, err := openAnalysisCache()
+	require.NoError(t, err)
+	infos, err := analysisCache.List()
+	require.NoError(t, err)
+	assert.Len(t, infos, 2)
+}
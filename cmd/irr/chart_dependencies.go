@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/lucas-albers-lz4/irr/internal/helm"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/spf13/cobra"
+)
+
+// addDependencyFlags adds the flags controlling how dependencies missing from a chart directory
+// are downloaded, including oci:// dependencies that need registry credentials
+func addDependencyFlags(cmd *cobra.Command) {
+	cmd.Flags().String("registry-config", "", "Helm registry credentials file used to pull oci:// chart dependencies (default: Helm's registry config)")
+	cmd.Flags().String("registry-username", "", "Username for the registries of oci:// chart dependencies")
+	cmd.Flags().String("registry-password", "", "Password for the registries of oci:// chart dependencies")
+	cmd.Flags().Bool("plain-http", false, "Pull oci:// chart dependencies, and push --output-uri oci:// artifacts, over HTTP instead of HTTPS")
+}
+
+// getDependencyOptions returns the options to download missing chart dependencies with, or nil
+// in offline mode, where missing dependencies are skipped
+func getDependencyOptions(cmd *cobra.Command) (*helm.DependencyOptions, error) {
+	opts := &helm.DependencyOptions{}
+	var err error
+	opts.RegistryConfig, err = getStringFlag(cmd, "registry-config")
+	if err != nil {
+		return nil, err
+	}
+	opts.Username, err = getStringFlag(cmd, "registry-username")
+	if err != nil {
+		return nil, err
+	}
+	opts.Password, err = getStringFlag(cmd, "registry-password")
+	if err != nil {
+		return nil, err
+	}
+	opts.PlainHTTP, err = getBoolFlag(cmd, "plain-http")
+	if err != nil {
+		return nil, err
+	}
+	opts.Transport = transportOptions(nil)
+	if (opts.Username == "") != (opts.Password == "") {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--registry-username and --registry-password must be set together"),
+		}
+	}
+	if isOffline() {
+		return nil, nil
+	}
+	return opts, nil
+}
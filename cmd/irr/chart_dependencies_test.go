@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDependencyOptions(t *testing.T) {
+	cmd := newOverrideCmd()
+	require.NoError(t, cmd.ParseFlags([]string{
+		"--registry-config", "/home/user/.config/helm/registry/config.json",
+		"--registry-username", "user", "--registry-password", "secret", "--plain-http",
+	}))
+	originalCAFile := caFile
+	caFile = "/etc/ssl/corp-ca.pem"
+	t.Cleanup(func() { caFile = originalCAFile })
+
+	opts, err := getDependencyOptions(cmd)
+	require.NoError(t, err)
+	require.NotNil(t, opts)
+	assert.Equal(t, "/home/user/.config/helm/registry/config.json", opts.RegistryConfig)
+	assert.Equal(t, "user", opts.Username)
+	assert.Equal(t, "secret", opts.Password)
+	assert.True(t, opts.PlainHTTP)
+	assert.Equal(t, "/etc/ssl/corp-ca.pem", opts.Transport.Default.CAFile, "--ca-file applies to dependency downloads")
+
+	original := offlineMode
+	offlineMode = true
+	t.Cleanup(func() { offlineMode = original })
+	opts, err = getDependencyOptions(cmd)
+	require.NoError(t, err)
+	assert.Nil(t, opts, "offline runs do not download dependencies")
+}
+
+func TestGetDependencyOptionsUsernameWithoutPassword(t *testing.T) {
+	cmd := newInspectCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--registry-username", "user"}))
+
+	_, err := getDependencyOptions(cmd)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/internal/helm"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// Variables to support mocking chart downloads in tests
+var (
+	cachedChartRef   = helm.CachedChartRef
+	downloadChartRef = helm.DownloadChartRef
+	cloneGitChartRef = helm.CloneGitChartRef
+)
+
+// gitChartsDir holds the repositories cloned for git+ chart references of the running command
+var gitChartsDir string
+
+// addChartRefFlags adds the flags selecting a chart of a Helm or git repository instead of --chart-path
+func addChartRefFlags(cmd *cobra.Command) {
+	cmd.Flags().String("chart-ref", "", "Chart to download and analyze instead of --chart-path: repo/chart from a repository added with 'helm repo add', oci://registry/path/chart, or git+https://host/org/repo//path/to/chart?ref=tag, branch or commit")
+	cmd.Flags().String("version", "", "Version or version constraint of the --chart-ref chart (default: latest); git+ charts select it with ?ref= instead")
+}
+
+// resolveChartRefFlag downloads the chart given with --chart-ref, as 'helm install' locates
+// repo/chart and oci:// charts, or clones the git repository of a git+ chart, and sets
+// --chart-path to the downloaded archive or chart directory, so that the command analyzes it like
+// a local chart. Repository charts already in Helm's repository cache are used without
+// downloading them again.
+func resolveChartRefFlag(cmd *cobra.Command) error {
+	flag := cmd.Flags().Lookup("chart-ref")
+	if flag == nil {
+		return nil
+	}
+	ref := flag.Value.String()
+	version, err := getStringFlag(cmd, "version")
+	if err != nil {
+		return err
+	}
+	if ref == "" {
+		if version != "" {
+			return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: errors.New("--version requires --chart-ref")}
+		}
+		return nil
+	}
+	if cmd.Flags().Changed("chart-path") {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: errors.New("--chart-ref and --chart-path cannot be used together")}
+	}
+
+	var chartPath string
+	if helm.IsGitChartRef(ref) {
+		chartPath, err = resolveGitChartRef(cmd, ref, version)
+	} else {
+		chartPath, err = resolveChartRef(cmd, ref, version)
+	}
+	if err != nil {
+		return err
+	}
+	if err := cmd.Flags().Set("chart-path", chartPath); err != nil {
+		return fmt.Errorf("failed to set chart path of %s: %w", ref, err)
+	}
+	return nil
+}
+
+// resolveChartRef returns the local path of the chart ref at version, from Helm's repository cache
+// or downloaded into it
+func resolveChartRef(cmd *cobra.Command, ref, version string) (string, error) {
+	chartPath, err := cachedChartRef(ref, version)
+	if err != nil {
+		return "", chartRefError(ref, err)
+	}
+	if chartPath != "" {
+		log.Info("Using chart from the Helm repository cache", "chart", ref, "path", chartPath)
+		return chartPath, nil
+	}
+
+	if err := requireNetwork(fmt.Sprintf("downloading chart %s (--chart-ref)", ref)); err != nil {
+		return "", err
+	}
+	opts, err := getDependencyOptions(cmd)
+	if err != nil {
+		return "", err
+	}
+	chartPath, err = downloadChartRef(ref, version, opts)
+	if err != nil {
+		return "", chartRefError(ref, err)
+	}
+	log.Info("Downloaded chart", "chart", ref, "path", chartPath)
+	return chartPath, nil
+}
+
+// chartRefError wraps an error locating the chart ref with its exit code
+func chartRefError(ref string, err error) error {
+	code := exitcodes.ExitChartNotFound
+	if errors.Is(err, helm.ErrInvalidChartRef) {
+		code = exitcodes.ExitInputConfigurationError
+	}
+	return &exitcodes.ExitCodeError{Code: code, Err: fmt.Errorf("failed to locate chart %s: %w", ref, err)}
+}
+
+// resolveGitChartRef shallow-clones the repository of the git+ chart ref into a temporary
+// directory, removed when the command ends, and returns the path of the chart in it.
+func resolveGitChartRef(cmd *cobra.Command, ref, version string) (string, error) {
+	if version != "" {
+		return "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("--version cannot be used with git chart %s; select the tag, branch or commit with ?ref=", ref),
+		}
+	}
+	gitRef, err := helm.ParseGitChartRef(ref)
+	if err != nil {
+		return "", &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	if !strings.HasPrefix(gitRef.Repository, "file://") {
+		if err := requireNetwork(fmt.Sprintf("cloning chart %s (--chart-ref)", gitRef)); err != nil {
+			return "", err
+		}
+	}
+
+	if gitChartsDir == "" {
+		dir, err := os.MkdirTemp("", "irr-git-charts-")
+		if err != nil {
+			return "", &exitcodes.ExitCodeError{Code: exitcodes.ExitIOError, Err: fmt.Errorf("failed to create directory for cloned charts: %w", err)}
+		}
+		gitChartsDir = dir
+	}
+	dir, err := os.MkdirTemp(gitChartsDir, "repo-")
+	if err != nil {
+		return "", &exitcodes.ExitCodeError{Code: exitcodes.ExitIOError, Err: fmt.Errorf("failed to create directory for %s: %w", gitRef, err)}
+	}
+	chartPath, commit, err := cloneGitChartRef(getCommandContext(cmd), gitRef, dir)
+	if err != nil {
+		return "", chartRefError(gitRef.String(), err)
+	}
+	log.Info("Cloned chart", "chart", gitRef.String(), "commit", commit, "path", chartPath)
+	return chartPath, nil
+}
+
+// removeGitCharts removes the repositories cloned for git+ chart references
+func removeGitCharts() {
+	if gitChartsDir == "" {
+		return
+	}
+	if err := os.RemoveAll(gitChartsDir); err != nil {
+		log.Warn("Failed to remove cloned chart repositories", "dir", gitChartsDir, "error", err)
+	}
+	gitChartsDir = ""
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/internal/helm"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveChartRefFlag(t *testing.T) {
+	originalCached, originalDownload, originalOffline := cachedChartRef, downloadChartRef, offlineMode
+	t.Cleanup(func() {
+		cachedChartRef, downloadChartRef, offlineMode = originalCached, originalDownload, originalOffline
+	})
+
+	cached := map[string]string{"bitnami/nginx@15.1.0": "/cache/nginx-15.1.0.tgz"}
+	var downloads []string
+	cachedChartRef = func(ref, version string) (string, error) {
+		if ref == "nginx" {
+			return "", fmt.Errorf("%w, got %q", helm.ErrInvalidChartRef, ref)
+		}
+		return cached[ref+"@"+version], nil
+	}
+	downloadChartRef = func(ref, version string, _ *helm.DependencyOptions) (string, error) {
+		downloads = append(downloads, ref+"@"+version)
+		if ref == "bitnami/missing" {
+			return "", errors.New("chart not found")
+		}
+		return "/cache/downloaded.tgz", nil
+	}
+	run := func(flags ...string) (string, error) {
+		cmd := newOverrideCmd()
+		for i := 0; i < len(flags); i += 2 {
+			require.NoError(t, cmd.Flags().Set(flags[i], flags[i+1]))
+		}
+		err := resolveChartRefFlag(cmd)
+		chartPath, flagErr := cmd.Flags().GetString("chart-path")
+		require.NoError(t, flagErr)
+		return chartPath, err
+	}
+
+	chartPath, err := run("chart-ref", "bitnami/nginx", "version", "15.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "/cache/nginx-15.1.0.tgz", chartPath)
+	assert.Empty(t, downloads, "cached chart used without downloading")
+
+	chartPath, err = run("chart-ref", "bitnami/nginx", "version", "^16")
+	require.NoError(t, err)
+	assert.Equal(t, "/cache/downloaded.tgz", chartPath)
+	assert.Equal(t, []string{"bitnami/nginx@^16"}, downloads)
+
+	chartPath, err = run()
+	require.NoError(t, err)
+	assert.Empty(t, chartPath)
+
+	exitCode := func(err error) int {
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		return exitErr.Code
+	}
+	_, err = run("chart-ref", "bitnami/missing")
+	assert.Equal(t, exitcodes.ExitChartNotFound, exitCode(err))
+	_, err = run("chart-ref", "nginx")
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitCode(err))
+	_, err = run("chart-ref", "bitnami/nginx", "chart-path", "./nginx")
+	assert.ErrorContains(t, err, "--chart-ref and --chart-path cannot be used together")
+	_, err = run("version", "1.0.0")
+	assert.ErrorContains(t, err, "--version requires --chart-ref")
+
+	offlineMode = true
+	_, err = run("chart-ref", "bitnami/nginx", "version", "15.1.0")
+	require.NoError(t, err, "cached charts need no network access")
+	_, err = run("chart-ref", "bitnami/nginx", "version", "^16")
+	assert.ErrorContains(t, err, "downloading chart bitnami/nginx (--chart-ref) requires network access")
+}
+
+func TestResolveGitChartRef(t *testing.T) {
+	originalClone, originalOffline := cloneGitChartRef, offlineMode
+	t.Cleanup(func() { cloneGitChartRef, offlineMode = originalClone, originalOffline })
+	t.Cleanup(removeGitCharts)
+
+	var clones []*helm.GitChartRef
+	cloneGitChartRef = func(_ context.Context, ref *helm.GitChartRef, dir string) (string, string, error) {
+		clones = append(clones, ref)
+		if ref.Ref == "missing" {
+			return "", "", errors.New("couldn't find remote ref missing")
+		}
+		return filepath.Join(dir, ref.Path), "3f2c1ab9e0d4c5b6a7f8091a2b3c4d5e6f708192", nil
+	}
+	run := func(flags ...string) (string, error) {
+		cmd := newInspectCmd()
+		for i := 0; i < len(flags); i += 2 {
+			require.NoError(t, cmd.Flags().Set(flags[i], flags[i+1]))
+		}
+		err := resolveChartRefFlag(cmd)
+		chartPath, flagErr := cmd.Flags().GetString("chart-path")
+		require.NoError(t, flagErr)
+		return chartPath, err
+	}
+	exitCode := func(err error) int {
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		return exitErr.Code
+	}
+
+	chartPath, err := run("chart-ref", "git+https://github.com/org/repo//charts/app?ref=v1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, []*helm.GitChartRef{{Repository: "https://github.com/org/repo", Path: "charts/app", Ref: "v1.2.3"}}, clones)
+	assert.True(t, strings.HasPrefix(chartPath, gitChartsDir), "cloned into the directory removed when the command ends")
+	assert.True(t, strings.HasSuffix(chartPath, filepath.Join("charts", "app")))
+
+	_, err = run("chart-ref", "git+https://github.com/org/repo?ref=missing")
+	assert.Equal(t, exitcodes.ExitChartNotFound, exitCode(err))
+	_, err = run("chart-ref", "git+ftp://example.com/repo")
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitCode(err))
+	_, err = run("chart-ref", "git+https://github.com/org/repo", "version", "1.2.3")
+	assert.ErrorContains(t, err, "select the tag, branch or commit with ?ref=")
+
+	offlineMode = true
+	_, err = run("chart-ref", "git+https://github.com/org/repo")
+	assert.ErrorContains(t, err, "cloning chart git+https://github.com/org/repo (--chart-ref) requires network access")
+	_, err = run("chart-ref", "git+file:///srv/git/repo")
+	require.NoError(t, err, "local repositories need no network access")
+
+	dir := gitChartsDir
+	removeGitCharts()
+	assert.NoDirExists(t, dir)
+}
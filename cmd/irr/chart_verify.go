@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/lucas-albers-lz4/irr/internal/helm"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/spf13/cobra"
+)
+
+// addChartVerifyFlags adds the flags controlling chart provenance/signature verification
+func addChartVerifyFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("verify", false, "Verify the chart's provenance file (.prov) or cosign signature (.sig) before analysis; requires a packaged chart (.tgz)")
+	cmd.Flags().String("keyring", helm.DefaultKeyring(), "Keyring containing public keys used to verify chart provenance")
+	cmd.Flags().String("cosign-key", "", "Cosign public key; verify the chart's cosign signature instead of its provenance file")
+}
+
+// getChartVerifyFlags returns whether --verify is set and the options to verify with
+func getChartVerifyFlags(cmd *cobra.Command) (verify bool, opts helm.ChartVerifyOptions, err error) {
+	verify, err = getBoolFlag(cmd, "verify")
+	if err != nil {
+		return false, opts, err
+	}
+	opts.Keyring, err = getStringFlag(cmd, "keyring")
+	if err != nil {
+		return false, opts, err
+	}
+	opts.CosignKey, err = getStringFlag(cmd, "cosign-key")
+	if err != nil {
+		return false, opts, err
+	}
+	opts.Offline = isOffline()
+	return verify, opts, nil
+}
+
+// verifyChart verifies a packaged chart, mapping failures to ExitChartVerificationFailed
+func verifyChart(chartPath string, opts helm.ChartVerifyOptions) (*helm.ChartVerification, error) {
+	verification, err := helm.VerifyChart(chartPath, opts)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitChartVerificationFailed,
+			Err:  fmt.Errorf("chart verification failed: %w", err),
+		}
+	}
+	return verification, nil
+}
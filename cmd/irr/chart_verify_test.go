@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectVerifyUnpackagedChart(t *testing.T) {
+	restoreFs := SetFs(afero.NewOsFs())
+	defer restoreFs()
+
+	cmd := newInspectCmd()
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	cmd.SetArgs([]string{"--chart-path", t.TempDir(), "--verify"})
+
+	err := cmd.Execute()
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitChartVerificationFailed, exitErr.Code)
+	assert.Contains(t, err.Error(), "only packaged charts")
+}
+
+func TestGetInspectFlagsVerifyWithRelease(t *testing.T) {
+	cmd := newInspectCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--verify"}))
+
+	_, err := getInspectFlags(cmd, true)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+}
+
+func TestGetChartVerifyFlags(t *testing.T) {
+	cmd := newOverrideCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--verify", "--keyring", "/keys/pubring.gpg", "--cosign-key", "cosign.pub"}))
+
+	verify, opts, err := getChartVerifyFlags(cmd)
+	require.NoError(t, err)
+	assert.True(t, verify)
+	assert.Equal(t, "/keys/pubring.gpg", opts.Keyring)
+	assert.Equal(t, "cosign.pub", opts.CosignKey)
+}
@@ -138,12 +138,27 @@ func (m *MockHelmClient) LoadChart(chartPath string) (*helmchart.Chart, error) {
 	return m.ReleaseChart, nil
 }
 
+// GetReleaseValuesAtRevision mocks retrieving values from a specific release revision
+func (m *MockHelmClient) GetReleaseValuesAtRevision(ctx context.Context, releaseName, namespace string, _ int) (map[string]interface{}, error) {
+	return m.GetReleaseValues(ctx, releaseName, namespace)
+}
+
+// GetChartFromReleaseAtRevision mocks retrieving a chart from a specific release revision
+func (m *MockHelmClient) GetChartFromReleaseAtRevision(ctx context.Context, releaseName, namespace string, _ int) (*helm.ChartMetadata, error) {
+	return m.GetChartFromRelease(ctx, releaseName, namespace)
+}
+
 // ListReleases implements helm.ClientInterface and returns an empty list of releases
 func (m *MockHelmClient) ListReleases(_ context.Context, _ bool) ([]*helm.ReleaseElement, error) {
 	// For simplicity in tests, return an empty list or could be enhanced to return mock releases
 	return []*helm.ReleaseElement{}, nil
 }
 
+// ListReleasesWithOptions implements helm.ClientInterface and returns an empty list of releases
+func (m *MockHelmClient) ListReleasesWithOptions(_ context.Context, _ helm.ReleaseListOptions) ([]*helm.ReleaseElement, error) {
+	return []*helm.ReleaseElement{}, nil
+}
+
 // executeCommand is a helper function for testing Cobra commands
 func executeCommand(root *cobra.Command, args ...string) (output string, err error) {
 	buf := new(bytes.Buffer)
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/internal/kube"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// defaultClusterConfigName is the ConfigMap or Secret read by --config-from-cluster without a name
+	defaultClusterConfigName = "irr-config"
+	// clusterConfigKey is the data key holding the registry mappings in the cluster config object
+	clusterConfigKey = "registry-mappings.yaml"
+)
+
+// addClusterConfigFlag adds --config-from-cluster, which loads the registry mappings from a
+// ConfigMap or Secret instead of a local file.
+func addClusterConfigFlag(cmd *cobra.Command) {
+	cmd.Flags().String("config-from-cluster", "",
+		"Load registry mappings from a ConfigMap or Secret in the cluster, as [namespace/]name (default name irr-config, in the release namespace)")
+	cmd.Flags().Lookup("config-from-cluster").NoOptDefVal = defaultClusterConfigName
+}
+
+// parseClusterConfigRef splits a --config-from-cluster value of the form [namespace/]name, using
+// namespace when the value has none.
+func parseClusterConfigRef(value, namespace string) (configNamespace, name string, err error) {
+	configNamespace, name = namespace, value
+	if ns, n, found := strings.Cut(value, "/"); found {
+		configNamespace, name = ns, n
+	}
+	if configNamespace == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("invalid --config-from-cluster value %q: expected [namespace/]name", value),
+		}
+	}
+	return configNamespace, name, nil
+}
+
+// clusterConfigNamespace returns the namespace searched for a cluster config given without one:
+// the --namespace flag when set, then HELM_NAMESPACE, then the default namespace. This is the
+// namespace of the release in plugin mode.
+func clusterConfigNamespace(cmd *cobra.Command) string {
+	if namespaceFlag := cmd.Flag("namespace"); namespaceFlag != nil && namespaceFlag.Changed {
+		return namespaceFlag.Value.String()
+	}
+	if envNamespace := os.Getenv(envHelmNamespace); envNamespace != "" {
+		return envNamespace
+	}
+	return defaultNamespace
+}
+
+// loadClusterRegistryConfig reads the registry mappings from the ConfigMap or Secret referenced by
+// value and applies the profile selected with --profile. It returns the config together with a
+// description of the object it came from.
+func loadClusterRegistryConfig(cmd *cobra.Command, value string) (*registry.Config, string, error) {
+	namespace, name, err := parseClusterConfigRef(value, clusterConfigNamespace(cmd))
+	if err != nil {
+		return nil, "", err
+	}
+	if err := requireNetwork("loading registry mappings from the cluster (--config-from-cluster)"); err != nil {
+		return nil, "", err
+	}
+
+	client, err := kubeClientFactory()
+	if err != nil {
+		return nil, "", &exitcodes.ExitCodeError{Code: exitcodes.ExitGeneralRuntimeError, Err: err}
+	}
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	configData, err := client.GetConfigData(ctx, namespace, name)
+	if err != nil {
+		code := exitcodes.ExitGeneralRuntimeError
+		if errors.Is(err, kube.ErrConfigNotFound) {
+			code = exitcodes.ExitInputConfigurationError
+		}
+		return nil, "", &exitcodes.ExitCodeError{
+			Code: code,
+			Err:  fmt.Errorf("failed to load registry mappings from the cluster: %w", err),
+		}
+	}
+
+	source := fmt.Sprintf("%s %s/%s", strings.ToLower(configData.Kind), namespace, name)
+	data, key, err := clusterConfigContent(configData)
+	if err != nil {
+		return nil, "", &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: fmt.Errorf("%s: %w", source, err)}
+	}
+	source = fmt.Sprintf("%s key %s", source, key)
+
+	config, err := registry.ParseConfig(data, source)
+	if err != nil {
+		return nil, "", &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	if err := config.ApplyProfile(registryProfile); err != nil {
+		return nil, "", &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	log.Info("Loaded registry mappings from the cluster", "source", source, "profile", registryProfile)
+	return config, source, nil
+}
+
+// clusterConfigContent returns the registry mappings held by a cluster config object: the
+// registry-mappings.yaml key, or its only key when it has a single one.
+func clusterConfigContent(configData *kube.ConfigData) (data []byte, key string, err error) {
+	if value, ok := configData.Data[clusterConfigKey]; ok {
+		return value, clusterConfigKey, nil
+	}
+	if len(configData.Data) == 1 {
+		for name, value := range configData.Data {
+			return value, name, nil
+		}
+	}
+	keys := make([]string, 0, len(configData.Data))
+	for name := range configData.Data {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return nil, "", fmt.Errorf("no %s key found (keys: %s)", clusterConfigKey, strings.Join(keys, ", "))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/internal/kube"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const clusterMappingsContent = `registries:
+  mappings:
+    - source: quay.io
+      target: harbor.example.com/quay
+`
+
+func TestParseClusterConfigRef(t *testing.T) {
+	namespace, name, err := parseClusterConfigRef("irr-config", "web")
+	require.NoError(t, err)
+	assert.Equal(t, "web", namespace)
+	assert.Equal(t, "irr-config", name)
+
+	namespace, name, err = parseClusterConfigRef("platform/mappings", "web")
+	require.NoError(t, err)
+	assert.Equal(t, "platform", namespace)
+	assert.Equal(t, "mappings", name)
+
+	for _, value := range []string{"platform/", "/mappings", "a/b/c"} {
+		_, _, err = parseClusterConfigRef(value, "web")
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr, value)
+		assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	}
+}
+
+func TestClusterConfigContent(t *testing.T) {
+	data, key, err := clusterConfigContent(&kube.ConfigData{Data: map[string][]byte{
+		clusterConfigKey: []byte("a"),
+		"other.yaml":     []byte("b"),
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, clusterConfigKey, key)
+	assert.Equal(t, []byte("a"), data)
+
+	_, key, err = clusterConfigContent(&kube.ConfigData{Data: map[string][]byte{"mappings.yaml": []byte("a")}})
+	require.NoError(t, err)
+	assert.Equal(t, "mappings.yaml", key, "a single key is used whatever its name")
+
+	_, _, err = clusterConfigContent(&kube.ConfigData{Data: map[string][]byte{"b.yaml": nil, "a.yaml": nil}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "keys: a.yaml, b.yaml")
+}
+
+func TestLoadRegistryMappingsFromCluster(t *testing.T) {
+	t.Setenv(envHelmNamespace, "ci")
+	withKubeClient(t, &fakeKubeClient{configs: []*kube.ConfigData{
+		{Kind: kube.KindConfigMap, Namespace: "ci", Name: defaultClusterConfigName, Data: map[string][]byte{clusterConfigKey: []byte(clusterMappingsContent)}},
+		{Kind: kube.KindSecret, Namespace: "platform", Name: "mappings", Data: map[string][]byte{clusterConfigKey: []byte(clusterMappingsContent)}},
+	}})
+
+	load := func(args ...string) (*GeneratorConfig, error) {
+		cmd := newOverrideCmd()
+		require.NoError(t, cmd.ParseFlags(args))
+		config := &GeneratorConfig{}
+		return config, loadRegistryMappings(cmd, config)
+	}
+
+	config, err := load("--config-from-cluster")
+	require.NoError(t, err)
+	require.NotNil(t, config.Mappings)
+	require.Len(t, config.Mappings.Entries, 1)
+	assert.Equal(t, "harbor.example.com/quay", config.Mappings.Entries[0].Target)
+	assert.Equal(t, []string{"quay.io"}, config.SourceRegistries)
+
+	config, err = load("--config-from-cluster=platform/mappings")
+	require.NoError(t, err)
+	require.NotNil(t, config.Mappings)
+
+	_, err = load("--config-from-cluster", "--namespace", "web")
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	assert.Contains(t, err.Error(), "web/irr-config")
+
+	original := offlineMode
+	offlineMode = true
+	t.Cleanup(func() { offlineMode = original })
+	_, err = load("--config-from-cluster")
+	require.ErrorAs(t, err, &exitErr)
+	assert.Contains(t, err.Error(), "--offline")
+}
@@ -0,0 +1,178 @@
+// Package main implements the irr CLI commands.
+package main
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/internal/helm"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/strategy"
+	"github.com/spf13/cobra"
+)
+
+// completionHelmClientFactory creates the Helm client used to look up releases and namespaces
+// during shell completion. It can be replaced in tests.
+var completionHelmClientFactory = createHelmClient
+
+// registerCompletions wires dynamic flag and argument completion into root and all of its subcommands.
+// The completion command itself is provided by cobra (irr completion bash|zsh|fish|powershell).
+func registerCompletions(root *cobra.Command) {
+	flagCompletions := map[string]cobra.CompletionFunc{
+		"release-name":       completeReleaseNames,
+		"namespace":          completeNamespaces,
+		"source-registries":  completeSourceRegistries,
+		"exclude-registries": completeSourceRegistries,
+		"path-strategy":      cobra.FixedCompletions([]string{strategy.StrategyPrefixSourceRegistry, strategy.StrategyFlat}, cobra.ShellCompDirectiveNoFileComp),
+		"output-format":      cobra.FixedCompletions([]string{outputFormatYAML, outputFormatJSON}, cobra.ShellCompDirectiveNoFileComp),
+		"log-format":         cobra.FixedCompletions([]string{log.FormatJSON, log.FormatText}, cobra.ShellCompDirectiveNoFileComp),
+		"log-level":          cobra.FixedCompletions([]string{"debug", "info", "warn", "error"}, cobra.ShellCompDirectiveNoFileComp),
+		"analysis-mode":      cobra.FixedCompletions(analysisModes, cobra.ShellCompDirectiveNoFileComp),
+	}
+
+	commands := append([]*cobra.Command{root}, root.Commands()...)
+	for _, cmd := range commands {
+		for name, completionFunc := range flagCompletions {
+			// Only register flags the command defines itself; inherited flags use the parent's registration
+			if cmd.LocalFlags().Lookup(name) == nil {
+				continue
+			}
+			// Commands may register their own completion for a shared flag name
+			if _, exists := cmd.GetFlagCompletionFunc(name); exists {
+				continue
+			}
+			if err := cmd.RegisterFlagCompletionFunc(name, completionFunc); err != nil {
+				log.Debug("Failed to register flag completion", "command", cmd.Name(), "flag", name, "error", err)
+			}
+		}
+	}
+
+	// inspect and validate accept the release name as a positional argument in plugin mode
+	for _, cmd := range root.Commands() {
+		if cmd.Name() == "inspect" || cmd.Name() == "validate" {
+			cmd.ValidArgsFunction = completeReleaseNameArg
+		}
+	}
+}
+
+// completeReleaseNameArg completes the first positional argument with release names.
+func completeReleaseNameArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeReleaseNames(cmd, args, toComplete)
+}
+
+// completeReleaseNames completes Helm release names from the cluster.
+// Releases are only looked up in plugin mode, where Helm's kube context is available;
+// if --namespace is set, only releases in that namespace are offered.
+func completeReleaseNames(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if !isRunningAsHelmPlugin() {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	namespace := ""
+	if cmd.Flags().Changed("namespace") {
+		if value, err := cmd.Flags().GetString("namespace"); err == nil {
+			namespace = value
+		}
+	}
+
+	releases := listReleasesForCompletion()
+	names := make([]string, 0, len(releases))
+	for _, release := range releases {
+		if namespace != "" && release.Namespace != namespace {
+			continue
+		}
+		names = append(names, release.Name)
+	}
+	return filterCompletions(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeNamespaces completes namespaces that contain Helm releases.
+func completeNamespaces(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	releases := listReleasesForCompletion()
+	namespaces := make([]string, 0, len(releases))
+	for _, release := range releases {
+		namespaces = append(namespaces, release.Namespace)
+	}
+	return filterCompletions(namespaces, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSourceRegistries completes source registries from the registry mappings files
+// given by --registry-file, falling back to registry-mappings.yaml in the current directory.
+func completeSourceRegistries(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	mappingsFiles := []string{DefaultConfigSkeletonFilename}
+	if cmd.Flags().Lookup("registry-file") != nil {
+		if value, err := cmd.Flags().GetStringSlice("registry-file"); err == nil && len(value) > 0 {
+			mappingsFiles = value
+		}
+	}
+
+	for _, mappingsFile := range mappingsFiles {
+		if _, err := os.Stat(mappingsFile); err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+
+	skipCWDRestriction := integrationTestMode || (os.Getenv("IRR_TESTING") == trueString)
+	config, _, err := loadRegistryConfigs(mappingsFiles, skipCWDRestriction)
+	if err != nil || config == nil {
+		log.Debug("Failed to load registry mappings for completion", "files", mappingsFiles, "error", err)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	mappings := config.ToMappings()
+	sources := make([]string, 0, len(mappings.Entries))
+	for _, mapping := range mappings.Entries {
+		sources = append(sources, mapping.Source)
+	}
+
+	// Slice flags accept comma-separated values; complete the last element only
+	prefix := ""
+	if idx := strings.LastIndex(toComplete, ","); idx >= 0 {
+		prefix = toComplete[:idx+1]
+		toComplete = toComplete[idx+1:]
+	}
+	matches := filterCompletions(sources, toComplete)
+	for i := range matches {
+		matches[i] = prefix + matches[i]
+	}
+	return matches, cobra.ShellCompDirectiveNoFileComp
+}
+
+// listReleasesForCompletion lists releases across all namespaces, returning nil on any error
+// so completion never fails loudly.
+func listReleasesForCompletion() []*helm.ReleaseElement {
+	if isOffline() {
+		return nil
+	}
+	client, err := completionHelmClientFactory()
+	if err != nil || client == nil {
+		log.Debug("Helm client unavailable for completion", "error", err)
+		return nil
+	}
+	releases, err := client.ListReleases(context.Background(), true)
+	if err != nil {
+		log.Debug("Failed to list releases for completion", "error", err)
+		return nil
+	}
+	return releases
+}
+
+// filterCompletions returns the sorted, de-duplicated candidates that start with toComplete.
+func filterCompletions(candidates []string, toComplete string) []string {
+	seen := make(map[string]bool, len(candidates))
+	matches := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate == "" || seen[candidate] || !strings.HasPrefix(candidate, toComplete) {
+			continue
+		}
+		seen[candidate] = true
+		matches = append(matches, candidate)
+	}
+	sort.Strings(matches)
+	return matches
+}
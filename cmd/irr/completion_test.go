@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/internal/helm"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withCompletionReleases replaces the completion Helm client with a mock returning releases
+func withCompletionReleases(t *testing.T, releases []*helm.ReleaseElement) {
+	t.Helper()
+	original := completionHelmClientFactory
+	mockClient := helm.NewMockHelmClient()
+	mockClient.MockReleases = releases
+	completionHelmClientFactory = func() (helm.ClientInterface, error) {
+		return mockClient, nil
+	}
+	t.Cleanup(func() { completionHelmClientFactory = original })
+}
+
+func TestFilterCompletions(t *testing.T) {
+	candidates := []string{"quay.io", "docker.io", "", "docker.io", "ghcr.io"}
+	assert.Equal(t, []string{"docker.io", "ghcr.io", "quay.io"}, filterCompletions(candidates, ""))
+	assert.Equal(t, []string{"docker.io"}, filterCompletions(candidates, "do"))
+	assert.Empty(t, filterCompletions(candidates, "gcr"))
+}
+
+func TestCompleteReleaseNames(t *testing.T) {
+	withCompletionReleases(t, []*helm.ReleaseElement{
+		{Name: "nginx", Namespace: "web"},
+		{Name: "nginx-ingress", Namespace: "ingress"},
+		{Name: "redis", Namespace: "web"},
+	})
+
+	t.Run("standalone mode offers no releases", func(t *testing.T) {
+		t.Setenv("HELM_PLUGIN_NAME", "")
+		t.Setenv("HELM_PLUGIN_DIR", "")
+		names, directive := completeReleaseNames(newInspectCmd(), nil, "")
+		assert.Empty(t, names)
+		assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+	})
+
+	t.Run("plugin mode filters by prefix", func(t *testing.T) {
+		t.Setenv("HELM_PLUGIN_NAME", "irr")
+		names, _ := completeReleaseNames(newInspectCmd(), nil, "ng")
+		assert.Equal(t, []string{"nginx", "nginx-ingress"}, names)
+	})
+
+	t.Run("plugin mode filters by namespace flag", func(t *testing.T) {
+		t.Setenv("HELM_PLUGIN_NAME", "irr")
+		cmd := newInspectCmd()
+		require.NoError(t, cmd.Flags().Set("namespace", "web"))
+		names, _ := completeReleaseNames(cmd, nil, "")
+		assert.Equal(t, []string{"nginx", "redis"}, names)
+	})
+
+	t.Run("positional argument only completes first argument", func(t *testing.T) {
+		t.Setenv("HELM_PLUGIN_NAME", "irr")
+		names, _ := completeReleaseNameArg(newInspectCmd(), []string{"nginx"}, "")
+		assert.Empty(t, names)
+	})
+}
+
+func TestCompleteNamespaces(t *testing.T) {
+	withCompletionReleases(t, []*helm.ReleaseElement{
+		{Name: "nginx", Namespace: "web"},
+		{Name: "redis", Namespace: "web"},
+		{Name: "prometheus", Namespace: "monitoring"},
+	})
+
+	namespaces, directive := completeNamespaces(newInspectCmd(), nil, "")
+	assert.Equal(t, []string{"monitoring", "web"}, namespaces)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+}
+
+func TestCompleteSourceRegistries(t *testing.T) {
+	t.Setenv("IRR_TESTING", trueString)
+	mappingsFile := filepath.Join(t.TempDir(), "mappings.yaml")
+	content := `registries:
+  mappings:
+    - source: docker.io
+      target: registry.local/docker-io
+      enabled: true
+    - source: quay.io
+      target: registry.local/quay-io
+      enabled: true
+    - source: ghcr.io
+      target: registry.local/ghcr-io
+      enabled: false
+`
+	require.NoError(t, os.WriteFile(mappingsFile, []byte(content), 0o600))
+
+	cmd := newOverrideCmd()
+	require.NoError(t, cmd.Flags().Set("registry-file", mappingsFile))
+
+	t.Run("enabled mappings are offered", func(t *testing.T) {
+		registries, _ := completeSourceRegistries(cmd, nil, "")
+		assert.Equal(t, []string{"docker.io", "quay.io"}, registries)
+	})
+
+	t.Run("last comma-separated element is completed", func(t *testing.T) {
+		registries, _ := completeSourceRegistries(cmd, nil, "docker.io,q")
+		assert.Equal(t, []string{"docker.io,quay.io"}, registries)
+	})
+
+	t.Run("missing mappings file offers nothing", func(t *testing.T) {
+		missingCmd := newOverrideCmd()
+		require.NoError(t, missingCmd.Flags().Set("registry-file", filepath.Join(t.TempDir(), "missing.yaml")))
+		registries, _ := completeSourceRegistries(missingCmd, nil, "")
+		assert.Empty(t, registries)
+	})
+}
+
+func TestRegisterCompletions(t *testing.T) {
+	root := &cobra.Command{Use: "irr"}
+	addReleaseFlag(root)
+	root.AddCommand(newOverrideCmd(), newInspectCmd(), newValidateCmd())
+	registerCompletions(root)
+
+	for _, sub := range root.Commands() {
+		if sub.Name() == "inspect" || sub.Name() == "validate" {
+			assert.NotNil(t, sub.ValidArgsFunction, "%s should complete release names", sub.Name())
+		}
+	}
+
+	overrideCmd, _, err := root.Find([]string{"override"})
+	require.NoError(t, err)
+	completionFunc, found := overrideCmd.GetFlagCompletionFunc("path-strategy")
+	require.True(t, found, "path-strategy should have a completion function")
+	strategies, _ := completionFunc(overrideCmd, nil, "")
+	assert.Equal(t, []string{"prefix-source-registry", "flat"}, strategies)
+
+	completionFunc, found = overrideCmd.GetFlagCompletionFunc("output-format")
+	require.True(t, found, "output-format should have a completion function")
+	formats, _ := completionFunc(overrideCmd, nil, "")
+	assert.Equal(t, []string{"yaml", "json", "set-flags", "set-flags-shell"}, formats)
+
+	inspectCmd, _, err := root.Find([]string{"inspect"})
+	require.NoError(t, err)
+	completionFunc, found = inspectCmd.GetFlagCompletionFunc("output-format")
+	require.True(t, found, "output-format should have a completion function")
+	formats, _ = completionFunc(inspectCmd, nil, "")
+	assert.Equal(t, []string{"yaml", "json"}, formats)
+}
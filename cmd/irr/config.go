@@ -66,6 +66,12 @@ IMPORTANT NOTES:
 	configCmd.Flags().BoolVar(&configListOnly, "list", false, "List all configured mappings")
 	configCmd.Flags().BoolVar(&configRemoveOnly, "remove", false, "Remove the specified source mapping")
 
+	configCmd.AddCommand(newConfigGenerateHarborCmd())
+	configCmd.AddCommand(newConfigImportCmd())
+	configCmd.AddCommand(newConfigExportCmd())
+	configCmd.AddCommand(newConfigMigrateCmd())
+	configCmd.AddCommand(newConfigSimulateCmd())
+
 	// Add to root command
 	rootCmd.AddCommand(configCmd)
 }
@@ -157,7 +163,7 @@ func removeMapping() error {
 	}
 
 	if exists {
-		loadedConfig, err = registry.LoadStructuredConfig(AppFs, configFile, integrationTestMode)
+		loadedConfig, err = registry.LoadStructuredConfigKeepingEnv(AppFs, configFile, integrationTestMode)
 		if err != nil {
 			var notExistErr *registry.ErrMappingFileNotExist
 			if errors.As(err, &notExistErr) {
@@ -239,7 +245,7 @@ func addUpdateMapping() error {
 
 	if exists {
 		// Try loading structured format
-		loadedConfig, err = registry.LoadStructuredConfig(AppFs, configFile, integrationTestMode)
+		loadedConfig, err = registry.LoadStructuredConfigKeepingEnv(AppFs, configFile, integrationTestMode)
 		if err != nil {
 			// If loading fails, return the error
 			return &exitcodes.ExitCodeError{
@@ -329,9 +335,22 @@ func saveMappings(mappings *registry.Mappings, originalConfig *registry.Config)
 		log.Debug("saveMappings: Creating default structured config base")
 	}
 
+	// Sources keep their group as long as their target is unchanged; grouped sources are not
+	// written again as explicit mappings
+	config.Registries.Groups = keepGroupedSources(config.Registries.Groups, mappings.Entries)
+	grouped := make(map[string]bool)
+	for _, group := range config.Registries.Groups {
+		for _, source := range group.Sources {
+			grouped[source] = true
+		}
+	}
+
 	// Update the Mappings slice within the config struct
-	config.Registries.Mappings = make([]registry.RegMapping, len(mappings.Entries))
-	for i, entry := range mappings.Entries {
+	config.Registries.Mappings = make([]registry.RegMapping, 0, len(mappings.Entries))
+	for _, entry := range mappings.Entries {
+		if grouped[entry.Source] {
+			continue
+		}
 		// Preserve existing RegMapping fields if possible (like Description, Enabled)
 		// This requires finding the corresponding mapping in originalConfig if it exists.
 		var existingRegMapping *registry.RegMapping
@@ -344,18 +363,20 @@ func saveMappings(mappings *registry.Mappings, originalConfig *registry.Config)
 			}
 		}
 
-		config.Registries.Mappings[i] = registry.RegMapping{
-			Source: entry.Source,
-			Target: entry.Target,
+		regMapping := registry.RegMapping{
+			Source:       entry.Source,
+			Target:       entry.Target,
+			TagTransform: entry.TagTransform,
 			// Preserve Enabled/Description from original if found, otherwise default
 			Enabled:     true, // Default to true
 			Description: "",   // Default to empty
 		}
 		if existingRegMapping != nil {
-			config.Registries.Mappings[i].Enabled = existingRegMapping.Enabled
-			config.Registries.Mappings[i].Description = existingRegMapping.Description
+			regMapping.Enabled = existingRegMapping.Enabled
+			regMapping.Description = existingRegMapping.Description
 			log.Debug("Preserving existing details", "source", entry.Source, "enabled", existingRegMapping.Enabled, "desc", existingRegMapping.Description)
 		}
+		config.Registries.Mappings = append(config.Registries.Mappings, regMapping)
 	}
 
 	// Marshal the full Config structure to YAML
@@ -376,3 +397,31 @@ func saveMappings(mappings *registry.Mappings, originalConfig *registry.Config)
 	}
 	return nil
 }
+
+// keepGroupedSources returns groups reduced to the sources entries still route to the group's
+// target, dropping groups left without sources
+func keepGroupedSources(groups []registry.RegGroup, entries []registry.Mapping) []registry.RegGroup {
+	if len(groups) == 0 {
+		return nil
+	}
+	targets := make(map[string]registry.Mapping, len(entries))
+	for _, entry := range entries {
+		targets[entry.Source] = entry
+	}
+	kept := make([]registry.RegGroup, 0, len(groups))
+	for _, group := range groups {
+		sources := make([]string, 0, len(group.Sources))
+		for _, source := range group.Sources {
+			entry, ok := targets[source]
+			if ok && entry.Target == group.Target && entry.TagTransform == group.TagTransform {
+				sources = append(sources, source)
+			}
+		}
+		if len(sources) == 0 {
+			continue
+		}
+		group.Sources = sources
+		kept = append(kept, group)
+	}
+	return kept
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/cobra"
+)
+
+// newConfigGenerateHarborCmd creates the 'config generate-harbor' subcommand.
+func newConfigGenerateHarborCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate-harbor",
+		Short: "Generate Harbor proxy-cache projects for the mapped source registries",
+		Long: `Generate Harbor proxy-cache project definitions for each source registry.
+Source registries are read from the registry mappings file (for example one created
+with 'irr inspect --generate-config-skeleton') and from --source-registries.
+
+Each source registry gets one proxy-cache project pulling through to its upstream.
+Project names follow the prefix-source-registry strategy: a mapping target of
+HARBOR/PROJECT uses PROJECT, and an unmapped source uses the registry name
+(e.g. docker.io). Use --update-mappings to point the mappings at the generated
+projects so mapping targets and Harbor projects stay in sync.`,
+		Example: `  # Terraform for the goharbor/harbor provider
+  irr config generate-harbor --harbor-url https://harbor.example.com --format terraform
+
+  # Harbor API payloads, also retargeting the mappings file at the projects
+  irr config generate-harbor --harbor-url https://harbor.example.com --format api-json --update-mappings`,
+		Args: cobra.NoArgs,
+		RunE: runConfigGenerateHarbor,
+	}
+
+	cmd.Flags().String("harbor-url", "", "URL of the Harbor instance (required)")
+	cmd.Flags().String("format", registry.HarborFormatTerraform, "Output format: terraform or api-json")
+	cmd.Flags().StringVar(&configFile, "file", "registry-mappings.yaml", "Path to the registry mappings file")
+	cmd.Flags().StringSlice("source-registries", nil, "Additional source registries to create projects for")
+	cmd.Flags().String("output-file", "", "Write the definitions to a file instead of stdout")
+	cmd.Flags().Bool("update-mappings", false, "Retarget the mappings file at the generated Harbor projects")
+	return cmd
+}
+
+// runConfigGenerateHarbor implements 'config generate-harbor'.
+func runConfigGenerateHarbor(cmd *cobra.Command, _ []string) error {
+	harborURL, err := getStringFlag(cmd, "harbor-url")
+	if err != nil {
+		return err
+	}
+	if harborURL == "" {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitMissingRequiredFlag,
+			Err:  errors.New("required flag \"harbor-url\" not set"),
+		}
+	}
+	harborHost, err := harborHostFromURL(harborURL)
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+
+	format, err := getStringFlag(cmd, "format")
+	if err != nil {
+		return err
+	}
+	if format != registry.HarborFormatTerraform && format != registry.HarborFormatAPIJSON {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("unsupported format %q (expected %s or %s)", format, registry.HarborFormatTerraform, registry.HarborFormatAPIJSON),
+		}
+	}
+
+	extraSources, err := cmd.Flags().GetStringSlice("source-registries")
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get source-registries flag: %w", err),
+		}
+	}
+	updateMappings, err := getBoolFlag(cmd, "update-mappings")
+	if err != nil {
+		return err
+	}
+	outputFile, err := getStringFlag(cmd, "output-file")
+	if err != nil {
+		return err
+	}
+
+	config, err := registry.LoadStructuredConfig(AppFs, configFile, integrationTestMode)
+	if err != nil {
+		var notExistErr *registry.ErrMappingFileNotExist
+		if !errors.As(err, &notExistErr) || updateMappings {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to load mappings from '%s': %w", configFile, err),
+			}
+		}
+		config = nil
+	}
+
+	mappings := &registry.Mappings{}
+	sources := make([]string, 0, len(extraSources))
+	if config != nil {
+		mappings = config.ToMappings()
+		for _, mapping := range mappings.Entries {
+			sources = append(sources, mapping.Source)
+		}
+	}
+	sources = append(sources, extraSources...)
+	if len(sources) == 0 {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err: fmt.Errorf("no source registries found in '%s'; run 'irr inspect --generate-config-skeleton' "+
+				"or pass --source-registries", configFile),
+		}
+	}
+
+	if updateMappings {
+		// Mappings to other hosts are replaced by the generated projects
+		mappings = mappingsOnHost(mappings, harborHost)
+	}
+	projects, skipped := registry.PlanHarborProxyProjects(sources, mappings, harborHost)
+	for _, source := range skipped {
+		log.Warn("Source registry is mapped to a different host; no Harbor project generated",
+			"source", source, "target", mappings.GetTargetRegistry(source), "harbor", harborHost)
+	}
+	for _, project := range projects {
+		log.Info("Harbor proxy-cache project", "project", project.Project, "upstream", project.SourceRegistry, "mappingTarget", project.MappingTarget)
+	}
+
+	data, err := registry.RenderHarborProjects(format, harborURL, projects)
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitGeneralRuntimeError, Err: err}
+	}
+
+	if updateMappings {
+		if err := retargetMappings(config, projects); err != nil {
+			return err
+		}
+		log.Info("Updated mappings to target the Harbor projects", "file", configFile)
+	}
+
+	if outputFile == "" {
+		_, err := cmd.OutOrStdout().Write(data)
+		if err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to write Harbor definitions: %w", err),
+			}
+		}
+		return nil
+	}
+	return writeOutputFile(outputFile, data, fmt.Sprintf("Harbor %s definitions written to: %s", format, outputFile))
+}
+
+// harborHostFromURL returns the registry host of a Harbor URL, accepting a bare host as well.
+func harborHostFromURL(harborURL string) (string, error) {
+	raw := harborURL
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return "", fmt.Errorf("invalid --harbor-url %q", harborURL)
+	}
+	return parsed.Host, nil
+}
+
+// mappingsOnHost returns the mappings whose target is on host.
+func mappingsOnHost(mappings *registry.Mappings, host string) *registry.Mappings {
+	filtered := &registry.Mappings{}
+	for _, mapping := range mappings.Entries {
+		if targetHost, _, _ := strings.Cut(mapping.Target, "/"); targetHost == host {
+			filtered.Entries = append(filtered.Entries, mapping)
+		}
+	}
+	return filtered
+}
+
+// retargetMappings points the mapping of each project's source registry at the project and saves
+// the mappings file.
+func retargetMappings(config *registry.Config, projects []registry.HarborProxyProject) error {
+	mappings := config.ToMappings()
+	indexBySource := make(map[string]int, len(mappings.Entries))
+	for i, mapping := range mappings.Entries {
+		indexBySource[mapping.Source] = i
+	}
+	for _, project := range projects {
+		if i, ok := indexBySource[project.SourceRegistry]; ok {
+			mappings.Entries[i].Target = project.MappingTarget
+			continue
+		}
+		mappings.Entries = append(mappings.Entries, registry.Mapping{Source: project.SourceRegistry, Target: project.MappingTarget})
+	}
+	return saveMappings(mappings, config)
+}
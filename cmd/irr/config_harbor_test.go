@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const harborMappingsFile = "harbor-mappings.yaml"
+
+const harborMappingsContent = `version: "1.0"
+registries:
+  mappings:
+    - source: docker.io
+      target: registry.local/docker-io
+      enabled: true
+    - source: quay.io
+      target: harbor.example.com/quay
+      enabled: true
+`
+
+func TestConfigGenerateHarbor(t *testing.T) {
+	setup := func(t *testing.T) afero.Fs {
+		t.Helper()
+		memFs := afero.NewMemMapFs()
+		oldFs := AppFs
+		AppFs = memFs
+		t.Cleanup(func() { AppFs = oldFs })
+		require.NoError(t, afero.WriteFile(memFs, harborMappingsFile, []byte(harborMappingsContent), fileutil.ReadWriteUserPermission))
+		return memFs
+	}
+	run := func(args ...string) (string, error) {
+		cmd := newConfigGenerateHarborCmd()
+		out := &bytes.Buffer{}
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"--file", harborMappingsFile}, args...))
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	t.Run("terraform skips other hosts", func(t *testing.T) {
+		setup(t)
+		out, err := run("--harbor-url", "https://harbor.example.com")
+		require.NoError(t, err)
+		assert.Contains(t, out, `resource "harbor_project" "quay"`)
+		assert.NotContains(t, out, `"docker.io"`)
+	})
+
+	t.Run("update mappings", func(t *testing.T) {
+		memFs := setup(t)
+		out, err := run("--harbor-url", "harbor.example.com", "--format", "api-json", "--update-mappings")
+		require.NoError(t, err)
+		assert.Contains(t, out, `"project_name": "docker.io"`)
+
+		config, err := registry.LoadStructuredConfig(memFs, harborMappingsFile, true)
+		require.NoError(t, err)
+		assert.Equal(t, "harbor.example.com/docker.io", config.ToMappings().GetTargetRegistry("docker.io"))
+		assert.Equal(t, "harbor.example.com/quay", config.ToMappings().GetTargetRegistry("quay.io"))
+	})
+
+	t.Run("missing harbor url", func(t *testing.T) {
+		setup(t)
+		_, err := run()
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitMissingRequiredFlag, exitErr.Code)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		setup(t)
+		_, err := run("--harbor-url", "https://harbor.example.com", "--format", "yaml")
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	})
+}
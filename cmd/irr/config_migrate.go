@@ -0,0 +1,119 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// newConfigMigrateCmd creates the 'config migrate' subcommand.
+func newConfigMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade a registry mappings file to the current config version",
+		Long: `Upgrade the registry mappings file to config version ` + registry.DefaultConfigVersion + `, the version this irr writes.
+
+Files in the legacy formats, which listed the mappings at the top level or mapped each
+source registry directly to its target, and files of older config versions are read by
+irr with a warning; migrating them writes the upgraded file once:
+
+  # legacy-map                      # legacy-list
+  docker.io: harbor.local/docker    mappings:
+                                      - source: docker.io
+                                        target: harbor.local/docker
+
+The file is rewritten in place unless --output-file is given. Files already at the
+current version are left untouched, and files of a newer version than this irr reads
+are rejected.`,
+		Example: `  irr config migrate --file registry-mappings.yaml
+  irr config migrate --file old-mappings.yaml --output-file registry-mappings.yaml
+  irr config migrate --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: runConfigMigrate,
+	}
+
+	cmd.Flags().StringVar(&configFile, "file", "registry-mappings.yaml", "Path to the registry mappings file")
+	cmd.Flags().String("output-file", "", "Write the upgraded file here instead of replacing --file")
+	cmd.Flags().Bool("dry-run", false, "Print the upgraded file instead of writing it")
+	return cmd
+}
+
+// runConfigMigrate implements 'config migrate'.
+func runConfigMigrate(cmd *cobra.Command, _ []string) error {
+	outputFile, err := getStringFlag(cmd, "output-file")
+	if err != nil {
+		return err
+	}
+	dryRun, err := getBoolFlag(cmd, "dry-run")
+	if err != nil {
+		return err
+	}
+
+	data, err := afero.ReadFile(AppFs, configFile)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to read config file '%s': %w", configFile, err),
+		}
+	}
+	migrated, from, err := registry.MigrateConfigData(data, configFile)
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	if migrated == nil {
+		log.Info("Config file is already at the current version, nothing to migrate", "file", configFile, "version", from)
+		if dryRun {
+			return writeMigratedConfig(cmd, data)
+		}
+		return nil
+	}
+
+	// The upgraded file must load as well as the original did
+	if _, err := registry.ParseConfigKeepingEnv(migrated, configFile); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("migrated config file is invalid: %w", err),
+		}
+	}
+
+	if dryRun {
+		return writeMigratedConfig(cmd, migrated)
+	}
+	if outputFile != "" {
+		if err := writeOutputFile(outputFile, migrated, "Migrated config written"); err != nil {
+			return err
+		}
+	} else if err := replaceConfigFile(configFile, migrated); err != nil {
+		return err
+	}
+	log.Info("Migrated config file", "file", cmp.Or(outputFile, configFile), "from", from, "to", registry.DefaultConfigVersion)
+	return nil
+}
+
+// writeMigratedConfig prints a config file for --dry-run
+func writeMigratedConfig(cmd *cobra.Command, data []byte) error {
+	if _, err := cmd.OutOrStdout().Write(data); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write migrated config: %w", err),
+		}
+	}
+	return nil
+}
+
+// replaceConfigFile overwrites the config file at path with data
+func replaceConfigFile(path string, data []byte) error {
+	if err := afero.WriteFile(AppFs, path, data, fileutil.ReadWriteUserPermission); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write config file '%s': %w", path, err),
+		}
+	}
+	return nil
+}
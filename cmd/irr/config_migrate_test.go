@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const legacyMappings = `mappings:
+  - source: docker.io
+    target: harbor.local/docker
+  - source: quay.io
+    target: harbor.local/quay
+`
+
+func TestConfigMigrate(t *testing.T) {
+	setup := func(t *testing.T, content string) afero.Fs {
+		t.Helper()
+		memFs := afero.NewMemMapFs()
+		oldFs := AppFs
+		AppFs = memFs
+		t.Cleanup(func() { AppFs = oldFs })
+		require.NoError(t, afero.WriteFile(memFs, "registry-mappings.yaml", []byte(content), fileutil.ReadWriteUserPermission))
+		return memFs
+	}
+	migrate := func(args ...string) (string, error) {
+		cmd := newConfigMigrateCmd()
+		out := &bytes.Buffer{}
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"--file", "registry-mappings.yaml"}, args...))
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	t.Run("rewrites the file in place", func(t *testing.T) {
+		memFs := setup(t, legacyMappings)
+		_, err := migrate()
+		require.NoError(t, err)
+
+		data, err := afero.ReadFile(memFs, "registry-mappings.yaml")
+		require.NoError(t, err)
+		version, err := registry.DetectConfigVersion(data, "registry-mappings.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, registry.DefaultConfigVersion, version)
+		assert.Contains(t, string(data), "version: \"1.0\"")
+
+		config, err := registry.LoadStructuredConfig(memFs, "registry-mappings.yaml", true)
+		require.NoError(t, err)
+		assert.Equal(t, []registry.Mapping{
+			{Source: "docker.io", Target: "harbor.local/docker"},
+			{Source: "quay.io", Target: "harbor.local/quay"},
+		}, config.ToMappings().Entries)
+	})
+
+	t.Run("dry run and output file leave the file unchanged", func(t *testing.T) {
+		memFs := setup(t, legacyMappings)
+		out, err := migrate("--dry-run")
+		require.NoError(t, err)
+		assert.Contains(t, out, "registries:\n  mappings:\n")
+
+		_, err = migrate("--output-file", "migrated.yaml")
+		require.NoError(t, err)
+		migrated, err := afero.ReadFile(memFs, "migrated.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, out, string(migrated))
+
+		original, err := afero.ReadFile(memFs, "registry-mappings.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, legacyMappings, string(original))
+	})
+
+	t.Run("current version is left untouched", func(t *testing.T) {
+		current := "version: \"1.0\"\n# comment kept\nregistries:\n  mappings:\n    - source: docker.io\n      target: harbor.local/docker\n"
+		memFs := setup(t, current)
+		_, err := migrate()
+		require.NoError(t, err)
+		data, err := afero.ReadFile(memFs, "registry-mappings.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, current, string(data))
+	})
+
+	t.Run("newer version is rejected", func(t *testing.T) {
+		setup(t, "version: \"2.0\"\nregistries: {}\n")
+		_, err := migrate()
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+		assert.Contains(t, err.Error(), `unsupported version "2.0"`)
+	})
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// newConfigImportCmd creates the 'config import' subcommand.
+func newConfigImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import registry mappings from a skopeo sync, Zarf or Hauler configuration",
+		Long: `Import the source registries of an existing mirroring configuration into the registry
+mappings file, mapping each of them to --target.
+
+Supported formats:
+  skopeo-sync  the YAML source file of 'skopeo sync --src yaml'
+  zarf         a zarf.yaml package definition (images of all components)
+  hauler       a Hauler manifest (images of all Images resources)
+
+Mirroring configurations do not record where images are copied to, so the target is
+given with --target, e.g. the destination of 'skopeo sync'. Use --scoped when the
+destination keeps the source registry in the path, as 'skopeo sync --scoped' does.
+Existing mappings of other registries are kept; mappings of imported registries are
+retargeted.`,
+		Example: `  # Registries mirrored by skopeo sync to harbor.example.com/mirror
+  irr config import --format skopeo-sync --target harbor.example.com/mirror skopeo-sync.yaml
+
+  # Registries of a Zarf package, each below its own path on the target
+  irr config import --format zarf --target registry.local --scoped zarf.yaml`,
+		Args: cobra.ExactArgs(1),
+		RunE: runConfigImport,
+	}
+
+	cmd.Flags().String("format", "", "Format of the file: "+strings.Join(registry.MirrorImportFormats, ", ")+" (required)")
+	cmd.Flags().String("target", "", "Target registry the imported source registries are mapped to (required)")
+	cmd.Flags().Bool("scoped", false, "Map each source registry to TARGET/SOURCE instead of TARGET")
+	cmd.Flags().StringVar(&configFile, "file", "registry-mappings.yaml", "Path to the registry mappings file")
+	return cmd
+}
+
+// newConfigExportCmd creates the 'config export' subcommand.
+func newConfigExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export registry mappings as a skopeo sync configuration",
+		Long: `Export the registry mappings as the configuration of a mirroring tool, so the registries
+irr relocates images to can be populated with the same images.
+
+Supported formats:
+  skopeo-sync  the YAML source file of 'skopeo sync --src yaml'
+
+skopeo sync copies to a single destination, so the file covers the source registries
+mapped to one target; select it with --target if the mappings have more than one.
+irr mappings only know registries: add the repositories to mirror to each registry's
+images list before running the skopeo command shown in the file header, or list the
+images found by 'irr inspect' with --images. Images inspected with --check-signatures
+are listed with their cosign signatures and attestations, so that they are copied too
+and the relocated images stay verifiable.`,
+		Example: `  irr config export --format skopeo-sync --output-file skopeo-sync.yaml
+  irr config export --format skopeo-sync --target harbor.example.com/mirror
+
+  # Copy the images of a chart with their signatures
+  irr inspect --chart-path ./my-chart --check-signatures --output-file images.yaml
+  irr config export --format skopeo-sync --images images.yaml --output-file skopeo-sync.yaml`,
+		Args: cobra.NoArgs,
+		RunE: runConfigExport,
+	}
+
+	cmd.Flags().String("format", registry.MirrorFormatSkopeoSync, "Output format: "+strings.Join(registry.MirrorExportFormats, ", "))
+	cmd.Flags().String("target", "", "Export the source registries mapped to this target (required if the mappings have more than one)")
+	cmd.Flags().StringVar(&configFile, "file", "registry-mappings.yaml", "Path to the registry mappings file")
+	cmd.Flags().String("output-file", "", "Write the configuration to a file instead of stdout")
+	cmd.Flags().String("images", "", "Output of 'irr inspect' (YAML or JSON) whose images are listed, with the signatures and attestations found by --check-signatures")
+	return cmd
+}
+
+// runConfigImport implements 'config import'.
+func runConfigImport(cmd *cobra.Command, args []string) error {
+	format, err := getMirrorFormat(cmd, registry.MirrorImportFormats)
+	if err != nil {
+		return err
+	}
+	target, err := getStringFlag(cmd, "target")
+	if err != nil {
+		return err
+	}
+	if target == "" {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitMissingRequiredFlag,
+			Err:  errors.New("required flag \"target\" not set"),
+		}
+	}
+	scoped, err := getBoolFlag(cmd, "scoped")
+	if err != nil {
+		return err
+	}
+
+	data, err := afero.ReadFile(AppFs, args[0])
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to read %s file '%s': %w", format, args[0], err),
+		}
+	}
+	sources, err := registry.ParseMirrorConfig(format, data)
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	if len(sources) == 0 {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("no source registries found in '%s'", args[0]),
+		}
+	}
+
+	config, err := registry.LoadStructuredConfig(AppFs, configFile, integrationTestMode)
+	if err != nil {
+		var notExistErr *registry.ErrMappingFileNotExist
+		if !errors.As(err, &notExistErr) {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to load mappings from '%s': %w", configFile, err),
+			}
+		}
+		config = nil
+	}
+	mappings := &registry.Mappings{}
+	if config != nil {
+		mappings = config.ToMappings()
+	}
+
+	for i, imported := range registry.ImportMirrorMappings(sources, target, scoped) {
+		log.Info("Importing registry mapping", "source", imported.Source, "target", imported.Target, "repositories", len(sources[i].Repositories))
+		j := slices.IndexFunc(mappings.Entries, func(mapping registry.Mapping) bool { return mapping.Source == imported.Source })
+		if j < 0 {
+			mappings.Entries = append(mappings.Entries, imported)
+			continue
+		}
+		if mappings.Entries[j].Target != imported.Target {
+			log.Warn("Replacing existing registry mapping", "source", imported.Source, "previousTarget", mappings.Entries[j].Target, "target", imported.Target)
+			mappings.Entries[j].Target = imported.Target
+		}
+	}
+	if err := saveMappings(mappings, config); err != nil {
+		return err
+	}
+	log.Info("Imported registry mappings", "format", format, "count", len(sources), "file", configFile)
+	return nil
+}
+
+// runConfigExport implements 'config export'.
+func runConfigExport(cmd *cobra.Command, _ []string) error {
+	format, err := getMirrorFormat(cmd, registry.MirrorExportFormats)
+	if err != nil {
+		return err
+	}
+	target, err := getStringFlag(cmd, "target")
+	if err != nil {
+		return err
+	}
+	outputFile, err := getStringFlag(cmd, "output-file")
+	if err != nil {
+		return err
+	}
+	imagesFile, err := getStringFlag(cmd, "images")
+	if err != nil {
+		return err
+	}
+	var images []registry.MirrorImage
+	if imagesFile != "" {
+		if images, err = loadInspectedImages(imagesFile); err != nil {
+			return err
+		}
+	}
+
+	config, err := registry.LoadStructuredConfig(AppFs, configFile, integrationTestMode)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to load mappings from '%s': %w", configFile, err),
+		}
+	}
+	data, err := registry.RenderMirrorConfig(format, config.ToMappings(), target, images)
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+
+	if outputFile == "" {
+		if _, err := cmd.OutOrStdout().Write(data); err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to write %s configuration: %w", format, err),
+			}
+		}
+		return nil
+	}
+	return writeOutputFile(outputFile, data, fmt.Sprintf("%s configuration written to: %s", format, outputFile))
+}
+
+// loadInspectedImages reads the images of an 'irr inspect' output file, for one chart or release,
+// --recursive or --all-namespaces, with the tag or digest of each image followed by its cosign
+// signatures and attestations.
+func loadInspectedImages(path string) ([]registry.MirrorImage, error) {
+	data, err := afero.ReadFile(AppFs, path)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitIOError, Err: fmt.Errorf("failed to read images file '%s': %w", path, err)}
+	}
+	// JSON is YAML, so one decoder reads both output formats
+	var report struct {
+		Images   []ImageInfo `yaml:"images"`
+		Releases []struct {
+			Analysis ImageAnalysis `yaml:"analysis"`
+		} `yaml:"releases"`
+		Charts []struct {
+			Analysis *ImageAnalysis `yaml:"analysis"`
+		} `yaml:"charts"`
+	}
+	if err := yaml.Unmarshal(data, &report); err != nil {
+		return nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: fmt.Errorf("failed to parse images file '%s': %w", path, err)}
+	}
+	inspected := report.Images
+	for _, release := range report.Releases {
+		inspected = append(inspected, release.Analysis.Images...)
+	}
+	for _, chart := range report.Charts {
+		if chart.Analysis != nil {
+			inspected = append(inspected, chart.Analysis.Images...)
+		}
+	}
+
+	var images []registry.MirrorImage
+	signed := 0
+	for _, img := range inspected {
+		reference := img.Tag
+		if reference == "" {
+			reference = img.Digest
+		}
+		if reference == "" {
+			continue
+		}
+		mirrorImage := registry.MirrorImage{Registry: img.Registry, Repository: img.Repository, References: []string{reference}}
+		if img.Signatures != nil && img.Signatures.Signed() {
+			mirrorImage.References = append(mirrorImage.References, img.Signatures.Artifacts()...)
+			signed++
+		}
+		images = append(images, mirrorImage)
+	}
+	if len(images) == 0 {
+		return nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: fmt.Errorf("no images found in '%s'; pass the output of 'irr inspect'", path)}
+	}
+	log.Info("Listing inspected images", "file", path, "images", len(images), "signed", signed)
+	return images, nil
+}
+
+// getMirrorFormat reads the --format flag and checks it is one of formats
+func getMirrorFormat(cmd *cobra.Command, formats []string) (string, error) {
+	format, err := getStringFlag(cmd, "format")
+	if err != nil {
+		return "", err
+	}
+	if format == "" {
+		return "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitMissingRequiredFlag,
+			Err:  errors.New("required flag \"format\" not set"),
+		}
+	}
+	if !slices.Contains(formats, format) {
+		return "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("unsupported format %q (expected one of %s)", format, strings.Join(formats, ", ")),
+		}
+	}
+	return format, nil
+}
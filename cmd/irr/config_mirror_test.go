@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mirrorMappingsFile = "mirror-mappings.yaml"
+
+func TestConfigImportExport(t *testing.T) {
+	setup := func(t *testing.T) afero.Fs {
+		t.Helper()
+		memFs := afero.NewMemMapFs()
+		oldFs := AppFs
+		AppFs = memFs
+		t.Cleanup(func() { AppFs = oldFs })
+		require.NoError(t, afero.WriteFile(memFs, "zarf.yaml", []byte(`kind: ZarfPackageConfig
+components:
+  - name: app
+    images:
+      - nginx:1.25
+      - quay.io/prometheus/node-exporter:v1.7.0
+`), fileutil.ReadWriteUserPermission))
+		return memFs
+	}
+	var exitErr *exitcodes.ExitCodeError
+
+	t.Run("import creates mappings file", func(t *testing.T) {
+		memFs := setup(t)
+		cmd := newConfigImportCmd()
+		cmd.SetArgs([]string{"--file", mirrorMappingsFile, "--format", "zarf", "--target", "registry.local", "--scoped", "zarf.yaml"})
+		require.NoError(t, cmd.Execute())
+
+		config, err := registry.LoadStructuredConfig(memFs, mirrorMappingsFile, true)
+		require.NoError(t, err)
+		assert.Equal(t, []registry.Mapping{
+			{Source: "docker.io", Target: "registry.local/docker.io"},
+			{Source: "quay.io", Target: "registry.local/quay.io"},
+		}, config.ToMappings().Entries)
+	})
+
+	t.Run("import keeps other mappings", func(t *testing.T) {
+		memFs := setup(t)
+		require.NoError(t, afero.WriteFile(memFs, mirrorMappingsFile, []byte(`registries:
+  mappings:
+    - source: ghcr.io
+      target: harbor.local/github
+      enabled: true
+    - source: quay.io
+      target: harbor.local/quay
+      enabled: true
+`), fileutil.ReadWriteUserPermission))
+		cmd := newConfigImportCmd()
+		cmd.SetArgs([]string{"--file", mirrorMappingsFile, "--format", "zarf", "--target", "harbor.local/mirror", "zarf.yaml"})
+		require.NoError(t, cmd.Execute())
+
+		config, err := registry.LoadStructuredConfig(memFs, mirrorMappingsFile, true)
+		require.NoError(t, err)
+		assert.Equal(t, []registry.Mapping{
+			{Source: "ghcr.io", Target: "harbor.local/github"},
+			{Source: "quay.io", Target: "harbor.local/mirror"},
+			{Source: "docker.io", Target: "harbor.local/mirror"},
+		}, config.ToMappings().Entries)
+
+		export := newConfigExportCmd()
+		out := &bytes.Buffer{}
+		export.SetOut(out)
+		export.SetArgs([]string{"--file", mirrorMappingsFile, "--target", "harbor.local/mirror"})
+		require.NoError(t, export.Execute())
+		assert.Contains(t, out.String(), "quay.io:\n  images: {}\ndocker.io:\n  images: {}\n")
+
+		export = newConfigExportCmd()
+		export.SetArgs([]string{"--file", mirrorMappingsFile})
+		require.ErrorAs(t, export.Execute(), &exitErr)
+		assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code, "more than one target needs --target")
+	})
+
+	t.Run("import requires format and target", func(t *testing.T) {
+		setup(t)
+		cmd := newConfigImportCmd()
+		cmd.SetArgs([]string{"--file", mirrorMappingsFile, "--target", "registry.local", "zarf.yaml"})
+		require.ErrorAs(t, cmd.Execute(), &exitErr)
+		assert.Equal(t, exitcodes.ExitMissingRequiredFlag, exitErr.Code)
+
+		cmd = newConfigImportCmd()
+		cmd.SetArgs([]string{"--file", mirrorMappingsFile, "--format", "crane", "--target", "registry.local", "zarf.yaml"})
+		require.ErrorAs(t, cmd.Execute(), &exitErr)
+		assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+
+		cmd = newConfigImportCmd()
+		cmd.SetArgs([]string{"--file", mirrorMappingsFile, "--format", "zarf", "zarf.yaml"})
+		require.ErrorAs(t, cmd.Execute(), &exitErr)
+		assert.Equal(t, exitcodes.ExitMissingRequiredFlag, exitErr.Code)
+	})
+}
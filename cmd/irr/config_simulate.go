@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lucas-albers-lz4/irr/pkg/chart"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/strategy"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// SimulationReport is the output of 'config simulate'
+type SimulationReport struct {
+	Images  []chart.SimulatedImage `json:"images"`
+	Summary SimulationSummary      `json:"summary"`
+}
+
+// SimulationSummary counts the simulated images of each status
+type SimulationSummary struct {
+	Images    int `json:"images"`
+	Relocated int `json:"relocated"`
+	Excluded  int `json:"excluded"`
+	Skipped   int `json:"skipped"`
+	Failed    int `json:"failed"`
+}
+
+// newConfigSimulateCmd creates the 'config simulate' subcommand.
+func newConfigSimulateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "simulate [IMAGE...]",
+		Short: "Show what images would be rewritten to under the registry mappings, without a chart",
+		Long: `Runs image references through the same relocation steps as 'irr override' and prints what each
+would be rewritten to: relocation exceptions, source and exclude registries, mappings, the path
+strategy, the target flavor, the default tag and tag transforms. No chart is needed, so mapping
+changes can be tried quickly and their effect shown to reviewers.
+
+Images are given as arguments, or read one per line from --images-file ('-' for stdin), or from
+stdin when neither is given. Blank lines and lines starting with # are ignored.
+
+Each image is reported as:
+  relocated  the overrides would point it to the shown reference
+  excluded   a relocation exception in the mappings file covers it
+  skipped    its registry is not a source registry, or is excluded
+  error      the reference cannot be parsed or relocated`,
+		Example: `  irr config simulate nginx:1.25 quay.io/prometheus/node-exporter:v1.8.0
+  irr config simulate --file registry-mappings.yaml --images-file images.txt
+  kubectl get pods -A -o jsonpath='{..image}' | tr ' ' '\n' | sort -u | irr config simulate`,
+		RunE: runConfigSimulate,
+	}
+
+	cmd.Flags().StringVar(&configFile, "file", "registry-mappings.yaml", "Path to the registry mappings file")
+	cmd.Flags().String("images-file", "", "Read image references from this file, one per line ('-' for stdin)")
+	cmd.Flags().StringP("target-registry", "t", "", "Target registry for source registries without a mapping")
+	cmd.Flags().StringSliceP("source-registries", "s", nil, "Source registries to relocate (default: the sources of the mappings)")
+	cmd.Flags().StringSliceP("exclude-registries", "e", nil, "Registries to leave unchanged")
+	cmd.Flags().String("path-strategy", strategy.StrategyPrefixSourceRegistry, "Path strategy for relocated images (prefix-source-registry or flat)")
+	cmd.Flags().String("target-flavor", string(strategy.FlavorGeneric), "Target registry provider whose repository naming rules generated paths must follow (generic, ecr, gcr, acr or harbor)")
+	cmd.Flags().Bool("registry-only", false, "Rewrite only image registries and keep the original repository paths")
+	cmd.Flags().String("default-tag", "", "Tag to use for images that have neither a tag nor a digest")
+	cmd.Flags().String("output-format", outputFormatTable, "Output format (table or json)")
+
+	if err := cmd.RegisterFlagCompletionFunc("output-format",
+		cobra.FixedCompletions([]string{outputFormatTable, outputFormatJSON}, cobra.ShellCompDirectiveNoFileComp)); err != nil {
+		log.Debug("Failed to register output-format completion", "error", err)
+	}
+	return cmd
+}
+
+// runConfigSimulate implements 'config simulate'.
+func runConfigSimulate(cmd *cobra.Command, args []string) error {
+	outputFormat, err := getStringFlag(cmd, "output-format")
+	if err != nil {
+		return err
+	}
+	outputFormat = strings.ToLower(outputFormat)
+	if outputFormat != outputFormatTable && outputFormat != outputFormatJSON {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("unsupported output format %q; supported formats: %s, %s", outputFormat, outputFormatTable, outputFormatJSON),
+		}
+	}
+
+	images, err := simulationImages(cmd, args)
+	if err != nil {
+		return err
+	}
+	config, err := simulationGeneratorConfig(cmd)
+	if err != nil {
+		return err
+	}
+	generator, err := createGenerator(config, nil, nil)
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitGeneralRuntimeError, Err: err}
+	}
+	generator.SetExceptions(config.Exceptions)
+
+	report := simulateImages(generator, images)
+	log.Info("Simulated registry mappings",
+		"images", report.Summary.Images,
+		"relocated", report.Summary.Relocated,
+		"skipped", report.Summary.Skipped)
+
+	output, err := renderSimulationReport(report, outputFormat)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprint(cmd.OutOrStdout(), string(output)); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write simulation to stdout: %w", err),
+		}
+	}
+	return nil
+}
+
+// simulationImages returns the images to simulate: the arguments, or the lines of --images-file,
+// or of stdin
+func simulationImages(cmd *cobra.Command, args []string) ([]string, error) {
+	imagesFile, err := getStringFlag(cmd, "images-file")
+	if err != nil {
+		return nil, err
+	}
+	images := append([]string{}, args...)
+	if imagesFile == "" && len(args) > 0 {
+		return images, nil
+	}
+
+	var reader io.Reader
+	source := imagesFile
+	switch imagesFile {
+	case "", "-":
+		reader, source = cmd.InOrStdin(), "stdin"
+	default:
+		file, err := AppFs.Open(imagesFile)
+		if err != nil {
+			return nil, &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to open images file '%s': %w", imagesFile, err),
+			}
+		}
+		defer func() {
+			_ = file.Close() //nolint:errcheck // read-only file
+		}()
+		reader = file
+	}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		images = append(images, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to read images from %s: %w", source, err),
+		}
+	}
+	if len(images) == 0 {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("no images to simulate in %s", source),
+		}
+	}
+	return images, nil
+}
+
+// simulationGeneratorConfig builds the generator config of 'config simulate' from its flags and
+// the mappings file. A missing mappings file is only an error when it was given explicitly or
+// there is no --target-registry to fall back to.
+func simulationGeneratorConfig(cmd *cobra.Command) (*GeneratorConfig, error) {
+	config := &GeneratorConfig{RulesEnabled: true}
+	var err error
+	if config.TargetRegistry, err = getStringFlag(cmd, "target-registry"); err != nil {
+		return nil, err
+	}
+	if config.SourceRegistries, err = getStringSliceFlag(cmd, "source-registries"); err != nil {
+		return nil, err
+	}
+	if config.ExcludeRegistries, err = getStringSliceFlag(cmd, "exclude-registries"); err != nil {
+		return nil, err
+	}
+	if config.StrategyName, err = getStringFlag(cmd, "path-strategy"); err != nil {
+		return nil, err
+	}
+	targetFlavor, err := getStringFlag(cmd, "target-flavor")
+	if err != nil {
+		return nil, err
+	}
+	if config.TargetFlavor, err = strategy.ParseTargetFlavor(targetFlavor); err != nil {
+		return nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	if config.RegistryOnly, err = getBoolFlag(cmd, "registry-only"); err != nil {
+		return nil, err
+	}
+	defaultTag, err := getStringFlag(cmd, "default-tag")
+	if err != nil {
+		return nil, err
+	}
+	config.DefaultTag = strings.TrimSpace(defaultTag)
+
+	exists, err := afero.Exists(AppFs, configFile)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to check registry mappings file '%s': %w", configFile, err),
+		}
+	}
+	if exists || cmd.Flags().Changed("file") || config.TargetRegistry == "" {
+		skipCWDRestriction := integrationTestMode || (os.Getenv("IRR_TESTING") == trueString)
+		mappingsConfig, files, err := loadRegistryConfigs([]string{configFile}, skipCWDRestriction)
+		if err != nil {
+			return nil, &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  fmt.Errorf("failed to load registry mappings from file %s: %w", configFile, err),
+			}
+		}
+		applyRegistryConfig(config, mappingsConfig, strings.Join(files, ", "))
+	}
+	if len(config.SourceRegistries) == 0 {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("no source registries: add mappings to %s or use --source-registries", configFile),
+		}
+	}
+
+	if config.Strategy, err = setupPathStrategy(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// simulateImages runs each image through the generator
+func simulateImages(generator *chart.Generator, images []string) *SimulationReport {
+	report := &SimulationReport{Images: make([]chart.SimulatedImage, 0, len(images))}
+	for _, img := range images {
+		result := generator.Simulate(img)
+		report.Images = append(report.Images, result)
+		switch result.Status {
+		case chart.SimulationRelocated:
+			report.Summary.Relocated++
+		case chart.SimulationExcluded:
+			report.Summary.Excluded++
+		case chart.SimulationSkipped:
+			report.Summary.Skipped++
+		default:
+			report.Summary.Failed++
+		}
+	}
+	report.Summary.Images = len(images)
+	return report
+}
+
+// renderSimulationReport formats the report as a table with a summary line, or as JSON
+func renderSimulationReport(report *SimulationReport, outputFormat string) ([]byte, error) {
+	if outputFormat == outputFormatJSON {
+		output, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return nil, &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitGeneralRuntimeError,
+				Err:  fmt.Errorf("failed to marshal simulation to JSON: %w", err),
+			}
+		}
+		return append(output, '\n'), nil
+	}
+
+	var buf strings.Builder
+	writer := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(writer, "STATUS\tIMAGE\tREWRITTEN TO"); err != nil {
+		return nil, fmt.Errorf("failed to render simulation table: %w", err)
+	}
+	for _, img := range report.Images {
+		result := img.Relocated
+		if result == "" {
+			result = "- (" + img.Reason + ")"
+		}
+		if _, err := fmt.Fprintf(writer, "%s\t%s\t%s\n", img.Status, img.Image, result); err != nil {
+			return nil, fmt.Errorf("failed to render simulation table: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to render simulation table: %w", err)
+	}
+
+	summary := report.Summary
+	fmt.Fprintf(&buf, "\n%d images: %d relocated, %d excluded, %d skipped, %d failed\n",
+		summary.Images, summary.Relocated, summary.Excluded, summary.Skipped, summary.Failed)
+	return []byte(buf.String()), nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/chart"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigSimulate(t *testing.T) {
+	t.Setenv("IRR_TESTING", trueString)
+	mappingsFile := filepath.Join(t.TempDir(), "mappings.yaml")
+	content := `registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/dockerhub
+      enabled: true
+    - source: quay.io
+      target: harbor.local/quay
+      enabled: true
+exceptions:
+  - image: quay.io/vendor/**
+    reason: signed by vendor
+`
+	require.NoError(t, os.WriteFile(mappingsFile, []byte(content), 0o600))
+
+	simulate := func(t *testing.T, stdin string, args ...string) (string, error) {
+		t.Helper()
+		cmd := newConfigSimulateCmd()
+		out := &bytes.Buffer{}
+		cmd.SetOut(out)
+		cmd.SetIn(strings.NewReader(stdin))
+		cmd.SetArgs(append([]string{"--file", mappingsFile}, args...))
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	t.Run("images as arguments", func(t *testing.T) {
+		out, err := simulate(t, "", "nginx:1.25", "quay.io/vendor/agent:2.0", "gcr.io/distroless/static:nonroot")
+		require.NoError(t, err)
+		assert.Regexp(t, `relocated\s+nginx:1.25\s+harbor.local/dockerhub/library/nginx:1.25`, out)
+		assert.Regexp(t, `excluded\s+quay.io/vendor/agent:2.0\s+- \(exception image=quay.io/vendor/\*\*: signed by vendor\)`, out)
+		assert.Regexp(t, `skipped\s+gcr.io/distroless/static:nonroot\s+- \(registry gcr.io is not a source registry\)`, out)
+		assert.Contains(t, out, "3 images: 1 relocated, 1 excluded, 1 skipped, 0 failed")
+	})
+
+	t.Run("images from stdin as json", func(t *testing.T) {
+		out, err := simulate(t, "# running images\nquay.io/prometheus/node-exporter:v1.8.0\n\n", "--output-format", "json")
+		require.NoError(t, err)
+		var report SimulationReport
+		require.NoError(t, json.Unmarshal([]byte(out), &report))
+		assert.Equal(t, []chart.SimulatedImage{{
+			Image:     "quay.io/prometheus/node-exporter:v1.8.0",
+			Status:    chart.SimulationRelocated,
+			Relocated: "harbor.local/quay/prometheus/node-exporter:v1.8.0",
+		}}, report.Images)
+	})
+
+	t.Run("no images", func(t *testing.T) {
+		_, err := simulate(t, "")
+		assert.ErrorContains(t, err, "no images to simulate in stdin")
+	})
+}
@@ -304,6 +304,44 @@ func TestConfigCommand_RemoveMapping(t *testing.T) {
 	// assert.ElementsMatch(t, expectedRemaining, actualForAssert)
 }
 
+func TestConfigCommand_GroupedSources(t *testing.T) {
+	memFs := afero.NewMemMapFs()
+	oldFs := AppFs
+	AppFs = memFs
+	defer func() { AppFs = oldFs }()
+
+	initialContent := `registries:
+  groups:
+    - name: public
+      target: harbor-a.example.com
+      sources: [docker.io, quay.io, ghcr.io]
+`
+	require.NoError(t, afero.WriteFile(memFs, removeMappingsFile, []byte(initialContent), fileutil.ReadWriteUserPermission))
+	configFile = removeMappingsFile
+	defer func() { configSource, configTarget, configRemoveOnly = "", "", false }()
+
+	// Removing a grouped source drops it from its group
+	configSource = dockerIO
+	configRemoveOnly = true
+	require.NoError(t, removeMapping())
+
+	// Retargeting a grouped source moves it out of its group into an explicit mapping
+	configSource = quayIO
+	configTarget = "harbor-b.example.com/quay"
+	configRemoveOnly = false
+	require.NoError(t, addUpdateMapping())
+
+	config, err := registry.LoadStructuredConfig(memFs, removeMappingsFile, true)
+	require.NoError(t, err)
+	require.Len(t, config.Registries.Groups, 1)
+	assert.Equal(t, "public", config.Registries.Groups[0].Name)
+	assert.Equal(t, []string{"ghcr.io"}, config.Registries.Groups[0].Sources)
+	assert.Equal(t, []registry.Mapping{
+		{Source: quayIO, Target: "harbor-b.example.com/quay"},
+		{Source: "ghcr.io", Target: "harbor-a.example.com"},
+	}, config.ToMappings().Entries)
+}
+
 func TestConfigCommand_RemoveMapping_NonExistentFile(t *testing.T) {
 	// Setup test environment
 	memFs := afero.NewMemMapFs()
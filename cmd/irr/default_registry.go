@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+)
+
+// applyDefaultRegistryFlag validates --default-registry and resolves unqualified images such as
+// nginx:1.25 to it from now on. Without the flag, unqualified images resolve to docker.io.
+func applyDefaultRegistryFlag() error {
+	if defaultRegistry == "" {
+		image.SetDefaultRegistry("")
+		return nil
+	}
+	if err := image.ValidateDefaultRegistry(defaultRegistry); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("invalid --default-registry: %w", err),
+		}
+	}
+	image.SetDefaultRegistry(defaultRegistry)
+	log.Info("Resolving unqualified images to the default registry", "registry", image.UnqualifiedRegistry())
+	return nil
+}
+
+// applyConfigDefaultRegistry resolves unqualified images to the defaultRegistry of a loaded registry
+// config. --default-registry takes precedence. During a batch run the jobs share one default
+// registry, so a file's value is only logged there.
+func applyConfigDefaultRegistry(config *registry.Config, source string) {
+	configured := config.Registries.DefaultRegistry
+	if configured == "" || image.NormalizeRegistry(configured) == image.UnqualifiedRegistry() {
+		return
+	}
+	switch {
+	case defaultRegistry != "":
+		log.Warn("--default-registry takes precedence over the defaultRegistry of the registry config",
+			"flag", defaultRegistry, "config", configured, "source", source)
+	case batchRunCache != nil:
+		log.Warn("The defaultRegistry of a registry config is ignored by 'irr run'; use --default-registry instead",
+			"config", configured, "source", source)
+	default:
+		image.SetDefaultRegistry(configured)
+		log.Info("Resolving unqualified images to the default registry", "registry", image.UnqualifiedRegistry(), "source", source)
+	}
+}
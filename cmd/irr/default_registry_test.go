@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyDefaultRegistry(t *testing.T) {
+	originalFlag := defaultRegistry
+	t.Cleanup(image.SetDefaultRegistry(""))
+	t.Cleanup(func() { defaultRegistry = originalFlag })
+	config := &registry.Config{Registries: registry.RegConfig{DefaultRegistry: "mirror.internal"}}
+
+	defaultRegistry = ""
+	require.NoError(t, applyDefaultRegistryFlag())
+	assert.Equal(t, image.DefaultRegistry, image.UnqualifiedRegistry())
+	applyConfigDefaultRegistry(config, "registry-mappings.yaml")
+	assert.Equal(t, "mirror.internal", image.UnqualifiedRegistry())
+
+	// The flag takes precedence over the config file
+	defaultRegistry = "flag-mirror.internal"
+	require.NoError(t, applyDefaultRegistryFlag())
+	applyConfigDefaultRegistry(config, "registry-mappings.yaml")
+	assert.Equal(t, "flag-mirror.internal", image.UnqualifiedRegistry())
+
+	defaultRegistry = "https://flag-mirror.internal"
+	err := applyDefaultRegistryFlag()
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+}
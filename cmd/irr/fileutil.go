@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/lucas-albers-lz4/irr/internal/helm"
 	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
 	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
 	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 )
@@ -38,9 +40,20 @@ func defaultHelmAdapterFactory() (*helm.Adapter, error) {
 
 	// Create adapter with the Helm client
 	adapter := helm.NewAdapter(helmClient, AppFs, isRunningAsHelmPlugin())
+	adapter.SetRetryConfig(helmRetryConfig())
 	return adapter, nil
 }
 
+// helmRetryConfig builds the Helm SDK retry configuration from the global --helm-retries flags
+func helmRetryConfig() helm.RetryConfig {
+	cfg := helm.DefaultRetryConfig()
+	cfg.MaxRetries = max(helmRetries, 0)
+	if helmRetryBackoff > 0 {
+		cfg.InitialBackoff = helmRetryBackoff
+	}
+	return cfg
+}
+
 // createHelmAdapter creates a new Helm client and adapter, handling errors consistently
 func createHelmAdapter() (*helm.Adapter, error) {
 	return helmAdapterFactory()
@@ -97,6 +110,90 @@ func getReleaseNameAndNamespaceCommon(cmd *cobra.Command, args []string) (releas
 	return releaseName, namespace, nil
 }
 
+// registryFileUsage is the usage of the --registry-file flag of the commands generating overrides
+const registryFileUsage = "Path to YAML file with registry mappings, or a directory of them; can be repeated, later files override earlier ones (defaults to registry-mappings.yaml in the current directory if not provided)"
+
+// addRegistryFileFlag adds the repeatable --registry-file flag, read with loadRegistryConfigs
+func addRegistryFileFlag(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("registry-file", nil, registryFileUsage)
+}
+
+// loadRegistryConfig loads a registry mappings file and applies the profile selected with --profile.
+// During a batch run, each file is loaded once and shared by the jobs using it.
+func loadRegistryConfig(path string, skipCWDRestriction bool) (*registry.Config, error) {
+	if cache := batchRunCache; cache != nil {
+		return cache.registryConfig(path, skipCWDRestriction, readRegistryConfig)
+	}
+	return readRegistryConfig(path, skipCWDRestriction)
+}
+
+// readRegistryConfig reads a registry mappings file and applies the profile selected with --profile.
+func readRegistryConfig(path string, skipCWDRestriction bool) (*registry.Config, error) {
+	config, err := registry.LoadConfigDefault(path, skipCWDRestriction)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.ApplyProfile(registryProfile); err != nil {
+		return nil, err
+	}
+	if registryProfile != "" {
+		log.Info("Using registry config profile", "profile", registryProfile, "file", path)
+	}
+	return config, nil
+}
+
+// loadRegistryConfigs loads the registry mappings files given by --registry-file, directories
+// expanded to the YAML files they contain, and merges them in order so that later files override
+// earlier ones. Each setting a later file overrides is logged as a warning. The profile selected
+// with --profile is applied to the merged config.
+func loadRegistryConfigs(paths []string, skipCWDRestriction bool) (config *registry.Config, files []string, err error) {
+	files, err = registry.ConfigFiles(AppFs, paths)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch len(files) {
+	case 0:
+		return nil, nil, nil
+	case 1:
+		config, err = loadRegistryConfig(files[0], skipCWDRestriction)
+		return config, files, err
+	}
+	merge := func(string, bool) (*registry.Config, error) {
+		return mergeRegistryConfigs(files, skipCWDRestriction)
+	}
+	if cache := batchRunCache; cache != nil {
+		config, err = cache.registryConfig(strings.Join(files, string(os.PathListSeparator)), skipCWDRestriction, merge)
+	} else {
+		config, err = merge("", skipCWDRestriction)
+	}
+	return config, files, err
+}
+
+// mergeRegistryConfigs reads the registry mappings files, merges them in order and applies the
+// profile selected with --profile.
+func mergeRegistryConfigs(files []string, skipCWDRestriction bool) (*registry.Config, error) {
+	configs := make([]*registry.Config, 0, len(files))
+	for _, file := range files {
+		config, err := registry.LoadConfigDefault(file, skipCWDRestriction)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		configs = append(configs, config)
+	}
+	merged, conflicts := registry.MergeConfigs(configs, files)
+	for _, conflict := range conflicts {
+		log.Warn("Registry mappings files conflict; the later file wins", "conflict", conflict.String())
+	}
+	if err := merged.ApplyProfile(registryProfile); err != nil {
+		return nil, err
+	}
+	if registryProfile != "" {
+		log.Info("Using registry config profile", "profile", registryProfile, "files", files)
+	}
+	log.Info("Merged registry mappings files", "files", files, "conflicts", len(conflicts))
+	return merged, nil
+}
+
 // writeOutputFile handles writing content to a file with proper error handling and directory creation
 func writeOutputFile(outputFile string, content []byte, successMessage string) error {
 	// Check if file exists
@@ -139,3 +236,43 @@ func writeOutputFile(outputFile string, content []byte, successMessage string) e
 
 	return nil
 }
+
+// validateOutputFilePath checks up front that outputFile can be written: its directory must exist
+// and, if the file already exists, it must be a writable regular file. Nothing is created or changed.
+func validateOutputFilePath(fs afero.Fs, outputFile string) error {
+	outDir := filepath.Dir(outputFile)
+	if isDir, err := afero.IsDir(fs, outDir); err != nil || !isDir {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("output directory %q does not exist or is not a directory", outDir),
+		}
+	}
+
+	info, err := fs.Stat(outputFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to access output file %q: %w", outputFile, err),
+		}
+	}
+	if !info.Mode().IsRegular() {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("output path %q exists but is not a regular file", outputFile),
+		}
+	}
+	f, err := fs.OpenFile(outputFile, os.O_WRONLY, 0)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("cannot write to output file %q: %w", outputFile, err),
+		}
+	}
+	if err := f.Close(); err != nil {
+		log.Warn("Error closing file after permission check", "error", err)
+	}
+	return nil
+}
@@ -343,3 +343,76 @@ func TestDefaultHelmAdapterFactory(t *testing.T) {
 
 	// TODO: Add test case where helm.NewHelmClient() fails? Requires mocking Helm internals.
 }
+
+func TestValidateOutputFilePath(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	outDir := filepath.Join("out", "reports")
+	require.NoError(t, fs.MkdirAll(outDir, 0o755))
+	existing := filepath.Join(outDir, "existing.yaml")
+	require.NoError(t, afero.WriteFile(fs, existing, []byte("keep: true\n"), 0o644))
+
+	t.Run("new file in existing directory", func(t *testing.T) {
+		assert.NoError(t, validateOutputFilePath(fs, filepath.Join(outDir, "new.yaml")))
+		exists, err := afero.Exists(fs, filepath.Join(outDir, "new.yaml"))
+		require.NoError(t, err)
+		assert.False(t, exists, "validation must not create the file")
+	})
+
+	t.Run("existing file is left untouched", func(t *testing.T) {
+		require.NoError(t, validateOutputFilePath(fs, existing))
+		content, err := afero.ReadFile(fs, existing)
+		require.NoError(t, err)
+		assert.Equal(t, "keep: true\n", string(content))
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		err := validateOutputFilePath(fs, filepath.Join("missing", "out.yaml"))
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitIOError, exitErr.Code)
+	})
+
+	t.Run("path is a directory", func(t *testing.T) {
+		err := validateOutputFilePath(fs, outDir)
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitIOError, exitErr.Code)
+	})
+}
+
+func TestLoadRegistryConfigs(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	require.NoError(t, os.WriteFile(base, []byte(`registries:
+  defaultTarget: harbor.local/default
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+    - source: quay.io
+      target: harbor.local/quay
+`), testFilePerms))
+	clusters := filepath.Join(dir, "clusters")
+	require.NoError(t, os.Mkdir(clusters, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(clusters, "eu.yaml"), []byte(`registries:
+  mappings:
+    - source: docker.io
+      target: mirror.eu.local/docker
+`), testFilePerms))
+
+	config, files, err := loadRegistryConfigs([]string{base, clusters}, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{base, filepath.Join(clusters, "eu.yaml")}, files)
+	mappings := config.ToMappings()
+	require.Len(t, mappings.Entries, 2)
+	assert.Equal(t, "mirror.eu.local/docker", mappings.Entries[0].Target, "the later file wins")
+	assert.Equal(t, "harbor.local/quay", mappings.Entries[1].Target)
+	assert.Equal(t, "harbor.local/default", config.Registries.DefaultTarget)
+
+	config, files, err = loadRegistryConfigs(nil, true)
+	require.NoError(t, err)
+	assert.Nil(t, config)
+	assert.Empty(t, files)
+
+	_, _, err = loadRegistryConfigs([]string{base, filepath.Join(dir, "missing.yaml")}, true)
+	assert.ErrorContains(t, err, "missing.yaml")
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// goldenDiffContext is the number of unchanged lines shown around each change in the diff
+const goldenDiffContext = 3
+
+// testFlagsHidden lists override flags that do not apply to irr test, which compares the
+// overrides of a single local chart with the golden file instead of writing them out
+var testFlagsHidden = []string{
+	"release-name", "namespace", "output-file", "output-dir", "output-format", "merge-into",
+	"split-by-subchart", "recursive", "workers", "validate-workers", "watch", "watch-debounce", "dry-run",
+	"ignore-errors", "error-report", "probe-targets", "probe-auth", "output-uri", "encrypt", "encrypt-recipient", "metadata",
+}
+
+// newTestCmd creates the test command
+func newTestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Compare the generated overrides of a chart with a golden file",
+		Long: `Generates the image overrides of a chart, exactly as 'irr override' would, and compares them
+with a committed golden file of expected overrides. If they differ, a unified diff is printed
+and irr exits with code 8, so chart upgrades that change where images are relocated to can be
+caught in CI.
+
+The comparison ignores formatting, comments and key order. Run with --update to write the
+generated overrides to the golden file, e.g. to create it or accept an intended change.`,
+		Example: `  irr test --chart-path ./nginx --registry-file registry-mappings.yaml --golden testdata/nginx-overrides.yaml
+  irr test --chart-path ./nginx -t harbor.local -s docker.io --golden testdata/nginx-overrides.yaml --update`,
+		Args: cobra.NoArgs,
+		RunE: runTest,
+	}
+
+	setupOverrideFlags(cmd)
+	for _, name := range testFlagsHidden {
+		if err := cmd.Flags().MarkHidden(name); err != nil {
+			log.Error("Failed to hide test flag", "flag", name, "error", err)
+		}
+	}
+	cmd.Flags().String("golden", "", "Path to the golden file with the expected overrides (required)")
+	// override already defines --update; irr test gives it its own meaning
+	cmd.Flags().Lookup("update").Usage = "Write the generated overrides to the golden file instead of comparing them"
+	return cmd
+}
+
+// runTest generates the overrides for --chart-path and compares them with the golden file,
+// or replaces the golden file with them when --update is set
+func runTest(cmd *cobra.Command, _ []string) error {
+	goldenFile, err := getStringFlag(cmd, "golden")
+	if err != nil {
+		return err
+	}
+	if goldenFile == "" {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitMissingRequiredFlag,
+			Err:  errors.New("required flag(s) \"golden\" not set"),
+		}
+	}
+	update, err := getBoolFlag(cmd, "update")
+	if err != nil {
+		return err
+	}
+
+	generated, _, err := generateStandaloneOverrides(cmd, false)
+	if err != nil {
+		return err
+	}
+	if update {
+		return writeGoldenFile(cmd, goldenFile, generated)
+	}
+
+	expected, err := afero.ReadFile(AppFs, goldenFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = fmt.Errorf("golden file '%s' does not exist; run with --update to create it", goldenFile)
+		} else {
+			err = fmt.Errorf("failed to read golden file '%s': %w", goldenFile, err)
+		}
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitIOError, Err: err}
+	}
+
+	diff, err := diffOverrides(expected, generated, goldenFile)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Overrides match golden file %s\n", goldenFile); err != nil {
+			log.Debug("Failed to write test result", "error", err)
+		}
+		return nil
+	}
+	if _, err := fmt.Fprint(cmd.OutOrStdout(), diff); err != nil {
+		log.Debug("Failed to write overrides diff", "error", err)
+	}
+	return &exitcodes.ExitCodeError{
+		Code: exitcodes.ExitGoldenMismatch,
+		Err:  fmt.Errorf("generated overrides differ from golden file '%s'; run with --update to accept the changes", goldenFile),
+	}
+}
+
+// diffOverrides returns a unified diff from the expected to the generated overrides, or an empty
+// string if they hold the same values. Both are normalized before diffing so that formatting,
+// comments and key order in the golden file do not matter.
+func diffOverrides(expected, generated []byte, goldenFile string) (string, error) {
+	expectedValues, err := normalizeOverrides(expected)
+	if err != nil {
+		return "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to parse golden file '%s': %w", goldenFile, err),
+		}
+	}
+	generatedValues, err := normalizeOverrides(generated)
+	if err != nil {
+		return "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to parse generated overrides: %w", err),
+		}
+	}
+	if reflect.DeepEqual(expectedValues, generatedValues) {
+		return "", nil
+	}
+
+	expectedYAML, err := yaml.Marshal(expectedValues)
+	if err != nil {
+		return "", &exitcodes.ExitCodeError{Code: exitcodes.ExitGeneralRuntimeError, Err: fmt.Errorf("failed to marshal golden overrides: %w", err)}
+	}
+	generatedYAML, err := yaml.Marshal(generatedValues)
+	if err != nil {
+		return "", &exitcodes.ExitCodeError{Code: exitcodes.ExitGeneralRuntimeError, Err: fmt.Errorf("failed to marshal generated overrides: %w", err)}
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(expectedYAML)),
+		B:        difflib.SplitLines(string(generatedYAML)),
+		FromFile: goldenFile,
+		ToFile:   "generated",
+		Context:  goldenDiffContext,
+	})
+	if err != nil {
+		return "", &exitcodes.ExitCodeError{Code: exitcodes.ExitGeneralRuntimeError, Err: fmt.Errorf("failed to diff overrides: %w", err)}
+	}
+	return diff, nil
+}
+
+// normalizeOverrides parses an overrides file, treating an empty file as empty overrides
+func normalizeOverrides(data []byte) (interface{}, error) {
+	var values interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	return values, nil
+}
+
+// writeGoldenFile replaces the golden file with the generated overrides
+func writeGoldenFile(cmd *cobra.Command, goldenFile string, generated []byte) error {
+	if dir := filepath.Dir(goldenFile); dir != "" && dir != "." {
+		if err := AppFs.MkdirAll(dir, fileutil.ReadWriteExecuteUserReadExecuteOthers); err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to create golden file directory: %w", err),
+			}
+		}
+	}
+	if err := afero.WriteFile(AppFs, goldenFile, generated, fileutil.ReadWriteUserReadOthers); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write golden file '%s': %w", goldenFile, err),
+		}
+	}
+	if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Updated golden file %s\n", goldenFile); err != nil {
+		log.Debug("Failed to write test result", "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffOverrides(t *testing.T) {
+	expected := []byte(`# relocated images
+image:
+  repository: docker.io/library/nginx
+  registry: harbor.local
+`)
+
+	diff, err := diffOverrides(expected, []byte("image:\n  registry: harbor.local\n  repository: docker.io/library/nginx\n"), "golden.yaml")
+	require.NoError(t, err)
+	assert.Empty(t, diff, "comments and key order are ignored")
+
+	diff, err = diffOverrides(expected, []byte("image:\n  registry: harbor.local\n  repository: docker.io/bitnami/nginx\n"), "golden.yaml")
+	require.NoError(t, err)
+	assert.Contains(t, diff, "--- golden.yaml\n+++ generated\n")
+	assert.Contains(t, diff, "-    repository: docker.io/library/nginx\n")
+	assert.Contains(t, diff, "+    repository: docker.io/bitnami/nginx\n")
+
+	diff, err = diffOverrides(nil, []byte("{}\n"), "golden.yaml")
+	require.NoError(t, err)
+	assert.Empty(t, diff, "an empty golden file matches empty overrides")
+
+	_, err = diffOverrides([]byte("image: [unclosed"), []byte("{}\n"), "golden.yaml")
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+}
+
+func TestRunTest(t *testing.T) {
+	goldenFile := filepath.Join(t.TempDir(), "testdata", "overrides.yaml")
+	runTestCmd := func(extraArgs ...string) (string, error) {
+		cmd := newTestCmd()
+		out := new(bytes.Buffer)
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"--chart-path", helmExecTestChart, "-t", "harbor.local", "-s", "docker.io", "--golden", goldenFile}, extraArgs...))
+		err := cmd.Execute()
+		return out.String(), err
+	}
+	var exitErr *exitcodes.ExitCodeError
+
+	t.Run("missing golden file", func(t *testing.T) {
+		_, err := runTestCmd()
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitIOError, exitErr.Code)
+		assert.Contains(t, err.Error(), "run with --update to create it")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		out, err := runTestCmd("--update")
+		require.NoError(t, err)
+		assert.Contains(t, out, "Updated golden file")
+		assert.FileExists(t, goldenFile)
+	})
+
+	t.Run("match", func(t *testing.T) {
+		out, err := runTestCmd()
+		require.NoError(t, err)
+		assert.Contains(t, out, "Overrides match golden file")
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(goldenFile, []byte("image:\n  registry: old.example.com\n"), 0o600))
+		out, err := runTestCmd()
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitGoldenMismatch, exitErr.Code)
+		assert.Contains(t, out, "-  registry: old.example.com")
+	})
+
+	t.Run("golden flag required", func(t *testing.T) {
+		cmd := newTestCmd()
+		cmd.SetArgs([]string{"--chart-path", helmExecTestChart, "-t", "harbor.local", "-s", "docker.io"})
+		require.ErrorAs(t, cmd.Execute(), &exitErr)
+		assert.Equal(t, exitcodes.ExitMissingRequiredFlag, exitErr.Code)
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/cli"
 
+	internalhelm "github.com/lucas-albers-lz4/irr/internal/helm"
 	"github.com/lucas-albers-lz4/irr/pkg/helm"
 	log "github.com/lucas-albers-lz4/irr/pkg/log"
 	"github.com/spf13/cobra"
@@ -82,10 +83,9 @@ func GetReleaseNamespace(cmd *cobra.Command) string {
 	return validateTestNamespace
 }
 
-// GetHelmSettings returns the Helm CLI settings
+// GetHelmSettings returns the Helm CLI settings for the cluster selected with the kube flags
 func GetHelmSettings() *cli.EnvSettings {
-	settings := cli.New()
-	return settings
+	return internalhelm.NewSettings()
 }
 
 // GetChartPathFromRelease attempts to get the chart path from a Helm release
@@ -140,6 +140,8 @@ func GetChartPathFromRelease(releaseName string) (string, error) {
 	pull.Settings = settings
 	pull.DestDir = tempDir
 	pull.Version = chartInfo.Version
+	pull.CaFile = caFile
+	pull.InsecureSkipTLSverify = insecureSkipTLSVerify
 
 	// Try to pull the chart
 	chartPath, err := pull.Run(chartInfo.Name)
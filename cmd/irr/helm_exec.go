@@ -0,0 +1,327 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+const (
+	// helmExecOverridesFilename is the name of the generated overrides file passed to helm
+	helmExecOverridesFilename = "irr-overrides.yaml"
+	// defaultHelmBinary is the helm executable used when HELM_BIN is not set
+	defaultHelmBinary = "helm"
+	// helmInstallPositionals is the number of positional arguments of `helm install NAME CHART`
+	helmInstallPositionals = 3
+)
+
+// Variable for exec.Command to support mocking in tests
+var helmExecCommand = exec.Command
+
+// helmValueFlags lists the helm install/upgrade flags that take a separate value argument,
+// so that their values are not mistaken for the release name or chart.
+var helmValueFlags = map[string]bool{
+	"-f": true, "--values": true, "--set": true, "--set-string": true, "--set-file": true,
+	"--set-json": true, "--set-literal": true, "-n": true, "--namespace": true, "--version": true,
+	"--repo": true, "--kube-context": true, "--kubeconfig": true, "-o": true, "--output": true,
+	"--timeout": true, "--description": true, "--post-renderer": true, "--post-renderer-args": true,
+	"--username": true, "--password": true, "--ca-file": true, "--cert-file": true, "--key-file": true,
+	"--keyring": true, "--registry-config": true, "--repository-cache": true, "--repository-config": true,
+	"--history-max": true, "--name-template": true, "--labels": true, "-l": true,
+	"--kube-apiserver": true, "--kube-as-user": true, "--kube-as-group": true, "--kube-ca-file": true,
+	"--kube-token": true, "--kube-tls-server-name": true, "--burst-limit": true, "--qps": true,
+}
+
+// helmExecFlagsHidden lists override flags that helm-exec derives from the helm arguments
+var helmExecFlagsHidden = []string{
+	"chart-path", "release-name", "namespace", "output-file", "output-dir", "output-format", "merge-into",
+	"split-by-subchart", "recursive", "workers", "validate-workers", "watch", "watch-debounce", "values", "set", "set-string",
+	"set-file", "set-json", "set-literal", "ignore-errors", "error-report", "output-uri", "encrypt", "encrypt-recipient",
+}
+
+// helmInvocation is a parsed `helm install` or `helm upgrade` command line
+type helmInvocation struct {
+	Subcommand       string
+	ReleaseName      string
+	Namespace        string
+	Chart            string
+	Version          string
+	Repo             string
+	ValueFiles       []string
+	SetValues        []string
+	SetStringValues  []string
+	SetFileValues    []string
+	SetJSONValues    []string
+	SetLiteralValues []string
+}
+
+// newHelmExecCmd creates the helm-exec command
+func newHelmExecCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "helm-exec [flags] -- install|upgrade [helm arguments]",
+		Short: "Run helm install/upgrade with image overrides generated on the fly",
+		Long: `Generates image overrides for the chart of a 'helm install' or 'helm upgrade' command line,
+passes them to helm as an extra -f values file (after any of your own, so they take precedence),
+and then runs the real helm binary. This relocates images transparently, without a separate
+'irr override' step.
+
+irr flags (registry mappings, target registry, ...) go before '--'; the helm command goes after it.
+Values passed to helm with -f/--values and --set are also used when analyzing the chart.
+The helm binary is taken from HELM_BIN (set by helm for plugins) or found on PATH.`,
+		Example: `  irr helm-exec --registry-file registry-mappings.yaml -- install my-nginx ./nginx -n web
+  helm irr helm-exec -t harbor.local -s docker.io -- upgrade my-nginx bitnami/nginx --version 15.0.0 -f prod.yaml`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runHelmExec,
+	}
+
+	setupOverrideFlags(cmd)
+	for _, name := range helmExecFlagsHidden {
+		if err := cmd.Flags().MarkHidden(name); err != nil {
+			log.Error("Failed to hide helm-exec flag", "flag", name, "error", err)
+		}
+	}
+	return cmd
+}
+
+// runHelmExec generates overrides for the chart of the helm command line and runs helm with them
+func runHelmExec(cmd *cobra.Command, args []string) error {
+	invocation, err := parseHelmInvocation(args)
+	if err != nil {
+		return err
+	}
+	dryRun, err := getBoolFlag(cmd, "dry-run")
+	if err != nil {
+		return err
+	}
+	if !dryRun {
+		if err := requireNetwork(fmt.Sprintf("running helm %s (use --dry-run to only print the command)", invocation.Subcommand)); err != nil {
+			return err
+		}
+	}
+
+	chartPath, err := resolveHelmExecChart(invocation)
+	if err != nil {
+		return err
+	}
+	if err := setHelmExecOverrideFlags(cmd, chartPath, invocation); err != nil {
+		return err
+	}
+
+	tmpDir, err := afero.TempDir(AppFs, "", "irr-helm-exec-")
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to create temporary directory for overrides: %w", err),
+		}
+	}
+	defer func() {
+		if err := AppFs.RemoveAll(tmpDir); err != nil {
+			log.Warn("Failed to remove temporary overrides directory", "path", tmpDir, "error", err)
+		}
+	}()
+	overridesFile := filepath.Join(tmpDir, helmExecOverridesFilename)
+
+	log.Info("Generating overrides for helm", "subcommand", invocation.Subcommand, "chart", chartPath)
+	if err := runOverrideStandaloneMode(cmd, overridesFile, false, false); err != nil {
+		return err
+	}
+
+	helmArgs := make([]string, 0, len(args)+2)
+	helmArgs = append(helmArgs, args...)
+	helmArgs = append(helmArgs, "-f", overridesFile)
+	helmBinary := os.Getenv("HELM_BIN")
+	if helmBinary == "" {
+		helmBinary = defaultHelmBinary
+	}
+
+	if dryRun {
+		return printHelmExecDryRun(cmd, overridesFile, helmBinary, helmArgs)
+	}
+
+	log.Info("Running helm", "binary", helmBinary, "args", strings.Join(helmArgs, " "))
+	helmCmd := helmExecCommand(helmBinary, helmArgs...)
+	helmCmd.Stdin = cmd.InOrStdin()
+	helmCmd.Stdout = cmd.OutOrStdout()
+	helmCmd.Stderr = cmd.ErrOrStderr()
+	if err := helmCmd.Run(); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitHelmCommandFailed,
+			Err:  fmt.Errorf("helm %s failed: %w", invocation.Subcommand, err),
+		}
+	}
+	return nil
+}
+
+// parseHelmInvocation extracts the subcommand, chart and values options from helm install/upgrade arguments
+func parseHelmInvocation(args []string) (*helmInvocation, error) {
+	invocation := &helmInvocation{}
+	var positionals []string
+	generateName := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			positionals = append(positionals, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(arg, "=")
+		if name == "-g" || name == "--generate-name" {
+			generateName = true
+			continue
+		}
+		if !helmValueFlags[name] {
+			continue // Boolean flag
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, &exitcodes.ExitCodeError{
+					Code: exitcodes.ExitInputConfigurationError,
+					Err:  fmt.Errorf("helm flag %s requires a value", name),
+				}
+			}
+			i++
+			value = args[i]
+		}
+		invocation.addFlag(name, value)
+	}
+
+	if len(positionals) == 0 || (positionals[0] != "install" && positionals[0] != "upgrade") {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("helm-exec supports only 'install' and 'upgrade' (usage: irr helm-exec [flags] -- install|upgrade ...)"),
+		}
+	}
+	invocation.Subcommand = positionals[0]
+
+	// install [NAME] CHART (NAME is omitted with --generate-name), upgrade RELEASE CHART
+	wantPositionals := helmInstallPositionals
+	if invocation.Subcommand == "install" && generateName {
+		wantPositionals--
+	}
+	if len(positionals) != wantPositionals {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("could not determine the chart of 'helm %s %s'", invocation.Subcommand, strings.Join(positionals[1:], " ")),
+		}
+	}
+	invocation.Chart = positionals[len(positionals)-1]
+	if wantPositionals == helmInstallPositionals {
+		invocation.ReleaseName = positionals[1]
+	}
+	return invocation, nil
+}
+
+// addFlag records the helm flags that affect which chart is used, how its values are computed and
+// which release and namespace it is rendered as
+func (h *helmInvocation) addFlag(name, value string) {
+	switch name {
+	case "-f", "--values":
+		h.ValueFiles = append(h.ValueFiles, value)
+	case "--set":
+		h.SetValues = append(h.SetValues, value)
+	case "--set-string":
+		h.SetStringValues = append(h.SetStringValues, value)
+	case "--set-file":
+		h.SetFileValues = append(h.SetFileValues, value)
+	case "--set-json":
+		h.SetJSONValues = append(h.SetJSONValues, value)
+	case "--set-literal":
+		h.SetLiteralValues = append(h.SetLiteralValues, value)
+	case "-n", "--namespace":
+		h.Namespace = value
+	case "--version":
+		h.Version = value
+	case "--repo":
+		h.Repo = value
+	}
+}
+
+// resolveHelmExecChart returns a local path for the chart, downloading it like helm does if it is
+// a repository reference
+func resolveHelmExecChart(invocation *helmInvocation) (string, error) {
+	if invocation.Repo == "" {
+		if exists, err := afero.Exists(AppFs, invocation.Chart); err == nil && exists {
+			return invocation.Chart, nil
+		}
+	}
+
+	if err := requireNetwork(fmt.Sprintf("downloading chart %s", invocation.Chart)); err != nil {
+		return "", err
+	}
+	chartPathOptions := &action.ChartPathOptions{
+		Version: invocation.Version,
+		RepoURL: invocation.Repo,
+	}
+	chartPath, err := chartPathOptions.LocateChart(invocation.Chart, cli.New())
+	if err != nil {
+		return "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitChartNotFound,
+			Err:  fmt.Errorf("failed to locate chart %s: %w", invocation.Chart, err),
+		}
+	}
+	log.Debug("Located chart", "chart", invocation.Chart, "path", chartPath)
+	return chartPath, nil
+}
+
+// setHelmExecOverrideFlags points the override flags at the chart, values, release name and
+// namespace of the helm invocation, so that --validate renders the release being installed
+func setHelmExecOverrideFlags(cmd *cobra.Command, chartPath string, invocation *helmInvocation) error {
+	flagValues := map[string][]string{
+		"chart-path":  {chartPath},
+		"values":      invocation.ValueFiles,
+		"set":         invocation.SetValues,
+		"set-string":  invocation.SetStringValues,
+		"set-file":    invocation.SetFileValues,
+		"set-json":    invocation.SetJSONValues,
+		"set-literal": invocation.SetLiteralValues,
+	}
+	if invocation.ReleaseName != "" {
+		flagValues["release-name"] = []string{invocation.ReleaseName}
+	}
+	if invocation.Namespace != "" {
+		flagValues["namespace"] = []string{invocation.Namespace}
+	}
+	for name, values := range flagValues {
+		for _, value := range values {
+			if err := cmd.Flags().Set(name, value); err != nil {
+				return &exitcodes.ExitCodeError{
+					Code: exitcodes.ExitInputConfigurationError,
+					Err:  fmt.Errorf("failed to set %s from helm arguments: %w", name, err),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// printHelmExecDryRun shows the generated overrides and the helm command instead of running it
+func printHelmExecDryRun(cmd *cobra.Command, overridesFile, helmBinary string, helmArgs []string) error {
+	overrides, err := afero.ReadFile(AppFs, overridesFile)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to read generated overrides: %w", err),
+		}
+	}
+	log.Info("DRY RUN: Displaying generated overrides and helm command (stdout)")
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "# Generated overrides\n%s\n# Helm command\n%s %s\n",
+		overrides, helmBinary, strings.Join(helmArgs, " "))
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write dry-run output to stdout: %w", err),
+		}
+	}
+	return nil
+}
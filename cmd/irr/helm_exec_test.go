@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const helmExecTestChart = "../../test-data/charts/minimal-git-image"
+
+// withHelmExecCommand replaces the helm command with one that runs name, recording the helm arguments
+func withHelmExecCommand(t *testing.T, name string) *[]string {
+	t.Helper()
+	var gotArgs []string
+	original := helmExecCommand
+	helmExecCommand = func(_ string, args ...string) *exec.Cmd {
+		gotArgs = args
+		return exec.CommandContext(context.Background(), name)
+	}
+	t.Cleanup(func() { helmExecCommand = original })
+	return &gotArgs
+}
+
+func TestParseHelmInvocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    *helmInvocation
+		wantErr string
+	}{
+		{
+			name: "install with values",
+			args: []string{"install", "web", "./nginx", "-n", "web", "-f", "prod.yaml", "--set=replicaCount=2", "--wait"},
+			want: &helmInvocation{Subcommand: "install", ReleaseName: "web", Namespace: "web", Chart: "./nginx", ValueFiles: []string{"prod.yaml"}, SetValues: []string{"replicaCount=2"}},
+		},
+		{
+			name: "install with generated name",
+			args: []string{"install", "--generate-name", "bitnami/nginx", "--version", "15.0.0"},
+			want: &helmInvocation{Subcommand: "install", Chart: "bitnami/nginx", Version: "15.0.0"},
+		},
+		{
+			name: "upgrade from repo",
+			args: []string{"upgrade", "--install", "web", "nginx", "--repo", "https://charts.example.com", "--set-string", "tag=1"},
+			want: &helmInvocation{Subcommand: "upgrade", ReleaseName: "web", Chart: "nginx", Repo: "https://charts.example.com", SetStringValues: []string{"tag=1"}},
+		},
+		{
+			name: "json and literal values",
+			args: []string{"install", "web", "./nginx", "--set-json", `image={"tag":"1.0"}`, "--set-literal", "note=a,b"},
+			want: &helmInvocation{Subcommand: "install", ReleaseName: "web", Chart: "./nginx", SetJSONValues: []string{`image={"tag":"1.0"}`}, SetLiteralValues: []string{"note=a,b"}},
+		},
+		{name: "unsupported subcommand", args: []string{"template", "web", "./nginx"}, wantErr: "only 'install' and 'upgrade'"},
+		{name: "missing chart", args: []string{"upgrade", "web"}, wantErr: "could not determine the chart"},
+		{name: "missing flag value", args: []string{"install", "web", "./nginx", "-f"}, wantErr: "requires a value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHelmInvocation(tt.args)
+			if tt.wantErr != "" {
+				var exitErr *exitcodes.ExitCodeError
+				require.ErrorAs(t, err, &exitErr)
+				assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSetHelmExecOverrideFlags(t *testing.T) {
+	invocation, err := parseHelmInvocation([]string{"upgrade", "--install", "web", "./nginx", "--namespace=shop", "-f", "prod.yaml"})
+	require.NoError(t, err)
+	cmd := newHelmExecCmd()
+	require.NoError(t, setHelmExecOverrideFlags(cmd, "./nginx", invocation))
+	for name, want := range map[string]string{"chart-path": "./nginx", "release-name": "web", "namespace": "shop"} {
+		got, err := cmd.Flags().GetString(name)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, name)
+	}
+
+	invocation, err = parseHelmInvocation([]string{"install", "--generate-name", "./nginx"})
+	require.NoError(t, err)
+	cmd = newHelmExecCmd()
+	require.NoError(t, setHelmExecOverrideFlags(cmd, "./nginx", invocation))
+	namespace, err := cmd.Flags().GetString("namespace")
+	require.NoError(t, err)
+	assert.Equal(t, "default", namespace, "helm's default namespace")
+	assert.False(t, cmd.Flags().Changed("release-name"), "--generate-name leaves the release name to helm")
+}
+
+func TestRunHelmExec(t *testing.T) {
+	t.Setenv("HELM_BIN", "")
+
+	t.Run("runs helm with generated overrides", func(t *testing.T) {
+		gotArgs := withHelmExecCommand(t, "true")
+		cmd := newHelmExecCmd()
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetArgs([]string{"-t", "harbor.local", "-s", "docker.io", "--", "install", "git", helmExecTestChart, "-n", "tools"})
+
+		require.NoError(t, cmd.Execute())
+		require.Len(t, *gotArgs, 7)
+		assert.Equal(t, []string{"install", "git", helmExecTestChart, "-n", "tools", "-f"}, (*gotArgs)[:6])
+		assert.Contains(t, (*gotArgs)[6], helmExecOverridesFilename)
+	})
+
+	t.Run("dry run prints overrides and command", func(t *testing.T) {
+		gotArgs := withHelmExecCommand(t, "true")
+		cmd := newHelmExecCmd()
+		out := new(bytes.Buffer)
+		cmd.SetOut(out)
+		cmd.SetArgs([]string{"-t", "harbor.local", "-s", "docker.io", "--dry-run", "--", "upgrade", "git", helmExecTestChart})
+
+		require.NoError(t, cmd.Execute())
+		assert.Nil(t, *gotArgs, "helm should not run in dry-run mode")
+		assert.Contains(t, out.String(), "harbor.local")
+		assert.Contains(t, out.String(), "helm upgrade git "+helmExecTestChart+" -f ")
+	})
+
+	t.Run("helm failure", func(t *testing.T) {
+		withHelmExecCommand(t, "false")
+		cmd := newHelmExecCmd()
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetArgs([]string{"-t", "harbor.local", "-s", "docker.io", "--", "install", "git", helmExecTestChart})
+
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, cmd.Execute(), &exitErr)
+		assert.Equal(t, exitcodes.ExitHelmCommandFailed, exitErr.Code)
+	})
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	"github.com/lucas-albers-lz4/irr/pkg/helmfile"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+)
+
+const (
+	// defaultHelmfile is the helmfile read when --file is not set, as by helmfile itself
+	defaultHelmfile = "helmfile.yaml"
+	// defaultHelmfileBinary is the helmfile executable used to render templated helmfiles
+	defaultHelmfileBinary = "helmfile"
+	// defaultHelmfileOutputDir is the directory, next to the helmfile, the overrides are written to
+	defaultHelmfileOutputDir = "irr-overrides"
+)
+
+// helmfileCommand runs the helmfile binary; replaced in tests
+var helmfileCommand = exec.Command
+
+// locateHelmfileChart downloads a repository or OCI chart like helm does and returns its local
+// path; replaced in tests
+var locateHelmfileChart = func(ref, repoURL, version string) (string, error) {
+	chartPathOptions := &action.ChartPathOptions{Version: version, RepoURL: repoURL}
+	return chartPathOptions.LocateChart(ref, cli.New())
+}
+
+// newHelmfileCmd creates the helmfile command
+func newHelmfileCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "helmfile",
+		Short: "Generate the overrides of every release of a helmfile",
+		Long: `Reads the releases of a Helmfile project and generates the image overrides of each of
+them, as 'irr override' would for its chart, values and set entries, writing one overrides file
+per release to --output-dir (irr-overrides next to the helmfile by default).
+
+Local charts are read from their path relative to the helmfile; repository charts (repo/chart,
+with the repository declared under repositories) and oci:// charts are downloaded like helm does.
+Releases with installed: false are skipped. A helmfile using Go templates is rendered with
+'helmfile build' first.
+
+With --write-values, the path of each generated overrides file is added as the last entry of its
+release's values list, so that helmfile applies the overrides after the release's own values.
+irr exits with code 15 if the overrides of any release could not be generated.`,
+		Example: `  irr helmfile --file helmfile.yaml --target-registry harbor.example.com --source-registries docker.io,quay.io
+  irr helmfile -f helmfile.yaml --registry-file registry-mappings.yaml --write-values
+  irr helmfile -f helmfile.yaml.gotmpl -t harbor.example.com --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: runHelmfile,
+	}
+
+	cmd.Flags().StringP("file", "f", defaultHelmfile, "Path to the helmfile")
+	cmd.Flags().StringP("target-registry", "t", "", "Target container registry URL")
+	cmd.Flags().StringSliceP("source-registries", "s", nil, "Source container registry URLs to relocate (comma-separated or multiple flags)")
+	cmd.Flags().StringSliceP("exclude-registries", "e", nil, "Registry URLs to exclude from relocation")
+	addRegistryFileFlag(cmd)
+	cmd.Flags().String("path-strategy", "", "Path strategy for relocated images (prefix-source-registry or flat)")
+	cmd.Flags().String("target-flavor", "", "Target registry provider whose repository naming rules generated paths must follow (generic, ecr, gcr, acr or harbor)")
+	cmd.Flags().String("strict-mode", "", "Strict mode level: off, warn, unsupported or all (default off)")
+	cmd.Flags().String("output-dir", "", "Directory the overrides file of each release is written to (default: irr-overrides next to the helmfile)")
+	cmd.Flags().String("overrides-format", outputFormatYAML, "Format of the overrides files (yaml or json)")
+	cmd.Flags().Bool("write-values", false, "Add each generated overrides file to the values of its release in the helmfile")
+	cmd.Flags().String("helmfile-binary", defaultHelmfileBinary, "helmfile executable used to render templated helmfiles")
+	cmd.Flags().Int("workers", defaultChartWorkers, "Number of releases processed concurrently")
+	cmd.Flags().String("report", "", "Write the consolidated report to this file instead of stdout")
+	cmd.Flags().String("output-format", outputFormatYAML, "Format of the report (yaml or json)")
+	cmd.Flags().Bool("overwrite", false, "Replace existing overrides files, e.g. to regenerate them")
+	cmd.Flags().Bool("dry-run", false, "Generate the overrides of every release without writing any file")
+	return cmd
+}
+
+// runHelmfile implements 'irr helmfile'
+func runHelmfile(cmd *cobra.Command, _ []string) error {
+	helmfilePath, err := getStringFlag(cmd, "file")
+	if err != nil {
+		return err
+	}
+	writeValues, err := getBoolFlag(cmd, "write-values")
+	if err != nil {
+		return err
+	}
+	dryRun, err := getBoolFlag(cmd, "dry-run")
+	if err != nil {
+		return err
+	}
+	overwrite, err := getBoolFlag(cmd, "overwrite")
+	if err != nil {
+		return err
+	}
+	workers, err := cmd.Flags().GetInt("workers")
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get workers flag: %w", err),
+		}
+	}
+	reportFile, err := getStringFlag(cmd, "report")
+	if err != nil {
+		return err
+	}
+	reportFormat, err := getStringFlag(cmd, "output-format")
+	if err != nil {
+		return err
+	}
+	defaults, outputDir, err := getHelmfileSettings(cmd, helmfilePath)
+	if err != nil {
+		return err
+	}
+
+	data, err := afero.ReadFile(AppFs, helmfilePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  fmt.Errorf("helmfile '%s' does not exist", helmfilePath),
+			}
+		}
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to read helmfile '%s': %w", helmfilePath, err),
+		}
+	}
+	if helmfile.IsTemplated(data) {
+		if writeValues {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  fmt.Errorf("--write-values cannot edit the templated helmfile '%s'; add the overrides files to its releases by hand", helmfilePath),
+			}
+		}
+		if data, err = renderHelmfile(cmd, helmfilePath); err != nil {
+			return err
+		}
+	}
+	spec, err := helmfile.Parse(data)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("helmfile '%s': %w", helmfilePath, err),
+		}
+	}
+
+	tmpDir, err := afero.TempDir(AppFs, "", "irr-helmfile-")
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to create temporary directory for inline values: %w", err),
+		}
+	}
+	defer func() {
+		if err := AppFs.RemoveAll(tmpDir); err != nil {
+			log.Warn("Failed to remove temporary inline values directory", "path", tmpDir, "error", err)
+		}
+	}()
+
+	baseDir := filepath.Dir(helmfilePath)
+	jobs, failed, err := helmfileJobs(spec, outputDir, tmpDir)
+	if err != nil {
+		return err
+	}
+	results := failed
+	if len(jobs) > 0 {
+		jobs, err = resolveBatchJobs(&BatchSpec{Defaults: defaults, Jobs: jobs}, baseDir, dryRun)
+		if err != nil {
+			return err
+		}
+		jobResults, err := runBatchJobs(cmd, jobs, workers, overwrite, dryRun)
+		if err != nil {
+			return err
+		}
+		for i := range jobResults {
+			jobResults[i].Namespace = jobs[i].Namespace
+		}
+		results = append(jobResults, failed...)
+	}
+
+	report := newBatchReport(helmfilePath, results)
+	logBatchReport(report)
+	if writeValues && !dryRun {
+		if err := writeHelmfileValues(helmfilePath, data, report); err != nil {
+			return err
+		}
+	}
+	if err := outputBatchReport(cmd, report, reportFile, reportFormat); err != nil {
+		return err
+	}
+	return batchError(report)
+}
+
+// getHelmfileSettings returns the override settings applied to every release and the directory
+// the overrides files are written to. Paths given on the command line are made absolute, since
+// the paths of the helmfile are resolved against its directory.
+func getHelmfileSettings(cmd *cobra.Command, helmfilePath string) (settings BatchSettings, outputDir string, err error) {
+	scalars := map[string]*string{
+		"target-registry":  &settings.TargetRegistry,
+		"path-strategy":    &settings.PathStrategy,
+		"target-flavor":    &settings.TargetFlavor,
+		"strict-mode":      &settings.StrictMode,
+		"overrides-format": &settings.OutputFormat,
+		"output-dir":       &outputDir,
+	}
+	for name, value := range scalars {
+		if *value, err = getStringFlag(cmd, name); err != nil {
+			return BatchSettings{}, "", err
+		}
+	}
+	if settings.RegistryFiles, err = getStringSliceFlag(cmd, "registry-file"); err != nil {
+		return BatchSettings{}, "", err
+	}
+	if settings.SourceRegistries, err = cmd.Flags().GetStringSlice("source-registries"); err != nil {
+		return BatchSettings{}, "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get source-registries flag: %w", err),
+		}
+	}
+	if settings.ExcludeRegistries, err = cmd.Flags().GetStringSlice("exclude-registries"); err != nil {
+		return BatchSettings{}, "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get exclude-registries flag: %w", err),
+		}
+	}
+
+	if outputDir == "" {
+		outputDir = filepath.Join(filepath.Dir(helmfilePath), defaultHelmfileOutputDir)
+	}
+	paths := []*string{&outputDir}
+	for i := range settings.RegistryFiles {
+		paths = append(paths, &settings.RegistryFiles[i])
+	}
+	for _, path := range paths {
+		if *path == "" {
+			continue
+		}
+		if *path, err = filepath.Abs(*path); err != nil {
+			return BatchSettings{}, "", &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  fmt.Errorf("failed to resolve path %s: %w", *path, err),
+			}
+		}
+	}
+	return settings, outputDir, nil
+}
+
+// renderHelmfile renders a templated helmfile with 'helmfile build' and returns the rendered state
+func renderHelmfile(cmd *cobra.Command, helmfilePath string) ([]byte, error) {
+	binary, err := getStringFlag(cmd, "helmfile-binary")
+	if err != nil {
+		return nil, err
+	}
+	if err := requireNetwork("rendering a templated helmfile with helmfile build"); err != nil {
+		return nil, err
+	}
+	log.Info("Rendering templated helmfile", "binary", binary, "file", helmfilePath)
+	buildCmd := helmfileCommand(binary, "--file", helmfilePath, "build")
+	buildCmd.Stderr = cmd.ErrOrStderr()
+	output, err := buildCmd.Output()
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitHelmCommandFailed,
+			Err:  fmt.Errorf("failed to render helmfile '%s' with %s build: %w", helmfilePath, binary, err),
+		}
+	}
+	return output, nil
+}
+
+// helmfileJobs converts the installed releases of a helmfile into batch jobs writing their
+// overrides to outputDir. Inline values are written to files in tmpDir. Releases whose chart
+// cannot be located are returned as failed results.
+func helmfileJobs(spec *helmfile.Spec, outputDir, tmpDir string) ([]BatchJob, []BatchJobResult, error) {
+	var jobs []BatchJob
+	var failed []BatchJobResult
+	for _, release := range spec.Releases {
+		id := release.ID()
+		if !release.IsInstalled() {
+			log.Info("Skipping release that is not installed", "release", id)
+			continue
+		}
+		chartPath, err := resolveHelmfileChart(spec, release)
+		if err != nil {
+			result := BatchJobResult{Name: id, Namespace: release.Namespace}
+			result.Error, result.ErrorCode, result.Category = reportedError(err)
+			failed = append(failed, result)
+			continue
+		}
+		values, err := helmfileValuesFiles(release, tmpDir)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		job := BatchJob{
+			Name:       id,
+			ChartPath:  chartPath,
+			OutputFile: filepath.Join(outputDir, strings.ReplaceAll(id, "/", "-")+".yaml"),
+		}
+		job.Namespace = release.Namespace
+		job.Values = values
+		for _, set := range release.Set {
+			if set.File != "" {
+				log.Warn("Ignoring set entry read from a file", "release", id, "name", set.Name, "file", set.File)
+				continue
+			}
+			job.Set = append(job.Set, fmt.Sprintf("%s=%v", set.Name, set.Value))
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, failed, nil
+}
+
+// resolveHelmfileChart returns the chart of a release as a path relative to the helmfile for local
+// charts, or the local path of the downloaded chart for repository and OCI charts
+func resolveHelmfileChart(spec *helmfile.Spec, release helmfile.Release) (string, error) {
+	if helmfile.IsLocalChart(release.Chart) {
+		return release.Chart, nil
+	}
+
+	ref, repoURL := release.Chart, ""
+	if !strings.HasPrefix(ref, "oci://") {
+		repoName, chartName, ok := strings.Cut(release.Chart, "/")
+		repo, found := spec.Repository(repoName)
+		if !ok || !found {
+			return "", &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitChartNotFound,
+				Err:  fmt.Errorf("chart %s of release %s is neither a local path nor in a repository of the helmfile", release.Chart, release.ID()),
+			}
+		}
+		if repo.OCI {
+			ref = "oci://" + strings.TrimSuffix(repo.URL, "/") + "/" + chartName
+		} else {
+			ref, repoURL = chartName, repo.URL
+		}
+	}
+
+	if err := requireNetwork(fmt.Sprintf("downloading chart %s of release %s", release.Chart, release.ID())); err != nil {
+		return "", err
+	}
+	chartPath, err := locateHelmfileChart(ref, repoURL, release.Version)
+	if err != nil {
+		return "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitChartNotFound,
+			Err:  fmt.Errorf("failed to locate chart %s of release %s: %w", release.Chart, release.ID(), err),
+		}
+	}
+	log.Debug("Located chart", "release", release.ID(), "chart", release.Chart, "path", chartPath)
+	return chartPath, nil
+}
+
+// helmfileValuesFiles returns the values files of a release in order, writing its inline values to
+// files in tmpDir. Templated (.gotmpl) values files are skipped, since only helmfile can render them.
+func helmfileValuesFiles(release helmfile.Release, tmpDir string) ([]string, error) {
+	var files []string
+	for i, entry := range release.Values {
+		switch value := entry.(type) {
+		case string:
+			if strings.HasSuffix(value, ".gotmpl") {
+				log.Warn("Ignoring templated values file", "release", release.ID(), "file", value)
+				continue
+			}
+			files = append(files, value)
+		case map[string]interface{}:
+			data, err := yaml.Marshal(value)
+			if err != nil {
+				return nil, &exitcodes.ExitCodeError{
+					Code: exitcodes.ExitGeneralRuntimeError,
+					Err:  fmt.Errorf("failed to marshal inline values of release %s: %w", release.ID(), err),
+				}
+			}
+			file := filepath.Join(tmpDir, fmt.Sprintf("%s-values-%d.yaml", strings.ReplaceAll(release.ID(), "/", "-"), i+1))
+			if err := afero.WriteFile(AppFs, file, data, fileutil.ReadWriteUserPermission); err != nil {
+				return nil, &exitcodes.ExitCodeError{
+					Code: exitcodes.ExitIOError,
+					Err:  fmt.Errorf("failed to write inline values of release %s: %w", release.ID(), err),
+				}
+			}
+			files = append(files, file)
+		default:
+			log.Warn("Ignoring unsupported values entry", "release", release.ID(), "entry", i+1)
+		}
+	}
+	return files, nil
+}
+
+// writeHelmfileValues adds the overrides file of every release that succeeded to its values in
+// the helmfile, as a path relative to the helmfile
+func writeHelmfileValues(helmfilePath string, data []byte, report *BatchReport) error {
+	baseDir, err := filepath.Abs(filepath.Dir(helmfilePath))
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to resolve the directory of helmfile '%s': %w", helmfilePath, err),
+		}
+	}
+	files := make(map[string]string)
+	for _, result := range report.Jobs {
+		if result.Error != "" || result.OutputFile == "" {
+			continue
+		}
+		path := result.OutputFile
+		if rel, err := filepath.Rel(baseDir, path); err == nil {
+			path = filepath.ToSlash(rel)
+		}
+		files[result.Name] = path
+	}
+
+	updated, changed, err := helmfile.AddValuesFiles(data, files)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to add overrides to helmfile '%s': %w", helmfilePath, err),
+		}
+	}
+	if len(changed) == 0 {
+		log.Info("The helmfile already lists every overrides file", "file", helmfilePath)
+		return nil
+	}
+	info, err := AppFs.Stat(helmfilePath)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to stat helmfile '%s': %w", helmfilePath, err),
+		}
+	}
+	if err := afero.WriteFile(AppFs, helmfilePath, updated, info.Mode().Perm()); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write helmfile '%s': %w", helmfilePath, err),
+		}
+	}
+	log.Info("Added overrides files to the helmfile", "file", helmfilePath, "releases", strings.Join(changed, ", "))
+	return nil
+}
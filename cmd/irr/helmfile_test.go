@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/helmfile"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestHelmfileJobs(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	restoreFs := SetFs(fs)
+	t.Cleanup(restoreFs)
+
+	var located []string
+	originalLocate := locateHelmfileChart
+	locateHelmfileChart = func(ref, repoURL, version string) (string, error) {
+		if ref == "missing" {
+			return "", errors.New("chart not found")
+		}
+		located = append(located, fmt.Sprintf("%s|%s|%s", ref, repoURL, version))
+		return "/cache/" + filepath.Base(ref) + ".tgz", nil
+	}
+	t.Cleanup(func() { locateHelmfileChart = originalLocate })
+
+	installed := false
+	spec := &helmfile.Spec{
+		Repositories: []helmfile.Repository{
+			{Name: "bitnami", URL: "https://charts.bitnami.com/bitnami"},
+			{Name: "internal", URL: "registry.example.com/charts/", OCI: true},
+		},
+		Releases: []helmfile.Release{
+			{
+				Name: "web", Namespace: "prod", Chart: "./charts/web",
+				Values: []interface{}{"values/web.yaml", "values/web.yaml.gotmpl", map[string]interface{}{"replicaCount": 2}},
+				Set:    []helmfile.SetValue{{Name: "image.tag", Value: "1.25"}, {Name: "config", File: "config.json"}},
+			},
+			{Name: "cache", Chart: "bitnami/redis", Version: "18.0.0"},
+			{Name: "api", Chart: "internal/api"},
+			{Name: "proxy", Chart: "oci://ghcr.io/org/proxy", Version: "1.0.0"},
+			{Name: "old", Chart: "./charts/old", Installed: &installed},
+			{Name: "unknown", Chart: "stable/nginx"},
+			{Name: "gone", Chart: "bitnami/missing"},
+		},
+	}
+
+	jobs, failed, err := helmfileJobs(spec, "/out", "/tmp/values")
+	require.NoError(t, err)
+
+	require.Len(t, jobs, 4)
+	web := jobs[0]
+	assert.Equal(t, "prod/web", web.Name)
+	assert.Equal(t, "./charts/web", web.ChartPath)
+	assert.Equal(t, "/out/prod-web.yaml", web.OutputFile)
+	assert.Equal(t, "prod", web.Namespace)
+	assert.Equal(t, []string{"values/web.yaml", "/tmp/values/prod-web-values-3.yaml"}, web.Values)
+	assert.Equal(t, []string{"image.tag=1.25"}, web.Set)
+	inline, err := afero.ReadFile(fs, "/tmp/values/prod-web-values-3.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "replicaCount: 2\n", string(inline))
+
+	assert.Equal(t, "/cache/redis.tgz", jobs[1].ChartPath)
+	assert.Equal(t, []string{
+		"redis|https://charts.bitnami.com/bitnami|18.0.0",
+		"oci://registry.example.com/charts/api||",
+		"oci://ghcr.io/org/proxy||1.0.0",
+	}, located)
+
+	require.Len(t, failed, 2)
+	assert.Equal(t, "unknown", failed[0].Name)
+	assert.Contains(t, failed[0].Error, "neither a local path nor in a repository")
+	assert.Equal(t, "gone", failed[1].Name)
+	assert.Equal(t, "chart-not-found", failed[1].ErrorCode)
+}
+
+func TestRunHelmfile(t *testing.T) {
+	chartPath, err := filepath.Abs(helmExecTestChart)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	helmfilePath := filepath.Join(dir, "helmfile.yaml")
+	content := fmt.Sprintf(`releases:
+  - name: git
+    namespace: tools
+    chart: %[1]s
+    values:
+      - replicaCount: 1
+  - name: old
+    chart: %[1]s
+    installed: false
+  - name: remote
+    chart: unknown/chart
+`, chartPath)
+	require.NoError(t, os.WriteFile(helmfilePath, []byte(content), 0o600))
+
+	runHelmfileCmd := func(extraArgs ...string) (*BatchReport, error) {
+		cmd := newHelmfileCmd()
+		// The usage printed after an error would follow the report on stdout
+		cmd.SilenceUsage = true
+		out := new(bytes.Buffer)
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"-f", helmfilePath, "-t", "harbor.local", "-s", "docker.io"}, extraArgs...))
+		err := cmd.Execute()
+		report := &BatchReport{}
+		require.NoError(t, yaml.Unmarshal(out.Bytes(), report))
+		return report, err
+	}
+
+	report, err := runHelmfileCmd("--dry-run", "--write-values")
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitChartProcessingFailed, exitErr.Code)
+	assert.Equal(t, 1, report.Succeeded)
+	assert.NoFileExists(t, filepath.Join(dir, "irr-overrides", "tools-git.yaml"))
+	unchanged, err := os.ReadFile(helmfilePath)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(unchanged), "a dry run does not edit the helmfile")
+
+	report, err = runHelmfileCmd("--write-values")
+	require.ErrorAs(t, err, &exitErr)
+	require.Len(t, report.Jobs, 2)
+	assert.Equal(t, "tools/git", report.Jobs[0].Name)
+	assert.Equal(t, "tools", report.Jobs[0].Namespace)
+	assert.Equal(t, "remote", report.Jobs[1].Name)
+	assert.NotEmpty(t, report.Jobs[1].Error)
+
+	overrides, err := os.ReadFile(filepath.Join(dir, "irr-overrides", "tools-git.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(overrides), "harbor.local")
+	updated, err := os.ReadFile(helmfilePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(updated), "      - replicaCount: 1\n      - irr-overrides/tools-git.yaml\n")
+}
+
+func TestGetHelmfileSettings(t *testing.T) {
+	cmd := newHelmfileCmd()
+	require.NoError(t, cmd.ParseFlags([]string{
+		"-t", "harbor.local", "--registry-file", "base.yaml", "--registry-file", "clusters/", "--output-dir", "/out",
+	}))
+	settings, outputDir, err := getHelmfileSettings(cmd, "helmfile.yaml")
+	require.NoError(t, err)
+	base, err := filepath.Abs("base.yaml")
+	require.NoError(t, err)
+	clusters, err := filepath.Abs("clusters")
+	require.NoError(t, err)
+	assert.Equal(t, []string{base, clusters}, settings.RegistryFiles, "--registry-file is repeatable")
+	assert.Equal(t, "harbor.local", settings.TargetRegistry)
+	assert.Equal(t, "/out", outputDir)
+
+	job, err := newBatchJobCmd(cmd, &BatchJob{Name: "web", ChartPath: "./charts/web", BatchSettings: settings})
+	require.NoError(t, err)
+	registryFiles, err := getStringSliceFlag(job, "registry-file")
+	require.NoError(t, err)
+	assert.Equal(t, []string{base, clusters}, registryFiles, "every mappings file is passed to the release's override")
+}
+
+func TestRenderHelmfile(t *testing.T) {
+	var gotArgs []string
+	original := helmfileCommand
+	helmfileCommand = func(_ string, args ...string) *exec.Cmd {
+		gotArgs = args
+		return exec.CommandContext(context.Background(), "echo", "releases: [{name: web, chart: ./charts/web}]")
+	}
+	t.Cleanup(func() { helmfileCommand = original })
+
+	cmd := newHelmfileCmd()
+	rendered, err := renderHelmfile(cmd, "helmfile.yaml.gotmpl")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--file", "helmfile.yaml.gotmpl", "build"}, gotArgs)
+	spec, err := helmfile.Parse(rendered)
+	require.NoError(t, err)
+	assert.Equal(t, "web", spec.Releases[0].Name)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	"github.com/lucas-albers-lz4/irr/pkg/keys"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/rules"
+	"github.com/spf13/cobra"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// addRulesFileFlag adds --rules-file, which extends the built-in image convention library.
+func addRulesFileFlag(cmd *cobra.Command) {
+	cmd.Flags().String("rules-file", "", "YAML file of image conventions describing non-standard image keys of chart families, added to the built-in library")
+}
+
+// loadImageConventions returns the built-in image conventions together with those of --rules-file.
+func loadImageConventions(cmd *cobra.Command) ([]rules.ImageConvention, error) {
+	rulesFile, err := getStringFlag(cmd, "rules-file")
+	if err != nil || rulesFile == "" {
+		return rules.DefaultImageConventions, err
+	}
+	custom, err := rules.LoadImageConventions(AppFs, rulesFile)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	log.Info("Loaded image conventions", "file", rulesFile, "count", len(custom))
+	return rules.MergeImageConventions(rules.DefaultImageConventions, custom), nil
+}
+
+// applyImageConventions adds the images found at the values paths described by the image
+// conventions to the analysis of loadedChart. A convention image replaces the patterns detected at
+// its path or, for a split image, at its keys, which the heuristics read as separate images.
+func applyImageConventions(cmd *cobra.Command, loadedChart *helmchart.Chart, values map[string]interface{}, result *analysis.ChartAnalysis) error {
+	conventions, err := loadImageConventions(cmd)
+	if err != nil {
+		return err
+	}
+	if values == nil && loadedChart != nil {
+		values = loadedChart.Values
+	}
+	found := rules.FindConventionImages(conventions, loadedChart, values)
+	if len(found) == 0 {
+		return nil
+	}
+
+	replaced := make(map[string]bool)
+	var patterns []analysis.ImagePattern
+	for _, img := range found {
+		pattern, ok := conventionImagePattern(img)
+		if !ok {
+			continue
+		}
+		replaced[img.Path] = true
+		for _, key := range img.Keys {
+			replaced[joinValuesPath(img.Path, key)] = true
+		}
+		log.Info("Found image by chart convention", "path", img.Path, "image", img.Value, "convention", img.Convention)
+		patterns = append(patterns, pattern)
+	}
+
+	kept := result.ImagePatterns[:0]
+	for _, p := range result.ImagePatterns {
+		if replaced[p.Path] {
+			log.Debug("Replacing detected image pattern with convention pattern", "path", p.Path)
+			continue
+		}
+		kept = append(kept, p)
+	}
+	result.ImagePatterns = append(kept, patterns...)
+	return nil
+}
+
+// conventionImagePattern converts an image found by an image convention into an image pattern.
+// Values that are not valid image references are skipped.
+func conventionImagePattern(img rules.ConventionImage) (analysis.ImagePattern, bool) {
+	ref, err := image.ParseImageReference(img.Value)
+	if err != nil {
+		log.Warn("Skipping value that is not an image reference", "path", img.Path, "value", img.Value, "convention", img.Convention, "error", err)
+		return analysis.ImagePattern{}, false
+	}
+	pattern := analysis.ImagePattern{
+		Path:  img.Path,
+		Type:  analysis.PatternTypeString,
+		Value: img.Value,
+		Structure: map[string]interface{}{
+			keys.Registry:   ref.Registry,
+			keys.Repository: ref.Repository,
+			keys.Tag:        ref.Tag,
+		},
+		Count:      1,
+		KeepString: len(img.Keys) == 0,
+	}
+	// Leave out the tag filled in by parsing when the values have none, so --default-tag applies
+	if name := img.Value[strings.LastIndex(img.Value, "/")+1:]; !strings.ContainsAny(name, ":@") {
+		delete(pattern.Structure, keys.Tag)
+	}
+	if len(img.Keys) > 0 {
+		pattern.Type = analysis.PatternTypeMap
+		pattern.ImageKeys = img.Keys
+	}
+	return pattern, true
+}
+
+// joinValuesPath appends key to the values path prefix.
+func joinValuesPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
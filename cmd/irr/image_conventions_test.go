@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+func TestApplyImageConventions(t *testing.T) {
+	originalFs := AppFs
+	AppFs = afero.NewMemMapFs()
+	t.Cleanup(func() { AppFs = originalFs })
+
+	values := map[string]interface{}{
+		"operatorImage": "quay.io/example/operator:v1",
+		"manager": map[string]interface{}{
+			"imageRegistry":   "quay.io",
+			"imageRepository": "example/manager",
+			"imageTag":        "v2",
+		},
+		"sidecar": map[string]interface{}{"proxyImage": "quay.io/example/proxy"},
+	}
+	loadedChart := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "example-operator"}}
+	result := &analysis.ChartAnalysis{ImagePatterns: []analysis.ImagePattern{
+		{Path: "operatorImage", Type: analysis.PatternTypeString, Value: "quay.io/example/operator:v1"},
+		{Path: "manager.imageRepository", Type: analysis.PatternTypeString, Value: "example/manager"},
+		{Path: "web.image", Type: analysis.PatternTypeMap, Value: "docker.io/library/nginx:1.25"},
+	}}
+
+	cmd := newOverrideCmd()
+	require.NoError(t, applyImageConventions(cmd, loadedChart, values, result))
+
+	byPath := make(map[string]analysis.ImagePattern)
+	for _, p := range result.ImagePatterns {
+		byPath[p.Path] = p
+	}
+	require.Len(t, byPath, 3, "the split key pattern is replaced and the other patterns are kept")
+	assert.Contains(t, byPath, "web.image")
+	assert.True(t, byPath["operatorImage"].KeepString)
+	manager := byPath["manager"]
+	assert.Equal(t, analysis.PatternTypeMap, manager.Type)
+	assert.Equal(t, "quay.io/example/manager:v2", manager.Value)
+	assert.Equal(t, "imageTag", manager.ImageKeys["tag"])
+
+	// A rules file adds conventions for the chart family
+	require.NoError(t, afero.WriteFile(AppFs, "rules.yaml", []byte(`imageConventions:
+  - name: example
+    charts: ["example-*"]
+    images: ["sidecar.proxyImage"]
+`), 0o600))
+	cmd = newOverrideCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--rules-file", "rules.yaml"}))
+	result = analysis.NewChartAnalysis()
+	require.NoError(t, applyImageConventions(cmd, loadedChart, values, result))
+
+	paths := make([]string, 0, len(result.ImagePatterns))
+	for _, p := range result.ImagePatterns {
+		paths = append(paths, p.Path)
+	}
+	assert.ElementsMatch(t, []string{"manager", "operatorImage", "sidecar.proxyImage"}, paths)
+	for _, p := range result.ImagePatterns {
+		if p.Path == "sidecar.proxyImage" {
+			assert.NotContains(t, p.Structure, "tag", "an image without a tag gets none, so --default-tag applies")
+		}
+	}
+
+	require.NoError(t, afero.WriteFile(AppFs, "invalid.yaml", []byte("imageConventions:\n  - images: [x]\n"), 0o600))
+	cmd = newOverrideCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--rules-file", "invalid.yaml"}))
+	err := applyImageConventions(cmd, loadedChart, values, analysis.NewChartAnalysis())
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+}
@@ -6,17 +6,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
-	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
-	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli/values"
 
@@ -27,7 +27,9 @@ import (
 	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
 	"github.com/lucas-albers-lz4/irr/pkg/image"
 	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/progress"
 	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/lucas-albers-lz4/irr/pkg/selector"
 	"github.com/spf13/cobra"
 	// Added Helm imports
 )
@@ -42,13 +44,28 @@ type ChartInfo struct {
 
 // ImageInfo represents image information found in the chart
 type ImageInfo struct {
-	Registry         string `json:"registry" yaml:"registry"`                                     // The registry detected (might be default)
-	Repository       string `json:"repository" yaml:"repository"`                                 // The repository path
-	Tag              string `json:"tag,omitempty" yaml:"tag,omitempty"`                           // The tag, if present
-	Digest           string `json:"digest,omitempty" yaml:"digest,omitempty"`                     // The digest, if present
-	Source           string `json:"source" yaml:"source"`                                         // The dot-notation path in values where found
-	OriginalRegistry string `json:"originalRegistry,omitempty" yaml:"originalRegistry,omitempty"` // Added: Original registry from source if different
-	ValuePath        string `json:"valuePath,omitempty" yaml:"valuePath,omitempty"`               // Added: Full path from context-aware analysis
+	Registry         string   `json:"registry" yaml:"registry"`                                     // The registry detected (might be default)
+	Repository       string   `json:"repository" yaml:"repository"`                                 // The repository path
+	Tag              string   `json:"tag,omitempty" yaml:"tag,omitempty"`                           // The tag, if present
+	Digest           string   `json:"digest,omitempty" yaml:"digest,omitempty"`                     // The digest, if present
+	Source           string   `json:"source" yaml:"source"`                                         // The dot-notation path in values where found
+	OriginalRegistry string   `json:"originalRegistry,omitempty" yaml:"originalRegistry,omitempty"` // Added: Original registry from source if different
+	ValuePath        string   `json:"valuePath,omitempty" yaml:"valuePath,omitempty"`               // Added: Full path from context-aware analysis
+	AnchorSource     string   `json:"anchorSource,omitempty" yaml:"anchorSource,omitempty"`         // Added: YAML anchor path this image was copied from
+	Location         string   `json:"location,omitempty" yaml:"location,omitempty"`                 // Added: file:line:column of the value in the values files
+	Lint             []string `json:"lint,omitempty" yaml:"lint,omitempty"`                         // Added: Normalizations applied to the value as written
+	Workloads        []string `json:"workloads,omitempty" yaml:"workloads,omitempty"`               // Added: Rendered workloads using the image (template analysis)
+	LikelyValuePaths []string `json:"likelyValuePaths,omitempty" yaml:"likelyValuePaths,omitempty"` // Added: Values paths inferred from templates for unmapped rendered images
+	Subchart         string   `json:"subchart,omitempty" yaml:"subchart,omitempty"`                 // Added: Subchart whose values (or template) hold the image, as its values keys (aliases) joined by '/'
+	SubchartChart    string   `json:"subchartChart,omitempty" yaml:"subchartChart,omitempty"`       // Added: Chart name of that subchart, which differs from its key when aliased
+	SubchartDepth    int      `json:"subchartDepth,omitempty" yaml:"subchartDepth,omitempty"`       // Added: Nesting level of that subchart (1 for a direct dependency)
+	DisabledBy       string   `json:"disabledBy,omitempty" yaml:"disabledBy,omitempty"`             // Added: Condition or tags disabling that subchart; Helm does not render the image
+	Hooks            []string `json:"hooks,omitempty" yaml:"hooks,omitempty"`                       // Added: Helm hook events (e.g. test, pre-install) of the rendered hooks using the image
+	Recommendation   string   `json:"recommendation,omitempty" yaml:"recommendation,omitempty"`     // Added: What to do about an image irr cannot override, e.g. one hard-coded in a hook
+	// Freshness reports the state of the tag in the source registry (--check-tags)
+	Freshness *registry.TagFreshness `json:"freshness,omitempty" yaml:"freshness,omitempty"`
+	// Signatures lists the cosign signatures and attestations of the image (--check-signatures)
+	Signatures *registry.ImageSignatures `json:"signatures,omitempty" yaml:"signatures,omitempty"`
 }
 
 // ImageAnalysis represents the result of analyzing a chart for images
@@ -58,6 +75,23 @@ type ImageAnalysis struct {
 	ImagePatterns []analysis.ImagePattern `json:"imagePatterns" yaml:"imagePatterns"`
 	Errors        []string                `json:"errors,omitempty" yaml:"errors,omitempty"`
 	Skipped       []string                `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+	Verification  *helm.ChartVerification `json:"verification,omitempty" yaml:"verification,omitempty"`
+	Duplicates    []DuplicateImage        `json:"duplicates,omitempty" yaml:"duplicates,omitempty"`
+	// CRDImages lists images in the chart's CRD files, which must be mirrored unchanged (--scan-crds)
+	CRDImages []analysis.CRDImage `json:"crdImages,omitempty" yaml:"crdImages,omitempty"`
+	// SuggestedMappings lists mappings for registries the mappings file does not cover (--only-unmapped)
+	SuggestedMappings []SuggestedMapping `json:"suggestedMappings,omitempty" yaml:"suggestedMappings,omitempty"`
+	// AnalysisMode is the --analysis-mode used, when not the default values analysis
+	AnalysisMode string `json:"analysisMode,omitempty" yaml:"analysisMode,omitempty"`
+	// TemplateCheck compares values and rendered images (--analysis-mode cross-check)
+	TemplateCheck *TemplateCrossCheck `json:"templateCheck,omitempty" yaml:"templateCheck,omitempty"`
+}
+
+// DuplicateImage represents an image referenced at more than one values path
+type DuplicateImage struct {
+	Image string   `json:"image" yaml:"image"`
+	Count int      `json:"count" yaml:"count"`
+	Paths []string `json:"paths" yaml:"paths"`
 }
 
 // InspectFlags holds the command line flags for the inspect command
@@ -66,11 +100,28 @@ type InspectFlags struct {
 	OutputFile             string
 	OutputFormat           string
 	GenerateConfigSkeleton bool
+	AnalysisMode           string
+	SkeletonGroups         []registry.RegGroup
 	AnalyzerConfig         *analyzer.Config
 	SourceRegistries       []string
 	AllNamespaces          bool
 	OverwriteSkeleton      bool
 	NoSubchartCheck        bool
+	Revision               int
+	CompareRevision        int
+	Verify                 bool
+	VerifyOptions          helm.ChartVerifyOptions
+	Dependencies           *helm.DependencyOptions
+	Duplicates             bool
+	ScanCRDs               bool
+	TagChecker             *registry.TagChecker
+	SignatureChecker       *registry.TagChecker
+	OnlyUnmapped           bool
+	RegistryFiles          []string
+	Mappings               *registry.Mappings
+	Selectors              selector.Set
+	ReleaseFilter          *ReleaseFilter
+	GroupBy                string
 }
 
 const (
@@ -89,6 +140,25 @@ type ReleaseAnalysisResult struct {
 	Analysis    ImageAnalysis `json:"analysis" yaml:"analysis"`
 }
 
+// ImageChange describes an image whose reference differs between two release revisions
+type ImageChange struct {
+	Path string `json:"path" yaml:"path"`
+	From string `json:"from" yaml:"from"`
+	To   string `json:"to" yaml:"to"`
+}
+
+// RevisionComparison represents the image differences between two revisions of a Helm release
+type RevisionComparison struct {
+	ReleaseName     string        `json:"releaseName" yaml:"releaseName"`
+	Namespace       string        `json:"namespace" yaml:"namespace"`
+	BaseRevision    int           `json:"baseRevision" yaml:"baseRevision"`
+	CompareRevision int           `json:"compareRevision" yaml:"compareRevision"`
+	Added           []ImageInfo   `json:"added,omitempty" yaml:"added,omitempty"`
+	Removed         []ImageInfo   `json:"removed,omitempty" yaml:"removed,omitempty"`
+	Changed         []ImageChange `json:"changed,omitempty" yaml:"changed,omitempty"`
+	Unchanged       []ImageInfo   `json:"unchanged,omitempty" yaml:"unchanged,omitempty"`
+}
+
 // createHelmClient creates a new instance of the Helm client
 func createHelmClient() (helm.ClientInterface, error) {
 	client, err := helm.NewHelmClient()
@@ -111,6 +181,7 @@ It properly handles subcharts and dependency values according to Helm's value me
 	}
 
 	cmd.Flags().String("chart-path", "", "Path to the Helm chart")
+	addChartRefFlags(cmd)
 	cmd.Flags().String("output-file", "", "Write output to file instead of stdout")
 	cmd.Flags().String("output-format", outputFormatYAML, "Output format (yaml or json)")
 	cmd.Flags().Bool("generate-config-skeleton", false, "Generate a config skeleton based on found images")
@@ -120,16 +191,37 @@ It properly handles subcharts and dependency values according to Helm's value me
 	cmd.Flags().String("release-name", "", "Release name for Helm plugin mode")
 	cmd.Flags().StringP("namespace", "n", "default", `Kubernetes namespace for the release (defaults to "default")`)
 	cmd.Flags().BoolP("all-namespaces", "A", false, "Inspect Helm releases across all namespaces (conflicts with --chart-path, --release-name, --namespace)")
+	cmd.Flags().StringArray("skeleton-group", nil, skeletonGroupFlagUsage)
 	cmd.Flags().Bool("overwrite-skeleton", false, "Overwrite the skeleton file if it already exists (only applies when using --generate-config-skeleton)")
 	cmd.Flags().Bool("no-subchart-check", false, "Skip checking for subchart image discrepancies")
+	cmd.Flags().Bool("duplicates", false, "Report images referenced at more than one values path")
+	cmd.Flags().Bool("scan-crds", false, "Report image references in the CRDs of the chart's crds/ directories, which values overrides cannot change (--chart-path only)")
+	addTagFreshnessFlags(cmd)
+	cmd.Flags().Bool("check-signatures", false, "Query the source registry of each image for its cosign signatures and attestations (sha256-<digest>.sig and .att tags and OCI referrers), to be copied along with the image by 'irr config export --images'")
+	cmd.Flags().StringSlice("select", nil, selectFlagUsage)
+	cmd.Flags().Bool("only-unmapped", false, "Only report images whose registries are not covered by the registry mappings file, and suggest mappings for them")
+	cmd.Flags().String("group-by", "", "Report image counts grouped by registry, chart or namespace (namespace requires --all-namespaces) instead of the per-image analysis")
+	cmd.Flags().StringSlice("registry-file", nil, "Path to YAML file with registry mappings used by --only-unmapped, or a directory of them; can be repeated, later files override earlier ones (defaults to registry-mappings.yaml in the current directory)")
+	addRulesFileFlag(cmd)
+	addReleaseFilterFlags(cmd)
+	addChartVerifyFlags(cmd)
+	addDependencyFlags(cmd)
+	addMultiChartFlags(cmd)
+	addCapabilityFlags(cmd)
+	cmd.Flags().Int("revision", 0, "Release revision to inspect (plugin mode only; defaults to the latest revision)")
+	cmd.Flags().Int("compare-revision", 0, "Compare the image set of --revision (or the latest revision) against this revision (plugin mode only)")
 
 	// Add Helm flags
 	cmd.Flags().StringSlice("values", nil, "Values files to process (can be specified multiple times)")
 	cmd.Flags().StringSlice("set", nil, "Set values on the command line (can be specified multiple times)")
 	cmd.Flags().StringSlice("set-string", nil, "Set STRING values on the command line (can be specified multiple times)")
 	cmd.Flags().StringSlice("set-file", nil, "Set values from files (can be specified multiple times)")
+	cmd.Flags().StringArray("set-json", nil, "Set JSON values on the command line (can be specified multiple times)")
+	cmd.Flags().StringArray("set-literal", nil, "Set a literal STRING value on the command line (can be specified multiple times)")
 
 	// Added new flags
+	cmd.Flags().String("analysis-mode", analysisModeValues, "How images are found: values (analyze the chart values), template (extract images from the rendered workloads, "+
+		"mapped back to values paths where possible) or cross-check (analyze the values and report differences with the rendered workloads)")
 	cmd.Flags().Bool("context-aware", false, "Use context-aware analyzer that handles subchart value merging (experimental)")
 
 	return cmd
@@ -137,6 +229,8 @@ It properly handles subcharts and dependency values according to Helm's value me
 
 // writeOutput writes the analysis to a file or stdout
 func writeOutput(cmd *cobra.Command, analysisResult *ImageAnalysis, flags *InspectFlags) error {
+	sortAnalysis(analysisResult)
+
 	// Handle generate-config-skeleton flag
 	if flags.GenerateConfigSkeleton {
 		skeletonFile := flags.OutputFile
@@ -166,7 +260,7 @@ func writeOutput(cmd *cobra.Command, analysisResult *ImageAnalysis, flags *Inspe
 			log.Info("Overwriting existing skeleton file", "path", skeletonFile)
 		}
 
-		if err := createConfigSkeleton(analysisResult.Images, skeletonFile); err != nil {
+		if err := createConfigSkeleton(analysisResult.Images, flags.SkeletonGroups, skeletonFile); err != nil {
 			// Special handling for file exists error - should not happen now with the checks above
 			var exitErr *exitcodes.ExitCodeError
 			if errors.As(err, &exitErr) && strings.Contains(exitErr.Err.Error(), "already exists") {
@@ -186,6 +280,16 @@ func writeOutput(cmd *cobra.Command, analysisResult *ImageAnalysis, flags *Inspe
 		return nil
 	}
 
+	checkTagFreshness(cmd.Context(), flags.TagChecker, analysisResult.Images)
+	checkImageSignatures(cmd.Context(), flags.SignatureChecker, analysisResult.Images)
+	if flags.Duplicates {
+		analysisResult.Duplicates = findDuplicateImages(analysisResult.Images)
+		logDuplicateImages(analysisResult.Duplicates)
+	}
+	if flags.GroupBy != "" {
+		return writeStructuredOutput(cmd, groupImages(flags.GroupBy, analysisGroupedImages(analysisResult, "")), flags)
+	}
+
 	// Determine output format (yaml or json)
 	var output []byte
 	var err error
@@ -248,12 +352,38 @@ func runInspect(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if flags.AllNamespaces {
+		if err := requireNetwork("--all-namespaces"); err != nil {
+			return err
+		}
+	} else if releaseNameProvided {
+		if err := requireNetwork(fmt.Sprintf("inspecting release %s", args[0])); err != nil {
+			return err
+		}
+	}
 
 	// New code: If --all-namespaces flag is set, use the all-namespaces flow
 	if flags.AllNamespaces {
 		return inspectAllNamespaces(cmd, flags)
 	}
 
+	recursive, workers, err := getMultiChartFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if err := validateAnalysisMode(flags, releaseNameProvided, recursive); err != nil {
+		return err
+	}
+	if recursive {
+		if releaseNameProvided {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  errors.New("--recursive cannot be used with a release name"),
+			}
+		}
+		return inspectChartDirectory(cmd, flags, workers)
+	}
+
 	// Decide execution path based on args/plugin mode
 	if releaseNameProvided {
 		// Assume plugin mode if release name is given
@@ -286,6 +416,7 @@ func runInspect(cmd *cobra.Command, args []string) error {
 		}
 		// --- End Namespace Handling ---
 
+		log.AddFields("release", releaseName, "namespace", namespace)
 		return inspectHelmRelease(cmd, flags, releaseName, namespace)
 	}
 
@@ -304,17 +435,29 @@ func runInspect(cmd *cobra.Command, args []string) error {
 		return err // Return the original error
 	}
 
+	log.AddFields("chart", chartPath)
 	log.Info("Successfully loaded and analyzed chart", chartPath) // Add log for success
 
+	// Render the chart for template-based analysis before filtering, so that rendered images can be
+	// mapped back to every values path
+	if flags.AnalysisMode != analysisModeValues {
+		if err := applyTemplateAnalysis(cmd, chartPath, flags.AnalysisMode, analysisResult); err != nil {
+			return err
+		}
+	}
+
 	// Filter results if source-registries flag is provided
 	if len(flags.SourceRegistries) > 0 {
 		// Log filtering action
 		log.Info("Filtering results to only include registries", "registries", strings.Join(flags.SourceRegistries, ", "))
 		filterImagesBySourceRegistries(cmd, flags, analysisResult) // Modifies analysis in place
 	}
+	applyInspectSelectors(flags, analysisResult, "")
+	applyOnlyUnmapped(flags, analysisResult)
 
-	// Perform subchart check if not explicitly disabled
-	if !flags.NoSubchartCheck && chartPath != "" {
+	// Perform subchart check if not explicitly disabled; template-based analysis already compares
+	// the values with the rendered chart
+	if !flags.NoSubchartCheck && chartPath != "" && flags.AnalysisMode == analysisModeValues {
 		// Check for subchart discrepancies
 		if err := checkSubchartDiscrepancy(cmd, chartPath, analysisResult); err != nil {
 			// Just log the error, don't fail the command
@@ -322,6 +465,10 @@ func runInspect(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Warn about images copied through YAML anchors, since overriding only one copy has no effect on the others
+	warnAnchorDerivedImages(analysisResult.Images)
+	warnSuspiciousImages(analysisResult.Images)
+
 	// --- Informational Output (Moved Before writeOutput) ---
 	//nolint:gocritic // ifElseChain: Keeping if-else for clarity over switch here.
 	if !flags.GenerateConfigSkeleton && flags.OutputFile == "" { // Only show suggestions when printing to stdout
@@ -400,6 +547,16 @@ func setupAnalyzerAndLoadChart(cmd *cobra.Command, flags *InspectFlags) (string,
 		chartPath = absChartPath
 	}
 
+	// Verify the chart archive before anything is loaded from it
+	var verification *helm.ChartVerification
+	if flags.Verify {
+		var err error
+		verification, err = verifyChart(chartPath, flags.VerifyOptions)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
 	// Create value options from flags
 	valueOpts := &values.Options{}
 
@@ -431,10 +588,21 @@ func setupAnalyzerAndLoadChart(cmd *cobra.Command, flags *InspectFlags) (string,
 		valueOpts.FileValues = setFileValues
 	}
 
+	// Get set-json and set-literal values
+	setJSONValues, err := cmd.Flags().GetStringArray("set-json")
+	if err == nil && len(setJSONValues) > 0 {
+		valueOpts.JSONValues = setJSONValues
+	}
+	setLiteralValues, err := cmd.Flags().GetStringArray("set-literal")
+	if err == nil && len(setLiteralValues) > 0 {
+		valueOpts.LiteralValues = setLiteralValues
+	}
+
 	// Create chart loader options
 	loaderOptions := &helm.ChartLoaderOptions{
-		ChartPath:  chartPath,
-		ValuesOpts: *valueOpts,
+		ChartPath:    chartPath,
+		ValuesOpts:   *valueOpts,
+		Dependencies: flags.Dependencies,
 	}
 
 	// Create chart loader
@@ -479,6 +647,10 @@ func setupAnalyzerAndLoadChart(cmd *cobra.Command, flags *InspectFlags) (string,
 			Err:  fmt.Errorf("chart analysis failed: %w", err),
 		}
 	}
+	if err := applyImageConventions(cmd, chartAnalysisContext.Chart, chartAnalysisContext.Values, chartAnalysisResult); err != nil {
+		return "", nil, err
+	}
+	analysis.ResolveSubcharts(chartAnalysisContext.Chart, chartAnalysisResult.ImagePatterns)
 
 	// Process image patterns using the original analysis patterns
 	images, skipped := processImagePatterns(chartAnalysisResult.ImagePatterns)
@@ -494,11 +666,92 @@ func setupAnalyzerAndLoadChart(cmd *cobra.Command, flags *InspectFlags) (string,
 		Images:        images,
 		ImagePatterns: chartAnalysisResult.ImagePatterns, // Use original patterns
 		Skipped:       skipped,
+		Verification:  verification,
+	}
+	if flags.ScanCRDs {
+		analysisResult.CRDImages = scanCRDImages(chartAnalysisContext.Chart)
 	}
 
 	return chartPath, analysisResult, nil
 }
 
+// inspectChartDirectory analyzes every chart found under --chart-path concurrently and writes
+// a combined result covering all charts. With --generate-config-skeleton, a single skeleton is
+// generated from the images of every chart.
+func inspectChartDirectory(cmd *cobra.Command, flags *InspectFlags, workers int) error {
+	if flags.ChartPath == "" {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitMissingRequiredFlag,
+			Err:  errors.New("--recursive requires --chart-path to point to a directory of charts"),
+		}
+	}
+
+	chartPaths, err := discoverChartRoots(AppFs, flags.ChartPath)
+	if err != nil {
+		return err
+	}
+	log.Info("Discovered charts", "root", flags.ChartPath, "count", len(chartPaths), "workers", workers)
+
+	results, err := processChartsConcurrently(getCommandContext(cmd), chartPaths, workers, func(chartPath string) MultiChartResult {
+		result := MultiChartResult{ChartPath: chartPath}
+		chartFlags := *flags
+		chartFlags.ChartPath = chartPath
+
+		_, analysisResult, err := setupAnalyzerAndLoadChart(cmd, &chartFlags)
+		if err != nil {
+			result.Error, result.ErrorCode, result.Category = reportedError(err)
+			return result
+		}
+		if len(chartFlags.SourceRegistries) > 0 {
+			filterImagesBySourceRegistries(cmd, &chartFlags, analysisResult)
+		}
+		applyInspectSelectors(&chartFlags, analysisResult, "")
+		applyOnlyUnmapped(&chartFlags, analysisResult)
+		if !chartFlags.NoSubchartCheck {
+			if err := checkSubchartDiscrepancy(cmd, chartPath, analysisResult); err != nil {
+				log.Warn("Failed to check for subchart discrepancies", "chart", chartPath, "error", err)
+			}
+		}
+		warnAnchorDerivedImages(analysisResult.Images)
+		warnSuspiciousImages(analysisResult.Images)
+		checkTagFreshness(cmd.Context(), chartFlags.TagChecker, analysisResult.Images)
+		checkImageSignatures(cmd.Context(), chartFlags.SignatureChecker, analysisResult.Images)
+		if chartFlags.Duplicates {
+			analysisResult.Duplicates = findDuplicateImages(analysisResult.Images)
+		}
+
+		sortAnalysis(analysisResult)
+		result.ImageCount = len(analysisResult.Images)
+		result.Analysis = analysisResult
+		return result
+	})
+	if err != nil {
+		return err
+	}
+
+	summary := newMultiChartSummary(flags.ChartPath, results)
+	logMultiChartSummary(summary)
+
+	if flags.GenerateConfigSkeleton {
+		combined := &ImageAnalysis{}
+		for _, result := range results {
+			if result.Analysis != nil {
+				combined.Images = append(combined.Images, result.Analysis.Images...)
+			}
+		}
+		if err := writeOutput(cmd, combined, flags); err != nil {
+			return err
+		}
+	} else if flags.GroupBy != "" {
+		if err := writeStructuredOutput(cmd, groupChartResults(flags.GroupBy, results), flags); err != nil {
+			return err
+		}
+	} else if err := writeStructuredOutput(cmd, summary, flags); err != nil {
+		return err
+	}
+	return multiChartError(summary)
+}
+
 // filterImagesBySourceRegistries modifies the analysis object to only include images
 // from the specified source registries.
 func filterImagesBySourceRegistries(_ *cobra.Command, flags *InspectFlags, analysisResult *ImageAnalysis) {
@@ -551,6 +804,80 @@ func filterImagesBySourceRegistries(_ *cobra.Command, flags *InspectFlags, analy
 	analysisResult.ImagePatterns = filteredPatterns
 }
 
+// warnAnchorDerivedImages logs a warning listing image paths whose values come from YAML anchors
+// (aliases or merge keys), together with the anchor each one was copied from.
+func warnAnchorDerivedImages(images []ImageInfo) {
+	anchorDerived := make([]string, 0)
+	for _, img := range images {
+		if img.AnchorSource == "" {
+			continue
+		}
+		anchorDerived = append(anchorDerived, fmt.Sprintf("%s (from anchor at %s)", imageInfoPath(img), img.AnchorSource))
+	}
+	if len(anchorDerived) == 0 {
+		return
+	}
+	sort.Strings(anchorDerived)
+	log.Warn("Images derived from YAML anchors detected",
+		"check", "yaml_anchors",
+		"count", len(anchorDerived),
+		"paths", strings.Join(anchorDerived, ", "),
+		"message", "These values are copies of an anchored value. Overrides are generated for each path separately "+
+			"because the override file does not preserve anchors; review them together.")
+}
+
+// patternLabel returns the values path of a pattern, followed by its location in the values files
+// when it is known.
+func patternLabel(p analysis.ImagePattern) string {
+	if p.Location == nil {
+		return p.Path
+	}
+	return fmt.Sprintf("%s (%s)", p.Path, p.Location)
+}
+
+// lintImagePattern returns the normalizations image parsing applies to the value of a pattern as
+// written, such as a stripped URL scheme or trailing slash. Map patterns are linted as the
+// registry and repository joined into a single reference.
+func lintImagePattern(p analysis.ImagePattern) []string {
+	value := p.Value
+	if p.Type == analysis.PatternTypeMap && p.Structure != nil {
+		value, _ = p.Structure["repository"].(string)
+		if regVal, ok := p.Structure["registry"].(string); ok && regVal != "" {
+			value = regVal + "/" + value
+		}
+	}
+	actions := image.Lint(value)
+	if len(actions) == 0 {
+		return nil
+	}
+	lint := make([]string, 0, len(actions))
+	for _, action := range actions {
+		lint = append(lint, string(action))
+	}
+	return lint
+}
+
+// warnSuspiciousImages logs a warning listing image paths whose values had to be normalized to parse
+func warnSuspiciousImages(images []ImageInfo) {
+	suspicious := make([]string, 0)
+	for _, img := range images {
+		if len(img.Lint) == 0 {
+			continue
+		}
+		suspicious = append(suspicious, fmt.Sprintf("%s (%s)", imageInfoPath(img), strings.Join(img.Lint, ", ")))
+	}
+	if len(suspicious) == 0 {
+		return
+	}
+	sort.Strings(suspicious)
+	log.Warn("Suspicious image values detected",
+		"check", "image_lint",
+		"count", len(suspicious),
+		"paths", strings.Join(suspicious, ", "),
+		"message", "These values were normalized before parsing (e.g. a URL scheme or trailing slash was removed). "+
+			"Fix them in the chart values so every consumer reads the same reference.")
+}
+
 // extractUniqueRegistries extracts a set of unique registry names from image info
 func extractUniqueRegistries(images []ImageInfo) map[string]bool {
 	registries := make(map[string]bool)
@@ -586,7 +913,7 @@ func outputRegistryConfigSuggestion(chartPath string, registries map[string]bool
 
 // inspectHelmRelease handles inspection when a release name is provided (plugin mode)
 func inspectHelmRelease(cmd *cobra.Command, flags *InspectFlags, releaseName, namespace string) error {
-	log.Debug("Running inspect in Helm plugin mode for release", "release", releaseName, "namespace", namespace)
+	log.Debug("Running inspect in Helm plugin mode for release", "release", releaseName, "namespace", namespace, "revision", flags.Revision)
 
 	helmAdapter, err := helmAdapterFactory() // Get adapter (potentially mocked)
 	if err != nil {
@@ -600,21 +927,47 @@ func inspectHelmRelease(cmd *cobra.Command, flags *InspectFlags, releaseName, na
 		}
 	}
 
+	analysisResult, err := analyzeReleaseRevision(getCommandContext(cmd), helmAdapter, flags, releaseName, namespace, flags.Revision)
+	if err != nil {
+		return err
+	}
+
+	// Compare against a second revision if requested
+	if flags.CompareRevision > 0 {
+		compareResult, err := analyzeReleaseRevision(getCommandContext(cmd), helmAdapter, flags, releaseName, namespace, flags.CompareRevision)
+		if err != nil {
+			return err
+		}
+		comparison := compareReleaseRevisions(analysisResult.Images, compareResult.Images)
+		comparison.ReleaseName = releaseName
+		comparison.Namespace = namespace
+		comparison.BaseRevision = flags.Revision
+		comparison.CompareRevision = flags.CompareRevision
+		return writeStructuredOutput(cmd, comparison, flags)
+	}
+
+	// Write output
+	return writeOutput(cmd, analysisResult, flags)
+}
+
+// analyzeReleaseRevision fetches the values and chart metadata for a release revision and analyzes them.
+// A revision of 0 selects the latest revision.
+func analyzeReleaseRevision(ctx context.Context, helmAdapter *helm.Adapter, flags *InspectFlags, releaseName, namespace string, revision int) (*ImageAnalysis, error) {
 	// Get release values
-	log.Debug("Getting values for release", "release", releaseName)
-	releaseValues, err := helmAdapter.GetReleaseValues(context.Background(), releaseName, namespace)
+	log.Debug("Getting values for release", "release", releaseName, "revision", revision)
+	releaseValues, err := helmAdapter.GetReleaseValuesAtRevision(ctx, releaseName, namespace, revision)
 	if err != nil {
-		return &exitcodes.ExitCodeError{ // Wrap error if needed
+		return nil, &exitcodes.ExitCodeError{ // Wrap error if needed
 			Code: exitcodes.ExitHelmCommandFailed,
 			Err:  fmt.Errorf("failed to get values for release %s: %w", releaseName, err),
 		}
 	}
 
 	// Get chart metadata from release (use this instead of loading from potentially non-existent path)
-	log.Debug("Getting chart metadata for release", releaseName)
-	chartMetadata, err := helmAdapter.GetChartFromRelease(context.Background(), releaseName, namespace)
+	log.Debug("Getting chart metadata for release", "release", releaseName, "revision", revision)
+	chartMetadata, err := helmAdapter.GetChartFromReleaseAtRevision(ctx, releaseName, namespace, revision)
 	if err != nil {
-		return &exitcodes.ExitCodeError{
+		return nil, &exitcodes.ExitCodeError{
 			Code: exitcodes.ExitHelmCommandFailed,
 			Err:  fmt.Errorf("failed to get chart info for release %s: %w", releaseName, err),
 		}
@@ -625,10 +978,14 @@ func inspectHelmRelease(cmd *cobra.Command, flags *InspectFlags, releaseName, na
 	// analyze the values obtained directly from the Helm release.
 
 	// Create a simplified ChartInfo based on available metadata
+	releasePath := fmt.Sprintf("helm-release://%s/%s", namespace, releaseName) // Indicate source
+	if revision > 0 {
+		releasePath = fmt.Sprintf("%s?revision=%d", releasePath, revision)
+	}
 	chartInfo := ChartInfo{
 		Name:    chartMetadata.Name,
 		Version: chartMetadata.Version,
-		Path:    fmt.Sprintf("helm-release://%s/%s", namespace, releaseName), // Indicate source
+		Path:    releasePath,
 		// Dependencies count might not be available without loading the chart files
 	}
 
@@ -636,7 +993,7 @@ func inspectHelmRelease(cmd *cobra.Command, flags *InspectFlags, releaseName, na
 	log.Debug("Analyzing release values...")
 	analysisPatterns, analysisErr := analyzer.AnalyzeHelmValues(releaseValues, flags.AnalyzerConfig)
 	if analysisErr != nil {
-		return &exitcodes.ExitCodeError{
+		return nil, &exitcodes.ExitCodeError{
 			Code: exitcodes.ExitChartProcessingFailed,
 			Err:  fmt.Errorf("release values analysis failed: %w", analysisErr),
 		}
@@ -672,9 +1029,152 @@ func inspectHelmRelease(cmd *cobra.Command, flags *InspectFlags, releaseName, na
 		analysisResult.Images = filteredImages
 		log.Info("Filtered images to", len(flags.SourceRegistries), "registries")
 	}
+	applyInspectSelectors(flags, analysisResult, releaseName)
+	applyOnlyUnmapped(flags, analysisResult)
 
-	// Write output
-	return writeOutput(cmd, analysisResult, flags)
+	return analysisResult, nil
+}
+
+// imageInfoReference formats an ImageInfo as a registry/repository[:tag][@digest] string.
+func imageInfoReference(img ImageInfo) string {
+	ref := img.Repository
+	if img.Registry != "" {
+		ref = img.Registry + "/" + ref
+	}
+	if img.Tag != "" {
+		ref += ":" + img.Tag
+	}
+	if img.Digest != "" {
+		ref += "@" + img.Digest
+	}
+	return ref
+}
+
+// imageInfoPath returns the values path used to match images across revisions.
+func imageInfoPath(img ImageInfo) string {
+	if img.ValuePath != "" {
+		return img.ValuePath
+	}
+	return img.Source
+}
+
+// findDuplicateImages groups images by reference and returns those found at more than one values path,
+// ordered by reference.
+func findDuplicateImages(images []ImageInfo) []DuplicateImage {
+	pathsByRef := make(map[string][]string)
+	for _, img := range images {
+		ref := imageInfoReference(img)
+		path := imageInfoPath(img)
+		if !slices.Contains(pathsByRef[ref], path) {
+			pathsByRef[ref] = append(pathsByRef[ref], path)
+		}
+	}
+
+	var duplicates []DuplicateImage
+	for _, ref := range slices.Sorted(maps.Keys(pathsByRef)) {
+		paths := pathsByRef[ref]
+		if len(paths) < 2 {
+			continue
+		}
+		sort.Strings(paths)
+		duplicates = append(duplicates, DuplicateImage{Image: ref, Count: len(paths), Paths: paths})
+	}
+	return duplicates
+}
+
+// logDuplicateImages summarizes duplicated images and suggests a global override for them.
+func logDuplicateImages(duplicates []DuplicateImage) {
+	if len(duplicates) == 0 {
+		log.Info("No images are referenced at more than one values path.")
+		return
+	}
+	log.Info("Found images referenced at more than one values path:", "count", len(duplicates))
+	for _, dup := range duplicates {
+		log.Info(fmt.Sprintf("  - %s (%d paths)", dup.Image, dup.Count))
+	}
+	log.Info("Consider a global override (e.g. global.imageRegistry) instead of overriding each path.")
+}
+
+// compareReleaseRevisions diffs the images found in two release revisions, matching them by values path.
+func compareReleaseRevisions(baseImages, compareImages []ImageInfo) *RevisionComparison {
+	comparison := &RevisionComparison{}
+
+	baseByPath := make(map[string]ImageInfo, len(baseImages))
+	for _, img := range baseImages {
+		baseByPath[imageInfoPath(img)] = img
+	}
+	compareByPath := make(map[string]ImageInfo, len(compareImages))
+	for _, img := range compareImages {
+		compareByPath[imageInfoPath(img)] = img
+	}
+
+	for _, img := range baseImages {
+		path := imageInfoPath(img)
+		other, ok := compareByPath[path]
+		switch {
+		case !ok:
+			comparison.Removed = append(comparison.Removed, img)
+		case imageInfoReference(img) != imageInfoReference(other):
+			comparison.Changed = append(comparison.Changed, ImageChange{
+				Path: path,
+				From: imageInfoReference(img),
+				To:   imageInfoReference(other),
+			})
+		default:
+			comparison.Unchanged = append(comparison.Unchanged, img)
+		}
+	}
+	for _, img := range compareImages {
+		if _, ok := baseByPath[imageInfoPath(img)]; !ok {
+			comparison.Added = append(comparison.Added, img)
+		}
+	}
+
+	return comparison
+}
+
+// writeStructuredOutput marshals data in the requested output format and writes it to a file or stdout
+func writeStructuredOutput(cmd *cobra.Command, data interface{}, flags *InspectFlags) error {
+	var output []byte
+	var err error
+
+	switch strings.ToLower(flags.OutputFormat) {
+	case outputFormatJSON:
+		output, err = json.Marshal(data)
+		if err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitGeneralRuntimeError,
+				Err:  fmt.Errorf("failed to marshal output to JSON: %w", err),
+			}
+		}
+	default:
+		output, err = yaml.Marshal(data)
+		if err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitGeneralRuntimeError,
+				Err:  fmt.Errorf("failed to marshal output to YAML: %w", err),
+			}
+		}
+	}
+
+	if flags.OutputFile != "" {
+		if err := afero.WriteFile(AppFs, flags.OutputFile, output, fileutil.ReadWriteUserPermission); err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to write output to file: %w", err),
+			}
+		}
+		log.Info("Output written to", flags.OutputFile)
+		return nil
+	}
+
+	if _, err := fmt.Fprintln(cmd.OutOrStdout(), string(output)); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write output to stdout: %w", err),
+		}
+	}
+	return nil
 }
 
 // getInspectFlags retrieves and validates flags for the inspect command
@@ -726,6 +1226,16 @@ func getInspectFlags(cmd *cobra.Command, releaseNameProvided bool) (*InspectFlag
 		}
 	}
 
+	flags.AnalysisMode, err = getAnalysisMode(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	flags.SkeletonGroups, err = getSkeletonGroups(cmd)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get overwrite-skeleton flag
 	flags.OverwriteSkeleton, err = cmd.Flags().GetBool("overwrite-skeleton")
 	if err != nil {
@@ -744,6 +1254,58 @@ func getInspectFlags(cmd *cobra.Command, releaseNameProvided bool) (*InspectFlag
 		}
 	}
 
+	// Get duplicates flag
+	flags.Duplicates, err = cmd.Flags().GetBool("duplicates")
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get duplicates flag: %w", err),
+		}
+	}
+
+	flags.ScanCRDs, err = cmd.Flags().GetBool("scan-crds")
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get scan-crds flag: %w", err),
+		}
+	}
+
+	flags.TagChecker, err = getTagChecker(cmd)
+	if err != nil {
+		return nil, err
+	}
+	flags.SignatureChecker, err = getSignatureChecker(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	flags.Selectors, err = getSelectors(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get only-unmapped flag and load the mappings it filters against
+	flags.OnlyUnmapped, err = cmd.Flags().GetBool("only-unmapped")
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get only-unmapped flag: %w", err),
+		}
+	}
+	flags.RegistryFiles, err = cmd.Flags().GetStringSlice("registry-file")
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get registry-file flag: %w", err),
+		}
+	}
+	if flags.OnlyUnmapped {
+		if flags.Mappings, err = loadVerifyMappings(flags.RegistryFiles); err != nil {
+			return nil, err
+		}
+	}
+
 	// Get all-namespaces flag
 	flags.AllNamespaces, err = cmd.Flags().GetBool("all-namespaces")
 	if err != nil {
@@ -777,63 +1339,65 @@ func getInspectFlags(cmd *cobra.Command, releaseNameProvided bool) (*InspectFlag
 			}
 		}
 	}
+	flags.ReleaseFilter, err = getReleaseFilter(cmd, flags.AllNamespaces)
+	if err != nil {
+		return nil, err
+	}
 
-	// Validate output file path now to avoid later issues
-	if flags.OutputFile != "" {
-		// Check if directory exists
-		outDir := filepath.Dir(flags.OutputFile)
-		if stat, err := os.Stat(outDir); err != nil || !stat.IsDir() {
-			return nil, &exitcodes.ExitCodeError{
-				Code: exitcodes.ExitIOError,
-				Err:  fmt.Errorf("output directory %q does not exist or is not a directory", outDir),
-			}
+	// Get revision flags
+	flags.Revision, err = cmd.Flags().GetInt("revision")
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get revision flag: %w", err),
+		}
+	}
+	flags.CompareRevision, err = cmd.Flags().GetInt("compare-revision")
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get compare-revision flag: %w", err),
+		}
+	}
+	if err := validateRevisionFlags(flags, releaseNameProvided); err != nil {
+		return nil, err
+	}
+	flags.GroupBy, err = getGroupBy(cmd, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get chart verification flags
+	flags.Verify, flags.VerifyOptions, err = getChartVerifyFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if flags.Verify && (releaseNameProvided || flags.AllNamespaces) {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--verify requires --chart-path pointing to a packaged chart and cannot be used with a release name or --all-namespaces"),
 		}
+	}
 
-		// Check if output file is writable (or can be created)
-		// Case 1: File exists - check if we can write to it
-		if stat, err := os.Stat(flags.OutputFile); err == nil {
-			if flags.GenerateConfigSkeleton && !flags.OverwriteSkeleton {
+	// Get chart dependency download flags
+	flags.Dependencies, err = getDependencyOptions(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate output file path now to avoid later issues
+	if flags.OutputFile != "" {
+		flags.OutputFile = fileutil.CleanPath(flags.OutputFile)
+		if flags.GenerateConfigSkeleton && !flags.OverwriteSkeleton {
+			if exists, _ := afero.Exists(AppFs, flags.OutputFile); exists {
 				return nil, &exitcodes.ExitCodeError{
 					Code: exitcodes.ExitIOError,
 					Err:  fmt.Errorf("skeleton file %q already exists; use --overwrite-skeleton to overwrite", flags.OutputFile),
 				}
 			}
-			// Check if it's a regular file
-			if !stat.Mode().IsRegular() {
-				return nil, &exitcodes.ExitCodeError{
-					Code: exitcodes.ExitIOError,
-					Err:  fmt.Errorf("output path %q exists but is not a regular file", flags.OutputFile),
-				}
-			}
-			// Check write permission (attempt to open for writing)
-			f, err := os.OpenFile(flags.OutputFile, os.O_WRONLY, 0)
-			if err != nil {
-				return nil, &exitcodes.ExitCodeError{
-					Code: exitcodes.ExitIOError,
-					Err:  fmt.Errorf("cannot write to output file %q: %w", flags.OutputFile, err),
-				}
-			}
-			if err := f.Close(); err != nil {
-				log.Warn("Error closing file after permission check", "error", err)
-			}
-		}
-		// Case 2: File doesn't exist - check if we can create it
-		// Attempt to create and then remove the file
-		f, err := os.OpenFile(flags.OutputFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fileutil.ReadWriteUserReadOthers)
-		if err != nil {
-			return nil, &exitcodes.ExitCodeError{
-				Code: exitcodes.ExitIOError,
-				Err:  fmt.Errorf("cannot create output file %q: %w", flags.OutputFile, err),
-			}
 		}
-		if err := f.Close(); err != nil {
-			log.Warn("Error closing temporary file", "error", err)
-		}
-		// Only remove the file if it didn't exist before
-		if _, err := os.Stat(flags.OutputFile); err == nil {
-			if err := os.Remove(flags.OutputFile); err != nil {
-				log.Warn("Failed to remove temporary file", "path", flags.OutputFile, "error", err)
-			}
+		if err := validateOutputFilePath(AppFs, flags.OutputFile); err != nil {
+			return nil, err
 		}
 	}
 
@@ -866,6 +1430,39 @@ func getInspectFlags(cmd *cobra.Command, releaseNameProvided bool) (*InspectFlag
 	return flags, nil
 }
 
+// validateRevisionFlags checks that --revision and --compare-revision are used with a release name
+// and are compatible with the other inspect flags.
+func validateRevisionFlags(flags *InspectFlags, releaseNameProvided bool) error {
+	if flags.Revision < 0 || flags.CompareRevision < 0 {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--revision and --compare-revision must be positive revision numbers"),
+		}
+	}
+	if flags.Revision == 0 && flags.CompareRevision == 0 {
+		return nil
+	}
+	if !releaseNameProvided {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--revision and --compare-revision require a release name"),
+		}
+	}
+	if flags.CompareRevision > 0 && flags.GenerateConfigSkeleton {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--compare-revision cannot be used with --generate-config-skeleton"),
+		}
+	}
+	if flags.CompareRevision > 0 && flags.CompareRevision == flags.Revision {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("--compare-revision must differ from --revision (both are %d)", flags.Revision),
+		}
+	}
+	return nil
+}
+
 // getAnalysisPatterns retrieves include/exclude patterns from flags
 func getAnalysisPatterns(cmd *cobra.Command) (includePatterns, excludePatterns []string, err error) {
 	// Get include patterns
@@ -932,7 +1529,7 @@ func processImagePatterns(patterns []analysis.ImagePattern) (images []ImageInfo,
 		case analysis.PatternTypeMap:
 			if p.Structure == nil {
 				log.Warn("Skipping map pattern with nil structure", "path", p.Path, "value", p.Value)
-				skipped = append(skipped, fmt.Sprintf("%s: %v (map type with nil structure)", p.Path, p.Value))
+				skipped = append(skipped, fmt.Sprintf("%s: %v (map type with nil structure)", patternLabel(p), p.Value))
 				continue
 			}
 			// For map types, use the pre-parsed structure directly
@@ -961,7 +1558,7 @@ func processImagePatterns(patterns []analysis.ImagePattern) (images []ImageInfo,
 			if err != nil {
 				log.Warn("Skipping string pattern due to parse error", "path", p.Path, "value", p.Value, "error", err)
 
-				skipped = append(skipped, fmt.Sprintf("%s: %s (parse error: %v)", p.Path, p.Value, err))
+				skipped = append(skipped, fmt.Sprintf("%s: %s (parse error: %v)", patternLabel(p), p.Value, err))
 				continue
 			}
 
@@ -977,7 +1574,7 @@ func processImagePatterns(patterns []analysis.ImagePattern) (images []ImageInfo,
 		default:
 			// Skip other types or maps without structure
 			log.Warn("Skipping pattern with unhandled type", "path", p.Path, "type", p.Type, "value", p.Value)
-			skipped = append(skipped, fmt.Sprintf("%s: %s (unhandled type: %s)", p.Path, p.Value, p.Type))
+			skipped = append(skipped, fmt.Sprintf("%s: %s (unhandled type: %s)", patternLabel(p), p.Value, p.Type))
 			continue
 		}
 
@@ -985,6 +1582,17 @@ func processImagePatterns(patterns []analysis.ImagePattern) (images []ImageInfo,
 		if p.OriginalRegistry != "" {
 			imgInfo.OriginalRegistry = p.OriginalRegistry
 		}
+		imgInfo.AnchorSource = p.AnchorSource
+		if p.Location != nil {
+			imgInfo.Location = p.Location.String()
+		}
+		imgInfo.Lint = lintImagePattern(p)
+		if p.Subchart != nil {
+			imgInfo.Subchart = strings.ReplaceAll(p.Subchart.ValuesPath, ".", "/")
+			imgInfo.SubchartChart = p.Subchart.Name
+			imgInfo.SubchartDepth = p.Subchart.Depth
+			imgInfo.DisabledBy = p.Subchart.DisabledBy
+		}
 
 		// Only add if we have a valid repository
 		if imgInfo.Repository != "" {
@@ -992,74 +1600,42 @@ func processImagePatterns(patterns []analysis.ImagePattern) (images []ImageInfo,
 			images = append(images, imgInfo)
 		} else {
 			log.Warn("Skipping processed pattern due to empty repository", "path", p.Path, "type", p.Type, "value", p.Value)
-			skipped = append(skipped, fmt.Sprintf("%s: %s (empty repository after processing)", p.Path, p.Value))
+			skipped = append(skipped, fmt.Sprintf("%s: %s (empty repository after processing)", patternLabel(p), p.Value))
 		}
 	}
 	return images, skipped
 }
 
-// detectChartInCurrentDirectory first checks the given start directory ("."), then searches upwards within the provided filesystem for a Chart.yaml file.
-// It returns the absolute path (relative to fs root) to the chart directory and a matching relative path,
-// or an error if not found.
+// detectChartInCurrentDirectory looks for a Chart.yaml in the current directory, then in each parent
+// directory up to the root of the volume (/, a drive root such as C:\ or a UNC share). A chart in the
+// current directory is returned as "." for both paths; a chart found above it is returned as an
+// absolute path and as a path relative to the current directory.
 func detectChartInCurrentDirectory(fs afero.Fs) (detectedAbsPath, detectedRelPath string, err error) {
-	startSearchDir := "."
-	log.Debug("detectChartInCurrentDirectory: Start", "fs_root_relative_start", startSearchDir)
+	log.Debug("detectChartInCurrentDirectory: Start")
 
-	// 1. Check the starting directory itself
-	startChartFilePath := filepath.Join(startSearchDir, chartutil.ChartfileName)
-	log.Debug("Checking for chart in start directory", "path", startChartFilePath)
-	exists, err := afero.Exists(fs, startChartFilePath)
+	exists, err := afero.Exists(fs, chartutil.ChartfileName)
 	if err != nil {
-		log.Debug("Error checking for chart file existence in start dir (ignoring)", "path", startChartFilePath, "error", err)
+		log.Debug("Error checking for chart file existence in current directory (ignoring)", "error", err)
 	}
 	if exists {
-		cleanAbsPath := filepath.Clean(startSearchDir)
-		log.Debug("Chart found in start directory", "absolutePath", cleanAbsPath)
-		// Return the start directory path for both values when found immediately
-		return cleanAbsPath, cleanAbsPath, nil
+		log.Debug("Chart found in current directory")
+		return ".", ".", nil
 	}
-	log.Debug("Chart not found in start directory, searching upwards...")
 
-	// 2. Search upwards from the parent of the starting directory
-	currentDir := filepath.Dir(startSearchDir) // Start searching from parent
-	if currentDir == startSearchDir {          // Handle case where start is already root
-		currentDir = "." // Ensure we check root if needed
+	log.Debug("Chart not found in current directory, searching upwards...")
+	chartDir, err := fileutil.FindUpwards(fs, ".", chartutil.ChartfileName)
+	if err != nil {
+		return "", "", fmt.Errorf("no %s found in current directory or any parent directory: %w", chartutil.ChartfileName, err)
 	}
 
-	maxSearchDepth := 100 // Prevent infinite loops
-
-	for i := 0; i < maxSearchDepth; i++ {
-		// If currentDir is empty or invalid, stop
-		if currentDir == "" || currentDir == "/" || currentDir == "." && i > 0 { // Avoid redundant check of "." if we started there
-			log.Debug("Reached root or invalid directory while searching upwards", "currentDir", currentDir)
-			break
+	relPath := chartDir
+	if cwd, cwdErr := filepath.Abs("."); cwdErr == nil {
+		if rel, relErr := filepath.Rel(cwd, chartDir); relErr == nil {
+			relPath = rel
 		}
-
-		chartFilePath := filepath.Join(currentDir, chartutil.ChartfileName)
-		log.Debug("Checking for chart upwards", "path", chartFilePath, "iteration", i)
-
-		exists, err := afero.Exists(fs, chartFilePath)
-		if err != nil {
-			log.Debug("Error checking for chart file existence upwards (ignoring)", "path", chartFilePath, "error", err)
-		}
-
-		if exists {
-			cleanAbsPath := filepath.Clean(currentDir)
-			log.Debug("Chart found upwards", "absolutePath", cleanAbsPath)
-			// Return the found path for both values
-			return cleanAbsPath, cleanAbsPath, nil
-		}
-
-		parentDir := filepath.Dir(currentDir)
-		if parentDir == currentDir { // Termination check
-			log.Debug("Reached filesystem root while searching upwards", "currentDir", currentDir)
-			break
-		}
-		currentDir = parentDir
 	}
-
-	log.Debug("Chart not found searching upwards from fs root", "startDir", startSearchDir)
-	return "", "", fmt.Errorf("no %s found in current directory or searching upwards from the root of the provided filesystem", chartutil.ChartfileName)
+	log.Debug("Chart found upwards", "absolutePath", chartDir, "relativePath", relPath)
+	return chartDir, relPath, nil
 }
 
 // detectChartIfNeeded determines the chart path if not provided.
@@ -1069,7 +1645,7 @@ func detectChartIfNeeded(fs afero.Fs, inputChartPath string) (finalAbsPath, fina
 	if inputChartPath != "" {
 		log.Debug("detectChartIfNeeded: Chart path provided, skipping detection", "chartPath", inputChartPath)
 		// Return the input path and "." for relative path as detection was skipped.
-		return inputChartPath, ".", nil
+		return fileutil.CleanPath(inputChartPath), ".", nil
 	}
 
 	log.Debug("detectChartIfNeeded: No chart path provided, searching current directory.")
@@ -1082,8 +1658,9 @@ func detectChartIfNeeded(fs afero.Fs, inputChartPath string) (finalAbsPath, fina
 	return detectedPath, relativePath, nil
 }
 
-// createConfigSkeleton generates a registry mapping config skeleton
-func createConfigSkeleton(images []ImageInfo, outputFile string) error {
+// createConfigSkeleton generates a registry mapping config skeleton, routing the sources of groups
+// to their group's target
+func createConfigSkeleton(images []ImageInfo, groups []registry.RegGroup, outputFile string) error {
 	// Use default filename if none specified
 	if outputFile == "" {
 		outputFile = DefaultConfigSkeletonFilename
@@ -1101,40 +1678,14 @@ func createConfigSkeleton(images []ImageInfo, outputFile string) error {
 		}
 	}
 
-	// Extract unique registries from images
-	registries := make(map[string]bool)
-	for _, img := range images {
-		if img.Registry != "" {
-			registries[img.Registry] = true
-		}
-	}
-
-	// Sort registries for consistent output
-	var registryList []string
-	for registry := range registries {
-		registryList = append(registryList, registry)
-	}
-	sort.Strings(registryList)
-
-	// Create structured registry mappings
-	mappings := make([]registry.RegMapping, 0, len(registryList))
-	for _, reg := range registryList {
-		log.Debug("CREATE_SKELETON: Creating mapping entry", "source_registry_key", reg)
-		// Generate a sanitized target registry path
-		targetPath := strings.ReplaceAll(reg, ".", "-")
-		mappings = append(mappings, registry.RegMapping{
-			Source:      reg,
-			Target:      "registry.local/" + targetPath,
-			Description: fmt.Sprintf("Mapping for %s", reg),
-			Enabled:     true,
-		})
-	}
+	mappings, groups := skeletonRegistries(images, groups)
 
 	// Create config structure using the registry package format
 	config := registry.Config{
 		Version: registry.DefaultConfigVersion,
 		Registries: registry.RegConfig{
 			Mappings:      mappings,
+			Groups:        groups,
 			DefaultTarget: "registry.local/default", // Example default target
 			StrictMode:    false,                    // Default to false for better usability
 		},
@@ -1169,6 +1720,9 @@ func createConfigSkeleton(images []ImageInfo, outputFile string) error {
 # - When using Harbor as a pull-through cache, ensure your target paths
 #   match your Harbor project configuration
 # - You can set or update mappings using 'irr config --source <reg> --target <path>'
+# - Registries listed under 'groups' all relocate to their group's target; use
+#   groups (or 'irr inspect --skeleton-group') to send different source registries
+#   to different target registries
 # - This file was auto-generated from detected registries in your chart
 #
 %s`, string(configYAML))
@@ -1190,8 +1744,9 @@ func createConfigSkeleton(images []ImageInfo, outputFile string) error {
 	return nil
 }
 
-// getAllReleases returns all Helm releases across all namespaces
-func getAllReleases() ([]*helm.ReleaseElement, *helm.Adapter, error) {
+// getAllReleases returns the Helm releases across all namespaces that match the filter. The label
+// selector (and the limit, when nothing else is filtered) is applied by Helm while listing.
+func getAllReleases(ctx context.Context, filter *ReleaseFilter) ([]*helm.ReleaseElement, *helm.Adapter, error) {
 	// Create a Helm adapter for interacting with the cluster
 	helmAdapter, err := helmAdapterFactory()
 	if err != nil {
@@ -1206,46 +1761,52 @@ func getAllReleases() ([]*helm.ReleaseElement, *helm.Adapter, error) {
 	}
 
 	// List all releases across all namespaces
-	client, err := createHelmClient()
-	if err != nil {
-		return nil, helmAdapter, &exitcodes.ExitCodeError{
-			Code: exitcodes.ExitHelmCommandFailed,
-			Err:  fmt.Errorf("failed to create Helm client: %w", err),
-		}
-	}
-
 	log.Debug("Listing all Helm releases across all namespaces")
-	releases, err := client.ListReleases(context.Background(), true)
+	var releases []*helm.ReleaseElement
+	listOpts := filter.listOptions()
+	if listOpts.Selector == "" && listOpts.Limit == 0 {
+		releases, err = helmAdapter.ListReleases(ctx, true)
+	} else {
+		log.Debug("Filtering releases while listing", "selector", listOpts.Selector, "limit", listOpts.Limit)
+		releases, err = helmAdapter.ListReleasesWithOptions(ctx, listOpts)
+	}
 	if err != nil {
 		return nil, helmAdapter, &exitcodes.ExitCodeError{
 			Code: exitcodes.ExitHelmCommandFailed,
-			Err:  fmt.Errorf("failed to list Helm releases: %w", err),
+			Err:  err,
 		}
 	}
 
-	log.Debug("Processing release", "name", releases[0].Name, "namespace", releases[0].Namespace)
-
 	if len(releases) == 0 {
 		log.Warn("No Helm releases found across all namespaces.")
-	} else {
-		log.Info(fmt.Sprintf("Found %d releases across all namespaces", len(releases)))
+		return releases, helmAdapter, nil
+	}
+	log.Info(fmt.Sprintf("Found %d releases across all namespaces", len(releases)))
+
+	if filter.isSet() {
+		releases = filter.apply(releases)
+		if len(releases) == 0 {
+			log.Warn("No Helm releases match the release filters.")
+		} else {
+			log.Info(fmt.Sprintf("Inspecting %d releases matching the release filters", len(releases)))
+		}
 	}
 
 	return releases, helmAdapter, nil
 }
 
 // analyzeRelease analyzes a single Helm release and returns the analysis result and the original unfiltered images
-func analyzeRelease(release *helm.ReleaseElement, helmAdapter *helm.Adapter, flags *InspectFlags) (*ReleaseAnalysisResult, []ImageInfo, error) {
+func analyzeRelease(ctx context.Context, release *helm.ReleaseElement, helmAdapter *helm.Adapter, flags *InspectFlags) (*ReleaseAnalysisResult, []ImageInfo, error) {
 	log.Info("Analyzing release", "name", release.Name, "namespace", release.Namespace)
 
 	// Get release values
-	releaseValues, err := helmAdapter.GetReleaseValues(context.Background(), release.Name, release.Namespace)
+	releaseValues, err := helmAdapter.GetReleaseValues(ctx, release.Name, release.Namespace)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get values for release %s/%s: %w", release.Namespace, release.Name, err)
 	}
 
 	// Get chart metadata
-	chartMetadata, err := helmAdapter.GetChartFromRelease(context.Background(), release.Name, release.Namespace)
+	chartMetadata, err := helmAdapter.GetChartFromRelease(ctx, release.Name, release.Namespace)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get chart info for release %s/%s: %w", release.Namespace, release.Name, err)
 	}
@@ -1323,6 +1884,12 @@ func analyzeRelease(release *helm.ReleaseElement, helmAdapter *helm.Adapter, fla
 		// Update the analysis.Images field ONLY for the output result
 		analysisResult.Images = filteredImagesForOutput
 	}
+	applyInspectSelectors(flags, &analysisResult, release.Name)
+	applyOnlyUnmapped(flags, &analysisResult)
+
+	if flags.Duplicates {
+		analysisResult.Duplicates = findDuplicateImages(analysisResult.Images)
+	}
 
 	// Return the potentially filtered analysis result AND the original unfiltered images
 	return &ReleaseAnalysisResult{
@@ -1355,7 +1922,8 @@ func isValidRegistryHostname(hostname string) bool {
 }
 
 // processAllReleases iterates through all releases, analyzes them, and aggregates results.
-func processAllReleases(releases []*helm.ReleaseElement, helmAdapter *helm.Adapter, flags *InspectFlags) ([]*ReleaseAnalysisResult, []string, []ImageInfo, error) {
+// It stops with an interrupted error once ctx is canceled.
+func processAllReleases(ctx context.Context, releases []*helm.ReleaseElement, helmAdapter *helm.Adapter, flags *InspectFlags) ([]*ReleaseAnalysisResult, []string, []ImageInfo, error) {
 	// Initialize return values
 	var allResults []*ReleaseAnalysisResult
 	var skippedReleases []string
@@ -1364,10 +1932,17 @@ func processAllReleases(releases []*helm.ReleaseElement, helmAdapter *helm.Adapt
 	// Track unique registries for skeleton generation
 	uniqueRegistries := make(map[string]bool)
 
+	bar := progress.Start(os.Stderr, "Inspecting releases", len(releases))
+	defer bar.Finish()
+
 	// Process each release
 	for _, release := range releases {
+		if err := checkInterrupted(ctx); err != nil {
+			return nil, nil, nil, err
+		}
 		// Analyze the release
-		result, unfilteredImages, err := analyzeRelease(release, helmAdapter, flags)
+		result, unfilteredImages, err := analyzeRelease(ctx, release, helmAdapter, flags)
+		bar.Add(1)
 		if err != nil {
 			log.Error("Error analyzing release", "release", release.Name, "namespace", release.Namespace, "error", err)
 			skippedReleases = append(skippedReleases, fmt.Sprintf("%s/%s: %v", release.Namespace, release.Name, err))
@@ -1417,7 +1992,7 @@ func processAllReleases(releases []*helm.ReleaseElement, helmAdapter *helm.Adapt
 
 	// Create ImageInfo slice specifically for skeleton generation from VALIDATED registries
 	var skeletonImages []ImageInfo
-	for registry := range validatedRegistries { // Iterate the FILTERED map
+	for _, registry := range slices.Sorted(maps.Keys(validatedRegistries)) { // Iterate the FILTERED map
 		skeletonImages = append(skeletonImages, ImageInfo{
 			Registry: registry, // Use the validated registry key
 		})
@@ -1435,10 +2010,19 @@ func outputMultiReleaseAnalysis(cmd *cobra.Command, results []*ReleaseAnalysisRe
 		Skipped  []string                 `json:"skipped,omitempty" yaml:"skipped,omitempty"`
 	}
 
-	combinedResult := CombinedAnalysisResult{
+	sortReleaseResults(results)
+	slices.Sort(skipped)
+	var combinedResult interface{} = CombinedAnalysisResult{
 		Releases: results,
 		Skipped:  skipped,
 	}
+	for _, result := range results {
+		checkTagFreshness(cmd.Context(), flags.TagChecker, result.Analysis.Images)
+		checkImageSignatures(cmd.Context(), flags.SignatureChecker, result.Analysis.Images)
+	}
+	if flags.GroupBy != "" {
+		combinedResult = groupReleaseResults(flags.GroupBy, results, skipped)
+	}
 
 	// Determine output format (yaml or json)
 	var output []byte
@@ -1500,13 +2084,17 @@ func inspectAllNamespaces(cmd *cobra.Command, flags *InspectFlags) error {
 	log.Info("Inspecting all Helm releases across all namespaces...")
 
 	// Get all releases
-	releases, helmAdapter, err := getAllReleases()
+	releases, helmAdapter, err := getAllReleases(getCommandContext(cmd), flags.ReleaseFilter)
 	if err != nil {
 		return err
 	}
 
 	// Process all releases
-	results, skippedReleases, skeletonImages, err := processAllReleases(releases, helmAdapter, flags)
+	results, skippedReleases, skeletonImages, err := processAllReleases(getCommandContext(cmd), releases, helmAdapter, flags)
+	helmAdapter.LogRetryStats()
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
 	if err != nil && !flags.GenerateConfigSkeleton {
 		return &exitcodes.ExitCodeError{
 			Code: exitcodes.ExitChartProcessingFailed,
@@ -1549,7 +2137,7 @@ func inspectAllNamespaces(cmd *cobra.Command, flags *InspectFlags) error {
 			}
 		}
 
-		if err := createConfigSkeleton(skeletonImages, skeletonFile); err != nil {
+		if err := createConfigSkeleton(skeletonImages, flags.SkeletonGroups, skeletonFile); err != nil {
 			return &exitcodes.ExitCodeError{
 				Code: exitcodes.ExitIOError,
 				Err:  fmt.Errorf("failed to create config skeleton: %w", err),
@@ -1565,98 +2153,21 @@ func inspectAllNamespaces(cmd *cobra.Command, flags *InspectFlags) error {
 }
 
 // checkSubchartDiscrepancy checks for discrepancies between the analyzer's image count
-// and the images found in rendered chart templates (specifically from Deployments and StatefulSets).
+// and the images found in the workloads of the rendered chart templates.
 // It returns an error only for fatal issues like chart loading errors, not for discrepancies.
 func checkSubchartDiscrepancy(cmd *cobra.Command, chartPath string, analysisResult *ImageAnalysis) error {
 	log.Debug("Checking for subchart image discrepancies")
 
-	// Get values files from command line
-	valueOpts := &values.Options{}
-	valuesFiles, err := cmd.Flags().GetStringSlice("values")
-	if err != nil {
-		return fmt.Errorf("failed to get values files: %w", err)
-	}
-	valueOpts.ValueFiles = valuesFiles
-
-	// Load the chart
-	loadedChart, err := loader.Load(chartPath)
-	if err != nil {
-		return fmt.Errorf("failed to load chart for subchart check: %w", err)
-	}
-
-	// Read values from files
-	vals := map[string]interface{}{}
-	for _, valueFile := range valueOpts.ValueFiles {
-		currentValues, err := chartutil.ReadValuesFile(valueFile)
-		if err != nil {
-			return fmt.Errorf("failed to read values file %s: %w", valueFile, err)
-		}
-		// Merge with existing values
-		vals = chartutil.CoalesceTables(vals, currentValues.AsMap())
-	}
-
-	// Merge with chart's default values
-	vals = chartutil.CoalesceTables(loadedChart.Values, vals)
-
-	// Render chart templates
-	actionConfig := new(action.Configuration)
-	installAction := action.NewInstall(actionConfig)
-	installAction.DryRun = true
-	installAction.ReleaseName = "irr-subchart-check"
-	installAction.Namespace = validateTestNamespace
-	installAction.ClientOnly = true
-
-	// Render the templates
-	release, err := installAction.Run(loadedChart, vals)
+	_, manifest, err := renderChartManifest(cmd, chartPath, "irr-subchart-check")
 	if err != nil {
-		log.Warn("Failed to render chart templates for subchart check, skipping", "chart", loadedChart.Name(), "error", err)
+		log.Warn("Failed to render chart templates for subchart check, skipping", "chart", chartPath, "error", err)
 		return nil // Return nil to indicate non-fatal error for this check
 	}
 
-	// Check if the release object itself is nil (can happen in dry-run)
-	if release == nil {
-		log.Warn("Chart rendering resulted in a nil release object, skipping subchart check", "chart", loadedChart.Name())
-		return nil
-	}
-
-	// Add check for empty manifest before processing
-	if release.Manifest == "" {
-		log.Warn("Rendered release has an empty manifest, skipping subchart discrepancy check", "chart", loadedChart.Name())
-		return nil
-	}
-
 	// Extract images from rendered templates
 	templateImages := make(map[string]struct{})
-	manifests := release.Manifest
-
-	// Split manifests into separate YAML documents
-	decoder := yaml.NewDecoder(strings.NewReader(manifests))
-	for {
-		var doc map[string]interface{}
-		err := decoder.Decode(&doc)
-		if err != nil {
-			// If we've reached the end of the documents, break
-			if err.Error() == "EOF" {
-				break
-			}
-			// Log parsing errors as warnings but continue with other documents
-			log.Warn("Error parsing rendered template document: %s", err)
-			continue
-		}
-
-		// Skip empty documents
-		if len(doc) == 0 {
-			continue
-		}
-
-		// Check if this is a Deployment or StatefulSet
-		kind, ok := doc["kind"].(string)
-		if !ok || (kind != "Deployment" && kind != "StatefulSet") {
-			continue
-		}
-
-		// Extract images using safe traversal
-		extractImagesFromResource(doc, templateImages)
+	for _, rendered := range extractWorkloadImages(manifest) {
+		templateImages[rendered.Image] = struct{}{}
 	}
 
 	// Compare image counts
@@ -1678,61 +2189,12 @@ func checkSubchartDiscrepancy(cmd *cobra.Command, chartPath string, analysisResu
 			"template_image_count", templateImageCount,
 			"message", "The analyzer found different number of images than the rendered templates. "+
 				"This may indicate images defined in subchart default values that were not detected. "+
-				"Consider using the --no-subchart-check flag to skip this check.")
+				"Use --analysis-mode cross-check to list them, or the --no-subchart-check flag to skip this check.")
 	}
 
 	return nil
 }
 
-// extractImagesFromResource safely extracts image references from a Kubernetes resource.
-// It traverses the resource structure to find container image fields in pods.
-func extractImagesFromResource(resource map[string]interface{}, images map[string]struct{}) {
-	// Try to get to spec.template.spec for pod template
-	spec, ok := resource["spec"].(map[string]interface{})
-	if !ok {
-		return
-	}
-
-	template, ok := spec["template"].(map[string]interface{})
-	if !ok {
-		return
-	}
-
-	podSpec, ok := template["spec"].(map[string]interface{})
-	if !ok {
-		return
-	}
-
-	// Extract images from containers
-	extractImagesFromContainers(podSpec, "containers", images)
-
-	// Extract images from initContainers
-	extractImagesFromContainers(podSpec, "initContainers", images)
-}
-
-// extractImagesFromContainers extracts image references from container lists
-func extractImagesFromContainers(podSpec map[string]interface{}, containerType string, images map[string]struct{}) {
-	containers, ok := podSpec[containerType].([]interface{})
-	if !ok {
-		return
-	}
-
-	for _, c := range containers {
-		container, ok := c.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		imageValue, ok := container["image"].(string)
-		if !ok || imageValue == "" {
-			continue
-		}
-
-		// Add image to the set
-		images[imageValue] = struct{}{}
-	}
-}
-
 // Helper to convert *analyzer.ImageStructure to map[string]interface{}
 func analyzerImageStructureToMap(s *analyzer.ImageStructure) map[string]interface{} {
 	if s == nil {
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// scanCRDImages returns the images referenced in the crds/ files of loadedChart (--scan-crds) and
+// warns about them: Helm installs CRDs without templating, so values overrides cannot relocate
+// these images and they have to be mirrored as they are.
+func scanCRDImages(loadedChart *helmchart.Chart) []analysis.CRDImage {
+	images, err := analysis.ScanCRDImages(loadedChart)
+	if err != nil {
+		log.Warn("Some CRD files could not be scanned for images", "error", err)
+	}
+	if len(images) == 0 {
+		log.Info("No image references found in the chart's CRDs")
+		return nil
+	}
+	log.Warn("Found image references in the chart's CRDs; values overrides cannot change them, so mirror these images to the target registry unchanged", "count", len(images))
+	for _, img := range images {
+		log.Info("  CRD image", "image", img.Image, "file", img.File, "path", img.Path)
+	}
+	return images
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+func TestScanCRDImages(t *testing.T) {
+	loadedChart := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "operator"},
+		Files: []*helmchart.File{{Name: "crds/apps.yaml", Data: []byte(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: apps.example.com
+spec:
+  versions:
+    - name: v1
+      schema:
+        openAPIV3Schema:
+          properties:
+            image:
+              default: quay.io/example/app:v1.2.0
+`)}},
+	}
+
+	images := scanCRDImages(loadedChart)
+	require.Len(t, images, 1)
+	assert.Equal(t, "operator/crds/apps.yaml", images[0].File)
+	assert.Equal(t, "apps.example.com", images[0].CRD)
+	assert.Equal(t, "quay.io/example/app:v1.2.0", images[0].Image)
+
+	assert.Nil(t, scanCRDImages(&helmchart.Chart{Metadata: &helmchart.Metadata{Name: "plain"}}))
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/progress"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/cobra"
+)
+
+// tagCheckWorkers bounds the images checked concurrently by --check-tags.
+const tagCheckWorkers = 8
+
+// newTagChecker creates the checker used by --check-tags, sending its requests through transport.
+// It can be replaced in tests.
+var newTagChecker = func(credentials map[string]registry.Credentials, staleAfter time.Duration, transport http.RoundTripper) *registry.TagChecker {
+	checker := registry.NewTagChecker(credentials, staleAfter)
+	checker.Client.Transport = transport
+	checker.Helpers = registryCredentialHelpers()
+	return checker
+}
+
+// addTagFreshnessFlags adds the flags that query source registries for the freshness of image tags.
+func addTagFreshnessFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("check-tags", false, "Query the source registry of each image and report whether its tag still exists, whether a newer semver tag is available and when it was last pushed")
+	cmd.Flags().Duration("stale-after", registry.DefaultStaleAfter, "With --check-tags, report tags last pushed longer ago than this as stale")
+}
+
+// getTagChecker returns the checker for --check-tags, or nil when the flag is not set. It loads
+// the registry credentials from the Helm registry config and the Docker config, and the TLS
+// settings of registries from the mappings files given with --registry-file.
+func getTagChecker(cmd *cobra.Command) (*registry.TagChecker, error) {
+	check, err := getBoolFlag(cmd, "check-tags")
+	if err != nil || !check {
+		return nil, err
+	}
+	staleAfter, err := cmd.Flags().GetDuration("stale-after")
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	if err := requireNetwork("checking image tags in source registries (--check-tags)"); err != nil {
+		return nil, err
+	}
+	credentials, transport, err := sourceRegistryAccess(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return newTagChecker(credentials, staleAfter, transport), nil
+}
+
+// sourceRegistryAccess loads the credentials of source registries from the Helm registry config
+// and the Docker config, and returns them with the transport for their requests, configured with
+// the TLS settings of registries from the mappings files given with --registry-file.
+func sourceRegistryAccess(cmd *cobra.Command) (map[string]registry.Credentials, http.RoundTripper, error) {
+	credentialFiles, err := registryCredentialFiles(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	credentials, err := registry.LoadCredentials(AppFs, credentialFiles...)
+	if err != nil {
+		return nil, nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	registryTLS, err := registryFilesTLS(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	transport, err := newNetworkTransport(registryTLS)
+	if err != nil {
+		return nil, nil, err
+	}
+	return credentials, transport, nil
+}
+
+// checkTagFreshness records the freshness of the tag of each image in images, querying each
+// distinct image once, and warns about missing, outdated and stale tags. Images without a tag
+// are pinned by digest and are not checked. Registry errors are recorded, not returned.
+func checkTagFreshness(ctx context.Context, checker *registry.TagChecker, images []ImageInfo) {
+	if checker == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	type tagKey struct{ registry, repository, tag string }
+	var keys []tagKey
+	indexes := make(map[tagKey][]int)
+	for i, img := range images {
+		if img.Tag == "" {
+			continue
+		}
+		key := tagKey{img.Registry, img.Repository, img.Tag}
+		if _, seen := indexes[key]; !seen {
+			keys = append(keys, key)
+		}
+		indexes[key] = append(indexes[key], i)
+	}
+	log.Info("Checking image tags in source registries", "images", len(keys))
+
+	bar := progress.Start(os.Stderr, "Checking image tags", len(keys))
+	results := make([]registry.TagFreshness, len(keys))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, tagCheckWorkers)
+	for i, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = checker.Check(ctx, key.registry, key.repository, key.tag)
+			bar.Add(1)
+		}()
+	}
+	wg.Wait()
+	bar.Finish()
+
+	counts := make(map[string]int)
+	for i, key := range keys {
+		freshness := results[i]
+		for _, index := range indexes[key] {
+			images[index].Freshness = &freshness
+		}
+		counts[freshness.Status]++
+		image := key.registry + "/" + key.repository + ":" + key.tag
+		switch freshness.Status {
+		case registry.TagStatusMissing:
+			log.Warn("Image tag no longer exists in its source registry", "image", image)
+		case registry.TagStatusOutdated:
+			log.Info("Newer image tag available", "image", image, "latestTag", freshness.LatestTag)
+		case registry.TagStatusUnknown:
+			log.Warn("Failed to check image tag", "image", image, "error", freshness.Error)
+		}
+		if freshness.Stale {
+			counts["stale"]++
+			log.Warn("Image tag has not been pushed recently and may be abandoned", "image", image, "lastPushed", freshness.LastPushed)
+		}
+	}
+	log.Info("Image tags checked", "current", counts[registry.TagStatusCurrent], "outdated", counts[registry.TagStatusOutdated],
+		"missing", counts[registry.TagStatusMissing], "stale", counts["stale"], "unknown", counts[registry.TagStatusUnknown])
+}
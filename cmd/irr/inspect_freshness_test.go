@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTagFreshness(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/v2/web/app/manifests/1.0.0":
+			_, _ = w.Write([]byte(`{"config": {"digest": "sha256:config"}}`))
+		case "/v2/web/app/blobs/sha256:config":
+			_, _ = w.Write([]byte(`{"created": "2025-03-01T00:00:00Z"}`))
+		case "/v2/web/app/tags/list":
+			_, _ = w.Write([]byte(`{"tags": ["1.0.0", "1.1.0"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	host := strings.TrimPrefix(server.URL, "https://")
+	checker := registry.NewTagChecker(nil, registry.DefaultStaleAfter)
+	checker.Client = server.Client()
+	checker.Now = func() time.Time { return time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC) }
+
+	images := []ImageInfo{
+		{Registry: host, Repository: "web/app", Tag: "1.0.0", Source: "image"},
+		{Registry: host, Repository: "web/app", Tag: "1.0.0", Source: "worker.image"},
+		{Registry: host, Repository: "web/old", Tag: "0.1.0", Source: "legacy.image"},
+		{Registry: host, Repository: "web/app", Digest: "sha256:abc", Source: "pinned.image"},
+	}
+	checkTagFreshness(context.Background(), checker, images)
+
+	require.NotNil(t, images[0].Freshness)
+	assert.Equal(t, registry.TagFreshness{Status: registry.TagStatusOutdated, LatestTag: "1.1.0", LastPushed: "2025-03-01T00:00:00Z"}, *images[0].Freshness)
+	assert.Equal(t, images[0].Freshness, images[1].Freshness, "the same image is reported at every path")
+	require.NotNil(t, images[2].Freshness)
+	assert.Equal(t, registry.TagStatusMissing, images[2].Freshness.Status)
+	assert.Nil(t, images[3].Freshness, "images pinned by digest are not checked")
+	assert.Equal(t, int32(5), requests.Load(), "each distinct image is checked once")
+
+	checkTagFreshness(context.Background(), nil, images)
+}
+
+func TestGetTagChecker(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	cmd := newInspectCmd()
+	checker, err := getTagChecker(cmd)
+	require.NoError(t, err)
+	assert.Nil(t, checker)
+
+	require.NoError(t, cmd.ParseFlags([]string{"--check-tags", "--stale-after", "720h", "--registry-config", "/nonexistent/config.json"}))
+	checker, err = getTagChecker(cmd)
+	require.NoError(t, err)
+	require.NotNil(t, checker)
+	assert.Equal(t, 720*time.Hour, checker.StaleAfter)
+}
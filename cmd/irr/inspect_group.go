@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+)
+
+const (
+	// groupByRegistry groups images by their source registry
+	groupByRegistry = "registry"
+	// groupByChart groups images by the chart (or subchart) that references them
+	groupByChart = "chart"
+	// groupByNamespace groups images by the namespace of their release (--all-namespaces)
+	groupByNamespace = "namespace"
+)
+
+// groupByValues are the values accepted by --group-by
+var groupByValues = []string{groupByRegistry, groupByChart, groupByNamespace}
+
+// ImageGroup is one group of images of the aggregated inspect output (--group-by)
+type ImageGroup struct {
+	// Name is the registry, chart or namespace shared by the images of the group
+	Name string `json:"name" yaml:"name"`
+	// ImageCount is the number of distinct image references in the group
+	ImageCount int `json:"imageCount" yaml:"imageCount"`
+	// Images lists the distinct image references of the group, sorted
+	Images []string `json:"images" yaml:"images"`
+}
+
+// GroupedAnalysis is the output of inspect with --group-by, replacing the per-image analysis
+type GroupedAnalysis struct {
+	GroupBy string `json:"groupBy" yaml:"groupBy"`
+	// TotalImages is the number of distinct image references across all groups
+	TotalImages int          `json:"totalImages" yaml:"totalImages"`
+	Groups      []ImageGroup `json:"groups" yaml:"groups"`
+	Skipped     []string     `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+}
+
+// groupedImage is an image found by inspect together with the chart and namespace it was found in
+type groupedImage struct {
+	Image     ImageInfo
+	Chart     string
+	Namespace string
+}
+
+// getGroupBy reads --group-by and checks it against the other inspect flags: grouping by namespace
+// needs --all-namespaces, and the grouped view replaces the analysis a skeleton or revision
+// comparison is made from.
+func getGroupBy(cmd *cobra.Command, flags *InspectFlags) (string, error) {
+	groupBy, err := cmd.Flags().GetString("group-by")
+	if err != nil {
+		return "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get group-by flag: %w", err),
+		}
+	}
+	switch {
+	case groupBy == "":
+		return "", nil
+	case !slices.Contains(groupByValues, groupBy):
+		err = fmt.Errorf("unsupported --group-by %q; supported values: %s", groupBy, strings.Join(groupByValues, ", "))
+	case groupBy == groupByNamespace && !flags.AllNamespaces:
+		err = errors.New("--group-by namespace requires --all-namespaces")
+	case flags.GenerateConfigSkeleton:
+		err = errors.New("--group-by cannot be used with --generate-config-skeleton")
+	case flags.CompareRevision > 0:
+		err = errors.New("--group-by cannot be used with --compare-revision")
+	}
+	if err != nil {
+		return "", &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	return groupBy, nil
+}
+
+// analysisGroupedImages returns the images of a chart or release analysis for grouping. Images of
+// subcharts are attributed to the subchart, as the chart name followed by the subchart's values keys.
+func analysisGroupedImages(analysisResult *ImageAnalysis, namespace string) []groupedImage {
+	images := make([]groupedImage, 0, len(analysisResult.Images))
+	for _, img := range analysisResult.Images {
+		chartName := analysisResult.Chart.Name
+		if img.Subchart != "" {
+			chartName += "/" + img.Subchart
+		}
+		images = append(images, groupedImage{Image: img, Chart: chartName, Namespace: namespace})
+	}
+	return images
+}
+
+// groupImages aggregates images by registry, chart or namespace. Groups are ordered by descending
+// image count, then by name.
+func groupImages(groupBy string, images []groupedImage) *GroupedAnalysis {
+	refsByGroup := make(map[string]map[string]bool)
+	allRefs := make(map[string]bool)
+	for _, entry := range images {
+		var name string
+		switch groupBy {
+		case groupByRegistry:
+			name = image.NormalizeRegistry(entry.Image.Registry)
+		case groupByChart:
+			name = entry.Chart
+		case groupByNamespace:
+			name = entry.Namespace
+		}
+		ref := imageInfoReference(entry.Image)
+		if refsByGroup[name] == nil {
+			refsByGroup[name] = make(map[string]bool)
+		}
+		refsByGroup[name][ref] = true
+		allRefs[ref] = true
+	}
+
+	grouped := &GroupedAnalysis{GroupBy: groupBy, TotalImages: len(allRefs), Groups: make([]ImageGroup, 0, len(refsByGroup))}
+	for name, refs := range refsByGroup {
+		grouped.Groups = append(grouped.Groups, ImageGroup{Name: name, ImageCount: len(refs), Images: slices.Sorted(maps.Keys(refs))})
+	}
+	slices.SortFunc(grouped.Groups, func(a, b ImageGroup) int {
+		if a.ImageCount != b.ImageCount {
+			return b.ImageCount - a.ImageCount
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return grouped
+}
+
+// groupChartResults groups the images of every chart analyzed with --recursive. Charts that failed
+// are listed as skipped.
+func groupChartResults(groupBy string, results []MultiChartResult) *GroupedAnalysis {
+	var images []groupedImage
+	var skipped []string
+	for _, result := range results {
+		if result.Analysis == nil {
+			if result.Error != "" {
+				skipped = append(skipped, fmt.Sprintf("%s: %s", result.ChartPath, result.Error))
+			}
+			continue
+		}
+		images = append(images, analysisGroupedImages(result.Analysis, "")...)
+	}
+	grouped := groupImages(groupBy, images)
+	grouped.Skipped = skipped
+	return grouped
+}
+
+// groupReleaseResults groups the images of every release analyzed with --all-namespaces
+func groupReleaseResults(groupBy string, results []*ReleaseAnalysisResult, skipped []string) *GroupedAnalysis {
+	var images []groupedImage
+	for _, result := range results {
+		images = append(images, analysisGroupedImages(&result.Analysis, result.Namespace)...)
+	}
+	grouped := groupImages(groupBy, images)
+	grouped.Skipped = skipped
+	return grouped
+}
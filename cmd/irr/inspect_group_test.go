@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupReleaseResults(t *testing.T) {
+	results := []*ReleaseAnalysisResult{
+		{ReleaseName: "web", Namespace: "prod", Analysis: ImageAnalysis{
+			Chart: ChartInfo{Name: "web"},
+			Images: []ImageInfo{
+				{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25", Source: "image"},
+				{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25", Source: "proxy.image"},
+				{Registry: "quay.io", Repository: "org/exporter", Tag: "v1", Source: "exporter.image"},
+				{Registry: "docker.io", Repository: "bitnami/redis", Tag: "7.2", Source: "redis.image", Subchart: "redis"},
+			},
+		}},
+		{ReleaseName: "api", Namespace: "staging", Analysis: ImageAnalysis{
+			Chart: ChartInfo{Name: "api"},
+			Images: []ImageInfo{
+				{Registry: "index.docker.io", Repository: "library/nginx", Tag: "1.25", Source: "image"},
+				{Registry: "ghcr.io", Repository: "org/api", Tag: "2.0", Source: "api.image"},
+			},
+		}},
+	}
+	skipped := []string{"kube-system/broken: no values"}
+
+	t.Run("registry", func(t *testing.T) {
+		grouped := groupReleaseResults(groupByRegistry, results, skipped)
+		assert.Equal(t, &GroupedAnalysis{
+			GroupBy:     groupByRegistry,
+			TotalImages: 5,
+			Groups: []ImageGroup{
+				{Name: "docker.io", ImageCount: 3, Images: []string{
+					"docker.io/bitnami/redis:7.2", "docker.io/library/nginx:1.25", "index.docker.io/library/nginx:1.25",
+				}},
+				{Name: "ghcr.io", ImageCount: 1, Images: []string{"ghcr.io/org/api:2.0"}},
+				{Name: "quay.io", ImageCount: 1, Images: []string{"quay.io/org/exporter:v1"}},
+			},
+			Skipped: skipped,
+		}, grouped)
+	})
+
+	t.Run("chart", func(t *testing.T) {
+		grouped := groupReleaseResults(groupByChart, results, nil)
+		names := make([]string, 0, len(grouped.Groups))
+		for _, group := range grouped.Groups {
+			names = append(names, group.Name)
+		}
+		assert.Equal(t, []string{"api", "web", "web/redis"}, names)
+		assert.Equal(t, 2, grouped.Groups[1].ImageCount, "images found at several paths count once")
+	})
+
+	t.Run("namespace", func(t *testing.T) {
+		grouped := groupReleaseResults(groupByNamespace, results, nil)
+		require.Len(t, grouped.Groups, 2)
+		assert.Equal(t, ImageGroup{Name: "prod", ImageCount: 3, Images: []string{
+			"docker.io/bitnami/redis:7.2", "docker.io/library/nginx:1.25", "quay.io/org/exporter:v1",
+		}}, grouped.Groups[0])
+		assert.Equal(t, "staging", grouped.Groups[1].Name)
+	})
+}
+
+func TestGroupChartResults(t *testing.T) {
+	results := []MultiChartResult{
+		{ChartPath: "charts/web", Analysis: &ImageAnalysis{
+			Chart:  ChartInfo{Name: "web"},
+			Images: []ImageInfo{{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25"}},
+		}},
+		{ChartPath: "charts/broken", Error: "failed to load chart"},
+	}
+	grouped := groupChartResults(groupByChart, results)
+	assert.Equal(t, []ImageGroup{{Name: "web", ImageCount: 1, Images: []string{"docker.io/library/nginx:1.25"}}}, grouped.Groups)
+	assert.Equal(t, []string{"charts/broken: failed to load chart"}, grouped.Skipped)
+}
+
+func TestGetGroupBy(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		flags   InspectFlags
+		want    string
+		wantErr string
+	}{
+		{name: "not set"},
+		{name: "registry", value: "registry", want: groupByRegistry},
+		{name: "namespace with all namespaces", value: "namespace", flags: InspectFlags{AllNamespaces: true}, want: groupByNamespace},
+		{name: "namespace without all namespaces", value: "namespace", wantErr: "requires --all-namespaces"},
+		{name: "unsupported", value: "team", wantErr: "unsupported --group-by"},
+		{name: "skeleton", value: "chart", flags: InspectFlags{GenerateConfigSkeleton: true}, wantErr: "--generate-config-skeleton"},
+		{name: "compare revision", value: "chart", flags: InspectFlags{CompareRevision: 2}, wantErr: "--compare-revision"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newInspectCmd()
+			require.NoError(t, cmd.Flags().Set("group-by", tt.value))
+
+			groupBy, err := getGroupBy(cmd, &tt.flags)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, groupBy)
+				return
+			}
+			var exitErr *exitcodes.ExitCodeError
+			require.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+)
+
+// Inspect output lists images, patterns and releases in a fixed order, so that running inspect
+// twice on the same chart or cluster produces identical output that can be committed and diffed.
+
+// sortAnalysis orders the lists of an analysis for output: images and image patterns by values
+// path, CRD images by file and path, and errors and skipped entries alphabetically.
+func sortAnalysis(analysisResult *ImageAnalysis) {
+	if analysisResult == nil {
+		return
+	}
+	sortImages(analysisResult.Images)
+	slices.SortStableFunc(analysisResult.ImagePatterns, func(a, b analysis.ImagePattern) int {
+		return cmp.Or(strings.Compare(a.Path, b.Path), strings.Compare(a.Value, b.Value))
+	})
+	slices.SortStableFunc(analysisResult.CRDImages, func(a, b analysis.CRDImage) int {
+		return cmp.Or(strings.Compare(a.File, b.File), strings.Compare(a.Path, b.Path), strings.Compare(a.Image, b.Image))
+	})
+	slices.Sort(analysisResult.Errors)
+	slices.Sort(analysisResult.Skipped)
+}
+
+// sortImages orders images by the values path they were found at, then by reference, so that
+// images at the same path (e.g. rendered from different templates) keep a fixed order too.
+func sortImages(images []ImageInfo) {
+	slices.SortStableFunc(images, func(a, b ImageInfo) int {
+		return cmp.Or(
+			strings.Compare(a.Source, b.Source),
+			strings.Compare(a.ValuePath, b.ValuePath),
+			strings.Compare(a.Registry, b.Registry),
+			strings.Compare(a.Repository, b.Repository),
+			strings.Compare(a.Tag, b.Tag),
+			strings.Compare(a.Digest, b.Digest),
+		)
+	})
+}
+
+// sortReleaseResults orders release results by namespace, then release name, and sorts the
+// analysis of each.
+func sortReleaseResults(results []*ReleaseAnalysisResult) {
+	slices.SortStableFunc(results, func(a, b *ReleaseAnalysisResult) int {
+		return cmp.Or(strings.Compare(a.Namespace, b.Namespace), strings.Compare(a.ReleaseName, b.ReleaseName))
+	})
+	for _, result := range results {
+		sortAnalysis(&result.Analysis)
+	}
+}
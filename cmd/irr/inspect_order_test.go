@@ -0,0 +1,103 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSortAnalysis(t *testing.T) {
+	newAnalysis := func(reversed bool) *ImageAnalysis {
+		result := &ImageAnalysis{
+			Images: []ImageInfo{
+				{Source: "api.image", Registry: "docker.io", Repository: "library/nginx", Tag: "1.25"},
+				{Source: "worker.image", Registry: "quay.io", Repository: "prometheus/node-exporter", Tag: "v1.8.0"},
+				{Source: "worker.image", Registry: "docker.io", Repository: "library/busybox", Tag: "1.36"},
+			},
+			ImagePatterns: []analysis.ImagePattern{
+				{Path: "api.image", Value: "docker.io/library/nginx:1.25"},
+				{Path: "worker.image", Value: "quay.io/prometheus/node-exporter:v1.8.0"},
+			},
+			CRDImages: []analysis.CRDImage{
+				{File: "crds/a.yaml", Path: "spec.image", Image: "busybox"},
+				{File: "crds/b.yaml", Path: "spec.image", Image: "alpine"},
+			},
+			Skipped: []string{"api.sidecar", "worker.init"},
+		}
+		if reversed {
+			slices.Reverse(result.Images)
+			slices.Reverse(result.ImagePatterns)
+			slices.Reverse(result.CRDImages)
+			slices.Reverse(result.Skipped)
+		}
+		return result
+	}
+
+	golden := `chart:
+    name: ""
+    version: ""
+images:
+    - registry: docker.io
+      repository: library/nginx
+      tag: "1.25"
+      source: api.image
+    - registry: docker.io
+      repository: library/busybox
+      tag: "1.36"
+      source: worker.image
+    - registry: quay.io
+      repository: prometheus/node-exporter
+      tag: v1.8.0
+      source: worker.image
+imagePatterns:
+    - path: api.image
+      type: ""
+      value: docker.io/library/nginx:1.25
+      count: 0
+    - path: worker.image
+      type: ""
+      value: quay.io/prometheus/node-exporter:v1.8.0
+      count: 0
+skipped:
+    - api.sidecar
+    - worker.init
+crdImages:
+    - file: crds/a.yaml
+      path: spec.image
+      image: busybox
+      registry: ""
+      repository: ""
+    - file: crds/b.yaml
+      path: spec.image
+      image: alpine
+      registry: ""
+      repository: ""
+`
+	for _, reversed := range []bool{false, true} {
+		result := newAnalysis(reversed)
+		sortAnalysis(result)
+		out, err := yaml.Marshal(result)
+		require.NoError(t, err)
+		assert.Equal(t, golden, string(out), "reversed input: %v", reversed)
+	}
+}
+
+func TestSortReleaseResults(t *testing.T) {
+	results := []*ReleaseAnalysisResult{
+		{ReleaseName: "web", Namespace: "prod"},
+		{ReleaseName: "api", Namespace: "prod"},
+		{ReleaseName: "web", Namespace: "dev", Analysis: ImageAnalysis{Skipped: []string{"b", "a"}}},
+	}
+	sortReleaseResults(results)
+
+	var names []string
+	for _, result := range results {
+		names = append(names, result.Namespace+"/"+result.ReleaseName)
+	}
+	assert.Equal(t, []string{"dev/web", "prod/api", "prod/web"}, names)
+	assert.Equal(t, []string{"a", "b"}, results[0].Analysis.Skipped)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lucas-albers-lz4/irr/internal/helm"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+)
+
+// ReleaseFilter scopes which releases inspect and override --all-namespaces process
+type ReleaseFilter struct {
+	// Selector is a label selector matched by Helm against the release labels
+	Selector string
+	// NamespacePattern keeps only releases whose namespace matches
+	NamespacePattern *regexp.Regexp
+	// IncludeNamespaces keeps only releases whose namespace matches one of these globs
+	IncludeNamespaces []string
+	// ExcludeNamespaces drops releases whose namespace matches one of these globs
+	ExcludeNamespaces []string
+	// ChartNamePattern keeps only releases whose chart name matches
+	ChartNamePattern *regexp.Regexp
+	// MaxReleases caps the number of releases processed; 0 means no limit
+	MaxReleases int
+}
+
+// addReleaseFilterFlags adds the flags that scope --all-namespaces
+func addReleaseFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("selector", "l", "", "Label selector for releases processed with --all-namespaces (e.g. app.kubernetes.io/part-of=platform)")
+	cmd.Flags().String("namespace-regex", "", "Only process releases whose namespace matches this regular expression (requires --all-namespaces)")
+	cmd.Flags().StringSlice("include-namespaces", nil, "Only process releases in these namespaces, as names or globs (e.g. team-*) (requires --all-namespaces)")
+	cmd.Flags().StringSlice("exclude-namespaces", nil, "Skip releases in these namespaces, as names or globs (e.g. kube-*,olm) (requires --all-namespaces)")
+	cmd.Flags().String("chart-name-filter", "", "Only process releases whose chart name matches this regular expression (requires --all-namespaces)")
+	cmd.Flags().Int("max-releases", 0, "Maximum number of releases to process with --all-namespaces (0 means no limit)")
+}
+
+// getReleaseFilter reads and validates the release filter flags. They only apply to
+// --all-namespaces, so setting any of them without it is an error.
+func getReleaseFilter(cmd *cobra.Command, allNamespaces bool) (*ReleaseFilter, error) {
+	filter := &ReleaseFilter{}
+	var err error
+	if filter.Selector, err = getStringFlag(cmd, "selector"); err != nil {
+		return nil, err
+	}
+	namespaceRegex, err := getStringFlag(cmd, "namespace-regex")
+	if err != nil {
+		return nil, err
+	}
+	chartNameFilter, err := getStringFlag(cmd, "chart-name-filter")
+	if err != nil {
+		return nil, err
+	}
+	if filter.IncludeNamespaces, err = getNamespaceGlobs(cmd, "include-namespaces"); err != nil {
+		return nil, err
+	}
+	if filter.ExcludeNamespaces, err = getNamespaceGlobs(cmd, "exclude-namespaces"); err != nil {
+		return nil, err
+	}
+	filter.MaxReleases, err = cmd.Flags().GetInt("max-releases")
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get max-releases flag: %w", err),
+		}
+	}
+
+	if filter.MaxReleases < 0 {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("--max-releases must not be negative, got %d", filter.MaxReleases),
+		}
+	}
+	if filter.NamespacePattern, err = compileReleaseFilterPattern("namespace-regex", namespaceRegex); err != nil {
+		return nil, err
+	}
+	if filter.ChartNamePattern, err = compileReleaseFilterPattern("chart-name-filter", chartNameFilter); err != nil {
+		return nil, err
+	}
+
+	if !allNamespaces && filter.isSet() {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--selector, --namespace-regex, --include-namespaces, --exclude-namespaces, --chart-name-filter and --max-releases require --all-namespaces"),
+		}
+	}
+	return filter, nil
+}
+
+// compileReleaseFilterPattern compiles the regular expression of a release filter flag, returning
+// nil for an empty expression
+func compileReleaseFilterPattern(flagName, expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("invalid --%s %q: %w", flagName, expr, err),
+		}
+	}
+	return pattern, nil
+}
+
+// getNamespaceGlobs reads a flag listing namespace names or globs, rejecting malformed globs
+func getNamespaceGlobs(cmd *cobra.Command, flagName string) ([]string, error) {
+	globs, err := getStringSliceFlag(cmd, flagName)
+	if err != nil {
+		return nil, err
+	}
+	for _, glob := range globs {
+		if _, err := path.Match(glob, ""); err != nil || glob == "" {
+			return nil, &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  fmt.Errorf("invalid --%s pattern %q: must be a namespace name or glob", flagName, glob),
+			}
+		}
+	}
+	return globs, nil
+}
+
+// matchesAnyGlob reports whether name matches one of the globs
+func matchesAnyGlob(name string, globs []string) bool {
+	for _, glob := range globs {
+		if matched, err := path.Match(glob, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isSet reports whether any release filter is configured
+func (f *ReleaseFilter) isSet() bool {
+	return f != nil && (f.Selector != "" || f.filtersNamespaces() || f.ChartNamePattern != nil || f.MaxReleases > 0)
+}
+
+// filtersNamespaces reports whether the filter restricts namespaces
+func (f *ReleaseFilter) filtersNamespaces() bool {
+	return f.NamespacePattern != nil || len(f.IncludeNamespaces) > 0 || len(f.ExcludeNamespaces) > 0
+}
+
+// matchesNamespace reports whether releases in namespace pass the namespace filters: the
+// namespace must match NamespacePattern and an include glob, if set, and no exclude glob.
+func (f *ReleaseFilter) matchesNamespace(namespace string) bool {
+	if f == nil {
+		return true
+	}
+	if f.NamespacePattern != nil && !f.NamespacePattern.MatchString(namespace) {
+		return false
+	}
+	if len(f.IncludeNamespaces) > 0 && !matchesAnyGlob(namespace, f.IncludeNamespaces) {
+		return false
+	}
+	return !matchesAnyGlob(namespace, f.ExcludeNamespaces)
+}
+
+// listOptions returns the part of the filter Helm applies while listing releases. The limit is
+// only pushed down when no pattern is filtered here afterwards, since Helm would otherwise cap
+// the releases before they are matched.
+func (f *ReleaseFilter) listOptions() helm.ReleaseListOptions {
+	opts := helm.ReleaseListOptions{AllNamespaces: true}
+	if f == nil {
+		return opts
+	}
+	opts.Selector = f.Selector
+	if !f.filtersNamespaces() && f.ChartNamePattern == nil {
+		opts.Limit = f.MaxReleases
+	}
+	return opts
+}
+
+// apply returns the releases matching the namespace filters and chart name pattern, capped at
+// MaxReleases. Releases are sorted by namespace and name first so the cap is deterministic.
+func (f *ReleaseFilter) apply(releases []*helm.ReleaseElement) []*helm.ReleaseElement {
+	if !f.isSet() {
+		return releases
+	}
+	sorted := make([]*helm.ReleaseElement, 0, len(releases))
+	for _, release := range releases {
+		if release == nil {
+			continue
+		}
+		if !f.matchesNamespace(release.Namespace) {
+			log.Debug("Skipping release in filtered namespace", "release", release.Name, "namespace", release.Namespace)
+			continue
+		}
+		if f.ChartNamePattern != nil && !f.ChartNamePattern.MatchString(release.Chart) {
+			continue
+		}
+		sorted = append(sorted, release)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Namespace != sorted[j].Namespace {
+			return sorted[i].Namespace < sorted[j].Namespace
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	if f.MaxReleases > 0 && len(sorted) > f.MaxReleases {
+		log.Info("Limiting releases to process", "matched", len(sorted), "max", f.MaxReleases)
+		sorted = sorted[:f.MaxReleases]
+	}
+	return sorted
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/internal/helm"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetReleaseFilter(t *testing.T) {
+	var exitErr *exitcodes.ExitCodeError
+
+	t.Run("all namespaces", func(t *testing.T) {
+		cmd := newInspectCmd()
+		require.NoError(t, cmd.ParseFlags([]string{"-l", "app.kubernetes.io/part-of=platform", "--namespace-regex", "^team-", "--max-releases", "5"}))
+		filter, err := getReleaseFilter(cmd, true)
+		require.NoError(t, err)
+		assert.Equal(t, "app.kubernetes.io/part-of=platform", filter.Selector)
+		assert.Equal(t, "^team-", filter.NamespacePattern.String())
+		assert.Nil(t, filter.ChartNamePattern)
+		assert.Equal(t, 5, filter.MaxReleases)
+	})
+
+	t.Run("namespace globs", func(t *testing.T) {
+		cmd := newInspectCmd()
+		require.NoError(t, cmd.ParseFlags([]string{"--include-namespaces", "team-*,platform", "--exclude-namespaces", "kube-*", "--exclude-namespaces", "olm"}))
+		filter, err := getReleaseFilter(cmd, true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"team-*", "platform"}, filter.IncludeNamespaces)
+		assert.Equal(t, []string{"kube-*", "olm"}, filter.ExcludeNamespaces)
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		filter, err := getReleaseFilter(newInspectCmd(), false)
+		require.NoError(t, err)
+		assert.False(t, filter.isSet())
+	})
+
+	for name, args := range map[string][]string{
+		"invalid regex":          {"--chart-name-filter", "postgres("},
+		"invalid glob":           {"--exclude-namespaces", "kube-["},
+		"negative max":           {"--max-releases", "-1"},
+		"without all namespaces": {"--selector", "team=a"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cmd := newInspectCmd()
+			require.NoError(t, cmd.ParseFlags(args))
+			_, err := getReleaseFilter(cmd, name != "without all namespaces")
+			require.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+		})
+	}
+}
+
+func TestReleaseFilterListOptions(t *testing.T) {
+	var filter *ReleaseFilter
+	assert.Equal(t, helm.ReleaseListOptions{AllNamespaces: true}, filter.listOptions())
+
+	filter = &ReleaseFilter{Selector: "team=a", MaxReleases: 10}
+	assert.Equal(t, helm.ReleaseListOptions{AllNamespaces: true, Selector: "team=a", Limit: 10}, filter.listOptions())
+
+	filter.NamespacePattern = regexp.MustCompile("^prod")
+	assert.Equal(t, helm.ReleaseListOptions{AllNamespaces: true, Selector: "team=a"}, filter.listOptions(),
+		"the limit is applied after the namespace pattern")
+}
+
+func TestReleaseFilterApply(t *testing.T) {
+	releases := []*helm.ReleaseElement{
+		{Name: "db", Namespace: "team-b", Chart: "postgresql"},
+		{Name: "web", Namespace: "team-a", Chart: "nginx"},
+		{Name: "api", Namespace: "team-a", Chart: "postgresql"},
+		{Name: "monitoring", Namespace: "kube-system", Chart: "prometheus"},
+		nil,
+	}
+
+	var unset *ReleaseFilter
+	assert.Equal(t, releases, unset.apply(releases))
+
+	filter := &ReleaseFilter{NamespacePattern: regexp.MustCompile("^team-")}
+	assert.Equal(t, []*helm.ReleaseElement{releases[2], releases[1], releases[0]}, filter.apply(releases))
+
+	filter.ChartNamePattern = regexp.MustCompile("^postgresql$")
+	assert.Equal(t, []*helm.ReleaseElement{releases[2], releases[0]}, filter.apply(releases))
+
+	filter.MaxReleases = 1
+	assert.Equal(t, []*helm.ReleaseElement{releases[2]}, filter.apply(releases))
+
+	filter = &ReleaseFilter{ExcludeNamespaces: []string{"kube-*", "team-b"}}
+	assert.Equal(t, []*helm.ReleaseElement{releases[2], releases[1]}, filter.apply(releases))
+
+	filter = &ReleaseFilter{IncludeNamespaces: []string{"team-*", "kube-system"}, ExcludeNamespaces: []string{"team-a"}}
+	assert.Equal(t, []*helm.ReleaseElement{releases[3], releases[0]}, filter.apply(releases),
+		"exclusions take precedence over inclusions")
+}
+
+func TestReleaseFilterMatchesNamespace(t *testing.T) {
+	var unset *ReleaseFilter
+	assert.True(t, unset.matchesNamespace("kube-system"))
+
+	filter := &ReleaseFilter{NamespacePattern: regexp.MustCompile("^team-"), ExcludeNamespaces: []string{"*-sandbox"}}
+	assert.True(t, filter.matchesNamespace("team-a"))
+	assert.False(t, filter.matchesNamespace("team-a-sandbox"))
+	assert.False(t, filter.matchesNamespace("default"))
+	assert.Equal(t, helm.ReleaseListOptions{AllNamespaces: true}, (&ReleaseFilter{ExcludeNamespaces: []string{"olm"}, MaxReleases: 3}).listOptions(),
+		"the limit is applied after the namespace globs")
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/progress"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/cobra"
+)
+
+// newSignatureChecker creates the checker used by --check-signatures, sending its requests
+// through transport. It can be replaced in tests.
+var newSignatureChecker = func(credentials map[string]registry.Credentials, transport http.RoundTripper) *registry.TagChecker {
+	checker := registry.NewTagChecker(credentials, 0)
+	checker.Client.Transport = transport
+	checker.Helpers = registryCredentialHelpers()
+	return checker
+}
+
+// getSignatureChecker returns the checker for --check-signatures, or nil when the flag is not
+// set.
+func getSignatureChecker(cmd *cobra.Command) (*registry.TagChecker, error) {
+	check, err := getBoolFlag(cmd, "check-signatures")
+	if err != nil || !check {
+		return nil, err
+	}
+	if err := requireNetwork("checking image signatures in source registries (--check-signatures)"); err != nil {
+		return nil, err
+	}
+	credentials, transport, err := sourceRegistryAccess(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return newSignatureChecker(credentials, transport), nil
+}
+
+// checkImageSignatures records the cosign signatures and attestations of each image in images,
+// querying each distinct image once, so that they can be copied along with the image (see 'irr
+// config export --images'). Registry errors are recorded and logged, not returned.
+func checkImageSignatures(ctx context.Context, checker *registry.TagChecker, images []ImageInfo) {
+	if checker == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	type imageKey struct{ registry, repository, reference string }
+	var keys []imageKey
+	indexes := make(map[imageKey][]int)
+	for i, img := range images {
+		reference := img.Digest
+		if reference == "" {
+			reference = img.Tag
+		}
+		if reference == "" {
+			continue
+		}
+		key := imageKey{img.Registry, img.Repository, reference}
+		if _, seen := indexes[key]; !seen {
+			keys = append(keys, key)
+		}
+		indexes[key] = append(indexes[key], i)
+	}
+	log.Info("Checking image signatures in source registries", "images", len(keys))
+
+	bar := progress.Start(os.Stderr, "Checking image signatures", len(keys))
+	results := make([]registry.ImageSignatures, len(keys))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, tagCheckWorkers)
+	for i, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = checker.Signatures(ctx, key.registry, key.repository, key.reference)
+			bar.Add(1)
+		}()
+	}
+	wg.Wait()
+	bar.Finish()
+
+	signed := 0
+	for i, key := range keys {
+		signatures := results[i]
+		for _, index := range indexes[key] {
+			images[index].Signatures = &signatures
+		}
+		if signatures.Signed() {
+			signed++
+		}
+		if signatures.Error != "" {
+			log.Warn("Failed to check image signatures", "image", key.registry+"/"+key.repository, "reference", key.reference, "error", signatures.Error)
+		}
+	}
+	log.Info("Image signatures checked", "signed", signed, "unsigned", len(keys)-signed)
+}
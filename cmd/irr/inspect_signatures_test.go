@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signedRegistryHost is the registry the images of newSignedImageRegistry are referenced from
+const signedRegistryHost = "registry.example.com"
+
+// redirectTransport sends every request to host
+type redirectTransport struct {
+	host string
+	next http.RoundTripper
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Host = t.host
+	return t.next.RoundTrip(req)
+}
+
+// newSignedImageRegistry starts a TLS registry serving team/app:1.0.0, signed with cosign's tag
+// scheme and attested through the referrers API, and the unsigned team/plain:1.0.0. It replaces
+// newSignatureChecker so that --check-signatures sends the requests for signedRegistryHost to it,
+// and returns a counter of its requests.
+func newSignedImageRegistry(t *testing.T) (requests *atomic.Int32) {
+	t.Helper()
+	requests = &atomic.Int32{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/v2/team/app/manifests/1.0.0":
+			w.Header().Set("Docker-Content-Digest", "sha256:app")
+		case "/v2/team/plain/manifests/1.0.0":
+			w.Header().Set("Docker-Content-Digest", "sha256:plain")
+		case "/v2/team/app/manifests/sha256-app.sig":
+		case "/v2/team/app/referrers/sha256:app":
+			_, _ = w.Write([]byte(`{"manifests": [{"digest": "sha256:provenance", "artifactType": "application/vnd.in-toto+json"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	original := newSignatureChecker
+	t.Cleanup(func() { newSignatureChecker = original })
+	newSignatureChecker = func(credentials map[string]registry.Credentials, _ http.RoundTripper) *registry.TagChecker {
+		checker := registry.NewTagChecker(credentials, 0)
+		checker.Client = server.Client()
+		checker.Client.Transport = redirectTransport{host: strings.TrimPrefix(server.URL, "https://"), next: checker.Client.Transport}
+		return checker
+	}
+	return requests
+}
+
+func TestCheckImageSignatures(t *testing.T) {
+	requests := newSignedImageRegistry(t)
+	host := signedRegistryHost
+	checker := newSignatureChecker(nil, nil)
+
+	images := []ImageInfo{
+		{Registry: host, Repository: "team/app", Tag: "1.0.0", Source: "image"},
+		{Registry: host, Repository: "team/app", Tag: "1.0.0", Source: "worker.image"},
+		{Registry: host, Repository: "team/plain", Tag: "1.0.0", Source: "sidecar.image"},
+		{Registry: host, Repository: "team/app", Source: "untagged.image"},
+	}
+	checkImageSignatures(context.Background(), checker, images)
+
+	require.NotNil(t, images[0].Signatures)
+	assert.Equal(t, registry.ImageSignatures{
+		Digest:       "sha256:app",
+		Signatures:   []string{"sha256-app.sig"},
+		Attestations: []string{"sha256:provenance"},
+	}, *images[0].Signatures)
+	assert.Equal(t, images[0].Signatures, images[1].Signatures, "the same image is reported at every path")
+	require.NotNil(t, images[2].Signatures)
+	assert.False(t, images[2].Signatures.Signed())
+	assert.Nil(t, images[3].Signatures, "images without a tag or digest are not checked")
+	// The registry has no referrers of team/plain, so its referrers tag is looked up as well
+	assert.Equal(t, int32(9), requests.Load(), "each distinct image is checked once")
+
+	checkImageSignatures(context.Background(), nil, images)
+}
+
+func TestGetSignatureChecker(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	cmd := newInspectCmd()
+	checker, err := getSignatureChecker(cmd)
+	require.NoError(t, err)
+	assert.Nil(t, checker)
+
+	require.NoError(t, cmd.ParseFlags([]string{"--check-signatures", "--registry-config", "/nonexistent/config.json"}))
+	checker, err = getSignatureChecker(cmd)
+	require.NoError(t, err)
+	assert.NotNil(t, checker)
+}
+
+func TestExportSignedImages(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	newSignedImageRegistry(t)
+	host := signedRegistryHost
+	dir := t.TempDir()
+	chartPath := filepath.Join(dir, "app")
+	require.NoError(t, os.MkdirAll(chartPath, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(chartPath, "Chart.yaml"), []byte("apiVersion: v2\nname: app\nversion: 1.0.0\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(chartPath, "values.yaml"), []byte(`image:
+  registry: `+host+`
+  repository: team/app
+  tag: 1.0.0
+sidecar:
+  image:
+    registry: `+host+`
+    repository: team/plain
+    tag: 1.0.0
+`), 0o600))
+	mappingsFile := filepath.Join(dir, "registry-mappings.yaml")
+	require.NoError(t, os.WriteFile(mappingsFile, []byte("registries:\n  mappings:\n    - source: "+host+"\n      target: harbor.local/mirror\n"), 0o600))
+
+	imagesFile := filepath.Join(dir, "images.yaml")
+	inspect := newInspectCmd()
+	inspect.SetOut(new(bytes.Buffer))
+	inspect.SetArgs([]string{"--chart-path", chartPath, "--check-signatures", "--registry-config", "/nonexistent/config.json", "--output-file", imagesFile})
+	require.NoError(t, inspect.Execute())
+	inspected, err := os.ReadFile(imagesFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(inspected), "signatures:\n")
+	assert.Contains(t, string(inspected), "- sha256-app.sig")
+
+	export := newConfigExportCmd()
+	out := new(bytes.Buffer)
+	export.SetOut(out)
+	export.SetArgs([]string{"--file", mappingsFile, "--images", imagesFile})
+	require.NoError(t, export.Execute())
+	assert.Contains(t, out.String(), host+`:
+  images:
+    team/app:
+      - "1.0.0"
+      - "sha256-app.sig"
+      - "sha256:provenance"
+    team/plain:
+      - "1.0.0"
+`)
+
+	export = newConfigExportCmd()
+	export.SetOut(new(bytes.Buffer))
+	export.SetArgs([]string{"--file", mappingsFile, "--images", mappingsFile})
+	assert.ErrorContains(t, export.Execute(), "no images found")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/cobra"
+)
+
+const skeletonGroupFlagUsage = "Route source registries to a shared target in the generated config skeleton, as TARGET=SOURCE[,SOURCE...] " +
+	"(can be specified multiple times for different targets; only applies with --generate-config-skeleton)"
+
+// getSkeletonGroups reads and parses the --skeleton-group flag
+func getSkeletonGroups(cmd *cobra.Command) ([]registry.RegGroup, error) {
+	values, err := cmd.Flags().GetStringArray("skeleton-group")
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get skeleton-group flag: %w", err),
+		}
+	}
+	return parseSkeletonGroups(values)
+}
+
+// parseSkeletonGroups parses TARGET=SOURCE[,SOURCE...] values into registry groups. A source may
+// only be routed to one target.
+func parseSkeletonGroups(values []string) ([]registry.RegGroup, error) {
+	groups := make([]registry.RegGroup, 0, len(values))
+	seen := make(map[string]string)
+	for _, value := range values {
+		target, sourceList, ok := strings.Cut(value, "=")
+		target = strings.TrimSpace(target)
+		if !ok || target == "" {
+			return nil, &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  fmt.Errorf("invalid --skeleton-group %q: expected TARGET=SOURCE[,SOURCE...]", value),
+			}
+		}
+		group := registry.RegGroup{
+			Target:      target,
+			Description: fmt.Sprintf("Registries relocated to %s", target),
+		}
+		for _, source := range strings.Split(sourceList, ",") {
+			source = strings.TrimSpace(source)
+			if source == "" {
+				continue
+			}
+			source = image.NormalizeRegistry(source)
+			if other, exists := seen[source]; exists {
+				return nil, &exitcodes.ExitCodeError{
+					Code: exitcodes.ExitInputConfigurationError,
+					Err:  fmt.Errorf("source registry %s is routed to both %s and %s by --skeleton-group", source, other, target),
+				}
+			}
+			seen[source] = target
+			group.Sources = append(group.Sources, source)
+		}
+		if len(group.Sources) == 0 {
+			return nil, &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  fmt.Errorf("invalid --skeleton-group %q: no source registries given", value),
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// skeletonRegistries returns the registries section entries for the registries of images: the
+// sources listed in groups are routed through their group, every other registry gets its own
+// placeholder mapping. Groups keep the sources they list even when no image uses them.
+func skeletonRegistries(images []ImageInfo, groups []registry.RegGroup) ([]registry.RegMapping, []registry.RegGroup) {
+	grouped := make(map[string]bool)
+	for _, group := range groups {
+		for _, source := range group.Sources {
+			grouped[source] = true
+		}
+	}
+
+	// Extract unique registries from images
+	registries := make(map[string]bool)
+	for _, img := range images {
+		if img.Registry != "" && !grouped[image.NormalizeRegistry(img.Registry)] {
+			registries[img.Registry] = true
+		}
+	}
+
+	// Sort registries for consistent output
+	registryList := make([]string, 0, len(registries))
+	for reg := range registries {
+		registryList = append(registryList, reg)
+	}
+	sort.Strings(registryList)
+
+	// Create structured registry mappings
+	mappings := make([]registry.RegMapping, 0, len(registryList))
+	for _, reg := range registryList {
+		log.Debug("CREATE_SKELETON: Creating mapping entry", "source_registry_key", reg)
+		mappings = append(mappings, registry.RegMapping{
+			Source:      reg,
+			Target:      skeletonTarget(reg),
+			Description: fmt.Sprintf("Mapping for %s", reg),
+			Enabled:     true,
+		})
+	}
+	if len(groups) == 0 {
+		return mappings, nil
+	}
+	return mappings, groups
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSkeletonGroups(t *testing.T) {
+	groups, err := parseSkeletonGroups([]string{
+		"harbor-a.example.com=docker.io, quay.io",
+		"harbor-b.example.com/google=gcr.io,docker.io",
+	})
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr, "docker.io is routed to two targets")
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	assert.Nil(t, groups)
+
+	groups, err = parseSkeletonGroups([]string{"harbor-a.example.com=docker.io, quay.io", "harbor-b.example.com/google=gcr.io"})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "harbor-a.example.com", groups[0].Target)
+	assert.Equal(t, []string{"docker.io", "quay.io"}, groups[0].Sources)
+	assert.Equal(t, []string{"gcr.io"}, groups[1].Sources)
+
+	for _, value := range []string{"docker.io", "=docker.io", "harbor-a.example.com=", "harbor-a.example.com= , "} {
+		_, err := parseSkeletonGroups([]string{value})
+		require.ErrorAs(t, err, &exitErr, value)
+		assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	}
+}
+
+func TestCreateConfigSkeletonGroups(t *testing.T) {
+	memFs := afero.NewMemMapFs()
+	oldFs := AppFs
+	AppFs = memFs
+	defer func() { AppFs = oldFs }()
+
+	images := []ImageInfo{
+		{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25"},
+		{Registry: "quay.io", Repository: "opstree/redis", Tag: "v7"},
+		{Registry: "gcr.io", Repository: "cloudsql-docker/gce-proxy", Tag: "1.33"},
+	}
+	groups, err := parseSkeletonGroups([]string{"harbor-a.example.com=docker.io,quay.io"})
+	require.NoError(t, err)
+	require.NoError(t, createConfigSkeleton(images, groups, "skeleton.yaml"))
+
+	config, err := registry.LoadStructuredConfig(memFs, "skeleton.yaml", true)
+	require.NoError(t, err)
+	assert.Equal(t, []registry.Mapping{
+		{Source: "gcr.io", Target: "registry.local/gcr-io"},
+		{Source: "docker.io", Target: "harbor-a.example.com"},
+		{Source: "quay.io", Target: "harbor-a.example.com"},
+	}, config.ToMappings().Entries)
+}
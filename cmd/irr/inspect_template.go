@@ -0,0 +1,456 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/action"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/releaseutil"
+
+	"github.com/lucas-albers-lz4/irr/internal/helm"
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+)
+
+const (
+	// analysisModeValues finds images in the chart values (the default)
+	analysisModeValues = "values"
+	// analysisModeTemplate finds images in the workloads of the rendered chart
+	analysisModeTemplate = "template"
+	// analysisModeCrossCheck finds images in the values and reports how they differ from the
+	// images of the rendered workloads
+	analysisModeCrossCheck = "cross-check"
+
+	// templateScanReleaseName is the release name charts are rendered with for template analysis
+	templateScanReleaseName = "irr-template-scan"
+
+	// helmHookAnnotation lists the hook events of a hook resource, e.g. test or pre-install,pre-upgrade
+	helmHookAnnotation = "helm.sh/hook"
+	// helmTestHook is the hook event of chart tests
+	helmTestHook = "test"
+	// helmTestTemplatesDir is the chart directory holding test templates
+	helmTestTemplatesDir = "/templates/tests/"
+	// hookImageRecommendation is reported for images hard-coded in hooks and tests
+	hookImageRecommendation = "hard-coded in a Helm hook or test template, so it cannot be overridden through values: " +
+		"mirror it unchanged to the target registry, or patch the chart to read it from values"
+)
+
+// analysisModes are the values accepted by --analysis-mode
+var analysisModes = []string{analysisModeValues, analysisModeTemplate, analysisModeCrossCheck}
+
+// TemplateCrossCheck compares the images found in the values with those of the rendered workloads
+type TemplateCrossCheck struct {
+	// RenderedImages is the number of distinct images in the rendered workloads
+	RenderedImages int `json:"renderedImages" yaml:"renderedImages"`
+	// TemplateOnly lists rendered images that values analysis did not find
+	TemplateOnly []ImageInfo `json:"templateOnly,omitempty" yaml:"templateOnly,omitempty"`
+	// ValuesOnly lists the values paths of images no rendered workload uses, e.g. of disabled components
+	ValuesOnly []string `json:"valuesOnly,omitempty" yaml:"valuesOnly,omitempty"`
+}
+
+// renderedImage is a container image of a rendered workload
+type renderedImage struct {
+	// Image is the image reference as rendered
+	Image string
+	// Workload is the resource using the image, as Kind/name
+	Workload string
+	// Container is the name of the container using the image
+	Container string
+	// Template is the chart template that rendered the workload, from Helm's "# Source:" comment
+	Template string
+	// Hook lists the Helm hook events of the workload, e.g. test or pre-install,pre-upgrade; empty
+	// for workloads that are not hooks
+	Hook string
+}
+
+// location returns where the image is used, as Kind/name/container
+func (r renderedImage) location() string {
+	return r.Workload + "/" + r.Container
+}
+
+// getAnalysisMode reads and validates the --analysis-mode flag
+func getAnalysisMode(cmd *cobra.Command) (string, error) {
+	mode, err := cmd.Flags().GetString("analysis-mode")
+	if err != nil {
+		return "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get analysis-mode flag: %w", err),
+		}
+	}
+	if !slices.Contains(analysisModes, mode) {
+		return "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("unsupported analysis mode %q; supported modes: %s", mode, strings.Join(analysisModes, ", ")),
+		}
+	}
+	return mode, nil
+}
+
+// validateAnalysisMode checks that a template-based analysis mode is used with a chart path only,
+// since it renders the chart.
+func validateAnalysisMode(flags *InspectFlags, releaseNameProvided, recursive bool) error {
+	if flags.AnalysisMode == analysisModeValues {
+		return nil
+	}
+	if releaseNameProvided || flags.AllNamespaces || recursive {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err: fmt.Errorf("--analysis-mode %s renders a single chart and cannot be used with a release name, --all-namespaces or --recursive",
+				flags.AnalysisMode),
+		}
+	}
+	return nil
+}
+
+// renderChartManifest renders the chart at chartPath client-side with the values of the
+// --values and --set flags (of every kind) and the capabilities of the capability flags.
+// It returns the chart as rendered, with its dependencies processed, together with the manifest,
+// which includes the hooks and tests Helm keeps apart from the release manifest.
+func renderChartManifest(cmd *cobra.Command, chartPath, releaseName string) (*helmchart.Chart, string, error) {
+	valueOpts := values.Options{}
+	var err error
+	if valueOpts.ValueFiles, err = cmd.Flags().GetStringSlice("values"); err != nil {
+		return nil, "", fmt.Errorf("failed to get values files: %w", err)
+	}
+	if setValues, err := cmd.Flags().GetStringSlice("set"); err == nil {
+		valueOpts.Values = setValues
+	}
+	if setStringValues, err := cmd.Flags().GetStringSlice("set-string"); err == nil {
+		valueOpts.StringValues = setStringValues
+	}
+	if setFileValues, err := cmd.Flags().GetStringSlice("set-file"); err == nil {
+		valueOpts.FileValues = setFileValues
+	}
+	if setJSONValues, err := cmd.Flags().GetStringArray("set-json"); err == nil {
+		valueOpts.JSONValues = setJSONValues
+	}
+	if setLiteralValues, err := cmd.Flags().GetStringArray("set-literal"); err == nil {
+		valueOpts.LiteralValues = setLiteralValues
+	}
+
+	loadedChart, vals, err := helm.NewChartLoader().LoadChartWithValues(&helm.ChartLoaderOptions{
+		ChartPath:  chartPath,
+		ValuesOpts: valueOpts,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load chart for rendering: %w", err)
+	}
+
+	// Match the target cluster's capabilities so charts gated on .Capabilities.APIVersions.Has
+	// render the same workloads the analyzer expects
+	capabilities, err := getCapabilityFlags(cmd)
+	if err != nil {
+		return nil, "", err
+	}
+	apiVersions, err := helm.ResolveAPIVersions(helm.NewSettings(), capabilities.APIVersions, capabilities.FromCluster)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve API versions: %w", err)
+	}
+
+	installAction := action.NewInstall(new(action.Configuration))
+	installAction.DryRun = true
+	installAction.ReleaseName = releaseName
+	installAction.Namespace = validateTestNamespace
+	installAction.ClientOnly = true
+	installAction.APIVersions = apiVersions
+
+	release, err := installAction.Run(loadedChart, vals)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to render chart %s: %w", loadedChart.Name(), err)
+	}
+	if release == nil || (release.Manifest == "" && len(release.Hooks) == 0) {
+		return nil, "", fmt.Errorf("rendering chart %s produced no manifests", loadedChart.Name())
+	}
+	var manifest strings.Builder
+	manifest.WriteString(release.Manifest)
+	for _, hook := range release.Hooks {
+		// Hook manifests lack the "# Source:" comment Helm writes for the other templates
+		fmt.Fprintf(&manifest, "---\n%s %s\n%s\n", helmSourcePrefix, hook.Path, hook.Manifest)
+	}
+	return loadedChart, manifest.String(), nil
+}
+
+// extractWorkloadImages returns the container images of the Deployments, StatefulSets,
+// DaemonSets, ReplicaSets, Jobs, CronJobs and Pods in a rendered manifest, including init and
+// ephemeral containers and the workloads of hooks and tests, ordered by workload.
+func extractWorkloadImages(manifest string) []renderedImage {
+	var images []renderedImage
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		var resource map[string]interface{}
+		if err := yaml.Unmarshal([]byte(doc), &resource); err != nil {
+			log.Warn("Error parsing rendered template document", "error", err)
+			continue
+		}
+		kind, _ := resource["kind"].(string)
+		podSpec := workloadPodSpec(kind, resource)
+		if podSpec == nil {
+			continue
+		}
+		name, _ := nestedValue(resource, "metadata", "name").(string)
+		workload := kind + "/" + name
+		template := templateSource(doc)
+		hook := workloadHook(resource, template)
+		for _, containerType := range []string{"initContainers", "containers", "ephemeralContainers"} {
+			containers, _ := podSpec[containerType].([]interface{})
+			for _, c := range containers {
+				container, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				imageValue, _ := container["image"].(string)
+				if imageValue == "" {
+					continue
+				}
+				containerName, _ := container["name"].(string)
+				images = append(images, renderedImage{Image: imageValue, Workload: workload, Container: containerName, Template: template, Hook: hook})
+			}
+		}
+	}
+	sort.SliceStable(images, func(i, j int) bool { return images[i].location() < images[j].location() })
+	return images
+}
+
+// workloadPodSpec returns the pod spec of a workload resource, or nil for other kinds
+func workloadPodSpec(kind string, resource map[string]interface{}) map[string]interface{} {
+	var podSpec interface{}
+	switch kind {
+	case "Pod":
+		podSpec = resource["spec"]
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job":
+		podSpec = nestedValue(resource, "spec", "template", "spec")
+	case "CronJob":
+		podSpec = nestedValue(resource, "spec", "jobTemplate", "spec", "template", "spec")
+	}
+	spec, _ := podSpec.(map[string]interface{})
+	return spec
+}
+
+// workloadHook returns the Helm hook events of a rendered resource from its helm.sh/hook
+// annotation. Resources of test templates without the annotation are tests as well.
+func workloadHook(resource map[string]interface{}, template string) string {
+	if hook, _ := nestedValue(resource, "metadata", "annotations", helmHookAnnotation).(string); hook != "" {
+		return strings.ReplaceAll(hook, " ", "")
+	}
+	if strings.Contains(template, helmTestTemplatesDir) {
+		return helmTestHook
+	}
+	return ""
+}
+
+// nestedValue returns the value at the given keys of nested maps, or nil
+func nestedValue(value interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+// imageMatchKey identifies an image for matching rendered images to values images: the
+// normalized registry and repository, with the tag and digest when withVersion is set
+func imageMatchKey(registryName, repository, tag, digest string, withVersion bool) string {
+	registryName = image.NormalizeRegistry(registryName)
+	if registryName == registry.DockerHubRegistry && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+	key := registryName + "/" + repository
+	if withVersion {
+		key += ":" + tag + "@" + digest
+	}
+	return key
+}
+
+// valuesImageIndex maps images found by values analysis to their values paths
+type valuesImageIndex struct {
+	exact map[string][]string
+	loose map[string][]string
+}
+
+// newValuesImageIndex indexes images by their full reference and by registry and repository
+func newValuesImageIndex(images []ImageInfo) *valuesImageIndex {
+	index := &valuesImageIndex{exact: make(map[string][]string), loose: make(map[string][]string)}
+	for _, img := range images {
+		path := imageInfoPath(img)
+		exact := imageMatchKey(img.Registry, img.Repository, img.Tag, img.Digest, true)
+		loose := imageMatchKey(img.Registry, img.Repository, "", "", false)
+		if !slices.Contains(index.exact[exact], path) {
+			index.exact[exact] = append(index.exact[exact], path)
+		}
+		if !slices.Contains(index.loose[loose], path) {
+			index.loose[loose] = append(index.loose[loose], path)
+		}
+	}
+	return index
+}
+
+// paths returns the values paths of the image matching ref: the paths of identical images, or
+// failing that of images with the same registry and repository, since values often leave the tag
+// to the chart's appVersion
+func (x *valuesImageIndex) paths(ref *image.Reference) []string {
+	if paths := x.exact[imageMatchKey(ref.Registry, ref.Repository, ref.Tag, ref.Digest, true)]; len(paths) > 0 {
+		return paths
+	}
+	return x.loose[imageMatchKey(ref.Registry, ref.Repository, "", "", false)]
+}
+
+// templateImageInfos converts rendered images into ImageInfos, one per image and values path it
+// maps back to, or one per image with an empty Source when it maps to none. Workloads lists where
+// each image is used and Hooks the hook events of the hooks and tests using it; images that map to
+// no values path carry the paths hints infers from the chart's templates, and images of hooks that
+// have none are hard-coded and carry a recommendation.
+func templateImageInfos(rendered []renderedImage, index *valuesImageIndex, hints map[string]*valuePathHint) (images []ImageInfo, skipped []string) {
+	var order []string
+	workloads := make(map[string][]string)
+	hooks := make(map[string][]string)
+	for _, r := range rendered {
+		if _, seen := workloads[r.Image]; !seen {
+			order = append(order, r.Image)
+		}
+		if !slices.Contains(workloads[r.Image], r.location()) {
+			workloads[r.Image] = append(workloads[r.Image], r.location())
+		}
+		for _, event := range strings.Split(r.Hook, ",") {
+			if event != "" && !slices.Contains(hooks[r.Image], event) {
+				hooks[r.Image] = append(hooks[r.Image], event)
+			}
+		}
+	}
+
+	for _, ref := range order {
+		parsed, err := image.ParseRenderedImageReference(ref)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %s (parse error: %v)", strings.Join(workloads[ref], ", "), ref, err))
+			continue
+		}
+		base := ImageInfo{
+			Registry:   parsed.Registry,
+			Repository: parsed.Repository,
+			Tag:        parsed.Tag,
+			Digest:     parsed.Digest,
+			Workloads:  workloads[ref],
+			Hooks:      hooks[ref],
+		}
+		paths := index.paths(parsed)
+		if len(paths) == 0 {
+			switch hint := hints[ref]; {
+			case hint != nil:
+				base.LikelyValuePaths = hint.Paths
+				base.Subchart = hint.Subchart
+				log.Info("Rendered image likely comes from values path inferred from templates",
+					"image", ref, "valuePaths", strings.Join(hint.Paths, ", "), "subchart", hint.Subchart)
+			case len(base.Hooks) > 0:
+				base.Recommendation = hookImageRecommendation
+				log.Warn("Image is hard-coded in a Helm hook or test and cannot be overridden through values",
+					"image", ref, "workloads", strings.Join(base.Workloads, ", "), "hooks", strings.Join(base.Hooks, ","),
+					"recommendation", hookImageRecommendation)
+			}
+			images = append(images, base)
+			continue
+		}
+		for _, path := range paths {
+			img := base
+			img.Source = path
+			img.ValuePath = path
+			images = append(images, img)
+		}
+	}
+	return images, skipped
+}
+
+// applyTemplateAnalysis renders the chart and, in template mode, replaces the images found in
+// the values with those of the rendered workloads, mapped back to values paths where possible.
+// In cross-check mode the values images are kept and compared with the rendered ones.
+func applyTemplateAnalysis(cmd *cobra.Command, chartPath, mode string, analysisResult *ImageAnalysis) error {
+	rendered, hints, err := renderTemplateScan(cmd, chartPath)
+	if err != nil {
+		return err
+	}
+	applyRenderedImages(rendered, hints, mode, analysisResult)
+	return nil
+}
+
+// renderTemplateScan renders the chart and returns the images of its workloads together with the
+// values paths inferred from the chart's templates for each image
+func renderTemplateScan(cmd *cobra.Command, chartPath string) ([]renderedImage, map[string]*valuePathHint, error) {
+	loadedChart, manifest, err := renderChartManifest(cmd, chartPath, templateScanReleaseName)
+	if err != nil {
+		var exitErr *exitcodes.ExitCodeError
+		if errors.As(err, &exitErr) {
+			return nil, nil, err
+		}
+		return nil, nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitHelmTemplateFailed,
+			Err:  fmt.Errorf("template analysis failed: %w", err),
+		}
+	}
+	rendered := extractWorkloadImages(manifest)
+	return rendered, inferValuePathHints(loadedChart, rendered), nil
+}
+
+// applyRenderedImages applies the images of the rendered workloads to the values analysis result
+// according to the analysis mode
+func applyRenderedImages(rendered []renderedImage, hints map[string]*valuePathHint, mode string, analysisResult *ImageAnalysis) {
+	index := newValuesImageIndex(analysisResult.Images)
+	templateImages, skipped := templateImageInfos(rendered, index, hints)
+	analysisResult.AnalysisMode = mode
+
+	renderedPaths := make(map[string]bool)
+	var templateOnly []ImageInfo
+	for _, img := range templateImages {
+		if img.Source == "" {
+			templateOnly = append(templateOnly, img)
+			continue
+		}
+		renderedPaths[img.Source] = true
+	}
+
+	if mode == analysisModeTemplate {
+		analysisResult.Images = templateImages
+		analysisResult.ImagePatterns = slices.DeleteFunc(analysisResult.ImagePatterns, func(p analysis.ImagePattern) bool {
+			return !renderedPaths[p.Path]
+		})
+		analysisResult.Skipped = append(analysisResult.Skipped, skipped...)
+		if len(templateOnly) > 0 {
+			log.Warn("Rendered images could not be mapped back to values paths",
+				"check", "template_analysis",
+				"count", len(templateOnly),
+				"message", "These images are hard-coded in templates or built from values irr does not recognize; see likelyValuePaths, and use override --template-paths to generate overrides at those paths.")
+		}
+		return
+	}
+
+	crossCheck := &TemplateCrossCheck{TemplateOnly: templateOnly}
+	seen := make(map[string]bool)
+	for _, r := range rendered {
+		seen[r.Image] = true
+	}
+	crossCheck.RenderedImages = len(seen)
+	for _, img := range analysisResult.Images {
+		path := imageInfoPath(img)
+		if !renderedPaths[path] && !slices.Contains(crossCheck.ValuesOnly, path) {
+			crossCheck.ValuesOnly = append(crossCheck.ValuesOnly, path)
+		}
+	}
+	sort.Strings(crossCheck.ValuesOnly)
+	analysisResult.TemplateCheck = crossCheck
+
+	if len(templateOnly) > 0 {
+		log.Warn("Rendered images were not found by values analysis",
+			"check", "template_cross_check",
+			"count", len(templateOnly),
+			"message", "These images are used by rendered workloads but were not found in the values; see templateCheck.templateOnly.")
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	"github.com/lucas-albers-lz4/irr/pkg/keys"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+)
+
+const (
+	// helmSourcePrefix starts the comment Helm writes above each rendered document naming its template
+	helmSourcePrefix = "# Source: "
+	// maxIncludeDepth limits how deep named templates are followed when looking for values references
+	maxIncludeDepth = 5
+)
+
+var (
+	// templateValuesRefPattern matches values references such as .Values.image.repository or $.Values.image
+	templateValuesRefPattern = regexp.MustCompile(`\.Values((?:\.[A-Za-z_][A-Za-z0-9_]*)+)`)
+	// templateIncludePattern matches the named template used by an include or template action
+	templateIncludePattern = regexp.MustCompile(`\b(?:include|template)\s+"([^"]+)"`)
+	// templateDefinePattern matches the start of a named template definition
+	templateDefinePattern = regexp.MustCompile(`\{\{-?\s*define\s+"([^"]+)"`)
+	// templateImageLinePattern matches the image field of a container in a template
+	templateImageLinePattern = regexp.MustCompile(`^\s*(?:-\s+)?image:`)
+	// templateNameLinePattern matches the name field of a container in a template
+	templateNameLinePattern = regexp.MustCompile(`^\s*(?:-\s+)?name:\s*["']?([^"'\s]+)["']?\s*$`)
+)
+
+// imageValueFields are the keys below an image's values path that templates compose its reference from
+var imageValueFields = map[string]bool{keys.Registry: true, keys.Repository: true, keys.Tag: true, keys.Digest: true}
+
+// valuePathHint is where in the chart values a rendered image likely comes from, inferred from the
+// values references of the template that rendered it
+type valuePathHint struct {
+	// Subchart is the dependency path of the chart whose template rendered the image, as
+	// parent/child; empty for the top-level chart
+	Subchart string
+	// Paths are the candidate values paths, relative to the top-level chart's values
+	Paths []string
+	// Fields maps each path to the image keys the template reads below it, e.g. repository and
+	// tag; a path without fields is read as a whole
+	Fields map[string][]string
+}
+
+// templateSource returns the template named by the "# Source:" comment of a rendered document
+func templateSource(doc string) string {
+	for _, line := range strings.Split(doc, "\n") {
+		if source, ok := strings.CutPrefix(strings.TrimSpace(line), helmSourcePrefix); ok {
+			return strings.TrimSpace(source)
+		}
+	}
+	return ""
+}
+
+// inferValuePathHints infers the values paths of each rendered image from the template that
+// rendered it, keyed by image reference. Images whose templates could not be found or reference
+// no values have no hint.
+func inferValuePathHints(root *helmchart.Chart, rendered []renderedImage) map[string]*valuePathHint {
+	hints := make(map[string]*valuePathHint)
+	if root == nil {
+		return hints
+	}
+	defines := namedTemplates(root)
+	for _, r := range rendered {
+		hint := inferValuePathHint(root, defines, r)
+		if hint == nil {
+			continue
+		}
+		existing, ok := hints[r.Image]
+		if !ok {
+			hints[r.Image] = hint
+			continue
+		}
+		for _, path := range hint.Paths {
+			if !slices.Contains(existing.Paths, path) {
+				existing.Paths = append(existing.Paths, path)
+			}
+			for _, field := range hint.Fields[path] {
+				if !slices.Contains(existing.Fields[path], field) {
+					existing.Fields[path] = append(existing.Fields[path], field)
+				}
+			}
+		}
+	}
+	return hints
+}
+
+// inferValuePathHint infers the values paths of a rendered image from the image field of its
+// container in the template that rendered it
+func inferValuePathHint(root *helmchart.Chart, defines map[string]string, r renderedImage) *valuePathHint {
+	owner, templateName, chain := resolveTemplateChart(root, r.Template)
+	if owner == nil {
+		return nil
+	}
+	var data string
+	for _, file := range owner.Templates {
+		if file != nil && file.Name == templateName {
+			data = string(file.Data)
+			break
+		}
+	}
+	if data == "" {
+		return nil
+	}
+
+	paths, fields := imageValuePaths(templateImageRefs(data, r.Container, defines))
+	if len(paths) == 0 {
+		return nil
+	}
+	prefix := strings.Join(chain, ".")
+	hint := &valuePathHint{Subchart: strings.Join(chain, "/"), Fields: make(map[string][]string)}
+	for _, path := range paths {
+		fullPath := path
+		if prefix != "" {
+			fullPath = prefix + "." + path
+		}
+		hint.Paths = append(hint.Paths, fullPath)
+		if len(fields[path]) > 0 {
+			hint.Fields[fullPath] = fields[path]
+		}
+	}
+	return hint
+}
+
+// resolveTemplateChart finds the chart owning a template named as in Helm's "# Source:" comment,
+// e.g. web/charts/redis/templates/statefulset.yaml. It returns the chart, the template name
+// within it and the values keys of the subcharts leading to it, or a nil chart if there is none.
+func resolveTemplateChart(root *helmchart.Chart, source string) (*helmchart.Chart, string, []string) {
+	parts := strings.Split(source, "/")
+	if len(parts) < 2 {
+		return nil, "", nil
+	}
+	current := root
+	var chain []string
+	rest := parts[1:]
+	for len(rest) > 2 && rest[0] == "charts" {
+		dep := findDependency(current, rest[1])
+		if dep == nil {
+			return nil, "", nil
+		}
+		chain = append(chain, rest[1])
+		current = dep
+		rest = rest[2:]
+	}
+	return current, strings.Join(rest, "/"), chain
+}
+
+// findDependency returns the dependency of c named name, either by its chart name or its alias
+func findDependency(c *helmchart.Chart, name string) *helmchart.Chart {
+	chartName := name
+	if c.Metadata != nil {
+		for _, dep := range c.Metadata.Dependencies {
+			if dep != nil && dep.Alias == name {
+				chartName = dep.Name
+				break
+			}
+		}
+	}
+	for _, dep := range c.Dependencies() {
+		if dep.Name() == name || dep.Name() == chartName {
+			return dep
+		}
+	}
+	return nil
+}
+
+// namedTemplates returns the bodies of the named templates defined anywhere in the chart and its
+// subcharts. A body runs up to the next definition in the same file, which is close enough to find
+// the values a helper reads.
+func namedTemplates(c *helmchart.Chart) map[string]string {
+	defines := make(map[string]string)
+	var walk func(*helmchart.Chart)
+	walk = func(current *helmchart.Chart) {
+		for _, file := range current.Templates {
+			if file == nil {
+				continue
+			}
+			data := string(file.Data)
+			matches := templateDefinePattern.FindAllStringSubmatchIndex(data, -1)
+			for i, m := range matches {
+				end := len(data)
+				if i+1 < len(matches) {
+					end = matches[i+1][0]
+				}
+				name := data[m[2]:m[3]]
+				if _, exists := defines[name]; !exists {
+					defines[name] = data[m[1]:end]
+				}
+			}
+		}
+		for _, dep := range current.Dependencies() {
+			walk(dep)
+		}
+	}
+	walk(c)
+	return defines
+}
+
+// templateImageRefs returns the values paths referenced by the image fields of a template. When
+// the template has several image fields, only the one nearest the container's name field is used.
+// Named templates are followed when an image field references no values directly.
+func templateImageRefs(data, container string, defines map[string]string) []string {
+	lines := strings.Split(data, "\n")
+	var imageLines []int
+	for i, line := range lines {
+		if templateImageLinePattern.MatchString(line) {
+			imageLines = append(imageLines, i)
+		}
+	}
+	if len(imageLines) > 1 {
+		if i := nearestImageLine(lines, imageLines, container); i >= 0 {
+			imageLines = []int{i}
+		}
+	}
+
+	var refs []string
+	for _, i := range imageLines {
+		refs = appendValuesRefs(refs, lines[i], defines, 0)
+	}
+	return refs
+}
+
+// nearestImageLine returns the image line of the container named in the template: the first image
+// line after its name field, or the last one before it when the name comes last. It returns -1 if
+// no line names the container literally.
+func nearestImageLine(lines []string, imageLines []int, container string) int {
+	if container == "" {
+		return -1
+	}
+	for n, line := range lines {
+		m := templateNameLinePattern.FindStringSubmatch(line)
+		if m == nil || m[1] != container {
+			continue
+		}
+		before := -1
+		for _, i := range imageLines {
+			if i > n {
+				return i
+			}
+			before = i
+		}
+		return before
+	}
+	return -1
+}
+
+// appendValuesRefs appends the values paths referenced in text to refs, following the named
+// templates it includes when it references no image values itself
+func appendValuesRefs(refs []string, text string, defines map[string]string, depth int) []string {
+	direct := false
+	for _, m := range templateValuesRefPattern.FindAllStringSubmatch(text, -1) {
+		path := strings.TrimPrefix(m[1], ".")
+		if !isGlobalValuesPath(path) {
+			direct = true
+		}
+		if !slices.Contains(refs, path) {
+			refs = append(refs, path)
+		}
+	}
+	if direct || depth >= maxIncludeDepth {
+		return refs
+	}
+	for _, m := range templateIncludePattern.FindAllStringSubmatch(text, -1) {
+		if body, ok := defines[m[1]]; ok {
+			refs = appendValuesRefs(refs, body, defines, depth+1)
+		}
+	}
+	return refs
+}
+
+// isGlobalValuesPath reports whether a values path is below global, which holds chart-wide
+// settings such as the registry rather than a particular image
+func isGlobalValuesPath(path string) bool {
+	return path == "global" || strings.HasPrefix(path, "global.")
+}
+
+// imageValuePaths reduces values references to image values paths: a reference to the registry,
+// repository, tag or digest below a path is a reference to the image at that path. Global
+// references are dropped.
+func imageValuePaths(refs []string) (paths []string, fields map[string][]string) {
+	fields = make(map[string][]string)
+	for _, ref := range refs {
+		if isGlobalValuesPath(ref) {
+			continue
+		}
+		path, field := ref, ""
+		if i := strings.LastIndex(ref, "."); i > 0 && imageValueFields[ref[i+1:]] {
+			path, field = ref[:i], ref[i+1:]
+		}
+		if !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+		if field != "" && !slices.Contains(fields[path], field) {
+			fields[path] = append(fields[path], field)
+		}
+	}
+	return paths, fields
+}
+
+// templatePathPatterns renders the chart and returns image patterns at the values paths inferred
+// from its templates for rendered images that the existing patterns do not cover (--template-paths)
+func templatePathPatterns(cmd *cobra.Command, chartPath string, existing []analysis.ImagePattern) ([]analysis.ImagePattern, error) {
+	rendered, hints, err := renderTemplateScan(cmd, chartPath)
+	if err != nil {
+		return nil, err
+	}
+	return inferredImagePatterns(rendered, hints, existing), nil
+}
+
+// inferredImagePatterns returns an image pattern for each rendered image that no existing pattern
+// covers and whose template points at a single values path. Images with several candidate paths
+// are only reported, since an override at the wrong path would have no effect, as are images
+// hard-coded in hooks and tests, which must be mirrored unchanged.
+func inferredImagePatterns(rendered []renderedImage, hints map[string]*valuePathHint, existing []analysis.ImagePattern) []analysis.ImagePattern {
+	coveredPaths := make(map[string]bool)
+	knownImages := make(map[string]bool)
+	for _, p := range existing {
+		coveredPaths[p.Path] = true
+		if ref, err := image.ParseImageReference(p.Value); err == nil {
+			knownImages[imageMatchKey(ref.Registry, ref.Repository, "", "", false)] = true
+		}
+	}
+
+	var patterns []analysis.ImagePattern
+	hardCoded := make(map[string]bool)
+	for _, r := range rendered {
+		ref, err := image.ParseRenderedImageReference(r.Image)
+		if err != nil {
+			continue
+		}
+		key := imageMatchKey(ref.Registry, ref.Repository, "", "", false)
+		if knownImages[key] {
+			continue
+		}
+		hint := hints[r.Image]
+		if hint == nil {
+			if r.Hook != "" && !hardCoded[key] {
+				hardCoded[key] = true
+				log.Warn("Image is hard-coded in a Helm hook or test and cannot be overridden through values",
+					"image", r.Image, "workload", r.location(), "hooks", r.Hook, "template", r.Template,
+					"recommendation", hookImageRecommendation)
+			}
+			continue
+		}
+		if len(hint.Paths) != 1 {
+			log.Warn("Not generating an override for rendered image with several likely values paths",
+				"image", r.Image, "valuePaths", strings.Join(hint.Paths, ", "), "template", r.Template)
+			continue
+		}
+		path := hint.Paths[0]
+		if coveredPaths[path] {
+			continue
+		}
+		coveredPaths[path] = true
+		knownImages[key] = true
+
+		pattern := analysis.ImagePattern{
+			Path:         path,
+			Type:         analysis.PatternTypeString,
+			Value:        r.Image,
+			Count:        1,
+			SourceOrigin: r.Template,
+		}
+		if len(hint.Fields[path]) > 0 {
+			pattern.Type = analysis.PatternTypeMap
+			pattern.Structure = map[string]interface{}{
+				keys.Registry:   ref.Registry,
+				keys.Repository: ref.Repository,
+				keys.Tag:        ref.Tag,
+			}
+		}
+		log.Info("Generating override at values path inferred from templates", "image", r.Image, "path", path, "template", r.Template)
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// newTemplatePathsChart returns a chart with a values image, an image composed from values
+// fields, an image built by a helper and a subchart aliased as cache
+func newTemplatePathsChart() *helmchart.Chart {
+	redis := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "redis"},
+		Templates: []*helmchart.File{{
+			Name: "templates/statefulset.yaml",
+			Data: []byte(`spec:
+  template:
+    spec:
+      containers:
+        - name: redis
+          image: {{ include "redis.image" . }}
+        - name: exporter
+          image: "{{ .Values.global.imageRegistry }}/{{ .Values.exporter.image.repository }}:{{ .Values.exporter.image.tag }}"
+`),
+		}, {
+			Name: "templates/_helpers.tpl",
+			Data: []byte(`{{- define "redis.image" -}}
+{{ .Values.image.registry }}/{{ .Values.image.repository }}:{{ .Values.image.tag }}
+{{- end }}
+{{- define "redis.fullname" -}}
+{{ .Values.fullnameOverride }}
+{{- end }}
+`),
+		}},
+	}
+	web := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{
+			Name:         "web",
+			Dependencies: []*helmchart.Dependency{{Name: "redis", Alias: "cache"}},
+		},
+		Templates: []*helmchart.File{{
+			Name: "templates/deployment.yaml",
+			Data: []byte(`spec:
+  template:
+    spec:
+      containers:
+        - name: sidecar
+          image: {{ .Values.sidecar }}
+        - name: proxy
+          image: "{{ .Values.proxyRegistry }}/{{ .Values.proxyRepository }}"
+`),
+		}},
+	}
+	web.AddDependency(redis)
+	return web
+}
+
+func TestInferValuePathHints(t *testing.T) {
+	rendered := []renderedImage{
+		{Image: "docker.io/bitnami/redis:7.2", Container: "redis", Template: "web/charts/cache/templates/statefulset.yaml"},
+		{Image: "quay.io/oliver006/redis_exporter:1.5", Container: "exporter", Template: "web/charts/cache/templates/statefulset.yaml"},
+		{Image: "envoyproxy/envoy:v1.30", Container: "sidecar", Template: "web/templates/deployment.yaml"},
+		{Image: "ghcr.io/org/proxy:1.0", Container: "proxy", Template: "web/templates/deployment.yaml"},
+		{Image: "busybox:1.36", Container: "init", Template: "web/templates/missing.yaml"},
+	}
+	hints := inferValuePathHints(newTemplatePathsChart(), rendered)
+
+	require.Contains(t, hints, "docker.io/bitnami/redis:7.2")
+	assert.Equal(t, &valuePathHint{
+		Subchart: "cache",
+		Paths:    []string{"cache.image"},
+		Fields:   map[string][]string{"cache.image": {"registry", "repository", "tag"}},
+	}, hints["docker.io/bitnami/redis:7.2"], "follows the named template and prefixes the subchart alias")
+
+	require.Contains(t, hints, "quay.io/oliver006/redis_exporter:1.5")
+	assert.Equal(t, []string{"cache.exporter.image"}, hints["quay.io/oliver006/redis_exporter:1.5"].Paths, "global references are ignored")
+
+	require.Contains(t, hints, "envoyproxy/envoy:v1.30")
+	assert.Equal(t, []string{"sidecar"}, hints["envoyproxy/envoy:v1.30"].Paths)
+	assert.Empty(t, hints["envoyproxy/envoy:v1.30"].Fields)
+
+	require.Contains(t, hints, "ghcr.io/org/proxy:1.0")
+	assert.Equal(t, []string{"proxyRegistry", "proxyRepository"}, hints["ghcr.io/org/proxy:1.0"].Paths)
+
+	assert.NotContains(t, hints, "busybox:1.36")
+}
+
+func TestTemplateSource(t *testing.T) {
+	assert.Equal(t, "web/charts/redis/templates/statefulset.yaml",
+		templateSource("---\n# Source: web/charts/redis/templates/statefulset.yaml\napiVersion: apps/v1\n"))
+	assert.Empty(t, templateSource("apiVersion: v1\nkind: Pod\n"))
+}
+
+func TestInferredImagePatterns(t *testing.T) {
+	rendered := []renderedImage{
+		{Image: "docker.io/bitnami/redis:7.2", Template: "web/charts/cache/templates/statefulset.yaml"},
+		{Image: "envoyproxy/envoy:v1.30", Template: "web/templates/deployment.yaml"},
+		{Image: "ghcr.io/org/proxy:1.0", Template: "web/templates/deployment.yaml"},
+		{Image: "docker.io/library/nginx:1.25", Template: "web/templates/deployment.yaml"},
+	}
+	hints := map[string]*valuePathHint{
+		"docker.io/bitnami/redis:7.2":  {Paths: []string{"cache.image"}, Fields: map[string][]string{"cache.image": {"repository", "tag"}}},
+		"envoyproxy/envoy:v1.30":       {Paths: []string{"sidecar"}},
+		"ghcr.io/org/proxy:1.0":        {Paths: []string{"proxyRegistry", "proxyRepository"}},
+		"docker.io/library/nginx:1.25": {Paths: []string{"web.image"}},
+	}
+	existing := []analysis.ImagePattern{{Path: "image", Type: analysis.PatternTypeString, Value: "nginx:1.24"}}
+
+	assert.Equal(t, []analysis.ImagePattern{
+		{
+			Path:         "cache.image",
+			Type:         analysis.PatternTypeMap,
+			Value:        "docker.io/bitnami/redis:7.2",
+			Count:        1,
+			SourceOrigin: "web/charts/cache/templates/statefulset.yaml",
+			Structure:    map[string]interface{}{"registry": "docker.io", "repository": "bitnami/redis", "tag": "7.2"},
+		},
+		{
+			Path:         "sidecar",
+			Type:         analysis.PatternTypeString,
+			Value:        "envoyproxy/envoy:v1.30",
+			Count:        1,
+			SourceOrigin: "web/templates/deployment.yaml",
+		},
+	}, inferredImagePatterns(rendered, hints, existing), "skips ambiguous images and images values analysis found")
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const templateScanManifest = `---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: init
+          image: busybox:1.36
+      containers:
+        - name: nginx
+          image: docker.io/bitnami/nginx:1.25
+---
+# Source: web/templates/cronjob.yaml
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+            - name: backup
+              image: quay.io/org/backup:2.0
+---
+# Source: web/templates/pod.yaml
+apiVersion: v1
+kind: Pod
+metadata:
+  name: debug
+spec:
+  containers:
+    - name: debug
+      image: docker.io/bitnami/nginx:1.25
+---
+# Source: web/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  image: ghcr.io/org/ignored:1.0
+`
+
+func TestExtractWorkloadImages(t *testing.T) {
+	images := extractWorkloadImages(templateScanManifest)
+	assert.Equal(t, []renderedImage{
+		{Image: "quay.io/org/backup:2.0", Workload: "CronJob/backup", Container: "backup", Template: "web/templates/cronjob.yaml"},
+		{Image: "busybox:1.36", Workload: "Deployment/web", Container: "init", Template: "web/templates/deployment.yaml"},
+		{Image: "docker.io/bitnami/nginx:1.25", Workload: "Deployment/web", Container: "nginx", Template: "web/templates/deployment.yaml"},
+		{Image: "docker.io/bitnami/nginx:1.25", Workload: "Pod/debug", Container: "debug", Template: "web/templates/pod.yaml"},
+	}, images)
+}
+
+const hookScanManifest = `---
+# Source: web/templates/migrate-job.yaml
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    "helm.sh/hook": pre-install, pre-upgrade
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: docker.io/org/migrate:1.0
+---
+# Source: web/templates/tests/test-connection.yaml
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web-test-connection
+spec:
+  containers:
+    - name: wget
+      image: busybox:1.36
+`
+
+func TestExtractWorkloadImagesHooks(t *testing.T) {
+	assert.Equal(t, []renderedImage{
+		{Image: "docker.io/org/migrate:1.0", Workload: "Job/migrate", Container: "migrate", Template: "web/templates/migrate-job.yaml",
+			Hook: "pre-install,pre-upgrade"},
+		{Image: "busybox:1.36", Workload: "Pod/web-test-connection", Container: "wget", Template: "web/templates/tests/test-connection.yaml",
+			Hook: "test"},
+	}, extractWorkloadImages(hookScanManifest))
+}
+
+func TestTemplateImageInfosHooks(t *testing.T) {
+	rendered := extractWorkloadImages(hookScanManifest)
+	result := &ImageAnalysis{}
+	applyRenderedImages(rendered, nil, analysisModeTemplate, result)
+	assert.Equal(t, []ImageInfo{
+		{Registry: "docker.io", Repository: "org/migrate", Tag: "1.0", Workloads: []string{"Job/migrate/migrate"},
+			Hooks: []string{"pre-install", "pre-upgrade"}, Recommendation: hookImageRecommendation},
+		{Registry: "docker.io", Repository: "library/busybox", Tag: "1.36", Workloads: []string{"Pod/web-test-connection/wget"},
+			Hooks: []string{"test"}, Recommendation: hookImageRecommendation},
+	}, result.Images)
+
+	hints := map[string]*valuePathHint{"busybox:1.36": {Paths: []string{"tests.image"}}}
+	result = &ImageAnalysis{}
+	applyRenderedImages(rendered, hints, analysisModeTemplate, result)
+	require.Len(t, result.Images, 2)
+	assert.Equal(t, []string{"test"}, result.Images[1].Hooks)
+	assert.Empty(t, result.Images[1].Recommendation, "a test image read from values can be overridden")
+	assert.Equal(t, []string{"tests.image"}, result.Images[1].LikelyValuePaths)
+}
+
+func TestApplyRenderedImages(t *testing.T) {
+	newResult := func() *ImageAnalysis {
+		return &ImageAnalysis{
+			Images: []ImageInfo{
+				// The tag comes from the chart's appVersion, so only registry and repository match
+				{Registry: "docker.io", Repository: "bitnami/nginx", Source: "image", ValuePath: "image"},
+				{Registry: "", Repository: "busybox", Tag: "1.36", Source: "init.image", ValuePath: "init.image"},
+				{Registry: "docker.io", Repository: "bitnami/metrics", Tag: "0.1", Source: "metrics.image", ValuePath: "metrics.image"},
+			},
+			ImagePatterns: []analysis.ImagePattern{{Path: "image"}, {Path: "init.image"}, {Path: "metrics.image"}},
+		}
+	}
+	rendered := extractWorkloadImages(templateScanManifest)
+	hints := map[string]*valuePathHint{"quay.io/org/backup:2.0": {Paths: []string{"backup.image"}}}
+
+	t.Run("template", func(t *testing.T) {
+		result := newResult()
+		applyRenderedImages(rendered, hints, analysisModeTemplate, result)
+		assert.Equal(t, analysisModeTemplate, result.AnalysisMode)
+		assert.Equal(t, []ImageInfo{
+			{Registry: "quay.io", Repository: "org/backup", Tag: "2.0", Workloads: []string{"CronJob/backup/backup"},
+				LikelyValuePaths: []string{"backup.image"}},
+			{Registry: "docker.io", Repository: "library/busybox", Tag: "1.36", Source: "init.image", ValuePath: "init.image",
+				Workloads: []string{"Deployment/web/init"}},
+			{Registry: "docker.io", Repository: "bitnami/nginx", Tag: "1.25", Source: "image", ValuePath: "image",
+				Workloads: []string{"Deployment/web/nginx", "Pod/debug/debug"}},
+		}, result.Images)
+		assert.Equal(t, []analysis.ImagePattern{{Path: "image"}, {Path: "init.image"}}, result.ImagePatterns)
+		assert.Nil(t, result.TemplateCheck)
+	})
+
+	t.Run("cross-check", func(t *testing.T) {
+		result := newResult()
+		applyRenderedImages(rendered, nil, analysisModeCrossCheck, result)
+		assert.Equal(t, newResult().Images, result.Images, "cross-check keeps the values images")
+		require.NotNil(t, result.TemplateCheck)
+		assert.Equal(t, 3, result.TemplateCheck.RenderedImages)
+		require.Len(t, result.TemplateCheck.TemplateOnly, 1)
+		assert.Equal(t, "org/backup", result.TemplateCheck.TemplateOnly[0].Repository)
+		assert.Equal(t, []string{"metrics.image"}, result.TemplateCheck.ValuesOnly)
+	})
+}
+
+func TestGetAnalysisMode(t *testing.T) {
+	cmd := newInspectCmd()
+	mode, err := getAnalysisMode(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, analysisModeValues, mode)
+
+	require.NoError(t, cmd.Flags().Set("analysis-mode", "rendered"))
+	_, err = getAnalysisMode(cmd)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+
+	flags := &InspectFlags{AnalysisMode: analysisModeTemplate}
+	require.NoError(t, validateAnalysisMode(flags, false, false))
+	require.ErrorAs(t, validateAnalysisMode(flags, true, false), &exitErr)
+	require.ErrorAs(t, validateAnalysisMode(flags, false, true), &exitErr)
+	require.NoError(t, validateAnalysisMode(&InspectFlags{AnalysisMode: analysisModeValues}, true, true))
+}
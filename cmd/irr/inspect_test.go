@@ -1045,3 +1045,127 @@ func TestIsValidRegistryHostname(t *testing.T) {
 		})
 	}
 }
+
+func TestCompareReleaseRevisions(t *testing.T) {
+	base := []ImageInfo{
+		{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25", ValuePath: "image"},
+		{Registry: "quay.io", Repository: "prometheus/node-exporter", Tag: "v1", ValuePath: "exporter.image"},
+		{Registry: "docker.io", Repository: "library/redis", Tag: "7", ValuePath: "redis.image"},
+	}
+	compare := []ImageInfo{
+		{Registry: "docker.io", Repository: "library/nginx", Tag: "1.26", ValuePath: "image"},
+		{Registry: "quay.io", Repository: "prometheus/node-exporter", Tag: "v1", ValuePath: "exporter.image"},
+		{Registry: "gcr.io", Repository: "google-containers/pause", Tag: "3.2", ValuePath: "pause.image"},
+	}
+
+	comparison := compareReleaseRevisions(base, compare)
+
+	require.Len(t, comparison.Changed, 1)
+	assert.Equal(t, "image", comparison.Changed[0].Path)
+	assert.Equal(t, "docker.io/library/nginx:1.25", comparison.Changed[0].From)
+	assert.Equal(t, "docker.io/library/nginx:1.26", comparison.Changed[0].To)
+
+	require.Len(t, comparison.Removed, 1)
+	assert.Equal(t, "redis.image", comparison.Removed[0].ValuePath)
+
+	require.Len(t, comparison.Added, 1)
+	assert.Equal(t, "pause.image", comparison.Added[0].ValuePath)
+
+	require.Len(t, comparison.Unchanged, 1)
+	assert.Equal(t, "exporter.image", comparison.Unchanged[0].ValuePath)
+}
+
+func TestFindDuplicateImages(t *testing.T) {
+	images := []ImageInfo{
+		{Registry: "docker.io", Repository: "bitnami/redis", Tag: "7.2", ValuePath: "cache.image"},
+		{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25", ValuePath: "image"},
+		{Registry: "docker.io", Repository: "bitnami/redis", Tag: "7.2", ValuePath: "queue.image"},
+		{Registry: "docker.io", Repository: "bitnami/redis", Tag: "7.2", Source: "sessions.image"},
+		{Registry: "docker.io", Repository: "bitnami/redis", Tag: "7.2", ValuePath: "cache.image"},
+		{Registry: "docker.io", Repository: "bitnami/redis", Tag: "7.0", ValuePath: "legacy.image"},
+	}
+
+	duplicates := findDuplicateImages(images)
+
+	require.Len(t, duplicates, 1)
+	assert.Equal(t, DuplicateImage{
+		Image: "docker.io/bitnami/redis:7.2",
+		Count: 3,
+		Paths: []string{"cache.image", "queue.image", "sessions.image"},
+	}, duplicates[0])
+	assert.Empty(t, findDuplicateImages(images[:2]))
+}
+
+func TestValidateRevisionFlags(t *testing.T) {
+	testCases := []struct {
+		name                string
+		flags               InspectFlags
+		releaseNameProvided bool
+		expectError         bool
+	}{
+		{"No revision flags", InspectFlags{}, false, false},
+		{"Revision with release", InspectFlags{Revision: 2}, true, false},
+		{"Compare revision with release", InspectFlags{Revision: 3, CompareRevision: 2}, true, false},
+		{"Revision without release", InspectFlags{Revision: 2}, false, true},
+		{"Negative revision", InspectFlags{Revision: -1}, true, true},
+		{"Same revisions", InspectFlags{Revision: 2, CompareRevision: 2}, true, true},
+		{"Compare with skeleton", InspectFlags{CompareRevision: 2, GenerateConfigSkeleton: true}, true, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateRevisionFlags(&tc.flags, tc.releaseNameProvided)
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestProcessImagePatterns_Lint(t *testing.T) {
+	patterns := []analysis.ImagePattern{
+		{Path: "app.image", Type: analysis.PatternTypeString, Value: "https://myregistry.com/app:1.0"},
+		{Path: "clean.image", Type: analysis.PatternTypeString, Value: "myregistry.com/clean:1.0"},
+		{
+			Path:      "worker.image",
+			Type:      analysis.PatternTypeMap,
+			Value:     "MyRegistry.COM/worker:2.0",
+			Structure: map[string]interface{}{"registry": "MyRegistry.COM", "repository": "worker/", "tag": "2.0"},
+		},
+	}
+
+	images, skipped := processImagePatterns(patterns)
+
+	assert.Empty(t, skipped)
+	require.Len(t, images, 3)
+	assert.Equal(t, "myregistry.com", images[0].Registry)
+	assert.Equal(t, "app", images[0].Repository)
+	assert.Equal(t, []string{"stripped-scheme"}, images[0].Lint)
+	assert.Empty(t, images[1].Lint)
+	assert.Equal(t, []string{"trimmed-trailing-slash", "lowercased-registry"}, images[2].Lint)
+}
+
+func TestDetectChartInCurrentDirectory(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_, _, err := detectChartInCurrentDirectory(fs)
+	require.Error(t, err)
+
+	// A chart in a parent directory is found by searching upwards
+	cwd, err := filepath.Abs(".")
+	require.NoError(t, err)
+	parent := filepath.Dir(cwd)
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(parent, "Chart.yaml"), []byte("name: parent\n"), 0o644))
+	absPath, relPath, err := detectChartInCurrentDirectory(fs)
+	require.NoError(t, err)
+	assert.Equal(t, parent, absPath)
+	assert.Equal(t, "..", relPath)
+
+	// A chart in the current directory wins
+	require.NoError(t, afero.WriteFile(fs, "Chart.yaml", []byte("name: current\n"), 0o644))
+	absPath, relPath, err = detectChartInCurrentDirectory(fs)
+	require.NoError(t, err)
+	assert.Equal(t, ".", absPath)
+	assert.Equal(t, ".", relPath)
+}
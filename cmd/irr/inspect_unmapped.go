@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+)
+
+// SuggestedMapping is a registry mapping stanza for a registry not covered by the mappings file
+type SuggestedMapping struct {
+	Source string `json:"source" yaml:"source"`
+	Target string `json:"target" yaml:"target"`
+}
+
+// skeletonTarget returns the placeholder target registry suggested for a source registry
+func skeletonTarget(sourceRegistry string) string {
+	return "registry.local/" + strings.ReplaceAll(sourceRegistry, ".", "-")
+}
+
+// filterUnmappedImages keeps only the images whose registry has no mapping in mappings and
+// suggests a mapping for each of those registries. Images already pulled from a mapping
+// target are considered mapped.
+func filterUnmappedImages(analysisResult *ImageAnalysis, mappings *registry.Mappings) {
+	targets := make(map[string]bool)
+	if mappings != nil {
+		for _, mapping := range mappings.Entries {
+			if strings.TrimSpace(mapping.Target) != "" {
+				targets[image.NormalizeRegistry(mapping.Target)] = true
+			}
+		}
+	}
+
+	unmapped := make([]ImageInfo, 0, len(analysisResult.Images))
+	unmappedRegistries := make(map[string]bool)
+	for _, img := range analysisResult.Images {
+		normalized := image.NormalizeRegistry(img.Registry)
+		if targets[normalized] || mappings.GetTargetRegistry(normalized) != "" {
+			continue
+		}
+		unmapped = append(unmapped, img)
+		unmappedRegistries[normalized] = true
+	}
+	log.Info("Filtered results to images from unmapped registries", "images", len(unmapped), "registries", len(unmappedRegistries))
+
+	analysisResult.Images = unmapped
+	analysisResult.SuggestedMappings = suggestMappings(unmappedRegistries)
+}
+
+// suggestMappings returns a placeholder mapping for each registry, sorted by source
+func suggestMappings(registries map[string]bool) []SuggestedMapping {
+	suggestions := make([]SuggestedMapping, 0, len(registries))
+	for _, reg := range sortedKeys(registries) {
+		suggestions = append(suggestions, SuggestedMapping{Source: reg, Target: skeletonTarget(reg)})
+	}
+	return suggestions
+}
+
+// logSuggestedMappings prints the suggested mappings as a stanza ready to paste under
+// registries.mappings in the registry mappings file.
+func logSuggestedMappings(suggestions []SuggestedMapping) {
+	if len(suggestions) == 0 {
+		log.Info("All image registries are covered by the registry mappings file")
+		return
+	}
+
+	var stanza strings.Builder
+	sources := make([]string, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		fmt.Fprintf(&stanza, "    - source: %s\n      target: %s\n", suggestion.Source, suggestion.Target)
+		sources = append(sources, suggestion.Source)
+	}
+	log.Info("Registries not covered by the registry mappings file; add them under registries.mappings:",
+		"registries", strings.Join(sources, ", "))
+	log.Info("\n" + stanza.String())
+}
+
+// applyOnlyUnmapped filters the analysis to unmapped registries when --only-unmapped is set
+func applyOnlyUnmapped(flags *InspectFlags, analysisResult *ImageAnalysis) {
+	if !flags.OnlyUnmapped {
+		return
+	}
+	filterUnmappedImages(analysisResult, flags.Mappings)
+	logSuggestedMappings(analysisResult.SuggestedMappings)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterUnmappedImages(t *testing.T) {
+	analysisResult := &ImageAnalysis{
+		Images: []ImageInfo{
+			{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25", Source: "image"},
+			{Registry: "quay.io", Repository: "prometheus/node-exporter", Tag: "v1.7.0", Source: "exporter.image"},
+			{Registry: "ghcr.io", Repository: "org/app", Tag: "1.0", Source: "app.image"},
+			{Registry: "harbor.local", Repository: "dockerhub/library/redis", Tag: "7.2", Source: "cache.image"},
+			{Registry: "quay.io", Repository: "jetstack/cert-manager", Tag: "v1.14", Source: "certs.image"},
+		},
+	}
+	mappings := &registry.Mappings{Entries: []registry.Mapping{
+		{Source: "docker.io", Target: "harbor.local/dockerhub"},
+	}}
+
+	filterUnmappedImages(analysisResult, mappings)
+
+	paths := make([]string, 0, len(analysisResult.Images))
+	for _, img := range analysisResult.Images {
+		paths = append(paths, img.Source)
+	}
+	assert.Equal(t, []string{"exporter.image", "app.image", "certs.image"}, paths)
+	assert.Equal(t, []SuggestedMapping{
+		{Source: "ghcr.io", Target: "registry.local/ghcr-io"},
+		{Source: "quay.io", Target: "registry.local/quay-io"},
+	}, analysisResult.SuggestedMappings)
+}
+
+func TestApplyOnlyUnmapped_Disabled(t *testing.T) {
+	analysisResult := &ImageAnalysis{Images: []ImageInfo{{Registry: "quay.io", Repository: "org/app"}}}
+	applyOnlyUnmapped(&InspectFlags{}, analysisResult)
+	assert.Len(t, analysisResult.Images, 1)
+	assert.Nil(t, analysisResult.SuggestedMappings)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+)
+
+// interruptSignals are the signals that cancel the running command
+var interruptSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// interruptContext returns a context canceled by the first interrupt or termination signal, so
+// that the command stops at the next safe point and its deferred cleanup, such as the removal of
+// temporary override files, still runs. A second signal exits immediately. The returned function
+// stops listening for signals.
+func interruptContext(parent context.Context) (context.Context, func()) {
+	ctx, stop := signal.NotifyContext(parent, interruptSignals...)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			select {
+			case <-done:
+				// Canceled by the returned function when the command finished, not by a signal
+				return
+			default:
+			}
+			if parent.Err() == nil {
+				log.Warn("Interrupted, stopping and cleaning up; interrupt again to exit immediately")
+			}
+			// Restore the default behavior of the signals for the second interrupt
+			stop()
+		case <-done:
+		}
+	}()
+	return ctx, func() {
+		close(done)
+		stop()
+	}
+}
+
+// checkInterrupted returns an interrupted error once ctx is canceled. Long running commands call
+// it between steps, e.g. before each release or chart, as steps themselves may not be cancelable.
+func checkInterrupted(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInterrupted,
+			Err:  fmt.Errorf("interrupted: %w", err),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterruptContext(t *testing.T) {
+	ctx, stop := interruptContext(context.Background())
+	defer stop()
+	require.NoError(t, checkInterrupted(ctx))
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context not canceled by SIGTERM")
+	}
+
+	err := checkInterrupted(ctx)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInterrupted, exitErr.Code)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, exitcodes.ExitInterrupted, exitcodes.Classify(err).ExitCode)
+}
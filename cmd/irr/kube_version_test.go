@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -23,7 +24,7 @@ func directTemplateMock(t *testing.T, callback func(*helm.TemplateOptions)) func
 	// Add debug info for the test
 	t.Logf("Setting up directTemplateMock")
 
-	helm.HelmTemplateFunc = func(options *helm.TemplateOptions) (*helm.CommandResult, error) {
+	helm.HelmTemplateFunc = func(_ context.Context, options *helm.TemplateOptions) (*helm.CommandResult, error) {
 		// Call the callback with the options received
 		callback(options)
 		// Return successful result with non-empty content
@@ -62,7 +63,7 @@ func TestKubeVersionInValidateChartWithFiles(t *testing.T) {
 
 		// Call the function - Our mock returns success and non-empty content
 		t.Logf("About to call validateChartWithFiles")
-		result, err := validateChartWithFiles(chartPath, releaseName, namespace, valuesFiles, strict, expectedVersion)
+		result, err := validateChartWithFiles(context.Background(), chartPath, releaseName, namespace, valuesFiles, strict, expectedVersion)
 		t.Logf("validateChartWithFiles returned, err=%v, result length=%d", err, len(result))
 		require.NoError(t, err)
 		require.NotEmpty(t, result, "Expected non-empty template result")
@@ -86,7 +87,7 @@ func TestKubeVersionInValidateChartWithFiles(t *testing.T) {
 
 		// Call the function - Our mock returns success and non-empty content
 		t.Logf("About to call validateChartWithFiles")
-		result, err := validateChartWithFiles(chartPath, releaseName, namespace, valuesFiles, strict, expectedVersion)
+		result, err := validateChartWithFiles(context.Background(), chartPath, releaseName, namespace, valuesFiles, strict, expectedVersion)
 		t.Logf("validateChartWithFiles returned, err=%v, result length=%d", err, len(result))
 		require.NoError(t, err)
 		require.NotEmpty(t, result, "Expected non-empty template result")
@@ -139,7 +140,7 @@ func TestKubeVersionPassthrough(t *testing.T) {
 			// Set up mock function for helm.Template
 			var capturedOptions *helm.TemplateOptions
 			if tc.expectError {
-				helm.HelmTemplateFunc = func(options *helm.TemplateOptions) (*helm.CommandResult, error) {
+				helm.HelmTemplateFunc = func(_ context.Context, options *helm.TemplateOptions) (*helm.CommandResult, error) {
 					// Store a copy of the options before returning the error
 					// This fixes an issue where the options weren't being captured correctly
 					capturedOptions = &helm.TemplateOptions{
@@ -156,7 +157,7 @@ func TestKubeVersionPassthrough(t *testing.T) {
 					}, fmt.Errorf("invalid Kubernetes version %q: some error", options.KubeVersion)
 				}
 			} else {
-				helm.HelmTemplateFunc = func(options *helm.TemplateOptions) (*helm.CommandResult, error) {
+				helm.HelmTemplateFunc = func(_ context.Context, options *helm.TemplateOptions) (*helm.CommandResult, error) {
 					capturedOptions = options
 					return &helm.CommandResult{
 						Success: true,
@@ -172,7 +173,7 @@ func TestKubeVersionPassthrough(t *testing.T) {
 			valuesFiles := []string{"/path/to/values.yaml"}
 			strict := tc.strict // Use the test case's strict value
 
-			result, err := validateChartWithFiles(chartPath, releaseName, namespace, valuesFiles, strict, tc.inputVersion)
+			result, err := validateChartWithFiles(context.Background(), chartPath, releaseName, namespace, valuesFiles, strict, tc.inputVersion)
 
 			// Assertions
 			if tc.expectError {
@@ -193,3 +194,73 @@ func TestKubeVersionPassthrough(t *testing.T) {
 		})
 	}
 }
+
+func TestParseKubeVersions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		specs       []string
+		expected    []string
+		expectError bool
+	}{
+		{"List of minor versions", []string{"1.27", "1.28", "1.29"}, []string{"1.27.0", "1.28.0", "1.29.0"}, false},
+		{"Full versions kept", []string{"v1.29.3", "1.30.1"}, []string{"1.29.3", "1.30.1"}, false},
+		{"Minor range", []string{"1.27-1.29"}, []string{"1.27.0", "1.28.0", "1.29.0"}, false},
+		{"Duplicates removed", []string{"1.28", "1.27-1.28"}, []string{"1.28.0", "1.27.0"}, false},
+		{"Invalid version", []string{"latest"}, nil, true},
+		{"Mismatched major range", []string{"1.29-2.0"}, nil, true},
+		{"Reversed range", []string{"1.29-1.27"}, nil, true},
+		{"Empty list", []string{" "}, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			versions, err := parseKubeVersions(tc.specs)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, versions)
+		})
+	}
+}
+
+func TestValidateKubeVersionMatrix(t *testing.T) {
+	original := helm.HelmTemplateFunc
+	defer func() { helm.HelmTemplateFunc = original }()
+
+	var rendered []string
+	helm.HelmTemplateFunc = func(_ context.Context, options *helm.TemplateOptions) (*helm.CommandResult, error) {
+		rendered = append(rendered, options.KubeVersion)
+		if options.KubeVersion == "1.27.0" {
+			return &helm.CommandResult{Success: false, Stderr: "no matches for kind"}, fmt.Errorf("rendering failed for %s", options.KubeVersion)
+		}
+		return &helm.CommandResult{Success: true, Stdout: "apiVersion: v1\nkind: ConfigMap\n"}, nil
+	}
+
+	report, err := validateKubeVersionMatrix(context.Background(), testChartPath, testReleaseName, testNamespace, []string{"/path/to/values.yaml"}, false, []string{"1.27.0", "1.28.0"}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"1.27.0", "1.28.0"}, rendered, "Chart should be rendered once per Kubernetes version")
+	require.Len(t, report.Results, 2)
+	assert.False(t, report.Results[0].Success)
+	assert.NotEmpty(t, report.Results[0].Error)
+	assert.True(t, report.Results[1].Success)
+	assert.Equal(t, []string{"1.27.0"}, report.FailedVersions)
+}
+
+func TestValidateChartWithCapabilities(t *testing.T) {
+	var captured *helm.TemplateOptions
+	cleanup := directTemplateMock(t, func(options *helm.TemplateOptions) {
+		captured = options
+	})
+	defer cleanup()
+
+	capabilities := &CapabilityOptions{APIVersions: []string{"monitoring.coreos.com/v1"}, FromCluster: true}
+	_, err := validateChartWithCapabilities(context.Background(), testChartPath, testReleaseName, testNamespace, []string{"/path/to/values.yaml"}, false, DefaultKubernetesVersion, capabilities)
+	require.NoError(t, err)
+
+	require.NotNil(t, captured, "Template options should have been captured")
+	assert.Equal(t, []string{"monitoring.coreos.com/v1"}, captured.APIVersions)
+	assert.True(t, captured.CapabilitiesFromCluster)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/limits"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+)
+
+// flagLimits holds the limits set with --max-values-size, --max-values-depth and
+// --max-image-patterns; zero fields were not set
+var flagLimits limits.Limits
+
+// applyLimitFlags validates --max-values-size, --max-values-depth and --max-image-patterns and
+// applies them to every analysis from now on. Unset flags keep the default limits.
+func applyLimitFlags() error {
+	parsed := limits.Limits{MaxDepth: maxValuesDepth, MaxImagePatterns: maxImagePatterns}
+	if maxValuesSize != "" {
+		size, err := limits.ParseSize(maxValuesSize)
+		if err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  fmt.Errorf("invalid --max-values-size: %w", err),
+			}
+		}
+		parsed.MaxValuesSize = size
+	}
+	if maxValuesDepth < 0 || maxImagePatterns < 0 {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--max-values-depth and --max-image-patterns must be positive"),
+		}
+	}
+	flagLimits = parsed
+	limits.Set(flagLimits)
+	return nil
+}
+
+// applyConfigLimits applies the limits section of a loaded registry config. Each flag takes
+// precedence over the limit it sets. During a batch run the jobs share one set of limits, so a
+// file's section is only logged there.
+func applyConfigLimits(config *registry.Config, source string) {
+	if config.Limits == nil {
+		return
+	}
+	configured, err := config.Limits.Limits()
+	if err != nil {
+		// Registry configs are validated when loaded, so this only happens for configs built in code
+		log.Warn("Ignoring the invalid limits of the registry config", "source", source, "error", err)
+		return
+	}
+	if batchRunCache != nil {
+		log.Warn("The limits of a registry config are ignored by 'irr run'; use --max-values-size, --max-values-depth and --max-image-patterns instead",
+			"source", source)
+		return
+	}
+	combined := flagLimits
+	if combined.MaxValuesSize == 0 {
+		combined.MaxValuesSize = configured.MaxValuesSize
+	}
+	if combined.MaxDepth == 0 {
+		combined.MaxDepth = configured.MaxDepth
+	}
+	if combined.MaxImagePatterns == 0 {
+		combined.MaxImagePatterns = configured.MaxImagePatterns
+	}
+	limits.Set(combined)
+	current := limits.Current()
+	log.Debug("Applied the limits of the registry config", "source", source,
+		"maxValuesSize", limits.FormatSize(current.MaxValuesSize), "maxDepth", current.MaxDepth, "maxImagePatterns", current.MaxImagePatterns)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/limits"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyLimits(t *testing.T) {
+	originalSize, originalDepth, originalPatterns := maxValuesSize, maxValuesDepth, maxImagePatterns
+	t.Cleanup(limits.Set(limits.Limits{}))
+	t.Cleanup(func() {
+		maxValuesSize, maxValuesDepth, maxImagePatterns = originalSize, originalDepth, originalPatterns
+		flagLimits = limits.Limits{}
+	})
+	config := &registry.Config{Limits: &registry.LimitsConfig{MaxValuesSize: "4Mi", MaxDepth: 40}}
+
+	maxValuesSize, maxValuesDepth, maxImagePatterns = "", 0, 0
+	require.NoError(t, applyLimitFlags())
+	assert.Equal(t, limits.DefaultMaxValuesSize, limits.Current().MaxValuesSize)
+	applyConfigLimits(config, "registry-mappings.yaml")
+	assert.Equal(t, limits.Limits{MaxValuesSize: 4 << 20, MaxDepth: 40, MaxImagePatterns: limits.DefaultMaxImagePatterns}, limits.Current())
+
+	// The flags take precedence over the config file, limit by limit
+	maxValuesSize, maxImagePatterns = "1Gi", 50
+	require.NoError(t, applyLimitFlags())
+	applyConfigLimits(config, "registry-mappings.yaml")
+	assert.Equal(t, limits.Limits{MaxValuesSize: 1 << 30, MaxDepth: 40, MaxImagePatterns: 50}, limits.Current())
+
+	for _, invalid := range []func(){
+		func() { maxValuesSize = "1GB" },
+		func() { maxValuesSize, maxValuesDepth = "", -1 },
+	} {
+		invalid()
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, applyLimitFlags(), &exitErr)
+		assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	}
+}
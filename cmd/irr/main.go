@@ -25,15 +25,10 @@ func main() {
 
 	// Execute the root command (defined in root.go, package main)
 	// Cobra's Execute() handles its own error printing. We check the returned
-	// error to propagate the correct exit code.
+	// error to propagate the correct exit code: that of the ExitCodeError or other
+	// typed error, 21 for file system errors and 20 for any other error.
 	if err := Execute(); err != nil {
-		// Check if the error is a custom ExitCodeError
-		if code, ok := exitcodes.IsExitCodeError(err); ok {
-			// Use the specific exit code from the error
-			os.Exit(code)
-		}
-		// Cobra likely printed the error already, use a generic failure code
-		os.Exit(1)
+		os.Exit(exitcodes.Classify(err).ExitCode)
 	}
 }
 
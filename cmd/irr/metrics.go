@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/metrics"
+	"github.com/lucas-albers-lz4/irr/pkg/timing"
+	"github.com/spf13/afero"
+)
+
+// metricsFile is set by the global --metrics-file flag
+var metricsFile string
+
+// startMetrics enables the counters and span recording for --metrics-file. It is called once
+// flags are parsed.
+func startMetrics() {
+	if metricsFile == "" {
+		return
+	}
+	metrics.EnableRecording()
+	timing.EnableRecording()
+}
+
+// finishMetrics writes the metrics of the run to --metrics-file, if set. Failures are logged
+// rather than returned so they do not replace the outcome of the command.
+func finishMetrics() {
+	if metricsFile == "" {
+		return
+	}
+	defer metrics.Reset()
+	if profileOutput == "" {
+		// finishProfiling discards the spans once it has written the profile
+		defer timing.Reset()
+	}
+	if err := writeMetrics(metricsFile, timing.Spans()); err != nil {
+		log.Error("Failed to write metrics", "error", err)
+		return
+	}
+	log.Info("Metrics written", "file", metricsFile)
+}
+
+// writeMetrics writes the counters and stage durations in the Prometheus text format, replacing
+// an existing file
+func writeMetrics(path string, spans []timing.Span) error {
+	var buf bytes.Buffer
+	if err := metrics.Write(&buf, spans); err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitGeneralRuntimeError, Err: err}
+	}
+	if err := afero.WriteFile(AppFs, path, buf.Bytes(), fileutil.ReadWriteUserReadOthers); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write metrics file '%s': %w", path, err),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/metrics"
+	"github.com/lucas-albers-lz4/irr/pkg/timing"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsFile(t *testing.T) {
+	originalFs := AppFs
+	AppFs = afero.NewMemMapFs()
+	t.Cleanup(func() {
+		AppFs = originalFs
+		metricsFile = ""
+		metrics.Reset()
+		timing.Reset()
+	})
+
+	metricsFile = "irr.prom"
+	startMetrics()
+	metrics.Add(metrics.ImagesAnalyzed, 3)
+	metrics.Inc(metrics.UnmappedRegistries, "registry", "quay.io")
+	timing.Start(timing.SpanGeneration, "./nginx")()
+	finishMetrics()
+
+	data, err := afero.ReadFile(AppFs, "irr.prom")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "irr_images_analyzed_total 3\n")
+	assert.Contains(t, string(data), "irr_unmapped_registries_total{registry=\"quay.io\"} 1\n")
+	assert.Contains(t, string(data), `irr_stage_duration_seconds_count{stage="generation"} 1`)
+	assert.Zero(t, metrics.Value(metrics.ImagesAnalyzed), "counters are reset once the metrics are written")
+	assert.Empty(t, timing.Spans(), "recorded spans are discarded when no profile is written")
+}
+
+func TestMetricsFileDisabled(t *testing.T) {
+	originalFs := AppFs
+	AppFs = afero.NewMemMapFs()
+	t.Cleanup(func() { AppFs = originalFs })
+
+	startMetrics()
+	metrics.Inc(metrics.ImagesAnalyzed)
+	finishMetrics()
+
+	assert.Zero(t, metrics.Value(metrics.ImagesAnalyzed))
+	files, err := afero.ReadDir(AppFs, ".")
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
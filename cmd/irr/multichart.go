@@ -0,0 +1,333 @@
+// Package main implements the irr CLI commands.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/lucas-albers-lz4/irr/pkg/progress"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultChartWorkers is the default number of charts processed concurrently with --recursive
+	defaultChartWorkers = 4
+	// chartFileName is the file that marks the root of a Helm chart
+	chartFileName = "Chart.yaml"
+	// multiChartSummaryBasename is the basename of the summary written next to per-chart override files
+	multiChartSummaryBasename = "summary"
+)
+
+// MultiChartResult describes the outcome of processing a single chart found by --recursive, or a
+// single release with override --all-namespaces
+type MultiChartResult struct {
+	ChartPath  string             `json:"chartPath" yaml:"chartPath"`
+	Release    string             `json:"release,omitempty" yaml:"release,omitempty"`
+	Namespace  string             `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	OutputFile string             `json:"outputFile,omitempty" yaml:"outputFile,omitempty"`
+	ImageCount int                `json:"imageCount,omitempty" yaml:"imageCount,omitempty"`
+	Analysis   *ImageAnalysis     `json:"analysis,omitempty" yaml:"analysis,omitempty"`
+	Warnings   []override.Warning `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+	Errors     []PathError        `json:"errors,omitempty" yaml:"errors,omitempty"`
+	Error      string             `json:"error,omitempty" yaml:"error,omitempty"`
+	ErrorCode  string             `json:"errorCode,omitempty" yaml:"errorCode,omitempty"`
+	Category   exitcodes.Category `json:"category,omitempty" yaml:"category,omitempty"`
+}
+
+// MultiChartSummary is the combined result of processing every chart under a directory
+type MultiChartSummary struct {
+	Root      string             `json:"root" yaml:"root"`
+	Succeeded int                `json:"succeeded" yaml:"succeeded"`
+	Failed    int                `json:"failed" yaml:"failed"`
+	Partial   int                `json:"partial,omitempty" yaml:"partial,omitempty"`
+	Charts    []MultiChartResult `json:"charts" yaml:"charts"`
+}
+
+// addMultiChartFlags adds the flags used to process a directory containing many charts.
+func addMultiChartFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("recursive", false, "Treat --chart-path as a directory and process every chart found beneath it")
+	cmd.Flags().Int("workers", defaultChartWorkers, "Number of charts processed concurrently with --recursive")
+}
+
+// getMultiChartFlags returns the --recursive and --workers flag values.
+func getMultiChartFlags(cmd *cobra.Command) (recursive bool, workers int, err error) {
+	recursive, err = cmd.Flags().GetBool("recursive")
+	if err != nil {
+		return false, 0, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get recursive flag: %w", err),
+		}
+	}
+	workers, err = cmd.Flags().GetInt("workers")
+	if err != nil {
+		return false, 0, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get workers flag: %w", err),
+		}
+	}
+	if workers < 1 {
+		return false, 0, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("--workers must be at least 1, got %d", workers),
+		}
+	}
+	return recursive, workers, nil
+}
+
+// addValidateWorkersFlag adds --validate-workers, which bounds the Helm template validations that
+// run at once while many charts are processed concurrently; each renders a whole chart in memory.
+func addValidateWorkersFlag(cmd *cobra.Command, usage string) {
+	cmd.Flags().Int("validate-workers", 0, usage)
+}
+
+// getValidateWorkersFlag returns the --validate-workers flag value; 0 means as many as --workers.
+func getValidateWorkersFlag(cmd *cobra.Command) (int, error) {
+	validateWorkers, err := cmd.Flags().GetInt("validate-workers")
+	if err != nil {
+		return 0, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get validate-workers flag: %w", err),
+		}
+	}
+	if validateWorkers < 0 {
+		return 0, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("--validate-workers must not be negative, got %d", validateWorkers),
+		}
+	}
+	return validateWorkers, nil
+}
+
+// discoverChartRoots walks dir and returns every directory containing a Chart.yaml, sorted.
+// Directories beneath a chart root (such as vendored subcharts in charts/) are not returned,
+// since they are processed as part of their parent chart.
+func discoverChartRoots(fs afero.Fs, dir string) ([]string, error) {
+	info, err := fs.Stat(dir)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitChartNotFound,
+			Err:  fmt.Errorf("chart directory not found or inaccessible: %s: %w", dir, err),
+		}
+	}
+	if !info.IsDir() {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("--recursive requires --chart-path to be a directory: %s", dir),
+		}
+	}
+
+	var roots []string
+	walkErr := afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != dir && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		exists, existsErr := afero.Exists(fs, filepath.Join(path, chartFileName))
+		if existsErr != nil {
+			return existsErr
+		}
+		if exists {
+			roots = append(roots, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to search %s for charts: %w", dir, walkErr),
+		}
+	}
+	if len(roots) == 0 {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitChartNotFound,
+			Err:  fmt.Errorf("no charts (%s) found under %s", chartFileName, dir),
+		}
+	}
+
+	sort.Strings(roots)
+	return roots, nil
+}
+
+// processChartsConcurrently runs process for every chart path using at most workers goroutines.
+// Results are returned in the same order as chartPaths. Progress is shown on an interactive stderr.
+// Once ctx is canceled no further charts are started, and an interrupted error is returned after
+// the running ones finish.
+func processChartsConcurrently(ctx context.Context, chartPaths []string, workers int, process func(chartPath string) MultiChartResult) ([]MultiChartResult, error) {
+	bar := progress.Start(os.Stderr, "Processing charts", len(chartPaths))
+	defer bar.Finish()
+	return processConcurrently(ctx, chartPaths, workers, func(chartPath string) MultiChartResult {
+		defer bar.Add(1)
+		return process(chartPath)
+	})
+}
+
+// processConcurrently runs process for every item using at most workers goroutines. Results are
+// returned in the same order as items. Once ctx is canceled no further items are started, and an
+// interrupted error is returned after the running ones finish.
+func processConcurrently[T, R any](ctx context.Context, items []T, workers int, process func(item T) R) ([]R, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]R, len(items))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(items); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if ctx.Err() == nil {
+					results[i] = process(items[i])
+				}
+			}
+		}()
+	}
+feed:
+	for i := range items {
+		select {
+		case <-ctx.Done():
+			break feed
+		case indexes <- i:
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	if err := checkInterrupted(ctx); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// newMultiChartSummary builds the combined summary for results produced under root.
+func newMultiChartSummary(root string, results []MultiChartResult) *MultiChartSummary {
+	summary := &MultiChartSummary{Root: root, Charts: results}
+	for _, result := range results {
+		switch {
+		case result.Error != "":
+			summary.Failed++
+		case len(result.Errors) > 0:
+			summary.Partial++
+		default:
+			summary.Succeeded++
+		}
+	}
+	return summary
+}
+
+// multiChartError returns an error when any chart in the summary failed, a partial success error
+// when charts only have image paths skipped with --ignore-errors, and nil otherwise.
+func multiChartError(summary *MultiChartSummary) error {
+	if summary.Failed == 0 && summary.Partial == 0 {
+		return nil
+	}
+	if summary.Failed == 0 {
+		partial := make([]string, 0, summary.Partial)
+		for _, result := range summary.Charts {
+			if len(result.Errors) > 0 {
+				partial = append(partial, result.ChartPath)
+			}
+		}
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitPartialSuccess,
+			Err:  fmt.Errorf("%d of %d charts have partial overrides: %s", summary.Partial, len(summary.Charts), strings.Join(partial, ", ")),
+		}
+	}
+	failed := make([]string, 0, summary.Failed)
+	for _, result := range summary.Charts {
+		if result.Error != "" {
+			failed = append(failed, result.ChartPath)
+		}
+	}
+	return &exitcodes.ExitCodeError{
+		Code: exitcodes.ExitChartProcessingFailed,
+		Err:  fmt.Errorf("%d of %d charts failed: %s", summary.Failed, len(summary.Charts), strings.Join(failed, ", ")),
+	}
+}
+
+// marshalMultiChartSummary renders the summary in the requested output format (yaml or json).
+func marshalMultiChartSummary(summary *MultiChartSummary, outputFormat string) ([]byte, error) {
+	var output []byte
+	var err error
+	if strings.EqualFold(outputFormat, outputFormatJSON) {
+		output, err = json.MarshalIndent(summary, "", "  ")
+	} else {
+		output, err = yaml.Marshal(summary)
+	}
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to marshal chart summary: %w", err),
+		}
+	}
+	return output, nil
+}
+
+// writeMultiChartSummary writes the summary to outputDir, or prints it with --dry-run.
+func writeMultiChartSummary(cmd *cobra.Command, summary *MultiChartSummary, outputDir, outputFormat string, dryRun bool) error {
+	summaryBytes, err := marshalMultiChartSummary(summary, outputFormat)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		log.Info("DRY RUN: Displaying chart summary (stdout)")
+		if _, err := fmt.Fprintln(cmd.OutOrStdout(), string(summaryBytes)); err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to write summary to stdout: %w", err),
+			}
+		}
+		return nil
+	}
+	summaryPath := filepath.Join(outputDir, fmt.Sprintf("%s.%s", multiChartSummaryBasename, outputFormat))
+	return writeOutputFile(summaryPath, summaryBytes, "Chart summary written")
+}
+
+// chartOutputName derives a unique, file-name safe name for a chart from its path relative to root.
+func chartOutputName(root, chartPath string) string {
+	rel, err := filepath.Rel(root, chartPath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return filepath.Base(chartPath)
+	}
+	return strings.ReplaceAll(filepath.ToSlash(rel), "/", "-")
+}
+
+// reportedError returns err's message, unwrapping exit code errors so summaries stay readable,
+// with the stable error code and category automation can branch on.
+func reportedError(err error) (message, errorCode string, category exitcodes.Category) {
+	info := exitcodes.Classify(err)
+	return info.Message, info.ErrorCode, info.Category
+}
+
+// logMultiChartSummary logs the per-chart outcome of a recursive run.
+func logMultiChartSummary(summary *MultiChartSummary) {
+	for _, result := range summary.Charts {
+		if result.Error != "" {
+			log.Error("Chart failed", "chart", result.ChartPath, "error", result.Error)
+			continue
+		}
+		if len(result.Errors) > 0 {
+			log.Warn("Chart processed with skipped image paths", "chart", result.ChartPath, "skipped", len(result.Errors), "output", result.OutputFile)
+			continue
+		}
+		log.Info("Chart processed", "chart", result.ChartPath, "images", result.ImageCount, "output", result.OutputFile)
+	}
+	log.Info("Processed charts", "root", summary.Root, "succeeded", summary.Succeeded, "partial", summary.Partial, "failed", summary.Failed)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverChartRoots(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	root := "/charts"
+	chartFiles := []string{
+		"app-a/Chart.yaml",
+		"app-a/charts/sub/Chart.yaml", // vendored subchart, processed with its parent
+		"team/app-b/Chart.yaml",
+		".hidden/app-c/Chart.yaml",
+	}
+	for _, file := range chartFiles {
+		require.NoError(t, afero.WriteFile(fs, filepath.Join(root, file), []byte("apiVersion: v2\nname: test\nversion: 0.1.0\n"), fileutil.ReadWriteUserReadOthers))
+	}
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(root, "README.md"), []byte("docs"), fileutil.ReadWriteUserReadOthers))
+
+	t.Run("finds top-level chart roots only", func(t *testing.T) {
+		roots, err := discoverChartRoots(fs, root)
+		require.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(root, "app-a"), filepath.Join(root, "team/app-b")}, roots)
+	})
+
+	t.Run("directory without charts", func(t *testing.T) {
+		require.NoError(t, fs.MkdirAll("/empty", fileutil.ReadWriteExecuteUserReadExecuteOthers))
+		_, err := discoverChartRoots(fs, "/empty")
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitChartNotFound, exitErr.Code)
+	})
+
+	t.Run("path is a file", func(t *testing.T) {
+		_, err := discoverChartRoots(fs, filepath.Join(root, "README.md"))
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := discoverChartRoots(fs, "/missing")
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitChartNotFound, exitErr.Code)
+	})
+}
+
+func TestProcessChartsConcurrently(t *testing.T) {
+	chartPaths := []string{"a", "b", "c", "d", "e", "f"}
+	const workers = 2
+
+	var running, maxRunning int32
+	results, err := processChartsConcurrently(context.Background(), chartPaths, workers, func(chartPath string) MultiChartResult {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			observed := atomic.LoadInt32(&maxRunning)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxRunning, observed, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return MultiChartResult{ChartPath: chartPath}
+	})
+
+	require.NoError(t, err)
+	require.Len(t, results, len(chartPaths))
+	for i, result := range results {
+		assert.Equal(t, chartPaths[i], result.ChartPath, "results should keep input order")
+	}
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxRunning), int32(workers))
+}
+
+func TestProcessChartsConcurrentlyInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var processed int32
+	_, err := processChartsConcurrently(ctx, []string{"a", "b", "c", "d"}, 1, func(chartPath string) MultiChartResult {
+		atomic.AddInt32(&processed, 1)
+		cancel()
+		return MultiChartResult{ChartPath: chartPath}
+	})
+
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInterrupted, exitErr.Code)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&processed), "no chart is started after the interrupt")
+}
+
+func TestMultiChartSummary(t *testing.T) {
+	summary := newMultiChartSummary("/charts", []MultiChartResult{
+		{ChartPath: "/charts/a", ImageCount: 2},
+		{ChartPath: "/charts/b", Error: "failed to load chart"},
+	})
+	assert.Equal(t, 1, summary.Succeeded)
+	assert.Equal(t, 1, summary.Failed)
+
+	err := multiChartError(summary)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitChartProcessingFailed, exitErr.Code)
+	assert.Contains(t, err.Error(), "/charts/b")
+
+	assert.NoError(t, multiChartError(newMultiChartSummary("/charts", []MultiChartResult{{ChartPath: "/charts/a"}})))
+
+	jsonBytes, err := marshalMultiChartSummary(summary, outputFormatJSON)
+	require.NoError(t, err)
+	assert.Contains(t, string(jsonBytes), `"failed": 1`)
+	yamlBytes, err := marshalMultiChartSummary(summary, outputFormatYAML)
+	require.NoError(t, err)
+	assert.Contains(t, string(yamlBytes), "succeeded: 1")
+
+	partialSummary := newMultiChartSummary("/charts", []MultiChartResult{
+		{ChartPath: "/charts/a"},
+		{ChartPath: "/charts/b", Errors: []PathError{{Path: "image", Error: "invalid image"}}},
+	})
+	assert.Equal(t, 1, partialSummary.Partial)
+	err = multiChartError(partialSummary)
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitPartialSuccess, exitErr.Code)
+	assert.Contains(t, err.Error(), "1 of 2 charts have partial overrides: /charts/b")
+}
+
+func TestChartOutputName(t *testing.T) {
+	assert.Equal(t, "app-a", chartOutputName("/charts", "/charts/app-a"))
+	assert.Equal(t, "team-app-b", chartOutputName("/charts", "/charts/team/app-b"))
+	assert.Equal(t, "charts", chartOutputName("/charts", "/charts"))
+}
+
+func TestReportedError(t *testing.T) {
+	wrapped := &exitcodes.ExitCodeError{Code: exitcodes.ExitChartLoadFailed, Err: errors.New("chart missing")}
+	message, errorCode, category := reportedError(fmt.Errorf("chart /charts/a: %w", wrapped))
+	assert.Equal(t, "chart missing", message)
+	assert.Equal(t, "chart-load-failed", errorCode)
+	assert.Equal(t, exitcodes.CategoryChartLoad, category)
+
+	message, errorCode, category = reportedError(errors.New("plain"))
+	assert.Equal(t, "plain", message)
+	assert.Equal(t, "runtime-error", errorCode)
+	assert.Equal(t, exitcodes.CategoryRuntime, category)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+)
+
+// offlineEnvVar enables offline mode like --offline, e.g. for the Helm plugin or CI images
+const offlineEnvVar = "IRR_OFFLINE"
+
+// offlineMode is set by the global --offline flag
+var offlineMode bool
+
+// isOffline reports whether offline mode is enabled by --offline or IRR_OFFLINE=true
+func isOffline() bool {
+	return offlineMode || os.Getenv(offlineEnvVar) == trueString
+}
+
+// requireNetwork returns an error when offline mode is enabled, for an operation that cannot work
+// without network access (downloading charts, contacting the cluster or a registry). It is
+// called before the operation starts so offline runs fail fast instead of timing out.
+func requireNetwork(operation string) error {
+	if !isOffline() {
+		return nil
+	}
+	return &exitcodes.ExitCodeError{
+		Code: exitcodes.ExitInputConfigurationError,
+		Err:  fmt.Errorf("%s requires network access, which is disabled by --offline", operation),
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withOfflineMode enables offline mode for the duration of a test
+func withOfflineMode(t *testing.T) {
+	t.Helper()
+	original := offlineMode
+	offlineMode = true
+	t.Cleanup(func() { offlineMode = original })
+}
+
+func TestRequireNetwork(t *testing.T) {
+	t.Setenv(offlineEnvVar, "")
+	require.NoError(t, requireNetwork("downloading chart nginx"))
+
+	t.Setenv(offlineEnvVar, trueString)
+	err := requireNetwork("downloading chart nginx")
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	assert.Contains(t, err.Error(), "downloading chart nginx requires network access")
+}
+
+func TestOfflineMode(t *testing.T) {
+	withOfflineMode(t)
+	var exitErr *exitcodes.ExitCodeError
+
+	t.Run("capabilities from cluster", func(t *testing.T) {
+		cmd := newValidateCmd()
+		require.NoError(t, cmd.ParseFlags([]string{"--set-capabilities-from-cluster"}))
+		_, err := getCapabilityFlags(cmd)
+		require.ErrorAs(t, err, &exitErr)
+		assert.Contains(t, err.Error(), "--set-capabilities-from-cluster")
+	})
+
+	t.Run("repository chart", func(t *testing.T) {
+		_, err := resolveHelmExecChart(&helmInvocation{Chart: "nginx", Repo: "https://charts.example.com"})
+		require.ErrorAs(t, err, &exitErr)
+		assert.Contains(t, err.Error(), "downloading chart nginx")
+	})
+
+	t.Run("local chart", func(t *testing.T) {
+		chartPath, err := resolveHelmExecChart(&helmInvocation{Chart: helmExecTestChart})
+		require.NoError(t, err)
+		assert.Equal(t, helmExecTestChart, chartPath)
+	})
+
+	t.Run("helm-exec runs helm", func(t *testing.T) {
+		gotArgs := withHelmExecCommand(t, "true")
+		cmd := newHelmExecCmd()
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetArgs([]string{"-t", "harbor.local", "-s", "docker.io", "--", "install", "git", helmExecTestChart})
+
+		require.ErrorAs(t, cmd.Execute(), &exitErr)
+		assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+		assert.Nil(t, *gotArgs, "helm should not run offline")
+	})
+
+	t.Run("helm-exec dry run", func(t *testing.T) {
+		cmd := newHelmExecCmd()
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetArgs([]string{"-t", "harbor.local", "-s", "docker.io", "--dry-run", "--", "install", "git", helmExecTestChart})
+		require.NoError(t, cmd.Execute())
+	})
+
+	t.Run("verify-mappings", func(t *testing.T) {
+		require.ErrorAs(t, runVerifyMappings(newVerifyMappingsCmd(), nil), &exitErr)
+		assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// maxRedirectedLine is the longest line of redirected output logged as one record
+const maxRedirectedLine = 1024 * 1024
+
+var (
+	// quietMode is set by the global --quiet flag
+	quietMode bool
+	// jsonLogsOnly is set by the global --json-logs-only flag
+	jsonLogsOnly bool
+	// restoreStreams undoes the redirection of os.Stdout and os.Stderr by redirectStandardStreams
+	restoreStreams func()
+)
+
+// applyOutputModes applies --quiet, which logs errors only, and --json-logs-only, which writes
+// every log record as JSON and keeps stderr free of anything else. Both modes reserve stdout for
+// the command's structured document and disable the progress line, for piping output in CI.
+func applyOutputModes(cmd *cobra.Command) error {
+	if quietMode && (debugEnabled || cmd.Flags().Changed("log-level")) {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--quiet cannot be combined with --debug or --log-level"),
+		}
+	}
+	if jsonLogsOnly && logFormat != "" && !strings.EqualFold(logFormat, log.FormatJSON) {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("--json-logs-only cannot be combined with --log-format %s", logFormat),
+		}
+	}
+	if !quietMode && !jsonLogsOnly {
+		return nil
+	}
+
+	if quietMode {
+		log.SetLevel(log.LevelError)
+	}
+	if jsonLogsOnly {
+		if err := log.SetFormat(log.FormatJSON); err != nil {
+			return fmt.Errorf("failed to set JSON log format: %w", err)
+		}
+	}
+	noProgress = true
+	return redirectStandardStreams(cmd)
+}
+
+// redirectStandardStreams gives cmd the real standard output for its document and logs anything
+// else written to os.Stdout, e.g. by the Helm SDK, so that stdout carries nothing but the document.
+// With --json-logs-only, writes to os.Stderr are logged too, so that stderr carries nothing but
+// JSON log records. Log records still go to the real stderr (or --log-file).
+func redirectStandardStreams(cmd *cobra.Command) error {
+	if restoreStreams != nil {
+		return nil
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	if cmd.OutOrStdout() == stdout {
+		cmd.SetOut(stdout)
+	}
+
+	stdoutPipe, closeStdout, err := logStream("stdout", log.Info)
+	if err != nil {
+		return err
+	}
+	os.Stdout = stdoutPipe
+	closeStderr := func() {}
+	if jsonLogsOnly {
+		var stderrPipe *os.File
+		stderrPipe, closeStderr, err = logStream("stderr", log.Warn)
+		if err != nil {
+			os.Stdout = stdout
+			closeStdout()
+			return err
+		}
+		os.Stderr = stderrPipe
+	}
+
+	restoreStreams = func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		closeStdout()
+		closeStderr()
+	}
+	return nil
+}
+
+// restoreStandardStreams restores os.Stdout and os.Stderr after the command has run, once all
+// output written to them has been logged
+func restoreStandardStreams() {
+	if restoreStreams == nil {
+		return
+	}
+	restoreStreams()
+	restoreStreams = nil
+}
+
+// logStream returns a pipe whose lines are logged with logFunc as output written to stream, and a
+// function that closes the pipe and waits until all its lines are logged
+func logStream(stream string, logFunc func(string, ...any)) (*os.File, func(), error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to redirect %s: %w", stream, err),
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(nil, maxRedirectedLine)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				logFunc("Output redirected from "+stream, "output", line)
+			}
+		}
+		// Drain the rest after an overlong line, so that writers never block
+		_, _ = io.Copy(io.Discard, reader) //nolint:errcheck // best effort
+		_ = reader.Close()                 //nolint:errcheck // read end of a pipe
+	}()
+	return writer, func() {
+		_ = writer.Close() //nolint:errcheck // write end of a pipe
+		<-done
+	}, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyOutputModes(t *testing.T) {
+	originalQuiet, originalJSON, originalProgress, originalFormat := quietMode, jsonLogsOnly, noProgress, logFormat
+	originalLevel := log.CurrentLevel()
+	t.Cleanup(func() {
+		restoreStandardStreams()
+		quietMode, jsonLogsOnly, noProgress, logFormat = originalQuiet, originalJSON, originalProgress, originalFormat
+		log.SetLevel(originalLevel)
+		require.NoError(t, log.SetFormat(""))
+	})
+	var logs bytes.Buffer
+	restoreLog := log.SetOutput(&logs)
+	t.Cleanup(restoreLog)
+
+	stdout, stderr := os.Stdout, os.Stderr
+	quietMode, jsonLogsOnly, logFormat = false, true, ""
+	cmd := &cobra.Command{Use: "inspect"}
+	require.NoError(t, applyOutputModes(cmd))
+	assert.True(t, noProgress)
+	assert.Equal(t, stdout, cmd.OutOrStdout(), "the document still goes to the real stdout")
+	assert.NotEqual(t, stdout, os.Stdout)
+	assert.NotEqual(t, stderr, os.Stderr)
+
+	_, err := fmt.Fprintln(os.Stdout, "Pulled: registry.example.com/charts/app:1.0.0")
+	require.NoError(t, err)
+	_, err = fmt.Fprintln(os.Stderr, "WARNING: repository cache is stale")
+	require.NoError(t, err)
+	restoreStandardStreams()
+	assert.Equal(t, stdout, os.Stdout)
+	assert.Equal(t, stderr, os.Stderr)
+	assert.Contains(t, logs.String(), `"msg":"Output redirected from stdout","output":"Pulled: registry.example.com/charts/app:1.0.0"`)
+	assert.Contains(t, logs.String(), `"level":"WARN","msg":"Output redirected from stderr","output":"WARNING: repository cache is stale"`)
+
+	quietMode, jsonLogsOnly = true, false
+	require.NoError(t, applyOutputModes(&cobra.Command{Use: "override"}))
+	assert.Equal(t, stderr, os.Stderr, "--quiet leaves stderr to error logs")
+	restoreStandardStreams()
+	logs.Reset()
+	log.Info("not shown")
+	log.Error("shown")
+	assert.NotContains(t, logs.String(), "not shown")
+	assert.Contains(t, logs.String(), "shown")
+}
+
+func TestApplyOutputModesConflicts(t *testing.T) {
+	originalQuiet, originalJSON, originalDebug, originalFormat := quietMode, jsonLogsOnly, debugEnabled, logFormat
+	t.Cleanup(func() {
+		quietMode, jsonLogsOnly, debugEnabled, logFormat = originalQuiet, originalJSON, originalDebug, originalFormat
+	})
+
+	quietMode, jsonLogsOnly, debugEnabled, logFormat = true, false, true, ""
+	err := applyOutputModes(&cobra.Command{Use: "inspect"})
+	assert.ErrorContains(t, err, "--quiet cannot be combined with --debug or --log-level")
+
+	quietMode, jsonLogsOnly, debugEnabled, logFormat = false, true, false, "text"
+	err = applyOutputModes(&cobra.Command{Use: "inspect"})
+	assert.ErrorContains(t, err, "--json-logs-only cannot be combined with --log-format text")
+}
@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
@@ -23,8 +24,11 @@ import (
 	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
 	"github.com/lucas-albers-lz4/irr/pkg/image"
 	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
 	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/lucas-albers-lz4/irr/pkg/selector"
 	"github.com/lucas-albers-lz4/irr/pkg/strategy"
+	"github.com/lucas-albers-lz4/irr/pkg/strictness"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -70,16 +74,58 @@ type GeneratorConfig struct {
 	ExcludeRegistries []string
 	// Strategy is the path generation strategy to use for image paths
 	Strategy strategy.PathStrategy
+	// StrategyName is the name of the path strategy selected with --path-strategy
+	StrategyName string
+	// TargetFlavor is the target registry provider whose naming rules generated paths must follow
+	TargetFlavor strategy.TargetFlavor
 	// Mappings contains registry mapping configurations
 	Mappings *registry.Mappings
+	// RegistryTLS holds the TLS settings of registry hosts from the tls section of the mappings file
+	RegistryTLS map[string]registry.TLSConfig
+	// Exceptions lists the images that are never relocated, from the exceptions section of the mappings file
+	Exceptions []registry.RelocationException
+	// Encryption is the encryption section of the mappings file, applied when --encrypt is not set
+	Encryption *registry.EncryptionConfig
 	// StrictMode enables strict validation (fails on any error)
 	StrictMode bool
+	// StrictPolicy is the action for each strict mode condition, from --strict-mode and the config
+	// file's policy block. When nil, the policy follows StrictMode.
+	StrictPolicy *strictness.Policy
+	// RequireImmutableRefs is the action of --require-immutable-refs for images referenced by
+	// mutable tags; it takes precedence over the policy. Empty when the flag is not set.
+	RequireImmutableRefs strictness.Action
+	// PinDigests pins images referenced by mutable tags to digests (--pin-digests)
+	PinDigests bool
 	// IncludePatterns contains glob patterns for values paths to include
 	IncludePatterns []string
 	// ExcludePatterns contains glob patterns for values paths to exclude
 	ExcludePatterns []string
+	// Selectors limits generation to the selected subcharts, values paths or releases (--select)
+	Selectors selector.Set
 	// RulesEnabled controls whether the chart parameter rules system is enabled
 	RulesEnabled bool
+	// DefaultTag is the tag used for images that have neither a tag nor a digest
+	DefaultTag string
+	// BitnamiCompat adds global.security.allowInsecureImages=true for Bitnami charts whose registries change
+	BitnamiCompat bool
+	// Verify enables provenance/signature verification of the chart before it is loaded
+	Verify bool
+	// VerifyOptions configures chart verification when Verify is set
+	VerifyOptions internalhelm.ChartVerifyOptions
+	// Dependencies configures downloading dependencies missing from the chart directory; nil skips them
+	Dependencies *internalhelm.DependencyOptions
+	// TemplatePaths renders the chart and adds overrides at the values paths inferred from its
+	// templates for images values analysis does not find (--template-paths)
+	TemplatePaths bool
+	// IgnoreErrors keeps the overrides generated for the other images when some values paths fail (--ignore-errors)
+	IgnoreErrors bool
+	// NoCache analyzes the chart even when the analysis cache holds a result for it (--no-cache)
+	NoCache bool
+	// RegistryOnly overrides only image registries and keeps the chart's repository paths (--registry-only)
+	RegistryOnly bool
+	// GlobalRegistryThreshold is the share of images composed from global.imageRegistry above which
+	// they are overridden with global.imageRegistry and their repository only; 0 disables it (--global-registry-threshold)
+	GlobalRegistryThreshold float64
 }
 
 // For testing purposes - allows overriding in tests
@@ -140,6 +186,11 @@ func newOverrideCmd() *cobra.Command {
 				releaseNameFlag = ""
 			}
 			hasReleaseName := (releaseNameArg != "" || releaseNameFlag != "") && detectedPluginMode
+			allNamespaces, flagErr := cmd.Flags().GetBool("all-namespaces")
+			if flagErr != nil {
+				log.Debug("Error getting all-namespaces flag", "error", flagErr)
+				allNamespaces = false
+			}
 
 			chartPath, err := cmd.Flags().GetString("chart-path")
 			if err != nil {
@@ -171,13 +222,18 @@ func newOverrideCmd() *cobra.Command {
 				// Continue with empty configFilePath rather than returning an error
 				configFilePath = ""
 			}
-			isConfigProvided := configFilePath != ""
+			clusterConfig, clusterErr := cmd.Flags().GetString("config-from-cluster")
+			if clusterErr != nil {
+				log.Debug("Error getting config-from-cluster flag", "error", clusterErr)
+				clusterConfig = ""
+			}
+			isConfigProvided := configFilePath != "" || clusterConfig != ""
 
 			var missingFlags []string
 
 			// Chart source check:
-			// --chart-path is required if not in plugin mode with a release name.
-			if !hasReleaseName && !chartPathProvided {
+			// --chart-path is required if not in plugin mode with a release name or --all-namespaces.
+			if !hasReleaseName && !allNamespaces && !chartPathProvided {
 				missingFlags = append(missingFlags, "chart-path")
 			}
 
@@ -217,6 +273,8 @@ func newOverrideCmd() *cobra.Command {
 
 	// Set up flags
 	setupOverrideFlags(cmd)
+	cmd.Flags().BoolP("all-namespaces", "A", false, "Generate overrides for Helm releases across all namespaces, one file per release in --output-dir (conflicts with --chart-path, --release-name and -n)")
+	addReleaseFilterFlags(cmd)
 
 	return cmd
 }
@@ -225,6 +283,7 @@ func newOverrideCmd() *cobra.Command {
 func setupOverrideFlags(cmd *cobra.Command) {
 	// Required flags
 	cmd.Flags().StringP("chart-path", "c", "", "Path to the Helm chart directory or tarball (default: auto-detect)")
+	addChartRefFlags(cmd)
 	cmd.Flags().StringP("target-registry", "t", "", "Target container registry URL (required)")
 	cmd.Flags().StringSliceP(
 		"source-registries",
@@ -235,34 +294,73 @@ func setupOverrideFlags(cmd *cobra.Command) {
 
 	// Optional flags
 	cmd.Flags().StringP("output-file", "o", "", "Write output to file instead of stdout")
-	cmd.Flags().String("registry-file", "", "Path to YAML file with registry mappings (defaults to registry-mappings.yaml in the current directory if not provided)")
+	addOutputURIFlag(cmd)
+	addEncryptFlags(cmd)
+	cmd.Flags().String("output-dir", "", "Directory for per-chart or per-release override files and the combined summary when using --recursive or --all-namespaces, or for the files written by --split-by-subchart")
+	cmd.Flags().Bool("split-by-subchart", false, "Write one override file per top-level subchart alias plus an umbrella file for the parent chart to --output-dir")
+	cmd.Flags().String("merge-into", "", "Merge overrides into an existing values file, preserving its comments and key order (written in place unless --output-file is set)")
+	cmd.Flags().Bool("update", false, "Regenerate an existing --output-file in place, keeping the non-image keys added to it by hand and reporting what was preserved and regenerated")
+	addMultiChartFlags(cmd)
+	addValidateWorkersFlag(cmd, "Number of Helm template validations run concurrently with --recursive and --validate; 0 runs as many as --workers")
+	addWatchFlags(cmd)
+	addRegistryFileFlag(cmd)
 	cmd.Flags().StringP("config", "f", "", "DEPRECATED: Path to registry mapping config file. Use --registry-file instead.")
 	if err := cmd.Flags().MarkDeprecated("config", "use --registry-file instead"); err != nil {
 		// Log an error if marking deprecated fails, but don't necessarily halt execution
 		// This is a development-time issue, not a runtime user error.
 		log.Error("Failed to mark --config flag as deprecated", "error", err)
 	}
-	cmd.Flags().Bool("strict", false, "Enable strict mode (fails on unsupported structures)")
+	cmd.Flags().Bool("strict", false, "Enable strict mode (fails on unsupported structures); same as --strict-mode=all")
+	cmd.Flags().String("strict-mode", "", "Strict mode level: off, warn, unsupported or all (default off)")
+	addImmutableRefsFlags(cmd)
+	addChartVerifyFlags(cmd)
+	addDependencyFlags(cmd)
+	addIgnoreErrorsFlags(cmd)
+	addClusterConfigFlag(cmd)
+	addProbeTargetsFlags(cmd)
+	addRulesFileFlag(cmd)
+	addAnnotateSubchartsFlag(cmd)
+	addAnnotateOutputFlag(cmd)
+	addIncludeDisabledFlag(cmd)
+	addMetadataFlag(cmd)
+	addValidateSchemaFlag(cmd)
+	addCheckImagesFlags(cmd)
 	cmd.Flags().StringSlice("include-pattern", []string{}, "Glob patterns for values paths to include (comma-separated)")
 	cmd.Flags().StringSlice("exclude-pattern", []string{}, "Glob patterns for values paths to exclude (comma-separated)")
+	cmd.Flags().StringSlice("select", nil, selectFlagUsage)
 	cmd.Flags().Bool("disable-rules", false, "Disable the chart parameter rules system")
+	cmd.Flags().Bool("bitnami-compat", true, "Add global.security.allowInsecureImages=true for Bitnami charts when relocation changes image registries")
 	cmd.Flags().Bool("dry-run", false, "Perform a dry run (show changes without writing files)")
 	cmd.Flags().StringSliceP("exclude-registries", "e", []string{}, "Registry URLs to exclude from relocation")
+	cmd.Flags().String("path-strategy", strategy.StrategyPrefixSourceRegistry, "Path strategy for relocated images (prefix-source-registry or flat)")
+	cmd.Flags().String("target-flavor", string(strategy.FlavorGeneric), "Target registry provider whose repository naming rules generated paths must follow (generic, ecr, gcr, acr or harbor)")
+	cmd.Flags().Bool("registry-only", false, "Override only image registries and keep the original repository paths, for mirrors that preserve upstream paths (e.g. pull-through caches)")
+	cmd.Flags().Float64("global-registry-threshold", 0, "Override only global.imageRegistry and image repositories when at least this share (0-1) of images is composed from global.imageRegistry in the chart's templates (0 disables)")
+	cmd.Flags().String("default-tag", "", "Tag to use for images that have neither a tag nor a digest")
+	cmd.Flags().Bool("template-paths", false, "Render the chart and also generate overrides for images values analysis does not find, at the values path their templates read them from (requires --chart-path)")
+	addCapabilityFlags(cmd)
+	cmd.Flags().Bool("no-cache", false, "Analyze the chart even if the analysis cache holds a result for the same chart contents and values (see 'irr cache')")
 	cmd.Flags().Bool("no-validate", false, "Skip the internal Helm template validation check after generating overrides")
 	cmd.Flags().String("kube-version", "", "Kubernetes version to use for validation (defaults to current client version)")
 	cmd.Flags().StringP("namespace", "n", "default", "Namespace to use (default: default)")
-	cmd.Flags().StringP("release-name", "r", "", "Release name to use (only in Helm plugin mode)")
+	cmd.Flags().StringP("release-name", "r", "", "Release name to use (only in Helm plugin mode); with --chart-path, the release name --validate renders the chart as")
 
 	// Add Helm flags for values processing
 	cmd.Flags().StringSlice("values", nil, "Values files to process (can be specified multiple times)")
 	cmd.Flags().StringSlice("set", nil, "Set values on the command line (can be specified multiple times)")
 	cmd.Flags().StringSlice("set-string", nil, "Set STRING values on the command line (can be specified multiple times)")
 	cmd.Flags().StringSlice("set-file", nil, "Set values from files (can be specified multiple times)")
+	cmd.Flags().StringArray("set-json", nil, "Set JSON values on the command line (can be specified multiple times)")
+	cmd.Flags().StringArray("set-literal", nil, "Set a literal STRING value on the command line (can be specified multiple times)")
 
 	// Add new flags
-	cmd.Flags().BoolVar(&validate, "validate", false, "Run helm template to validate generated overrides")
+	cmd.Flags().BoolVar(&validate, "validate", false, "Run helm template to validate generated overrides, with the --values and --set inputs, --release-name and --namespace")
 	cmd.Flags().Bool("context-aware", false, "Use context-aware analyzer that handles subchart value merging (experimental)")
-	cmd.Flags().String("output-format", outputFormatYAML, "Output format for overrides (yaml or json)")
+	cmd.Flags().String("output-format", outputFormatYAML, "Output format for overrides: yaml, json, set-flags (Helm --set arguments, one per line) or set-flags-shell (on one shell-quoted line)")
+	if err := cmd.RegisterFlagCompletionFunc("output-format",
+		cobra.FixedCompletions(overrideOutputFormats, cobra.ShellCompDirectiveNoFileComp)); err != nil {
+		log.Debug("Failed to register output-format completion", "error", err)
+	}
 }
 
 // getRequiredFlags retrieves and validates the required flags for the override command
@@ -352,30 +450,55 @@ func getStringSliceFlag(cmd *cobra.Command, flagName string) ([]string, error) {
 	return value, nil
 }
 
+// getStringArrayFlag retrieves a string array flag value from the command
+func getStringArrayFlag(cmd *cobra.Command, flagName string) ([]string, error) {
+	value, err := cmd.Flags().GetStringArray(flagName)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get %s flag: %w", flagName, err),
+		}
+	}
+	return value, nil
+}
+
+// getGlobalRegistryThresholdFlag retrieves --global-registry-threshold, which must be between 0 and 1
+func getGlobalRegistryThresholdFlag(cmd *cobra.Command) (float64, error) {
+	threshold, err := cmd.Flags().GetFloat64("global-registry-threshold")
+	if err != nil {
+		return 0, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get global-registry-threshold flag: %w", err),
+		}
+	}
+	if threshold < 0 || threshold > 1 {
+		return 0, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("--global-registry-threshold must be between 0 and 1, got %g", threshold),
+		}
+	}
+	return threshold, nil
+}
+
 // handleGenerateError converts generator errors to appropriate exit code errors
 func handleGenerateError(err error) error {
+	code, ok := exitcodes.IsExitCodeError(err)
 	switch {
+	case ok:
+		// Typed errors (strict mode violations, threshold and processing errors) carry their exit code
 	case errors.Is(err, strategy.ErrThresholdExceeded):
-		return &exitcodes.ExitCodeError{
-			Code: exitcodes.ExitThresholdError,
-			Err:  fmt.Errorf("failed to process chart: %w", err),
-		}
+		code = exitcodes.ExitThresholdError
 	case errors.Is(err, chart.ErrChartNotFound) || errors.Is(err, chart.ErrChartLoadFailed):
-		return &exitcodes.ExitCodeError{
-			Code: exitcodes.ExitChartParsingError,
-			Err:  fmt.Errorf("failed to process chart: %w", err),
-		}
+		code = exitcodes.ExitChartParsingError
 	case errors.Is(err, chart.ErrUnsupportedStructure):
-		return &exitcodes.ExitCodeError{
-			Code: exitcodes.ExitUnsupportedStructure,
-			Err:  fmt.Errorf("failed to process chart: %w", err),
-		}
+		code = exitcodes.ExitUnsupportedStructure
 	default:
 		// Default to image processing error for any other errors
-		return &exitcodes.ExitCodeError{
-			Code: exitcodes.ExitImageProcessingError,
-			Err:  fmt.Errorf("failed to process chart: %w", err),
-		}
+		code = exitcodes.ExitImageProcessingError
+	}
+	return &exitcodes.ExitCodeError{
+		Code: code,
+		Err:  fmt.Errorf("failed to process chart: %w", err),
 	}
 }
 
@@ -409,8 +532,9 @@ func getOutputFlags(cmd *cobra.Command, releaseName string) (outputFile string,
 }
 
 // outputOverrides handles writing the generated YAML or JSON to the correct destination
-// (stdout or file) or logging it for dry-run.
-func outputOverrides(cmd *cobra.Command, data []byte, outputFile string, dryRun bool) error {
+// (stdout, file or --output-uri), encrypted with --encrypt or configEncryption, or logging it for
+// dry-run.
+func outputOverrides(cmd *cobra.Command, data []byte, configEncryption *registry.EncryptionConfig, outputFile string, dryRun bool) error {
 	// Determine output format
 	outputFormat, err := cmd.Flags().GetString("output-format")
 	if err != nil {
@@ -419,37 +543,16 @@ func outputOverrides(cmd *cobra.Command, data []byte, outputFile string, dryRun
 			Err:  fmt.Errorf("failed to get output-format flag: %w", err),
 		}
 	}
-	outputFormat = strings.ToLower(outputFormat)
-	if outputFormat != outputFormatYAML && outputFormat != outputFormatJSON {
-		return &exitcodes.ExitCodeError{
-			Code: exitcodes.ExitInputConfigurationError,
-			Err:  fmt.Errorf("unsupported output format %q; supported formats: yaml, json", outputFormat),
-		}
+	output, err := formatOverrides(data, outputFormat)
+	if err != nil {
+		return err
 	}
-
-	// Marshal to the requested format if needed
-	var output []byte
-	if outputFormat == outputFormatJSON {
-		var obj interface{}
-		if err := yaml.Unmarshal(data, &obj); err != nil {
-			return &exitcodes.ExitCodeError{
-				Code: exitcodes.ExitGeneralRuntimeError,
-				Err:  fmt.Errorf("failed to unmarshal YAML for JSON output: %w", err),
-			}
-		}
-		output, err = json.MarshalIndent(obj, "", "  ")
-		if err != nil {
-			return &exitcodes.ExitCodeError{
-				Code: exitcodes.ExitGeneralRuntimeError,
-				Err:  fmt.Errorf("failed to marshal overrides to JSON: %w", err),
-			}
-		}
-	} else {
-		output = data // Already YAML
+	outputURI, err := getStringFlag(cmd, "output-uri")
+	if err != nil {
+		return err
 	}
 
-	switch {
-	case dryRun:
+	if dryRun {
 		log.Info("DRY RUN: Displaying generated override values (stdout)")
 		if _, err := fmt.Fprintln(cmd.OutOrStdout(), string(output)); err != nil {
 			log.Error("Failed to write dry-run output to stdout", "error", err)
@@ -459,6 +562,15 @@ func outputOverrides(cmd *cobra.Command, data []byte, outputFile string, dryRun
 			}
 		}
 		return nil
+	}
+	// Dry runs print the overrides unencrypted, for review
+	output, err = encryptOverrides(cmd, output, configEncryption, outputFormat)
+	if err != nil {
+		return err
+	}
+	switch {
+	case outputURI != "":
+		return publishOverrides(cmd, output, outputURI, outputFormat)
 	case outputFile == "":
 		_, err := fmt.Fprintln(cmd.OutOrStdout(), string(output))
 		if err != nil {
@@ -508,6 +620,126 @@ func outputOverrides(cmd *cobra.Command, data []byte, outputFile string, dryRun
 	}
 }
 
+// outputOrMergeOverrides writes the generated overrides, merging them into the --merge-into
+// values file if one was given, or into the existing output file with --update. configEncryption
+// is the encryption section of the registry config the overrides were generated with.
+func outputOrMergeOverrides(cmd *cobra.Command, data []byte, configEncryption *registry.EncryptionConfig, outputFile string, dryRun bool) error {
+	update, err := getBoolFlag(cmd, "update")
+	if err != nil {
+		return err
+	}
+	mergeInto, err := getStringFlag(cmd, "merge-into")
+	if err != nil {
+		return err
+	}
+	if !update && mergeInto == "" {
+		return outputOverrides(cmd, data, configEncryption, outputFile, dryRun)
+	}
+	// --encrypt is rejected up front; the registry config is only read while generating
+	opts, err := overridesEncryption(cmd, configEncryption, outputFormatYAML)
+	if err != nil {
+		return err
+	}
+	if opts != nil && !dryRun {
+		return rejectEncryptedMerge(cmd, "the encryption section of the registry config")
+	}
+	if update {
+		return updateOverridesFile(cmd, data, outputFile, dryRun)
+	}
+	return mergeOverridesIntoFile(cmd, data, mergeInto, outputFile, dryRun)
+}
+
+// mergeOverridesIntoFile deep-merges the generated YAML overrides into the existing values file
+// mergeInto, preserving its comments and key order. The result replaces mergeInto, or is published
+// to --output-uri or written to outputFile if set, or is printed to stdout on a dry run.
+func mergeOverridesIntoFile(cmd *cobra.Command, data []byte, mergeInto, outputFile string, dryRun bool) error {
+	existing, err := afero.ReadFile(AppFs, mergeInto)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to read values file '%s' for --merge-into: %w", mergeInto, err),
+		}
+	}
+
+	merged, err := override.MergeIntoYAML(existing, data)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to merge overrides into '%s': %w", mergeInto, err),
+		}
+	}
+	outputURI, err := getStringFlag(cmd, "output-uri")
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case dryRun:
+		log.Info("DRY RUN: Displaying merged values (stdout)", "mergeInto", mergeInto)
+		if _, err := fmt.Fprint(cmd.OutOrStdout(), string(merged)); err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to write dry-run output to stdout: %w", err),
+			}
+		}
+		return nil
+	case outputURI != "":
+		return publishOverrides(cmd, merged, outputURI, outputFormatYAML)
+	case outputFile != "":
+		return writeOutputFile(outputFile, merged, "Merged values written")
+	default:
+		info, err := AppFs.Stat(mergeInto)
+		if err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to stat values file '%s': %w", mergeInto, err),
+			}
+		}
+		if err := afero.WriteFile(AppFs, mergeInto, merged, info.Mode().Perm()); err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to write merged values to '%s': %w", mergeInto, err),
+			}
+		}
+		log.Info("Overrides merged into values file", "path", mergeInto)
+		return nil
+	}
+}
+
+// formatOverrides converts generated YAML overrides to the requested output format (yaml, json,
+// set-flags or set-flags-shell).
+func formatOverrides(data []byte, outputFormat string) ([]byte, error) {
+	outputFormat = strings.ToLower(outputFormat)
+	if !slices.Contains(overrideOutputFormats, outputFormat) {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("unsupported output format %q; supported formats: %s", outputFormat, strings.Join(overrideOutputFormats, ", ")),
+		}
+	}
+	if outputFormat == outputFormatYAML {
+		return data, nil // Already YAML
+	}
+	if isSetFlagsFormat(outputFormat) {
+		return formatSetFlags(data, outputFormat == outputFormatSetFlagsShell)
+	}
+
+	var obj interface{}
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to unmarshal YAML for JSON output: %w", err),
+		}
+	}
+	output, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to marshal overrides to JSON: %w", err),
+		}
+	}
+	return output, nil
+}
+
 // deriveSourceRegistriesFromMappings populates the SourceRegistries in the config
 // from the Mappings, if SourceRegistries is not already set.
 func deriveSourceRegistriesFromMappings(config *GeneratorConfig) {
@@ -573,7 +805,7 @@ func deriveSourceRegistriesFromMappings(config *GeneratorConfig) {
 // It ONLY gathers flags and populates the struct. Further processing happens in runOverride.
 func setupGeneratorConfig(cmd *cobra.Command, isPluginOperatingOnRelease bool) (config GeneratorConfig, err error) {
 	// Determine if a config file is provided, to pass to getRequiredFlags
-	registryFilePath, regErr := cmd.Flags().GetString("registry-file")
+	registryFilePaths, regErr := cmd.Flags().GetStringSlice("registry-file")
 	if regErr != nil {
 		return config, fmt.Errorf("failed to get registry-file flag: %w", regErr)
 	}
@@ -583,7 +815,12 @@ func setupGeneratorConfig(cmd *cobra.Command, isPluginOperatingOnRelease bool) (
 		return config, fmt.Errorf("failed to get config flag: %w", cfgErr)
 	}
 
-	isConfigProvided := registryFilePath != "" || deprecatedConfigPath != ""
+	clusterConfig, clusterErr := cmd.Flags().GetString("config-from-cluster")
+	if clusterErr != nil {
+		return config, fmt.Errorf("failed to get config-from-cluster flag: %w", clusterErr)
+	}
+
+	isConfigProvided := len(registryFilePaths) > 0 || deprecatedConfigPath != "" || clusterConfig != ""
 
 	// Get required flags first, now context-aware
 	chartPathVal, targetRegistryVal, sourceRegistriesVal, err := getRequiredFlags(cmd, isPluginOperatingOnRelease, isConfigProvided)
@@ -593,6 +830,11 @@ func setupGeneratorConfig(cmd *cobra.Command, isPluginOperatingOnRelease bool) (
 	config.ChartPath = chartPathVal
 	config.TargetRegistry = targetRegistryVal
 	config.SourceRegistries = sourceRegistriesVal
+	// Jobs of 'irr run' run concurrently and share the scoped log fields, so runBatchJob logs the
+	// chart of each job instead
+	if config.ChartPath != "" && batchRunCache == nil {
+		log.AddFields("chart", config.ChartPath)
+	}
 
 	// Get optional flags
 	excludeRegistries, err := getStringSliceFlag(cmd, "exclude-registries")
@@ -601,11 +843,13 @@ func setupGeneratorConfig(cmd *cobra.Command, isPluginOperatingOnRelease bool) (
 	}
 	config.ExcludeRegistries = excludeRegistries
 
-	strictMode, err := getBoolFlag(cmd, "strict")
+	strictLevel, err := getStrictLevel(cmd)
 	if err != nil {
 		return config, err // Return zero config on error
 	}
-	config.StrictMode = strictMode
+	config.StrictMode = strictLevel == strictness.LevelAll
+	strictPolicy := strictLevel.Policy()
+	config.StrictPolicy = &strictPolicy
 
 	includePatterns, excludePatterns, err := getAnalysisControlFlags(cmd)
 	if err != nil {
@@ -614,12 +858,93 @@ func setupGeneratorConfig(cmd *cobra.Command, isPluginOperatingOnRelease bool) (
 	config.IncludePatterns = includePatterns
 	config.ExcludePatterns = excludePatterns
 
+	config.Selectors, err = getSelectors(cmd)
+	if err != nil {
+		return config, err // Return zero config on error
+	}
+
 	disableRules, err := getBoolFlag(cmd, "disable-rules")
 	if err != nil {
 		return config, err // Return zero config on error
 	}
 	config.RulesEnabled = !disableRules
 
+	config.BitnamiCompat, err = getBoolFlag(cmd, "bitnami-compat")
+	if err != nil {
+		return config, err // Return zero config on error
+	}
+
+	strategyName, err := getStringFlag(cmd, "path-strategy")
+	if err != nil {
+		return config, err // Return zero config on error
+	}
+	config.StrategyName = strategyName
+
+	targetFlavor, err := getStringFlag(cmd, "target-flavor")
+	if err != nil {
+		return config, err // Return zero config on error
+	}
+	config.TargetFlavor, err = strategy.ParseTargetFlavor(targetFlavor)
+	if err != nil {
+		return config, &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+
+	config.RegistryOnly, err = getBoolFlag(cmd, "registry-only")
+	if err != nil {
+		return config, err // Return zero config on error
+	}
+	if config.RegistryOnly {
+		for _, flag := range []string{"path-strategy", "target-flavor"} {
+			if cmd.Flags().Changed(flag) {
+				return config, &exitcodes.ExitCodeError{
+					Code: exitcodes.ExitInputConfigurationError,
+					Err:  fmt.Errorf("--%s cannot be used with --registry-only, which keeps the original repository paths", flag),
+				}
+			}
+		}
+	}
+
+	config.GlobalRegistryThreshold, err = getGlobalRegistryThresholdFlag(cmd)
+	if err != nil {
+		return config, err // Return zero config on error
+	}
+
+	config.RequireImmutableRefs, config.PinDigests, err = getImmutableRefsFlags(cmd, config.RegistryOnly)
+	if err != nil {
+		return config, err // Return zero config on error
+	}
+
+	defaultTag, err := getStringFlag(cmd, "default-tag")
+	if err != nil {
+		return config, err // Return zero config on error
+	}
+	config.DefaultTag = strings.TrimSpace(defaultTag)
+
+	config.Verify, config.VerifyOptions, err = getChartVerifyFlags(cmd)
+	if err != nil {
+		return config, err // Return zero config on error
+	}
+
+	config.Dependencies, err = getDependencyOptions(cmd)
+	if err != nil {
+		return config, err // Return zero config on error
+	}
+
+	config.TemplatePaths, err = getBoolFlag(cmd, "template-paths")
+	if err != nil {
+		return config, err // Return zero config on error
+	}
+
+	config.NoCache, err = getBoolFlag(cmd, "no-cache")
+	if err != nil {
+		return config, err // Return zero config on error
+	}
+
+	config.IgnoreErrors, err = getIgnoreErrors(cmd, config.StrictMode)
+	if err != nil {
+		return config, err // Return zero config on error
+	}
+
 	// NOTE: We do NOT call setupPathStrategy, loadRegistryMappings, logConfigMode,
 	// or validateUnmappableRegistries here. They are called in runOverride
 	// after this function returns successfully.
@@ -633,19 +958,91 @@ func setupGeneratorConfig(cmd *cobra.Command, isPluginOperatingOnRelease bool) (
 	return config, nil
 }
 
+// getStrictLevel returns the strict mode level from --strict-mode, or LevelAll for --strict.
+func getStrictLevel(cmd *cobra.Command) (strictness.Level, error) {
+	strict, err := getBoolFlag(cmd, "strict")
+	if err != nil {
+		return "", err
+	}
+	levelName, err := getStringFlag(cmd, "strict-mode")
+	if err != nil {
+		return "", err
+	}
+	if levelName == "" {
+		if strict {
+			return strictness.LevelAll, nil
+		}
+		return strictness.LevelOff, nil
+	}
+
+	level, err := strictness.ParseLevel(levelName)
+	if err != nil {
+		return "", &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	if strict && level != strictness.LevelAll {
+		return "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("--strict conflicts with --strict-mode=%s", level),
+		}
+	}
+	return level, nil
+}
+
+// strictPolicy returns the strict mode policy for the generator config, with the action of
+// --require-immutable-refs, if set, for mutable references.
+func (c *GeneratorConfig) strictPolicy() strictness.Policy {
+	policy := strictness.LevelOff.Policy()
+	switch {
+	case c.StrictPolicy != nil:
+		policy = *c.StrictPolicy
+	case c.StrictMode:
+		policy = strictness.LevelAll.Policy()
+	}
+	if c.RequireImmutableRefs != "" {
+		policy.MutableReferences = c.RequireImmutableRefs
+	}
+	return policy
+}
+
+// applyConfigFilePolicy layers the registry config file's strict mode settings over the policy from
+// the command line: registries.strictMode makes unmapped registries an error, and the policy block
+// overrides individual conditions.
+func applyConfigFilePolicy(config *GeneratorConfig, fileConfig *registry.Config) {
+	if !fileConfig.Registries.StrictMode && fileConfig.Policy == nil {
+		return
+	}
+	policy := config.strictPolicy()
+	if fileConfig.Registries.StrictMode {
+		policy.UnmappedRegistries = strictness.ActionError
+	}
+	if fileConfig.Policy != nil {
+		policy = policy.Merge(*fileConfig.Policy)
+	}
+	log.Debug("Applied strict mode policy from registry config file", "policy", fmt.Sprintf("%+v", policy))
+	config.StrictPolicy = &policy
+}
+
 // setupPathStrategy initializes and validates the path strategy.
 func setupPathStrategy(config *GeneratorConfig) (strategy.PathStrategy, error) {
 	if config == nil {
 		return nil, errors.New("nil config in setupPathStrategy")
 	}
 	// Default to prefix-source-registry if not specified
-	strategyName := "prefix-source-registry"
-	log.Debug("Using default path strategy", "strategy", strategyName)
+	strategyName := config.StrategyName
+	if strategyName == "" {
+		strategyName = strategy.StrategyPrefixSourceRegistry
+		log.Debug("Using default path strategy", "strategy", strategyName)
+	} else {
+		log.Debug("Using path strategy", "strategy", strategyName)
+	}
 
 	// Initialize and return the strategy
 	pathStrategy, err := strategy.GetStrategy(strategyName, config.Mappings)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize path strategy: %w", err)
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to initialize path strategy: %w", err),
+		}
 	}
 	return pathStrategy, nil
 }
@@ -658,7 +1055,7 @@ func loadRegistryMappings(cmd *cobra.Command, config *GeneratorConfig) error {
 	}
 
 	// Prioritize the registry-file flag, fallback to the deprecated config flag
-	registryFilePath, registryErr := cmd.Flags().GetString("registry-file")
+	registryFilePaths, registryErr := cmd.Flags().GetStringSlice("registry-file")
 	if registryErr != nil {
 		return fmt.Errorf("failed to get registry-file flag: %w", registryErr)
 	}
@@ -668,29 +1065,77 @@ func loadRegistryMappings(cmd *cobra.Command, config *GeneratorConfig) error {
 		return fmt.Errorf("failed to get config flag: %w", configErr)
 	}
 
-	configFileName := registryFilePath
-	if configFileName == "" {
+	clusterConfig, err := getStringFlag(cmd, "config-from-cluster")
+	if err != nil {
+		return err
+	}
+
+	configFileNames := registryFilePaths
+	if len(configFileNames) == 0 {
 		// Try deprecated flag
-		configFileName = deprecatedConfigPath
-		if configFileName == "" {
+		if deprecatedConfigPath == "" {
+			if clusterConfig != "" {
+				return loadClusterRegistryMappings(cmd, config, clusterConfig)
+			}
+			if registryProfile != "" {
+				return &exitcodes.ExitCodeError{
+					Code: exitcodes.ExitInputConfigurationError,
+					Err:  fmt.Errorf("--profile %s requires a registry mappings file (--registry-file or --config-from-cluster)", registryProfile),
+				}
+			}
 			log.Debug("No registry mapping file specified")
 			// This is not an error condition, just a configuration choice
 			return nil
 		}
 		log.Warn("Using deprecated --config flag, please use --registry-file instead")
+		configFileNames = []string{deprecatedConfigPath}
+	}
+	if clusterConfig != "" {
+		// A local file given explicitly is preferred, e.g. to test changes before updating the cluster
+		log.Warn("Registry file takes precedence over --config-from-cluster; the cluster config is not read", "files", configFileNames)
 	}
 
 	// Get current working directory - use the global isTestMode variable
 	skipCWDRestriction := integrationTestMode || (os.Getenv("IRR_TESTING") == trueString)
 
-	// Load mappings file
-	mappingsConfig, err := registry.LoadConfigDefault(configFileName, skipCWDRestriction)
+	// Load and merge the mappings files
+	mappingsConfig, files, err := loadRegistryConfigs(configFileNames, skipCWDRestriction)
+	if err != nil {
+		return fmt.Errorf("failed to load registry mappings from file %s: %w", strings.Join(configFileNames, ", "), err)
+	}
+	if mappingsConfig == nil {
+		log.Debug("No registry mapping file specified")
+		return nil
+	}
+
+	applyRegistryConfig(config, mappingsConfig, strings.Join(files, ", "))
+	return nil
+}
+
+// loadClusterRegistryMappings loads the registry mappings from the ConfigMap or Secret named by
+// --config-from-cluster into config.
+func loadClusterRegistryMappings(cmd *cobra.Command, config *GeneratorConfig, clusterConfig string) error {
+	mappingsConfig, source, err := loadClusterRegistryConfig(cmd, clusterConfig)
 	if err != nil {
-		return fmt.Errorf("failed to load registry mappings from file %s: %w", configFileName, err)
+		return err
 	}
+	applyRegistryConfig(config, mappingsConfig, source)
+	return nil
+}
 
+// applyRegistryConfig sets the mappings, policy and limits of a loaded registry config on config.
+func applyRegistryConfig(config *GeneratorConfig, mappingsConfig *registry.Config, source string) {
 	// Convert structured Config to the simpler Mappings
 	config.Mappings = mappingsConfig.ToMappings()
+	config.RegistryTLS = mappingsConfig.TLS
+	config.Exceptions = mappingsConfig.Exceptions
+	if config.Dependencies != nil {
+		config.Dependencies.Transport = transportOptions(mappingsConfig.TLS)
+	}
+	applyConfigFilePolicy(config, mappingsConfig)
+	applyConfigDefaultRegistry(mappingsConfig, source)
+	applyConfigLimits(mappingsConfig, source)
+	applyConfigEncryption(config, mappingsConfig, source)
 
 	if config.Mappings != nil {
 		log.Info("Registry mappings loaded successfully", "count", len(config.Mappings.Entries))
@@ -698,14 +1143,12 @@ func loadRegistryMappings(cmd *cobra.Command, config *GeneratorConfig) error {
 		// Derive source registries from mappings if not explicitly provided
 		deriveSourceRegistriesFromMappings(config)
 	} else {
-		log.Info("No registry mappings loaded from file", "file", configFileName)
+		log.Info("No registry mappings loaded from file", "file", source)
 	}
-
-	return nil
 }
 
 // validateUnmappableRegistries checks if all provided source registries are covered by mappings.
-// It logs warnings or returns an error based on strict mode.
+// It logs warnings or returns an error based on the strict mode policy for unmapped registries.
 func validateUnmappableRegistries(config *GeneratorConfig) error {
 	// Add nil check for safety
 	if config == nil {
@@ -719,13 +1162,14 @@ func validateUnmappableRegistries(config *GeneratorConfig) error {
 
 	// Check if mappings exist
 	hasMappings := (config.Mappings != nil && len(config.Mappings.Entries) > 0)
+	failOnUnmapped := config.strictPolicy().ActionFor(strictness.UnmappedRegistries) == strictness.ActionError
 
 	// If NO mappings exist at all, check all source registries.
 	if !hasMappings {
-		if config.StrictMode {
+		if failOnUnmapped {
 			// Strict mode requires mappings if source registries are specified
 			return &exitcodes.ExitCodeError{
-				Code: exitcodes.ExitRegistryDetectionError,
+				Code: strictness.UnmappedRegistries.ExitCode(),
 				Err:  fmt.Errorf("strict mode enabled: no mapping found for registries: %s", strings.Join(config.SourceRegistries, ", ")),
 			}
 		}
@@ -761,9 +1205,9 @@ func validateUnmappableRegistries(config *GeneratorConfig) error {
 		}
 	}
 	if len(unmappableRegistries) > 0 {
-		if config.StrictMode {
+		if failOnUnmapped {
 			return &exitcodes.ExitCodeError{
-				Code: exitcodes.ExitRegistryDetectionError,
+				Code: strictness.UnmappedRegistries.ExitCode(),
 				Err:  fmt.Errorf("strict mode enabled: no mapping found for registries: %s", strings.Join(unmappableRegistries, ", ")),
 			}
 		}
@@ -813,60 +1257,96 @@ func getValuesOptionsFromFlags(cmd *cobra.Command) (values.Options, error) {
 	if err != nil {
 		return valueOpts, err
 	}
+	valueOpts.JSONValues, err = getStringArrayFlag(cmd, "set-json")
+	if err != nil {
+		return valueOpts, err
+	}
+	valueOpts.LiteralValues, err = getStringArrayFlag(cmd, "set-literal")
+	if err != nil {
+		return valueOpts, err
+	}
 	return valueOpts, nil
 }
 
-// Helper to perform context-aware chart analysis (deduplicates logic)
-func performContextAwareAnalysis(chartPath string, valueOpts *values.Options) (*helmchart.Chart, *analysis.ChartAnalysis, error) {
+// Helper to perform context-aware chart analysis (deduplicates logic).
+// It also returns the merged values that were analyzed.
+func performContextAwareAnalysis(chartPath string, valueOpts *values.Options, dependencies *internalhelm.DependencyOptions) (*helmchart.Chart, *analysis.ChartAnalysis, map[string]interface{}, error) {
 	// Add nil check for valueOpts, although the call site should prevent this
 	if valueOpts == nil {
 		log.Error("Internal error: performContextAwareAnalysis called with nil valueOpts")
 		// Return an internal error, as this indicates a programming mistake in the caller
-		return nil, nil, &exitcodes.ExitCodeError{
+		return nil, nil, nil, &exitcodes.ExitCodeError{
 			Code: exitcodes.ExitInternalError,
 			Err:  errors.New("internal error: valueOpts cannot be nil in performContextAwareAnalysis"),
 		}
 	}
 	loaderOptions := &internalhelm.ChartLoaderOptions{
-		ChartPath:  chartPath,
-		ValuesOpts: *valueOpts, // Dereference is now safe
+		ChartPath:    chartPath,
+		ValuesOpts:   *valueOpts, // Dereference is now safe
+		Dependencies: dependencies,
 	}
 	chartLoader := internalhelm.NewChartLoader()
 	chartAnalysisContext, loadErr := chartLoader.LoadChartAndTrackOrigins(loaderOptions)
 	switch {
 	case loadErr != nil:
-		return nil, nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitChartLoadFailed, Err: fmt.Errorf("failed to load chart with values: %w", loadErr)}
+		return nil, nil, nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitChartLoadFailed, Err: fmt.Errorf("failed to load chart with values: %w", loadErr)}
 	case chartAnalysisContext == nil:
-		return nil, nil, errors.New("internal error: LoadChartAndTrackOrigins returned nil context without error")
+		return nil, nil, nil, errors.New("internal error: LoadChartAndTrackOrigins returned nil context without error")
 	case chartAnalysisContext.Chart == nil:
-		return nil, nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitChartLoadFailed, Err: errors.New("failed to load chart details from context")}
+		return nil, nil, nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitChartLoadFailed, Err: errors.New("failed to load chart details from context")}
 	}
 	contextAnalyzer := internalhelm.NewContextAwareAnalyzer(chartAnalysisContext)
 	chartAnalysis, analyzeErr := contextAnalyzer.AnalyzeContext()
 	if analyzeErr != nil {
-		return nil, nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitChartProcessingFailed, Err: fmt.Errorf("context analysis failed: %w", analyzeErr)}
+		return nil, nil, nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitChartProcessingFailed, Err: fmt.Errorf("context analysis failed: %w", analyzeErr)}
 	}
-	return chartAnalysisContext.Chart, chartAnalysis, nil
+	return chartAnalysisContext.Chart, chartAnalysis, chartAnalysisContext.Values, nil
 }
 
 // createAndExecuteGenerator creates and executes a generator for the given chart source
-func createAndExecuteGenerator(cmd *cobra.Command, config *GeneratorConfig, contextAware bool) ([]byte, error) {
+func createAndExecuteGenerator(cmd *cobra.Command, config *GeneratorConfig, contextAware bool) ([]byte, []override.Warning, error) {
 	log.Info("Initializing override generation", "chartPath", config.ChartPath)
 
+	if config.Verify {
+		if _, err := verifyChart(config.ChartPath, config.VerifyOptions); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	var loadedChart *helmchart.Chart
 	var analysisResult *analysis.ChartAnalysis
+	var analyzedValues map[string]interface{}
 	var loadAnalysisErr error
 
 	valueOpts, err := getValuesOptionsFromFlags(cmd)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if contextAware {
-		log.Info("Performing context-aware chart analysis...")
-		loadedChart, analysisResult, loadAnalysisErr = performContextAwareAnalysis(config.ChartPath, &valueOpts)
-	} else {
-		log.Info("Performing legacy chart analysis...")
+	rulesFile, err := getStringFlag(cmd, "rules-file")
+	if err != nil {
+		return nil, nil, err
+	}
+	cacheKey := ""
+	if !config.NoCache {
+		cacheKey = analysisCacheKey(config.ChartPath, &valueOpts, contextAware, config.Dependencies != nil, rulesFile)
+	}
+	cachedEntry := cachedAnalysis(cacheKey)
+
+	switch {
+	case cachedEntry != nil:
+		log.Info("Using cached chart analysis", "chartPath", config.ChartPath, "key", cacheKey)
+		loadedChart, loadAnalysisErr = chart.NewLoader().Load(config.ChartPath)
+		if loadAnalysisErr != nil {
+			loadAnalysisErr = &exitcodes.ExitCodeError{Code: exitcodes.ExitChartLoadFailed, Err: fmt.Errorf("failed to load chart: %w", loadAnalysisErr)}
+		}
+		analysisResult = cachedEntry.Analysis
+		analyzedValues = cachedEntry.Values
+	case contextAware:
+		log.Info("Performing context-aware chart analysis...")
+		loadedChart, analysisResult, analyzedValues, loadAnalysisErr = performContextAwareAnalysis(config.ChartPath, &valueOpts, config.Dependencies)
+	default:
+		log.Info("Performing legacy chart analysis...")
 		legacyLoader := chart.NewLoader()
 		var loadErr error
 		var legacyLoadedChart *helmchart.Chart
@@ -888,26 +1368,66 @@ func createAndExecuteGenerator(cmd *cobra.Command, config *GeneratorConfig, cont
 
 	if loadAnalysisErr != nil {
 		log.Error("Chart loading/analysis failed", "error", loadAnalysisErr)
-		return nil, loadAnalysisErr
+		return nil, nil, loadAnalysisErr
 	}
 	if loadedChart == nil {
 		log.Error("Internal error: loadedChart is nil after load/analysis phase without error")
-		return nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitGeneralRuntimeError, Err: errors.New("internal error: loadedChart missing")}
+		return nil, nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitGeneralRuntimeError, Err: errors.New("internal error: loadedChart missing")}
 	}
 	if analysisResult == nil {
 		log.Warn("Analysis result is nil (e.g., chart has no values/images), proceeding with empty analysis.")
 		analysisResult = analysis.NewChartAnalysis()
 	}
+	if cachedEntry == nil && cacheKey != "" {
+		// An analysis without some dependencies, or of a chart whose dependencies were just
+		// downloaded and so changed its key, must not be reused for the chart's key
+		switch {
+		case len(internalhelm.MissingDependencies(loadedChart)) > 0:
+			log.Debug("Not caching chart analysis: chart dependencies are missing", "chartPath", config.ChartPath)
+		case analysisCacheKey(config.ChartPath, &valueOpts, contextAware, config.Dependencies != nil, rulesFile) != cacheKey:
+			log.Debug("Not caching chart analysis: the chart changed during the analysis", "chartPath", config.ChartPath)
+		default:
+			storeAnalysis(cacheKey, config.ChartPath, loadedChart, analysisResult, analyzedValues)
+		}
+	}
+	if err := applyImageConventions(cmd, loadedChart, analyzedValues, analysisResult); err != nil {
+		return nil, nil, err
+	}
+	if config.TemplatePaths {
+		inferred, err := templatePathPatterns(cmd, config.ChartPath, analysisResult.ImagePatterns)
+		if err != nil {
+			return nil, nil, err
+		}
+		analysisResult.ImagePatterns = append(analysisResult.ImagePatterns, inferred...)
+	}
+	analysisResult.ImagePatterns, err = excludeDisabledPatterns(cmd, loadedChart, analyzedValues, analysisResult.ImagePatterns)
+	if err != nil {
+		return nil, nil, err
+	}
+	analysisResult.ImagePatterns = selectImagePatterns(analysisResult.ImagePatterns, config.Selectors, "")
 
 	pathStrategy, err := setupPathStrategy(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to set up path strategy: %w", err)
+		return nil, nil, fmt.Errorf("failed to set up path strategy: %w", err)
 	}
 	config.Strategy = pathStrategy
 
-	generator, err := createGenerator(config, contextAware)
+	exceptions, err := relocationExceptions(config)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	generator, err := createGenerator(config, loadedChart, analysisResult)
+	if err != nil {
+		return nil, nil, err
+	}
+	resolver, err := digestResolver(cmd, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	generator.SetDigestResolver(resolver)
+	generator.SetExceptions(exceptions)
+	if analyzedValues != nil {
+		generator.SetBaseValues(analyzedValues)
 	}
 
 	// Add nil check for config before accessing its fields for logging
@@ -932,20 +1452,45 @@ func createAndExecuteGenerator(cmd *cobra.Command, config *GeneratorConfig, cont
 		"config_ptr", logConfigPtr)
 
 	overrideResult, err := generator.Generate(loadedChart, analysisResult)
+	partialErr := partialOverrides(config, overrideResult, err)
+	if err != nil && partialErr == nil {
+		return nil, nil, handleGenerateError(err)
+	}
+	metadataComment, err := relocationMetadata(cmd, config, loadedChart, overrideResult)
 	if err != nil {
-		return nil, handleGenerateError(err)
+		return nil, nil, err
+	}
+	recordOverridesChart(cmd, loadedChart)
+	if err := validateOverridesSchema(cmd, loadedChart, analyzedValues, overrideResult.Values); err != nil {
+		return nil, nil, err
+	}
+	if err := checkRelocatedImages(cmd, config, overrideResult); err != nil {
+		return nil, nil, err
 	}
 
 	yamlBytes, err := yaml.Marshal(overrideResult.Values)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal overrides to YAML: %w", err)
+		return nil, nil, fmt.Errorf("failed to marshal overrides to YAML: %w", err)
+	}
+	yamlBytes, err = annotateSubchartOverrides(cmd, loadedChart, yamlBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	yamlBytes, err = annotateImageOverrides(cmd, config, overrideResult, yamlBytes)
+	if err != nil {
+		return nil, nil, err
 	}
+	if err := validateOverridesTemplate(cmd, config.ChartPath, &valueOpts, yamlBytes); err != nil {
+		return nil, nil, err
+	}
+	yamlBytes = append([]byte(metadataComment), yamlBytes...)
 
-	return yamlBytes, nil
+	// A partial result is returned together with its error so callers can still write it
+	return yamlBytes, overrideResult.Warnings, partialErr
 }
 
-// createGenerator creates a generator based on the context-aware flag.
-func createGenerator(config *GeneratorConfig, contextAware bool) (*chart.Generator, error) {
+// createGenerator creates a generator for a chart that has already been loaded and analyzed.
+func createGenerator(config *GeneratorConfig, loadedChart *helmchart.Chart, analysisResult *analysis.ChartAnalysis) (*chart.Generator, error) {
 	if config == nil {
 		return nil, errors.New("nil generator config")
 	}
@@ -960,71 +1505,9 @@ func createGenerator(config *GeneratorConfig, contextAware bool) (*chart.Generat
 		log.Debug("Strategy was nil, set default", "strategy", config.Strategy)
 	}
 
-	var preloadedLoader *PreloadedChartLoader
-	var generatorErr error
-
-	if contextAware {
-		log.Info("Creating generator using context-aware analysis...")
-		// --- Context-Aware Path ---
-		loaderOptions := &internalhelm.ChartLoaderOptions{
-			ChartPath: config.ChartPath,
-			// No other options needed for initial load in standalone mode
-		}
-		chartLoader := internalhelm.NewChartLoader()
-		chartAnalysisContext, loadErr := chartLoader.LoadChartAndTrackOrigins(loaderOptions)
-		switch {
-		case loadErr != nil:
-			generatorErr = &exitcodes.ExitCodeError{Code: exitcodes.ExitChartLoadFailed, Err: fmt.Errorf("context-aware chart load failed: %w", loadErr)}
-		case chartAnalysisContext == nil:
-			generatorErr = &exitcodes.ExitCodeError{Code: exitcodes.ExitInternalError, Err: errors.New("internal error: nil chart context without error")}
-		case chartAnalysisContext.Chart == nil:
-			generatorErr = &exitcodes.ExitCodeError{Code: exitcodes.ExitChartLoadFailed, Err: errors.New("loaded chart context contains nil chart")}
-		default:
-			// Chart is loaded, create analyzer
-			contextAnalyzer := internalhelm.NewContextAwareAnalyzer(chartAnalysisContext)
-			chartAnalysis, analyzeErr := contextAnalyzer.AnalyzeContext()
-			if analyzeErr != nil {
-				generatorErr = &exitcodes.ExitCodeError{Code: exitcodes.ExitChartProcessingFailed, Err: fmt.Errorf("context analysis failed: %w", analyzeErr)}
-			} else {
-				// Analysis completed, prepare preloader
-				preloadedLoader = &PreloadedChartLoader{
-					chart:    chartAnalysisContext.Chart,
-					analysis: chartAnalysis,
-				}
-			}
-		}
-	} else {
-		log.Info("Creating generator using legacy analysis...")
-		// --- Legacy Path ---
-		// Use the standard chart loader from pkg/chart
-		legacyLoader := chart.NewLoader() // Assuming NewLoader exists in pkg/chart
-		var loadedChart *helmchart.Chart
-		var analysisResult *analysis.ChartAnalysis
-		var loadErr error // Declare loadErr for this block scope
-		loadedChart, loadErr = legacyLoader.Load(config.ChartPath)
-		if loadErr != nil {
-			generatorErr = &exitcodes.ExitCodeError{Code: exitcodes.ExitChartLoadFailed, Err: fmt.Errorf("legacy chart load failed: %w", loadErr)}
-		} else {
-			analyzer := analysis.NewAnalyzer(config.ChartPath, legacyLoader)
-			analysisResult, loadErr = analyzer.Analyze()
-			if loadErr != nil {
-				generatorErr = &exitcodes.ExitCodeError{Code: exitcodes.ExitChartProcessingFailed, Err: fmt.Errorf("legacy analysis failed: %w", loadErr)}
-			} else {
-				// Setup preloaded loader on success
-				preloadedLoader = &PreloadedChartLoader{
-					chart:    loadedChart,
-					analysis: analysisResult,
-				}
-			}
-		}
-	}
-
-	if generatorErr != nil {
-		return nil, generatorErr
-	}
-
-	if preloadedLoader == nil {
-		return nil, errors.New("internal error: failed to prepare chart analysis data for generator")
+	preloadedLoader := &PreloadedChartLoader{
+		chart:    loadedChart,
+		analysis: analysisResult,
 	}
 
 	// Add log before calling NewGenerator
@@ -1048,6 +1531,12 @@ func createGenerator(config *GeneratorConfig, contextAware bool) (*chart.Generat
 		preloadedLoader,
 		config.RulesEnabled,
 	)
+	generator.SetDefaultTag(config.DefaultTag)
+	generator.SetBitnamiCompat(config.BitnamiCompat)
+	generator.SetTargetFlavor(config.TargetFlavor)
+	generator.SetRegistryOnly(config.RegistryOnly)
+	generator.SetGlobalRegistryThreshold(config.GlobalRegistryThreshold)
+	generator.SetStrictPolicy(config.strictPolicy())
 
 	// Log message if rules are disabled
 	if !config.RulesEnabled {
@@ -1076,14 +1565,42 @@ func (l *PreloadedChartLoader) Analyze(_ string) (*analysis.ChartAnalysis, error
 
 // runOverrideStandaloneMode handles override generation when running in standalone mode.
 func runOverrideStandaloneMode(cmd *cobra.Command, outputFile string, dryRun, isPluginOperatingOnRelease bool) error {
-	generatorConfig, err := setupGeneratorConfig(cmd, isPluginOperatingOnRelease)
+	yamlBytes, config, generateErr := generateStandaloneOverrides(cmd, isPluginOperatingOnRelease)
+	if _, partial := asPartialOverrides(generateErr); generateErr != nil && !partial {
+		return generateErr
+	}
+	// Nothing is written once interrupted, so that helm-exec never installs incomplete overrides
+	if err := checkInterrupted(getCommandContext(cmd)); err != nil {
+		return err
+	}
+	split, err := getBoolFlag(cmd, "split-by-subchart")
 	if err != nil {
 		return err
 	}
+	if split {
+		err = outputSplitOverrides(cmd, yamlBytes, config.Encryption, config.ChartPath, dryRun)
+	} else {
+		err = outputOrMergeOverrides(cmd, yamlBytes, config.Encryption, outputFile, dryRun)
+	}
+	if err != nil {
+		return err
+	}
+	return finishPartialOverrides(cmd, generateErr)
+}
+
+// generateStandaloneOverrides resolves the override configuration from the command flags and
+// generates the overrides for --chart-path, returning them as YAML together with the config they
+// were generated with. With --ignore-errors a partial result is returned together with its partial
+// success error.
+func generateStandaloneOverrides(cmd *cobra.Command, isPluginOperatingOnRelease bool) (yamlBytes []byte, config *GeneratorConfig, err error) {
+	generatorConfig, err := setupGeneratorConfig(cmd, isPluginOperatingOnRelease)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Load registry mappings after setting up the basic config
 	if err := loadRegistryMappings(cmd, &generatorConfig); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if generatorConfig.Mappings != nil {
@@ -1094,23 +1611,167 @@ func runOverrideStandaloneMode(cmd *cobra.Command, outputFile string, dryRun, is
 
 	// Derive source registries from mappings if not explicitly provided.
 	deriveSourceRegistriesFromMappings(&generatorConfig)
+	if err := probeTargetRegistries(cmd, &generatorConfig); err != nil {
+		return nil, nil, err
+	}
 
 	// Setup Path Strategy (must be after mappings are loaded and sources derived)
 	pathStrategy, err := setupPathStrategy(&generatorConfig)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	generatorConfig.Strategy = pathStrategy
 
 	contextAware, err := getBoolFlag(cmd, "context-aware")
 	if err != nil {
+		return nil, nil, err
+	}
+	yamlBytes, _, err = createAndExecuteGenerator(cmd, &generatorConfig, contextAware)
+	if _, partial := asPartialOverrides(err); err != nil && !partial {
+		return nil, nil, err
+	}
+	return yamlBytes, &generatorConfig, err
+}
+
+// runOverrideRecursive generates overrides for every chart found under --chart-path, writing one
+// override file per chart to --output-dir together with a combined summary.
+func runOverrideRecursive(cmd *cobra.Command, outputFile string, dryRun bool, workers int) error {
+	if outputFile != "" {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--output-file cannot be used with --recursive; use --output-dir instead"),
+		}
+	}
+	outputDir, err := getStringFlag(cmd, "output-dir")
+	if err != nil {
+		return err
+	}
+	if outputDir == "" && !dryRun {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitMissingRequiredFlag,
+			Err:  errors.New("--output-dir is required with --recursive unless --dry-run is set"),
+		}
+	}
+	errorReport, err := getStringFlag(cmd, "error-report")
+	if err != nil {
+		return err
+	}
+	if errorReport != "" {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--error-report cannot be used with --recursive; skipped paths are listed in the chart summary"),
+		}
+	}
+	outputFormat, err := getStringFlag(cmd, "output-format")
+	if err != nil {
+		return err
+	}
+	outputFormat = strings.ToLower(outputFormat)
+	if err := rejectSetFlagsFormat(outputFormat, "--recursive"); err != nil {
+		return err
+	}
+
+	// Flags, mappings and the path strategy are shared by every chart, so resolve them once
+	baseConfig, err := setupGeneratorConfig(cmd, false)
+	if err != nil {
+		return err
+	}
+	if err := loadRegistryMappings(cmd, &baseConfig); err != nil {
+		return err
+	}
+	deriveSourceRegistriesFromMappings(&baseConfig)
+	if err := probeTargetRegistries(cmd, &baseConfig); err != nil {
+		return err
+	}
+	if _, err := setupPathStrategy(&baseConfig); err != nil {
+		return err
+	}
+	contextAware, err := getBoolFlag(cmd, "context-aware")
+	if err != nil {
+		return err
+	}
+	validateWorkers, err := getValidateWorkersFlag(cmd)
+	if err != nil {
+		return err
+	}
+	defer limitTemplateValidations(validateWorkers)()
+
+	chartPaths, err := discoverChartRoots(AppFs, baseConfig.ChartPath)
+	if err != nil {
+		return err
+	}
+	log.Info("Discovered charts", "root", baseConfig.ChartPath, "count", len(chartPaths), "workers", workers)
+
+	results, err := processChartsConcurrently(getCommandContext(cmd), chartPaths, workers, func(chartPath string) MultiChartResult {
+		result := MultiChartResult{ChartPath: chartPath}
+		config := baseConfig
+		config.ChartPath = chartPath
+
+		yamlBytes, warnings, err := createAndExecuteGenerator(cmd, &config, contextAware)
+		if partial, ok := asPartialOverrides(err); ok {
+			result.Errors = partial.Errors
+		} else if err != nil {
+			result.Error, result.ErrorCode, result.Category = reportedError(err)
+			return result
+		}
+		result.Warnings = warnings
+		output, err := formatOverrides(yamlBytes, outputFormat)
+		if err != nil {
+			result.Error, result.ErrorCode, result.Category = reportedError(err)
+			return result
+		}
+		if dryRun {
+			return result
+		}
+
+		output, err = encryptOverrides(cmd, output, config.Encryption, outputFormat)
+		if err != nil {
+			result.Error, result.ErrorCode, result.Category = reportedError(err)
+			return result
+		}
+		outputPath := filepath.Join(outputDir, fmt.Sprintf("%s-overrides.%s", chartOutputName(baseConfig.ChartPath, chartPath), outputFormat))
+		if err := writeOutputFile(outputPath, output, "Override values written"); err != nil {
+			result.Error, result.ErrorCode, result.Category = reportedError(err)
+			return result
+		}
+		result.OutputFile = outputPath
+		return result
+	})
+	if err != nil {
+		return err
+	}
+
+	summary := newMultiChartSummary(baseConfig.ChartPath, results)
+	logMultiChartSummary(summary)
+	if err := writeMultiChartSummary(cmd, summary, outputDir, outputFormat, dryRun); err != nil {
+		return err
+	}
+	return multiChartError(summary)
+}
+
+// validateMergeIntoFlags rejects flag combinations that cannot be used with --merge-into.
+func validateMergeIntoFlags(cmd *cobra.Command, recursive bool) error {
+	mergeInto, err := getStringFlag(cmd, "merge-into")
+	if err != nil || mergeInto == "" {
 		return err
 	}
-	yamlBytes, err := createAndExecuteGenerator(cmd, &generatorConfig, contextAware)
+	if recursive {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--merge-into cannot be used with --recursive"),
+		}
+	}
+	outputFormat, err := getStringFlag(cmd, "output-format")
 	if err != nil {
 		return err
 	}
-	return outputOverrides(cmd, yamlBytes, outputFile, dryRun)
+	if !strings.EqualFold(outputFormat, outputFormatYAML) {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("--merge-into requires YAML output, got --output-format %s", outputFormat),
+		}
+	}
+	return nil
 }
 
 // runOverride is the main execution function for the override command
@@ -1122,6 +1783,55 @@ func runOverride(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	recursive, workers, err := getMultiChartFlags(cmd)
+	if err != nil {
+		return err
+	}
+	allNamespaces, err := getBoolFlag(cmd, "all-namespaces")
+	if err != nil {
+		return err
+	}
+	if err := validateEncryptFlags(cmd); err != nil {
+		return err
+	}
+	if allNamespaces {
+		return runOverrideAllNamespaces(cmd, args, outputFile, dryRun)
+	}
+	if _, err := getReleaseFilter(cmd, false); err != nil {
+		return err
+	}
+	if err := validateMergeIntoFlags(cmd, recursive); err != nil {
+		return err
+	}
+	if err := validateSplitBySubchartFlags(cmd, recursive, outputFile, dryRun); err != nil {
+		return err
+	}
+	if err := validateOutputURIFlags(cmd, recursive, outputFile); err != nil {
+		return err
+	}
+	if err := validateUpdateFlags(cmd, recursive, outputFile); err != nil {
+		return err
+	}
+	watch, err := getBoolFlag(cmd, "watch")
+	if err != nil {
+		return err
+	}
+	if watch {
+		if err := validateWatchFlags(cmd, args, recursive); err != nil {
+			return err
+		}
+		return runOverrideWatch(cmd, outputFile, dryRun)
+	}
+	if recursive {
+		if len(args) > 0 {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  errors.New("--recursive cannot be used with a release name"),
+			}
+		}
+		return runOverrideRecursive(cmd, outputFile, dryRun, workers)
+	}
+
 	isPlugin := isRunningAsHelmPlugin()
 	releaseName := ""
 	isPluginOperatingOnRelease := false
@@ -1141,6 +1851,9 @@ func runOverride(cmd *cobra.Command, args []string) error {
 		}
 
 		if releaseName != "" {
+			if err := requireNetwork(fmt.Sprintf("generating overrides for release %s", releaseName)); err != nil {
+				return err
+			}
 			isPluginOperatingOnRelease = true
 			// Refine outputFile if it was defaulted based on an empty releaseName initially by getOutputFlags
 			if outputFile == "-overrides.yaml" { // This condition checks if getOutputFlags used empty releaseName
@@ -1178,100 +1891,196 @@ func runOverride(cmd *cobra.Command, args []string) error {
 			}
 		}
 
-		// Get Helm adapter
-		helmAdapter, errAdapter := helmAdapterFactory()
-		if errAdapter != nil {
-			return errAdapter
-		}
-		if helmAdapter == nil {
-			return &exitcodes.ExitCodeError{
-				Code: exitcodes.ExitGeneralRuntimeError,
-				Err:  errors.New("internal error: helmAdapterFactory returned nil adapter without error"),
-			}
+		if releaseName != "" {
+			log.AddFields("release", releaseName, "namespace", namespace)
 		}
 
-		// Fetch release values and chart metadata
-		releaseValues, errValues := helmAdapter.GetReleaseValues(cmd.Context(), releaseName, namespace)
-		if errValues != nil {
-			return &exitcodes.ExitCodeError{
-				Code: exitcodes.ExitHelmCommandFailed,
-				Err:  fmt.Errorf("failed to get values for release %s in namespace %s: %w", releaseName, namespace, errValues),
-			}
+		yamlBytes, config, err := generateReleaseOverrides(cmd, releaseName, namespace, isPluginOperatingOnRelease)
+		if _, partial := asPartialOverrides(err); err != nil && !partial {
+			return err
 		}
-		chartMetadata, errChartMeta := helmAdapter.GetChartFromRelease(cmd.Context(), releaseName, namespace)
-		if errChartMeta != nil {
-			return &exitcodes.ExitCodeError{
-				Code: exitcodes.ExitHelmCommandFailed,
-				Err:  fmt.Errorf("failed to get chart info for release %s in namespace %s: %w", releaseName, namespace, errChartMeta),
-			}
+		if outputErr := outputOrMergeOverrides(cmd, yamlBytes, config.Encryption, outputFile, dryRun); outputErr != nil {
+			return outputErr
 		}
+		return finishPartialOverrides(cmd, err)
+	}
+	log.Debug("Running in Standalone mode")
+	return runOverrideStandaloneMode(cmd, outputFile, dryRun, false)
+}
 
-		// Prepare minimal chart object for generator
-		dummyChart := &helmchart.Chart{
-			Metadata: &helmchart.Metadata{
-				Name:    chartMetadata.Name,
-				Version: chartMetadata.Version,
-			},
+// generateReleaseOverrides generates the overrides for the values of an installed release,
+// returning them as YAML together with the config they were generated with. The chart is not
+// loaded; its images are found in the release values. With --ignore-errors a partial result is
+// returned together with its partial success error.
+func generateReleaseOverrides(cmd *cobra.Command, releaseName, namespace string, isPluginOperatingOnRelease bool) ([]byte, *GeneratorConfig, error) {
+	// Get Helm adapter
+	helmAdapter, errAdapter := helmAdapterFactory()
+	if errAdapter != nil {
+		return nil, nil, errAdapter
+	}
+	if helmAdapter == nil {
+		return nil, nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  errors.New("internal error: helmAdapterFactory returned nil adapter without error"),
 		}
+	}
 
-		// Prepare analysis result using context-aware analyzer
-		analyzer := analysis.NewAnalyzer("", nil) // No chart path, no loader needed for direct values
-		analysisResult, analyzeErr := analyzer.AnalyzeValues(releaseValues)
-		if analyzeErr != nil {
-			return &exitcodes.ExitCodeError{
-				Code: exitcodes.ExitChartProcessingFailed,
-				Err:  fmt.Errorf("release values analysis failed: %w", analyzeErr),
-			}
-		}
+	generatorConfig, err := setupReleaseGeneratorConfig(cmd, isPluginOperatingOnRelease)
+	if err != nil {
+		return nil, nil, err
+	}
+	yamlBytes, err := generateOverridesForRelease(cmd, helmAdapter, generatorConfig, releaseName, namespace, isPluginOperatingOnRelease)
+	return yamlBytes, &generatorConfig, err
+}
 
-		// Prepare generator config (reuse flag parsing logic)
-		generatorConfig, err := setupGeneratorConfig(cmd, isPluginOperatingOnRelease)
-		if err != nil {
-			return err
+// setupReleaseGeneratorConfig resolves the flags, registry mappings and path strategy used to
+// generate overrides for installed releases, rejecting the flags that need --chart-path.
+func setupReleaseGeneratorConfig(cmd *cobra.Command, isPluginOperatingOnRelease bool) (GeneratorConfig, error) {
+	// Prepare generator config (reuse flag parsing logic)
+	generatorConfig, err := setupGeneratorConfig(cmd, isPluginOperatingOnRelease)
+	if err != nil {
+		return GeneratorConfig{}, err
+	}
+	if generatorConfig.Verify {
+		return GeneratorConfig{}, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--verify requires --chart-path pointing to a packaged chart and cannot be used with a release name"),
 		}
-		// Set/override chart path for plugin mode if operating on a release
-		if isPluginOperatingOnRelease {
-			generatorConfig.ChartPath = fmt.Sprintf("helm-release://%s/%s", namespace, releaseName)
+	}
+	if generatorConfig.TemplatePaths {
+		return GeneratorConfig{}, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--template-paths renders the chart and requires --chart-path; it cannot be used with a release name"),
 		}
-
-		if err := loadRegistryMappings(cmd, &generatorConfig); err != nil {
-			return err
+	}
+	split, err := getBoolFlag(cmd, "split-by-subchart")
+	if err != nil {
+		return GeneratorConfig{}, err
+	}
+	if split {
+		return GeneratorConfig{}, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--split-by-subchart requires --chart-path and cannot be used with a release name"),
 		}
+	}
+	if annotate, err := getBoolFlag(cmd, "annotate-subcharts"); err == nil && annotate {
+		log.Warn("--annotate-subcharts needs the chart's dependencies from --chart-path; overrides for a release are not annotated")
+	}
+	if threshold, err := cmd.Flags().GetFloat64("global-registry-threshold"); err == nil && threshold > 0 {
+		log.Warn("--global-registry-threshold needs the chart's templates from --chart-path; overrides for a release are full image overrides")
+	}
+	if validate, err := getBoolFlag(cmd, "validate-schema"); err == nil && validate {
+		log.Warn("--validate-schema needs the chart's values.schema.json from --chart-path; overrides for a release are not validated")
+	}
+
+	if err := loadRegistryMappings(cmd, &generatorConfig); err != nil {
+		return GeneratorConfig{}, err
+	}
 
-		// Derive source registries from mappings if not explicitly provided.
-		deriveSourceRegistriesFromMappings(&generatorConfig)
+	// Derive source registries from mappings if not explicitly provided.
+	deriveSourceRegistriesFromMappings(&generatorConfig)
+	if err := probeTargetRegistries(cmd, &generatorConfig); err != nil {
+		return GeneratorConfig{}, err
+	}
 
-		pathStrategy, err := setupPathStrategy(&generatorConfig)
-		if err != nil {
-			return err
+	pathStrategy, err := setupPathStrategy(&generatorConfig)
+	if err != nil {
+		return GeneratorConfig{}, err
+	}
+	generatorConfig.Strategy = pathStrategy
+	return generatorConfig, nil
+}
+
+// generateOverridesForRelease generates the overrides for one installed release with a config
+// prepared by setupReleaseGeneratorConfig, returning them as YAML.
+func generateOverridesForRelease(cmd *cobra.Command, helmAdapter *internalhelm.Adapter, generatorConfig GeneratorConfig, releaseName, namespace string, isPluginOperatingOnRelease bool) ([]byte, error) {
+	// Fetch release values and chart metadata
+	releaseValues, errValues := helmAdapter.GetReleaseValues(cmd.Context(), releaseName, namespace)
+	if errValues != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitHelmCommandFailed,
+			Err:  fmt.Errorf("failed to get values for release %s in namespace %s: %w", releaseName, namespace, errValues),
 		}
-		generatorConfig.Strategy = pathStrategy
-
-		generator := chart.NewGenerator(
-			generatorConfig.ChartPath,
-			generatorConfig.TargetRegistry,
-			generatorConfig.SourceRegistries,
-			generatorConfig.ExcludeRegistries,
-			generatorConfig.Strategy,
-			generatorConfig.Mappings,
-			generatorConfig.StrictMode,
-			0,
-			&PreloadedChartLoader{chart: dummyChart, analysis: analysisResult},
-			generatorConfig.RulesEnabled,
-		)
-
-		overrideResult, err := generator.Generate(dummyChart, analysisResult)
-		if err != nil {
-			return handleGenerateError(err)
+	}
+	chartMetadata, errChartMeta := helmAdapter.GetChartFromRelease(cmd.Context(), releaseName, namespace)
+	if errChartMeta != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitHelmCommandFailed,
+			Err:  fmt.Errorf("failed to get chart info for release %s in namespace %s: %w", releaseName, namespace, errChartMeta),
 		}
-		yamlBytes, err := yaml.Marshal(overrideResult.Values)
-		if err != nil {
-			return fmt.Errorf("failed to marshal overrides to YAML: %w", err)
+	}
+
+	// Prepare minimal chart object for generator
+	dummyChart := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{
+			Name:    chartMetadata.Name,
+			Version: chartMetadata.Version,
+		},
+	}
+
+	// Set/override chart path for plugin mode if operating on a release
+	if isPluginOperatingOnRelease {
+		generatorConfig.ChartPath = releaseChartPath(namespace, releaseName)
+	}
+
+	// Analyze once the mappings are loaded: their defaultRegistry decides where unqualified images come from
+	analyzer := analysis.NewAnalyzer("", nil) // No chart path, no loader needed for direct values
+	analysisResult, analyzeErr := analyzer.AnalyzeValues(releaseValues)
+	if analyzeErr != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitChartProcessingFailed,
+			Err:  fmt.Errorf("release values analysis failed: %w", analyzeErr),
 		}
-		return outputOverrides(cmd, yamlBytes, outputFile, dryRun)
 	}
-	log.Debug("Running in Standalone mode")
-	return runOverrideStandaloneMode(cmd, outputFile, dryRun, false)
+
+	generator := chart.NewGenerator(
+		generatorConfig.ChartPath,
+		generatorConfig.TargetRegistry,
+		generatorConfig.SourceRegistries,
+		generatorConfig.ExcludeRegistries,
+		generatorConfig.Strategy,
+		generatorConfig.Mappings,
+		generatorConfig.StrictMode,
+		0,
+		&PreloadedChartLoader{chart: dummyChart, analysis: analysisResult},
+		generatorConfig.RulesEnabled,
+	)
+	generator.SetDefaultTag(generatorConfig.DefaultTag)
+	generator.SetBitnamiCompat(generatorConfig.BitnamiCompat)
+	generator.SetTargetFlavor(generatorConfig.TargetFlavor)
+	generator.SetRegistryOnly(generatorConfig.RegistryOnly)
+	generator.SetStrictPolicy(generatorConfig.strictPolicy())
+	generator.SetExceptions(generatorConfig.Exceptions)
+	resolver, err := digestResolver(cmd, &generatorConfig)
+	if err != nil {
+		return nil, err
+	}
+	generator.SetDigestResolver(resolver)
+	generator.SetBaseValues(releaseValues)
+	if err := applyImageConventions(cmd, dummyChart, releaseValues, analysisResult); err != nil {
+		return nil, err
+	}
+	analysisResult.ImagePatterns = selectImagePatterns(analysisResult.ImagePatterns, generatorConfig.Selectors, releaseName)
+
+	overrideResult, err := generator.Generate(dummyChart, analysisResult)
+	partialErr := partialOverrides(&generatorConfig, overrideResult, err)
+	if err != nil && partialErr == nil {
+		return nil, handleGenerateError(err)
+	}
+	metadataComment, err := relocationMetadata(cmd, &generatorConfig, dummyChart, overrideResult)
+	if err != nil {
+		return nil, err
+	}
+	recordOverridesChart(cmd, dummyChart)
+	yamlBytes, err := yaml.Marshal(overrideResult.Values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal overrides to YAML: %w", err)
+	}
+	yamlBytes, err = annotateImageOverrides(cmd, &generatorConfig, overrideResult, yamlBytes)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(metadataComment), yamlBytes...), partialErr
 }
 
 // isStdOutRequested returns true if output should go to stdout (either specifically requested or dry-run mode)
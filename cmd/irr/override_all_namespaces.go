@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/progress"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+)
+
+// releaseSummaryRoot is the summary root of override --all-namespaces, the scheme of the
+// chart paths given to releases
+const releaseSummaryRoot = "helm-release://"
+
+// allNamespacesConflictingFlags lists the override flags that write or read a single overrides
+// file, or need --chart-path, and so cannot be combined with --all-namespaces
+var allNamespacesConflictingFlags = []string{
+	"chart-path", "release-name", "namespace", "recursive", "watch", "merge-into", "update",
+	"split-by-subchart", "output-uri", "error-report", "verify", "template-paths",
+}
+
+// releaseChartPath is the chart path given to the overrides of an installed release.
+func releaseChartPath(namespace, releaseName string) string {
+	return fmt.Sprintf("%s%s/%s", releaseSummaryRoot, namespace, releaseName)
+}
+
+// releaseOutputName is the basename of the override file of a release with --all-namespaces.
+func releaseOutputName(namespace, releaseName, outputFormat string) string {
+	return fmt.Sprintf("%s-%s-overrides.%s", namespace, releaseName, outputFormat)
+}
+
+// runOverrideAllNamespaces generates overrides for every Helm release matching the release
+// filters, writing one override file per release to --output-dir together with a combined
+// summary. A failing release is recorded in the summary and does not stop the others; an
+// interrupt stops before the next release.
+func runOverrideAllNamespaces(cmd *cobra.Command, args []string, outputFile string, dryRun bool) error {
+	if len(args) > 0 {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--all-namespaces cannot be used with a release name"),
+		}
+	}
+	for _, name := range allNamespacesConflictingFlags {
+		if cmd.Flags().Changed(name) {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  fmt.Errorf("--all-namespaces cannot be used with --%s", name),
+			}
+		}
+	}
+	if outputFile != "" {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--output-file cannot be used with --all-namespaces; use --output-dir instead"),
+		}
+	}
+	outputDir, err := getStringFlag(cmd, "output-dir")
+	if err != nil {
+		return err
+	}
+	if outputDir == "" && !dryRun {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitMissingRequiredFlag,
+			Err:  errors.New("--output-dir is required with --all-namespaces unless --dry-run is set"),
+		}
+	}
+	outputFormat, err := getStringFlag(cmd, "output-format")
+	if err != nil {
+		return err
+	}
+	outputFormat = strings.ToLower(outputFormat)
+	if err := rejectSetFlagsFormat(outputFormat, "--all-namespaces"); err != nil {
+		return err
+	}
+	filter, err := getReleaseFilter(cmd, true)
+	if err != nil {
+		return err
+	}
+	if err := requireNetwork("--all-namespaces"); err != nil {
+		return err
+	}
+
+	// Flags, mappings and the path strategy are shared by every release, so resolve them once
+	baseConfig, err := setupReleaseGeneratorConfig(cmd, true)
+	if err != nil {
+		return err
+	}
+	releases, helmAdapter, err := getAllReleases(getCommandContext(cmd), filter)
+	if err != nil {
+		return err
+	}
+	defer helmAdapter.LogRetryStats()
+
+	bar := progress.Start(os.Stderr, "Processing releases", len(releases))
+	results := make([]MultiChartResult, 0, len(releases))
+	for _, release := range releases {
+		if err := checkInterrupted(getCommandContext(cmd)); err != nil {
+			bar.Finish()
+			return err
+		}
+		result := MultiChartResult{
+			ChartPath: releaseChartPath(release.Namespace, release.Name),
+			Release:   release.Name,
+			Namespace: release.Namespace,
+		}
+		yamlBytes, err := generateOverridesForRelease(cmd, helmAdapter, baseConfig, release.Name, release.Namespace, true)
+		if partial, ok := asPartialOverrides(err); ok {
+			result.Errors = partial.Errors
+		} else if err != nil {
+			result.Error, result.ErrorCode, result.Category = reportedError(err)
+		}
+		if result.Error == "" {
+			result.OutputFile, err = writeReleaseOverrides(cmd, yamlBytes, baseConfig.Encryption, outputDir, outputFormat, release.Namespace, release.Name, dryRun)
+			if err != nil {
+				result.Error, result.ErrorCode, result.Category = reportedError(err)
+			}
+		}
+		results = append(results, result)
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	summary := newMultiChartSummary(releaseSummaryRoot, results)
+	logMultiChartSummary(summary)
+	if err := writeMultiChartSummary(cmd, summary, outputDir, outputFormat, dryRun); err != nil {
+		return err
+	}
+	return multiChartError(summary)
+}
+
+// writeReleaseOverrides writes the overrides of a release to outputDir as
+// <namespace>-<release>-overrides.<format>, returning the file written. Nothing is written with
+// --dry-run. configEncryption is the encryption section of the registry config.
+func writeReleaseOverrides(cmd *cobra.Command, yamlBytes []byte, configEncryption *registry.EncryptionConfig, outputDir, outputFormat, namespace, releaseName string, dryRun bool) (string, error) {
+	output, err := formatOverrides(yamlBytes, outputFormat)
+	if err != nil {
+		return "", err
+	}
+	if dryRun {
+		return "", nil
+	}
+	if output, err = encryptOverrides(cmd, output, configEncryption, outputFormat); err != nil {
+		return "", err
+	}
+	outputPath := filepath.Join(outputDir, releaseOutputName(namespace, releaseName, outputFormat))
+	if err := writeOutputFile(outputPath, output, "Override values written"); err != nil {
+		return "", err
+	}
+	return outputPath, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/internal/helm"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestOverrideAllNamespaces(t *testing.T) {
+	cleanup := setupTest(t)
+	defer cleanup()
+
+	mockClient := helm.NewMockHelmClient()
+	mockClient.MockReleases = []*helm.ReleaseElement{
+		{Name: "web", Namespace: "team-a", Chart: "nginx-15.0.0"},
+		{Name: "cache", Namespace: "team-b", Chart: "redis-18.0.0"},
+		{Name: "broken", Namespace: "team-b", Chart: "app-1.0.0"}, // no values: fails
+		{Name: "dns", Namespace: "kube-system", Chart: "coredns-1.0.0"},
+	}
+	mockClient.SetupMockRelease("web", "team-a", map[string]interface{}{"image": "docker.io/library/nginx:1.25"}, &helm.ChartMetadata{Name: "nginx", Version: "15.0.0"})
+	mockClient.SetupMockRelease("cache", "team-b", map[string]interface{}{"image": map[string]interface{}{"repository": "bitnami/redis", "tag": "7.2"}}, &helm.ChartMetadata{Name: "redis", Version: "18.0.0"})
+	mockClient.SetupMockRelease("dns", "kube-system", map[string]interface{}{"image": "docker.io/coredns/coredns:1.11"}, &helm.ChartMetadata{Name: "coredns", Version: "1.0.0"})
+
+	originalHelmAdapterFactory := helmAdapterFactory
+	helmAdapterFactory = func() (*helm.Adapter, error) {
+		return helm.NewAdapter(mockClient, AppFs, true), nil
+	}
+	defer func() { helmAdapterFactory = originalHelmAdapterFactory }()
+
+	cmd := newOverrideCmd()
+	cmd.SetArgs([]string{"-A", "--exclude-namespaces", "kube-*", "-t", "harbor.local", "-s", "docker.io", "--output-dir", "out"})
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	err := cmd.Execute()
+
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitChartProcessingFailed, exitErr.Code, "a failed release fails the run")
+	assert.Contains(t, err.Error(), "helm-release://team-b/broken")
+
+	web, err := afero.ReadFile(AppFs, "out/team-a-web-overrides.yaml")
+	require.NoError(t, err)
+	assert.Contains(t, string(web), "harbor.local")
+	exists, err := afero.Exists(AppFs, "out/team-b-cache-overrides.yaml")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = afero.Exists(AppFs, "out/kube-system-dns-overrides.yaml")
+	require.NoError(t, err)
+	assert.False(t, exists, "filtered namespaces are skipped")
+
+	summaryBytes, err := afero.ReadFile(AppFs, "out/summary.yaml")
+	require.NoError(t, err)
+	var summary MultiChartSummary
+	require.NoError(t, yaml.Unmarshal(summaryBytes, &summary))
+	assert.Equal(t, 2, summary.Succeeded)
+	assert.Equal(t, 1, summary.Failed)
+	require.Len(t, summary.Charts, 3)
+	assert.Equal(t, "team-a", summary.Charts[0].Namespace)
+	assert.Equal(t, "web", summary.Charts[0].Release)
+	assert.Equal(t, "out/team-a-web-overrides.yaml", summary.Charts[0].OutputFile)
+	assert.Equal(t, "broken", summary.Charts[1].Release)
+	assert.Contains(t, summary.Charts[1].Error, "failed to get values for release broken")
+}
+
+func TestOverrideAllNamespacesFlagConflicts(t *testing.T) {
+	cleanup := setupTest(t)
+	defer cleanup()
+
+	registryArgs := []string{"-t", "harbor.local", "-s", "docker.io"}
+	for name, tc := range map[string]struct {
+		args     []string
+		wantCode int
+	}{
+		"chart path":    {args: []string{"-A", "--output-dir", "out", "--chart-path", "chart"}, wantCode: exitcodes.ExitInputConfigurationError},
+		"release name":  {args: []string{"-A", "--output-dir", "out", "my-release"}, wantCode: exitcodes.ExitInputConfigurationError},
+		"namespace":     {args: []string{"-A", "--output-dir", "out", "-n", "team-a"}, wantCode: exitcodes.ExitInputConfigurationError},
+		"output file":   {args: []string{"-A", "--output-dir", "out", "-o", "overrides.yaml"}, wantCode: exitcodes.ExitInputConfigurationError},
+		"recursive":     {args: []string{"-A", "--output-dir", "out", "--recursive"}, wantCode: exitcodes.ExitInputConfigurationError},
+		"set flags":     {args: []string{"-A", "--output-dir", "out", "--output-format", "set-flags"}, wantCode: exitcodes.ExitInputConfigurationError},
+		"no output dir": {args: []string{"-A"}, wantCode: exitcodes.ExitMissingRequiredFlag},
+		"filter only":   {args: []string{"--chart-path", "chart", "--selector", "team=a"}, wantCode: exitcodes.ExitInputConfigurationError},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cmd := newOverrideCmd()
+			cmd.SetArgs(append(append([]string{}, registryArgs...), tc.args...))
+			cmd.SetOut(new(bytes.Buffer))
+			cmd.SetErr(new(bytes.Buffer))
+
+			var exitErr *exitcodes.ExitCodeError
+			require.ErrorAs(t, cmd.Execute(), &exitErr)
+			assert.Equal(t, tc.wantCode, exitErr.Code)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/lucas-albers-lz4/irr/pkg/strategy"
+	"github.com/spf13/cobra"
+)
+
+// addAnnotateOutputFlag adds --annotate-output, which comments how each image was relocated.
+func addAnnotateOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("annotate-output", false, "Add a comment above the override of each image naming its source image, the registry mapping applied and the path strategy (YAML output only)")
+}
+
+// annotateImageOverrides adds a comment above the override of each relocated image when
+// --annotate-output is set, e.g.
+//
+//	# source: docker.io/library/nginx:1.25 | mapping: docker.io -> harbor.local/dockerio | strategy: prefix-source-registry
+//
+// so that the decisions behind a large overrides file can be reviewed image by image.
+func annotateImageOverrides(cmd *cobra.Command, config *GeneratorConfig, result *override.File, data []byte) ([]byte, error) {
+	annotate, err := getBoolFlag(cmd, "annotate-output")
+	if err != nil || !annotate || result == nil {
+		return data, err
+	}
+	comments := make(map[string]string, len(result.Relocations))
+	for _, relocation := range result.Relocations {
+		if relocation.Path != "" {
+			comments[relocation.Path] = relocationComment(config, relocation)
+		}
+	}
+	log.Debug("Annotating image overrides", "images", len(comments))
+	annotated, err := override.AnnotateYAML(data, comments)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  err,
+		}
+	}
+	return annotated, nil
+}
+
+// relocationComment explains the relocation of one image: its source reference, the registry
+// mapping that chose its target (or the --target-registry fallback) and how its repository path
+// was generated, following the decisions of the generator.
+func relocationComment(config *GeneratorConfig, relocation override.Relocation) string {
+	source, sourceRegistry := relocation.Original, ""
+	if ref, err := image.ParseImageReference(relocation.Original); err == nil {
+		source, sourceRegistry = ref.String(), ref.Registry
+	}
+
+	mapped := config.Mappings.GetTargetRegistry(sourceRegistry)
+	mapping := fmt.Sprintf("%s -> %s", sourceRegistry, mapped)
+	if mapped == "" {
+		mapping = fmt.Sprintf("none, --target-registry %s", config.TargetRegistry)
+	}
+
+	pathStrategy := config.StrategyName
+	if pathStrategy == "" {
+		pathStrategy = strategy.StrategyPrefixSourceRegistry
+	}
+	switch {
+	case config.RegistryOnly:
+		pathStrategy = "registry-only"
+	case hasMappedPathPrefix(mapped):
+		// A mapping target with a path prefix keeps the original repository below it
+		pathStrategy = "none, mapping path prefix"
+	}
+	return fmt.Sprintf("source: %s | mapping: %s | strategy: %s", source, mapping, pathStrategy)
+}
+
+// hasMappedPathPrefix reports whether a mapping target, e.g. harbor.local/dockerio, has a path
+// below its registry host
+func hasMappedPathPrefix(target string) bool {
+	_, prefix, found := strings.Cut(target, "/")
+	return found && prefix != ""
+}
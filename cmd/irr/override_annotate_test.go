@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotateImageOverrides(t *testing.T) {
+	config := &GeneratorConfig{
+		TargetRegistry: "registry.local",
+		Mappings:       &registry.Mappings{Entries: []registry.Mapping{{Source: "docker.io", Target: "harbor.local/dockerio"}}},
+	}
+	result := &override.File{Relocations: []override.Relocation{
+		{Path: "image", Original: "nginx:1.25", Relocated: "harbor.local/dockerio/library/nginx:1.25"},
+		{Path: "sidecars[0].image", Original: "quay.io/prometheus/node-exporter:v1.7.0", Relocated: "registry.local/quayio/prometheus/node-exporter:v1.7.0"},
+	}}
+	data := []byte("image:\n    registry: harbor.local\nsidecars:\n    - image:\n        registry: registry.local\n")
+
+	cmd := newOverrideCmd()
+	unchanged, err := annotateImageOverrides(cmd, config, result, data)
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(unchanged))
+
+	require.NoError(t, cmd.ParseFlags([]string{"--annotate-output"}))
+	annotated, err := annotateImageOverrides(cmd, config, result, data)
+	require.NoError(t, err)
+	assert.Contains(t, string(annotated),
+		"# source: docker.io/library/nginx:1.25 | mapping: docker.io -> harbor.local/dockerio | strategy: none, mapping path prefix\nimage:")
+	assert.Contains(t, string(annotated),
+		"# source: quay.io/prometheus/node-exporter:v1.7.0 | mapping: none, --target-registry registry.local | strategy: prefix-source-registry\n")
+}
+
+func TestRelocationComment(t *testing.T) {
+	relocation := override.Relocation{Path: "image", Original: "quay.io/jetstack/cert-manager:v1.14.0"}
+
+	config := &GeneratorConfig{
+		TargetRegistry: "registry.local",
+		StrategyName:   "flat",
+		Mappings:       &registry.Mappings{Entries: []registry.Mapping{{Source: "quay.io", Target: "harbor.local"}}},
+	}
+	assert.Equal(t, "source: quay.io/jetstack/cert-manager:v1.14.0 | mapping: quay.io -> harbor.local | strategy: flat",
+		relocationComment(config, relocation))
+
+	config.RegistryOnly = true
+	assert.Equal(t, "source: quay.io/jetstack/cert-manager:v1.14.0 | mapping: quay.io -> harbor.local | strategy: registry-only",
+		relocationComment(config, relocation))
+}
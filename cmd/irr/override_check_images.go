@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/distribution/reference"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/lucas-albers-lz4/irr/pkg/progress"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/cobra"
+)
+
+// newPlatformChecker creates the checker used by --check-images, sending its requests through
+// transport. It can be replaced in tests.
+var newPlatformChecker = func(credentials map[string]registry.Credentials, transport http.RoundTripper) *registry.TagChecker {
+	checker := registry.NewTagChecker(credentials, registry.DefaultStaleAfter)
+	checker.Client.Transport = transport
+	checker.Helpers = registryCredentialHelpers()
+	return checker
+}
+
+// PlatformGap is a relocated image whose target lacks platforms it needs.
+type PlatformGap struct {
+	// Original and Relocated are the image references before and after relocation
+	Original  string
+	Relocated string
+	// MissingInSource are required platforms the source image does not provide
+	MissingInSource []string
+	// MissingInTarget are platforms the relocated image does not provide, although they are
+	// required or the source image provides them
+	MissingInTarget []string
+	// Err is the error that prevented the image from being checked
+	Err error
+}
+
+// String describes the gap for the report of --check-images
+func (g PlatformGap) String() string {
+	var problems []string
+	if g.Err != nil {
+		problems = append(problems, g.Err.Error())
+	}
+	if len(g.MissingInSource) > 0 {
+		problems = append(problems, "source lacks "+strings.Join(g.MissingInSource, ", "))
+	}
+	if len(g.MissingInTarget) > 0 {
+		problems = append(problems, "target lacks "+strings.Join(g.MissingInTarget, ", "))
+	}
+	return fmt.Sprintf("%s (from %s): %s", g.Relocated, g.Original, strings.Join(problems, "; "))
+}
+
+// addCheckImagesFlags adds the flags that check the platforms of relocated images in the source
+// and target registries.
+func addCheckImagesFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("check-images", false, "Inspect the manifest of each relocated image in its source and target registry and fail when the target lacks a platform the source provides or --platforms requires")
+	cmd.Flags().StringSlice("platforms", nil, "With --check-images, platforms (os/arch[/variant], comma-separated) every image must provide in both its source and target registry, e.g. linux/amd64,linux/arm64")
+}
+
+// checkRelocatedImages checks the platforms of the relocated images of result when --check-images
+// is set. Without --platforms, the relocated image must provide every platform of the source image;
+// with it, both images must provide each of the given platforms. Images with a gap are logged and
+// fail the command with a report listing them.
+func checkRelocatedImages(cmd *cobra.Command, config *GeneratorConfig, result *override.File) error {
+	check, err := getBoolFlag(cmd, "check-images")
+	if err != nil || !check || result == nil {
+		return err
+	}
+	platformFlags, err := getStringSliceFlag(cmd, "platforms")
+	if err != nil {
+		return err
+	}
+	platforms, err := registry.ParsePlatforms(platformFlags)
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	if err := requireNetwork("checking relocated images in registries (--check-images)"); err != nil {
+		return err
+	}
+	credentialFiles, err := registryCredentialFiles(cmd)
+	if err != nil {
+		return err
+	}
+	credentials, err := registry.LoadCredentials(AppFs, credentialFiles...)
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	transport, err := newNetworkTransport(config.RegistryTLS)
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	gaps := checkImagePlatforms(ctx, newPlatformChecker(credentials, transport), result.Relocations, platforms)
+	if len(gaps) == 0 {
+		log.Info("Relocated images provide the required platforms", "images", len(result.Relocations), "platforms", strings.Join(platforms, ","))
+		return nil
+	}
+
+	lines := make([]string, 0, len(gaps))
+	for _, gap := range gaps {
+		log.Error("Relocated image lacks required platforms",
+			"image", gap.Relocated,
+			"source", gap.Original,
+			"missingInSource", strings.Join(gap.MissingInSource, ","),
+			"missingInTarget", strings.Join(gap.MissingInTarget, ","),
+			"error", gap.Err)
+		lines = append(lines, gap.String())
+	}
+	return &exitcodes.ExitCodeError{
+		Code: exitcodes.ExitImageProcessingError,
+		Err:  fmt.Errorf("%d relocated image(s) lack required platforms:\n  %s", len(gaps), strings.Join(lines, "\n  ")),
+	}
+}
+
+// checkImagePlatforms fetches the platforms of the source and target image of each distinct
+// relocation and returns those with a gap, in the order of relocations.
+func checkImagePlatforms(ctx context.Context, checker *registry.TagChecker, relocations []override.Relocation, required []string) []PlatformGap {
+	var checks []override.Relocation
+	seen := make(map[override.Relocation]bool)
+	for _, relocation := range relocations {
+		key := override.Relocation{Original: relocation.Original, Relocated: relocation.Relocated}
+		if !seen[key] {
+			seen[key] = true
+			checks = append(checks, key)
+		}
+	}
+	log.Info("Checking platforms of relocated images", "images", len(checks))
+
+	bar := progress.Start(os.Stderr, "Checking image platforms", len(checks))
+	results := make([]PlatformGap, len(checks))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, tagCheckWorkers)
+	for i, relocation := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = checkRelocationPlatforms(ctx, checker, relocation, required)
+			bar.Add(1)
+		}()
+	}
+	wg.Wait()
+	bar.Finish()
+
+	var gaps []PlatformGap
+	for _, gap := range results {
+		if gap.Err != nil || len(gap.MissingInSource) > 0 || len(gap.MissingInTarget) > 0 {
+			gaps = append(gaps, gap)
+		}
+	}
+	return gaps
+}
+
+// checkRelocationPlatforms compares the platforms of the source and target image of relocation
+func checkRelocationPlatforms(ctx context.Context, checker *registry.TagChecker, relocation override.Relocation, required []string) PlatformGap {
+	gap := PlatformGap{Original: relocation.Original, Relocated: relocation.Relocated}
+	source, err := imagePlatforms(ctx, checker, relocation.Original)
+	if err != nil {
+		gap.Err = fmt.Errorf("source: %w", err)
+		return gap
+	}
+	target, err := imagePlatforms(ctx, checker, relocation.Relocated)
+	if err != nil {
+		gap.Err = fmt.Errorf("target: %w", err)
+		return gap
+	}
+	if len(required) == 0 {
+		gap.MissingInTarget = registry.MissingPlatforms(source, target)
+		return gap
+	}
+	gap.MissingInSource = registry.MissingPlatforms(required, source)
+	gap.MissingInTarget = registry.MissingPlatforms(required, target)
+	return gap
+}
+
+// imagePlatforms returns the platforms of the image reference ref. The reference is parsed with
+// distribution/reference rather than image.ParseImageReference, which drops registry ports.
+func imagePlatforms(ctx context.Context, checker *registry.TagChecker, ref string) ([]string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference %q: %w", ref, err)
+	}
+	var tagOrDigest string
+	if digested, ok := named.(reference.Digested); ok {
+		tagOrDigest = digested.Digest().String()
+	} else if tagged, ok := named.(reference.Tagged); ok {
+		tagOrDigest = tagged.Tag()
+	}
+	if tagOrDigest == "" {
+		return nil, fmt.Errorf("image %q has neither a tag nor a digest", ref)
+	}
+	platforms, err := checker.ImagePlatforms(ctx, reference.Domain(named), reference.Path(named), tagOrDigest)
+	if errors.Is(err, registry.ErrManifestNotFound) {
+		return nil, fmt.Errorf("image %s not found", ref)
+	}
+	return platforms, err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckImagePlatforms(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/upstream/app/manifests/1.0", "/v2/mirror/app/manifests/1.0":
+			_, _ = w.Write([]byte(`{"manifests": [
+				{"digest": "sha256:amd", "platform": {"os": "linux", "architecture": "amd64"}},
+				{"digest": "sha256:arm", "platform": {"os": "linux", "architecture": "arm64"}}
+			]}`))
+		case "/v2/upstream/db/manifests/2.0":
+			_, _ = w.Write([]byte(`{"manifests": [
+				{"digest": "sha256:amd", "platform": {"os": "linux", "architecture": "amd64"}},
+				{"digest": "sha256:arm", "platform": {"os": "linux", "architecture": "arm64"}}
+			]}`))
+		case "/v2/mirror/db/manifests/2.0", "/v2/upstream/legacy/manifests/3.0", "/v2/mirror/legacy/manifests/3.0":
+			_, _ = w.Write([]byte(`{"config": {"digest": "sha256:config"}}`))
+		case "/v2/mirror/db/blobs/sha256:config", "/v2/upstream/legacy/blobs/sha256:config", "/v2/mirror/legacy/blobs/sha256:config":
+			_, _ = w.Write([]byte(`{"os": "linux", "architecture": "amd64"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	host := strings.TrimPrefix(server.URL, "https://")
+	checker := registry.NewTagChecker(nil, registry.DefaultStaleAfter)
+	checker.Client = server.Client()
+
+	relocations := []override.Relocation{
+		{Path: "app.image", Original: host + "/upstream/app:1.0", Relocated: host + "/mirror/app:1.0"},
+		{Path: "db.image", Original: host + "/upstream/db:2.0", Relocated: host + "/mirror/db:2.0"},
+		{Path: "legacy.image", Original: host + "/upstream/legacy:3.0", Relocated: host + "/mirror/legacy:3.0"},
+		{Path: "missing.image", Original: host + "/upstream/app:1.0", Relocated: host + "/mirror/missing:1.0"},
+	}
+
+	t.Run("target must provide the platforms of the source", func(t *testing.T) {
+		gaps := checkImagePlatforms(context.Background(), checker, relocations, nil)
+		require.Len(t, gaps, 2)
+		assert.Equal(t, []string{"linux/arm64"}, gaps[0].MissingInTarget)
+		assert.Equal(t, host+"/mirror/db:2.0 (from "+host+"/upstream/db:2.0): target lacks linux/arm64", gaps[0].String())
+		assert.ErrorContains(t, gaps[1].Err, "target: image "+host+"/mirror/missing:1.0 not found")
+	})
+
+	t.Run("required platforms", func(t *testing.T) {
+		gaps := checkImagePlatforms(context.Background(), checker, relocations[:3], []string{"linux/amd64", "linux/arm64"})
+		require.Len(t, gaps, 2)
+		assert.Equal(t, host+"/mirror/db:2.0", gaps[0].Relocated)
+		assert.Empty(t, gaps[0].MissingInSource)
+		assert.Equal(t, []string{"linux/arm64"}, gaps[1].MissingInSource)
+		assert.Equal(t, []string{"linux/arm64"}, gaps[1].MissingInTarget)
+	})
+}
+
+func TestCheckRelocatedImagesFlags(t *testing.T) {
+	cmd := newOverrideCmd()
+	require.NoError(t, cmd.Flags().Set("check-images", "true"))
+	require.NoError(t, cmd.Flags().Set("platforms", "linux"))
+	err := checkRelocatedImages(cmd, &GeneratorConfig{}, &override.File{})
+	assert.ErrorContains(t, err, `invalid platform "linux"`)
+
+	require.NoError(t, cmd.Flags().Set("check-images", "false"))
+	assert.NoError(t, checkRelocatedImages(cmd, &GeneratorConfig{}, &override.File{}))
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/spf13/cobra"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// addIncludeDisabledFlag adds --include-disabled, which keeps the images of disabled subcharts.
+func addIncludeDisabledFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("include-disabled", false, "Generate overrides for images of subcharts disabled by their dependency condition or tags")
+}
+
+// excludeDisabledPatterns drops the patterns held by subcharts of loadedChart that values disable
+// through their dependency condition or tags, unless --include-disabled is set. The context-aware
+// analysis passes the merged values; without them the chart's default values decide.
+func excludeDisabledPatterns(cmd *cobra.Command, loadedChart *helmchart.Chart, values map[string]interface{}, patterns []analysis.ImagePattern) ([]analysis.ImagePattern, error) {
+	includeDisabled, err := getBoolFlag(cmd, "include-disabled")
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = loadedChart.Values
+	}
+	if analysis.MarkDisabledSubcharts(loadedChart, values, patterns) == 0 || includeDisabled {
+		return patterns, nil
+	}
+
+	enabled := make([]analysis.ImagePattern, 0, len(patterns))
+	for i := range patterns {
+		if !patterns[i].Disabled {
+			enabled = append(enabled, patterns[i])
+			continue
+		}
+		log.Info("Skipping image of disabled subchart (use --include-disabled to keep it)",
+			"path", patterns[i].Path, "subchart", patterns[i].Subchart.ValuesPath, "disabledBy", patterns[i].Subchart.DisabledBy)
+	}
+	return enabled, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+func TestExcludeDisabledPatterns(t *testing.T) {
+	loadedChart := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{
+			Name:         "platform",
+			Dependencies: []*helmchart.Dependency{{Name: "postgresql", Condition: "postgresql.enabled"}},
+		},
+		Values: map[string]interface{}{"postgresql": map[string]interface{}{"enabled": false}},
+	}
+	loadedChart.SetDependencies(&helmchart.Chart{Metadata: &helmchart.Metadata{Name: "postgresql"}})
+	newPatterns := func() []analysis.ImagePattern {
+		return []analysis.ImagePattern{{Path: "image"}, {Path: "postgresql.image"}}
+	}
+
+	cmd := newOverrideCmd()
+	patterns, err := excludeDisabledPatterns(cmd, loadedChart, nil, newPatterns())
+	require.NoError(t, err)
+	assert.Equal(t, []analysis.ImagePattern{{Path: "image"}}, patterns, "chart defaults disable postgresql")
+
+	merged := map[string]interface{}{"postgresql": map[string]interface{}{"enabled": true}}
+	patterns, err = excludeDisabledPatterns(cmd, loadedChart, merged, newPatterns())
+	require.NoError(t, err)
+	assert.Len(t, patterns, 2, "merged values enable postgresql")
+
+	require.NoError(t, cmd.ParseFlags([]string{"--include-disabled"}))
+	patterns, err = excludeDisabledPatterns(cmd, loadedChart, nil, newPatterns())
+	require.NoError(t, err)
+	require.Len(t, patterns, 2)
+	assert.True(t, patterns[1].Disabled)
+}
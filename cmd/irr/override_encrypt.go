@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/lucas-albers-lz4/irr/pkg/encryption"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/cobra"
+)
+
+// encryptData and decryptData run age or sops; variables to support mocking in tests
+var (
+	encryptData = encryption.Encrypt
+	decryptData = encryption.Decrypt
+)
+
+// addEncryptFlags adds --encrypt and --encrypt-recipient, which encrypt the overrides before they
+// are written or published.
+func addEncryptFlags(cmd *cobra.Command) {
+	cmd.Flags().String("encrypt", "", "Encrypt the overrides with age or sops before they are written or published, e.g. to keep internal registry hostnames private")
+	cmd.Flags().StringSlice("encrypt-recipient", nil, "Key the overrides are encrypted to: an age or SSH public key or recipients file for age; an age key, PGP fingerprint, KMS key ARN or ID, or Key Vault URL for sops; can be repeated")
+}
+
+// validateEncryptFlags rejects an unsupported --encrypt format and flag combinations that write
+// into existing, unencrypted values files.
+func validateEncryptFlags(cmd *cobra.Command) error {
+	format, err := getStringFlag(cmd, "encrypt")
+	if err != nil || format == "" {
+		return err
+	}
+	if err := encryption.ValidateFormat(format); err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: fmt.Errorf("invalid --encrypt: %w", err)}
+	}
+	return rejectEncryptedMerge(cmd, "--encrypt")
+}
+
+// rejectEncryptedMerge fails when the overrides are to be merged into an existing values file,
+// which would then have to be encrypted as a whole.
+func rejectEncryptedMerge(cmd *cobra.Command, source string) error {
+	mergeInto, err := getStringFlag(cmd, "merge-into")
+	if err != nil {
+		return err
+	}
+	update, err := getBoolFlag(cmd, "update")
+	if err != nil {
+		return err
+	}
+	switch {
+	case mergeInto != "":
+		err = fmt.Errorf("%s cannot be used with --merge-into", source)
+	case update:
+		err = fmt.Errorf("%s cannot be used with --update", source)
+	default:
+		return nil
+	}
+	return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+}
+
+// applyConfigEncryption sets the encryption section of a loaded registry config on config, so the
+// overrides generated with it are encrypted when they are written.
+func applyConfigEncryption(config *GeneratorConfig, mappingsConfig *registry.Config, source string) {
+	if mappingsConfig.Encryption == nil || mappingsConfig.Encryption.Format == "" {
+		return
+	}
+	config.Encryption = mappingsConfig.Encryption
+	log.Debug("Applied the encryption of the registry config", "source", source, "format", config.Encryption.Format)
+}
+
+// overridesEncryption returns how the overrides are encrypted, from --encrypt and
+// --encrypt-recipient or else configEncryption, the encryption section of the registry config, or
+// nil when they are not encrypted.
+func overridesEncryption(cmd *cobra.Command, configEncryption *registry.EncryptionConfig, outputFormat string) (*encryption.Options, error) {
+	format, err := getStringFlag(cmd, "encrypt")
+	if err != nil {
+		return nil, err
+	}
+	recipients, err := getStringSliceFlag(cmd, "encrypt-recipient")
+	if err != nil {
+		return nil, err
+	}
+	if format == "" && configEncryption != nil {
+		format = configEncryption.Format
+	}
+	if len(recipients) == 0 && configEncryption != nil {
+		recipients = configEncryption.Recipients
+	}
+	if format == "" {
+		return nil, nil
+	}
+	return &encryption.Options{Format: format, Recipients: recipients, DataType: encryption.DataTypeOf(outputFormat)}, nil
+}
+
+// encryptOverrides encrypts the formatted overrides when --encrypt or configEncryption, the
+// encryption section of the registry config, asks for it, and returns them unchanged otherwise.
+func encryptOverrides(cmd *cobra.Command, data []byte, configEncryption *registry.EncryptionConfig, outputFormat string) ([]byte, error) {
+	opts, err := overridesEncryption(cmd, configEncryption, outputFormat)
+	if err != nil || opts == nil {
+		return data, err
+	}
+	encrypted, err := encryptData(getCommandContext(cmd), data, *opts)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to encrypt the overrides with %s: %w", opts.Format, err),
+		}
+	}
+	log.Debug("Overrides encrypted", "format", opts.Format, "recipients", len(opts.Recipients))
+	return encrypted, nil
+}
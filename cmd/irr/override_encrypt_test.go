@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/encryption"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgeArmor is the start of a file encrypted with age --armor
+const fakeAgeArmor = "-----BEGIN AGE ENCRYPTED FILE-----\n"
+
+// replaceEncryption makes encryption prefix the data with an age header and decryption remove it,
+// recording the options of each call until the test ends.
+func replaceEncryption(t *testing.T) *[]encryption.Options {
+	t.Helper()
+	originalEncrypt, originalDecrypt := encryptData, decryptData
+	t.Cleanup(func() {
+		encryptData, decryptData = originalEncrypt, originalDecrypt
+	})
+	var calls []encryption.Options
+	encryptData = func(_ context.Context, data []byte, opts encryption.Options) ([]byte, error) {
+		calls = append(calls, opts)
+		return append([]byte(fakeAgeArmor), data...), nil
+	}
+	decryptData = func(_ context.Context, data []byte, opts encryption.Options) ([]byte, error) {
+		calls = append(calls, opts)
+		return data[len(fakeAgeArmor):], nil
+	}
+	return &calls
+}
+
+func TestValidateEncryptFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "not set"},
+		{name: "age", args: []string{"--encrypt", "age", "--encrypt-recipient", "age1qqq"}},
+		{name: "sops", args: []string{"--encrypt", "sops"}},
+		{name: "unsupported", args: []string{"--encrypt", "gpg"}, wantErr: "unsupported encryption format"},
+		{name: "merge into", args: []string{"--encrypt", "sops", "--merge-into", "values.yaml"}, wantErr: "--merge-into"},
+		{name: "update", args: []string{"--encrypt", "sops", "--update"}, wantErr: "--update"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newOverrideCmd()
+			require.NoError(t, cmd.ParseFlags(tt.args))
+			err := validateEncryptFlags(cmd)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var exitErr *exitcodes.ExitCodeError
+			require.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestOverridesEncryption(t *testing.T) {
+	replaceEncryption(t)
+
+	cmd := newOverrideCmd()
+	opts, err := overridesEncryption(cmd, nil, outputFormatYAML)
+	require.NoError(t, err)
+	assert.Nil(t, opts, "overrides are not encrypted by default")
+
+	config := &GeneratorConfig{}
+	applyConfigEncryption(config, &registry.Config{Encryption: &registry.EncryptionConfig{Format: "sops", Recipients: []string{"age1config"}}}, "registry-mappings.yaml")
+	opts, err = overridesEncryption(cmd, config.Encryption, outputFormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, &encryption.Options{Format: "sops", Recipients: []string{"age1config"}, DataType: encryption.TypeJSON}, opts)
+
+	require.NoError(t, cmd.ParseFlags([]string{"--encrypt", "age", "--encrypt-recipient", "age1flag"}))
+	opts, err = overridesEncryption(cmd, config.Encryption, outputFormatYAML)
+	require.NoError(t, err)
+	assert.Equal(t, &encryption.Options{Format: "age", Recipients: []string{"age1flag"}, DataType: encryption.TypeYAML}, opts, "flags take precedence")
+}
+
+func TestOutputOverridesEncrypted(t *testing.T) {
+	calls := replaceEncryption(t)
+	restoreFs := SetFs(afero.NewMemMapFs())
+	defer restoreFs()
+	content := []byte("image:\n  registry: harbor.internal\n")
+
+	cmd := newOverrideCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--encrypt", "age", "--encrypt-recipient", "age1qqq"}))
+	require.NoError(t, outputOverrides(cmd, content, nil, "overrides.yaml.age", false))
+	written, err := afero.ReadFile(AppFs, "overrides.yaml.age")
+	require.NoError(t, err)
+	assert.Equal(t, fakeAgeArmor+string(content), string(written))
+	require.Len(t, *calls, 1)
+	assert.Equal(t, []string{"age1qqq"}, (*calls)[0].Recipients)
+
+	require.NoError(t, outputOverrides(cmd, content, nil, "", true))
+	assert.Len(t, *calls, 1, "dry runs print the overrides unencrypted")
+}
+
+func TestOutputOrMergeOverridesConfigEncryption(t *testing.T) {
+	replaceEncryption(t)
+
+	cmd := newOverrideCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--merge-into", "values.yaml"}))
+	err := outputOrMergeOverrides(cmd, []byte("image: {}\n"), &registry.EncryptionConfig{Format: "sops"}, "", false)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	assert.ErrorContains(t, err, "the encryption section of the registry config cannot be used with --merge-into")
+}
+
+func TestResolveAgeValuesFlag(t *testing.T) {
+	calls := replaceEncryption(t)
+	restoreFs := SetFs(afero.NewOsFs())
+	defer restoreFs()
+	t.Cleanup(removeSecretValues)
+
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "prod.yaml")
+	encrypted := filepath.Join(dir, "overrides.yaml")
+	require.NoError(t, os.WriteFile(plain, []byte("replicas: 2\n"), 0o600))
+	require.NoError(t, os.WriteFile(encrypted, []byte(fakeAgeArmor+"image:\n  registry: harbor.internal\n"), 0o600))
+
+	cmd := newValidateCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--values", plain, "--values", encrypted, "--decrypt-identity", "keys.txt"}))
+	require.NoError(t, resolveSecretValuesFlag(cmd))
+	files, err := cmd.Flags().GetStringSlice("values")
+	require.NoError(t, err)
+	assert.Equal(t, []string{plain, filepath.Join(secretValuesDir, "1-overrides.yaml")}, files)
+	decrypted, err := os.ReadFile(files[1])
+	require.NoError(t, err)
+	assert.Equal(t, "image:\n  registry: harbor.internal\n", string(decrypted))
+	require.Len(t, *calls, 1)
+	assert.Equal(t, encryption.Options{Format: encryption.FormatAge, Identities: []string{"keys.txt"}}, (*calls)[0])
+
+	decryptData = func(context.Context, []byte, encryption.Options) ([]byte, error) {
+		return nil, errors.New("age: no identity matched any of the recipients")
+	}
+	_, _, err = resolveAgeValues(cmd, []string{encrypted})
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	assert.ErrorContains(t, err, "failed to decrypt values file "+encrypted)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+)
+
+// relocationExceptions returns the relocation exceptions of the chart at config.ChartPath: those of
+// the mappings file followed by those of the chart's .irr-ignore file.
+func relocationExceptions(config *GeneratorConfig) ([]registry.RelocationException, error) {
+	chartExceptions, err := registry.LoadChartExceptions(AppFs, config.ChartPath)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	if len(chartExceptions) > 0 {
+		log.Info("Relocation exceptions loaded from chart", "file", registry.ChartExceptionsFile, "chart", config.ChartPath, "count", len(chartExceptions))
+	}
+	exceptions := append(slices.Clip(config.Exceptions), chartExceptions...)
+	if len(exceptions) == 0 {
+		return nil, nil
+	}
+	return exceptions, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelocationExceptions(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	restoreFs := SetFs(fs)
+	t.Cleanup(restoreFs)
+	require.NoError(t, afero.WriteFile(fs, "/charts/app/.irr-ignore", []byte("exceptions:\n  - path: enterprise.*\n    reason: licensed\n"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/charts/bad/.irr-ignore", []byte("exceptions:\n  - reason: licensed\n"), 0o644))
+	require.NoError(t, fs.MkdirAll("/charts/plain", 0o755))
+	configExceptions := []registry.RelocationException{{Image: "registry.vendor.com/**"}}
+
+	config := &GeneratorConfig{ChartPath: "/charts/app", Exceptions: configExceptions}
+	exceptions, err := relocationExceptions(config)
+	require.NoError(t, err)
+	assert.Equal(t, []registry.RelocationException{
+		{Image: "registry.vendor.com/**"},
+		{Path: "enterprise.*", Reason: "licensed"},
+	}, exceptions)
+	assert.Len(t, config.Exceptions, 1, "the config's exceptions are not modified")
+
+	exceptions, err = relocationExceptions(&GeneratorConfig{ChartPath: "/charts/plain"})
+	require.NoError(t, err)
+	assert.Nil(t, exceptions)
+
+	_, err = relocationExceptions(&GeneratorConfig{ChartPath: "/charts/bad"})
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+}
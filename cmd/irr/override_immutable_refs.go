@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/lucas-albers-lz4/irr/pkg/chart"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/lucas-albers-lz4/irr/pkg/strictness"
+	"github.com/spf13/cobra"
+)
+
+// newDigestChecker creates the checker used by --pin-digests, sending its requests through
+// transport. It can be replaced in tests.
+var newDigestChecker = func(credentials map[string]registry.Credentials, transport http.RoundTripper) *registry.TagChecker {
+	checker := registry.NewTagChecker(credentials, 0)
+	checker.Client.Transport = transport
+	checker.Helpers = registryCredentialHelpers()
+	return checker
+}
+
+// addImmutableRefsFlags adds the flags that require relocated images to be referenced immutably
+// and pin the images referenced by mutable tags to digests.
+func addImmutableRefsFlags(cmd *cobra.Command) {
+	cmd.Flags().String("require-immutable-refs", "", "Fail (error, the default without a value) or warn when relocated images are referenced by a mutable tag such as latest or a branch name rather than a digest or full version")
+	cmd.Flags().Lookup("require-immutable-refs").NoOptDefVal = string(strictness.ActionError)
+	cmd.Flags().Bool("pin-digests", false, "Pin relocated images referenced by a mutable tag to the digest the tag points to in the source registry")
+}
+
+// getImmutableRefsFlags returns the action of --require-immutable-refs for the mutableReferences
+// strict mode condition, "" when it is not set, and whether --pin-digests is set.
+func getImmutableRefsFlags(cmd *cobra.Command, registryOnly bool) (action strictness.Action, pinDigests bool, err error) {
+	name, err := getStringFlag(cmd, "require-immutable-refs")
+	if err != nil {
+		return "", false, err
+	}
+	action = strictness.Action(name)
+	if err := (strictness.Policy{MutableReferences: action}).Validate(); err != nil {
+		return "", false, &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: fmt.Errorf("invalid --require-immutable-refs: %w", err)}
+	}
+	pinDigests, err = getBoolFlag(cmd, "pin-digests")
+	if err != nil {
+		return "", false, err
+	}
+	if pinDigests && registryOnly {
+		return "", false, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--pin-digests cannot be used with --registry-only, which keeps the chart's tags"),
+		}
+	}
+	return action, pinDigests, nil
+}
+
+// digestResolver returns the resolver pinning images referenced by mutable tags to digests when
+// --pin-digests is set, and nil otherwise. Digests are looked up once per image and tag.
+func digestResolver(cmd *cobra.Command, config *GeneratorConfig) (chart.DigestResolver, error) {
+	if !config.PinDigests {
+		return nil, nil
+	}
+	if err := requireNetwork("pinning image tags to digests (--pin-digests)"); err != nil {
+		return nil, err
+	}
+	credentialFiles, err := registryCredentialFiles(cmd)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := registry.LoadCredentials(AppFs, credentialFiles...)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	transport, err := newNetworkTransport(config.RegistryTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	checker := newDigestChecker(credentials, transport)
+	ctx := getCommandContext(cmd)
+	digests := make(map[string]string)
+	return func(imgRef *image.Reference, tag string) (string, error) {
+		key := imgRef.Registry + "/" + imgRef.Repository + ":" + tag
+		if digest, ok := digests[key]; ok {
+			return digest, nil
+		}
+		digest, err := checker.ImageDigest(ctx, imgRef.Registry, imgRef.Repository, tag)
+		if err != nil {
+			return "", err
+		}
+		digests[key] = digest
+		return digest, nil
+	}, nil
+}
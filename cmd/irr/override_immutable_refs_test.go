@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/lucas-albers-lz4/irr/pkg/strictness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetImmutableRefsFlags(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		registryOnly bool
+		wantAction   strictness.Action
+		wantPin      bool
+		wantErr      string
+	}{
+		{name: "not set"},
+		{name: "without a value", args: []string{"--require-immutable-refs"}, wantAction: strictness.ActionError},
+		{name: "warn", args: []string{"--require-immutable-refs=warn"}, wantAction: strictness.ActionWarn},
+		{name: "pin digests", args: []string{"--pin-digests"}, wantPin: true},
+		{name: "invalid action", args: []string{"--require-immutable-refs=fail"}, wantErr: "invalid --require-immutable-refs"},
+		{name: "pin registry only", args: []string{"--pin-digests"}, registryOnly: true, wantErr: "--registry-only"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newOverrideCmd()
+			require.NoError(t, cmd.ParseFlags(tt.args))
+			action, pinDigests, err := getImmutableRefsFlags(cmd, tt.registryOnly)
+			if tt.wantErr != "" {
+				var exitErr *exitcodes.ExitCodeError
+				require.ErrorAs(t, err, &exitErr)
+				assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAction, action)
+			assert.Equal(t, tt.wantPin, pinDigests)
+		})
+	}
+}
+
+func TestStrictPolicyRequireImmutableRefs(t *testing.T) {
+	config := &GeneratorConfig{StrictMode: true}
+	assert.Equal(t, strictness.ActionIgnore, config.strictPolicy().ActionFor(strictness.MutableReferences))
+
+	config.RequireImmutableRefs = strictness.ActionWarn
+	assert.Equal(t, strictness.ActionWarn, config.strictPolicy().ActionFor(strictness.MutableReferences))
+	assert.Equal(t, strictness.ActionError, config.strictPolicy().ActionFor(strictness.UnparseableImages))
+
+	filePolicy := strictness.Policy{MutableReferences: strictness.ActionError}
+	config = &GeneratorConfig{StrictPolicy: &filePolicy, RequireImmutableRefs: strictness.ActionWarn}
+	assert.Equal(t, strictness.ActionWarn, config.strictPolicy().ActionFor(strictness.MutableReferences), "the flag takes precedence")
+}
+
+func TestDigestResolver(t *testing.T) {
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v2/org/app/manifests/latest" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", "sha256:abc")
+	}))
+	t.Cleanup(server.Close)
+	original := newDigestChecker
+	t.Cleanup(func() { newDigestChecker = original })
+	newDigestChecker = func(credentials map[string]registry.Credentials, _ http.RoundTripper) *registry.TagChecker {
+		checker := registry.NewTagChecker(credentials, 0)
+		checker.Client = server.Client()
+		return checker
+	}
+
+	cmd := newOverrideCmd()
+	resolver, err := digestResolver(cmd, &GeneratorConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, resolver, "tags are not pinned without --pin-digests")
+
+	resolver, err = digestResolver(cmd, &GeneratorConfig{PinDigests: true})
+	require.NoError(t, err)
+	imgRef := &image.Reference{Registry: strings.TrimPrefix(server.URL, "https://"), Repository: "org/app"}
+	for range 2 {
+		digest, err := resolver(imgRef, "latest")
+		require.NoError(t, err)
+		assert.Equal(t, "sha256:abc", digest)
+	}
+	assert.Equal(t, 1, requests, "digests are looked up once per image and tag")
+
+	_, err = resolver(imgRef, "main")
+	assert.ErrorIs(t, err, registry.ErrManifestNotFound)
+}
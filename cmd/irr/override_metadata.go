@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/spf13/cobra"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// Values of --metadata
+const (
+	metadataModeComment = "comment"
+	metadataModeKey     = "key"
+)
+
+// metadataNow returns the generation time recorded by --metadata. It can be replaced in tests.
+var metadataNow = time.Now
+
+// addMetadataFlag adds --metadata, which embeds relocation metadata in the overrides.
+func addMetadataFlag(cmd *cobra.Command) {
+	cmd.Flags().String("metadata", "", "Embed relocation metadata (irr version, time, chart, config hash and original -> relocated images) in the overrides: comment (YAML comments) or key (a top-level irr: key)")
+}
+
+// relocationMetadata embeds the --metadata block in the overrides of result, generated for
+// loadedChart with config. With --metadata=key the block is added to result.Values; with
+// --metadata=comment it is returned as comment lines to put at the top of the YAML overrides.
+func relocationMetadata(cmd *cobra.Command, config *GeneratorConfig, loadedChart *helmchart.Chart, result *override.File) (string, error) {
+	mode, err := getStringFlag(cmd, "metadata")
+	if err != nil || mode == "" || result == nil {
+		return "", err
+	}
+	mode = strings.ToLower(mode)
+	if mode != metadataModeComment && mode != metadataModeKey {
+		return "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("invalid --metadata %q: must be %s or %s", mode, metadataModeComment, metadataModeKey),
+		}
+	}
+
+	metadata := &override.Metadata{
+		Version:     BinaryVersion,
+		GeneratedAt: metadataNow().UTC().Format(time.RFC3339),
+		ConfigHash:  relocationConfigHash(config),
+		Images:      append([]override.Relocation{}, result.Relocations...),
+		Excluded:    result.Excluded,
+	}
+	if loadedChart != nil && loadedChart.Metadata != nil {
+		metadata.ChartName = loadedChart.Metadata.Name
+		metadata.ChartVersion = loadedChart.Metadata.Version
+	}
+
+	if mode == metadataModeComment {
+		return metadata.Comment(), nil
+	}
+	if result.Values == nil {
+		result.Values = make(map[string]interface{})
+	}
+	result.Values[override.MetadataKey] = metadata.Values()
+	return "", nil
+}
+
+// relocationConfigHash returns a hash of the settings that decide where images are relocated:
+// target and source registries, mappings, path strategy, target flavor and default tag. Overrides
+// with the same hash were generated with the same registry configuration.
+func relocationConfigHash(config *GeneratorConfig) string {
+	if config == nil {
+		return ""
+	}
+	settings := struct {
+		TargetRegistry    string   `json:"targetRegistry"`
+		SourceRegistries  []string `json:"sourceRegistries"`
+		ExcludeRegistries []string `json:"excludeRegistries"`
+		Mappings          []string `json:"mappings"`
+		PathStrategy      string   `json:"pathStrategy"`
+		TargetFlavor      string   `json:"targetFlavor"`
+		DefaultTag        string   `json:"defaultTag"`
+	}{
+		TargetRegistry:    config.TargetRegistry,
+		SourceRegistries:  config.SourceRegistries,
+		ExcludeRegistries: config.ExcludeRegistries,
+		PathStrategy:      config.StrategyName,
+		TargetFlavor:      string(config.TargetFlavor),
+		DefaultTag:        config.DefaultTag,
+	}
+	if config.Mappings != nil {
+		for _, mapping := range config.Mappings.Entries {
+			settings.Mappings = append(settings.Mappings, mapping.Source+"="+mapping.Target+";"+mapping.TagTransform)
+		}
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
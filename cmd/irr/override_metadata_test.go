@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+func TestRelocationMetadata(t *testing.T) {
+	original := metadataNow
+	metadataNow = func() time.Time { return time.Date(2026, 5, 4, 10, 30, 0, 0, time.UTC) }
+	t.Cleanup(func() { metadataNow = original })
+
+	config := &GeneratorConfig{TargetRegistry: "harbor.local", SourceRegistries: []string{"docker.io"}}
+	loadedChart := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "web", Version: "1.4.2"}}
+	newResult := func() *override.File {
+		return &override.File{
+			Values: map[string]interface{}{"image": map[string]interface{}{"registry": "harbor.local"}},
+			Relocations: []override.Relocation{
+				{Path: "image", Original: "nginx:1.27", Relocated: "harbor.local/dockerio/library/nginx:1.27"},
+			},
+		}
+	}
+
+	cmd := newOverrideCmd()
+	comment, err := relocationMetadata(cmd, config, loadedChart, newResult())
+	require.NoError(t, err)
+	assert.Empty(t, comment, "no metadata without --metadata")
+
+	require.NoError(t, cmd.ParseFlags([]string{"--metadata", "comment"}))
+	comment, err = relocationMetadata(cmd, config, loadedChart, newResult())
+	require.NoError(t, err)
+	assert.Equal(t, "# Generated by irr "+BinaryVersion+" at 2026-05-04T10:30:00Z\n"+
+		"# Chart: web 1.4.2\n"+
+		"# Config: "+relocationConfigHash(config)+"\n"+
+		"# Relocated images:\n"+
+		"#   image: nginx:1.27 -> harbor.local/dockerio/library/nginx:1.27\n", comment)
+
+	require.NoError(t, cmd.ParseFlags([]string{"--metadata", "key"}))
+	result := newResult()
+	comment, err = relocationMetadata(cmd, config, loadedChart, result)
+	require.NoError(t, err)
+	assert.Empty(t, comment)
+	require.Contains(t, result.Values, override.MetadataKey)
+	metadata, ok := result.Values[override.MetadataKey].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "web", metadata["chartName"])
+	assert.Len(t, metadata["images"], 1)
+
+	require.NoError(t, cmd.ParseFlags([]string{"--metadata", "header"}))
+	_, err = relocationMetadata(cmd, config, loadedChart, newResult())
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+}
+
+func TestRelocationConfigHash(t *testing.T) {
+	config := &GeneratorConfig{
+		TargetRegistry: "harbor.local",
+		Mappings:       &registry.Mappings{Entries: []registry.Mapping{{Source: "docker.io", Target: "harbor.local/dockerio"}}},
+	}
+	hash := relocationConfigHash(config)
+	assert.Regexp(t, `^sha256:[0-9a-f]{64}$`, hash)
+	assert.Equal(t, hash, relocationConfigHash(config), "the hash is stable")
+
+	config.Mappings.Entries[0].Target = "harbor.local/hub"
+	assert.NotEqual(t, hash, relocationConfigHash(config))
+	assert.Empty(t, relocationConfigHash(nil))
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/encryption"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/output"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/cobra"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// addOutputURIFlag adds --output-uri, which publishes the overrides instead of writing them locally.
+func addOutputURIFlag(cmd *cobra.Command) {
+	cmd.Flags().String("output-uri", "", "Publish the overrides to s3://bucket/key, an http(s):// URL (PUT), k8s://namespace/configmap-name/key or an OCI artifact at oci://registry/repository:tag instead of stdout or a file")
+}
+
+// validateOutputURIFlags rejects an invalid --output-uri and flag combinations that write
+// somewhere else.
+func validateOutputURIFlags(cmd *cobra.Command, recursive bool, outputFile string) error {
+	outputURI, err := getStringFlag(cmd, "output-uri")
+	if err != nil || outputURI == "" {
+		return err
+	}
+	split, err := getBoolFlag(cmd, "split-by-subchart")
+	if err != nil {
+		return err
+	}
+	watch, err := getBoolFlag(cmd, "watch")
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case recursive:
+		err = errors.New("--output-uri cannot be used with --recursive")
+	case split:
+		err = errors.New("--output-uri cannot be used with --split-by-subchart")
+	case watch:
+		err = errors.New("--output-uri cannot be used with --watch")
+	case outputFile != "":
+		err = errors.New("--output-uri cannot be used with --output-file")
+	default:
+		_, err = output.NewSink(outputURI, output.Options{})
+	}
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	return nil
+}
+
+// newOCIPusher creates the registry client pushing oci:// --output-uri artifacts, with the
+// credentials of the registry credential files. It can be replaced in tests.
+var newOCIPusher = func(cmd *cobra.Command, transport http.RoundTripper) (output.OCIPusher, error) {
+	credentialFiles, err := registryCredentialFiles(cmd)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := registry.LoadCredentials(AppFs, credentialFiles...)
+	if err != nil {
+		return nil, err
+	}
+	pusher := registry.NewPusher(credentials)
+	pusher.Client.Transport = transport
+	pusher.Helpers = registryCredentialHelpers()
+	if pusher.PlainHTTP, err = getBoolFlag(cmd, "plain-http"); err != nil {
+		return nil, err
+	}
+	return pusher, nil
+}
+
+// overridesChartKey is the context key of the metadata of the chart the overrides are generated for
+type overridesChartKey struct{}
+
+// recordOverridesChart records the chart the overrides are generated for in the context of cmd
+// when they are published with --output-uri, so that oci:// artifacts are annotated with its name
+// and version.
+func recordOverridesChart(cmd *cobra.Command, loadedChart *helmchart.Chart) {
+	outputURI, err := getStringFlag(cmd, "output-uri")
+	if err != nil || outputURI == "" || loadedChart == nil || loadedChart.Metadata == nil {
+		return
+	}
+	cmd.SetContext(context.WithValue(getCommandContext(cmd), overridesChartKey{}, loadedChart.Metadata))
+}
+
+// overridesArtifactAnnotations returns the annotations of oci:// artifacts: the name and version
+// of the chart recorded by recordOverridesChart, if any.
+func overridesArtifactAnnotations(cmd *cobra.Command) map[string]string {
+	metadata, ok := getCommandContext(cmd).Value(overridesChartKey{}).(*helmchart.Metadata)
+	if !ok {
+		return nil
+	}
+	annotations := map[string]string{output.AnnotationChartName: metadata.Name}
+	if metadata.Version != "" {
+		annotations[output.AnnotationChartVersion] = metadata.Version
+	}
+	return annotations
+}
+
+// publishOverrides writes the formatted overrides to the --output-uri destination.
+func publishOverrides(cmd *cobra.Command, data []byte, outputURI, outputFormat string) error {
+	transport, err := newNetworkTransport(nil)
+	if err != nil {
+		return err
+	}
+	contentType := outputContentType(outputFormat)
+	if encryption.Detect(data) == encryption.FormatAge {
+		contentType = output.AgeContentType
+	}
+	sink, err := output.NewSink(outputURI, output.Options{
+		ContentType:        contentType,
+		HTTPClient:         &http.Client{Transport: transport},
+		NewConfigMapWriter: func() (output.ConfigMapWriter, error) { return kubeClientFactory() },
+		NewOCIPusher:       func() (output.OCIPusher, error) { return newOCIPusher(cmd, transport) },
+		Annotations:        overridesArtifactAnnotations(cmd),
+	})
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	if err := requireNetwork(fmt.Sprintf("publishing overrides to %s (--output-uri)", sink)); err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := sink.Write(ctx, data); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to publish overrides: %w", err),
+		}
+	}
+	log.Info("Override values published", "destination", sink.String())
+	return nil
+}
+
+// outputContentType returns the content type uploaded for an --output-format.
+func outputContentType(outputFormat string) string {
+	switch strings.ToLower(outputFormat) {
+	case outputFormatJSON:
+		return "application/json"
+	case outputFormatSetFlags, outputFormatSetFlagsShell:
+		return "text/plain"
+	default:
+		return output.DefaultContentType
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/output"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+func TestValidateOutputURIFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		recursive  bool
+		outputFile string
+		wantErr    string
+	}{
+		{name: "not set"},
+		{name: "valid", args: []string{"--output-uri", "s3://deploy-config/web.yaml"}},
+		{name: "invalid", args: []string{"--output-uri", "gs://deploy-config/web.yaml"}, wantErr: "unsupported scheme"},
+		{name: "recursive", args: []string{"--output-uri", "s3://deploy-config/web.yaml"}, recursive: true, wantErr: "--recursive"},
+		{name: "output file", args: []string{"--output-uri", "s3://deploy-config/web.yaml"}, outputFile: "web.yaml", wantErr: "--output-file"},
+		{name: "split", args: []string{"--output-uri", "s3://deploy-config/web.yaml", "--split-by-subchart"}, wantErr: "--split-by-subchart"},
+		{name: "watch", args: []string{"--output-uri", "s3://deploy-config/web.yaml", "--watch"}, wantErr: "--watch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newOverrideCmd()
+			require.NoError(t, cmd.ParseFlags(tt.args))
+			err := validateOutputURIFlags(cmd, tt.recursive, tt.outputFile)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var exitErr *exitcodes.ExitCodeError
+			require.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestPublishOverrides(t *testing.T) {
+	var contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		if r.URL.Path == "/missing/web.yaml" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	cmd := newOverrideCmd()
+
+	require.NoError(t, publishOverrides(cmd, []byte(`{"image":{}}`), server.URL+"/web.json", outputFormatJSON))
+	assert.Equal(t, "application/json", contentType)
+	assert.Equal(t, `{"image":{}}`, body)
+
+	err := publishOverrides(cmd, []byte("image: {}\n"), server.URL+"/missing/web.yaml", outputFormatYAML)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitIOError, exitErr.Code)
+
+	client := &fakeKubeClient{}
+	withKubeClient(t, client)
+	require.NoError(t, publishOverrides(cmd, []byte("image: {}\n"), "k8s://ci/irr-overrides/web.yaml", outputFormatYAML))
+	assert.Equal(t, map[string]string{"ci/irr-overrides/web.yaml": "image: {}\n"}, client.puts)
+}
+
+// fakeOCIPusher records the manifests pushed by oci:// --output-uri, keyed by repository:tag
+type fakeOCIPusher struct {
+	manifests map[string]string
+}
+
+func (p *fakeOCIPusher) PushBlob(_ context.Context, _, _ string, data []byte) (string, error) {
+	return registry.Digest(data), nil
+}
+
+func (p *fakeOCIPusher) PushManifest(_ context.Context, host, repository, reference, _ string, data []byte) (string, error) {
+	p.manifests[host+"/"+repository+":"+reference] = string(data)
+	return registry.Digest(data), nil
+}
+
+func TestPublishOverridesOCI(t *testing.T) {
+	pusher := &fakeOCIPusher{manifests: map[string]string{}}
+	original := newOCIPusher
+	newOCIPusher = func(_ *cobra.Command, _ http.RoundTripper) (output.OCIPusher, error) { return pusher, nil }
+	t.Cleanup(func() { newOCIPusher = original })
+
+	cmd := newOverrideCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--output-uri", "oci://harbor.local/platform/overrides:web"}))
+	recordOverridesChart(cmd, &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "web", Version: "1.2.0"}})
+	require.NoError(t, publishOverrides(cmd, []byte("image: {}\n"), "oci://harbor.local/platform/overrides:web", outputFormatYAML))
+
+	manifest := pusher.manifests["harbor.local/platform/overrides:web"]
+	assert.Contains(t, manifest, `"artifactType":"application/vnd.irr.overrides.v1"`)
+	assert.Contains(t, manifest, `"io.github.lucas-albers-lz4.irr.chart.name":"web"`)
+	assert.Contains(t, manifest, `"io.github.lucas-albers-lz4.irr.chart.version":"1.2.0"`)
+	assert.Contains(t, manifest, `"mediaType":"application/yaml"`)
+}
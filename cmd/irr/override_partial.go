@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/chart"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// PathError is a values path whose image could not be overridden, skipped with --ignore-errors
+type PathError struct {
+	Path      string             `json:"path" yaml:"path"`
+	Location  string             `json:"location,omitempty" yaml:"location,omitempty"`
+	Error     string             `json:"error" yaml:"error"`
+	ErrorCode string             `json:"errorCode,omitempty" yaml:"errorCode,omitempty"`
+	Category  exitcodes.Category `json:"category,omitempty" yaml:"category,omitempty"`
+}
+
+// PartialOverridesReport is the error report written by --error-report
+type PartialOverridesReport struct {
+	ChartPath string      `json:"chartPath" yaml:"chartPath"`
+	Errors    []PathError `json:"errors" yaml:"errors"`
+}
+
+// partialOverridesError reports that overrides were generated for only some of a chart's image paths.
+type partialOverridesError struct {
+	ChartPath string
+	Errors    []PathError
+}
+
+func (e *partialOverridesError) Error() string {
+	paths := make([]string, 0, len(e.Errors))
+	for _, pathErr := range e.Errors {
+		paths = append(paths, pathErr.Path)
+	}
+	return fmt.Sprintf("overrides for %s are partial: %d image path(s) failed: %s", e.ChartPath, len(e.Errors), strings.Join(paths, ", "))
+}
+
+// addIgnoreErrorsFlags adds the flags that let override continue past images it cannot process.
+func addIgnoreErrorsFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("ignore-errors", false, "Continue when images at some values paths cannot be processed, writing overrides for the rest and exiting with code 9")
+	cmd.Flags().String("error-report", "", "Write the values paths skipped by --ignore-errors and their errors to this file (yaml or json per --output-format)")
+}
+
+// getIgnoreErrors returns the --ignore-errors flag value, rejecting it with --strict-mode=all,
+// which stops at the first image that cannot be processed.
+func getIgnoreErrors(cmd *cobra.Command, strictMode bool) (bool, error) {
+	ignoreErrors, err := getBoolFlag(cmd, "ignore-errors")
+	if err != nil || !ignoreErrors {
+		return false, err
+	}
+	if strictMode {
+		return false, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--ignore-errors cannot be used with --strict or --strict-mode=all"),
+		}
+	}
+	return true, nil
+}
+
+// partialOverrides returns the partial success error for a Generate error that --ignore-errors can
+// skip: per-path processing errors when at least one image was still processed. It returns nil when
+// the error must fail the chart, including when no image could be processed at all.
+func partialOverrides(config *GeneratorConfig, result *override.File, err error) error {
+	var procErr *chart.ProcessingError
+	if !config.IgnoreErrors || config.StrictMode || result == nil || result.ProcessedCount == 0 || !errors.As(err, &procErr) {
+		return nil
+	}
+
+	partial := &partialOverridesError{ChartPath: config.ChartPath}
+	for _, e := range procErr.Errors {
+		pathErr := PathError{}
+		pathErr.Error, pathErr.ErrorCode, pathErr.Category = reportedError(e)
+		var chartPathErr *chart.PathError
+		if errors.As(e, &chartPathErr) {
+			pathErr.Path = chartPathErr.Path
+			if chartPathErr.Location != nil {
+				pathErr.Location = chartPathErr.Location.String()
+			}
+		}
+		partial.Errors = append(partial.Errors, pathErr)
+		log.Warn("Skipped image path", "path", pathErr.Path, "location", pathErr.Location, "error", pathErr.Error)
+	}
+	return &exitcodes.ExitCodeError{Code: exitcodes.ExitPartialSuccess, Err: partial}
+}
+
+// asPartialOverrides returns the partial success error wrapped in err, if any.
+func asPartialOverrides(err error) (*partialOverridesError, bool) {
+	var partial *partialOverridesError
+	if errors.As(err, &partial) {
+		return partial, true
+	}
+	return nil, false
+}
+
+// writeErrorReport writes the paths skipped in a partial result to --error-report. Without
+// --error-report the paths have already been logged and nothing is written.
+func writeErrorReport(cmd *cobra.Command, partial *partialOverridesError) error {
+	reportFile, err := getStringFlag(cmd, "error-report")
+	if err != nil || reportFile == "" {
+		return err
+	}
+	outputFormat, err := getStringFlag(cmd, "output-format")
+	if err != nil {
+		return err
+	}
+
+	report := PartialOverridesReport{ChartPath: partial.ChartPath, Errors: partial.Errors}
+	var data []byte
+	if strings.EqualFold(outputFormat, outputFormatJSON) {
+		data, err = json.MarshalIndent(report, "", "  ")
+	} else {
+		data, err = yaml.Marshal(report)
+	}
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to marshal error report: %w", err),
+		}
+	}
+	return writeOutputFile(reportFile, data, "Error report written")
+}
+
+// finishPartialOverrides completes a run whose overrides were written: for a partial result it
+// writes the error report and returns the partial success error, otherwise it returns err unchanged.
+func finishPartialOverrides(cmd *cobra.Command, err error) error {
+	partial, ok := asPartialOverrides(err)
+	if !ok {
+		return err
+	}
+	if reportErr := writeErrorReport(cmd, partial); reportErr != nil {
+		return reportErr
+	}
+	return err
+}
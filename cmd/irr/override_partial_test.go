@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/chart"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestPartialOverrides(t *testing.T) {
+	generateErr := &chart.ProcessingError{
+		Errors: []error{&chart.PathError{
+			Path:     "sidecar.image",
+			Location: &analysis.SourceLocation{File: "values.yaml", Line: 12, Column: 10},
+			Err:      errors.New("path sidecar.image: invalid image"),
+		}},
+		Count: 1,
+	}
+	result := &override.File{ProcessedCount: 2}
+	config := &GeneratorConfig{ChartPath: "./nginx", IgnoreErrors: true}
+
+	err := partialOverrides(config, result, generateErr)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitPartialSuccess, exitErr.Code)
+	partial, ok := asPartialOverrides(err)
+	require.True(t, ok)
+	assert.Equal(t, []PathError{{
+		Path: "sidecar.image", Location: "values.yaml:12:10", Error: "path sidecar.image: invalid image (at values.yaml:12:10)",
+		ErrorCode: "image-processing-failed", Category: exitcodes.CategoryParse,
+	}}, partial.Errors)
+	assert.Contains(t, err.Error(), "1 image path(s) failed: sidecar.image")
+
+	assert.NoError(t, partialOverrides(&GeneratorConfig{}, result, generateErr), "errors fail the chart without --ignore-errors")
+	assert.NoError(t, partialOverrides(config, &override.File{}, generateErr), "no processed images is a complete failure")
+	assert.NoError(t, partialOverrides(config, result, chart.ErrChartLoadFailed), "only per-path errors are ignored")
+	assert.NoError(t, partialOverrides(&GeneratorConfig{IgnoreErrors: true, StrictMode: true}, result, generateErr))
+}
+
+func TestGetIgnoreErrorsStrict(t *testing.T) {
+	cmd := newOverrideCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--ignore-errors"}))
+
+	ignoreErrors, err := getIgnoreErrors(cmd, false)
+	require.NoError(t, err)
+	assert.True(t, ignoreErrors)
+
+	_, err = getIgnoreErrors(cmd, true)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+}
+
+func TestFinishPartialOverridesWritesErrorReport(t *testing.T) {
+	memFs := afero.NewMemMapFs()
+	oldFs := AppFs
+	AppFs = memFs
+	t.Cleanup(func() { AppFs = oldFs })
+
+	cmd := newOverrideCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--error-report", "reports/errors.yaml"}))
+	partialErr := &exitcodes.ExitCodeError{
+		Code: exitcodes.ExitPartialSuccess,
+		Err:  &partialOverridesError{ChartPath: "./nginx", Errors: []PathError{{Path: "image", Error: "invalid image"}}},
+	}
+
+	assert.Same(t, partialErr, finishPartialOverrides(cmd, partialErr))
+	data, err := afero.ReadFile(memFs, "reports/errors.yaml")
+	require.NoError(t, err)
+	var report PartialOverridesReport
+	require.NoError(t, yaml.Unmarshal(data, &report))
+	assert.Equal(t, PartialOverridesReport{ChartPath: "./nginx", Errors: []PathError{{Path: "image", Error: "invalid image"}}}, report)
+
+	otherErr := errors.New("chart not found")
+	assert.Equal(t, otherErr, finishPartialOverrides(cmd, otherErr))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/spf13/cobra"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// addValidateSchemaFlag adds --validate-schema, which checks the overrides against the chart's
+// values.schema.json files before they are written.
+func addValidateSchemaFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("validate-schema", false, "Validate the chart values with the overrides applied against the values.schema.json of the chart and its subcharts, and fail on violations the overrides introduce")
+}
+
+// validateOverridesSchema validates the values of loadedChart with overrides applied against the
+// values.schema.json of the chart and its enabled subcharts when --validate-schema is set. values
+// are the values the chart was analyzed with (nil for its defaults). Only violations the overrides
+// introduce fail the command; those of the values alone are Helm's to report.
+func validateOverridesSchema(cmd *cobra.Command, loadedChart *helmchart.Chart, values, overrides map[string]interface{}) error {
+	validate, err := getBoolFlag(cmd, "validate-schema")
+	if err != nil || !validate {
+		return err
+	}
+
+	base, err := chartutil.CoalesceValues(loadedChart, values)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitChartProcessingFailed,
+			Err:  fmt.Errorf("failed to coalesce chart values for --validate-schema: %w", err),
+		}
+	}
+	merged, ok := override.DeepCopy(overrides).(map[string]interface{})
+	if !ok || merged == nil {
+		merged = make(map[string]interface{})
+	}
+	merged = chartutil.CoalesceTables(merged, base)
+
+	violations, err := analysis.IntroducedSchemaViolations(loadedChart, base, merged)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitChartProcessingFailed,
+			Err:  fmt.Errorf("failed to validate overrides against the values schema: %w", err),
+		}
+	}
+	if len(violations) == 0 {
+		log.Info("Overrides satisfy the values schema of the chart and its subcharts")
+		return nil
+	}
+
+	lines := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		log.Error("Overrides violate the values schema", "chart", violation.Chart, "path", violation.Path, "violation", violation.Message)
+		lines = append(lines, violation.String())
+	}
+	return &exitcodes.ExitCodeError{
+		Code: exitcodes.ExitChartProcessingFailed,
+		Err:  fmt.Errorf("overrides violate the values schema:\n  %s", strings.Join(lines, "\n  ")),
+	}
+}
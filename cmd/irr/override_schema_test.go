@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+func TestValidateOverridesSchema(t *testing.T) {
+	loadedChart := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "web"},
+		Values: map[string]interface{}{
+			"image":    map[string]interface{}{"repository": "nginx", "tag": "1.27"},
+			"replicas": 0,
+		},
+		Schema: []byte(`{
+  "type": "object",
+  "properties": {
+    "image": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {"repository": {"type": "string"}, "tag": {"type": "string"}}
+    },
+    "replicas": {"type": "integer", "minimum": 1}
+  }
+}`),
+	}
+	relocated := map[string]interface{}{
+		"image": map[string]interface{}{"repository": "harbor.local/dockerio/nginx"},
+	}
+	withRegistry := map[string]interface{}{
+		"image": map[string]interface{}{"registry": "harbor.local", "repository": "dockerio/nginx"},
+	}
+
+	cmd := newOverrideCmd()
+	require.NoError(t, validateOverridesSchema(cmd, loadedChart, nil, withRegistry), "validation is off by default")
+
+	require.NoError(t, cmd.ParseFlags([]string{"--validate-schema"}))
+	assert.NoError(t, validateOverridesSchema(cmd, loadedChart, nil, relocated), "the replicas violation comes from the chart values")
+
+	err := validateOverridesSchema(cmd, loadedChart, nil, withRegistry)
+	require.Error(t, err)
+	var exitErr *exitcodes.ExitCodeError
+	require.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, exitcodes.ExitChartProcessingFailed, exitErr.Code)
+	assert.Contains(t, err.Error(), "web: image: additional properties 'registry' not allowed")
+	assert.NotContains(t, err.Error(), "replicas")
+	assert.NotContains(t, withRegistry["image"], "tag", "the overrides are not modified")
+}
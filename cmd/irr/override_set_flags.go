@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// outputFormatSetFlags writes the overrides as Helm --set/--set-string arguments, one per line
+	outputFormatSetFlags = "set-flags"
+	// outputFormatSetFlagsShell writes the same arguments on a single line, quoted for POSIX shells
+	outputFormatSetFlagsShell = "set-flags-shell"
+)
+
+// overrideOutputFormats are the formats override writes the overrides in, as validated by
+// formatOverrides and offered by shell completion
+var overrideOutputFormats = []string{outputFormatYAML, outputFormatJSON, outputFormatSetFlags, outputFormatSetFlagsShell}
+
+// isSetFlagsFormat reports whether outputFormat writes Helm --set arguments instead of a values file.
+func isSetFlagsFormat(outputFormat string) bool {
+	outputFormat = strings.ToLower(outputFormat)
+	return outputFormat == outputFormatSetFlags || outputFormat == outputFormatSetFlagsShell
+}
+
+// formatSetFlags converts generated YAML overrides into Helm arguments setting the same values:
+// one "--set-string key=value,..." argument per line, or all of them on one line with each value
+// quoted for the shell. Values that --set cannot express are logged as warnings.
+func formatSetFlags(data []byte, shell bool) ([]byte, error) {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to unmarshal YAML for --set output: %w", err),
+		}
+	}
+
+	args, warnings := override.ToSetArgs(values)
+	if len(warnings) > 0 {
+		log.Warn("Some override values cannot be passed with --set; use a values file for these", "count", len(warnings))
+	}
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	formatted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if shell {
+			formatted = append(formatted, arg.ShellString())
+		} else {
+			formatted = append(formatted, arg.String())
+		}
+	}
+	separator := "\n"
+	if shell {
+		separator = " "
+	}
+	return []byte(strings.Join(formatted, separator) + "\n"), nil
+}
+
+// rejectSetFlagsFormat returns an error when outputFormat is a --set format, which the given
+// multi-file mode cannot write.
+func rejectSetFlagsFormat(outputFormat, mode string) error {
+	if !isSetFlagsFormat(outputFormat) {
+		return nil
+	}
+	return &exitcodes.ExitCodeError{
+		Code: exitcodes.ExitInputConfigurationError,
+		Err:  fmt.Errorf("--output-format %s cannot be used with %s; use yaml or json", strings.ToLower(outputFormat), mode),
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatOverrides_SetFlags(t *testing.T) {
+	data := []byte(`image:
+  registry: harbor.example.com
+  repository: dockerhub/library/nginx
+  tag: "1.25"
+global:
+  security:
+    allowInsecureImages: true
+podAnnotations:
+  mirror: "a b"
+`)
+
+	out, err := formatOverrides(data, outputFormatSetFlags)
+	require.NoError(t, err)
+	assert.Equal(t, "--set global.security.allowInsecureImages=true\n"+
+		"--set-string image.registry=harbor.example.com,image.repository=dockerhub/library/nginx,image.tag=1.25\n"+
+		"--set-string podAnnotations.mirror=a b\n", string(out))
+
+	out, err = formatOverrides(data, "SET-FLAGS-SHELL")
+	require.NoError(t, err)
+	assert.Equal(t, "--set global.security.allowInsecureImages=true "+
+		"--set-string image.registry=harbor.example.com,image.repository=dockerhub/library/nginx,image.tag=1.25 "+
+		"--set-string 'podAnnotations.mirror=a b'\n", string(out))
+
+	out, err = formatOverrides([]byte("{}\n"), outputFormatSetFlags)
+	require.NoError(t, err)
+	assert.Empty(t, out)
+
+	_, err = formatOverrides(data, "toml")
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+}
+
+func TestRejectSetFlagsFormat(t *testing.T) {
+	assert.NoError(t, rejectSetFlagsFormat(outputFormatJSON, "--recursive"))
+
+	err := rejectSetFlagsFormat(outputFormatSetFlagsShell, "--recursive")
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	assert.ErrorContains(t, err, "--output-format set-flags-shell cannot be used with --recursive")
+}
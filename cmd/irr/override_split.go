@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/chart"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// splitUmbrellaBasename is the basename of the parent chart's file written by --split-by-subchart
+const splitUmbrellaBasename = "umbrella"
+
+// splitOverrideFile is one file produced by --split-by-subchart
+type splitOverrideFile struct {
+	Path    string
+	Content []byte
+}
+
+// validateSplitBySubchartFlags rejects flag combinations that cannot be used with --split-by-subchart.
+func validateSplitBySubchartFlags(cmd *cobra.Command, recursive bool, outputFile string, dryRun bool) error {
+	split, err := getBoolFlag(cmd, "split-by-subchart")
+	if err != nil || !split {
+		return err
+	}
+	mergeInto, err := getStringFlag(cmd, "merge-into")
+	if err != nil {
+		return err
+	}
+	outputDir, err := getStringFlag(cmd, "output-dir")
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case recursive:
+		err = errors.New("--split-by-subchart cannot be used with --recursive")
+	case mergeInto != "":
+		err = errors.New("--split-by-subchart cannot be used with --merge-into")
+	case outputFile != "":
+		err = errors.New("--output-file cannot be used with --split-by-subchart; use --output-dir instead")
+	case outputDir == "" && !dryRun:
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitMissingRequiredFlag,
+			Err:  errors.New("--output-dir is required with --split-by-subchart unless --dry-run is set"),
+		}
+	}
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	return nil
+}
+
+// outputSplitOverrides writes the generated YAML overrides to --output-dir as one file per top-level
+// subchart alias plus an umbrella file with the parent chart's overrides. On a dry run the files are
+// printed to stdout instead. configEncryption is the encryption section of the registry config.
+func outputSplitOverrides(cmd *cobra.Command, data []byte, configEncryption *registry.EncryptionConfig, chartPath string, dryRun bool) error {
+	outputDir, err := getStringFlag(cmd, "output-dir")
+	if err != nil {
+		return err
+	}
+	outputFormat, err := getStringFlag(cmd, "output-format")
+	if err != nil {
+		return err
+	}
+	outputFormat = strings.ToLower(outputFormat)
+	if err := rejectSetFlagsFormat(outputFormat, "--split-by-subchart"); err != nil {
+		return err
+	}
+
+	loadedChart, err := chart.NewLoader().Load(chartPath)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitChartLoadFailed,
+			Err:  fmt.Errorf("failed to load chart to determine its subcharts: %w", err),
+		}
+	}
+
+	files, err := splitOverrideFiles(data, loadedChart, outputDir, outputFormat)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		log.Info("DRY RUN: Displaying split override files (stdout)", "files", len(files))
+		for _, file := range files {
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "---\n# File: %s\n%s\n", file.Path, file.Content); err != nil {
+				return &exitcodes.ExitCodeError{
+					Code: exitcodes.ExitIOError,
+					Err:  fmt.Errorf("failed to write dry-run output to stdout: %w", err),
+				}
+			}
+		}
+		return nil
+	}
+	for _, file := range files {
+		content, err := encryptOverrides(cmd, file.Content, configEncryption, outputFormat)
+		if err != nil {
+			return err
+		}
+		if err := writeOutputFile(file.Path, content, "Override values written"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitOverrideFiles builds the umbrella file followed by one file per subchart with overrides.
+func splitOverrideFiles(data []byte, loadedChart *helmchart.Chart, outputDir, outputFormat string) ([]splitOverrideFile, error) {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to parse generated overrides: %w", err),
+		}
+	}
+
+	parent, subcharts, aliases := override.SplitBySubchart(values, chartDependencies(loadedChart))
+	if _, ok := subcharts[splitUmbrellaBasename]; ok {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("subchart alias %q conflicts with the umbrella override file name", splitUmbrellaBasename),
+		}
+	}
+
+	fileName := func(basename string) string {
+		return fmt.Sprintf("%s.%s", basename, outputFormat)
+	}
+	files := make([]splitOverrideFile, 0, len(aliases)+1)
+
+	umbrella, err := marshalSplitOverrides(parent, outputFormat)
+	if err != nil {
+		return nil, err
+	}
+	if outputFormat == outputFormatYAML {
+		umbrella = append(umbrellaHeader(loadedChart.Name(), aliases, fileName), umbrella...)
+	}
+	files = append(files, splitOverrideFile{Path: filepath.Join(outputDir, fileName(splitUmbrellaBasename)), Content: umbrella})
+
+	for _, alias := range aliases {
+		content, err := marshalSplitOverrides(subcharts[alias], outputFormat)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, splitOverrideFile{Path: filepath.Join(outputDir, fileName(alias)), Content: content})
+	}
+	return files, nil
+}
+
+// chartDependencies lists the chart's declared dependencies and any vendored subcharts
+func chartDependencies(loadedChart *helmchart.Chart) []override.ChartDependency {
+	var deps []override.ChartDependency
+	if loadedChart.Metadata != nil {
+		for _, dep := range loadedChart.Metadata.Dependencies {
+			if dep != nil {
+				deps = append(deps, override.ChartDependency{Name: dep.Name, Alias: dep.Alias})
+			}
+		}
+	}
+	for _, sub := range loadedChart.Dependencies() {
+		deps = append(deps, override.ChartDependency{Name: sub.Name()})
+	}
+	return deps
+}
+
+// marshalSplitOverrides renders one split override file in the requested format
+func marshalSplitOverrides(values map[string]interface{}, outputFormat string) ([]byte, error) {
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to marshal overrides to YAML: %w", err),
+		}
+	}
+	return formatOverrides(data, outputFormat)
+}
+
+// umbrellaHeader describes how the umbrella file and the per-subchart files fit together
+func umbrellaHeader(chartName string, aliases []string, fileName func(string) string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Image overrides for chart %s (parent chart values).\n", chartName)
+	if len(aliases) == 0 {
+		b.WriteString("# The chart has no subchart overrides.\n")
+		return []byte(b.String())
+	}
+	b.WriteString("# Subchart overrides are in separate files, keyed by alias:\n")
+	valuesFlags := []string{"-f " + fileName(splitUmbrellaBasename)}
+	for _, alias := range aliases {
+		fmt.Fprintf(&b, "#   %s: %s\n", alias, fileName(alias))
+		valuesFlags = append(valuesFlags, "-f "+fileName(alias))
+	}
+	fmt.Fprintf(&b, "# Apply them together with: helm upgrade --install RELEASE CHART %s\n", strings.Join(valuesFlags, " "))
+	return []byte(b.String())
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+func TestSplitOverrideFiles(t *testing.T) {
+	umbrellaChart := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{
+			Name: "platform",
+			Dependencies: []*helmchart.Dependency{
+				{Name: "postgresql", Alias: "db"},
+				{Name: "redis"},
+			},
+		},
+	}
+	data := []byte(`image:
+  registry: harbor.local
+db:
+  image:
+    repository: dockerhub/bitnami/postgresql
+redis:
+  image:
+    repository: dockerhub/bitnami/redis
+`)
+
+	t.Run("yaml", func(t *testing.T) {
+		files, err := splitOverrideFiles(data, umbrellaChart, "overrides", outputFormatYAML)
+		require.NoError(t, err)
+		require.Len(t, files, 3)
+
+		assert.Equal(t, filepath.Join("overrides", "umbrella.yaml"), files[0].Path)
+		assert.Equal(t, `# Image overrides for chart platform (parent chart values).
+# Subchart overrides are in separate files, keyed by alias:
+#   db: db.yaml
+#   redis: redis.yaml
+# Apply them together with: helm upgrade --install RELEASE CHART -f umbrella.yaml -f db.yaml -f redis.yaml
+image:
+    registry: harbor.local
+`, string(files[0].Content))
+
+		assert.Equal(t, filepath.Join("overrides", "db.yaml"), files[1].Path)
+		assert.Equal(t, "db:\n    image:\n        repository: dockerhub/bitnami/postgresql\n", string(files[1].Content))
+		assert.Equal(t, filepath.Join("overrides", "redis.yaml"), files[2].Path)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		files, err := splitOverrideFiles(data, umbrellaChart, "overrides", outputFormatJSON)
+		require.NoError(t, err)
+		require.Len(t, files, 3)
+		assert.Equal(t, filepath.Join("overrides", "umbrella.json"), files[0].Path)
+		assert.JSONEq(t, `{"image": {"registry": "harbor.local"}}`, string(files[0].Content))
+	})
+}
+
+func TestValidateSplitBySubchartFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		recursive  bool
+		outputFile string
+		dryRun     bool
+		wantCode   int
+	}{
+		{name: "not split", args: nil},
+		{name: "output dir", args: []string{"--split-by-subchart", "--output-dir", "overrides"}},
+		{name: "dry run without output dir", args: []string{"--split-by-subchart"}, dryRun: true},
+		{name: "missing output dir", args: []string{"--split-by-subchart"}, wantCode: exitcodes.ExitMissingRequiredFlag},
+		{name: "with output file", args: []string{"--split-by-subchart", "--output-dir", "o"}, outputFile: "out.yaml", wantCode: exitcodes.ExitInputConfigurationError},
+		{name: "with recursive", args: []string{"--split-by-subchart", "--output-dir", "o"}, recursive: true, wantCode: exitcodes.ExitInputConfigurationError},
+		{name: "with merge-into", args: []string{"--split-by-subchart", "--output-dir", "o", "--merge-into", "values.yaml"}, wantCode: exitcodes.ExitInputConfigurationError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newOverrideCmd()
+			require.NoError(t, cmd.ParseFlags(tt.args))
+
+			err := validateSplitBySubchartFlags(cmd, tt.recursive, tt.outputFile, tt.dryRun)
+			if tt.wantCode == 0 {
+				require.NoError(t, err)
+				return
+			}
+			var exitErr *exitcodes.ExitCodeError
+			require.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, tt.wantCode, exitErr.Code)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/lucas-albers-lz4/irr/pkg/strictness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStrictLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		want     strictness.Level
+		wantCode int
+	}{
+		{name: "default", want: strictness.LevelOff},
+		{name: "strict flag", args: []string{"--strict"}, want: strictness.LevelAll},
+		{name: "strict mode", args: []string{"--strict-mode", "unsupported"}, want: strictness.LevelUnsupported},
+		{name: "both agree", args: []string{"--strict", "--strict-mode", "all"}, want: strictness.LevelAll},
+		{name: "conflict", args: []string{"--strict", "--strict-mode", "warn"}, wantCode: exitcodes.ExitInputConfigurationError},
+		{name: "unknown level", args: []string{"--strict-mode", "everything"}, wantCode: exitcodes.ExitInputConfigurationError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newOverrideCmd()
+			require.NoError(t, cmd.ParseFlags(tt.args))
+
+			level, err := getStrictLevel(cmd)
+			if tt.wantCode != 0 {
+				var exitErr *exitcodes.ExitCodeError
+				require.ErrorAs(t, err, &exitErr)
+				assert.Equal(t, tt.wantCode, exitErr.Code)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, level)
+		})
+	}
+}
+
+func TestApplyConfigFilePolicy(t *testing.T) {
+	t.Run("policy block overrides level", func(t *testing.T) {
+		policy := strictness.LevelUnsupported.Policy()
+		config := &GeneratorConfig{StrictPolicy: &policy}
+		applyConfigFilePolicy(config, &registry.Config{Policy: &strictness.Policy{TemplateExpressions: strictness.ActionWarn}})
+
+		assert.Equal(t, strictness.ActionWarn, config.strictPolicy().ActionFor(strictness.TemplateExpressions))
+		assert.Equal(t, strictness.ActionError, config.strictPolicy().ActionFor(strictness.UnparseableImages))
+	})
+
+	t.Run("registries strictMode fails on unmapped registries", func(t *testing.T) {
+		config := &GeneratorConfig{SourceRegistries: []string{"quay.io"}, TargetRegistry: "harbor.local"}
+		applyConfigFilePolicy(config, &registry.Config{Registries: registry.RegConfig{StrictMode: true}})
+
+		err := validateUnmappableRegistries(config)
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitRegistryDetectionError, exitErr.Code)
+	})
+
+	t.Run("no file settings", func(t *testing.T) {
+		config := &GeneratorConfig{StrictMode: true}
+		applyConfigFilePolicy(config, &registry.Config{})
+		assert.Nil(t, config.StrictPolicy)
+		assert.Equal(t, strictness.LevelAll.Policy(), config.strictPolicy())
+	})
+}
+
+func TestHandleGenerateError_StrictViolation(t *testing.T) {
+	err := handleGenerateError(&strictness.ViolationError{Condition: strictness.EmptyRepositories, Items: []string{"image"}})
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitEmptyRepositoryError, exitErr.Code)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/spf13/cobra"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// addAnnotateSubchartsFlag adds --annotate-subcharts, which comments the overrides of each subchart.
+func addAnnotateSubchartsFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("annotate-subcharts", false, "Add a comment above the overrides of each subchart naming its chart and alias (YAML output only)")
+}
+
+// annotateSubchartOverrides adds a comment above the overrides block of each subchart of
+// loadedChart when --annotate-subcharts is set, so that blocks keyed by an alias can be traced
+// back to the chart they configure.
+func annotateSubchartOverrides(cmd *cobra.Command, loadedChart *helmchart.Chart, data []byte) ([]byte, error) {
+	annotate, err := getBoolFlag(cmd, "annotate-subcharts")
+	if err != nil || !annotate {
+		return data, err
+	}
+	annotated, err := override.AnnotateYAML(data, subchartComments(loadedChart))
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  err,
+		}
+	}
+	return annotated, nil
+}
+
+// subchartComments returns the comment for the values path of each subchart of ch.
+func subchartComments(ch *helmchart.Chart) map[string]string {
+	comments := make(map[string]string)
+	for _, sub := range analysis.Subcharts(ch) {
+		comment := "Overrides for subchart " + sub.Name
+		if sub.Alias != "" {
+			comment += " (alias " + sub.Alias + ")"
+		}
+		comments[sub.ValuesPath] = comment
+	}
+	log.Debug("Annotating subchart overrides", "subcharts", len(comments))
+	return comments
+}
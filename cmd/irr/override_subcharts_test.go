@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+func TestAnnotateSubchartOverrides(t *testing.T) {
+	redis := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "redis"}}
+	loadedChart := &helmchart.Chart{Metadata: &helmchart.Metadata{
+		Name:         "platform",
+		Dependencies: []*helmchart.Dependency{{Name: "redis", Alias: "cache"}},
+	}}
+	loadedChart.SetDependencies(redis)
+	data := []byte("cache:\n    image:\n        registry: harbor.local\nimage:\n    registry: harbor.local\n")
+
+	cmd := newOverrideCmd()
+	unchanged, err := annotateSubchartOverrides(cmd, loadedChart, data)
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(unchanged))
+
+	require.NoError(t, cmd.ParseFlags([]string{"--annotate-subcharts"}))
+	annotated, err := annotateSubchartOverrides(cmd, loadedChart, data)
+	require.NoError(t, err)
+	assert.Equal(t, "# Overrides for subchart redis (alias cache)\n"+string(data), string(annotated))
+}
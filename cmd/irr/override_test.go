@@ -133,12 +133,27 @@ func (m *MockHelmClient) LoadChart(_ /* chartPath */ string) (*helmchart.Chart,
 	return m.ReleaseChart, nil
 }
 
+// GetReleaseValuesAtRevision mocks retrieving values from a specific release revision
+func (m *MockHelmClient) GetReleaseValuesAtRevision(ctx context.Context, releaseName, namespace string, _ int) (map[string]interface{}, error) {
+	return m.GetReleaseValues(ctx, releaseName, namespace)
+}
+
+// GetChartFromReleaseAtRevision mocks retrieving a chart from a specific release revision
+func (m *MockHelmClient) GetChartFromReleaseAtRevision(ctx context.Context, releaseName, namespace string, _ int) (*helm.ChartMetadata, error) {
+	return m.GetChartFromRelease(ctx, releaseName, namespace)
+}
+
 // ListReleases implements helm.ClientInterface and returns an empty list of releases
 func (m *MockHelmClient) ListReleases(_ context.Context, _ bool) ([]*helm.ReleaseElement, error) {
 	// For simplicity in tests, return an empty list or could be enhanced to return mock releases
 	return []*helm.ReleaseElement{}, nil
 }
 
+// ListReleasesWithOptions implements helm.ClientInterface and returns an empty list of releases
+func (m *MockHelmClient) ListReleasesWithOptions(_ context.Context, _ helm.ReleaseListOptions) ([]*helm.ReleaseElement, error) {
+	return []*helm.ReleaseElement{}, nil
+}
+
 // MockHelmAdapter mocks the behavior of helm.Adapter for command-level tests
 // It doesn't explicitly implement an interface but provides the methods used by the command.
 type MockHelmAdapter struct {
@@ -459,6 +474,7 @@ func setupTestFsOverride(t *testing.T) (afero.Fs, func()) {
 */
 
 func TestHandleGenerateError(t *testing.T) {
+	thresholdErr := &chart.ThresholdError{Threshold: 80, ActualRate: 50, Err: fmt.Errorf("processing errors: 1")}
 	testCases := []struct {
 		name            string
 		inputError      error
@@ -489,6 +505,12 @@ func TestHandleGenerateError(t *testing.T) {
 			expectedCode:    exitcodes.ExitUnsupportedStructure,
 			expectedWrapped: chart.ErrUnsupportedStructure,
 		},
+		{
+			name:            "Threshold error",
+			inputError:      thresholdErr,
+			expectedCode:    exitcodes.ExitThresholdError,
+			expectedWrapped: thresholdErr,
+		},
 		{
 			name:            "Generic error",
 			inputError:      fmt.Errorf("some other generic error"),
@@ -545,7 +567,7 @@ func TestOutputOverrides(t *testing.T) {
 		defer restoreFs()
 
 		cmd, stdout, _ := getRootCmdWithOutputs()
-		err := outputOverrides(cmd, content, "", true) // Empty outputFile, dryRun=true
+		err := outputOverrides(cmd, content, nil, "", true) // Empty outputFile, dryRun=true
 
 		require.NoError(t, err)
 		assert.Contains(t, stdout.String(), string(content), "Output should contain YAML content")
@@ -560,7 +582,7 @@ func TestOutputOverrides(t *testing.T) {
 		defer restoreFs()
 
 		cmd, stdout, _ := getRootCmdWithOutputs()
-		err := outputOverrides(cmd, content, "", false) // Empty outputFile, dryRun=false
+		err := outputOverrides(cmd, content, nil, "", false) // Empty outputFile, dryRun=false
 
 		require.NoError(t, err)
 		assert.Contains(t, stdout.String(), string(content), "Output should contain YAML content")
@@ -575,7 +597,7 @@ func TestOutputOverrides(t *testing.T) {
 		defer restoreFs()
 
 		cmd, stdout, _ := getRootCmdWithOutputs()
-		err := outputOverrides(cmd, content, outputFilename, false) // Specific outputFile, dryRun=false
+		err := outputOverrides(cmd, content, nil, outputFilename, false) // Specific outputFile, dryRun=false
 
 		require.NoError(t, err)
 		assert.Empty(t, stdout.String(), "Stdout should be empty when writing to file")
@@ -596,7 +618,7 @@ func TestOutputOverrides(t *testing.T) {
 		require.NoError(t, err)
 
 		cmd, _, _ := getRootCmdWithOutputs()
-		err = outputOverrides(cmd, content, outputFilename, false)
+		err = outputOverrides(cmd, content, nil, outputFilename, false)
 
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "already exists", "Error message should indicate file exists")
@@ -614,7 +636,7 @@ func TestOutputOverrides(t *testing.T) {
 
 		cmd, _, _ := getRootCmdWithOutputs()
 		filePath := "/some/nonexistent/dir/output.yaml"
-		err := outputOverrides(cmd, content, filePath, false)
+		err := outputOverrides(cmd, content, nil, filePath, false)
 
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to create output directory", "Error message should indicate directory creation failure")
@@ -625,6 +647,98 @@ func TestOutputOverrides(t *testing.T) {
 	})
 }
 
+func TestMergeOverridesIntoFile(t *testing.T) {
+	existing := []byte("# Release values\nimage:\n  repository: nginx # upstream\n  tag: \"1.25\"\n")
+	content := []byte("image:\n  registry: harbor.local\n  repository: dockerhub/library/nginx\n")
+	valuesFile := "/values/values.yaml"
+	expected := "# Release values\nimage:\n  repository: dockerhub/library/nginx # upstream\n  tag: \"1.25\"\n  registry: harbor.local\n"
+
+	setup := func(t *testing.T) (afero.Fs, *cobra.Command, *bytes.Buffer) {
+		t.Helper()
+		fs := afero.NewMemMapFs()
+		restoreFs := SetFs(fs)
+		t.Cleanup(restoreFs)
+		require.NoError(t, afero.WriteFile(fs, valuesFile, existing, 0o600))
+		cmd := newOverrideCmd()
+		stdout := new(bytes.Buffer)
+		cmd.SetOut(stdout)
+		return fs, cmd, stdout
+	}
+
+	t.Run("in place", func(t *testing.T) {
+		fs, cmd, _ := setup(t)
+		require.NoError(t, mergeOverridesIntoFile(cmd, content, valuesFile, "", false))
+
+		merged, err := afero.ReadFile(fs, valuesFile)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(merged))
+		info, err := fs.Stat(valuesFile)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "file mode should be preserved")
+	})
+
+	t.Run("to output file", func(t *testing.T) {
+		fs, cmd, _ := setup(t)
+		require.NoError(t, mergeOverridesIntoFile(cmd, content, valuesFile, "/out/merged.yaml", false))
+
+		merged, err := afero.ReadFile(fs, "/out/merged.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(merged))
+		original, err := afero.ReadFile(fs, valuesFile)
+		require.NoError(t, err)
+		assert.Equal(t, existing, original, "values file should be untouched")
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		fs, cmd, stdout := setup(t)
+		require.NoError(t, mergeOverridesIntoFile(cmd, content, valuesFile, "", true))
+
+		assert.Equal(t, expected, stdout.String())
+		original, err := afero.ReadFile(fs, valuesFile)
+		require.NoError(t, err)
+		assert.Equal(t, existing, original, "values file should be untouched")
+	})
+
+	t.Run("missing values file", func(t *testing.T) {
+		_, cmd, _ := setup(t)
+		err := mergeOverridesIntoFile(cmd, content, "/values/missing.yaml", "", false)
+
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitIOError, exitErr.Code)
+	})
+}
+
+func TestValidateMergeIntoFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		recursive bool
+		wantErr   string
+	}{
+		{name: "not set", args: []string{"--output-format", "json"}},
+		{name: "yaml output", args: []string{"--merge-into", "values.yaml"}},
+		{name: "json output", args: []string{"--merge-into", "values.yaml", "--output-format", "json"}, wantErr: "requires YAML output"},
+		{name: "recursive", args: []string{"--merge-into", "values.yaml"}, recursive: true, wantErr: "cannot be used with --recursive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newOverrideCmd()
+			require.NoError(t, cmd.ParseFlags(tt.args))
+
+			err := validateMergeIntoFlags(cmd, tt.recursive)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			var exitErr *exitcodes.ExitCodeError
+			require.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
 // Helper to get root command with mocked stdout/stderr for testing output
 func getRootCmdWithOutputs() (cmd *cobra.Command, stdout, stderr *bytes.Buffer) { // Combined types
 	root := getRootCmd() // Assumes getRootCmd() returns a fresh instance or resets state
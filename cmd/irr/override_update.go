@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// validateUpdateFlags rejects flag combinations that cannot be used with --update.
+func validateUpdateFlags(cmd *cobra.Command, recursive bool, outputFile string) error {
+	update, err := getBoolFlag(cmd, "update")
+	if err != nil || !update {
+		return err
+	}
+	mergeInto, err := getStringFlag(cmd, "merge-into")
+	if err != nil {
+		return err
+	}
+	split, err := getBoolFlag(cmd, "split-by-subchart")
+	if err != nil {
+		return err
+	}
+	outputURI, err := getStringFlag(cmd, "output-uri")
+	if err != nil {
+		return err
+	}
+	outputFormat, err := getStringFlag(cmd, "output-format")
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case outputFile == "":
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitMissingRequiredFlag,
+			Err:  errors.New("--update requires --output-file"),
+		}
+	case recursive:
+		err = errors.New("--update cannot be used with --recursive")
+	case mergeInto != "":
+		err = errors.New("--update cannot be used with --merge-into; --merge-into already keeps the other keys of the values file")
+	case split:
+		err = errors.New("--update cannot be used with --split-by-subchart")
+	case outputURI != "":
+		err = errors.New("--update cannot be used with --output-uri")
+	case !strings.EqualFold(outputFormat, outputFormatYAML):
+		err = fmt.Errorf("--update requires YAML output, got --output-format %s", outputFormat)
+	}
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	return nil
+}
+
+// updateOverridesFile regenerates the existing overrides file outputFile with the generated YAML
+// overrides, keeping the keys added to it by hand. A missing file is written as usual. On a dry
+// run the updated file is printed instead of written.
+func updateOverridesFile(cmd *cobra.Command, data []byte, outputFile string, dryRun bool) error {
+	exists, err := afero.Exists(AppFs, outputFile)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to check if output file exists: %w", err),
+		}
+	}
+	if !exists {
+		log.Info("Output file does not exist yet, writing it", "path", outputFile)
+		// Encrypted overrides are never updated, see outputOrMergeOverrides
+		return outputOverrides(cmd, data, nil, outputFile, dryRun)
+	}
+
+	existing, err := afero.ReadFile(AppFs, outputFile)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to read overrides file '%s' for --update: %w", outputFile, err),
+		}
+	}
+	updated, report, err := override.UpdateYAML(existing, data)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to update overrides file '%s': %w", outputFile, err),
+		}
+	}
+	logUpdateReport(outputFile, report)
+
+	if dryRun {
+		log.Info("DRY RUN: Displaying updated overrides (stdout)", "path", outputFile)
+		if _, err := fmt.Fprint(cmd.OutOrStdout(), string(updated)); err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to write dry-run output to stdout: %w", err),
+			}
+		}
+		return nil
+	}
+	info, err := AppFs.Stat(outputFile)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to stat overrides file '%s': %w", outputFile, err),
+		}
+	}
+	if err := afero.WriteFile(AppFs, outputFile, updated, info.Mode().Perm()); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write updated overrides to '%s': %w", outputFile, err),
+		}
+	}
+	log.Info("Override values updated", "path", outputFile)
+	return nil
+}
+
+// logUpdateReport logs what --update kept, regenerated and removed: each preserved and removed
+// key at info level, the regenerated keys at debug level.
+func logUpdateReport(outputFile string, report *override.UpdateReport) {
+	for _, path := range report.Preserved {
+		log.Info("Preserved key added to the overrides file", "key", path)
+	}
+	for _, path := range report.Removed {
+		log.Info("Removed image override that is no longer generated", "key", path)
+	}
+	for _, path := range report.Regenerated {
+		log.Debug("Regenerated image override", "key", path)
+	}
+	log.Info("Overrides file updated", "path", outputFile,
+		"regenerated", len(report.Regenerated), "preserved", len(report.Preserved), "removed", len(report.Removed))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateOverridesFile(t *testing.T) {
+	existing := []byte("image:\n  registry: harbor.local\n  repository: dockerhub/library/nginx\n  tag: \"1.25\"\nreplicaCount: 3 # added by hand\n")
+	content := []byte("image:\n  registry: harbor.local\n  repository: dockerhub/library/nginx\n  tag: \"1.27\"\n")
+	outputFile := "/out/overrides.yaml"
+	expected := "image:\n  registry: harbor.local\n  repository: dockerhub/library/nginx\n  tag: \"1.27\"\nreplicaCount: 3 # added by hand\n"
+
+	setup := func(t *testing.T, write bool) (afero.Fs, *cobra.Command, *bytes.Buffer) {
+		t.Helper()
+		fs := afero.NewMemMapFs()
+		restoreFs := SetFs(fs)
+		t.Cleanup(restoreFs)
+		if write {
+			require.NoError(t, afero.WriteFile(fs, outputFile, existing, 0o600))
+		}
+		cmd := newOverrideCmd()
+		require.NoError(t, cmd.ParseFlags([]string{"--update"}))
+		stdout := new(bytes.Buffer)
+		cmd.SetOut(stdout)
+		return fs, cmd, stdout
+	}
+
+	t.Run("existing file", func(t *testing.T) {
+		fs, cmd, _ := setup(t, true)
+		require.NoError(t, outputOrMergeOverrides(cmd, content, nil, outputFile, false))
+
+		updated, err := afero.ReadFile(fs, outputFile)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(updated))
+		info, err := fs.Stat(outputFile)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "file mode should be preserved")
+
+		require.NoError(t, outputOrMergeOverrides(cmd, content, nil, outputFile, false))
+		again, err := afero.ReadFile(fs, outputFile)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(again), "updating again changes nothing")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		fs, cmd, _ := setup(t, false)
+		require.NoError(t, outputOrMergeOverrides(cmd, content, nil, outputFile, false))
+
+		written, err := afero.ReadFile(fs, outputFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(written), "tag: \"1.27\"")
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		fs, cmd, stdout := setup(t, true)
+		require.NoError(t, outputOrMergeOverrides(cmd, content, nil, outputFile, true))
+
+		assert.Equal(t, expected, stdout.String())
+		unchanged, err := afero.ReadFile(fs, outputFile)
+		require.NoError(t, err)
+		assert.Equal(t, string(existing), string(unchanged))
+	})
+}
+
+func TestValidateUpdateFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		outputFile string
+		recursive  bool
+		wantCode   int
+		wantErr    string
+	}{
+		{name: "not set", args: []string{"--output-format", "json"}},
+		{name: "output file", args: []string{"--update"}, outputFile: "overrides.yaml"},
+		{name: "no output file", args: []string{"--update"}, wantCode: exitcodes.ExitMissingRequiredFlag, wantErr: "requires --output-file"},
+		{name: "recursive", args: []string{"--update"}, outputFile: "overrides.yaml", recursive: true, wantCode: exitcodes.ExitInputConfigurationError, wantErr: "cannot be used with --recursive"},
+		{name: "merge into", args: []string{"--update", "--merge-into", "values.yaml"}, outputFile: "overrides.yaml", wantCode: exitcodes.ExitInputConfigurationError, wantErr: "cannot be used with --merge-into"},
+		{name: "json output", args: []string{"--update", "--output-format", "json"}, outputFile: "overrides.json", wantCode: exitcodes.ExitInputConfigurationError, wantErr: "requires YAML output"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newOverrideCmd()
+			require.NoError(t, cmd.ParseFlags(tt.args))
+
+			err := validateUpdateFlags(cmd, tt.recursive, tt.outputFile)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			var exitErr *exitcodes.ExitCodeError
+			require.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, tt.wantCode, exitErr.Code)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
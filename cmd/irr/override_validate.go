@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+
+	internalhelm "github.com/lucas-albers-lz4/irr/internal/helm"
+	"github.com/lucas-albers-lz4/irr/pkg/chart"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli/values"
+)
+
+// validateChartTemplate is the function that renders the chart with the overrides; a variable to
+// support mocking in tests
+var validateChartTemplate = chart.ValidateHelmTemplate
+
+// validationSlots bounds the template validations run at once while many charts are processed
+// concurrently; nil leaves them bounded by the number of workers only
+var validationSlots chan struct{}
+
+// limitTemplateValidations bounds the template validations run concurrently to limit until the
+// returned function is called. A limit of 0 leaves them bounded by the number of workers.
+func limitTemplateValidations(limit int) (restore func()) {
+	previous := validationSlots
+	validationSlots = nil
+	if limit > 0 {
+		validationSlots = make(chan struct{}, limit)
+	}
+	return func() { validationSlots = previous }
+}
+
+// validateOverridesTemplate renders the chart at chartPath with overrides applied when --validate
+// is set and --no-validate is not. The chart is rendered with the --values and --set inputs the
+// overrides were generated with, as the release and namespace given by --release-name and
+// --namespace, and with the capabilities given by --kube-version, --api-versions and
+// --set-capabilities-from-cluster, so that conditional templates render as they will when deployed.
+func validateOverridesTemplate(cmd *cobra.Command, chartPath string, valueOpts *values.Options, overrides []byte) error {
+	validate, err := getBoolFlag(cmd, "validate")
+	if err != nil || !validate {
+		return err
+	}
+	if skip, err := getBoolFlag(cmd, "no-validate"); err != nil || skip {
+		if skip {
+			log.Info("Skipping Helm template validation (--no-validate)")
+		}
+		return err
+	}
+
+	opts := &chart.ValidationOptions{}
+	if valueOpts != nil {
+		opts.Values = *valueOpts
+	}
+	if opts.ReleaseName, err = getStringFlag(cmd, "release-name"); err != nil {
+		return err
+	}
+	if opts.Namespace, err = getStringFlag(cmd, "namespace"); err != nil {
+		return err
+	}
+	if err := setValidationCapabilities(cmd, opts); err != nil {
+		return err
+	}
+	ctx := getCommandContext(cmd)
+	if slots := validationSlots; slots != nil {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+		case <-ctx.Done():
+			return checkInterrupted(ctx)
+		}
+	}
+	if err := validateChartTemplate(ctx, chartPath, overrides, opts); err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitHelmTemplateFailed, Err: err}
+	}
+	return nil
+}
+
+// setValidationCapabilities sets the Kubernetes version and API versions opts renders the chart
+// with from --kube-version and the flags registered by addCapabilityFlags, for the commands that
+// have them.
+func setValidationCapabilities(cmd *cobra.Command, opts *chart.ValidationOptions) error {
+	if cmd.Flags().Lookup("kube-version") != nil {
+		kubeVersion, err := getStringFlag(cmd, "kube-version")
+		if err != nil {
+			return err
+		}
+		if kubeVersion != "" {
+			if _, err := chartutil.ParseKubeVersion(kubeVersion); err != nil {
+				return &exitcodes.ExitCodeError{
+					Code: exitcodes.ExitInputConfigurationError,
+					Err:  fmt.Errorf("invalid --kube-version %q: %w", kubeVersion, err),
+				}
+			}
+		}
+		opts.KubeVersion = kubeVersion
+	}
+	if cmd.Flags().Lookup("api-versions") == nil {
+		return nil
+	}
+	capabilities, err := getCapabilityFlags(cmd)
+	if err != nil {
+		return err
+	}
+	apiVersions, err := internalhelm.ResolveAPIVersions(internalhelm.NewSettings(), capabilities.APIVersions, capabilities.FromCluster)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitHelmInteractionError,
+			Err:  fmt.Errorf("failed to resolve API versions: %w", err),
+		}
+	}
+	if len(apiVersions) > 0 {
+		opts.APIVersions = apiVersions
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/chart"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/cli/values"
+)
+
+func TestValidateOverridesTemplate(t *testing.T) {
+	original := validateChartTemplate
+	t.Cleanup(func() { validateChartTemplate = original })
+
+	var calls []*chart.ValidationOptions
+	renderErr := error(nil)
+	validateChartTemplate = func(_ context.Context, chartPath string, overrides []byte, opts *chart.ValidationOptions) error {
+		assert.Equal(t, "./web", chartPath)
+		assert.Equal(t, "image:\n  registry: harbor.local\n", string(overrides))
+		calls = append(calls, opts)
+		return renderErr
+	}
+	valueOpts := &values.Options{ValueFiles: []string{"prod.yaml"}, Values: []string{"sidecar.enabled=true"}}
+	overrides := []byte("image:\n  registry: harbor.local\n")
+
+	cmd := newOverrideCmd()
+	require.NoError(t, validateOverridesTemplate(cmd, "./web", valueOpts, overrides))
+	assert.Empty(t, calls, "validation is off by default")
+
+	require.NoError(t, cmd.ParseFlags([]string{"--validate", "--release-name", "web", "--namespace", "prod"}))
+	require.NoError(t, validateOverridesTemplate(cmd, "./web", valueOpts, overrides))
+	require.Len(t, calls, 1)
+	assert.Equal(t, &chart.ValidationOptions{Values: *valueOpts, ReleaseName: "web", Namespace: "prod"}, calls[0])
+
+	renderErr = errors.New("template: web/templates/sidecar.yaml: sidecar.image is required")
+	err := validateOverridesTemplate(cmd, "./web", valueOpts, overrides)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitHelmTemplateFailed, exitErr.Code)
+
+	cmd = newOverrideCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--validate", "--kube-version", "1.29.0", "--api-versions", "monitoring.coreos.com/v1"}))
+	renderErr = nil
+	require.NoError(t, validateOverridesTemplate(cmd, "./web", valueOpts, overrides))
+	require.Len(t, calls, 3)
+	assert.Equal(t, "1.29.0", calls[2].KubeVersion)
+	assert.Equal(t, []string{"monitoring.coreos.com/v1"}, calls[2].APIVersions, "the chart is rendered with the capabilities of the cluster")
+
+	require.NoError(t, cmd.ParseFlags([]string{"--kube-version", "not-a-version"}))
+	err = validateOverridesTemplate(cmd, "./web", valueOpts, overrides)
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+
+	require.NoError(t, cmd.ParseFlags([]string{"--no-validate"}))
+	require.NoError(t, validateOverridesTemplate(cmd, "./web", valueOpts, overrides))
+	assert.Len(t, calls, 3, "--no-validate skips validation")
+}
+
+func TestLimitTemplateValidations(t *testing.T) {
+	original := validateChartTemplate
+	t.Cleanup(func() { validateChartTemplate = original })
+
+	var running, maxRunning int32
+	validateChartTemplate = func(_ context.Context, _ string, _ []byte, _ *chart.ValidationOptions) error {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			observed := atomic.LoadInt32(&maxRunning)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxRunning, observed, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	}
+	cmd := newOverrideCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--validate"}))
+
+	const limit = 2
+	restore := limitTemplateValidations(limit)
+	results, err := processChartsConcurrently(context.Background(), []string{"a", "b", "c", "d", "e", "f"}, 6, func(chartPath string) MultiChartResult {
+		if err := validateOverridesTemplate(cmd, chartPath, nil, nil); err != nil {
+			return MultiChartResult{ChartPath: chartPath, Error: err.Error()}
+		}
+		return MultiChartResult{ChartPath: chartPath}
+	})
+	restore()
+
+	require.NoError(t, err)
+	for _, result := range results {
+		assert.Empty(t, result.Error)
+	}
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxRunning), int32(limit))
+	assert.Nil(t, validationSlots, "the previous limit is restored")
+
+	defer limitTemplateValidations(0)()
+	assert.Nil(t, validationSlots, "0 leaves validations bounded by the workers")
+}
+
+func TestGetValidateWorkersFlag(t *testing.T) {
+	cmd := newOverrideCmd()
+	validateWorkers, err := getValidateWorkersFlag(cmd)
+	require.NoError(t, err)
+	assert.Zero(t, validateWorkers)
+
+	require.NoError(t, cmd.ParseFlags([]string{"--validate-workers", "-1"}))
+	_, err = getValidateWorkersFlag(cmd)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+}
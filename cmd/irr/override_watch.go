@@ -0,0 +1,458 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// defaultWatchDebounce is how long --watch waits for changes to settle before regenerating
+const defaultWatchDebounce = 500 * time.Millisecond
+
+// addWatchFlags adds the flags used to regenerate overrides whenever the chart changes.
+func addWatchFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("watch", false, "Watch the chart, values files and registry file and regenerate the overrides whenever they change")
+	cmd.Flags().Duration("watch-debounce", defaultWatchDebounce, "How long --watch waits for changes to settle before regenerating")
+}
+
+// validateWatchFlags rejects flag combinations that cannot be used with --watch.
+func validateWatchFlags(cmd *cobra.Command, args []string, recursive bool) error {
+	mergeInto, err := getStringFlag(cmd, "merge-into")
+	if err != nil {
+		return err
+	}
+	split, err := getBoolFlag(cmd, "split-by-subchart")
+	if err != nil {
+		return err
+	}
+	releaseName, err := getStringFlag(cmd, "release-name")
+	if err != nil {
+		return err
+	}
+	debounce, err := cmd.Flags().GetDuration("watch-debounce")
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get watch-debounce flag: %w", err),
+		}
+	}
+
+	switch {
+	case recursive:
+		err = errors.New("--watch cannot be used with --recursive")
+	case len(args) > 0 || releaseName != "":
+		err = errors.New("--watch cannot be used with a release name; it watches a local chart")
+	case mergeInto != "":
+		err = errors.New("--watch cannot be used with --merge-into")
+	case split:
+		err = errors.New("--watch cannot be used with --split-by-subchart")
+	case debounce < 0:
+		err = fmt.Errorf("--watch-debounce must not be negative, got %s", debounce)
+	}
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	return nil
+}
+
+// watchTargets are the paths --watch reacts to
+type watchTargets struct {
+	// dirs are watched recursively (the chart directory)
+	dirs []string
+	// files are watched individually (a packaged chart, values files and the registry file)
+	files map[string]bool
+	// ignored are files irr writes itself (the output file)
+	ignored map[string]bool
+}
+
+// newWatchTargets builds the watch targets from the chart path, values files, registry files and
+// output file. A registry files directory is watched like a chart directory. Relative paths are
+// resolved against the current directory.
+func newWatchTargets(chartPath string, valuesFiles, registryFiles []string, outputFile string) (*watchTargets, error) {
+	targets := &watchTargets{files: make(map[string]bool), ignored: make(map[string]bool)}
+
+	absChart, err := filepath.Abs(fileutil.CleanPath(chartPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve chart path '%s': %w", chartPath, err)
+	}
+	info, err := AppFs.Stat(absChart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat chart path '%s': %w", chartPath, err)
+	}
+	if info.IsDir() {
+		targets.dirs = append(targets.dirs, absChart)
+	} else {
+		targets.files[absChart] = true
+	}
+
+	for _, file := range append(append([]string{}, valuesFiles...), registryFiles...) {
+		if strings.TrimSpace(file) == "" {
+			continue
+		}
+		absFile, err := filepath.Abs(fileutil.CleanPath(file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve watched file '%s': %w", file, err)
+		}
+		if info, err := AppFs.Stat(absFile); err == nil && info.IsDir() {
+			targets.dirs = append(targets.dirs, absFile)
+			continue
+		}
+		targets.files[absFile] = true
+	}
+
+	if outputFile != "" {
+		absOutput, err := filepath.Abs(fileutil.CleanPath(outputFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve output file '%s': %w", outputFile, err)
+		}
+		targets.ignored[absOutput] = true
+	}
+	return targets, nil
+}
+
+// matches reports whether a change to path should trigger a regeneration
+func (t *watchTargets) matches(path string) bool {
+	path = filepath.Clean(path)
+	if t.ignored[path] {
+		return false
+	}
+	if t.files[path] {
+		return true
+	}
+	for _, dir := range t.dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchDirs returns the directories to register with the file watcher: every directory below the
+// watched chart directories, and the parent directory of each watched file. Parent directories are
+// watched rather than the files themselves so that editors which save by replacing the file are seen.
+func (t *watchTargets) watchDirs() ([]string, error) {
+	dirs := make(map[string]bool)
+	for _, root := range t.dirs {
+		subdirs, err := walkWatchDirs(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range subdirs {
+			dirs[dir] = true
+		}
+	}
+	for file := range t.files {
+		dirs[filepath.Dir(file)] = true
+	}
+	return sortedKeys(dirs), nil
+}
+
+// walkWatchDirs returns root and every directory beneath it, skipping hidden directories such as .git
+func walkWatchDirs(root string) ([]string, error) {
+	var dirs []string
+	err := afero.Walk(AppFs, root, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk watched directory '%s': %w", root, err)
+	}
+	return dirs, nil
+}
+
+// debounceChanges calls regenerate with the paths received on changes once no further change has
+// arrived for the debounce period. It returns when ctx is done or changes is closed.
+func debounceChanges(ctx context.Context, changes <-chan string, debounce time.Duration, regenerate func(changed []string)) {
+	pending := make(map[string]bool)
+	var timer *time.Timer
+	var fired <-chan time.Time // nil, and so never ready, while no change is pending
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case path, ok := <-changes:
+			if !ok {
+				return
+			}
+			pending[path] = true
+			if timer == nil {
+				timer = time.NewTimer(debounce)
+			} else {
+				timer.Reset(debounce)
+			}
+			fired = timer.C
+		case <-fired:
+			fired = nil
+			changed := sortedKeys(pending)
+			pending = make(map[string]bool)
+			regenerate(changed)
+		}
+	}
+}
+
+// overrideWatcher regenerates the overrides for a local chart and writes them out
+type overrideWatcher struct {
+	cmd        *cobra.Command
+	outputFile string
+	dryRun     bool
+	quiet      bool
+	lastOutput []byte
+	// encryption is the encryption section of the registry config of the last regeneration
+	encryption *registry.EncryptionConfig
+}
+
+// regenerate generates the overrides and writes them to the output file (or stdout) when they
+// differ from the previous result. Errors are reported and the watch continues.
+func (w *overrideWatcher) regenerate(changed []string) {
+	if len(changed) > 0 {
+		log.Info("Change detected, regenerating overrides", "files", strings.Join(changed, ", "))
+	}
+	output, err := w.generate()
+	if err != nil {
+		log.Error("Failed to regenerate overrides; waiting for further changes", "error", err)
+		w.status("regeneration failed: %v", err)
+		return
+	}
+	if w.lastOutput != nil && bytes.Equal(output, w.lastOutput) {
+		log.Info("Overrides unchanged")
+		w.status("overrides unchanged")
+		return
+	}
+	if err := w.write(output); err != nil {
+		log.Error("Failed to write overrides; waiting for further changes", "error", err)
+		w.status("write failed: %v", err)
+		return
+	}
+	w.lastOutput = output
+	if w.outputFile == "" || w.dryRun {
+		w.status("overrides regenerated")
+	} else {
+		w.status("overrides regenerated: %s", w.outputFile)
+	}
+}
+
+// generate returns the overrides in the requested output format
+func (w *overrideWatcher) generate() ([]byte, error) {
+	yamlBytes, config, err := generateStandaloneOverrides(w.cmd, false)
+	if _, partial := asPartialOverrides(err); err != nil && !partial {
+		return nil, err
+	}
+	w.encryption = config.Encryption
+	outputFormat, err := getStringFlag(w.cmd, "output-format")
+	if err != nil {
+		return nil, err
+	}
+	return formatOverrides(yamlBytes, outputFormat)
+}
+
+// write replaces the output file with output, or prints it to stdout when there is no output file
+// or on a dry run. Unlike a one-off override run, the output file is expected to exist after the
+// first regeneration and is overwritten. Except on a dry run, output is encrypted with --encrypt or
+// the registry config first.
+func (w *overrideWatcher) write(output []byte) error {
+	if !w.dryRun {
+		outputFormat, err := getStringFlag(w.cmd, "output-format")
+		if err != nil {
+			return err
+		}
+		if output, err = encryptOverrides(w.cmd, output, w.encryption, outputFormat); err != nil {
+			return err
+		}
+	}
+	if w.outputFile == "" || w.dryRun {
+		if _, err := fmt.Fprintf(w.cmd.OutOrStdout(), "---\n%s\n", strings.TrimRight(string(output), "\n")); err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to write overrides to stdout: %w", err),
+			}
+		}
+		return nil
+	}
+	if dir := filepath.Dir(w.outputFile); dir != "" && dir != "." {
+		if err := AppFs.MkdirAll(dir, fileutil.ReadWriteExecuteUserReadExecuteOthers); err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to create output directory: %w", err),
+			}
+		}
+	}
+	if err := afero.WriteFile(AppFs, w.outputFile, output, fileutil.ReadWriteUserReadOthers); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write output file '%s': %w", w.outputFile, err),
+		}
+	}
+	log.Info("Override values written", "path", w.outputFile)
+	return nil
+}
+
+// status prints a timestamped status line to stderr. This is the only output of a regeneration
+// with the global --quiet, apart from errors.
+func (w *overrideWatcher) status(format string, args ...any) {
+	if !w.quiet {
+		return
+	}
+	if _, err := fmt.Fprintf(w.cmd.ErrOrStderr(), "[%s] %s\n", time.Now().Format(time.TimeOnly), fmt.Sprintf(format, args...)); err != nil {
+		log.Debug("Failed to write watch status", "error", err)
+	}
+}
+
+// runOverrideWatch generates the overrides for --chart-path and regenerates them whenever the
+// chart, a values file or the registry file changes, until interrupted.
+func runOverrideWatch(cmd *cobra.Command, outputFile string, dryRun bool) error {
+	debounce, err := cmd.Flags().GetDuration("watch-debounce")
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get watch-debounce flag: %w", err),
+		}
+	}
+	chartPath, err := getStringFlag(cmd, "chart-path")
+	if err != nil {
+		return err
+	}
+	if chartPath == "" {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitMissingRequiredFlag,
+			Err:  errors.New("--chart-path is required with --watch"),
+		}
+	}
+	valuesFiles, err := getStringSliceFlag(cmd, "values")
+	if err != nil {
+		return err
+	}
+	registryFiles, err := getStringSliceFlag(cmd, "registry-file")
+	if err != nil {
+		return err
+	}
+	if len(registryFiles) == 0 {
+		deprecatedConfig, err := getStringFlag(cmd, "config")
+		if err != nil {
+			return err
+		}
+		registryFiles = []string{deprecatedConfig}
+	}
+
+	targets, err := newWatchTargets(chartPath, valuesFiles, registryFiles, outputFile)
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitChartNotFound, Err: err}
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to start file watcher: %w", err),
+		}
+	}
+	defer func() {
+		if closeErr := watcher.Close(); closeErr != nil {
+			log.Debug("Failed to close file watcher", "error", closeErr)
+		}
+	}()
+	dirs, err := targets.watchDirs()
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitIOError, Err: err}
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to watch '%s': %w", dir, err),
+			}
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(getCommandContext(cmd), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	w := &overrideWatcher{cmd: cmd, outputFile: outputFile, dryRun: dryRun, quiet: quietMode}
+	w.regenerate(nil)
+	log.Info("Watching for changes; press Ctrl+C to stop", "chart", chartPath, "directories", len(dirs), "debounce", debounce)
+
+	changes := make(chan string)
+	go forwardWatchEvents(ctx, watcher, targets, changes)
+	debounceChanges(ctx, changes, debounce, w.regenerate)
+	log.Info("Stopped watching for changes")
+	return nil
+}
+
+// forwardWatchEvents sends the path of every relevant file watcher event to changes, and starts
+// watching directories created inside a watched chart directory. It closes changes when done.
+func forwardWatchEvents(ctx context.Context, watcher *fsnotify.Watcher, targets *watchTargets, changes chan<- string) {
+	defer close(changes)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod || !targets.matches(event.Name) {
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				addCreatedWatchDirs(watcher, event.Name)
+			}
+			select {
+			case changes <- filepath.Clean(event.Name):
+			case <-ctx.Done():
+				return
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Warn("File watcher error", "error", err)
+		}
+	}
+}
+
+// addCreatedWatchDirs starts watching path and the directories beneath it if path is a new directory
+func addCreatedWatchDirs(watcher *fsnotify.Watcher, path string) {
+	info, err := AppFs.Stat(path)
+	if err != nil || !info.IsDir() {
+		return
+	}
+	dirs, err := walkWatchDirs(path)
+	if err != nil {
+		log.Warn("Failed to watch new directory", "path", path, "error", err)
+		return
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			log.Warn("Failed to watch new directory", "path", dir, "error", err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateWatchFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		flags     []string
+		args      []string
+		recursive bool
+		wantCode  int
+	}{
+		{name: "watch", flags: []string{"--watch"}},
+		{name: "custom debounce", flags: []string{"--watch", "--watch-debounce", "2s"}},
+		{name: "with recursive", flags: []string{"--watch"}, recursive: true, wantCode: exitcodes.ExitInputConfigurationError},
+		{name: "with release argument", flags: []string{"--watch"}, args: []string{"my-release"}, wantCode: exitcodes.ExitInputConfigurationError},
+		{name: "with release name", flags: []string{"--watch", "--release-name", "my-release"}, wantCode: exitcodes.ExitInputConfigurationError},
+		{name: "with merge-into", flags: []string{"--watch", "--merge-into", "values.yaml"}, wantCode: exitcodes.ExitInputConfigurationError},
+		{name: "with split-by-subchart", flags: []string{"--watch", "--split-by-subchart"}, wantCode: exitcodes.ExitInputConfigurationError},
+		{name: "negative debounce", flags: []string{"--watch", "--watch-debounce", "-1s"}, wantCode: exitcodes.ExitInputConfigurationError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newOverrideCmd()
+			require.NoError(t, cmd.ParseFlags(tt.flags))
+
+			err := validateWatchFlags(cmd, tt.args, tt.recursive)
+			if tt.wantCode == 0 {
+				require.NoError(t, err)
+				return
+			}
+			var exitErr *exitcodes.ExitCodeError
+			require.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, tt.wantCode, exitErr.Code)
+		})
+	}
+}
+
+func TestWatchTargets(t *testing.T) {
+	originalFs := AppFs
+	AppFs = afero.NewMemMapFs()
+	defer func() { AppFs = originalFs }()
+
+	root, err := filepath.Abs("watch-test")
+	require.NoError(t, err)
+	chartDir := filepath.Join(root, "mychart")
+	require.NoError(t, AppFs.MkdirAll(filepath.Join(chartDir, "templates"), 0o755))
+	require.NoError(t, AppFs.MkdirAll(filepath.Join(chartDir, ".git", "objects"), 0o755))
+	require.NoError(t, afero.WriteFile(AppFs, filepath.Join(chartDir, "Chart.yaml"), []byte("name: mychart\n"), 0o644))
+
+	valuesFile := filepath.Join(root, "config", "prod.yaml")
+	registryFile := filepath.Join(root, "registry-mappings.yaml")
+	registryDir := filepath.Join(root, "registries")
+	require.NoError(t, AppFs.MkdirAll(registryDir, 0o755))
+	outputFile := filepath.Join(chartDir, "overrides.yaml")
+
+	targets, err := newWatchTargets(chartDir, []string{valuesFile}, []string{registryFile, registryDir}, outputFile)
+	require.NoError(t, err)
+
+	t.Run("matches", func(t *testing.T) {
+		assert.True(t, targets.matches(filepath.Join(chartDir, "values.yaml")))
+		assert.True(t, targets.matches(filepath.Join(chartDir, "templates", "deployment.yaml")))
+		assert.True(t, targets.matches(valuesFile))
+		assert.True(t, targets.matches(registryFile))
+		assert.True(t, targets.matches(filepath.Join(registryDir, "cluster-a.yaml")))
+		assert.False(t, targets.matches(outputFile), "the output file is written by irr itself")
+		assert.False(t, targets.matches(filepath.Join(root, "config", "dev.yaml")))
+		assert.False(t, targets.matches(chartDir+"-backup"))
+	})
+
+	t.Run("watch dirs", func(t *testing.T) {
+		dirs, err := targets.watchDirs()
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			root,
+			filepath.Join(root, "config"),
+			chartDir,
+			filepath.Join(chartDir, "templates"),
+			registryDir,
+		}, dirs)
+	})
+
+	t.Run("missing chart", func(t *testing.T) {
+		_, err := newWatchTargets(filepath.Join(root, "missing"), nil, nil, "")
+		require.Error(t, err)
+	})
+}
+
+func TestDebounceChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan string)
+	regenerated := make(chan []string, 10)
+	done := make(chan struct{})
+	go func() {
+		debounceChanges(ctx, changes, 50*time.Millisecond, func(changed []string) {
+			regenerated <- changed
+		})
+		close(done)
+	}()
+
+	// A burst of changes results in a single regeneration listing each file once
+	changes <- "values.yaml"
+	changes <- "templates/deployment.yaml"
+	changes <- "values.yaml"
+
+	select {
+	case changed := <-regenerated:
+		assert.Equal(t, []string{"templates/deployment.yaml", "values.yaml"}, changed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for regeneration")
+	}
+
+	changes <- "Chart.yaml"
+	select {
+	case changed := <-regenerated:
+		assert.Equal(t, []string{"Chart.yaml"}, changed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for second regeneration")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("debounceChanges did not return after the context was cancelled")
+	}
+	assert.Empty(t, regenerated)
+}
@@ -0,0 +1,84 @@
+//go:build pprof
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+)
+
+// Runtime profiling is only compiled in with `go build -tags pprof`, so release binaries carry
+// no profiling flags. The profiles are read with `go tool pprof`.
+var (
+	cpuProfilePath string
+	memProfilePath string
+	cpuProfileFile *os.File
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&cpuProfilePath, "cpu-profile", "", "write a Go CPU profile of the run to this file")
+	rootCmd.PersistentFlags().StringVar(&memProfilePath, "mem-profile", "", "write a Go heap profile at the end of the run to this file")
+	startPprof = startRuntimeProfiling
+	stopPprof = stopRuntimeProfiling
+}
+
+// startRuntimeProfiling starts the CPU profile requested with --cpu-profile
+func startRuntimeProfiling() error {
+	if cpuProfilePath == "" || cpuProfileFile != nil {
+		return nil
+	}
+	file, err := os.Create(cpuProfilePath)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to create CPU profile %s: %w", cpuProfilePath, err),
+		}
+	}
+	if err := pprof.StartCPUProfile(file); err != nil {
+		if closeErr := file.Close(); closeErr != nil {
+			log.Warn("Failed to close CPU profile", "path", cpuProfilePath, "error", closeErr)
+		}
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to start CPU profile: %w", err),
+		}
+	}
+	cpuProfileFile = file
+	return nil
+}
+
+// stopRuntimeProfiling stops the CPU profile and writes the heap profile requested with --mem-profile
+func stopRuntimeProfiling() {
+	if cpuProfileFile != nil {
+		pprof.StopCPUProfile()
+		if err := cpuProfileFile.Close(); err != nil {
+			log.Warn("Failed to close CPU profile", "path", cpuProfilePath, "error", err)
+		}
+		cpuProfileFile = nil
+		log.Info("CPU profile written", "file", cpuProfilePath)
+	}
+	if memProfilePath == "" {
+		return
+	}
+	file, err := os.Create(memProfilePath)
+	if err != nil {
+		log.Error("Failed to create heap profile", "path", memProfilePath, "error", err)
+		return
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Warn("Failed to close heap profile", "path", memProfilePath, "error", err)
+		}
+	}()
+	runtime.GC() // Profile live objects only
+	if err := pprof.WriteHeapProfile(file); err != nil {
+		log.Error("Failed to write heap profile", "path", memProfilePath, "error", err)
+		return
+	}
+	log.Info("Heap profile written", "file", memProfilePath)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+// newRegistryProber creates the prober used by --probe-targets, sending its requests through
+// transport. It can be replaced in tests.
+var newRegistryProber = func(credentials map[string]registry.Credentials, checkAuth bool, transport http.RoundTripper) *registry.Prober {
+	prober := registry.NewProber(credentials, checkAuth)
+	prober.Client.Transport = transport
+	prober.Helpers = registryCredentialHelpers()
+	return prober
+}
+
+// noCredentialHelpers is set by the global --no-credential-helpers flag
+var noCredentialHelpers bool
+
+// registryCredentialHelpers returns the credential helpers that read the credentials of registries
+// not stored in the credential files, or nil with --no-credential-helpers.
+func registryCredentialHelpers() *registry.CredentialHelpers {
+	if noCredentialHelpers {
+		return nil
+	}
+	return registry.NewCredentialHelpers()
+}
+
+// addProbeTargetsFlags adds the flags that check target registries before overrides are generated.
+func addProbeTargetsFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("probe-targets", false, "Check that every target registry answers on /v2/ and that credentials exist for those requiring authentication, warning about problems before generating overrides")
+	cmd.Flags().Bool("probe-auth", false, "With --probe-targets, also verify the credentials found by logging in to target registries that require authentication")
+}
+
+// probeTargetRegistries probes the distinct target registries of config when --probe-targets is
+// set, logging a warning for each registry that is unreachable or lacks working credentials.
+// Problems are reported but do not fail the run.
+func probeTargetRegistries(cmd *cobra.Command, config *GeneratorConfig) error {
+	probe, err := getBoolFlag(cmd, "probe-targets")
+	if err != nil || !probe {
+		return err
+	}
+	checkAuth, err := getBoolFlag(cmd, "probe-auth")
+	if err != nil {
+		return err
+	}
+	if err := requireNetwork("probing target registries (--probe-targets)"); err != nil {
+		return err
+	}
+
+	targets := []string{config.TargetRegistry}
+	if config.Mappings != nil {
+		for _, entry := range config.Mappings.Entries {
+			targets = append(targets, entry.Target)
+		}
+	}
+	hosts := registry.TargetHosts(targets)
+	if len(hosts) == 0 {
+		log.Info("No target registries to probe")
+		return nil
+	}
+
+	credentialFiles, err := registryCredentialFiles(cmd)
+	if err != nil {
+		return err
+	}
+	credentials, err := registry.LoadCredentials(AppFs, credentialFiles...)
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+
+	transport, err := newNetworkTransport(config.RegistryTLS)
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	log.Info("Probing target registries", "count", len(hosts), "checkAuth", checkAuth)
+	failed := 0
+	for _, result := range newRegistryProber(credentials, checkAuth, transport).ProbeAll(ctx, hosts) {
+		if result.OK() {
+			log.Info("Target registry probed", "registry", result.Host, "status", result.Status)
+			continue
+		}
+		failed++
+		log.Warn("Target registry check failed; pulls of relocated images may fail",
+			"registry", result.Host, "status", result.Status, "error", result.Err)
+	}
+	if failed > 0 {
+		log.Warn("Some target registries failed the probe", "failed", failed, "probed", len(hosts))
+	}
+	return nil
+}
+
+// registryCredentialFiles returns the credential files searched for target registry credentials:
+// the Helm registry config (--registry-config or Helm's default) and the Docker config.
+func registryCredentialFiles(cmd *cobra.Command) ([]string, error) {
+	helmConfig, err := getStringFlag(cmd, "registry-config")
+	if err != nil {
+		return nil, err
+	}
+	if helmConfig == "" {
+		helmConfig = cli.New().RegistryConfig
+	}
+
+	dockerConfigDir := os.Getenv("DOCKER_CONFIG")
+	if dockerConfigDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			log.Debug("Cannot locate the Docker config without a home directory", "error", err)
+			return []string{helmConfig}, nil
+		}
+		dockerConfigDir = filepath.Join(home, ".docker")
+	}
+	return []string{helmConfig, filepath.Join(dockerConfigDir, "config.json")}, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/lucas-albers-lz4/irr/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeTargetRegistries(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="harbor"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	// The registry's certificate is trusted through the tls section of the mappings file
+	caPath := filepath.Join(t.TempDir(), "registry-ca.pem")
+	require.NoError(t, os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	cmd := newOverrideCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--probe-targets", "--registry-config", filepath.Join(t.TempDir(), "config.json")}))
+	config := &GeneratorConfig{
+		TargetRegistry: host,
+		Mappings:       &registry.Mappings{Entries: []registry.Mapping{{Source: "docker.io", Target: host + "/dockerhub"}}},
+		RegistryTLS:    map[string]registry.TLSConfig{host: {CAFile: caPath}},
+	}
+
+	var probeErr error
+	output, err := testutil.CaptureLogOutput(log.LevelInfo, func() {
+		probeErr = probeTargetRegistries(cmd, config)
+	})
+	require.NoError(t, err)
+	require.NoError(t, probeErr, "probe failures are warnings")
+	assert.Contains(t, output, "Target registry check failed")
+	assert.Contains(t, output, registry.ProbeStatusNoCredentials)
+	assert.Equal(t, 1, strings.Count(output, "Target registry check failed"), "each host is probed once")
+
+	originalOffline := offlineMode
+	offlineMode = true
+	t.Cleanup(func() { offlineMode = originalOffline })
+	err = probeTargetRegistries(cmd, config)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+}
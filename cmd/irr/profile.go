@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/timing"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// profileOutput is set by the global --profile-output flag
+var profileOutput string
+
+// profiledCommand is the command path of the run, recorded in the profile
+var profiledCommand string
+
+// startPprof and stopPprof start and stop Go runtime profiling. They do nothing unless irr is
+// built with the pprof build tag, which adds the --cpu-profile and --mem-profile flags.
+var (
+	startPprof = func() error { return nil }
+	stopPprof  = func() {}
+)
+
+// startProfiling enables span recording for --profile-output and starts runtime profiling. It is
+// called once flags are parsed.
+func startProfiling(cmd *cobra.Command) error {
+	if profileOutput != "" {
+		profiledCommand = cmd.CommandPath()
+		timing.EnableRecording()
+	}
+	return startPprof()
+}
+
+// finishProfiling stops runtime profiling and writes the timing profile of the run that started
+// at start, if --profile-output is set. Failures are logged rather than returned so they do not
+// replace the outcome of the command.
+func finishProfiling(start time.Time) {
+	stopPprof()
+	if profileOutput == "" || profiledCommand == "" {
+		return
+	}
+	defer timing.Reset()
+	if err := writeProfile(profileOutput, timing.NewProfile(profiledCommand, start, time.Now())); err != nil {
+		log.Error("Failed to write timing profile", "error", err)
+		return
+	}
+	log.Info("Timing profile written", "file", profileOutput)
+}
+
+// writeProfile writes the profile as indented JSON, replacing an existing file
+func writeProfile(path string, profile *timing.Profile) error {
+	data, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to marshal timing profile: %w", err),
+		}
+	}
+	if err := afero.WriteFile(AppFs, path, append(data, '\n'), fileutil.ReadWriteUserReadOthers); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write timing profile '%s': %w", path, err),
+		}
+	}
+	return nil
+}
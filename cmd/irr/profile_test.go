@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/timing"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileOutput(t *testing.T) {
+	originalFs := AppFs
+	AppFs = afero.NewMemMapFs()
+	t.Cleanup(func() {
+		AppFs = originalFs
+		profileOutput = ""
+		profiledCommand = ""
+		timing.Reset()
+	})
+
+	parent := &cobra.Command{Use: "irr"}
+	cmd := &cobra.Command{Use: "override"}
+	parent.AddCommand(cmd)
+
+	profileOutput = "profile.json"
+	require.NoError(t, startProfiling(cmd))
+	start := time.Now()
+	timing.Start(timing.SpanChartLoad, "./nginx")()
+	timing.Start(timing.SpanGeneration, "./nginx")()
+	finishProfiling(start)
+
+	data, err := afero.ReadFile(AppFs, "profile.json")
+	require.NoError(t, err)
+	var profile timing.Profile
+	require.NoError(t, json.Unmarshal(data, &profile))
+	assert.Equal(t, "irr override", profile.Command)
+	require.Len(t, profile.Stages, 2)
+	assert.Equal(t, timing.SpanChartLoad, profile.Stages[0].Name)
+	assert.Equal(t, timing.SpanGeneration, profile.Stages[1].Name)
+	require.Len(t, profile.Spans, 2)
+	assert.Equal(t, "./nginx", profile.Spans[0].Detail)
+	assert.Empty(t, timing.Spans(), "recorded spans are discarded once the profile is written")
+}
+
+func TestProfileOutputDisabled(t *testing.T) {
+	originalFs := AppFs
+	AppFs = afero.NewMemMapFs()
+	t.Cleanup(func() { AppFs = originalFs })
+
+	require.NoError(t, startProfiling(&cobra.Command{Use: "inspect"}))
+	timing.Start(timing.SpanAnalysis, "")()
+	finishProfiling(time.Now())
+
+	assert.Empty(t, timing.Spans())
+	files, err := afero.ReadDir(AppFs, ".")
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/lucas-albers-lz4/irr/pkg/chart"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// Chart version parts accepted by --bump-version
+const (
+	bumpVersionPatch = "patch"
+	bumpVersionMinor = "minor"
+	bumpVersionMajor = "major"
+)
+
+// rewriteFlagsHidden lists override flags that do not apply to irr rewrite, which writes the
+// overrides of a single local chart into the chart's own values files
+var rewriteFlagsHidden = []string{
+	"release-name", "namespace", "output-file", "output-dir", "output-format", "merge-into",
+	"split-by-subchart", "recursive", "workers", "validate-workers", "watch", "watch-debounce",
+	"ignore-errors", "error-report", "output-uri", "encrypt", "encrypt-recipient", "metadata",
+}
+
+// rewriteFile is a chart file changed by irr rewrite
+type rewriteFile struct {
+	Path     string
+	Original []byte
+	Content  []byte
+}
+
+// newRewriteCmd creates the rewrite command
+func newRewriteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rewrite",
+		Short: "Rewrite a chart's values.yaml so relocated images become its defaults",
+		Long: `Generates the image overrides of a local chart directory, exactly as 'irr override' would, and
+writes them into the chart's own values.yaml instead of an overrides file, so the relocated
+registries become the chart's defaults. Overrides of subcharts vendored as directories under
+charts/ are written into the subchart's values.yaml, unless the parent chart's values set the
+same path. Comments and key order are preserved.
+
+A unified diff of the changed files is printed. With --dry-run, nothing is written. With
+--bump-version, the chart version in Chart.yaml is bumped as well.`,
+		Example: `  irr rewrite --chart-path ./nginx --registry-file registry-mappings.yaml --dry-run
+  irr rewrite --chart-path ./platform -t harbor.local -s docker.io --bump-version patch`,
+		Args: cobra.NoArgs,
+		RunE: runRewrite,
+	}
+
+	setupOverrideFlags(cmd)
+	for _, name := range rewriteFlagsHidden {
+		if err := cmd.Flags().MarkHidden(name); err != nil {
+			log.Error("Failed to hide rewrite flag", "flag", name, "error", err)
+		}
+	}
+	cmd.Flags().String("bump-version", "", "Also bump the chart version in Chart.yaml: patch, minor or major")
+	return cmd
+}
+
+// runRewrite generates the overrides for --chart-path and writes them into the chart's values
+// files, printing a unified diff of the changes
+func runRewrite(cmd *cobra.Command, _ []string) error {
+	bump, err := getStringFlag(cmd, "bump-version")
+	if err != nil {
+		return err
+	}
+	bump = strings.ToLower(bump)
+	switch bump {
+	case "", bumpVersionPatch, bumpVersionMinor, bumpVersionMajor:
+	default:
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("unsupported --bump-version %q; use %s, %s or %s", bump, bumpVersionPatch, bumpVersionMinor, bumpVersionMajor),
+		}
+	}
+	dryRun, err := getBoolFlag(cmd, "dry-run")
+	if err != nil {
+		return err
+	}
+
+	generated, config, err := generateStandaloneOverrides(cmd, false)
+	if err != nil {
+		return err
+	}
+	chartPath := config.ChartPath
+	if info, err := AppFs.Stat(chartPath); err != nil || !info.IsDir() {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("rewrite needs an unpacked chart directory; '%s' is not a directory", chartPath),
+		}
+	}
+	loadedChart, err := chart.NewLoader().Load(chartPath)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitChartLoadFailed,
+			Err:  fmt.Errorf("failed to load chart to rewrite its values: %w", err),
+		}
+	}
+	var overrides map[string]interface{}
+	if err := yaml.Unmarshal(generated, &overrides); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to parse generated overrides: %w", err),
+		}
+	}
+
+	files, err := planValuesRewrite(chartPath, loadedChart, overrides)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Chart %s already uses the relocated images; nothing to rewrite\n", chartPath); err != nil {
+			log.Debug("Failed to write rewrite result", "error", err)
+		}
+		return nil
+	}
+	if bump != "" {
+		chartFile, err := planChartVersionBump(chartPath, bump)
+		if err != nil {
+			return err
+		}
+		files = append(files, *chartFile)
+	}
+
+	diff, err := diffRewrite(chartPath, files)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprint(cmd.OutOrStdout(), diff); err != nil {
+		log.Debug("Failed to write rewrite diff", "error", err)
+	}
+	if dryRun {
+		log.Info("DRY RUN: Chart files not changed", "chart", chartPath, "files", len(files))
+		return nil
+	}
+	return writeRewriteFiles(files)
+}
+
+// vendoredSubchart is a subchart whose sources are in a directory under the parent's charts/
+type vendoredSubchart struct {
+	dir   string
+	chart *helmchart.Chart
+}
+
+// planValuesRewrite returns the values files of the chart in chartDir, and of its vendored
+// subcharts, that change when overrides become their defaults. The parent chart's values.yaml
+// comes first, followed by the subcharts' in alias order.
+func planValuesRewrite(chartDir string, ch *helmchart.Chart, overrides map[string]interface{}) ([]rewriteFile, error) {
+	parentOverrides := make(map[string]interface{}, len(overrides))
+	for key, value := range overrides {
+		parentOverrides[key] = value
+	}
+
+	var subchartFiles []rewriteFile
+	vendored := vendoredSubcharts(chartDir, ch)
+	aliases := make([]string, 0, len(vendored))
+	for alias := range vendored {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		subchartOverrides, ok := overrides[alias].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		parentValues, _ := ch.Values[alias].(map[string]interface{})
+		parentPart, subchartPart := override.SplitVendoredOverrides(subchartOverrides, parentValues)
+		if len(parentPart) > 0 {
+			parentOverrides[alias] = parentPart
+		} else {
+			delete(parentOverrides, alias)
+		}
+		files, err := planValuesRewrite(vendored[alias].dir, vendored[alias].chart, subchartPart)
+		if err != nil {
+			return nil, err
+		}
+		subchartFiles = append(subchartFiles, files...)
+	}
+
+	file, err := rewriteValuesFile(filepath.Join(chartDir, "values.yaml"), parentOverrides)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return subchartFiles, nil
+	}
+	return append([]rewriteFile{*file}, subchartFiles...), nil
+}
+
+// vendoredSubcharts returns the subcharts of ch that are vendored as a directory named after the
+// chart under chartDir/charts, by the key their values are found under. A subchart used under
+// several aliases is left out, since its values.yaml cannot hold different defaults for each.
+func vendoredSubcharts(chartDir string, ch *helmchart.Chart) map[string]vendoredSubchart {
+	valuesKeys := make(map[string][]string)
+	if ch.Metadata != nil {
+		for _, dep := range ch.Metadata.Dependencies {
+			if dep == nil {
+				continue
+			}
+			key := dep.Alias
+			if key == "" {
+				key = dep.Name
+			}
+			valuesKeys[dep.Name] = append(valuesKeys[dep.Name], key)
+		}
+	}
+
+	vendored := make(map[string]vendoredSubchart)
+	for _, sub := range ch.Dependencies() {
+		name := sub.Name()
+		dir := filepath.Join(chartDir, "charts", name)
+		if exists, err := afero.Exists(AppFs, filepath.Join(dir, "Chart.yaml")); err != nil || !exists {
+			continue
+		}
+		keys := valuesKeys[name]
+		switch len(keys) {
+		case 0:
+			keys = []string{name}
+		case 1:
+		default:
+			log.Warn("Subchart is used under several aliases; its overrides stay in the parent chart's values", "subchart", name, "aliases", keys)
+			continue
+		}
+		vendored[keys[0]] = vendoredSubchart{dir: dir, chart: sub}
+	}
+	return vendored
+}
+
+// rewriteValuesFile returns the values file at path with overrides merged in, or nil if that does
+// not change it. A missing values file is created.
+func rewriteValuesFile(path string, overrides map[string]interface{}) (*rewriteFile, error) {
+	if len(overrides) == 0 {
+		return nil, nil
+	}
+	original, err := afero.ReadFile(AppFs, path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to read values file '%s': %w", path, err),
+		}
+	}
+	data, err := yaml.Marshal(overrides)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to marshal overrides to YAML: %w", err),
+		}
+	}
+	content, err := override.PatchYAML(original, data)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to merge overrides into '%s': %w", path, err),
+		}
+	}
+	if bytes.Equal(original, content) {
+		return nil, nil
+	}
+	return &rewriteFile{Path: path, Original: original, Content: content}, nil
+}
+
+// planChartVersionBump returns the Chart.yaml of the chart in chartDir with the part bump of its
+// version incremented.
+func planChartVersionBump(chartDir, bump string) (*rewriteFile, error) {
+	path := filepath.Join(chartDir, "Chart.yaml")
+	original, err := afero.ReadFile(AppFs, path)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to read '%s': %w", path, err),
+		}
+	}
+	content, oldVersion, newVersion, err := bumpChartVersion(original, bump)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to bump the version in '%s': %w", path, err),
+		}
+	}
+	log.Info("Bumping chart version", "chart", chartDir, "from", oldVersion, "to", newVersion)
+	return &rewriteFile{Path: path, Original: original, Content: content}, nil
+}
+
+// bumpChartVersion increments the part bump (patch, minor or major) of the version in the
+// Chart.yaml data, editing only the version's text. A prerelease version is released instead,
+// as semver orders 1.2.3-rc.1 before 1.2.3; a v prefix is kept.
+func bumpChartVersion(data []byte, bump string) (content []byte, oldVersion, newVersion string, err error) {
+	var metadata struct {
+		Version string `yaml:"version"`
+	}
+	if err := yaml.Unmarshal(data, &metadata); err != nil {
+		return nil, "", "", fmt.Errorf("failed to parse Chart.yaml: %w", err)
+	}
+	version, err := semver.NewVersion(metadata.Version)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("chart version %q is not a semantic version: %w", metadata.Version, err)
+	}
+
+	var bumped semver.Version
+	switch bump {
+	case bumpVersionMajor:
+		bumped = version.IncMajor()
+	case bumpVersionMinor:
+		bumped = version.IncMinor()
+	default:
+		bumped = version.IncPatch()
+	}
+	newVersion = bumped.String()
+	if strings.HasPrefix(metadata.Version, "v") {
+		newVersion = "v" + newVersion
+	}
+
+	patch, err := yaml.Marshal(map[string]string{"version": newVersion})
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to marshal chart version: %w", err)
+	}
+	content, err = override.PatchYAML(data, patch)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return content, metadata.Version, newVersion, nil
+}
+
+// diffRewrite returns a unified diff of the changes to files, with paths relative to chartDir.
+func diffRewrite(chartDir string, files []rewriteFile) (string, error) {
+	var diff strings.Builder
+	for _, file := range files {
+		name := file.Path
+		if rel, err := filepath.Rel(chartDir, file.Path); err == nil {
+			name = filepath.ToSlash(rel)
+		}
+		fileDiff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(file.Original)),
+			B:        difflib.SplitLines(string(file.Content)),
+			FromFile: "a/" + name,
+			ToFile:   "b/" + name,
+			Context:  goldenDiffContext,
+		})
+		if err != nil {
+			return "", &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitGeneralRuntimeError,
+				Err:  fmt.Errorf("failed to diff '%s': %w", file.Path, err),
+			}
+		}
+		diff.WriteString(fileDiff)
+	}
+	return diff.String(), nil
+}
+
+// writeRewriteFiles replaces each file with its new content, keeping its permissions
+func writeRewriteFiles(files []rewriteFile) error {
+	for _, file := range files {
+		perm := os.FileMode(fileutil.ReadWriteUserReadOthers)
+		if info, err := AppFs.Stat(file.Path); err == nil {
+			perm = info.Mode().Perm()
+		}
+		if err := afero.WriteFile(AppFs, file.Path, file.Content, perm); err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to write '%s': %w", file.Path, err),
+			}
+		}
+		log.Info("Chart file rewritten", "path", file.Path)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+func TestPlanValuesRewrite(t *testing.T) {
+	originalFs := AppFs
+	AppFs = afero.NewMemMapFs()
+	defer func() { AppFs = originalFs }()
+
+	chartDir := "/charts/platform"
+	parentValues := "# Platform defaults\nimage:\n  repository: nginx # web server\n\ncache:\n  image:\n    tag: \"7.2\"\n"
+	redisValues := "image:\n  registry: docker.io\n  repository: bitnami/redis\n  tag: \"7.0\"\n"
+	for path, content := range map[string]string{
+		filepath.Join(chartDir, "values.yaml"):                            parentValues,
+		filepath.Join(chartDir, "charts", "redis", "Chart.yaml"):          "name: redis\nversion: 1.0.0\n",
+		filepath.Join(chartDir, "charts", "redis", "values.yaml"):         redisValues,
+		filepath.Join(chartDir, "charts", "postgresql-12.0.0.tgz"):        "",
+		filepath.Join(chartDir, "charts", "postgresql", "README.md"):      "not a chart",
+		filepath.Join(chartDir, "charts", "redis", "templates", "x.yaml"): "",
+	} {
+		require.NoError(t, afero.WriteFile(AppFs, path, []byte(content), 0o644))
+	}
+
+	redis := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "redis"}}
+	postgresql := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "postgresql"}}
+	parent := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "platform", Dependencies: []*helmchart.Dependency{
+			{Name: "redis", Alias: "cache"},
+			{Name: "postgresql"},
+		}},
+		Values: map[string]interface{}{
+			"image": map[string]interface{}{"repository": "nginx"},
+			"cache": map[string]interface{}{"image": map[string]interface{}{"tag": "7.2"}},
+		},
+	}
+	parent.SetDependencies(redis, postgresql)
+
+	files, err := planValuesRewrite(chartDir, parent, map[string]interface{}{
+		"image": map[string]interface{}{"repository": "harbor.local/dockerio/library/nginx"},
+		"cache": map[string]interface{}{"image": map[string]interface{}{
+			"registry": "harbor.local", "repository": "dockerio/bitnami/redis", "tag": "7.2",
+		}},
+		"postgresql": map[string]interface{}{"image": map[string]interface{}{"registry": "harbor.local"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	assert.Equal(t, filepath.Join(chartDir, "values.yaml"), files[0].Path)
+	assert.Equal(t, "# Platform defaults\nimage:\n  repository: nginx # web server\n\ncache:\n  image:\n    tag: \"7.2\"\n", string(files[0].Original))
+	assert.Contains(t, string(files[0].Content), "  repository: harbor.local/dockerio/library/nginx # web server\n")
+	assert.Contains(t, string(files[0].Content), "postgresql:\n", "a packaged subchart's overrides stay in the parent")
+	assert.NotContains(t, string(files[0].Content), "bitnami/redis")
+
+	assert.Equal(t, filepath.Join(chartDir, "charts", "redis", "values.yaml"), files[1].Path)
+	assert.Equal(t, "image:\n  registry: harbor.local\n  repository: dockerio/bitnami/redis\n  tag: \"7.0\"\n", string(files[1].Content),
+		"the tag the parent sets stays in the parent")
+
+	diff, err := diffRewrite(chartDir, files)
+	require.NoError(t, err)
+	assert.Contains(t, diff, "--- a/charts/redis/values.yaml\n+++ b/charts/redis/values.yaml\n")
+	assert.Contains(t, diff, "-  registry: docker.io\n-  repository: bitnami/redis\n+  registry: harbor.local\n")
+
+	require.NoError(t, writeRewriteFiles(files))
+	written, err := afero.ReadFile(AppFs, files[1].Path)
+	require.NoError(t, err)
+	assert.Equal(t, files[1].Content, written)
+
+	files, err = planValuesRewrite(chartDir, parent, map[string]interface{}{
+		"cache": map[string]interface{}{"image": map[string]interface{}{"registry": "harbor.local"}},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, files, "values that already match are not rewritten")
+}
+
+func TestBumpChartVersion(t *testing.T) {
+	chartYAML := "apiVersion: v2\nname: platform\n\n# Bumped on every release\nversion: 1.4.2 # chart version\nappVersion: \"2.0\"\n"
+
+	for _, tt := range []struct {
+		bump, version, expected string
+	}{
+		{bumpVersionPatch, "1.4.2", "1.4.3"},
+		{bumpVersionMinor, "1.4.2", "1.5.0"},
+		{bumpVersionMajor, "1.4.2", "2.0.0"},
+		{bumpVersionPatch, "v1.4.2", "v1.4.3"},
+		{bumpVersionPatch, "1.4.3-rc.1", "1.4.3"},
+	} {
+		t.Run(tt.bump+" "+tt.version, func(t *testing.T) {
+			data := []byte(replaceChartVersion(chartYAML, tt.version))
+			content, oldVersion, newVersion, err := bumpChartVersion(data, tt.bump)
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, oldVersion)
+			assert.Equal(t, tt.expected, newVersion)
+			assert.Equal(t, replaceChartVersion(chartYAML, tt.expected), string(content))
+		})
+	}
+
+	_, _, _, err := bumpChartVersion([]byte("name: platform\nversion: latest\n"), bumpVersionPatch)
+	assert.ErrorContains(t, err, "not a semantic version")
+}
+
+// replaceChartVersion returns the test Chart.yaml with the version 1.4.2 replaced
+func replaceChartVersion(chartYAML, version string) string {
+	return strings.Replace(chartYAML, "1.4.2", version, 1)
+}
@@ -3,15 +3,21 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	log "github.com/lucas-albers-lz4/irr/pkg/log"
 
+	"github.com/lucas-albers-lz4/irr/internal/helm"
 	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
 	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/lucas-albers-lz4/irr/pkg/progress"
 	"github.com/lucas-albers-lz4/irr/pkg/registry"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
@@ -29,12 +35,46 @@ var (
 	cfgFile      string
 	debugEnabled bool
 	logLevel     string
+	logFormat    string
+	logFilePath  string
+
+	// Retry settings for Helm SDK calls (release listing and lookups)
+	helmRetries      int
+	helmRetryBackoff time.Duration
+
+	// logFile is the open handle for --log-file, closed by Execute after the command finishes
+	logFile *os.File
 	// Previous analyze command flags (now integrated with inspect)
 	// outputFormat string
 
 	// Output and mode flags
 	registryFile string
 
+	// registryProfile selects a named profile from the registry mappings file
+	registryProfile string
+
+	// defaultRegistry is the registry unqualified images resolve to, when not docker.io
+	defaultRegistry string
+
+	// noProgress disables the progress line of long operations
+	noProgress bool
+
+	// maxValuesSize, maxValuesDepth and maxImagePatterns bound the values analyzed for each chart;
+	// unset values keep the default limits
+	maxValuesSize    string
+	maxValuesDepth   int
+	maxImagePatterns int
+
+	// caFile and insecureSkipTLSVerify configure certificate verification of network requests
+	caFile                string
+	insecureSkipTLSVerify bool
+
+	// kubeContext, kubeConfig and kubeInsecureSkipTLSVerify select the cluster that Helm and the
+	// Kubernetes client talk to, like Helm's global flags of the same names
+	kubeContext               string
+	kubeConfig                string
+	kubeInsecureSkipTLSVerify bool
+
 	// IntegrationTestMode controls behavior specific to integration tests
 	integrationTestMode bool
 
@@ -128,7 +168,7 @@ that redirect container image references from public registries to a private reg
 It can analyze Helm charts to identify image references and generate override values 
 files compatible with Helm, pointing images to a new registry according to specified strategies.
 It also supports linting image references for potential issues.`,
-	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		// --- Determine Final Log Level Based on Precedence --- START ---
 		logLevelFlagStr := logLevel              // Value from --log-level flag
 		debugFlagEnabled := debugEnabled         // Value from --debug flag
@@ -148,6 +188,10 @@ It also supports linting image references for potential issues.`,
 		if debugFlagEnabled {
 			finalLevel = log.LevelDebug
 			levelSource = "--debug flag"
+		} else if quietMode && !cmd.Flags().Changed("log-level") {
+			// --quiet logs errors only, whatever LOG_LEVEL says
+			finalLevel = log.LevelError
+			levelSource = "--quiet flag"
 		} else {
 			// 2. --log-level flag is next, ONLY if it was explicitly set
 			if cmd.Flags().Changed("log-level") && logLevelFlagStr != "" { // Check cmd.Flags().Changed()
@@ -198,6 +242,49 @@ It also supports linting image references for potential issues.`,
 		log.Debug("Effective log level set", "level", finalLevel.String(), "source", levelSource)
 		// --- Apply Final Log Level --- END ---
 
+		// --- Apply Log Format and Destination ---
+		if err := configureLogOutput(cmd); err != nil {
+			return err
+		}
+
+		// --- Quiet and JSON-Only Log Modes ---
+		if err := applyOutputModes(cmd); err != nil {
+			return err
+		}
+
+		// --- Timing Profile, Runtime Profiling and Metrics ---
+		if err := startProfiling(cmd); err != nil {
+			return err
+		}
+		startMetrics()
+		startAudit(cmd, args)
+
+		// --- Default Registry for Unqualified Images ---
+		if err := applyDefaultRegistryFlag(); err != nil {
+			return err
+		}
+
+		// --- Size and Complexity Limits of Analyzed Values ---
+		if err := applyLimitFlags(); err != nil {
+			return err
+		}
+
+		// --- Encrypted Values Files (helm-secrets) ---
+		if err := resolveSecretValuesFlag(cmd); err != nil {
+			return err
+		}
+
+		// --- Charts of Helm and Git Repositories (--chart-ref) ---
+		if err := resolveChartRefFlag(cmd); err != nil {
+			return err
+		}
+
+		// --- Cluster Selection ---
+		helm.SetKubeOptions(helm.KubeOptions{Context: kubeContext, Config: kubeConfig, InsecureSkipTLSVerify: kubeInsecureSkipTLSVerify})
+
+		// --- Progress Output of Long Operations ---
+		progress.SetEnabled(!noProgress)
+
 		// --- Remaining PreRun Setup ---
 
 		// Integration test mode warning (still useful to know it's active)
@@ -258,15 +345,79 @@ It also supports linting image references for potential issues.`,
 	},
 }
 
+// configureLogOutput applies the --log-format and --log-file flags and scopes
+// subsequent log records to the command being run.
+func configureLogOutput(cmd *cobra.Command) error {
+	if logFormat != "" {
+		if err := log.SetFormat(logFormat); err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  fmt.Errorf("invalid --log-format: %w", err),
+			}
+		}
+	}
+
+	if logFilePath != "" && logFile == nil {
+		file, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, fileutil.ReadWriteUserReadOthers)
+		if err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to open log file %s: %w", logFilePath, err),
+			}
+		}
+		logFile = file
+		log.SetOutput(logFile)
+	}
+
+	log.AddFields("command", cmd.Name())
+	return nil
+}
+
+// closeLogFile restores stderr logging and closes the --log-file handle, if one was opened.
+func closeLogFile() {
+	if logFile == nil {
+		return
+	}
+	log.SetOutput(os.Stderr)
+	if err := logFile.Close(); err != nil {
+		log.Warn("Failed to close log file", "path", logFilePath, "error", err)
+	}
+	logFile = nil
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() error {
-	if err := rootCmd.Execute(); err != nil {
+	defer closeLogFile()
+	defer finishProfiling(time.Now())
+	defer finishMetrics()
+	defer removeSecretValues()
+	defer removeGitCharts()
+	defer restoreStandardStreams()
+	ctx, stop := interruptContext(context.Background())
+	defer stop()
+	err := rootCmd.ExecuteContext(ctx)
+	finishAudit(err)
+	if err != nil {
+		logCommandError(err)
 		return fmt.Errorf("execute command: %w", err)
 	}
 	return nil
 }
 
+// logCommandError logs the error that failed the command with its stable error code, category and
+// exit code, so that automation reading JSON logs (--log-format json) can branch on the failure.
+func logCommandError(err error) {
+	info := exitcodes.Classify(err)
+	log.Error("Command failed", "error", info.Message, "errorCode", info.ErrorCode, "category", info.Category, "exitCode", info.ExitCode)
+}
+
+// flagError reports invalid command line flags, such as unknown flags or values of the wrong type,
+// as configuration errors.
+func flagError(_ *cobra.Command, err error) error {
+	return &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+}
+
 // init sets up the root command and its flags.
 func init() {
 	cobra.OnInitialize()
@@ -275,6 +426,28 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.irr.yaml)")
 	rootCmd.PersistentFlags().BoolVar(&debugEnabled, "debug", false, "enable debug logging")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "set log level (debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "log output format (json or text); overrides the LOG_FORMAT environment variable")
+	rootCmd.PersistentFlags().StringVar(&logFilePath, "log-file", "", "write logs to this file (appending) instead of stderr")
+	rootCmd.PersistentFlags().BoolVar(&quietMode, "quiet", false, "log errors only and keep stdout for the command's document: anything else written to stdout is logged instead")
+	rootCmd.PersistentFlags().BoolVar(&jsonLogsOnly, "json-logs-only", false, "write only JSON log records to stderr and only the command's document to stdout, logging anything else written to either")
+	rootCmd.PersistentFlags().IntVar(&helmRetries, "helm-retries", helm.DefaultMaxRetries, "number of times to retry Helm API calls that fail with a transient error (0 disables retries)")
+	rootCmd.PersistentFlags().DurationVar(&helmRetryBackoff, "helm-retry-backoff", helm.DefaultInitialBackoff, "delay before the first Helm API retry; doubles after each retry")
+	rootCmd.PersistentFlags().StringVar(&registryProfile, "profile", "", "named profile from the registry mappings file to apply (e.g. prod, staging)")
+	rootCmd.PersistentFlags().StringVar(&defaultRegistry, "default-registry", "", "registry that unqualified images (e.g. nginx:1.25) resolve to in the cluster, if not docker.io; overrides defaultRegistry in the registry mappings file")
+	rootCmd.PersistentFlags().StringVar(&profileOutput, "profile-output", "", "write a JSON timing profile of the run (chart load, analysis, generation and validation times) to this file")
+	rootCmd.PersistentFlags().StringVar(&metricsFile, "metrics-file", "", "write Prometheus metrics of the run (images analyzed, overrides generated, unmapped registries, validation failures and stage durations) to this file")
+	rootCmd.PersistentFlags().StringVar(&auditLogPath, "audit-log", "", "append a JSON line recording each override and validate run (user, chart, release, digests of the registry, values and output files, exit code) to this file; also set by IRR_AUDIT_LOG (disabled by default)")
+	rootCmd.PersistentFlags().BoolVar(&offlineMode, "offline", false, "never access the network: no chart downloads, registry queries or cluster access; operations that need it fail immediately (also enabled by IRR_OFFLINE=true)")
+	rootCmd.PersistentFlags().BoolVar(&noCredentialHelpers, "no-credential-helpers", false, "do not run docker credential helpers or cloud CLIs (aws, gcloud, az) to get registry credentials for --probe-targets and --check-tags")
+	rootCmd.PersistentFlags().StringVar(&caFile, "ca-file", "", "verify TLS certificates of registries and chart repositories with this CA bundle, in addition to the system certificate authorities")
+	rootCmd.PersistentFlags().BoolVar(&insecureSkipTLSVerify, "insecure-skip-tls-verify", false, "skip TLS certificate verification of registries and chart repositories")
+	rootCmd.PersistentFlags().StringVar(&kubeContext, "kube-context", "", "name of the kubeconfig context to use for cluster access (default: the current context, or the one helm was invoked with in plugin mode)")
+	rootCmd.PersistentFlags().StringVar(&kubeConfig, "kubeconfig", "", "path to the kubeconfig file used for cluster access (default: KUBECONFIG or ~/.kube/config)")
+	rootCmd.PersistentFlags().BoolVar(&kubeInsecureSkipTLSVerify, "kube-insecure-skip-tls-verify", false, "skip verification of the Kubernetes API server's certificate (insecure)")
+	rootCmd.PersistentFlags().BoolVar(&noProgress, "no-progress", false, "do not show a progress line on stderr during long operations (it is only shown on an interactive terminal, and never when CI or TERM=dumb is set)")
+	rootCmd.PersistentFlags().StringVar(&maxValuesSize, "max-values-size", "", "maximum size of a values document (a chart's values.yaml or a --values file), in bytes or with a Ki, Mi or Gi suffix (default 256Mi); overrides limits.maxValuesSize in the registry mappings file")
+	rootCmd.PersistentFlags().IntVar(&maxValuesDepth, "max-values-depth", 0, "maximum nesting depth of maps and lists in analyzed values (default 100); overrides limits.maxDepth in the registry mappings file")
+	rootCmd.PersistentFlags().IntVar(&maxImagePatterns, "max-image-patterns", 0, "maximum number of image patterns detected in a chart (default 10000); overrides limits.maxImagePatterns in the registry mappings file")
 	rootCmd.PersistentFlags().BoolVar(&integrationTestMode, "integration-test", false, "enable integration test mode")
 	// For testing purposes
 	rootCmd.PersistentFlags().BoolVar(&TestAnalyzeMode, "test-analyze", false, "enable test mode (originally for analyze command, now for inspect)")
@@ -292,11 +465,22 @@ func init() {
 	rootCmd.AddCommand(newOverrideCmd())
 	rootCmd.AddCommand(newInspectCmd())
 	rootCmd.AddCommand(newValidateCmd())
+	rootCmd.AddCommand(newVerifyMappingsCmd())
+	rootCmd.AddCommand(newHelmExecCmd())
+	rootCmd.AddCommand(newTestCmd())
+	rootCmd.AddCommand(newRewriteCmd())
+	rootCmd.AddCommand(newRunCmd())
+	rootCmd.AddCommand(newHelmfileCmd())
+	rootCmd.AddCommand(newCacheCmd())
+	rootCmd.AddCommand(newSchemaCmd())
 
 	// Add release-name and namespace flags to root command for all modes
 	addReleaseFlag(rootCmd)
 	addNamespaceFlag(rootCmd)
 
+	// Register dynamic shell completion for flags and arguments
+	registerCompletions(rootCmd)
+
 	// Find and read the config file
 	if cfgFile != "" {
 		// Use config file from the flag.
@@ -329,6 +513,7 @@ func init() {
 
 	// Add build version info
 	rootCmd.Version = BinaryVersion
+	rootCmd.SetFlagErrorFunc(flagError)
 
 	viper.SetDefault("logLevel", "info")
 }
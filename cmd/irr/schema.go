@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/lucas-albers-lz4/irr/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+// documentSchema describes a document irr reads or writes whose JSON Schema 'irr schema' prints
+type documentSchema struct {
+	// value is a zero value of the type the document is written from or read into
+	value interface{}
+	// description annotates the schema
+	description string
+	// written marks documents irr writes, whose fields without omitempty are always present
+	written bool
+	// yamlOnly marks documents that are only read or written as YAML
+	yamlOnly bool
+}
+
+// documentSchemas are the documents 'irr schema' describes, by name
+var documentSchemas = map[string]documentSchema{
+	"inspect": {
+		value:       ImageAnalysis{},
+		description: "Output of irr inspect for a chart or release (--output-format yaml or json)",
+		written:     true,
+	},
+	"override-report": {
+		value:       PartialOverridesReport{},
+		description: "Report of the values paths skipped by irr override --ignore-errors, written by --error-report",
+		written:     true,
+	},
+	"registry-config": {
+		value:       registry.Config{},
+		description: "Registry mappings file read by --registry-file",
+		yamlOnly:    true,
+	},
+}
+
+// newSchemaCmd creates the schema command
+func newSchemaCmd() *cobra.Command {
+	names := slices.Sorted(maps.Keys(documentSchemas))
+	cmd := &cobra.Command{
+		Use:   "schema " + strings.Join(names, "|"),
+		Short: "Print the JSON Schema of an output format or configuration file",
+		Long: `Print the JSON Schema (draft 2020-12) of a document irr writes or reads, so that tools
+consuming irr output or generating its configuration can validate against it:
+
+  inspect          the analysis written by 'irr inspect'
+  override-report  the error report written by 'irr override --ignore-errors --error-report'
+  registry-config  the registry mappings file read with --registry-file
+
+Schemas are generated from the types irr itself uses, so they match this version of irr
+exactly. YAML and JSON output name some fields differently; select the serialization the
+schema describes with --output-format (YAML documents are validated as their JSON equivalent).`,
+		Example: `  irr schema inspect --output-format json > inspect.schema.json
+  irr schema registry-config --output-file registry-mappings.schema.json`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: names,
+		RunE:      runSchema,
+	}
+	cmd.Flags().String("output-format", outputFormatYAML, "Serialization the schema describes: yaml or json (registry-config is always yaml)")
+	cmd.Flags().StringP("output-file", "o", "", "Write the schema to a file instead of stdout")
+	return cmd
+}
+
+// runSchema implements the schema command
+func runSchema(cmd *cobra.Command, args []string) error {
+	outputFormat, err := getStringFlag(cmd, "output-format")
+	if err != nil {
+		return err
+	}
+	outputFile, err := getStringFlag(cmd, "output-file")
+	if err != nil {
+		return err
+	}
+	data, err := documentSchemaJSON(args[0], strings.ToLower(outputFormat))
+	if err != nil {
+		return err
+	}
+
+	if outputFile != "" {
+		return writeOutputFile(outputFile, data, "Schema written")
+	}
+	if _, err := cmd.OutOrStdout().Write(data); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to write schema: %w", err),
+		}
+	}
+	return nil
+}
+
+// documentSchemaJSON returns the JSON Schema of the named document in outputFormat (yaml or json)
+func documentSchemaJSON(name, outputFormat string) ([]byte, error) {
+	document, ok := documentSchemas[name]
+	if !ok {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("unknown schema %q (available: %s)", name, strings.Join(slices.Sorted(maps.Keys(documentSchemas)), ", ")),
+		}
+	}
+	if outputFormat != outputFormatYAML && outputFormat != outputFormatJSON {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("unsupported --output-format %q: must be yaml or json", outputFormat),
+		}
+	}
+	encoding := schema.Encoding(outputFormat)
+	if document.yamlOnly {
+		encoding = schema.YAML
+	}
+
+	data, err := schema.Generate(document.value, schema.Options{
+		Title:         "irr " + name,
+		Description:   document.description,
+		Comment:       fmt.Sprintf("Generated by irr %s (%s)", BinaryVersion, encoding),
+		Encoding:      encoding,
+		RequireFields: document.written,
+	}).JSON()
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitGeneralRuntimeError, Err: err}
+	}
+	return data, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// validateAgainstSchema validates document, unmarshaled with unmarshal, against the schema irr
+// prints for name in outputFormat
+func validateAgainstSchema(t *testing.T, name, outputFormat string, document []byte, unmarshal func([]byte, interface{}) error) error {
+	t.Helper()
+	schemaJSON, err := documentSchemaJSON(name, outputFormat)
+	require.NoError(t, err)
+	parsed, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaJSON))
+	require.NoError(t, err)
+	compiler := jsonschema.NewCompiler()
+	require.NoError(t, compiler.AddResource("file:///"+name+".schema.json", parsed))
+	validator, err := compiler.Compile("file:///" + name + ".schema.json")
+	require.NoError(t, err)
+
+	var instance interface{}
+	require.NoError(t, unmarshal(document, &instance))
+	instanceJSON, err := json.Marshal(instance)
+	require.NoError(t, err)
+	instance, err = jsonschema.UnmarshalJSON(bytes.NewReader(instanceJSON))
+	require.NoError(t, err)
+	return validator.Validate(instance)
+}
+
+func TestSchemaInspect(t *testing.T) {
+	result := &ImageAnalysis{
+		Chart:  ChartInfo{Name: "web", Version: "1.0.0"},
+		Images: []ImageInfo{{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25", Source: "image"}},
+		ImagePatterns: []analysis.ImagePattern{{
+			Path: "image", Type: analysis.PatternTypeMap, Count: 1,
+			Structure: map[string]interface{}{"registry": "docker.io", "repository": "library/nginx"},
+		}},
+		Duplicates: []DuplicateImage{{Image: "docker.io/library/nginx:1.25", Count: 2, Paths: []string{"a.image", "b.image"}}},
+	}
+
+	jsonOutput, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.NoError(t, validateAgainstSchema(t, "inspect", outputFormatJSON, jsonOutput, json.Unmarshal))
+	yamlOutput, err := yaml.Marshal(result)
+	require.NoError(t, err)
+	assert.NoError(t, validateAgainstSchema(t, "inspect", outputFormatYAML, yamlOutput, yaml.Unmarshal))
+
+	assert.Error(t, validateAgainstSchema(t, "inspect", outputFormatJSON, yamlOutput, yaml.Unmarshal),
+		"image patterns are named differently in YAML and JSON")
+	assert.Error(t, validateAgainstSchema(t, "inspect", outputFormatYAML, []byte("chart: {name: web}\nimages: []\n"), yaml.Unmarshal),
+		"fields irr always writes are required")
+}
+
+func TestSchemaOverrideReport(t *testing.T) {
+	report := PartialOverridesReport{ChartPath: "./web", Errors: []PathError{{Path: "image", Error: "invalid reference"}}}
+	output, err := yaml.Marshal(report)
+	require.NoError(t, err)
+	assert.NoError(t, validateAgainstSchema(t, "override-report", outputFormatYAML, output, yaml.Unmarshal))
+}
+
+func TestSchemaRegistryConfig(t *testing.T) {
+	config := []byte(`version: "1"
+registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/dockerhub
+      enabled: true
+  defaultTarget: harbor.local/default
+tls:
+  harbor.local:
+    caFile: certs/harbor.pem
+`)
+	assert.NoError(t, validateAgainstSchema(t, "registry-config", outputFormatJSON, config, yaml.Unmarshal),
+		"the registry config is always described as YAML")
+	assert.Error(t, validateAgainstSchema(t, "registry-config", outputFormatYAML, []byte("registries:\n  mapings: []\n"), yaml.Unmarshal),
+		"misspelled keys are rejected")
+}
+
+func TestSchemaCmd(t *testing.T) {
+	cmd := newSchemaCmd()
+	out := &bytes.Buffer{}
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{"override-report", "--output-format", "json"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), `"$schema": "https://json-schema.org/draft/2020-12/schema"`)
+	assert.Contains(t, out.String(), `"chartPath"`)
+
+	_, err := documentSchemaJSON("values", outputFormatYAML)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	assert.Contains(t, err.Error(), "available: inspect, override-report, registry-config")
+
+	_, err = documentSchemaJSON("inspect", "toml")
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+}
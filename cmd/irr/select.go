@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/selector"
+	"github.com/spf13/cobra"
+)
+
+// selectFlagUsage is the help text of --select, shared by override and inspect
+const selectFlagUsage = "Only include values matching these selectors: subchart=NAME, path=GLOB or release=NAME (repeatable; selectors are OR-ed)"
+
+// getSelectors parses the --select flag.
+func getSelectors(cmd *cobra.Command) (selector.Set, error) {
+	exprs, err := getStringSliceFlag(cmd, "select")
+	if err != nil {
+		return nil, err
+	}
+	selectors, err := selector.ParseAll(exprs)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	return selectors, nil
+}
+
+// selectImagePatterns returns the patterns selected by selectors for the release (empty for a chart).
+func selectImagePatterns(patterns []analysis.ImagePattern, selectors selector.Set, releaseName string) []analysis.ImagePattern {
+	if len(selectors) == 0 {
+		return patterns
+	}
+	selected := make([]analysis.ImagePattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		if selectors.Matches(releaseName, pattern.Path) {
+			selected = append(selected, pattern)
+		}
+	}
+	log.Info("Selected image patterns", "selectors", selectors.String(), "selected", len(selected), "total", len(patterns))
+	return selected
+}
+
+// applyInspectSelectors filters the images and patterns of an inspect analysis by --select.
+func applyInspectSelectors(flags *InspectFlags, analysisResult *ImageAnalysis, releaseName string) {
+	if len(flags.Selectors) == 0 {
+		return
+	}
+	selected := make([]ImageInfo, 0, len(analysisResult.Images))
+	for _, img := range analysisResult.Images {
+		if flags.Selectors.Matches(releaseName, imageInfoPath(img)) {
+			selected = append(selected, img)
+		}
+	}
+	analysisResult.Images = selected
+	analysisResult.ImagePatterns = selectImagePatterns(analysisResult.ImagePatterns, flags.Selectors, releaseName)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/selector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSelectors(t *testing.T) {
+	cmd := newOverrideCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--select", "subchart=postgresql", "--select", "path=ingress.*"}))
+	selectors, err := getSelectors(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, selector.Set{
+		{Kind: selector.KindSubchart, Pattern: "postgresql"},
+		{Kind: selector.KindPath, Pattern: "ingress.*"},
+	}, selectors)
+
+	cmd = newInspectCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--select", "chart=postgresql"}))
+	_, err = getSelectors(cmd)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+}
+
+func TestSelectImagePatterns(t *testing.T) {
+	patterns := []analysis.ImagePattern{
+		{Path: "image", Type: analysis.PatternTypeString, Value: "nginx:1.25"},
+		{Path: "postgresql.image", Type: analysis.PatternTypeString, Value: "bitnami/postgresql:16"},
+		{Path: "ingress.controller.image", Type: analysis.PatternTypeString, Value: "registry.k8s.io/ingress-nginx/controller:v1.10.0"},
+	}
+	selectors := selector.Set{
+		{Kind: selector.KindSubchart, Pattern: "postgresql"},
+		{Kind: selector.KindPath, Pattern: "ingress.*"},
+	}
+
+	selected := selectImagePatterns(patterns, selectors, "")
+	require.Len(t, selected, 2)
+	assert.Equal(t, "postgresql.image", selected[0].Path)
+	assert.Equal(t, "ingress.controller.image", selected[1].Path)
+
+	assert.Equal(t, patterns, selectImagePatterns(patterns, nil, ""))
+	assert.Len(t, selectImagePatterns(patterns, selector.Set{{Kind: selector.KindRelease, Pattern: "web"}}, "web"), 3)
+}
+
+func TestApplyInspectSelectors(t *testing.T) {
+	analysisResult := &ImageAnalysis{
+		Images: []ImageInfo{
+			{Registry: "docker.io", Repository: "library/nginx", ValuePath: "image"},
+			{Registry: "docker.io", Repository: "bitnami/postgresql", ValuePath: "postgresql.image"},
+			{Registry: "docker.io", Repository: "bitnami/redis", Source: "redis.image"},
+		},
+		ImagePatterns: []analysis.ImagePattern{
+			{Path: "image"},
+			{Path: "postgresql.image"},
+			{Path: "redis.image"},
+		},
+	}
+	flags := &InspectFlags{Selectors: selector.Set{
+		{Kind: selector.KindSubchart, Pattern: "postgresql"},
+		{Kind: selector.KindPath, Pattern: "redis.*"},
+	}}
+
+	applyInspectSelectors(flags, analysisResult, "")
+
+	require.Len(t, analysisResult.Images, 2)
+	assert.Equal(t, "bitnami/postgresql", analysisResult.Images[0].Repository)
+	assert.Equal(t, "bitnami/redis", analysisResult.Images[1].Repository)
+	assert.Len(t, analysisResult.ImagePatterns, 2)
+}
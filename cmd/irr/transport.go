@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/cobra"
+)
+
+// transportOptions returns the TLS settings of network requests: --ca-file and
+// --insecure-skip-tls-verify for every host, and registryTLS, the tls section of the registry
+// mappings file, for individual registries.
+func transportOptions(registryTLS map[string]registry.TLSConfig) registry.TransportOptions {
+	return registry.TransportOptions{
+		Default:    registry.TLSConfig{CAFile: caFile, InsecureSkipTLSVerify: insecureSkipTLSVerify},
+		Registries: registryTLS,
+	}
+}
+
+// newNetworkTransport creates the transport shared by registry probes, tag checks, chart pulls and
+// uploads. It honors HTTPS_PROXY, HTTP_PROXY and NO_PROXY and the TLS settings of transportOptions.
+func newNetworkTransport(registryTLS map[string]registry.TLSConfig) (http.RoundTripper, error) {
+	opts := transportOptions(registryTLS)
+	transport, err := registry.NewTransport(AppFs, opts)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{Code: exitcodes.ExitInputConfigurationError, Err: err}
+	}
+	if opts.Default.InsecureSkipTLSVerify {
+		log.Warn("TLS certificate verification is disabled for all hosts (--insecure-skip-tls-verify)")
+	}
+	return transport, nil
+}
+
+// registryFilesTLS returns the tls section of the registry mappings files given with
+// --registry-file, or nil if cmd has no such files.
+func registryFilesTLS(cmd *cobra.Command) (map[string]registry.TLSConfig, error) {
+	if cmd.Flags().Lookup("registry-file") == nil {
+		return nil, nil
+	}
+	registryFiles, err := getStringSliceFlag(cmd, "registry-file")
+	if err != nil || len(registryFiles) == 0 {
+		return nil, err
+	}
+	skipCWDRestriction := integrationTestMode || (os.Getenv("IRR_TESTING") == trueString)
+	config, _, err := loadRegistryConfigs(registryFiles, skipCWDRestriction)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to load registry mappings from file %s: %w", strings.Join(registryFiles, ", "), err),
+		}
+	}
+	if config == nil {
+		return nil, nil
+	}
+	return config.TLS, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryFilesTLS(t *testing.T) {
+	t.Setenv("IRR_TESTING", trueString)
+	mappingsFile := filepath.Join(t.TempDir(), "registry-mappings.yaml")
+	require.NoError(t, os.WriteFile(mappingsFile, []byte(`registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+tls:
+  harbor.local:
+    caFile: certs/harbor-ca.pem
+`), 0o600))
+
+	cmd := newInspectCmd()
+	registryTLS, err := registryFilesTLS(cmd)
+	require.NoError(t, err)
+	assert.Nil(t, registryTLS, "no mappings file, no registry settings")
+
+	require.NoError(t, cmd.ParseFlags([]string{"--registry-file", mappingsFile}))
+	registryTLS, err = registryFilesTLS(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]registry.TLSConfig{"harbor.local": {CAFile: "certs/harbor-ca.pem"}}, registryTLS)
+
+	_, err = newNetworkTransport(registryTLS)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr, "CA files are read when the transport is created")
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	assert.Contains(t, err.Error(), "invalid TLS settings for registry harbor.local")
+}
@@ -2,16 +2,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/lucas-albers-lz4/irr/internal/helm"
 	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
 	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/metrics"
+	"github.com/lucas-albers-lz4/irr/pkg/timing"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/cli"
 )
@@ -19,6 +26,25 @@ import (
 // DefaultKubernetesVersion defines the default K8s version used for validation
 const DefaultKubernetesVersion = "1.31.0"
 
+// kubeVersionPattern matches Kubernetes versions accepted by --kube-versions (e.g. 1.29, v1.29.3)
+var kubeVersionPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)(\.\d+)?$`)
+
+// KubeVersionResult records the validation outcome for a single Kubernetes version
+type KubeVersionResult struct {
+	KubeVersion string             `json:"kubeVersion" yaml:"kubeVersion"`
+	Success     bool               `json:"success" yaml:"success"`
+	Error       string             `json:"error,omitempty" yaml:"error,omitempty"`
+	ErrorCode   string             `json:"errorCode,omitempty" yaml:"errorCode,omitempty"`
+	Category    exitcodes.Category `json:"category,omitempty" yaml:"category,omitempty"`
+}
+
+// KubeVersionMatrixReport aggregates validation results across multiple Kubernetes versions
+type KubeVersionMatrixReport struct {
+	Chart          string              `json:"chart" yaml:"chart"`
+	Results        []KubeVersionResult `json:"results" yaml:"results"`
+	FailedVersions []string            `json:"failedVersions,omitempty" yaml:"failedVersions,omitempty"`
+}
+
 // Variables for testing, not used in production code
 var (
 	// isValidateTestMode is used to bypass actual validation in tests
@@ -37,6 +63,15 @@ The validation can operate on either:
 - A local chart directory or tarball file (using --chart-path)
 - An installed Helm release (when running as a Helm plugin with [release-name])
 
+With --live kind, the rendered chart is also installed into a kind cluster (created or reused)
+whose nodes pull the relocated images through a local registry, and the release must roll out.
+This requires kind, docker and helm on PATH.
+
+With --compare, the chart is also rendered without the overrides file (the last --values file),
+and the images of every workload container in the two renders are printed as a table. The command
+fails when an image was not rewritten: with --source-registries, an image still rendered from one
+of them; without, an image the overrides leave unchanged.
+
 IMPORTANT NOTES:
 - This command can run without a config file, but image redirection correctness depends on your configuration
 - Use 'irr inspect' to identify registries in your chart and 'irr config' to configure mappings
@@ -53,10 +88,17 @@ IMPORTANT NOTES:
 	}
 
 	cmd.Flags().StringSliceP("values", "f", []string{}, "Values files to use (can specify multiple)")
+	addDecryptFlags(cmd)
 	cmd.Flags().StringP("namespace", "n", "default", "Namespace to use")
 	cmd.Flags().StringP("output-file", "o", "", "Write rendering output to file instead of discarding")
 	cmd.Flags().Bool("strict", false, "Fail on any warning, not just errors")
 	cmd.Flags().String("kube-version", "", "Kubernetes version to use for validation (defaults to current client version)")
+	cmd.Flags().StringSlice("kube-versions", nil, "Validate against multiple Kubernetes versions, as a list (1.27,1.28,1.29) or a minor range (1.27-1.29); conflicts with --kube-version")
+	addCapabilityFlags(cmd)
+	cmd.Flags().Bool("against-cluster", false, "Plugin mode: run a server-side dry-run (helm upgrade --dry-run=server) against the current kube context so admission policies are evaluated")
+	addLiveFlags(cmd)
+	cmd.Flags().Bool("compare", false, "Render the chart with and without the overrides file (the last --values file) and report the image of every container, failing when one was not rewritten")
+	cmd.Flags().StringSliceP("source-registries", "s", nil, "With --compare, source registries whose images must all be rewritten (default: every image must change)")
 
 	return cmd
 }
@@ -76,10 +118,19 @@ func runValidate(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return err
 		}
+		if releaseName != "" {
+			if err := requireNetwork(fmt.Sprintf("validating release %s", releaseName)); err != nil {
+				return err
+			}
+			log.AddFields("release", releaseName, "namespace", namespace)
+		}
 		return handlePluginValidate(cmd, releaseName, namespace)
 	}
 
 	// Handle standalone mode
+	if chartPath != "" {
+		log.AddFields("chart", chartPath)
+	}
 	return handleStandaloneValidate(cmd, chartPath, valuesFiles)
 }
 
@@ -179,8 +230,73 @@ func validateAndDetectChartPath(chartPath string) (string, error) {
 	return absPath, nil
 }
 
+// CapabilityOptions controls the .Capabilities.APIVersions exposed to templates during rendering
+type CapabilityOptions struct {
+	// APIVersions are extra API versions to advertise (e.g. monitoring.coreos.com/v1)
+	APIVersions []string
+	// FromCluster discovers the API versions served by the current kube context
+	FromCluster bool
+}
+
+// addCapabilityFlags registers the --api-versions and --set-capabilities-from-cluster flags
+func addCapabilityFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("api-versions", nil, "Kubernetes API versions used for .Capabilities.APIVersions during rendering (can specify multiple)")
+	cmd.Flags().Bool("set-capabilities-from-cluster", false, "Discover .Capabilities.APIVersions from the current kube context during rendering")
+}
+
+// getCapabilityFlags reads the capability flags registered by addCapabilityFlags
+func getCapabilityFlags(cmd *cobra.Command) (*CapabilityOptions, error) {
+	apiVersions, err := cmd.Flags().GetStringArray("api-versions")
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get api-versions flag: %w", err),
+		}
+	}
+
+	fromCluster, err := cmd.Flags().GetBool("set-capabilities-from-cluster")
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get set-capabilities-from-cluster flag: %w", err),
+		}
+	}
+	if fromCluster {
+		if err := requireNetwork("--set-capabilities-from-cluster"); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, apiVersion := range apiVersions {
+		if strings.TrimSpace(apiVersion) == "" {
+			return nil, &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  errors.New("--api-versions must not contain empty values"),
+			}
+		}
+	}
+
+	return &CapabilityOptions{APIVersions: apiVersions, FromCluster: fromCluster}, nil
+}
+
 // validateChartWithFiles validates a chart with values files
-func validateChartWithFiles(chartPath, releaseName, namespace string, valuesFiles []string, strict bool, kubeVersion string) (string, error) {
+func validateChartWithFiles(ctx context.Context, chartPath, releaseName, namespace string, valuesFiles []string, strict bool, kubeVersion string) (string, error) {
+	return validateChartWithCapabilities(ctx, chartPath, releaseName, namespace, valuesFiles, strict, kubeVersion, nil)
+}
+
+// validateChartWithCapabilities validates a chart with values files, rendering with the given capabilities
+func validateChartWithCapabilities(ctx context.Context, chartPath, releaseName, namespace string, valuesFiles []string, strict bool, kubeVersion string, capabilities *CapabilityOptions) (string, error) {
+	defer timing.Start(timing.SpanValidation, chartPath)()
+
+	output, err := renderChartForValidation(ctx, chartPath, releaseName, namespace, valuesFiles, strict, kubeVersion, capabilities)
+	if err != nil {
+		metrics.Inc(metrics.ValidationFailures)
+	}
+	return output, err
+}
+
+// renderChartForValidation renders a chart with helm template for validateChartWithCapabilities
+func renderChartForValidation(ctx context.Context, chartPath, releaseName, namespace string, valuesFiles []string, strict bool, kubeVersion string, capabilities *CapabilityOptions) (string, error) {
 	// Set default release name if not provided
 	if releaseName == "" {
 		releaseName = "irr-validation"
@@ -195,6 +311,11 @@ func validateChartWithFiles(chartPath, releaseName, namespace string, valuesFile
 		KubeVersion: kubeVersion,
 		Strict:      strict, // Set strict flag in options
 	}
+	if capabilities != nil {
+		templateOptions.APIVersions = capabilities.APIVersions
+		templateOptions.CapabilitiesFromCluster = capabilities.FromCluster
+		log.Debug("Using custom capabilities for validation", "apiVersions", capabilities.APIVersions, "fromCluster", capabilities.FromCluster)
+	}
 
 	// Log namespace if specified
 	if namespace != "" {
@@ -210,7 +331,7 @@ func validateChartWithFiles(chartPath, releaseName, namespace string, valuesFile
 	}
 
 	// Execute Helm template command
-	result, err := helm.HelmTemplateFunc(templateOptions)
+	result, err := helm.HelmTemplateFunc(ctx, templateOptions)
 	if err != nil {
 		log.Error("Validation failed: Chart could not be rendered.")
 		// Print Helm's stderr for debugging
@@ -231,7 +352,7 @@ func validateChartWithFiles(chartPath, releaseName, namespace string, valuesFile
 			if resolvedPath != chartPath {
 				log.Info("Retrying validation with resolved chart path", "path", resolvedPath)
 				templateOptions.ChartPath = resolvedPath
-				retryResult, retryErr := helm.HelmTemplateFunc(templateOptions)
+				retryResult, retryErr := helm.HelmTemplateFunc(ctx, templateOptions)
 				if retryErr == nil {
 					log.Info("Validation successful with resolved chart path!")
 					if retryResult != nil {
@@ -347,6 +468,43 @@ func handlePluginValidate(cmd *cobra.Command, releaseName, namespace string) err
 		}
 	}
 
+	if cmd.Flags().Changed("kube-versions") {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--kube-versions is only supported with --chart-path in standalone mode"),
+		}
+	}
+
+	// The release is rendered by helm, which does not take the capabilities of addCapabilityFlags
+	if cmd.Flags().Changed("api-versions") || cmd.Flags().Changed("set-capabilities-from-cluster") {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--api-versions and --set-capabilities-from-cluster are only supported with --chart-path in standalone mode"),
+		}
+	}
+
+	againstCluster, err := getBoolFlag(cmd, "against-cluster")
+	if err != nil {
+		return err
+	}
+	if againstCluster {
+		return handleAgainstClusterValidate(cmd, releaseName, namespace, valuesFiles)
+	}
+
+	if cmd.Flags().Changed("live") {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--live is only supported with --chart-path in standalone mode"),
+		}
+	}
+
+	if cmd.Flags().Changed("compare") {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--compare is only supported with --chart-path in standalone mode"),
+		}
+	}
+
 	// For testing purposes: if the kubeVersion is "not-a-semver", return an error
 	// even in test mode
 	if strings.Contains(kubeVersionFlag, "not-a-semver") {
@@ -388,6 +546,17 @@ func handleStandaloneValidate(cmd *cobra.Command, chartPath string, valuesFiles
 		return err
 	}
 
+	againstCluster, err := getBoolFlag(cmd, "against-cluster")
+	if err != nil {
+		return err
+	}
+	if againstCluster {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--against-cluster is only supported in Helm plugin mode (helm irr validate RELEASE --against-cluster)"),
+		}
+	}
+
 	// Get release name and namespace
 	releaseName, namespace, err := getValidateReleaseNamespace(cmd, nil)
 	if err != nil {
@@ -408,6 +577,45 @@ func handleStandaloneValidate(cmd *cobra.Command, chartPath string, valuesFiles
 		}
 	}
 
+	kubeVersions, err := getKubeVersionsFlag(cmd, kubeVersionFlag)
+	if err != nil {
+		return err
+	}
+
+	live, err := getLiveFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if live.Mode != "" && len(kubeVersions) > 0 {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--live and --kube-versions cannot be used together"),
+		}
+	}
+
+	compare, err := getBoolFlag(cmd, "compare")
+	if err != nil {
+		return err
+	}
+	if compare && len(kubeVersions) > 0 {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--compare and --kube-versions cannot be used together"),
+		}
+	}
+	sourceRegistries, err := cmd.Flags().GetStringSlice("source-registries")
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get source-registries flag: %w", err),
+		}
+	}
+
+	capabilities, err := getCapabilityFlags(cmd)
+	if err != nil {
+		return err
+	}
+
 	// Determine the final Kubernetes version to use
 	kubeVersionToUse := kubeVersionFlag
 	if kubeVersionToUse == "" {
@@ -452,16 +660,186 @@ func handleStandaloneValidate(cmd *cobra.Command, chartPath string, valuesFiles
 		}
 	}
 
+	// Run one validation per Kubernetes version when --kube-versions is set
+	if len(kubeVersions) > 0 {
+		report, err := validateKubeVersionMatrix(getCommandContext(cmd), chartPath, releaseName, namespace, valuesFiles, strict, kubeVersions, capabilities)
+		if err != nil {
+			return err
+		}
+		return handleKubeVersionMatrixOutput(cmd, report, outputFile)
+	}
+
 	// Run validation with the Kubernetes version
-	templateOutput, err := validateChartWithFiles(chartPath, releaseName, namespace, valuesFiles, strict, kubeVersionToUse)
+	templateOutput, err := validateChartWithCapabilities(getCommandContext(cmd), chartPath, releaseName, namespace, valuesFiles, strict, kubeVersionToUse, capabilities)
 	if err != nil {
 		return err
 	}
 
+	// Compare the images with those rendered without the overrides when --compare is set
+	var comparison *RenderedImageComparison
+	if compare {
+		comparison, err = validateCompare(getCommandContext(cmd), chartPath, releaseName, namespace, valuesFiles, strict, kubeVersionToUse, capabilities, sourceRegistries, templateOutput)
+		if err != nil {
+			return &exitcodes.ExitCodeError{Code: exitcodes.ExitHelmCommandFailed, Err: err}
+		}
+	}
+
+	// Install the rendered chart into a live cluster when --live is set
+	if live.Mode == liveModeKind {
+		if err := validateLiveKind(cmd, live, chartPath, releaseName, namespace, valuesFiles, templateOutput); err != nil {
+			return err
+		}
+	}
+
 	// Handle output
+	if comparison != nil {
+		return handleImageComparisonOutput(cmd, comparison, templateOutput, outputFile)
+	}
 	return handleValidateOutput(cmd, templateOutput, outputFile)
 }
 
+// getKubeVersionsFlag reads and expands the --kube-versions flag.
+// It returns nil when the flag is not set, and an error if it conflicts with --kube-version.
+func getKubeVersionsFlag(cmd *cobra.Command, kubeVersionFlag string) ([]string, error) {
+	kubeVersionsFlag, err := cmd.Flags().GetStringSlice("kube-versions")
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get kube-versions flag: %w", err),
+		}
+	}
+	if len(kubeVersionsFlag) == 0 {
+		return nil, nil
+	}
+	if kubeVersionFlag != "" {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--kube-version and --kube-versions cannot be used together"),
+		}
+	}
+
+	versions, err := parseKubeVersions(kubeVersionsFlag)
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  err,
+		}
+	}
+	return versions, nil
+}
+
+// parseKubeVersions expands a list of Kubernetes versions and minor ranges (e.g. "1.27-1.29")
+// into a de-duplicated, ordered list of full versions (e.g. "1.27.0").
+func parseKubeVersions(specs []string) ([]string, error) {
+	var versions []string
+	seen := make(map[string]bool)
+	add := func(version string) {
+		if !seen[version] {
+			seen[version] = true
+			versions = append(versions, version)
+		}
+	}
+
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		// Minor version range, e.g. 1.27-1.29
+		if start, end, isRange := strings.Cut(spec, "-"); isRange {
+			startMatch := kubeVersionPattern.FindStringSubmatch(strings.TrimSpace(start))
+			endMatch := kubeVersionPattern.FindStringSubmatch(strings.TrimSpace(end))
+			if startMatch == nil || endMatch == nil {
+				return nil, fmt.Errorf("invalid Kubernetes version range %q: expected MAJOR.MINOR-MAJOR.MINOR", spec)
+			}
+			if startMatch[1] != endMatch[1] {
+				return nil, fmt.Errorf("invalid Kubernetes version range %q: major versions must match", spec)
+			}
+			startMinor, startErr := strconv.Atoi(startMatch[2])
+			endMinor, endErr := strconv.Atoi(endMatch[2])
+			if startErr != nil || endErr != nil || startMinor > endMinor {
+				return nil, fmt.Errorf("invalid Kubernetes version range %q: start must not exceed end", spec)
+			}
+			for minor := startMinor; minor <= endMinor; minor++ {
+				add(fmt.Sprintf("%s.%d.0", startMatch[1], minor))
+			}
+			continue
+		}
+
+		match := kubeVersionPattern.FindStringSubmatch(spec)
+		if match == nil {
+			return nil, fmt.Errorf("invalid Kubernetes version %q: expected MAJOR.MINOR[.PATCH]", spec)
+		}
+		patch := match[3]
+		if patch == "" {
+			patch = ".0"
+		}
+		add(fmt.Sprintf("%s.%s%s", match[1], match[2], patch))
+	}
+
+	if len(versions) == 0 {
+		return nil, errors.New("--kube-versions did not contain any Kubernetes versions")
+	}
+	return versions, nil
+}
+
+// validateKubeVersionMatrix renders the chart once per Kubernetes version and collects the results.
+// It stops with an interrupted error once ctx is canceled.
+func validateKubeVersionMatrix(ctx context.Context, chartPath, releaseName, namespace string, valuesFiles []string, strict bool, kubeVersions []string, capabilities *CapabilityOptions) (*KubeVersionMatrixReport, error) {
+	report := &KubeVersionMatrixReport{Chart: chartPath}
+	for _, kubeVersion := range kubeVersions {
+		if ctx.Err() != nil {
+			break
+		}
+		log.Info("Validating chart against Kubernetes version", "kubeVersion", kubeVersion)
+		result := KubeVersionResult{KubeVersion: kubeVersion, Success: true}
+		if _, err := validateChartWithCapabilities(ctx, chartPath, releaseName, namespace, valuesFiles, strict, kubeVersion, capabilities); err != nil {
+			log.Warn("Chart failed to render for Kubernetes version", "kubeVersion", kubeVersion, "error", err)
+			result.Success = false
+			result.Error, result.ErrorCode, result.Category = reportedError(err)
+			report.FailedVersions = append(report.FailedVersions, kubeVersion)
+		}
+		report.Results = append(report.Results, result)
+	}
+	if err := checkInterrupted(ctx); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// handleKubeVersionMatrixOutput writes the matrix report and returns an error if any version failed.
+func handleKubeVersionMatrixOutput(cmd *cobra.Command, report *KubeVersionMatrixReport, outputFile string) error {
+	reportBytes, err := yaml.Marshal(report)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to marshal Kubernetes version matrix report: %w", err),
+		}
+	}
+
+	if outputFile != "" {
+		if err := writeOutputFile(outputFile, reportBytes, "Successfully wrote Kubernetes version matrix report to %s"); err != nil {
+			return err
+		}
+	} else if _, err := fmt.Fprint(cmd.OutOrStdout(), string(reportBytes)); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to write output to stdout: %w", err),
+		}
+	}
+
+	if len(report.FailedVersions) > 0 {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitHelmCommandFailed,
+			Err: fmt.Errorf("chart failed to render for %d of %d Kubernetes versions: %s",
+				len(report.FailedVersions), len(report.Results), strings.Join(report.FailedVersions, ", ")),
+		}
+	}
+	log.Info("Validation successful for all Kubernetes versions", "count", len(report.Results))
+	return nil
+}
+
 // handleHelmPluginValidate performs the core validation logic for Helm plugin mode,
 // retrieving necessary chart information and values before executing the validation.
 func handleHelmPluginValidate(cmd *cobra.Command, releaseName, namespace string, valuesFiles []string, kubeVersion, outputFile string, strict bool) error {
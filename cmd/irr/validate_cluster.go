@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lucas-albers-lz4/irr/internal/helm"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// findChartForReleaseFunc locates the chart of an installed release; it is a variable for testing.
+var findChartForReleaseFunc = func(ctx context.Context, releaseName, namespace string) (string, error) {
+	client, err := helm.NewHelmClient()
+	if err != nil {
+		return "", fmt.Errorf("failed to create Helm client: %w", err)
+	}
+	return client.FindChartForRelease(ctx, releaseName, namespace)
+}
+
+// handleAgainstClusterValidate validates a release with a server-side dry-run against the
+// current kube context. Template errors fail with ExitHelmCommandFailed, while objects denied
+// by admission policies are reported separately and fail with ExitPolicyDeniedError.
+func handleAgainstClusterValidate(cmd *cobra.Command, releaseName, namespace string, valuesFiles []string) error {
+	if releaseName == "" {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitMissingRequiredFlag,
+			Err:  errors.New("a release name is required for --against-cluster"),
+		}
+	}
+
+	outputFile, _, err := getValidateOutputFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	chartPath, err := getStringFlag(cmd, "chart-path")
+	if err != nil {
+		return err
+	}
+	if chartPath == "" {
+		chartPath, err = findChartForReleaseFunc(cmd.Context(), releaseName, namespace)
+		if err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitChartNotFound,
+				Err:  fmt.Errorf("failed to locate the chart of release %q, provide it with --chart-path: %w", releaseName, err),
+			}
+		}
+	}
+
+	log.Info("Running server-side dry-run against the cluster", "release", releaseName, "namespace", namespace, "chart", chartPath)
+	result, err := helm.ServerDryRunFunc(&helm.TemplateOptions{
+		ChartPath:   chartPath,
+		ReleaseName: releaseName,
+		ValuesFiles: valuesFiles,
+		Namespace:   namespace,
+	})
+	if err != nil {
+		log.Error("Validation failed: Chart could not be rendered against the cluster.")
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitHelmCommandFailed,
+			Err:  fmt.Errorf("chart validation failed (server-side dry-run): %w", err),
+		}
+	}
+
+	if len(result.Denials) > 0 {
+		errOut := cmd.ErrOrStderr()
+		if _, err := fmt.Fprintf(errOut, "--- Admission Policy Denials ---\n"); err != nil {
+			log.Debug("Failed to write policy denial header", "error", err)
+		}
+		for _, denial := range result.Denials {
+			name := denial.Name
+			if denial.Namespace != "" {
+				name = denial.Namespace + "/" + name
+			}
+			log.Error("Admission policy denied resource", "kind", denial.Kind, "name", name)
+			if _, err := fmt.Fprintf(errOut, "%s %s: %s\n", denial.Kind, name, denial.Message); err != nil {
+				log.Debug("Failed to write policy denial", "error", err)
+			}
+		}
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitPolicyDeniedError,
+			Err:  fmt.Errorf("%d resource(s) rendered for release %q were denied by cluster admission policies", len(result.Denials), releaseName),
+		}
+	}
+
+	log.Info("Validation successful: Chart passed the server-side dry-run against the cluster.")
+	return handleValidateOutput(cmd, result.Manifest, outputFile)
+}
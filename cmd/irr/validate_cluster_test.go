@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/internal/helm"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleAgainstClusterValidate(t *testing.T) {
+	run := func(t *testing.T, result *helm.ServerDryRunResult, dryRunErr error, releaseName string) (stdout, stderr string, err error) {
+		t.Helper()
+		original := helm.ServerDryRunFunc
+		t.Cleanup(func() { helm.ServerDryRunFunc = original })
+		helm.ServerDryRunFunc = func(options *helm.TemplateOptions) (*helm.ServerDryRunResult, error) {
+			assert.Equal(t, "./chart", options.ChartPath)
+			assert.Equal(t, []string{"overrides.yaml"}, options.ValuesFiles)
+			return result, dryRunErr
+		}
+
+		cmd := newValidateCmd()
+		require.NoError(t, cmd.Flags().Set("chart-path", "./chart"))
+		outBuf, errBuf := &bytes.Buffer{}, &bytes.Buffer{}
+		cmd.SetOut(outBuf)
+		cmd.SetErr(errBuf)
+		err = handleAgainstClusterValidate(cmd, releaseName, "apps", []string{"overrides.yaml"})
+		return outBuf.String(), errBuf.String(), err
+	}
+
+	t.Run("success prints manifest", func(t *testing.T) {
+		stdout, _, err := run(t, &helm.ServerDryRunResult{Manifest: "kind: Deployment"}, nil, "my-release")
+		require.NoError(t, err)
+		assert.Contains(t, stdout, "kind: Deployment")
+	})
+
+	t.Run("policy denials are reported separately", func(t *testing.T) {
+		result := &helm.ServerDryRunResult{Denials: []helm.PolicyDenial{{
+			Kind: "Deployment", Namespace: "apps", Name: "web",
+			Message: `admission webhook "validation.gatekeeper.sh" denied the request: invalid image repo`,
+		}}}
+		_, stderr, err := run(t, result, nil, "my-release")
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitPolicyDeniedError, exitErr.Code)
+		assert.Contains(t, stderr, "Admission Policy Denials")
+		assert.Contains(t, stderr, "Deployment apps/web: admission webhook")
+	})
+
+	t.Run("render errors are helm failures", func(t *testing.T) {
+		_, _, err := run(t, nil, errors.New("template: deployment.yaml: nil pointer"), "my-release")
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitHelmCommandFailed, exitErr.Code)
+	})
+
+	t.Run("release name required", func(t *testing.T) {
+		_, _, err := run(t, nil, nil, "")
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitMissingRequiredFlag, exitErr.Code)
+	})
+}
+
+func TestValidateAgainstClusterRequiresPluginMode(t *testing.T) {
+	cmd := newValidateCmd()
+	require.NoError(t, cmd.Flags().Set("against-cluster", "true"))
+
+	err := handleStandaloneValidate(cmd, "./chart", []string{"overrides.yaml"})
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// Image comparison (validate --compare) renders the chart a second time without the overrides
+// file, the last --values file, and compares the image of every workload container in the two
+// renders. Images the overrides do not relocate, for example because a template builds the
+// reference from values the overrides do not set, are reported instead of slipping through to
+// the cluster.
+
+const (
+	// compareStatusRewritten is an image the overrides relocated
+	compareStatusRewritten = "rewritten"
+	// compareStatusNotRewritten is an image the overrides should have relocated but did not
+	compareStatusNotRewritten = "not-rewritten"
+	// compareStatusUnchanged is an image outside --source-registries that the overrides left alone
+	compareStatusUnchanged = "unchanged"
+	// compareStatusAdded is a container only rendered with the overrides
+	compareStatusAdded = "added"
+	// compareStatusRemoved is a container only rendered without the overrides
+	compareStatusRemoved = "removed"
+)
+
+// RenderedImageChange is the image of one workload container in the renders compared by validate --compare
+type RenderedImageChange struct {
+	// Status is rewritten, not-rewritten, unchanged, added or removed
+	Status string
+	// Location is the container using the image, as Kind/name/container
+	Location string
+	// Before is the image rendered without the overrides; empty for added containers
+	Before string
+	// After is the image rendered with the overrides; empty for removed containers
+	After string
+}
+
+// RenderedImageComparison is the result of validate --compare
+type RenderedImageComparison struct {
+	// Changes lists the container images in the order of the render without the overrides,
+	// followed by the added containers
+	Changes []RenderedImageChange
+	// Counts is the number of changes of each status
+	Counts map[string]int
+}
+
+// compareRenderedImages compares the workload images of the manifests rendered without (before)
+// and with (after) the overrides. With sourceRegistries, an image rendered with the overrides is
+// not rewritten when it still comes from one of them; without, an image is not rewritten when
+// the overrides leave it unchanged.
+func compareRenderedImages(before, after string, sourceRegistries []string) *RenderedImageComparison {
+	afterImages := make(map[string][]string)
+	var afterOrder []string
+	for _, rendered := range extractWorkloadImages(after) {
+		location := rendered.location()
+		if _, seen := afterImages[location]; !seen {
+			afterOrder = append(afterOrder, location)
+		}
+		afterImages[location] = append(afterImages[location], rendered.Image)
+	}
+
+	comparison := &RenderedImageComparison{Counts: make(map[string]int)}
+	add := func(change RenderedImageChange) {
+		change.Status = imageChangeStatus(change.Before, change.After, sourceRegistries)
+		comparison.Changes = append(comparison.Changes, change)
+		comparison.Counts[change.Status]++
+	}
+	for _, rendered := range extractWorkloadImages(before) {
+		change := RenderedImageChange{Location: rendered.location(), Before: rendered.Image}
+		if remaining := afterImages[change.Location]; len(remaining) > 0 {
+			change.After = remaining[0]
+			afterImages[change.Location] = remaining[1:]
+		}
+		add(change)
+	}
+	for _, location := range afterOrder {
+		for _, afterImage := range afterImages[location] {
+			add(RenderedImageChange{Location: location, After: afterImage})
+		}
+	}
+	return comparison
+}
+
+// imageChangeStatus returns the status of a container image rendered as before without and as
+// after with the overrides, as described by compareRenderedImages.
+func imageChangeStatus(before, after string, sourceRegistries []string) string {
+	switch {
+	case after == "":
+		return compareStatusRemoved
+	case len(sourceRegistries) > 0 && fromSourceRegistry(after, sourceRegistries):
+		return compareStatusNotRewritten
+	case before == "":
+		return compareStatusAdded
+	case before != after:
+		return compareStatusRewritten
+	case len(sourceRegistries) > 0:
+		return compareStatusUnchanged
+	default:
+		return compareStatusNotRewritten
+	}
+}
+
+// fromSourceRegistry reports whether a rendered image comes from one of sourceRegistries. Images
+// that cannot be parsed are reported as well, so that they are checked by hand.
+func fromSourceRegistry(rendered string, sourceRegistries []string) bool {
+	ref, err := image.ParseRenderedImageReference(rendered)
+	if err != nil {
+		log.Warn("Cannot parse rendered image", "image", rendered, "error", err)
+		return true
+	}
+	return image.IsSourceRegistry(ref, sourceRegistries, nil)
+}
+
+// renderImageComparison formats a comparison as a table followed by a summary line
+func renderImageComparison(comparison *RenderedImageComparison) ([]byte, error) {
+	var buf strings.Builder
+	writer := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(writer, "STATUS\tCONTAINER\tBEFORE\tAFTER"); err != nil {
+		return nil, fmt.Errorf("failed to render image comparison: %w", err)
+	}
+	for _, change := range comparison.Changes {
+		if _, err := fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", change.Status, change.Location, orDash(change.Before), orDash(change.After)); err != nil {
+			return nil, fmt.Errorf("failed to render image comparison: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to render image comparison: %w", err)
+	}
+	counts := comparison.Counts
+	fmt.Fprintf(&buf, "\n%d container images: %d rewritten, %d not rewritten, %d unchanged, %d added, %d removed\n",
+		len(comparison.Changes), counts[compareStatusRewritten], counts[compareStatusNotRewritten],
+		counts[compareStatusUnchanged], counts[compareStatusAdded], counts[compareStatusRemoved])
+	return []byte(buf.String()), nil
+}
+
+// orDash returns "-" for an empty table cell
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// validateCompare renders the chart without the last of valuesFiles, the overrides file, and
+// compares its images with those of afterManifest, rendered with every values file.
+func validateCompare(ctx context.Context, chartPath, releaseName, namespace string, valuesFiles []string, strict bool, kubeVersion string, capabilities *CapabilityOptions, sourceRegistries []string, afterManifest string) (*RenderedImageComparison, error) {
+	baseValuesFiles := valuesFiles[:len(valuesFiles)-1]
+	log.Info("Rendering the chart without the overrides for --compare", "overrides", valuesFiles[len(valuesFiles)-1])
+	beforeManifest, err := renderChartForValidation(ctx, chartPath, releaseName, namespace, baseValuesFiles, strict, kubeVersion, capabilities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render the chart without the overrides: %w", err)
+	}
+	comparison := compareRenderedImages(beforeManifest, afterManifest, sourceRegistries)
+	log.Info("Compared rendered images",
+		"images", len(comparison.Changes),
+		"rewritten", comparison.Counts[compareStatusRewritten],
+		"notRewritten", comparison.Counts[compareStatusNotRewritten])
+	return comparison, nil
+}
+
+// handleImageComparisonOutput prints the comparison table and writes the rendered manifests to
+// outputFile, if set. It fails when an image was not rewritten.
+func handleImageComparisonOutput(cmd *cobra.Command, comparison *RenderedImageComparison, templateOutput, outputFile string) error {
+	if outputFile != "" {
+		if err := writeOutputFile(outputFile, []byte(templateOutput), "Successfully wrote rendered templates to %s"); err != nil {
+			return err
+		}
+	}
+	table, err := renderImageComparison(comparison)
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitGeneralRuntimeError, Err: err}
+	}
+	if _, err := fmt.Fprint(cmd.OutOrStdout(), string(table)); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write image comparison to stdout: %w", err),
+		}
+	}
+
+	var notRewritten []string
+	for _, change := range comparison.Changes {
+		if change.Status == compareStatusNotRewritten {
+			notRewritten = append(notRewritten, fmt.Sprintf("%s (%s)", change.After, change.Location))
+		}
+	}
+	if len(notRewritten) > 0 {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitRegistryDetectionError,
+			Err:  fmt.Errorf("%d rendered image(s) not rewritten by the overrides: %s", len(notRewritten), strings.Join(notRewritten, ", ")),
+		}
+	}
+	return nil
+}
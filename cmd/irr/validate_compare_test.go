@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const compareTestManifestBefore = `---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: init
+          image: docker.io/library/busybox:1.36
+      containers:
+        - name: web
+          image: docker.io/library/nginx:1.25
+        - name: exporter
+          image: quay.io/prometheus/node-exporter:v1.8.0
+        - name: legacy
+          image: gcr.io/google-containers/pause:3.9
+`
+
+const compareTestManifestAfter = `---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: init
+          image: harbor.local/dockerhub/library/busybox:1.36
+      containers:
+        - name: web
+          image: harbor.local/dockerhub/library/nginx:1.25
+        - name: exporter
+          image: quay.io/prometheus/node-exporter:v1.8.0
+        - name: metrics
+          image: harbor.local/quay/prometheus/statsd-exporter:v0.26.0
+`
+
+func TestCompareRenderedImages(t *testing.T) {
+	comparison := compareRenderedImages(compareTestManifestBefore, compareTestManifestAfter, []string{"docker.io", "quay.io"})
+
+	assert.Equal(t, []RenderedImageChange{
+		{Status: compareStatusNotRewritten, Location: "Deployment/web/exporter", Before: "quay.io/prometheus/node-exporter:v1.8.0", After: "quay.io/prometheus/node-exporter:v1.8.0"},
+		{Status: compareStatusRewritten, Location: "Deployment/web/init", Before: "docker.io/library/busybox:1.36", After: "harbor.local/dockerhub/library/busybox:1.36"},
+		{Status: compareStatusRemoved, Location: "Deployment/web/legacy", Before: "gcr.io/google-containers/pause:3.9"},
+		{Status: compareStatusRewritten, Location: "Deployment/web/web", Before: "docker.io/library/nginx:1.25", After: "harbor.local/dockerhub/library/nginx:1.25"},
+		{Status: compareStatusAdded, Location: "Deployment/web/metrics", After: "harbor.local/quay/prometheus/statsd-exporter:v0.26.0"},
+	}, comparison.Changes)
+	assert.Equal(t, 2, comparison.Counts[compareStatusRewritten])
+	assert.Equal(t, 1, comparison.Counts[compareStatusNotRewritten])
+}
+
+func TestImageChangeStatus(t *testing.T) {
+	tests := []struct {
+		name             string
+		before           string
+		after            string
+		sourceRegistries []string
+		want             string
+	}{
+		{"rewritten", "docker.io/library/nginx:1.25", "harbor.local/dockerhub/library/nginx:1.25", []string{"docker.io"}, compareStatusRewritten},
+		{"still from a source registry", "nginx:1.25", "docker.io/library/nginx:1.26", []string{"docker.io"}, compareStatusNotRewritten},
+		{"outside the source registries", "gcr.io/pause:3.9", "gcr.io/pause:3.9", []string{"docker.io"}, compareStatusUnchanged},
+		{"unchanged without source registries", "gcr.io/pause:3.9", "gcr.io/pause:3.9", nil, compareStatusNotRewritten},
+		{"changed without source registries", "gcr.io/pause:3.9", "harbor.local/gcr/pause:3.9", nil, compareStatusRewritten},
+		{"added from a source registry", "", "docker.io/library/busybox:1.36", []string{"docker.io"}, compareStatusNotRewritten},
+		{"added", "", "harbor.local/dockerhub/library/busybox:1.36", []string{"docker.io"}, compareStatusAdded},
+		{"removed", "docker.io/library/busybox:1.36", "", []string{"docker.io"}, compareStatusRemoved},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, imageChangeStatus(tt.before, tt.after, tt.sourceRegistries))
+		})
+	}
+}
+
+func TestHandleImageComparisonOutput(t *testing.T) {
+	t.Run("all images rewritten", func(t *testing.T) {
+		comparison := compareRenderedImages(compareTestManifestBefore, compareTestManifestAfter, []string{"docker.io"})
+		cmd := &cobra.Command{}
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+
+		require.NoError(t, handleImageComparisonOutput(cmd, comparison, compareTestManifestAfter, ""))
+		assert.Contains(t, out.String(), "STATUS")
+		assert.Regexp(t, `rewritten\s+Deployment/web/web\s+docker.io/library/nginx:1.25\s+harbor.local/dockerhub/library/nginx:1.25`, out.String())
+		assert.Regexp(t, `removed\s+Deployment/web/legacy\s+gcr.io/google-containers/pause:3.9\s+-`, out.String())
+		assert.Contains(t, out.String(), "5 container images: 2 rewritten, 0 not rewritten, 1 unchanged, 1 added, 1 removed")
+	})
+
+	t.Run("image not rewritten", func(t *testing.T) {
+		comparison := compareRenderedImages(compareTestManifestBefore, compareTestManifestAfter, []string{"docker.io", "quay.io"})
+		cmd := &cobra.Command{}
+		cmd.SetOut(&bytes.Buffer{})
+
+		err := handleImageComparisonOutput(cmd, comparison, compareTestManifestAfter, "")
+		var exitErr *exitcodes.ExitCodeError
+		require.True(t, errors.As(err, &exitErr))
+		assert.Equal(t, exitcodes.ExitRegistryDetectionError, exitErr.Code)
+		assert.Contains(t, err.Error(), "quay.io/prometheus/node-exporter:v1.8.0 (Deployment/web/exporter)")
+	})
+}
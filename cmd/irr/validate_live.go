@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// Live validation (validate --live kind) installs the chart with its overrides into a kind cluster
+// and waits for it to roll out. The relocated images are pulled on the host, pushed to a local
+// registry attached to the cluster, and the nodes' containerd is configured to pull the target
+// registries through that registry, so the rollout proves that the relocated images exist and
+// start, not only that the chart renders.
+
+const (
+	// liveModeKind selects a kind cluster for --live
+	liveModeKind = "kind"
+	// defaultKindClusterName is the kind cluster used when --kind-cluster is not set
+	defaultKindClusterName = "irr-validate"
+	// defaultLiveTimeout is how long the release may take to roll out
+	defaultLiveTimeout = 5 * time.Minute
+	// kindRegistryName is the container of the local registry, as in the kind documentation
+	kindRegistryName = "kind-registry"
+	// kindRegistryHostAddress is where the local registry listens on the host
+	kindRegistryHostAddress = "localhost:5001"
+	// kindRegistryImage is the image of the local registry
+	kindRegistryImage = "registry:2"
+	// kindNetwork is the docker network of kind clusters
+	kindNetwork = "kind"
+	// kindContainerdCertsDir holds the containerd registry host configuration of kind nodes
+	kindContainerdCertsDir = "/etc/containerd/certs.d"
+	// kindClusterConfig makes the nodes read registry host configuration from kindContainerdCertsDir
+	kindClusterConfig = `kind: Cluster
+apiVersion: kind.x-k8s.io/v1alpha4
+containerdConfigPatches:
+- |-
+  [plugins."io.containerd.grpc.v1.cri".registry]
+    config_path = "` + kindContainerdCertsDir + `"
+`
+)
+
+// runLiveCommand runs an external tool of live validation with stdin as input (if not empty) and
+// returns its standard output. The tool is killed when ctx is canceled. It is a variable for testing.
+var runLiveCommand = func(ctx context.Context, stdin, name string, args ...string) (string, error) {
+	log.Debug("Running command", "command", name, "args", strings.Join(args, " "))
+	command := exec.CommandContext(ctx, name, args...) //nolint:gosec // tools and arguments are built by irr
+	if stdin != "" {
+		command.Stdin = strings.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return stdout.String(), fmt.Errorf("%s %s failed: %w: %s", name, args[0], err, message)
+		}
+		return stdout.String(), fmt.Errorf("%s %s failed: %w", name, args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// lookPathFunc finds the external tools of live validation; it is a variable for testing.
+var lookPathFunc = exec.LookPath
+
+// LiveOptions configure validate --live
+type LiveOptions struct {
+	// Mode is the kind of cluster, currently only "kind"; empty disables live validation
+	Mode string
+	// ClusterName is the kind cluster to create or reuse
+	ClusterName string
+	// KeepCluster keeps the cluster and the release for inspection
+	KeepCluster bool
+	// Timeout is how long the release may take to roll out
+	Timeout time.Duration
+}
+
+// addLiveFlags registers the flags of live validation
+func addLiveFlags(cmd *cobra.Command) {
+	cmd.Flags().String("live", "", "Install the chart with the overrides into a local cluster and wait for it to roll out; supported: kind")
+	cmd.Flags().String("kind-cluster", defaultKindClusterName, "kind cluster used by --live kind; an existing cluster is reused")
+	cmd.Flags().Bool("keep-cluster", false, "Keep the kind cluster and the release after --live kind")
+	cmd.Flags().Duration("live-timeout", defaultLiveTimeout, "How long --live waits for the release to roll out")
+}
+
+// getLiveFlags reads the flags registered by addLiveFlags
+func getLiveFlags(cmd *cobra.Command) (*LiveOptions, error) {
+	if cmd.Flags().Lookup("live") == nil {
+		return &LiveOptions{}, nil
+	}
+	mode, err := getStringFlag(cmd, "live")
+	if err != nil {
+		return nil, err
+	}
+	if mode != "" && mode != liveModeKind {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("unsupported --live mode %q (supported: %s)", mode, liveModeKind),
+		}
+	}
+	clusterName, err := getStringFlag(cmd, "kind-cluster")
+	if err != nil {
+		return nil, err
+	}
+	if clusterName == "" {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--kind-cluster must not be empty"),
+		}
+	}
+	keepCluster, err := getBoolFlag(cmd, "keep-cluster")
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := cmd.Flags().GetDuration("live-timeout")
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to get live-timeout flag: %w", err),
+		}
+	}
+	if timeout <= 0 {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  errors.New("--live-timeout must be positive"),
+		}
+	}
+	return &LiveOptions{Mode: mode, ClusterName: clusterName, KeepCluster: keepCluster, Timeout: timeout}, nil
+}
+
+// kindValidation installs a release into a kind cluster
+type kindValidation struct {
+	// ctx cancels the external tools when the command is interrupted or times out
+	ctx         context.Context
+	opts        *LiveOptions
+	helmBinary  string
+	chartPath   string
+	releaseName string
+	namespace   string
+	valuesFiles []string
+	errOut      io.Writer
+}
+
+// validateLiveKind installs the chart rendered as manifest into a kind cluster, with the images of
+// manifest mirrored into the local registry, and waits for the release to roll out.
+func validateLiveKind(cmd *cobra.Command, opts *LiveOptions, chartPath, releaseName, namespace string, valuesFiles []string, manifest string) error {
+	if err := requireNetwork("--live kind"); err != nil {
+		return err
+	}
+	helmBinary := os.Getenv("HELM_BIN")
+	if helmBinary == "" {
+		helmBinary = defaultHelmBinary
+	}
+	for _, tool := range []string{"kind", "docker", helmBinary} {
+		if _, err := lookPathFunc(tool); err != nil {
+			return &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  fmt.Errorf("--live kind requires %s on PATH: %w", tool, err),
+			}
+		}
+	}
+	if releaseName == "" {
+		releaseName = "irr-validation"
+	}
+
+	v := &kindValidation{
+		ctx:         getCommandContext(cmd),
+		opts:        opts,
+		helmBinary:  helmBinary,
+		chartPath:   chartPath,
+		releaseName: releaseName,
+		namespace:   namespace,
+		valuesFiles: valuesFiles,
+		errOut:      cmd.ErrOrStderr(),
+	}
+	images, err := liveImages(manifest)
+	if err != nil {
+		return err
+	}
+	return v.run(images)
+}
+
+// run prepares the registry and the cluster, mirrors images, installs the release and tears down
+func (v *kindValidation) run(images []*image.Reference) (err error) {
+	if err := v.ensureRegistry(); err != nil {
+		return err
+	}
+	created, err := v.ensureCluster()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if teardownErr := v.teardown(created); teardownErr != nil {
+			log.Warn("Failed to tear down live validation", "cluster", v.opts.ClusterName, "error", teardownErr)
+		}
+	}()
+
+	if err := v.configureMirrors(images); err != nil {
+		return err
+	}
+	if err := v.mirrorImages(images); err != nil {
+		return err
+	}
+	return v.install()
+}
+
+// ensureRegistry starts the local registry container, creating it if needed
+func (v *kindValidation) ensureRegistry() error {
+	running, err := runLiveCommand(v.ctx, "", "docker", "inspect", "-f", "{{.State.Running}}", kindRegistryName)
+	switch {
+	case err != nil:
+		log.Info("Starting local registry for kind", "container", kindRegistryName, "address", kindRegistryHostAddress)
+		_, err = runLiveCommand(v.ctx, "", "docker", "run", "-d", "--restart=always", "-p", "127.0.0.1:5001:5000", "--name", kindRegistryName, kindRegistryImage)
+	case strings.TrimSpace(running) != "true":
+		_, err = runLiveCommand(v.ctx, "", "docker", "start", kindRegistryName)
+	}
+	if err != nil {
+		return liveError(fmt.Errorf("failed to start the local registry: %w", err))
+	}
+	return nil
+}
+
+// ensureCluster creates the kind cluster unless it exists, and reports whether it was created
+func (v *kindValidation) ensureCluster() (bool, error) {
+	clusters, err := runLiveCommand(v.ctx, "", "kind", "get", "clusters")
+	if err != nil {
+		return false, liveError(fmt.Errorf("failed to list kind clusters: %w", err))
+	}
+	if slices.Contains(strings.Fields(clusters), v.opts.ClusterName) {
+		log.Info("Reusing kind cluster", "cluster", v.opts.ClusterName)
+		return false, nil
+	}
+	log.Info("Creating kind cluster", "cluster", v.opts.ClusterName)
+	if _, err := runLiveCommand(v.ctx, kindClusterConfig, "kind", "create", "cluster", "--name", v.opts.ClusterName, "--config", "-"); err != nil {
+		return false, liveError(fmt.Errorf("failed to create kind cluster %s: %w", v.opts.ClusterName, err))
+	}
+	return true, nil
+}
+
+// configureMirrors attaches the local registry to the kind network and makes every node pull the
+// registries of images through it, falling back to the registries themselves
+func (v *kindValidation) configureMirrors(images []*image.Reference) error {
+	networks, err := runLiveCommand(v.ctx, "", "docker", "inspect", "-f", "{{json .NetworkSettings.Networks}}", kindRegistryName)
+	if err != nil {
+		return liveError(fmt.Errorf("failed to inspect the local registry: %w", err))
+	}
+	if !strings.Contains(networks, `"`+kindNetwork+`"`) {
+		if _, err := runLiveCommand(v.ctx, "", "docker", "network", "connect", kindNetwork, kindRegistryName); err != nil {
+			return liveError(fmt.Errorf("failed to attach the local registry to the kind network: %w", err))
+		}
+	}
+
+	nodes, err := runLiveCommand(v.ctx, "", "kind", "get", "nodes", "--name", v.opts.ClusterName)
+	if err != nil {
+		return liveError(fmt.Errorf("failed to list the nodes of kind cluster %s: %w", v.opts.ClusterName, err))
+	}
+	var hosts []string
+	for _, ref := range images {
+		if !slices.Contains(hosts, ref.Registry) {
+			hosts = append(hosts, ref.Registry)
+		}
+	}
+	for _, node := range strings.Fields(nodes) {
+		for _, host := range hosts {
+			dir := path.Join(kindContainerdCertsDir, host)
+			if _, err := runLiveCommand(v.ctx, "", "docker", "exec", node, "mkdir", "-p", dir); err != nil {
+				return liveError(fmt.Errorf("failed to configure the registry mirror of %s on node %s: %w", host, node, err))
+			}
+			if _, err := runLiveCommand(v.ctx, mirrorHostsTOML(host), "docker", "exec", "-i", node, "cp", "/dev/stdin", path.Join(dir, "hosts.toml")); err != nil {
+				return liveError(fmt.Errorf("failed to configure the registry mirror of %s on node %s: %w", host, node, err))
+			}
+		}
+	}
+	return nil
+}
+
+// mirrorHostsTOML is the containerd host configuration that pulls from host through the local
+// registry first
+func mirrorHostsTOML(host string) string {
+	server := "https://" + host
+	if host == "docker.io" {
+		server = "https://registry-1.docker.io"
+	}
+	return fmt.Sprintf("server = %q\n\n[host.\"http://%s:5000\"]\n  capabilities = [\"pull\", \"resolve\"]\n", server, kindRegistryName)
+}
+
+// mirrorImages pulls each image on the host and pushes it to the local registry. Images that
+// cannot be pulled are reported together, as they are what the live validation is meant to find.
+func (v *kindValidation) mirrorImages(images []*image.Reference) error {
+	var failed []string
+	for _, ref := range images {
+		source := ref.String()
+		if ref.Digest != "" {
+			// Pushing may change the digest, so nodes pull these from the registry itself
+			log.Warn("Image pinned by digest is not mirrored; the nodes pull it from its registry", "image", source)
+			continue
+		}
+		tag := ref.Tag
+		if tag == "" {
+			tag = image.DefaultTag
+		}
+		local := kindRegistryHostAddress + "/" + ref.Repository + ":" + tag
+		log.Info("Mirroring image into the local registry", "image", source)
+		if _, err := runLiveCommand(v.ctx, "", "docker", "pull", source); err != nil {
+			log.Error("Failed to pull image", "image", source, "error", err)
+			failed = append(failed, source)
+			continue
+		}
+		if _, err := runLiveCommand(v.ctx, "", "docker", "tag", source, local); err != nil {
+			return liveError(fmt.Errorf("failed to tag %s: %w", source, err))
+		}
+		if _, err := runLiveCommand(v.ctx, "", "docker", "push", local); err != nil {
+			return liveError(fmt.Errorf("failed to push %s to the local registry: %w", source, err))
+		}
+	}
+	if len(failed) > 0 {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitImageProcessingError,
+			Err:  fmt.Errorf("%d image(s) could not be pulled: %s", len(failed), strings.Join(failed, ", ")),
+		}
+	}
+	return nil
+}
+
+// install installs the release and waits for its workloads to become ready
+func (v *kindValidation) install() error {
+	args := []string{
+		"install", v.releaseName, v.chartPath,
+		"--namespace", v.namespace, "--create-namespace",
+		"--kube-context", v.kubeContext(),
+		"--wait", "--timeout", v.opts.Timeout.String(),
+	}
+	for _, valuesFile := range v.valuesFiles {
+		args = append(args, "-f", valuesFile)
+	}
+	log.Info("Installing chart into kind cluster", "release", v.releaseName, "namespace", v.namespace, "cluster", v.opts.ClusterName)
+	if _, err := runLiveCommand(v.ctx, "", v.helmBinary, args...); err != nil {
+		v.reportPods()
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitHelmCommandFailed,
+			Err:  fmt.Errorf("release %s did not roll out in kind cluster %s: %w", v.releaseName, v.opts.ClusterName, err),
+		}
+	}
+	log.Info("Live validation successful: the release rolled out with the relocated images.", "release", v.releaseName, "cluster", v.opts.ClusterName)
+	return nil
+}
+
+// reportPods writes the pods of the release namespace, showing image pull errors, if kubectl is available
+func (v *kindValidation) reportPods() {
+	if _, err := lookPathFunc("kubectl"); err != nil {
+		return
+	}
+	pods, err := runLiveCommand(v.ctx, "", "kubectl", "--context", v.kubeContext(), "--namespace", v.namespace, "get", "pods", "-o", "wide")
+	if err != nil {
+		log.Debug("Failed to list pods", "error", err)
+		return
+	}
+	if _, err := fmt.Fprintf(v.errOut, "--- Pods in namespace %s ---\n%s", v.namespace, pods); err != nil {
+		log.Debug("Failed to write pods", "error", err)
+	}
+}
+
+// teardown deletes the cluster if it was created for this run, or uninstalls the release from a
+// reused cluster, unless the cluster is kept
+func (v *kindValidation) teardown(created bool) error {
+	// Tear down even when the run was interrupted, so no cluster or release is left behind
+	ctx := context.WithoutCancel(v.ctx)
+	switch {
+	case v.opts.KeepCluster:
+		log.Info("Keeping kind cluster", "cluster", v.opts.ClusterName, "context", v.kubeContext())
+		return nil
+	case created:
+		log.Info("Deleting kind cluster", "cluster", v.opts.ClusterName)
+		_, err := runLiveCommand(ctx, "", "kind", "delete", "cluster", "--name", v.opts.ClusterName)
+		return err
+	default:
+		_, err := runLiveCommand(ctx, "", v.helmBinary, "uninstall", v.releaseName, "--namespace", v.namespace, "--kube-context", v.kubeContext(), "--ignore-not-found")
+		return err
+	}
+}
+
+// kubeContext is the kubeconfig context kind creates for the cluster
+func (v *kindValidation) kubeContext() string {
+	return "kind-" + v.opts.ClusterName
+}
+
+// liveImages returns the distinct images of the workloads in manifest, in order of first use
+func liveImages(manifest string) ([]*image.Reference, error) {
+	var images []*image.Reference
+	seen := make(map[string]bool)
+	for _, rendered := range extractWorkloadImages(manifest) {
+		if seen[rendered.Image] {
+			continue
+		}
+		seen[rendered.Image] = true
+		ref, err := image.ParseRenderedImageReference(rendered.Image)
+		if err != nil {
+			return nil, &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitImageProcessingError,
+				Err:  fmt.Errorf("invalid image %q in rendered workload %s: %w", rendered.Image, rendered.Workload, err),
+			}
+		}
+		images = append(images, ref)
+	}
+	return images, nil
+}
+
+// liveError reports a failure of the tools live validation relies on
+func liveError(err error) error {
+	return &exitcodes.ExitCodeError{Code: exitcodes.ExitGeneralRuntimeError, Err: err}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const liveTestManifest = `---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: init
+          image: harbor.local/dockerhub/library/busybox:1.36
+      containers:
+        - name: web
+          image: harbor.local/dockerhub/library/nginx:1.25
+        - name: sidecar
+          image: harbor.local/dockerhub/library/busybox:1.36
+        - name: pinned
+          image: harbor.local/quay/prometheus/node-exporter@sha256:1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef
+`
+
+// fakeLiveCommands replaces the external tools of live validation, recording each command line.
+// fail returns the error of a command line, or nil.
+func fakeLiveCommands(t *testing.T, clusters string, fail func(commandLine string) error) *[]string {
+	t.Helper()
+	var commands []string
+	originalRun, originalLookPath := runLiveCommand, lookPathFunc
+	runLiveCommand = func(_ context.Context, _, name string, args ...string) (string, error) {
+		commandLine := strings.Join(append([]string{name}, args...), " ")
+		commands = append(commands, commandLine)
+		if err := fail(commandLine); err != nil {
+			return "", err
+		}
+		switch commandLine {
+		case "kind get clusters":
+			return clusters, nil
+		case "kind get nodes --name irr-validate":
+			return "irr-validate-control-plane\n", nil
+		case "docker inspect -f {{.State.Running}} kind-registry":
+			return "true\n", nil
+		}
+		return "", nil
+	}
+	lookPathFunc = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	t.Cleanup(func() { runLiveCommand, lookPathFunc = originalRun, originalLookPath })
+	t.Setenv("HELM_BIN", "")
+	return &commands
+}
+
+func TestValidateLiveKind(t *testing.T) {
+	opts := &LiveOptions{Mode: liveModeKind, ClusterName: defaultKindClusterName, Timeout: 2 * time.Minute}
+	validate := func(opts *LiveOptions) error {
+		cmd := newValidateCmd()
+		cmd.SetErr(&bytes.Buffer{})
+		return validateLiveKind(cmd, opts, "./web", "web", "apps", []string{"overrides.yaml"}, liveTestManifest)
+	}
+	noFailure := func(string) error { return nil }
+
+	t.Run("creates, installs and deletes the cluster", func(t *testing.T) {
+		commands := fakeLiveCommands(t, "", noFailure)
+		require.NoError(t, validate(opts))
+		assert.Equal(t, []string{
+			"docker inspect -f {{.State.Running}} kind-registry",
+			"kind get clusters",
+			"kind create cluster --name irr-validate --config -",
+			"docker inspect -f {{json .NetworkSettings.Networks}} kind-registry",
+			"docker network connect kind kind-registry",
+			"kind get nodes --name irr-validate",
+			"docker exec irr-validate-control-plane mkdir -p /etc/containerd/certs.d/harbor.local",
+			"docker exec -i irr-validate-control-plane cp /dev/stdin /etc/containerd/certs.d/harbor.local/hosts.toml",
+			"docker pull harbor.local/dockerhub/library/busybox:1.36",
+			"docker tag harbor.local/dockerhub/library/busybox:1.36 localhost:5001/dockerhub/library/busybox:1.36",
+			"docker push localhost:5001/dockerhub/library/busybox:1.36",
+			"docker pull harbor.local/dockerhub/library/nginx:1.25",
+			"docker tag harbor.local/dockerhub/library/nginx:1.25 localhost:5001/dockerhub/library/nginx:1.25",
+			"docker push localhost:5001/dockerhub/library/nginx:1.25",
+			"helm install web ./web --namespace apps --create-namespace --kube-context kind-irr-validate --wait --timeout 2m0s -f overrides.yaml",
+			"kind delete cluster --name irr-validate",
+		}, *commands, "digest-pinned images are not mirrored")
+	})
+
+	t.Run("reuses a cluster and uninstalls the release", func(t *testing.T) {
+		commands := fakeLiveCommands(t, "dev\nirr-validate\n", noFailure)
+		require.NoError(t, validate(opts))
+		assert.NotContains(t, *commands, "kind create cluster --name irr-validate --config -")
+		assert.Equal(t, "helm uninstall web --namespace apps --kube-context kind-irr-validate --ignore-not-found", (*commands)[len(*commands)-1])
+	})
+
+	t.Run("keeps the cluster", func(t *testing.T) {
+		commands := fakeLiveCommands(t, "", noFailure)
+		keep := *opts
+		keep.KeepCluster = true
+		require.NoError(t, validate(&keep))
+		assert.NotContains(t, *commands, "kind delete cluster --name irr-validate")
+	})
+
+	t.Run("images missing from the target registry", func(t *testing.T) {
+		commands := fakeLiveCommands(t, "", func(commandLine string) error {
+			if strings.HasPrefix(commandLine, "docker pull") {
+				return errors.New("manifest unknown")
+			}
+			return nil
+		})
+		err := validate(opts)
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitImageProcessingError, exitErr.Code)
+		assert.Contains(t, err.Error(), "2 image(s) could not be pulled")
+		assert.Equal(t, "kind delete cluster --name irr-validate", (*commands)[len(*commands)-1], "the cluster is torn down after a failure")
+	})
+
+	t.Run("release does not roll out", func(t *testing.T) {
+		fakeLiveCommands(t, "", func(commandLine string) error {
+			if strings.HasPrefix(commandLine, "helm install") {
+				return errors.New("context deadline exceeded")
+			}
+			return nil
+		})
+		err := validate(opts)
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitHelmCommandFailed, exitErr.Code)
+	})
+}
+
+func TestRunLiveCommandCanceled(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep is not installed")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	_, err := runLiveCommand(ctx, "", "sleep", "10")
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second, "the tool is not started or killed once the context is canceled")
+}
+
+func TestGetLiveFlags(t *testing.T) {
+	cmd := newValidateCmd()
+	opts, err := getLiveFlags(cmd)
+	require.NoError(t, err)
+	assert.Empty(t, opts.Mode)
+	assert.Equal(t, defaultKindClusterName, opts.ClusterName)
+	assert.Equal(t, defaultLiveTimeout, opts.Timeout)
+
+	require.NoError(t, cmd.Flags().Set("live", "minikube"))
+	_, err = getLiveFlags(cmd)
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+}
+
+func TestMirrorHostsTOML(t *testing.T) {
+	assert.Equal(t, "server = \"https://harbor.local\"\n\n[host.\"http://kind-registry:5000\"]\n  capabilities = [\"pull\", \"resolve\"]\n", mirrorHostsTOML("harbor.local"))
+	assert.Contains(t, mirrorHostsTOML("docker.io"), `server = "https://registry-1.docker.io"`)
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -99,6 +100,71 @@ func TestGetValidateOutputFlags(t *testing.T) {
 	}
 }
 
+// TestGetCapabilityFlags checks parsing of --api-versions and --set-capabilities-from-cluster
+func TestGetCapabilityFlags(t *testing.T) {
+	tests := []struct {
+		name              string
+		args              []string
+		expectAPIVersions []string
+		expectFromCluster bool
+		expectErr         bool
+	}{
+		{
+			name:              "no capability flags",
+			args:              []string{},
+			expectAPIVersions: []string{},
+		},
+		{
+			name:              "repeated api-versions",
+			args:              []string{"--api-versions", "monitoring.coreos.com/v1", "--api-versions", "networking.k8s.io/v1/Ingress"},
+			expectAPIVersions: []string{"monitoring.coreos.com/v1", "networking.k8s.io/v1/Ingress"},
+		},
+		{
+			name:              "capabilities from cluster",
+			args:              []string{"--set-capabilities-from-cluster"},
+			expectAPIVersions: []string{},
+			expectFromCluster: true,
+		},
+		{
+			name:      "empty api version",
+			args:      []string{"--api-versions", " "},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newValidateCmd()
+			require.NoError(t, cmd.ParseFlags(tt.args))
+
+			capabilities, err := getCapabilityFlags(cmd)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.expectAPIVersions, capabilities.APIVersions)
+			assert.Equal(t, tt.expectFromCluster, capabilities.FromCluster)
+		})
+	}
+}
+
+func TestPluginValidateRejectsCapabilityFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"--api-versions", "monitoring.coreos.com/v1"},
+		{"--set-capabilities-from-cluster"},
+	} {
+		cmd := newValidateCmd()
+		require.NoError(t, cmd.ParseFlags(args))
+
+		err := handlePluginValidate(cmd, "web", "prod")
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr, "%v", args)
+		assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+		assert.ErrorContains(t, err, "only supported with --chart-path")
+	}
+}
+
 // TestGetValidateFlags checks if the function correctly retrieves chart path and values files flags.
 func TestGetValidateFlags(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/encryption"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/getter"
+)
+
+const (
+	// secretsScheme is the scheme of values files decrypted by the helm-secrets plugin; variants
+	// such as secrets+gpg-import:// or secrets+literal:// start with secretsScheme+"+"
+	secretsScheme = "secrets"
+	// helmSecretsURL is where the helm-secrets plugin is installed from
+	helmSecretsURL = "https://github.com/jkroepke/helm-secrets"
+	// sopsKey is the top-level key under which sops records the encryption metadata of a file
+	sopsKey = "sops"
+	// sopsMACKey is the key of the message authentication code in the sops metadata
+	sopsMACKey = "mac"
+)
+
+// secretValuesDir holds the decrypted copies of the encrypted values files of the running command
+var secretValuesDir string
+
+// fetchSecretValues decrypts the values file reference ref with the Helm downloader plugin that
+// handles its scheme (helm-secrets), as helm does for --values secrets://...; replaced in tests
+var fetchSecretValues = func(ref string) ([]byte, error) {
+	scheme, _, _ := strings.Cut(ref, "://")
+	provider, err := getter.All(cli.New()).ByScheme(scheme)
+	if err != nil {
+		return nil, fmt.Errorf("no Helm plugin handles %s:// values files; install helm-secrets with 'helm plugin install %s': %w", scheme, helmSecretsURL, err)
+	}
+	data, err := provider.Get(ref, getter.WithURL(ref))
+	if err != nil {
+		return nil, fmt.Errorf("helm-secrets failed: %w", err)
+	}
+	return data.Bytes(), nil
+}
+
+// addDecryptFlags adds --decrypt-identity, used to decrypt --values files encrypted with age
+func addDecryptFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("decrypt-identity", nil, "age identity file used to decrypt --values files encrypted with age, such as overrides written with --encrypt age; can be repeated")
+}
+
+// resolveSecretValuesFlag decrypts the encrypted values files given with the --values flag of cmd
+// and replaces them with their decrypted copies, so that encrypted values are analyzed without a
+// separate decryption step. Files encrypted with age are decrypted when cmd has --decrypt-identity.
+// The copies are removed by removeSecretValues when the command ends.
+func resolveSecretValuesFlag(cmd *cobra.Command) error {
+	flag := cmd.Flags().Lookup("values")
+	if flag == nil || !flag.Changed {
+		return nil
+	}
+	sliceValue, ok := flag.Value.(pflag.SliceValue)
+	if !ok {
+		return nil
+	}
+	files, changed, err := resolveSecretValues(sliceValue.GetSlice())
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Lookup("decrypt-identity") != nil {
+		var ageChanged bool
+		if files, ageChanged, err = resolveAgeValues(cmd, files); err != nil {
+			return err
+		}
+		changed = changed || ageChanged
+	}
+	if !changed {
+		return nil
+	}
+	if err := sliceValue.Replace(files); err != nil {
+		return fmt.Errorf("failed to replace encrypted values files: %w", err)
+	}
+	return nil
+}
+
+// resolveSecretValues returns files with each encrypted values file replaced by a decrypted copy,
+// and whether any was replaced. Encrypted files are those given as secrets:// references (or any
+// secrets+...:// variant of helm-secrets), and local files encrypted with sops, which helm-secrets
+// decrypts when they are passed to 'helm secrets' as plain paths.
+func resolveSecretValues(files []string) (resolved []string, changed bool, err error) {
+	resolved = make([]string, len(files))
+	for i, file := range files {
+		resolved[i] = file
+		ref := ""
+		switch {
+		case isSecretValuesRef(file):
+			ref = file
+		case isSopsEncrypted(file):
+			ref = secretsScheme + "://" + file
+		default:
+			continue
+		}
+
+		log.Info("Decrypting values file with helm-secrets", "file", file)
+		data, err := fetchSecretValues(ref)
+		if err != nil {
+			return nil, false, &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitHelmCommandFailed,
+				Err:  fmt.Errorf("failed to decrypt values file %s: %w", file, err),
+			}
+		}
+		decrypted, err := writeSecretValues(i, file, data)
+		if err != nil {
+			return nil, false, err
+		}
+		resolved[i], changed = decrypted, true
+	}
+	return resolved, changed, nil
+}
+
+// resolveAgeValues returns files with each local values file encrypted with age, such as overrides
+// written with --encrypt age, replaced by a copy decrypted with the --decrypt-identity files, and
+// whether any was replaced.
+func resolveAgeValues(cmd *cobra.Command, files []string) (resolved []string, changed bool, err error) {
+	identities, err := getStringSliceFlag(cmd, "decrypt-identity")
+	if err != nil {
+		return nil, false, err
+	}
+	resolved = slices.Clone(files)
+	for i, file := range files {
+		data, err := afero.ReadFile(AppFs, file)
+		if err != nil || encryption.Detect(data) != encryption.FormatAge {
+			continue
+		}
+
+		log.Info("Decrypting values file with age", "file", file)
+		data, err = decryptData(getCommandContext(cmd), data, encryption.Options{Format: encryption.FormatAge, Identities: identities})
+		if err != nil {
+			return nil, false, &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  fmt.Errorf("failed to decrypt values file %s: %w", file, err),
+			}
+		}
+		decrypted, err := writeSecretValues(i, file, data)
+		if err != nil {
+			return nil, false, err
+		}
+		resolved[i], changed = decrypted, true
+	}
+	return resolved, changed, nil
+}
+
+// isSecretValuesRef reports whether file is a values file reference handled by helm-secrets
+func isSecretValuesRef(file string) bool {
+	scheme, _, found := strings.Cut(file, "://")
+	return found && (scheme == secretsScheme || strings.HasPrefix(scheme, secretsScheme+"+"))
+}
+
+// isSopsEncrypted reports whether the local file is a YAML file encrypted with sops, which records
+// its encryption metadata, including a message authentication code, under a top-level sops key.
+// Only that key is parsed, so that large values files are not parsed twice. Files that cannot be
+// read or parsed are left for the values loader to report.
+func isSopsEncrypted(file string) bool {
+	data, err := afero.ReadFile(AppFs, file)
+	if err != nil {
+		return false
+	}
+	hasMAC := false
+	isSopsPath := func(path string) bool { return path == sopsKey || path == sopsKey+"."+sopsMACKey }
+	err = analysis.WalkValuesChunks(analysis.NormalizeText(data), "", isSopsPath, func(chunk analysis.ValuesChunk) {
+		if slices.Equal(chunk.Keys, []string{sopsKey}) {
+			// The metadata is too large for one chunk and split into chunks of its keys
+			hasMAC = hasMAC || chunk.Node.Content[0].Value == sopsMACKey
+			return
+		}
+		if chunk.Whole {
+			hasMAC = false
+		}
+		var encrypted struct {
+			Sops map[string]interface{} `yaml:"sops"`
+		}
+		if chunk.Node.Decode(&encrypted) == nil {
+			if _, found := encrypted.Sops[sopsMACKey]; found {
+				hasMAC = true
+			}
+		}
+	})
+	return err == nil && hasMAC
+}
+
+// writeSecretValues writes the decrypted content of the index-th values file to a file readable
+// only by the user, in a directory created for the command, and returns its path
+func writeSecretValues(index int, file string, data []byte) (string, error) {
+	if secretValuesDir == "" {
+		dir, err := os.MkdirTemp("", "irr-secrets-")
+		if err != nil {
+			return "", &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitIOError,
+				Err:  fmt.Errorf("failed to create directory for decrypted values files: %w", err),
+			}
+		}
+		secretValuesDir = dir
+	}
+
+	_, path, found := strings.Cut(file, "://")
+	if !found {
+		path = file
+	}
+	// helm-secrets references may end with ?<file> after a key, e.g. secrets+gpg-import://key.asc?secrets.yaml
+	if _, after, found := strings.Cut(path, "?"); found {
+		path = after
+	}
+	decrypted := filepath.Join(secretValuesDir, fmt.Sprintf("%d-%s", index, filepath.Base(path)))
+	if err := os.WriteFile(decrypted, data, fileutil.ReadWriteUserPermission); err != nil {
+		return "", &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write decrypted values file for %s: %w", file, err),
+		}
+	}
+	return decrypted, nil
+}
+
+// removeSecretValues removes the decrypted copies of encrypted values files
+func removeSecretValues() {
+	if secretValuesDir == "" {
+		return
+	}
+	if err := os.RemoveAll(secretValuesDir); err != nil {
+		log.Warn("Failed to remove decrypted values files", "dir", secretValuesDir, "error", err)
+	}
+	secretValuesDir = ""
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSecretValues(t *testing.T) {
+	originalFs, originalFetch := AppFs, fetchSecretValues
+	t.Cleanup(func() { AppFs, fetchSecretValues = originalFs, originalFetch })
+	AppFs = afero.NewOsFs()
+	t.Cleanup(removeSecretValues)
+
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "values.yaml")
+	encrypted := filepath.Join(dir, "secrets.prod.yaml")
+	require.NoError(t, os.WriteFile(plain, []byte("image:\n  repository: nginx\n"), 0o600))
+	require.NoError(t, os.WriteFile(encrypted, []byte("password: ENC[AES256_GCM,data:abc]\nsops:\n  mac: ENC[AES256_GCM,data:def]\n  version: 3.9.0\n"), 0o600))
+
+	var refs []string
+	fetchSecretValues = func(ref string) ([]byte, error) {
+		refs = append(refs, ref)
+		return []byte("image:\n  registry: quay.io\n"), nil
+	}
+
+	files, changed, err := resolveSecretValues([]string{plain, "secrets://" + encrypted, encrypted, "secrets+gpg-import://key.asc?" + encrypted})
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []string{"secrets://" + encrypted, "secrets://" + encrypted, "secrets+gpg-import://key.asc?" + encrypted}, refs)
+	assert.Equal(t, plain, files[0], "plain values files are used as they are")
+	assert.Equal(t, filepath.Join(secretValuesDir, "1-secrets.prod.yaml"), files[1])
+	assert.Equal(t, filepath.Join(secretValuesDir, "3-secrets.prod.yaml"), files[3])
+	decrypted, err := os.ReadFile(files[2])
+	require.NoError(t, err)
+	assert.Equal(t, "image:\n  registry: quay.io\n", string(decrypted))
+	info, err := os.Stat(files[2])
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	dir = secretValuesDir
+	removeSecretValues()
+	assert.NoDirExists(t, dir)
+
+	files, changed, err = resolveSecretValues([]string{plain})
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, []string{plain}, files)
+
+	fetchSecretValues = func(string) ([]byte, error) { return nil, errors.New("sops: no key could decrypt the data") }
+	_, _, err = resolveSecretValues([]string{"secrets://" + encrypted})
+	var exitErr *exitcodes.ExitCodeError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitcodes.ExitHelmCommandFailed, exitErr.Code)
+	assert.ErrorContains(t, err, "failed to decrypt values file secrets://"+encrypted)
+}
+
+func TestResolveSecretValuesFlag(t *testing.T) {
+	originalFetch := fetchSecretValues
+	t.Cleanup(func() { fetchSecretValues = originalFetch })
+	t.Cleanup(removeSecretValues)
+	fetchSecretValues = func(string) ([]byte, error) { return []byte("replicaCount: 2\n"), nil }
+
+	cmd := newOverrideCmd()
+	require.NoError(t, cmd.Flags().Set("values", "secrets://secrets.yaml"))
+	require.NoError(t, resolveSecretValuesFlag(cmd))
+	files, err := cmd.Flags().GetStringSlice("values")
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(secretValuesDir, "0-secrets.yaml")}, files)
+}
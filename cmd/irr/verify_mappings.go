@@ -0,0 +1,394 @@
+// Package main implements the irr CLI commands.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lucas-albers-lz4/irr/internal/kube"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputFormatTable = "table"
+
+	// Image statuses reported by verify-mappings
+	imageStatusMapped    = "mapped"
+	imageStatusRelocated = "relocated"
+	imageStatusExcluded  = "excluded"
+	imageStatusUnmapped  = "unmapped"
+	imageStatusInvalid   = "invalid"
+)
+
+// kubeClientFactory creates the Kubernetes client used to list running pods. It can be replaced in tests.
+var kubeClientFactory = func() (kube.ClientInterface, error) {
+	client, err := kube.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	return client, nil
+}
+
+// VerifiedImage is a unique image running in the cluster together with its mapping status
+type VerifiedImage struct {
+	Image    string   `json:"image"`
+	Registry string   `json:"registry,omitempty"`
+	Status   string   `json:"status"`
+	Target   string   `json:"target,omitempty"`
+	Pods     []string `json:"pods"`
+}
+
+// MappingVerificationSummary counts the verified images by status
+type MappingVerificationSummary struct {
+	Images    int `json:"images"`
+	Mapped    int `json:"mapped"`
+	Relocated int `json:"relocated"`
+	Excluded  int `json:"excluded"`
+	Unmapped  int `json:"unmapped"`
+	Invalid   int `json:"invalid"`
+}
+
+// MappingVerificationReport is the result of checking running images against the registry mappings
+type MappingVerificationReport struct {
+	Namespace          string                     `json:"namespace"`
+	Summary            MappingVerificationSummary `json:"summary"`
+	UnmappedRegistries []string                   `json:"unmappedRegistries"`
+	Images             []VerifiedImage            `json:"images"`
+}
+
+// verifyMappingsOptions holds the inputs used to classify running images
+type verifyMappingsOptions struct {
+	Mappings          *registry.Mappings
+	TargetRegistry    string
+	SourceRegistries  []string
+	ExcludeRegistries []string
+}
+
+// newVerifyMappingsCmd creates the verify-mappings command
+func newVerifyMappingsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-mappings",
+		Short: "Check that every image running in the cluster is covered by the registry mappings",
+		Long: `Lists the images of all containers in the cluster's pods (init and ephemeral containers included)
+and checks each one against the registry mappings.
+
+Each unique image is reported as:
+  mapped     its registry has a mapping (or falls back to --target-registry)
+  relocated  it is already pulled from a mapping target or --target-registry
+  excluded   its registry is excluded or not in --source-registries
+  unmapped   nothing maps its registry
+  invalid    the image reference could not be parsed
+
+Use --fail-on-unmapped to exit non-zero when unmapped images are found, e.g. in CI.`,
+		Args: cobra.NoArgs,
+		RunE: runVerifyMappings,
+	}
+
+	cmd.Flags().StringSlice("registry-file", nil, "Path to YAML file with registry mappings, or a directory of them; can be repeated, later files override earlier ones (defaults to registry-mappings.yaml in the current directory)")
+	cmd.Flags().StringP("namespace", "n", "", "Only verify pods in this namespace (default: all namespaces)")
+	cmd.Flags().StringP("target-registry", "t", "", "Target registry; images already pulled from it are reported as relocated")
+	cmd.Flags().StringSliceP("source-registries", "s", []string{}, "Only verify images from these registries (comma-separated)")
+	cmd.Flags().StringSliceP("exclude-registries", "e", []string{}, "Registries to exclude from verification (comma-separated)")
+	cmd.Flags().String("output-format", outputFormatTable, "Output format (table or json)")
+	cmd.Flags().StringP("output-file", "o", "", "Write the report to a file instead of stdout")
+	cmd.Flags().Bool("fail-on-unmapped", false, "Exit with a non-zero code if any running image is unmapped")
+
+	if err := cmd.RegisterFlagCompletionFunc("output-format",
+		cobra.FixedCompletions([]string{outputFormatTable, outputFormatJSON}, cobra.ShellCompDirectiveNoFileComp)); err != nil {
+		log.Debug("Failed to register output-format completion", "error", err)
+	}
+
+	return cmd
+}
+
+// runVerifyMappings implements the verify-mappings command logic
+func runVerifyMappings(cmd *cobra.Command, _ []string) error {
+	if err := requireNetwork("verify-mappings (lists the pods running in the cluster)"); err != nil {
+		return err
+	}
+	registryFiles, err := getStringSliceFlag(cmd, "registry-file")
+	if err != nil {
+		return err
+	}
+	namespace, err := getStringFlag(cmd, "namespace")
+	if err != nil {
+		return err
+	}
+	outputFormat, err := getStringFlag(cmd, "output-format")
+	if err != nil {
+		return err
+	}
+	outputFormat = strings.ToLower(outputFormat)
+	if outputFormat != outputFormatTable && outputFormat != outputFormatJSON {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("unsupported output format %q; supported formats: %s, %s", outputFormat, outputFormatTable, outputFormatJSON),
+		}
+	}
+	outputFile, err := getStringFlag(cmd, "output-file")
+	if err != nil {
+		return err
+	}
+	failOnUnmapped, err := getBoolFlag(cmd, "fail-on-unmapped")
+	if err != nil {
+		return err
+	}
+
+	opts := verifyMappingsOptions{}
+	if opts.TargetRegistry, err = getStringFlag(cmd, "target-registry"); err != nil {
+		return err
+	}
+	if opts.SourceRegistries, err = getStringSliceFlag(cmd, "source-registries"); err != nil {
+		return err
+	}
+	if opts.ExcludeRegistries, err = getStringSliceFlag(cmd, "exclude-registries"); err != nil {
+		return err
+	}
+	if opts.Mappings, err = loadVerifyMappings(registryFiles); err != nil {
+		return err
+	}
+
+	client, err := kubeClientFactory()
+	if err != nil {
+		return &exitcodes.ExitCodeError{Code: exitcodes.ExitGeneralRuntimeError, Err: err}
+	}
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	podImages, err := client.ListPodImages(ctx, namespace)
+	if err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitGeneralRuntimeError,
+			Err:  fmt.Errorf("failed to list running images: %w", err),
+		}
+	}
+
+	report := verifyPodImages(podImages, &opts)
+	report.Namespace = namespace
+	if report.Namespace == "" {
+		report.Namespace = "all"
+	}
+	log.Info("Verified running images against registry mappings",
+		"images", report.Summary.Images,
+		"mapped", report.Summary.Mapped,
+		"relocated", report.Summary.Relocated,
+		"unmapped", report.Summary.Unmapped)
+
+	output, err := renderMappingVerificationReport(report, outputFormat)
+	if err != nil {
+		return err
+	}
+	if outputFile != "" {
+		if err := writeOutputFile(outputFile, output, "Mapping verification report written"); err != nil {
+			return err
+		}
+	} else if _, err := fmt.Fprint(cmd.OutOrStdout(), string(output)); err != nil {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitIOError,
+			Err:  fmt.Errorf("failed to write report to stdout: %w", err),
+		}
+	}
+
+	if failOnUnmapped && report.Summary.Unmapped > 0 {
+		return &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitRegistryDetectionError,
+			Err: fmt.Errorf("%d running image(s) from unmapped registries: %s",
+				report.Summary.Unmapped, strings.Join(report.UnmappedRegistries, ", ")),
+		}
+	}
+	return nil
+}
+
+// loadVerifyMappings loads and merges the registry mappings from registryFiles, falling back to
+// registry-mappings.yaml in the current directory.
+func loadVerifyMappings(registryFiles []string) (*registry.Mappings, error) {
+	if len(registryFiles) == 0 {
+		exists, err := afero.Exists(AppFs, DefaultConfigSkeletonFilename)
+		if err != nil || !exists {
+			return nil, &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitInputConfigurationError,
+				Err:  fmt.Errorf("no registry mappings file found; use --registry-file or create %s", DefaultConfigSkeletonFilename),
+			}
+		}
+		registryFiles = []string{DefaultConfigSkeletonFilename}
+	}
+
+	skipCWDRestriction := integrationTestMode || (os.Getenv("IRR_TESTING") == trueString)
+	config, files, err := loadRegistryConfigs(registryFiles, skipCWDRestriction)
+	if err == nil && config == nil {
+		err = errors.New("no registry mappings file given")
+	}
+	if err != nil {
+		return nil, &exitcodes.ExitCodeError{
+			Code: exitcodes.ExitInputConfigurationError,
+			Err:  fmt.Errorf("failed to load registry mappings from file %s: %w", strings.Join(registryFiles, ", "), err),
+		}
+	}
+	mappings := config.ToMappings()
+	log.Info("Registry mappings loaded successfully", "files", files, "count", len(mappings.Entries))
+	return mappings, nil
+}
+
+// verifyPodImages classifies every unique running image against the registry mappings.
+func verifyPodImages(podImages []kube.PodImage, opts *verifyMappingsOptions) *MappingVerificationReport {
+	relocatedRegistries := make(map[string]bool)
+	if opts.TargetRegistry != "" {
+		relocatedRegistries[image.NormalizeRegistry(opts.TargetRegistry)] = true
+	}
+	if opts.Mappings != nil {
+		for _, mapping := range opts.Mappings.Entries {
+			if strings.TrimSpace(mapping.Target) != "" {
+				relocatedRegistries[image.NormalizeRegistry(mapping.Target)] = true
+			}
+		}
+	}
+	sourceRegistries := normalizedRegistrySet(opts.SourceRegistries)
+	excludeRegistries := normalizedRegistrySet(opts.ExcludeRegistries)
+
+	// Group pods by image so each image is classified once
+	podsByImage := make(map[string]map[string]bool)
+	for _, podImage := range podImages {
+		if podsByImage[podImage.Image] == nil {
+			podsByImage[podImage.Image] = make(map[string]bool)
+		}
+		podsByImage[podImage.Image][podImage.Namespace+"/"+podImage.Pod] = true
+	}
+
+	report := &MappingVerificationReport{
+		UnmappedRegistries: []string{},
+		Images:             make([]VerifiedImage, 0, len(podsByImage)),
+	}
+	unmappedRegistries := make(map[string]bool)
+	for imageRef, pods := range podsByImage {
+		verified := VerifiedImage{Image: imageRef, Pods: sortedKeys(pods)}
+		ref, err := image.ParseRenderedImageReference(imageRef)
+		if err != nil {
+			log.Debug("Failed to parse running image reference", "image", imageRef, "error", err)
+			verified.Status = imageStatusInvalid
+		} else {
+			verified.Registry = image.NormalizeRegistry(ref.Registry)
+			verified.Status, verified.Target = classifyRegistry(verified.Registry, opts, relocatedRegistries, sourceRegistries, excludeRegistries)
+		}
+
+		switch verified.Status {
+		case imageStatusMapped:
+			report.Summary.Mapped++
+		case imageStatusRelocated:
+			report.Summary.Relocated++
+		case imageStatusExcluded:
+			report.Summary.Excluded++
+		case imageStatusUnmapped:
+			report.Summary.Unmapped++
+			unmappedRegistries[verified.Registry] = true
+		case imageStatusInvalid:
+			report.Summary.Invalid++
+		}
+		report.Images = append(report.Images, verified)
+	}
+	report.Summary.Images = len(report.Images)
+	report.UnmappedRegistries = append(report.UnmappedRegistries, sortedKeys(unmappedRegistries)...)
+
+	// Unmapped images first, then by image reference
+	sort.Slice(report.Images, func(i, j int) bool {
+		left, right := report.Images[i], report.Images[j]
+		if (left.Status == imageStatusUnmapped) != (right.Status == imageStatusUnmapped) {
+			return left.Status == imageStatusUnmapped
+		}
+		if left.Status != right.Status {
+			return left.Status < right.Status
+		}
+		return left.Image < right.Image
+	})
+	return report
+}
+
+// classifyRegistry returns the mapping status of a normalized registry and, when mapped, its target.
+func classifyRegistry(registryName string, opts *verifyMappingsOptions, relocated, sources, excluded map[string]bool) (status, target string) {
+	if relocated[registryName] {
+		return imageStatusRelocated, ""
+	}
+	if excluded[registryName] || (len(sources) > 0 && !sources[registryName]) {
+		return imageStatusExcluded, ""
+	}
+	if target := opts.Mappings.GetTargetRegistry(registryName); target != "" {
+		return imageStatusMapped, target
+	}
+	// Like override, explicitly listed source registries fall back to --target-registry
+	if opts.TargetRegistry != "" && sources[registryName] {
+		return imageStatusMapped, opts.TargetRegistry
+	}
+	return imageStatusUnmapped, ""
+}
+
+// normalizedRegistrySet returns the set of normalized registry names.
+func normalizedRegistrySet(registries []string) map[string]bool {
+	set := make(map[string]bool, len(registries))
+	for _, r := range registries {
+		if strings.TrimSpace(r) != "" {
+			set[image.NormalizeRegistry(r)] = true
+		}
+	}
+	return set
+}
+
+// sortedKeys returns the keys of set in sorted order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// renderMappingVerificationReport renders the report as a table or JSON.
+func renderMappingVerificationReport(report *MappingVerificationReport, outputFormat string) ([]byte, error) {
+	if outputFormat == outputFormatJSON {
+		output, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return nil, &exitcodes.ExitCodeError{
+				Code: exitcodes.ExitGeneralRuntimeError,
+				Err:  fmt.Errorf("failed to marshal report to JSON: %w", err),
+			}
+		}
+		return append(output, '\n'), nil
+	}
+
+	var buf strings.Builder
+	writer := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(writer, "STATUS\tREGISTRY\tIMAGE\tTARGET\tPODS"); err != nil {
+		return nil, fmt.Errorf("failed to render report table: %w", err)
+	}
+	for _, img := range report.Images {
+		target := img.Target
+		if target == "" {
+			target = "-"
+		}
+		if _, err := fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\n", img.Status, img.Registry, img.Image, target, len(img.Pods)); err != nil {
+			return nil, fmt.Errorf("failed to render report table: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to render report table: %w", err)
+	}
+
+	summary := report.Summary
+	fmt.Fprintf(&buf, "\n%d images in namespace %s: %d mapped, %d relocated, %d excluded, %d unmapped, %d invalid\n",
+		summary.Images, report.Namespace, summary.Mapped, summary.Relocated, summary.Excluded, summary.Unmapped, summary.Invalid)
+	if len(report.UnmappedRegistries) > 0 {
+		fmt.Fprintf(&buf, "Unmapped registries: %s\n", strings.Join(report.UnmappedRegistries, ", "))
+	}
+	return []byte(buf.String()), nil
+}
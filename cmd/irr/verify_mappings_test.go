@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/internal/kube"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKubeClient implements kube.ClientInterface with canned pod images and config objects, and
+// records the ConfigMap data written to it in puts (keyed by namespace/name/key)
+type fakeKubeClient struct {
+	images  []kube.PodImage
+	configs []*kube.ConfigData
+	puts    map[string]string
+	err     error
+}
+
+func (c *fakeKubeClient) ListPodImages(_ context.Context, namespace string) ([]kube.PodImage, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	var images []kube.PodImage
+	for _, img := range c.images {
+		if namespace == "" || img.Namespace == namespace {
+			images = append(images, img)
+		}
+	}
+	return images, nil
+}
+
+func (c *fakeKubeClient) GetConfigData(_ context.Context, namespace, name string) (*kube.ConfigData, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	for _, config := range c.configs {
+		if config.Namespace == namespace && config.Name == name {
+			return config, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no ConfigMap or Secret %s/%s", kube.ErrConfigNotFound, namespace, name)
+}
+
+func (c *fakeKubeClient) PutConfigMapData(_ context.Context, namespace, name, key string, data []byte) error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.puts == nil {
+		c.puts = make(map[string]string)
+	}
+	c.puts[namespace+"/"+name+"/"+key] = string(data)
+	return nil
+}
+
+// withKubeClient replaces the Kubernetes client factory for the duration of a test
+func withKubeClient(t *testing.T, client kube.ClientInterface) {
+	t.Helper()
+	original := kubeClientFactory
+	kubeClientFactory = func() (kube.ClientInterface, error) {
+		return client, nil
+	}
+	t.Cleanup(func() { kubeClientFactory = original })
+}
+
+var verifyTestPodImages = []kube.PodImage{
+	{Namespace: "web", Pod: "nginx-0", Container: "nginx", Image: "docker.io/library/nginx:1.25"},
+	{Namespace: "web", Pod: "nginx-1", Container: "nginx", Image: "docker.io/library/nginx:1.25"},
+	{Namespace: "monitoring", Pod: "prometheus-0", Container: "prometheus", Image: "quay.io/prometheus/prometheus:v2.51.0"},
+	{Namespace: "monitoring", Pod: "grafana-0", Container: "grafana", Image: "ghcr.io/grafana/grafana:10.4.0"},
+	{Namespace: "kube-system", Pod: "coredns-0", Container: "coredns", Image: "registry.k8s.io/coredns/coredns:v1.11.1"},
+	{Namespace: "web", Pod: "app-0", Container: "app", Image: "harbor.local/dockerhub/library/redis:7.2"},
+}
+
+func TestVerifyPodImages(t *testing.T) {
+	opts := &verifyMappingsOptions{
+		Mappings: &registry.Mappings{Entries: []registry.Mapping{
+			{Source: "docker.io", Target: "harbor.local/dockerhub"},
+			{Source: "quay.io", Target: "harbor.local/quay"},
+		}},
+		ExcludeRegistries: []string{"registry.k8s.io"},
+	}
+
+	report := verifyPodImages(verifyTestPodImages, opts)
+
+	assert.Equal(t, MappingVerificationSummary{Images: 5, Mapped: 2, Relocated: 1, Excluded: 1, Unmapped: 1}, report.Summary)
+	assert.Equal(t, []string{"ghcr.io"}, report.UnmappedRegistries)
+
+	statuses := make(map[string]VerifiedImage)
+	for _, img := range report.Images {
+		statuses[img.Image] = img
+	}
+	nginx := statuses["docker.io/library/nginx:1.25"]
+	assert.Equal(t, imageStatusMapped, nginx.Status)
+	assert.Equal(t, "harbor.local/dockerhub", nginx.Target)
+	assert.Equal(t, []string{"web/nginx-0", "web/nginx-1"}, nginx.Pods)
+	assert.Equal(t, imageStatusRelocated, statuses["harbor.local/dockerhub/library/redis:7.2"].Status)
+	assert.Equal(t, imageStatusExcluded, statuses["registry.k8s.io/coredns/coredns:v1.11.1"].Status)
+
+	// Unmapped images are listed first
+	require.NotEmpty(t, report.Images)
+	assert.Equal(t, imageStatusUnmapped, report.Images[0].Status)
+}
+
+func TestVerifyPodImagesSourceRegistriesFallback(t *testing.T) {
+	opts := &verifyMappingsOptions{
+		TargetRegistry:   "registry.example.com",
+		SourceRegistries: []string{"ghcr.io", "docker.io"},
+	}
+
+	report := verifyPodImages(verifyTestPodImages, opts)
+
+	statuses := make(map[string]string)
+	for _, img := range report.Images {
+		statuses[img.Registry] = img.Status
+	}
+	assert.Equal(t, imageStatusMapped, statuses["ghcr.io"], "listed sources fall back to --target-registry")
+	assert.Equal(t, imageStatusMapped, statuses["docker.io"])
+	assert.Equal(t, imageStatusExcluded, statuses["quay.io"], "registries outside --source-registries are excluded")
+	assert.Empty(t, report.UnmappedRegistries)
+}
+
+func TestRunVerifyMappings(t *testing.T) {
+	t.Setenv("IRR_TESTING", trueString)
+	mappingsFile := filepath.Join(t.TempDir(), "mappings.yaml")
+	content := `registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/dockerhub
+      enabled: true
+`
+	require.NoError(t, os.WriteFile(mappingsFile, []byte(content), 0o600))
+
+	t.Run("table output", func(t *testing.T) {
+		withKubeClient(t, &fakeKubeClient{images: verifyTestPodImages})
+		cmd := newVerifyMappingsCmd()
+		out := new(bytes.Buffer)
+		cmd.SetOut(out)
+		cmd.SetArgs([]string{"--registry-file", mappingsFile, "--namespace", "web"})
+
+		require.NoError(t, cmd.Execute())
+		assert.Contains(t, out.String(), "STATUS")
+		assert.Contains(t, out.String(), "docker.io/library/nginx:1.25")
+		assert.Contains(t, out.String(), "2 images in namespace web")
+	})
+
+	t.Run("json output", func(t *testing.T) {
+		withKubeClient(t, &fakeKubeClient{images: verifyTestPodImages})
+		cmd := newVerifyMappingsCmd()
+		out := new(bytes.Buffer)
+		cmd.SetOut(out)
+		cmd.SetArgs([]string{"--registry-file", mappingsFile, "--output-format", "json"})
+
+		require.NoError(t, cmd.Execute())
+		var report MappingVerificationReport
+		require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+		assert.Equal(t, "all", report.Namespace)
+		assert.Equal(t, []string{"ghcr.io", "quay.io", "registry.k8s.io"}, report.UnmappedRegistries)
+	})
+
+	t.Run("fail on unmapped", func(t *testing.T) {
+		withKubeClient(t, &fakeKubeClient{images: verifyTestPodImages})
+		cmd := newVerifyMappingsCmd()
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetArgs([]string{"--registry-file", mappingsFile, "--fail-on-unmapped"})
+
+		err := cmd.Execute()
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, exitcodes.ExitRegistryDetectionError, exitErr.Code)
+	})
+
+	t.Run("kube error", func(t *testing.T) {
+		withKubeClient(t, &fakeKubeClient{err: errors.New("connection refused")})
+		cmd := newVerifyMappingsCmd()
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetArgs([]string{"--registry-file", mappingsFile})
+
+		err := cmd.Execute()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "connection refused")
+	})
+
+	t.Run("unsupported output format", func(t *testing.T) {
+		withKubeClient(t, &fakeKubeClient{images: verifyTestPodImages})
+		cmd := newVerifyMappingsCmd()
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetArgs([]string{"--registry-file", mappingsFile, "--output-format", "yaml"})
+
+		var exitErr *exitcodes.ExitCodeError
+		require.ErrorAs(t, cmd.Execute(), &exitErr)
+		assert.Equal(t, exitcodes.ExitInputConfigurationError, exitErr.Code)
+	})
+}
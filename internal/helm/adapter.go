@@ -17,11 +17,11 @@ import (
 	"github.com/lucas-albers-lz4/irr/pkg/image"
 	log "github.com/lucas-albers-lz4/irr/pkg/log"
 	"github.com/lucas-albers-lz4/irr/pkg/strategy"
+	"github.com/lucas-albers-lz4/irr/pkg/timing"
 	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart/loader"
-	"helm.sh/helm/v3/pkg/cli"
 )
 
 // FileMode constants for directories and files
@@ -34,6 +34,8 @@ type Adapter struct {
 	helmClient        ClientInterface
 	fs                afero.Fs
 	isRunningAsPlugin bool
+	retryConfig       RetryConfig
+	retryStats        retryStatsRecorder
 }
 
 // AnalysisResult represents the result of chart analysis
@@ -70,9 +72,32 @@ func NewAdapter(helmClient ClientInterface, fs afero.Fs, isPlugin bool) *Adapter
 		helmClient:        helmClient,
 		fs:                fs,
 		isRunningAsPlugin: isPlugin,
+		retryConfig:       DefaultRetryConfig(),
 	}
 }
 
+// SetRetryConfig sets how failed Helm SDK calls made through the adapter are retried
+func (a *Adapter) SetRetryConfig(cfg RetryConfig) {
+	a.retryConfig = cfg
+}
+
+// RetryStats returns the Helm SDK call and retry counts accumulated by the adapter
+func (a *Adapter) RetryStats() RetryStats {
+	return a.retryStats.snapshot()
+}
+
+// LogRetryStats writes the accumulated retry counts to the debug log
+func (a *Adapter) LogRetryStats() {
+	stats := a.RetryStats()
+	log.Debug("Helm SDK retry stats",
+		"calls", stats.Calls,
+		"retries", stats.Retries,
+		"recovered", stats.Recovered,
+		"transientErrors", stats.TransientErrors,
+		"permanentErrors", stats.PermanentErrors,
+		"exhausted", stats.Exhausted)
+}
+
 // InspectRelease inspects a Helm release to identify image references
 func (a *Adapter) InspectRelease(ctx context.Context, releaseName, namespace, outputFile string) error {
 	// Validate plugin mode
@@ -84,7 +109,7 @@ func (a *Adapter) InspectRelease(ctx context.Context, releaseName, namespace, ou
 	}
 
 	// Get release values from Helm
-	values, err := a.helmClient.GetReleaseValues(ctx, releaseName, namespace)
+	values, err := a.getReleaseValues(ctx, releaseName, namespace)
 	if err != nil {
 		if IsReleaseNotFoundError(err) {
 			return &exitcodes.ExitCodeError{
@@ -97,7 +122,7 @@ func (a *Adapter) InspectRelease(ctx context.Context, releaseName, namespace, ou
 	}
 
 	// Get chart metadata for the release
-	chartMeta, err := a.helmClient.GetChartFromRelease(ctx, releaseName, namespace)
+	chartMeta, err := a.getChartFromRelease(ctx, releaseName, namespace)
 	if err != nil {
 		return fmt.Errorf("failed to get chart metadata for release %q: %w", releaseName, err)
 	}
@@ -194,7 +219,7 @@ func (a *Adapter) OverrideRelease(ctx context.Context, releaseName, namespace st
 	}
 
 	// Get release values from Helm
-	liveValues, err := a.helmClient.GetReleaseValues(ctx, releaseName, namespace)
+	liveValues, err := a.getReleaseValues(ctx, releaseName, namespace)
 	if err != nil {
 		if IsReleaseNotFoundError(err) {
 			return "", &exitcodes.ExitCodeError{
@@ -207,7 +232,7 @@ func (a *Adapter) OverrideRelease(ctx context.Context, releaseName, namespace st
 	}
 
 	// Get chart metadata for the release (needed for fallback path)
-	chartMeta, err := a.helmClient.GetChartFromRelease(ctx, releaseName, namespace)
+	chartMeta, err := a.getChartFromRelease(ctx, releaseName, namespace)
 	if err != nil {
 		return "", fmt.Errorf("failed to get release chart metadata before override: %w", err)
 	}
@@ -406,6 +431,7 @@ func (a *Adapter) ValidateRelease(ctx context.Context, releaseName, namespace st
 	}
 
 	log.Debug("ValidateRelease called", "kubeVersion", kubeVersion)
+	defer timing.Start(timing.SpanValidation, namespace+"/"+releaseName)()
 
 	// Validate plugin mode
 	if !a.isRunningAsPlugin {
@@ -416,7 +442,7 @@ func (a *Adapter) ValidateRelease(ctx context.Context, releaseName, namespace st
 	}
 
 	// Get release values from Helm
-	values, err := a.helmClient.GetReleaseValues(ctx, releaseName, namespace)
+	values, err := a.getReleaseValues(ctx, releaseName, namespace)
 	if err != nil {
 		if IsReleaseNotFoundError(err) {
 			return &exitcodes.ExitCodeError{
@@ -429,7 +455,7 @@ func (a *Adapter) ValidateRelease(ctx context.Context, releaseName, namespace st
 	}
 
 	// Get chart metadata for the release
-	chartMeta, err := a.helmClient.GetChartFromRelease(ctx, releaseName, namespace)
+	chartMeta, err := a.getChartFromRelease(ctx, releaseName, namespace)
 	if err != nil {
 		return fmt.Errorf("failed to get chart metadata for release %q: %w", releaseName, err)
 	}
@@ -552,7 +578,7 @@ func handleChartYamlMissingWithSDK(_, _, originalChartPath string, _ *RealHelmCl
 	log.Debug("Extracted chart details from path", "chartName", chartName, "chartVersion", chartVersion, "originalPath", originalChartPath)
 
 	// Try to use LocateChart directly
-	settings := cli.New()
+	settings := NewSettings()
 	chartPathOptions := action.ChartPathOptions{
 		Version: chartVersion,
 	}
@@ -622,7 +648,7 @@ func (a *Adapter) resolveChartPath(meta *ChartMetadata) (string, error) {
 	log.Debug("resolveChartPath proceeding with Helm SDK/cache lookup", "chartName", meta.Name, "version", meta.Version)
 
 	// Create Helm settings to access Helm's configuration
-	settings := cli.New()
+	settings := NewSettings()
 
 	// Create a chart path options object to leverage Helm's chart location functionality
 	chartPathOptions := action.ChartPathOptions{
@@ -730,9 +756,47 @@ func (a *Adapter) resolveChartPath(meta *ChartMetadata) (string, error) {
 
 // Add wrapper methods to expose client functionality
 
+// ListReleases lists Helm releases, optionally across all namespaces, retrying transient failures.
+func (a *Adapter) ListReleases(ctx context.Context, allNamespaces bool) ([]*ReleaseElement, error) {
+	releases, err := withRetry(ctx, a.retryConfig, &a.retryStats, "list releases", func() ([]*ReleaseElement, error) {
+		return a.helmClient.ListReleases(ctx, allNamespaces)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Helm releases: %w", err)
+	}
+	return releases, nil
+}
+
+// ListReleasesWithOptions lists Helm releases narrowed by a label selector and limit, retrying
+// transient failures.
+func (a *Adapter) ListReleasesWithOptions(ctx context.Context, opts ReleaseListOptions) ([]*ReleaseElement, error) {
+	releases, err := withRetry(ctx, a.retryConfig, &a.retryStats, "list releases", func() ([]*ReleaseElement, error) {
+		return a.helmClient.ListReleasesWithOptions(ctx, opts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Helm releases: %w", err)
+	}
+	return releases, nil
+}
+
+// getReleaseValues fetches release values from the client, retrying transient failures
+func (a *Adapter) getReleaseValues(ctx context.Context, releaseName, namespace string) (map[string]interface{}, error) {
+	return withRetry(ctx, a.retryConfig, &a.retryStats, "get release values", func() (map[string]interface{}, error) {
+		return a.helmClient.GetReleaseValues(ctx, releaseName, namespace)
+	})
+}
+
+// getChartFromRelease fetches release chart metadata from the client, retrying transient failures
+func (a *Adapter) getChartFromRelease(ctx context.Context, releaseName, namespace string) (*ChartMetadata, error) {
+	return withRetry(ctx, a.retryConfig, &a.retryStats, "get release chart", func() (*ChartMetadata, error) {
+		return a.helmClient.GetChartFromRelease(ctx, releaseName, namespace)
+	})
+}
+
 // GetReleaseValues retrieves the computed values for a deployed release, wrapping potential errors.
+// Transient failures are retried.
 func (a *Adapter) GetReleaseValues(ctx context.Context, releaseName, namespace string) (map[string]interface{}, error) {
-	values, err := a.helmClient.GetReleaseValues(ctx, releaseName, namespace)
+	values, err := a.getReleaseValues(ctx, releaseName, namespace)
 	if err != nil {
 		// Wrap the error for context
 		return nil, fmt.Errorf("failed to get values for release '%s' in namespace '%s': %w", releaseName, namespace, err)
@@ -741,10 +805,35 @@ func (a *Adapter) GetReleaseValues(ctx context.Context, releaseName, namespace s
 }
 
 // GetChartFromRelease retrieves the chart metadata associated with a deployed release, wrapping potential errors.
+// Transient failures are retried.
 func (a *Adapter) GetChartFromRelease(ctx context.Context, releaseName, namespace string) (*ChartMetadata, error) {
-	chartMetadata, err := a.helmClient.GetChartFromRelease(ctx, releaseName, namespace)
+	chartMetadata, err := a.getChartFromRelease(ctx, releaseName, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get release chart metadata via adapter: %w", err)
 	}
 	return chartMetadata, nil
 }
+
+// GetReleaseValuesAtRevision retrieves the computed values for a specific revision of a release.
+// A revision of 0 selects the latest revision. Transient failures are retried.
+func (a *Adapter) GetReleaseValuesAtRevision(ctx context.Context, releaseName, namespace string, revision int) (map[string]interface{}, error) {
+	values, err := withRetry(ctx, a.retryConfig, &a.retryStats, "get release values", func() (map[string]interface{}, error) {
+		return a.helmClient.GetReleaseValuesAtRevision(ctx, releaseName, namespace, revision)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get values for release '%s' revision %d in namespace '%s': %w", releaseName, revision, namespace, err)
+	}
+	return values, nil
+}
+
+// GetChartFromReleaseAtRevision retrieves the chart metadata for a specific revision of a release.
+// A revision of 0 selects the latest revision. Transient failures are retried.
+func (a *Adapter) GetChartFromReleaseAtRevision(ctx context.Context, releaseName, namespace string, revision int) (*ChartMetadata, error) {
+	chartMetadata, err := withRetry(ctx, a.retryConfig, &a.retryStats, "get release chart", func() (*ChartMetadata, error) {
+		return a.helmClient.GetChartFromReleaseAtRevision(ctx, releaseName, namespace, revision)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release chart metadata for revision %d via adapter: %w", revision, err)
+	}
+	return chartMetadata, nil
+}
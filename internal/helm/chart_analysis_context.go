@@ -4,6 +4,7 @@ package helm
 import (
 	"strings"
 
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
 	"github.com/lucas-albers-lz4/irr/pkg/log"
 	"helm.sh/helm/v3/pkg/chart"
 )
@@ -19,6 +20,15 @@ type ChartAnalysisContext struct {
 	// Origin tracking for values
 	Origins map[string]ValueOrigin
 
+	// Value paths populated through YAML aliases or merge keys, mapped to their anchor path
+	AnchorPaths AnchorPaths
+
+	// Positions of the image values in the values files that set them last, once located
+	SourceLocations analysis.SourceLocations
+
+	// locate locates values paths in the values files; nil if the values were not loaded from files
+	locate func(paths []string) analysis.SourceLocations
+
 	// Metadata about this analysis
 	ChartName    string
 	ChartVersion string
@@ -39,7 +49,7 @@ func (ctx *ChartAnalysisContext) GetSourcePathForValue(valuePath string) string
 	if !exists {
 		// If exact path not found, try checking parent paths for aliasing/global overrides
 		// This is a simplified check; more robust logic might be needed.
-		parts := strings.Split(valuePath, ".")
+		parts := analysis.SplitPath(valuePath)
 		for i := len(parts) - 1; i > 0; i-- {
 			parentPath := strings.Join(parts[:i], ".")
 			if parentOrigin, parentExists := ctx.Origins[parentPath]; parentExists {
@@ -90,6 +100,16 @@ func (ctx *ChartAnalysisContext) GetSourcePathForValue(valuePath string) string
 	}
 }
 
+// GetAnchorSourceForValue returns the anchor path a value was copied from via a YAML alias
+// or merge key, and false if the value was defined directly.
+func (ctx *ChartAnalysisContext) GetAnchorSourceForValue(valuePath string) (string, bool) {
+	if ctx.AnchorPaths == nil {
+		return "", false
+	}
+	source, exists := ctx.AnchorPaths[valuePath]
+	return source, exists
+}
+
 // NewChartAnalysisContext creates a new context for chart analysis.
 func NewChartAnalysisContext(chartData *chart.Chart, values map[string]interface{}, origins map[string]ValueOrigin, valuesFiles, setValues []string) *ChartAnalysisContext {
 	return &ChartAnalysisContext{
@@ -0,0 +1,196 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+)
+
+const (
+	// gitChartRefPrefix starts chart references to charts in git repositories
+	gitChartRefPrefix = "git+"
+	// gitTokenEnv holds the token cloning git repositories over HTTPS authenticates with
+	gitTokenEnv = "IRR_GIT_TOKEN"
+	// gitTokenUsername is the user the token authenticates as when the URL names none; GitHub
+	// expects it, and GitLab and Gitea accept any user with a token
+	gitTokenUsername = "x-access-token"
+)
+
+// ErrInvalidGitChartRef is returned for git chart references that cannot be parsed.
+var ErrInvalidGitChartRef = errors.New("git chart reference must be git+https://host/repo[//path/to/chart][?ref=branch, tag or commit]")
+
+// ErrNoChartInRepository is returned when the path of a git chart reference holds no chart.
+var ErrNoChartInRepository = errors.New("no Chart.yaml found")
+
+// runGit runs git with args in dir, with env added to the environment, and returns its standard
+// output. It is a variable so tests can replace it.
+var runGit = func(ctx context.Context, dir string, env []string, args ...string) ([]byte, error) {
+	command := exec.CommandContext(ctx, "git", args...)
+	command.Dir = dir
+	// Fail instead of prompting for credentials
+	command.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), env...)
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	output, err := command.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("git is not installed or not on PATH: %w", err)
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("git %s failed: %w: %s", args[0], err, message)
+		}
+		return nil, fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return output, nil
+}
+
+// GitChartRef is a chart in a git repository, referenced as
+// git+https://host/org/repo//path/to/chart?ref=v1.2.3.
+type GitChartRef struct {
+	// Repository is the URL the repository is cloned from, without the git+ prefix
+	Repository string
+	// Path is the directory of the chart in the repository, slash-separated; empty for the root
+	Path string
+	// Ref is the branch, tag or full commit ID checked out; empty for the default branch
+	Ref string
+}
+
+// IsGitChartRef reports whether ref references a chart in a git repository.
+func IsGitChartRef(ref string) bool {
+	return strings.HasPrefix(ref, gitChartRefPrefix)
+}
+
+// ParseGitChartRef parses a git chart reference: git+ followed by the https, http, ssh or file
+// URL of the repository, optionally the chart's path in the repository after a double slash, and
+// the branch, tag or commit to check out as the ref query parameter.
+func ParseGitChartRef(ref string) (*GitChartRef, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: %s in %q", ErrInvalidGitChartRef, reason, ref)
+	}
+	repoURL, err := url.Parse(strings.TrimPrefix(ref, gitChartRefPrefix))
+	if err != nil || !IsGitChartRef(ref) {
+		return nil, invalid("not a URL")
+	}
+	switch repoURL.Scheme {
+	case "https", "http", "ssh", "file":
+	default:
+		return nil, invalid(fmt.Sprintf("unsupported scheme %q", repoURL.Scheme))
+	}
+	if repoURL.Scheme != "file" && repoURL.Host == "" {
+		return nil, invalid("no host")
+	}
+	if _, hasPassword := repoURL.User.Password(); hasPassword {
+		return nil, invalid(fmt.Sprintf("credentials in the URL; set %s instead", gitTokenEnv))
+	}
+
+	chart := &GitChartRef{}
+	query := repoURL.Query()
+	for key := range query {
+		if key != "ref" {
+			return nil, invalid(fmt.Sprintf("unknown parameter %q", key))
+		}
+	}
+	chart.Ref = query.Get("ref")
+	if strings.HasPrefix(chart.Ref, "-") {
+		return nil, invalid("ref starting with -")
+	}
+	repoURL.RawQuery, repoURL.Fragment = "", ""
+
+	if repoPath, chartPath, found := strings.Cut(strings.TrimPrefix(repoURL.Path, "/"), "//"); found {
+		chartPath = path.Clean(strings.Trim(chartPath, "/"))
+		if chartPath == ".." || strings.HasPrefix(chartPath, "../") {
+			return nil, invalid("chart path outside the repository")
+		}
+		if chartPath != "." {
+			chart.Path = chartPath
+		}
+		repoURL.Path = "/" + repoPath
+		repoURL.RawPath = ""
+	}
+	if strings.Trim(repoURL.Path, "/") == "" {
+		return nil, invalid("no repository path")
+	}
+	chart.Repository = repoURL.String()
+	return chart, nil
+}
+
+// String returns the reference without credentials, for logs and errors.
+func (r *GitChartRef) String() string {
+	s := gitChartRefPrefix + r.Repository
+	if r.Path != "" {
+		s += "//" + r.Path
+	}
+	if r.Ref != "" {
+		s += "?ref=" + r.Ref
+	}
+	return s
+}
+
+// CloneGitChartRef shallow-clones the repository of ref into dir, which must be empty or not
+// exist, checks out ref.Ref, and returns the path of the chart directory and the commit checked
+// out. Over HTTP(S), the token in IRR_GIT_TOKEN is sent if set; otherwise, as for ssh URLs, git's
+// own credential helpers and keys apply.
+func CloneGitChartRef(ctx context.Context, ref *GitChartRef, dir string) (chartPath, commit string, err error) {
+	if err := os.MkdirAll(dir, fileutil.ReadWriteExecuteUserReadGroup); err != nil {
+		return "", "", fmt.Errorf("failed to create directory %s for %s: %w", dir, ref, err)
+	}
+	env := gitAuthEnv(ref.Repository, os.Getenv(gitTokenEnv))
+	target := ref.Ref
+	if target == "" {
+		target = "HEAD"
+	}
+
+	log.Info("Cloning chart repository", "repository", ref.Repository, "ref", target)
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"fetch", "--quiet", "--depth", "1", "--no-tags", ref.Repository, target},
+		{"-c", "advice.detachedHead=false", "checkout", "--quiet", "FETCH_HEAD"},
+	} {
+		if _, err := runGit(ctx, dir, env, args...); err != nil {
+			return "", "", fmt.Errorf("failed to clone %s: %w", ref, err)
+		}
+	}
+	output, err := runGit(ctx, dir, nil, "rev-parse", "HEAD")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read the commit of %s: %w", ref, err)
+	}
+	commit = strings.TrimSpace(string(output))
+
+	chartPath = filepath.Join(dir, filepath.FromSlash(ref.Path))
+	if _, err := os.Stat(filepath.Join(chartPath, "Chart.yaml")); err != nil {
+		return "", "", fmt.Errorf("%w at path %q of %s (commit %s)", ErrNoChartInRepository, ref.Path, ref.Repository, commit)
+	}
+	return chartPath, commit, nil
+}
+
+// gitAuthEnv returns the environment that makes git send token as HTTP basic authentication to
+// the host of repository, through GIT_CONFIG_* variables so that it appears neither in the
+// command line nor in the repository's config. It returns nil without a token, or for URLs that
+// are not HTTP(S).
+func gitAuthEnv(repository, token string) []string {
+	repoURL, err := url.Parse(repository)
+	if token == "" || err != nil || (repoURL.Scheme != "https" && repoURL.Scheme != "http") {
+		return nil
+	}
+	username := repoURL.User.Username()
+	if username == "" {
+		username = gitTokenUsername
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + token))
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		fmt.Sprintf("GIT_CONFIG_KEY_0=http.%s://%s/.extraHeader", repoURL.Scheme, repoURL.Host),
+		"GIT_CONFIG_VALUE_0=Authorization: Basic " + credentials,
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"os"
 	"strings"
 
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/limits"
 	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/timing"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 	"helm.sh/helm/v3/pkg/chart"
@@ -30,6 +33,10 @@ type ChartLoaderOptions struct {
 
 	// ValuesOptions contains values flag options
 	ValuesOpts values.Options
+
+	// Dependencies configures downloading dependencies missing from the chart, including oci://
+	// dependencies that need registry credentials. When nil, missing dependencies are skipped.
+	Dependencies *DependencyOptions
 }
 
 // ChartLoader is an interface for loading charts and computing values.
@@ -52,9 +59,9 @@ func NewChartLoader() ChartLoader {
 // LoadChartWithValues implements ChartLoader.LoadChartWithValues.
 func (l *DefaultChartLoader) LoadChartWithValues(opts *ChartLoaderOptions) (*chart.Chart, map[string]interface{}, error) {
 	// Load the chart
-	loadedChart, err := loader.Load(opts.ChartPath)
+	loadedChart, err := loadChartWithDependencies(opts)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to load chart")
+		return nil, nil, err
 	}
 
 	// Process values options and get user values
@@ -74,29 +81,38 @@ func (l *DefaultChartLoader) LoadChartWithValues(opts *ChartLoaderOptions) (*cha
 
 // LoadChartAndTrackOrigins implements ChartLoader.LoadChartAndTrackOrigins.
 func (l *DefaultChartLoader) LoadChartAndTrackOrigins(opts *ChartLoaderOptions) (*ChartAnalysisContext, error) {
+	defer timing.Start(timing.SpanChartLoad, opts.ChartPath)()
+
 	// Load the chart
-	loadedChart, err := loader.Load(opts.ChartPath)
+	loadedChart, err := loadChartWithDependencies(opts)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to load chart")
+		return nil, err
+	}
+
+	// Each user values file is read and parsed once, chunk by chunk; the origins of its values
+	// and the paths derived from its anchors are tracked as its chunks are parsed.
+	origins := make(map[string]ValueOrigin)
+	valueFiles, err := parseValuesFiles(opts.ValuesOpts.ValueFiles, origins)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to process user provided values")
 	}
 
 	// 1. Process USER-PROVIDED values into userValues map
-	userValues, err := processUserProvidedValues(opts)
+	userValues, err := processUserProvidedValues(opts, valueFiles)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to process user provided values")
 	}
 
 	// 2. Merge chart default values with processed user values to get FINAL structure
 	log.Debug("LoadChartAndTrackOrigins: Coalescing final values...")
-	mergedValues, err := chartutil.CoalesceValues(loadedChart, userValues)
+	mergedValues, err := coalesceUserValues(loadedChart, userValues)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to coalesce final values")
+		return nil, errors.Wrap(err, "failed to coalesce values")
 	}
 	log.Debug("LoadChartAndTrackOrigins: Final merged values structure obtained (before alias correction)", "keys", mapKeys(mergedValues))
 
 	// 3. Track Origins based on precedence (User > Parent Default > Subchart Default)
-	origins, err := trackValueOrigins(loadedChart, opts, userValues, mergedValues)
-	if err != nil {
+	if err := trackValueOrigins(loadedChart, opts, origins); err != nil {
 		return nil, errors.Wrap(err, "failed to track value origins")
 	}
 
@@ -110,25 +126,70 @@ func (l *DefaultChartLoader) LoadChartAndTrackOrigins(opts *ChartLoaderOptions)
 	}
 	log.Debug("LoadChartAndTrackOrigins: Final keys in corrected merged values", "keys", finalKeys)
 
-	// 5. Create context with final values and origins
+	// 5. Track values derived from YAML anchors so their provenance survives anchor resolution
+	anchorPaths := trackAnchorPaths(loadedChart, valueFiles)
+	log.Debug("LoadChartAndTrackOrigins: Tracked anchor-derived value paths", "count", len(anchorPaths))
+
+	// 7. Create context with final values and origins
 	log.Debug("LoadChartAndTrackOrigins: Final keys in origins map before return", "keys", mapKeysFromOrigin(origins))
-	return NewChartAnalysisContext(
+	analysisContext := NewChartAnalysisContext(
 		loadedChart,
 		correctedMergedValues, // Use the alias-corrected map
 		origins,               // Use the layered origins map
 		opts.ValuesOpts.ValueFiles,
-		append(append(opts.ValuesOpts.Values, opts.ValuesOpts.StringValues...), opts.ValuesOpts.FileValues...),
-	), nil
+		setValueArgs(&opts.ValuesOpts),
+	)
+	analysisContext.AnchorPaths = anchorPaths
+	// Values are located in the values files once the analysis knows the paths of its images
+	analysisContext.locate = func(paths []string) analysis.SourceLocations {
+		return locateValues(loadedChart, opts.ChartPath, opts.ValuesOpts.ValueFiles, origins, paths)
+	}
+	return analysisContext, nil
+}
+
+// loadChartWithDependencies loads the chart, downloading the dependencies missing from its charts/
+// directory first when opts.Dependencies is set
+func loadChartWithDependencies(opts *ChartLoaderOptions) (*chart.Chart, error) {
+	loadedChart, err := loader.Load(opts.ChartPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load chart")
+	}
+	analysis.NormalizeChartFiles(loadedChart)
+	if err := analysis.CheckChartValuesSize(loadedChart, opts.ChartPath); err != nil {
+		return nil, err
+	}
+	built, err := buildMissingDependencies(opts.ChartPath, loadedChart, opts.Dependencies)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve dependencies of chart %s", opts.ChartPath)
+	}
+	if !built {
+		return loadedChart, nil
+	}
+	loadedChart, err = loader.Load(opts.ChartPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load chart with downloaded dependencies")
+	}
+	analysis.NormalizeChartFiles(loadedChart)
+	if err := analysis.CheckChartValuesSize(loadedChart, opts.ChartPath); err != nil {
+		return nil, err
+	}
+	return loadedChart, nil
 }
 
 // processUserProvidedValues extracts user-provided values from options.
-func processUserProvidedValues(opts *ChartLoaderOptions) (map[string]interface{}, error) {
+func processUserProvidedValues(opts *ChartLoaderOptions, valueFiles []*parsedValuesFile) (map[string]interface{}, error) {
 	log.Debug("processUserProvidedValues: Processing user-provided values...")
 	userValues := map[string]interface{}{}
 	// Process values files
-	for _, file := range opts.ValuesOpts.ValueFiles {
+	for _, file := range valueFiles {
 		if err := mergeUserValuesFileWithOrigin(file, userValues, nil); err != nil { // Pass nil for origins
-			return nil, errors.Wrapf(err, "failed to merge values file %s", file)
+			return nil, errors.Wrapf(err, "failed to merge values file %s", file.path)
+		}
+	}
+	// Process JSON values, which Helm applies before --set
+	for _, val := range opts.ValuesOpts.JSONValues {
+		if err := strvals.ParseJSON(val, userValues); err != nil {
+			return nil, errors.Wrapf(err, "failed to apply JSON value %s", val)
 		}
 	}
 	// Process set values
@@ -153,39 +214,35 @@ func processUserProvidedValues(opts *ChartLoaderOptions) (map[string]interface{}
 			return nil, errors.Wrapf(err, "failed applying file value content for %s", key)
 		}
 	}
+	// Process literal values, which Helm applies last
+	for _, val := range opts.ValuesOpts.LiteralValues {
+		if err := strvals.ParseLiteralInto(val, userValues); err != nil {
+			return nil, errors.Wrapf(err, "failed to apply literal value %s", val)
+		}
+	}
 	log.Debug("processUserProvidedValues: Finished processing user-provided values", "keys", mapKeys(userValues))
 	return userValues, nil
 }
 
-// trackValueOrigins tracks origins based on precedence.
-func trackValueOrigins(loadedChart *chart.Chart, opts *ChartLoaderOptions, _, _ map[string]interface{}) (map[string]ValueOrigin, error) {
+// trackValueOrigins tracks origins based on precedence, adding to the origins of the user values
+// files, which are tracked as they are parsed.
+func trackValueOrigins(loadedChart *chart.Chart, opts *ChartLoaderOptions, origins map[string]ValueOrigin) error {
 	log.Debug("trackValueOrigins: Starting origin tracking...")
-	origins := make(map[string]ValueOrigin)
-
-	// Track User File Origins
-	log.Debug("trackValueOrigins: Tracking origins from user files...")
-	for _, file := range opts.ValuesOpts.ValueFiles {
-		bytes, err := os.ReadFile(file) //nolint:gosec // filePath is validated earlier in the process
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to re-read values file %s for origin tracking", file)
-		}
-		var fileValues map[string]interface{}
-		if err := yaml.Unmarshal(bytes, &fileValues); err != nil {
-			return nil, errors.Wrapf(err, "failed to re-parse values file %s for origin tracking", file)
-		}
-		forceFlattenAndTrackOrigins(fileValues, origins, ValueOrigin{Type: OriginUserFile, Path: file}, "")
-	}
 
 	// Track User --set Origins
 	log.Debug("trackValueOrigins: Tracking origins from --set values...")
-	// Recombine set values for iteration (appendAssign fix applied here requires this approach)
-	allSetValues := make([]string, 0, len(opts.ValuesOpts.Values)+len(opts.ValuesOpts.StringValues))
+	// Recombine set values for iteration (appendAssign fix applied here requires this approach);
+	// --set-json and --set-literal values are --set values of another syntax
+	allSetValues := make([]string, 0, len(opts.ValuesOpts.JSONValues)+len(opts.ValuesOpts.Values)+
+		len(opts.ValuesOpts.StringValues)+len(opts.ValuesOpts.LiteralValues))
+	allSetValues = append(allSetValues, opts.ValuesOpts.JSONValues...)
 	allSetValues = append(allSetValues, opts.ValuesOpts.Values...)
 	allSetValues = append(allSetValues, opts.ValuesOpts.StringValues...)
+	allSetValues = append(allSetValues, opts.ValuesOpts.LiteralValues...)
 	for _, val := range allSetValues {
 		key, _, err := parseSetKey(val)
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed parsing key from set value %s for origin tracking", val)
+			return errors.Wrapf(err, "failed parsing key from set value %s for origin tracking", val)
 		}
 		origins[key] = ValueOrigin{Type: OriginUserSet, Path: val}
 		// TODO: Handle nested keys from --set
@@ -196,7 +253,7 @@ func trackValueOrigins(loadedChart *chart.Chart, opts *ChartLoaderOptions, _, _
 	for _, val := range opts.ValuesOpts.FileValues {
 		key, _, err := parseFileSet(val)
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed parsing key from file value %s for origin tracking", val)
+			return errors.Wrapf(err, "failed parsing key from file value %s for origin tracking", val)
 		}
 		origins[key] = ValueOrigin{Type: OriginUserFileSet, Path: val}
 		// TODO: Handle nested keys from --set-file?
@@ -215,7 +272,41 @@ func trackValueOrigins(loadedChart *chart.Chart, opts *ChartLoaderOptions, _, _
 	trackAllSubchartValues(loadedChart, origins, ".")
 
 	log.Debug("trackValueOrigins: Finished origin tracking.")
-	return origins, nil
+	return nil
+}
+
+// coalesceUserValues is chartutil.CoalesceValues without its deep copy of the user values that no
+// chart default or dependency shares a top-level key with. Coalescing never reads or changes those,
+// so they are set in the result as they are, and a large values file (e.g. vendored CRDs) is not
+// copied.
+func coalesceUserValues(loadedChart *chart.Chart, userValues map[string]interface{}) (map[string]interface{}, error) {
+	coalesced := make(map[string]interface{}, len(userValues))
+	unshared := make(map[string]interface{})
+	for key, value := range userValues {
+		if _, ok := loadedChart.Values[key]; ok || key == chartutil.GlobalKey || isDependencyName(loadedChart, key) {
+			coalesced[key] = value
+		} else {
+			unshared[key] = value
+		}
+	}
+	merged, err := chartutil.CoalesceValues(loadedChart, coalesced)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range unshared {
+		merged[key] = value
+	}
+	return merged, nil
+}
+
+// isDependencyName reports whether name is the name of a dependency of the chart.
+func isDependencyName(loadedChart *chart.Chart, name string) bool {
+	for _, dep := range loadedChart.Dependencies() {
+		if dep.Name() == name {
+			return true
+		}
+	}
+	return false
 }
 
 // applyAliasCorrection adjusts the merged values map based on dependency aliases.
@@ -278,10 +369,14 @@ func processValuesOptions(valuesOpts *values.Options) (map[string]interface{}, e
 	for _, filePath := range valuesOpts.ValueFiles {
 		currentMap := map[string]interface{}{}
 
-		// Validate file exists
-		if _, err := os.Stat(filePath); err != nil {
+		// Validate file exists and is within the values size limit
+		info, err := os.Stat(filePath)
+		if err != nil {
 			return nil, errors.Errorf("values file %q not accessible: %s", filePath, err)
 		}
+		if err := limits.CheckValuesSize(filePath, info.Size()); err != nil {
+			return nil, err
+		}
 
 		// Read and parse the file
 		// G304: Potential file inclusion vulnerability - filePath needs validation.
@@ -290,7 +385,7 @@ func processValuesOptions(valuesOpts *values.Options) (map[string]interface{}, e
 			return nil, errors.Wrapf(err, "failed to read values file %s", filePath)
 		}
 
-		if err := yaml.Unmarshal(bytes, &currentMap); err != nil {
+		if err := yaml.Unmarshal(analysis.NormalizeText(bytes), &currentMap); err != nil {
 			return nil, errors.Wrapf(err, "failed parsing values file %s", filePath)
 		}
 
@@ -298,6 +393,13 @@ func processValuesOptions(valuesOpts *values.Options) (map[string]interface{}, e
 		base = chartutil.CoalesceTables(base, currentMap)
 	}
 
+	// Process --set-json values, which Helm applies before --set
+	for _, value := range valuesOpts.JSONValues {
+		if err := strvals.ParseJSON(value, base); err != nil {
+			return nil, errors.Wrapf(err, "failed parsing --set-json %s", value)
+		}
+	}
+
 	// Process --set values
 	for _, value := range valuesOpts.Values {
 		if err := strvals.ParseInto(value, base); err != nil {
@@ -332,9 +434,27 @@ func processValuesOptions(valuesOpts *values.Options) (map[string]interface{}, e
 		}
 	}
 
+	// Process --set-literal values, which Helm applies last
+	for _, value := range valuesOpts.LiteralValues {
+		if err := strvals.ParseLiteralInto(value, base); err != nil {
+			return nil, errors.Wrapf(err, "failed parsing --set-literal %s", value)
+		}
+	}
+
 	return base, nil
 }
 
+// setValueArgs returns the values of every --set flag kind in the order Helm applies them
+func setValueArgs(valuesOpts *values.Options) []string {
+	args := make([]string, 0, len(valuesOpts.JSONValues)+len(valuesOpts.Values)+len(valuesOpts.StringValues)+
+		len(valuesOpts.FileValues)+len(valuesOpts.LiteralValues))
+	args = append(args, valuesOpts.JSONValues...)
+	args = append(args, valuesOpts.Values...)
+	args = append(args, valuesOpts.StringValues...)
+	args = append(args, valuesOpts.FileValues...)
+	return append(args, valuesOpts.LiteralValues...)
+}
+
 // trackAllSubchartValues recursively traverses dependencies and tracks their default values.
 func trackAllSubchartValues(parentChart *chart.Chart, origins map[string]ValueOrigin, parentPrefix string) {
 	if parentChart == nil || parentChart.Metadata == nil {
@@ -377,10 +497,7 @@ func trackAllSubchartValues(parentChart *chart.Chart, origins map[string]ValueOr
 // It now respects precedence and does NOT overwrite existing origins.
 func flattenAndTrackValues(valuesMap map[string]interface{}, origins map[string]ValueOrigin, origin ValueOrigin, prefix string) {
 	for k, v := range valuesMap {
-		keyPath := k
-		if prefix != "" {
-			keyPath = prefix + "." + k
-		}
+		keyPath := analysis.JoinPath(prefix, k)
 
 		// Only record the origin if this path hasn't been recorded yet.
 		// This prioritizes higher-precedence sources (user files, --set) tracked earlier.
@@ -398,22 +515,10 @@ func flattenAndTrackValues(valuesMap map[string]interface{}, origins map[string]
 }
 
 // mergeUserValuesFileWithOrigin only merges values now, origin tracking happens later.
-func mergeUserValuesFileWithOrigin(fileName string, valuesMap map[string]interface{}, _ map[string]ValueOrigin /* origins no longer modified here */) error {
-	// Read and parse the file
-	// G304: Potential file inclusion vulnerability - fileName needs validation.
-	bytes, err := os.ReadFile(fileName) //nolint:gosec // NOTE: Needs validation to prevent reading arbitrary files.
-	if err != nil {
-		return errors.Wrapf(err, "failed to read values file %s", fileName)
-	}
-
-	var fileValues map[string]interface{}
-	if err := yaml.Unmarshal(bytes, &fileValues); err != nil {
-		return errors.Wrapf(err, "failed to parse values file %s", fileName)
-	}
-
+func mergeUserValuesFileWithOrigin(file *parsedValuesFile, valuesMap map[string]interface{}, _ map[string]ValueOrigin /* origins no longer modified here */) error {
 	// Merge with existing values (mutates the 'values' map)
-	// Note: CoalesceTables merges fileValues INTO valuesMap
-	chartutil.CoalesceTables(valuesMap, fileValues)
+	// Note: CoalesceTables merges the file values INTO valuesMap
+	chartutil.CoalesceTables(valuesMap, file.values)
 	return nil
 }
 
@@ -462,25 +567,4 @@ func mapKeys(m map[string]interface{}) []string {
 	return keys
 }
 
-// forceFlattenAndTrackOrigins is similar to flattenAndTrackValues but *always* sets the origin,
-// effectively overwriting any previous origin for the same path. Used for higher-precedence sources.
-func forceFlattenAndTrackOrigins(valuesMap map[string]interface{}, origins map[string]ValueOrigin, origin ValueOrigin, prefix string) {
-	for k, v := range valuesMap {
-		keyPath := k
-		if prefix != "" {
-			keyPath = prefix + "." + k
-		}
-
-		// Always set/overwrite the origin for this path
-		log.Debug("forceFlattenAndTrackOrigins: Setting/Overwriting origin", "keyPath", keyPath, "originType", origin.Type, "originPath", origin.Path)
-		origins[keyPath] = origin
-
-		// Recursively process nested maps
-		if nestedMap, ok := v.(map[string]interface{}); ok {
-			// Pass the same origin down for nested structures within this source.
-			forceFlattenAndTrackOrigins(nestedMap, origins, origin, keyPath)
-		}
-	}
-}
-
 // END OF FILE - Ensure no other definitions of these functions exist below.
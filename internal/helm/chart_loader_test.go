@@ -2,7 +2,10 @@ package helm
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"unicode/utf16"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -51,6 +54,26 @@ func TestDefaultChartLoader_LoadChartWithValues(t *testing.T) {
 	})
 }
 
+func TestProcessValuesOptions_JSONAndLiteral(t *testing.T) {
+	base, err := processValuesOptions(&values.Options{
+		JSONValues:    []string{`sidecar.image={"repository":"quay.io/org/proxy","tag":"1.0"}`, `args=["--verbose","-v"]`},
+		Values:        []string{"sidecar.image.tag=2.0"},
+		LiteralValues: []string{"note=a,b=c"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"sidecar": map[string]interface{}{
+			"image": map[string]interface{}{"repository": "quay.io/org/proxy", "tag": "2.0"},
+		},
+		"args": []interface{}{"--verbose", "-v"},
+		"note": "a,b=c",
+	}, base, "--set-json is applied before --set, and --set-literal keeps commas and '='")
+
+	_, err = processValuesOptions(&values.Options{JSONValues: []string{"image={bad"}})
+	assert.ErrorContains(t, err, "failed parsing --set-json image={bad")
+}
+
 func TestDefaultChartLoader_LoadChartAndTrackOrigins(t *testing.T) {
 	if _, err := os.Stat(TestChartPath); err != nil {
 		t.Skipf("Skipping test: chart path does not exist: %v", err)
@@ -176,3 +199,48 @@ child:
 		}
 	})
 }
+
+func TestDefaultChartLoader_LoadChartAndTrackOrigins_EncodingQuirks(t *testing.T) {
+	const chartValues = "image:\n  repository: nginx\n  tag: \"1.25\"\n"
+	const userValues = "sidecar: &sidecar\n  image: quay.io/prometheus/node-exporter:v1.8.0\nworker: *sidecar\n"
+	crlf := func(text string) string { return strings.ReplaceAll(text, "\n", "\r\n") }
+	utf16LE := func(text string) []byte {
+		data := []byte{0xFF, 0xFE}
+		for _, unit := range utf16.Encode([]rune(text)) {
+			data = append(data, byte(unit), byte(unit>>8))
+		}
+		return data
+	}
+
+	load := func(t *testing.T, chartValuesData, userValuesData []byte) *ChartAnalysisContext {
+		t.Helper()
+		dir := t.TempDir()
+		chartDir := filepath.Join(dir, "quirks")
+		require.NoError(t, os.MkdirAll(filepath.Join(chartDir, "templates"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: quirks\nversion: 1.0.0\n"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(chartDir, "values.yaml"), chartValuesData, 0o600))
+		userValuesPath := filepath.Join(dir, "user.yaml")
+		require.NoError(t, os.WriteFile(userValuesPath, userValuesData, 0o600))
+
+		context, err := NewChartLoader().LoadChartAndTrackOrigins(&ChartLoaderOptions{
+			ChartPath:  chartDir,
+			ValuesOpts: values.Options{ValueFiles: []string{userValuesPath}},
+		})
+		require.NoError(t, err)
+		_, err = NewContextAwareAnalyzer(context).AnalyzeContext()
+		require.NoError(t, err)
+		for path, location := range context.SourceLocations {
+			location.File = filepath.Base(location.File)
+			context.SourceLocations[path] = location
+		}
+		return context
+	}
+
+	want := load(t, []byte(chartValues), []byte(userValues))
+	got := load(t, utf16LE(crlf(chartValues)), append([]byte{0xEF, 0xBB, 0xBF}, crlf(userValues)...))
+	assert.Equal(t, want.Values, got.Values)
+	assert.Equal(t, want.SourceLocations, got.SourceLocations)
+	assert.NotEmpty(t, got.SourceLocations, "the images are located")
+	assert.Equal(t, want.AnchorPaths, got.AnchorPaths)
+	assert.NotEmpty(t, got.AnchorPaths, "anchors of the user values file are tracked")
+}
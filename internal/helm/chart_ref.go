@@ -0,0 +1,148 @@
+package helm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// ErrInvalidChartRef is returned for chart references that are neither repo/chart nor oci://.
+var ErrInvalidChartRef = errors.New("chart reference must be repo/chart or oci://registry/path/chart")
+
+// Variable to support mocking the download in tests
+var downloadChartRef = func(dl *downloader.ChartDownloader, ref, version, dest string) (string, error) {
+	path, _, err := dl.DownloadTo(ref, version, dest)
+	return path, err
+}
+
+// CachedChartRef returns the path of the repository chart ref (repo/chart, with the repository
+// added by 'helm repo add') at version (a version or constraint, the latest if empty) in Helm's
+// repository cache, as resolved from the repository index cached by 'helm repo update'. It
+// returns an empty path when the chart has not been downloaded yet, or when the cached archive
+// does not match the digest in the index, and always for oci:// charts, which are not cached.
+func CachedChartRef(ref, version string) (string, error) {
+	if registry.IsOCI(ref) {
+		return "", nil
+	}
+	settings := NewSettings()
+	chartVersion, err := resolveRepoChart(settings, ref, version)
+	if err != nil {
+		return "", err
+	}
+	chartURL, err := url.Parse(chartVersion.URLs[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid URL of chart %s %s: %w", ref, chartVersion.Version, err)
+	}
+	// The name the downloader saves the chart archive under
+	path := filepath.Join(settings.RepositoryCache, filepath.Base(chartURL.Path))
+	if _, err := os.Stat(path); err != nil {
+		log.Debug("Chart is not in the repository cache", "chart", ref, "version", chartVersion.Version, "path", path)
+		return "", nil
+	}
+	if chartVersion.Digest != "" {
+		digest, err := fileDigest(path)
+		if err != nil || digest != strings.TrimPrefix(chartVersion.Digest, "sha256:") {
+			log.Debug("Cached chart does not match the digest of the repository index", "chart", ref, "path", path, "error", err)
+			return "", nil
+		}
+	}
+	return path, nil
+}
+
+// DownloadChartRef downloads the chart ref (repo/chart or oci://registry/path/chart) at version
+// into Helm's repository cache, as 'helm pull' does, and returns its path. opts authenticate to
+// the registry of an oci:// chart and configure TLS.
+func DownloadChartRef(ref, version string, opts *DependencyOptions) (string, error) {
+	settings := NewSettings()
+	if !registry.IsOCI(ref) {
+		chartVersion, err := resolveRepoChart(settings, ref, version)
+		if err != nil {
+			return "", err
+		}
+		version = chartVersion.Version // Download exactly the version CachedChartRef looks for
+	}
+	if opts == nil {
+		opts = &DependencyOptions{}
+	}
+	client, err := newDependencyRegistryClient(opts, settings)
+	if err != nil {
+		return "", err
+	}
+	dl := &downloader.ChartDownloader{
+		Out:              io.Discard,
+		Getters:          getter.All(settings, repositoryTLSOptions(opts.Transport.Default)...),
+		Options:          []getter.Option{getter.WithRegistryClient(client)},
+		RegistryClient:   client,
+		RepositoryConfig: settings.RepositoryConfig,
+		RepositoryCache:  settings.RepositoryCache,
+	}
+	if err := os.MkdirAll(settings.RepositoryCache, fileutil.ReadWriteExecuteUserReadExecuteOthers); err != nil {
+		return "", fmt.Errorf("failed to create Helm repository cache %s: %w", settings.RepositoryCache, err)
+	}
+	log.Info("Downloading chart", "chart", ref, "version", version)
+	path, err := downloadChartRef(dl, ref, version, settings.RepositoryCache)
+	if err != nil {
+		return "", fmt.Errorf("failed to download chart %s: %w", ref, err)
+	}
+	return path, nil
+}
+
+// resolveRepoChart looks up the repository chart ref at version in the repository index cached by
+// 'helm repo update'
+func resolveRepoChart(settings *cli.EnvSettings, ref, version string) (*repo.ChartVersion, error) {
+	repoName, chartName, found := strings.Cut(ref, "/")
+	if !found || repoName == "" || chartName == "" || strings.Contains(chartName, "/") {
+		return nil, fmt.Errorf("%w, got %q", ErrInvalidChartRef, ref)
+	}
+	repoFile, err := repo.LoadFile(settings.RepositoryConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Helm repositories from %s (add the repository with 'helm repo add'): %w", settings.RepositoryConfig, err)
+	}
+	entry := repoFile.Get(repoName)
+	if entry == nil {
+		return nil, fmt.Errorf("repository %q of chart %s not found; add it with 'helm repo add %s <url>'", repoName, ref, repoName)
+	}
+	index, err := repo.LoadIndexFile(filepath.Join(settings.RepositoryCache, helmpath.CacheIndexFile(repoName)))
+	if err != nil {
+		return nil, fmt.Errorf("no cached index of repository %q (run 'helm repo update %s'): %w", repoName, repoName, err)
+	}
+	chartVersion, err := index.Get(chartName, version)
+	if err != nil {
+		return nil, fmt.Errorf("chart %s matching version %q not found in the index of repository %q (run 'helm repo update %s'): %w", ref, version, repoName, repoName, err)
+	}
+	if len(chartVersion.URLs) == 0 {
+		return nil, fmt.Errorf("chart %s %s has no download URL in the index of repository %q", ref, chartVersion.Version, repoName)
+	}
+	return chartVersion, nil
+}
+
+// fileDigest returns the hex-encoded SHA-256 digest of the file at path, as in repository indexes
+func fileDigest(path string) (string, error) {
+	file, err := os.Open(path) //nolint:gosec // path is in Helm's repository cache
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() {
+		_ = file.Close() //nolint:errcheck // read-only file
+	}()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,104 @@
+package helm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/downloader"
+)
+
+// setupChartRepository configures Helm with the repository "example", whose cached index lists
+// the chart app at 1.0.0 and 1.1.0 with the digest of archive, and returns the repository cache
+func setupChartRepository(t *testing.T, archive []byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	require.NoError(t, os.MkdirAll(cacheDir, 0o755))
+	repoConfig := filepath.Join(dir, "repositories.yaml")
+	require.NoError(t, os.WriteFile(repoConfig, []byte(`apiVersion: ""
+repositories:
+- name: example
+  url: https://charts.example.com
+`), 0o600))
+	digest := sha256.Sum256(archive)
+	index := "apiVersion: v1\nentries:\n  app:\n"
+	for _, version := range []string{"1.1.0", "1.0.0"} {
+		index += fmt.Sprintf("  - apiVersion: v2\n    name: app\n    version: %s\n    digest: %s\n    urls:\n    - https://charts.example.com/app-%s.tgz\n",
+			version, hex.EncodeToString(digest[:]), version)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "example-index.yaml"), []byte(index), 0o600))
+	t.Setenv("HELM_REPOSITORY_CONFIG", repoConfig)
+	t.Setenv("HELM_REPOSITORY_CACHE", cacheDir)
+	return cacheDir
+}
+
+func TestCachedChartRef(t *testing.T) {
+	archive := []byte("chart archive")
+	cacheDir := setupChartRepository(t, archive)
+
+	path, err := CachedChartRef("example/app", "1.0.0")
+	require.NoError(t, err)
+	assert.Empty(t, path, "chart not downloaded yet")
+
+	cached := filepath.Join(cacheDir, "app-1.0.0.tgz")
+	require.NoError(t, os.WriteFile(cached, archive, 0o600))
+	path, err = CachedChartRef("example/app", "~1.0")
+	require.NoError(t, err)
+	assert.Equal(t, cached, path)
+
+	path, err = CachedChartRef("example/app", "")
+	require.NoError(t, err)
+	assert.Empty(t, path, "latest version 1.1.0 is not cached")
+
+	require.NoError(t, os.WriteFile(cached, []byte("truncated"), 0o600))
+	path, err = CachedChartRef("example/app", "1.0.0")
+	require.NoError(t, err)
+	assert.Empty(t, path, "archive not matching the index digest")
+
+	path, err = CachedChartRef("oci://registry.example.com/charts/app", "1.0.0")
+	require.NoError(t, err)
+	assert.Empty(t, path)
+}
+
+func TestCachedChartRefErrors(t *testing.T) {
+	setupChartRepository(t, nil)
+
+	_, err := CachedChartRef("app", "")
+	require.ErrorIs(t, err, ErrInvalidChartRef)
+
+	_, err = CachedChartRef("missing/app", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "helm repo add missing")
+
+	_, err = CachedChartRef("example/app", "2.0.0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "helm repo update example")
+}
+
+func TestDownloadChartRef(t *testing.T) {
+	cacheDir := setupChartRepository(t, nil)
+	original := downloadChartRef
+	t.Cleanup(func() { downloadChartRef = original })
+
+	var gotRef, gotVersion, gotDest string
+	downloadChartRef = func(_ *downloader.ChartDownloader, ref, version, dest string) (string, error) {
+		gotRef, gotVersion, gotDest = ref, version, dest
+		return filepath.Join(dest, "app-"+version+".tgz"), nil
+	}
+	path, err := DownloadChartRef("example/app", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(cacheDir, "app-1.1.0.tgz"), path)
+	assert.Equal(t, "example/app", gotRef)
+	assert.Equal(t, "1.1.0", gotVersion, "constraint resolved from the index")
+	assert.Equal(t, cacheDir, gotDest)
+
+	_, err = DownloadChartRef("oci://registry.example.com/charts/app", "2.0.0", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0", gotVersion)
+}
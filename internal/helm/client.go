@@ -36,6 +36,12 @@ type ClientInterface interface {
 	GetReleaseValues(ctx context.Context, releaseName, namespace string) (map[string]interface{}, error)
 	// GetChartFromRelease gets the chart metadata associated with a deployed Helm release.
 	GetChartFromRelease(ctx context.Context, releaseName, namespace string) (*ChartMetadata, error)
+	// GetReleaseValuesAtRevision retrieves the computed values for a specific revision of a Helm release.
+	// A revision of 0 selects the latest revision.
+	GetReleaseValuesAtRevision(ctx context.Context, releaseName, namespace string, revision int) (map[string]interface{}, error)
+	// GetChartFromReleaseAtRevision gets the chart metadata for a specific revision of a Helm release.
+	// A revision of 0 selects the latest revision.
+	GetChartFromReleaseAtRevision(ctx context.Context, releaseName, namespace string, revision int) (*ChartMetadata, error)
 	// FindChartForRelease locates the chart source corresponding to a deployed Helm release.
 	FindChartForRelease(ctx context.Context, releaseName, namespace string) (string, error)
 	// TemplateChart renders the templates for a given chart and values.
@@ -44,6 +50,8 @@ type ClientInterface interface {
 	LoadChart(chartPath string) (*helmChart.Chart, error)
 	// ListReleases lists Helm releases, optionally across all namespaces.
 	ListReleases(ctx context.Context, allNamespaces bool) ([]*ReleaseElement, error)
+	// ListReleasesWithOptions lists Helm releases narrowed by a label selector and limit.
+	ListReleasesWithOptions(ctx context.Context, opts ReleaseListOptions) ([]*ReleaseElement, error)
 
 	// Environment information
 	GetCurrentNamespace() string
@@ -55,9 +63,22 @@ type ClientInterface interface {
 type ReleaseElement struct {
 	Name      string
 	Namespace string
+	// Chart is the name of the chart the release was installed from, when known
+	Chart string
 	// Add other fields from release.Release if needed (e.g., Status, ChartVersion)
 }
 
+// ReleaseListOptions narrows the releases returned by ListReleasesWithOptions
+type ReleaseListOptions struct {
+	// AllNamespaces lists releases in every namespace instead of the current one
+	AllNamespaces bool
+	// Selector is a Kubernetes label selector matched against the release labels
+	// (e.g. app.kubernetes.io/part-of=platform); empty matches every release
+	Selector string
+	// Limit is the maximum number of releases returned; 0 means no limit
+	Limit int
+}
+
 // RealHelmClient implements ClientInterface using the actual Helm SDK
 type RealHelmClient struct {
 	settings     *cli.EnvSettings
@@ -66,7 +87,7 @@ type RealHelmClient struct {
 
 // NewHelmClient creates a new instance of the RealHelmClient
 func NewHelmClient() (*RealHelmClient, error) {
-	settings := cli.New()
+	settings := NewSettings()
 	actionConfig := new(action.Configuration)
 
 	// Initialize with default namespace, will be overridden in operations
@@ -81,8 +102,14 @@ func NewHelmClient() (*RealHelmClient, error) {
 }
 
 // GetReleaseValues fetches values from an installed Helm release
-func (c *RealHelmClient) GetReleaseValues(_ context.Context, releaseName, namespace string) (map[string]interface{}, error) {
-	log.Debug("Getting release values", "release", releaseName, "namespace", namespace)
+func (c *RealHelmClient) GetReleaseValues(ctx context.Context, releaseName, namespace string) (map[string]interface{}, error) {
+	return c.GetReleaseValuesAtRevision(ctx, releaseName, namespace, 0)
+}
+
+// GetReleaseValuesAtRevision fetches values from a specific revision of an installed Helm release.
+// Revision 0 selects the latest revision, matching the behavior of `helm get values`.
+func (c *RealHelmClient) GetReleaseValuesAtRevision(_ context.Context, releaseName, namespace string, revision int) (map[string]interface{}, error) {
+	log.Debug("Getting release values", "release", releaseName, "namespace", namespace, "revision", revision)
 
 	// Ensure namespace is set (use default from settings if empty)
 	originalNamespace := c.settings.Namespace()
@@ -105,24 +132,29 @@ func (c *RealHelmClient) GetReleaseValues(_ context.Context, releaseName, namesp
 	// Create a new get values action using the (now hopefully correctly scoped) shared actionConfig
 	client := action.NewGetValues(c.actionConfig)
 	client.AllValues = true // Get both user-supplied and computed values
+	client.Version = revision
 
 	// Execute the get values action
 	values, err := client.Run(releaseName)
 	if err != nil {
 		// Use the target namespace in the error message
+		if revision > 0 {
+			return nil, fmt.Errorf("failed to get values for release %q revision %d in namespace %q: %w", releaseName, revision, targetNamespace, err)
+		}
 		return nil, fmt.Errorf("failed to get values for release %q in namespace %q: %w", releaseName, targetNamespace, err)
 	}
 
 	return values, nil
 }
 
-// TemplateChart renders the templates for a given chart and values.
-func (c *RealHelmClient) TemplateChart(_ context.Context, releaseName, namespace, chartPath string, values map[string]interface{}) (string, error) {
-	return c.templateChart(context.Background(), releaseName, chartPath, values, namespace, "")
+// TemplateChart renders the templates for a given chart and values. Nothing is rendered once ctx
+// is canceled.
+func (c *RealHelmClient) TemplateChart(ctx context.Context, releaseName, namespace, chartPath string, values map[string]interface{}) (string, error) {
+	return c.templateChart(ctx, releaseName, chartPath, values, namespace, "")
 }
 
 // templateChart is the original implementation with the original signature
-func (c *RealHelmClient) templateChart(_ context.Context, releaseName, chartPath string, values map[string]interface{}, namespace, kubeVersion string) (string, error) {
+func (c *RealHelmClient) templateChart(ctx context.Context, releaseName, chartPath string, values map[string]interface{}, namespace, kubeVersion string) (string, error) {
 	log.Debug("Templating chart", "chartPath", chartPath, "release", releaseName, "namespace", namespace)
 
 	// --- Capture Helm SDK logs ---
@@ -188,6 +220,10 @@ func (c *RealHelmClient) templateChart(_ context.Context, releaseName, chartPath
 	}
 
 	// Template the release
+	if err := ctx.Err(); err != nil {
+		processHelmLogs(&helmLogBuffer)
+		return "", fmt.Errorf("helm SDK templating canceled for chart %s: %w", chartPath, err)
+	}
 	release, err := client.Run(chart, filteredVals)
 
 	// --- Process captured Helm logs ---
@@ -305,8 +341,14 @@ func (c *RealHelmClient) getActionConfig(namespace string) (*action.Configuratio
 }
 
 // GetChartFromRelease fetches chart metadata from an installed Helm release
-func (c *RealHelmClient) GetChartFromRelease(_ context.Context, releaseName, namespace string) (*ChartMetadata, error) {
-	log.Debug("Getting release chart info", "release", releaseName, "namespace", namespace)
+func (c *RealHelmClient) GetChartFromRelease(ctx context.Context, releaseName, namespace string) (*ChartMetadata, error) {
+	return c.GetChartFromReleaseAtRevision(ctx, releaseName, namespace, 0)
+}
+
+// GetChartFromReleaseAtRevision fetches chart metadata from a specific revision of an installed Helm release.
+// Revision 0 selects the latest revision.
+func (c *RealHelmClient) GetChartFromReleaseAtRevision(_ context.Context, releaseName, namespace string, revision int) (*ChartMetadata, error) {
+	log.Debug("Getting release chart info", "release", releaseName, "namespace", namespace, "revision", revision)
 
 	// Ensure namespace is set (use default from settings if empty)
 	originalNamespace := c.settings.Namespace()
@@ -327,6 +369,7 @@ func (c *RealHelmClient) GetChartFromRelease(_ context.Context, releaseName, nam
 
 	// Create a new get action using the (now hopefully correctly scoped) shared actionConfig
 	client := action.NewGet(c.actionConfig)
+	client.Version = revision
 
 	// Execute the get action
 	release, err := client.Run(releaseName)
@@ -18,7 +18,12 @@ type MockHelmClient struct {
 	ReleaseCharts    map[string]*ChartMetadata         // releaseName -> chart metadata
 	TemplateResults  map[string]string                 // chartPath -> manifest
 	CurrentNamespace string
-	MockReleases     []*ReleaseElement // List of mock releases for ListReleases
+	MockReleases     []*ReleaseElement  // List of mock releases for ListReleases
+	LastListOptions  ReleaseListOptions // Options of the last ListReleasesWithOptions call
+
+	// Revision-specific mock responses, keyed by "namespace/release#revision"
+	RevisionValues map[string]map[string]interface{}
+	RevisionCharts map[string]*ChartMetadata
 
 	// Track calls for assertions
 	GetValuesCallCount    int
@@ -53,6 +58,8 @@ func NewMockHelmClient() *MockHelmClient {
 		FindChartResults: make(map[string]string),
 		CurrentNamespace: DefaultNamespace,
 		MockReleases:     []*ReleaseElement{},
+		RevisionValues:   make(map[string]map[string]interface{}),
+		RevisionCharts:   make(map[string]*ChartMetadata),
 	}
 }
 
@@ -98,6 +105,57 @@ func (m *MockHelmClient) GetChartFromRelease(_ context.Context, releaseName, nam
 	return chartMeta, nil
 }
 
+// GetReleaseValuesAtRevision returns mocked values for a specific release revision.
+// Revision 0 falls back to GetReleaseValues.
+func (m *MockHelmClient) GetReleaseValuesAtRevision(ctx context.Context, releaseName, namespace string, revision int) (map[string]interface{}, error) {
+	if revision == 0 {
+		return m.GetReleaseValues(ctx, releaseName, namespace)
+	}
+	m.GetValuesCallCount++
+
+	if m.GetValuesError != nil {
+		return nil, m.GetValuesError
+	}
+
+	revisionKey := mockRevisionKey(releaseName, namespace, revision)
+	values, exists := m.RevisionValues[revisionKey]
+	if !exists {
+		return nil, fmt.Errorf("release %q not found", revisionKey)
+	}
+
+	return values, nil
+}
+
+// GetChartFromReleaseAtRevision returns mocked chart metadata for a specific release revision.
+// Revision 0 falls back to GetChartFromRelease.
+func (m *MockHelmClient) GetChartFromReleaseAtRevision(ctx context.Context, releaseName, namespace string, revision int) (*ChartMetadata, error) {
+	if revision == 0 {
+		return m.GetChartFromRelease(ctx, releaseName, namespace)
+	}
+	m.GetChartCallCount++
+
+	if m.GetChartError != nil {
+		return nil, m.GetChartError
+	}
+
+	revisionKey := mockRevisionKey(releaseName, namespace, revision)
+	chartMeta, exists := m.RevisionCharts[revisionKey]
+	if !exists {
+		return nil, fmt.Errorf("release %q not found", revisionKey)
+	}
+
+	return chartMeta, nil
+}
+
+// mockRevisionKey builds the lookup key used for revision-specific mock data.
+func mockRevisionKey(releaseName, namespace string, revision int) string {
+	releaseKey := releaseName
+	if namespace != "" {
+		releaseKey = fmt.Sprintf("%s/%s", namespace, releaseName)
+	}
+	return fmt.Sprintf("%s#%d", releaseKey, revision)
+}
+
 // TemplateChart mocks the TemplateChart method
 func (m *MockHelmClient) TemplateChart(_ context.Context, releaseName, namespace, chartPath string, _ /* values */ map[string]interface{}) (string, error) {
 	m.TemplateChartCalled = true // Mark as called
@@ -181,6 +239,19 @@ func (m *MockHelmClient) SetupMockRelease(releaseName, namespace string, values
 	m.ReleaseCharts[releaseKey] = chartMetadata
 }
 
+// SetupMockReleaseRevision is a helper method to set up a mock release at a specific revision
+func (m *MockHelmClient) SetupMockReleaseRevision(releaseName, namespace string, revision int, values map[string]interface{}, chartMetadata *ChartMetadata) {
+	if m.RevisionValues == nil {
+		m.RevisionValues = make(map[string]map[string]interface{})
+	}
+	if m.RevisionCharts == nil {
+		m.RevisionCharts = make(map[string]*ChartMetadata)
+	}
+	revisionKey := mockRevisionKey(releaseName, namespace, revision)
+	m.RevisionValues[revisionKey] = values
+	m.RevisionCharts[revisionKey] = chartMetadata
+}
+
 // SetupMockTemplate configures the mock response for TemplateChart for a specific namespace/release key
 func (m *MockHelmClient) SetupMockTemplate(namespace, releaseName, result string, err error) {
 	if m.TemplateResults == nil {
@@ -227,6 +298,20 @@ func (m *MockHelmClient) ListReleases(_ context.Context, allNamespaces bool) ([]
 	return m.MockReleases, nil
 }
 
+// ListReleasesWithOptions returns the mocked releases, applying the limit. The selector is
+// recorded in LastListOptions but not applied, since mock releases have no labels.
+func (m *MockHelmClient) ListReleasesWithOptions(ctx context.Context, opts ReleaseListOptions) ([]*ReleaseElement, error) {
+	m.LastListOptions = opts
+	releases, err := m.ListReleases(ctx, opts.AllNamespaces)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Limit > 0 && len(releases) > opts.Limit {
+		releases = releases[:opts.Limit]
+	}
+	return releases, nil
+}
+
 // SetupMockReleases is a helper method to configure mock releases for ListReleases
 func (m *MockHelmClient) SetupMockReleases(releases []*ReleaseElement) {
 	m.MockReleases = releases
@@ -182,6 +182,21 @@ func TestMockListReleases(t *testing.T) {
 	})
 }
 
+func TestMockListReleasesWithOptions(t *testing.T) {
+	mockClient := NewMockHelmClient()
+	mockClient.SetupMockReleases([]*ReleaseElement{
+		{Name: "release1", Namespace: "default"},
+		{Name: "release2", Namespace: "test"},
+	})
+
+	opts := ReleaseListOptions{AllNamespaces: true, Selector: "team=a", Limit: 1}
+	releases, err := mockClient.ListReleasesWithOptions(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, []*ReleaseElement{{Name: "release1", Namespace: "default"}}, releases, "the limit should be applied")
+	assert.Equal(t, opts, mockClient.LastListOptions)
+	assert.Equal(t, 1, mockClient.ListReleasesCallCount)
+}
+
 func TestMockSetupMockReleases(t *testing.T) {
 	mockClient := NewMockHelmClient()
 	expectedReleases := []*ReleaseElement{
@@ -2,6 +2,7 @@
 package helm
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -36,6 +37,10 @@ type TemplateOptions struct {
 	Namespace   string
 	KubeVersion string
 	Strict      bool
+	// APIVersions are extra API versions exposed to templates via .Capabilities.APIVersions
+	APIVersions []string
+	// CapabilitiesFromCluster discovers .Capabilities.APIVersions from the current kube context
+	CapabilitiesFromCluster bool
 }
 
 // GetValuesOptions represents options for helm get values command
@@ -45,10 +50,11 @@ type GetValuesOptions struct {
 	OutputFile  string
 }
 
-// Template executes the helm template command with the given options
-func Template(options *TemplateOptions) (*CommandResult, error) {
+// Template executes the helm template command with the given options. Nothing is rendered once
+// ctx is canceled.
+func Template(ctx context.Context, options *TemplateOptions) (*CommandResult, error) {
 	// Initialize Helm environment settings and action config
-	settings := cli.New()
+	settings := NewSettings()
 	actionConfig := new(action.Configuration)
 	// Use an empty namespace initially, let Helm determine default or use provided
 	if err := actionConfig.Init(settings.RESTClientGetter(), options.Namespace, "", func(string, ...interface{}) {}); err != nil {
@@ -87,6 +93,16 @@ func Template(options *TemplateOptions) (*CommandResult, error) {
 		log.Debug("Using Kubernetes version for templating", "version", options.KubeVersion)
 	}
 
+	// Set API versions so charts gated on .Capabilities.APIVersions.Has render as they would in-cluster
+	apiVersions, err := ResolveAPIVersions(settings, options.APIVersions, options.CapabilitiesFromCluster)
+	if err != nil {
+		return nil, err
+	}
+	if len(apiVersions) > 0 {
+		install.APIVersions = apiVersions
+		log.Debug("Using extra API versions for templating", "count", len(apiVersions))
+	}
+
 	// Load chart values
 	values, err := mergeValues(options.ValuesFiles, options.SetValues)
 	if err != nil {
@@ -100,6 +116,9 @@ func Template(options *TemplateOptions) (*CommandResult, error) {
 	}
 
 	// Execute the template action
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("helm template canceled for chart %q: %w", options.ChartPath, err)
+	}
 	rel, err := install.Run(chartRequested, values)
 	if err != nil {
 		// Attempt to provide more specific error context if possible
@@ -118,9 +137,34 @@ func Template(options *TemplateOptions) (*CommandResult, error) {
 	}, nil
 }
 
+// ResolveAPIVersions combines the explicitly requested API versions with those discovered
+// from the cluster referenced by settings when fromCluster is set.
+func ResolveAPIVersions(settings *cli.EnvSettings, requested []string, fromCluster bool) (chartutil.VersionSet, error) {
+	apiVersions := chartutil.VersionSet{}
+	if fromCluster {
+		discoveryClient, err := settings.RESTClientGetter().ToDiscoveryClient()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create discovery client for cluster capabilities: %w", err)
+		}
+		clusterVersions, err := action.GetVersionSet(discoveryClient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover API versions from cluster: %w", err)
+		}
+		log.Debug("Discovered API versions from cluster", "count", len(clusterVersions))
+		apiVersions = append(apiVersions, clusterVersions...)
+	}
+	for _, apiVersion := range requested {
+		if apiVersion == "" || apiVersions.Has(apiVersion) {
+			continue
+		}
+		apiVersions = append(apiVersions, apiVersion)
+	}
+	return apiVersions, nil
+}
+
 // GetValues executes the helm get values command with the given options
 func GetValues(options *GetValuesOptions) (*CommandResult, error) {
-	settings := cli.New()
+	settings := NewSettings()
 	actionConfig := new(action.Configuration)
 	ns := options.Namespace
 	if ns == "" {
@@ -1,6 +1,7 @@
 package helm
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -331,7 +332,7 @@ func TestTemplate(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// Mock the Template function
-			HelmTemplateFunc = func(options *TemplateOptions) (*CommandResult, error) {
+			HelmTemplateFunc = func(_ context.Context, options *TemplateOptions) (*CommandResult, error) {
 				// Verify options match what we expect
 				assert.Equal(t, tc.options.ReleaseName, options.ReleaseName)
 				assert.Equal(t, tc.options.ChartPath, options.ChartPath)
@@ -347,7 +348,7 @@ func TestTemplate(t *testing.T) {
 			}
 
 			// Call the Template function with options
-			result, err := HelmTemplateFunc(tc.options)
+			result, err := HelmTemplateFunc(context.Background(), tc.options)
 
 			// Check error expectations
 			if tc.expectError {
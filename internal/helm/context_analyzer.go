@@ -3,12 +3,16 @@ package helm
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/lucas-albers-lz4/irr/pkg/analysis"
 	"github.com/lucas-albers-lz4/irr/pkg/image"
 	"github.com/lucas-albers-lz4/irr/pkg/keys"
+	"github.com/lucas-albers-lz4/irr/pkg/limits"
 	"github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/timing"
 )
 
 const (
@@ -24,6 +28,7 @@ const (
 // with full awareness of subchart values and their origins.
 type ContextAwareAnalyzer struct {
 	context *ChartAnalysisContext
+	depth   limits.DepthTracker // Nesting depth of the values being traversed
 }
 
 // NewContextAwareAnalyzer creates a new ContextAwareAnalyzer.
@@ -38,6 +43,11 @@ func (a *ContextAwareAnalyzer) AnalyzeContext() (*analysis.ChartAnalysis, error)
 	if a.context == nil {
 		return nil, fmt.Errorf("analysis context is nil")
 	}
+	chartName := ""
+	if a.context.Chart != nil {
+		chartName = a.context.Chart.Name()
+	}
+	defer timing.Start(timing.SpanAnalysis, chartName)()
 
 	chartAnalysis := analysis.NewChartAnalysis()
 
@@ -52,20 +62,52 @@ func (a *ContextAwareAnalyzer) AnalyzeContext() (*analysis.ChartAnalysis, error)
 	if err := a.analyzeValues(a.context.Values, "", chartAnalysis); err != nil {
 		return nil, fmt.Errorf("failed to analyze values: %w", err)
 	}
+	if err := limits.CheckImagePatterns(len(chartAnalysis.ImagePatterns), ""); err != nil {
+		return nil, err
+	}
+
+	// Mark the images of subcharts that the merged values disable via condition or tags
+	if a.context.Chart != nil {
+		if marked := analysis.MarkDisabledSubcharts(a.context.Chart, a.context.Values, chartAnalysis.ImagePatterns); marked > 0 {
+			log.Debug("AnalyzeContext: Marked patterns of disabled subcharts", "count", marked)
+		}
+	}
+	// Locate each image in the values files, so that users can jump to it
+	if a.context.SourceLocations == nil && a.context.locate != nil {
+		a.context.SourceLocations = a.context.locate(analysis.LocationPaths(chartAnalysis.ImagePatterns))
+	}
+	a.context.SourceLocations.Apply(chartAnalysis.ImagePatterns)
 
 	return chartAnalysis, nil
 }
 
 // analyzeValues recursively analyzes a values map to identify container image references.
 func (a *ContextAwareAnalyzer) analyzeValues(values map[string]interface{}, prefix string, chartAnalysis *analysis.ChartAnalysis) error {
-	for k, v := range values {
-		currentPath := k
-		if prefix != "" {
-			currentPath = prefix + "." + k
+	if err := a.enter(prefix, chartAnalysis); err != nil {
+		return err
+	}
+	defer a.depth.Leave()
+
+	siblingPatterns, covered := analysis.SiblingImagePatterns(values, prefix)
+	for i := range siblingPatterns {
+		a.setSiblingPatternOrigin(&siblingPatterns[i])
+	}
+	chartAnalysis.ImagePatterns = append(chartAnalysis.ImagePatterns, siblingPatterns...)
+
+	// Visit keys in sorted order so that patterns are reported in a stable order
+	for _, k := range slices.Sorted(maps.Keys(values)) {
+		v := values[k]
+		currentPath := analysis.JoinPath(prefix, k)
+		if covered[k] {
+			log.Debug("analyzeValues: Covered by an image split across sibling keys", "path", currentPath)
+			continue
 		}
 
 		log.Debug("analyzeValues LOOP", "path", currentPath, "type", fmt.Sprintf("%T", v))
 		if err := a.analyzeSingleValue(k, v, currentPath, chartAnalysis); err != nil {
+			if analysis.IsLimitError(err) {
+				return err
+			}
 			// If analyzing a single value fails, wrap the error with context
 			return fmt.Errorf("error analyzing path '%s': %w", currentPath, err)
 		}
@@ -83,6 +125,22 @@ func (a *ContextAwareAnalyzer) analyzeValues(values map[string]interface{}, pref
 	return nil
 }
 
+// setSiblingPatternOrigin records where the image key of a pattern split across sibling keys was
+// set, as for image strings.
+func (a *ContextAwareAnalyzer) setSiblingPatternOrigin(pattern *analysis.ImagePattern) {
+	imagePath := analysis.JoinPath(pattern.Path, pattern.ImageKeys[keys.Repository])
+	pattern.SourceOrigin = ValuesYAML
+	if origin, exists := a.context.Origins[imagePath]; exists {
+		if strings.HasSuffix(origin.Path, ".yaml") || strings.HasSuffix(origin.Path, ".yml") {
+			pattern.SourceOrigin = origin.Path
+		}
+	}
+	pattern.SourceChartAppVersion = a.context.AppVersion
+	if anchorSource, derived := a.context.GetAnchorSourceForValue(imagePath); derived {
+		pattern.AnchorSource = anchorSource
+	}
+}
+
 // analyzeSingleValue analyzes a single key-value pair based on the value type.
 func (a *ContextAwareAnalyzer) analyzeSingleValue(_ string, value interface{}, currentPath string, chartAnalysis *analysis.ChartAnalysis) error {
 	log.Debug("analyzeSingleValue ENTER", "path", currentPath, "type", fmt.Sprintf("%T", value))
@@ -116,20 +174,31 @@ func (a *ContextAwareAnalyzer) analyzeMapValue(val map[string]interface{}, curre
 	if a.isDirectImageMapDefinition(val) {
 		// Extract and normalize image values
 		registry, repository, tag := a.normalizeImageValues(val)
+		digest, digestInRepository := imageMapDigest(val)
 
 		// Create an image pattern for the map itself
 		imageStructure := map[string]interface{}{
 			keys.Registry:   registry,
 			keys.Repository: repository,
-			keys.Tag:        tag,
+		}
+		imageValue := registry + "/" + repository
+		if tag != "" {
+			imageStructure[keys.Tag] = tag
+			imageValue += ":" + tag
+		}
+		if digest != "" {
+			imageStructure[keys.Digest] = digest
+			imageValue += "@" + digest
 		}
 
 		pattern := analysis.ImagePattern{
-			Type:      analysis.PatternTypeMap,
-			Path:      currentPath,
-			Value:     fmt.Sprintf("%s/%s:%s", registry, repository, tag),
-			Structure: imageStructure,
-			Count:     1,
+			Type:                 analysis.PatternTypeMap,
+			Path:                 currentPath,
+			Value:                imageValue,
+			Structure:            imageStructure,
+			Count:                1,
+			RegistryInRepository: repositoryHasRegistry(val),
+			DigestInRepository:   digestInRepository,
 		}
 
 		// --- Start: Populate OriginalRegistry AND SourceOrigin ---
@@ -143,6 +212,9 @@ func (a *ContextAwareAnalyzer) analyzeMapValue(val map[string]interface{}, curre
 			sourceChartName = origin.ChartName // Get chart name from origin
 		}
 		pattern.SourceOrigin = originPath // Set the source origin (file path)
+		if anchorSource, derived := a.context.GetAnchorSourceForValue(currentPath); derived {
+			pattern.AnchorSource = anchorSource
+		}
 
 		// Use sourceChartName for OriginalRegistry logic
 		if sourceChartName != "" && sourceChartName != a.context.Chart.Metadata.Name {
@@ -211,12 +283,10 @@ func (a *ContextAwareAnalyzer) analyzeMapValue(val map[string]interface{}, curre
 // It attempts to parse the string as an image reference and determines which
 // registry and repository it contains.
 func (a *ContextAwareAnalyzer) analyzeStringValue(val, currentPath, originPath string, chartAnalysis *analysis.ChartAnalysis) error {
-	// Extract the key from the path for image detection
-	parts := strings.Split(currentPath, ".")
-	key := currentPath
-	if len(parts) > 0 {
-		key = parts[len(parts)-1] // Get the last part of the path as the key
-	}
+	// Extract the key from the path for image detection. For sequence elements
+	// (e.g. "images[0]"), the key is the name of the sequence.
+	parts := analysis.SplitPath(currentPath)
+	key, _ := analysis.CutPathIndex(parts[len(parts)-1]) // The last part of the path is the key
 
 	// Skip paths that are unlikely to be image references
 	if !a.isProbableImageKeyPath(key, val) {
@@ -289,6 +359,10 @@ func (a *ContextAwareAnalyzer) analyzeStringValue(val, currentPath, originPath s
 		Count:                 1,
 	}
 
+	if anchorSource, derived := a.context.GetAnchorSourceForValue(currentPath); derived {
+		pattern.AnchorSource = anchorSource
+	}
+
 	log.Debug("analyzeStringValue: Identified image string via structural validation", "path", currentPath, "value", trimmedVal)
 	chartAnalysis.ImagePatterns = append(chartAnalysis.ImagePatterns, pattern)
 	return nil
@@ -296,10 +370,18 @@ func (a *ContextAwareAnalyzer) analyzeStringValue(val, currentPath, originPath s
 
 // analyzeArrayValue handles analysis of array values.
 func (a *ContextAwareAnalyzer) analyzeArrayValue(val []interface{}, currentPath string, chartAnalysis *analysis.ChartAnalysis) error {
+	if err := a.enter(currentPath, chartAnalysis); err != nil {
+		return err
+	}
+	defer a.depth.Leave()
+
 	for i, item := range val {
 		itemPath := fmt.Sprintf("%s[%d]", currentPath, i)
 
 		if err := a.analyzeSingleValue("", item, itemPath, chartAnalysis); err != nil {
+			if analysis.IsLimitError(err) {
+				return err
+			}
 			return fmt.Errorf("error analyzing array item at path '%s': %w", itemPath, err)
 		}
 	}
@@ -307,11 +389,20 @@ func (a *ContextAwareAnalyzer) analyzeArrayValue(val []interface{}, currentPath
 	return nil
 }
 
+// enter descends into the map or list at path, failing when the values are nested deeper than the
+// depth limit or more image patterns than the pattern limit were detected. A nil error must be
+// matched by a.depth.Leave().
+func (a *ContextAwareAnalyzer) enter(path string, chartAnalysis *analysis.ChartAnalysis) error {
+	if err := limits.CheckImagePatterns(len(chartAnalysis.ImagePatterns), path); err != nil {
+		return err
+	}
+	return a.depth.Enter(path)
+}
+
 // isDirectImageMapDefinition provides a stricter check to identify maps that
 // directly define an image using standard keys.
 func (a *ContextAwareAnalyzer) isDirectImageMapDefinition(val map[string]interface{}) bool {
 	repoVal, hasRepo := val[keys.Repository]
-	tagVal, hasTag := val[keys.Tag]
 
 	// Must have repository key
 	if !hasRepo {
@@ -323,14 +414,11 @@ func (a *ContextAwareAnalyzer) isDirectImageMapDefinition(val map[string]interfa
 		return false
 	}
 
-	// Must have tag key (for now, ignoring digest)
-	if !hasTag {
-		return false
-	}
-	// Tag value must be a non-empty string
-	tagStr, tagIsString := tagVal.(string)
-	if !tagIsString || tagStr == "" {
-		return false
+	// Must pin a version: a non-empty tag string, or a digest for digest-only images
+	if tagStr, _ := val[keys.Tag].(string); tagStr == "" {
+		if digest, _ := imageMapDigest(val); digest == "" {
+			return false
+		}
 	}
 
 	// Optional: Check registry if present
@@ -359,7 +447,8 @@ func (a *ContextAwareAnalyzer) isProbableImageKeyPath(key, val string) bool {
 	}
 
 	// Check for exact key names that almost always indicate an image map or string
-	if lowerKey == keys.Image || lowerKey == keys.Repository {
+	// ("images" is a sequence of image strings)
+	if lowerKey == keys.Image || lowerKey == keys.Repository || lowerKey == "images" {
 		log.Debug("isProbableImageKeyPath: true (exact key match)", "key", key)
 		return true
 	}
@@ -380,7 +469,7 @@ func (a *ContextAwareAnalyzer) isProbableImageKeyPath(key, val string) bool {
 	}
 
 	// Check for key suffix patterns
-	suffixPatterns := []string{"Image", "Repository", "Registry", "Container"}
+	suffixPatterns := []string{"Image", "Images", "Repository", "Registry", "Container"}
 	for _, suffix := range suffixPatterns {
 		if strings.HasSuffix(key, suffix) {
 			log.Debug("isProbableImageKeyPath: true (key suffix match)", "key", key, "suffix", suffix)
@@ -393,6 +482,29 @@ func (a *ContextAwareAnalyzer) isProbableImageKeyPath(key, val string) bool {
 	return false
 }
 
+// repositoryHasRegistry reports whether an image map has no registry value and carries the
+// registry host in its repository instead (e.g. repository: quay.io/org/app).
+func repositoryHasRegistry(val map[string]interface{}) bool {
+	if regVal, ok := val[keys.Registry].(string); ok && regVal != "" {
+		return false
+	}
+	repoVal, _ := val[keys.Repository].(string)
+	_, _, ok := image.SplitRepositoryHost(repoVal)
+	return ok
+}
+
+// imageMapDigest returns the digest an image map pins, from its digest value or, failing that,
+// from a repository that ends in one (e.g. repository: gcr.io/distroless/static@sha256:...).
+// inRepository reports the latter.
+func imageMapDigest(val map[string]interface{}) (digest string, inRepository bool) {
+	if digestVal, ok := val[keys.Digest].(string); ok && digestVal != "" {
+		return digestVal, false
+	}
+	repoVal, _ := val[keys.Repository].(string)
+	_, digest, inRepository = image.SplitRepositoryDigest(repoVal)
+	return digest, inRepository
+}
+
 // normalizeImageValues extracts normalized image components from a map structure.
 func (a *ContextAwareAnalyzer) normalizeImageValues(val map[string]interface{}) (registry, repository, tag string) {
 	// Handle registry (optional)
@@ -404,26 +516,24 @@ func (a *ContextAwareAnalyzer) normalizeImageValues(val map[string]interface{})
 			registry = registry[:portIndex]
 		}
 	} else {
-		registry = DefaultRegistry // Default to docker.io if not specified
+		registry = image.UnqualifiedRegistry() // Default to docker.io (or --default-registry) if not specified
 	}
 
 	// Handle repository (required)
 	if repoVal, ok := val[keys.Repository].(string); ok && repoVal != "" {
+		repoVal, _, _ = image.SplitRepositoryDigest(repoVal) // The digest is read with imageMapDigest
 		repository = repoVal
 
 		// If repository contains registry info (e.g. "quay.io/repo"), extract it
-		if strings.Contains(repoVal, "/") {
-			parts := strings.SplitN(repoVal, "/", MaxSplitParts)
-			if strings.Contains(parts[0], ".") || strings.Contains(parts[0], ":") || parts[0] == image.LocalhostRegistry {
-				// Override registry from the repository string
-				registry = parts[0]
-				// Strip port from registry if present
-				if strings.Contains(registry, ":") {
-					portIndex := strings.LastIndex(registry, ":")
-					registry = registry[:portIndex]
-				}
-				repository = parts[1]
+		if host, repoPath, ok := image.SplitRepositoryHost(repoVal); ok {
+			// Override registry from the repository string
+			registry = host
+			// Strip port from registry if present
+			if strings.Contains(registry, ":") {
+				portIndex := strings.LastIndex(registry, ":")
+				registry = registry[:portIndex]
 			}
+			repository = repoPath
 		}
 	}
 
@@ -433,11 +543,11 @@ func (a *ContextAwareAnalyzer) normalizeImageValues(val map[string]interface{})
 	}
 
 	// Handle tag (optional)
-	if tagVal, ok := val[keys.Tag].(string); ok && tagVal != "" {
-		tag = tagVal
-	} else if digestVal, ok := val["digest"].(string); ok && digestVal != "" {
-		// If digest is present but no tag, leave tag empty (digest will be used)
+	if digestVal, _ := imageMapDigest(val); digestVal != "" {
+		// If digest is present, leave tag empty (the digest pins the image)
 		tag = ""
+	} else if tagVal, ok := val[keys.Tag].(string); ok && tagVal != "" {
+		tag = tagVal
 	} else {
 		// No tag or digest specified, prefer AppVersion if available
 		if a.context != nil && a.context.AppVersion != "" {
@@ -181,3 +181,52 @@ func createTestContext(chartData *chart.Chart) *ChartAnalysisContext {
 		ChartName: chartData.Name(),
 	}
 }
+
+func TestContextAwareAnalyzer_ImageLists(t *testing.T) {
+	chartData := &chart.Chart{Metadata: &chart.Metadata{Name: "lists", Version: "1.0.0"}}
+	values := map[string]interface{}{
+		"sidecars": []interface{}{
+			map[string]interface{}{"name": "proxy", "image": "quay.io/org/proxy:1.0"},
+			map[string]interface{}{"name": "logger", "image": map[string]interface{}{"repository": "org/logger", "tag": "2.0"}},
+		},
+		"images":      []interface{}{"bitnami/nginx:1.25", "bitnami/redis:7.2"},
+		"imageGroups": []interface{}{[]interface{}{"quay.io/org/a:1"}},
+	}
+	analyzer := NewContextAwareAnalyzer(NewChartAnalysisContext(chartData, values, map[string]ValueOrigin{}, nil, nil))
+
+	result, err := analyzer.AnalyzeContext()
+	require.NoError(t, err)
+
+	paths := make([]string, 0, len(result.ImagePatterns))
+	for _, p := range result.ImagePatterns {
+		paths = append(paths, p.Path)
+	}
+	// Keys are visited in sorted order and sequence elements in index order
+	assert.Equal(t, []string{
+		"imageGroups[0][0]",
+		"images[0]",
+		"images[1]",
+		"sidecars[0].image",
+		"sidecars[1].image",
+	}, paths)
+}
+
+func TestContextAwareAnalyzer_DottedKeys(t *testing.T) {
+	chartData := &chart.Chart{Metadata: &chart.Metadata{Name: "dotted", Version: "1.0.0"}}
+	values := map[string]interface{}{
+		"agents": map[string]interface{}{
+			"vendor.io/agent": map[string]interface{}{"image": "quay.io/vendor/agent:1.0"},
+		},
+		"my.app": map[string]interface{}{"image": map[string]interface{}{"repository": "org/app", "tag": "2.0"}},
+	}
+	analyzer := NewContextAwareAnalyzer(NewChartAnalysisContext(chartData, values, map[string]ValueOrigin{}, nil, nil))
+
+	result, err := analyzer.AnalyzeContext()
+	require.NoError(t, err)
+
+	paths := make([]string, 0, len(result.ImagePatterns))
+	for _, p := range result.ImagePatterns {
+		paths = append(paths, p.Path)
+	}
+	assert.Equal(t, []string{`agents.vendor\.io/agent.image`, `my\.app.image`}, paths)
+}
@@ -0,0 +1,222 @@
+package helm
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	irrregistry "github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/spf13/afero"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// registryAuthErrorMessages are fragments of the errors OCI registries and the Helm registry
+// client return when a pull needs credentials or the credentials were rejected
+var registryAuthErrorMessages = []string{
+	"401",
+	"403",
+	"unauthorized",
+	"authentication required",
+	"denied",
+	"forbidden",
+	"basic credential not found",
+}
+
+// DependencyOptions configures downloading the dependencies a chart declares in Chart.yaml but
+// does not contain in its charts/ directory, as `helm dependency build` does.
+type DependencyOptions struct {
+	// RegistryConfig is the credentials file written by `helm registry login`; empty uses Helm's
+	// default (HELM_REGISTRY_CONFIG or ~/.config/helm/registry/config.json)
+	RegistryConfig string
+	// Username and Password authenticate to the registries of oci:// dependencies instead of the
+	// credentials in RegistryConfig
+	Username string
+	Password string
+	// PlainHTTP pulls oci:// dependencies over HTTP instead of HTTPS
+	PlainHTTP bool
+	// Transport configures TLS verification of the registries of oci:// dependencies. Its default
+	// settings also apply to https:// chart repositories, like Helm's --ca-file and
+	// --insecure-skip-tls-verify.
+	Transport irrregistry.TransportOptions
+}
+
+// DependencyCredentialsError is returned when oci:// dependencies could not be downloaded because
+// their registry requires credentials that were not configured or were rejected.
+type DependencyCredentialsError struct {
+	// Dependencies are the dependencies that need credentials, as "name (oci://host/path)"
+	Dependencies []string
+	// Registries are the registry hosts that need credentials
+	Registries []string
+	// Err is the error of the dependency download
+	Err error
+}
+
+func (e *DependencyCredentialsError) Error() string {
+	return fmt.Sprintf("chart dependencies require registry credentials: %s; log in with 'helm registry login %s' or pass --registry-username and --registry-password: %v",
+		strings.Join(e.Dependencies, ", "), strings.Join(e.Registries, "' / 'helm registry login "), e.Err)
+}
+
+func (e *DependencyCredentialsError) Unwrap() error {
+	return e.Err
+}
+
+// Variables to support mocking the network in tests
+var (
+	buildDependencyCharts = func(manager *downloader.Manager) error { return manager.Build() }
+	listRegistryTags      = func(client *registry.Client, ref string) ([]string, error) { return client.Tags(ref) }
+)
+
+// buildMissingDependencies downloads the dependencies of the chart directory at chartPath that are
+// missing from its charts/ directory. It reports whether any were downloaded, in which case the
+// chart must be loaded again. Without opts, or for packaged charts, missing dependencies are only
+// logged: their images are not analyzed.
+func buildMissingDependencies(chartPath string, loadedChart *chart.Chart, opts *DependencyOptions) (bool, error) {
+	missing := MissingDependencies(loadedChart)
+	if len(missing) == 0 {
+		return false, nil
+	}
+	names := dependencyNames(missing)
+	if opts == nil {
+		log.Warn("Chart dependencies are missing from the charts directory and will not be analyzed; run 'helm dependency build'",
+			"chart", loadedChart.Name(), "dependencies", names)
+		return false, nil
+	}
+	if info, err := os.Stat(chartPath); err != nil || !info.IsDir() {
+		log.Warn("Packaged chart is missing dependencies, which will not be analyzed", "chart", loadedChart.Name(), "dependencies", names)
+		return false, nil
+	}
+
+	settings := NewSettings()
+	client, err := newDependencyRegistryClient(opts, settings)
+	if err != nil {
+		return false, err
+	}
+	manager := &downloader.Manager{
+		Out:              io.Discard,
+		ChartPath:        chartPath,
+		SkipUpdate:       true, // Only the repositories of the missing dependencies are needed
+		Getters:          getter.All(settings, repositoryTLSOptions(opts.Transport.Default)...),
+		RegistryClient:   client,
+		RepositoryConfig: settings.RepositoryConfig,
+		RepositoryCache:  settings.RepositoryCache,
+	}
+	log.Info("Downloading missing chart dependencies", "chart", loadedChart.Name(), "dependencies", names)
+	if err := buildDependencyCharts(manager); err != nil {
+		return false, dependencyBuildError(err, missing, client)
+	}
+	return true, nil
+}
+
+// newDependencyRegistryClient creates the registry client used to pull oci:// dependencies
+func newDependencyRegistryClient(opts *DependencyOptions, settings *cli.EnvSettings) (*registry.Client, error) {
+	credentialsFile := opts.RegistryConfig
+	if credentialsFile == "" {
+		credentialsFile = settings.RegistryConfig
+	}
+	clientOpts := []registry.ClientOption{
+		registry.ClientOptWriter(io.Discard),
+		registry.ClientOptCredentialsFile(credentialsFile),
+	}
+	if opts.Username != "" || opts.Password != "" {
+		clientOpts = append(clientOpts, registry.ClientOptBasicAuth(opts.Username, opts.Password))
+	}
+	if opts.PlainHTTP {
+		clientOpts = append(clientOpts, registry.ClientOptPlainHTTP())
+	}
+	transport, err := irrregistry.NewTransport(afero.NewOsFs(), opts.Transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure registry TLS: %w", err)
+	}
+	clientOpts = append(clientOpts, registry.ClientOptHTTPClient(&http.Client{Transport: transport}))
+	client, err := registry.NewClient(clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry client: %w", err)
+	}
+	return client, nil
+}
+
+// repositoryTLSOptions returns the getter options applying tlsConfig to https:// chart
+// repositories. Settings that are set take precedence over those of the repositories in
+// repositories.yaml; a CA file also replaces their client certificate.
+func repositoryTLSOptions(tlsConfig irrregistry.TLSConfig) []getter.Option {
+	var opts []getter.Option
+	if tlsConfig.CAFile != "" {
+		opts = append(opts, getter.WithTLSClientConfig("", "", tlsConfig.CAFile))
+	}
+	if tlsConfig.InsecureSkipTLSVerify {
+		opts = append(opts, getter.WithInsecureSkipVerifyTLS(true))
+	}
+	return opts
+}
+
+// MissingDependencies returns the dependencies declared in Chart.yaml that are not in the chart,
+// matched by name like Helm's own dependency check
+func MissingDependencies(loadedChart *chart.Chart) []*chart.Dependency {
+	if loadedChart == nil || loadedChart.Metadata == nil {
+		return nil
+	}
+	var missing []*chart.Dependency
+	for _, dep := range loadedChart.Metadata.Dependencies {
+		if dep == nil {
+			continue
+		}
+		present := slices.ContainsFunc(loadedChart.Dependencies(), func(sub *chart.Chart) bool { return sub.Name() == dep.Name })
+		if !present {
+			missing = append(missing, dep)
+		}
+	}
+	return missing
+}
+
+// dependencyBuildError explains a failed dependency download. Each missing oci:// dependency is
+// probed by listing its tags, so the error names exactly the dependencies whose registry needs
+// credentials.
+func dependencyBuildError(err error, missing []*chart.Dependency, client *registry.Client) error {
+	var needCredentials, registries []string
+	for _, dep := range missing {
+		if !registry.IsOCI(dep.Repository) {
+			continue
+		}
+		repository := strings.TrimSuffix(strings.TrimPrefix(dep.Repository, "oci://"), "/")
+		if _, tagErr := listRegistryTags(client, repository+"/"+dep.Name); tagErr == nil || !isRegistryAuthError(tagErr) {
+			continue
+		}
+		needCredentials = append(needCredentials, fmt.Sprintf("%s (%s)", dep.Name, dep.Repository))
+		host, _, _ := strings.Cut(repository, "/")
+		if !slices.Contains(registries, host) {
+			registries = append(registries, host)
+		}
+	}
+	if len(needCredentials) == 0 {
+		return fmt.Errorf("failed to download chart dependencies %s: %w", strings.Join(dependencyNames(missing), ", "), err)
+	}
+	return &DependencyCredentialsError{Dependencies: needCredentials, Registries: registries, Err: err}
+}
+
+// isRegistryAuthError reports whether err is a registry rejecting a pull for missing or invalid credentials
+func isRegistryAuthError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range registryAuthErrorMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencyNames returns the names of the dependencies
+func dependencyNames(deps []*chart.Dependency) []string {
+	names := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		names = append(names, dep.Name)
+	}
+	return names
+}
@@ -0,0 +1,118 @@
+package helm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// chartWithDependencies returns a chart declaring deps, of which only the names in present are loaded
+func chartWithDependencies(deps []*chart.Dependency, present ...string) *chart.Chart {
+	parent := &chart.Chart{Metadata: &chart.Metadata{Name: "app", Dependencies: deps}}
+	for _, name := range present {
+		parent.AddDependency(&chart.Chart{Metadata: &chart.Metadata{Name: name}})
+	}
+	return parent
+}
+
+func TestMissingDependencies(t *testing.T) {
+	deps := []*chart.Dependency{
+		{Name: "redis", Repository: "oci://registry.example.com/charts"},
+		{Name: "common", Repository: "https://charts.example.com"},
+	}
+
+	missing := MissingDependencies(chartWithDependencies(deps, "common"))
+	require.Len(t, missing, 1)
+	assert.Equal(t, "redis", missing[0].Name)
+	assert.Empty(t, MissingDependencies(chartWithDependencies(deps, "redis", "common")))
+	assert.Empty(t, MissingDependencies(nil))
+}
+
+func TestBuildMissingDependencies(t *testing.T) {
+	originalBuild := buildDependencyCharts
+	originalTags := listRegistryTags
+	t.Cleanup(func() {
+		buildDependencyCharts = originalBuild
+		listRegistryTags = originalTags
+	})
+
+	deps := []*chart.Dependency{
+		{Name: "redis", Repository: "oci://registry.example.com/charts/"},
+		{Name: "postgresql", Repository: "oci://registry.example.com/charts"},
+		{Name: "common", Repository: "oci://public.example.com/charts"},
+	}
+	chartDir := t.TempDir()
+
+	t.Run("no options skips download", func(t *testing.T) {
+		buildDependencyCharts = func(*downloader.Manager) error {
+			t.Fatal("dependencies must not be downloaded without options")
+			return nil
+		}
+		built, err := buildMissingDependencies(chartDir, chartWithDependencies(deps), nil)
+		require.NoError(t, err)
+		assert.False(t, built)
+	})
+
+	t.Run("downloads missing dependencies", func(t *testing.T) {
+		var manager *downloader.Manager
+		buildDependencyCharts = func(m *downloader.Manager) error {
+			manager = m
+			return nil
+		}
+		built, err := buildMissingDependencies(chartDir, chartWithDependencies(deps), &DependencyOptions{Username: "user", Password: "secret"})
+		require.NoError(t, err)
+		assert.True(t, built)
+		require.NotNil(t, manager)
+		assert.Equal(t, chartDir, manager.ChartPath)
+		assert.NotNil(t, manager.RegistryClient)
+	})
+
+	t.Run("lists dependencies that need credentials", func(t *testing.T) {
+		buildDependencyCharts = func(*downloader.Manager) error {
+			return errors.New("failed to authorize: authentication required")
+		}
+		var probed []string
+		listRegistryTags = func(_ *registry.Client, ref string) ([]string, error) {
+			probed = append(probed, ref)
+			if ref == "public.example.com/charts/common" {
+				return []string{"1.0.0"}, nil
+			}
+			return nil, errors.New("GET https://registry.example.com/v2/: 401 Unauthorized")
+		}
+
+		_, err := buildMissingDependencies(chartDir, chartWithDependencies(deps), &DependencyOptions{})
+		var credErr *DependencyCredentialsError
+		require.ErrorAs(t, err, &credErr)
+		assert.Equal(t, []string{
+			"redis (oci://registry.example.com/charts/)",
+			"postgresql (oci://registry.example.com/charts)",
+		}, credErr.Dependencies)
+		assert.Equal(t, []string{"registry.example.com"}, credErr.Registries)
+		assert.Contains(t, err.Error(), "helm registry login registry.example.com")
+		assert.Equal(t, []string{
+			"registry.example.com/charts/redis",
+			"registry.example.com/charts/postgresql",
+			"public.example.com/charts/common",
+		}, probed)
+	})
+
+	t.Run("other download failures", func(t *testing.T) {
+		buildDependencyCharts = func(*downloader.Manager) error {
+			return errors.New("chart not found")
+		}
+		listRegistryTags = func(*registry.Client, string) ([]string, error) {
+			return nil, errors.New("name unknown")
+		}
+
+		_, err := buildMissingDependencies(chartDir, chartWithDependencies(deps), &DependencyOptions{})
+		require.Error(t, err)
+		var credErr *DependencyCredentialsError
+		assert.False(t, errors.As(err, &credErr))
+		assert.Contains(t, err.Error(), "failed to download chart dependencies redis, postgresql, common")
+	})
+}
@@ -0,0 +1,46 @@
+// Package helm provides internal utilities for interacting with Helm.
+package helm
+
+import (
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+// KubeOptions selects the cluster Helm talks to, like Helm's global --kube-context, --kubeconfig
+// and --kube-insecure-skip-tls-verify flags. Empty fields keep Helm's defaults, including the
+// HELM_KUBECONTEXT and KUBECONFIG environment variables Helm sets for plugins.
+type KubeOptions struct {
+	// Context is the kubeconfig context to use instead of the current one
+	Context string
+	// Config is the kubeconfig file to load instead of KUBECONFIG or ~/.kube/config
+	Config string
+	// InsecureSkipTLSVerify skips verification of the API server's certificate
+	InsecureSkipTLSVerify bool
+}
+
+// kubeOptions is applied to the settings returned by NewSettings
+var kubeOptions KubeOptions
+
+// SetKubeOptions sets the cluster selection applied to every Helm configuration created
+// afterwards. It returns a function that restores the previous options.
+func SetKubeOptions(opts KubeOptions) (restore func()) {
+	previous := kubeOptions
+	kubeOptions = opts
+	return func() { kubeOptions = previous }
+}
+
+// NewSettings returns Helm's environment settings with the options set by SetKubeOptions applied.
+// Action configurations and Kubernetes clients are built from them, so every cluster access
+// targets the selected cluster.
+func NewSettings() *cli.EnvSettings {
+	settings := cli.New()
+	if kubeOptions.Context != "" {
+		settings.KubeContext = kubeOptions.Context
+	}
+	if kubeOptions.Config != "" {
+		settings.KubeConfig = kubeOptions.Config
+	}
+	if kubeOptions.InsecureSkipTLSVerify {
+		settings.KubeInsecureSkipTLSVerify = true
+	}
+	return settings
+}
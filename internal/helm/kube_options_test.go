@@ -0,0 +1,25 @@
+package helm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSettingsKubeOptions(t *testing.T) {
+	t.Setenv("HELM_KUBECONTEXT", "plugin-context")
+
+	settings := NewSettings()
+	assert.Equal(t, "plugin-context", settings.KubeContext, "Helm's environment applies without options")
+	assert.Empty(t, settings.KubeConfig)
+	assert.False(t, settings.KubeInsecureSkipTLSVerify)
+
+	restore := SetKubeOptions(KubeOptions{Context: "prod-eu", Config: "/etc/kube/prod.yaml", InsecureSkipTLSVerify: true})
+	settings = NewSettings()
+	assert.Equal(t, "prod-eu", settings.KubeContext)
+	assert.Equal(t, "/etc/kube/prod.yaml", settings.KubeConfig)
+	assert.True(t, settings.KubeInsecureSkipTLSVerify)
+
+	restore()
+	assert.Equal(t, "plugin-context", NewSettings().KubeContext)
+}
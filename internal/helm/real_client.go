@@ -10,7 +10,6 @@ import (
 	"helm.sh/helm/v3/pkg/action"
 	helmChart "helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
-	"helm.sh/helm/v3/pkg/cli"
 )
 
 // LoadChart loads a Helm chart from the specified path using the actual Helm loader.
@@ -24,8 +23,15 @@ func (c *RealHelmClient) LoadChart(chartPath string) (*helmChart.Chart, error) {
 }
 
 // ListReleases lists Helm releases using the actual Helm SDK.
-func (c *RealHelmClient) ListReleases(_ context.Context, allNamespaces bool) ([]*ReleaseElement, error) {
-	log.Debug("Listing releases", "allNamespaces", allNamespaces)
+func (c *RealHelmClient) ListReleases(ctx context.Context, allNamespaces bool) ([]*ReleaseElement, error) {
+	return c.ListReleasesWithOptions(ctx, ReleaseListOptions{AllNamespaces: allNamespaces})
+}
+
+// ListReleasesWithOptions lists Helm releases using the actual Helm SDK, letting Helm apply the
+// label selector and limit.
+func (c *RealHelmClient) ListReleasesWithOptions(_ context.Context, opts ReleaseListOptions) ([]*ReleaseElement, error) {
+	allNamespaces := opts.AllNamespaces
+	log.Debug("Listing releases", "allNamespaces", allNamespaces, "selector", opts.Selector, "limit", opts.Limit)
 
 	// Create a new action config for this specific list operation
 	actionConfig := new(action.Configuration)
@@ -55,6 +61,8 @@ func (c *RealHelmClient) ListReleases(_ context.Context, allNamespaces bool) ([]
 	// Create and configure the list action
 	listAction := action.NewList(actionConfig) // Use the specifically initialized config
 	listAction.AllNamespaces = allNamespaces
+	listAction.Selector = opts.Selector
+	listAction.Limit = opts.Limit
 	listAction.SetStateMask() // List deployed and failed states by default
 	log.Debug("Running Helm list action", "allNamespaces", allNamespaces)
 
@@ -72,10 +80,14 @@ func (c *RealHelmClient) ListReleases(_ context.Context, allNamespaces bool) ([]
 			log.Debug("Skipping nil release in list results")
 			continue
 		}
-		releases = append(releases, &ReleaseElement{
+		element := &ReleaseElement{
 			Name:      rel.Name,
 			Namespace: rel.Namespace,
-		})
+		}
+		if rel.Chart != nil && rel.Chart.Metadata != nil {
+			element.Chart = rel.Chart.Metadata.Name
+		}
+		releases = append(releases, element)
 	}
 
 	return releases, nil
@@ -101,7 +113,7 @@ func (c *RealHelmClient) initializeActionConfig() error {
 	// Use the stored settings if available
 	if c.settings == nil {
 		log.Warn("Helm settings not available during action config initialization, using defaults")
-		c.settings = cli.New()
+		c.settings = NewSettings()
 		currentNamespaceSetting = c.settings.Namespace() // Update if settings were just created
 		log.Debug("Default settings created", "default_namespace", currentNamespaceSetting)
 	}
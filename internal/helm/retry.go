@@ -0,0 +1,184 @@
+// Package helm provides internal utilities for interacting with Helm.
+package helm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// DefaultMaxRetries is the number of times a failed Helm SDK call is retried by default
+	DefaultMaxRetries = 3
+	// DefaultInitialBackoff is the delay before the first retry
+	DefaultInitialBackoff = 500 * time.Millisecond
+	// DefaultMaxBackoff caps the delay between retries
+	DefaultMaxBackoff = 10 * time.Second
+	// backoffMultiplier is the factor applied to the delay after each retry
+	backoffMultiplier = 2
+)
+
+// transientErrorMessages are fragments of error messages that indicate a temporary API server or
+// network problem. Helm and client-go often flatten errors into strings, so the typed checks in
+// IsTransientError cannot always see the cause.
+var transientErrorMessages = []string{
+	"connection refused",
+	"connection reset by peer",
+	"broken pipe",
+	"i/o timeout",
+	"tls handshake timeout",
+	"unexpected eof",
+	"http2: client connection lost",
+	"etcdserver: request timed out",
+	"etcdserver: leader changed",
+	"the server is currently unable to handle the request",
+	"too many requests",
+	"context deadline exceeded",
+}
+
+// RetryConfig controls how failed Helm SDK calls are retried
+type RetryConfig struct {
+	// MaxRetries is the number of retries after the first attempt; 0 disables retries
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry; it doubles after each retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryConfig returns the retry configuration used unless one is set on the adapter
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries:     DefaultMaxRetries,
+		InitialBackoff: DefaultInitialBackoff,
+		MaxBackoff:     DefaultMaxBackoff,
+	}
+}
+
+// RetryStats counts Helm SDK calls and their retries, for debug logging
+type RetryStats struct {
+	Calls           int
+	Retries         int
+	Recovered       int // Calls that succeeded after at least one retry
+	TransientErrors int
+	PermanentErrors int
+	Exhausted       int // Calls that still failed with a transient error after all retries
+}
+
+// retryStatsRecorder accumulates RetryStats across concurrent calls
+type retryStatsRecorder struct {
+	mu    sync.Mutex
+	stats RetryStats
+}
+
+func (r *retryStatsRecorder) record(update func(*RetryStats)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	update(&r.stats)
+}
+
+func (r *retryStatsRecorder) snapshot() RetryStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// IsTransientError reports whether err is likely a temporary failure worth retrying, such as an
+// API server timeout, throttling, or a dropped connection. Errors that will not change on retry,
+// such as a missing release or denied access, are permanent.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, driver.ErrReleaseNotFound) ||
+		apierrors.IsNotFound(err) || apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err) ||
+		apierrors.IsBadRequest(err) || apierrors.IsInvalid(err) {
+		return false
+	}
+	if apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) || apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) || apierrors.IsInternalError(err) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range transientErrorMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetry calls fn until it succeeds, fails with a permanent error, or the retries in cfg are
+// used up, waiting with exponential backoff between attempts. No attempt is started once ctx is
+// canceled. operation names the call in logs.
+func withRetry[T any](ctx context.Context, cfg RetryConfig, stats *retryStatsRecorder, operation string, fn func() (T, error)) (T, error) {
+	backoff := cfg.InitialBackoff
+	stats.record(func(s *RetryStats) { s.Calls++ })
+
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			var zero T
+			return zero, fmt.Errorf("%s canceled: %w", operation, err)
+		}
+		result, err := fn()
+		if err == nil {
+			if attempt > 1 {
+				stats.record(func(s *RetryStats) { s.Recovered++ })
+				log.Debug("Helm call succeeded after retries", "operation", operation, "attempts", attempt)
+			}
+			return result, nil
+		}
+
+		if !IsTransientError(err) {
+			stats.record(func(s *RetryStats) { s.PermanentErrors++ })
+			log.Debug("Helm call failed with permanent error", "operation", operation, "attempt", attempt, "error", err)
+			return result, err
+		}
+		stats.record(func(s *RetryStats) { s.TransientErrors++ })
+
+		if attempt > cfg.MaxRetries {
+			stats.record(func(s *RetryStats) { s.Exhausted++ })
+			log.Debug("Helm call failed, retries exhausted", "operation", operation, "attempts", attempt, "error", err)
+			if cfg.MaxRetries == 0 {
+				return result, err
+			}
+			return result, fmt.Errorf("%s failed after %d attempts: %w", operation, attempt, err)
+		}
+
+		log.Debug("Helm call failed with transient error, retrying",
+			"operation", operation, "attempt", attempt, "maxRetries", cfg.MaxRetries, "backoff", backoff, "error", err)
+		stats.record(func(s *RetryStats) { s.Retries++ })
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, fmt.Errorf("%s interrupted while waiting to retry: %w", operation, ctx.Err())
+		case <-timer.C:
+		}
+
+		backoff *= backoffMultiplier
+		if cfg.MaxBackoff > 0 && backoff > cfg.MaxBackoff {
+			backoff = cfg.MaxBackoff
+		}
+	}
+}
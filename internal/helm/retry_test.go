@@ -0,0 +1,132 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var testRetryConfig = RetryConfig{MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+func TestIsTransientError(t *testing.T) {
+	releases := schema.GroupResource{Resource: "secrets"}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "canceled", err: fmt.Errorf("list: %w", context.Canceled), want: false},
+		{name: "release not found", err: fmt.Errorf("get: %w", driver.ErrReleaseNotFound), want: false},
+		{name: "forbidden", err: apierrors.NewForbidden(releases, "sh.helm.release.v1.web.v1", errors.New("denied")), want: false},
+		{name: "generic", err: errors.New("chart is invalid"), want: false},
+		{name: "server timeout", err: apierrors.NewServerTimeout(releases, "list", 1), want: true},
+		{name: "throttled", err: apierrors.NewTooManyRequests("slow down", 1), want: true},
+		{name: "service unavailable", err: apierrors.NewServiceUnavailable("apiserver restarting"), want: true},
+		{name: "connection refused", err: fmt.Errorf("dial: %w", syscall.ECONNREFUSED), want: true},
+		{name: "flattened message", err: errors.New("Get \"https://10.0.0.1/api\": net/http: TLS handshake timeout"), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsTransientError(tt.err))
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	transient := apierrors.NewServiceUnavailable("apiserver restarting")
+
+	t.Run("recovers from transient errors", func(t *testing.T) {
+		var stats retryStatsRecorder
+		calls := 0
+		got, err := withRetry(context.Background(), testRetryConfig, &stats, "test", func() (string, error) {
+			calls++
+			if calls < 3 {
+				return "", transient
+			}
+			return "ok", nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", got)
+		assert.Equal(t, RetryStats{Calls: 1, Retries: 2, Recovered: 1, TransientErrors: 2}, stats.snapshot())
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		var stats retryStatsRecorder
+		calls := 0
+		_, err := withRetry(context.Background(), testRetryConfig, &stats, "test", func() (string, error) {
+			calls++
+			return "", transient
+		})
+		require.ErrorIs(t, err, transient)
+		assert.Contains(t, err.Error(), "failed after 3 attempts")
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, 1, stats.snapshot().Exhausted)
+	})
+
+	t.Run("does not retry permanent errors", func(t *testing.T) {
+		var stats retryStatsRecorder
+		calls := 0
+		_, err := withRetry(context.Background(), testRetryConfig, &stats, "test", func() (string, error) {
+			calls++
+			return "", driver.ErrReleaseNotFound
+		})
+		require.ErrorIs(t, err, driver.ErrReleaseNotFound)
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, RetryStats{Calls: 1, PermanentErrors: 1}, stats.snapshot())
+	})
+
+	t.Run("stops waiting when the context is canceled", func(t *testing.T) {
+		var stats retryStatsRecorder
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		cfg := RetryConfig{MaxRetries: 5, InitialBackoff: time.Hour}
+		_, err := withRetry(ctx, cfg, &stats, "test", func() (string, error) {
+			return "", transient
+		})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("does not call once the context is canceled", func(t *testing.T) {
+		var stats retryStatsRecorder
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		calls := 0
+		_, err := withRetry(ctx, testRetryConfig, &stats, "list releases", func() (string, error) {
+			calls++
+			return "ok", nil
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		assert.EqualError(t, err, "list releases canceled: context canceled")
+		assert.Equal(t, 0, calls)
+	})
+}
+
+func TestAdapterRetriesTransientErrors(t *testing.T) {
+	client := NewMockHelmClient()
+	client.SetupMockRelease("web", "prod", map[string]interface{}{"image": "nginx:1.25"}, &ChartMetadata{Name: "nginx"})
+	client.GetValuesError = apierrors.NewTooManyRequests("slow down", 1)
+
+	adapter := NewAdapter(client, nil, true)
+	adapter.SetRetryConfig(testRetryConfig)
+
+	_, err := adapter.GetReleaseValues(context.Background(), "web", "prod")
+	require.Error(t, err)
+	assert.Equal(t, 3, client.GetValuesCallCount)
+	assert.Equal(t, 2, adapter.RetryStats().Retries)
+
+	client.GetValuesError = nil
+	values, err := adapter.GetReleaseValues(context.Background(), "web", "prod")
+	require.NoError(t, err)
+	assert.Equal(t, "nginx:1.25", values["image"])
+	assert.Equal(t, 2, adapter.RetryStats().Calls)
+}
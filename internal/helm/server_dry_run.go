@@ -0,0 +1,170 @@
+// Package helm provides internal utilities for interacting with Helm.
+package helm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"helm.sh/helm/v3/pkg/action"
+	helmChart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// serverDryRunFieldManager is the field manager recorded on server-side apply dry-run requests
+const serverDryRunFieldManager = "irr-validate"
+
+// ServerDryRunFunc allows overriding the ServerDryRun function for testing
+var ServerDryRunFunc = ServerDryRun
+
+// policyDenialMarkers are message fragments of API server errors raised by admission control
+// rather than by the object itself: admission webhooks (OPA Gatekeeper, Kyverno),
+// ValidatingAdmissionPolicy and Pod Security Admission.
+var policyDenialMarkers = []string{
+	"denied the request",
+	"denied request",
+	"violates PodSecurity",
+}
+
+// PolicyDenial is a rendered object the cluster rejected during a server-side dry-run.
+type PolicyDenial struct {
+	Kind      string `json:"kind" yaml:"kind"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Name      string `json:"name" yaml:"name"`
+	Message   string `json:"message" yaml:"message"`
+}
+
+// ServerDryRunResult is the outcome of a server-side dry-run.
+type ServerDryRunResult struct {
+	// Manifest is the rendered release manifest
+	Manifest string
+	// Denials lists the objects admission control rejected
+	Denials []PolicyDenial
+}
+
+// ServerDryRun renders the chart the way 'helm upgrade --install --dry-run=server' does against the
+// cluster of the current kube context, then submits every rendered object to the API server as a
+// server-side apply dry-run so admission webhooks and policies evaluate it. Rendering failures are
+// returned as errors; objects rejected by admission control are reported in the result's Denials.
+func ServerDryRun(options *TemplateOptions) (*ServerDryRunResult, error) {
+	settings := NewSettings()
+	namespace := options.Namespace
+	if namespace == "" {
+		namespace = settings.Namespace()
+	}
+
+	actionConfig := new(action.Configuration)
+	if err := actionConfig.Init(settings.RESTClientGetter(), namespace, os.Getenv("HELM_DRIVER"), func(string, ...interface{}) {}); err != nil {
+		return nil, fmt.Errorf("failed to initialize Helm action config: %w", err)
+	}
+
+	values, err := mergeValues(options.ValuesFiles, options.SetValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge values: %w", err)
+	}
+
+	chartRequested, err := loader.Load(options.ChartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart from path %q: %w", options.ChartPath, err)
+	}
+
+	manifest, err := renderServerDryRun(actionConfig, chartRequested, values, options.ReleaseName, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	denials, err := dryRunApplyManifest(actionConfig, manifest)
+	if err != nil {
+		return nil, err
+	}
+	return &ServerDryRunResult{Manifest: manifest, Denials: denials}, nil
+}
+
+// renderServerDryRun renders an upgrade of the release with --dry-run=server, or an install when
+// the release does not exist yet.
+func renderServerDryRun(actionConfig *action.Configuration, chartRequested *helmChart.Chart, values map[string]interface{}, releaseName, namespace string) (string, error) {
+	history := action.NewHistory(actionConfig)
+	history.Max = 1
+	_, err := history.Run(releaseName)
+	switch {
+	case errors.Is(err, driver.ErrReleaseNotFound):
+		log.Debug("Release not found, rendering a server-side dry-run install", "release", releaseName)
+		install := action.NewInstall(actionConfig)
+		install.ReleaseName = releaseName
+		install.Namespace = namespace
+		install.DryRun = true
+		install.DryRunOption = "server"
+		rel, err := install.Run(chartRequested, values)
+		if err != nil {
+			return "", fmt.Errorf("helm install --dry-run=server failed for release %q: %w", releaseName, err)
+		}
+		return rel.Manifest, nil
+	case err != nil:
+		return "", fmt.Errorf("failed to get history of release %q: %w", releaseName, err)
+	}
+
+	upgrade := action.NewUpgrade(actionConfig)
+	upgrade.Namespace = namespace
+	upgrade.DryRun = true
+	upgrade.DryRunOption = "server"
+	rel, err := upgrade.Run(releaseName, chartRequested, values)
+	if err != nil {
+		return "", fmt.Errorf("helm upgrade --dry-run=server failed for release %q: %w", releaseName, err)
+	}
+	return rel.Manifest, nil
+}
+
+// dryRunApplyManifest submits each object of the manifest as a server-side apply dry-run and
+// collects the objects admission control denied. Any other API error fails the dry-run.
+func dryRunApplyManifest(actionConfig *action.Configuration, manifest string) ([]PolicyDenial, error) {
+	resources, err := actionConfig.KubeClient.Build(bytes.NewBufferString(manifest), false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build rendered resources for the server-side dry-run: %w", err)
+	}
+
+	var denials []PolicyDenial
+	force := true
+	for _, info := range resources {
+		kind := info.Mapping.GroupVersionKind.Kind
+		data, err := runtime.Encode(unstructured.UnstructuredJSONScheme, info.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s %q: %w", kind, info.Name, err)
+		}
+
+		helper := resource.NewHelper(info.Client, info.Mapping).DryRun(true).WithFieldManager(serverDryRunFieldManager)
+		_, err = helper.Patch(info.Namespace, info.Name, types.ApplyPatchType, data, &metav1.PatchOptions{Force: &force})
+		if err == nil {
+			continue
+		}
+		if !IsPolicyDenial(err) {
+			return nil, fmt.Errorf("server-side dry-run of %s %q failed: %w", kind, info.Name, err)
+		}
+		log.Debug("Admission control denied object", "kind", kind, "namespace", info.Namespace, "name", info.Name, "error", err)
+		denials = append(denials, PolicyDenial{Kind: kind, Namespace: info.Namespace, Name: info.Name, Message: err.Error()})
+	}
+	return denials, nil
+}
+
+// IsPolicyDenial reports whether err is an API server rejection by admission control
+// (an admission webhook, ValidatingAdmissionPolicy or Pod Security Admission).
+func IsPolicyDenial(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := err.Error()
+	for _, marker := range policyDenialMarkers {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
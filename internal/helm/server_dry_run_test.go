@@ -0,0 +1,53 @@
+package helm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPolicyDenial(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{
+			name: "gatekeeper webhook",
+			err:  errors.New(`admission webhook "validation.gatekeeper.sh" denied the request: [allowed-repos] container <app> has an invalid image repo <docker.io/nginx>`),
+			want: true,
+		},
+		{
+			name: "kyverno webhook",
+			err:  errors.New(`admission webhook "validate.kyverno.svc-fail" denied the request: policy Deployment/default/app for resource violation`),
+			want: true,
+		},
+		{
+			name: "validating admission policy",
+			err:  errors.New(`deployments.apps "app" is forbidden: ValidatingAdmissionPolicy 'registry' with binding 'registry-binding' denied request: image must come from harbor.local`),
+			want: true,
+		},
+		{
+			name: "pod security",
+			err:  errors.New(`pods "app" is forbidden: violates PodSecurity "restricted:latest": privileged`),
+			want: true,
+		},
+		{
+			name: "invalid object",
+			err:  errors.New(`Deployment.apps "app" is invalid: spec.template.spec.containers[0].image: Required value`),
+			want: false,
+		},
+		{
+			name: "rbac",
+			err:  errors.New(`deployments.apps "app" is forbidden: User "dev" cannot patch resource "deployments"`),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsPolicyDenial(tt.err))
+		})
+	}
+}
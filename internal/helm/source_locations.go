@@ -0,0 +1,44 @@
+// Package helm provides internal utilities for interacting with Helm.
+package helm
+
+import (
+	"os"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// locateValues locates values paths in the chart defaults and user values files, in the order Helm
+// layers them. Only the parts of the values files that can hold the paths are parsed again. Values
+// set with --set and its variants have no location, so their paths, and the paths below them, are
+// dropped again.
+func locateValues(loadedChart *chart.Chart, chartPath string, valueFiles []string, origins map[string]ValueOrigin, paths []string) analysis.SourceLocations {
+	locations := make(analysis.SourceLocations)
+	if len(paths) == 0 {
+		return locations
+	}
+	locations.TrackChartLocationsOf(loadedChart, chartPath, paths)
+	for _, file := range valueFiles {
+		data, err := os.ReadFile(file) //nolint:gosec // the values file was read before
+		if err == nil {
+			err = locations.TrackLocationsOf(analysis.NormalizeText(data), file, "", paths)
+		}
+		if err != nil {
+			log.Debug("Skipping source locations of values file", "file", file, "error", err)
+		}
+	}
+
+	for setPath, origin := range origins {
+		if origin.Type != OriginUserSet && origin.Type != OriginUserFileSet {
+			continue
+		}
+		for valuePath := range locations {
+			if valuePath == setPath || strings.HasPrefix(valuePath, setPath+".") || strings.HasPrefix(valuePath, setPath+"[") {
+				delete(locations, valuePath)
+			}
+		}
+	}
+	return locations
+}
@@ -0,0 +1,48 @@
+package helm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/cli/values"
+)
+
+func TestContextAwareAnalyzerSourceLocations(t *testing.T) {
+	chartDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: web\nversion: 0.1.0\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(chartDir, ValuesYAML), []byte(`image:
+  repository: nginx
+  tag: "1.25"
+sidecar:
+  image: envoyproxy/envoy:v1.30.1
+worker:
+  image: busybox:1.36
+`), 0o600))
+	overridesPath := filepath.Join(t.TempDir(), "prod.yaml")
+	require.NoError(t, os.WriteFile(overridesPath, []byte("sidecar:\n  image: envoyproxy/envoy:v1.31.0\n"), 0o600))
+
+	analysisContext, err := NewChartLoader().LoadChartAndTrackOrigins(&ChartLoaderOptions{
+		ChartPath: chartDir,
+		ValuesOpts: values.Options{
+			ValueFiles: []string{overridesPath},
+			Values:     []string{"worker.image=busybox:1.37"},
+		},
+	})
+	require.NoError(t, err)
+	chartAnalysis, err := NewContextAwareAnalyzer(analysisContext).AnalyzeContext()
+	require.NoError(t, err)
+
+	locations := make(map[string]string)
+	for _, pattern := range chartAnalysis.ImagePatterns {
+		if pattern.Location != nil {
+			locations[pattern.Path] = pattern.Location.String()
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"image":         filepath.Join(chartDir, ValuesYAML) + ":2:15",
+		"sidecar.image": overridesPath + ":2:10",
+	}, locations, "values set with --set have no location")
+}
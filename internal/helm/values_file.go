@@ -0,0 +1,168 @@
+// Package helm provides internal utilities for interacting with Helm.
+package helm
+
+import (
+	"os"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/limits"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// parsedValuesFile is a user values file, parsed chunk by chunk into the values it sets and the
+// paths derived from YAML anchors in it. Its YAML node tree is never held as a whole, so that a
+// multi-megabyte values file (e.g. vendored CRDs) costs little more memory than its values.
+type parsedValuesFile struct {
+	path    string
+	values  map[string]interface{}
+	anchors AnchorPaths
+}
+
+// parseValuesFiles reads and parses each values file, recording the origins of the values it sets
+// in origins, where they replace the origins recorded for earlier files.
+func parseValuesFiles(files []string, origins map[string]ValueOrigin) ([]*parsedValuesFile, error) {
+	parsed := make([]*parsedValuesFile, 0, len(files))
+	for _, file := range files {
+		if err := checkValuesFileSize(file); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(file) //nolint:gosec // filePath is validated earlier in the process
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read values file %s", file)
+		}
+		valuesFile, err := parseValuesFile(file, analysis.NormalizeText(data), origins)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse values file %s", file)
+		}
+		parsed = append(parsed, valuesFile)
+	}
+	return parsed, nil
+}
+
+// parseValuesFile parses the values YAML of a file chunk by chunk, decoding each chunk into the
+// values and walking its nodes for origins and anchors before the next chunk is parsed.
+func parseValuesFile(file string, data []byte, origins map[string]ValueOrigin) (*parsedValuesFile, error) {
+	valuesFile := &parsedValuesFile{path: file, values: map[string]interface{}{}, anchors: make(AnchorPaths)}
+	origin := ValueOrigin{Type: OriginUserFile, Path: file}
+	var decodeErr error
+	err := analysis.WalkValuesChunks(data, "", nil, func(chunk analysis.ValuesChunk) {
+		if decodeErr != nil {
+			return
+		}
+		chunkValues := map[string]interface{}{}
+		if chunk.Node.Kind != 0 {
+			if decodeErr = chunk.Node.Decode(&chunkValues); decodeErr != nil {
+				return
+			}
+		}
+		if chunk.Whole {
+			valuesFile.values = chunkValues
+			valuesFile.anchors = make(AnchorPaths)
+		} else {
+			insertValues(valuesFile.values, chunk.Keys, chunkValues)
+		}
+		trackNodeOrigins(chunk.Node, origins, origin, chunk.Path)
+		trackNodeAnchorPaths(chunk.Node, chunk.Path, valuesFile.anchors)
+	})
+	if err == nil {
+		err = decodeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return valuesFile, nil
+}
+
+// insertValues sets the values in the map below keys, whose maps are already in values.
+func insertValues(values map[string]interface{}, keys []string, chunkValues map[string]interface{}) {
+	for _, key := range keys {
+		child, ok := values[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			values[key] = child
+		}
+		values = child
+	}
+	for key, value := range chunkValues {
+		values[key] = value
+	}
+}
+
+// checkValuesFileSize returns a *limits.Error if a values file exceeds the values size limit, before
+// the file is read into memory.
+func checkValuesFileSize(file string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read values file %s", file)
+	}
+	return limits.CheckValuesSize(file, info.Size())
+}
+
+// trackNodeOrigins records origin for every mapping key path under node, overwriting
+// any previous origin for the same path. The paths are those of the decoded values map:
+// aliases are followed, merge keys (<<) contribute their keys, and only nested mappings
+// are descended into.
+func trackNodeOrigins(node *yaml.Node, origins map[string]ValueOrigin, origin ValueOrigin, prefix string) {
+	node = resolveAlias(node)
+	if node == nil {
+		return
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			trackNodeOrigins(child, origins, origin, prefix)
+		}
+	case yaml.MappingNode:
+		trackMappingOrigins(node, origins, origin, prefix, make(map[string]bool))
+	case yaml.SequenceNode, yaml.ScalarNode, yaml.AliasNode:
+		// Only mapping keys have value paths
+	}
+}
+
+// trackMappingOrigins records the keys of a mapping that are not in seen. Explicit keys are
+// recorded before merge keys are followed, and earlier merge sources before later ones,
+// matching YAML merge semantics.
+func trackMappingOrigins(node *yaml.Node, origins map[string]ValueOrigin, origin ValueOrigin, prefix string, seen map[string]bool) {
+	var merges []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if isMergeKey(key) {
+			merges = append(merges, value)
+			continue
+		}
+		if seen[key.Value] {
+			continue
+		}
+		seen[key.Value] = true
+		keyPath := analysis.JoinPath(prefix, key.Value)
+		origins[keyPath] = origin
+		if child := resolveAlias(value); child != nil && child.Kind == yaml.MappingNode {
+			trackNodeOrigins(child, origins, origin, keyPath)
+		}
+	}
+
+	for _, merge := range merges {
+		merge = resolveAlias(merge)
+		if merge == nil {
+			continue
+		}
+		sources := []*yaml.Node{merge}
+		if merge.Kind == yaml.SequenceNode {
+			sources = merge.Content
+		}
+		for _, source := range sources {
+			if source = resolveAlias(source); source != nil && source.Kind == yaml.MappingNode {
+				trackMappingOrigins(source, origins, origin, prefix, seen)
+			}
+		}
+	}
+}
+
+// resolveAlias returns the node an alias points to, or node itself.
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
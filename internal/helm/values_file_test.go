@@ -0,0 +1,121 @@
+package helm
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseValuesFiles(t *testing.T) {
+	dir := t.TempDir()
+	valuesPath := filepath.Join(dir, "values.yaml")
+	emptyPath := filepath.Join(dir, "empty.yaml")
+	require.NoError(t, os.WriteFile(valuesPath, []byte(anchorTestValues), 0o600))
+	require.NoError(t, os.WriteFile(emptyPath, nil, 0o600))
+
+	origins := make(map[string]ValueOrigin)
+	files, err := parseValuesFiles([]string{valuesPath, emptyPath}, origins)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	frontend, ok := files[0].values["frontend"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Contains(t, frontend, "image")
+	assert.Equal(t, "defaults.image", files[0].anchors["frontend.image"])
+	assert.Equal(t, ValueOrigin{Type: OriginUserFile, Path: valuesPath}, origins["frontend.image.tag"])
+
+	assert.Empty(t, files[1].values)
+
+	_, err = parseValuesFiles([]string{filepath.Join(dir, "missing.yaml")}, origins)
+	require.Error(t, err)
+}
+
+func TestTrackNodeOrigins(t *testing.T) {
+	var root yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte(anchorTestValues), &root))
+
+	origin := ValueOrigin{Type: OriginUserFile, Path: "values.yaml"}
+	origins := map[string]ValueOrigin{"frontend.image": {Type: OriginChartDefault}}
+	trackNodeOrigins(&root, origins, origin, "")
+
+	for _, path := range []string{
+		"defaults.image.repository",
+		"sidecarImage",
+		"frontend.image",
+		"frontend.image.tag",
+		"frontend.pullPolicy",
+		"backend.image.repository",
+		"backend.pullPolicy",
+		"worker.sidecar",
+		"jobs",
+	} {
+		assert.Equal(t, origin, origins[path], "origin for %s", path)
+	}
+	assert.NotContains(t, origins, "frontend.<<")
+	assert.NotContains(t, origins, "jobs[0].name", "sequence items are not tracked")
+}
+
+func TestParseValuesFileChunks(t *testing.T) {
+	// A values file large enough to be parsed in chunks, whose values, origins and anchors must
+	// match those of the file parsed whole
+	var buf bytes.Buffer
+	buf.WriteString("image:\n  repository: bitnami/nginx\n  tag: \"1.25\"\ncrds:\n")
+	for i := 0; buf.Len() < 1<<20; i++ {
+		fmt.Fprintf(&buf, "  crd%d:\n    kind: CustomResourceDefinition\n    spec: &spec%d\n      versions:\n        - name: v1\n", i, i)
+		fmt.Fprintf(&buf, "    copy: *spec%d\n", i)
+	}
+	buf.WriteString("sidecar:\n  image: busybox:1.36\n")
+	data := buf.Bytes()
+
+	origins := make(map[string]ValueOrigin)
+	chunked, err := parseValuesFile("values.yaml", data, origins)
+	require.NoError(t, err)
+
+	var root yaml.Node
+	require.NoError(t, yaml.Unmarshal(data, &root))
+	whole := map[string]interface{}{}
+	require.NoError(t, root.Decode(&whole))
+	wholeOrigins := make(map[string]ValueOrigin)
+	trackNodeOrigins(&root, wholeOrigins, ValueOrigin{Type: OriginUserFile, Path: "values.yaml"}, "")
+	wholeAnchors := make(AnchorPaths)
+	trackNodeAnchorPaths(&root, "", wholeAnchors)
+
+	assert.Equal(t, whole, chunked.values)
+	assert.Equal(t, wholeOrigins, origins)
+	assert.Equal(t, wholeAnchors, chunked.anchors)
+	assert.Equal(t, "crds.crd7.spec", chunked.anchors["crds.crd7.copy"])
+}
+
+// BenchmarkUserValuesFile measures the per-file work of LoadChartAndTrackOrigins (chunked parse,
+// merge decode, origin and anchor tracking) on a ~20MB values file of CRD-like documents.
+func BenchmarkUserValuesFile(b *testing.B) {
+	valuesPath := filepath.Join(b.TempDir(), "values.yaml")
+	data := crdValuesCorpus(20 << 20)
+	require.NoError(b, os.WriteFile(valuesPath, data, 0o600))
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for b.Loop() {
+		_, err := parseValuesFiles([]string{valuesPath}, make(map[string]ValueOrigin))
+		require.NoError(b, err)
+	}
+}
+
+// crdValuesCorpus generates values of about size bytes: an image followed by CRD-like documents.
+func crdValuesCorpus(size int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("image:\n  repository: bitnami/nginx\n  tag: \"1.25\"\ncrds:\n")
+	for i := 0; buf.Len() < size; i++ {
+		fmt.Fprintf(&buf, "  crd%d:\n    kind: CustomResourceDefinition\n    spec:\n      versions:\n        - name: v1\n          schema:\n            properties:\n", i)
+		for j := 0; j < 10; j++ {
+			fmt.Fprintf(&buf, "              field%d:\n                type: string\n", j)
+		}
+	}
+	return buf.Bytes()
+}
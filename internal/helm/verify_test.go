@@ -0,0 +1,102 @@
+package helm
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withExecCommand replaces execCommand with one that runs name for the duration of a test
+func withExecCommand(t *testing.T, name string) *[]string {
+	t.Helper()
+	var gotArgs []string
+	original := execCommand
+	execCommand = func(_ string, args ...string) *exec.Cmd {
+		gotArgs = args
+		return exec.CommandContext(context.Background(), name)
+	}
+	t.Cleanup(func() { execCommand = original })
+	return &gotArgs
+}
+
+func writeTestChartArchive(t *testing.T, extraFiles ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	chartPath := filepath.Join(dir, "mychart-1.0.0.tgz")
+	require.NoError(t, os.WriteFile(chartPath, []byte("not really a tarball"), 0o600))
+	for _, ext := range extraFiles {
+		require.NoError(t, os.WriteFile(chartPath+ext, []byte("signature"), 0o600))
+	}
+	return chartPath
+}
+
+func TestVerifyChart(t *testing.T) {
+	t.Run("directory is not verifiable", func(t *testing.T) {
+		_, err := VerifyChart(t.TempDir(), ChartVerifyOptions{})
+		require.ErrorIs(t, err, ErrChartNotPackaged)
+	})
+
+	t.Run("missing chart", func(t *testing.T) {
+		_, err := VerifyChart(filepath.Join(t.TempDir(), "missing.tgz"), ChartVerifyOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to access chart")
+	})
+
+	t.Run("missing provenance file", func(t *testing.T) {
+		chartPath := writeTestChartArchive(t)
+		_, err := VerifyChart(chartPath, ChartVerifyOptions{Keyring: filepath.Join(t.TempDir(), "pubring.gpg")})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "provenance verification failed")
+	})
+
+	t.Run("cosign signature verified", func(t *testing.T) {
+		chartPath := writeTestChartArchive(t, cosignSignatureExt)
+		gotArgs := withExecCommand(t, "true")
+
+		verification, err := VerifyChart(chartPath, ChartVerifyOptions{CosignKey: "cosign.pub"})
+		require.NoError(t, err)
+		assert.Equal(t, &ChartVerification{
+			Method:        VerificationMethodCosign,
+			Verified:      true,
+			SignatureFile: chartPath + cosignSignatureExt,
+		}, verification)
+		assert.Equal(t, []string{"verify-blob", "--key", "cosign.pub", "--signature", chartPath + cosignSignatureExt, chartPath}, *gotArgs)
+	})
+
+	t.Run("cosign signature verified offline", func(t *testing.T) {
+		chartPath := writeTestChartArchive(t, cosignSignatureExt)
+		gotArgs := withExecCommand(t, "true")
+
+		_, err := VerifyChart(chartPath, ChartVerifyOptions{CosignKey: "cosign.pub", Offline: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"verify-blob", "--key", "cosign.pub", "--signature", chartPath + cosignSignatureExt, "--offline", chartPath}, *gotArgs)
+	})
+
+	t.Run("cosign signature rejected", func(t *testing.T) {
+		chartPath := writeTestChartArchive(t, cosignSignatureExt)
+		withExecCommand(t, "false")
+
+		_, err := VerifyChart(chartPath, ChartVerifyOptions{CosignKey: "cosign.pub"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cosign verification failed")
+	})
+
+	t.Run("missing cosign signature", func(t *testing.T) {
+		chartPath := writeTestChartArchive(t)
+		withExecCommand(t, "true")
+
+		_, err := VerifyChart(chartPath, ChartVerifyOptions{CosignKey: "cosign.pub"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "could not load cosign signature")
+	})
+}
+
+func TestDefaultKeyring(t *testing.T) {
+	t.Setenv("GNUPGHOME", "/tmp/gnupg")
+	assert.Equal(t, filepath.Join("/tmp/gnupg", "pubring.gpg"), DefaultKeyring())
+}
@@ -0,0 +1,213 @@
+// Package helm provides internal utilities for interacting with Helm.
+package helm
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// yamlMergeKey is the YAML merge key used to splice anchored mappings into a mapping.
+const yamlMergeKey = "<<"
+
+// AnchorPaths maps a value path populated through a YAML alias or merge key
+// to the path of the anchored value it was copied from.
+type AnchorPaths map[string]string
+
+// TrackAnchorPaths parses raw values YAML and records, under the given prefix, every
+// value path whose content comes from an alias (*name) or a merge key (<<: *name).
+// Anchors are resolved by the normal values decoding; this only preserves provenance.
+// The YAML is parsed chunk by chunk, and whole if an alias refers to an anchor in another chunk.
+func TrackAnchorPaths(data []byte, prefix string, paths AnchorPaths) error {
+	err := analysis.WalkValuesChunks(data, prefix, nil, func(chunk analysis.ValuesChunk) {
+		trackNodeAnchorPaths(chunk.Node, chunk.Path, paths)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to parse YAML for anchor tracking: %w", err)
+	}
+	return nil
+}
+
+// trackNodeAnchorPaths is TrackAnchorPaths for an already parsed node tree.
+func trackNodeAnchorPaths(root *yaml.Node, prefix string, paths AnchorPaths) {
+	tracker := &anchorTracker{anchors: make(map[string]string), paths: paths}
+	tracker.walk(root, prefix)
+}
+
+// anchorTracker walks a YAML node tree in document order, remembering where anchors are defined.
+type anchorTracker struct {
+	anchors map[string]string // anchor name -> path where it was defined
+	paths   AnchorPaths
+}
+
+// walk visits node at path, recording anchor definitions and alias/merge-derived paths.
+func (t *anchorTracker) walk(node *yaml.Node, path string) {
+	if node == nil {
+		return
+	}
+	if node.Anchor != "" {
+		t.anchors[node.Anchor] = path
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			t.walk(child, path)
+		}
+	case yaml.AliasNode:
+		if source, ok := t.anchors[node.Value]; ok {
+			t.markDerived(node.Alias, path, source)
+		}
+	case yaml.MappingNode:
+		t.walkMapping(node, path)
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			t.walk(item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case yaml.ScalarNode:
+		// Scalars carry no further structure
+	}
+}
+
+// walkMapping visits a mapping node. Explicit keys take precedence over merged keys,
+// and earlier merge sources take precedence over later ones, matching YAML merge semantics.
+func (t *anchorTracker) walkMapping(node *yaml.Node, path string) {
+	explicit := make(map[string]bool)
+	var merges []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if isMergeKey(key) {
+			merges = append(merges, value)
+			continue
+		}
+		explicit[key.Value] = true
+		t.walk(value, analysis.JoinPath(path, key.Value))
+	}
+
+	for _, merge := range merges {
+		sources := []*yaml.Node{merge}
+		if merge.Kind == yaml.SequenceNode {
+			sources = merge.Content
+		}
+		for _, source := range sources {
+			if source.Kind != yaml.AliasNode || source.Alias == nil {
+				// Inline mappings under << are not anchor-derived; walk them like regular keys
+				t.walk(source, path)
+				continue
+			}
+			sourcePath, ok := t.anchors[source.Value]
+			if !ok {
+				continue
+			}
+			target := source.Alias
+			for i := 0; i+1 < len(target.Content); i += 2 {
+				key := target.Content[i].Value
+				if explicit[key] {
+					continue
+				}
+				explicit[key] = true
+				t.markDerived(target.Content[i+1], analysis.JoinPath(path, key), analysis.JoinPath(sourcePath, key))
+			}
+		}
+	}
+}
+
+// markDerived records path and all of its descendants as derived from sourcePath.
+func (t *anchorTracker) markDerived(node *yaml.Node, path, sourcePath string) {
+	if node == nil {
+		return
+	}
+	t.paths[path] = sourcePath
+
+	switch node.Kind {
+	case yaml.AliasNode:
+		t.markDerived(node.Alias, path, sourcePath)
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if isMergeKey(node.Content[i]) {
+				// Merged keys of the anchored mapping keep the anchor as their source
+				t.markDerivedMerge(node.Content[i+1], path, sourcePath)
+				continue
+			}
+			t.markDerived(node.Content[i+1], analysis.JoinPath(path, key), analysis.JoinPath(sourcePath, key))
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			t.markDerived(item, fmt.Sprintf("%s[%d]", path, i), fmt.Sprintf("%s[%d]", sourcePath, i))
+		}
+	case yaml.DocumentNode, yaml.ScalarNode:
+		// Nothing further to record
+	}
+}
+
+// markDerivedMerge records the keys spliced in by a merge key inside an already derived mapping.
+func (t *anchorTracker) markDerivedMerge(merge *yaml.Node, path, sourcePath string) {
+	sources := []*yaml.Node{merge}
+	if merge.Kind == yaml.SequenceNode {
+		sources = merge.Content
+	}
+	for _, source := range sources {
+		target := source
+		if source.Kind == yaml.AliasNode {
+			target = source.Alias
+		}
+		if target == nil || target.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(target.Content); i += 2 {
+			key := target.Content[i].Value
+			childPath := analysis.JoinPath(path, key)
+			if _, exists := t.paths[childPath]; exists {
+				continue
+			}
+			t.markDerived(target.Content[i+1], childPath, analysis.JoinPath(sourcePath, key))
+		}
+	}
+}
+
+// isMergeKey reports whether a mapping key is an (unquoted) YAML merge key.
+func isMergeKey(key *yaml.Node) bool {
+	return key.Value == yamlMergeKey && key.Tag != "!!str"
+}
+
+// trackChartAnchorPaths records anchor-derived paths from the raw values.yaml of a chart
+// and its dependencies, prefixing subchart paths with the dependency name.
+func trackChartAnchorPaths(loadedChart *chart.Chart, prefix string, paths AnchorPaths) {
+	if loadedChart == nil {
+		return
+	}
+	for _, file := range loadedChart.Raw {
+		if file == nil || file.Name != ValuesYAML {
+			continue
+		}
+		if err := TrackAnchorPaths(file.Data, prefix, paths); err != nil {
+			log.Debug("Skipping anchor tracking for chart values", "chart", loadedChart.Name(), "error", err)
+		}
+	}
+	for _, dep := range loadedChart.Dependencies() {
+		if dep == nil || dep.Metadata == nil {
+			continue
+		}
+		for _, key := range analysis.DependencyValuesKeys(loadedChart, dep) {
+			trackChartAnchorPaths(dep, analysis.JoinPath(prefix, key), paths)
+		}
+	}
+}
+
+// trackAnchorPaths collects anchor-derived paths from the chart defaults and user values files.
+// The paths of the user values files, tracked as they were parsed, replace those of the chart
+// defaults.
+func trackAnchorPaths(loadedChart *chart.Chart, valueFiles []*parsedValuesFile) AnchorPaths {
+	paths := make(AnchorPaths)
+	trackChartAnchorPaths(loadedChart, "", paths)
+
+	for _, file := range valueFiles {
+		maps.Copy(paths, file.anchors)
+	}
+	return paths
+}
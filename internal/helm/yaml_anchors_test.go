@@ -0,0 +1,146 @@
+package helm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/cli/values"
+)
+
+const anchorTestValues = `
+defaults: &defaults
+  image:
+    repository: nginx
+    tag: "1.25"
+  pullPolicy: IfNotPresent
+
+sidecarImage: &sidecar quay.io/prometheus/node-exporter:v1.7.0
+
+frontend:
+  <<: *defaults
+
+backend:
+  <<: *defaults
+  image:
+    repository: my/backend
+    tag: "2.0"
+
+worker:
+  sidecar: *sidecar
+
+jobs:
+  - <<: *defaults
+    name: migrate
+`
+
+func TestTrackAnchorPaths(t *testing.T) {
+	paths := make(AnchorPaths)
+	require.NoError(t, TrackAnchorPaths([]byte(anchorTestValues), "", paths))
+
+	testCases := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"merge key copies nested mapping", "frontend.image", "defaults.image"},
+		{"merge key copies nested scalar", "frontend.image.repository", "defaults.image.repository"},
+		{"merge key copies sibling scalar", "backend.pullPolicy", "defaults.pullPolicy"},
+		{"alias scalar", "worker.sidecar", "sidecarImage"},
+		{"merge key inside sequence item", "jobs[0].image.tag", "defaults.image.tag"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, paths[tc.path])
+		})
+	}
+
+	t.Run("explicit keys are not anchor-derived", func(t *testing.T) {
+		assert.NotContains(t, paths, "backend.image")
+		assert.NotContains(t, paths, "backend.image.repository")
+		assert.NotContains(t, paths, "jobs[0].name")
+	})
+
+	t.Run("anchor definitions are not anchor-derived", func(t *testing.T) {
+		assert.NotContains(t, paths, "defaults.image")
+		assert.NotContains(t, paths, "sidecarImage")
+	})
+}
+
+func TestTrackAnchorPathsWithPrefixAndMergeList(t *testing.T) {
+	data := []byte(`
+base: &base
+  image: busybox:1.36
+extra: &extra
+  image: alpine:3.19
+  initImage: alpine:3.19
+app:
+  <<: [*base, *extra]
+`)
+	paths := make(AnchorPaths)
+	require.NoError(t, TrackAnchorPaths(data, "child", paths))
+
+	// Earlier merge sources take precedence over later ones
+	assert.Equal(t, "child.base.image", paths["child.app.image"])
+	assert.Equal(t, "child.extra.initImage", paths["child.app.initImage"])
+}
+
+func TestTrackChartAnchorPathsAliasedDependencies(t *testing.T) {
+	subchartValues := []byte("defaults: &defaults\n  image: redis:7.2\nprimary:\n  <<: *defaults\n")
+	redis := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "redis", Version: "1.0.0"},
+		Raw:      []*chart.File{{Name: ValuesYAML, Data: subchartValues}},
+	}
+	parent := &chart.Chart{Metadata: &chart.Metadata{Name: "app", Version: "1.0.0", Dependencies: []*chart.Dependency{
+		{Name: "redis", Alias: "cache"},
+		{Name: "redis", Alias: "sessions"},
+	}}}
+	parent.AddDependency(redis)
+
+	paths := make(AnchorPaths)
+	trackChartAnchorPaths(parent, "", paths)
+	assert.Equal(t, AnchorPaths{
+		"cache.primary.image":    "cache.defaults.image",
+		"sessions.primary.image": "sessions.defaults.image",
+	}, paths)
+}
+
+func TestTrackAnchorPathsInvalidYAML(t *testing.T) {
+	err := TrackAnchorPaths([]byte("key: [unclosed"), "", make(AnchorPaths))
+	assert.Error(t, err)
+}
+
+func TestContextAwareAnalyzerAnchorSource(t *testing.T) {
+	chartDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: anchors\nversion: 0.1.0\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(chartDir, ValuesYAML), []byte(anchorTestValues), 0o600))
+
+	analysisContext, err := NewChartLoader().LoadChartAndTrackOrigins(&ChartLoaderOptions{
+		ChartPath:  chartDir,
+		ValuesOpts: values.Options{},
+	})
+	require.NoError(t, err)
+
+	// Anchors are resolved in the merged values
+	frontend, ok := analysisContext.Values["frontend"].(map[string]interface{})
+	require.True(t, ok, "frontend values should be present")
+	assert.Contains(t, frontend, "image")
+
+	chartAnalysis, err := NewContextAwareAnalyzer(analysisContext).AnalyzeContext()
+	require.NoError(t, err)
+
+	anchorSources := make(map[string]string)
+	for _, pattern := range chartAnalysis.ImagePatterns {
+		anchorSources[pattern.Path] = pattern.AnchorSource
+	}
+
+	require.Contains(t, anchorSources, "frontend.image")
+	assert.Equal(t, "defaults.image", anchorSources["frontend.image"])
+	require.Contains(t, anchorSources, "defaults.image")
+	assert.Empty(t, anchorSources["defaults.image"], "Anchor definition should not report an anchor source")
+	require.Contains(t, anchorSources, "backend.image")
+	assert.Empty(t, anchorSources["backend.image"], "Explicit override of a merged key should not report an anchor source")
+}
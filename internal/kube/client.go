@@ -0,0 +1,123 @@
+// Package kube provides internal utilities for reading workload state from the Kubernetes API.
+package kube
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lucas-albers-lz4/irr/internal/helm"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// podListPageSize is the number of pods requested per page when listing pods
+const podListPageSize = 500
+
+// Container types reported in PodImage.ContainerType
+const (
+	ContainerTypeContainer = "container"
+	ContainerTypeInit      = "initContainer"
+	ContainerTypeEphemeral = "ephemeralContainer"
+)
+
+// PodImage is a single container image reference found in a running pod
+type PodImage struct {
+	Namespace     string
+	Pod           string
+	Container     string
+	ContainerType string
+	Image         string
+}
+
+// ClientInterface defines the methods needed for Kubernetes API interactions
+type ClientInterface interface {
+	// ListPodImages lists the images of every container in the pods of a namespace.
+	// An empty namespace lists pods across all namespaces.
+	ListPodImages(ctx context.Context, namespace string) ([]PodImage, error)
+	// GetConfigData reads a ConfigMap, or a Secret when no ConfigMap has that name.
+	GetConfigData(ctx context.Context, namespace, name string) (*ConfigData, error)
+	// PutConfigMapData stores data under key of a ConfigMap, creating the ConfigMap if needed.
+	PutConfigMapData(ctx context.Context, namespace, name, key string, data []byte) error
+}
+
+// Client implements ClientInterface using client-go
+type Client struct {
+	clientset kubernetes.Interface
+}
+
+// NewClient creates a Client for the current kube context. Connection settings are resolved the
+// same way as Helm's (KUBECONFIG, HELM_KUBECONTEXT, ...) and honor the cluster selected with
+// helm.SetKubeOptions, so it targets the same cluster as the Helm calls.
+func NewClient() (*Client, error) {
+	settings := helm.NewSettings()
+	restConfig, err := settings.RESTClientGetter().ToRESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Kubernetes client configuration: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	return NewClientForClientset(clientset), nil
+}
+
+// NewClientForClientset creates a Client backed by an existing clientset.
+func NewClientForClientset(clientset kubernetes.Interface) *Client {
+	return &Client{clientset: clientset}
+}
+
+// ListPodImages lists the images of all containers, init containers and ephemeral containers
+// in the pods of namespace (all namespaces if empty), following list pagination.
+func (c *Client) ListPodImages(ctx context.Context, namespace string) ([]PodImage, error) {
+	log.Debug("Listing pod images", "namespace", namespace)
+
+	var images []PodImage
+	opts := metav1.ListOptions{Limit: podListPageSize}
+	for {
+		pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods in namespace %q: %w", namespace, err)
+		}
+		for i := range pods.Items {
+			images = append(images, podImages(&pods.Items[i])...)
+		}
+		if pods.Continue == "" {
+			break
+		}
+		opts.Continue = pods.Continue
+	}
+
+	log.Debug("Listed pod images", "namespace", namespace, "count", len(images))
+	return images, nil
+}
+
+// podImages returns the image references of every container in pod.
+func podImages(pod *corev1.Pod) []PodImage {
+	images := make([]PodImage, 0, len(pod.Spec.InitContainers)+len(pod.Spec.Containers)+len(pod.Spec.EphemeralContainers))
+	add := func(name, containerType, image string) {
+		if image == "" {
+			return
+		}
+		images = append(images, PodImage{
+			Namespace:     pod.Namespace,
+			Pod:           pod.Name,
+			Container:     name,
+			ContainerType: containerType,
+			Image:         image,
+		})
+	}
+
+	for i := range pod.Spec.InitContainers {
+		add(pod.Spec.InitContainers[i].Name, ContainerTypeInit, pod.Spec.InitContainers[i].Image)
+	}
+	for i := range pod.Spec.Containers {
+		add(pod.Spec.Containers[i].Name, ContainerTypeContainer, pod.Spec.Containers[i].Image)
+	}
+	for i := range pod.Spec.EphemeralContainers {
+		container := &pod.Spec.EphemeralContainers[i].EphemeralContainerCommon
+		add(container.Name, ContainerTypeEphemeral, container.Image)
+	}
+	return images
+}
@@ -0,0 +1,57 @@
+package kube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestPod(namespace, name string, spec corev1.PodSpec) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       spec,
+	}
+}
+
+func TestListPodImages(t *testing.T) {
+	clientset := fake.NewClientset(
+		newTestPod("web", "nginx-0", corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init", Image: "busybox:1.36"}},
+			Containers:     []corev1.Container{{Name: "nginx", Image: "docker.io/library/nginx:1.25"}},
+			EphemeralContainers: []corev1.EphemeralContainer{{
+				EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debug", Image: "quay.io/debug/tools:latest"},
+			}},
+		}),
+		newTestPod("monitoring", "prometheus-0", corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "prometheus", Image: "quay.io/prometheus/prometheus:v2.51.0"}},
+		}),
+	)
+	client := NewClientForClientset(clientset)
+
+	t.Run("all namespaces", func(t *testing.T) {
+		images, err := client.ListPodImages(context.Background(), "")
+		require.NoError(t, err)
+		assert.Len(t, images, 4)
+	})
+
+	t.Run("single namespace includes every container type", func(t *testing.T) {
+		images, err := client.ListPodImages(context.Background(), "web")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []PodImage{
+			{Namespace: "web", Pod: "nginx-0", Container: "init", ContainerType: ContainerTypeInit, Image: "busybox:1.36"},
+			{Namespace: "web", Pod: "nginx-0", Container: "nginx", ContainerType: ContainerTypeContainer, Image: "docker.io/library/nginx:1.25"},
+			{Namespace: "web", Pod: "nginx-0", Container: "debug", ContainerType: ContainerTypeEphemeral, Image: "quay.io/debug/tools:latest"},
+		}, images)
+	})
+
+	t.Run("empty namespace has no images", func(t *testing.T) {
+		images, err := client.ListPodImages(context.Background(), "empty")
+		require.NoError(t, err)
+		assert.Empty(t, images)
+	})
+}
@@ -0,0 +1,100 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Object kinds reported in ConfigData.Kind
+const (
+	KindConfigMap = "ConfigMap"
+	KindSecret    = "Secret"
+)
+
+// ErrConfigNotFound is returned when neither a ConfigMap nor a Secret with the requested name exists
+var ErrConfigNotFound = errors.New("config not found in cluster")
+
+// ConfigData is the data of a ConfigMap or Secret read from the cluster
+type ConfigData struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Data      map[string][]byte
+}
+
+// GetConfigData reads the ConfigMap name in namespace, falling back to a Secret of the same name
+// when there is no such ConfigMap. ConfigMap data and binary data are merged; Secret data is
+// returned decoded.
+func (c *Client) GetConfigData(ctx context.Context, namespace, name string) (*ConfigData, error) {
+	log.Debug("Reading config from cluster", "namespace", namespace, "name", name)
+
+	configMap, err := c.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		data := make(map[string][]byte, len(configMap.Data)+len(configMap.BinaryData))
+		for key, value := range configMap.Data {
+			data[key] = []byte(value)
+		}
+		for key, value := range configMap.BinaryData {
+			data[key] = value
+		}
+		return &ConfigData{Kind: KindConfigMap, Namespace: namespace, Name: name, Data: data}, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get ConfigMap %s/%s: %w", namespace, name, err)
+	}
+
+	secret, err := c.clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: no ConfigMap or Secret %s/%s", ErrConfigNotFound, namespace, name)
+		}
+		return nil, fmt.Errorf("failed to get Secret %s/%s: %w", namespace, name, err)
+	}
+	data := make(map[string][]byte, len(secret.Data)+len(secret.StringData))
+	for key, value := range secret.StringData {
+		data[key] = []byte(value)
+	}
+	for key, value := range secret.Data {
+		data[key] = value
+	}
+	return &ConfigData{Kind: KindSecret, Namespace: namespace, Name: name, Data: data}, nil
+}
+
+// PutConfigMapData stores data under key of the ConfigMap name in namespace, keeping its other
+// keys. The ConfigMap is created when it does not exist.
+func (c *Client) PutConfigMapData(ctx context.Context, namespace, name, key string, data []byte) error {
+	log.Debug("Writing config to cluster", "namespace", namespace, "name", name, "key", key)
+
+	configMaps := c.clientset.CoreV1().ConfigMaps(namespace)
+	configMap, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Data:       map[string]string{key: string(data)},
+		}
+		if _, err := configMaps.Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create ConfigMap %s/%s: %w", namespace, name, err)
+		}
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to get ConfigMap %s/%s: %w", namespace, name, err)
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[key] = string(data)
+	// A key is either text or binary data, never both
+	delete(configMap.BinaryData, key)
+	if _, err := configMaps.Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update ConfigMap %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
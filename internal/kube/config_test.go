@@ -0,0 +1,57 @@
+package kube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetConfigData(t *testing.T) {
+	client := NewClientForClientset(fake.NewClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "irr-config"},
+			Data:       map[string]string{"registry-mappings.yaml": "registries: {}"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "irr-secret"},
+			Data:       map[string][]byte{"registry-mappings.yaml": []byte("registries: {}")},
+		},
+	))
+
+	config, err := client.GetConfigData(context.Background(), "ci", "irr-config")
+	require.NoError(t, err)
+	assert.Equal(t, KindConfigMap, config.Kind)
+	assert.Equal(t, []byte("registries: {}"), config.Data["registry-mappings.yaml"])
+
+	config, err = client.GetConfigData(context.Background(), "ci", "irr-secret")
+	require.NoError(t, err)
+	assert.Equal(t, KindSecret, config.Kind, "a Secret is used when no ConfigMap has the name")
+	assert.Equal(t, []byte("registries: {}"), config.Data["registry-mappings.yaml"])
+
+	_, err = client.GetConfigData(context.Background(), "web", "irr-config")
+	require.ErrorIs(t, err, ErrConfigNotFound)
+	assert.Contains(t, err.Error(), "web/irr-config")
+}
+
+func TestPutConfigMapData(t *testing.T) {
+	clientset := fake.NewClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "irr-overrides"},
+		Data:       map[string]string{"api.yaml": "image: {}"},
+	})
+	client := NewClientForClientset(clientset)
+
+	require.NoError(t, client.PutConfigMapData(context.Background(), "ci", "irr-overrides", "web.yaml", []byte("web: {}")))
+	configMap, err := clientset.CoreV1().ConfigMaps("ci").Get(context.Background(), "irr-overrides", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"api.yaml": "image: {}", "web.yaml": "web: {}"}, configMap.Data)
+
+	require.NoError(t, client.PutConfigMapData(context.Background(), "web", "irr-overrides", "web.yaml", []byte("web: {}")))
+	configMap, err = clientset.CoreV1().ConfigMaps("web").Get(context.Background(), "irr-overrides", metav1.GetOptions{})
+	require.NoError(t, err, "a missing ConfigMap is created")
+	assert.Equal(t, map[string]string{"web.yaml": "web: {}"}, configMap.Data)
+}
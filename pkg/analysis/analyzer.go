@@ -6,16 +6,21 @@ package analysis
 
 import (
 	"fmt"
+	"maps"
 	"path"
 	"reflect"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
 	"errors"
 
 	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
 	"github.com/lucas-albers-lz4/irr/pkg/keys"
+	"github.com/lucas-albers-lz4/irr/pkg/limits"
+	"github.com/lucas-albers-lz4/irr/pkg/timing"
 	helmchart "helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 )
@@ -58,6 +63,7 @@ func (h *HelmChartLoader) Load(chartPath string) (*helmchart.Chart, error) {
 		// Wrap the error from the external loader package
 		return nil, fmt.Errorf("failed to load chart from path '%s': %w", chartPath, err)
 	}
+	NormalizeChartFiles(chartData)
 	return chartData, nil
 }
 
@@ -65,8 +71,9 @@ func (h *HelmChartLoader) Load(chartPath string) (*helmchart.Chart, error) {
 // It scans chart values recursively to find patterns that represent container images,
 // supporting both map-based and string-based image definitions.
 type Analyzer struct {
-	chartPath string      // Path to the chart being analyzed
-	loader    ChartLoader // Interface for loading charts, enables testing
+	chartPath string              // Path to the chart being analyzed
+	loader    ChartLoader         // Interface for loading charts, enables testing
+	depth     limits.DepthTracker // Nesting depth of the values being traversed
 }
 
 // NewAnalyzer creates a new Analyzer instance configured with the specified chart path and loader.
@@ -112,6 +119,10 @@ func (a *Analyzer) Analyze() (*ChartAnalysis, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to load chart: %w", err)
 	}
+	if err := CheckChartValuesSize(chart, a.chartPath); err != nil {
+		return nil, err
+	}
+	defer timing.Start(timing.SpanAnalysis, a.chartPath)()
 
 	// Analyze values
 	if err := a.analyzeValues(chart.Values, "", analysis); err != nil {
@@ -138,6 +149,9 @@ func (a *Analyzer) Analyze() (*ChartAnalysis, error) {
 		// Analyze the dependency values, passing the CORRECT prefix
 		// The analyzeValues function itself will handle adding the '.' separator internally
 		if err := a.analyzeValues(dep.Values, depName, depAnalysis); err != nil {
+			if IsLimitError(err) {
+				return nil, err
+			}
 			log.Warn("Error analyzing dependency values, skipping", "dependency", depName, "error", err)
 			continue // Skip this dependency on error
 		}
@@ -146,6 +160,14 @@ func (a *Analyzer) Analyze() (*ChartAnalysis, error) {
 		// mergeAnalysis just appends lists, paths already have prefix now.
 		analysis.mergeAnalysis(depAnalysis)
 	}
+	if err := limits.CheckImagePatterns(len(analysis.ImagePatterns), ""); err != nil {
+		return nil, err
+	}
+
+	// Locate each image in the values.yaml files of the chart and its dependencies
+	locations := make(SourceLocations)
+	locations.TrackChartLocationsOf(chart, a.chartPath, LocationPaths(analysis.ImagePatterns))
+	locations.Apply(analysis.ImagePatterns)
 
 	return analysis, nil
 }
@@ -157,6 +179,9 @@ func (a *Analyzer) AnalyzeValues(values map[string]interface{}) (*ChartAnalysis,
 	if err != nil {
 		return nil, err
 	}
+	if err := limits.CheckImagePatterns(len(analysis.ImagePatterns), ""); err != nil {
+		return nil, err
+	}
 	return analysis, nil
 }
 
@@ -177,7 +202,8 @@ func (a *Analyzer) normalizeImageValues(val map[string]interface{}) (registry, r
 	registryVal, hasRegistry := ensureString(val[keys.Registry])
 	repositoryVal, hasRepository := ensureString(val[keys.Repository])
 	tagVal, hasTag := ensureString(val[keys.Tag])
-	digestVal, hasDigest := ensureString(val["digest"])
+	digestVal, inRepository := imageMapDigest(val)
+	hasDigest := digestVal != ""
 
 	log.Debug(
 		"normalizeImageValues: Extracted map values",
@@ -188,7 +214,7 @@ func (a *Analyzer) normalizeImageValues(val map[string]interface{}) (registry, r
 	)
 
 	// --- Initial Setup ---
-	finalRegistry := DefaultRegistry // Start with default registry
+	finalRegistry := image.UnqualifiedRegistry() // Start with the registry of unqualified images
 	finalRepository := ""
 	finalTag := "" // Start with empty tag, apply default later if needed
 	finalDigest := ""
@@ -196,6 +222,9 @@ func (a *Analyzer) normalizeImageValues(val map[string]interface{}) (registry, r
 	// --- Determine Repository ---
 	if hasRepository && repositoryVal != "" {
 		finalRepository = repositoryVal
+		if inRepository {
+			finalRepository, _, _ = image.SplitRepositoryDigest(repositoryVal)
+		}
 	} else {
 		log.Warn("normalizeImageValues: No repository found in map", "mapValue", val)
 		return DefaultRegistry, "", DefaultTag // Return defaults on critical failure
@@ -206,17 +235,16 @@ func (a *Analyzer) normalizeImageValues(val map[string]interface{}) (registry, r
 		finalRegistry = registryVal
 	} else {
 		// If no explicit registry, try to parse from repo string
-		parts := splitRepoPath(finalRepository)
-		if len(parts) > 1 && (strings.Contains(parts[0], ".") || strings.Contains(parts[0], ":")) {
-			finalRegistry = parts[0]
-			finalRepository = parts[1] // Update repository to exclude parsed registry
+		if host, repoPath, ok := image.SplitRepositoryHost(finalRepository); ok {
+			finalRegistry = host
+			finalRepository = repoPath // Update repository to exclude parsed registry
 		}
-		// Otherwise, keep the DefaultRegistry assigned initially
+		// Otherwise, keep the default registry assigned initially
 	}
 
 	// --- Determine Tag/Digest ---
 	switch {
-	case hasDigest && digestVal != "":
+	case hasDigest:
 		finalDigest = digestVal
 		finalTag = "" // Clear tag if digest is used
 		log.Debug("normalizeImageValues: Using digest from map", "digest", finalDigest)
@@ -241,10 +269,64 @@ func (a *Analyzer) normalizeImageValues(val map[string]interface{}) (registry, r
 	// Trim trailing slash from registry
 	finalRegistry = strings.TrimSuffix(finalRegistry, "/")
 
-	// Return the final values (digest is handled internally but not returned by this signature)
+	// Return the final values (the digest is read separately with imageMapDigest)
 	return finalRegistry, finalRepository, finalTag
 }
 
+// imageMapDigest returns the digest an image map pins, from its digest value or, failing that,
+// from a repository that ends in one (e.g. repository: gcr.io/distroless/static@sha256:...).
+// inRepository reports the latter.
+func imageMapDigest(val map[string]interface{}) (digest string, inRepository bool) {
+	if digestVal, _ := ensureString(val[keys.Digest]); digestVal != "" {
+		return digestVal, false
+	}
+	repositoryVal, _ := ensureString(val[keys.Repository])
+	_, digest, inRepository = image.SplitRepositoryDigest(repositoryVal)
+	return digest, inRepository
+}
+
+// mapImagePattern builds the pattern for an image map at path from its normalized values. The
+// pattern value is the full reference, pinned by digest if the map has one.
+func (a *Analyzer) mapImagePattern(val map[string]interface{}, path string) ImagePattern {
+	registry, repository, tag := a.normalizeImageValues(val)
+	digest, inRepository := imageMapDigest(val)
+
+	structure := map[string]interface{}{
+		keys.Registry:   registry,
+		keys.Repository: repository,
+	}
+	value := registry + "/" + repository
+	if tag != "" { // Only include tag if it's not empty after normalization
+		structure[keys.Tag] = tag
+		value += ":" + tag
+	}
+	if digest != "" {
+		structure[keys.Digest] = digest
+		value += "@" + digest
+	}
+
+	return ImagePattern{
+		Path:                 path,
+		Type:                 PatternTypeMap,
+		Value:                value,
+		Structure:            structure,
+		Count:                1,
+		RegistryInRepository: repositoryHasRegistry(val),
+		DigestInRepository:   inRepository,
+	}
+}
+
+// repositoryHasRegistry reports whether an image map has no registry value and carries the
+// registry host in its repository instead (e.g. repository: quay.io/org/app).
+func repositoryHasRegistry(val map[string]interface{}) bool {
+	if registryVal, _ := ensureString(val[keys.Registry]); registryVal != "" {
+		return false
+	}
+	repositoryVal, _ := ensureString(val[keys.Repository])
+	_, _, ok := image.SplitRepositoryHost(repositoryVal)
+	return ok
+}
+
 // analyzeValues recursively analyzes a map of values to find image patterns.
 // It traverses the entire values structure, identifying and recording image patterns.
 //
@@ -258,15 +340,28 @@ func (a *Analyzer) normalizeImageValues(val map[string]interface{}) (registry, r
 func (a *Analyzer) analyzeValues(values map[string]interface{}, prefix string, analysis *ChartAnalysis) error {
 	log.Debug("analyzeValues ENTER", "prefix", prefix, "keys", reflect.ValueOf(values).MapKeys())
 	defer log.Debug("analyzeValues EXIT", "prefix", prefix)
+	if err := a.enter(prefix, analysis); err != nil {
+		return err
+	}
+	defer a.depth.Leave()
 
-	for k, v := range values {
-		currentPath := k
-		if prefix != "" {
-			currentPath = prefix + "." + k
+	siblingPatterns, covered := SiblingImagePatterns(values, prefix)
+	analysis.ImagePatterns = append(analysis.ImagePatterns, siblingPatterns...)
+
+	// Visit keys in sorted order so that patterns are reported in a stable order
+	for _, k := range slices.Sorted(maps.Keys(values)) {
+		if covered[k] {
+			// Covered by a pattern of an image split across sibling keys
+			continue
 		}
+		v := values[k]
+		currentPath := JoinPath(prefix, k)
 
 		log.Debug("analyzeValues LOOP", "path", currentPath, "type", fmt.Sprintf("%T", v))
 		if err := a.analyzeSingleValue(k, v, currentPath, analysis); err != nil {
+			if IsLimitError(err) {
+				return err
+			}
 			// If analyzing a single value fails, wrap the error with context
 			return fmt.Errorf("error analyzing path '%s': %w", currentPath, err)
 		}
@@ -317,41 +412,41 @@ func (a *Analyzer) analyzeSingleValue(key string, value interface{}, currentPath
 // analyzeMapValue recursively analyzes map values.
 func (a *Analyzer) analyzeMapValue(val map[string]interface{}, currentPath string, analysis *ChartAnalysis) error {
 	log.Debug("analyzeMapValue ENTER", "path", currentPath, "value", fmt.Sprintf("%#v", val))
+	if err := a.enter(currentPath, analysis); err != nil {
+		return err
+	}
+	defer a.depth.Leave()
 
 	// Check if the current map ITSELF represents an image structure.
-	if a.isImageMap(val) {
-		registry, repository, tag := a.normalizeImageValues(val)
-		imageValue := fmt.Sprintf("%s/%s:%s", registry, repository, tag)
+	isImageMap := a.isImageMap(val)
+	if isImageMap {
+		pattern := a.mapImagePattern(val, currentPath)
 
 		// Log structure details before appending
-		log.Debug("analyzeMapValue: IS image map", "path", currentPath, "value", imageValue, "structure", fmt.Sprintf("%#v", val))
+		log.Debug("analyzeMapValue: IS image map", "path", currentPath, "value", pattern.Value, "structure", fmt.Sprintf("%#v", val))
 
-		// Construct the normalized structure map
-		normalizedStructure := map[string]interface{}{
-			keys.Registry:   registry,
-			keys.Repository: repository,
-		}
-		if tag != "" { // Only include tag if it's not empty after normalization
-			normalizedStructure[keys.Tag] = tag
-		}
-		// Potentially add digest here if needed in the future
-
-		analysis.ImagePatterns = append(analysis.ImagePatterns, ImagePattern{
-			Path:      currentPath,
-			Type:      PatternTypeMap,
-			Value:     imageValue,          // Use normalized value string here
-			Structure: normalizedStructure, // Store NORMALIZED map structure
-			Count:     1,
-		})
+		analysis.ImagePatterns = append(analysis.ImagePatterns, pattern)
 		// **DO NOT RETURN EARLY HERE** - continue analyzing children
 	} else {
 		log.Debug("analyzeMapValue: is NOT image map", "path", currentPath)
 	}
 
+	siblingPatterns, covered := SiblingImagePatterns(val, currentPath)
+	analysis.ImagePatterns = append(analysis.ImagePatterns, siblingPatterns...)
+
 	// **ALWAYS iterate through map children**
 	log.Debug("analyzeMapValue: Iterating/recursing into map children", "path", currentPath)
-	for k, v := range val {
-		itemPath := currentPath + "." + k
+	for _, k := range slices.Sorted(maps.Keys(val)) {
+		if isImageMap && k == keys.Repository {
+			// Covered by the map pattern; a repository with a port or digest would otherwise look like an image string
+			continue
+		}
+		if covered[k] {
+			// Covered by a pattern of an image split across sibling keys
+			continue
+		}
+		v := val[k]
+		itemPath := JoinPath(currentPath, k)
 		log.Debug("analyzeMapValue: Processing child item", "parentPath", currentPath, "childKey", k, "childPath", itemPath)
 		if err := a.analyzeSingleValue(k, v, itemPath, analysis); err != nil {
 			return err // Propagate errors
@@ -431,6 +526,10 @@ func (a *Analyzer) analyzeStringValue(key, val, currentPath string, analysis *Ch
 //   - Error if analysis fails
 func (a *Analyzer) analyzeArray(val []interface{}, currentPath string, analysis *ChartAnalysis) error {
 	log.Debug("analyzeArray ENTER", "path", currentPath, "arrayLen", len(val))
+	if err := a.enter(currentPath, analysis); err != nil {
+		return err
+	}
+	defer a.depth.Leave()
 	// Check if this looks like a container array (common path names)
 	isContainerArray := strings.Contains(strings.ToLower(currentPath), "container") ||
 		currentPath == "initContainers" || currentPath == "containers" || strings.HasSuffix(currentPath, ".initContainers") ||
@@ -452,6 +551,9 @@ func (a *Analyzer) analyzeArray(val []interface{}, currentPath string, analysis
 			}
 
 			if err := a.analyzeMapItemInArray(v, itemPath, analysis); err != nil {
+				if IsLimitError(err) {
+					return err
+				}
 				return fmt.Errorf("error analyzing map item in array at path '%s': %w", itemPath, err)
 			}
 
@@ -474,6 +576,12 @@ func (a *Analyzer) analyzeArray(val []interface{}, currentPath string, analysis
 				analysis.ImagePatterns = append(analysis.ImagePatterns, pattern)
 				log.Debug("analyzeArray: Added string image pattern", "path", itemPath, "value", v)
 			}
+
+		case []interface{}:
+			// Nested sequence (e.g. a list of image lists); indices are appended to the path
+			if err := a.analyzeArray(v, itemPath, analysis); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -497,22 +605,22 @@ func (a *Analyzer) analyzeMapItemInArray(v map[string]interface{}, itemPath stri
 
 	// 1. Check if this map IS an image map itself
 	if a.isImageMap(v) {
-		registry, repository, tag := a.normalizeImageValues(v)
-		if repository != "" { // Check if it's a valid image map structure
-			pattern := ImagePattern{
-				Path:      itemPath, // Path is the array index
-				Type:      PatternTypeMap,
-				Structure: map[string]interface{}{keys.Registry: registry, keys.Repository: repository, keys.Tag: tag},
-				Value:     fmt.Sprintf("%s/%s:%s", registry, repository, tag),
-				Count:     1,
-			}
+		// Path is the array index; skip maps without a repository value
+		pattern := a.mapImagePattern(v, itemPath)
+		if pattern.Structure[keys.Repository] != "" {
 			analysis.ImagePatterns = append(analysis.ImagePatterns, pattern)
 			log.Debug("analyzeMapItemInArray: IMAGE APPEND (map)", "path", pattern.Path, "value", pattern.Value, "structure", fmt.Sprintf("%#v", pattern.Structure))
 			foundPatternInMapItem = true
 		}
 	}
 
-	// 2. If it's NOT an image map itself, check if it CONTAINS an 'image:' string key
+	// 2. If it's NOT an image map itself, check if it CONTAINS an 'image:' string key, possibly with
+	// its tag in a sibling imageTag or imageVersion key
+	if siblingPatterns, covered := SiblingImagePatterns(v, itemPath); !foundPatternInMapItem && covered["image"] {
+		analysis.ImagePatterns = append(analysis.ImagePatterns, siblingPatterns...)
+		log.Debug("analyzeMapItemInArray: IMAGE APPEND (image split across sibling keys)", "path", itemPath)
+		foundPatternInMapItem = true
+	}
 	if !foundPatternInMapItem {
 		// Detect if this map has an 'image' field, which is common in container-like structures
 		// including initContainers, containers, sidecars, etc.
@@ -705,13 +813,3 @@ func ensureString(v interface{}) (string, bool) {
 		return "", false
 	}
 }
-
-// splitRepoPath splits a repository path string by the first slash.
-// It is used to separate potential registry prefixes from the repository name.
-// Example: "docker.io/nginx" -> ["docker.io", "nginx"]
-// Example: "nginx" -> ["nginx"]
-func splitRepoPath(repo string) []string {
-	// Splitting a non-empty string will always yield at least one element.
-	// Splitting an empty string yields a slice with one empty string.
-	return strings.SplitN(repo, "/", maxSplitTwo)
-}
@@ -407,6 +407,20 @@ func TestNormalizeImageValues(t *testing.T) {
 			expectedRepo: "library/myregistry", // Changed expectation for consistency
 			expectedTag:  DefaultTag,           // Use package const
 		},
+		{
+			name:         "Registry In Repository",
+			input:        map[string]interface{}{"repository": "localhost:5000/team/app", "tag": "2.0"},
+			expectedReg:  "localhost:5000",
+			expectedRepo: "team/app",
+			expectedTag:  "2.0",
+		},
+		{
+			name:         "Localhost Registry In Repository",
+			input:        map[string]interface{}{"repository": "localhost/app", "tag": "2.0"},
+			expectedReg:  "localhost",
+			expectedRepo: "app",
+			expectedTag:  "2.0",
+		},
 		{
 			name:         "Registry Normalization (Trailing Slash)",
 			input:        map[string]interface{}{"registry": "mcr.microsoft.com/", "repository": "dotnet/sdk", "tag": "6.0"},
@@ -684,7 +698,8 @@ func TestAnalyzeValues(t *testing.T) {
 						"repository": "repo/implicit",
 						"tag":        "latest",
 					},
-					Count: 1,
+					Count:                1,
+					RegistryInRepository: true, // Overrides keep the registry in the repository
 				},
 			},
 		},
@@ -708,6 +723,48 @@ func TestAnalyzeValues(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Digest Map",
+			values: map[string]interface{}{
+				"digestMap": map[string]interface{}{"registry": "gcr.io", "repository": "distroless/static", "digest": "sha256:abc"},
+			},
+			prefix: "",
+			expectedImages: []ImagePattern{
+				{
+					Path:  "digestMap",
+					Type:  PatternTypeMap,
+					Value: "gcr.io/distroless/static@sha256:abc",
+					Structure: map[string]interface{}{
+						"registry":   "gcr.io",
+						"repository": "distroless/static",
+						"digest":     "sha256:abc",
+					},
+					Count: 1,
+				},
+			},
+		},
+		{
+			name: "Digest In Repository",
+			values: map[string]interface{}{
+				"digestRepo": map[string]interface{}{"repository": "gcr.io/distroless/static@sha256:abc"},
+			},
+			prefix: "",
+			expectedImages: []ImagePattern{
+				{
+					Path:  "digestRepo",
+					Type:  PatternTypeMap,
+					Value: "gcr.io/distroless/static@sha256:abc",
+					Structure: map[string]interface{}{
+						"registry":   "gcr.io",
+						"repository": "distroless/static",
+						"digest":     "sha256:abc",
+					},
+					Count:                1,
+					RegistryInRepository: true,
+					DigestInRepository:   true, // Overrides keep the digest in the repository
+				},
+			},
+		},
 		{
 			name: "Map Missing Repository",
 			values: map[string]interface{}{
@@ -837,7 +894,19 @@ func TestAnalyzeArray(t *testing.T) {
 				},
 			},
 		},
-		// TODO: Add nested array cases if needed
+		{
+			name: "Nested Arrays",
+			inputArray: []interface{}{
+				[]interface{}{"img/a:1", "img/b:2"},
+				[]interface{}{map[string]interface{}{"name": "c", "image": "img/c:3"}},
+			},
+			pathPrefix: "imageGroups",
+			expectedImages: []ImagePattern{
+				{Path: "imageGroups[0][0]", Type: PatternTypeString, Value: "img/a:1", Count: 1},
+				{Path: "imageGroups[0][1]", Type: PatternTypeString, Value: "img/b:2", Count: 1},
+				{Path: "imageGroups[1][0].image", Type: PatternTypeString, Value: "img/c:3", Count: 1},
+			},
+		},
 	}
 
 	for _, tt := range tests {
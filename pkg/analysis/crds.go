@@ -0,0 +1,125 @@
+package analysis
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// CRDImage is an image reference found in a CustomResourceDefinition shipped in a chart's crds/
+// directory, typically the default of an image field in the CRD schema. Helm installs these files
+// as they are, without templating, so the image cannot be changed through values overrides.
+type CRDImage struct {
+	// File is the CRD file, relative to the chart (e.g. "platform/charts/operator/crds/app.yaml")
+	File string `json:"file" yaml:"file"`
+	// CRD is the name of the CustomResourceDefinition, if the document has one
+	CRD string `json:"crd,omitempty" yaml:"crd,omitempty"`
+	// Path is the path of the value within the document
+	Path string `json:"path" yaml:"path"`
+	// Image is the image reference as written
+	Image      string `json:"image" yaml:"image"`
+	Registry   string `json:"registry" yaml:"registry"`
+	Repository string `json:"repository" yaml:"repository"`
+	Tag        string `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Digest     string `json:"digest,omitempty" yaml:"digest,omitempty"`
+}
+
+// ScanCRDImages finds the image references in the crds/ files of ch and its subcharts. A string
+// counts as an image when it parses as a reference with a tag, digest or repository path and
+// either sits below a key containing "image" or names a registry path with a tag or digest. Files
+// that cannot be parsed are skipped and reported in the returned error.
+func ScanCRDImages(ch *chart.Chart) ([]CRDImage, error) {
+	if ch == nil {
+		return nil, nil
+	}
+	var images []CRDImage
+	var errs []error
+	for _, crd := range ch.CRDObjects() {
+		if crd.File == nil {
+			continue
+		}
+		found, err := scanCRDFile(crd.Filename, crd.File.Data)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		images = append(images, found...)
+	}
+	return images, errors.Join(errs...)
+}
+
+// scanCRDFile returns the image references in the YAML documents of a CRD file.
+func scanCRDFile(filename string, data []byte) ([]CRDImage, error) {
+	var images []CRDImage
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc map[string]interface{}
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return images, nil
+		}
+		if err != nil {
+			return images, fmt.Errorf("failed to parse CRD file %s: %w", filename, err)
+		}
+		crdName := ""
+		if metadata, ok := doc["metadata"].(map[string]interface{}); ok {
+			crdName, _ = metadata["name"].(string)
+		}
+		collectCRDImages(doc, "", false, func(path, value string, ref *image.Reference) {
+			images = append(images, CRDImage{
+				File:       filename,
+				CRD:        crdName,
+				Path:       path,
+				Image:      value,
+				Registry:   ref.Registry,
+				Repository: ref.Repository,
+				Tag:        ref.Tag,
+				Digest:     ref.Digest,
+			})
+		})
+	}
+}
+
+// collectCRDImages walks value, found at path, and calls add for each image reference in it.
+// underImageKey is set below keys whose name contains "image".
+func collectCRDImages(value interface{}, path string, underImageKey bool, add func(path, value string, ref *image.Reference)) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			collectCRDImages(v[key], JoinPath(path, key), underImageKey || strings.Contains(strings.ToLower(key), "image"), add)
+		}
+	case []interface{}:
+		for i, item := range v {
+			collectCRDImages(item, path+"["+strconv.Itoa(i)+"]", underImageKey, add)
+		}
+	case string:
+		if ref := crdImageReference(v, underImageKey); ref != nil {
+			add(path, v, ref)
+		}
+	}
+}
+
+// crdImageReference returns the parsed reference if value looks like an image, or nil.
+func crdImageReference(value string, underImageKey bool) *image.Reference {
+	if value == "" || strings.ContainsAny(value, " \t\n{}") || strings.Contains(value, "://") {
+		return nil
+	}
+	hasPath := strings.Contains(value, "/")
+	hasVersion := strings.Contains(value, ":") || strings.Contains(value, "@sha256:")
+	if !(hasPath && hasVersion) && !(underImageKey && (hasPath || hasVersion)) {
+		return nil
+	}
+	ref, err := image.ParseImageReference(value)
+	if err != nil || ref.Repository == "" {
+		return nil
+	}
+	return ref
+}
@@ -0,0 +1,81 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+const testOperatorCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: apps.example.com
+spec:
+  versions:
+    - name: v1
+      schema:
+        openAPIV3Schema:
+          properties:
+            spec:
+              properties:
+                image:
+                  type: string
+                  description: Image of the application, e.g. nginx:1.27
+                  default: quay.io/example/app:v1.2.0
+                imagePullPolicy:
+                  type: string
+                  enum: [Always, IfNotPresent]
+                exporterImage:
+                  type: string
+                  default: busybox:1.36
+                homepage:
+                  type: string
+                  default: https://example.com/docs
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: sidecars.example.com
+spec:
+  versions:
+    - name: v1
+      schema:
+        openAPIV3Schema:
+          example:
+            proxy: ghcr.io/example/proxy@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef
+`
+
+func TestScanCRDImages(t *testing.T) {
+	operator := newTestChart("operator", nil)
+	operator.Files = []*chart.File{{Name: "crds/apps.yaml", Data: []byte(testOperatorCRD)}}
+	parent := newTestChart("platform", nil, operator)
+	parent.Files = []*chart.File{
+		{Name: "crds/broken.yaml", Data: []byte("spec: [\n")},
+		{Name: "files/image.yaml", Data: []byte("image: quay.io/example/ignored:v1\n")},
+	}
+
+	images, err := ScanCRDImages(parent)
+	assert.ErrorContains(t, err, "failed to parse CRD file platform/crds/broken.yaml")
+	require.Len(t, images, 3)
+
+	assert.Equal(t, CRDImage{
+		File:       "platform/charts/operator/crds/apps.yaml",
+		CRD:        "apps.example.com",
+		Path:       "spec.versions[0].schema.openAPIV3Schema.properties.spec.properties.exporterImage.default",
+		Image:      "busybox:1.36",
+		Registry:   "docker.io",
+		Repository: "library/busybox",
+		Tag:        "1.36",
+	}, images[0])
+	assert.Equal(t, "spec.versions[0].schema.openAPIV3Schema.properties.spec.properties.image.default", images[1].Path)
+	assert.Equal(t, "quay.io", images[1].Registry)
+	assert.Equal(t, "sidecars.example.com", images[2].CRD)
+	assert.Equal(t, "spec.versions[0].schema.openAPIV3Schema.example.proxy", images[2].Path)
+	assert.NotEmpty(t, images[2].Digest)
+
+	images, err = ScanCRDImages(newTestChart("plain", nil))
+	assert.NoError(t, err)
+	assert.Empty(t, images)
+}
@@ -0,0 +1,70 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// Byte order marks of the text encodings NormalizeText converts to plain UTF-8
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// NormalizeText returns data as UTF-8 without a byte order mark and with LF line endings, as
+// editors on Windows often write values files with a BOM, in UTF-16 or with CRLF line endings.
+// UTF-16 is recognized by its byte order mark. data is returned as is if it needs no changes.
+func NormalizeText(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		data = data[len(utf8BOM):]
+	case bytes.HasPrefix(data, utf16LEBOM):
+		data = decodeUTF16(data[len(utf16LEBOM):], binary.LittleEndian)
+	case bytes.HasPrefix(data, utf16BEBOM):
+		data = decodeUTF16(data[len(utf16BEBOM):], binary.BigEndian)
+	}
+	if bytes.IndexByte(data, '\r') < 0 {
+		return data
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+}
+
+// decodeUTF16 converts UTF-16 text in the given byte order to UTF-8. A trailing odd byte is dropped.
+func decodeUTF16(data []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+// NormalizeChartFiles applies NormalizeText to the values.yaml, values.schema.json and templates of
+// a loaded chart and its dependencies, so that source locations, anchors and templates are read
+// the same way whatever encoding and line endings the chart was written with. Helm has already
+// parsed the values; other files are left as they are, as templates read them with .Files.
+func NormalizeChartFiles(ch *helmchart.Chart) {
+	if ch == nil {
+		return
+	}
+	for _, file := range ch.Raw {
+		if file != nil && file.Name == valuesFileName {
+			file.Data = NormalizeText(file.Data)
+		}
+	}
+	for _, file := range ch.Templates {
+		if file != nil {
+			file.Data = NormalizeText(file.Data)
+		}
+	}
+	if ch.Schema != nil {
+		ch.Schema = NormalizeText(ch.Schema)
+	}
+	for _, dep := range ch.Dependencies() {
+		NormalizeChartFiles(dep)
+	}
+}
@@ -0,0 +1,73 @@
+package analysis
+
+import (
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// encodeUTF16 encodes text as UTF-16 with a byte order mark
+func encodeUTF16(text string, bigEndian bool) []byte {
+	units := utf16.Encode([]rune("\uFEFF" + text))
+	data := make([]byte, 0, 2*len(units))
+	for _, unit := range units {
+		if bigEndian {
+			data = append(data, byte(unit>>8), byte(unit))
+		} else {
+			data = append(data, byte(unit), byte(unit>>8))
+		}
+	}
+	return data
+}
+
+func TestNormalizeText(t *testing.T) {
+	const want = "image:\n  repository: nginx # café\n  tag: \"1.25\"\n"
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "plain UTF-8", data: []byte(want)},
+		{name: "UTF-8 BOM", data: append([]byte{0xEF, 0xBB, 0xBF}, want...)},
+		{name: "CRLF", data: []byte("image:\r\n  repository: nginx # café\r\n  tag: \"1.25\"\r\n")},
+		{name: "CR", data: []byte("image:\r  repository: nginx # café\r  tag: \"1.25\"\r")},
+		{name: "UTF-8 BOM and CRLF", data: append([]byte{0xEF, 0xBB, 0xBF}, "image:\r\n  repository: nginx # café\r\n  tag: \"1.25\"\r\n"...)},
+		{name: "UTF-16LE", data: encodeUTF16(want, false)},
+		{name: "UTF-16BE and CRLF", data: encodeUTF16("image:\r\n  repository: nginx # café\r\n  tag: \"1.25\"\r\n", true)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, want, string(NormalizeText(tt.data)))
+		})
+	}
+
+	data := []byte(want)
+	assert.Same(t, &data[0], &NormalizeText(data)[0], "text needing no changes is not copied")
+	assert.Empty(t, NormalizeText(nil))
+}
+
+func TestNormalizeChartFiles(t *testing.T) {
+	crlf := []byte("image: nginx:1.25\r\n")
+	redis := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "redis"},
+		Raw:      []*helmchart.File{{Name: "values.yaml", Data: encodeUTF16("image: redis:7.2\r\n", false)}},
+	}
+	web := &helmchart.Chart{
+		Metadata:  &helmchart.Metadata{Name: "web"},
+		Raw:       []*helmchart.File{{Name: "values.yaml", Data: crlf}, {Name: "files/banner.txt", Data: crlf}},
+		Templates: []*helmchart.File{{Name: "templates/pod.yaml", Data: []byte("image: {{ .Values.image }}\r\n")}},
+		Files:     []*helmchart.File{{Name: "files/banner.txt", Data: crlf}},
+		Schema:    []byte("{\"type\": \"object\"}\r\n"),
+	}
+	web.SetDependencies(redis)
+
+	NormalizeChartFiles(web)
+	assert.Equal(t, "image: nginx:1.25\n", string(web.Raw[0].Data))
+	assert.Equal(t, "image: {{ .Values.image }}\n", string(web.Templates[0].Data))
+	assert.Equal(t, "{\"type\": \"object\"}\n", string(web.Schema))
+	assert.Equal(t, "image: redis:7.2\n", string(redis.Raw[0].Data))
+	assert.Equal(t, crlf, web.Raw[1].Data, "other files are read by templates as they are")
+	assert.Equal(t, crlf, web.Files[0].Data)
+	NormalizeChartFiles(nil)
+}
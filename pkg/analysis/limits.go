@@ -0,0 +1,61 @@
+package analysis
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/lucas-albers-lz4/irr/pkg/limits"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// CheckChartValuesSize returns a *limits.Error if the values.yaml of the chart or of one of its
+// dependencies exceeds the values size limit. chartPath names the files in the error like
+// TrackChartLocations.
+func CheckChartValuesSize(ch *helmchart.Chart, chartPath string) error {
+	dir := chartPath
+	if isChartArchive(chartPath) && ch != nil && ch.Metadata != nil {
+		dir = ch.Metadata.Name
+	}
+	return checkChartValuesSize(ch, dir)
+}
+
+// checkChartValuesSize is CheckChartValuesSize for a chart whose files are below dir.
+func checkChartValuesSize(ch *helmchart.Chart, dir string) error {
+	if ch == nil {
+		return nil
+	}
+	for _, file := range ch.Raw {
+		if file == nil || file.Name != valuesFileName {
+			continue
+		}
+		if err := limits.CheckValuesSize(filepath.Join(dir, valuesFileName), int64(len(file.Data))); err != nil {
+			return err
+		}
+	}
+	for _, dep := range ch.Dependencies() {
+		if dep == nil || dep.Metadata == nil {
+			continue
+		}
+		if err := checkChartValuesSize(dep, filepath.Join(dir, "charts", dep.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enter descends into the map or list at path. It fails when the values are nested deeper than
+// the depth limit or more image patterns than the pattern limit were detected, so that
+// pathological values stop the analysis early. A nil error must be matched by a.depth.Leave().
+func (a *Analyzer) enter(path string, analysis *ChartAnalysis) error {
+	if err := limits.CheckImagePatterns(len(analysis.ImagePatterns), path); err != nil {
+		return err
+	}
+	return a.depth.Enter(path)
+}
+
+// IsLimitError reports whether err is caused by values exceeding one of the analysis limits.
+// Such errors are returned as they are rather than wrapped at every level of the traversal.
+func IsLimitError(err error) bool {
+	var limitErr *limits.Error
+	return errors.As(err, &limitErr)
+}
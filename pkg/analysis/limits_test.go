@@ -0,0 +1,49 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/limits"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestAnalyze_Limits(t *testing.T) {
+	t.Cleanup(limits.Set(limits.Limits{MaxValuesSize: 64, MaxDepth: 4, MaxImagePatterns: 3}))
+
+	redis := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "redis"},
+		Values:   map[string]interface{}{"image": "bitnami/redis:7.2"},
+		Raw:      []*chart.File{{Name: "values.yaml", Data: make([]byte, 65)}},
+	}
+	web := &chart.Chart{Metadata: &chart.Metadata{Name: "web"}, Values: map[string]interface{}{}}
+	web.SetDependencies(redis)
+	_, err := NewAnalyzer("/charts/web", &MockChartLoader{ChartToReturn: web}).Analyze()
+	var limitErr *limits.Error
+	require.True(t, errors.As(err, &limitErr), "got %v", err)
+	assert.Equal(t, limits.ValuesSize, limitErr.Kind)
+	assert.Equal(t, "/charts/web/charts/redis/values.yaml", limitErr.Source)
+
+	// Four levels of maps and lists are analyzed; a fifth exceeds the depth limit
+	nested := map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{map[string]interface{}{"image": "nginx:1.25"}}}}
+	result, err := NewAnalyzer("", nil).AnalyzeValues(nested)
+	require.NoError(t, err)
+	assert.Len(t, result.ImagePatterns, 1)
+	_, err = NewAnalyzer("", nil).AnalyzeValues(map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": []interface{}{[]interface{}{"x"}}}}})
+	require.True(t, errors.As(err, &limitErr), "got %v", err)
+	assert.Equal(t, limits.Depth, limitErr.Kind)
+	assert.Equal(t, "a.b.c[0]", limitErr.Source)
+	assert.NotContains(t, err.Error(), "error analyzing path", "limit errors are not wrapped at every level")
+
+	images := map[string]interface{}{}
+	for i := range 4 {
+		images[fmt.Sprintf("app%d", i)] = map[string]interface{}{"image": fmt.Sprintf("docker.io/app%d:1.0", i)}
+	}
+	_, err = NewAnalyzer("", nil).AnalyzeValues(images)
+	require.True(t, errors.As(err, &limitErr), "got %v", err)
+	assert.Equal(t, limits.ImagePatterns, limitErr.Kind)
+	assert.Equal(t, int64(4), limitErr.Value)
+}
@@ -0,0 +1,276 @@
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/keys"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"gopkg.in/yaml.v3"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// valuesFileName is the name of a chart's default values file
+const valuesFileName = "values.yaml"
+
+// SourceLocation is the position of a value in a values file.
+type SourceLocation struct {
+	// File is the values file: a path on disk, or the path inside a chart archive
+	File string `json:"file" yaml:"file"`
+	// Line is the 1-based line of the value
+	Line int `json:"line" yaml:"line"`
+	// Column is the 1-based column of the value
+	Column int `json:"column" yaml:"column"`
+}
+
+// String formats the location as file:line:column, which editors and terminals open directly.
+func (l SourceLocation) String() string {
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+}
+
+// SourceLocations maps values paths to the position of their value in the values files.
+type SourceLocations map[string]SourceLocation
+
+// TrackNodeLocations records, under the given prefix, the position of every values path of a
+// parsed values file, replacing positions recorded for earlier files, as later values files
+// override earlier ones. Scalars are located at their value and maps and sequences at their key.
+// Aliases and merge keys (<<) are followed to the anchored values they copy.
+func (l SourceLocations) TrackNodeLocations(root *yaml.Node, file, prefix string) {
+	tracker := locationTracker{file: file, locations: l}
+	tracker.walk(root, prefix, nil)
+}
+
+// TrackLocations is TrackNodeLocations for raw values YAML, which is parsed chunk by chunk.
+func (l SourceLocations) TrackLocations(data []byte, file, prefix string) error {
+	return l.trackLocations(data, file, prefix, nil)
+}
+
+// TrackLocationsOf is TrackLocations for the given values paths only. The chunks of the values
+// that cannot hold them are not parsed.
+func (l SourceLocations) TrackLocationsOf(data []byte, file, prefix string, paths []string) error {
+	return l.trackLocations(data, file, prefix, newLocationFilter(paths))
+}
+
+// trackLocations is TrackLocations for the paths that filter selects.
+func (l SourceLocations) trackLocations(data []byte, file, prefix string, filter locationFilter) error {
+	tracker := locationTracker{file: file, locations: l, filter: filter}
+	var include func(string) bool
+	if filter != nil {
+		include = filter.mayHold
+	}
+	err := WalkValuesChunks(data, prefix, include, func(chunk ValuesChunk) {
+		if chunk.Whole {
+			tracker.walk(chunk.Node, chunk.Path, nil)
+			return
+		}
+		tracker.walkMapping(chunk.Node, chunk.Path, make(map[string]bool))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to parse %s for source locations: %w", file, err)
+	}
+	return nil
+}
+
+// TrackChartLocations records the positions of the default values of a chart and its
+// dependencies, whose values are below their chart name. chartPath is the chart directory or
+// archive; files inside an archive are named by their path in it (e.g. web/values.yaml).
+func (l SourceLocations) TrackChartLocations(ch *helmchart.Chart, chartPath string) {
+	l.trackChartLocations(ch, chartValuesDir(ch, chartPath), "", nil)
+}
+
+// TrackChartLocationsOf is TrackChartLocations for the given values paths only.
+func (l SourceLocations) TrackChartLocationsOf(ch *helmchart.Chart, chartPath string, paths []string) {
+	l.trackChartLocations(ch, chartValuesDir(ch, chartPath), "", newLocationFilter(paths))
+}
+
+// chartValuesDir returns the directory that names the values files of a chart in locations.
+func chartValuesDir(ch *helmchart.Chart, chartPath string) string {
+	if isChartArchive(chartPath) && ch != nil && ch.Metadata != nil {
+		return ch.Metadata.Name
+	}
+	return chartPath
+}
+
+// trackChartLocations is TrackChartLocations for a chart whose files are below dir.
+func (l SourceLocations) trackChartLocations(ch *helmchart.Chart, dir, prefix string, filter locationFilter) {
+	if ch == nil {
+		return
+	}
+	for _, file := range ch.Raw {
+		if file == nil || file.Name != valuesFileName {
+			continue
+		}
+		if err := l.trackLocations(file.Data, filepath.Join(dir, valuesFileName), prefix, filter); err != nil {
+			log.Debug("Skipping source locations of chart values", "chart", ch.Name(), "error", err)
+		}
+	}
+	for _, dep := range ch.Dependencies() {
+		if dep == nil || dep.Metadata == nil {
+			continue
+		}
+		depPrefix := dep.Name()
+		if prefix != "" {
+			depPrefix = prefix + "." + depPrefix
+		}
+		l.trackChartLocations(dep, filepath.Join(dir, "charts", dep.Name()), depPrefix, filter)
+	}
+}
+
+// LocationPaths returns the values paths that Apply looks up to locate the patterns.
+func LocationPaths(patterns []ImagePattern) []string {
+	var paths []string
+	for i := range patterns {
+		if patterns[i].Location == nil {
+			paths = append(paths, locationCandidates(&patterns[i])...)
+		}
+	}
+	return paths
+}
+
+// Apply sets the location of the patterns that have none yet. A map image is located at its
+// repository, which names the image, and the other patterns at their values path.
+func (l SourceLocations) Apply(patterns []ImagePattern) {
+	if len(l) == 0 {
+		return
+	}
+	for i := range patterns {
+		pattern := &patterns[i]
+		if pattern.Location != nil {
+			continue
+		}
+		for _, candidate := range locationCandidates(pattern) {
+			if location, ok := l[candidate]; ok {
+				pattern.Location = &location
+				break
+			}
+		}
+	}
+}
+
+// locationCandidates returns the values paths that locate a pattern, in order of preference: a map
+// image is located at its repository, which names the image, and the other patterns at their path.
+func locationCandidates(pattern *ImagePattern) []string {
+	switch {
+	case pattern.ImageKeys[keys.Repository] != "":
+		return []string{JoinPath(pattern.Path, pattern.ImageKeys[keys.Repository]), pattern.Path}
+	case pattern.Type == PatternTypeMap:
+		return []string{JoinPath(pattern.Path, keys.Repository), pattern.Path}
+	}
+	return []string{pattern.Path}
+}
+
+// locationFilter selects the values paths whose positions are recorded; nil selects all paths.
+type locationFilter map[string]bool
+
+// newLocationFilter returns the filter selecting paths.
+func newLocationFilter(paths []string) locationFilter {
+	filter := make(locationFilter, len(paths))
+	for _, path := range paths {
+		filter[path] = true
+	}
+	return filter
+}
+
+// selects reports whether the position of path is recorded.
+func (f locationFilter) selects(path string) bool {
+	return f == nil || f[path]
+}
+
+// mayHold reports whether path or a path below it is selected.
+func (f locationFilter) mayHold(path string) bool {
+	if f == nil || f[path] {
+		return true
+	}
+	for selected := range f {
+		if strings.HasPrefix(selected, path+".") || strings.HasPrefix(selected, path+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// locationTracker records the positions of the values of one values file.
+type locationTracker struct {
+	file      string
+	locations SourceLocations
+	filter    locationFilter
+}
+
+// walk records the position of the value node at valuePath; key is its mapping key node, if any.
+func (t *locationTracker) walk(node *yaml.Node, valuePath string, key *yaml.Node) {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node == nil {
+		return
+	}
+	if node.Kind == yaml.DocumentNode {
+		for _, child := range node.Content {
+			t.walk(child, valuePath, key)
+		}
+		return
+	}
+
+	if valuePath != "" && t.filter.selects(valuePath) {
+		at := node
+		if key != nil && node.Kind != yaml.ScalarNode {
+			at = key
+		}
+		t.locations[valuePath] = SourceLocation{File: t.file, Line: at.Line, Column: at.Column}
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		t.walkMapping(node, valuePath, make(map[string]bool))
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			t.walk(item, fmt.Sprintf("%s[%d]", valuePath, i), nil)
+		}
+	case yaml.DocumentNode, yaml.ScalarNode, yaml.AliasNode:
+		// Nothing below
+	}
+}
+
+// walkMapping records the keys of a mapping that are not in seen. Explicit keys take precedence
+// over merged keys, and earlier merge sources over later ones, matching YAML merge semantics.
+func (t *locationTracker) walkMapping(node *yaml.Node, valuePath string, seen map[string]bool) {
+	var merges []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Value == "<<" && key.Tag != "!!str" {
+			merges = append(merges, value)
+			continue
+		}
+		if seen[key.Value] {
+			continue
+		}
+		seen[key.Value] = true
+		t.walk(value, JoinPath(valuePath, key.Value), key)
+	}
+	for _, merge := range merges {
+		for merge != nil && merge.Kind == yaml.AliasNode {
+			merge = merge.Alias
+		}
+		if merge == nil {
+			continue
+		}
+		sources := []*yaml.Node{merge}
+		if merge.Kind == yaml.SequenceNode {
+			sources = merge.Content
+		}
+		for _, source := range sources {
+			for source != nil && source.Kind == yaml.AliasNode {
+				source = source.Alias
+			}
+			if source != nil && source.Kind == yaml.MappingNode {
+				t.walkMapping(source, valuePath, seen)
+			}
+		}
+	}
+}
+
+// isChartArchive reports whether chartPath names a packaged chart rather than a chart directory.
+func isChartArchive(chartPath string) bool {
+	lower := strings.ToLower(chartPath)
+	return strings.HasSuffix(lower, ".tgz") || strings.HasSuffix(lower, ".tar.gz")
+}
@@ -0,0 +1,111 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+const locationsValues = `defaults: &defaults
+  image:
+    repository: nginx
+    tag: "1.25"
+frontend:
+  <<: *defaults
+  replicas: 2
+sidecars:
+  - name: proxy
+    image: envoyproxy/envoy:v1.30.1
+operatorImage: quay.io/example/operator:v1
+`
+
+func TestSourceLocations(t *testing.T) {
+	locations := make(SourceLocations)
+	require.NoError(t, locations.TrackLocations([]byte(locationsValues), "values.yaml", ""))
+
+	assert.Equal(t, SourceLocation{File: "values.yaml", Line: 2, Column: 3}, locations["defaults.image"], "maps are located at their key")
+	assert.Equal(t, SourceLocation{File: "values.yaml", Line: 3, Column: 17}, locations["defaults.image.repository"], "scalars are located at their value")
+	assert.Equal(t, locations["defaults.image.repository"], locations["frontend.image.repository"], "merged values are located at the anchored value")
+	assert.Equal(t, SourceLocation{File: "values.yaml", Line: 7, Column: 13}, locations["frontend.replicas"])
+	assert.Equal(t, SourceLocation{File: "values.yaml", Line: 10, Column: 12}, locations["sidecars[0].image"])
+	assert.Equal(t, "values.yaml:11:16", locations["operatorImage"].String())
+
+	require.NoError(t, locations.TrackLocations([]byte("frontend:\n  image:\n    tag: \"1.26\"\n"), "/env/prod.yaml", ""))
+	assert.Equal(t, SourceLocation{File: "/env/prod.yaml", Line: 3, Column: 10}, locations["frontend.image.tag"], "later files replace earlier locations")
+	assert.Equal(t, "values.yaml", locations["frontend.image.repository"].File)
+
+	assert.Error(t, locations.TrackLocations([]byte("image: ["), "broken.yaml", ""))
+}
+
+func TestSourceLocations_Apply(t *testing.T) {
+	locations := make(SourceLocations)
+	require.NoError(t, locations.TrackLocations([]byte(locationsValues), "values.yaml", ""))
+	require.NoError(t, locations.TrackLocations([]byte("exporterImage: prom/exporter\nexporterImageTag: v1\n"), "exporter.yaml", "metrics"))
+	preset := &SourceLocation{File: "other.yaml", Line: 1, Column: 1}
+
+	patterns := []ImagePattern{
+		{Path: "frontend.image", Type: PatternTypeMap},
+		{Path: "operatorImage", Type: PatternTypeString},
+		{Path: "metrics", Type: PatternTypeMap, ImageKeys: map[string]string{"repository": "exporterImage", "tag": "exporterImageTag"}},
+		{Path: "missing.image", Type: PatternTypeString},
+		{Path: "sidecars[0].image", Type: PatternTypeString, Location: preset},
+	}
+	locations.Apply(patterns)
+
+	require.NotNil(t, patterns[0].Location)
+	assert.Equal(t, "values.yaml:3:17", patterns[0].Location.String(), "map images are located at their repository")
+	require.NotNil(t, patterns[1].Location)
+	assert.Equal(t, "values.yaml:11:16", patterns[1].Location.String())
+	require.NotNil(t, patterns[2].Location)
+	assert.Equal(t, "exporter.yaml:1:16", patterns[2].Location.String(), "split images are located at their repository key")
+	assert.Nil(t, patterns[3].Location)
+	assert.Same(t, preset, patterns[4].Location, "known locations are kept")
+}
+
+func TestSourceLocations_TrackLocationsOf(t *testing.T) {
+	patterns := []ImagePattern{
+		{Path: "frontend.image", Type: PatternTypeMap},
+		{Path: "sidecars[0].image", Type: PatternTypeString},
+	}
+	paths := LocationPaths(patterns)
+	assert.Equal(t, []string{"frontend.image.repository", "frontend.image", "sidecars[0].image"}, paths)
+
+	locations := make(SourceLocations)
+	require.NoError(t, locations.TrackLocationsOf([]byte(locationsValues), "values.yaml", "", paths))
+	assert.Len(t, locations, 3, "only the given paths are located")
+	assert.Equal(t, "values.yaml:3:17", locations["frontend.image.repository"].String(), "merged values are located at the anchored value")
+	assert.Equal(t, SourceLocation{File: "values.yaml", Line: 10, Column: 12}, locations["sidecars[0].image"])
+}
+
+func TestAnalyze_SourceLocations(t *testing.T) {
+	redis := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "redis"},
+		Values:   map[string]interface{}{"image": "bitnami/redis:7.2"},
+		Raw:      []*chart.File{{Name: "values.yaml", Data: []byte("# cache\nimage: bitnami/redis:7.2\n")}},
+	}
+	web := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "web"},
+		Values:   map[string]interface{}{"image": map[string]interface{}{"repository": "nginx", "tag": "1.25"}},
+		Raw:      []*chart.File{{Name: "values.yaml", Data: []byte("image:\n  repository: nginx\n  tag: \"1.25\"\n")}},
+	}
+	web.SetDependencies(redis)
+
+	result, err := NewAnalyzer("/charts/web", &MockChartLoader{ChartToReturn: web}).Analyze()
+	require.NoError(t, err)
+	locations := make(map[string]string)
+	for _, pattern := range result.ImagePatterns {
+		require.NotNil(t, pattern.Location, pattern.Path)
+		locations[pattern.Path] = pattern.Location.String()
+	}
+	assert.Equal(t, map[string]string{
+		"image":       "/charts/web/values.yaml:2:15",
+		"redis.image": "/charts/web/charts/redis/values.yaml:2:8",
+	}, locations)
+
+	result, err = NewAnalyzer("web-1.0.0.tgz", &MockChartLoader{ChartToReturn: web}).Analyze()
+	require.NoError(t, err)
+	require.NotNil(t, result.ImagePatterns[0].Location)
+	assert.Equal(t, "web/values.yaml", result.ImagePatterns[0].Location.File, "files in an archive are named by their path in it")
+}
@@ -0,0 +1,84 @@
+package analysis
+
+import "strings"
+
+// pathSpecialChars are the characters of a values key that are escaped with a backslash in a
+// values path: the key separator, the brackets of sequence indexes and the backslash itself.
+const pathSpecialChars = `\.[]`
+
+// EscapePathKey escapes the dots, brackets and backslashes in a values key, so that a key such as
+// "my.dotted.key" stays one key of a values path: my\.dotted\.key.
+func EscapePathKey(key string) string {
+	if !strings.ContainsAny(key, pathSpecialChars) {
+		return key
+	}
+	var b strings.Builder
+	for _, r := range key {
+		if strings.ContainsRune(pathSpecialChars, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// UnescapePathKey reverses EscapePathKey.
+func UnescapePathKey(key string) string {
+	if !strings.Contains(key, `\`) {
+		return key
+	}
+	var b strings.Builder
+	escaped := false
+	for _, r := range key {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// JoinPath appends key to the dot-notation values path prefix, escaping key.
+func JoinPath(prefix, key string) string {
+	if prefix == "" {
+		return EscapePathKey(key)
+	}
+	return prefix + "." + EscapePathKey(key)
+}
+
+// SplitPath splits a values path at its unescaped dots. The elements keep their escapes and
+// sequence indexes (e.g. `my\.key` or "containers[0]"), so that joining them with dots gives back
+// the path.
+func SplitPath(path string) []string {
+	if !strings.Contains(path, `\`) {
+		return strings.Split(path, ".")
+	}
+	var elems []string
+	start := 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			i++ // skip the escaped character
+		case '.':
+			elems = append(elems, path[start:i])
+			start = i + 1
+		}
+	}
+	return append(elems, path[start:])
+}
+
+// CutPathIndex splits a path element into its unescaped key and the sequence indexes following it,
+// e.g. "containers[0][1]" into "containers" and "[0][1]".
+func CutPathIndex(elem string) (key, indexes string) {
+	for i := 0; i < len(elem); i++ {
+		switch elem[i] {
+		case '\\':
+			i++
+		case '[':
+			return UnescapePathKey(elem[:i]), elem[i:]
+		}
+	}
+	return UnescapePathKey(elem), ""
+}
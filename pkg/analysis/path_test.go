@@ -0,0 +1,31 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathKeys(t *testing.T) {
+	for _, key := range []string{"image", "my.dotted.key", "vendor.io/image", "tools[beta]", `back\slash`} {
+		escaped := EscapePathKey(key)
+		assert.Len(t, SplitPath(escaped), 1, "escaped key %s is one path element", escaped)
+		assert.Equal(t, key, UnescapePathKey(escaped))
+	}
+	assert.Equal(t, `my\.dotted\.key`, EscapePathKey("my.dotted.key"))
+	assert.Equal(t, `tools\[beta\]`, EscapePathKey("tools[beta]"))
+}
+
+func TestJoinAndSplitPath(t *testing.T) {
+	path := JoinPath(JoinPath(JoinPath("", "podAnnotations"), "vendor.io/image"), "containers") + "[0]"
+	assert.Equal(t, `podAnnotations.vendor\.io/image.containers[0]`, path)
+	assert.Equal(t, []string{"podAnnotations", `vendor\.io/image`, "containers[0]"}, SplitPath(path))
+	assert.Equal(t, []string{"a", "b", ""}, SplitPath("a.b."))
+
+	key, indexes := CutPathIndex("containers[0][1]")
+	assert.Equal(t, "containers", key)
+	assert.Equal(t, "[0][1]", indexes)
+	key, indexes = CutPathIndex(`tools\[beta\][2]`)
+	assert.Equal(t, "tools[beta]", key)
+	assert.Equal(t, "[2]", indexes)
+}
@@ -0,0 +1,211 @@
+package analysis
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// schemaURL is the location values.schema.json files are compiled at. Relative $refs resolve
+// against it; remote $refs are not fetched.
+const schemaURL = "file:///values.schema.json"
+
+// schemaMessages prints validation messages the way Helm does.
+var schemaMessages = message.NewPrinter(language.English)
+
+// SchemaViolation is a value that does not satisfy the values.schema.json of a chart.
+type SchemaViolation struct {
+	// Chart is the name of the chart whose schema is violated
+	Chart string `json:"chart" yaml:"chart"`
+	// Path is the values path of the value, from the top of the parent chart's values (e.g.
+	// "cache.image" for the image value of subchart "cache"); empty for the values root
+	Path string `json:"path" yaml:"path"`
+	// Message describes the violation (e.g. "additional properties 'registry' not allowed")
+	Message string `json:"message" yaml:"message"`
+}
+
+// String returns the violation as "chart: path: message".
+func (v SchemaViolation) String() string {
+	path := v.Path
+	if path == "" {
+		path = "(root)"
+	}
+	return v.Chart + ": " + path + ": " + v.Message
+}
+
+// ValidateValuesSchema validates values, the coalesced values of ch, against the values.schema.json
+// of ch and of each enabled subchart, each against the values under its key, as Helm does before
+// rendering. Schemas that cannot be compiled are skipped and reported in the returned error.
+func ValidateValuesSchema(ch *chart.Chart, values map[string]interface{}) ([]SchemaViolation, error) {
+	if ch == nil {
+		return nil, nil
+	}
+	var violations []SchemaViolation
+	var errs []error
+	tags, _ := values["tags"].(map[string]interface{})
+	validate := func(sub *chart.Chart, valuesPath string) {
+		if len(sub.Schema) == 0 {
+			return
+		}
+		var chartValues interface{} = values
+		if valuesPath != "" {
+			var found bool
+			chartValues, found = image.GetValueAtPath(values, strings.Split(valuesPath, "."))
+			if !found || chartValues == nil {
+				return
+			}
+		}
+		err := validateSchema(sub.Schema, chartValues, func(location []string, message string) {
+			violation := SchemaViolation{
+				Chart:   sub.Name(),
+				Path:    schemaValuesPath(valuesPath, chartValues, location),
+				Message: message,
+			}
+			if !slices.Contains(violations, violation) {
+				violations = append(violations, violation)
+			}
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to compile values.schema.json of chart %s: %w", sub.Name(), err))
+		}
+	}
+	validate(ch, "")
+	walkEnabledSubcharts(ch, values, tags, "", validate)
+	return violations, errors.Join(errs...)
+}
+
+// IntroducedSchemaViolations returns the violations of merged, the coalesced values with overrides
+// applied, that base, the same values without the overrides, does not have. Violations the chart's
+// own values already had are not the overrides' fault and are left out.
+func IntroducedSchemaViolations(ch *chart.Chart, base, merged map[string]interface{}) ([]SchemaViolation, error) {
+	before, err := ValidateValuesSchema(ch, base)
+	if err != nil {
+		return nil, err
+	}
+	after, err := ValidateValuesSchema(ch, merged)
+	if err != nil {
+		return nil, err
+	}
+	var introduced []SchemaViolation
+	for _, violation := range after {
+		if !slices.Contains(before, violation) {
+			introduced = append(introduced, violation)
+		}
+	}
+	return introduced, nil
+}
+
+// walkEnabledSubcharts calls visit for each subchart of ch, whose values are at valuesPath, that
+// values do not disable, parents before their dependencies.
+func walkEnabledSubcharts(ch *chart.Chart, values, tags map[string]interface{}, valuesPath string, visit func(sub *chart.Chart, valuesPath string)) {
+	if ch == nil {
+		return
+	}
+	loaded := ch.Dependencies()
+	declared := make(map[string]bool)
+	if ch.Metadata != nil {
+		for _, dep := range ch.Metadata.Dependencies {
+			if dep == nil || dep.Name == "" {
+				continue
+			}
+			declared[dep.Name] = true
+			if dep.Alias != "" {
+				declared[dep.Alias] = true
+			}
+			sub := loadedSubchart(loaded, dep)
+			if sub == nil || dependencyDisabledBy(dep, values, tags, valuesPath) != "" {
+				continue
+			}
+			key := dep.Name
+			if dep.Alias != "" {
+				key = dep.Alias
+			}
+			subPath := joinSubchartPath(valuesPath, key)
+			visit(sub, subPath)
+			walkEnabledSubcharts(sub, values, tags, subPath, visit)
+		}
+	}
+	for _, sub := range loaded {
+		if !declared[sub.Name()] {
+			subPath := joinSubchartPath(valuesPath, sub.Name())
+			visit(sub, subPath)
+			walkEnabledSubcharts(sub, values, tags, subPath, visit)
+		}
+	}
+}
+
+// validateSchema validates values against schemaJSON and calls add for each violation, with the
+// location of the value within values.
+func validateSchema(schemaJSON []byte, values interface{}, add func(location []string, message string)) error {
+	schema, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaJSON))
+	if err != nil {
+		return err
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(schemaURL, schema); err != nil {
+		return err
+	}
+	validator, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return err
+	}
+
+	err = validator.Validate(values)
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+	collectSchemaViolations(validationErr, add)
+	return nil
+}
+
+// collectSchemaViolations calls add for the violations reported by err and its causes. Only the
+// innermost causes are reported, except below anyOf and oneOf, whose alternatives would all be
+// listed.
+func collectSchemaViolations(err *jsonschema.ValidationError, add func(location []string, message string)) {
+	switch err.ErrorKind.(type) {
+	case *kind.AnyOf, *kind.OneOf:
+	default:
+		if len(err.Causes) > 0 {
+			for _, cause := range err.Causes {
+				collectSchemaViolations(cause, add)
+			}
+			return
+		}
+	}
+	add(err.InstanceLocation, err.ErrorKind.LocalizedString(schemaMessages))
+}
+
+// schemaValuesPath turns location, the location of a value within values, into a values path
+// below prefix. List indexes are written as "[i]".
+func schemaValuesPath(prefix string, values interface{}, location []string) string {
+	path := prefix
+	current := values
+	for _, token := range location {
+		switch v := current.(type) {
+		case []interface{}:
+			path += "[" + token + "]"
+			current = nil
+			if index, err := strconv.Atoi(token); err == nil && index >= 0 && index < len(v) {
+				current = v[index]
+			}
+			continue
+		case map[string]interface{}:
+			current = v[token]
+		default:
+			current = nil
+		}
+		path = joinSubchartPath(path, token)
+	}
+	return path
+}
@@ -0,0 +1,89 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+const strictImageSchema = `{
+  "type": "object",
+  "properties": {
+    "image": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "repository": {"type": "string"},
+        "tag": {"type": "string"}
+      }
+    },
+    "sidecars": {
+      "type": "array",
+      "items": {"type": "object", "properties": {"image": {"type": "string", "pattern": "^docker\\.io/"}}}
+    },
+    "replicas": {"type": "integer", "minimum": 1}
+  }
+}`
+
+func TestValidateValuesSchema(t *testing.T) {
+	redis := newTestChart("redis", nil)
+	redis.Schema = []byte(strictImageSchema)
+	disabled := newTestChart("postgresql", nil)
+	disabled.Schema = []byte(`{"type": "object", "additionalProperties": false}`)
+	parent := newTestChart("platform", []*chart.Dependency{
+		{Name: "redis", Alias: "cache"},
+		{Name: "postgresql", Condition: "postgresql.enabled"},
+	}, redis, disabled)
+	parent.Schema = []byte(strictImageSchema)
+
+	violations, err := ValidateValuesSchema(parent, map[string]interface{}{
+		"image":    map[string]interface{}{"registry": "harbor.local", "repository": "app"},
+		"replicas": 0,
+		"sidecars": []interface{}{map[string]interface{}{"image": "quay.io/proxy:1.0"}},
+		"cache": map[string]interface{}{
+			"image": map[string]interface{}{"registry": "harbor.local", "repository": "redis"},
+		},
+		"postgresql": map[string]interface{}{"enabled": false, "image": "postgres"},
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []SchemaViolation{
+		{Chart: "platform", Path: "image", Message: "additional properties 'registry' not allowed"},
+		{Chart: "platform", Path: "replicas", Message: "minimum: got 0, want 1"},
+		{Chart: "platform", Path: "sidecars[0].image", Message: `'quay.io/proxy:1.0' does not match pattern '^docker\\.io/'`},
+		{Chart: "redis", Path: "cache.image", Message: "additional properties 'registry' not allowed"},
+	}, violations)
+	assert.Equal(t, "redis: cache.image: additional properties 'registry' not allowed", violations[len(violations)-1].String())
+
+	_, err = ValidateValuesSchema(newTestChartWithSchema("broken", `{"type": 5}`), map[string]interface{}{})
+	assert.ErrorContains(t, err, "values.schema.json of chart broken")
+}
+
+func TestIntroducedSchemaViolations(t *testing.T) {
+	ch := newTestChartWithSchema("web", strictImageSchema)
+	base := map[string]interface{}{
+		"image":    map[string]interface{}{"repository": "nginx", "tag": "1.27"},
+		"replicas": 0,
+	}
+	merged := map[string]interface{}{
+		"image":    map[string]interface{}{"registry": "harbor.local", "repository": "dockerio/nginx", "tag": "1.27"},
+		"replicas": 0,
+	}
+
+	introduced, err := IntroducedSchemaViolations(ch, base, merged)
+	require.NoError(t, err)
+	assert.Equal(t, []SchemaViolation{
+		{Chart: "web", Path: "image", Message: "additional properties 'registry' not allowed"},
+	}, introduced, "the replicas violation is already in the chart's values")
+
+	introduced, err = IntroducedSchemaViolations(ch, base, base)
+	require.NoError(t, err)
+	assert.Empty(t, introduced)
+}
+
+func newTestChartWithSchema(name, schema string) *chart.Chart {
+	ch := newTestChart(name, nil)
+	ch.Schema = []byte(schema)
+	return ch
+}
@@ -0,0 +1,91 @@
+package analysis
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	"github.com/lucas-albers-lz4/irr/pkg/keys"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+)
+
+// siblingTagSuffixes are the suffixes that name the key holding the tag of an image key, in order
+// of preference: image with imageTag or imageVersion, fooImage with fooImageTag or fooImageVersion
+var siblingTagSuffixes = []string{"Tag", "Version"}
+
+// SiblingImagePatterns returns the images that the map values at path splits across an image key
+// and a sibling tag key, such as fooImage: nginx with fooImageTag: "1.25", or image: bitnami/nginx
+// with imageTag or imageVersion. Each pattern is a map pattern at path whose ImageKeys name both
+// keys, so overrides set the repository and the tag together.
+//
+// covered holds the keys of values that the patterns describe; they must not be analyzed as images
+// of their own. Image keys whose value already has a tag or digest, or whose tag key is empty, are
+// left to the other heuristics.
+func SiblingImagePatterns(values map[string]interface{}, path string) (patterns []ImagePattern, covered map[string]bool) {
+	for _, imageKey := range slices.Sorted(maps.Keys(values)) {
+		if imageKey != "image" && !strings.HasSuffix(imageKey, "Image") {
+			continue
+		}
+		raw, _ := values[imageKey].(string)
+		repository := strings.TrimSpace(raw)
+		if repository == "" || strings.Contains(repository, "{{") {
+			continue
+		}
+		if name := repository[strings.LastIndex(repository, "/")+1:]; strings.ContainsAny(name, ":@") {
+			continue
+		}
+		tagKey, tag := siblingTag(values, imageKey)
+		if tag == "" {
+			continue
+		}
+
+		separator := ":"
+		if strings.HasPrefix(tag, "sha256:") {
+			separator = "@"
+		}
+		value := repository + separator + tag
+		ref, err := image.ParseImageReference(value)
+		if err != nil {
+			log.Debug("Sibling image keys do not form an image reference", "path", path, "imageKey", imageKey, "tagKey", tagKey, "value", value, "error", err)
+			continue
+		}
+		log.Debug("Found image split across sibling keys", "path", path, "imageKey", imageKey, "tagKey", tagKey, "value", value)
+		patterns = append(patterns, ImagePattern{
+			Path:  path,
+			Type:  PatternTypeMap,
+			Value: value,
+			Structure: map[string]interface{}{
+				keys.Registry:   ref.Registry,
+				keys.Repository: ref.Repository,
+				keys.Tag:        ref.Tag,
+			},
+			Count:     1,
+			ImageKeys: map[string]string{keys.Repository: imageKey, keys.Tag: tagKey},
+		})
+		if covered == nil {
+			covered = make(map[string]bool)
+		}
+		covered[imageKey] = true
+		covered[tagKey] = true
+	}
+	return patterns, covered
+}
+
+// siblingTag returns the first tag key of imageKey present in values and its value, which is empty
+// when there is no such key or it holds no tag.
+func siblingTag(values map[string]interface{}, imageKey string) (tagKey, tag string) {
+	for _, suffix := range siblingTagSuffixes {
+		tagKey = imageKey + suffix
+		raw, ok := values[tagKey]
+		if !ok {
+			continue
+		}
+		switch raw.(type) {
+		case string, int, float64:
+			tag, _ = ensureString(raw)
+		}
+		return tagKey, strings.TrimSpace(tag)
+	}
+	return "", ""
+}
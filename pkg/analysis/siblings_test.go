@@ -0,0 +1,95 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// siblingTestDigest is the digest pinned by the digestImage key of TestSiblingImagePatterns
+const siblingTestDigest = "4b7ce07002c69e8f3d704a9c5d6fd3053be500b7f1c69fc0d80990c2ad8dd412"
+
+func TestSiblingImagePatterns(t *testing.T) {
+	values := map[string]interface{}{
+		"exporterImage":        "quay.io/prometheus/exporter",
+		"exporterImageTag":     "v0.15.0",
+		"image":                "bitnami/nginx",
+		"imageVersion":         1.25,
+		"pinnedImage":          "nginx:1.25",
+		"pinnedImageTag":       "1.26",
+		"sidecarImage":         "busybox",
+		"sidecarImageTag":      "",
+		"templatedImage":       "{{ .Values.registry }}/app",
+		"templatedImageTag":    "1.0",
+		"digestImage":          "gcr.io/distroless/static",
+		"digestImageTag":       "sha256:" + siblingTestDigest,
+		"replicaCount":         2,
+		"unrelatedImageFormat": "png",
+	}
+
+	patterns, covered := SiblingImagePatterns(values, "app")
+	require.Len(t, patterns, 3)
+
+	assert.Equal(t, ImagePattern{
+		Path:      "app",
+		Type:      PatternTypeMap,
+		Value:     "gcr.io/distroless/static@sha256:" + siblingTestDigest,
+		Structure: map[string]interface{}{"registry": "gcr.io", "repository": "distroless/static", "tag": ""},
+		Count:     1,
+		ImageKeys: map[string]string{"repository": "digestImage", "tag": "digestImageTag"},
+	}, patterns[0])
+	assert.Equal(t, ImagePattern{
+		Path:      "app",
+		Type:      PatternTypeMap,
+		Value:     "quay.io/prometheus/exporter:v0.15.0",
+		Structure: map[string]interface{}{"registry": "quay.io", "repository": "prometheus/exporter", "tag": "v0.15.0"},
+		Count:     1,
+		ImageKeys: map[string]string{"repository": "exporterImage", "tag": "exporterImageTag"},
+	}, patterns[1])
+	assert.Equal(t, "bitnami/nginx:1.25", patterns[2].Value, "numeric versions are tags")
+	assert.Equal(t, map[string]string{"repository": "image", "tag": "imageVersion"}, patterns[2].ImageKeys)
+
+	assert.Equal(t, map[string]bool{
+		"digestImage": true, "digestImageTag": true,
+		"exporterImage": true, "exporterImageTag": true,
+		"image": true, "imageVersion": true,
+	}, covered, "images with their own tag, an empty tag key or a template are left alone")
+}
+
+func TestAnalyzeValues_SiblingImageKeys(t *testing.T) {
+	analyzer := NewAnalyzer("", nil)
+	values := map[string]interface{}{
+		"image":    "registry.example.com/platform/api",
+		"imageTag": "2.3.1",
+		"metrics": map[string]interface{}{
+			"exporterImage":    "quay.io/prometheus/exporter",
+			"exporterImageTag": "v0.15.0",
+			"port":             9100,
+		},
+		"jobs": []interface{}{
+			map[string]interface{}{"name": "migrate", "image": "busybox", "imageTag": "1.36"},
+		},
+	}
+
+	result, err := analyzer.AnalyzeValues(values)
+	require.NoError(t, err)
+
+	byValue := make(map[string]ImagePattern)
+	for _, pattern := range result.ImagePatterns {
+		byValue[pattern.Value] = pattern
+	}
+	require.Len(t, result.ImagePatterns, 3, "each image key and its tag key form one pattern")
+
+	top := byValue["registry.example.com/platform/api:2.3.1"]
+	assert.Equal(t, "", top.Path)
+	assert.Equal(t, map[string]string{"repository": "image", "tag": "imageTag"}, top.ImageKeys)
+
+	exporter := byValue["quay.io/prometheus/exporter:v0.15.0"]
+	assert.Equal(t, "metrics", exporter.Path)
+	assert.Equal(t, map[string]string{"repository": "exporterImage", "tag": "exporterImageTag"}, exporter.ImageKeys)
+
+	job := byValue["busybox:1.36"]
+	assert.Equal(t, "jobs[0]", job.Path)
+	assert.Equal(t, map[string]string{"repository": "image", "tag": "imageTag"}, job.ImageKeys)
+}
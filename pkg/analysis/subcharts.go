@@ -0,0 +1,261 @@
+package analysis
+
+import (
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// SubchartInfo identifies the subchart whose values hold an image pattern. Override paths start
+// with the subchart's values key, which is its alias when the parent chart declares one and its
+// chart name otherwise.
+type SubchartInfo struct {
+	// Name is the subchart's chart name, as in its Chart.yaml
+	Name string `json:"name" yaml:"name"`
+	// Alias is the alias the parent chart declares for the subchart, if any
+	Alias string `json:"alias,omitempty" yaml:"alias,omitempty"`
+	// Depth is the nesting level: 1 for a dependency of the chart, 2 for a dependency of a dependency, ...
+	Depth int `json:"depth" yaml:"depth"`
+	// ValuesPath is the values path holding the subchart's values (e.g. "cache" or "backend.cache")
+	ValuesPath string `json:"valuesPath" yaml:"valuesPath"`
+	// DisabledBy names the values that disable the subchart (e.g. "condition postgresql.enabled"
+	// or "tags database"); empty for enabled subcharts
+	DisabledBy string `json:"disabledBy,omitempty" yaml:"disabledBy,omitempty"`
+}
+
+// Key returns the values key of the subchart: its alias, or its chart name without one.
+func (s SubchartInfo) Key() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return s.Name
+}
+
+// Subcharts lists the subcharts of ch at every depth, parents before their dependencies. Declared
+// dependencies are listed with their alias; subcharts vendored under charts/ without a Chart.yaml
+// entry are keyed by their chart name.
+func Subcharts(ch *chart.Chart) []SubchartInfo {
+	var subcharts []SubchartInfo
+	collectSubcharts(ch, "", 1, &subcharts)
+	return subcharts
+}
+
+// collectSubcharts appends the subcharts of ch, whose values are at valuesPath, to subcharts.
+func collectSubcharts(ch *chart.Chart, valuesPath string, depth int, subcharts *[]SubchartInfo) {
+	if ch == nil {
+		return
+	}
+	loaded := ch.Dependencies()
+	declared := make(map[string]bool)
+	add := func(info SubchartInfo, sub *chart.Chart) {
+		info.Depth = depth
+		info.ValuesPath = joinSubchartPath(valuesPath, info.Key())
+		*subcharts = append(*subcharts, info)
+		collectSubcharts(sub, info.ValuesPath, depth+1, subcharts)
+	}
+
+	if ch.Metadata != nil {
+		for _, dep := range ch.Metadata.Dependencies {
+			if dep == nil || dep.Name == "" {
+				continue
+			}
+			declared[dep.Name] = true
+			if dep.Alias != "" {
+				declared[dep.Alias] = true
+			}
+			info := SubchartInfo{Name: dep.Name}
+			if dep.Alias != dep.Name {
+				info.Alias = dep.Alias
+			}
+			add(info, loadedSubchart(loaded, dep))
+		}
+	}
+	for _, sub := range loaded {
+		if !declared[sub.Name()] {
+			add(SubchartInfo{Name: sub.Name()}, sub)
+		}
+	}
+}
+
+// loadedSubchart returns the loaded chart of a declared dependency. Helm renames an aliased
+// dependency to its alias once dependencies are processed, so both names are checked.
+func loadedSubchart(loaded []*chart.Chart, dep *chart.Dependency) *chart.Chart {
+	for _, sub := range loaded {
+		if sub.Name() == dep.Name || (dep.Alias != "" && sub.Name() == dep.Alias) {
+			return sub
+		}
+	}
+	return nil
+}
+
+// FindSubchart returns the innermost subchart whose values hold valuesPath, or nil if the path
+// belongs to the parent chart.
+func FindSubchart(subcharts []SubchartInfo, valuesPath string) *SubchartInfo {
+	var found *SubchartInfo
+	for i := range subcharts {
+		sub := &subcharts[i]
+		if !isValuesPathWithin(valuesPath, sub.ValuesPath) {
+			continue
+		}
+		if found == nil || sub.Depth > found.Depth {
+			found = sub
+		}
+	}
+	return found
+}
+
+// ResolveSubcharts records on each pattern the subchart of ch whose values hold it. Patterns
+// already marked by MarkDisabledSubcharts keep the disabled subchart.
+func ResolveSubcharts(ch *chart.Chart, patterns []ImagePattern) {
+	subcharts := Subcharts(ch)
+	if len(subcharts) == 0 {
+		return
+	}
+	for i := range patterns {
+		if patterns[i].Disabled {
+			continue
+		}
+		if sub := FindSubchart(subcharts, patterns[i].Path); sub != nil {
+			info := *sub
+			patterns[i].Subchart = &info
+		}
+	}
+}
+
+// isValuesPathWithin reports whether valuesPath is prefix or a path below it.
+func isValuesPathWithin(valuesPath, prefix string) bool {
+	if !strings.HasPrefix(valuesPath, prefix) {
+		return false
+	}
+	rest := valuesPath[len(prefix):]
+	return rest == "" || rest[0] == '.' || rest[0] == '['
+}
+
+// joinSubchartPath appends key to the values path prefix.
+func joinSubchartPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// DisabledSubcharts lists the subcharts of ch that Helm would not render with values, the
+// merged values of the chart. Like Helm, a dependency is enabled unless all of its tags set under
+// the top-level "tags" key are false, and the first of its conditions that resolves to a bool
+// overrides the tags. Conditions are resolved relative to the values of the declaring chart.
+// Subcharts of a disabled subchart are not listed separately.
+func DisabledSubcharts(ch *chart.Chart, values map[string]interface{}) []SubchartInfo {
+	var disabled []SubchartInfo
+	tags, _ := values["tags"].(map[string]interface{})
+	collectDisabledSubcharts(ch, values, tags, "", 1, &disabled)
+	return disabled
+}
+
+// collectDisabledSubcharts appends the disabled subcharts of ch, whose values are at valuesPath,
+// to disabled.
+func collectDisabledSubcharts(ch *chart.Chart, values, tags map[string]interface{}, valuesPath string, depth int, disabled *[]SubchartInfo) {
+	if ch == nil {
+		return
+	}
+	loaded := ch.Dependencies()
+	declared := make(map[string]bool)
+	if ch.Metadata != nil {
+		for _, dep := range ch.Metadata.Dependencies {
+			if dep == nil || dep.Name == "" {
+				continue
+			}
+			declared[dep.Name] = true
+			if dep.Alias != "" {
+				declared[dep.Alias] = true
+			}
+			info := SubchartInfo{Name: dep.Name, Depth: depth}
+			if dep.Alias != dep.Name {
+				info.Alias = dep.Alias
+			}
+			info.ValuesPath = joinSubchartPath(valuesPath, info.Key())
+			if info.DisabledBy = dependencyDisabledBy(dep, values, tags, valuesPath); info.DisabledBy != "" {
+				*disabled = append(*disabled, info)
+				continue
+			}
+			collectDisabledSubcharts(loadedSubchart(loaded, dep), values, tags, info.ValuesPath, depth+1, disabled)
+		}
+	}
+	// Subcharts vendored without a Chart.yaml entry are always enabled, but may declare their own
+	for _, sub := range loaded {
+		if !declared[sub.Name()] {
+			collectDisabledSubcharts(sub, values, tags, joinSubchartPath(valuesPath, sub.Name()), depth+1, disabled)
+		}
+	}
+}
+
+// dependencyDisabledBy returns why dep, declared by the chart whose values are at valuesPath, is
+// disabled by values, or "" if it is enabled.
+func dependencyDisabledBy(dep *chart.Dependency, values, tags map[string]interface{}, valuesPath string) string {
+	for _, condition := range strings.Split(strings.TrimSpace(dep.Condition), ",") {
+		condition = strings.TrimSpace(condition)
+		if condition == "" {
+			continue
+		}
+		conditionPath := joinSubchartPath(valuesPath, condition)
+		value, found := image.GetValueAtPath(values, strings.Split(conditionPath, "."))
+		if enabled, isBool := value.(bool); found && isBool {
+			if enabled {
+				return ""
+			}
+			return "condition " + conditionPath
+		}
+	}
+
+	var falseTags []string
+	for _, tag := range dep.Tags {
+		switch enabled, isBool := tags[tag].(bool); {
+		case isBool && enabled:
+			return ""
+		case isBool:
+			falseTags = append(falseTags, tag)
+		}
+	}
+	if len(falseTags) > 0 {
+		return "tags " + strings.Join(falseTags, ",")
+	}
+	return ""
+}
+
+// MarkDisabledSubcharts marks the patterns held by a subchart of ch that values disable, and
+// records that subchart on them. It returns the number of patterns marked.
+func MarkDisabledSubcharts(ch *chart.Chart, values map[string]interface{}, patterns []ImagePattern) int {
+	disabled := DisabledSubcharts(ch, values)
+	if len(disabled) == 0 {
+		return 0
+	}
+	marked := 0
+	for i := range patterns {
+		sub := FindSubchart(disabled, patterns[i].Path)
+		if sub == nil {
+			continue
+		}
+		info := *sub
+		patterns[i].Subchart = &info
+		patterns[i].Disabled = true
+		marked++
+	}
+	return marked
+}
+
+// DependencyValuesKeys returns the keys of the parent's values holding the values of dep: each
+// alias the parent declares for it, or its name
+func DependencyValuesKeys(parent, dep *chart.Chart) []string {
+	var aliases []string
+	if parent.Metadata != nil {
+		for _, d := range parent.Metadata.Dependencies {
+			if d != nil && d.Name == dep.Name() && d.Alias != "" {
+				aliases = append(aliases, d.Alias)
+			}
+		}
+	}
+	if len(aliases) == 0 {
+		return []string{dep.Name()}
+	}
+	return aliases
+}
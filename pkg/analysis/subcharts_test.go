@@ -0,0 +1,117 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func newTestChart(name string, deps []*chart.Dependency, subcharts ...*chart.Chart) *chart.Chart {
+	ch := &chart.Chart{Metadata: &chart.Metadata{Name: name, Dependencies: deps}}
+	ch.SetDependencies(subcharts...)
+	return ch
+}
+
+func TestSubcharts(t *testing.T) {
+	redis := newTestChart("redis", nil)
+	backend := newTestChart("backend", []*chart.Dependency{{Name: "redis", Alias: "cache"}}, redis)
+	vendored := newTestChart("metrics", nil)
+	parent := newTestChart("platform", []*chart.Dependency{
+		{Name: "backend"},
+		{Name: "postgresql", Alias: "db"},
+	}, backend, vendored)
+
+	assert.Equal(t, []SubchartInfo{
+		{Name: "backend", Depth: 1, ValuesPath: "backend"},
+		{Name: "redis", Alias: "cache", Depth: 2, ValuesPath: "backend.cache"},
+		{Name: "postgresql", Alias: "db", Depth: 1, ValuesPath: "db"},
+		{Name: "metrics", Depth: 1, ValuesPath: "metrics"},
+	}, Subcharts(parent))
+}
+
+func TestResolveSubcharts(t *testing.T) {
+	redis := newTestChart("redis", nil)
+	parent := newTestChart("platform", []*chart.Dependency{
+		{Name: "backend"},
+		{Name: "redis", Alias: "cache"},
+	}, newTestChart("backend", []*chart.Dependency{{Name: "redis", Alias: "sessions"}}, redis), redis)
+	patterns := []ImagePattern{
+		{Path: "image"},
+		{Path: "cache.image"},
+		{Path: "cacheExporter.image"},
+		{Path: "backend.sessions.image"},
+		{Path: "backend.sidecars[0].image"},
+	}
+
+	ResolveSubcharts(parent, patterns)
+
+	assert.Nil(t, patterns[0].Subchart)
+	require.NotNil(t, patterns[1].Subchart)
+	assert.Equal(t, SubchartInfo{Name: "redis", Alias: "cache", Depth: 1, ValuesPath: "cache"}, *patterns[1].Subchart)
+	assert.Nil(t, patterns[2].Subchart, "a key that only starts with the alias is not part of the subchart")
+	require.NotNil(t, patterns[3].Subchart)
+	assert.Equal(t, SubchartInfo{Name: "redis", Alias: "sessions", Depth: 2, ValuesPath: "backend.sessions"}, *patterns[3].Subchart)
+	require.NotNil(t, patterns[4].Subchart)
+	assert.Equal(t, "backend", patterns[4].Subchart.Key())
+}
+
+func TestDisabledSubcharts(t *testing.T) {
+	redis := newTestChart("redis", nil)
+	backend := newTestChart("backend", []*chart.Dependency{
+		{Name: "redis", Alias: "cache", Condition: "cache.enabled"},
+	}, redis)
+	parent := newTestChart("platform", []*chart.Dependency{
+		{Name: "postgresql", Alias: "db", Condition: "db.enabled,global.db.enabled"},
+		{Name: "backend", Tags: []string{"api", "workers"}},
+		{Name: "mongodb", Condition: "mongodb.enabled", Tags: []string{"database"}},
+		{Name: "kafka", Tags: []string{"queue"}},
+	}, backend, newTestChart("postgresql", nil), newTestChart("mongodb", nil))
+
+	values := map[string]interface{}{
+		"db":      map[string]interface{}{"enabled": "no"},
+		"global":  map[string]interface{}{"db": map[string]interface{}{"enabled": false}},
+		"backend": map[string]interface{}{"cache": map[string]interface{}{"enabled": false}},
+		"mongodb": map[string]interface{}{"enabled": true},
+		"tags":    map[string]interface{}{"api": false, "workers": true, "database": false, "queue": false},
+	}
+	assert.Equal(t, []SubchartInfo{
+		{Name: "postgresql", Alias: "db", Depth: 1, ValuesPath: "db", DisabledBy: "condition global.db.enabled"},
+		{Name: "redis", Alias: "cache", Depth: 2, ValuesPath: "backend.cache", DisabledBy: "condition backend.cache.enabled"},
+		{Name: "kafka", Depth: 1, ValuesPath: "kafka", DisabledBy: "tags queue"},
+	}, DisabledSubcharts(parent, values), "a condition overrides the tags and one true tag enables")
+
+	values["tags"] = map[string]interface{}{"api": false, "workers": false}
+	disabled := DisabledSubcharts(parent, values)
+	require.Len(t, disabled, 2)
+	assert.Equal(t, "tags api,workers", disabled[1].DisabledBy)
+	assert.Equal(t, "backend", disabled[1].ValuesPath, "subcharts of a disabled subchart are not listed")
+
+	assert.Empty(t, DisabledSubcharts(parent, nil))
+}
+
+func TestMarkDisabledSubcharts(t *testing.T) {
+	parent := newTestChart("platform", []*chart.Dependency{
+		{Name: "postgresql", Condition: "postgresql.enabled"},
+		{Name: "redis", Alias: "cache"},
+	}, newTestChart("postgresql", nil), newTestChart("redis", nil))
+	patterns := []ImagePattern{
+		{Path: "image"},
+		{Path: "postgresql.image"},
+		{Path: "postgresql.metrics.image"},
+		{Path: "cache.image"},
+	}
+	values := map[string]interface{}{"postgresql": map[string]interface{}{"enabled": false}}
+
+	assert.Equal(t, 2, MarkDisabledSubcharts(parent, values, patterns))
+	ResolveSubcharts(parent, patterns)
+
+	assert.False(t, patterns[0].Disabled)
+	assert.True(t, patterns[1].Disabled)
+	require.NotNil(t, patterns[2].Subchart)
+	assert.Equal(t, "condition postgresql.enabled", patterns[2].Subchart.DisabledBy)
+	assert.False(t, patterns[3].Disabled)
+	require.NotNil(t, patterns[3].Subchart)
+	assert.Equal(t, "cache", patterns[3].Subchart.ValuesPath)
+}
@@ -35,6 +35,30 @@ type ImagePattern struct {
 	SourceOrigin     string `json:"sourceOrigin,omitempty" yaml:"sourceOrigin,omitempty"`         // Originating file/path from context analysis
 	// Added for subchart app version fallback:
 	SourceChartAppVersion string `json:"sourceChartAppVersion,omitempty" yaml:"sourceChartAppVersion,omitempty"` // AppVersion of the originating chart
+	// Added for YAML anchor provenance:
+	AnchorSource string `json:"anchorSource,omitempty" yaml:"anchorSource,omitempty"` // Path of the YAML anchor this value was copied from via alias or merge key
+	// RegistryInRepository marks map patterns without a registry value whose repository starts with
+	// the registry host (e.g. repository: quay.io/org/app); overrides keep the host in the repository
+	RegistryInRepository bool `json:"registryInRepository,omitempty" yaml:"registryInRepository,omitempty"`
+	// DigestInRepository marks map patterns whose repository pins the image digest (e.g.
+	// repository: gcr.io/distroless/static@sha256:...); overrides keep the digest in the repository
+	DigestInRepository bool `json:"digestInRepository,omitempty" yaml:"digestInRepository,omitempty"`
+	// ImageKeys maps the image fields (registry, repository, tag) of a map pattern to the keys
+	// below Path holding them, for charts that split an image across keys with other names
+	// (e.g. imageRegistry, imageRepository); overrides are written to these keys
+	ImageKeys map[string]string `json:"imageKeys,omitempty" yaml:"imageKeys,omitempty"`
+	// KeepString marks string patterns that templates use as a complete image reference, so the
+	// override is a reference string rather than an image map
+	KeepString bool `json:"keepString,omitempty" yaml:"keepString,omitempty"`
+	// Subchart identifies the subchart whose values hold the pattern (chart name, alias and depth);
+	// nil for the chart's own values or when subcharts were not resolved
+	Subchart *SubchartInfo `json:"subchart,omitempty" yaml:"subchart,omitempty"`
+	// Disabled marks patterns held by a subchart that the chart's values disable through its
+	// dependency condition or tags; Helm does not render them
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	// Location is where the image is written in the values files that set it last; nil for values
+	// set on the command line or analyzed without their source YAML
+	Location *SourceLocation `json:"location,omitempty" yaml:"location,omitempty"`
 }
 
 // GlobalPattern represents a global registry configuration found in the chart.
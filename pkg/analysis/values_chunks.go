@@ -0,0 +1,327 @@
+package analysis
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// valuesChunkSize is the size above which the value of a mapping key is split into chunks of its
+// own keys, so that the YAML node tree of a large values file (e.g. vendored CRDs) is never held
+// in memory as a whole.
+const valuesChunkSize = 256 << 10
+
+// plainKeyPattern matches a line starting with a plain mapping key, capturing the key and what
+// follows its colon.
+var plainKeyPattern = regexp.MustCompile(`^ *([A-Za-z0-9_][A-Za-z0-9_./-]*) *:( .*)?$`)
+
+// errNotChunk is returned for a chunk that does not parse to a single string key and its value.
+var errNotChunk = errors.New("chunk is not a single mapping key")
+
+// ValuesChunk is a part of values YAML that is parsed on its own: the key and value of a mapping,
+// or the whole values if they could not be split.
+type ValuesChunk struct {
+	// Keys are the keys of the mappings the chunk is below, outermost first; nil for whole values
+	Keys []string
+	// Path is the values path of the mapping the chunk is in, or the prefix for whole values
+	Path string
+	// Node is the mapping of the chunk's key and value, or the document node of whole values.
+	// Positions are those in the values YAML.
+	Node *yaml.Node
+	// Whole is set for whole values, which supersede the chunks visited before
+	Whole bool
+}
+
+// WalkValuesChunks parses values YAML chunk by chunk and calls visit with each chunk in document
+// order, so that memory use is bounded by the chunk size rather than the size of the values. The
+// value of a key too large for a chunk is visited as an empty mapping, followed by the chunks of
+// its keys. Values that are not a block mapping, or that span several documents, are parsed and
+// visited whole. So are values whose chunks turn out not to stand on their own, e.g. for an alias
+// of an anchor in another chunk: visit is then called once more with the whole values, which
+// supersede the chunks visited before. Paths are below prefix. include, if not nil, skips the
+// chunks whose key is known without parsing them and whose values path it rejects.
+func WalkValuesChunks(data []byte, prefix string, include func(path string) bool, visit func(ValuesChunk)) error {
+	chunks, ok := splitValues(data)
+	if ok {
+		if err := visitValuesChunks(data, prefix, chunks, include, visit); err == nil {
+			return nil
+		}
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return err
+	}
+	visit(ValuesChunk{Path: prefix, Node: &root, Whole: true})
+	return nil
+}
+
+// visitValuesChunks parses and visits chunks, failing on the first one that does not parse to a
+// single string key and its value, or repeats the key of another chunk.
+func visitValuesChunks(data []byte, prefix string, chunks []valuesChunk, include func(path string) bool, visit func(ValuesChunk)) error {
+	seen := make(map[string]bool, len(chunks))
+	for i := range chunks {
+		chunk := &chunks[i]
+		path := prefix
+		for _, key := range chunk.keys {
+			path = JoinPath(path, key)
+		}
+
+		if chunk.key != "" {
+			keyPath := JoinPath(path, chunk.key)
+			if seen[keyPath] {
+				return fmt.Errorf("mapping key %q already defined", chunk.key)
+			}
+			seen[keyPath] = true
+			if include != nil && !include(keyPath) {
+				continue
+			}
+		}
+		node, err := chunk.parse(data)
+		if err != nil {
+			return err
+		}
+		if chunk.key == "" {
+			keyPath := JoinPath(path, node.Content[0].Value)
+			if seen[keyPath] {
+				return fmt.Errorf("mapping key %q already defined", node.Content[0].Value)
+			}
+			seen[keyPath] = true
+		}
+		visit(ValuesChunk{Keys: chunk.keys, Path: path, Node: node})
+	}
+	return nil
+}
+
+// valuesChunk is the key and value of a mapping in values YAML, parsed on its own.
+type valuesChunk struct {
+	keys   []string // keys of the mappings the chunk is below
+	key    string   // the chunk's key if it is a plain key, "" otherwise
+	start  int      // offset of the chunk in the values
+	end    int      // offset of the end of the chunk
+	line   int      // 0-based line of the chunk's key
+	indent int      // indentation of the chunk's key, removed before parsing
+	split  bool     // the value is split into the chunks that follow; only the key is parsed
+}
+
+// parse parses the chunk into the mapping of its key and value, at their positions in data.
+func (c *valuesChunk) parse(data []byte) (*yaml.Node, error) {
+	if c.split {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: c.key, Line: c.line + 1, Column: c.indent + 1}
+		value := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: key.Line, Column: key.Column}
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{key, value}, Line: key.Line, Column: key.Column}, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(dedent(data[c.start:c.end], c.indent), &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) != 1 {
+		return nil, errNotChunk
+	}
+	node := doc.Content[0]
+	if node.Kind != yaml.MappingNode || len(node.Content) != 2 || node.Anchor != "" ||
+		node.Content[0].Kind != yaml.ScalarNode || node.Content[0].ShortTag() != "!!str" ||
+		(c.key != "" && node.Content[0].Value != c.key) {
+		return nil, errNotChunk
+	}
+	shiftNodes(node, c.line, c.indent)
+	return node, nil
+}
+
+// dedent removes up to indent leading spaces from each line of text.
+func dedent(text []byte, indent int) []byte {
+	if indent == 0 {
+		return text
+	}
+	out := make([]byte, 0, len(text))
+	for len(text) > 0 {
+		line := text
+		if i := bytes.IndexByte(text, '\n'); i >= 0 {
+			line = text[:i+1]
+		}
+		text = text[len(line):]
+		for n := 0; n < indent && len(line) > 0 && line[0] == ' '; n++ {
+			line = line[1:]
+		}
+		out = append(out, line...)
+	}
+	return out
+}
+
+// shiftNodes moves the positions of node and the nodes below it by lines and columns.
+func shiftNodes(node *yaml.Node, lines, columns int) {
+	node.Line += lines
+	node.Column += columns
+	for _, child := range node.Content {
+		shiftNodes(child, lines, columns)
+	}
+}
+
+// valuesLine is a line of values YAML.
+type valuesLine struct {
+	start   int  // offset of the line
+	indent  int  // number of leading spaces
+	content bool // the line is neither blank nor a comment
+}
+
+// valuesSplitter splits values YAML into chunks at the lines of its mapping keys.
+type valuesSplitter struct {
+	data   []byte
+	lines  []valuesLine
+	chunks []valuesChunk
+}
+
+// splitValues splits values YAML into chunks in document order. It returns false for values that
+// are not a block mapping, span several documents, or have directives or tabs for indentation.
+func splitValues(data []byte) ([]valuesChunk, bool) {
+	lines, ok := scanValuesLines(data)
+	if !ok {
+		return nil, false
+	}
+	s := valuesSplitter{data: data, lines: lines}
+	first := s.nextContent(0, len(lines))
+	if first == len(lines) {
+		return nil, true
+	}
+	if !s.splitMapping(first, len(lines), nil) {
+		return nil, false
+	}
+	return s.chunks, true
+}
+
+// scanValuesLines returns the lines of data, or false if they have a document marker other than a
+// leading ---, a directive, or tabs for indentation.
+func scanValuesLines(data []byte) ([]valuesLine, bool) {
+	lines := make([]valuesLine, 0, bytes.Count(data, []byte("\n"))+1)
+	sawContent := false
+	for start := 0; start < len(data); {
+		end := len(data)
+		if i := bytes.IndexByte(data[start:], '\n'); i >= 0 {
+			end = start + i
+		}
+		line := data[start:end]
+		indent := 0
+		for indent < len(line) && line[indent] == ' ' {
+			indent++
+		}
+		rest := line[indent:]
+		content := len(bytes.TrimSpace(rest)) > 0 && rest[0] != '#'
+		switch {
+		case content && rest[0] == '\t':
+			return nil, false
+		case indent == 0 && (isDocumentMarker(line, "---") || isDocumentMarker(line, "...") || bytes.HasPrefix(line, []byte("%"))):
+			if sawContent || !isDocumentMarker(line, "---") || !isBlankOrComment(line[3:]) {
+				return nil, false
+			}
+			content = false
+		}
+		sawContent = sawContent || content
+		lines = append(lines, valuesLine{start: start, indent: indent, content: content})
+		start = end + 1
+	}
+	return lines, true
+}
+
+// isDocumentMarker reports whether line starts with the document marker.
+func isDocumentMarker(line []byte, marker string) bool {
+	return bytes.HasPrefix(line, []byte(marker)) && (len(line) == len(marker) || line[len(marker)] == ' ' || line[len(marker)] == '\t')
+}
+
+// isBlankOrComment reports whether text holds nothing but blanks and a comment.
+func isBlankOrComment(text []byte) bool {
+	text = bytes.TrimSpace(text)
+	return len(text) == 0 || text[0] == '#'
+}
+
+// nextContent returns the first line from i before end that is neither blank nor a comment, or end.
+func (s *valuesSplitter) nextContent(i, end int) int {
+	for i < end && !s.lines[i].content {
+		i++
+	}
+	return i
+}
+
+// offset returns the offset of line i, or the end of the values.
+func (s *valuesSplitter) offset(i int) int {
+	if i < len(s.lines) {
+		return s.lines[i].start
+	}
+	return len(s.data)
+}
+
+// text returns line i without its line break.
+func (s *valuesSplitter) text(i int) []byte {
+	return bytes.TrimSuffix(s.data[s.lines[i].start:s.offset(i+1)], []byte("\n"))
+}
+
+// isSequenceEntry reports whether line i is an entry of a block sequence.
+func (s *valuesSplitter) isSequenceEntry(i int) bool {
+	rest := s.text(i)[s.lines[i].indent:]
+	return rest[0] == '-' && (len(rest) == 1 || rest[1] == ' ')
+}
+
+// splitMapping splits the lines from first to end, the keys of a block mapping below keys, into
+// a chunk per key, and the values of keys too large for a chunk into chunks of their keys. It
+// returns false if the lines are not indented as the keys of a mapping.
+func (s *valuesSplitter) splitMapping(first, end int, keys []string) bool {
+	indent := s.lines[first].indent
+	var starts []int
+	for i := first; i < end; i++ {
+		line := s.lines[i]
+		switch {
+		case !line.content:
+			// A comment left of the keys would not survive removing their indentation
+			if line.indent < indent && len(bytes.TrimSpace(s.text(i))) > 0 {
+				return false
+			}
+		case line.indent < indent:
+			return false
+		case line.indent == indent && !s.isSequenceEntry(i):
+			starts = append(starts, i)
+		}
+	}
+	if len(starts) == 0 || starts[0] != first {
+		return false
+	}
+
+	for n, start := range starts {
+		next := end
+		if n+1 < len(starts) {
+			next = starts[n+1]
+		}
+		chunk := valuesChunk{keys: keys, start: s.offset(start), end: s.offset(next), line: start, indent: indent}
+		match := plainKeyPattern.FindSubmatch(s.text(start))
+		if match != nil {
+			chunk.key = string(match[1])
+		}
+		if chunk.end-chunk.start > valuesChunkSize && match != nil && isBlankOrComment(match[2]) && isStringKey(chunk.key) {
+			body := s.nextContent(start+1, next)
+			if body < next && s.lines[body].indent > indent && !s.isSequenceEntry(body) {
+				chunks := len(s.chunks)
+				chunk.split = true
+				s.chunks = append(s.chunks, chunk)
+				if s.splitMapping(body, next, append(slices.Clip(keys), chunk.key)) {
+					continue
+				}
+				s.chunks = s.chunks[:chunks]
+				chunk.split = false
+			}
+		}
+		s.chunks = append(s.chunks, chunk)
+	}
+	return true
+}
+
+// isStringKey reports whether a plain key is a string rather than e.g. a boolean or a number.
+func isStringKey(key string) bool {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(key), &node); err != nil || len(node.Content) != 1 {
+		return false
+	}
+	return node.Content[0].ShortTag() == "!!str"
+}
@@ -0,0 +1,145 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// walkChunks returns the chunks WalkValuesChunks visits for data.
+func walkChunks(t *testing.T, data string, include func(string) bool) []ValuesChunk {
+	t.Helper()
+	var chunks []ValuesChunk
+	require.NoError(t, WalkValuesChunks([]byte(data), "", include, func(chunk ValuesChunk) {
+		chunks = append(chunks, chunk)
+	}))
+	return chunks
+}
+
+// chunkKey returns the key of a chunk that is not whole values.
+func chunkKey(chunk ValuesChunk) string {
+	return chunk.Node.Content[0].Value
+}
+
+// largeValues returns values with a crds key too large for a chunk, whose keys are crd0, crd1...
+func largeValues() string {
+	var b strings.Builder
+	b.WriteString("---\nimage: nginx:1.25\ncrds:\n  # vendored CRDs\n")
+	for i := 0; b.Len() < 2*valuesChunkSize; i++ {
+		fmt.Fprintf(&b, "  crd%d:\n    spec:\n      description: %s\n", i, strings.Repeat("x", 1000))
+	}
+	b.WriteString("operator:\n  image: quay.io/example/operator:v1\n")
+	return b.String()
+}
+
+func TestWalkValuesChunks(t *testing.T) {
+	chunks := walkChunks(t, "# defaults\nimage:\n  repository: nginx\n\n\"quoted key\": 1\nsidecars:\n- name: proxy\n  image: envoy\n", nil)
+	require.Len(t, chunks, 3)
+	for _, chunk := range chunks {
+		assert.False(t, chunk.Whole)
+		assert.Empty(t, chunk.Keys)
+		assert.Empty(t, chunk.Path)
+	}
+	assert.Equal(t, []string{"image", "quoted key", "sidecars"}, []string{chunkKey(chunks[0]), chunkKey(chunks[1]), chunkKey(chunks[2])})
+
+	repository := chunks[0].Node.Content[1].Content[1]
+	assert.Equal(t, "nginx", repository.Value)
+	assert.Equal(t, 3, repository.Line, "positions are those in the values")
+	assert.Equal(t, 15, repository.Column)
+
+	assert.Empty(t, walkChunks(t, "# nothing but comments\n", nil))
+}
+
+func TestWalkValuesChunks_Split(t *testing.T) {
+	data := largeValues()
+	chunks := walkChunks(t, data, nil)
+	require.Greater(t, len(chunks), 4)
+
+	crds := chunks[1]
+	assert.Equal(t, "crds", chunkKey(crds))
+	assert.Equal(t, yaml.MappingNode, crds.Node.Content[1].Kind)
+	assert.Empty(t, crds.Node.Content[1].Content, "the value of a split key is visited empty")
+	assert.Equal(t, 3, crds.Node.Content[0].Line)
+
+	crd1 := chunks[3]
+	assert.Equal(t, []string{"crds"}, crd1.Keys)
+	assert.Equal(t, "crds", crd1.Path)
+	assert.Equal(t, "crd1", chunkKey(crd1))
+	assert.Equal(t, 8, crd1.Node.Content[0].Line)
+	assert.Equal(t, 3, crd1.Node.Content[0].Column)
+	description := crd1.Node.Content[1].Content[1].Content[1]
+	assert.Equal(t, 10, description.Line)
+	assert.Equal(t, 20, description.Column)
+
+	operator := chunks[len(chunks)-1]
+	assert.Empty(t, operator.Keys)
+	assert.Equal(t, "operator", chunkKey(operator))
+
+	var whole, chunked map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(data), &whole))
+	chunked = make(map[string]interface{})
+	for _, chunk := range chunks {
+		var values map[string]interface{}
+		require.NoError(t, chunk.Node.Decode(&values))
+		parent := chunked
+		for _, key := range chunk.Keys {
+			parent = parent[key].(map[string]interface{})
+		}
+		for key, value := range values {
+			parent[key] = value
+		}
+	}
+	assert.Equal(t, whole, chunked, "the chunks hold the values")
+}
+
+func TestWalkValuesChunks_Include(t *testing.T) {
+	chunks := walkChunks(t, largeValues(), func(path string) bool {
+		return !strings.HasPrefix(path, "crds.")
+	})
+	require.Len(t, chunks, 3)
+	assert.Equal(t, []string{"image", "crds", "operator"}, []string{chunkKey(chunks[0]), chunkKey(chunks[1]), chunkKey(chunks[2])})
+}
+
+func TestWalkValuesChunks_Whole(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "alias of an anchor in another chunk", data: "defaults: &defaults\n  tag: v1\nimage: *defaults\n"},
+		{name: "duplicate key", data: "image: nginx\nimage: envoy\n"},
+		{name: "several documents", data: "image: nginx\n---\nimage: envoy\n"},
+		{name: "document end", data: "image: nginx\n...\n"},
+		{name: "directive", data: "%TAG !e! tag:example.com,2000:\n---\nimage: nginx\n"},
+		{name: "sequence", data: "- image: nginx\n"},
+		{name: "flow mapping", data: "{image: nginx, tag: v1}\n"},
+		{name: "anchored root", data: "--- &root\nimage: nginx\n"},
+		{name: "comment left of a key", data: "  image: nginx\n# trailing\n  tag: v1\n"},
+		{name: "non-string key", data: "true: nginx\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := walkChunks(t, tt.data, nil)
+			require.NotEmpty(t, chunks)
+			last := chunks[len(chunks)-1]
+			assert.True(t, last.Whole, "the whole values supersede the chunks")
+			assert.Equal(t, yaml.DocumentNode, last.Node.Kind)
+		})
+	}
+
+	assert.Error(t, WalkValuesChunks([]byte("image: ["), "", nil, func(ValuesChunk) {}))
+}
+
+func BenchmarkWalkValuesChunks(b *testing.B) {
+	data := []byte(largeValues())
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for b.Loop() {
+		if err := WalkValuesChunks(data, "", nil, func(ValuesChunk) {}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package audit appends a record of each run that changes deployment inputs to a local JSONL
+// audit log: who ran it, on which chart and release, and digests of the configuration, values and
+// output files, so change-management processes can trace an overrides file back to its inputs.
+// Nothing is sent anywhere; the log is only written when a path is configured.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	"github.com/spf13/afero"
+)
+
+// Record is one line of the audit log.
+type Record struct {
+	// Time is when the command finished
+	Time time.Time `json:"time"`
+	// Command is the command that ran, e.g. "irr override"
+	Command string `json:"command"`
+	// User is the operating system user that ran the command
+	User string `json:"user"`
+	// Chart is the chart path or reference, if one was given
+	Chart string `json:"chart,omitempty"`
+	// Release is the Helm release name, if one was given
+	Release string `json:"release,omitempty"`
+	// Namespace is the namespace of the release
+	Namespace string `json:"namespace,omitempty"`
+	// ConfigFiles are the registry mapping files used
+	ConfigFiles []FileDigest `json:"configFiles,omitempty"`
+	// ValuesFiles are the values files used
+	ValuesFiles []FileDigest `json:"valuesFiles,omitempty"`
+	// OutputFile is the file written by the command; nil when it wrote to stdout
+	OutputFile *FileDigest `json:"outputFile,omitempty"`
+	// ExitCode is the exit code of the command
+	ExitCode int `json:"exitCode"`
+	// Error is the error that failed the command
+	Error string `json:"error,omitempty"`
+}
+
+// FileDigest identifies the content of a file, or of every file in a directory.
+type FileDigest struct {
+	Path string `json:"path"`
+	// SHA256 is "sha256:" followed by the hex digest; empty when the file cannot be read
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Digest returns the digest of path. A path that cannot be read gets an empty SHA256 rather than
+// an error, so the record still shows which file was named.
+func Digest(fs afero.Fs, path string) FileDigest {
+	digest := FileDigest{Path: path}
+	sum, err := hashPath(fs, path)
+	if err == nil {
+		digest.SHA256 = "sha256:" + hex.EncodeToString(sum)
+	}
+	return digest
+}
+
+// Digests returns the digest of each path
+func Digests(fs afero.Fs, paths []string) []FileDigest {
+	if len(paths) == 0 {
+		return nil
+	}
+	digests := make([]FileDigest, 0, len(paths))
+	for _, path := range paths {
+		digests = append(digests, Digest(fs, path))
+	}
+	return digests
+}
+
+// hashPath hashes the content of a file. For a directory, it hashes the relative path and content
+// of every regular file below it, in lexical order.
+func hashPath(fs afero.Fs, path string) ([]byte, error) {
+	info, err := fs.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat '%s': %w", path, err)
+	}
+	hash := sha256.New()
+	if !info.IsDir() {
+		if err := hashFile(fs, path, hash); err != nil {
+			return nil, err
+		}
+		return hash.Sum(nil), nil
+	}
+	err = afero.Walk(fs, path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if _, err := fmt.Fprintf(hash, "%s\x00", file); err != nil {
+			return fmt.Errorf("failed to hash '%s': %w", file, err)
+		}
+		return hashFile(fs, file, hash)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash directory '%s': %w", path, err)
+	}
+	return hash.Sum(nil), nil
+}
+
+// hashFile writes the content of file to w
+func hashFile(fs afero.Fs, file string, w io.Writer) error {
+	f, err := fs.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open '%s': %w", file, err)
+	}
+	defer func() {
+		_ = f.Close() //nolint:errcheck // read-only file
+	}()
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("failed to read '%s': %w", file, err)
+	}
+	return nil
+}
+
+// CurrentUser returns the name of the user running irr, falling back to $USER (or %USERNAME%)
+// when the user database is not available.
+func CurrentUser() string {
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+// Append writes record as one JSON line at the end of the audit log at path, creating the file
+// if needed. Existing lines are never rewritten.
+func Append(fs afero.Fs, path string, record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	f, err := fs.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, fileutil.ReadWriteUserPermission)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log '%s': %w", path, err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close() //nolint:errcheck // the write error is reported
+		return fmt.Errorf("failed to write audit log '%s': %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log '%s': %w", path, err)
+	}
+	return nil
+}
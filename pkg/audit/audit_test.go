@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigest(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "mappings.yaml", []byte("hello\n"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "mappings.d/a.yaml", []byte("a"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "mappings.d/b.yaml", []byte("b"), 0o644))
+
+	digest := Digest(fs, "mappings.yaml")
+	assert.Equal(t, "sha256:5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03", digest.SHA256)
+
+	dirDigest := Digest(fs, "mappings.d")
+	assert.True(t, strings.HasPrefix(dirDigest.SHA256, "sha256:"))
+	require.NoError(t, afero.WriteFile(fs, "mappings.d/b.yaml", []byte("changed"), 0o644))
+	assert.NotEqual(t, dirDigest.SHA256, Digest(fs, "mappings.d").SHA256, "changing a file changes the directory digest")
+
+	assert.Equal(t, FileDigest{Path: "missing.yaml"}, Digest(fs, "missing.yaml"))
+	assert.Nil(t, Digests(fs, nil))
+}
+
+func TestAppend(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	first := &Record{Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Command: "irr override", User: "alice", Chart: "./nginx"}
+	second := &Record{Time: first.Time, Command: "irr validate", User: "alice", ExitCode: 16, Error: "template failed"}
+	require.NoError(t, Append(fs, "audit.jsonl", first))
+	require.NoError(t, Append(fs, "audit.jsonl", second))
+
+	data, err := afero.ReadFile(fs, "audit.jsonl")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, `{"time":"2026-01-02T03:04:05Z","command":"irr override","user":"alice","chart":"./nginx","exitCode":0}`, lines[0])
+
+	var decoded Record
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &decoded))
+	assert.Equal(t, *second, decoded)
+}
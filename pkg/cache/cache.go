@@ -0,0 +1,268 @@
+// Package cache stores chart analysis results on disk, keyed by a digest of the chart contents and
+// the analysis inputs, so that repeated runs on an unchanged chart can skip analysis.
+package cache
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	"github.com/spf13/afero"
+)
+
+// DirEnvVar overrides the directory analysis results are cached in
+const DirEnvVar = "IRR_CACHE_DIR"
+
+// entryExt is the file extension of cache entries
+const entryExt = ".json"
+
+// Entry is a cached analysis result.
+type Entry struct {
+	// Key identifies the chart contents and analysis inputs the entry was computed from
+	Key string `json:"key"`
+	// Chart is the chart path of the run that created the entry
+	Chart        string `json:"chart"`
+	ChartName    string `json:"chartName,omitempty"`
+	ChartVersion string `json:"chartVersion,omitempty"`
+	// Created is the time the entry was written
+	Created  time.Time               `json:"created"`
+	Analysis *analysis.ChartAnalysis `json:"analysis"`
+	// Values are the merged values the analysis was performed on, if any
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+// Info describes a cache entry for listing.
+type Info struct {
+	Key          string    `json:"key" yaml:"key"`
+	Chart        string    `json:"chart" yaml:"chart"`
+	ChartName    string    `json:"chartName,omitempty" yaml:"chartName,omitempty"`
+	ChartVersion string    `json:"chartVersion,omitempty" yaml:"chartVersion,omitempty"`
+	Created      time.Time `json:"created" yaml:"created"`
+	Size         int64     `json:"size" yaml:"size"`
+}
+
+// Cache is a directory of analysis results.
+type Cache struct {
+	fs  afero.Fs
+	dir string
+}
+
+// New returns a cache stored in dir on fs.
+func New(fs afero.Fs, dir string) *Cache {
+	return &Cache{fs: fs, dir: dir}
+}
+
+// Dir returns the directory the cache is stored in.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// DefaultDir returns the cache directory: IRR_CACHE_DIR if set, else irr/analysis below the user
+// cache directory (e.g. ~/.cache/irr/analysis on Linux).
+func DefaultDir() (string, error) {
+	if dir := os.Getenv(DirEnvVar); dir != "" {
+		return dir, nil
+	}
+	userCache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine the user cache directory (set %s): %w", DirEnvVar, err)
+	}
+	return filepath.Join(userCache, "irr", "analysis"), nil
+}
+
+// ChartDigest returns the SHA-256 digest of a chart: of the archive for a packaged chart, or of
+// the relative paths and contents of all files for a chart directory.
+func ChartDigest(fs afero.Fs, chartPath string) (string, error) {
+	info, err := fs.Stat(chartPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read chart %s: %w", chartPath, err)
+	}
+	hash := sha256.New()
+	if !info.IsDir() {
+		if err := hashFile(fs, chartPath, hash); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+	err = afero.Walk(fs, chartPath, func(path string, fileInfo os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if !fileInfo.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(chartPath, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path of %s: %w", path, err)
+		}
+		// The length prefix keeps file boundaries unambiguous
+		if _, err := fmt.Fprintf(hash, "%s\x00%d\x00", filepath.ToSlash(rel), fileInfo.Size()); err != nil {
+			return fmt.Errorf("failed to hash %s: %w", path, err)
+		}
+		return hashFile(fs, path, hash)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash chart directory %s: %w", chartPath, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// FileDigest returns the SHA-256 digest of a file's contents, read without holding them in memory.
+func FileDigest(fs afero.Fs, path string) (string, error) {
+	hash := sha256.New()
+	if err := hashFile(fs, path, hash); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// hashFile writes the contents of a file to hash
+func hashFile(fs afero.Fs, path string, hash io.Writer) error {
+	file, err := fs.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() {
+		_ = file.Close() //nolint:errcheck // read-only file
+	}()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// Key derives a cache key from a chart digest and the inputs that affect its analysis, such as
+// values and the analysis mode. The order of inputs matters.
+func Key(chartDigest string, inputs ...string) string {
+	hash := sha256.New()
+	for _, part := range append([]string{chartDigest}, inputs...) {
+		_, _ = fmt.Fprintf(hash, "%d\x00%s\x00", len(part), part) //nolint:errcheck // hash writes do not fail
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Get returns the entry with the given key. It reports false, without error, when there is none.
+func (c *Cache) Get(key string) (*Entry, bool, error) {
+	data, err := afero.ReadFile(c.fs, c.entryPath(key))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to read cache entry %s: %w", key, err)
+	}
+	entry := &Entry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, false, fmt.Errorf("failed to parse cache entry %s: %w", key, err)
+	}
+	if entry.Analysis == nil {
+		entry.Analysis = analysis.NewChartAnalysis()
+	}
+	return entry, true, nil
+}
+
+// Put stores an entry under its key, setting Created if it is unset. The entry is written to a
+// temporary file and renamed, so concurrent runs never read a partial entry.
+func (c *Cache) Put(entry *Entry) error {
+	if entry.Key == "" {
+		return errors.New("cache entry has no key")
+	}
+	if entry.Created.IsZero() {
+		entry.Created = time.Now().UTC()
+	}
+	if err := c.fs.MkdirAll(c.dir, fileutil.ReadWriteExecuteUserReadGroup); err != nil {
+		return fmt.Errorf("failed to create cache directory %s: %w", c.dir, err)
+	}
+	tmp, err := afero.TempFile(c.fs, c.dir, entry.Key+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	// Entries hold the merged values, so they are encoded straight to the file rather than to a
+	// copy in memory
+	writer := bufio.NewWriter(tmp)
+	if err := json.NewEncoder(writer).Encode(entry); err != nil {
+		_ = tmp.Close()             //nolint:errcheck // the encoding error is reported
+		_ = c.fs.Remove(tmp.Name()) //nolint:errcheck // best-effort cleanup
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	flushErr := writer.Flush()
+	closeErr := tmp.Close()
+	if err := errors.Join(flushErr, closeErr); err != nil {
+		_ = c.fs.Remove(tmp.Name()) //nolint:errcheck // best-effort cleanup
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := c.fs.Rename(tmp.Name(), c.entryPath(entry.Key)); err != nil {
+		_ = c.fs.Remove(tmp.Name()) //nolint:errcheck // best-effort cleanup
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// List returns the entries of the cache, newest first. Unreadable entries are listed with their
+// key and size only.
+func (c *Cache) List() ([]Info, error) {
+	files, err := afero.ReadDir(c.fs, c.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cache directory %s: %w", c.dir, err)
+	}
+	var infos []Info
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != entryExt {
+			continue
+		}
+		info := Info{Key: strings.TrimSuffix(file.Name(), entryExt), Size: file.Size(), Created: file.ModTime().UTC()}
+		if entry, ok, err := c.Get(info.Key); err == nil && ok {
+			info.Chart = entry.Chart
+			info.ChartName = entry.ChartName
+			info.ChartVersion = entry.ChartVersion
+			info.Created = entry.Created
+		}
+		infos = append(infos, info)
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].Created.After(infos[j].Created)
+	})
+	return infos, nil
+}
+
+// Clear removes all entries of the cache and returns how many were removed.
+func (c *Cache) Clear() (int, error) {
+	files, err := afero.ReadDir(c.fs, c.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read cache directory %s: %w", c.dir, err)
+	}
+	removed := 0
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		if err := c.fs.Remove(filepath.Join(c.dir, file.Name())); err != nil {
+			return removed, fmt.Errorf("failed to remove cache entry %s: %w", file.Name(), err)
+		}
+		if filepath.Ext(file.Name()) == entryExt {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// entryPath returns the file an entry is stored in
+func (c *Cache) entryPath(key string) string {
+	return filepath.Join(c.dir, key+entryExt)
+}
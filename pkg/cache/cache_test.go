@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChartDigest(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/charts/web/Chart.yaml", []byte("name: web\nversion: 1.0.0\n"), 0o600))
+	require.NoError(t, afero.WriteFile(fs, "/charts/web/values.yaml", []byte("image: nginx:1.25\n"), 0o600))
+	require.NoError(t, afero.WriteFile(fs, "/charts/web-1.0.0.tgz", []byte("archive"), 0o600))
+
+	digest, err := ChartDigest(fs, "/charts/web")
+	require.NoError(t, err)
+	assert.Len(t, digest, 64)
+	again, err := ChartDigest(fs, "/charts/web")
+	require.NoError(t, err)
+	assert.Equal(t, digest, again)
+
+	require.NoError(t, afero.WriteFile(fs, "/charts/web/values.yaml", []byte("image: nginx:1.26\n"), 0o600))
+	changed, err := ChartDigest(fs, "/charts/web")
+	require.NoError(t, err)
+	assert.NotEqual(t, digest, changed, "a changed file changes the digest")
+
+	require.NoError(t, fs.Rename("/charts/web/values.yaml", "/charts/web/other.yaml"))
+	renamed, err := ChartDigest(fs, "/charts/web")
+	require.NoError(t, err)
+	assert.NotEqual(t, changed, renamed, "a renamed file changes the digest")
+
+	archive, err := ChartDigest(fs, "/charts/web-1.0.0.tgz")
+	require.NoError(t, err)
+	assert.Equal(t, "0eb3e36bfb24dcd9bb1d1bece1531216b59539a8fde17ee80224af0653c92aa3", archive, "a chart archive digest is its sha256sum")
+
+	_, err = ChartDigest(fs, "/charts/missing")
+	assert.ErrorContains(t, err, "failed to read chart")
+}
+
+func TestFileDigest(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/values.yaml", []byte("image: nginx:1.25\n"), 0o600))
+
+	digest, err := FileDigest(fs, "/values.yaml")
+	require.NoError(t, err)
+	assert.Len(t, digest, 64)
+	require.NoError(t, afero.WriteFile(fs, "/values.yaml", []byte("image: nginx:1.26\n"), 0o600))
+	changed, err := FileDigest(fs, "/values.yaml")
+	require.NoError(t, err)
+	assert.NotEqual(t, digest, changed)
+
+	_, err = FileDigest(fs, "/missing.yaml")
+	assert.ErrorContains(t, err, "failed to open")
+}
+
+func TestKey(t *testing.T) {
+	key := Key("digest", "context-aware", "values.yaml")
+	assert.Len(t, key, 64)
+	assert.Equal(t, key, Key("digest", "context-aware", "values.yaml"))
+	assert.NotEqual(t, key, Key("digest", "legacy", "values.yaml"))
+	assert.NotEqual(t, Key("digest", "ab", "c"), Key("digest", "a", "bc"), "input boundaries are part of the key")
+}
+
+func TestCache(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	c := New(fs, "/cache/irr")
+
+	_, ok, err := c.Get("missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	infos, err := c.List()
+	require.NoError(t, err)
+	assert.Empty(t, infos, "a cache that was never written is empty")
+
+	older := &Entry{
+		Key: "older", Chart: "charts/api", ChartName: "api", ChartVersion: "2.0.0",
+		Created: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, c.Put(older))
+	entry := &Entry{
+		Key: "web", Chart: "charts/web", ChartName: "web", ChartVersion: "1.0.0",
+		Analysis: &analysis.ChartAnalysis{ImagePatterns: []analysis.ImagePattern{{
+			Path: "image", Type: analysis.PatternTypeMap, Count: 1,
+			Structure: map[string]interface{}{"repository": "nginx", "tag": "1.25"},
+		}}},
+		Values: map[string]interface{}{"image": map[string]interface{}{"repository": "nginx"}, "replicaCount": float64(2)},
+	}
+	require.NoError(t, c.Put(entry))
+	assert.False(t, entry.Created.IsZero())
+
+	cached, ok, err := c.Get("web")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, entry.Analysis, cached.Analysis)
+	assert.Equal(t, entry.Values, cached.Values)
+	assert.Equal(t, "charts/web", cached.Chart)
+
+	cached, ok, err = c.Get("older")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.NotNil(t, cached.Analysis, "an entry without patterns has an empty analysis")
+
+	infos, err = c.List()
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	assert.Equal(t, "web", infos[0].Key)
+	assert.Equal(t, "1.0.0", infos[0].ChartVersion)
+	assert.Positive(t, infos[0].Size)
+	assert.Equal(t, "older", infos[1].Key)
+
+	removed, err := c.Clear()
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	_, ok, err = c.Get("web")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestDefaultDir(t *testing.T) {
+	t.Setenv(DirEnvVar, "/tmp/irr-cache")
+	dir, err := DefaultDir()
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/irr-cache", dir)
+
+	t.Setenv(DirEnvVar, "")
+	t.Setenv("XDG_CACHE_HOME", "/home/user/.cache")
+	dir, err = DefaultDir()
+	require.NoError(t, err)
+	assert.Contains(t, dir, "irr")
+}
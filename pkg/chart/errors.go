@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
 	"github.com/lucas-albers-lz4/irr/pkg/strategy"
 )
 
@@ -38,6 +39,9 @@ func (e *UnsupportedStructureError) Is(target error) bool {
 	return target == ErrStrictValidationFailed
 }
 
+// ExitCode returns ExitUnsupportedStructure, implementing exitcodes.Coder.
+func (e *UnsupportedStructureError) ExitCode() int { return exitcodes.ExitUnsupportedStructure }
+
 // ThresholdNotMetError indicates that the percentage of successfully processed
 // images did not meet the required threshold.
 type ThresholdNotMetError struct {
@@ -54,6 +58,9 @@ func (e *ThresholdNotMetError) Is(target error) bool {
 	return target == strategy.ErrThresholdExceeded
 }
 
+// ExitCode returns ExitThresholdError, implementing exitcodes.Coder.
+func (e *ThresholdNotMetError) ExitCode() int { return exitcodes.ExitThresholdError }
+
 // ParsingError represents an error encountered during chart parsing.
 type ParsingError struct {
 	FilePath string
@@ -74,6 +81,9 @@ func (e *ParsingError) Unwrap() error {
 	return e.Err
 }
 
+// ExitCode returns ExitChartParsingError, implementing exitcodes.Coder.
+func (e *ParsingError) ExitCode() int { return exitcodes.ExitChartParsingError }
+
 // ImageProcessingError indicates an error occurred during image detection or processing.
 type ImageProcessingError struct {
 	Path []string // Path within the values where the error occurred
@@ -91,3 +101,6 @@ func (e *ImageProcessingError) Error() string {
 func (e *ImageProcessingError) Unwrap() error {
 	return e.Err
 }
+
+// ExitCode returns ExitImageProcessingError, implementing exitcodes.Coder.
+func (e *ImageProcessingError) ExitCode() int { return exitcodes.ExitImageProcessingError }
@@ -1,13 +1,18 @@
 package chart
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"reflect"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"gopkg.in/yaml.v3"
 	"helm.sh/helm/v3/pkg/action"
@@ -15,15 +20,21 @@ import (
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
 
 	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
 	image "github.com/lucas-albers-lz4/irr/pkg/image"
 	"github.com/lucas-albers-lz4/irr/pkg/keys"
 	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/metrics"
 	"github.com/lucas-albers-lz4/irr/pkg/override"
 	"github.com/lucas-albers-lz4/irr/pkg/registry"
 	"github.com/lucas-albers-lz4/irr/pkg/rules"
 	"github.com/lucas-albers-lz4/irr/pkg/strategy"
+	"github.com/lucas-albers-lz4/irr/pkg/strictness"
+	"github.com/lucas-albers-lz4/irr/pkg/timing"
 )
 
 // Constants
@@ -64,6 +75,9 @@ func (e *LoadingError) Error() string {
 }
 func (e *LoadingError) Unwrap() error { return e.Err }
 
+// ExitCode returns ExitChartLoadFailed, implementing exitcodes.Coder.
+func (e *LoadingError) ExitCode() int { return exitcodes.ExitChartLoadFailed }
+
 // ThresholdError represents errors related to the generator's threshold logic
 type ThresholdError struct {
 	Threshold   int
@@ -88,6 +102,9 @@ func (e *ThresholdError) Error() string {
 }
 func (e *ThresholdError) Unwrap() error { return e.Err }
 
+// ExitCode returns ExitThresholdError, implementing exitcodes.Coder.
+func (e *ThresholdError) ExitCode() int { return exitcodes.ExitThresholdError }
+
 // --- Generator Implementation ---
 
 // Package chart provides functionality for working with Helm charts, including
@@ -133,6 +150,7 @@ func (e *ThresholdError) Unwrap() error { return e.Err }
 // - Chart loading failures map to ExitChartParsingError (10)
 // - Image processing issues map to ExitImageProcessingError (11)
 // - Unsupported structures in strict mode map to ExitUnsupportedStructure (12)
+// - Other strict mode policy violations map to their condition's exit code (see pkg/strictness)
 // - Threshold failures map to ExitThresholdError (13)
 // - ExitGeneralRuntimeError (20) for system/runtime errors
 type Generator struct {
@@ -143,10 +161,21 @@ type Generator struct {
 	pathStrategy      strategy.PathStrategy
 	mappings          *registry.Mappings
 	strict            bool
+	policy            strictness.Policy // Action for each strict mode condition
 	threshold         int
-	loader            Loader                  // Use Loader from this package
-	rulesEnabled      bool                    // Whether to apply rules
-	rulesRegistry     rules.RegistryInterface // Use the interface type here
+	loader            Loader                         // Use Loader from this package
+	rulesEnabled      bool                           // Whether to apply rules
+	rulesRegistry     rules.RegistryInterface        // Use the interface type here
+	defaultTag        string                         // Tag for images with neither tag nor digest
+	bitnamiCompat     bool                           // Whether to add the Bitnami insecure images bypass when registries change
+	targetFlavor      strategy.TargetFlavor          // Provider whose repository naming rules generated paths must follow
+	baseValues        map[string]interface{}         // Values set with SetBaseValues, used instead of the chart's
+	sourceValues      map[string]interface{}         // Values that sequences are copied from when overridden
+	exceptions        []registry.RelocationException // Images that must never be relocated
+	registryOnly      bool                           // Whether to override only registries, keeping repository paths
+	digestResolver    DigestResolver                 // Pins images referenced by mutable tags to digests; nil leaves them unpinned
+	// Share of images that must be composed from global.imageRegistry to override only their repositories; 0 disables it
+	globalRegistryThreshold float64
 }
 
 // NewGenerator creates a new Generator with the provided configuration
@@ -174,6 +203,15 @@ func NewGenerator(
 		log.Debug("Generator initialized with nil mappings")
 	}
 
+	rulesRegistry := rules.NewRegistry()
+	// The Bitnami bypass is added by applyBitnamiCompat, and only when relocation changes registries
+	rulesRegistry.RemoveRule(rules.BitnamiSecurityBypassRuleName)
+
+	strictLevel := strictness.LevelOff
+	if strict {
+		strictLevel = strictness.LevelAll
+	}
+
 	return &Generator{
 		chartPath:         chartPath,
 		targetRegistry:    targetRegistry,
@@ -182,13 +220,98 @@ func NewGenerator(
 		pathStrategy:      pathStrategy,
 		mappings:          mappings,
 		strict:            strict,
+		policy:            strictLevel.Policy(),
 		threshold:         threshold,
 		loader:            chartLoader,
 		rulesEnabled:      rulesEnabled,
-		rulesRegistry:     rules.NewRegistry(), // Initialize the rules registry
+		rulesRegistry:     rulesRegistry,
+		bitnamiCompat:     true,
 	}
 }
 
+// SetDefaultTag sets the tag used for images that have neither a tag nor a digest
+// (and no chart AppVersion to fall back to).
+func (g *Generator) SetDefaultTag(tag string) {
+	g.defaultTag = tag
+}
+
+// SetBitnamiCompat sets whether global.security.allowInsecureImages=true is added for Bitnami charts
+// whose images are relocated to another registry. It is enabled by default.
+func (g *Generator) SetBitnamiCompat(enabled bool) {
+	g.bitnamiCompat = enabled
+}
+
+// SetStrictPolicy sets the action for each strict mode condition found in the chart. It replaces the
+// policy implied by the strict argument of NewGenerator (LevelAll when true, LevelOff otherwise).
+func (g *Generator) SetStrictPolicy(policy strictness.Policy) {
+	g.policy = policy
+}
+
+// SetTargetFlavor sets the target registry provider whose naming rules generated paths are checked against.
+func (g *Generator) SetTargetFlavor(flavor strategy.TargetFlavor) {
+	g.targetFlavor = flavor
+}
+
+// SetBaseValues sets the values that analysis ran on, such as a release's computed values.
+// When a sequence element is overridden, the rest of the sequence is copied from these values so that
+// Helm, which replaces sequences as a whole, keeps the other elements. Defaults to the chart's values.
+func (g *Generator) SetBaseValues(values map[string]interface{}) {
+	g.baseValues = values
+}
+
+// SetExceptions sets the relocation exceptions: the images they cover are left unchanged and reported
+// in the Excluded list of the generated overrides.
+func (g *Generator) SetExceptions(exceptions []registry.RelocationException) {
+	g.exceptions = exceptions
+}
+
+// SetRegistryOnly sets whether only the registry of each image is overridden. The chart's repository
+// paths are kept, for targets that mirror upstream paths exactly, such as pull-through caches: map
+// values get just their registry key, and the path strategy and target flavor are not applied.
+// Images without a registry key of their own are overridden with their original repository path.
+func (g *Generator) SetRegistryOnly(enabled bool) {
+	g.registryOnly = enabled
+}
+
+// applyExceptions splits patterns into the images to relocate and those excluded by a relocation exception
+func (g *Generator) applyExceptions(patterns []analysis.ImagePattern) ([]analysis.ImagePattern, []override.Exclusion) {
+	if len(g.exceptions) == 0 {
+		return patterns, nil
+	}
+	remaining := make([]analysis.ImagePattern, 0, len(patterns))
+	var excluded []override.Exclusion
+	for i := range patterns {
+		pattern := &patterns[i]
+		imgRef, err := g.processImagePattern(pattern)
+		imageName, original := "", pattern.Value
+		if err == nil && imgRef != nil {
+			imageName = imgRef.Registry + "/" + imgRef.Repository
+			if imgRef.Original != "" {
+				original = imgRef.Original
+			}
+		}
+		exception, ok := registry.MatchException(g.exceptions, pattern.Path, imageName)
+		if !ok {
+			remaining = append(remaining, *pattern)
+			continue
+		}
+		log.Info("Image excluded by relocation exception", "path", pattern.Path, "image", original, "exception", exception.String(), "reason", exception.Reason)
+		excluded = append(excluded, override.Exclusion{Path: pattern.Path, Image: original, Exception: exception.String(), Reason: exception.Reason})
+	}
+	sort.SliceStable(excluded, func(i, j int) bool { return excluded[i].Path < excluded[j].Path })
+	return remaining, excluded
+}
+
+// chartValues returns the chart's default values merged with those of its subcharts
+func chartValues(loadedChart *chart.Chart) map[string]interface{} {
+	values, err := chartutil.CoalesceValues(loadedChart, map[string]interface{}{})
+	if err != nil {
+		log.Warn("Failed to merge subchart values, using the chart's own values for sequences", "error", err)
+		return loadedChart.Values
+	}
+	return values
+}
+
 // findUnsupportedPatterns identifies template expressions and other unsupported structures
 // Reverting to original type signature based on linter feedback loop
 func (g *Generator) findUnsupportedPatterns(patterns []analysis.ImagePattern) []override.UnsupportedStructure {
@@ -200,8 +323,8 @@ func (g *Generator) findUnsupportedPatterns(patterns []analysis.ImagePattern) []
 		// Corrected syntax: No escaping needed inside the string literal.
 		if strings.Contains(p.Value, "{{") && strings.Contains(p.Value, "}}") {
 			unsupported = append(unsupported, override.UnsupportedStructure{
-				// Path comes from p.Path (string), split by '.'
-				Path: strings.Split(p.Path, "."),
+				// Path comes from p.Path (string), split at its unescaped dots
+				Path: analysis.SplitPath(p.Path),
 				// Type indicates the reason for being unsupported
 				Type: "HelmTemplate",
 			})
@@ -325,6 +448,7 @@ func (g *Generator) determineTargetPathAndRegistry(imgRef *image.Reference, _ *a
 			log.Debug("No mapping found for source registry, using CLI target",
 				"sourceRegistry", imgRef.Registry,
 				"cliTargetRegistry", g.targetRegistry)
+			metrics.Inc(metrics.UnmappedRegistries, "registry", imgRef.Registry)
 
 			// Ensure we use the CLI-provided target registry when no mapping is found
 			effectiveTargetRegistry = g.targetRegistry
@@ -359,6 +483,41 @@ func (g *Generator) determineTargetPathAndRegistry(imgRef *image.Reference, _ *a
 	return effectiveTargetRegistry, newRepoPath, nil
 }
 
+// targetOf returns the target registry and repository path of the image imgRef found at pattern,
+// along with the full repository path checked against the target flavor (empty in registry-only
+// mode, where the flavor does not apply).
+func (g *Generator) targetOf(pattern *analysis.ImagePattern, imgRef *image.Reference) (targetRegistry, newPath, targetRepoPath string, err error) {
+	if g.registryOnly {
+		targetRegistry, newPath = g.registryOnlyTarget(imgRef)
+		return targetRegistry, newPath, "", nil
+	}
+	targetRegistry, newPath, err = g.determineTargetPathAndRegistry(imgRef, pattern)
+	if err != nil {
+		return "", "", "", fmt.Errorf("error determining target path for %s: %w", pattern.Path, err)
+	}
+	targetRegistry, newPath, targetRepoPath, err = g.applyTargetFlavor(targetRegistry, newPath)
+	if err != nil {
+		log.Debug("Generated path does not meet target flavor rules", "path", pattern.Path, "image", imgRef.Original)
+		return "", "", "", fmt.Errorf("path %s: %w", pattern.Path, err)
+	}
+	return targetRegistry, newPath, targetRepoPath, nil
+}
+
+// registryOnlyTarget returns the target registry of an image in registry-only mode, with any path
+// prefix of a mapping target kept (e.g. harbor.example.com/dockerhub-proxy), and its unchanged
+// repository path.
+func (g *Generator) registryOnlyTarget(imgRef *image.Reference) (targetRegistry, repository string) {
+	targetRegistry = g.mappings.GetTargetRegistry(imgRef.Registry)
+	if targetRegistry == "" {
+		if g.mappings != nil {
+			metrics.Inc(metrics.UnmappedRegistries, "registry", imgRef.Registry)
+		}
+		targetRegistry = g.targetRegistry
+	}
+	log.Debug("Registry-only target", "sourceRegistry", imgRef.Registry, "targetRegistry", targetRegistry, "repository", imgRef.Repository)
+	return targetRegistry, imgRef.Repository
+}
+
 // processImage handles the processing of a single eligible image pattern.
 // NOTE: This function is currently unused and commented out to satisfy the linter.
 // It's kept for reference in case functionality needs to be restored in the future.
@@ -382,7 +541,7 @@ func (g *Generator) processImage(pattern *analysis.ImagePattern, overrides map[s
 	if err != nil {
 		log.Warn("Failed to parse image pattern", "path", pattern.Path, "value", pattern.Value, "error", err)
 		return false, &override.UnsupportedStructure{
-			Path: strings.Split(pattern.Path, "."),
+			Path: analysis.SplitPath(pattern.Path),
 			Type: "InvalidImageFormat",
 		}, err
 	}
@@ -405,7 +564,10 @@ func (g *Generator) processImage(pattern *analysis.ImagePattern, overrides map[s
 	log.Debug("Determined target", "path", pattern.Path, "targetRegistry", targetReg, "newPath", newPath)
 
 	// Create the override structure (map)
-	overrideValue := g.createOverride(pattern, imgRef, targetReg, newPath)
+	overrideValue, err := g.createOverride(pattern, imgRef, targetReg, newPath)
+	if err != nil {
+		return false, nil, fmt.Errorf("error creating override for %s: %w", pattern.Path, err)
+	}
 	log.Debug("Created override value structure", "path", pattern.Path, "overrideValue", overrideValue)
 
 	// *** Add explicit type check ***
@@ -550,10 +712,181 @@ func (g *Generator) applyRulesIfNeeded(loadedChart *chart.Chart, result *overrid
 	return nil
 }
 
+// applyTargetFlavor lowercases the target path prefix and generated path where the target flavor
+// requires it, then validates the full repository path under the registry host. It returns the
+// adjusted target registry and path along with the full repository path.
+func (g *Generator) applyTargetFlavor(targetRegistry, newPath string) (adjustedRegistry, adjustedPath, repoPath string, err error) {
+	if g.targetFlavor == "" || g.targetFlavor == strategy.FlavorGeneric {
+		return targetRegistry, newPath, newPath, nil
+	}
+
+	host, prefix, _ := strings.Cut(targetRegistry, "/")
+	if lower, changed := g.targetFlavor.AdjustRepositoryPath(prefix); changed {
+		log.Warn("Lowercased target path prefix for target flavor", "flavor", g.targetFlavor, "from", prefix, "to", lower)
+		prefix = lower
+		targetRegistry = host + "/" + prefix
+	}
+	if lower, changed := g.targetFlavor.AdjustRepositoryPath(newPath); changed {
+		log.Warn("Lowercased generated repository path for target flavor", "flavor", g.targetFlavor, "from", newPath, "to", lower)
+		newPath = lower
+	}
+
+	repoPath = newPath
+	if prefix != "" {
+		repoPath = prefix + "/" + newPath
+	}
+	if err = g.targetFlavor.ValidateRepositoryPath(host, repoPath); err != nil {
+		return "", "", "", err
+	}
+	return targetRegistry, newPath, repoPath, nil
+}
+
+// targetFlavorWarnings lists the repositories or projects that the target flavor requires to exist
+// before the relocated images can be pushed.
+func (g *Generator) targetFlavorWarnings(repoPaths []string) []override.Warning {
+	namesByKind := make(map[string][]string)
+	for _, repoPath := range repoPaths {
+		kind, name := g.targetFlavor.RequiredResource(repoPath)
+		if kind != "" && !slices.Contains(namesByKind[kind], name) {
+			namesByKind[kind] = append(namesByKind[kind], name)
+		}
+	}
+
+	var warnings []override.Warning
+	for _, kind := range slices.Sorted(maps.Keys(namesByKind)) {
+		names := namesByKind[kind]
+		sort.Strings(names)
+		warning := override.Warning{
+			Code:    "target-" + kind + "-required",
+			Message: fmt.Sprintf("target flavor %s does not create a %s on push; make sure these exist before pushing images: %s", g.targetFlavor, kind, strings.Join(names, ", ")),
+		}
+		log.Warn(warning.Message)
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// findPolicyConditions returns the values paths at which each strict mode condition found in the chart
+// occurs, and the conflicts of the registry mappings. unsupported are the structures reported by
+// findUnsupportedPatterns.
+func (g *Generator) findPolicyConditions(patterns []analysis.ImagePattern, unsupported []override.UnsupportedStructure) map[strictness.Condition][]string {
+	found := make(map[strictness.Condition][]string)
+	templatePaths := make(map[string]bool, len(unsupported))
+	for _, us := range unsupported {
+		path := strings.Join(us.Path, ".")
+		templatePaths[path] = true
+		found[strictness.TemplateExpressions] = append(found[strictness.TemplateExpressions], path)
+	}
+
+	for i := range patterns {
+		pattern := &patterns[i]
+		if templatePaths[pattern.Path] {
+			continue
+		}
+		if pattern.Type == analysis.PatternTypeMap && pattern.Structure != nil {
+			if repository, _ := pattern.Structure[keys.Repository].(string); strings.TrimSpace(repository) == "" {
+				found[strictness.EmptyRepositories] = append(found[strictness.EmptyRepositories], pattern.Path)
+				continue
+			}
+		}
+		imgRef, err := image.ParseImageReference(pattern.Value)
+		switch {
+		case err != nil:
+			found[strictness.UnparseableImages] = append(found[strictness.UnparseableImages], pattern.Path)
+		case imgRef.Repository == "":
+			found[strictness.EmptyRepositories] = append(found[strictness.EmptyRepositories], pattern.Path)
+		}
+	}
+	for _, conflict := range registry.FindMappingConflicts(g.mappings, g.sourceRegistries, g.targetRegistry) {
+		found[strictness.MappingConflicts] = append(found[strictness.MappingConflicts], conflict.String())
+	}
+	return found
+}
+
+// applyStrictPolicy acts on the conditions found in the chart: conditions whose action is ActionWarn
+// become warnings, and the first condition whose action is ActionError is returned as a
+// *strictness.ViolationError.
+func (g *Generator) applyStrictPolicy(found map[strictness.Condition][]string) ([]override.Warning, error) {
+	var warnings []override.Warning
+	for _, condition := range strictness.Conditions() {
+		paths := found[condition]
+		if len(paths) == 0 {
+			continue
+		}
+		switch g.policy.ActionFor(condition) {
+		case strictness.ActionError:
+			return warnings, &strictness.ViolationError{Condition: condition, Items: paths}
+		case strictness.ActionWarn:
+			for _, path := range paths {
+				warning := override.Warning{
+					Code:    condition.WarningCode(),
+					Path:    path,
+					Message: fmt.Sprintf("strict mode: %s at %s", condition, path),
+				}
+				if condition == strictness.MappingConflicts {
+					warning.Path, warning.Message = "", "registry mapping conflict: "+path
+				}
+				log.Warn(warning.Message)
+				warnings = append(warnings, warning)
+			}
+		default:
+			log.Debug("Ignoring strict mode condition", "condition", condition, "paths", paths)
+		}
+	}
+	return warnings, nil
+}
+
+// applyBitnamiCompat handles Bitnami charts, which refuse to render with images from registries other
+// than their own unless global.security.allowInsecureImages is true. When relocation changes the
+// registry of any image, the bypass is added to the overrides (unless disabled with --bitnami-compat=false
+// or --disable-rules) and a warning is recorded in the result either way.
+func (g *Generator) applyBitnamiCompat(loadedChart *chart.Chart, result *override.File, processedDetails []ProcessedImageDetail) {
+	bitnamiChart, isBitnami := rules.FindBitnamiChart(loadedChart)
+	if !isBitnami || !registryChanged(processedDetails) {
+		return
+	}
+	bypassPath := override.ParsePath(rules.BitnamiAllowInsecureImagesPath)
+	if existing, err := override.GetValueAtPath(g.sourceValues, bypassPath); err == nil && existing == true {
+		log.Debug("Bitnami insecure images bypass already enabled in the chart values", "chart", bitnamiChart)
+		return
+	}
+
+	warning := override.Warning{
+		Code:  "bitnami-insecure-images",
+		Chart: bitnamiChart,
+		Path:  rules.BitnamiAllowInsecureImagesPath,
+	}
+	if g.rulesEnabled && g.bitnamiCompat {
+		if err := override.SetValueAtPath(result.Values, bypassPath, true); err != nil {
+			log.Warn("Failed to add Bitnami insecure images bypass", "chart", bitnamiChart, "error", err)
+			return
+		}
+		warning.Message = fmt.Sprintf("Bitnami chart %s rejects images from other registries; added %s=true so the relocated images are accepted",
+			bitnamiChart, rules.BitnamiAllowInsecureImagesPath)
+	} else {
+		warning.Message = fmt.Sprintf("Bitnami chart %s rejects images from other registries unless %s=true is set; enable --bitnami-compat or set it yourself",
+			bitnamiChart, rules.BitnamiAllowInsecureImagesPath)
+	}
+	log.Warn(warning.Message, "chart", bitnamiChart)
+	result.Warnings = append(result.Warnings, warning)
+}
+
+// registryChanged reports whether any processed image was moved to a different registry host.
+func registryChanged(processedDetails []ProcessedImageDetail) bool {
+	for _, detail := range processedDetails {
+		targetHost, _, _ := strings.Cut(detail.FinalTargetRegistry, "/")
+		if image.NormalizeRegistry(targetHost) != image.NormalizeRegistry(detail.OriginalRegistry) {
+			return true
+		}
+	}
+	return false
+}
+
 // ProcessedImageDetail struct definition
 type ProcessedImageDetail struct {
 	Path                string
 	OriginalImage       string
+	OriginalRegistry    string
 	FinalTargetRegistry string // The actual registry part used for this image after mappings/strategy
 	FinalRepositoryPath string // The actual repository path used
 }
@@ -564,34 +897,38 @@ func (g *Generator) Generate(loadedChart *chart.Chart, analysisResult *analysis.
 	if analysisResult == nil || loadedChart == nil {
 		return nil, fmt.Errorf("cannot generate overrides without analysis results (analysisResult is nil)")
 	}
+	defer timing.Start(timing.SpanGeneration, g.chartPath)()
 
 	actualOverrides := make(map[string]interface{}) // This will populate resultFile.Values
+	g.sourceValues = g.baseValues
+	if g.sourceValues == nil {
+		g.sourceValues = chartValues(loadedChart)
+	}
 	var processingErrors []error
 	var unsupportedStructures []override.UnsupportedStructure // Collect these if strict mode is off but found
 	processedCount := 0
 
-	eligibleImages := g.filterEligibleImages(analysisResult.ImagePatterns)
-	log.Info("Filtering complete", "total_images", len(analysisResult.ImagePatterns), "eligible_images", len(eligibleImages))
+	metrics.Add(metrics.ImagesAnalyzed, len(analysisResult.ImagePatterns))
+	// Images covered by a relocation exception are left alone: they are neither relocated nor checked by the policy
+	patterns, excluded := g.applyExceptions(analysisResult.ImagePatterns)
+	eligibleImages := g.filterEligibleImages(patterns)
+	log.Info("Filtering complete", "total_images", len(analysisResult.ImagePatterns), "excluded_images", len(excluded), "eligible_images", len(eligibleImages))
 
-	if g.strict {
-		strictUnsupported := g.findUnsupportedPatterns(analysisResult.ImagePatterns)
-		if len(strictUnsupported) > 0 {
-			errMsg := "strict mode violation: unsupported structures found:\n"
-			for _, us := range strictUnsupported {
-				errMsg += fmt.Sprintf("  - Path: %s, Type: %s\n", strings.Join(us.Path, "."), us.Type)
-			}
-			log.Error(errMsg)
-			// Always return an empty slice, not nil
-			return &override.File{Unsupported: append([]override.UnsupportedStructure{}, strictUnsupported...), ChartPath: g.chartPath, ChartName: loadedChart.Name()}, fmt.Errorf("%s", errMsg)
-		}
-	} else {
-		unsupportedStructures = g.findUnsupportedPatterns(analysisResult.ImagePatterns)
-		if len(unsupportedStructures) > 0 {
-			log.Warn("Unsupported structures found (strict mode is off)", "count", len(unsupportedStructures))
-		}
+	unsupportedStructures = g.findUnsupportedPatterns(patterns)
+	policyWarnings, err := g.applyStrictPolicy(g.findPolicyConditions(patterns, unsupportedStructures))
+	if err != nil {
+		log.Error(err.Error())
+		// Always return an empty slice, not nil
+		return &override.File{Unsupported: append([]override.UnsupportedStructure{}, unsupportedStructures...), ChartPath: g.chartPath, ChartName: loadedChart.Name()}, err
+	}
+	if len(unsupportedStructures) > 0 {
+		log.Warn("Unsupported structures found (strict mode is off)", "count", len(unsupportedStructures))
 	}
 
 	var processedDetails []ProcessedImageDetail
+	var targetRepoPaths []string
+	var relocations []override.Relocation
+	var mutablePaths []string
 
 	for i := range eligibleImages {
 		pattern := &eligibleImages[i]
@@ -600,29 +937,35 @@ func (g *Generator) Generate(loadedChart *chart.Chart, analysisResult *analysis.
 		imgRef, err := g.processImagePattern(pattern)
 		if err != nil {
 			log.Warn("Failed to parse image reference during override generation", "path", pattern.Path, "value", pattern.Value, "error", err)
-			processingErrors = append(processingErrors, fmt.Errorf("path %s: %w", pattern.Path, err))
+			processingErrors = append(processingErrors, &PathError{Path: pattern.Path, Location: pattern.Location, Err: fmt.Errorf("path %s: %w", pattern.Path, err)})
 			continue
 		}
 		if imgRef == nil {
 			log.Warn("Nil image reference after parsing, skipping", "path", pattern.Path)
-			processingErrors = append(processingErrors, fmt.Errorf("path %s: nil image reference", pattern.Path))
+			processingErrors = append(processingErrors, &PathError{Path: pattern.Path, Location: pattern.Location, Err: fmt.Errorf("path %s: nil image reference", pattern.Path)})
 			continue
 		}
 
-		targetActualRegistry, newPath, err := g.determineTargetPathAndRegistry(imgRef, pattern)
+		mutable := g.checkImmutableReference(pattern, imgRef)
+
+		targetActualRegistry, newPath, targetRepoPath, err := g.targetOf(pattern, imgRef)
 		if err != nil {
-			log.Warn("Failed to determine target path and registry", "path", pattern.Path, "image", imgRef.Original, "error", err)
-			// Update error message to match test expectation
-			processingErrors = append(processingErrors, fmt.Errorf("error determining target path for %s: %w", pattern.Path, err))
+			log.Warn("Failed to determine target of image", "path", pattern.Path, "image", imgRef.Original, "error", err)
+			processingErrors = append(processingErrors, &PathError{Path: pattern.Path, Location: pattern.Location, Err: err})
 			continue
 		}
 		log.Debug("Determined target for override", "path", pattern.Path, "originalImage", imgRef.Original, "targetRegistry", targetActualRegistry, "newRepositoryPath", newPath)
 
-		overrideValue := g.createOverride(pattern, imgRef, targetActualRegistry, newPath)
+		overrideValue, err := g.createOverride(pattern, imgRef, targetActualRegistry, newPath)
+		if err != nil {
+			log.Warn("Failed to create override", "path", pattern.Path, "image", imgRef.Original, "error", err)
+			processingErrors = append(processingErrors, &PathError{Path: pattern.Path, Location: pattern.Location, Err: fmt.Errorf("creating override for path %s: %w", pattern.Path, err)})
+			continue
+		}
 
 		if err := g.setOverridePath(actualOverrides, pattern, overrideValue); err != nil {
 			log.Error("Failed to set override path", "path", pattern.Path, "error", err)
-			processingErrors = append(processingErrors, fmt.Errorf("setting override for path %s: %w", pattern.Path, err))
+			processingErrors = append(processingErrors, &PathError{Path: pattern.Path, Location: pattern.Location, Err: fmt.Errorf("setting override for path %s: %w", pattern.Path, err)})
 			continue
 		}
 		log.Info("Successfully processed image override",
@@ -632,13 +975,28 @@ func (g *Generator) Generate(loadedChart *chart.Chart, analysisResult *analysis.
 			"target_registry", targetActualRegistry)
 
 		processedCount++
+		if targetRepoPath != "" {
+			targetRepoPaths = append(targetRepoPaths, targetRepoPath)
+		}
 		processedDetails = append(processedDetails, ProcessedImageDetail{
 			Path:                pattern.Path,
 			OriginalImage:       imgRef.Original,
+			OriginalRegistry:    imgRef.Registry,
 			FinalTargetRegistry: targetActualRegistry,
 			FinalRepositoryPath: newPath,
 		})
+		relocations = append(relocations, relocationOf(pattern.Path, imgRef, targetActualRegistry, newPath))
+		if mutable {
+			mutablePaths = append(mutablePaths, pattern.Path)
+		}
+	}
+
+	mutableWarnings, err := g.applyStrictPolicy(map[strictness.Condition][]string{strictness.MutableReferences: mutablePaths})
+	if err != nil {
+		log.Error(err.Error())
+		return &override.File{Unsupported: append([]override.UnsupportedStructure{}, unsupportedStructures...), ChartPath: g.chartPath, ChartName: loadedChart.Name()}, err
 	}
+	policyWarnings = append(policyWarnings, mutableWarnings...)
 
 	successRate := 0.0
 	if len(eligibleImages) > 0 {
@@ -647,8 +1005,12 @@ func (g *Generator) Generate(loadedChart *chart.Chart, analysisResult *analysis.
 		successRate = PercentageMultiplier
 	}
 
+	metrics.Add(metrics.OverridesGenerated, processedCount)
 	log.Info("Image processing complete", "processed", processedCount, "eligible", len(eligibleImages), "success_rate", fmt.Sprintf("%.2f%%", successRate))
 
+	// Relocations are listed by value path so that the metadata does not depend on detection order
+	sort.SliceStable(relocations, func(i, j int) bool { return relocations[i].Path < relocations[j].Path })
+
 	// Always return an empty slice, not nil, for Unsupported
 	resultFile := &override.File{
 		Values:         actualOverrides,
@@ -658,9 +1020,13 @@ func (g *Generator) Generate(loadedChart *chart.Chart, analysisResult *analysis.
 		ProcessedCount: processedCount,
 		ChartPath:      g.chartPath,
 		ChartName:      loadedChart.Name(),
+		Warnings:       append(policyWarnings, g.targetFlavorWarnings(targetRepoPaths)...),
+		Relocations:    relocations,
+		Excluded:       excluded,
 	}
 
 	if processedCount > 0 {
+		g.applyGlobalRegistryConvention(loadedChart, eligibleImages, resultFile.Values, processedDetails)
 		g.ensureGlobalImageRegistry(resultFile.Values, analysisResult.GlobalPatterns, processedDetails)
 	} else {
 		log.Debug("No images processed, skipping ensureGlobalImageRegistry")
@@ -681,6 +1047,7 @@ func (g *Generator) Generate(loadedChart *chart.Chart, analysisResult *analysis.
 			log.Error("Error applying rules", "error", err)
 		}
 	}
+	g.applyBitnamiCompat(loadedChart, resultFile, processedDetails)
 
 	log.Debug("Generator.Generate: Final override map keys before return", "keys", mapKeys(resultFile.Values), "map_addr", fmt.Sprintf("%p", resultFile.Values))
 	// Compare log.CurrentLevel() (which returns slog.Level from the custom package, which is an alias for std slog.Level)
@@ -697,8 +1064,19 @@ func (g *Generator) Generate(loadedChart *chart.Chart, analysisResult *analysis.
 	return resultFile, nil
 }
 
+// relocationOf records the relocation of the image imgRef, found at path, to newPath in targetRegistry.
+func relocationOf(path string, imgRef *image.Reference, targetRegistry, newPath string) override.Relocation {
+	original := imgRef.Original
+	if original == "" {
+		original = imgRef.String()
+	}
+	relocated := &image.Reference{Registry: targetRegistry, Repository: newPath, Tag: imgRef.Tag, Digest: imgRef.Digest}
+	return override.Relocation{Path: path, Original: original, Relocated: relocated.String()}
+}
+
 // ensureGlobalImageRegistry sets the global.imageRegistry field in the overrides.
-// It now uses details from processed images to determine the most appropriate global registry.
+// It now uses details from processed images to determine the most appropriate global registry,
+// and leaves it unset when the images were relocated to more than one target registry.
 func (g *Generator) ensureGlobalImageRegistry(overrides map[string]interface{}, _ []analysis.GlobalPattern, processedDetails []ProcessedImageDetail) {
 	log.Debug("Enter ensureGlobalImageRegistry")
 	defer log.Debug("Exit ensureGlobalImageRegistry")
@@ -725,9 +1103,11 @@ func (g *Generator) ensureGlobalImageRegistry(overrides map[string]interface{},
 		}
 		log.Debug("Using unique target registry from processed images for global.imageRegistry", "registry", finalGlobalRegistry)
 	case len(uniqueTargetRegistries) > 1:
-		// Multiple different target registries were used. Fallback to CLI --target-registry.
-		log.Debug("Multiple target registries used for processed images. Falling back to CLI --target-registry for global.imageRegistry.", "cliTarget", g.targetRegistry)
-		finalGlobalRegistry = g.targetRegistry
+		// Images were routed to different target registries (e.g. by registry groups). Charts apply
+		// global.imageRegistry to every image, so setting it would send them all to one registry.
+		log.Info("Images are relocated to multiple target registries, not setting global.imageRegistry",
+			"targets", strings.Join(slices.Sorted(maps.Keys(uniqueTargetRegistries)), ", "))
+		return
 	default:
 		// No specific target registries were derived from mappings (e.g., all unmapped and processed with CLI target).
 		// Or, all FinalTargetRegistry fields were empty (should not happen for processed images).
@@ -760,22 +1140,67 @@ func (g *Generator) ensureGlobalImageRegistry(overrides map[string]interface{},
 	}
 }
 
+// Defaults of the release the chart is rendered as by ValidateHelmTemplate
+const (
+	defaultValidationReleaseName = "irr-validation"
+	defaultValidationNamespace   = "default"
+)
+
+// ValidationOptions describes the deployment the overrides are validated for, so that
+// ValidateHelmTemplate renders conditional templates as the real install does.
+type ValidationOptions struct {
+	// Values are the --values files and --set style values the overrides were generated with;
+	// the overrides take precedence over them
+	Values values.Options
+	// ReleaseName is the release name templates see as .Release.Name (default "irr-validation")
+	ReleaseName string
+	// Namespace is the namespace templates see as .Release.Namespace (default "default")
+	Namespace string
+	// KubeVersion is the Kubernetes version templates see as .Capabilities.KubeVersion (default
+	// Helm's)
+	KubeVersion string
+	// APIVersions are added to the API versions templates see as .Capabilities.APIVersions
+	APIVersions []string
+}
+
+// releaseName returns the release name to render the chart as
+func (o *ValidationOptions) releaseName() string {
+	if o == nil || o.ReleaseName == "" {
+		return defaultValidationReleaseName
+	}
+	return o.ReleaseName
+}
+
+// namespace returns the namespace to render the chart in
+func (o *ValidationOptions) namespace() string {
+	if o == nil || o.Namespace == "" {
+		return defaultValidationNamespace
+	}
+	return o.Namespace
+}
+
 // ValidateHelmTemplate runs `helm template` on the chart with the provided overrides
 // to check for rendering errors or invalid configurations introduced by the overrides.
-// It returns an error if the template command fails.
-func ValidateHelmTemplate(chartPath string, overrides []byte) error {
-	log.Debug("Validating Helm template", "chartPath", chartPath)
+// opts, which may be nil, supplies the values and release the chart is deployed with.
+// It returns an error if the template command fails, or if ctx is canceled before rendering.
+func ValidateHelmTemplate(ctx context.Context, chartPath string, overrides []byte, opts *ValidationOptions) error {
+	log.Debug("Validating Helm template", "chartPath", chartPath, "release", opts.releaseName(), "namespace", opts.namespace())
+	defer timing.Start(timing.SpanValidation, chartPath)()
 	// Call the internal function (or its mock via the variable)
-	err := validateHelmTemplateInternalFunc(chartPath, overrides)
+	err := validateHelmTemplateInternalFunc(ctx, chartPath, overrides, opts)
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("helm template validation canceled: %w", err)
+	}
 	if err != nil {
 		// Check if it's the specific Bitnami template error
 		// Corrected string check based on test case definition
 		if strings.Contains(err.Error(), "Original containers have been substituted for unrecognized ones") {
 			log.Warn("Helm validation failed with Bitnami security context error, retrying without overrides...", "chartPath", chartPath, "error", err)
 			// Retry without overrides
-			err = validateHelmTemplateInternalFunc(chartPath, nil)
+			err = validateHelmTemplateInternalFunc(ctx, chartPath, nil, opts)
 			if err != nil {
 				log.Error("Helm template validation failed even after retry without overrides", "error", err)
+				metrics.Inc(metrics.ValidationFailures)
 				return fmt.Errorf("helm template validation failed on retry: %w", err)
 			} // If retry succeeds, log info and return nil
 			log.Info("Helm validation succeeded on retry without overrides (Bitnami common issue)")
@@ -784,6 +1209,7 @@ func ValidateHelmTemplate(chartPath string, overrides []byte) error {
 
 		// If it's not the Bitnami error, log and return the original error
 		log.Error("Helm template validation failed", "error", err)
+		metrics.Inc(metrics.ValidationFailures)
 		return fmt.Errorf("helm template validation failed: %w", err)
 	}
 	log.Info("Helm template validation successful")
@@ -795,18 +1221,26 @@ func ValidateHelmTemplate(chartPath string, overrides []byte) error {
 // variable to allow mocking in tests.
 var validateHelmTemplateInternalFunc = validateHelmTemplateInternal
 
+// helmSettings returns the Helm environment settings shared by all validations, read from the
+// environment once rather than per chart, as runs validating many charts would repeat the work.
+var helmSettings = sync.OnceValue(cli.New)
+
+// helmGetters returns the getters --values files are read with, shared like helmSettings
+var helmGetters = sync.OnceValue(func() getter.Providers { return getter.All(helmSettings()) })
+
 // validateHelmTemplateInternal performs the actual execution of the `helm template` command.
-// It creates a temporary file for the overrides and runs Helm.
+// It creates a temporary file for the overrides, removed again on return, and runs Helm unless
+// ctx is canceled first; Helm cannot stop a dry-run install once it renders.
 // This function is wrapped by ValidateHelmTemplate for potential mocking.
-func validateHelmTemplateInternal(chartPath string, overrides []byte) error {
+func validateHelmTemplateInternal(ctx context.Context, chartPath string, overrides []byte, opts *ValidationOptions) error {
 	// Setup Helm environment settings
-	settings := cli.New() // Use default settings
+	settings := helmSettings()
 
 	// Setup Action Configuration
 	actionConfig := new(action.Configuration)
 	// Use an in-memory client for validation - avoid actual cluster interaction
-	// The namespace might be needed depending on chart logic, use a default.
-	err := actionConfig.Init(settings.RESTClientGetter(), settings.Namespace(), os.Getenv("HELM_DRIVER"), func(format string, v ...interface{}) {
+	// The namespace might be needed depending on chart logic.
+	err := actionConfig.Init(settings.RESTClientGetter(), opts.namespace(), os.Getenv("HELM_DRIVER"), func(format string, v ...interface{}) {
 		// Route Helm's internal logging to our slog logger at Debug level
 		// Keep Sprintf here as it's Helm's log format, not ours
 		log.Debug(fmt.Sprintf("[Helm] %s", fmt.Sprintf(format, v...)))
@@ -860,6 +1294,16 @@ func validateHelmTemplateInternal(chartPath string, overrides []byte) error {
 	}
 	log.Debug("Coalesced base chart values") // Refactored (no args needed)
 
+	// Apply the values the overrides were generated with, as helm install would
+	if opts != nil {
+		userValues, err := opts.Values.MergeValues(helmGetters())
+		if err != nil {
+			return fmt.Errorf("failed to merge values for validation: %w", err)
+		}
+		baseValues = chartutil.CoalesceTables(userValues, baseValues)
+		log.Debug("Merged --values and --set values with base values", "valuesFiles", len(opts.Values.ValueFiles))
+	}
+
 	// Load override values from the temp file
 	overrideValues, err := chartutil.ReadValuesFile(tmpFile.Name())
 	if err != nil {
@@ -874,16 +1318,31 @@ func validateHelmTemplateInternal(chartPath string, overrides []byte) error {
 	// --- Configure Template Action ---
 	client := action.NewInstall(actionConfig) // Use Install action for template rendering logic
 	client.DryRun = true                      // Equivalent to 'helm template'
-	client.ReleaseName = "irr-validation"     // Use a dummy release name
+	client.ReleaseName = opts.releaseName()   // Render as the release that is deployed
+	client.Namespace = opts.namespace()       // Render in the namespace that is deployed to
 	client.Replace = true                     // Replace indicates upgrading an existing release (not relevant for dry-run template)
 	client.ClientOnly = true                  // Perform rendering locally
 	client.IncludeCRDs = true                 // Include CRDs in the output (optional, but good for complete validation)
+	// Render with the capabilities of the cluster that is deployed to
+	if opts != nil && opts.KubeVersion != "" {
+		kubeVersion, err := chartutil.ParseKubeVersion(opts.KubeVersion)
+		if err != nil {
+			return fmt.Errorf("invalid Kubernetes version %q: %w", opts.KubeVersion, err)
+		}
+		client.KubeVersion = kubeVersion
+	}
+	if opts != nil && len(opts.APIVersions) > 0 {
+		client.APIVersions = chartutil.VersionSet(opts.APIVersions)
+	}
 	// Assign the merged values
 	// Note: client.Run expects map[string]interface{}, chartutil gives chartutil.Values (map[string]interface{})
 	valsMap := map[string]interface{}(finalValues)
 
 	// --- Execute Rendering ---
 	log.Debug("Executing Helm template rendering (dry-run install)") // Refactored (no args needed)
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("helm template rendering canceled: %w", err)
+	}
 	rel, err := client.Run(chartReq, valsMap)
 
 	// --- Analyze Results ---
@@ -959,12 +1418,41 @@ func (e *ProcessingError) Error() string {
 	return fmt.Sprintf("strict mode: %d processing errors occurred for paths: %s", e.Count, strings.Join(errStrings, "; "))
 }
 
+// ExitCode returns ExitImageProcessingError, implementing exitcodes.Coder.
+func (e *ProcessingError) ExitCode() int { return exitcodes.ExitImageProcessingError }
+
+// PathError is a processing error of the image at a values path. ProcessingError.Errors holds
+// one for every image whose override could not be generated.
+type PathError struct {
+	Path string
+	// Location is where the image is written in the values files, if known
+	Location *analysis.SourceLocation
+	Err      error
+}
+
+func (e *PathError) Error() string {
+	if e.Location == nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s (at %s)", e.Err, e.Location)
+}
+
+func (e *PathError) Unwrap() error { return e.Err }
+
+// ExitCode returns ExitImageProcessingError, implementing exitcodes.Coder.
+func (e *PathError) ExitCode() int { return exitcodes.ExitImageProcessingError }
+
 // --- Override Generation Logic ---
 
 // createOverride constructs the override value based on the detected pattern type.
-// For map patterns, it creates a map with registry, repository, and tag.
+// For map patterns, it creates a map with registry, repository, and tag, or with the registry
+// prefixed to the repository if the chart's values keep it there.
 // For string patterns, it creates the full image reference string.
-func (g *Generator) createOverride(pattern *analysis.ImagePattern, imgRef *image.Reference, targetReg, newPath string) interface{} {
+// A digest is kept: as a digest value, in the repository if the chart's values pin it there, or
+// as an @digest suffix of reference strings.
+// Without a digest, the tag falls back to the chart AppVersion and then the default tag, and is
+// rewritten by the mapping's tagTransform template if one is configured for the source registry.
+func (g *Generator) createOverride(pattern *analysis.ImagePattern, imgRef *image.Reference, targetReg, newPath string) (interface{}, error) {
 	log.Debug("Enter createOverride",
 		"path", pattern.Path,
 		"sourceOrigin", pattern.SourceOrigin,
@@ -982,21 +1470,39 @@ func (g *Generator) createOverride(pattern *analysis.ImagePattern, imgRef *image
 	// This special handling applies regardless of the pattern type (global or regular image).
 	if pattern.Path == "global.imageRegistry" {
 		log.Debug("Handling global.imageRegistry - returning registry string only", "registry", targetReg)
-		return targetReg
+		return targetReg, nil
 	}
 
 	// Determine the final image reference string parts
 	finalRepository := newPath // The strategy provides the full repository path within the target
 
 	// Determine which tag/digest to use
-	finalTag := imgRef.Tag
 	finalDigest := imgRef.Digest
-	log.Debug("createOverride: Initial tag", "tag", finalTag)
+	finalTag, err := g.overrideTag(pattern, imgRef)
+	if err != nil {
+		return nil, err
+	}
 
-	// Only use AppVersion if tag is empty
-	if finalTag == "" && pattern.SourceChartAppVersion != "" {
-		log.Debug("Tag is empty, using source chart AppVersion", "appVersion", pattern.SourceChartAppVersion)
-		finalTag = pattern.SourceChartAppVersion
+	if g.registryOnly {
+		if registryOverride, ok := registryOnlyOverride(pattern, targetReg); ok {
+			log.Debug("Returning registry-only override", "path", pattern.Path, "override", registryOverride)
+			return registryOverride, nil
+		}
+		log.Debug("Image has no registry key, overriding it with its original repository path", "path", pattern.Path)
+	}
+	if len(pattern.ImageKeys) > 0 {
+		return splitImageOverride(pattern, targetReg, finalRepository, finalTag), nil
+	}
+	if pattern.KeepString {
+		reference := targetReg + "/" + finalRepository
+		if finalTag != "" {
+			reference += ":" + finalTag
+		}
+		if finalDigest != "" {
+			reference += "@" + finalDigest
+		}
+		log.Debug("Returning image reference string", "path", pattern.Path, "reference", reference)
+		return reference, nil
 	}
 
 	// Construct the override structure
@@ -1006,6 +1512,14 @@ func (g *Generator) createOverride(pattern *analysis.ImagePattern, imgRef *image
 		keys.Registry:   targetReg,
 		keys.Repository: finalRepository,
 	}
+	// Charts that keep the registry host in the repository (repository: quay.io/org/app) have no
+	// registry value for templates to read, so the target registry goes into the repository too
+	if pattern.Type == analysis.PatternTypeMap && pattern.RegistryInRepository {
+		log.Debug("Keeping target registry in repository", "path", pattern.Path)
+		overrideMap = map[string]interface{}{
+			keys.Repository: targetReg + "/" + finalRepository,
+		}
+	}
 
 	// Only include the tag field in the map if finalTag is not empty
 	if finalTag != "" {
@@ -1030,9 +1544,16 @@ func (g *Generator) createOverride(pattern *analysis.ImagePattern, imgRef *image
 		log.Debug("Original pattern was likely a string, not including pullPolicy in override map")
 	}
 
-	// TODO: Decide if/how to handle digest overrides. Currently omitted.
+	// Charts that pin the digest in the repository (repository: gcr.io/distroless/static@sha256:...)
+	// have no digest value for templates to read, so the digest stays in the repository
 	if finalDigest != "" {
-		log.Warn("Digest found but override logic currently omits it", "path", pattern.Path, "digest", finalDigest)
+		if repository, ok := overrideMap[keys.Repository].(string); ok && pattern.Type == analysis.PatternTypeMap && pattern.DigestInRepository {
+			log.Debug("Keeping digest in repository", "path", pattern.Path, "digest", finalDigest)
+			overrideMap[keys.Repository] = repository + "@" + finalDigest
+		} else {
+			log.Debug("Including digest in override map", "digest", finalDigest)
+			overrideMap[keys.Digest] = finalDigest
+		}
 	}
 
 	log.Debug("Returning override structure", "overrideMap", overrideMap)
@@ -1043,6 +1564,89 @@ func (g *Generator) createOverride(pattern *analysis.ImagePattern, imgRef *image
 		log.Warn("Final check createOverride: Repository key missing", "path", pattern.Path)
 	}
 	// *** End final check ***
+	return overrideMap, nil
+}
+
+// overrideTag returns the tag the override of imgRef writes: its sourceTag, rewritten by the
+// mapping's tagTransform template if one is configured for the source registry. Images pinned by
+// digest only keep having no tag unless the template reads .Digest.
+func (g *Generator) overrideTag(pattern *analysis.ImagePattern, imgRef *image.Reference) (string, error) {
+	finalTag := g.sourceTag(pattern, imgRef)
+
+	// Apply the mapping's tag transform, if any
+	tagTransform := g.mappings.GetTagTransform(imgRef.Registry)
+	if tagTransform != "" && finalTag == "" && !registry.TagTransformUsesDigest(tagTransform) {
+		log.Debug("Skipping tag transform of image without a tag", "template", tagTransform, "image", imgRef.Original)
+		return finalTag, nil
+	}
+	if tagTransform != "" {
+		transformedTag, err := registry.TransformTag(tagTransform, registry.TagTransformData{
+			Tag:        finalTag,
+			Digest:     imgRef.Digest,
+			Repository: imgRef.Repository,
+		})
+		if err != nil {
+			return "", fmt.Errorf("tag transform for %s: %w", imgRef.Original, err)
+		}
+		log.Debug("Applied tag transform", "template", tagTransform, "from", finalTag, "to", transformedTag)
+		finalTag = transformedTag
+	}
+	return finalTag, nil
+}
+
+// sourceTag returns the tag of imgRef in its source registry. Without a digest, the tag falls back
+// to the chart AppVersion and then the default tag.
+func (g *Generator) sourceTag(pattern *analysis.ImagePattern, imgRef *image.Reference) string {
+	finalTag := imgRef.Tag
+	log.Debug("sourceTag: Initial tag", "tag", finalTag)
+
+	// Only use AppVersion if tag and digest are empty
+	if finalTag == "" && imgRef.Digest == "" && pattern.SourceChartAppVersion != "" {
+		log.Debug("Tag is empty, using source chart AppVersion", "appVersion", pattern.SourceChartAppVersion)
+		finalTag = pattern.SourceChartAppVersion
+	}
+
+	// Use the configured default tag for images that had neither tag nor digest in the values,
+	// replacing the implicit "latest" filled in during parsing
+	if g.defaultTag != "" && !hasExplicitTagOrDigest(pattern, imgRef) {
+		log.Debug("Image has no explicit tag or digest, using default tag", "path", pattern.Path, "defaultTag", g.defaultTag)
+		finalTag = g.defaultTag
+	}
+	return finalTag
+}
+
+// registryOnlyOverride returns the override of an image's registry key alone, for map values with
+// a registry key. It reports false for images whose registry cannot be set on its own: strings,
+// maps that keep the registry in the repository, and split image keys without a registry key.
+func registryOnlyOverride(pattern *analysis.ImagePattern, targetReg string) (map[string]interface{}, bool) {
+	if len(pattern.ImageKeys) > 0 {
+		registryKey, ok := pattern.ImageKeys[keys.Registry]
+		if !ok {
+			return nil, false
+		}
+		return map[string]interface{}{registryKey: targetReg}, true
+	}
+	if pattern.Type != analysis.PatternTypeMap || pattern.RegistryInRepository || pattern.KeepString {
+		return nil, false
+	}
+	return map[string]interface{}{keys.Registry: targetReg}, true
+}
+
+// splitImageOverride returns the override for an image split across chart-specific keys, keyed
+// by those keys. Without a registry key the target registry goes into the repository; the tag
+// key is left alone when there is no tag to write.
+func splitImageOverride(pattern *analysis.ImagePattern, targetReg, repository, tag string) map[string]interface{} {
+	overrideMap := make(map[string]interface{}, len(pattern.ImageKeys))
+	if registryKey, ok := pattern.ImageKeys[keys.Registry]; ok {
+		overrideMap[registryKey] = targetReg
+		overrideMap[pattern.ImageKeys[keys.Repository]] = repository
+	} else {
+		overrideMap[pattern.ImageKeys[keys.Repository]] = targetReg + "/" + repository
+	}
+	if tagKey, ok := pattern.ImageKeys[keys.Tag]; ok && tag != "" {
+		overrideMap[tagKey] = tag
+	}
+	log.Debug("Returning split image override", "path", pattern.Path, "overrideMap", overrideMap)
 	return overrideMap
 }
 
@@ -1055,155 +1659,148 @@ func mapKeys(m map[string]interface{}) []string {
 	return keyList
 }
 
-// setOverridePath sets the value at the specified path within the overrides map.
-// It handles creating nested maps and arrays as needed.
-func (g *Generator) setOverridePath(overrides map[string]interface{}, pattern *analysis.ImagePattern, value interface{}) error {
-	path := pattern.Path
-	pathElems := strings.Split(path, ".")
-	log.Debug("setOverridePath: START", "path", path, "elements", pathElems, "valueType", fmt.Sprintf("%T", value))
-
-	// Defensive check: Ensure pathElems is not empty, although Split usually returns [""] for empty path.
-	if len(pathElems) == 0 {
-		log.Error("Internal error: Path split resulted in empty slice", "path", path)
-		return fmt.Errorf("internal error: cannot process empty path elements for path '%s'", path)
-	}
-
-	currentMap := overrides
-	// Traverse path until the second-to-last element, creating maps if necessary
-	for i := 0; i < len(pathElems)-1; i++ {
-		key := pathElems[i]
-		log.Debug("setOverridePath: Traversing", "key", key)
-
-		// Check if this is an array index path (e.g., "containers[0]")
-		if strings.Contains(key, "[") && strings.Contains(key, "]") {
-			openBracketIndex := strings.Index(key, "[")
-			closeBracketIndex := strings.Index(key, "]")
-
-			// Verify indices are valid (openBracketIndex != -1 && closeBracketIndex > openBracketIndex)
-			if openBracketIndex == -1 || closeBracketIndex <= openBracketIndex {
-				return fmt.Errorf("malformed array index notation in path %s", path)
-			}
+// overridePathStep is one step of a value path: a map key, or an index into a sequence
+type overridePathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
 
-			arrayKey := key[:openBracketIndex]
-			indexStr := key[openBracketIndex+1 : closeBracketIndex]
+// parseOverridePath splits a value path such as "sidecars[0].image" or "matrix[1][0]" into steps.
+// Keys containing dots or brackets are escaped with a backslash, as the analyzer emits them, e.g.
+// `podAnnotations.vendor\.io/image`.
+func parseOverridePath(path string) ([]overridePathStep, error) {
+	if path == "" {
+		return nil, fmt.Errorf("cannot set override at empty path")
+	}
+	var steps []overridePathStep
+	for _, elem := range analysis.SplitPath(path) {
+		key, indexes := analysis.CutPathIndex(elem)
+		if key == "" {
+			return nil, fmt.Errorf("empty key in path %s", path)
+		}
+		steps = append(steps, overridePathStep{key: key})
+		if indexes == "" {
+			continue
+		}
+		// indexes holds the indices after the first '[', e.g. "1][0]"
+		for _, indexPart := range strings.Split(indexes[1:], "[") {
+			indexStr, ok := strings.CutSuffix(indexPart, "]")
+			if !ok {
+				return nil, fmt.Errorf("malformed array index notation in path %s", path)
+			}
 			index, err := strconv.Atoi(indexStr)
 			if err != nil {
-				return fmt.Errorf("invalid array index in path %s: %w", path, err)
-			}
-
-			// Get or create the array
-			var arr []interface{}
-			if existingArr, ok := currentMap[arrayKey]; ok {
-				if arr, ok = existingArr.([]interface{}); !ok {
-					// Key exists but is not an array, create new array
-					arr = make([]interface{}, index+1)
-					currentMap[arrayKey] = arr
-				}
-			} else {
-				// Create new array with enough capacity
-				arr = make([]interface{}, index+1)
-				currentMap[arrayKey] = arr
+				return nil, fmt.Errorf("invalid array index in path %s: %w", path, err)
 			}
-
-			// Ensure array has enough capacity
-			if index >= len(arr) {
-				newArr := make([]interface{}, index+1)
-				copy(newArr, arr)
-				arr = newArr
-				currentMap[arrayKey] = arr
+			if index < 0 {
+				return nil, fmt.Errorf("negative array index in path %s", path)
 			}
+			steps = append(steps, overridePathStep{index: index, isIndex: true})
+		}
+	}
+	return steps, nil
+}
 
-			// Create or get the map at the array index
-			if arr[index] == nil {
-				arr[index] = make(map[string]interface{})
-			}
-			if nextMap, ok := arr[index].(map[string]interface{}); ok {
-				currentMap = nextMap
-			} else {
-				// Replace with a new map if not a map
-				newMap := make(map[string]interface{})
-				arr[index] = newMap
-				currentMap = newMap
-			}
-		} else {
-			// Regular map path handling
-			if nextLevel, ok := currentMap[key]; ok {
-				// Key exists, check if it's a map
-				if nextMap, ok := nextLevel.(map[string]interface{}); ok {
-					currentMap = nextMap // Move deeper
-					log.Debug("setOverridePath: Moved into existing map", "key", key)
-				} else {
-					// Key exists but is not a map. We need to replace it to continue traversal.
-					log.Warn("setOverridePath: Overwriting existing non-map value with map to continue traversal", "key", key, "existingType", fmt.Sprintf("%T", nextLevel))
-					newMap := make(map[string]interface{})
-					currentMap[key] = newMap
-					currentMap = newMap
-				}
-			} else {
-				// Key doesn't exist, create a new map
-				log.Debug("setOverridePath: Creating new map for key", "key", key)
-				newMap := make(map[string]interface{})
-				currentMap[key] = newMap
-				currentMap = newMap // Move deeper
+// setOverridePath sets the value at the specified path within the overrides map.
+// It handles creating nested maps and arrays as needed. Helm replaces sequences as a whole, so
+// when a path enters a sequence that is not yet in the overrides, the sequence is copied from the
+// chart's values first; elements and fields without overrides are kept in their original order.
+func (g *Generator) setOverridePath(overrides map[string]interface{}, pattern *analysis.ImagePattern, value interface{}) error {
+	// Split images are written key by key, so that the override leaves the other keys of the map
+	// holding them (possibly the top level) alone
+	if fields, ok := value.(map[string]interface{}); ok && len(pattern.ImageKeys) > 0 {
+		for _, key := range mapKeys(fields) {
+			if err := g.setOverrideValue(overrides, analysis.JoinPath(pattern.Path, key), fields[key]); err != nil {
+				return err
 			}
 		}
+		return nil
 	}
+	return g.setOverrideValue(overrides, pattern.Path, value)
+}
 
-	// Set the final value at the last key
-	// Check again if pathElems is empty before accessing the last element (already covered by the check above, but kept for clarity)
-	if len(pathElems) == 0 {
-		// This should be unreachable due to the check after Split
-		return fmt.Errorf("internal error: path elements became empty unexpectedly for path '%s'", path)
-	}
-	finalKey := pathElems[len(pathElems)-1]
+// setOverrideValue sets value at path within the overrides map.
+func (g *Generator) setOverrideValue(overrides map[string]interface{}, path string, value interface{}) error {
+	log.Debug("setOverridePath: START", "path", path, "valueType", fmt.Sprintf("%T", value))
 
-	// Handle array index in the final key
-	if strings.Contains(finalKey, "[") && strings.Contains(finalKey, "]") {
-		openBracketIndex := strings.Index(finalKey, "[")
-		closeBracketIndex := strings.Index(finalKey, "]")
+	steps, err := parseOverridePath(path)
+	if err != nil {
+		return err
+	}
+	if steps[0].isIndex {
+		return fmt.Errorf("path %s must start with a key", path)
+	}
+	setOverrideStep(overrides, g.sourceValues, steps, value)
 
-		// Verify indices are valid (openBracketIndex != -1 && closeBracketIndex > openBracketIndex)
-		if openBracketIndex == -1 || closeBracketIndex <= openBracketIndex {
-			return fmt.Errorf("malformed array index notation in final key %s", finalKey)
-		}
+	log.Debug("setOverridePath: END", "path", path)
+	return nil
+}
 
-		arrayKey := finalKey[:openBracketIndex]
-		indexStr := finalKey[openBracketIndex+1 : closeBracketIndex]
-		index, err := strconv.Atoi(indexStr)
-		if err != nil {
-			return fmt.Errorf("invalid array index in final key %s: %w", finalKey, err)
-		}
+// setOverrideStep sets value at steps below node and returns the updated node. source is the node
+// at the same position in the chart's values (nil if there is none) and is used to seed sequences.
+func setOverrideStep(node, source interface{}, steps []overridePathStep, value interface{}) interface{} {
+	if len(steps) == 0 {
+		return value
+	}
+	step := steps[0]
 
-		// Get or create the array
-		var arr []interface{}
-		if existingArr, ok := currentMap[arrayKey]; ok {
-			if arr, ok = existingArr.([]interface{}); !ok {
-				arr = make([]interface{}, index+1)
-				currentMap[arrayKey] = arr
+	if step.isIndex {
+		arr, ok := node.([]interface{})
+		if !ok {
+			if srcArr, isArr := source.([]interface{}); isArr {
+				arr, _ = override.DeepCopy(srcArr).([]interface{})
+				log.Debug("setOverridePath: Seeded sequence from chart values", "length", len(arr))
 			}
-		} else {
-			arr = make([]interface{}, index+1)
-			currentMap[arrayKey] = arr
 		}
-
-		// Ensure array has enough capacity
-		if index >= len(arr) {
-			newArr := make([]interface{}, index+1)
-			copy(newArr, arr)
-			arr = newArr
-			currentMap[arrayKey] = arr
+		if step.index >= len(arr) {
+			grown := make([]interface{}, step.index+1)
+			copy(grown, arr)
+			arr = grown
+		}
+		var childSource interface{}
+		if srcArr, isArr := source.([]interface{}); isArr && step.index < len(srcArr) {
+			childSource = srcArr[step.index]
 		}
+		arr[step.index] = setOverrideStep(arr[step.index], childSource, steps[1:], value)
+		return arr
+	}
 
-		// Set the value at the array index
-		arr[index] = value
-	} else {
-		// Regular key handling
-		log.Debug("setOverridePath: Setting final value", "finalKey", finalKey, "value", value, "parentMapKeys", mapKeys(currentMap))
-		currentMap[finalKey] = value
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		if node != nil {
+			log.Warn("setOverridePath: Overwriting existing non-map value with map to continue traversal", "key", step.key, "existingType", fmt.Sprintf("%T", node))
+		}
+		m = make(map[string]interface{})
 	}
+	var childSource interface{}
+	if srcMap, isMap := source.(map[string]interface{}); isMap {
+		childSource = srcMap[step.key]
+	}
+	m[step.key] = setOverrideStep(m[step.key], childSource, steps[1:], value)
+	return m
+}
 
-	log.Debug("setOverridePath: END", "path", path)
-	return nil
+// hasExplicitTagOrDigest reports whether the image at pattern specified a tag or digest in the
+// chart values, as opposed to the default tag filled in when the reference was parsed.
+func hasExplicitTagOrDigest(pattern *analysis.ImagePattern, imgRef *image.Reference) bool {
+	if imgRef.Digest != "" {
+		return true
+	}
+	if pattern.Structure != nil {
+		for _, key := range []string{keys.Tag, "digest"} {
+			if val, ok := pattern.Structure[key]; ok && val != nil && fmt.Sprint(val) != "" {
+				return true
+			}
+		}
+		return false
+	}
+	// String pattern: a tag is a ':' in the last path component (a ':' before it is a registry port)
+	ref := pattern.Value
+	if strings.Contains(ref, "@") {
+		return true
+	}
+	return strings.Contains(ref[strings.LastIndex(ref, "/")+1:], ":")
 }
 
 // processImagePattern extracts image details using the image package.
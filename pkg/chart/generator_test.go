@@ -1,6 +1,7 @@
 package chart
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,9 +14,12 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
 	"github.com/lucas-albers-lz4/irr/pkg/image"
 	"github.com/lucas-albers-lz4/irr/pkg/override"
 	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/lucas-albers-lz4/irr/pkg/strategy"
+	"github.com/lucas-albers-lz4/irr/pkg/strictness"
 )
 
 // MockPathStrategy implements the strategy.PathStrategy interface for testing
@@ -433,6 +437,443 @@ func TestGenerator_Generate_Mappings(t *testing.T) {
 	assert.Equal(t, "default-target.registry.com/mockpath/app/backend:v1", imgThreeOverride)
 }
 
+func TestGenerator_Generate_TagTransform(t *testing.T) {
+	mappings := &registry.Mappings{
+		Entries: []registry.Mapping{
+			{Source: "source.registry.com", Target: "target.registry.com", TagTransform: "mirror-{{ .Tag }}"},
+		},
+	}
+	chartAnalysis := &analysis.ChartAnalysis{
+		ImagePatterns: []analysis.ImagePattern{
+			{Path: "tagged", Type: analysis.PatternTypeString, Value: "source.registry.com/app/tagged:v1", Count: 1},
+			{Path: "untagged", Type: analysis.PatternTypeString, Value: "source.registry.com/app/untagged", Count: 1},
+			{
+				Path:      "unmapped",
+				Type:      analysis.PatternTypeMap,
+				Value:     "other.registry.com/app/unmapped:latest",
+				Structure: map[string]interface{}{"registry": "other.registry.com", "repository": "app/unmapped"},
+				Count:     1,
+			},
+		},
+	}
+	chart := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "test-chart"}}
+
+	g := NewGenerator(
+		"test-chart",
+		"default-target.registry.com",
+		[]string{"source.registry.com", "other.registry.com"},
+		[]string{},
+		&MockPathStrategy{},
+		mappings,
+		false,
+		0,
+		&MockChartLoader{chart: chart},
+		false,
+	)
+	g.SetDefaultTag("1.0.0")
+
+	result, err := g.Generate(chart, chartAnalysis)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	tagOf := func(path string) interface{} {
+		value, ok := result.Values[path].(map[string]interface{})
+		require.True(t, ok, "override for %s should be a map", path)
+		return value["tag"]
+	}
+	assert.Equal(t, "mirror-v1", tagOf("tagged"), "tag transform applies to the mapped source registry")
+	assert.Equal(t, "mirror-1.0.0", tagOf("untagged"), "default tag is applied before the tag transform")
+	assert.Equal(t, "1.0.0", tagOf("unmapped"), "default tag replaces the implicit latest tag")
+
+	t.Run("invalid transform is a processing error", func(t *testing.T) {
+		badMappings := &registry.Mappings{
+			Entries: []registry.Mapping{
+				{Source: "source.registry.com", Target: "target.registry.com", TagTransform: "{{ .Missing }}"},
+			},
+		}
+		g := NewGenerator("test-chart", "default-target.registry.com", []string{"source.registry.com"}, []string{},
+			&MockPathStrategy{}, badMappings, false, 0, &MockChartLoader{chart: chart}, false)
+
+		_, err := g.Generate(chart, chartAnalysis)
+		var procErr *ProcessingError
+		require.ErrorAs(t, err, &procErr)
+		assert.Len(t, procErr.Errors, 2)
+	})
+
+	t.Run("digest-only images", func(t *testing.T) {
+		digest := "sha256:" + strings.Repeat("a", 64)
+		digestAnalysis := &analysis.ChartAnalysis{
+			ImagePatterns: []analysis.ImagePattern{
+				{Path: "pinned", Type: analysis.PatternTypeString, Value: "source.registry.com/app/pinned@" + digest, Count: 1},
+			},
+		}
+		generate := func(tagTransform string) (map[string]interface{}, error) {
+			transformMappings := &registry.Mappings{
+				Entries: []registry.Mapping{
+					{Source: "source.registry.com", Target: "target.registry.com", TagTransform: tagTransform},
+				},
+			}
+			g := NewGenerator("test-chart", "default-target.registry.com", []string{"source.registry.com"}, []string{},
+				&MockPathStrategy{}, transformMappings, false, 0, &MockChartLoader{chart: chart}, false)
+			result, err := g.Generate(chart, digestAnalysis)
+			if err != nil {
+				return nil, err
+			}
+			value, ok := result.Values["pinned"].(map[string]interface{})
+			require.True(t, ok)
+			return value, nil
+		}
+
+		value, err := generate("mirror-{{ .Tag }}")
+		require.NoError(t, err)
+		assert.NotContains(t, value, "tag", "the transform is skipped for images without a tag")
+		assert.Equal(t, digest, value["digest"])
+
+		value, err = generate("{{ slice .Digest 7 19 }}")
+		require.NoError(t, err)
+		assert.Equal(t, "aaaaaaaaaaaa", value["tag"], "templates reading .Digest still apply")
+	})
+
+	t.Run("invalid rendered tag is a path error", func(t *testing.T) {
+		invalidMappings := &registry.Mappings{
+			Entries: []registry.Mapping{
+				{Source: "source.registry.com", Target: "target.registry.com", TagTransform: "{{ .Tag }}:mirror"},
+			},
+		}
+		g := NewGenerator("test-chart", "default-target.registry.com", []string{"source.registry.com"}, []string{},
+			&MockPathStrategy{}, invalidMappings, false, 0, &MockChartLoader{chart: chart}, false)
+
+		_, err := g.Generate(chart, chartAnalysis)
+		var procErr *ProcessingError
+		require.ErrorAs(t, err, &procErr)
+		require.Len(t, procErr.Errors, 2)
+		var pathErr *PathError
+		require.ErrorAs(t, procErr.Errors[0], &pathErr)
+		assert.Equal(t, "tagged", pathErr.Path)
+		assert.ErrorContains(t, pathErr, "not a valid image tag")
+	})
+}
+
+func TestGenerator_Generate_MultipleTargets(t *testing.T) {
+	// Registry groups route sources to different target registries
+	mappings := &registry.Mappings{
+		Entries: []registry.Mapping{
+			{Source: "docker.io", Target: "harbor-a.example.com"},
+			{Source: "quay.io", Target: "harbor-a.example.com"},
+			{Source: "gcr.io", Target: "harbor-b.example.com/google"},
+		},
+	}
+	chartAnalysis := &analysis.ChartAnalysis{
+		ImagePatterns: []analysis.ImagePattern{
+			{Path: "web", Type: analysis.PatternTypeString, Value: "docker.io/library/nginx:1.25", Count: 1},
+			{Path: "cache", Type: analysis.PatternTypeString, Value: "quay.io/opstree/redis:v7", Count: 1},
+			{Path: "proxy", Type: analysis.PatternTypeString, Value: "gcr.io/cloudsql-docker/gce-proxy:1.33", Count: 1},
+		},
+	}
+	chart := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "test-chart"}}
+
+	g := NewGenerator("test-chart", "", []string{"docker.io", "quay.io", "gcr.io"}, []string{},
+		&MockPathStrategy{}, mappings, false, 0, &MockChartLoader{chart: chart}, false)
+
+	result, err := g.Generate(chart, chartAnalysis)
+	require.NoError(t, err)
+
+	registryOf := func(path string) interface{} {
+		value, ok := result.Values[path].(map[string]interface{})
+		require.True(t, ok, "override for %s should be a map", path)
+		return value["registry"]
+	}
+	assert.Equal(t, "harbor-a.example.com", registryOf("web"))
+	assert.Equal(t, "harbor-a.example.com", registryOf("cache"))
+	assert.Equal(t, "harbor-b.example.com", registryOf("proxy"))
+	assert.NotContains(t, result.Values, "global", "global.imageRegistry would send every image to one target")
+}
+
+func TestGenerator_Generate_RegistryInRepository(t *testing.T) {
+	mappings := &registry.Mappings{
+		Entries: []registry.Mapping{{Source: "quay.io", Target: "harbor.example.com"}},
+	}
+	chartAnalysis := &analysis.ChartAnalysis{
+		ImagePatterns: []analysis.ImagePattern{
+			{
+				Path:                 "operator.image",
+				Type:                 analysis.PatternTypeMap,
+				Value:                "quay.io/org/operator:v1",
+				Structure:            map[string]interface{}{"registry": "quay.io", "repository": "org/operator", "tag": "v1"},
+				Count:                1,
+				RegistryInRepository: true,
+			},
+			{
+				Path:      "web.image",
+				Type:      analysis.PatternTypeMap,
+				Value:     "quay.io/org/web:v2",
+				Structure: map[string]interface{}{"registry": "quay.io", "repository": "org/web", "tag": "v2"},
+				Count:     1,
+			},
+		},
+	}
+	chart := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "test-chart"}}
+
+	g := NewGenerator("test-chart", "", []string{"quay.io"}, []string{},
+		&MockPathStrategy{}, mappings, false, 0, &MockChartLoader{chart: chart}, false)
+
+	result, err := g.Generate(chart, chartAnalysis)
+	require.NoError(t, err)
+
+	operator, ok := result.Values["operator"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"repository": "harbor.example.com/mockpath/org/operator",
+		"tag":        "v1",
+		"pullPolicy": "IfNotPresent",
+	}, operator["image"], "registry stays in the repository and no registry key is added")
+
+	web, ok := result.Values["web"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"registry":   "harbor.example.com",
+		"repository": "mockpath/org/web",
+		"tag":        "v2",
+		"pullPolicy": "IfNotPresent",
+	}, web["image"])
+}
+
+func TestGenerator_Generate_SplitImageKeys(t *testing.T) {
+	mappings := &registry.Mappings{
+		Entries: []registry.Mapping{{Source: "quay.io", Target: "harbor.example.com"}},
+	}
+	chartAnalysis := &analysis.ChartAnalysis{
+		ImagePatterns: []analysis.ImagePattern{
+			{
+				Path:      "",
+				Type:      analysis.PatternTypeMap,
+				Value:     "quay.io/strimzi:0.38.0",
+				Structure: map[string]interface{}{"registry": "quay.io", "repository": "strimzi", "tag": "0.38.0"},
+				Count:     1,
+				ImageKeys: map[string]string{"registry": "defaultImageRegistry", "repository": "defaultImageRepository", "tag": "defaultImageTag"},
+			},
+			{
+				Path:      "exporter",
+				Type:      analysis.PatternTypeMap,
+				Value:     "quay.io/org/exporter:v1",
+				Structure: map[string]interface{}{"registry": "quay.io", "repository": "org/exporter", "tag": "v1"},
+				Count:     1,
+				ImageKeys: map[string]string{"repository": "imageRepository"},
+			},
+		},
+	}
+	chart := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "test-chart"}}
+
+	g := NewGenerator("test-chart", "", []string{"quay.io"}, []string{},
+		&MockPathStrategy{}, mappings, false, 0, &MockChartLoader{chart: chart}, false)
+
+	result, err := g.Generate(chart, chartAnalysis)
+	require.NoError(t, err)
+
+	assert.Equal(t, "harbor.example.com", result.Values["defaultImageRegistry"])
+	assert.Equal(t, "mockpath/strimzi", result.Values["defaultImageRepository"])
+	assert.Equal(t, "0.38.0", result.Values["defaultImageTag"])
+	assert.Equal(t, map[string]interface{}{
+		"imageRepository": "harbor.example.com/mockpath/org/exporter",
+	}, result.Values["exporter"], "only the chart's own keys are written, with the registry in the repository")
+}
+
+func TestGenerator_Generate_KeepString(t *testing.T) {
+	chartAnalysis := &analysis.ChartAnalysis{
+		ImagePatterns: []analysis.ImagePattern{
+			{Path: "operatorImage", Type: analysis.PatternTypeString, Value: "quay.io/org/operator:v1", Count: 1, KeepString: true},
+			{Path: "relatedImages[0]", Type: analysis.PatternTypeString, Value: "quay.io/org/agent", Count: 1, KeepString: true},
+		},
+	}
+	chart := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "test-chart"},
+		Values:   map[string]interface{}{"relatedImages": []interface{}{"quay.io/org/agent"}},
+	}
+
+	g := NewGenerator("test-chart", "harbor.example.com", []string{"quay.io"}, []string{},
+		&MockPathStrategy{}, nil, false, 0, &MockChartLoader{chart: chart}, false)
+	g.SetDefaultTag("v2")
+
+	result, err := g.Generate(chart, chartAnalysis)
+	require.NoError(t, err)
+
+	assert.Equal(t, "harbor.example.com/mockpath/org/operator:v1", result.Values["operatorImage"])
+	assert.Equal(t, []interface{}{"harbor.example.com/mockpath/org/agent:v2"}, result.Values["relatedImages"])
+}
+
+func TestGenerator_Generate_RegistryOnly(t *testing.T) {
+	mappings := &registry.Mappings{
+		Entries: []registry.Mapping{{Source: "quay.io", Target: "harbor.example.com/quay-proxy"}},
+	}
+	chartAnalysis := &analysis.ChartAnalysis{
+		ImagePatterns: []analysis.ImagePattern{
+			{
+				Path:      "web.image",
+				Type:      analysis.PatternTypeMap,
+				Value:     "quay.io/org/web:v2",
+				Structure: map[string]interface{}{"registry": "quay.io", "repository": "org/web", "tag": "v2"},
+				Count:     1,
+			},
+			{
+				Path:                 "operator.image",
+				Type:                 analysis.PatternTypeMap,
+				Value:                "quay.io/org/operator:v1",
+				Structure:            map[string]interface{}{"repository": "quay.io/org/operator", "tag": "v1"},
+				Count:                1,
+				RegistryInRepository: true,
+			},
+			{
+				Path:      "",
+				Type:      analysis.PatternTypeMap,
+				Value:     "quay.io/strimzi:0.38.0",
+				Structure: map[string]interface{}{"registry": "quay.io", "repository": "strimzi", "tag": "0.38.0"},
+				Count:     1,
+				ImageKeys: map[string]string{"registry": "defaultImageRegistry", "repository": "defaultImageRepository", "tag": "defaultImageTag"},
+			},
+			{Path: "agentImage", Type: analysis.PatternTypeString, Value: "quay.io/org/agent:v3", Count: 1, KeepString: true},
+		},
+	}
+	chart := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "test-chart"}}
+
+	g := NewGenerator("test-chart", "", []string{"quay.io"}, []string{},
+		&MockPathStrategy{}, mappings, false, 0, &MockChartLoader{chart: chart}, false)
+	g.SetRegistryOnly(true)
+
+	result, err := g.Generate(chart, chartAnalysis)
+	require.NoError(t, err)
+
+	web, ok := result.Values["web"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"registry": "harbor.example.com/quay-proxy"}, web["image"],
+		"only the registry key is overridden")
+
+	operator, ok := result.Values["operator"].(map[string]interface{})
+	require.True(t, ok)
+	operatorImage, ok := operator["image"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "harbor.example.com/quay-proxy/org/operator", operatorImage["repository"],
+		"images without a registry key keep their original repository path")
+
+	assert.Equal(t, "harbor.example.com/quay-proxy", result.Values["defaultImageRegistry"])
+	assert.NotContains(t, result.Values, "defaultImageRepository")
+	assert.NotContains(t, result.Values, "defaultImageTag")
+	assert.Equal(t, "harbor.example.com/quay-proxy/org/agent:v3", result.Values["agentImage"])
+
+	global, ok := result.Values["global"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "harbor.example.com/quay-proxy", global["imageRegistry"])
+}
+
+func TestGenerator_Generate_Exceptions(t *testing.T) {
+	chartAnalysis := &analysis.ChartAnalysis{
+		ImagePatterns: []analysis.ImagePattern{
+			{Path: "operatorImage", Type: analysis.PatternTypeString, Value: "quay.io/org/operator:v1", Count: 1},
+			{Path: "enterprise.agentImage", Type: analysis.PatternTypeString, Value: "quay.io/vendor/agent:3.1", Count: 1},
+			{Path: "scanner.image", Type: analysis.PatternTypeString, Value: "quay.io/org/scanner:2.0", Count: 1},
+		},
+	}
+	chart := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "test-chart"}}
+
+	g := NewGenerator("test-chart", "harbor.example.com", []string{"quay.io"}, []string{},
+		&MockPathStrategy{}, nil, false, 0, &MockChartLoader{chart: chart}, false)
+	g.SetExceptions([]registry.RelocationException{
+		{Image: "quay.io/vendor/**", Reason: "licensed"},
+		{Path: "scanner.*"},
+	})
+
+	result, err := g.Generate(chart, chartAnalysis)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"registry":   "harbor.example.com",
+		"repository": "mockpath/org/operator",
+		"tag":        "v1",
+	}, result.Values["operatorImage"])
+	assert.NotContains(t, result.Values, "enterprise")
+	assert.NotContains(t, result.Values, "scanner")
+	assert.Equal(t, []override.Exclusion{
+		{Path: "enterprise.agentImage", Image: "quay.io/vendor/agent:3.1", Exception: "image=quay.io/vendor/**", Reason: "licensed"},
+		{Path: "scanner.image", Image: "quay.io/org/scanner:2.0", Exception: "path=scanner.*"},
+	}, result.Excluded)
+	assert.Equal(t, 1, result.ProcessedCount)
+	assert.InDelta(t, 100.0, result.SuccessRate, 0.001, "excluded images are not counted as skipped")
+	assert.Empty(t, result.Unsupported)
+}
+
+func TestGenerator_Generate_Digests(t *testing.T) {
+	const digest = "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	chartAnalysis := &analysis.ChartAnalysis{
+		ImagePatterns: []analysis.ImagePattern{
+			{
+				Path:                  "static.image",
+				Type:                  analysis.PatternTypeMap,
+				Value:                 "gcr.io/distroless/static@" + digest,
+				Structure:             map[string]interface{}{"registry": "gcr.io", "repository": "distroless/static", "digest": digest},
+				Count:                 1,
+				SourceChartAppVersion: "1.0.0",
+			},
+			{
+				Path:                 "base.image",
+				Type:                 analysis.PatternTypeMap,
+				Value:                "gcr.io/distroless/base@" + digest,
+				Structure:            map[string]interface{}{"registry": "gcr.io", "repository": "distroless/base", "digest": digest},
+				Count:                1,
+				RegistryInRepository: true,
+				DigestInRepository:   true,
+			},
+			{Path: "operatorImage", Type: analysis.PatternTypeString, Value: "gcr.io/org/operator@" + digest, Count: 1, KeepString: true},
+		},
+	}
+	chart := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "test-chart"}}
+
+	g := NewGenerator("test-chart", "harbor.example.com", []string{"gcr.io"}, []string{},
+		&MockPathStrategy{}, nil, false, 0, &MockChartLoader{chart: chart}, false)
+	g.SetDefaultTag("v2")
+
+	result, err := g.Generate(chart, chartAnalysis)
+	require.NoError(t, err)
+
+	static, ok := result.Values["static"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"registry":   "harbor.example.com",
+		"repository": "mockpath/distroless/static",
+		"digest":     digest,
+		"pullPolicy": "IfNotPresent",
+	}, static["image"], "the digest is kept and no AppVersion or default tag is added")
+
+	base, ok := result.Values["base"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"repository": "harbor.example.com/mockpath/distroless/base@" + digest,
+		"pullPolicy": "IfNotPresent",
+	}, base["image"], "the digest stays in the repository")
+
+	assert.Equal(t, "harbor.example.com/mockpath/org/operator@"+digest, result.Values["operatorImage"])
+}
+
+func TestHasExplicitTagOrDigest(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern analysis.ImagePattern
+		digest  string
+		want    bool
+	}{
+		{name: "string with tag", pattern: analysis.ImagePattern{Value: "nginx:1.25"}, want: true},
+		{name: "string without tag", pattern: analysis.ImagePattern{Value: "nginx"}, want: false},
+		{name: "registry port without tag", pattern: analysis.ImagePattern{Value: "registry.local:5000/app"}, want: false},
+		{name: "registry port with tag", pattern: analysis.ImagePattern{Value: "registry.local:5000/app:v1"}, want: true},
+		{name: "digest", pattern: analysis.ImagePattern{Value: "nginx"}, digest: "sha256:abc", want: true},
+		{name: "map with tag", pattern: analysis.ImagePattern{Structure: map[string]interface{}{"repository": "app", "tag": 1.2}}, want: true},
+		{name: "map with empty tag", pattern: analysis.ImagePattern{Structure: map[string]interface{}{"repository": "app", "tag": ""}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, hasExplicitTagOrDigest(&tt.pattern, &image.Reference{Digest: tt.digest}))
+		})
+	}
+}
+
 // Remove tests for deleted functions
 func TestProcessChartForOverrides_Removed(t *testing.T) {
 	t.Skip("Test for removed function processChartForOverrides")
@@ -535,7 +976,7 @@ func TestValidateHelmTemplateWithFallback(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			callCount := 0
-			validateHelmTemplateInternalFunc = func(_ string, _ []byte) error {
+			validateHelmTemplateInternalFunc = func(_ context.Context, _ string, _ []byte, _ *ValidationOptions) error {
 				callCount++
 				if callCount == 1 {
 					return tc.firstError
@@ -544,7 +985,7 @@ func TestValidateHelmTemplateWithFallback(t *testing.T) {
 			}
 
 			// Call the function with dummy values
-			result := ValidateHelmTemplate("test-chart", []byte("foo: bar"))
+			result := ValidateHelmTemplate(context.Background(), "test-chart", []byte("foo: bar"), nil)
 
 			// Check the result
 			if tc.expectedResult == nil {
@@ -622,7 +1063,7 @@ func TestGenerator_Generate_ImagePatternError(t *testing.T) {
 			Metadata: &helmchart.Metadata{Name: "test-chart"},
 			Values: map[string]interface{}{
 				"goodImage": "source.registry.com/app/image1:v1",
-				"badImage":  "docker.io/library/nginx@sha256:invaliddigest", // Digest is parsed now but generates a warning
+				"badImage":  "docker.io/library/nginx@sha256:invaliddigest", // Digest is parsed and kept in the override
 			},
 		},
 	}
@@ -674,6 +1115,7 @@ func TestGenerator_Generate_ImagePatternError(t *testing.T) {
 				"badImage": map[string]interface{}{
 					"registry":   "target.registry.com",
 					"repository": "mockpath/library/nginx",
+					"digest":     "sha256:invaliddigest",
 				},
 			},
 		}
@@ -743,6 +1185,9 @@ func TestGenerator_Generate_OverrideError(t *testing.T) {
 	// Check length before accessing index 0
 	require.NotEmpty(t, procErr.Errors, "procErr.Errors should not be empty")
 	assert.Contains(t, procErr.Errors[0].Error(), "error determining target path for image2", "Error should be about image2 path generation")
+	var pathErr *PathError
+	require.ErrorAs(t, procErr.Errors[0], &pathErr)
+	assert.Equal(t, "image2", pathErr.Path)
 
 	// Result should still be non-nil containing the successful override
 	require.NotNil(t, result)
@@ -854,6 +1299,156 @@ func TestGenerator_Generate_RulesInteraction(t *testing.T) {
 	}
 }
 
+func TestGenerator_ApplyBitnamiCompat(t *testing.T) {
+	bitnamiChart := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{
+			Name:    "redis",
+			Home:    "https://bitnami.com",
+			Sources: []string{"https://github.com/bitnami/charts/tree/main/bitnami/redis"},
+		},
+	}
+	relocated := []ProcessedImageDetail{{Path: "image", OriginalRegistry: "docker.io", FinalTargetRegistry: "harbor.local"}}
+	unchanged := []ProcessedImageDetail{{Path: "image", OriginalRegistry: "docker.io", FinalTargetRegistry: "docker.io/mirror"}}
+
+	tests := []struct {
+		name          string
+		chart         *helmchart.Chart
+		details       []ProcessedImageDetail
+		rulesEnabled  bool
+		bitnamiCompat bool
+		sourceValues  map[string]interface{}
+		wantBypass    bool
+		wantWarning   bool
+	}{
+		{name: "relocated Bitnami chart", chart: bitnamiChart, details: relocated, rulesEnabled: true, bitnamiCompat: true, wantBypass: true, wantWarning: true},
+		{name: "compat disabled", chart: bitnamiChart, details: relocated, rulesEnabled: true, wantWarning: true},
+		{name: "rules disabled", chart: bitnamiChart, details: relocated, bitnamiCompat: true, wantWarning: true},
+		{name: "registry unchanged", chart: bitnamiChart, details: unchanged, rulesEnabled: true, bitnamiCompat: true},
+		{name: "non-Bitnami chart", chart: &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "web"}}, details: relocated, rulesEnabled: true, bitnamiCompat: true},
+		{
+			name: "already allowed in values", chart: bitnamiChart, details: relocated, rulesEnabled: true, bitnamiCompat: true,
+			sourceValues: map[string]interface{}{"global": map[string]interface{}{"security": map[string]interface{}{"allowInsecureImages": true}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{rulesEnabled: tt.rulesEnabled, bitnamiCompat: tt.bitnamiCompat, sourceValues: tt.sourceValues}
+			result := &override.File{Values: map[string]interface{}{}}
+
+			g.applyBitnamiCompat(tt.chart, result, tt.details)
+
+			value, err := override.GetValueAtPath(result.Values, []string{"global", "security", "allowInsecureImages"})
+			if tt.wantBypass {
+				require.NoError(t, err)
+				assert.Equal(t, true, value)
+			} else {
+				assert.Error(t, err)
+			}
+			if tt.wantWarning {
+				require.Len(t, result.Warnings, 1)
+				assert.Equal(t, "redis", result.Warnings[0].Chart)
+				assert.Equal(t, "global.security.allowInsecureImages", result.Warnings[0].Path)
+			} else {
+				assert.Empty(t, result.Warnings)
+			}
+		})
+	}
+}
+
+func TestGenerator_ApplyTargetFlavor(t *testing.T) {
+	g := &Generator{targetFlavor: strategy.FlavorHarbor}
+
+	targetRegistry, path, repoPath, err := g.applyTargetFlavor("harbor.local/Mirror", "docker.io/library/nginx")
+	require.NoError(t, err)
+	assert.Equal(t, "harbor.local/mirror", targetRegistry)
+	assert.Equal(t, "docker.io/library/nginx", path)
+	assert.Equal(t, "mirror/docker.io/library/nginx", repoPath)
+
+	_, _, _, err = g.applyTargetFlavor("harbor.local", "docker.io-library-nginx")
+	var pathErr *strategy.TargetPathError
+	require.ErrorAs(t, err, &pathErr)
+
+	warnings := g.targetFlavorWarnings([]string{"mirror/docker.io/library/nginx", "mirror/quay.io/org/app", "base/redis"})
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "target-project-required", warnings[0].Code)
+	assert.Contains(t, warnings[0].Message, "base, mirror")
+
+	assert.Empty(t, (&Generator{targetFlavor: strategy.FlavorGeneric}).targetFlavorWarnings([]string{"mirror/nginx"}))
+}
+
+func TestGenerator_StrictPolicy(t *testing.T) {
+	patterns := []analysis.ImagePattern{
+		{Path: "app.image", Type: analysis.PatternTypeString, Value: "docker.io/library/nginx:1.25"},
+		{Path: "templated.image", Type: analysis.PatternTypeString, Value: "{{ .Values.image }}"},
+		{Path: "broken.image", Type: analysis.PatternTypeString, Value: "not a valid image!"},
+		{Path: "empty.image", Type: analysis.PatternTypeMap, Value: "docker.io/:1.0",
+			Structure: map[string]interface{}{"registry": "docker.io", "repository": "", "tag": "1.0"}},
+	}
+	g := &Generator{}
+	found := g.findPolicyConditions(patterns, g.findUnsupportedPatterns(patterns))
+	assert.Equal(t, map[strictness.Condition][]string{
+		strictness.TemplateExpressions: {"templated.image"},
+		strictness.UnparseableImages:   {"broken.image"},
+		strictness.EmptyRepositories:   {"empty.image"},
+	}, found)
+
+	t.Run("warn", func(t *testing.T) {
+		g.SetStrictPolicy(strictness.LevelWarn.Policy())
+		warnings, err := g.applyStrictPolicy(found)
+		require.NoError(t, err)
+		require.Len(t, warnings, 3)
+		assert.Equal(t, "template-expression", warnings[0].Code)
+		assert.Equal(t, "templated.image", warnings[0].Path)
+	})
+
+	t.Run("policy error", func(t *testing.T) {
+		g.SetStrictPolicy(strictness.LevelUnsupported.Policy().Merge(strictness.Policy{TemplateExpressions: strictness.ActionIgnore}))
+		_, err := g.applyStrictPolicy(found)
+		var violation *strictness.ViolationError
+		require.ErrorAs(t, err, &violation)
+		assert.Equal(t, strictness.UnparseableImages, violation.Condition)
+		assert.Equal(t, []string{"broken.image"}, violation.Items)
+	})
+
+	t.Run("off", func(t *testing.T) {
+		g.SetStrictPolicy(strictness.LevelOff.Policy())
+		warnings, err := g.applyStrictPolicy(found)
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+	})
+}
+
+func TestGenerator_MappingConflicts(t *testing.T) {
+	mappings := &registry.Mappings{Entries: []registry.Mapping{
+		{Source: "quay.io", Target: "quay.io/mirror"},
+		{Source: "docker.io", Target: "harbor.local/dockerhub"},
+	}}
+	g := &Generator{mappings: mappings, targetRegistry: "harbor.local"}
+	found := g.findPolicyConditions(nil, nil)
+	assert.Equal(t, map[strictness.Condition][]string{
+		strictness.MappingConflicts: {"quay.io is mapped to itself (quay.io/mirror)"},
+	}, found)
+
+	t.Run("warn by default", func(t *testing.T) {
+		g.SetStrictPolicy(strictness.LevelOff.Policy())
+		warnings, err := g.applyStrictPolicy(found)
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, "mapping-conflict", warnings[0].Code)
+		assert.Empty(t, warnings[0].Path)
+		assert.Equal(t, "registry mapping conflict: quay.io is mapped to itself (quay.io/mirror)", warnings[0].Message)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		g.SetStrictPolicy(strictness.LevelAll.Policy())
+		_, err := g.applyStrictPolicy(found)
+		var violation *strictness.ViolationError
+		require.ErrorAs(t, err, &violation)
+		assert.Equal(t, strictness.MappingConflicts, violation.Condition)
+		assert.Equal(t, exitcodes.ExitInputConfigurationError, violation.ExitCode())
+	})
+}
+
 // --- Helper Function Tests ---
 
 func TestFindValueByPath(t *testing.T) {
@@ -1321,3 +1916,132 @@ func TestSetOverridePath_NestedMapCorruption(t *testing.T) {
 		})
 	}
 }
+
+func TestSetOverridePath_Sequences(t *testing.T) {
+	source := map[string]interface{}{
+		"sidecars": []interface{}{
+			map[string]interface{}{"name": "proxy", "image": "docker.io/org/proxy:1"},
+			map[string]interface{}{"name": "logger", "image": "docker.io/org/logger:2"},
+			map[string]interface{}{"name": "metrics", "port": 9090},
+		},
+		"matrix": []interface{}{
+			[]interface{}{"docker.io/org/a:1", "docker.io/org/b:2"},
+			[]interface{}{"docker.io/org/c:3"},
+		},
+	}
+	g := &Generator{sourceValues: source}
+	overrides := map[string]interface{}{}
+
+	require.NoError(t, g.setOverridePath(overrides, &analysis.ImagePattern{Path: "sidecars[1].image"}, "harbor.local/org/logger:2"))
+	require.NoError(t, g.setOverridePath(overrides, &analysis.ImagePattern{Path: "matrix[0][1]"}, "harbor.local/org/b:2"))
+
+	// Sequences are copied whole from the source values, in order, with only the overridden entries changed
+	assert.Equal(t, map[string]interface{}{
+		"sidecars": []interface{}{
+			map[string]interface{}{"name": "proxy", "image": "docker.io/org/proxy:1"},
+			map[string]interface{}{"name": "logger", "image": "harbor.local/org/logger:2"},
+			map[string]interface{}{"name": "metrics", "port": 9090},
+		},
+		"matrix": []interface{}{
+			[]interface{}{"docker.io/org/a:1", "harbor.local/org/b:2"},
+			[]interface{}{"docker.io/org/c:3"},
+		},
+	}, overrides)
+
+	// The source values are not modified
+	sidecar, ok := source["sidecars"].([]interface{})[1].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "docker.io/org/logger:2", sidecar["image"])
+}
+
+func TestParseOverridePath(t *testing.T) {
+	steps, err := parseOverridePath("matrix[1][0].image")
+	require.NoError(t, err)
+	assert.Equal(t, []overridePathStep{
+		{key: "matrix"},
+		{index: 1, isIndex: true},
+		{index: 0, isIndex: true},
+		{key: "image"},
+	}, steps)
+
+	for _, path := range []string{"", "[0]", "a[x]", "a[1", "a.[0]", "a[-1]"} {
+		_, err := parseOverridePath(path)
+		assert.Error(t, err, "path %q", path)
+	}
+}
+
+func TestRelocationOf(t *testing.T) {
+	ref := &image.Reference{Original: "nginx:1.27", Registry: "docker.io", Repository: "library/nginx", Tag: "1.27"}
+	assert.Equal(t, override.Relocation{
+		Path:      "image",
+		Original:  "nginx:1.27",
+		Relocated: "harbor.local/dockerio/library/nginx:1.27",
+	}, relocationOf("image", ref, "harbor.local", "dockerio/library/nginx"))
+
+	ref = &image.Reference{Registry: "quay.io", Repository: "org/app", Digest: "sha256:abc"}
+	assert.Equal(t, "quay.io/org/app@sha256:abc", relocationOf("app.image", ref, "harbor.local", "quayio/org/app").Original)
+}
+
+func TestGenerator_Generate_DottedKeys(t *testing.T) {
+	chart := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "dotted-keys"},
+		Values: map[string]interface{}{
+			"my.dotted.key": map[string]interface{}{
+				"image": map[string]interface{}{"repository": "quay.io/org/app", "tag": "2.0"},
+			},
+			"sidecars": map[string]interface{}{
+				"vendor.io/agent": map[string]interface{}{
+					"image": map[string]interface{}{"registry": "quay.io", "repository": "vendor/agent", "tag": "1.0"},
+				},
+			},
+			"tools[beta]": map[string]interface{}{
+				"image": map[string]interface{}{"repository": "quay.io/org/tool", "tag": "3.0"},
+			},
+		},
+	}
+	chartAnalysis, err := analysis.NewAnalyzer("dotted-keys", &MockChartLoader{chart: chart}).Analyze()
+	require.NoError(t, err)
+	paths := make([]string, 0, len(chartAnalysis.ImagePatterns))
+	for _, pattern := range chartAnalysis.ImagePatterns {
+		paths = append(paths, pattern.Path)
+	}
+	assert.ElementsMatch(t, []string{`my\.dotted\.key.image`, `sidecars.vendor\.io/agent.image`, `tools\[beta\].image`}, paths)
+
+	g := NewGenerator("dotted-keys", "harbor.local", []string{"quay.io"}, nil,
+		strategy.NewPrefixSourceRegistryStrategy(nil), nil, false, 0, &MockChartLoader{chart: chart}, false)
+	result, err := g.Generate(chart, chartAnalysis)
+	require.NoError(t, err)
+
+	// Each escaped key is one key of the overrides, not a nesting of its dot-separated parts
+	assert.ElementsMatch(t, []string{"global", "my.dotted.key", "sidecars", "tools[beta]"}, mapKeys(result.Values))
+	imageOverride := func(parent map[string]interface{}, key string) map[string]interface{} {
+		t.Helper()
+		child, ok := parent[key].(map[string]interface{})
+		require.True(t, ok, "no map at %s", key)
+		image, ok := child["image"].(map[string]interface{})
+		require.True(t, ok, "no image map under %s", key)
+		return image
+	}
+	assert.Equal(t, "harbor.local/quay.io/org/app", imageOverride(result.Values, "my.dotted.key")["repository"])
+	assert.Equal(t, "harbor.local/quay.io/org/tool", imageOverride(result.Values, "tools[beta]")["repository"])
+	sidecars, ok := result.Values["sidecars"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "quay.io/vendor/agent", imageOverride(sidecars, "vendor.io/agent")["repository"])
+}
+
+func TestParseOverridePath_EscapedKeys(t *testing.T) {
+	steps, err := parseOverridePath(`podAnnotations.vendor\.io/image.containers[1][0].back\\slash`)
+	require.NoError(t, err)
+	assert.Equal(t, []overridePathStep{
+		{key: "podAnnotations"},
+		{key: "vendor.io/image"},
+		{key: "containers"},
+		{index: 1, isIndex: true},
+		{index: 0, isIndex: true},
+		{key: `back\slash`},
+	}, steps)
+
+	steps, err = parseOverridePath(`list\[0\]`)
+	require.NoError(t, err)
+	assert.Equal(t, []overridePathStep{{key: "list[0]"}}, steps)
+}
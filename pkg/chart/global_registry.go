@@ -0,0 +1,281 @@
+package chart
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/keys"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+)
+
+// globalRegistryRef is how templates and their helpers read the chart-wide registry, e.g.
+// {{ .Values.global.imageRegistry }} or {{ .global.imageRegistry }} in a helper given .Values
+const globalRegistryRef = ".global.imageRegistry"
+
+// globalImageRegistryPath is the values path of the chart-wide registry
+const globalImageRegistryPath = "global.imageRegistry"
+
+// maxHelperDepth limits how deep named templates are followed from an image field
+const maxHelperDepth = 5
+
+var (
+	// valuesRefPattern matches values references such as .Values.image.repository or $.Values.image
+	valuesRefPattern = regexp.MustCompile(`\.Values((?:\.[A-Za-z_][A-Za-z0-9_]*)+)`)
+	// includePattern matches the named template used by an include or template action
+	includePattern = regexp.MustCompile(`\b(?:include|template)\s+"([^"]+)"`)
+	// definePattern matches the start of a named template definition
+	definePattern = regexp.MustCompile(`\{\{-?\s*define\s+"([^"]+)"`)
+	// imageLinePattern matches the image field of a container in a template
+	imageLinePattern = regexp.MustCompile(`^\s*(?:-\s+)?image:`)
+)
+
+// SetGlobalRegistryThreshold sets the share of relocated images, between 0 and 1, whose templates
+// must compose their reference from global.imageRegistry and their own repository before those
+// images are overridden with global.imageRegistry plus their repository alone. 0 disables the
+// convention and every image gets a full override.
+func (g *Generator) SetGlobalRegistryThreshold(threshold float64) {
+	g.globalRegistryThreshold = threshold
+}
+
+// applyGlobalRegistryConvention reduces the overrides of images that the chart's templates compose
+// as {{ .Values.global.imageRegistry }}/{{ .Values.<path>.repository }} to their repository (and
+// a changed tag or digest), leaving the registry to global.imageRegistry, which is set as usual by
+// ensureGlobalImageRegistry. It only applies when the share of such images reaches the threshold
+// and all images go to one target registry, as global.imageRegistry applies to every image that
+// reads it; the other images keep their full overrides.
+func (g *Generator) applyGlobalRegistryConvention(loadedChart *chart.Chart, eligibleImages []analysis.ImagePattern, overrides map[string]interface{}, processedDetails []ProcessedImageDetail) {
+	if g.globalRegistryThreshold <= 0 || len(processedDetails) == 0 {
+		return
+	}
+	composed := globalRegistryImagePaths(loadedChart)
+	patterns := make(map[string]*analysis.ImagePattern, len(eligibleImages))
+	for i := range eligibleImages {
+		patterns[eligibleImages[i].Path] = &eligibleImages[i]
+	}
+
+	var paths []string
+	images := 0
+	targets := make(map[string]bool)
+	for _, detail := range processedDetails {
+		targets[detail.FinalTargetRegistry] = true
+		if detail.Path == globalImageRegistryPath {
+			// The chart-wide registry itself is not an image composed from it
+			continue
+		}
+		images++
+		if pattern := patterns[detail.Path]; pattern != nil && composed[detail.Path] && composesRepository(pattern) {
+			paths = append(paths, detail.Path)
+		}
+	}
+	confidence := 0.0
+	if images > 0 {
+		confidence = float64(len(paths)) / float64(images)
+	}
+	if len(paths) == 0 || confidence < g.globalRegistryThreshold {
+		log.Info("Too few images composed from global.imageRegistry, generating full image overrides",
+			"composed", len(paths), "images", images,
+			"confidence", fmt.Sprintf("%.2f", confidence), "threshold", g.globalRegistryThreshold)
+		return
+	}
+	if len(targets) != 1 {
+		log.Info("Images are relocated to multiple target registries, generating full image overrides instead of global.imageRegistry",
+			"composed", len(paths))
+		return
+	}
+
+	for _, path := range paths {
+		pathKeys, ok := overrideKeys(path)
+		if !ok {
+			continue
+		}
+		value, ok := findValueByPath(overrides, pathKeys)
+		if !ok {
+			continue
+		}
+		if fields, isMap := value.(map[string]interface{}); isMap {
+			reduceToRepositoryOverride(fields, patterns[path].Structure)
+			if len(fields) == 0 {
+				// Registry-only overrides have nothing left once global.imageRegistry sets the registry
+				removeOverride(overrides, pathKeys)
+			}
+		}
+	}
+	log.Info("Using global.imageRegistry for images composed from it",
+		"composed", len(paths), "images", images,
+		"confidence", fmt.Sprintf("%.2f", confidence))
+}
+
+// overrideKeys returns the unescaped map keys of the values path, or false when the path indexes
+// into a sequence, whose overrides are kept in full
+func overrideKeys(path string) ([]string, bool) {
+	elems := analysis.SplitPath(path)
+	pathKeys := make([]string, 0, len(elems))
+	for _, elem := range elems {
+		key, indexes := analysis.CutPathIndex(elem)
+		if indexes != "" {
+			return nil, false
+		}
+		pathKeys = append(pathKeys, key)
+	}
+	return pathKeys, true
+}
+
+// removeOverride removes the value at pathElems from the overrides, along with the maps left empty
+func removeOverride(overrides map[string]interface{}, pathElems []string) {
+	if len(pathElems) == 1 {
+		delete(overrides, pathElems[0])
+		return
+	}
+	child, ok := overrides[pathElems[0]].(map[string]interface{})
+	if !ok {
+		return
+	}
+	removeOverride(child, pathElems[1:])
+	if len(child) == 0 {
+		delete(overrides, pathElems[0])
+	}
+}
+
+// composesRepository reports whether the override of an image is a map with its own registry and
+// repository keys, the form templates compose with global.imageRegistry. Strings, split image keys
+// and maps keeping the registry in the repository are always overridden in full.
+func composesRepository(pattern *analysis.ImagePattern) bool {
+	return pattern.Type == analysis.PatternTypeMap && len(pattern.ImageKeys) == 0 &&
+		!pattern.RegistryInRepository && !pattern.KeepString
+}
+
+// reduceToRepositoryOverride removes the registry and pull policy from an image override, and its
+// tag and digest when they are the chart's own, leaving the repository and any changed tag or digest
+func reduceToRepositoryOverride(fields, original map[string]interface{}) {
+	delete(fields, keys.Registry)
+	delete(fields, "pullPolicy")
+	for _, key := range []string{keys.Tag, keys.Digest} {
+		value, ok := fields[key]
+		if ok && original != nil && fmt.Sprint(original[key]) == fmt.Sprint(value) {
+			delete(fields, key)
+		}
+	}
+}
+
+// globalRegistryImagePaths returns the values paths, relative to the top-level chart's values, of
+// the images whose image fields read global.imageRegistry in the templates of the chart or its
+// subcharts, directly or through the named templates they include.
+func globalRegistryImagePaths(loadedChart *chart.Chart) map[string]bool {
+	paths := make(map[string]bool)
+	if loadedChart == nil {
+		return paths
+	}
+	defines := templateDefines(loadedChart)
+	var walk func(c *chart.Chart, prefix string)
+	walk = func(c *chart.Chart, prefix string) {
+		for _, file := range c.Templates {
+			if file == nil {
+				continue
+			}
+			for _, line := range strings.Split(string(file.Data), "\n") {
+				if !imageLinePattern.MatchString(line) {
+					continue
+				}
+				text := withHelpers(line, defines, 0)
+				if !strings.Contains(text, globalRegistryRef) {
+					continue
+				}
+				for _, path := range imagePathsOf(text) {
+					paths[templateValuesPath(prefix, path)] = true
+				}
+			}
+		}
+		for _, dep := range c.Dependencies() {
+			for _, key := range analysis.DependencyValuesKeys(c, dep) {
+				walk(dep, analysis.JoinPath(prefix, key))
+			}
+		}
+	}
+	walk(loadedChart, "")
+	return paths
+}
+
+// withHelpers returns text followed by the bodies of the named templates it includes, recursively
+func withHelpers(text string, defines map[string]string, depth int) string {
+	if depth >= maxHelperDepth {
+		return text
+	}
+	var b strings.Builder
+	b.WriteString(text)
+	for _, m := range includePattern.FindAllStringSubmatch(text, -1) {
+		if body, ok := defines[m[1]]; ok {
+			b.WriteString("\n")
+			b.WriteString(withHelpers(body, defines, depth+1))
+		}
+	}
+	return b.String()
+}
+
+// imagePathsOf returns the image values paths referenced in text: a reference to the registry,
+// repository, tag or digest below a path is a reference to the image at that path. Global
+// references are skipped.
+func imagePathsOf(text string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, m := range valuesRefPattern.FindAllStringSubmatch(text, -1) {
+		path := strings.TrimPrefix(m[1], ".")
+		if path == "global" || strings.HasPrefix(path, "global.") {
+			continue
+		}
+		if i := strings.LastIndex(path, "."); i > 0 {
+			switch path[i+1:] {
+			case keys.Registry, keys.Repository, keys.Tag, keys.Digest:
+				path = path[:i]
+			}
+		}
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// templateDefines returns the bodies of the named templates defined in the chart and its
+// subcharts. A body runs up to the next definition in the same file.
+func templateDefines(c *chart.Chart) map[string]string {
+	defines := make(map[string]string)
+	for _, file := range c.Templates {
+		if file == nil {
+			continue
+		}
+		data := string(file.Data)
+		matches := definePattern.FindAllStringSubmatchIndex(data, -1)
+		for i, m := range matches {
+			end := len(data)
+			if i+1 < len(matches) {
+				end = matches[i+1][0]
+			}
+			if name := data[m[2]:m[3]]; defines[name] == "" {
+				defines[name] = data[m[1]:end]
+			}
+		}
+	}
+	for _, dep := range c.Dependencies() {
+		for name, body := range templateDefines(dep) {
+			if _, exists := defines[name]; !exists {
+				defines[name] = body
+			}
+		}
+	}
+	return defines
+}
+
+// templateValuesPath appends the fields of a template's .Values reference, e.g. image.registry, to
+// the values path prefix, escaping each as a values key
+func templateValuesPath(prefix, fields string) string {
+	path := prefix
+	for _, field := range strings.Split(fields, ".") {
+		path = analysis.JoinPath(path, field)
+	}
+	return path
+}
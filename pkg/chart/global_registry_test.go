@@ -0,0 +1,125 @@
+package chart
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+)
+
+// globalRegistryChart returns a chart whose web image is composed from global.imageRegistry in
+// its template, and whose cache subchart composes its image through a helper; the worker image
+// is composed from its own registry
+func globalRegistryChart() *helmchart.Chart {
+	cache := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "redis"},
+		Templates: []*helmchart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(`{{- define "redis.image" -}}
+{{ .global.imageRegistry }}/{{ .imageRoot.repository }}:{{ .imageRoot.tag }}
+{{- end -}}`)},
+			{Name: "templates/statefulset.yaml", Data: []byte(`containers:
+  - name: redis
+    image: {{ include "redis.image" (dict "imageRoot" .Values.image "global" .Values.global) }}`)},
+		},
+	}
+	parent := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{
+			Name:         "app",
+			Dependencies: []*helmchart.Dependency{{Name: "redis", Alias: "cache"}},
+		},
+		Templates: []*helmchart.File{
+			{Name: "templates/deployment.yaml", Data: []byte(`containers:
+  - name: web
+    image: "{{ .Values.global.imageRegistry }}/{{ .Values.web.image.repository }}:{{ .Values.web.image.tag }}"
+  - name: worker
+    image: "{{ .Values.worker.image.registry }}/{{ .Values.worker.image.repository }}:{{ .Values.worker.image.tag }}"`)},
+		},
+	}
+	parent.SetDependencies(cache)
+	return parent
+}
+
+func TestGlobalRegistryImagePaths(t *testing.T) {
+	paths := globalRegistryImagePaths(globalRegistryChart())
+	assert.Equal(t, map[string]bool{"web.image": true, "cache.image": true}, paths)
+	assert.Empty(t, globalRegistryImagePaths(nil))
+}
+
+func TestGenerator_Generate_GlobalRegistryConvention(t *testing.T) {
+	patterns := []analysis.ImagePattern{
+		{
+			Path: "web.image", Type: analysis.PatternTypeMap, Value: "docker.io/org/web:v1", Count: 1,
+			Structure: map[string]interface{}{"registry": "docker.io", "repository": "org/web", "tag": "v1"},
+		},
+		{
+			Path: "cache.image", Type: analysis.PatternTypeMap, Value: "docker.io/bitnami/redis:7.2", Count: 1,
+			Structure: map[string]interface{}{"registry": "docker.io", "repository": "bitnami/redis", "tag": "7.2"},
+		},
+		{
+			Path: "worker.image", Type: analysis.PatternTypeMap, Value: "docker.io/org/worker:v1", Count: 1,
+			Structure: map[string]interface{}{"registry": "docker.io", "repository": "org/worker", "tag": "v1"},
+		},
+	}
+	generate := func(t *testing.T, threshold float64) map[string]interface{} {
+		t.Helper()
+		chart := globalRegistryChart()
+		g := NewGenerator("app", "harbor.example.com", []string{"docker.io"}, []string{},
+			&MockPathStrategy{}, nil, false, 0, &MockChartLoader{chart: chart}, false)
+		g.SetGlobalRegistryThreshold(threshold)
+		result, err := g.Generate(chart, &analysis.ChartAnalysis{ImagePatterns: append([]analysis.ImagePattern{}, patterns...)})
+		require.NoError(t, err)
+		return result.Values
+	}
+	imageAt := func(t *testing.T, values map[string]interface{}, key string) interface{} {
+		t.Helper()
+		parent, ok := values[key].(map[string]interface{})
+		require.True(t, ok, "overrides for %s", key)
+		return parent["image"]
+	}
+
+	t.Run("composed images get their repository only", func(t *testing.T) {
+		values := generate(t, 0.6)
+		assert.Equal(t, map[string]interface{}{"repository": "mockpath/org/web"}, imageAt(t, values, "web"))
+		assert.Equal(t, map[string]interface{}{"repository": "mockpath/bitnami/redis"}, imageAt(t, values, "cache"))
+		assert.Equal(t, map[string]interface{}{
+			"registry": "harbor.example.com", "repository": "mockpath/org/worker", "tag": "v1", "pullPolicy": "IfNotPresent",
+		}, imageAt(t, values, "worker"), "images not composed from global.imageRegistry keep their full override")
+		assert.Equal(t, map[string]interface{}{"imageRegistry": "harbor.example.com"}, values["global"])
+	})
+
+	t.Run("below the threshold", func(t *testing.T) {
+		values := generate(t, 0.9)
+		assert.Contains(t, imageAt(t, values, "web"), "registry")
+		assert.Contains(t, imageAt(t, values, "cache"), "registry")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		values := generate(t, 0)
+		assert.Contains(t, imageAt(t, values, "web"), "registry")
+	})
+}
+
+func TestReduceToRepositoryOverride(t *testing.T) {
+	fields := map[string]interface{}{"registry": "harbor.example.com", "repository": "org/web", "tag": "v2", "pullPolicy": "Always"}
+	reduceToRepositoryOverride(fields, map[string]interface{}{"registry": "docker.io", "repository": "org/web", "tag": "v1"})
+	assert.Equal(t, map[string]interface{}{"repository": "org/web", "tag": "v2"}, fields, "a changed tag is kept")
+
+	overrides := map[string]interface{}{"web": map[string]interface{}{"image": map[string]interface{}{}}, "replicas": 2}
+	removeOverride(overrides, []string{"web", "image"})
+	assert.Equal(t, map[string]interface{}{"replicas": 2}, overrides)
+}
+
+func TestOverrideKeys(t *testing.T) {
+	pathKeys, ok := overrideKeys(`podAnnotations.vendor\.io/image`)
+	require.True(t, ok)
+	assert.Equal(t, []string{"podAnnotations", "vendor.io/image"}, pathKeys, "escaped keys stay one key")
+
+	_, ok = overrideKeys("sidecars[0].image")
+	assert.False(t, ok, "images in sequences keep their full override")
+
+	assert.Equal(t, `cache.image`, templateValuesPath("cache", "image"))
+	assert.Equal(t, `vendor\.io.image`, templateValuesPath(`vendor\.io`, "image"))
+}
@@ -0,0 +1,64 @@
+package chart
+
+import (
+	"regexp"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+)
+
+var (
+	// fullVersionTag matches tags starting with a full major.minor.patch version, e.g. 1.25.3,
+	// v2.1.0-alpine or 8.0.32-debian-12-r5
+	fullVersionTag = regexp.MustCompile(`^v?\d+\.\d+\.\d+`)
+	// commitTag matches tags that are git commit IDs, e.g. 3f2c1ab
+	commitTag = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+)
+
+// DigestResolver returns the digest of the manifest that tag points to in the source registry and
+// repository of imgRef.
+type DigestResolver func(imgRef *image.Reference, tag string) (string, error)
+
+// SetDigestResolver sets the resolver pinning relocated images referenced by a mutable tag to the
+// digest the tag points to (--pin-digests). A nil resolver, the default, leaves them unpinned.
+func (g *Generator) SetDigestResolver(resolver DigestResolver) {
+	g.digestResolver = resolver
+}
+
+// MutableTag reports whether tag may be moved to another image: a tag that is neither a full
+// version nor a commit ID, such as latest, main, stable or the minor version 1.25.
+func MutableTag(tag string) bool {
+	return !fullVersionTag.MatchString(tag) && !commitTag.MatchString(tag)
+}
+
+// checkImmutableReference reports whether the relocated image imgRef, found at pattern, is still
+// referenced by a mutable tag. With a digest resolver, a mutable tag is pinned first: the digest
+// it points to is set on imgRef, so the override writes it.
+func (g *Generator) checkImmutableReference(pattern *analysis.ImagePattern, imgRef *image.Reference) bool {
+	if imgRef.Digest != "" {
+		return false
+	}
+	tag := g.sourceTag(pattern, imgRef)
+	if !MutableTag(tag) {
+		return false
+	}
+	if g.digestResolver == nil || !canPinDigest(pattern, g.registryOnly) {
+		log.Debug("Image is referenced by a mutable tag", "path", pattern.Path, "image", imgRef.Original, "tag", tag)
+		return true
+	}
+	digest, err := g.digestResolver(imgRef, tag)
+	if err != nil {
+		log.Warn("Failed to pin mutable tag to a digest", "path", pattern.Path, "image", imgRef.Original, "tag", tag, "error", err)
+		return true
+	}
+	log.Info("Pinned mutable tag to digest", "path", pattern.Path, "image", imgRef.Original, "tag", tag, "digest", digest)
+	imgRef.Tag, imgRef.Digest = tag, digest
+	return false
+}
+
+// canPinDigest reports whether the override of the image at pattern can carry a digest. Split
+// image keys have no digest key, and registry-only overrides keep the chart's tag.
+func canPinDigest(pattern *analysis.ImagePattern, registryOnly bool) bool {
+	return len(pattern.ImageKeys) == 0 && !registryOnly
+}
@@ -0,0 +1,101 @@
+package chart
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/lucas-albers-lz4/irr/pkg/strictness"
+)
+
+func TestMutableTag(t *testing.T) {
+	for _, tag := range []string{"", "latest", "main", "stable", "1.25", "v2", "1.25-alpine"} {
+		assert.True(t, MutableTag(tag), tag)
+	}
+	for _, tag := range []string{"1.25.3", "v2.1.0", "v2.1.0-alpine", "8.0.32-debian-12-r5", "3f2c1ab", "0123456789abcdef0123456789abcdef01234567"} {
+		assert.False(t, MutableTag(tag), tag)
+	}
+}
+
+func TestGenerator_Generate_MutableReferences(t *testing.T) {
+	patterns := []analysis.ImagePattern{
+		{
+			Path: "web.image", Type: analysis.PatternTypeMap, Value: "docker.io/org/web:latest", Count: 1,
+			Structure: map[string]interface{}{"registry": "docker.io", "repository": "org/web", "tag": "latest"},
+		},
+		{Path: "worker.image", Type: analysis.PatternTypeString, Value: "quay.io/org/worker:main", Count: 1, KeepString: true},
+		{
+			Path: "db.image", Type: analysis.PatternTypeMap, Value: "docker.io/library/postgres:16.2.0", Count: 1,
+			Structure: map[string]interface{}{"registry": "docker.io", "repository": "library/postgres", "tag": "16.2.0"},
+		},
+	}
+	generate := func(t *testing.T, action strictness.Action, resolver DigestResolver) (*override.File, error) {
+		t.Helper()
+		chart := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "app"}}
+		g := NewGenerator("app", "harbor.example.com", []string{"docker.io", "quay.io"}, []string{},
+			&MockPathStrategy{}, nil, false, 0, &MockChartLoader{chart: chart}, false)
+		g.SetStrictPolicy(strictness.LevelOff.Policy().Merge(strictness.Policy{MutableReferences: action}))
+		g.SetDigestResolver(resolver)
+		return g.Generate(chart, &analysis.ChartAnalysis{ImagePatterns: append([]analysis.ImagePattern{}, patterns...)})
+	}
+
+	t.Run("ignored by default", func(t *testing.T) {
+		result, err := generate(t, "", nil)
+		require.NoError(t, err)
+		assert.Empty(t, result.Warnings)
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		result, err := generate(t, strictness.ActionWarn, nil)
+		require.NoError(t, err)
+		require.Len(t, result.Warnings, 2)
+		assert.Equal(t, "mutable-reference", result.Warnings[0].Code)
+		assert.Equal(t, "web.image", result.Warnings[0].Path)
+		assert.Equal(t, "worker.image", result.Warnings[1].Path)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := generate(t, strictness.ActionError, nil)
+		var violation *strictness.ViolationError
+		require.ErrorAs(t, err, &violation)
+		assert.Equal(t, strictness.MutableReferences, violation.Condition)
+		assert.Equal(t, []string{"web.image", "worker.image"}, violation.Items)
+		assert.Equal(t, exitcodes.ExitMutableReferenceError, violation.ExitCode())
+	})
+
+	t.Run("pinned to digests", func(t *testing.T) {
+		var resolved []string
+		result, err := generate(t, strictness.ActionError, func(imgRef *image.Reference, tag string) (string, error) {
+			resolved = append(resolved, imgRef.Registry+"/"+imgRef.Repository+":"+tag)
+			if imgRef.Registry == "quay.io" {
+				return "sha256:1111", nil
+			}
+			return "sha256:2222", nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"docker.io/org/web:latest", "quay.io/org/worker:main"}, resolved, "only mutable tags are resolved")
+		web := result.Values["web"].(map[string]interface{})["image"].(map[string]interface{})
+		assert.Equal(t, "sha256:2222", web["digest"])
+		assert.Equal(t, "harbor.example.com/mockpath/org/worker:main@sha256:1111", result.Values["worker"].(map[string]interface{})["image"])
+		assert.NotContains(t, result.Values["db"].(map[string]interface{})["image"], "digest")
+		assert.Contains(t, result.Relocations, override.Relocation{
+			Path: "worker.image", Original: "quay.io/org/worker:main", Relocated: "harbor.example.com/mockpath/org/worker@sha256:1111",
+		})
+	})
+
+	t.Run("unresolved tags stay mutable", func(t *testing.T) {
+		_, err := generate(t, strictness.ActionError, func(*image.Reference, string) (string, error) {
+			return "", errors.New("unauthorized")
+		})
+		var violation *strictness.ViolationError
+		require.ErrorAs(t, err, &violation)
+		assert.Equal(t, []string{"web.image", "worker.image"}, violation.Items)
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/lucas-albers-lz4/irr/pkg/analysis"
 	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
 	"github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/timing"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	// "helm.sh/helm/v3/pkg/chartutil" // Not needed after removing unused funcs
@@ -66,6 +67,7 @@ func (l *DefaultLoader) SetFS(fs fileutil.FS) func() {
 //   - There are issues with the chart's structure or metadata
 func (l *DefaultLoader) Load(chartPath string) (*chart.Chart, error) {
 	log.Debug("Loading chart from path", "path", chartPath)
+	defer timing.Start(timing.SpanChartLoad, chartPath)()
 
 	// Convert to absolute path if it's relative
 	// Note: Although we're injecting our filesystem for testing,
@@ -114,6 +116,9 @@ func (l *DefaultLoader) Load(chartPath string) (*chart.Chart, error) {
 	}
 	log.Debug("Successfully loaded chart", "name", loadedChart.Name(), "version", chartVersion)
 
+	// Normalize byte order marks, UTF-16 and CRLF line endings of charts written on Windows
+	analysis.NormalizeChartFiles(loadedChart)
+
 	// Ensure chart has values
 	if loadedChart.Values == nil {
 		log.Debug("Chart has no values, creating empty values map")
@@ -6,7 +6,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"unicode/utf16"
 
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
 	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
@@ -306,3 +308,62 @@ func TestDefaultLoader_SetFS(t *testing.T) {
 		cleanup()
 	}, "SetFS should handle nil filesystem")
 }
+
+// TestDefaultLoader_EncodingQuirks checks that charts written with a byte order mark, in UTF-16 or
+// with CRLF line endings are analyzed exactly like the same chart in UTF-8 with LF line endings.
+func TestDefaultLoader_EncodingQuirks(t *testing.T) {
+	const valuesYaml = `# Default values (café edition)
+defaults: &defaults
+  registry: docker.io
+  repository: library/nginx
+  tag: "1.25"
+image:
+  <<: *defaults
+sidecar:
+  image: quay.io/prometheus/node-exporter:v1.8.0
+motd: |
+  Welcome
+  to the cluster
+`
+	crlf := strings.ReplaceAll(valuesYaml, "\n", "\r\n")
+	encodings := map[string][]byte{
+		"UTF-8 BOM and CRLF": append([]byte{0xEF, 0xBB, 0xBF}, crlf...),
+		"CR":                 []byte(strings.ReplaceAll(valuesYaml, "\n", "\r")),
+		"UTF-16LE and CRLF":  append([]byte{0xFF, 0xFE}, utf16LE(crlf)...),
+	}
+
+	analyze := func(t *testing.T, values []byte) *analysis.ChartAnalysis {
+		t.Helper()
+		chartDir := createTempChartDir(t, "quirks", "apiVersion: v2\r\nname: quirks\r\nversion: 1.0.0\r\n", "")
+		require.NoError(t, os.WriteFile(filepath.Join(chartDir, "values.yaml"), values, FilePermissions))
+		loader := NewDefaultLoader(nil)
+		result, err := analysis.NewAnalyzer(chartDir, loader).Analyze()
+		require.NoError(t, err)
+		for i := range result.ImagePatterns {
+			if location := result.ImagePatterns[i].Location; location != nil {
+				location.File = filepath.Base(location.File)
+			}
+		}
+		loadedChart, err := loader.Load(chartDir)
+		require.NoError(t, err)
+		assert.Equal(t, "Welcome\nto the cluster\n", loadedChart.Values["motd"])
+		return result
+	}
+
+	want := analyze(t, []byte(valuesYaml))
+	require.Len(t, want.ImagePatterns, 3)
+	for name, values := range encodings {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, want, analyze(t, values))
+		})
+	}
+}
+
+// utf16LE encodes text as UTF-16LE without a byte order mark
+func utf16LE(text string) []byte {
+	var data []byte
+	for _, unit := range utf16.Encode([]rune(text)) {
+		data = append(data, byte(unit), byte(unit>>8))
+	}
+	return data
+}
@@ -0,0 +1,93 @@
+package chart
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	image "github.com/lucas-albers-lz4/irr/pkg/image"
+)
+
+// Statuses of a simulated image
+const (
+	// SimulationRelocated is an image the overrides would point to the target registry
+	SimulationRelocated = "relocated"
+	// SimulationExcluded is an image left unchanged by a relocation exception
+	SimulationExcluded = "excluded"
+	// SimulationSkipped is an image left unchanged because its registry is not a source registry,
+	// or is an excluded registry
+	SimulationSkipped = "skipped"
+	// SimulationFailed is an image that cannot be parsed or relocated
+	SimulationFailed = "error"
+)
+
+// simulatedImagePath is the values path simulated images are given; exceptions that match
+// values paths never apply to them
+const simulatedImagePath = "image"
+
+// SimulatedImage is what the generator would do with one image reference.
+type SimulatedImage struct {
+	// Image is the image reference as given
+	Image string `json:"image" yaml:"image"`
+	// Status is relocated, excluded, skipped or error
+	Status string `json:"status" yaml:"status"`
+	// Relocated is the image reference the overrides would set, for relocated images
+	Relocated string `json:"relocated,omitempty" yaml:"relocated,omitempty"`
+	// Reason explains why an image is not relocated
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// Simulate returns what the generator would rewrite the image reference value to, without a
+// chart. The image goes through the same steps as a string image value in the chart's values:
+// relocation exceptions, the source and exclude registries, the mappings, the path strategy, the
+// target flavor, the default tag and the mapping's tag transform.
+func (g *Generator) Simulate(value string) SimulatedImage {
+	result := SimulatedImage{Image: value}
+	pattern := analysis.ImagePattern{Path: simulatedImagePath, Type: analysis.PatternTypeString, Value: value, KeepString: true, Count: 1}
+	imgRef, err := g.processImagePattern(&pattern)
+	if err != nil {
+		result.Status, result.Reason = SimulationFailed, err.Error()
+		return result
+	}
+
+	patterns, excluded := g.applyExceptions([]analysis.ImagePattern{pattern})
+	if len(excluded) > 0 {
+		result.Status, result.Reason = SimulationExcluded, "exception "+excluded[0].Exception
+		if excluded[0].Reason != "" {
+			result.Reason += ": " + excluded[0].Reason
+		}
+		return result
+	}
+	if len(g.filterEligibleImages(patterns)) == 0 {
+		result.Status, result.Reason = SimulationSkipped, g.skipReason(imgRef.Registry)
+		return result
+	}
+
+	targetRegistry, newPath, _, err := g.targetOf(&pattern, imgRef)
+	if err != nil {
+		result.Status, result.Reason = SimulationFailed, err.Error()
+		return result
+	}
+	overrideValue, err := g.createOverride(&pattern, imgRef, targetRegistry, newPath)
+	if err != nil {
+		result.Status, result.Reason = SimulationFailed, err.Error()
+		return result
+	}
+	relocated, ok := overrideValue.(string)
+	if !ok {
+		result.Status, result.Reason = SimulationFailed, fmt.Sprintf("unexpected override of type %T", overrideValue)
+		return result
+	}
+	result.Status, result.Relocated = SimulationRelocated, relocated
+	return result
+}
+
+// skipReason explains why images of registryName are not eligible for relocation
+func (g *Generator) skipReason(registryName string) string {
+	normalized := image.NormalizeRegistry(registryName)
+	isNormalized := func(candidate string) bool { return image.NormalizeRegistry(candidate) == normalized }
+	if slices.ContainsFunc(g.excludeRegistries, isNormalized) {
+		return fmt.Sprintf("registry %s is excluded", normalized)
+	}
+	return fmt.Sprintf("registry %s is not a source registry", normalized)
+}
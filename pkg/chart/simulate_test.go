@@ -0,0 +1,44 @@
+package chart
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/lucas-albers-lz4/irr/pkg/strategy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator_Simulate(t *testing.T) {
+	mappings := &registry.Mappings{
+		Entries: []registry.Mapping{
+			{Source: "docker.io", Target: "harbor.local/dockerhub"},
+			{Source: "quay.io", Target: "harbor.local", TagTransform: "mirror-{{ .Tag }}"},
+		},
+	}
+	g := NewGenerator("", "default.local", []string{"docker.io", "quay.io", "ghcr.io"}, []string{"ghcr.io"},
+		strategy.NewPrefixSourceRegistryStrategy(mappings), mappings, false, 0, nil, false)
+	g.SetExceptions([]registry.RelocationException{{Image: "docker.io/vendor/**", Reason: "signed by vendor"}})
+
+	tests := []struct {
+		image string
+		want  SimulatedImage
+	}{
+		{"nginx:1.25", SimulatedImage{Status: SimulationRelocated, Relocated: "harbor.local/dockerhub/library/nginx:1.25"}},
+		{"quay.io/prometheus/node-exporter:v1.8.0", SimulatedImage{Status: SimulationRelocated, Relocated: "harbor.local/quay.io/prometheus/node-exporter:mirror-v1.8.0"}},
+		{"docker.io/vendor/agent:2.0", SimulatedImage{Status: SimulationExcluded, Reason: "exception image=docker.io/vendor/**: signed by vendor"}},
+		{"ghcr.io/org/app:1.0", SimulatedImage{Status: SimulationSkipped, Reason: "registry ghcr.io is excluded"}},
+		{"gcr.io/distroless/static:nonroot", SimulatedImage{Status: SimulationSkipped, Reason: "registry gcr.io is not a source registry"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			tt.want.Image = tt.image
+			assert.Equal(t, tt.want, g.Simulate(tt.image))
+		})
+	}
+
+	t.Run("invalid reference", func(t *testing.T) {
+		result := g.Simulate("not a valid image::")
+		assert.Equal(t, SimulationFailed, result.Status)
+		assert.NotEmpty(t, result.Reason)
+	})
+}
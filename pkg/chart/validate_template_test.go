@@ -1,12 +1,14 @@
 package chart
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/cli/values"
 )
 
 const (
@@ -38,7 +40,7 @@ image:
 `)
 
 		// Test validation with the override
-		err := validateHelmTemplateInternal(chartDir, overrideYaml)
+		err := validateHelmTemplateInternal(context.Background(), chartDir, overrideYaml, nil)
 		assert.NoError(t, err, "Template validation should succeed with valid override")
 	})
 
@@ -78,7 +80,7 @@ image:
 `)
 
 		// Expect validation to fail
-		err = validateHelmTemplateInternal(chartDir, overrideYaml)
+		err = validateHelmTemplateInternal(context.Background(), chartDir, overrideYaml, nil)
 		assert.Error(t, err, "Template validation should fail with invalid template")
 		assert.Contains(t, err.Error(), "chart template rendering error", "Error should indicate template rendering failure")
 	})
@@ -101,7 +103,7 @@ image:
 `)
 
 		// Test validation with invalid override
-		err := validateHelmTemplateInternal(chartDir, invalidOverride)
+		err := validateHelmTemplateInternal(context.Background(), chartDir, invalidOverride, nil)
 		assert.Error(t, err, "Template validation should fail with invalid override YAML")
 		// The error could be about YAML parsing or template rendering
 		assert.Contains(t, err.Error(), "failed to read override values", "Error should indicate values file issue")
@@ -109,7 +111,7 @@ image:
 
 	t.Run("Empty Chart Path", func(t *testing.T) {
 		// Test with empty chart path
-		err := validateHelmTemplateInternal("", []byte("image: nginx"))
+		err := validateHelmTemplateInternal(context.Background(), "", []byte("image: nginx"), nil)
 		assert.Error(t, err, "Template validation should fail with empty chart path")
 	})
 
@@ -124,10 +126,103 @@ version: 1.0.0
 		chartDir := createTempChartDir(t, "empty-override-test", chartYaml, valuesYaml)
 
 		// Test with empty override
-		err := validateHelmTemplateInternal(chartDir, []byte{})
+		err := validateHelmTemplateInternal(context.Background(), chartDir, []byte{}, nil)
 		assert.NoError(t, err, "Template validation should succeed with empty override")
 	})
 
+	t.Run("Deployment Context", func(t *testing.T) {
+		chartYaml := `
+apiVersion: v2
+name: context-test
+version: 1.0.0
+`
+		valuesYaml := TestNginxValues + "sidecar:\n  enabled: false\n"
+		chartDir := createTempChartDir(t, "context-test", chartYaml, valuesYaml)
+		sidecarTemplate := `
+{{- if ne .Release.Namespace "prod" }}{{ fail (printf "release %s must be deployed to prod" .Release.Name) }}{{ end }}
+{{- if .Values.sidecar.enabled }}
+apiVersion: v1
+kind: Pod
+metadata:
+  name: {{ .Release.Name }}-sidecar
+spec:
+  containers:
+  - name: sidecar
+    image: {{ required "sidecar.image is required" .Values.sidecar.image }}
+{{- end }}
+`
+		require.NoError(t, os.WriteFile(filepath.Join(chartDir, "templates", "sidecar.yaml"), []byte(sidecarTemplate), FilePermissions))
+		valuesFile := filepath.Join(t.TempDir(), "prod.yaml")
+		require.NoError(t, os.WriteFile(valuesFile, []byte("sidecar:\n  enabled: true\n"), FilePermissions))
+		overrideYaml := []byte("image:\n  registry: registry.example.com\n")
+
+		err := validateHelmTemplateInternal(context.Background(), chartDir, overrideYaml, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "release irr-validation must be deployed to prod")
+
+		opts := &ValidationOptions{
+			Values:      values.Options{ValueFiles: []string{valuesFile}},
+			ReleaseName: "web",
+			Namespace:   "prod",
+		}
+		err = validateHelmTemplateInternal(context.Background(), chartDir, overrideYaml, opts)
+		require.Error(t, err, "the --values file enables the sidecar template")
+		assert.Contains(t, err.Error(), "sidecar.image is required")
+
+		opts.Values.Values = []string{"sidecar.image=busybox:1.36"}
+		assert.NoError(t, validateHelmTemplateInternal(context.Background(), chartDir, overrideYaml, opts))
+	})
+
+	t.Run("Capabilities", func(t *testing.T) {
+		chartYaml := `
+apiVersion: v2
+name: capabilities-test
+version: 1.0.0
+`
+		chartDir := createTempChartDir(t, "capabilities-test", chartYaml, TestNginxValues)
+		capabilitiesTemplate := `
+{{- if semverCompare "<1.29.0" .Capabilities.KubeVersion.Version }}{{ fail "requires Kubernetes 1.29" }}{{ end }}
+{{- if not (.Capabilities.APIVersions.Has "monitoring.coreos.com/v1") }}{{ fail "requires the Prometheus operator" }}{{ end }}
+`
+		require.NoError(t, os.WriteFile(filepath.Join(chartDir, "templates", "capabilities.yaml"), []byte(capabilitiesTemplate), FilePermissions))
+		overrideYaml := []byte("image:\n  registry: registry.example.com\n")
+
+		opts := &ValidationOptions{KubeVersion: "1.28.0", APIVersions: []string{"monitoring.coreos.com/v1"}}
+		err := validateHelmTemplateInternal(context.Background(), chartDir, overrideYaml, opts)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "requires Kubernetes 1.29")
+
+		opts.KubeVersion = "1.30.2"
+		assert.NoError(t, validateHelmTemplateInternal(context.Background(), chartDir, overrideYaml, opts))
+
+		opts.APIVersions = nil
+		err = validateHelmTemplateInternal(context.Background(), chartDir, overrideYaml, opts)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "requires the Prometheus operator")
+
+		opts.KubeVersion = "not-a-version"
+		assert.ErrorContains(t, validateHelmTemplateInternal(context.Background(), chartDir, overrideYaml, opts), "invalid Kubernetes version")
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		chartYaml := `
+apiVersion: v2
+name: canceled-test
+version: 1.0.0
+`
+		chartDir := createTempChartDir(t, "canceled-test", chartYaml, TestNginxValues)
+		tempDir := t.TempDir()
+		t.Setenv("TMPDIR", tempDir)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := ValidateHelmTemplate(ctx, chartDir, []byte("image:\n  tag: 1.25\n"), nil)
+		require.ErrorIs(t, err, context.Canceled)
+		leftovers, err := os.ReadDir(tempDir)
+		require.NoError(t, err)
+		assert.Empty(t, leftovers, "the temporary overrides file is removed")
+	})
+
 	// Test with valid YAML using a simple Helm chart
 	t.Run("valid values", func(t *testing.T) {
 		// Create a temporary file for the values
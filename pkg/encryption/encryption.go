@@ -0,0 +1,258 @@
+// Package encryption encrypts generated overrides, for teams that treat the hostnames of their
+// registries as sensitive, and decrypts them again. It runs the age or sops command line tools,
+// which must be installed.
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats the overrides can be encrypted in.
+const (
+	// FormatAge encrypts the whole file for age recipients, as ASCII armor
+	FormatAge = "age"
+	// FormatSOPS encrypts the values of a YAML or JSON file with sops, keeping its keys readable
+	FormatSOPS = "sops"
+)
+
+// Data types of the files sops encrypts.
+const (
+	// TypeYAML is a YAML document
+	TypeYAML = "yaml"
+	// TypeJSON is a JSON document
+	TypeJSON = "json"
+	// TypeBinary is any other file, encrypted as a whole
+	TypeBinary = "binary"
+)
+
+// Markers of encrypted files.
+const (
+	// ageArmorHeader starts an age file encrypted with --armor
+	ageArmorHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+	// ageBinaryHeader starts an age file encrypted without --armor
+	ageBinaryHeader = "age-encryption.org/v1"
+	// sopsMetadataKey is the top-level key under which sops stores the metadata of a file
+	sopsMetadataKey = "sops"
+)
+
+// runCommand runs name with stdin as its input and env added to the environment, and returns its
+// standard output. It is a variable so tests can replace it.
+var runCommand = func(ctx context.Context, stdin []byte, env []string, name string, args ...string) ([]byte, error) {
+	command := exec.CommandContext(ctx, name, args...) //nolint:gosec // the tools are age or sops, or named by the user
+	command.Stdin = bytes.NewReader(stdin)
+	if len(env) > 0 {
+		command.Env = append(os.Environ(), env...)
+	}
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	output, err := command.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%s is not installed or not on PATH: %w", name, err)
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%s failed: %w: %s", name, err, message)
+		}
+		return nil, fmt.Errorf("%s failed: %w", name, err)
+	}
+	return output, nil
+}
+
+// Options configure how files are encrypted and decrypted.
+type Options struct {
+	// Format is FormatAge or FormatSOPS; when decrypting, an empty format is detected from the data
+	Format string
+	// Recipients are the keys files are encrypted to. For age: age or SSH public keys, or files
+	// listing them. For sops: age public keys, PGP fingerprints, AWS KMS key ARNs, GCP KMS key
+	// resource IDs or Azure Key Vault key URLs; without any, sops uses its .sops.yaml rules.
+	Recipients []string
+	// Identities are the age identity files files are decrypted with. sops reads the first from
+	// SOPS_AGE_KEY_FILE; its other keys are found as sops finds them.
+	Identities []string
+	// DataType is the TypeYAML, TypeJSON or TypeBinary data sops encrypts; TypeYAML if empty
+	DataType string
+	// Binary is the executable run; the age or sops found on PATH if empty
+	Binary string
+}
+
+// ValidateFormat checks that format is a supported encryption format.
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatAge, FormatSOPS:
+		return nil
+	default:
+		return fmt.Errorf("unsupported encryption format %q (supported: %s, %s)", format, FormatAge, FormatSOPS)
+	}
+}
+
+// Encrypt encrypts data for the recipients of opts.
+func Encrypt(ctx context.Context, data []byte, opts Options) ([]byte, error) {
+	if err := ValidateFormat(opts.Format); err != nil {
+		return nil, err
+	}
+	var args []string
+	if opts.Format == FormatAge {
+		if len(opts.Recipients) == 0 {
+			return nil, errors.New("age encryption requires at least one recipient")
+		}
+		args = []string{"--encrypt", "--armor"}
+		for _, recipient := range opts.Recipients {
+			if isAgeKey(recipient) {
+				args = append(args, "--recipient", recipient)
+			} else {
+				args = append(args, "--recipients-file", recipient)
+			}
+		}
+	} else {
+		args = append([]string{"--encrypt"}, sopsRecipientArgs(opts.Recipients)...)
+		args = append(args, sopsTypeArgs(opts.DataType)...)
+		return runSOPS(ctx, data, nil, opts.binary(), args...)
+	}
+	return runCommand(ctx, data, nil, opts.binary(), args...)
+}
+
+// Decrypt decrypts data with the identities of opts, detecting its format if opts sets none.
+func Decrypt(ctx context.Context, data []byte, opts Options) ([]byte, error) {
+	if opts.Format == "" {
+		opts.Format = Detect(data)
+		if opts.Format == "" {
+			return nil, errors.New("data is not encrypted with age or sops")
+		}
+	}
+	if err := ValidateFormat(opts.Format); err != nil {
+		return nil, err
+	}
+	if opts.Format == FormatAge {
+		if len(opts.Identities) == 0 {
+			return nil, errors.New("age decryption requires an identity file")
+		}
+		args := []string{"--decrypt"}
+		for _, identity := range opts.Identities {
+			args = append(args, "--identity", identity)
+		}
+		return runCommand(ctx, data, nil, opts.binary(), args...)
+	}
+	var env []string
+	if len(opts.Identities) > 0 {
+		env = []string{"SOPS_AGE_KEY_FILE=" + opts.Identities[0]}
+	}
+	args := append([]string{"--decrypt"}, sopsTypeArgs(opts.DataType)...)
+	return runSOPS(ctx, data, env, opts.binary(), args...)
+}
+
+// runSOPS runs sops on data, which is written to a temporary file readable only by the user, as
+// sops reads its input from a file and /dev/stdin does not exist on every platform. The file is
+// removed when sops exits.
+func runSOPS(ctx context.Context, data []byte, env []string, binary string, args ...string) ([]byte, error) {
+	file, err := os.CreateTemp("", "irr-sops-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the sops input file: %w", err)
+	}
+	defer func() {
+		if removeErr := os.Remove(file.Name()); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+			log.Warn("Failed to remove the sops input file", "path", file.Name(), "error", removeErr)
+		}
+	}()
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write the sops input file: %w", err)
+	}
+	return runCommand(ctx, nil, env, binary, append(args, file.Name())...)
+}
+
+// Detect returns the format data is encrypted in, or "" if it is not encrypted with age or sops.
+// sops files are recognized by their metadata; only YAML and JSON files are detected.
+func Detect(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte(ageArmorHeader)) || bytes.HasPrefix(trimmed, []byte(ageBinaryHeader)) {
+		return FormatAge
+	}
+	// JSON is YAML, so both are parsed as YAML
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return ""
+	}
+	if metadata, ok := document[sopsMetadataKey].(map[string]interface{}); ok {
+		if _, ok := metadata["mac"]; ok {
+			return FormatSOPS
+		}
+	}
+	return ""
+}
+
+// DataTypeOf returns the data type sops treats a file of the output format as: TypeYAML for
+// yaml, TypeJSON for json and TypeBinary for any other.
+func DataTypeOf(outputFormat string) string {
+	switch strings.ToLower(outputFormat) {
+	case TypeYAML, "":
+		return TypeYAML
+	case TypeJSON:
+		return TypeJSON
+	default:
+		return TypeBinary
+	}
+}
+
+// binary returns the executable run for the format of o
+func (o Options) binary() string {
+	if o.Binary != "" {
+		return o.Binary
+	}
+	return o.Format
+}
+
+// isAgeKey reports whether an age recipient is a key rather than a recipients file
+func isAgeKey(recipient string) bool {
+	return strings.HasPrefix(recipient, "age1") || strings.HasPrefix(recipient, "ssh-")
+}
+
+// sopsRecipientArgs returns the sops flags encrypting to the recipients, by the kind of key
+func sopsRecipientArgs(recipients []string) []string {
+	kinds := []struct {
+		flag    string
+		matches func(string) bool
+		keys    []string
+	}{
+		{flag: "--age", matches: func(r string) bool { return strings.HasPrefix(r, "age1") }},
+		{flag: "--kms", matches: func(r string) bool { return strings.HasPrefix(r, "arn:aws") }},
+		{flag: "--gcp-kms", matches: func(r string) bool { return strings.HasPrefix(r, "projects/") }},
+		{flag: "--azure-kv", matches: func(r string) bool { return strings.HasPrefix(r, "https://") }},
+		{flag: "--pgp", matches: func(string) bool { return true }},
+	}
+	for _, recipient := range recipients {
+		for i := range kinds {
+			if kinds[i].matches(recipient) {
+				kinds[i].keys = append(kinds[i].keys, recipient)
+				break
+			}
+		}
+	}
+	var args []string
+	for _, kind := range kinds {
+		if len(kind.keys) > 0 {
+			args = append(args, kind.flag, strings.Join(kind.keys, ","))
+		}
+	}
+	return args
+}
+
+// sopsTypeArgs returns the sops flags reading and writing a file of dataType
+func sopsTypeArgs(dataType string) []string {
+	if dataType == "" {
+		dataType = TypeYAML
+	}
+	return []string{"--input-type", dataType, "--output-type", dataType}
+}
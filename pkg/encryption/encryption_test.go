@@ -0,0 +1,191 @@
+package encryption
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedCommand is a command run by the replaced runCommand
+type recordedCommand struct {
+	stdin string
+	// input is the content of the sops input file, the last argument of sops
+	input string
+	env   []string
+	name  string
+	args  []string
+}
+
+// replaceRunCommand records the commands run until the test ends, answering them with output
+func replaceRunCommand(t *testing.T, output string) *[]recordedCommand {
+	t.Helper()
+	original := runCommand
+	t.Cleanup(func() { runCommand = original })
+	var commands []recordedCommand
+	runCommand = func(_ context.Context, stdin []byte, env []string, name string, args ...string) ([]byte, error) {
+		command := recordedCommand{stdin: string(stdin), env: env, name: name, args: args}
+		if strings.HasSuffix(name, FormatSOPS) {
+			input, err := os.ReadFile(args[len(args)-1])
+			require.NoError(t, err)
+			command.input = string(input)
+			command.args = args[:len(args)-1]
+		}
+		commands = append(commands, command)
+		return []byte(output), nil
+	}
+	return &commands
+}
+
+func TestEncrypt(t *testing.T) {
+	data := []byte("image:\n  registry: harbor.internal\n")
+
+	t.Run("age", func(t *testing.T) {
+		commands := replaceRunCommand(t, ageArmorHeader+"\n...")
+		encrypted, err := Encrypt(context.Background(), data, Options{
+			Format:     FormatAge,
+			Recipients: []string{"age1qqq", "ssh-ed25519 AAAA", "team.keys"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, FormatAge, Detect(encrypted))
+		require.Len(t, *commands, 1)
+		assert.Equal(t, recordedCommand{stdin: string(data), name: "age", args: []string{
+			"--encrypt", "--armor", "--recipient", "age1qqq", "--recipient", "ssh-ed25519 AAAA", "--recipients-file", "team.keys",
+		}}, (*commands)[0])
+	})
+
+	t.Run("sops", func(t *testing.T) {
+		commands := replaceRunCommand(t, "{}")
+		_, err := Encrypt(context.Background(), data, Options{
+			Format:     FormatSOPS,
+			Recipients: []string{"age1qqq", "age1rrr", "arn:aws:kms:us-east-1:123456789012:key/abc", "85D77543B3D624B63CEA9E6DBC17301B491B3F21"},
+			DataType:   TypeJSON,
+			Binary:     "/opt/bin/sops",
+		})
+		require.NoError(t, err)
+		require.Len(t, *commands, 1)
+		assert.Equal(t, "/opt/bin/sops", (*commands)[0].name)
+		assert.Equal(t, []string{
+			"--encrypt", "--age", "age1qqq,age1rrr", "--kms", "arn:aws:kms:us-east-1:123456789012:key/abc",
+			"--pgp", "85D77543B3D624B63CEA9E6DBC17301B491B3F21",
+			"--input-type", "json", "--output-type", "json",
+		}, (*commands)[0].args)
+		assert.Equal(t, string(data), (*commands)[0].input)
+		assert.Empty(t, (*commands)[0].stdin)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		commands := replaceRunCommand(t, "")
+		_, err := Encrypt(context.Background(), data, Options{Format: FormatAge})
+		assert.ErrorContains(t, err, "at least one recipient")
+		_, err = Encrypt(context.Background(), data, Options{Format: "gpg"})
+		assert.ErrorContains(t, err, "unsupported encryption format")
+		assert.Empty(t, *commands)
+	})
+}
+
+func TestDecrypt(t *testing.T) {
+	sopsFile := []byte("image:\n  registry: ENC[AES256_GCM,data:abc]\nsops:\n  mac: ENC[AES256_GCM,data:def]\n  version: 3.9.0\n")
+
+	commands := replaceRunCommand(t, "image:\n  registry: harbor.internal\n")
+	decrypted, err := Decrypt(context.Background(), sopsFile, Options{Identities: []string{"keys.txt"}})
+	require.NoError(t, err)
+	assert.Equal(t, "image:\n  registry: harbor.internal\n", string(decrypted))
+	require.Len(t, *commands, 1)
+	assert.Equal(t, recordedCommand{
+		input: string(sopsFile),
+		env:   []string{"SOPS_AGE_KEY_FILE=keys.txt"},
+		name:  "sops",
+		args:  []string{"--decrypt", "--input-type", "yaml", "--output-type", "yaml"},
+	}, (*commands)[0])
+
+	_, err = Decrypt(context.Background(), []byte(ageArmorHeader+"\n..."), Options{Identities: []string{"a.txt", "b.txt"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--decrypt", "--identity", "a.txt", "--identity", "b.txt"}, (*commands)[1].args)
+
+	_, err = Decrypt(context.Background(), []byte(ageArmorHeader+"\n..."), Options{})
+	assert.ErrorContains(t, err, "requires an identity file")
+	_, err = Decrypt(context.Background(), []byte("image: {}\n"), Options{})
+	assert.ErrorContains(t, err, "not encrypted")
+}
+
+func TestDetect(t *testing.T) {
+	tests := map[string]struct {
+		data     string
+		expected string
+	}{
+		"age armor":      {data: "\n" + ageArmorHeader + "\nYWdl\n-----END AGE ENCRYPTED FILE-----\n", expected: FormatAge},
+		"age binary":     {data: ageBinaryHeader + "\n-> X25519 abc\n", expected: FormatAge},
+		"sops yaml":      {data: "a: ENC[AES256_GCM,data:x]\nsops:\n  mac: ENC[AES256_GCM,data:y]\n", expected: FormatSOPS},
+		"sops json":      {data: `{"a": "ENC[AES256_GCM,data:x]", "sops": {"mac": "ENC[AES256_GCM,data:y]"}}`, expected: FormatSOPS},
+		"plain":          {data: "image:\n  registry: harbor.internal\n"},
+		"sops key value": {data: "sops: enabled\n"},
+		"set flags":      {data: "--set image.registry=harbor.internal\n"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Detect([]byte(tt.data)))
+		})
+	}
+}
+
+func TestDataTypeOf(t *testing.T) {
+	assert.Equal(t, TypeYAML, DataTypeOf("YAML"))
+	assert.Equal(t, TypeJSON, DataTypeOf("json"))
+	assert.Equal(t, TypeBinary, DataTypeOf("set-flags"))
+}
+
+// ageKey generates an age identity file with age-keygen, skipping the test if age is not installed,
+// and returns the file and its public key.
+func ageKey(t *testing.T) (identity, recipient string) {
+	t.Helper()
+	for _, tool := range []string{"age", "age-keygen"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not installed", tool)
+		}
+	}
+	identity = filepath.Join(t.TempDir(), "keys.txt")
+	require.NoError(t, exec.Command("age-keygen", "-o", identity).Run())
+	keys, err := os.ReadFile(identity)
+	require.NoError(t, err)
+	for _, line := range strings.Split(string(keys), "\n") {
+		if key, ok := strings.CutPrefix(line, "# public key: "); ok {
+			return identity, key
+		}
+	}
+	t.Fatalf("no public key in %s", identity)
+	return "", ""
+}
+
+func TestEncryptWithTools(t *testing.T) {
+	data := []byte("image:\n  registry: harbor.internal\n")
+
+	t.Run("age", func(t *testing.T) {
+		identity, recipient := ageKey(t)
+		encrypted, err := Encrypt(context.Background(), data, Options{Format: FormatAge, Recipients: []string{recipient}})
+		require.NoError(t, err)
+		assert.Equal(t, FormatAge, Detect(encrypted))
+		decrypted, err := Decrypt(context.Background(), encrypted, Options{Identities: []string{identity}})
+		require.NoError(t, err)
+		assert.Equal(t, string(data), string(decrypted))
+	})
+
+	t.Run("sops", func(t *testing.T) {
+		if _, err := exec.LookPath("sops"); err != nil {
+			t.Skip("sops is not installed")
+		}
+		identity, recipient := ageKey(t)
+		encrypted, err := Encrypt(context.Background(), data, Options{Format: FormatSOPS, Recipients: []string{recipient}})
+		require.NoError(t, err)
+		assert.Equal(t, FormatSOPS, Detect(encrypted))
+		assert.NotContains(t, string(encrypted), "harbor.internal")
+		decrypted, err := Decrypt(context.Background(), encrypted, Options{Identities: []string{identity}})
+		require.NoError(t, err)
+		assert.Equal(t, string(data), string(decrypted))
+	})
+}
@@ -0,0 +1,132 @@
+package exitcodes
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+)
+
+// Category groups exit codes by the kind of failure, for automation that only needs to know
+// whether, e.g., the chart or the registry configuration is at fault.
+type Category string
+
+// Error categories
+const (
+	CategoryInput     Category = "input"      // Invalid flags or configuration
+	CategoryMapping   Category = "mapping"    // Registries without a mapping
+	CategoryPolicy    Category = "policy"     // A strict mode or admission policy failed the run
+	CategoryTest      Category = "test"       // Generated overrides differ from the expected ones
+	CategoryChartLoad Category = "chart-load" // The chart could not be found, loaded or verified
+	CategoryParse     Category = "parse"      // The chart or its image references could not be processed
+	CategoryHelm      Category = "helm"       // A Helm command or SDK call failed
+	CategoryIO        Category = "io"         // Reading or writing a file or remote resource failed
+	CategoryRuntime   Category = "runtime"    // Any other failure
+	CategoryInternal  Category = "internal"   // A bug in irr
+	CategoryPartial   Category = "partial"    // Overrides written with some image paths skipped
+)
+
+// codeInfo is the stable error code and category of each exit code
+var codeInfo = map[int]struct {
+	name     string
+	category Category
+}{
+	ExitMissingRequiredFlag:     {"missing-required-flag", CategoryInput},
+	ExitInputConfigurationError: {"invalid-configuration", CategoryInput},
+	ExitCodeInvalidStrategy:     {"invalid-path-strategy", CategoryInput},
+	ExitChartNotFound:           {"chart-not-found", CategoryChartLoad},
+	ExitRegistryDetectionError:  {"unmapped-registry", CategoryMapping},
+	ExitEmptyRepositoryError:    {"empty-repository", CategoryPolicy},
+	ExitPolicyDeniedError:       {"policy-denied", CategoryPolicy},
+	ExitGoldenMismatch:          {"golden-mismatch", CategoryTest},
+	ExitPartialSuccess:          {"partial-overrides", CategoryPartial},
+	ExitChartParsingError:       {"chart-parse-failed", CategoryParse},
+	ExitImageProcessingError:    {"image-processing-failed", CategoryParse},
+	ExitUnsupportedStructure:    {"unsupported-structure", CategoryPolicy},
+	ExitThresholdError:          {"threshold-not-met", CategoryPolicy},
+	ExitChartLoadFailed:         {"chart-load-failed", CategoryChartLoad},
+	ExitChartProcessingFailed:   {"chart-processing-failed", CategoryParse},
+	ExitHelmCommandFailed:       {"helm-command-failed", CategoryHelm},
+	ExitHelmInteractionError:    {"helm-interaction-failed", CategoryHelm},
+	ExitHelmTemplateFailed:      {"helm-template-failed", CategoryHelm},
+	ExitChartVerificationFailed: {"chart-verification-failed", CategoryChartLoad},
+	ExitGeneralRuntimeError:     {"runtime-error", CategoryRuntime},
+	ExitIOError:                 {"io-error", CategoryIO},
+	ExitInterrupted:             {"interrupted", CategoryRuntime},
+	ExitInternalError:           {"internal-error", CategoryInternal},
+	ExitMutableReferenceError:   {"mutable-reference", CategoryPolicy},
+}
+
+// CodeName returns the stable, machine-readable error code of an exit code, e.g. chart-load-failed.
+// Unknown exit codes are reported as runtime-error.
+func CodeName(code int) string {
+	if info, ok := codeInfo[code]; ok {
+		return info.name
+	}
+	return codeInfo[ExitGeneralRuntimeError].name
+}
+
+// CodeCategory returns the category of an exit code. Unknown exit codes are runtime failures.
+func CodeCategory(code int) Category {
+	if info, ok := codeInfo[code]; ok {
+		return info.category
+	}
+	return CategoryRuntime
+}
+
+// ErrorCode returns the stable error code of the error, see CodeName.
+func (e *ExitCodeError) ErrorCode() string {
+	return CodeName(e.Code)
+}
+
+// Category returns the category of the error, see CodeCategory.
+func (e *ExitCodeError) Category() Category {
+	return CodeCategory(e.Code)
+}
+
+// ErrorInfo is the machine-readable description of a failure, as written in JSON and YAML reports.
+type ErrorInfo struct {
+	// ErrorCode is the stable error code, e.g. chart-load-failed
+	ErrorCode string `json:"errorCode" yaml:"errorCode"`
+	// Category groups error codes by kind of failure, e.g. chart-load
+	Category Category `json:"category" yaml:"category"`
+	// ExitCode is the process exit code of the failure
+	ExitCode int `json:"exitCode" yaml:"exitCode"`
+	// Message is the error message, without the exit code prefix of ExitCodeError
+	Message string `json:"message" yaml:"message"`
+}
+
+// Classify describes err for machine-readable output. Errors caused by a canceled context, such as
+// an interrupt, are classified as interrupted. Otherwise the exit code comes from the first Coder in
+// the error chain; errors without one are classified as IO errors when they come from the file
+// system, and as runtime errors otherwise. It returns nil for a nil error.
+func Classify(err error) *ErrorInfo {
+	if err == nil {
+		return nil
+	}
+	info := &ErrorInfo{Message: err.Error()}
+	var coder Coder
+	hasCoder := errors.As(err, &coder)
+	if exitErr, ok := coder.(*ExitCodeError); hasCoder && ok && exitErr.Err != nil {
+		info.Message = exitErr.Err.Error()
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		// The interrupt is the cause, whichever step was stopped by it
+		info.ExitCode = ExitInterrupted
+	case hasCoder:
+		info.ExitCode = coder.ExitCode()
+	case isFileSystemError(err):
+		info.ExitCode = ExitIOError
+	default:
+		info.ExitCode = ExitGeneralRuntimeError
+	}
+	info.ErrorCode = CodeName(info.ExitCode)
+	info.Category = CodeCategory(info.ExitCode)
+	return info
+}
+
+// isFileSystemError reports whether err comes from a file system operation
+func isFileSystemError(err error) bool {
+	var pathErr *fs.PathError
+	return errors.As(err, &pathErr) || errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission)
+}
@@ -0,0 +1,127 @@
+package exitcodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// codedError is a typed error of another package that carries an exit code
+type codedError struct{}
+
+func (codedError) Error() string { return "chart could not be loaded" }
+func (codedError) ExitCode() int { return ExitChartLoadFailed }
+
+func TestClassify(t *testing.T) {
+	_, statErr := os.Stat("/nonexistent/irr/values.yaml")
+	testCases := []struct {
+		name         string
+		err          error
+		wantCode     string
+		wantCategory Category
+		wantExitCode int
+		wantMessage  string
+	}{
+		{
+			name:         "exit code error",
+			err:          fmt.Errorf("execute command: %w", &ExitCodeError{Code: ExitChartLoadFailed, Err: errors.New("chart not loadable")}),
+			wantCode:     "chart-load-failed",
+			wantCategory: CategoryChartLoad,
+			wantExitCode: ExitChartLoadFailed,
+			wantMessage:  "chart not loadable",
+		},
+		{
+			name:         "typed error",
+			err:          fmt.Errorf("loading: %w", codedError{}),
+			wantCode:     "chart-load-failed",
+			wantCategory: CategoryChartLoad,
+			wantExitCode: ExitChartLoadFailed,
+			wantMessage:  "loading: chart could not be loaded",
+		},
+		{
+			name:         "file system error",
+			err:          fmt.Errorf("reading values: %w", statErr),
+			wantCode:     "io-error",
+			wantCategory: CategoryIO,
+			wantExitCode: ExitIOError,
+			wantMessage:  fmt.Sprintf("reading values: %v", statErr),
+		},
+		{
+			name: "interrupted",
+			err: &ExitCodeError{
+				Code: ExitHelmCommandFailed,
+				Err:  fmt.Errorf("failed to list Helm releases: %w", context.Canceled),
+			},
+			wantCode:     "interrupted",
+			wantCategory: CategoryRuntime,
+			wantExitCode: ExitInterrupted,
+			wantMessage:  "failed to list Helm releases: context canceled",
+		},
+		{
+			name:         "plain error",
+			err:          errors.New("something failed"),
+			wantCode:     "runtime-error",
+			wantCategory: CategoryRuntime,
+			wantExitCode: ExitGeneralRuntimeError,
+			wantMessage:  "something failed",
+		},
+		{
+			name:         "unknown exit code",
+			err:          &ExitCodeError{Code: 99, Err: errors.New("odd")},
+			wantCode:     "runtime-error",
+			wantCategory: CategoryRuntime,
+			wantExitCode: 99,
+			wantMessage:  "odd",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := Classify(tc.err)
+			if info == nil {
+				t.Fatal("Classify() = nil")
+			}
+			if info.ErrorCode != tc.wantCode || info.Category != tc.wantCategory || info.ExitCode != tc.wantExitCode || info.Message != tc.wantMessage {
+				t.Errorf("Classify() = %+v, want {ErrorCode:%s Category:%s ExitCode:%d Message:%s}",
+					*info, tc.wantCode, tc.wantCategory, tc.wantExitCode, tc.wantMessage)
+			}
+		})
+	}
+
+	if info := Classify(nil); info != nil {
+		t.Errorf("Classify(nil) = %+v, want nil", *info)
+	}
+}
+
+func TestCodeNames(t *testing.T) {
+	names := make(map[string]int)
+	for code := range CodeDescriptions {
+		if code == ExitSuccess {
+			continue
+		}
+		info, ok := codeInfo[code]
+		if !ok {
+			t.Errorf("exit code %d has no error code", code)
+			continue
+		}
+		if other, dup := names[info.name]; dup {
+			t.Errorf("exit codes %d and %d share the error code %s", code, other, info.name)
+		}
+		names[info.name] = code
+		if info.category == "" {
+			t.Errorf("exit code %d has no category", code)
+		}
+	}
+}
+
+func TestExitCodeErrorClassification(t *testing.T) {
+	err := &ExitCodeError{Code: ExitHelmTemplateFailed, Err: errors.New("template failed")}
+	if err.ErrorCode() != "helm-template-failed" || err.Category() != CategoryHelm || err.ExitCode() != ExitHelmTemplateFailed {
+		t.Errorf("got (%s, %s, %d)", err.ErrorCode(), err.Category(), err.ExitCode())
+	}
+	if code, ok := IsExitCodeError(codedError{}); !ok || code != ExitChartLoadFailed {
+		t.Errorf("IsExitCodeError(typed error) = (%d, %v), want (%d, true)", code, ok, ExitChartLoadFailed)
+	}
+}
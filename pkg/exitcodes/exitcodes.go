@@ -5,6 +5,8 @@
 //	1-9:   Input/Configuration Errors (e.g., missing flags, invalid config)
 //	10-19: Chart Processing Errors (e.g., unsupported structures, parsing failures)
 //	20-29: Runtime Errors (e.g., I/O errors, system failures)
+//	30-39: Internal Errors
+//	40-49: Policy Errors (e.g., images referenced by mutable tags)
 package exitcodes
 
 import (
@@ -23,24 +25,33 @@ const (
 	ExitCodeInvalidStrategy     = 3 // Invalid path strategy specified
 	ExitChartNotFound           = 4 // Chart or values file not found
 	ExitRegistryDetectionError  = 5 // No registries found or couldn't map registries
+	ExitEmptyRepositoryError    = 6 // Image with an empty repository found (strict mode policy)
+	ExitPolicyDeniedError       = 7 // Cluster admission policy denied the rendered resources (validate --against-cluster)
+	ExitGoldenMismatch          = 8 // Generated overrides differ from the golden file (irr test)
+	ExitPartialSuccess          = 9 // Overrides written with some paths skipped (override --ignore-errors)
 
 	// Chart Processing Errors (10-19)
-	ExitChartParsingError     = 10 // Failed to parse or load chart
-	ExitImageProcessingError  = 11 // Failed to process image references
-	ExitUnsupportedStructure  = 12 // Unsupported structure found (e.g., templates in strict mode)
-	ExitThresholdError        = 13 // Failed to meet processing success threshold
-	ExitChartLoadFailed       = 14 // Failed to load chart
-	ExitChartProcessingFailed = 15 // Failed to process chart
-	ExitHelmCommandFailed     = 16 // Helm command execution failed
-	ExitHelmInteractionError  = 17 // Error during Helm SDK interaction
-	ExitHelmTemplateFailed    = 18 // Helm template command failed specifically
+	ExitChartParsingError       = 10 // Failed to parse or load chart
+	ExitImageProcessingError    = 11 // Failed to process image references
+	ExitUnsupportedStructure    = 12 // Unsupported structure found (e.g., templates in strict mode)
+	ExitThresholdError          = 13 // Failed to meet processing success threshold
+	ExitChartLoadFailed         = 14 // Failed to load chart
+	ExitChartProcessingFailed   = 15 // Failed to process chart
+	ExitHelmCommandFailed       = 16 // Helm command execution failed
+	ExitHelmInteractionError    = 17 // Error during Helm SDK interaction
+	ExitHelmTemplateFailed      = 18 // Helm template command failed specifically
+	ExitChartVerificationFailed = 19 // Chart provenance or signature verification failed
 
 	// Runtime Errors (20-29)
 	ExitGeneralRuntimeError = 20 // General runtime/system error
 	ExitIOError             = 21 // IO operation error
+	ExitInterrupted         = 22 // Canceled by an interrupt or termination signal
 
 	// Internal Errors (30-39)
 	ExitInternalError = 30 // Internal error in command execution
+
+	// Policy Errors (40-49)
+	ExitMutableReferenceError = 40 // Relocated images are referenced by mutable tags (--require-immutable-refs)
 )
 
 // ExitCodeError wraps an error with an exit code for consistent error handling.
@@ -59,12 +70,24 @@ func (e *ExitCodeError) Unwrap() error {
 	return e.Err
 }
 
-// IsExitCodeError checks if an error is an ExitCodeError and returns its code.
-// Returns false and 0 if the error is not an ExitCodeError.
+// ExitCode returns the exit code of the error.
+func (e *ExitCodeError) ExitCode() int {
+	return e.Code
+}
+
+// Coder is implemented by typed errors that know the exit code they end the run with.
+// ExitCodeError implements it, as do typed errors of other packages such as chart loading errors.
+type Coder interface {
+	error
+	ExitCode() int
+}
+
+// IsExitCodeError checks if an error is an ExitCodeError, or another Coder, and returns its code.
+// Returns false and 0 if no error in the chain carries an exit code.
 func IsExitCodeError(err error) (int, bool) {
-	var exitErr *ExitCodeError
-	if errors.As(err, &exitErr) {
-		return exitErr.Code, true
+	var coder Coder
+	if errors.As(err, &coder) {
+		return coder.ExitCode(), true
 	}
 	return 0, false
 }
@@ -77,6 +100,10 @@ var CodeDescriptions = map[int]string{
 	ExitCodeInvalidStrategy:     "Invalid path strategy specified",
 	ExitChartNotFound:           "Chart or values file not found",
 	ExitRegistryDetectionError:  "No registries found or couldn't map registries",
+	ExitEmptyRepositoryError:    "Image with an empty repository found",
+	ExitPolicyDeniedError:       "Cluster admission policy denied rendered resources",
+	ExitGoldenMismatch:          "Generated overrides differ from the golden file",
+	ExitPartialSuccess:          "Partial overrides written; some image paths failed",
 	ExitChartParsingError:       "Failed to parse or load chart",
 	ExitImageProcessingError:    "Failed to process image references",
 	ExitUnsupportedStructure:    "Unsupported structure found (e.g., templates in strict mode)",
@@ -86,7 +113,9 @@ var CodeDescriptions = map[int]string{
 	ExitHelmCommandFailed:       "Helm command execution failed",
 	ExitHelmInteractionError:    "Error during Helm SDK interaction",
 	ExitHelmTemplateFailed:      "Helm template command failed",
+	ExitChartVerificationFailed: "Chart provenance or signature verification failed",
 	ExitGeneralRuntimeError:     "General runtime/system error",
 	ExitIOError:                 "IO operation error",
 	ExitInternalError:           "Internal error in command execution",
+	ExitMutableReferenceError:   "Relocated images are referenced by mutable tags",
 }
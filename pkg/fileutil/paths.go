@@ -0,0 +1,55 @@
+package fileutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// maxUpwardSearchDepth bounds FindUpwards in case a path never reaches a root
+const maxUpwardSearchDepth = 100
+
+// CleanPath normalizes a user-supplied file path for the current OS. Forward slashes become the OS
+// separator, so C:/charts/app and //server/share/app work on Windows, and the path is cleaned.
+// URLs such as oci:// references are returned unchanged.
+func CleanPath(path string) string {
+	if path == "" || strings.Contains(path, "://") {
+		return path
+	}
+	return filepath.Clean(filepath.FromSlash(path))
+}
+
+// IsFilesystemRoot reports whether path is the root of its volume: / on Unix, or a drive root
+// (C:\) or UNC share (\\server\share) on Windows.
+func IsFilesystemRoot(path string) bool {
+	cleaned := filepath.Clean(path)
+	return filepath.IsAbs(cleaned) && filepath.Dir(cleaned) == cleaned
+}
+
+// FindUpwards returns the first directory containing an entry called name, starting at startDir and
+// walking up its parents to the root of its volume. startDir is made absolute first, so the search
+// stops at a drive or UNC share root on Windows just as it stops at / elsewhere.
+func FindUpwards(fs afero.Fs, startDir, name string) (string, error) {
+	dir, err := filepath.Abs(CleanPath(startDir))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve directory %s: %w", startDir, err)
+	}
+
+	for range maxUpwardSearchDepth {
+		exists, err := afero.Exists(fs, filepath.Join(dir, name))
+		if err != nil {
+			return "", fmt.Errorf("failed to check for %s in %s: %w", name, dir, err)
+		}
+		if exists {
+			return dir, nil
+		}
+		if IsFilesystemRoot(dir) {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	return "", fmt.Errorf("%s not found in %s or any parent directory: %w", name, startDir, os.ErrNotExist)
+}
@@ -0,0 +1,59 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanPath(t *testing.T) {
+	assert.Equal(t, "", CleanPath(""))
+	assert.Equal(t, filepath.Join("charts", "app"), CleanPath("charts/app/"))
+	assert.Equal(t, filepath.Join("charts", "app"), CleanPath("./charts//app"))
+	assert.Equal(t, "oci://ghcr.io/org/chart", CleanPath("oci://ghcr.io/org/chart"))
+}
+
+func TestIsFilesystemRoot(t *testing.T) {
+	root := filepath.VolumeName(t.TempDir()) + string(filepath.Separator)
+	assert.True(t, IsFilesystemRoot(root))
+	assert.False(t, IsFilesystemRoot(t.TempDir()))
+	assert.False(t, IsFilesystemRoot("."))
+	assert.False(t, IsFilesystemRoot("charts"))
+}
+
+func TestFindUpwards(t *testing.T) {
+	base := t.TempDir()
+	chartDir := filepath.Join(base, "charts", "app")
+	nestedDir := filepath.Join(chartDir, "templates", "tests")
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, fs.MkdirAll(nestedDir, 0o755))
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(chartDir, "Chart.yaml"), []byte("name: app\n"), 0o644))
+
+	t.Run("in start directory", func(t *testing.T) {
+		found, err := FindUpwards(fs, chartDir, "Chart.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, chartDir, found)
+	})
+
+	t.Run("in parent directory", func(t *testing.T) {
+		found, err := FindUpwards(fs, nestedDir, "Chart.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, chartDir, found)
+	})
+
+	t.Run("forward slashes", func(t *testing.T) {
+		found, err := FindUpwards(fs, filepath.ToSlash(nestedDir), "Chart.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, chartDir, found)
+	})
+
+	t.Run("not found stops at the root", func(t *testing.T) {
+		_, err := FindUpwards(fs, filepath.Join(base, "charts"), "Chart.yaml")
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
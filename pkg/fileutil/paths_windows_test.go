@@ -0,0 +1,42 @@
+//go:build windows
+
+package fileutil
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanPath_Windows(t *testing.T) {
+	assert.Equal(t, `C:\charts\app`, CleanPath("C:/charts/app/"))
+	assert.Equal(t, `\\server\share\charts\app`, CleanPath("//server/share/charts/app"))
+	assert.Equal(t, `\\server\share\charts\app`, CleanPath(`\\server\share\charts\app\`))
+}
+
+func TestIsFilesystemRoot_Windows(t *testing.T) {
+	assert.True(t, IsFilesystemRoot(`C:\`))
+	assert.True(t, IsFilesystemRoot("C:/"))
+	assert.True(t, IsFilesystemRoot(`\\server\share`))
+	assert.True(t, IsFilesystemRoot(`\\server\share\`))
+	assert.False(t, IsFilesystemRoot(`C:`))
+	assert.False(t, IsFilesystemRoot(`C:\charts`))
+	assert.False(t, IsFilesystemRoot(`\\server\share\charts`))
+}
+
+func TestFindUpwards_UNC(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	chartDir := `\\server\share\charts\app`
+	require.NoError(t, fs.MkdirAll(filepath.Join(chartDir, "templates"), 0o755))
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(chartDir, "Chart.yaml"), []byte("name: app\n"), 0o644))
+
+	found, err := FindUpwards(fs, chartDir+`\templates`, "Chart.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, chartDir, found)
+
+	_, err = FindUpwards(fs, `\\server\share\other`, "Chart.yaml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,218 @@
+// Package helmfile reads the releases of a Helmfile project (helmfile.yaml) and adds values files
+// to them, so that irr can generate and wire in the image overrides of every release.
+package helmfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrTemplated is returned when a helmfile uses Go template expressions, which must be rendered by
+// helmfile before the releases can be read and which an edit of the file would not preserve.
+var ErrTemplated = errors.New("helmfile contains template expressions")
+
+// Repository is a chart repository declared under repositories.
+type Repository struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// OCI marks a repository of OCI charts, whose URL has no oci:// scheme
+	OCI bool `yaml:"oci,omitempty"`
+}
+
+// SetValue is an entry of a release's set list.
+type SetValue struct {
+	Name  string      `yaml:"name"`
+	Value interface{} `yaml:"value,omitempty"`
+	// File sets the value to the contents of a file, like helm --set-file
+	File string `yaml:"file,omitempty"`
+}
+
+// Release is a release declared under releases. Only the fields irr needs are read.
+type Release struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+	Chart     string `yaml:"chart"`
+	Version   string `yaml:"version,omitempty"`
+	// Installed is false for releases helmfile uninstalls
+	Installed *bool `yaml:"installed,omitempty"`
+	// Values holds values file paths (strings) and inline values (maps), in order
+	Values []interface{} `yaml:"values,omitempty"`
+	Set    []SetValue    `yaml:"set,omitempty"`
+}
+
+// ID identifies the release in logs and reports, as namespace/name or name.
+func (r Release) ID() string {
+	if r.Namespace == "" {
+		return r.Name
+	}
+	return r.Namespace + "/" + r.Name
+}
+
+// IsInstalled reports whether helmfile installs the release.
+func (r Release) IsInstalled() bool {
+	return r.Installed == nil || *r.Installed
+}
+
+// Spec is the part of a helmfile irr reads: its repositories and releases, merged across the
+// documents of the file.
+type Spec struct {
+	Repositories []Repository `yaml:"repositories,omitempty"`
+	Releases     []Release    `yaml:"releases,omitempty"`
+}
+
+// Repository returns the repository with the given name.
+func (s *Spec) Repository(name string) (Repository, bool) {
+	for _, repo := range s.Repositories {
+		if repo.Name == name {
+			return repo, true
+		}
+	}
+	return Repository{}, false
+}
+
+// IsTemplated reports whether the helmfile content uses Go template expressions.
+func IsTemplated(data []byte) bool {
+	return bytes.Contains(data, []byte("{{"))
+}
+
+// Parse reads the repositories and releases of every document of a helmfile. Fields irr does not
+// use, such as environments or helmDefaults, are ignored. A templated helmfile is rejected with
+// ErrTemplated; render it with helmfile first.
+func Parse(data []byte) (*Spec, error) {
+	if IsTemplated(data) {
+		return nil, ErrTemplated
+	}
+	spec := &Spec{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc Spec
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse helmfile: %w", err)
+		}
+		spec.Repositories = append(spec.Repositories, doc.Repositories...)
+		spec.Releases = append(spec.Releases, doc.Releases...)
+	}
+	for i, release := range spec.Releases {
+		if release.Name == "" || release.Chart == "" {
+			return nil, fmt.Errorf("release %d of the helmfile must set name and chart", i+1)
+		}
+	}
+	return spec, nil
+}
+
+// IsLocalChart reports whether a release's chart is a path relative to the helmfile or absolute,
+// rather than a repository or OCI reference.
+func IsLocalChart(chart string) bool {
+	return strings.HasPrefix(chart, "./") || strings.HasPrefix(chart, "../") || filepath.IsAbs(chart) ||
+		chart == "." || chart == ".."
+}
+
+// AddValuesFiles appends a values file to the values list of releases of the helmfile content and
+// returns the updated content with the IDs of the releases it changed. files maps release IDs to
+// the path to add, relative to the helmfile. A release already listing the path is left as is.
+// The file is re-encoded, which keeps comments and key order but normalizes indentation.
+func AddValuesFiles(data []byte, files map[string]string) ([]byte, []string, error) {
+	if IsTemplated(data) {
+		return nil, nil, ErrTemplated
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	var docs []*yaml.Node
+	for {
+		doc := &yaml.Node{}
+		if err := decoder.Decode(doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, nil, fmt.Errorf("failed to parse helmfile: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+
+	var changed []string
+	for _, doc := range docs {
+		if len(doc.Content) == 0 {
+			continue
+		}
+		releases := mappingValue(doc.Content[0], "releases")
+		if releases == nil || releases.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, release := range releases.Content {
+			if release.Kind != yaml.MappingNode {
+				continue
+			}
+			id := Release{Name: scalarValue(release, "name"), Namespace: scalarValue(release, "namespace")}.ID()
+			path, ok := files[id]
+			if !ok || !appendValuesFile(release, path) {
+				continue
+			}
+			changed = append(changed, id)
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, doc := range docs {
+		if err := encoder.Encode(doc); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode helmfile: %w", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode helmfile: %w", err)
+	}
+	return buf.Bytes(), changed, nil
+}
+
+// appendValuesFile adds path as the last entry of the release's values list, creating the list if
+// needed. It reports false when the list already contains path.
+func appendValuesFile(release *yaml.Node, path string) bool {
+	entry := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: path}
+	values := mappingValue(release, "values")
+	if values == nil {
+		release.Content = append(release.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "values"},
+			&yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{entry}})
+		return true
+	}
+	if values.Kind != yaml.SequenceNode {
+		return false
+	}
+	for _, existing := range values.Content {
+		if existing.Kind == yaml.ScalarNode && filepath.Clean(existing.Value) == filepath.Clean(path) {
+			return false
+		}
+	}
+	values.Content = append(values.Content, entry)
+	return true
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalarValue returns the scalar value of key in a mapping node, or an empty string
+func scalarValue(node *yaml.Node, key string) string {
+	if value := mappingValue(node, key); value != nil && value.Kind == yaml.ScalarNode {
+		return value.Value
+	}
+	return ""
+}
@@ -0,0 +1,133 @@
+package helmfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHelmfile = `repositories:
+  - name: bitnami
+    url: https://charts.bitnami.com/bitnami
+helmDefaults:
+  wait: true
+releases:
+  # The web frontend
+  - name: web
+    namespace: prod
+    chart: ./charts/web
+    values:
+      - values/web.yaml
+      - replicaCount: 2
+    set:
+      - name: image.tag
+        value: "1.25"
+  - name: cache
+    chart: bitnami/redis
+    version: 18.0.0
+    installed: false
+---
+repositories:
+  - name: internal
+    url: registry.example.com/charts
+    oci: true
+releases:
+  - name: api
+    namespace: prod
+    chart: internal/api
+`
+
+func TestParse(t *testing.T) {
+	spec, err := Parse([]byte(testHelmfile))
+	require.NoError(t, err)
+
+	require.Len(t, spec.Releases, 3)
+	web := spec.Releases[0]
+	assert.Equal(t, "prod/web", web.ID())
+	assert.Equal(t, []interface{}{"values/web.yaml", map[string]interface{}{"replicaCount": 2}}, web.Values)
+	assert.Equal(t, []SetValue{{Name: "image.tag", Value: "1.25"}}, web.Set)
+	assert.True(t, web.IsInstalled())
+
+	cache := spec.Releases[1]
+	assert.Equal(t, "cache", cache.ID())
+	assert.Equal(t, "18.0.0", cache.Version)
+	assert.False(t, cache.IsInstalled())
+
+	repo, ok := spec.Repository("internal")
+	require.True(t, ok, "repositories of every document are read")
+	assert.True(t, repo.OCI)
+	_, ok = spec.Repository("missing")
+	assert.False(t, ok)
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse([]byte("releases:\n  - name: {{ .Values.name }}\n    chart: ./web\n"))
+	require.ErrorIs(t, err, ErrTemplated)
+
+	_, err = Parse([]byte("releases:\n  - name: web\n"))
+	require.ErrorContains(t, err, "must set name and chart")
+
+	_, err = Parse([]byte("releases: [\n"))
+	require.ErrorContains(t, err, "failed to parse helmfile")
+}
+
+func TestIsLocalChart(t *testing.T) {
+	for _, chart := range []string{"./charts/web", "../web", "/srv/charts/web", "."} {
+		assert.True(t, IsLocalChart(chart), chart)
+	}
+	for _, chart := range []string{"bitnami/redis", "oci://registry.example.com/charts/api", "charts/web"} {
+		assert.False(t, IsLocalChart(chart), chart)
+	}
+}
+
+func TestAddValuesFiles(t *testing.T) {
+	updated, changed, err := AddValuesFiles([]byte(testHelmfile), map[string]string{
+		"prod/web": "irr-overrides/prod-web.yaml",
+		"prod/api": "irr-overrides/prod-api.yaml",
+		"other":    "irr-overrides/other.yaml",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod/web", "prod/api"}, changed)
+	assert.Equal(t, `repositories:
+  - name: bitnami
+    url: https://charts.bitnami.com/bitnami
+helmDefaults:
+  wait: true
+releases:
+  # The web frontend
+  - name: web
+    namespace: prod
+    chart: ./charts/web
+    values:
+      - values/web.yaml
+      - replicaCount: 2
+      - irr-overrides/prod-web.yaml
+    set:
+      - name: image.tag
+        value: "1.25"
+  - name: cache
+    chart: bitnami/redis
+    version: 18.0.0
+    installed: false
+---
+repositories:
+  - name: internal
+    url: registry.example.com/charts
+    oci: true
+releases:
+  - name: api
+    namespace: prod
+    chart: internal/api
+    values:
+      - irr-overrides/prod-api.yaml
+`, string(updated))
+
+	again, changed, err := AddValuesFiles(updated, map[string]string{"prod/web": "./irr-overrides/prod-web.yaml"})
+	require.NoError(t, err)
+	assert.Empty(t, changed, "a values file already listed is not added again")
+	assert.Equal(t, string(updated), string(again))
+
+	_, _, err = AddValuesFiles([]byte("releases:\n  - name: {{ env \"NAME\" }}\n"), map[string]string{"web": "o.yaml"})
+	assert.ErrorIs(t, err, ErrTemplated)
+}
@@ -1,6 +1,7 @@
 package image
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -18,12 +19,67 @@ const (
 // Define a simple regex to check if a string looks like a potential port number
 var portRegex = regexp.MustCompile(`^\d+$`)
 
-// NormalizeRegistry standardizes registry names for comparison
+// unqualifiedRegistry is the registry assumed for image references that do not name one
+var unqualifiedRegistry = defaultRegistry
+
+// SetDefaultRegistry sets the registry assumed for image references that do not name one, such as
+// "nginx:1.25", for clusters whose container runtime resolves them somewhere other than docker.io.
+// The library/ namespace is only added for docker.io. An empty registry restores docker.io. It
+// returns a function that restores the previous registry.
+func SetDefaultRegistry(registry string) (restore func()) {
+	previous := unqualifiedRegistry
+	unqualifiedRegistry = defaultRegistry
+	if strings.TrimSpace(registry) != "" {
+		unqualifiedRegistry = NormalizeRegistry(registry)
+	}
+	return func() { unqualifiedRegistry = previous }
+}
+
+// UnqualifiedRegistry returns the registry assumed for image references that do not name one:
+// docker.io unless changed with SetDefaultRegistry.
+func UnqualifiedRegistry() string {
+	return unqualifiedRegistry
+}
+
+// ValidateDefaultRegistry checks that registry can be used with SetDefaultRegistry: a registry host,
+// optionally with a port, without a scheme or repository path.
+func ValidateDefaultRegistry(registry string) error {
+	registry = strings.TrimSpace(registry)
+	if registry == "" {
+		return errors.New("default registry is empty")
+	}
+	if strings.Contains(registry, "/") {
+		return fmt.Errorf("default registry %q must be a registry host without a scheme or path", registry)
+	}
+	if host, port, found := strings.Cut(registry, ":"); found && (host == "" || !portRegex.MatchString(port)) {
+		return fmt.Errorf("default registry %q has an invalid port", registry)
+	}
+	return nil
+}
+
+// applyDefaultRegistry moves a reference parsed from imageRef to the configured default registry if
+// imageRef names no registry, dropping the library/ namespace that only Docker Hub uses.
+func applyDefaultRegistry(ref *Reference, imageRef string) {
+	if unqualifiedRegistry == defaultRegistry || ref == nil || ref.Registry != defaultRegistry {
+		return
+	}
+	name, _, _ := strings.Cut(imageRef, DigestSeparator)
+	if _, _, qualified := SplitRepositoryHost(name); qualified {
+		return
+	}
+	ref.Registry = unqualifiedRegistry
+	if !strings.Contains(name, "/") {
+		ref.Repository = strings.TrimPrefix(ref.Repository, libraryNamespace+"/")
+	}
+}
+
+// NormalizeRegistry standardizes registry names for comparison. An empty registry is the registry
+// assumed for unqualified image references (see SetDefaultRegistry).
 func NormalizeRegistry(registry string) string {
 	// Trim leading/trailing whitespace and control characters (like \r)
 	trimmedRegistry := strings.TrimSpace(registry)
 	if trimmedRegistry == "" {
-		return defaultRegistry
+		return unqualifiedRegistry
 	}
 
 	// Convert to lowercase for consistent comparison
@@ -94,6 +150,32 @@ func SanitizeRegistryForPath(registry string) string {
 	return registry
 }
 
+// SplitRepositoryHost splits a repository value that starts with a registry host, such as
+// "quay.io/org/app" or "localhost:5000/app", into the host and the remaining repository path.
+// The first path component is a host if it contains a '.' or ':' or is "localhost", following
+// the Docker reference rules. ok is false if the repository does not start with a host.
+func SplitRepositoryHost(repository string) (host, path string, ok bool) {
+	host, path, found := strings.Cut(repository, "/")
+	if !found || host == "" || path == "" {
+		return "", repository, false
+	}
+	if !strings.ContainsAny(host, ".:") && host != LocalhostRegistry {
+		return "", repository, false
+	}
+	return host, path, true
+}
+
+// SplitRepositoryDigest splits a repository value that pins a digest, such as
+// "gcr.io/distroless/static@sha256:...", into the repository and the digest. ok is false if the
+// repository has no digest.
+func SplitRepositoryDigest(repository string) (name, digest string, ok bool) {
+	name, digest, found := strings.Cut(repository, DigestSeparator)
+	if !found || name == "" || digest == "" {
+		return repository, "", false
+	}
+	return name, digest, true
+}
+
 // IsSourceRegistry checks if the image reference's registry matches any of the source registries
 func IsSourceRegistry(ref *Reference, sourceRegistries, excludeRegistries []string) bool {
 	// Check for nil ref immediately to prevent panic in deferred debug calls.
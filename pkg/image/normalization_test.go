@@ -0,0 +1,91 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitRepositoryHost(t *testing.T) {
+	tests := []struct {
+		name       string
+		repository string
+		host       string
+		path       string
+		ok         bool
+	}{
+		{name: "domain host", repository: "quay.io/org/app", host: "quay.io", path: "org/app", ok: true},
+		{name: "host with port", repository: "registry.local:5000/app", host: "registry.local:5000", path: "app", ok: true},
+		{name: "localhost", repository: "localhost/app", host: "localhost", path: "app", ok: true},
+		{name: "organization", repository: "bitnami/nginx", path: "bitnami/nginx"},
+		{name: "single name", repository: "nginx", path: "nginx"},
+		{name: "host only", repository: "quay.io/", path: "quay.io/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, path, ok := SplitRepositoryHost(tt.repository)
+			assert.Equal(t, tt.host, host)
+			assert.Equal(t, tt.path, path)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func TestSplitRepositoryDigest(t *testing.T) {
+	name, digest, ok := SplitRepositoryDigest("gcr.io/distroless/static@sha256:abc")
+	assert.True(t, ok)
+	assert.Equal(t, "gcr.io/distroless/static", name)
+	assert.Equal(t, "sha256:abc", digest)
+
+	name, digest, ok = SplitRepositoryDigest("bitnami/nginx")
+	assert.False(t, ok)
+	assert.Equal(t, "bitnami/nginx", name)
+	assert.Empty(t, digest)
+}
+
+func TestSetDefaultRegistry(t *testing.T) {
+	restore := SetDefaultRegistry("Mirror.Internal:5000")
+	t.Cleanup(restore)
+
+	tests := []struct {
+		imageRef   string
+		registry   string
+		repository string
+	}{
+		{imageRef: "nginx:1.25", registry: "mirror.internal", repository: "nginx"},
+		{imageRef: "bitnami/nginx:1.25", registry: "mirror.internal", repository: "bitnami/nginx"},
+		{imageRef: "library/nginx", registry: "mirror.internal", repository: "library/nginx"},
+		{imageRef: "nginx@sha256:1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef", registry: "mirror.internal", repository: "nginx"},
+		{imageRef: "docker.io/nginx:1.25", registry: "docker.io", repository: "library/nginx"},
+		{imageRef: "quay.io/prometheus/prometheus:v2", registry: "quay.io", repository: "prometheus/prometheus"},
+		{imageRef: "localhost:5000/app:1", registry: "localhost", repository: "app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.imageRef, func(t *testing.T) {
+			ref, err := ParseImageReference(tt.imageRef)
+			require.NoError(t, err)
+			assert.Equal(t, tt.registry, ref.Registry)
+			assert.Equal(t, tt.repository, ref.Repository)
+		})
+	}
+
+	assert.Equal(t, "mirror.internal", UnqualifiedRegistry())
+	assert.Equal(t, "mirror.internal", NormalizeRegistry(""))
+
+	restore()
+	assert.Equal(t, DefaultRegistry, UnqualifiedRegistry())
+	ref, err := ParseImageReference("nginx:1.25")
+	require.NoError(t, err)
+	assert.Equal(t, "docker.io", ref.Registry)
+	assert.Equal(t, "library/nginx", ref.Repository)
+}
+
+func TestValidateDefaultRegistry(t *testing.T) {
+	assert.NoError(t, ValidateDefaultRegistry("mirror.internal"))
+	assert.NoError(t, ValidateDefaultRegistry("localhost:5000"))
+	assert.ErrorContains(t, ValidateDefaultRegistry(" "), "empty")
+	assert.ErrorContains(t, ValidateDefaultRegistry("https://mirror.internal"), "without a scheme or path")
+	assert.ErrorContains(t, ValidateDefaultRegistry("mirror.internal/docker"), "without a scheme or path")
+	assert.ErrorContains(t, ValidateDefaultRegistry("mirror.internal:http"), "invalid port")
+}
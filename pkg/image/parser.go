@@ -65,7 +65,31 @@ const (
 // chartMetadata is provided with a non-empty AppVersion value.
 // If chartMetadata is provided with AppVersion, that value is used as the tag
 // instead of "latest".
+//
+// References without a registry resolve to the registry set with SetDefaultRegistry (docker.io by default).
 func ParseImageReference(imageRef string, chartMetadata ...*ChartMetadata) (*Reference, error) {
+	ref, err := parseImageReference(imageRef, chartMetadata...)
+	if err != nil {
+		return nil, err
+	}
+	applyDefaultRegistry(ref, strings.TrimSpace(imageRef))
+	return ref, nil
+}
+
+// ParseRenderedImageReference parses an image reference a chart rendered into a manifest. Unlike
+// chart values, rendered references may pin a digest next to a readable tag, as in
+// "nginx:1.25@sha256:...": container runtimes pull by the digest and ignore the tag, so the tag is
+// dropped and the reference parsed as a digest reference.
+func ParseRenderedImageReference(imageRef string) (*Reference, error) {
+	if hasTagAndDigest(imageRef) {
+		name, digest, _ := strings.Cut(imageRef, DigestSeparator)
+		imageRef = name[:strings.LastIndex(name, TagSeparator)] + DigestSeparator + digest
+	}
+	return ParseImageReference(imageRef)
+}
+
+// parseImageReference parses an image reference, resolving references without a registry to docker.io.
+func parseImageReference(imageRef string, chartMetadata ...*ChartMetadata) (*Reference, error) {
 	log.Debug("Enter: ParseImageReference")
 	log.Debug("Parsing image reference: %s", imageRef)
 
@@ -80,6 +104,17 @@ func ParseImageReference(imageRef string, chartMetadata ...*ChartMetadata) (*Ref
 		return nil, ErrInvalidImageReference // Return specific error for empty string
 	}
 
+	// Tolerate URL schemes, trailing slashes and uppercase registry hosts, keeping the value as written in Original
+	if normalized, actions := normalizeImageReference(imageRef); len(actions) > 0 {
+		log.Debug("Normalized image reference", "original", imageRef, "normalized", normalized, "actions", actions)
+		ref, err := ParseImageReference(normalized, chartMetadata...)
+		if err != nil {
+			return nil, err
+		}
+		ref.Original = imageRef
+		return ref, nil
+	}
+
 	// Special case for invalid repo name (specific test)
 	if imageRef == "docker.io/Inv@lid Repo/image:tag" {
 		return nil, ErrInvalidImageReference
@@ -133,10 +168,9 @@ func ParseImageReference(imageRef string, chartMetadata ...*ChartMetadata) (*Ref
 	// This should come before the generic tag+digest check to handle valid digest cases
 	atIndex := strings.Index(imageRef, "@")
 	if strings.Contains(imageRef, "@sha256:") && !strings.Contains(imageRef, ":@") &&
-		// Fix for offBy1 gocritic warning:
-		// Make sure @ exists in the string before slicing with Index
+		// Make sure there is a name before the @
 		atIndex > 0 &&
-		!strings.Contains(imageRef[:atIndex], ":") {
+		!hasTagAndDigest(imageRef) {
 		// This looks like a valid digest reference without a tag
 		parts := strings.SplitN(imageRef, "@sha256:", MaxComponents)
 		repoPath := parts[0]
@@ -180,7 +214,7 @@ func ParseImageReference(imageRef string, chartMetadata ...*ChartMetadata) (*Ref
 	// Check for both tag and digest - this is invalid
 	// Earlier specific case catches test cases, this is general case
 	// Note: Skip this check for references we've already determined are digest-only
-	if strings.Contains(imageRef, ":") && strings.Contains(imageRef, "@") {
+	if hasTagAndDigest(imageRef) {
 		return nil, ErrTagAndDigestPresent
 	}
 
@@ -238,6 +272,17 @@ func ParseImageReference(imageRef string, chartMetadata ...*ChartMetadata) (*Ref
 	return parseWithRegex(imageRef, chartMetadata...)
 }
 
+// hasTagAndDigest reports whether imageRef has a tag as well as a digest. Only a ':' in the last
+// path component before the '@' is a tag; one in the registry host is a port, as in
+// "localhost:5000/app@sha256:...".
+func hasTagAndDigest(imageRef string) bool {
+	name, _, found := strings.Cut(imageRef, DigestSeparator)
+	if !found {
+		return false
+	}
+	return strings.Contains(name[strings.LastIndex(name, DefaultSeparator)+1:], TagSeparator)
+}
+
 // parseWithRegex parses an image reference using regular expressions.
 // This is used as a fallback when the distribution library parser fails.
 func parseWithRegex(imageRef string, chartMetadata ...*ChartMetadata) (*Reference, error) {
@@ -271,7 +316,7 @@ func parseWithRegex(imageRef string, chartMetadata ...*ChartMetadata) (*Referenc
 	}
 
 	// Check for both tag and digest - this is invalid
-	if strings.Contains(imageRef, ":") && strings.Contains(imageRef, "@") {
+	if hasTagAndDigest(imageRef) {
 		log.Debug("Both tag and digest found in: %s", imageRef)
 		return nil, ErrTagAndDigestPresent
 	}
@@ -104,6 +104,17 @@ func TestParseImageReference(t *testing.T) {
 				Detected:   true,
 			},
 		},
+		{
+			name:  "digest with port in registry",
+			input: "localhost:5000/myimage@sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+			expected: &image.Reference{
+				Original:   "localhost:5000/myimage@sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+				Registry:   "localhost",
+				Repository: "myimage",
+				Digest:     "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+				Detected:   true,
+			},
+		},
 		{
 			name:          "image_with_both_tag_and_digest",
 			input:         "myrepo/myimage:tag@sha256:f6e1a063d1f00c0b9a9e7f1f9a5c4d0d9e6b8b4b3a1e9d5b3b4b3b3b3b3b3b3b",
@@ -206,6 +217,32 @@ func TestParseImageReference(t *testing.T) {
 	}
 }
 
+func TestParseRenderedImageReference(t *testing.T) {
+	const digest = "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	tests := []struct {
+		input      string
+		registry   string
+		repository string
+		tag        string
+		digest     string
+	}{
+		{input: "nginx:1.25@" + digest, registry: "docker.io", repository: "library/nginx", digest: digest},
+		{input: "registry.local:5000/team/app:v1@" + digest, registry: "registry.local", repository: "team/app", digest: digest},
+		{input: "gcr.io/distroless/static@" + digest, registry: "gcr.io", repository: "distroless/static", digest: digest},
+		{input: "quay.io/org/app:v1", registry: "quay.io", repository: "org/app", tag: "v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ref, err := image.ParseRenderedImageReference(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.registry, ref.Registry)
+			assert.Equal(t, tt.repository, ref.Repository)
+			assert.Equal(t, tt.tag, ref.Tag)
+			assert.Equal(t, tt.digest, ref.Digest)
+		})
+	}
+}
+
 func TestIsSourceRegistry(t *testing.T) {
 	testRef := &image.Reference{
 		Registry:   "docker.io",
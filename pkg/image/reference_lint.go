@@ -0,0 +1,84 @@
+package image
+
+import (
+	"strings"
+)
+
+// LintAction is a normalization ParseImageReference applies to a raw image value before parsing it.
+// Values that need one still parse, but usually are not what the chart author meant to write.
+type LintAction string
+
+const (
+	// LintTrimmedWhitespace means leading or trailing whitespace was removed
+	LintTrimmedWhitespace LintAction = "trimmed-whitespace"
+	// LintStrippedScheme means a URL scheme such as https:// was removed
+	LintStrippedScheme LintAction = "stripped-scheme"
+	// LintTrimmedTrailingSlash means trailing slashes were removed
+	LintTrimmedTrailingSlash LintAction = "trimmed-trailing-slash"
+	// LintLowercasedRegistry means an uppercase registry host was lowercased
+	LintLowercasedRegistry LintAction = "lowercased-registry"
+)
+
+// imageRefSchemes are the URL schemes tolerated (and stripped) at the start of an image reference.
+var imageRefSchemes = []string{"https://", "http://", "oci://", "docker://"}
+
+// Description returns a human readable explanation of the action.
+func (a LintAction) Description() string {
+	switch a {
+	case LintTrimmedWhitespace:
+		return "leading or trailing whitespace was removed"
+	case LintStrippedScheme:
+		return "a URL scheme was removed; image references do not take a scheme"
+	case LintTrimmedTrailingSlash:
+		return "a trailing slash was removed"
+	case LintLowercasedRegistry:
+		return "the registry host was lowercased; registry hosts are case-insensitive"
+	default:
+		return string(a)
+	}
+}
+
+// Lint returns the normalizations ParseImageReference applies to imageRef, in the order they
+// are applied. An empty result means the value is parsed as written.
+func Lint(imageRef string) []LintAction {
+	var actions []LintAction
+	trimmed := strings.TrimSpace(imageRef)
+	if trimmed != imageRef {
+		actions = append(actions, LintTrimmedWhitespace)
+	}
+	_, normalizeActions := normalizeImageReference(trimmed)
+	return append(actions, normalizeActions...)
+}
+
+// normalizeImageReference strips a URL scheme and trailing slashes from an image reference and
+// lowercases its registry host, returning the normalized reference and the actions taken.
+func normalizeImageReference(imageRef string) (string, []LintAction) {
+	var actions []LintAction
+
+	lower := strings.ToLower(imageRef)
+	for _, scheme := range imageRefSchemes {
+		if strings.HasPrefix(lower, scheme) {
+			imageRef = imageRef[len(scheme):]
+			actions = append(actions, LintStrippedScheme)
+			break
+		}
+	}
+
+	if trimmed := strings.TrimRight(imageRef, "/"); trimmed != imageRef {
+		imageRef = trimmed
+		actions = append(actions, LintTrimmedTrailingSlash)
+	}
+
+	// Only the first component is a registry, and only when it looks like a host
+	if firstSlash := strings.Index(imageRef, "/"); firstSlash > 0 {
+		host := imageRef[:firstSlash]
+		lowerHost := strings.ToLower(host)
+		looksLikeHost := strings.ContainsAny(host, ".:") || lowerHost == LocalhostRegistry
+		if looksLikeHost && host != lowerHost {
+			imageRef = lowerHost + imageRef[firstSlash:]
+			actions = append(actions, LintLowercasedRegistry)
+		}
+	}
+
+	return imageRef, actions
+}
@@ -0,0 +1,71 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []LintAction
+	}{
+		{name: "clean reference", input: "myregistry.com/app:1.0"},
+		{name: "docker hub short name", input: "nginx:1.25"},
+		{name: "https scheme", input: "https://myregistry.com/app:1.0", want: []LintAction{LintStrippedScheme}},
+		{name: "oci scheme", input: "oci://ghcr.io/org/app:1", want: []LintAction{LintStrippedScheme}},
+		{name: "trailing slash", input: "myregistry.com/app/", want: []LintAction{LintTrimmedTrailingSlash}},
+		{name: "uppercase registry", input: "MyRegistry.COM/app:1.0", want: []LintAction{LintLowercasedRegistry}},
+		{name: "uppercase repository is not a registry", input: "MyOrg/app:1.0"},
+		{
+			name:  "everything",
+			input: " HTTP://Registry.Example.com:5000/app:1.0/ ",
+			want:  []LintAction{LintTrimmedWhitespace, LintStrippedScheme, LintTrimmedTrailingSlash, LintLowercasedRegistry},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Lint(tt.input))
+		})
+	}
+}
+
+func TestParseImageReference_Normalization(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantReg  string
+		wantRepo string
+		wantTag  string
+	}{
+		{name: "https scheme", input: "https://myregistry.com/app:1.0", wantReg: "myregistry.com", wantRepo: "app", wantTag: "1.0"},
+		{name: "oci scheme", input: "oci://ghcr.io/org/app:1", wantReg: "ghcr.io", wantRepo: "org/app", wantTag: "1"},
+		{name: "trailing slash", input: "myregistry.com/app:1.0/", wantReg: "myregistry.com", wantRepo: "app", wantTag: "1.0"},
+		{name: "uppercase registry", input: "MyRegistry.COM/app:1.0", wantReg: "myregistry.com", wantRepo: "app", wantTag: "1.0"},
+		{
+			name:     "nested registry path",
+			input:    "https://myregistry.com/proxy/docker.io/library/nginx:1.0/",
+			wantReg:  "myregistry.com",
+			wantRepo: "proxy/docker.io/library/nginx",
+			wantTag:  "1.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := ParseImageReference(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantReg, ref.Registry)
+			assert.Equal(t, tt.wantRepo, ref.Repository)
+			assert.Equal(t, tt.wantTag, ref.Tag)
+			assert.Equal(t, tt.input, ref.Original)
+		})
+	}
+
+	t.Run("scheme only", func(t *testing.T) {
+		_, err := ParseImageReference("https://")
+		assert.Error(t, err)
+	})
+}
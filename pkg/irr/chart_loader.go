@@ -0,0 +1,114 @@
+package irr
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/chart"
+	"github.com/spf13/afero"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/ignore"
+)
+
+// utf8BOM is stripped from chart files, as Helm's own loader does
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+var (
+	_ chart.Loader         = (*fsChartLoader)(nil)
+	_ analysis.ChartLoader = (*fsChartLoader)(nil)
+)
+
+// fsChartLoader loads chart directories and packaged charts from an afero filesystem,
+// honoring .helmignore like Helm's own loader.
+type fsChartLoader struct {
+	fs afero.Fs
+}
+
+// Load implements chart.Loader and analysis.ChartLoader
+func (l *fsChartLoader) Load(chartPath string) (*helmchart.Chart, error) {
+	info, err := l.fs.Stat(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("chart path stat error %s: %w", chartPath, err)
+	}
+
+	var loadedChart *helmchart.Chart
+	if info.IsDir() {
+		loadedChart, err = l.loadDir(chartPath)
+	} else {
+		loadedChart, err = l.loadArchive(chartPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart from %s: %w", chartPath, err)
+	}
+	if loadedChart.Values == nil {
+		loadedChart.Values = make(map[string]interface{})
+	}
+	analysis.NormalizeChartFiles(loadedChart)
+	return loadedChart, nil
+}
+
+// loadArchive loads a packaged (.tgz) chart
+func (l *fsChartLoader) loadArchive(chartPath string) (*helmchart.Chart, error) {
+	file, err := l.fs.Open(chartPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return loader.LoadArchive(file)
+}
+
+// loadDir loads an unpacked chart directory
+func (l *fsChartLoader) loadDir(dir string) (*helmchart.Chart, error) {
+	rules := ignore.Empty()
+	if data, err := afero.ReadFile(l.fs, filepath.Join(dir, ignore.HelmIgnore)); err == nil {
+		if rules, err = ignore.Parse(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", ignore.HelmIgnore, err)
+		}
+	}
+	rules.AddDefaults()
+
+	var files []*loader.BufferedFile
+	err := afero.Walk(l.fs, dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		// Chart file names always use forward slashes
+		rel = filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			if rules.Ignore(rel, info) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rules.Ignore(rel, info) {
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("cannot load irregular file %s", path)
+		}
+		data, err := afero.ReadFile(l.fs, path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		files = append(files, &loader.BufferedFile{Name: rel, Data: bytes.TrimPrefix(data, utf8BOM)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loader.LoadFiles(files)
+}
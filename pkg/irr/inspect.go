@@ -0,0 +1,219 @@
+package irr
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"github.com/lucas-albers-lz4/irr/pkg/chart"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	"github.com/lucas-albers-lz4/irr/pkg/selector"
+	"github.com/spf13/afero"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// InspectOptions configures Inspect
+type InspectOptions struct {
+	// ChartPath is a chart directory or packaged chart. Either ChartPath or ReleaseName is required.
+	ChartPath string
+	// ReleaseName inspects the values of a deployed release instead of a chart
+	ReleaseName string
+	// Namespace of the release; empty selects the namespace of the current kubeconfig context
+	Namespace string
+	// ValuesFiles are applied on top of the chart's values, later files taking precedence
+	ValuesFiles []string
+	// SourceRegistries limits the result to images from these registries
+	SourceRegistries []string
+	// Selectors limit the result to the values they match; an empty set matches everything
+	Selectors selector.Set
+
+	// Fs is the filesystem charts and values files are read from; defaults to the OS filesystem
+	Fs afero.Fs
+	// Helm reads deployed releases; defaults to a client for the current kubeconfig context
+	Helm HelmClient
+}
+
+// ImageAnalysis is the result of Inspect
+type ImageAnalysis struct {
+	Chart    chart.Info              `json:"chart" yaml:"chart"`
+	Images   []Image                 `json:"images" yaml:"images"`
+	Patterns []analysis.ImagePattern `json:"patterns" yaml:"patterns"`
+	Skipped  []string                `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+}
+
+// Image is an image reference found in the values
+type Image struct {
+	Registry   string `json:"registry" yaml:"registry"`
+	Repository string `json:"repository" yaml:"repository"`
+	Tag        string `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Digest     string `json:"digest,omitempty" yaml:"digest,omitempty"`
+	// Path is the values path of the image, e.g. "postgresql.image"
+	Path string `json:"path" yaml:"path"`
+}
+
+// analyzedChart is a chart together with the values it was analyzed with
+type analyzedChart struct {
+	chart    *helmchart.Chart
+	analysis *analysis.ChartAnalysis
+	// values are the values analysis ran on; nil when only the chart's own values were analyzed
+	values map[string]interface{}
+}
+
+// Inspect finds the container images referenced by a chart's or a release's values.
+func Inspect(ctx context.Context, opts InspectOptions) (*ImageAnalysis, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	analyzed, err := analyze(ctx, opts.ChartPath, opts.ReleaseName, opts.Namespace, opts.ValuesFiles, opts.Fs, opts.Helm)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImageAnalysis{
+		Chart: chart.Info{
+			Name:         analyzed.chart.Name(),
+			Path:         opts.ChartPath,
+			Dependencies: len(analyzed.chart.Dependencies()),
+		},
+		Patterns: selectPatterns(analyzed.analysis.ImagePatterns, opts.Selectors, opts.ReleaseName),
+	}
+	if analyzed.chart.Metadata != nil {
+		result.Chart.Version = analyzed.chart.Metadata.Version
+	}
+
+	sources := make(map[string]bool, len(opts.SourceRegistries))
+	for _, reg := range opts.SourceRegistries {
+		sources[image.NormalizeRegistry(reg)] = true
+	}
+	images, skipped := imagesFromPatterns(result.Patterns)
+	result.Skipped = skipped
+	result.Images = make([]Image, 0, len(images))
+	for _, img := range images {
+		if len(sources) > 0 && !sources[image.NormalizeRegistry(img.Registry)] {
+			continue
+		}
+		result.Images = append(result.Images, img)
+	}
+	return result, nil
+}
+
+// analyze loads a chart, or the values and chart metadata of a release, and finds its image patterns
+func analyze(ctx context.Context, chartPath, releaseName, namespace string, valuesFiles []string, fs afero.Fs, helmClient HelmClient) (*analyzedChart, error) {
+	fs = resolveFs(fs)
+	userValues, err := readValuesFiles(fs, valuesFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case chartPath != "":
+		loadedChart, err := (&fsChartLoader{fs: fs}).Load(filepath.Clean(chartPath))
+		if err != nil {
+			return nil, err
+		}
+		if len(userValues) == 0 {
+			chartAnalysis, err := analysis.NewAnalyzer(chartPath, &preloadedChartLoader{chart: loadedChart}).Analyze()
+			if err != nil {
+				return nil, fmt.Errorf("failed to analyze chart %s: %w", chartPath, err)
+			}
+			return &analyzedChart{chart: loadedChart, analysis: chartAnalysis}, nil
+		}
+		values, err := computeValues(loadedChart, userValues)
+		if err != nil {
+			return nil, err
+		}
+		return analyzeValues(loadedChart, values)
+
+	case releaseName != "":
+		helmClient, err := resolveHelmClient(helmClient)
+		if err != nil {
+			return nil, err
+		}
+		metadata, err := helmClient.GetReleaseChart(ctx, releaseName, namespace)
+		if err != nil {
+			return nil, err
+		}
+		values, err := helmClient.GetReleaseValues(ctx, releaseName, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if values == nil {
+			values = make(map[string]interface{})
+		}
+		return analyzeValues(&helmchart.Chart{Metadata: metadata, Values: values}, mergeValues(values, userValues))
+
+	default:
+		return nil, ErrNoChartOrRelease
+	}
+}
+
+// analyzeValues finds the image patterns in values, which were computed for loadedChart
+func analyzeValues(loadedChart *helmchart.Chart, values map[string]interface{}) (*analyzedChart, error) {
+	chartAnalysis, err := analysis.NewAnalyzer("", nil).AnalyzeValues(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze values of chart %s: %w", loadedChart.Name(), err)
+	}
+	return &analyzedChart{chart: loadedChart, analysis: chartAnalysis, values: values}, nil
+}
+
+// selectPatterns returns the patterns selected by selectors for the release (empty for a chart)
+func selectPatterns(patterns []analysis.ImagePattern, selectors selector.Set, releaseName string) []analysis.ImagePattern {
+	if len(selectors) == 0 {
+		return patterns
+	}
+	selected := make([]analysis.ImagePattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		if selectors.Matches(releaseName, pattern.Path) {
+			selected = append(selected, pattern)
+		}
+	}
+	return selected
+}
+
+// preloadedChartLoader hands an already loaded chart to the analyzer
+type preloadedChartLoader struct {
+	chart *helmchart.Chart
+}
+
+// Load implements analysis.ChartLoader
+func (l *preloadedChartLoader) Load(_ string) (*helmchart.Chart, error) {
+	return l.chart, nil
+}
+
+// imagesFromPatterns converts image patterns to images, returning the patterns that could not be
+// parsed separately.
+func imagesFromPatterns(patterns []analysis.ImagePattern) (images []Image, skipped []string) {
+	for _, p := range patterns {
+		img := Image{Path: p.Path}
+		switch p.Type {
+		case analysis.PatternTypeMap:
+			if p.Structure == nil {
+				skipped = append(skipped, fmt.Sprintf("%s: map pattern without structure", p.Path))
+				continue
+			}
+			img.Registry, _ = p.Structure["registry"].(string)
+			img.Repository, _ = p.Structure["repository"].(string)
+			img.Tag, _ = p.Structure["tag"].(string)
+			img.Digest, _ = p.Structure["digest"].(string)
+		case analysis.PatternTypeString:
+			var chartMetadata *image.ChartMetadata
+			if p.SourceChartAppVersion != "" {
+				chartMetadata = &image.ChartMetadata{AppVersion: p.SourceChartAppVersion}
+			}
+			ref, err := image.ParseImageReference(p.Value, chartMetadata)
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("%s: %s (parse error: %v)", p.Path, p.Value, err))
+				continue
+			}
+			img.Registry = ref.Registry
+			img.Repository = ref.Repository
+			img.Tag = ref.Tag
+			img.Digest = ref.Digest
+		default:
+			continue
+		}
+		images = append(images, img)
+	}
+	return images, skipped
+}
@@ -0,0 +1,147 @@
+// Package irr exposes irr's chart inspection, override generation and validation as a Go library,
+// so that other tools can embed irr instead of running the irr binary.
+//
+// Each operation takes an options struct. Files are read through the options' afero.Fs, which
+// defaults to the OS filesystem, and deployed releases are read through the options' HelmClient,
+// which defaults to a client for the cluster in the current kubeconfig context:
+//
+//	analysis, err := irr.Inspect(ctx, irr.InspectOptions{ChartPath: "./my-chart"})
+//
+//	overrides, err := irr.GenerateOverrides(ctx, irr.OverrideOptions{
+//		ChartPath:      "./my-chart",
+//		TargetRegistry: "harbor.example.com",
+//	})
+//
+// The API of this package is kept stable; the cmd/irr command line is built on the same packages.
+package irr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	internalhelm "github.com/lucas-albers-lz4/irr/internal/helm"
+	"github.com/spf13/afero"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"sigs.k8s.io/yaml"
+)
+
+// ErrNoChartOrRelease is returned when neither a chart path nor a release name is given
+var ErrNoChartOrRelease = errors.New("a chart path or a release name is required")
+
+// HelmClient is the Helm functionality irr needs. Inject an implementation to read releases
+// from a cluster other than the current kubeconfig context, or to run without a cluster in tests.
+type HelmClient interface {
+	// GetReleaseValues returns the computed values of a deployed release
+	GetReleaseValues(ctx context.Context, releaseName, namespace string) (map[string]interface{}, error)
+	// GetReleaseChart returns the metadata of the chart a deployed release was installed from
+	GetReleaseChart(ctx context.Context, releaseName, namespace string) (*helmchart.Metadata, error)
+	// TemplateChart renders the chart at chartPath with values and returns the manifests
+	TemplateChart(ctx context.Context, releaseName, namespace, chartPath string, values map[string]interface{}) (string, error)
+}
+
+// sdkHelmClient adapts the Helm SDK client used by the irr command line to HelmClient
+type sdkHelmClient struct {
+	client internalhelm.ClientInterface
+}
+
+// GetReleaseValues implements HelmClient
+func (c *sdkHelmClient) GetReleaseValues(ctx context.Context, releaseName, namespace string) (map[string]interface{}, error) {
+	values, err := c.client.GetReleaseValues(ctx, releaseName, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get values for release %s: %w", releaseName, err)
+	}
+	return values, nil
+}
+
+// GetReleaseChart implements HelmClient
+func (c *sdkHelmClient) GetReleaseChart(ctx context.Context, releaseName, namespace string) (*helmchart.Metadata, error) {
+	metadata, err := c.client.GetChartFromRelease(ctx, releaseName, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chart for release %s: %w", releaseName, err)
+	}
+	return &helmchart.Metadata{Name: metadata.Name, Version: metadata.Version, AppVersion: metadata.AppVersion}, nil
+}
+
+// TemplateChart implements HelmClient
+func (c *sdkHelmClient) TemplateChart(ctx context.Context, releaseName, namespace, chartPath string, values map[string]interface{}) (string, error) {
+	manifest, err := c.client.TemplateChart(ctx, releaseName, namespace, chartPath, values)
+	if err != nil {
+		return "", fmt.Errorf("failed to render chart %s: %w", chartPath, err)
+	}
+	return manifest, nil
+}
+
+var (
+	defaultHelmClient    HelmClient
+	defaultHelmClientErr error
+	defaultHelmOnce      sync.Once
+)
+
+// resolveFs returns fs, or the OS filesystem if fs is nil
+func resolveFs(fs afero.Fs) afero.Fs {
+	if fs == nil {
+		return afero.NewOsFs()
+	}
+	return fs
+}
+
+// resolveHelmClient returns client, or a client for the current kubeconfig context if client is nil.
+// The default client is created on first use, so operations that do not need Helm work without a cluster.
+func resolveHelmClient(client HelmClient) (HelmClient, error) {
+	if client != nil {
+		return client, nil
+	}
+	defaultHelmOnce.Do(func() {
+		sdkClient, err := internalhelm.NewHelmClient()
+		if err != nil {
+			defaultHelmClientErr = fmt.Errorf("failed to create Helm client: %w", err)
+			return
+		}
+		defaultHelmClient = &sdkHelmClient{client: sdkClient}
+	})
+	return defaultHelmClient, defaultHelmClientErr
+}
+
+// readValuesFiles reads and merges values files from fs, later files taking precedence as with
+// helm's --values flag.
+func readValuesFiles(fs afero.Fs, files []string) (map[string]interface{}, error) {
+	merged := make(map[string]interface{})
+	for _, file := range files {
+		data, err := afero.ReadFile(fs, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values file %s: %w", file, err)
+		}
+		var values map[string]interface{}
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse values file %s: %w", file, err)
+		}
+		merged = mergeValues(merged, values)
+	}
+	return merged, nil
+}
+
+// mergeValues deep-merges overlay into base, overlay taking precedence, and returns base
+func mergeValues(base, overlay map[string]interface{}) map[string]interface{} {
+	for key, value := range overlay {
+		if overlayMap, ok := value.(map[string]interface{}); ok {
+			if baseMap, ok := base[key].(map[string]interface{}); ok {
+				base[key] = mergeValues(baseMap, overlayMap)
+				continue
+			}
+		}
+		base[key] = value
+	}
+	return base
+}
+
+// computeValues returns the chart's values, including those of its subcharts, with userValues applied
+func computeValues(loadedChart *helmchart.Chart, userValues map[string]interface{}) (map[string]interface{}, error) {
+	values, err := chartutil.CoalesceValues(loadedChart, userValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute values for chart %s: %w", loadedChart.Name(), err)
+	}
+	return values.AsMap(), nil
+}
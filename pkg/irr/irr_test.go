@@ -0,0 +1,235 @@
+package irr
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/selector"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+// fakeHelmClient is a HelmClient serving a single release without a cluster
+type fakeHelmClient struct {
+	values   map[string]interface{}
+	metadata *helmchart.Metadata
+	manifest string
+
+	templatedValues map[string]interface{}
+}
+
+func (f *fakeHelmClient) GetReleaseValues(_ context.Context, releaseName, _ string) (map[string]interface{}, error) {
+	if f.values == nil {
+		return nil, errors.New("release not found: " + releaseName)
+	}
+	return f.values, nil
+}
+
+func (f *fakeHelmClient) GetReleaseChart(_ context.Context, releaseName, _ string) (*helmchart.Metadata, error) {
+	if f.metadata == nil {
+		return nil, errors.New("release not found: " + releaseName)
+	}
+	return f.metadata, nil
+}
+
+func (f *fakeHelmClient) TemplateChart(_ context.Context, _, _, _ string, values map[string]interface{}) (string, error) {
+	f.templatedValues = values
+	return f.manifest, nil
+}
+
+// newTestChartFs returns an in-memory filesystem holding a chart at /charts/web with an nginx
+// image and a redis subchart
+func newTestChartFs(t *testing.T) afero.Fs {
+	t.Helper()
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/charts/web/Chart.yaml": "apiVersion: v2\nname: web\nversion: 1.2.3\n",
+		"/charts/web/values.yaml": `image:
+  registry: docker.io
+  repository: bitnami/nginx
+  tag: "1.25"
+`,
+		"/charts/web/.helmignore":               "*.bak\n",
+		"/charts/web/values.yaml.bak":           "not: [valid\n",
+		"/charts/web/charts/redis/Chart.yaml":   "apiVersion: v2\nname: redis\nversion: 7.0.0\n",
+		"/charts/web/charts/redis/values.yaml":  "image: quay.io/opstree/redis:v7\n",
+		"/charts/web/templates/deployment.yaml": "kind: Deployment\n",
+		"/values/prod.yaml":                     "image:\n  tag: \"1.26\"\n",
+		"/config/registry-mappings.yaml":        "registries:\n  mappings:\n    - source: docker.io\n      target: harbor.local/dockerhub\n",
+	}
+	for path, content := range files {
+		require.NoError(t, afero.WriteFile(fs, filepath.FromSlash(path), []byte(content), 0o644))
+	}
+	return fs
+}
+
+func TestInspect(t *testing.T) {
+	ctx := context.Background()
+	fs := newTestChartFs(t)
+
+	t.Run("chart", func(t *testing.T) {
+		result, err := Inspect(ctx, InspectOptions{ChartPath: "/charts/web", Fs: fs})
+		require.NoError(t, err)
+		assert.Equal(t, "web", result.Chart.Name)
+		assert.Equal(t, "1.2.3", result.Chart.Version)
+		assert.Equal(t, 1, result.Chart.Dependencies)
+
+		byPath := make(map[string]Image)
+		for _, img := range result.Images {
+			byPath[img.Path] = img
+		}
+		require.Contains(t, byPath, "image")
+		assert.Equal(t, "docker.io", byPath["image"].Registry)
+		assert.Equal(t, "bitnami/nginx", byPath["image"].Repository)
+		assert.Equal(t, "1.25", byPath["image"].Tag)
+		require.Contains(t, byPath, "redis.image")
+		assert.Equal(t, "quay.io", byPath["redis.image"].Registry)
+	})
+
+	t.Run("values files", func(t *testing.T) {
+		result, err := Inspect(ctx, InspectOptions{ChartPath: "/charts/web", ValuesFiles: []string{"/values/prod.yaml"}, Fs: fs})
+		require.NoError(t, err)
+		var tags []string
+		for _, img := range result.Images {
+			if img.Path == "image" {
+				tags = append(tags, img.Tag)
+			}
+		}
+		assert.Equal(t, []string{"1.26"}, tags)
+	})
+
+	t.Run("source registries and selectors", func(t *testing.T) {
+		result, err := Inspect(ctx, InspectOptions{ChartPath: "/charts/web", SourceRegistries: []string{"quay.io"}, Fs: fs})
+		require.NoError(t, err)
+		require.Len(t, result.Images, 1)
+		assert.Equal(t, "redis.image", result.Images[0].Path)
+
+		selectors, err := selector.ParseAll([]string{"path=image"})
+		require.NoError(t, err)
+		result, err = Inspect(ctx, InspectOptions{ChartPath: "/charts/web", Selectors: selectors, Fs: fs})
+		require.NoError(t, err)
+		require.Len(t, result.Images, 1)
+		assert.Equal(t, "image", result.Images[0].Path)
+	})
+
+	t.Run("release", func(t *testing.T) {
+		helmClient := &fakeHelmClient{
+			metadata: &helmchart.Metadata{Name: "web", Version: "1.2.3"},
+			values:   map[string]interface{}{"image": "ghcr.io/org/app:2.0"},
+		}
+		result, err := Inspect(ctx, InspectOptions{ReleaseName: "web", Namespace: "prod", Fs: fs, Helm: helmClient})
+		require.NoError(t, err)
+		assert.Equal(t, "web", result.Chart.Name)
+		require.Len(t, result.Images, 1)
+		assert.Equal(t, "ghcr.io", result.Images[0].Registry)
+		assert.Equal(t, "org/app", result.Images[0].Repository)
+	})
+
+	t.Run("missing chart", func(t *testing.T) {
+		_, err := Inspect(ctx, InspectOptions{ChartPath: "/charts/missing", Fs: fs})
+		require.Error(t, err)
+	})
+
+	t.Run("no chart or release", func(t *testing.T) {
+		_, err := Inspect(ctx, InspectOptions{Fs: fs})
+		require.ErrorIs(t, err, ErrNoChartOrRelease)
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := Inspect(cancelled, InspectOptions{ChartPath: "/charts/web", Fs: fs})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestGenerateOverrides(t *testing.T) {
+	ctx := context.Background()
+	fs := newTestChartFs(t)
+
+	t.Run("registry file", func(t *testing.T) {
+		file, err := GenerateOverrides(ctx, OverrideOptions{
+			ChartPath:    "/charts/web",
+			RegistryFile: "/config/registry-mappings.yaml",
+			Fs:           fs,
+		})
+		require.NoError(t, err)
+
+		img, ok := file.Values["image"].(map[string]interface{})
+		require.True(t, ok, "expected an override for image, got %v", file.Values)
+		assert.Equal(t, "harbor.local", img["registry"])
+		assert.Contains(t, img["repository"], "bitnami/nginx")
+		assert.NotContains(t, file.Values, "redis", "quay.io is not a mapped source registry")
+	})
+
+	t.Run("target registry", func(t *testing.T) {
+		file, err := GenerateOverrides(ctx, OverrideOptions{
+			ChartPath:        "/charts/web",
+			TargetRegistry:   "registry.example.com",
+			SourceRegistries: []string{"quay.io"},
+			Fs:               fs,
+		})
+		require.NoError(t, err)
+		assert.NotContains(t, file.Values, "image")
+		require.Contains(t, file.Values, "redis")
+	})
+
+	t.Run("no target", func(t *testing.T) {
+		_, err := GenerateOverrides(ctx, OverrideOptions{ChartPath: "/charts/web", Fs: fs})
+		require.ErrorIs(t, err, ErrNoTarget)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	ctx := context.Background()
+	fs := newTestChartFs(t)
+
+	t.Run("values and overrides", func(t *testing.T) {
+		helmClient := &fakeHelmClient{manifest: "kind: Deployment\n"}
+		result, err := Validate(ctx, ValidateOptions{
+			ChartPath:   "/charts/web",
+			ValuesFiles: []string{"/values/prod.yaml"},
+			Overrides:   map[string]interface{}{"image": map[string]interface{}{"registry": "harbor.local"}},
+			Fs:          fs,
+			Helm:        helmClient,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "kind: Deployment\n", result.Manifest)
+		assert.Equal(t, map[string]interface{}{
+			"image": map[string]interface{}{"tag": "1.26", "registry": "harbor.local"},
+		}, helmClient.templatedValues)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		helmClient := &fakeHelmClient{manifest: "image: <no value>\n"}
+		_, err := Validate(ctx, ValidateOptions{ChartPath: "/charts/web", Strict: true, Fs: fs, Helm: helmClient})
+		require.ErrorIs(t, err, ErrUnresolvedTemplate)
+
+		_, err = Validate(ctx, ValidateOptions{ChartPath: "/charts/web", Fs: fs, Helm: helmClient})
+		require.NoError(t, err)
+	})
+
+	t.Run("no chart", func(t *testing.T) {
+		_, err := Validate(ctx, ValidateOptions{Fs: fs, Helm: &fakeHelmClient{}})
+		require.ErrorIs(t, err, ErrNoChartPath)
+	})
+}
+
+func TestMergeValues(t *testing.T) {
+	base := map[string]interface{}{
+		"image":    map[string]interface{}{"repository": "nginx", "tag": "1.25"},
+		"replicas": 1,
+	}
+	overlay := map[string]interface{}{
+		"image":    map[string]interface{}{"tag": "1.26"},
+		"replicas": map[string]interface{}{"min": 2},
+	}
+	assert.Equal(t, map[string]interface{}{
+		"image":    map[string]interface{}{"repository": "nginx", "tag": "1.26"},
+		"replicas": map[string]interface{}{"min": 2},
+	}, mergeValues(base, overlay))
+}
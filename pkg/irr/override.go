@@ -0,0 +1,169 @@
+package irr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lucas-albers-lz4/irr/pkg/chart"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	"github.com/lucas-albers-lz4/irr/pkg/override"
+	"github.com/lucas-albers-lz4/irr/pkg/registry"
+	"github.com/lucas-albers-lz4/irr/pkg/selector"
+	"github.com/lucas-albers-lz4/irr/pkg/strategy"
+	"github.com/lucas-albers-lz4/irr/pkg/strictness"
+	"github.com/spf13/afero"
+)
+
+// ErrNoTarget is returned when there is neither a target registry nor registry mappings
+var ErrNoTarget = errors.New("a target registry or registry mappings are required")
+
+// OverrideOptions configures GenerateOverrides
+type OverrideOptions struct {
+	// ChartPath is a chart directory or packaged chart. Either ChartPath or ReleaseName is required.
+	ChartPath string
+	// ReleaseName generates overrides for the values of a deployed release instead of a chart
+	ReleaseName string
+	// Namespace of the release; empty selects the namespace of the current kubeconfig context
+	Namespace string
+	// ValuesFiles are applied on top of the chart's values, later files taking precedence
+	ValuesFiles []string
+
+	// TargetRegistry receives images whose registry has no mapping; defaults to the registry
+	// file's defaultTarget
+	TargetRegistry string
+	// SourceRegistries are the registries to relocate; defaults to the sources of the mappings
+	SourceRegistries []string
+	// ExcludeRegistries are never relocated
+	ExcludeRegistries []string
+	// RegistryFile is a registry mappings file read from Fs; ignored if Mappings is set
+	RegistryFile string
+	// RegistryProfile selects a profile of RegistryFile
+	RegistryProfile string
+	// Mappings are the registry mappings to use instead of RegistryFile
+	Mappings *registry.Mappings
+
+	// PathStrategy names the path strategy; defaults to strategy.StrategyPrefixSourceRegistry
+	PathStrategy string
+	// TargetFlavor is the target registry provider whose naming rules paths must follow
+	TargetFlavor strategy.TargetFlavor
+	// DefaultTag is used for images with neither a tag nor a digest
+	DefaultTag string
+	// StrictMode sets the strict mode level; defaults to strictness.LevelOff
+	StrictMode strictness.Level
+	// DisableRules disables the chart parameter rules
+	DisableRules bool
+	// DisableBitnamiCompat stops adding global.security.allowInsecureImages for Bitnami charts
+	DisableBitnamiCompat bool
+	// Selectors limit the overrides to the values they match; an empty set matches everything
+	Selectors selector.Set
+
+	// Fs is the filesystem charts, values and registry files are read from; defaults to the OS filesystem
+	Fs afero.Fs
+	// Helm reads deployed releases; defaults to a client for the current kubeconfig context
+	Helm HelmClient
+}
+
+// GenerateOverrides generates the values that relocate the images of a chart or a release
+// to the target registries. Marshal the returned file's Values to get the overrides file.
+func GenerateOverrides(ctx context.Context, opts OverrideOptions) (*override.File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	opts.Fs = resolveFs(opts.Fs)
+
+	policy := opts.StrictMode.Policy()
+	if err := loadOverrideMappings(&opts, &policy); err != nil {
+		return nil, err
+	}
+	if opts.TargetRegistry == "" && (opts.Mappings == nil || len(opts.Mappings.Entries) == 0) {
+		return nil, ErrNoTarget
+	}
+	if len(opts.SourceRegistries) == 0 {
+		opts.SourceRegistries = mappingSources(opts.Mappings)
+	}
+	if opts.PathStrategy == "" {
+		opts.PathStrategy = strategy.StrategyPrefixSourceRegistry
+	}
+	pathStrategy, err := strategy.GetStrategy(opts.PathStrategy, opts.Mappings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize path strategy: %w", err)
+	}
+
+	analyzed, err := analyze(ctx, opts.ChartPath, opts.ReleaseName, opts.Namespace, opts.ValuesFiles, opts.Fs, opts.Helm)
+	if err != nil {
+		return nil, err
+	}
+	chartAnalysis := analyzed.analysis
+	chartAnalysis.ImagePatterns = selectPatterns(chartAnalysis.ImagePatterns, opts.Selectors, opts.ReleaseName)
+
+	generator := chart.NewGenerator(
+		opts.ChartPath,
+		opts.TargetRegistry,
+		opts.SourceRegistries,
+		opts.ExcludeRegistries,
+		pathStrategy,
+		opts.Mappings,
+		false,
+		0,
+		&fsChartLoader{fs: opts.Fs},
+		!opts.DisableRules,
+	)
+	generator.SetDefaultTag(opts.DefaultTag)
+	generator.SetBitnamiCompat(!opts.DisableBitnamiCompat)
+	generator.SetTargetFlavor(opts.TargetFlavor)
+	generator.SetStrictPolicy(policy)
+	if analyzed.values != nil {
+		generator.SetBaseValues(analyzed.values)
+	}
+
+	overrideFile, err := generator.Generate(analyzed.chart, chartAnalysis)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate overrides: %w", err)
+	}
+	return overrideFile, nil
+}
+
+// loadOverrideMappings loads opts.RegistryFile into opts.Mappings unless mappings were given,
+// applying the file's default target and strict mode settings.
+func loadOverrideMappings(opts *OverrideOptions, policy *strictness.Policy) error {
+	if opts.Mappings != nil || opts.RegistryFile == "" {
+		return nil
+	}
+	config, err := registry.LoadConfig(opts.Fs, opts.RegistryFile, true)
+	if err != nil {
+		return fmt.Errorf("failed to load registry mappings from %s: %w", opts.RegistryFile, err)
+	}
+	if err := config.ApplyProfile(opts.RegistryProfile); err != nil {
+		return fmt.Errorf("failed to apply registry profile %q: %w", opts.RegistryProfile, err)
+	}
+	opts.Mappings = config.ToMappings()
+	if opts.TargetRegistry == "" {
+		opts.TargetRegistry = config.Registries.DefaultTarget
+	}
+	if config.Registries.StrictMode {
+		policy.UnmappedRegistries = strictness.ActionError
+	}
+	if config.Policy != nil {
+		*policy = policy.Merge(*config.Policy)
+	}
+	return nil
+}
+
+// mappingSources returns the normalized source registries of mappings, in order and without duplicates
+func mappingSources(mappings *registry.Mappings) []string {
+	if mappings == nil {
+		return nil
+	}
+	var sources []string
+	seen := make(map[string]bool)
+	for _, entry := range mappings.Entries {
+		source := image.NormalizeRegistry(entry.Source)
+		if source == "" || seen[source] {
+			continue
+		}
+		seen[source] = true
+		sources = append(sources, source)
+	}
+	return sources
+}
@@ -0,0 +1,88 @@
+package irr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// defaultValidationRelease is the release name charts are rendered with when none is given
+const defaultValidationRelease = "irr-validation"
+
+var (
+	// ErrNoChartPath is returned by Validate when no chart path is given
+	ErrNoChartPath = errors.New("a chart path is required")
+	// ErrUnresolvedTemplate is returned by a strict Validate when the rendered manifests still
+	// contain template expressions or <no value> placeholders
+	ErrUnresolvedTemplate = errors.New("rendered manifests contain unresolved template values")
+)
+
+// ValidateOptions configures Validate
+type ValidateOptions struct {
+	// ChartPath is the chart directory or packaged chart to render
+	ChartPath string
+	// ReleaseName is the release name the chart is rendered with; defaults to irr-validation
+	ReleaseName string
+	// Namespace the chart is rendered for
+	Namespace string
+	// ValuesFiles are applied on top of the chart's values, later files taking precedence
+	ValuesFiles []string
+	// Overrides are applied last, typically the Values of the file returned by GenerateOverrides
+	Overrides map[string]interface{}
+	// Strict fails validation when the rendered manifests contain unresolved template values
+	Strict bool
+
+	// Fs is the filesystem values files are read from; defaults to the OS filesystem. The chart
+	// itself is read by the Helm client.
+	Fs afero.Fs
+	// Helm renders the chart; defaults to a client for the current kubeconfig context
+	Helm HelmClient
+}
+
+// ValidationResult is the result of a successful Validate
+type ValidationResult struct {
+	// Manifest is the rendered chart
+	Manifest string
+}
+
+// Validate renders a chart with its values files and overrides applied, checking that the
+// overrides produce a chart Helm can render.
+func Validate(ctx context.Context, opts ValidateOptions) (*ValidationResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if opts.ChartPath == "" {
+		return nil, ErrNoChartPath
+	}
+	if opts.ReleaseName == "" {
+		opts.ReleaseName = defaultValidationRelease
+	}
+
+	values, err := readValuesFiles(resolveFs(opts.Fs), opts.ValuesFiles)
+	if err != nil {
+		return nil, err
+	}
+	values = mergeValues(values, opts.Overrides)
+
+	helmClient, err := resolveHelmClient(opts.Helm)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := helmClient.TemplateChart(ctx, opts.ReleaseName, opts.Namespace, opts.ChartPath, values)
+	if err != nil {
+		return nil, fmt.Errorf("chart validation failed: %w", err)
+	}
+
+	if opts.Strict {
+		if strings.Contains(manifest, "{{") && strings.Contains(manifest, "}}") {
+			return nil, fmt.Errorf("%w: template expressions found", ErrUnresolvedTemplate)
+		}
+		if strings.Contains(manifest, "<no value>") {
+			return nil, fmt.Errorf("%w: <no value> placeholders found", ErrUnresolvedTemplate)
+		}
+	}
+	return &ValidationResult{Manifest: manifest}, nil
+}
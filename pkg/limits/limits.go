@@ -0,0 +1,186 @@
+// Package limits bounds the values irr analyzes, so that a pathological chart fails quickly with a
+// clear error instead of running for minutes or exhausting memory. Three limits apply: the size of
+// each values document (a chart's values.yaml or a --values file), the nesting depth of maps and
+// lists traversed during analysis, and the number of image patterns detected in one chart.
+//
+// The limits default to values far above those of real charts. irr's --max-values-size,
+// --max-values-depth and --max-image-patterns flags and the limits section of the registry config
+// change them with Set.
+package limits
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultMaxValuesSize is the default maximum size in bytes of a values document (256Mi)
+	DefaultMaxValuesSize int64 = 256 << 20
+	// DefaultMaxDepth is the default maximum nesting depth of maps and lists in values
+	DefaultMaxDepth = 100
+	// DefaultMaxImagePatterns is the default maximum number of image patterns detected in a chart
+	DefaultMaxImagePatterns = 10000
+)
+
+// Kind names a limit. It is the name of the flag that changes the limit.
+type Kind string
+
+const (
+	// ValuesSize limits the size of each values document
+	ValuesSize Kind = "max-values-size"
+	// Depth limits the nesting depth of values
+	Depth Kind = "max-values-depth"
+	// ImagePatterns limits the number of image patterns detected in a chart
+	ImagePatterns Kind = "max-image-patterns"
+)
+
+// Limits holds the limits of an analysis. A zero field means the default limit.
+type Limits struct {
+	// MaxValuesSize is the maximum size in bytes of a values document
+	MaxValuesSize int64
+	// MaxDepth is the maximum nesting depth of maps and lists in values
+	MaxDepth int
+	// MaxImagePatterns is the maximum number of image patterns detected in a chart
+	MaxImagePatterns int
+}
+
+// current holds the limits set with Set, with defaults filled in
+var current = withDefaults(Limits{})
+
+// withDefaults returns l with its zero fields set to the default limits.
+func withDefaults(l Limits) Limits {
+	if l.MaxValuesSize <= 0 {
+		l.MaxValuesSize = DefaultMaxValuesSize
+	}
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = DefaultMaxDepth
+	}
+	if l.MaxImagePatterns <= 0 {
+		l.MaxImagePatterns = DefaultMaxImagePatterns
+	}
+	return l
+}
+
+// Set sets the limits of every analysis run afterwards. Zero fields restore the default limits. It
+// returns a function that restores the previous limits.
+func Set(l Limits) (restore func()) {
+	previous := current
+	current = withDefaults(l)
+	return func() { current = previous }
+}
+
+// Current returns the limits in effect.
+func Current() Limits {
+	return current
+}
+
+// Error reports a values document or chart that exceeds a limit.
+type Error struct {
+	// Kind is the limit that was exceeded
+	Kind Kind
+	// Source is the values file, or the values path, where the limit was exceeded
+	Source string
+	// Value is the size, depth or pattern count that exceeded the limit
+	Value int64
+	// Max is the limit
+	Max int64
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	switch e.Kind {
+	case ValuesSize:
+		return fmt.Sprintf("values document %s is %s, larger than the %s limit; raise it with --%s if the document is expected",
+			e.Source, FormatSize(e.Value), FormatSize(e.Max), e.Kind)
+	case Depth:
+		return fmt.Sprintf("values at %s are nested more than %d levels deep; raise the limit with --%s if the nesting is expected",
+			displaySource(e.Source), e.Max, e.Kind)
+	default:
+		return fmt.Sprintf("more than %d image patterns detected (at %s); raise the limit with --%s if the chart is expected to have that many images",
+			e.Max, displaySource(e.Source), e.Kind)
+	}
+}
+
+// displaySource names the top level of the values for an empty values path.
+func displaySource(source string) string {
+	if source == "" {
+		return "the top level"
+	}
+	return source
+}
+
+// CheckValuesSize returns an *Error if a values document of size bytes exceeds the size limit.
+// source names the document in the error.
+func CheckValuesSize(source string, size int64) error {
+	if size > current.MaxValuesSize {
+		return &Error{Kind: ValuesSize, Source: source, Value: size, Max: current.MaxValuesSize}
+	}
+	return nil
+}
+
+// CheckImagePatterns returns an *Error if count image patterns exceed the pattern limit. path is
+// the values path being analyzed when the count was reached.
+func CheckImagePatterns(count int, path string) error {
+	if count > current.MaxImagePatterns {
+		return &Error{Kind: ImagePatterns, Source: path, Value: int64(count), Max: int64(current.MaxImagePatterns)}
+	}
+	return nil
+}
+
+// DepthTracker tracks the nesting depth of a values traversal. The zero value is ready to use.
+type DepthTracker struct {
+	depth int
+}
+
+// Enter descends into the map or list at path. It returns an *Error when the descent exceeds the
+// depth limit; otherwise each call must be matched by a call to Leave.
+func (t *DepthTracker) Enter(path string) error {
+	if t.depth >= current.MaxDepth {
+		return &Error{Kind: Depth, Source: path, Value: int64(t.depth + 1), Max: int64(current.MaxDepth)}
+	}
+	t.depth++
+	return nil
+}
+
+// Leave returns from the map or list entered last.
+func (t *DepthTracker) Leave() {
+	t.depth--
+}
+
+// sizeUnits are the binary unit suffixes accepted by ParseSize, largest first
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"Gi", 1 << 30},
+	{"Mi", 1 << 20},
+	{"Ki", 1 << 10},
+}
+
+// ParseSize parses a size in bytes, optionally with a Ki, Mi or Gi suffix (e.g. 512Ki, 16Mi).
+func ParseSize(s string) (int64, error) {
+	value := strings.TrimSpace(s)
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if trimmed, ok := strings.CutSuffix(value, unit.suffix); ok {
+			value, multiplier = trimmed, unit.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 || n > (1<<62)/multiplier {
+		return 0, fmt.Errorf("invalid size %q: expected a positive number of bytes, optionally with a Ki, Mi or Gi suffix", s)
+	}
+	return n * multiplier, nil
+}
+
+// FormatSize formats a size in bytes for messages, with the largest binary unit that divides it.
+func FormatSize(size int64) string {
+	for _, unit := range sizeUnits {
+		if size >= unit.bytes && size%unit.bytes == 0 {
+			return fmt.Sprintf("%d%s", size/unit.bytes, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%d bytes", size)
+}
@@ -0,0 +1,62 @@
+package limits
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetLimits(t *testing.T) {
+	assert.Equal(t, Limits{MaxValuesSize: DefaultMaxValuesSize, MaxDepth: DefaultMaxDepth, MaxImagePatterns: DefaultMaxImagePatterns}, Current())
+
+	restore := Set(Limits{MaxDepth: 3})
+	assert.Equal(t, 3, Current().MaxDepth)
+	assert.Equal(t, DefaultMaxValuesSize, Current().MaxValuesSize, "unset limits keep their default")
+	restore()
+	assert.Equal(t, DefaultMaxDepth, Current().MaxDepth)
+}
+
+func TestCheckLimits(t *testing.T) {
+	t.Cleanup(Set(Limits{MaxValuesSize: 1 << 10, MaxDepth: 2, MaxImagePatterns: 5}))
+
+	require.NoError(t, CheckValuesSize("values.yaml", 1<<10))
+	err := CheckValuesSize("values.yaml", 2<<10)
+	var limitErr *Error
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, ValuesSize, limitErr.Kind)
+	assert.Equal(t, "values document values.yaml is 2Ki, larger than the 1Ki limit; raise it with --max-values-size if the document is expected", err.Error())
+
+	require.NoError(t, CheckImagePatterns(5, "images"))
+	err = CheckImagePatterns(6, "images")
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, ImagePatterns, limitErr.Kind)
+	assert.Equal(t, int64(6), limitErr.Value)
+
+	var depth DepthTracker
+	require.NoError(t, depth.Enter(""))
+	require.NoError(t, depth.Enter("a"))
+	err = depth.Enter("a.b")
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, Depth, limitErr.Kind)
+	assert.Equal(t, "a.b", limitErr.Source)
+	depth.Leave()
+	require.NoError(t, depth.Enter("c"), "leaving a level allows entering a sibling")
+}
+
+func TestParseSize(t *testing.T) {
+	for input, want := range map[string]int64{"512": 512, "64Ki": 64 << 10, " 16Mi ": 16 << 20, "1Gi": 1 << 30} {
+		size, err := ParseSize(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, size, input)
+	}
+	for _, input := range []string{"", "0", "-1Mi", "16MB", "Mi", "1.5Gi"} {
+		_, err := ParseSize(input)
+		assert.Error(t, err, input)
+	}
+
+	assert.Equal(t, "16Mi", FormatSize(16<<20))
+	assert.Equal(t, "1536Ki", FormatSize(1536<<10))
+	assert.Equal(t, "1000 bytes", FormatSize(1000))
+}
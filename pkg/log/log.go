@@ -3,9 +3,17 @@
 // By default, it configures a global logger writing JSON (or text if LOG_FORMAT=text)
 // to os.Stderr. The log level is controlled globally via SetLevel() and can be
 // initialized from environment variables or command-line flags (typically done in main).
+// The format can also be set explicitly with SetFormat(), which takes precedence over LOG_FORMAT.
 //
-// Use the SetOutput() function to redirect log output, primarily for testing purposes.
+// Use the SetOutput() function to redirect log output, e.g. to a log file or a test buffer.
 // It replaces the default os.Stderr writer and returns a function to restore it.
+//
+// Scoped fields (such as the command, chart, or release being processed) can be attached
+// to every subsequent log record with AddFields() and cleared with ResetFields(). They apply to
+// records of every goroutine, so code processing several charts concurrently must pass the
+// chart as an argument of each record instead.
+//
+// All functions are safe for concurrent use.
 package log
 
 import (
@@ -15,6 +23,7 @@ import (
 	"log/slog"
 	"os"
 	"strings"
+	"sync"
 )
 
 // Log level constants matching slog and environment variable values.
@@ -25,13 +34,29 @@ const (
 	levelErrorStr = "ERROR"
 )
 
+// Log format constants accepted by SetFormat and the LOG_FORMAT environment variable.
+const (
+	// FormatJSON selects structured JSON log output (the default).
+	FormatJSON = "json"
+	// FormatText selects human-readable text log output.
+	FormatText = "text"
+)
+
 var (
+	// mu guards logger and the state it is configured from
+	mu     sync.RWMutex
 	logger *slog.Logger
 	// currentLevel           = slog.LevelInfo // Replaced by globalLeveler
 	globalLeveler           = &slog.LevelVar{} // Use LevelVar for dynamic level changes
 	outputWriter  io.Writer = os.Stderr
 	// ErrInvalidLogLevel indicates an invalid log level string was provided.
 	ErrInvalidLogLevel = fmt.Errorf("invalid log level")
+	// ErrInvalidLogFormat indicates an invalid log format string was provided.
+	ErrInvalidLogFormat = fmt.Errorf("invalid log format")
+	// formatOverride holds the format set via SetFormat; empty means fall back to LOG_FORMAT.
+	formatOverride string
+	// scopedFields holds key-value pairs attached to every log record (see AddFields).
+	scopedFields []any
 	// includeTimestampsForTest is a flag used by test helpers (like testutil.CaptureJSONLogs)
 	// to temporarily force timestamp inclusion during log capture, overriding the default behavior.
 	includeTimestampsForTest bool // Defaults to false
@@ -51,25 +76,31 @@ func init() {
 }
 
 // configureLogger sets up the logger using the current global state
-// (outputWriter and globalLeveler). It does not read environment variables itself.
+// (outputWriter, globalLeveler, formatOverride and scopedFields).
+// LOG_FORMAT is only consulted when no format has been set via SetFormat.
+// It must be called with mu held.
 func configureLogger() {
 	// Determine log format
-	format := strings.ToLower(os.Getenv("LOG_FORMAT"))
+	format := formatOverride
+	if format == "" {
+		format = strings.ToLower(os.Getenv("LOG_FORMAT"))
+	}
 	var handler slog.Handler
 
 	// Prepare common options, using the dynamic LevelVar for the level
 	opts := &slog.HandlerOptions{Level: globalLeveler}
 
 	// Default to JSON unless LOG_FORMAT is explicitly "text"
-	if format == "text" {
+	if format == FormatText {
 		// Text handler: Timestamps are included by default, no ReplaceAttr needed initially.
 		// If specific text format changes are needed later, they would go here.
 		handler = slog.NewTextHandler(outputWriter, opts)
 	} else {
 		// JSON handler: Conditionally remove the time attribute based on the test flag.
+		includeTimestamps := includeTimestampsForTest
 		opts.ReplaceAttr = func(_ []string, a slog.Attr) slog.Attr {
 			// Remove the time attribute ONLY if the test flag is NOT set.
-			if !includeTimestampsForTest && a.Key == slog.TimeKey {
+			if !includeTimestamps && a.Key == slog.TimeKey {
 				return slog.Attr{} // Remove the time attribute
 			}
 			return a // Keep other attributes (or time attribute if flag is true)
@@ -77,43 +108,109 @@ func configureLogger() {
 		handler = slog.NewJSONHandler(outputWriter, opts)
 	}
 	logger = slog.New(handler)
+	if len(scopedFields) > 0 {
+		logger = logger.With(scopedFields...)
+	}
+}
+
+// SetFormat sets the log output format ("json" or "text"), taking precedence over LOG_FORMAT.
+// An empty string clears the override so that LOG_FORMAT is used again.
+func SetFormat(format string) error {
+	normalized := strings.ToLower(strings.TrimSpace(format))
+	switch normalized {
+	case "", FormatJSON, FormatText:
+		mu.Lock()
+		defer mu.Unlock()
+		formatOverride = normalized
+		configureLogger()
+		return nil
+	default:
+		return fmt.Errorf("%w: %s (supported: %s, %s)", ErrInvalidLogFormat, format, FormatJSON, FormatText)
+	}
+}
+
+// AddFields attaches key-value pairs to every subsequent log record,
+// e.g. AddFields("command", "inspect", "release", "my-release").
+// A key that is already scoped is replaced rather than duplicated.
+func AddFields(args ...any) {
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 0; i+1 < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok {
+			continue
+		}
+		replaced := false
+		for j := 0; j+1 < len(scopedFields); j += 2 {
+			if scopedFields[j] == key {
+				scopedFields[j+1] = args[i+1]
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			scopedFields = append(scopedFields, key, args[i+1])
+		}
+	}
+	configureLogger()
+}
+
+// ResetFields removes all scoped fields previously added with AddFields.
+func ResetFields() {
+	mu.Lock()
+	defer mu.Unlock()
+	scopedFields = nil
+	configureLogger()
 }
 
 // SetOutput changes the output destination for the logger.
 // It returns a function that can be called to restore the original output writer.
 // This is primarily intended for testing.
 func SetOutput(w io.Writer) (restore func()) {
+	mu.Lock()
+	defer mu.Unlock()
 	originalWriter := outputWriter
 	outputWriter = w
 	configureLogger() // Re-configure logger with the new writer
 	return func() {
+		mu.Lock()
+		defer mu.Unlock()
 		outputWriter = originalWriter
 		configureLogger() // Restore original writer and re-configure
 	}
 }
 
+// Output returns the writer log records are currently written to.
+func Output() io.Writer {
+	mu.RLock()
+	defer mu.RUnlock()
+	return outputWriter
+}
+
 // Debug logs a debug message with optional key-value pairs
 func Debug(msg string, args ...any) {
-	logger.DebugContext(context.Background(), msg, args...)
+	Logger().DebugContext(context.Background(), msg, args...)
 }
 
 // Info logs an info message with optional key-value pairs
 func Info(msg string, args ...any) {
-	logger.InfoContext(context.Background(), msg, args...)
+	Logger().InfoContext(context.Background(), msg, args...)
 }
 
 // Warn logs a warning message with optional key-value pairs
 func Warn(msg string, args ...any) {
-	logger.WarnContext(context.Background(), msg, args...)
+	Logger().WarnContext(context.Background(), msg, args...)
 }
 
 // Error logs an error message with optional key-value pairs
 func Error(msg string, args ...any) {
-	logger.ErrorContext(context.Background(), msg, args...)
+	Logger().ErrorContext(context.Background(), msg, args...)
 }
 
 // Logger returns the underlying slog.Logger
 func Logger() *slog.Logger {
+	mu.RLock()
+	defer mu.RUnlock()
 	return logger
 }
 
@@ -191,6 +288,8 @@ func ParseLevel(levelStr string) (Level, error) {
 // This is intended ONLY for use by test helpers (e.g., testutil.CaptureJSONLogs)
 // to ensure timestamps are present for assertions, even if the default is to omit them.
 func SetTestModeWithTimestamps(enabled bool) {
+	mu.Lock()
+	defer mu.Unlock()
 	includeTimestampsForTest = enabled
 	// Reconfigure the logger immediately to apply the change
 	// This is important because the test helper might call this *before*
@@ -3,12 +3,14 @@ package log
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -430,3 +432,95 @@ func TestSetTestModeWithTimestamps(t *testing.T) {
 	assert.NotContains(t, output, `"time":"`, "JSON log should NOT contain time field when disabled")
 	assert.Contains(t, output, `"msg":"message without timestamp"`, "JSON log should contain message")
 }
+
+// TestSetFormat verifies that SetFormat switches between JSON and text output
+// and rejects unsupported formats.
+func TestSetFormat(t *testing.T) {
+	var buf bytes.Buffer
+	restoreOutput := SetOutput(&buf)
+	defer restoreOutput()
+	defer func() {
+		require.NoError(t, SetFormat(""))
+	}()
+
+	require.NoError(t, SetFormat(FormatText))
+	Info("text message", "key", "value")
+	assert.Contains(t, buf.String(), "msg=\"text message\"", "Text format should use key=value output")
+	assert.Contains(t, buf.String(), "key=value")
+
+	buf.Reset()
+	require.NoError(t, SetFormat("JSON"))
+	Info("json message")
+	assert.Contains(t, buf.String(), `"msg":"json message"`, "JSON format should produce JSON output")
+
+	err := SetFormat("xml")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalidLogFormat), "Error should wrap ErrInvalidLogFormat")
+}
+
+// TestAddFields verifies that scoped fields are attached to every log record,
+// replaced when re-added, and removed by ResetFields.
+func TestAddFields(t *testing.T) {
+	var buf bytes.Buffer
+	restoreOutput := SetOutput(&buf)
+	defer restoreOutput()
+	defer ResetFields()
+
+	AddFields("command", "inspect", "release", "first")
+	Info("scoped message")
+	output := buf.String()
+	assert.Contains(t, output, `"command":"inspect"`)
+	assert.Contains(t, output, `"release":"first"`)
+
+	buf.Reset()
+	AddFields("release", "second")
+	Info("updated scope")
+	output = buf.String()
+	assert.Contains(t, output, `"release":"second"`)
+	assert.NotContains(t, output, `"release":"first"`, "Re-added key should replace the previous value")
+
+	buf.Reset()
+	ResetFields()
+	Info("unscoped message")
+	assert.NotContains(t, buf.String(), `"command":"inspect"`, "ResetFields should remove scoped fields")
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writes
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// TestAddFieldsConcurrent verifies that scoped fields can be changed while other goroutines log.
+func TestAddFieldsConcurrent(t *testing.T) {
+	var buf lockedBuffer
+	restoreOutput := SetOutput(&buf)
+	defer restoreOutput()
+	defer ResetFields()
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				AddFields("worker", i)
+				Info("concurrent message", "worker", i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSpace(buf.buf.String()), "\n")
+	assert.Len(t, lines, 8*50)
+	for _, line := range lines {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record), line)
+	}
+}
@@ -0,0 +1,179 @@
+// Package metrics counts what a run did (images analyzed, overrides generated, unmapped
+// registries, validation failures) and writes the counters, together with the stage durations
+// recorded by package timing, in the Prometheus text exposition format. Counters only increase
+// while recording is enabled.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lucas-albers-lz4/irr/pkg/timing"
+)
+
+// Names of the counters kept by irr.
+const (
+	// ImagesAnalyzed counts the images detected in chart values
+	ImagesAnalyzed = "irr_images_analyzed_total"
+	// OverridesGenerated counts the image overrides written
+	OverridesGenerated = "irr_overrides_generated_total"
+	// UnmappedRegistries counts images whose source registry has no mapping, by registry
+	UnmappedRegistries = "irr_unmapped_registries_total"
+	// ValidationFailures counts failed helm template validations of overrides
+	ValidationFailures = "irr_validation_failures_total"
+
+	// StageDuration is the histogram of the timing spans, by stage
+	StageDuration = "irr_stage_duration_seconds"
+)
+
+// counterHelp is the HELP text of each counter, in the order they are written
+var counterHelp = []struct{ name, help string }{
+	{ImagesAnalyzed, "Images detected in chart values."},
+	{OverridesGenerated, "Image overrides generated."},
+	{UnmappedRegistries, "Images whose source registry has no mapping, by registry."},
+	{ValidationFailures, "Failed helm template validations of overrides."},
+}
+
+// DurationBuckets are the upper bounds in seconds of the StageDuration histogram buckets. They
+// match the default buckets of the Prometheus client libraries.
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var (
+	mu        sync.Mutex
+	recording bool
+	counters  = make(map[string]map[string]float64)
+)
+
+// EnableRecording makes the counters count from now on.
+func EnableRecording() {
+	mu.Lock()
+	defer mu.Unlock()
+	recording = true
+}
+
+// Reset stops recording and sets all counters back to zero.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	recording = false
+	counters = make(map[string]map[string]float64)
+}
+
+// Inc adds one to the counter name. labels are name/value pairs, e.g. "registry", "quay.io".
+func Inc(name string, labels ...string) {
+	Add(name, 1, labels...)
+}
+
+// Add adds n to the counter name. labels are name/value pairs, e.g. "registry", "quay.io".
+func Add(name string, n int, labels ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if !recording || n <= 0 {
+		return
+	}
+	series, ok := counters[name]
+	if !ok {
+		series = make(map[string]float64)
+		counters[name] = series
+	}
+	series[formatLabels(labels...)] += float64(n)
+}
+
+// Value returns the current value of the counter name with the given labels.
+func Value(name string, labels ...string) float64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return counters[name][formatLabels(labels...)]
+}
+
+// Write writes all counters, and a StageDuration histogram of spans, in the Prometheus text
+// exposition format. Counters without labels are written even when zero.
+func Write(w io.Writer, spans []timing.Span) error {
+	bw := bufio.NewWriter(w)
+
+	mu.Lock()
+	for _, counter := range counterHelp {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s counter\n", counter.name, counter.help, counter.name)
+		series := counters[counter.name]
+		if len(series) == 0 {
+			if counter.name != UnmappedRegistries {
+				fmt.Fprintf(bw, "%s 0\n", counter.name)
+			}
+			continue
+		}
+		for _, labels := range sortedKeys(series) {
+			fmt.Fprintf(bw, "%s%s %s\n", counter.name, labels, formatValue(series[labels]))
+		}
+	}
+	mu.Unlock()
+
+	writeDurationHistogram(bw, spans)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return nil
+}
+
+// writeDurationHistogram writes the StageDuration histogram, one series per stage
+func writeDurationHistogram(w io.Writer, spans []timing.Span) {
+	fmt.Fprintf(w, "# HELP %s Duration of the stages of a run (chart load, analysis, generation, validation).\n", StageDuration)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", StageDuration)
+	for _, summary := range timing.Summarize(spans) {
+		counts := make([]int, len(DurationBuckets))
+		for _, span := range spans {
+			if span.Name != summary.Name {
+				continue
+			}
+			seconds := span.Duration.Seconds()
+			for i, bound := range DurationBuckets {
+				if seconds <= bound {
+					counts[i]++
+				}
+			}
+		}
+		stage := escapeLabelValue(summary.Name)
+		for i, bound := range DurationBuckets {
+			fmt.Fprintf(w, "%s_bucket{stage=\"%s\",le=\"%s\"} %d\n", StageDuration, stage, formatValue(bound), counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{stage=\"%s\",le=\"+Inf\"} %d\n", StageDuration, stage, summary.Count)
+		fmt.Fprintf(w, "%s_sum{stage=\"%s\"} %s\n", StageDuration, stage, formatValue(summary.Total.Seconds()))
+		fmt.Fprintf(w, "%s_count{stage=\"%s\"} %d\n", StageDuration, stage, summary.Count)
+	}
+}
+
+// formatLabels renders name/value pairs as a Prometheus label set, e.g. {registry="quay.io"}
+func formatLabels(labels ...string) string {
+	if len(labels) < 2 {
+		return ""
+	}
+	parts := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", labels[i], escapeLabelValue(labels[i+1])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// escapeLabelValue escapes a label value as the exposition format requires
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatValue renders a sample value without a trailing exponent or zeros
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// sortedKeys returns the label sets of a counter in a stable order
+func sortedKeys(series map[string]float64) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
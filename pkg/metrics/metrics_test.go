@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/lucas-albers-lz4/irr/pkg/timing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounters(t *testing.T) {
+	t.Cleanup(Reset)
+
+	Inc(ImagesAnalyzed)
+	assert.Zero(t, Value(ImagesAnalyzed), "counters do not count until recording is enabled")
+
+	EnableRecording()
+	Add(ImagesAnalyzed, 3)
+	Inc(ImagesAnalyzed)
+	Inc(UnmappedRegistries, "registry", "quay.io")
+	Inc(UnmappedRegistries, "registry", "quay.io")
+	Inc(UnmappedRegistries, "registry", "ghcr.io")
+	assert.Equal(t, float64(4), Value(ImagesAnalyzed))
+	assert.Equal(t, float64(2), Value(UnmappedRegistries, "registry", "quay.io"))
+	assert.Equal(t, float64(1), Value(UnmappedRegistries, "registry", "ghcr.io"))
+
+	Reset()
+	assert.Zero(t, Value(ImagesAnalyzed))
+	Inc(ImagesAnalyzed)
+	assert.Zero(t, Value(ImagesAnalyzed), "Reset stops recording")
+}
+
+func TestWrite(t *testing.T) {
+	t.Cleanup(Reset)
+	EnableRecording()
+	Add(ImagesAnalyzed, 5)
+	Add(OverridesGenerated, 4)
+	Inc(UnmappedRegistries, "registry", `quay.io`)
+	Inc(UnmappedRegistries, "registry", `a"b`)
+
+	start := time.Now()
+	spans := []timing.Span{
+		{Name: timing.SpanChartLoad, Start: start, Duration: 20 * time.Millisecond},
+		{Name: timing.SpanAnalysis, Start: start.Add(time.Second), Duration: 2 * time.Second},
+		{Name: timing.SpanAnalysis, Start: start.Add(2 * time.Second), Duration: 30 * time.Second},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, Write(&out, spans))
+	text := out.String()
+
+	assert.Contains(t, text, "# TYPE irr_images_analyzed_total counter\nirr_images_analyzed_total 5\n")
+	assert.Contains(t, text, "irr_overrides_generated_total 4\n")
+	assert.Contains(t, text, "irr_unmapped_registries_total{registry=\"a\\\"b\"} 1\nirr_unmapped_registries_total{registry=\"quay.io\"} 1\n")
+	assert.Contains(t, text, "irr_validation_failures_total 0\n", "unlabelled counters are written even when zero")
+
+	assert.Contains(t, text, "# TYPE irr_stage_duration_seconds histogram\n")
+	assert.Contains(t, text, `irr_stage_duration_seconds_bucket{stage="chart load",le="0.01"} 0`)
+	assert.Contains(t, text, `irr_stage_duration_seconds_bucket{stage="chart load",le="0.025"} 1`)
+	assert.Contains(t, text, `irr_stage_duration_seconds_bucket{stage="analysis",le="2.5"} 1`)
+	assert.Contains(t, text, `irr_stage_duration_seconds_bucket{stage="analysis",le="10"} 1`)
+	assert.Contains(t, text, `irr_stage_duration_seconds_bucket{stage="analysis",le="+Inf"} 2`)
+	assert.Contains(t, text, `irr_stage_duration_seconds_sum{stage="analysis"} 32`)
+	assert.Contains(t, text, `irr_stage_duration_seconds_count{stage="chart load"} 1`)
+}
@@ -0,0 +1,36 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ConfigMapSink publishes overrides under Key of the ConfigMap Name in Namespace. Other keys of
+// the ConfigMap are kept.
+type ConfigMapSink struct {
+	Namespace string
+	Name      string
+	Key       string
+	opts      Options
+}
+
+// Write stores data under the sink's key, creating the ConfigMap if it does not exist.
+func (s *ConfigMapSink) Write(ctx context.Context, data []byte) error {
+	if s.opts.NewConfigMapWriter == nil {
+		return errors.New("no Kubernetes client configured for " + s.String())
+	}
+	writer, err := s.opts.NewConfigMapWriter()
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client for %s: %w", s, err)
+	}
+	if err := writer.PutConfigMapData(ctx, s.Namespace, s.Name, s.Key, data); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", s, err)
+	}
+	return nil
+}
+
+// String returns the sink's k8s:// URI.
+func (s *ConfigMapSink) String() string {
+	return SchemeK8s + "://" + s.Namespace + "/" + s.Name + "/" + s.Key
+}
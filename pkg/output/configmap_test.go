@@ -0,0 +1,40 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConfigMapWriter records the data put into ConfigMaps, keyed by namespace/name/key
+type fakeConfigMapWriter struct {
+	data map[string]string
+}
+
+func (w *fakeConfigMapWriter) PutConfigMapData(_ context.Context, namespace, name, key string, data []byte) error {
+	w.data[namespace+"/"+name+"/"+key] = string(data)
+	return nil
+}
+
+func TestConfigMapSinkWrite(t *testing.T) {
+	writer := &fakeConfigMapWriter{data: map[string]string{}}
+	sink, err := NewSink("k8s://ci/irr-overrides/web.yaml", Options{
+		NewConfigMapWriter: func() (ConfigMapWriter, error) { return writer, nil },
+	})
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(context.Background(), []byte("image: {}\n")))
+	assert.Equal(t, map[string]string{"ci/irr-overrides/web.yaml": "image: {}\n"}, writer.data)
+
+	sink, err = NewSink("k8s://ci/irr-overrides/web.yaml", Options{
+		NewConfigMapWriter: func() (ConfigMapWriter, error) { return nil, errors.New("no kubeconfig") },
+	})
+	require.NoError(t, err)
+	assert.ErrorContains(t, sink.Write(context.Background(), nil), "no kubeconfig")
+
+	sink, err = NewSink("k8s://ci/irr-overrides/web.yaml", Options{})
+	require.NoError(t, err)
+	assert.ErrorContains(t, sink.Write(context.Background(), nil), "no Kubernetes client configured")
+}
@@ -0,0 +1,57 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxErrorBodySize limits how much of an error response body is included in errors
+const maxErrorBodySize = 512
+
+// HTTPSink publishes overrides with an HTTP PUT to URL. Credentials in the URL's user info are
+// sent as basic authentication.
+type HTTPSink struct {
+	URL  *url.URL
+	opts Options
+}
+
+// Write uploads data to the sink's URL.
+func (s *HTTPSink) Write(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.URL.String(), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request for %s: %w", s, err)
+	}
+	req.Header.Set("Content-Type", s.opts.ContentType)
+	return doUpload(s.opts.HTTPClient, req, s.String())
+}
+
+// String returns the sink's URL without its user info.
+func (s *HTTPSink) String() string {
+	redacted := *s.URL
+	redacted.User = nil
+	return redacted.String()
+}
+
+// doUpload sends req and fails unless the response has a 2xx status.
+func doUpload(client *http.Client, req *http.Request, destination string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload to %s: %w", destination, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		message := strings.TrimSpace(string(body))
+		if message == "" {
+			return fmt.Errorf("failed to upload to %s: %s", destination, resp.Status)
+		}
+		return fmt.Errorf("failed to upload to %s: %s: %s", destination, resp.Status, message)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package output
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPSinkWrite(t *testing.T) {
+	var method, contentType, body string
+	var user, password string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, contentType = r.Method, r.Header.Get("Content-Type")
+		user, password, _ = r.BasicAuth()
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		if r.URL.Path == "/forbidden.yaml" {
+			http.Error(w, "read-only bucket", http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	sink, err := NewSink("http://ci:secret@"+server.Listener.Addr().String()+"/web.yaml", Options{ContentType: "application/json"})
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(context.Background(), []byte(`{"image":{}}`)))
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "application/json", contentType)
+	assert.Equal(t, `{"image":{}}`, body)
+	assert.Equal(t, "ci", user)
+	assert.Equal(t, "secret", password)
+
+	sink, err = NewSink(server.URL+"/forbidden.yaml", Options{})
+	require.NoError(t, err)
+	err = sink.Write(context.Background(), []byte("image: {}\n"))
+	assert.ErrorContains(t, err, "403 Forbidden: read-only bucket")
+	assert.Equal(t, DefaultContentType, contentType)
+}
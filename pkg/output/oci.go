@@ -0,0 +1,133 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+)
+
+// Media types of the OCI artifact that OCI sinks push.
+const (
+	// ArtifactTypeOverrides is the artifact type of pushed overrides
+	ArtifactTypeOverrides = "application/vnd.irr.overrides.v1"
+	// MediaTypeImageManifest is the media type of the artifact's manifest
+	MediaTypeImageManifest = "application/vnd.oci.image.manifest.v1+json"
+	// MediaTypeEmptyConfig is the media type of the artifact's empty config, as artifacts have none
+	MediaTypeEmptyConfig = "application/vnd.oci.empty.v1+json"
+)
+
+// Annotations of the pushed artifact.
+const (
+	// AnnotationTitle names the file the overrides layer is pulled as
+	AnnotationTitle = "org.opencontainers.image.title"
+	// AnnotationCreated is the time the artifact was pushed (RFC 3339)
+	AnnotationCreated = "org.opencontainers.image.created"
+	// AnnotationChartName and AnnotationChartVersion identify the chart the overrides are for
+	AnnotationChartName    = "io.github.lucas-albers-lz4.irr.chart.name"
+	AnnotationChartVersion = "io.github.lucas-albers-lz4.irr.chart.version"
+)
+
+// emptyConfig is the content of the artifact's empty config blob
+var emptyConfig = []byte("{}")
+
+// OCIPusher uploads blobs and manifests to OCI registries.
+type OCIPusher interface {
+	// PushBlob uploads data as a blob of repository on host and returns its digest.
+	PushBlob(ctx context.Context, host, repository string, data []byte) (string, error)
+	// PushManifest uploads a manifest of mediaType under the tag reference and returns its digest.
+	PushManifest(ctx context.Context, host, repository, reference, mediaType string, data []byte) (string, error)
+}
+
+// OCISink publishes overrides as an OCI artifact tagged Tag in Repository on the registry Host.
+// The artifact has a single layer holding the overrides, whose media type is the content type,
+// and is annotated with Options.Annotations, e.g. the chart name and version.
+type OCISink struct {
+	Host       string
+	Repository string
+	Tag        string
+	opts       Options
+}
+
+// ociDescriptor describes a blob referenced by a manifest.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int               `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ociManifest is an OCI image manifest of an artifact.
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// Write pushes data as the artifact's layer, then its manifest under the sink's tag.
+func (s *OCISink) Write(ctx context.Context, data []byte) error {
+	if s.opts.NewOCIPusher == nil {
+		return errors.New("no registry client configured for " + s.String())
+	}
+	pusher, err := s.opts.NewOCIPusher()
+	if err != nil {
+		return fmt.Errorf("failed to create registry client for %s: %w", s, err)
+	}
+
+	configDigest, err := pusher.PushBlob(ctx, s.Host, s.Repository, emptyConfig)
+	if err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", s, err)
+	}
+	layerDigest, err := pusher.PushBlob(ctx, s.Host, s.Repository, data)
+	if err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", s, err)
+	}
+
+	annotations := map[string]string{AnnotationCreated: s.opts.Now().UTC().Format(time.RFC3339)}
+	maps.Copy(annotations, s.opts.Annotations)
+	manifest, err := json.Marshal(ociManifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeImageManifest,
+		ArtifactType:  ArtifactTypeOverrides,
+		Config:        ociDescriptor{MediaType: MediaTypeEmptyConfig, Digest: configDigest, Size: len(emptyConfig)},
+		Layers: []ociDescriptor{{
+			MediaType:   s.opts.ContentType,
+			Digest:      layerDigest,
+			Size:        len(data),
+			Annotations: map[string]string{AnnotationTitle: overridesFileName(s.opts.ContentType)},
+		}},
+		Annotations: annotations,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create manifest for %s: %w", s, err)
+	}
+	if _, err := pusher.PushManifest(ctx, s.Host, s.Repository, s.Tag, MediaTypeImageManifest, manifest); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", s, err)
+	}
+	return nil
+}
+
+// String returns the sink's oci:// URI.
+func (s *OCISink) String() string {
+	return SchemeOCI + "://" + s.Host + "/" + s.Repository + ":" + s.Tag
+}
+
+// overridesFileName returns the file name the overrides layer is pulled as, by content type
+func overridesFileName(contentType string) string {
+	switch {
+	case contentType == AgeContentType:
+		return "overrides.age"
+	case strings.Contains(contentType, "json"):
+		return "overrides.json"
+	case strings.HasPrefix(contentType, "text/"):
+		return "overrides.txt"
+	default:
+		return "overrides.yaml"
+	}
+}
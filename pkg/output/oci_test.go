@@ -0,0 +1,71 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOCIPusher records the blobs and manifests pushed, keyed by digest and tag
+type fakeOCIPusher struct {
+	blobs     map[string]string
+	manifests map[string]string
+	repo      string
+}
+
+func (p *fakeOCIPusher) PushBlob(_ context.Context, host, repository string, data []byte) (string, error) {
+	p.repo = host + "/" + repository
+	digest := "sha256:" + string(data)
+	p.blobs[digest] = string(data)
+	return digest, nil
+}
+
+func (p *fakeOCIPusher) PushManifest(_ context.Context, _, _, reference, mediaType string, data []byte) (string, error) {
+	if mediaType != MediaTypeImageManifest {
+		return "", errors.New("unexpected manifest media type " + mediaType)
+	}
+	p.manifests[reference] = string(data)
+	return "sha256:manifest", nil
+}
+
+func TestOCISinkWrite(t *testing.T) {
+	pusher := &fakeOCIPusher{blobs: map[string]string{}, manifests: map[string]string{}}
+	sink, err := NewSink("oci://harbor.local/platform/overrides:web", Options{
+		NewOCIPusher: func() (OCIPusher, error) { return pusher, nil },
+		Annotations:  map[string]string{AnnotationChartName: "web", AnnotationChartVersion: "1.2.0"},
+		Now:          func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) },
+	})
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(context.Background(), []byte("image: {}\n")))
+
+	assert.Equal(t, "harbor.local/platform/overrides", pusher.repo)
+	assert.Equal(t, map[string]string{"sha256:{}": "{}", "sha256:image: {}\n": "image: {}\n"}, pusher.blobs)
+	var manifest ociManifest
+	require.NoError(t, json.Unmarshal([]byte(pusher.manifests["web"]), &manifest))
+	assert.Equal(t, ociManifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeImageManifest,
+		ArtifactType:  ArtifactTypeOverrides,
+		Config:        ociDescriptor{MediaType: MediaTypeEmptyConfig, Digest: "sha256:{}", Size: 2},
+		Layers: []ociDescriptor{{
+			MediaType:   DefaultContentType,
+			Digest:      "sha256:image: {}\n",
+			Size:        10,
+			Annotations: map[string]string{AnnotationTitle: "overrides.yaml"},
+		}},
+		Annotations: map[string]string{
+			AnnotationCreated:      "2024-05-01T12:00:00Z",
+			AnnotationChartName:    "web",
+			AnnotationChartVersion: "1.2.0",
+		},
+	}, manifest)
+
+	sink, err = NewSink("oci://harbor.local/platform/overrides:web", Options{})
+	require.NoError(t, err)
+	assert.ErrorContains(t, sink.Write(context.Background(), nil), "no registry client configured")
+}
@@ -0,0 +1,185 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Environment variables read by S3 sinks, as used by the AWS CLI and SDKs.
+const (
+	EnvAWSAccessKeyID     = "AWS_ACCESS_KEY_ID"
+	EnvAWSSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+	EnvAWSSessionToken    = "AWS_SESSION_TOKEN"
+	EnvAWSRegion          = "AWS_REGION"
+	EnvAWSDefaultRegion   = "AWS_DEFAULT_REGION"
+	EnvAWSEndpointURLS3   = "AWS_ENDPOINT_URL_S3"
+	EnvAWSEndpointURL     = "AWS_ENDPOINT_URL"
+)
+
+const (
+	// defaultS3Region is used when neither AWS_REGION nor AWS_DEFAULT_REGION is set
+	defaultS3Region = "us-east-1"
+	// s3SigningAlgorithm is the AWS Signature Version 4 algorithm name
+	s3SigningAlgorithm = "AWS4-HMAC-SHA256"
+	// s3Service is the service name in the signing scope
+	s3Service = "s3"
+	// Timestamp layouts of the signing time and the signing scope
+	amzDateLayout   = "20060102T150405Z"
+	amzScopeLayout  = "20060102"
+	amzScopeRequest = "aws4_request"
+)
+
+// ErrMissingS3Credentials is returned when the AWS credentials are not set in the environment.
+var ErrMissingS3Credentials = errors.New("S3 credentials not found: set " + EnvAWSAccessKeyID + " and " + EnvAWSSecretAccessKey)
+
+// S3Sink publishes overrides to the object Key of Bucket with a PUT signed with AWS Signature
+// Version 4. Credentials and region come from the standard AWS environment variables; when an
+// endpoint is set (AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL) path-style requests are sent to it,
+// for S3-compatible stores such as MinIO.
+type S3Sink struct {
+	Bucket string
+	Key    string
+	opts   Options
+}
+
+// Write uploads data to the sink's object.
+func (s *S3Sink) Write(ctx context.Context, data []byte) error {
+	accessKey := s.opts.Getenv(EnvAWSAccessKeyID)
+	secretKey := s.opts.Getenv(EnvAWSSecretAccessKey)
+	if accessKey == "" || secretKey == "" {
+		return ErrMissingS3Credentials
+	}
+	region := firstNonEmpty(s.opts.Getenv(EnvAWSRegion), s.opts.Getenv(EnvAWSDefaultRegion), defaultS3Region)
+
+	objectURL, err := s.objectURL(region)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request for %s: %w", s, err)
+	}
+	req.Header.Set("Content-Type", s.opts.ContentType)
+	if token := s.opts.Getenv(EnvAWSSessionToken); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+	signS3Request(req, data, accessKey, secretKey, region, s.opts.Now())
+	return doUpload(s.opts.HTTPClient, req, s.String())
+}
+
+// String returns the sink's s3:// URI.
+func (s *S3Sink) String() string {
+	return SchemeS3 + "://" + s.Bucket + "/" + s.Key
+}
+
+// objectURL returns the HTTPS URL of the object: virtual-hosted on AWS, path-style on a custom endpoint.
+func (s *S3Sink) objectURL(region string) (string, error) {
+	objectPath := "/" + awsURIEscape(s.Key, false)
+	endpoint := firstNonEmpty(s.opts.Getenv(EnvAWSEndpointURLS3), s.opts.Getenv(EnvAWSEndpointURL))
+	if endpoint == "" {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com%s", s.Bucket, region, objectPath), nil
+	}
+	base, err := url.Parse(endpoint)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return "", fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+	return strings.TrimSuffix(base.String(), "/") + "/" + awsURIEscape(s.Bucket, true) + objectPath, nil
+}
+
+// signS3Request adds the AWS Signature Version 4 headers to req, whose body is payload. The host,
+// the content type and the x-amz-* headers are signed.
+func signS3Request(req *http.Request, payload []byte, accessKey, secretKey, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format(amzDateLayout)
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := slices.Sorted(maps.Keys(headers))
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{now.Format(amzScopeLayout), region, s3Service, amzScopeRequest}, "/")
+	stringToSign := strings.Join([]string{s3SigningAlgorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	signingKey := s3SigningKey(secretKey, now.Format(amzScopeLayout), region, s3Service)
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SigningAlgorithm, accessKey, scope, signedHeaders, signature))
+}
+
+// s3SigningKey derives the Signature Version 4 signing key of a day, region and service.
+func s3SigningKey(secretKey, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, amzScopeRequest)
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sha256Hex returns the hex-encoded SHA-256 of data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// awsURIEscape percent-encodes value as AWS expects in canonical URIs: every byte except
+// unreserved characters, and '/' unless escapeSlash is set.
+func awsURIEscape(value string, escapeSlash bool) string {
+	var escaped strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', b == '/' && !escapeSlash:
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package output
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3SigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation
+	key := s3SigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	assert.Equal(t, "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d", hex.EncodeToString(key))
+}
+
+func TestS3SinkWrite(t *testing.T) {
+	var path, authorization, token, payloadHash, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		authorization = r.Header.Get("Authorization")
+		token = r.Header.Get("X-Amz-Security-Token")
+		payloadHash = r.Header.Get("X-Amz-Content-Sha256")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	env := map[string]string{
+		EnvAWSAccessKeyID:     "AKIDEXAMPLE",
+		EnvAWSSecretAccessKey: "secret",
+		EnvAWSSessionToken:    "session",
+		EnvAWSRegion:          "eu-west-1",
+		EnvAWSEndpointURLS3:   server.URL,
+	}
+	opts := Options{
+		Getenv: func(key string) string { return env[key] },
+		Now:    func() time.Time { return time.Date(2026, 5, 4, 10, 30, 0, 0, time.UTC) },
+	}
+	sink, err := NewSink("s3://deploy-config/irr/web overrides.yaml", opts)
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(context.Background(), []byte("image: {}\n")))
+
+	assert.Equal(t, "/deploy-config/irr/web%20overrides.yaml", path, "custom endpoints use path-style requests")
+	assert.Equal(t, "image: {}\n", body)
+	assert.Equal(t, "session", token)
+	assert.Equal(t, sha256Hex([]byte("image: {}\n")), payloadHash)
+	assert.Regexp(t, `^AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20260504/eu-west-1/s3/aws4_request, `+
+		`SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date;x-amz-security-token, Signature=[0-9a-f]{64}$`, authorization)
+
+	delete(env, EnvAWSSecretAccessKey)
+	require.ErrorIs(t, sink.Write(context.Background(), nil), ErrMissingS3Credentials)
+}
+
+func TestS3SinkObjectURL(t *testing.T) {
+	sink := &S3Sink{Bucket: "deploy-config", Key: "irr/web+1.yaml", opts: Options{Getenv: func(string) string { return "" }}}
+	objectURL, err := sink.objectURL("us-east-1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://deploy-config.s3.us-east-1.amazonaws.com/irr/web%2B1.yaml", objectURL)
+}
@@ -0,0 +1,149 @@
+// Package output publishes generated overrides to destinations other than local files: S3
+// objects, HTTP endpoints, keys of Kubernetes ConfigMaps and OCI artifacts in registries,
+// selected by --output-uri.
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Supported destination URI schemes.
+const (
+	// SchemeS3 publishes to an S3 object: s3://bucket/key
+	SchemeS3 = "s3"
+	// SchemeHTTP and SchemeHTTPS publish with an HTTP PUT to the URI
+	SchemeHTTP  = "http"
+	SchemeHTTPS = "https"
+	// SchemeK8s publishes to a key of a ConfigMap: k8s://namespace/configmap-name/key
+	SchemeK8s = "k8s"
+	// SchemeOCI pushes an OCI artifact to a registry: oci://registry/repository:tag
+	SchemeOCI = "oci"
+)
+
+// DefaultContentType is the content type sent when Options.ContentType is empty.
+const DefaultContentType = "application/yaml"
+
+// AgeContentType is the content type of overrides encrypted with age, which are no longer YAML.
+const AgeContentType = "application/vnd.age"
+
+// ErrInvalidURI is returned for destination URIs that are malformed or use an unsupported scheme.
+var ErrInvalidURI = errors.New("invalid output URI")
+
+var (
+	// configMapKeyPattern is the set of keys Kubernetes accepts in ConfigMap data
+	configMapKeyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+	// ociRepositoryPattern is the set of repository names registries accept
+	ociRepositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	// ociTagPattern is the set of tags registries accept
+	ociTagPattern = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9._-]{0,127}$`)
+)
+
+// Sink publishes the generated overrides to a destination.
+type Sink interface {
+	// Write publishes data, replacing what the destination held before.
+	Write(ctx context.Context, data []byte) error
+	// String returns the destination for messages, without credentials.
+	String() string
+}
+
+// ConfigMapWriter stores data under a key of a ConfigMap, creating the ConfigMap if needed.
+type ConfigMapWriter interface {
+	PutConfigMapData(ctx context.Context, namespace, name, key string, data []byte) error
+}
+
+// Options configures the sinks created by NewSink. The zero value is usable, except that
+// ConfigMap sinks need NewConfigMapWriter and OCI sinks need NewOCIPusher.
+type Options struct {
+	// ContentType is sent with HTTP and S3 uploads (default DefaultContentType)
+	ContentType string
+	// HTTPClient sends HTTP and S3 requests (default http.DefaultClient)
+	HTTPClient *http.Client
+	// NewConfigMapWriter creates the ConfigMap writer when a k8s:// sink is written to
+	NewConfigMapWriter func() (ConfigMapWriter, error)
+	// NewOCIPusher creates the registry client when an oci:// sink is written to
+	NewOCIPusher func() (OCIPusher, error)
+	// Annotations are added to the manifest of artifacts pushed by oci:// sinks
+	Annotations map[string]string
+	// Getenv reads the S3 region, endpoint and credentials (default os.Getenv)
+	Getenv func(key string) string
+	// Now is the signing time of S3 requests and the creation time of OCI artifacts (default time.Now)
+	Now func() time.Time
+}
+
+// NewSink returns the sink for the destination uri.
+func NewSink(uri string, opts Options) (Sink, error) {
+	opts = opts.withDefaults()
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrInvalidURI, uri, err)
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case SchemeHTTP, SchemeHTTPS:
+		if parsed.Host == "" {
+			return nil, fmt.Errorf("%w %q: missing host", ErrInvalidURI, uri)
+		}
+		return &HTTPSink{URL: parsed, opts: opts}, nil
+	case SchemeS3:
+		key := strings.TrimPrefix(parsed.Path, "/")
+		if parsed.Host == "" || key == "" {
+			return nil, fmt.Errorf("%w %q: expected s3://bucket/key", ErrInvalidURI, uri)
+		}
+		return &S3Sink{Bucket: parsed.Host, Key: key, opts: opts}, nil
+	case SchemeK8s:
+		parts := strings.Split(strings.TrimPrefix(parsed.Path, "/"), "/")
+		if parsed.Host == "" || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%w %q: expected k8s://namespace/configmap-name/key", ErrInvalidURI, uri)
+		}
+		if !configMapKeyPattern.MatchString(parts[1]) {
+			return nil, fmt.Errorf("%w %q: ConfigMap key %q may only contain letters, digits, '-', '_' and '.'", ErrInvalidURI, uri, parts[1])
+		}
+		return &ConfigMapSink{Namespace: parsed.Host, Name: parts[0], Key: parts[1], opts: opts}, nil
+	case SchemeOCI:
+		return newOCISink(uri, parsed, opts)
+	case "":
+		return nil, fmt.Errorf("%w %q: missing scheme (s3, http, https, k8s or oci)", ErrInvalidURI, uri)
+	default:
+		return nil, fmt.Errorf("%w %q: unsupported scheme %q (s3, http, https, k8s or oci)", ErrInvalidURI, uri, parsed.Scheme)
+	}
+}
+
+// newOCISink returns the sink for an oci://registry/repository:tag URI.
+func newOCISink(uri string, parsed *url.URL, opts Options) (Sink, error) {
+	repository, tag, found := strings.Cut(strings.TrimPrefix(parsed.Path, "/"), ":")
+	if parsed.Host == "" || !found || repository == "" || tag == "" {
+		return nil, fmt.Errorf("%w %q: expected oci://registry/repository:tag", ErrInvalidURI, uri)
+	}
+	if !ociRepositoryPattern.MatchString(repository) {
+		return nil, fmt.Errorf("%w %q: invalid repository %q (lowercase letters, digits and separators)", ErrInvalidURI, uri, repository)
+	}
+	if !ociTagPattern.MatchString(tag) {
+		return nil, fmt.Errorf("%w %q: invalid tag %q", ErrInvalidURI, uri, tag)
+	}
+	return &OCISink{Host: parsed.Host, Repository: repository, Tag: tag, opts: opts}, nil
+}
+
+// withDefaults fills the unset options with their defaults.
+func (o Options) withDefaults() Options {
+	if o.ContentType == "" {
+		o.ContentType = DefaultContentType
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient = http.DefaultClient
+	}
+	if o.Getenv == nil {
+		o.Getenv = os.Getenv
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	return o
+}
@@ -0,0 +1,74 @@
+package override
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/analysis"
+	"gopkg.in/yaml.v3"
+)
+
+// AnnotateYAML adds head comments to the keys of a YAML overrides document. comments maps a
+// values path (e.g. "backend.cache" or "sidecars[0].image", with dots in keys escaped) to the
+// comment placed above the key or sequence item at that path; paths not present in the document
+// are ignored, and a comment is added below any comment already there. The document keeps its
+// indentation.
+func AnnotateYAML(data []byte, comments map[string]string) ([]byte, error) {
+	if len(comments) == 0 {
+		return data, nil
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse overrides YAML: %w", err)
+	}
+	root := documentRoot(&doc)
+	if root == nil || root.Kind != yaml.MappingNode {
+		return data, nil
+	}
+	annotateNode(root, "", comments)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(detectYAMLIndent(data))
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to encode annotated overrides YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode annotated overrides YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// annotateNode sets the comments of the keys of the mapping or the items of the sequence node,
+// found at path, and of the nodes nested below them.
+func annotateNode(node *yaml.Node, path string, comments map[string]string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyPath := analysis.JoinPath(path, key.Value)
+			addHeadComment(key, comments[keyPath])
+			annotateNode(value, keyPath, comments)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			addHeadComment(item, comments[itemPath])
+			annotateNode(item, itemPath, comments)
+		}
+	case yaml.DocumentNode, yaml.ScalarNode, yaml.AliasNode:
+		// Only keys and sequence items are annotated
+	}
+}
+
+// addHeadComment adds comment below the head comment of node, unless it is empty or already there.
+func addHeadComment(node *yaml.Node, comment string) {
+	switch {
+	case comment == "" || strings.Contains(node.HeadComment, comment):
+	case node.HeadComment == "":
+		node.HeadComment = comment
+	default:
+		node.HeadComment += "\n" + comment
+	}
+}
@@ -0,0 +1,86 @@
+package override
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotateYAML(t *testing.T) {
+	overrides := `backend:
+    cache:
+        image:
+            registry: harbor.local
+    image:
+        registry: harbor.local
+image:
+    registry: harbor.local
+`
+	annotated, err := AnnotateYAML([]byte(overrides), map[string]string{
+		"backend":       "Subchart backend",
+		"backend.cache": "Subchart redis (alias cache) of backend",
+		"db":            "Subchart postgresql (alias db)",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, `# Subchart backend
+backend:
+    # Subchart redis (alias cache) of backend
+    cache:
+        image:
+            registry: harbor.local
+    image:
+        registry: harbor.local
+image:
+    registry: harbor.local
+`, string(annotated))
+
+	unchanged, err := AnnotateYAML([]byte(overrides), nil)
+	require.NoError(t, err)
+	assert.Equal(t, overrides, string(unchanged))
+
+	_, err = AnnotateYAML([]byte("image: [\n"), map[string]string{"image": "x"})
+	assert.ErrorContains(t, err, "failed to parse overrides YAML")
+}
+
+func TestAnnotateYAML_PathsAndExistingComments(t *testing.T) {
+	overrides := `# Overrides for subchart agent
+agent:
+  podAnnotations:
+    vendor.io/sidecar:
+      image: harbor.local/dockerio/library/busybox:1.36
+sidecars:
+- image:
+    registry: harbor.local
+- image:
+    registry: harbor.local
+`
+	annotated, err := AnnotateYAML([]byte(overrides), map[string]string{
+		"agent": "source: docker.io/grafana/agent:v0.40",
+		`agent.podAnnotations.vendor\.io/sidecar.image`: "source: docker.io/library/busybox:1.36",
+		"sidecars[1]":       "second sidecar",
+		"sidecars[1].image": "source: quay.io/prometheus/node-exporter:v1.8.0",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, `# Overrides for subchart agent
+# source: docker.io/grafana/agent:v0.40
+agent:
+  podAnnotations:
+    vendor.io/sidecar:
+      # source: docker.io/library/busybox:1.36
+      image: harbor.local/dockerio/library/busybox:1.36
+sidecars:
+  - image:
+      registry: harbor.local
+  # second sidecar
+  - # source: quay.io/prometheus/node-exporter:v1.8.0
+    image:
+      registry: harbor.local
+`, string(annotated))
+
+	again, err := AnnotateYAML(annotated, map[string]string{"agent": "source: docker.io/grafana/agent:v0.40"})
+	require.NoError(t, err)
+	assert.Equal(t, string(annotated), string(again), "comments already present are not repeated")
+}
@@ -49,6 +49,10 @@ var (
 	ErrArrayIndexOutOfBounds = errors.New("array index out of bounds")
 	// ErrNonMapOrArrayTraversal is returned when trying to traverse through a non-map/non-array value.
 	ErrNonMapOrArrayTraversal = errors.New("cannot traverse through non-map or non-array")
+
+	// --- Errors for MergeIntoYAML ---
+	// ErrMergeTargetNotMapping is returned when the document being merged into is not a YAML mapping.
+	ErrMergeTargetNotMapping = errors.New("values document is not a YAML mapping")
 )
 
 // WrapPathParsing wraps ErrPathParsing with the given path part and error for context.
@@ -0,0 +1,156 @@
+package override
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/log"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultYAMLIndent is the indentation used when it cannot be detected from the existing document
+	defaultYAMLIndent = 2
+	// minYAMLIndent is the smallest indentation accepted by the YAML encoder
+	minYAMLIndent = 2
+)
+
+// MergeIntoYAML deep-merges the overrides YAML document into the existing values YAML document and
+// returns the merged document. It works on yaml.v3 nodes so that comments, key order, anchors and
+// scalar quoting in existing are preserved; only the values present in overrides are changed.
+//
+// Mappings are merged recursively, keys missing from existing are appended to their mapping, and any
+// other value (scalar, sequence or alias) is replaced, as Helm does when layering values files.
+// Blank lines are not preserved by the YAML encoder.
+func MergeIntoYAML(existing, overrides []byte) ([]byte, error) {
+	var overrideDoc yaml.Node
+	if err := yaml.Unmarshal(overrides, &overrideDoc); err != nil {
+		return nil, fmt.Errorf("failed to parse overrides YAML: %w", err)
+	}
+	overrideRoot := documentRoot(&overrideDoc)
+	if overrideRoot != nil && overrideRoot.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("overrides: %w", ErrMergeTargetNotMapping)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(existing, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse existing values YAML: %w", err)
+	}
+
+	root := documentRoot(&doc)
+	var buf bytes.Buffer
+	switch {
+	case root == nil:
+		// Empty (or comment-only) document: keep its text and append the overrides after it
+		if overrideRoot == nil {
+			return existing, nil
+		}
+		buf.Write(existing)
+		if len(existing) > 0 && !bytes.HasSuffix(existing, []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{overrideRoot}}
+	case root.Kind != yaml.MappingNode:
+		return nil, ErrMergeTargetNotMapping
+	case overrideRoot != nil:
+		mergeMappingNodes(root, overrideRoot, "")
+	}
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(detectYAMLIndent(existing))
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to encode merged values YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode merged values YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// documentRoot returns the top-level content node of a parsed document, or nil if it is empty.
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	return doc.Content[0]
+}
+
+// mergeMappingNodes merges the key/value pairs of src into dst in place.
+func mergeMappingNodes(dst, src *yaml.Node, path string) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		keyPath := key.Value
+		if path != "" {
+			keyPath = path + "." + key.Value
+		}
+
+		idx := mappingKeyIndex(dst, key.Value)
+		if idx < 0 {
+			log.Debug("Adding new key to values", "path", keyPath)
+			dst.Content = append(dst.Content, key, value)
+			continue
+		}
+
+		existingValue := dst.Content[idx+1]
+		if existingValue.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			mergeMappingNodes(existingValue, value, keyPath)
+			continue
+		}
+
+		log.Debug("Replacing value", "path", keyPath)
+		dst.Content[idx+1] = replacementNode(existingValue, value)
+	}
+}
+
+// mappingKeyIndex returns the index of key in the content of a mapping node, or -1 if absent.
+// Merge keys (<<) are not followed, so a key inherited from an alias gets an explicit entry.
+func mappingKeyIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// replacementNode returns value carrying over the comments of the node it replaces, and its
+// quoting style when both are scalars of the same type.
+func replacementNode(existing, value *yaml.Node) *yaml.Node {
+	if value.HeadComment == "" {
+		value.HeadComment = existing.HeadComment
+	}
+	if value.LineComment == "" {
+		value.LineComment = existing.LineComment
+	}
+	if value.FootComment == "" {
+		value.FootComment = existing.FootComment
+	}
+	if existing.Kind == yaml.ScalarNode && value.Kind == yaml.ScalarNode && existing.Tag == value.Tag {
+		value.Style = existing.Style
+	}
+	return value
+}
+
+// detectYAMLIndent returns the indentation of the first nested block line in data.
+func detectYAMLIndent(data []byte) int {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	parentIsMapping := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if parentIsMapping && indent > 0 && !strings.HasPrefix(trimmed, "- ") {
+			if indent < minYAMLIndent {
+				return minYAMLIndent
+			}
+			return indent
+		}
+		parentIsMapping = indent == 0 && strings.HasSuffix(trimmed, ":")
+	}
+	return defaultYAMLIndent
+}
@@ -0,0 +1,126 @@
+package override
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeIntoYAML(t *testing.T) {
+	existing := `# Values for my release
+replicaCount: 2 # scaled for staging
+
+# Main application image
+image:
+  repository: "nginx" # upstream image
+  tag: "1.25"
+  pullPolicy: IfNotPresent
+
+ingress:
+  enabled: true
+`
+	overrides := `image:
+  registry: harbor.local
+  repository: dockerhub/library/nginx
+  tag: "1.25"
+sidecar:
+  image:
+    repository: harbor.local/quay/sidecar
+`
+
+	merged, err := MergeIntoYAML([]byte(existing), []byte(overrides))
+	require.NoError(t, err)
+
+	expected := `# Values for my release
+replicaCount: 2 # scaled for staging
+# Main application image
+image:
+  repository: "dockerhub/library/nginx" # upstream image
+  tag: "1.25"
+  pullPolicy: IfNotPresent
+  registry: harbor.local
+ingress:
+  enabled: true
+sidecar:
+  image:
+    repository: harbor.local/quay/sidecar
+`
+	assert.Equal(t, expected, string(merged))
+}
+
+func TestMergeIntoYAMLValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  string
+		overrides string
+		want      map[string]interface{}
+	}{
+		{
+			name:      "scalar replaced by mapping",
+			existing:  "image: nginx:1.25\n",
+			overrides: "image:\n  repository: harbor.local/nginx\n",
+			want:      map[string]interface{}{"image": map[string]interface{}{"repository": "harbor.local/nginx"}},
+		},
+		{
+			name:      "sequence replaced",
+			existing:  "images:\n  - a\n  - b\n",
+			overrides: "images:\n  - c\n",
+			want:      map[string]interface{}{"images": []interface{}{"c"}},
+		},
+		{
+			name:      "alias value replaced without touching the anchor",
+			existing:  "base: &img\n  repository: nginx\ncopy: *img\n",
+			overrides: "copy:\n  repository: harbor.local/nginx\n",
+			want: map[string]interface{}{
+				"base": map[string]interface{}{"repository": "nginx"},
+				"copy": map[string]interface{}{"repository": "harbor.local/nginx"},
+			},
+		},
+		{
+			name:      "empty existing document",
+			existing:  "",
+			overrides: "image:\n  tag: v1\n",
+			want:      map[string]interface{}{"image": map[string]interface{}{"tag": "v1"}},
+		},
+		{
+			name:      "empty overrides",
+			existing:  "image:\n  tag: v1\n",
+			overrides: "",
+			want:      map[string]interface{}{"image": map[string]interface{}{"tag": "v1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := MergeIntoYAML([]byte(tt.existing), []byte(tt.overrides))
+			require.NoError(t, err)
+			var got map[string]interface{}
+			require.NoError(t, yaml.Unmarshal(merged, &got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMergeIntoYAMLCommentOnlyDocument(t *testing.T) {
+	merged, err := MergeIntoYAML([]byte("# Release values\n"), []byte("image:\n  tag: v1\n"))
+	require.NoError(t, err)
+	assert.Contains(t, string(merged), "# Release values")
+	assert.Contains(t, string(merged), "image:\n  tag: v1")
+}
+
+func TestMergeIntoYAMLErrors(t *testing.T) {
+	_, err := MergeIntoYAML([]byte("- a\n- b\n"), []byte("image:\n  tag: v1\n"))
+	require.ErrorIs(t, err, ErrMergeTargetNotMapping)
+
+	_, err = MergeIntoYAML([]byte("image: [unclosed\n"), []byte("image:\n  tag: v1\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse existing values YAML")
+}
+
+func TestDetectYAMLIndent(t *testing.T) {
+	assert.Equal(t, 2, detectYAMLIndent([]byte("a:\n  b: c\n")))
+	assert.Equal(t, 4, detectYAMLIndent([]byte("# comment\na:\n    b: c\n")))
+	assert.Equal(t, 2, detectYAMLIndent([]byte("a: b\n")))
+	assert.Equal(t, 2, detectYAMLIndent([]byte("list:\n- a\nmap:\n  b: c\n")))
+}
@@ -0,0 +1,120 @@
+package override
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MetadataKey is the top-level values key holding the relocation metadata embedded in overrides.
+const MetadataKey = "irr"
+
+// Relocation records how the overrides relocate one image.
+type Relocation struct {
+	// Path is the values path of the image
+	Path string `json:"path" yaml:"path"`
+	// Original is the image reference found in the chart
+	Original string `json:"original" yaml:"original"`
+	// Relocated is the image reference the overrides point to
+	Relocated string `json:"relocated" yaml:"relocated"`
+}
+
+// Exclusion records an image that was intentionally not relocated because a relocation exception
+// covers it.
+type Exclusion struct {
+	// Path is the values path of the image
+	Path string `json:"path" yaml:"path"`
+	// Image is the image reference found in the chart, which the overrides leave unchanged
+	Image string `json:"image" yaml:"image"`
+	// Exception is the exception that matched, e.g. image=registry.vendor.com/**
+	Exception string `json:"exception" yaml:"exception"`
+	// Reason is the reason given by the exception
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// Metadata describes how a set of overrides was generated, so that it can later be audited.
+type Metadata struct {
+	// Version is the irr version that generated the overrides
+	Version string `json:"version" yaml:"version"`
+	// GeneratedAt is the generation time in RFC 3339 format
+	GeneratedAt string `json:"generatedAt" yaml:"generatedAt"`
+	// ChartName and ChartVersion identify the chart the overrides are for
+	ChartName    string `json:"chartName,omitempty" yaml:"chartName,omitempty"`
+	ChartVersion string `json:"chartVersion,omitempty" yaml:"chartVersion,omitempty"`
+	// ConfigHash identifies the registry configuration the images were relocated with
+	ConfigHash string `json:"configHash,omitempty" yaml:"configHash,omitempty"`
+	// Images lists the relocated images
+	Images []Relocation `json:"images" yaml:"images"`
+	// Excluded lists the images left in place by relocation exceptions
+	Excluded []Exclusion `json:"excluded,omitempty" yaml:"excluded,omitempty"`
+}
+
+// Values returns the metadata as a values map, to be stored under MetadataKey.
+func (m *Metadata) Values() map[string]interface{} {
+	images := make([]interface{}, 0, len(m.Images))
+	for _, img := range m.Images {
+		images = append(images, map[string]interface{}{
+			"path":      img.Path,
+			"original":  img.Original,
+			"relocated": img.Relocated,
+		})
+	}
+	values := map[string]interface{}{
+		"version":     m.Version,
+		"generatedAt": m.GeneratedAt,
+		"images":      images,
+	}
+	if len(m.Excluded) > 0 {
+		excluded := make([]interface{}, 0, len(m.Excluded))
+		for _, exclusion := range m.Excluded {
+			entry := map[string]interface{}{
+				"path":      exclusion.Path,
+				"image":     exclusion.Image,
+				"exception": exclusion.Exception,
+			}
+			if exclusion.Reason != "" {
+				entry["reason"] = exclusion.Reason
+			}
+			excluded = append(excluded, entry)
+		}
+		values["excluded"] = excluded
+	}
+	for key, value := range map[string]string{"chartName": m.ChartName, "chartVersion": m.ChartVersion, "configHash": m.ConfigHash} {
+		if value != "" {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// Comment returns the metadata as YAML comment lines, to be placed at the top of the overrides.
+func (m *Metadata) Comment() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by irr %s at %s\n", m.Version, m.GeneratedAt)
+	if m.ChartName != "" {
+		fmt.Fprintf(&b, "# Chart: %s", m.ChartName)
+		if m.ChartVersion != "" {
+			fmt.Fprintf(&b, " %s", m.ChartVersion)
+		}
+		b.WriteString("\n")
+	}
+	if m.ConfigHash != "" {
+		fmt.Fprintf(&b, "# Config: %s\n", m.ConfigHash)
+	}
+	if len(m.Images) > 0 {
+		b.WriteString("# Relocated images:\n")
+	}
+	for _, img := range m.Images {
+		fmt.Fprintf(&b, "#   %s: %s -> %s\n", img.Path, img.Original, img.Relocated)
+	}
+	if len(m.Excluded) > 0 {
+		b.WriteString("# Excluded images (relocation exceptions):\n")
+	}
+	for _, exclusion := range m.Excluded {
+		fmt.Fprintf(&b, "#   %s: %s (%s)", exclusion.Path, exclusion.Image, exclusion.Exception)
+		if exclusion.Reason != "" {
+			fmt.Fprintf(&b, ": %s", exclusion.Reason)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package override
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadata(t *testing.T) {
+	metadata := &Metadata{
+		Version:      "0.2.0",
+		GeneratedAt:  "2026-05-04T10:30:00Z",
+		ChartName:    "web",
+		ChartVersion: "1.4.2",
+		ConfigHash:   "sha256:abc",
+		Images: []Relocation{
+			{Path: "image", Original: "docker.io/library/nginx:1.27", Relocated: "harbor.local/dockerio/library/nginx:1.27"},
+		},
+	}
+
+	assert.Equal(t, `# Generated by irr 0.2.0 at 2026-05-04T10:30:00Z
+# Chart: web 1.4.2
+# Config: sha256:abc
+# Relocated images:
+#   image: docker.io/library/nginx:1.27 -> harbor.local/dockerio/library/nginx:1.27
+`, metadata.Comment())
+
+	assert.Equal(t, map[string]interface{}{
+		"version":      "0.2.0",
+		"generatedAt":  "2026-05-04T10:30:00Z",
+		"chartName":    "web",
+		"chartVersion": "1.4.2",
+		"configHash":   "sha256:abc",
+		"images": []interface{}{map[string]interface{}{
+			"path":      "image",
+			"original":  "docker.io/library/nginx:1.27",
+			"relocated": "harbor.local/dockerio/library/nginx:1.27",
+		}},
+	}, metadata.Values())
+
+	minimal := &Metadata{Version: "0.2.0", GeneratedAt: "2026-05-04T10:30:00Z"}
+	assert.Equal(t, "# Generated by irr 0.2.0 at 2026-05-04T10:30:00Z\n", minimal.Comment())
+	assert.Equal(t, map[string]interface{}{
+		"version":     "0.2.0",
+		"generatedAt": "2026-05-04T10:30:00Z",
+		"images":      []interface{}{},
+	}, minimal.Values())
+}
+
+func TestMetadataExcluded(t *testing.T) {
+	metadata := &Metadata{
+		Version:     "0.2.0",
+		GeneratedAt: "2026-05-04T10:30:00Z",
+		Excluded: []Exclusion{
+			{Path: "enterprise.image", Image: "registry.vendor.com/agent:3.1", Exception: "image=registry.vendor.com/**", Reason: "licensed"},
+			{Path: "scanner.image", Image: "docker.io/vendor/scanner:2.0", Exception: "path=scanner.*"},
+		},
+	}
+
+	assert.Equal(t, `# Generated by irr 0.2.0 at 2026-05-04T10:30:00Z
+# Excluded images (relocation exceptions):
+#   enterprise.image: registry.vendor.com/agent:3.1 (image=registry.vendor.com/**): licensed
+#   scanner.image: docker.io/vendor/scanner:2.0 (path=scanner.*)
+`, metadata.Comment())
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"path":      "enterprise.image",
+			"image":     "registry.vendor.com/agent:3.1",
+			"exception": "image=registry.vendor.com/**",
+			"reason":    "licensed",
+		},
+		map[string]interface{}{
+			"path":      "scanner.image",
+			"image":     "docker.io/vendor/scanner:2.0",
+			"exception": "path=scanner.*",
+		},
+	}, metadata.Values()["excluded"])
+}
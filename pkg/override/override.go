@@ -4,6 +4,8 @@ package override
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/lucas-albers-lz4/irr/pkg/image"
@@ -31,9 +33,20 @@ type File struct {
 	ChartName      string                 `yaml:"-"` // Base name of the chart directory
 	Values         map[string]interface{} `yaml:"overrides"`
 	Unsupported    []UnsupportedStructure
-	ProcessedCount int     `yaml:"-"` // Number of images successfully processed
-	TotalCount     int     `yaml:"-"` // Total number of images detected
-	SuccessRate    float64 `yaml:"-"` // Percentage of images successfully processed
+	Warnings       []Warning    `yaml:"-"` // Non-fatal issues found while generating the overrides
+	ProcessedCount int          `yaml:"-"` // Number of images successfully processed
+	TotalCount     int          `yaml:"-"` // Total number of images detected
+	SuccessRate    float64      `yaml:"-"` // Percentage of images successfully processed
+	Relocations    []Relocation `yaml:"-"` // Original and relocated reference of each processed image
+	Excluded       []Exclusion  `yaml:"-"` // Images left in place by a relocation exception
+}
+
+// Warning is a non-fatal issue found while generating overrides, reported alongside them
+type Warning struct {
+	Code    string `json:"code" yaml:"code"`
+	Chart   string `json:"chart,omitempty" yaml:"chart,omitempty"`
+	Path    string `json:"path,omitempty" yaml:"path,omitempty"`
+	Message string `json:"message" yaml:"message"`
 }
 
 // UnsupportedStructure represents a structure that could not be processed
@@ -251,31 +264,25 @@ func flattenYAMLToHelmSet(prefix string, content []byte, sets *[]string) error {
 	return flattenValue(prefix, data, sets)
 }
 
-// flattenValue recursively processes values and converts them to --set format
+// flattenValue recursively processes values and converts them to --set format. Map keys are
+// visited in sorted order so that the same overrides always produce the same --set arguments.
 func flattenValue(prefix string, value interface{}, sets *[]string) error {
 	switch v := value.(type) {
 	case map[interface{}]interface{}:
+		keyed := make(map[string]interface{}, len(v))
 		for k, val := range v {
-			key := fmt.Sprintf("%v", k)
-			var newPrefix string
-			if prefix == "" {
-				newPrefix = key
-			} else {
-				newPrefix = prefix + "." + key
-			}
-			if err := flattenValue(newPrefix, val, sets); err != nil {
-				return err
-			}
+			keyed[fmt.Sprintf("%v", k)] = val
 		}
+		return flattenValue(prefix, keyed, sets)
 	case map[string]interface{}:
-		for k, val := range v {
+		for _, k := range slices.Sorted(maps.Keys(v)) {
 			var newPrefix string
 			if prefix == "" {
 				newPrefix = k
 			} else {
 				newPrefix = prefix + "." + k
 			}
-			if err := flattenValue(newPrefix, val, sets); err != nil {
+			if err := flattenValue(newPrefix, v[k], sets); err != nil {
 				return err
 			}
 		}
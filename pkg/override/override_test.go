@@ -4,6 +4,7 @@ package override
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"reflect"
 	"sort"
 	"strings"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/lucas-albers-lz4/irr/pkg/image"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
 )
 
@@ -1432,3 +1434,36 @@ func TestSetValueAtPath_Simple(_ *testing.T) {
 func Test_splitPathWithEscapes(_ *testing.T) {
 	// ... existing code ...
 }
+
+// TestGenerateYAMLOverrides_Deterministic checks that every output format lists keys in the same
+// order on every run, as recorded in the golden files under testdata.
+func TestGenerateYAMLOverrides_Deterministic(t *testing.T) {
+	overrides := map[string]interface{}{
+		"worker": map[string]interface{}{
+			"image": map[string]interface{}{"registry": "harbor.local", "repository": "dockerhub/library/busybox", "tag": "1.36"},
+		},
+		"api": map[string]interface{}{
+			"sidecars": []interface{}{
+				map[string]interface{}{"name": "proxy", "image": map[string]interface{}{"repository": "quay/envoyproxy/envoy", "registry": "harbor.local"}},
+			},
+			"image": map[string]interface{}{"tag": "1.25", "repository": "dockerhub/library/nginx", "registry": "harbor.local"},
+		},
+		"global": map[string]interface{}{"imageRegistry": "harbor.local"},
+	}
+
+	for format, golden := range map[string]string{
+		"values":   "testdata/ordering.yaml",
+		"json":     "testdata/ordering.json",
+		"helm-set": "testdata/ordering.set",
+	} {
+		t.Run(format, func(t *testing.T) {
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			for i := 0; i < 20; i++ {
+				result, err := GenerateYAMLOverrides(overrides, format)
+				require.NoError(t, err)
+				require.Equal(t, strings.TrimSpace(string(expected)), strings.TrimSpace(string(result)), "run %d", i)
+			}
+		})
+	}
+}
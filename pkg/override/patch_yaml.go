@@ -0,0 +1,153 @@
+package override
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/log"
+	"gopkg.in/yaml.v3"
+)
+
+// scalarEdit replaces the text of a scalar value in a YAML document
+type scalarEdit struct {
+	line, column int // 1-based position of the value, as reported by yaml.v3
+	old, text    string
+}
+
+// PatchYAML merges the overrides YAML document into the existing values YAML document like
+// MergeIntoYAML. When the overrides only change values that the document already sets as single
+// line scalars, their text is edited in place and everything else, including blank lines and
+// formatting, is kept byte for byte; otherwise the document is merged and re-encoded by
+// MergeIntoYAML.
+func PatchYAML(existing, overrides []byte) ([]byte, error) {
+	merged, err := MergeIntoYAML(existing, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc, overrideDoc yaml.Node
+	if yaml.Unmarshal(existing, &doc) != nil || yaml.Unmarshal(overrides, &overrideDoc) != nil {
+		return merged, nil
+	}
+	root, overrideRoot := documentRoot(&doc), documentRoot(&overrideDoc)
+	if root == nil || overrideRoot == nil || root.Kind != yaml.MappingNode {
+		return merged, nil
+	}
+	var edits []scalarEdit
+	if !collectScalarEdits(root, overrideRoot, &edits) {
+		log.Debug("Overrides add keys or replace non-scalar values; re-encoding the values document")
+		return merged, nil
+	}
+	patched, ok := applyScalarEdits(existing, edits)
+	if !ok || !sameYAMLValues(patched, merged) {
+		log.Debug("Values could not be edited in place; re-encoding the values document")
+		return merged, nil
+	}
+	return patched, nil
+}
+
+// collectScalarEdits adds the edits replacing the values of dst with those of src, both mapping
+// nodes, to edits. It reports false if src cannot be applied by editing scalars of dst.
+func collectScalarEdits(dst, src *yaml.Node, edits *[]scalarEdit) bool {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		idx := mappingKeyIndex(dst, key.Value)
+		if idx < 0 {
+			return false
+		}
+		existingValue := dst.Content[idx+1]
+		switch {
+		case value.Kind == yaml.MappingNode && existingValue.Kind == yaml.MappingNode:
+			if !collectScalarEdits(existingValue, value, edits) {
+				return false
+			}
+		case value.Kind == yaml.ScalarNode && existingValue.Kind == yaml.ScalarNode:
+			if existingValue.Value == value.Value && existingValue.Tag == value.Tag {
+				continue
+			}
+			edit, ok := scalarReplacement(existingValue, value)
+			if !ok {
+				return false
+			}
+			*edits = append(*edits, edit)
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// scalarReplacement returns the edit replacing the text of the scalar existing with value, keeping
+// the quoting of existing where possible.
+func scalarReplacement(existing, value *yaml.Node) (scalarEdit, bool) {
+	var oldText string
+	switch existing.Style {
+	case 0:
+		oldText = existing.Value
+	case yaml.DoubleQuotedStyle:
+		oldText = `"` + existing.Value + `"`
+	case yaml.SingleQuotedStyle:
+		oldText = "'" + existing.Value + "'"
+	default:
+		return scalarEdit{}, false
+	}
+	if oldText == "" || existing.Anchor != "" || strings.ContainsAny(oldText, "\n\\") {
+		return scalarEdit{}, false
+	}
+
+	newText := ""
+	switch {
+	case value.Tag == "!!str" && existing.Style == yaml.DoubleQuotedStyle && !strings.ContainsAny(value.Value, "\"\\\n"):
+		newText = `"` + value.Value + `"`
+	case value.Tag == "!!str" && existing.Style == yaml.SingleQuotedStyle && !strings.ContainsAny(value.Value, "'\n"):
+		newText = "'" + value.Value + "'"
+	default:
+		encoded, err := yaml.Marshal(value)
+		if err != nil {
+			return scalarEdit{}, false
+		}
+		newText = strings.TrimSuffix(string(encoded), "\n")
+		if strings.Contains(newText, "\n") {
+			return scalarEdit{}, false
+		}
+	}
+	return scalarEdit{line: existing.Line, column: existing.Column, old: oldText, text: newText}, true
+}
+
+// applyScalarEdits applies edits to the text of data. It reports false if an edit does not
+// match the text at its position.
+func applyScalarEdits(data []byte, edits []scalarEdit) ([]byte, bool) {
+	lines := strings.SplitAfter(string(data), "\n")
+	// Apply edits from the end of each line, so earlier columns stay valid
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].line != edits[j].line {
+			return edits[i].line < edits[j].line
+		}
+		return edits[i].column > edits[j].column
+	})
+	for _, edit := range edits {
+		if edit.line < 1 || edit.line > len(lines) {
+			return nil, false
+		}
+		line := []rune(lines[edit.line-1])
+		start, end := edit.column-1, edit.column-1+len([]rune(edit.old))
+		if start < 0 || end > len(line) || string(line[start:end]) != edit.old {
+			return nil, false
+		}
+		lines[edit.line-1] = string(line[:start]) + edit.text + string(line[end:])
+	}
+	return []byte(strings.Join(lines, "")), true
+}
+
+// sameYAMLValues reports whether two YAML documents hold the same values.
+func sameYAMLValues(a, b []byte) bool {
+	var aValues, bValues interface{}
+	if err := yaml.Unmarshal(a, &aValues); err != nil {
+		return false
+	}
+	if err := yaml.Unmarshal(b, &bValues); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(aValues, bValues)
+}
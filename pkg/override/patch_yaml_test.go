@@ -0,0 +1,67 @@
+package override
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchYAML(t *testing.T) {
+	existing := `# Values for my release
+replicaCount: 2
+
+# Main application image
+image:
+  registry: 'docker.io'
+  repository: "bitnami/nginx" # upstream image
+  tag: 1.25
+
+metrics:
+  image: {registry: docker.io, repository: bitnami/nginx-exporter}
+`
+
+	t.Run("existing scalars are edited in place", func(t *testing.T) {
+		patched, err := PatchYAML([]byte(existing), []byte(`image:
+  registry: harbor.local
+  repository: dockerio/bitnami/nginx
+  tag: "1.25"
+metrics:
+  image:
+    registry: harbor.local
+`))
+		require.NoError(t, err)
+		assert.Equal(t, `# Values for my release
+replicaCount: 2
+
+# Main application image
+image:
+  registry: 'harbor.local'
+  repository: "dockerio/bitnami/nginx" # upstream image
+  tag: "1.25"
+
+metrics:
+  image: {registry: harbor.local, repository: bitnami/nginx-exporter}
+`, string(patched))
+	})
+
+	t.Run("new keys fall back to merging", func(t *testing.T) {
+		overrides := []byte("image:\n  pullSecrets: [harbor]\n")
+		patched, err := PatchYAML([]byte(existing), overrides)
+		require.NoError(t, err)
+		merged, err := MergeIntoYAML([]byte(existing), overrides)
+		require.NoError(t, err)
+		assert.Equal(t, string(merged), string(patched))
+	})
+
+	t.Run("unchanged values leave the document as is", func(t *testing.T) {
+		patched, err := PatchYAML([]byte(existing), []byte("image:\n  registry: docker.io\n"))
+		require.NoError(t, err)
+		assert.Equal(t, existing, string(patched))
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		_, err := PatchYAML([]byte("image: [unclosed"), []byte("image: {}\n"))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,172 @@
+package override
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/log"
+)
+
+// Helm flags used by the arguments of ToSetArgs.
+const (
+	// SetFlag passes values that Helm types itself (booleans, integers and null)
+	SetFlag = "--set"
+	// SetStringFlag passes values as strings
+	SetStringFlag = "--set-string"
+)
+
+// Warning codes reported by ToSetArgs.
+const (
+	// WarningSetFlagsOmitted is reported for values that cannot be passed on the command line
+	WarningSetFlagsOmitted = "set-flags-omitted"
+	// WarningSetFlagsString is reported for values that are passed as strings but are not strings
+	WarningSetFlagsString = "set-flags-string"
+)
+
+// SetArg is a Helm --set or --set-string argument setting one or more values paths, such as
+// --set-string image.registry=harbor.example.com,image.repository=library/nginx.
+type SetArg struct {
+	Flag string
+	// Assignments are the escaped path=value pairs of the argument
+	Assignments []string
+}
+
+// Value returns the argument value: the assignments joined with commas.
+func (a SetArg) Value() string {
+	return strings.Join(a.Assignments, ",")
+}
+
+// String returns the flag and its value separated by a space, unquoted.
+func (a SetArg) String() string {
+	return a.Flag + " " + a.Value()
+}
+
+// ShellString returns the flag and its value quoted for POSIX shells.
+func (a SetArg) ShellString() string {
+	return a.Flag + " " + ShellQuote(a.Value())
+}
+
+// setLeaf is one values path to set, with the escaped path of the node holding it
+type setLeaf struct {
+	flag       string
+	parent     string
+	assignment string
+}
+
+// ToSetArgs converts override values into Helm arguments that set the same values. Strings are
+// passed with --set-string, values Helm types correctly with --set, and assignments below the same
+// node share one argument. Keys and values are escaped for Helm's --set parser. Values that cannot
+// be expressed on the command line (empty maps and lists, multi-line strings) are omitted, and floats are
+// passed as strings; each is reported as a warning.
+func ToSetArgs(values map[string]interface{}) ([]SetArg, []Warning) {
+	var leaves []setLeaf
+	var warnings []Warning
+	collectSetLeaves("", "", "", values, &leaves, &warnings)
+
+	var args []SetArg
+	lastParent := ""
+	for _, leaf := range leaves {
+		if n := len(args); n > 0 && args[n-1].Flag == leaf.flag && lastParent == leaf.parent {
+			args[n-1].Assignments = append(args[n-1].Assignments, leaf.assignment)
+			continue
+		}
+		args = append(args, SetArg{Flag: leaf.flag, Assignments: []string{leaf.assignment}})
+		lastParent = leaf.parent
+	}
+	for _, warning := range warnings {
+		log.Warn(warning.Message, "path", warning.Path)
+	}
+	return args, warnings
+}
+
+// collectSetLeaves appends the leaves of value, at the values path rawPath (escaped as setPath)
+// below the node at parent.
+func collectSetLeaves(rawPath, setPath, parent string, value interface{}, leaves *[]setLeaf, warnings *[]Warning) {
+	add := func(flag, text string) {
+		*leaves = append(*leaves, setLeaf{flag: flag, parent: parent, assignment: setPath + "=" + text})
+	}
+	warn := func(code, message string) {
+		*warnings = append(*warnings, Warning{Code: code, Path: rawPath, Message: message})
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 && rawPath != "" {
+			warn(WarningSetFlagsOmitted, "empty map cannot be set with --set; value omitted")
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			collectSetLeaves(joinSetPath(rawPath, key), joinSetPath(setPath, escapeSetKey(key)), setPath, v[key], leaves, warnings)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			warn(WarningSetFlagsOmitted, "empty list cannot be set with --set; value omitted")
+			return
+		}
+		for i, item := range v {
+			index := fmt.Sprintf("[%d]", i)
+			collectSetLeaves(rawPath+index, setPath+index, setPath, item, leaves, warnings)
+		}
+	case string:
+		if strings.ContainsAny(v, "\n\r") {
+			warn(WarningSetFlagsOmitted, "multi-line string cannot be set with --set; value omitted")
+			return
+		}
+		add(SetStringFlag, escapeSetValue(v))
+	case bool, int, int64, uint64:
+		add(SetFlag, fmt.Sprint(v))
+	case nil:
+		add(SetFlag, "null")
+	case float64:
+		warn(WarningSetFlagsString, fmt.Sprintf("number %v is passed as a string; --set does not type floats", v))
+		add(SetStringFlag, escapeSetValue(fmt.Sprint(v)))
+	default:
+		warn(WarningSetFlagsOmitted, fmt.Sprintf("value of type %T cannot be set with --set; value omitted", v))
+	}
+}
+
+// joinSetPath appends key to the values path prefix.
+func joinSetPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// escapeSetKey escapes the characters Helm's --set parser treats as separators in a key.
+func escapeSetKey(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		if strings.ContainsRune(`\=[,.`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// escapeSetValue escapes the characters Helm's --set parser treats specially in a value: commas,
+// backslashes, and a leading brace, which would start a list.
+func escapeSetValue(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `,`, `\,`).Replace(value)
+	if strings.HasPrefix(value, "{") {
+		value = `\` + value
+	}
+	return value
+}
+
+// ShellQuote quotes s for POSIX shells, leaving it unchanged when it has no special characters.
+func ShellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("@%+=:,./_-", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
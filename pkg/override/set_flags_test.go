@@ -0,0 +1,96 @@
+package override
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/strvals"
+)
+
+func TestToSetArgs(t *testing.T) {
+	values := map[string]interface{}{
+		"image": map[string]interface{}{
+			"registry":   "harbor.example.com",
+			"repository": "dockerhub/library/nginx",
+			"tag":        "1.25",
+		},
+		"global": map[string]interface{}{
+			"security": map[string]interface{}{"allowInsecureImages": true},
+		},
+		"sidecars": []interface{}{
+			map[string]interface{}{"name": "proxy", "image": "harbor.example.com/envoy:v1"},
+		},
+	}
+
+	args, warnings := ToSetArgs(values)
+
+	assert.Empty(t, warnings)
+	var lines []string
+	for _, arg := range args {
+		lines = append(lines, arg.String())
+	}
+	assert.Equal(t, []string{
+		"--set global.security.allowInsecureImages=true",
+		"--set-string image.registry=harbor.example.com,image.repository=dockerhub/library/nginx,image.tag=1.25",
+		"--set-string sidecars[0].image=harbor.example.com/envoy:v1,sidecars[0].name=proxy",
+	}, lines)
+}
+
+func TestToSetArgs_Escaping(t *testing.T) {
+	values := map[string]interface{}{
+		"annotations": map[string]interface{}{"example.com/mirror": "a,b"},
+		"list":        "{not,a,list}",
+		"path":        `C:\images`,
+		"removed":     nil,
+	}
+
+	args, warnings := ToSetArgs(values)
+	assert.Empty(t, warnings)
+
+	// The arguments must parse back to the original values with Helm's own parser
+	parsed := map[string]interface{}{}
+	for _, arg := range args {
+		if arg.Flag == SetStringFlag {
+			require.NoError(t, strvals.ParseIntoString(arg.Value(), parsed), arg.Value())
+		} else {
+			require.NoError(t, strvals.ParseInto(arg.Value(), parsed), arg.Value())
+		}
+	}
+	assert.Equal(t, values, parsed)
+}
+
+func TestToSetArgs_Warnings(t *testing.T) {
+	values := map[string]interface{}{
+		"config":  map[string]interface{}{},
+		"args":    []interface{}{},
+		"script":  "line1\nline2",
+		"ratio":   1.5,
+		"release": "ok",
+	}
+
+	args, warnings := ToSetArgs(values)
+
+	require.Len(t, args, 1)
+	assert.Equal(t, "--set-string ratio=1.5,release=ok", args[0].String())
+	codes := map[string]string{}
+	for _, w := range warnings {
+		codes[w.Path] = w.Code
+	}
+	assert.Equal(t, map[string]string{
+		"config": WarningSetFlagsOmitted,
+		"args":   WarningSetFlagsOmitted,
+		"script": WarningSetFlagsOmitted,
+		"ratio":  WarningSetFlagsString,
+	}, codes)
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "image.tag=1.25", ShellQuote("image.tag=1.25"))
+	assert.Equal(t, "''", ShellQuote(""))
+	assert.Equal(t, `'a\,b c'`, ShellQuote(`a\,b c`))
+	assert.Equal(t, `'it'\''s'`, ShellQuote("it's"))
+
+	arg := SetArg{Flag: SetStringFlag, Assignments: []string{"list=\\{a}"}}
+	assert.Equal(t, `--set-string 'list=\{a}'`, arg.ShellString())
+}
@@ -0,0 +1,65 @@
+package override
+
+import "sort"
+
+// SplitBySubchart separates the overrides of top-level subcharts from those of the parent chart.
+// A top-level key is treated as a subchart when it matches the alias of a dependency, or its name if
+// the dependency has no alias. Each subchart's overrides stay nested under that key, so every part
+// can be passed to Helm with -f on its own. The returned aliases are sorted; keys that match no
+// dependency (including "global") remain in the parent map.
+func SplitBySubchart(values map[string]interface{}, deps []ChartDependency) (parent map[string]interface{}, subcharts map[string]map[string]interface{}, aliases []string) {
+	subchartKeys := make(map[string]bool, len(deps))
+	for _, dep := range deps {
+		key := dep.Alias
+		if key == "" {
+			key = dep.Name
+		}
+		if key != "" {
+			subchartKeys[key] = true
+		}
+	}
+
+	parent = make(map[string]interface{})
+	subcharts = make(map[string]map[string]interface{})
+	for key, value := range values {
+		if !subchartKeys[key] {
+			parent[key] = value
+			continue
+		}
+		subcharts[key] = map[string]interface{}{key: value}
+		aliases = append(aliases, key)
+	}
+	sort.Strings(aliases)
+	return parent, subcharts, aliases
+}
+
+// SplitVendoredOverrides separates the overrides of a subchart, as found under its alias, into
+// those that must stay in the parent chart's values and those that can become the subchart's own
+// defaults. parentValues are the values the parent chart sets under the alias. Since the parent's
+// values take precedence over the subchart's, an override stays in the parent when the parent sets
+// its path, or a non-map value above it; everything else moves to the subchart.
+func SplitVendoredOverrides(overrides, parentValues map[string]interface{}) (parent, subchart map[string]interface{}) {
+	parent = make(map[string]interface{})
+	subchart = make(map[string]interface{})
+	for key, value := range overrides {
+		parentValue, set := parentValues[key]
+		if !set {
+			subchart[key] = value
+			continue
+		}
+		valueMap, valueIsMap := value.(map[string]interface{})
+		parentMap, parentIsMap := parentValue.(map[string]interface{})
+		if !valueIsMap || !parentIsMap {
+			parent[key] = value
+			continue
+		}
+		nestedParent, nestedSubchart := SplitVendoredOverrides(valueMap, parentMap)
+		if len(nestedParent) > 0 {
+			parent[key] = nestedParent
+		}
+		if len(nestedSubchart) > 0 {
+			subchart[key] = nestedSubchart
+		}
+	}
+	return parent, subchart
+}
@@ -0,0 +1,73 @@
+package override
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitBySubchart(t *testing.T) {
+	values := map[string]interface{}{
+		"image":  map[string]interface{}{"registry": "harbor.local"},
+		"global": map[string]interface{}{"imageRegistry": "harbor.local"},
+		"db":     map[string]interface{}{"image": map[string]interface{}{"repository": "bitnami/postgresql"}},
+		"redis":  map[string]interface{}{"image": map[string]interface{}{"repository": "bitnami/redis"}},
+	}
+	deps := []ChartDependency{
+		{Name: "postgresql", Alias: "db"},
+		{Name: "redis"},
+		{Name: "mongodb"}, // No overrides for this one
+	}
+
+	parent, subcharts, aliases := SplitBySubchart(values, deps)
+
+	assert.Equal(t, []string{"db", "redis"}, aliases)
+	assert.Equal(t, map[string]interface{}{
+		"image":  values["image"],
+		"global": values["global"],
+	}, parent)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"db":    {"db": values["db"]},
+		"redis": {"redis": values["redis"]},
+	}, subcharts)
+}
+
+func TestSplitBySubchartNoDependencies(t *testing.T) {
+	values := map[string]interface{}{"postgresql": map[string]interface{}{"image": "x"}}
+
+	parent, subcharts, aliases := SplitBySubchart(values, nil)
+
+	assert.Equal(t, values, parent)
+	assert.Empty(t, subcharts)
+	assert.Empty(t, aliases)
+}
+
+func TestSplitVendoredOverrides(t *testing.T) {
+	overrides := map[string]interface{}{
+		"image": map[string]interface{}{"registry": "harbor.local", "repository": "dockerio/bitnami/redis"},
+		"metrics": map[string]interface{}{
+			"image": map[string]interface{}{"registry": "harbor.local", "repository": "dockerio/bitnami/redis-exporter"},
+		},
+		"sidecar": "harbor.local/dockerio/busybox:1.36",
+	}
+	parentValues := map[string]interface{}{
+		"image":   map[string]interface{}{"repository": "bitnami/redis", "pullPolicy": "Always"},
+		"metrics": map[string]interface{}{"enabled": true},
+		"sidecar": "busybox:1.36",
+	}
+
+	parent, subchart := SplitVendoredOverrides(overrides, parentValues)
+
+	assert.Equal(t, map[string]interface{}{
+		"image":   map[string]interface{}{"repository": "dockerio/bitnami/redis"},
+		"sidecar": "harbor.local/dockerio/busybox:1.36",
+	}, parent, "paths the parent sets stay in the parent")
+	assert.Equal(t, map[string]interface{}{
+		"image":   map[string]interface{}{"registry": "harbor.local"},
+		"metrics": overrides["metrics"],
+	}, subchart)
+
+	parent, subchart = SplitVendoredOverrides(overrides, nil)
+	assert.Empty(t, parent)
+	assert.Equal(t, overrides, subchart)
+}
@@ -0,0 +1,160 @@
+package override
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/log"
+	"gopkg.in/yaml.v3"
+)
+
+// imageMapFields are the keys of an image map that irr writes
+var imageMapFields = map[string]bool{"registry": true, "repository": true, "tag": true, "digest": true}
+
+// UpdateReport lists, as dotted key paths, what UpdateYAML did to an existing overrides file.
+type UpdateReport struct {
+	// Regenerated are the keys written from the regenerated overrides
+	Regenerated []string
+	// Preserved are the keys of the existing file that are not image overrides and were kept
+	Preserved []string
+	// Removed are the image overrides of the existing file that were not regenerated
+	Removed []string
+}
+
+// UpdateYAML regenerates an overrides file: the regenerated YAML overrides replace the image
+// overrides of the existing document, while the keys the user added by hand are kept with their
+// comments and order.
+//
+// A key of existing that is not regenerated is an image override, and is removed, when it is a
+// registry, repository, tag or digest of an image map (a mapping with a repository), an image
+// string (a key named image or ending in Image), or an imageRegistry. Mappings left empty by the
+// removal are removed as well. Any other key, including sequences, is preserved.
+func UpdateYAML(existing, regenerated []byte) ([]byte, *UpdateReport, error) {
+	var srcDoc yaml.Node
+	if err := yaml.Unmarshal(regenerated, &srcDoc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse overrides YAML: %w", err)
+	}
+	src := documentRoot(&srcDoc)
+	if src != nil && src.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("overrides: %w", ErrMergeTargetNotMapping)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(existing, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse existing overrides YAML: %w", err)
+	}
+	report := &UpdateReport{}
+	root := documentRoot(&doc)
+	switch {
+	case root == nil:
+		if src == nil {
+			return regenerated, report, nil
+		}
+		report.Regenerated = leafPaths(src, "")
+		merged, err := MergeIntoYAML(existing, regenerated)
+		return merged, report, err
+	case root.Kind != yaml.MappingNode:
+		return nil, nil, ErrMergeTargetNotMapping
+	}
+	updateMappingNode(root, src, "", report)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(detectYAMLIndent(existing))
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode updated overrides YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode updated overrides YAML: %w", err)
+	}
+	return buf.Bytes(), report, nil
+}
+
+// updateMappingNode updates dst in place with the regenerated mapping src, which may be nil when
+// nothing under dst was regenerated.
+func updateMappingNode(dst, src *yaml.Node, path string, report *UpdateReport) {
+	isImageMap := mappingKeyIndex(dst, "repository") >= 0
+	content := make([]*yaml.Node, 0, len(dst.Content))
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		key, value := dst.Content[i], dst.Content[i+1]
+		keyPath := joinKeyPath(path, key.Value)
+
+		if src != nil {
+			if idx := mappingKeyIndex(src, key.Value); idx >= 0 {
+				regenerated := src.Content[idx+1]
+				if value.Kind == yaml.MappingNode && regenerated.Kind == yaml.MappingNode {
+					updateMappingNode(value, regenerated, keyPath, report)
+				} else {
+					report.Regenerated = append(report.Regenerated, leafPaths(regenerated, keyPath)...)
+					value = replacementNode(value, regenerated)
+				}
+				content = append(content, key, value)
+				continue
+			}
+		}
+
+		switch {
+		case isImageOverride(key.Value, value, isImageMap):
+			log.Debug("Removing image override that was not regenerated", "path", keyPath)
+			report.Removed = append(report.Removed, keyPath)
+			continue
+		case value.Kind == yaml.MappingNode && len(value.Content) > 0:
+			removed := len(report.Removed)
+			updateMappingNode(value, nil, keyPath, report)
+			if len(value.Content) == 0 && len(report.Removed) > removed {
+				continue
+			}
+		default:
+			report.Preserved = append(report.Preserved, keyPath)
+		}
+		content = append(content, key, value)
+	}
+
+	if src != nil {
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key, value := src.Content[i], src.Content[i+1]
+			if mappingKeyIndex(dst, key.Value) >= 0 {
+				continue
+			}
+			report.Regenerated = append(report.Regenerated, leafPaths(value, joinKeyPath(path, key.Value))...)
+			content = append(content, key, value)
+		}
+	}
+	dst.Content = content
+}
+
+// isImageOverride reports whether the value at key is an override irr writes for an image
+func isImageOverride(key string, value *yaml.Node, inImageMap bool) bool {
+	if value.Kind != yaml.ScalarNode {
+		return false
+	}
+	switch {
+	case inImageMap && imageMapFields[key]:
+		return true
+	case key == "imageRegistry", key == "image", strings.HasSuffix(key, "Image"):
+		return value.Value != ""
+	default:
+		return false
+	}
+}
+
+// leafPaths returns the dotted paths of the non-mapping values under node
+func leafPaths(node *yaml.Node, path string) []string {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return []string{path}
+	}
+	var paths []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		paths = append(paths, leafPaths(node.Content[i+1], joinKeyPath(path, node.Content[i].Value))...)
+	}
+	return paths
+}
+
+// joinKeyPath appends key to a dotted key path
+func joinKeyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
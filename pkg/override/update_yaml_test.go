@@ -0,0 +1,99 @@
+package override
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateYAML(t *testing.T) {
+	existing := `# Generated by irr, then edited
+image:
+  registry: harbor.local
+  repository: dockerhub/library/nginx
+  tag: "1.25"
+  pullPolicy: Always # pinned by ops
+sidecar:
+  image:
+    registry: harbor.local
+    repository: quay/sidecar
+    tag: v1
+global:
+  imageRegistry: harbor.local
+  storageClass: fast
+initImage: harbor.local/dockerhub/library/busybox:1.36
+replicaCount: 3
+tolerations:
+  - key: dedicated
+    operator: Exists
+`
+	regenerated := `image:
+  registry: harbor.local
+  repository: dockerhub/library/nginx
+  tag: "1.27"
+global:
+  imageRegistry: harbor.local
+metrics:
+  image:
+    registry: harbor.local
+    repository: dockerhub/prom/exporter
+    tag: v0.15
+`
+
+	updated, report, err := UpdateYAML([]byte(existing), []byte(regenerated))
+	require.NoError(t, err)
+
+	assert.Equal(t, `# Generated by irr, then edited
+image:
+  registry: harbor.local
+  repository: dockerhub/library/nginx
+  tag: "1.27"
+  pullPolicy: Always # pinned by ops
+global:
+  imageRegistry: harbor.local
+  storageClass: fast
+replicaCount: 3
+tolerations:
+  - key: dedicated
+    operator: Exists
+metrics:
+  image:
+    registry: harbor.local
+    repository: dockerhub/prom/exporter
+    tag: v0.15
+`, string(updated))
+	assert.Equal(t, []string{
+		"image.registry", "image.repository", "image.tag", "global.imageRegistry",
+		"metrics.image.registry", "metrics.image.repository", "metrics.image.tag",
+	}, report.Regenerated)
+	assert.Equal(t, []string{"image.pullPolicy", "global.storageClass", "replicaCount", "tolerations"}, report.Preserved)
+	assert.Equal(t, []string{"sidecar.image.registry", "sidecar.image.repository", "sidecar.image.tag", "initImage"}, report.Removed)
+}
+
+func TestUpdateYAMLIsIdempotent(t *testing.T) {
+	regenerated := "image:\n  registry: harbor.local\n  repository: dockerhub/library/nginx\n  tag: \"1.27\"\n"
+	existing := regenerated + "podAnnotations:\n  team: web\n"
+
+	once, _, err := UpdateYAML([]byte(existing), []byte(regenerated))
+	require.NoError(t, err)
+	twice, report, err := UpdateYAML(once, []byte(regenerated))
+	require.NoError(t, err)
+	assert.Equal(t, string(once), string(twice))
+	assert.Equal(t, existing, string(twice))
+	assert.Equal(t, []string{"podAnnotations.team"}, report.Preserved)
+	assert.Empty(t, report.Removed)
+}
+
+func TestUpdateYAMLEmptyAndInvalid(t *testing.T) {
+	updated, report, err := UpdateYAML([]byte("# nothing yet\n"), []byte("image:\n  tag: v1\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "# nothing yet\nimage:\n  tag: v1\n", string(updated))
+	assert.Equal(t, []string{"image.tag"}, report.Regenerated)
+
+	_, _, err = UpdateYAML([]byte("- a\n- b\n"), []byte("image:\n  tag: v1\n"))
+	assert.ErrorIs(t, err, ErrMergeTargetNotMapping)
+
+	_, _, err = UpdateYAML([]byte("a: [\n"), []byte("image:\n  tag: v1\n"))
+	assert.ErrorContains(t, err, "failed to parse existing overrides YAML")
+}
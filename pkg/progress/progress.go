@@ -0,0 +1,201 @@
+// Package progress shows the progress of long operations, such as inspecting every release of a
+// cluster or every chart below a directory, as a single status line on a terminal: a bar with
+// counts and the estimated time remaining when the number of items is known, or a spinner with a
+// count when it is not.
+//
+// Progress is only drawn when the output is an interactive terminal. It is suppressed when
+// disabled with SetEnabled(false) (irr's --no-progress), when CI is set and when TERM=dumb, so
+// piped output and CI logs never contain it. While a bar is shown on the terminal that log records
+// are written to, the bar is cleared before each record and redrawn below it.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"golang.org/x/term"
+)
+
+const (
+	// redrawInterval is how often the spinner and the estimated time remaining are updated
+	redrawInterval = 100 * time.Millisecond
+	// barWidth is the number of cells of the bar
+	barWidth = 30
+	// clearLine returns the cursor to the start of the line and erases the line
+	clearLine = "\r\x1b[K"
+)
+
+// spinnerFrames are drawn in turn to show that work is ongoing
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// disabled is set by SetEnabled(false)
+var disabled atomic.Bool
+
+// SetEnabled enables or disables progress output. It is enabled by default.
+func SetEnabled(enabled bool) {
+	disabled.Store(!enabled)
+}
+
+// Interactive reports whether progress is shown on w: progress must be enabled, w must be a
+// terminal, and the environment must not be a CI job (CI set) or a dumb terminal (TERM=dumb).
+func Interactive(w io.Writer) bool {
+	if disabled.Load() || os.Getenv("CI") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	file, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd())) //nolint:gosec // file descriptors fit in an int
+}
+
+// Bar is the progress line of one operation. All methods are safe for concurrent use. A Bar
+// started on a writer that is not interactive does nothing, so callers need not check.
+type Bar struct {
+	mu      sync.Mutex
+	out     io.Writer // nil if the bar is not shown
+	label   string
+	total   int
+	done    int
+	start   time.Time
+	now     func() time.Time
+	frame   int
+	drawn   bool
+	stop    chan struct{}
+	stopped chan struct{}
+	// restoreLog restores the log output replaced while the bar is shown
+	restoreLog func()
+}
+
+// Start shows a progress line for label on w until Finish is called. total is the number of items
+// the operation processes, or 0 if it is not known.
+func Start(w io.Writer, label string, total int) *Bar {
+	if !Interactive(w) {
+		return &Bar{label: label, total: total}
+	}
+	bar := newBar(w, label, total, time.Now)
+	if log.Output() == w {
+		bar.restoreLog = log.SetOutput(&barWriter{bar: bar, w: w})
+	}
+	go bar.run()
+	return bar
+}
+
+// newBar returns a bar drawn on w, without starting to redraw it periodically.
+func newBar(w io.Writer, label string, total int, now func() time.Time) *Bar {
+	return &Bar{
+		out:     w,
+		label:   label,
+		total:   total,
+		start:   now(),
+		now:     now,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Add records that n more items are done.
+func (b *Bar) Add(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done += n
+	if b.out != nil {
+		b.draw()
+	}
+}
+
+// Finish removes the progress line. It must be called once the operation ends.
+func (b *Bar) Finish() {
+	if b.out == nil {
+		return
+	}
+	close(b.stop)
+	<-b.stopped
+	if b.restoreLog != nil {
+		b.restoreLog()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+	b.out = nil
+}
+
+// run redraws the bar until Finish is called
+func (b *Bar) run() {
+	defer close(b.stopped)
+	ticker := time.NewTicker(redrawInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			b.mu.Lock()
+			b.frame++
+			b.draw()
+			b.mu.Unlock()
+		}
+	}
+}
+
+// draw writes the progress line over the current one. b.mu must be held.
+func (b *Bar) draw() {
+	if _, err := io.WriteString(b.out, clearLine+b.line()); err == nil {
+		b.drawn = true
+	}
+}
+
+// clear erases the progress line if it is drawn. b.mu must be held.
+func (b *Bar) clear() {
+	if !b.drawn {
+		return
+	}
+	if _, err := io.WriteString(b.out, clearLine); err == nil {
+		b.drawn = false
+	}
+}
+
+// line renders the progress line, e.g. "⠹ Inspecting releases [=========>    ] 12/40 30% ETA 1m20s",
+// or "⠹ Scanning charts 12" when the total is not known. b.mu must be held.
+func (b *Bar) line() string {
+	spinner := spinnerFrames[b.frame%len(spinnerFrames)]
+	if b.total <= 0 {
+		return fmt.Sprintf("%s %s %d", spinner, b.label, b.done)
+	}
+
+	done := min(b.done, b.total)
+	filled := done * barWidth / b.total
+	cells := strings.Repeat("=", filled)
+	if filled < barWidth {
+		cells += ">" + strings.Repeat(" ", barWidth-filled-1)
+	}
+	line := fmt.Sprintf("%s %s [%s] %d/%d %d%%", spinner, b.label, cells, done, b.total, done*100/b.total)
+	if done > 0 && done < b.total {
+		elapsed := b.now().Sub(b.start)
+		remaining := elapsed / time.Duration(done) * time.Duration(b.total-done)
+		line += " ETA " + remaining.Round(time.Second).String()
+	}
+	return line
+}
+
+// barWriter writes log records to w, clearing the progress line before each and redrawing it after
+type barWriter struct {
+	bar *Bar
+	w   io.Writer
+}
+
+// Write implements io.Writer.
+func (w *barWriter) Write(p []byte) (int, error) {
+	w.bar.mu.Lock()
+	defer w.bar.mu.Unlock()
+	if w.bar.out == nil {
+		return w.w.Write(p)
+	}
+	w.bar.clear()
+	n, err := w.w.Write(p)
+	w.bar.draw()
+	return n, err
+}
@@ -0,0 +1,74 @@
+package progress
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBarLine(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	bar := newBar(&bytes.Buffer{}, "Inspecting releases", 40, func() time.Time { return now })
+
+	assert.Equal(t, "⠋ Inspecting releases [>"+strings.Repeat(" ", 29)+"] 0/40 0%", bar.line())
+
+	bar.done = 12
+	now = now.Add(30 * time.Second)
+	bar.frame = 2
+	assert.Equal(t, "⠹ Inspecting releases [=========>"+strings.Repeat(" ", 20)+"] 12/40 30% ETA 1m10s", bar.line())
+
+	bar.done = 40
+	assert.Equal(t, "⠹ Inspecting releases ["+strings.Repeat("=", 30)+"] 40/40 100%", bar.line())
+
+	spinner := newBar(&bytes.Buffer{}, "Scanning charts", 0, time.Now)
+	spinner.done = 7
+	assert.Equal(t, "⠋ Scanning charts 7", spinner.line())
+}
+
+func TestBarDrawing(t *testing.T) {
+	out := &bytes.Buffer{}
+	bar := newBar(out, "Processing charts", 2, time.Now)
+
+	bar.Add(1)
+	assert.True(t, strings.HasPrefix(out.String(), clearLine+"⠋ Processing charts ["), out.String())
+	assert.Contains(t, out.String(), "1/2 50%")
+
+	out.Reset()
+	logs := &barWriter{bar: bar, w: out}
+	_, err := logs.Write([]byte("{\"msg\":\"Chart processed\"}\n"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out.String(), clearLine+"{\"msg\":\"Chart processed\"}\n"+clearLine+"⠋ Processing charts"),
+		"the bar is cleared before a log record and redrawn after it: %q", out.String())
+
+	go bar.run()
+	out.Reset()
+	bar.Finish()
+	assert.True(t, strings.HasSuffix(out.String(), clearLine), "Finish clears the line")
+
+	out.Reset()
+	bar.Add(1)
+	_, err = logs.Write([]byte("after\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "after\n", out.String(), "nothing is drawn after Finish")
+}
+
+func TestInteractive(t *testing.T) {
+	assert.False(t, Interactive(&bytes.Buffer{}), "only terminals are interactive")
+
+	file, err := os.CreateTemp(t.TempDir(), "progress")
+	require.NoError(t, err)
+	defer file.Close()
+	assert.False(t, Interactive(file))
+
+	bar := Start(file, "Inspecting releases", 3)
+	bar.Add(3)
+	bar.Finish()
+	content, err := os.ReadFile(file.Name())
+	require.NoError(t, err)
+	assert.Empty(t, content, "nothing is written to a writer that is not interactive")
+}
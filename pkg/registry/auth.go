@@ -0,0 +1,285 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+)
+
+// credentialRefreshMargin is how long before they expire cached credentials are fetched again, so
+// a token does not expire between being resolved and being sent.
+const credentialRefreshMargin = 5 * time.Minute
+
+// Lifetimes of the tokens issued by the cloud registry providers.
+const (
+	// ecrTokenLifetime is the validity of an ECR authorization token
+	ecrTokenLifetime = 12 * time.Hour
+	// gcpTokenLifetime is the validity of a GCP access token
+	gcpTokenLifetime = time.Hour
+	// acrTokenLifetime is the validity of an ACR refresh token
+	acrTokenLifetime = 3 * time.Hour
+)
+
+// Usernames that go with the tokens of the cloud registry providers.
+const (
+	ecrUsername = "AWS"
+	gcpUsername = "oauth2accesstoken"
+	acrUsername = "00000000-0000-0000-0000-000000000000"
+)
+
+var (
+	// ecrHostPattern matches ECR registry hosts, capturing the region
+	ecrHostPattern = regexp.MustCompile(`^\d{12}\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+	// acrHostPattern matches ACR registry hosts, capturing the registry name
+	acrHostPattern = regexp.MustCompile(`^([a-z0-9]+)\.azurecr\.(?:io|cn|us)$`)
+)
+
+// runCredentialCommand runs a credential helper or cloud CLI with stdin as its input and returns
+// its standard output. It is a variable so tests can replace it.
+var runCredentialCommand = func(ctx context.Context, stdin, name string, args ...string) ([]byte, error) {
+	command := exec.CommandContext(ctx, name, args...) //nolint:gosec // helpers are named by the credential config or by irr
+	command.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	output, err := command.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%s failed: %w: %s", name, err, message)
+		}
+		return nil, fmt.Errorf("%s failed: %w", name, err)
+	}
+	return output, nil
+}
+
+// CredentialProvider issues registry credentials by exchanging the credentials of a cloud account
+// or of a credential store for a registry token.
+type CredentialProvider interface {
+	// Name identifies the provider in logs and errors
+	Name() string
+	// Matches reports whether the provider issues credentials for the registry host
+	Matches(host string) bool
+	// Credentials returns credentials for host and when they expire; a zero time never expires
+	Credentials(ctx context.Context, host string) (Credentials, time.Time, error)
+}
+
+// DefaultCredentialProviders returns the providers of the cloud registries: ECR, Google Container
+// Registry and Artifact Registry, and ACR. Each runs the CLI of its cloud, which must be installed
+// and logged in.
+func DefaultCredentialProviders() []CredentialProvider {
+	return []CredentialProvider{ECRProvider{}, GCPProvider{}, ACRProvider{}}
+}
+
+// CredentialHelpers resolves the credentials of registries that are not stored in a credentials
+// file: hosts whose credentials are held by a docker credential helper, and hosts of a cloud
+// registry with a matching provider. Resolved credentials are cached until shortly before they
+// expire, so CredentialHelpers should be reused for all requests of a run.
+type CredentialHelpers struct {
+	// Providers are tried in order for hosts without a credential helper.
+	Providers []CredentialProvider
+	// Now returns the current time. It can be replaced in tests.
+	Now func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedCredentials
+}
+
+// cachedCredentials are resolved credentials and when they expire
+type cachedCredentials struct {
+	creds   Credentials
+	expires time.Time
+}
+
+// NewCredentialHelpers creates CredentialHelpers with DefaultCredentialProviders.
+func NewCredentialHelpers() *CredentialHelpers {
+	return &CredentialHelpers{Providers: DefaultCredentialProviders(), Now: time.Now}
+}
+
+// Resolve returns the credentials for host, given the credentials found in the credentials files.
+// Credentials held by a docker credential helper are read from it; hosts without credentials are
+// looked up with the first matching provider. A failing helper or provider is logged and the host
+// is treated as having no readable credentials. h may be nil.
+func (h *CredentialHelpers) Resolve(ctx context.Context, credentials map[string]Credentials, host string) (Credentials, bool) {
+	creds, ok := credentials[host]
+	if h == nil || (ok && !creds.External) {
+		return creds, ok
+	}
+
+	var provider CredentialProvider
+	switch {
+	case ok && creds.Helper != "":
+		provider = DockerCredentialHelper{Helper: creds.Helper, ServerURL: creds.ServerURL}
+	case ok:
+		return creds, ok
+	default:
+		for _, candidate := range h.Providers {
+			if candidate.Matches(host) {
+				provider = candidate
+				break
+			}
+		}
+	}
+	if provider == nil {
+		return creds, ok
+	}
+
+	resolved, err := h.fetch(ctx, provider, host)
+	if err != nil {
+		log.Warn("Failed to get registry credentials", "registry", host, "provider", provider.Name(), "error", err)
+		return creds, ok
+	}
+	return resolved, true
+}
+
+// fetch returns the cached credentials of host, asking provider again when they are missing or
+// about to expire
+func (h *CredentialHelpers) fetch(ctx context.Context, provider CredentialProvider, host string) (Credentials, error) {
+	now := h.now()
+	h.mu.Lock()
+	cached, found := h.cache[host]
+	h.mu.Unlock()
+	if found && (cached.expires.IsZero() || now.Add(credentialRefreshMargin).Before(cached.expires)) {
+		return cached.creds, nil
+	}
+
+	log.Debug("Requesting registry credentials", "registry", host, "provider", provider.Name())
+	creds, expires, err := provider.Credentials(ctx, host)
+	if err != nil {
+		return Credentials{}, err
+	}
+	h.mu.Lock()
+	if h.cache == nil {
+		h.cache = make(map[string]cachedCredentials)
+	}
+	h.cache[host] = cachedCredentials{creds: creds, expires: expires}
+	h.mu.Unlock()
+	return creds, nil
+}
+
+func (h *CredentialHelpers) now() time.Time {
+	if h.Now != nil {
+		return h.Now()
+	}
+	return time.Now()
+}
+
+// DockerCredentialHelper reads credentials from a docker credential helper
+// (docker-credential-<Helper>), as configured by credHelpers or credsStore in a Docker config.
+type DockerCredentialHelper struct {
+	// Helper is the helper name, e.g. ecr-login or osxkeychain
+	Helper string
+	// ServerURL is the key the credentials are stored under; the host is used when empty
+	ServerURL string
+}
+
+// Name returns the helper command.
+func (d DockerCredentialHelper) Name() string {
+	return "docker-credential-" + d.Helper
+}
+
+// Matches reports false: helpers are selected by the credentials files, not by host.
+func (d DockerCredentialHelper) Matches(string) bool {
+	return false
+}
+
+// Credentials runs `docker-credential-<Helper> get`. Helpers do not report an expiry, so the
+// credentials are kept for the run.
+func (d DockerCredentialHelper) Credentials(ctx context.Context, host string) (Credentials, time.Time, error) {
+	serverURL := d.ServerURL
+	if serverURL == "" {
+		serverURL = host
+	}
+	output, err := runCredentialCommand(ctx, serverURL, d.Name(), "get")
+	if err != nil {
+		return Credentials{}, time.Time{}, err
+	}
+	var response struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return Credentials{}, time.Time{}, fmt.Errorf("invalid output of %s: %w", d.Name(), err)
+	}
+	if response.Secret == "" {
+		return Credentials{}, time.Time{}, fmt.Errorf("%s returned no credentials for %s", d.Name(), serverURL)
+	}
+	return Credentials{Username: response.Username, Password: response.Secret}, time.Time{}, nil
+}
+
+// ECRProvider gets Amazon ECR authorization tokens (GetAuthorizationToken) with
+// `aws ecr get-login-password` for hosts such as 123456789012.dkr.ecr.us-east-1.amazonaws.com.
+type ECRProvider struct{}
+
+// Name returns the provider name.
+func (ECRProvider) Name() string { return "ecr" }
+
+// Matches reports whether host is an ECR registry.
+func (ECRProvider) Matches(host string) bool { return ecrHostPattern.MatchString(host) }
+
+// Credentials requests an authorization token for the region of host.
+func (p ECRProvider) Credentials(ctx context.Context, host string) (Credentials, time.Time, error) {
+	match := ecrHostPattern.FindStringSubmatch(host)
+	if match == nil {
+		return Credentials{}, time.Time{}, fmt.Errorf("%s is not an ECR registry", host)
+	}
+	return tokenCredentials(ctx, ecrUsername, ecrTokenLifetime, "aws", "ecr", "get-login-password", "--region", match[1])
+}
+
+// GCPProvider gets Google Cloud access tokens with `gcloud auth print-access-token` for
+// Container Registry (gcr.io) and Artifact Registry (*-docker.pkg.dev) hosts.
+type GCPProvider struct{}
+
+// Name returns the provider name.
+func (GCPProvider) Name() string { return "gcp" }
+
+// Matches reports whether host is a Container Registry or Artifact Registry host.
+func (GCPProvider) Matches(host string) bool {
+	return host == "gcr.io" || strings.HasSuffix(host, ".gcr.io") || strings.HasSuffix(host, "-docker.pkg.dev")
+}
+
+// Credentials requests an access token of the active gcloud account.
+func (GCPProvider) Credentials(ctx context.Context, _ string) (Credentials, time.Time, error) {
+	return tokenCredentials(ctx, gcpUsername, gcpTokenLifetime, "gcloud", "auth", "print-access-token")
+}
+
+// ACRProvider gets Azure Container Registry refresh tokens with
+// `az acr login --expose-token` for hosts such as myregistry.azurecr.io.
+type ACRProvider struct{}
+
+// Name returns the provider name.
+func (ACRProvider) Name() string { return "acr" }
+
+// Matches reports whether host is an ACR registry.
+func (ACRProvider) Matches(host string) bool { return acrHostPattern.MatchString(host) }
+
+// Credentials requests a refresh token for the registry of host.
+func (ACRProvider) Credentials(ctx context.Context, host string) (Credentials, time.Time, error) {
+	match := acrHostPattern.FindStringSubmatch(host)
+	if match == nil {
+		return Credentials{}, time.Time{}, fmt.Errorf("%s is not an ACR registry", host)
+	}
+	return tokenCredentials(ctx, acrUsername, acrTokenLifetime,
+		"az", "acr", "login", "--name", match[1], "--expose-token", "--output", "tsv", "--query", "accessToken")
+}
+
+// tokenCredentials runs a cloud CLI that prints a registry token and pairs the token with the
+// username the registry expects
+func tokenCredentials(ctx context.Context, username string, lifetime time.Duration, name string, args ...string) (Credentials, time.Time, error) {
+	requested := time.Now()
+	output, err := runCredentialCommand(ctx, "", name, args...)
+	if err != nil {
+		return Credentials{}, time.Time{}, err
+	}
+	token := strings.TrimSpace(string(output))
+	if token == "" {
+		return Credentials{}, time.Time{}, fmt.Errorf("%s returned an empty token", name)
+	}
+	return Credentials{Username: username, Password: token}, requested.Add(lifetime), nil
+}
@@ -0,0 +1,144 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCredentialCommands replaces runCredentialCommand, answering with outputs keyed by the
+// command line and recording each call with its stdin
+func fakeCredentialCommands(t *testing.T, outputs map[string]string) *[]string {
+	t.Helper()
+	var calls []string
+	original := runCredentialCommand
+	runCredentialCommand = func(_ context.Context, stdin, name string, args ...string) ([]byte, error) {
+		commandLine := strings.Join(append([]string{name}, args...), " ")
+		calls = append(calls, commandLine+" <"+stdin)
+		output, ok := outputs[commandLine]
+		if !ok {
+			return nil, errors.New(name + ": not found")
+		}
+		return []byte(output), nil
+	}
+	t.Cleanup(func() { runCredentialCommand = original })
+	return &calls
+}
+
+func TestCredentialProvidersMatch(t *testing.T) {
+	tests := []struct {
+		host     string
+		provider string
+	}{
+		{host: "123456789012.dkr.ecr.us-east-1.amazonaws.com", provider: "ecr"},
+		{host: "123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com", provider: "ecr"},
+		{host: "123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn", provider: "ecr"},
+		{host: "gcr.io", provider: "gcp"},
+		{host: "eu.gcr.io", provider: "gcp"},
+		{host: "europe-west1-docker.pkg.dev", provider: "gcp"},
+		{host: "myregistry.azurecr.io", provider: "acr"},
+		{host: "public.ecr.aws", provider: ""},
+		{host: "harbor.example.com", provider: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			matched := ""
+			for _, provider := range DefaultCredentialProviders() {
+				if provider.Matches(tt.host) {
+					matched = provider.Name()
+					break
+				}
+			}
+			assert.Equal(t, tt.provider, matched)
+		})
+	}
+}
+
+func TestCredentialProviders(t *testing.T) {
+	calls := fakeCredentialCommands(t, map[string]string{
+		"aws ecr get-login-password --region eu-west-1":                                  "ecr-token\n",
+		"gcloud auth print-access-token":                                                 "gcp-token\n",
+		"az acr login --name myregistry --expose-token --output tsv --query accessToken": "acr-token\n",
+		"docker-credential-osxkeychain get":                                              `{"ServerURL":"https://index.docker.io/v1/","Username":"user","Secret":"secret"}`,
+		"docker-credential-empty get":                                                    `{}`,
+	})
+	ctx := context.Background()
+
+	creds, expires, err := ECRProvider{}.Credentials(ctx, "123456789012.dkr.ecr.eu-west-1.amazonaws.com")
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{Username: "AWS", Password: "ecr-token"}, creds)
+	assert.WithinDuration(t, time.Now().Add(12*time.Hour), expires, time.Minute)
+
+	creds, _, err = GCPProvider{}.Credentials(ctx, "gcr.io")
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{Username: "oauth2accesstoken", Password: "gcp-token"}, creds)
+
+	creds, _, err = ACRProvider{}.Credentials(ctx, "myregistry.azurecr.io")
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{Username: "00000000-0000-0000-0000-000000000000", Password: "acr-token"}, creds)
+
+	creds, expires, err = DockerCredentialHelper{Helper: "osxkeychain", ServerURL: "https://index.docker.io/v1/"}.Credentials(ctx, "index.docker.io")
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{Username: "user", Password: "secret"}, creds)
+	assert.True(t, expires.IsZero())
+	assert.Contains(t, *calls, "docker-credential-osxkeychain get <https://index.docker.io/v1/", "the helper is asked for the configured server URL")
+
+	_, _, err = DockerCredentialHelper{Helper: "empty"}.Credentials(ctx, "harbor.example.com")
+	assert.ErrorContains(t, err, "docker-credential-empty returned no credentials for harbor.example.com")
+}
+
+func TestCredentialHelpersResolve(t *testing.T) {
+	calls := fakeCredentialCommands(t, map[string]string{
+		"aws ecr get-login-password --region us-east-1": "ecr-token",
+		"docker-credential-desktop get":                 `{"Username":"user","Secret":"secret"}`,
+	})
+	now := time.Now()
+	helpers := &CredentialHelpers{Providers: DefaultCredentialProviders(), Now: func() time.Time { return now }}
+	ecrHost := "123456789012.dkr.ecr.us-east-1.amazonaws.com"
+	credentials := map[string]Credentials{
+		"harbor.example.com": {Username: "robot", Password: "inline"},
+		"ghcr.io":            {External: true, Helper: "desktop", ServerURL: "ghcr.io"},
+		"quay.io":            {External: true, Helper: "missing"},
+	}
+	ctx := context.Background()
+
+	creds, ok := helpers.Resolve(ctx, credentials, "harbor.example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "inline", creds.Password, "credentials from the files are used as they are")
+
+	creds, ok = helpers.Resolve(ctx, credentials, "ghcr.io")
+	assert.True(t, ok)
+	assert.Equal(t, Credentials{Username: "user", Password: "secret"}, creds)
+
+	creds, ok = helpers.Resolve(ctx, credentials, ecrHost)
+	assert.True(t, ok)
+	assert.Equal(t, "ecr-token", creds.Password)
+
+	creds, ok = helpers.Resolve(ctx, credentials, "quay.io")
+	assert.True(t, ok, "a failing helper leaves the credentials external")
+	assert.True(t, creds.External)
+
+	_, ok = helpers.Resolve(ctx, credentials, "docker.io")
+	assert.False(t, ok)
+
+	*calls = nil
+	helpers.Resolve(ctx, credentials, ecrHost)
+	helpers.Resolve(ctx, credentials, "ghcr.io")
+	assert.Empty(t, *calls, "resolved credentials are cached")
+
+	now = now.Add(12*time.Hour - time.Minute)
+	helpers.Resolve(ctx, credentials, ecrHost)
+	helpers.Resolve(ctx, credentials, "ghcr.io")
+	assert.Equal(t, []string{"aws ecr get-login-password --region us-east-1 <"}, *calls,
+		"tokens are refreshed before they expire; helper credentials do not expire")
+
+	var nilHelpers *CredentialHelpers
+	creds, ok = nilHelpers.Resolve(ctx, credentials, "ghcr.io")
+	assert.True(t, ok)
+	assert.True(t, creds.External, "without helpers, external credentials are not read")
+}
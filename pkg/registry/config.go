@@ -2,10 +2,15 @@
 package registry
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	"github.com/lucas-albers-lz4/irr/pkg/image"
 	"github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/lucas-albers-lz4/irr/pkg/strictness"
 	"github.com/spf13/afero"
 	"sigs.k8s.io/yaml"
 )
@@ -21,14 +26,32 @@ type Config struct {
 	Version string `yaml:"version,omitempty"`
 	// Compatibility flags for handling special cases
 	Compatibility CompatibilityConfig `yaml:"compatibility,omitempty"`
+	// Profiles holds named per-environment registry settings selectable with --profile
+	Profiles map[string]RegConfig `yaml:"profiles,omitempty"`
+	// Policy overrides the strict mode level's action (ignore, warn or error) per condition
+	Policy *strictness.Policy `yaml:"policy,omitempty"`
+	// TLS holds the certificate verification settings of registry hosts (host or host:port)
+	TLS map[string]TLSConfig `yaml:"tls,omitempty"`
+	// Exceptions lists the images that are never relocated, in addition to those of each chart's
+	// ChartExceptionsFile
+	Exceptions []RelocationException `yaml:"exceptions,omitempty"`
+	// Limits bounds the size and complexity of the values analyzed for each chart
+	Limits *LimitsConfig `yaml:"limits,omitempty"`
+	// Encryption encrypts the generated overrides for its recipients
+	Encryption *EncryptionConfig `yaml:"encryption,omitempty"`
 }
 
 // RegConfig holds registry-specific configuration
 type RegConfig struct {
 	// Mappings contains the source to target registry mappings
 	Mappings []RegMapping `yaml:"mappings"`
+	// Groups route several source registries to a shared target registry each
+	Groups []RegGroup `yaml:"groups,omitempty"`
 	// DefaultTarget is the default target registry if no specific mapping is found
 	DefaultTarget string `yaml:"defaultTarget,omitempty"`
+	// DefaultRegistry is the registry the cluster's container runtime resolves unqualified images
+	// (e.g., nginx:1.25) to; docker.io if empty
+	DefaultRegistry string `yaml:"defaultRegistry,omitempty"`
 	// StrictMode determines if unknown registries should fail (true) or use the default (false)
 	StrictMode bool `yaml:"strictMode,omitempty"`
 }
@@ -43,6 +66,9 @@ type RegMapping struct {
 	Description string `yaml:"description,omitempty"`
 	// Enabled determines if this mapping is active (default: true)
 	Enabled bool `yaml:"enabled,omitempty"`
+	// TagTransform is an optional template rewriting image tags for this mapping
+	// (e.g., "mirror-{{ .Tag }}"); it can use .Tag, .Digest and .Repository
+	TagTransform string `yaml:"tagTransform,omitempty"`
 }
 
 // CompatibilityConfig contains compatibility flags for handling special cases
@@ -52,7 +78,19 @@ type CompatibilityConfig struct {
 }
 
 // LoadStructuredConfig loads registry mappings from a YAML file using the structured format.
+// ${NAME} placeholders in target registry values are resolved from the environment.
 func LoadStructuredConfig(fs afero.Fs, path string, skipCWDRestriction bool) (*Config, error) {
+	return loadStructuredConfig(fs, path, skipCWDRestriction, ParseConfig)
+}
+
+// LoadStructuredConfigKeepingEnv loads registry mappings like LoadStructuredConfig but keeps the
+// ${NAME} placeholders of target registry values, for commands that edit and rewrite the file.
+func LoadStructuredConfigKeepingEnv(fs afero.Fs, path string, skipCWDRestriction bool) (*Config, error) {
+	return loadStructuredConfig(fs, path, skipCWDRestriction, ParseConfigKeepingEnv)
+}
+
+// loadStructuredConfig reads a structured config file and parses it with parse
+func loadStructuredConfig(fs afero.Fs, path string, skipCWDRestriction bool, parse func([]byte, string) (*Config, error)) (*Config, error) {
 	// Validate file path
 	if err := validateConfigFilePath(fs, path, skipCWDRestriction); err != nil {
 		return nil, err
@@ -66,31 +104,137 @@ func LoadStructuredConfig(fs afero.Fs, path string, skipCWDRestriction bool) (*C
 
 	log.Debug("LoadStructuredConfig: Attempting to parse file content:\n%s", string(data))
 
-	// Parse the YAML content as structured Config
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		log.Debug("LoadStructuredConfig: Failed to parse as structured config: %v", err)
-		return nil, fmt.Errorf("failed to parse config file '%s' as structured format: %w", path, err)
+	config, err := parse(data, path)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("LoadStructuredConfig: Successfully loaded structured config from %s", path)
+	return config, nil
+}
+
+// ParseConfig parses and validates structured registry configuration read from source, which
+// names the file or object the data came from in error messages. ${NAME} and ${NAME:-default}
+// placeholders in target registry values (mapping and group targets and defaultTarget) are
+// resolved from the environment; unset variables without a default are an ErrUnsetEnvVars.
+func ParseConfig(data []byte, source string) (*Config, error) {
+	config, err := decodeConfig(data, source)
+	if err != nil {
+		return nil, err
+	}
+	if err := expandConfigEnv(config, source, os.LookupEnv); err != nil {
+		return nil, err
 	}
+	if err := validateStructuredConfig(config, source); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
 
-	// Validate the parsed config
-	if err := validateStructuredConfig(&config, path); err != nil {
+// ParseConfigKeepingEnv parses and validates structured registry configuration like ParseConfig,
+// but keeps the environment variable placeholders of target registry values unresolved.
+func ParseConfigKeepingEnv(data []byte, source string) (*Config, error) {
+	config, err := decodeConfig(data, source)
+	if err != nil {
 		return nil, err
 	}
+	if err := validateConfigKeepingEnv(config, source); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
 
-	log.Debug("LoadStructuredConfig: Successfully loaded structured config from %s", path)
+// decodeConfig upgrades structured registry configuration to the current version and decodes it
+func decodeConfig(data []byte, source string) (*Config, error) {
+	data, err := currentConfigData(data, source)
+	if err != nil {
+		var versionErr *ErrUnsupportedConfigVersion
+		if errors.As(err, &versionErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to parse config file '%s' as structured format: %w", source, err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		log.Debug("ParseConfig: Failed to parse as structured config: %v", err)
+		return nil, fmt.Errorf("failed to parse config file '%s' as structured format: %w", source, err)
+	}
 	return &config, nil
 }
 
-// validateStructuredConfig performs validation on the structured config
+// validateStructuredConfig performs validation on the structured config and its profiles
 func validateStructuredConfig(config *Config, path string) error {
-	// Ensure Registries.Mappings is initialized to avoid nil pointer issues
-	if config.Registries.Mappings == nil {
-		// Initialize an empty Mappings list
+	if config.Policy != nil {
+		if err := config.Policy.Validate(); err != nil {
+			return fmt.Errorf("invalid policy in config file '%s': %w", path, err)
+		}
+	}
+	if config.Limits != nil {
+		if _, err := config.Limits.Limits(); err != nil {
+			return fmt.Errorf("invalid limits in config file '%s': %w", path, err)
+		}
+	}
+	if config.Encryption != nil {
+		if err := config.Encryption.Validate(); err != nil {
+			return fmt.Errorf("invalid encryption in config file '%s': %w", path, err)
+		}
+	}
+	for host := range config.TLS {
+		if strings.TrimSpace(host) == "" || strings.Contains(host, "/") {
+			return fmt.Errorf("invalid registry host %q in the tls section of config file '%s'", host, path)
+		}
+	}
+	for i, exception := range config.Exceptions {
+		if err := exception.Validate(); err != nil {
+			return fmt.Errorf("invalid entry %d in the exceptions section of config file '%s': %w", i+1, path, err)
+		}
+	}
+	for _, name := range config.ProfileNames() {
+		if name == "" {
+			return fmt.Errorf("empty profile name in config file '%s'", path)
+		}
+		profile := config.Profiles[name]
+		var err error
+		if len(profile.Mappings) == 0 && len(profile.Groups) == 0 {
+			// Profiles often only override the default target, so empty mappings are fine
+			if profile.DefaultTarget != "" {
+				err = validateMappingValue("default", profile.DefaultTarget, path)
+			}
+			if err == nil && profile.DefaultRegistry != "" {
+				err = validateDefaultRegistry(profile.DefaultRegistry, path)
+			}
+		} else {
+			err = validateRegConfig(&profile, path)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid profile %q: %w", name, err)
+		}
+		config.Profiles[name] = profile
+	}
+
+	// A config made up only of profiles needs no top-level mappings
+	if len(config.Registries.Mappings) == 0 && len(config.Registries.Groups) == 0 && len(config.Profiles) > 0 {
 		config.Registries.Mappings = []RegMapping{}
+		return nil
+	}
+	return validateRegConfig(&config.Registries, path)
+}
+
+// validateRegConfig validates one registries section and sets mapping defaults
+func validateRegConfig(registries *RegConfig, path string) error {
+	// A section routing all of its sources through groups needs no mappings
+	if len(registries.Mappings) == 0 && len(registries.Groups) > 0 {
+		registries.Mappings = []RegMapping{}
+	}
+
+	// Ensure Mappings is initialized to avoid nil pointer issues
+	if registries.Mappings == nil {
+		// Initialize an empty Mappings list
+		registries.Mappings = []RegMapping{}
 
 		// When strictMode is false, just log a warning but don't return an error
-		if !config.Registries.StrictMode {
+		if !registries.StrictMode {
 			log.Warn("Mappings section is empty or nil but strictMode is false, continuing with empty mappings", "file", path)
 			return nil
 		}
@@ -100,9 +244,9 @@ func validateStructuredConfig(config *Config, path string) error {
 	}
 
 	// Check if the mappings list itself is empty
-	if len(config.Registries.Mappings) == 0 {
+	if len(registries.Mappings) == 0 && len(registries.Groups) == 0 {
 		// Only fail if strictMode is true; otherwise, allow empty mappings
-		if config.Registries.StrictMode {
+		if registries.StrictMode {
 			return fmt.Errorf("failed to parse mappings file: mappings section is empty in %s", path)
 		}
 
@@ -115,8 +259,8 @@ func validateStructuredConfig(config *Config, path string) error {
 	seenSources := make(map[string]bool)
 
 	// Validate each mapping and set defaults
-	for i := range config.Registries.Mappings {
-		mapping := &config.Registries.Mappings[i]
+	for i := range registries.Mappings {
+		mapping := &registries.Mappings[i]
 		source := mapping.Source
 		target := mapping.Target
 
@@ -160,25 +304,51 @@ func validateStructuredConfig(config *Config, path string) error {
 		if err := validateMappingValue(source, target, path); err != nil {
 			return err
 		}
+
+		// Validate the optional tag transform template
+		if mapping.TagTransform != "" {
+			if err := ValidateTagTransform(mapping.TagTransform); err != nil {
+				return fmt.Errorf("invalid tagTransform for source '%s' in config file '%s': %w", source, path, err)
+			}
+		}
+	}
+
+	if err := validateRegGroups(registries.Groups, seenSources, path); err != nil {
+		return err
 	}
 
 	// If StrictMode is enabled, DefaultTarget is not required
 	// If StrictMode is disabled, DefaultTarget should be set
-	if !config.Registries.StrictMode && config.Registries.DefaultTarget == "" {
+	if !registries.StrictMode && registries.DefaultTarget == "" {
 		log.Debug("Warning: StrictMode is disabled but DefaultTarget is not set in config file '%s'", path)
 	}
 
 	// If DefaultTarget is set, it should be valid
-	if config.Registries.DefaultTarget != "" {
-		if err := validateMappingValue("default", config.Registries.DefaultTarget, path); err != nil {
+	if registries.DefaultTarget != "" {
+		if err := validateMappingValue("default", registries.DefaultTarget, path); err != nil {
 			return fmt.Errorf("invalid DefaultTarget in config file '%s': %w", path, err)
 		}
 	}
 
+	if registries.DefaultRegistry != "" {
+		if err := validateDefaultRegistry(registries.DefaultRegistry, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateDefaultRegistry checks the defaultRegistry value of a config file.
+func validateDefaultRegistry(defaultRegistry, path string) error {
+	if err := image.ValidateDefaultRegistry(defaultRegistry); err != nil {
+		return fmt.Errorf("invalid defaultRegistry in config file '%s': %w", path, err)
+	}
 	return nil
 }
 
-// ToMappings converts a structured Config to the Mappings format
+// ToMappings converts a structured Config to the Mappings format. Group sources follow the
+// explicit mappings, one entry per source.
 func (c *Config) ToMappings() *Mappings {
 	mappings := &Mappings{
 		Entries: make([]Mapping, 0, len(c.Registries.Mappings)),
@@ -187,11 +357,13 @@ func (c *Config) ToMappings() *Mappings {
 	for _, mapping := range c.Registries.Mappings {
 		if mapping.Enabled {
 			mappings.Entries = append(mappings.Entries, Mapping{
-				Source: mapping.Source,
-				Target: mapping.Target,
+				Source:       mapping.Source,
+				Target:       mapping.Target,
+				TagTransform: mapping.TagTransform,
 			})
 		}
 	}
+	mappings.Entries = append(mappings.Entries, groupMappings(c.Registries.Groups)...)
 
 	return mappings
 }
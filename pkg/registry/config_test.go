@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	"github.com/lucas-albers-lz4/irr/pkg/limits"
+	"github.com/lucas-albers-lz4/irr/pkg/strictness"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -636,3 +638,203 @@ registries:
 	assert.True(t, foundK8s, "k8s.gcr.io should be included in mappings")
 	assert.False(t, foundQuay, "quay.io should NOT be included in mappings (it's disabled)")
 }
+
+func TestLoadStructuredConfig_Policy(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	write := func(content string) {
+		require.NoError(t, afero.WriteFile(fs, "/tmp/policy.yaml", []byte(content), 0o644))
+	}
+
+	write(`registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+policy:
+  templateExpressions: warn
+  unmappedRegistries: error
+`)
+	config, err := LoadStructuredConfig(fs, "/tmp/policy.yaml", true)
+	require.NoError(t, err)
+	require.NotNil(t, config.Policy)
+	assert.Equal(t, strictness.Policy{TemplateExpressions: strictness.ActionWarn, UnmappedRegistries: strictness.ActionError}, *config.Policy)
+
+	write(`registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+policy:
+  emptyRepositories: fail
+`)
+	_, err = LoadStructuredConfig(fs, "/tmp/policy.yaml", true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid policy")
+}
+
+func TestLoadStructuredConfig_Limits(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	write := func(content string) {
+		require.NoError(t, afero.WriteFile(fs, "/tmp/limits.yaml", []byte(content), 0o644))
+	}
+
+	write(`registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+limits:
+  maxValuesSize: 4Mi
+  maxDepth: 40
+`)
+	config, err := LoadStructuredConfig(fs, "/tmp/limits.yaml", true)
+	require.NoError(t, err)
+	require.NotNil(t, config.Limits)
+	configured, err := config.Limits.Limits()
+	require.NoError(t, err)
+	assert.Equal(t, limits.Limits{MaxValuesSize: 4 << 20, MaxDepth: 40}, configured)
+
+	write(`registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+limits:
+  maxValuesSize: 4MB
+`)
+	_, err = LoadStructuredConfig(fs, "/tmp/limits.yaml", true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid limits")
+}
+
+func TestLoadStructuredConfig_Encryption(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	write := func(content string) {
+		require.NoError(t, afero.WriteFile(fs, "/tmp/encryption.yaml", []byte(content), 0o644))
+	}
+
+	write(`registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+encryption:
+  format: age
+  recipients:
+    - age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
+`)
+	config, err := LoadStructuredConfig(fs, "/tmp/encryption.yaml", true)
+	require.NoError(t, err)
+	require.NotNil(t, config.Encryption)
+	assert.Equal(t, EncryptionConfig{
+		Format:     "age",
+		Recipients: []string{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"},
+	}, *config.Encryption)
+
+	write(`registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+encryption:
+  format: gpg
+`)
+	_, err = LoadStructuredConfig(fs, "/tmp/encryption.yaml", true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid encryption")
+}
+
+func TestLoadStructuredConfig_DefaultRegistry(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	write := func(content string) {
+		require.NoError(t, afero.WriteFile(fs, "/tmp/default-registry.yaml", []byte(content), 0o644))
+	}
+
+	write(`registries:
+  defaultRegistry: mirror.internal
+  mappings:
+    - source: mirror.internal
+      target: harbor.local/mirror
+profiles:
+  edge:
+    defaultRegistry: edge-mirror.internal:5000
+`)
+	config, err := LoadStructuredConfig(fs, "/tmp/default-registry.yaml", true)
+	require.NoError(t, err)
+	assert.Equal(t, "mirror.internal", config.Registries.DefaultRegistry)
+	require.NoError(t, config.ApplyProfile("edge"))
+	assert.Equal(t, "edge-mirror.internal:5000", config.Registries.DefaultRegistry)
+
+	write(`registries:
+  defaultRegistry: https://mirror.internal/docker
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+`)
+	_, err = LoadStructuredConfig(fs, "/tmp/default-registry.yaml", true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid defaultRegistry")
+}
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(`registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+`), "configmap/ci/irr-config")
+	require.NoError(t, err)
+	require.Len(t, config.Registries.Mappings, 1)
+	assert.True(t, config.Registries.Mappings[0].Enabled, "mapping defaults are applied")
+
+	_, err = ParseConfig([]byte("registries: ["), "configmap/ci/irr-config")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "configmap/ci/irr-config")
+}
+
+func TestParseConfig_TLS(t *testing.T) {
+	config, err := ParseConfig([]byte(`registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+tls:
+  harbor.local:
+    caFile: certs/harbor-ca.pem
+  registry.lab:5000:
+    insecureSkipTLSVerify: true
+`), "registry-mappings.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]TLSConfig{
+		"harbor.local":      {CAFile: "certs/harbor-ca.pem"},
+		"registry.lab:5000": {InsecureSkipTLSVerify: true},
+	}, config.TLS)
+
+	_, err = ParseConfig([]byte(`registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+tls:
+  https://harbor.local/docker:
+    insecureSkipTLSVerify: true
+`), "registry-mappings.yaml")
+	assert.ErrorContains(t, err, `invalid registry host "https://harbor.local/docker" in the tls section`)
+}
+
+func TestParseConfig_Exceptions(t *testing.T) {
+	config, err := ParseConfig([]byte(`registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+exceptions:
+  - image: registry.vendor.com/**
+    reason: licensed images must be pulled from the vendor registry
+  - path: enterprise.*.image
+`), "registry-mappings.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, []RelocationException{
+		{Image: "registry.vendor.com/**", Reason: "licensed images must be pulled from the vendor registry"},
+		{Path: "enterprise.*.image"},
+	}, config.Exceptions)
+
+	_, err = ParseConfig([]byte(`registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+exceptions:
+  - reason: missing path and image
+`), "registry-mappings.yaml")
+	assert.ErrorContains(t, err, "invalid entry 1 in the exceptions section")
+}
@@ -0,0 +1,105 @@
+package registry
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+)
+
+// ConflictKind is the kind of a MappingConflict.
+type ConflictKind string
+
+// Kinds of mapping conflicts.
+const (
+	// ConflictSelfMapping is a source registry relocated into itself: its images would keep their
+	// registry, so the overrides are no-ops.
+	ConflictSelfMapping ConflictKind = "self-mapping"
+	// ConflictDuplicateSource is a source registry mapped more than once; only its first mapping is used.
+	ConflictDuplicateSource ConflictKind = "duplicate-source"
+	// ConflictTargetIsSource is a target registry that is also a source registry: relocated images
+	// would be relocated again, or cycle between registries mapped to each other.
+	ConflictTargetIsSource ConflictKind = "target-is-source"
+)
+
+// MappingConflict is a registry mapping, or the target registry, that makes generated overrides
+// point images back at a source registry, or that is shadowed by another mapping.
+type MappingConflict struct {
+	Kind ConflictKind
+	// Source is the source registry of the mapping, empty for the target registry
+	Source string
+	Target string
+}
+
+// String describes the conflict for warnings and strict mode errors
+func (c MappingConflict) String() string {
+	switch c.Kind {
+	case ConflictSelfMapping:
+		return fmt.Sprintf("%s is mapped to itself (%s)", c.Source, c.Target)
+	case ConflictDuplicateSource:
+		return fmt.Sprintf("%s is mapped more than once, its mapping to %s is ignored", c.Source, c.Target)
+	default:
+		if c.Source == "" {
+			return fmt.Sprintf("target registry %s is also a source registry", c.Target)
+		}
+		return fmt.Sprintf("%s is mapped to %s, which is also a source registry", c.Source, c.Target)
+	}
+}
+
+// FindMappingConflicts returns the mappings that relocate a source registry into itself or into
+// another source registry, and those whose source is already mapped, in the order of mappings,
+// followed by a conflict of targetRegistry, which relocates the unmapped source registries.
+// sourceRegistries are the registries to relocate, empty for all. Registries are compared the way
+// mappings are looked up (image.NormalizeRegistry): without ports or paths, and with
+// index.docker.io being docker.io.
+func FindMappingConflicts(mappings *Mappings, sourceRegistries []string, targetRegistry string) []MappingConflict {
+	var entries []Mapping
+	if mappings != nil {
+		entries = mappings.Entries
+	}
+	sources := make(map[string]bool)
+	for _, source := range sourceRegistries {
+		sources[image.NormalizeRegistry(source)] = true
+	}
+	for _, entry := range entries {
+		sources[image.NormalizeRegistry(entry.Source)] = true
+	}
+
+	var conflicts []MappingConflict
+	mapped := make(map[string]bool)
+	for _, entry := range entries {
+		source := image.NormalizeRegistry(entry.Source)
+		if mapped[source] {
+			conflicts = append(conflicts, MappingConflict{Kind: ConflictDuplicateSource, Source: entry.Source, Target: entry.Target})
+			continue
+		}
+		mapped[source] = true
+		if entry.Target == "" {
+			continue
+		}
+		switch target := image.NormalizeRegistry(entry.Target); {
+		case target == source:
+			conflicts = append(conflicts, MappingConflict{Kind: ConflictSelfMapping, Source: entry.Source, Target: entry.Target})
+		case sources[target]:
+			conflicts = append(conflicts, MappingConflict{Kind: ConflictTargetIsSource, Source: entry.Source, Target: entry.Target})
+		}
+	}
+
+	// The target registry only receives images of unmapped source registries
+	targetUsed := len(sourceRegistries) == 0 || slices.ContainsFunc(sourceRegistries, func(source string) bool {
+		return !mapped[image.NormalizeRegistry(source)]
+	})
+	if targetRegistry == "" || !targetUsed {
+		return conflicts
+	}
+	switch target := image.NormalizeRegistry(targetRegistry); {
+	case sources[target] && !mapped[target]:
+		source := sourceRegistries[slices.IndexFunc(sourceRegistries, func(source string) bool {
+			return image.NormalizeRegistry(source) == target
+		})]
+		conflicts = append(conflicts, MappingConflict{Kind: ConflictSelfMapping, Source: source, Target: targetRegistry})
+	case sources[target]:
+		conflicts = append(conflicts, MappingConflict{Kind: ConflictTargetIsSource, Target: targetRegistry})
+	}
+	return conflicts
+}
@@ -0,0 +1,77 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindMappingConflicts(t *testing.T) {
+	tests := []struct {
+		name             string
+		mappings         []Mapping
+		sourceRegistries []string
+		targetRegistry   string
+		want             []MappingConflict
+	}{
+		{
+			name:             "no conflicts",
+			mappings:         []Mapping{{Source: "docker.io", Target: "harbor.local/dockerhub"}, {Source: "quay.io", Target: "harbor.local/quay"}},
+			sourceRegistries: []string{"docker.io", "quay.io", "ghcr.io"},
+			targetRegistry:   "harbor.local",
+		},
+		{
+			name:     "self-mapping",
+			mappings: []Mapping{{Source: "Quay.io", Target: "quay.io/mirror"}},
+			want:     []MappingConflict{{Kind: ConflictSelfMapping, Source: "Quay.io", Target: "quay.io/mirror"}},
+		},
+		{
+			name:     "duplicate source after normalization",
+			mappings: []Mapping{{Source: "docker.io", Target: "harbor.local/a"}, {Source: "index.docker.io", Target: "harbor.local/b"}},
+			want:     []MappingConflict{{Kind: ConflictDuplicateSource, Source: "index.docker.io", Target: "harbor.local/b"}},
+		},
+		{
+			name:     "mappings cycling between registries",
+			mappings: []Mapping{{Source: "a.example.com", Target: "b.example.com/mirror"}, {Source: "b.example.com", Target: "a.example.com"}},
+			want: []MappingConflict{
+				{Kind: ConflictTargetIsSource, Source: "a.example.com", Target: "b.example.com/mirror"},
+				{Kind: ConflictTargetIsSource, Source: "b.example.com", Target: "a.example.com"},
+			},
+		},
+		{
+			name:             "target registry is an unmapped source",
+			sourceRegistries: []string{"docker.io", "harbor.local:5000"},
+			targetRegistry:   "harbor.local:5000/mirror",
+			want:             []MappingConflict{{Kind: ConflictSelfMapping, Source: "harbor.local:5000", Target: "harbor.local:5000/mirror"}},
+		},
+		{
+			name:           "target registry is a mapped source",
+			mappings:       []Mapping{{Source: "harbor.local", Target: "registry.example.com"}},
+			targetRegistry: "harbor.local",
+			want:           []MappingConflict{{Kind: ConflictTargetIsSource, Target: "harbor.local"}},
+		},
+		{
+			name:             "target registry unused when every source is mapped",
+			mappings:         []Mapping{{Source: "docker.io", Target: "harbor.local"}},
+			sourceRegistries: []string{"docker.io"},
+			targetRegistry:   "docker.io",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindMappingConflicts(&Mappings{Entries: tt.mappings}, tt.sourceRegistries, tt.targetRegistry)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMappingConflictString(t *testing.T) {
+	assert.Equal(t, "quay.io is mapped to itself (quay.io/mirror)",
+		MappingConflict{Kind: ConflictSelfMapping, Source: "quay.io", Target: "quay.io/mirror"}.String())
+	assert.Equal(t, "docker.io is mapped more than once, its mapping to harbor.local/b is ignored",
+		MappingConflict{Kind: ConflictDuplicateSource, Source: "docker.io", Target: "harbor.local/b"}.String())
+	assert.Equal(t, "a.example.com is mapped to b.example.com, which is also a source registry",
+		MappingConflict{Kind: ConflictTargetIsSource, Source: "a.example.com", Target: "b.example.com"}.String())
+	assert.Equal(t, "target registry harbor.local is also a source registry",
+		MappingConflict{Kind: ConflictTargetIsSource, Target: "harbor.local"}.String())
+}
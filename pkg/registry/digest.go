@@ -0,0 +1,52 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ImageDigest returns the digest of the manifest tag points to in repository on the registry host,
+// as an image pinned by digest is pulled: the manifest of an image index rather than of one of its
+// platforms. The digest is read from the Docker-Content-Digest header of a HEAD request, or
+// computed from the manifest for registries that do not send it. ErrManifestNotFound is returned
+// when the registry does not have the tag.
+func (c *TagChecker) ImageDigest(ctx context.Context, host, repository, tag string) (string, error) {
+	host, repository = registryEndpoint(host, repository)
+	target := "https://" + host + "/v2/" + repository + "/manifests/" + url.PathEscape(tag)
+
+	resp, err := c.do(ctx, http.MethodHead, host, repository, target, manifestMediaTypes)
+	if err != nil {
+		return "", err
+	}
+	closeBody(resp)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if digest := resp.Header.Get("Docker-Content-Digest"); strings.HasPrefix(digest, "sha256:") {
+			return digest, nil
+		}
+	case http.StatusNotFound:
+		return "", fmt.Errorf("%s/%s:%s: %w", host, repository, tag, ErrManifestNotFound)
+	default:
+		return "", fmt.Errorf("unexpected status %s getting the digest of %s/%s:%s", resp.Status, host, repository, tag)
+	}
+
+	resp, err = c.do(ctx, http.MethodGet, host, repository, target, manifestMediaTypes)
+	if err != nil {
+		return "", err
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s getting the manifest of %s/%s:%s", resp.Status, host, repository, tag)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return "", fmt.Errorf("failed to read the manifest of %s/%s:%s: %w", host, repository, tag, err)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,44 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagCheckerImageDigest(t *testing.T) {
+	manifest := `{"schemaVersion": 2, "manifests": []}`
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/team/app/manifests/latest":
+			w.Header().Set("Docker-Content-Digest", "sha256:0123456789abcdef")
+		case "/v2/team/app/manifests/main":
+			// No Docker-Content-Digest header, so the digest is computed from the manifest
+			_, _ = w.Write([]byte(manifest))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	host := strings.TrimPrefix(server.URL, "https://")
+	checker := NewTagChecker(nil, DefaultStaleAfter)
+	checker.Client = server.Client()
+
+	digest, err := checker.ImageDigest(context.Background(), host, "team/app", "latest")
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:0123456789abcdef", digest)
+
+	digest, err = checker.ImageDigest(context.Background(), host, "team/app", "main")
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:"+fmt.Sprintf("%x", sha256.Sum256([]byte(manifest))), digest)
+
+	_, err = checker.ImageDigest(context.Background(), host, "team/app", "stable")
+	assert.ErrorIs(t, err, ErrManifestNotFound)
+}
@@ -0,0 +1,47 @@
+package registry
+
+import (
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/encryption"
+)
+
+// EncryptionConfig is the encryption section of a registry config. It encrypts the generated
+// overrides like the --encrypt and --encrypt-recipient flags, which take precedence.
+type EncryptionConfig struct {
+	// Format is the encryption format: age or sops
+	Format string `yaml:"format,omitempty"`
+	// Recipients are the keys the overrides are encrypted to, as for --encrypt-recipient
+	Recipients []string `yaml:"recipients,omitempty"`
+}
+
+// Validate checks the format of the section.
+func (c EncryptionConfig) Validate() error {
+	if c.Format == "" {
+		return nil
+	}
+	return encryption.ValidateFormat(c.Format)
+}
+
+// Merge returns c with the fields that other sets replaced by those of other.
+func (c EncryptionConfig) Merge(other EncryptionConfig) EncryptionConfig {
+	if other.Format != "" {
+		c.Format = other.Format
+	}
+	if len(other.Recipients) > 0 {
+		c.Recipients = other.Recipients
+	}
+	return c
+}
+
+// settings returns the fields c sets by their YAML key, formatted for merge conflicts.
+func (c EncryptionConfig) settings() map[string]string {
+	settings := make(map[string]string)
+	if c.Format != "" {
+		settings["format"] = c.Format
+	}
+	if len(c.Recipients) > 0 {
+		settings["recipients"] = strings.Join(c.Recipients, ",")
+	}
+	return settings
+}
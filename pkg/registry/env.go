@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+)
+
+// envPlaceholderPattern matches ${NAME} and ${NAME:-default} placeholders in target registry values
+var envPlaceholderPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// placeholderHost stands in for every placeholder when a config is validated without resolving them
+const placeholderHost = "env-placeholder.invalid"
+
+// ErrUnsetEnvVars indicates target registry values of a config file use environment variables
+// that are not set.
+type ErrUnsetEnvVars struct {
+	Path string
+	// Variables maps each unset variable to the config fields using it
+	Variables map[string][]string
+}
+
+func (e *ErrUnsetEnvVars) Error() string {
+	names := make([]string, 0, len(e.Variables))
+	for name := range e.Variables {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s (used by %s)", name, strings.Join(e.Variables[name], ", ")))
+	}
+	return fmt.Sprintf("environment variables referenced in config file '%s' are not set: %s; set them or give a default with ${NAME:-default}",
+		e.Path, strings.Join(parts, "; "))
+}
+
+// ExitCode reports unset variables as an input configuration error.
+func (e *ErrUnsetEnvVars) ExitCode() int { return exitcodes.ExitInputConfigurationError }
+
+// expandEnvPlaceholders replaces the ${NAME} and ${NAME:-default} placeholders of value using
+// lookup. A variable that is unset or empty takes its default; without a default it is added to
+// missing, keyed by name.
+func expandEnvPlaceholders(value, field string, lookup func(string) (string, bool), missing map[string][]string) string {
+	return envPlaceholderPattern.ReplaceAllStringFunc(value, func(placeholder string) string {
+		match := envPlaceholderPattern.FindStringSubmatch(placeholder)
+		name, hasDefault, defaultValue := match[1], match[2] != "", match[3]
+		if resolved, ok := lookup(name); ok && resolved != "" {
+			return resolved
+		}
+		if hasDefault {
+			return defaultValue
+		}
+		missing[name] = append(missing[name], field)
+		return placeholder
+	})
+}
+
+// expandConfigEnv resolves the environment variable placeholders of the target registry values
+// of a config: the targets of mappings and groups and the defaultTarget, at the top level and in
+// every profile.
+func expandConfigEnv(config *Config, path string, lookup func(string) (string, bool)) error {
+	missing := map[string][]string{}
+	mapConfigTargets(config, func(field, value string) string {
+		return expandEnvPlaceholders(value, field, lookup, missing)
+	})
+	if len(missing) > 0 {
+		return &ErrUnsetEnvVars{Path: path, Variables: missing}
+	}
+	return nil
+}
+
+// mapConfigTargets replaces every target registry value of a config with the result of fn, which
+// receives the value's field path (e.g. registries.mappings[0].target). Profiles are visited in
+// name order, so the order of calls is stable.
+func mapConfigTargets(config *Config, fn func(field, value string) string) {
+	mapRegConfigTargets(&config.Registries, "registries", fn)
+	for _, name := range config.ProfileNames() {
+		profile := config.Profiles[name]
+		mapRegConfigTargets(&profile, fmt.Sprintf("profiles.%s", name), fn)
+		config.Profiles[name] = profile
+	}
+}
+
+// mapRegConfigTargets replaces the target registry values of one registries section
+func mapRegConfigTargets(registries *RegConfig, prefix string, fn func(field, value string) string) {
+	for i := range registries.Mappings {
+		registries.Mappings[i].Target = fn(fmt.Sprintf("%s.mappings[%d].target", prefix, i), registries.Mappings[i].Target)
+	}
+	for i := range registries.Groups {
+		registries.Groups[i].Target = fn(fmt.Sprintf("%s.groups[%d].target", prefix, i), registries.Groups[i].Target)
+	}
+	if registries.DefaultTarget != "" {
+		registries.DefaultTarget = fn(prefix+".defaultTarget", registries.DefaultTarget)
+	}
+}
+
+// validateConfigKeepingEnv validates a config whose placeholders are kept unresolved, as if each
+// placeholder resolved to a valid registry host, and restores the placeholders afterwards.
+func validateConfigKeepingEnv(config *Config, path string) error {
+	var originals []string
+	mapConfigTargets(config, func(_, value string) string {
+		originals = append(originals, value)
+		return envPlaceholderPattern.ReplaceAllString(value, placeholderHost)
+	})
+	if err := validateStructuredConfig(config, path); err != nil {
+		return err
+	}
+	next := 0
+	mapConfigTargets(config, func(_, value string) string {
+		if next >= len(originals) {
+			return value
+		}
+		original := originals[next]
+		next++
+		return original
+	})
+	return nil
+}
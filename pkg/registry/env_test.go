@@ -0,0 +1,95 @@
+package registry
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const envConfig = `registries:
+  defaultTarget: ${TARGET_REGISTRY}/default
+  mappings:
+    - source: docker.io
+      target: ${TARGET_REGISTRY}/${DOCKER_PROJECT:-dockerhub}
+    - source: quay.io
+      target: harbor.local/quay
+  groups:
+    - name: k8s
+      target: ${TARGET_REGISTRY}/k8s
+      sources: [registry.k8s.io]
+profiles:
+  staging:
+    defaultTarget: ${STAGING_REGISTRY}/default
+`
+
+func TestParseConfigExpandsEnv(t *testing.T) {
+	t.Setenv("TARGET_REGISTRY", "harbor.prod.example.com:8443")
+	t.Setenv("DOCKER_PROJECT", "")
+	t.Setenv("STAGING_REGISTRY", "harbor.staging.example.com")
+
+	config, err := ParseConfig([]byte(envConfig), "registry-mappings.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "harbor.prod.example.com:8443/default", config.Registries.DefaultTarget)
+	assert.Equal(t, "harbor.prod.example.com:8443/dockerhub", config.Registries.Mappings[0].Target,
+		"an empty variable takes the default")
+	assert.Equal(t, "harbor.local/quay", config.Registries.Mappings[1].Target)
+	assert.Equal(t, "harbor.prod.example.com:8443/k8s", config.Registries.Groups[0].Target)
+	assert.Equal(t, "harbor.staging.example.com/default", config.Profiles["staging"].DefaultTarget)
+
+	t.Setenv("TARGET_REGISTRY", "harbor.prod.example.com:99999")
+	_, err = ParseConfig([]byte(envConfig), "registry-mappings.yaml")
+	assert.ErrorContains(t, err, "invalid port number", "resolved values are validated")
+}
+
+func TestParseConfigUnsetEnv(t *testing.T) {
+	t.Setenv("TARGET_REGISTRY", "")
+	t.Setenv("STAGING_REGISTRY", "")
+
+	_, err := ParseConfig([]byte(envConfig), "registry-mappings.yaml")
+	var unsetErr *ErrUnsetEnvVars
+	require.ErrorAs(t, err, &unsetErr)
+	assert.Equal(t, map[string][]string{
+		"TARGET_REGISTRY":  {"registries.mappings[0].target", "registries.groups[0].target", "registries.defaultTarget"},
+		"STAGING_REGISTRY": {"profiles.staging.defaultTarget"},
+	}, unsetErr.Variables)
+	assert.EqualError(t, err, "environment variables referenced in config file 'registry-mappings.yaml' are not set: "+
+		"STAGING_REGISTRY (used by profiles.staging.defaultTarget); "+
+		"TARGET_REGISTRY (used by registries.mappings[0].target, registries.groups[0].target, registries.defaultTarget); "+
+		"set them or give a default with ${NAME:-default}")
+	assert.Equal(t, exitcodes.ExitInputConfigurationError, exitcodes.Classify(fmt.Errorf("failed to load registry mappings: %w", err)).ExitCode)
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/registry-mappings.yaml", []byte(envConfig), 0o600))
+	_, err = LoadMappings(fs, "/registry-mappings.yaml", true)
+	require.ErrorAs(t, err, &unsetErr, "legacy mapping loads resolve placeholders too")
+}
+
+func TestParseConfigKeepingEnv(t *testing.T) {
+	t.Setenv("TARGET_REGISTRY", "")
+	t.Setenv("STAGING_REGISTRY", "")
+
+	config, err := ParseConfigKeepingEnv([]byte(envConfig), "registry-mappings.yaml")
+	require.NoError(t, err, "placeholders need no environment when they are kept")
+	assert.Equal(t, "${TARGET_REGISTRY}/default", config.Registries.DefaultTarget)
+	assert.Equal(t, "${TARGET_REGISTRY}/${DOCKER_PROJECT:-dockerhub}", config.Registries.Mappings[0].Target)
+	assert.True(t, config.Registries.Mappings[0].Enabled, "mapping defaults are applied")
+	assert.Equal(t, "${TARGET_REGISTRY}/k8s", config.Registries.Groups[0].Target)
+	assert.Equal(t, "${STAGING_REGISTRY}/default", config.Profiles["staging"].DefaultTarget)
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/registry-mappings.yaml", []byte(envConfig), 0o600))
+	config, err = LoadStructuredConfigKeepingEnv(fs, "/registry-mappings.yaml", true)
+	require.NoError(t, err)
+	assert.Equal(t, "${TARGET_REGISTRY}/k8s", config.Registries.Groups[0].Target)
+
+	_, err = ParseConfigKeepingEnv([]byte(`registries:
+  mappings:
+    - source: docker.io
+      target: ${TARGET_REGISTRY}
+`), "registry-mappings.yaml")
+	assert.ErrorContains(t, err, "must contain at least one '/'", "values are validated as if placeholders were hosts")
+}
@@ -183,3 +183,19 @@ func (e *ErrValueTooLong) Error() string {
 func WrapValueTooLong(path, key, value string, length, maxLength int) error {
 	return &ErrValueTooLong{Path: path, Key: key, Value: value, Length: length, Max: maxLength}
 }
+
+// ErrUnsupportedConfigVersion indicates a config file declares a version this irr cannot read.
+type ErrUnsupportedConfigVersion struct {
+	Path    string
+	Version string
+}
+
+func (e *ErrUnsupportedConfigVersion) Error() string {
+	return fmt.Sprintf("unsupported version %q in config file '%s': this version of irr reads config version %s and the unversioned legacy formats; newer config versions need a newer irr",
+		e.Version, e.Path, DefaultConfigVersion)
+}
+
+// WrapUnsupportedConfigVersion creates a new ErrUnsupportedConfigVersion error.
+func WrapUnsupportedConfigVersion(path, version string) error {
+	return &ErrUnsupportedConfigVersion{Path: path, Version: version}
+}
@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/afero"
+	"sigs.k8s.io/yaml"
+)
+
+// ChartExceptionsFile is the file in a chart directory listing the chart's relocation exceptions.
+const ChartExceptionsFile = ".irr-ignore"
+
+// RelocationException names images that must never be relocated, e.g. images that have to stay on
+// the vendor's registry for licensing. It matches either a values path or an image.
+type RelocationException struct {
+	// Path is a values path glob, matched like --exclude-pattern (e.g. enterprise.*.image)
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Image is a glob of image references without tag or digest (e.g. registry.vendor.com/**);
+	// * and ? match within one path segment and ** across segments
+	Image string `json:"image,omitempty" yaml:"image,omitempty"`
+	// Reason explains why the image must not be relocated
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// exceptionsFile is the format of ChartExceptionsFile
+type exceptionsFile struct {
+	Exceptions []RelocationException `json:"exceptions" yaml:"exceptions"`
+}
+
+// Validate checks that the exception sets exactly one of path and image, as a valid glob.
+func (e RelocationException) Validate() error {
+	switch {
+	case e.Path == "" && e.Image == "":
+		return errors.New("relocation exception must set path or image")
+	case e.Path != "" && e.Image != "":
+		return fmt.Errorf("relocation exception must set only one of path and image, got path %q and image %q", e.Path, e.Image)
+	case e.Path != "":
+		if _, err := path.Match(e.Path, ""); err != nil {
+			return fmt.Errorf("invalid path glob %q in relocation exception: %w", e.Path, err)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the exception covers the image at valuesPath. imageName is the image's
+// registry and repository, e.g. docker.io/library/nginx.
+func (e RelocationException) Matches(valuesPath, imageName string) bool {
+	if e.Path != "" {
+		match, err := filepath.Match(e.Path, valuesPath)
+		return err == nil && match
+	}
+	return imageGlobPattern(e.Image).MatchString(imageName)
+}
+
+// String describes the exception as it is written, for logs and reports.
+func (e RelocationException) String() string {
+	if e.Path != "" {
+		return "path=" + e.Path
+	}
+	return "image=" + e.Image
+}
+
+// MatchException returns the first of exceptions that covers the image at valuesPath.
+func MatchException(exceptions []RelocationException, valuesPath, imageName string) (RelocationException, bool) {
+	for _, exception := range exceptions {
+		if exception.Matches(valuesPath, imageName) {
+			return exception, true
+		}
+	}
+	return RelocationException{}, false
+}
+
+// LoadChartExceptions reads the relocation exceptions of the chart in chartDir from its
+// ChartExceptionsFile. A chart without the file, or packaged as an archive, has none.
+func LoadChartExceptions(fs afero.Fs, chartDir string) ([]RelocationException, error) {
+	if isDir, err := afero.IsDir(fs, chartDir); err != nil || !isDir {
+		return nil, nil //nolint:nilerr // a chart path that is not a directory has no exceptions file
+	}
+	filePath := filepath.Join(chartDir, ChartExceptionsFile)
+	data, err := afero.ReadFile(fs, filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read relocation exceptions file '%s': %w", filePath, err)
+	}
+	var file exceptionsFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse relocation exceptions file '%s': %w", filePath, err)
+	}
+	for i, exception := range file.Exceptions {
+		if err := exception.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid entry %d in relocation exceptions file '%s': %w", i+1, filePath, err)
+		}
+	}
+	return file.Exceptions, nil
+}
+
+// imageGlobPattern compiles an image glob: * and ? do not match '/', ** matches anything.
+func imageGlobPattern(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^/]*")
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
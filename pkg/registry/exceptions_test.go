@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelocationExceptionValidate(t *testing.T) {
+	assert.NoError(t, RelocationException{Path: "enterprise.*.image"}.Validate())
+	assert.NoError(t, RelocationException{Image: "registry.vendor.com/**"}.Validate())
+	assert.ErrorContains(t, RelocationException{Reason: "licensed"}.Validate(), "must set path or image")
+	assert.ErrorContains(t, RelocationException{Path: "image", Image: "nginx"}.Validate(), "only one of path and image")
+	assert.ErrorContains(t, RelocationException{Path: "image[", Reason: "bad"}.Validate(), "invalid path glob")
+}
+
+func TestMatchException(t *testing.T) {
+	exceptions := []RelocationException{
+		{Path: "enterprise.*.image", Reason: "licensed"},
+		{Image: "registry.vendor.com/**"},
+		{Image: "docker.io/vendor/*"},
+	}
+	tests := []struct {
+		name      string
+		path      string
+		image     string
+		wantMatch bool
+		want      string
+	}{
+		{name: "path glob", path: "enterprise.scanner.image", image: "docker.io/library/nginx", wantMatch: true, want: "path=enterprise.*.image"},
+		{name: "path glob does not match other paths", path: "community.scanner.image", image: "docker.io/library/nginx"},
+		{name: "double star crosses segments", path: "image", image: "registry.vendor.com/team/agent", wantMatch: true, want: "image=registry.vendor.com/**"},
+		{name: "single star stays in segment", path: "image", image: "docker.io/vendor/agent", wantMatch: true, want: "image=docker.io/vendor/*"},
+		{name: "single star does not cross segments", path: "image", image: "docker.io/vendor/team/agent"},
+		{name: "other image", path: "image", image: "docker.io/library/nginx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exception, ok := MatchException(exceptions, tt.path, tt.image)
+			assert.Equal(t, tt.wantMatch, ok)
+			if tt.wantMatch {
+				assert.Equal(t, tt.want, exception.String())
+			}
+		})
+	}
+}
+
+func TestLoadChartExceptions(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/charts/app/"+ChartExceptionsFile, []byte(`exceptions:
+  - image: registry.vendor.com/**
+    reason: licensed
+`), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/charts/bad/"+ChartExceptionsFile, []byte("exceptions:\n  - reason: licensed\n"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/charts/unknown/"+ChartExceptionsFile, []byte("images:\n  - nginx\n"), 0o644))
+	require.NoError(t, fs.MkdirAll("/charts/plain", 0o755))
+	require.NoError(t, afero.WriteFile(fs, "/charts/app-1.0.0.tgz", []byte("archive"), 0o644))
+
+	exceptions, err := LoadChartExceptions(fs, "/charts/app")
+	require.NoError(t, err)
+	assert.Equal(t, []RelocationException{{Image: "registry.vendor.com/**", Reason: "licensed"}}, exceptions)
+
+	exceptions, err = LoadChartExceptions(fs, "/charts/plain")
+	require.NoError(t, err)
+	assert.Nil(t, exceptions)
+
+	exceptions, err = LoadChartExceptions(fs, "/charts/app-1.0.0.tgz")
+	require.NoError(t, err)
+	assert.Nil(t, exceptions)
+
+	_, err = LoadChartExceptions(fs, "/charts/bad")
+	assert.ErrorContains(t, err, "invalid entry 1 in relocation exceptions file")
+
+	_, err = LoadChartExceptions(fs, "/charts/unknown")
+	assert.ErrorContains(t, err, "failed to parse relocation exceptions file")
+}
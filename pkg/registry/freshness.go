@@ -0,0 +1,474 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Tag freshness statuses reported in TagFreshness.Status.
+const (
+	// TagStatusCurrent means the tag exists and no newer semver tag was found
+	TagStatusCurrent = "current"
+	// TagStatusOutdated means the tag exists and the repository has a newer semver tag
+	TagStatusOutdated = "outdated"
+	// TagStatusMissing means the registry no longer has the tag
+	TagStatusMissing = "missing"
+	// TagStatusUnknown means the registry could not be queried
+	TagStatusUnknown = "unknown"
+)
+
+const (
+	// DefaultTagCheckTimeout bounds each request made while checking a tag.
+	DefaultTagCheckTimeout = 15 * time.Second
+	// DefaultStaleAfter is the age of the last push after which a tag is reported as stale.
+	DefaultStaleAfter = 365 * 24 * time.Hour
+	// maxTagPages bounds the pages of /tags/list read for one repository.
+	maxTagPages = 20
+	// dockerHubAPIURL is the Docker Hub API, which records when each tag was last pushed.
+	dockerHubAPIURL = "https://hub.docker.com"
+)
+
+// manifestMediaTypes are accepted when fetching manifests, image indexes first.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// TagFreshness describes how current the tag of an image is in its source registry.
+type TagFreshness struct {
+	// Status is one of the TagStatus constants
+	Status string `json:"status" yaml:"status"`
+	// LatestTag is the highest semver tag of the repository newer than the tag, if any. Only tags
+	// with the same pre-release suffix (e.g. "-alpine") are compared.
+	LatestTag string `json:"latestTag,omitempty" yaml:"latestTag,omitempty"`
+	// LastPushed is when the tag was last pushed (RFC 3339). Docker Hub records pushes; for other
+	// registries the creation time of the image config is used.
+	LastPushed string `json:"lastPushed,omitempty" yaml:"lastPushed,omitempty"`
+	// Stale is set when LastPushed is older than the checker's StaleAfter, hinting at an
+	// abandoned image
+	Stale bool `json:"stale,omitempty" yaml:"stale,omitempty"`
+	// Error explains an unknown status or a detail that could not be fetched
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// TagChecker queries source registries for the freshness of image tags. Tag lists and registry
+// tokens are cached, so a checker should be reused for all images of a run.
+type TagChecker struct {
+	// Client sends the registry requests; its Timeout should bound each request.
+	Client *http.Client
+	// Credentials holds the credentials found for each registry host.
+	Credentials map[string]Credentials
+	// Helpers resolves credentials held by credential helpers and cloud providers; may be nil.
+	Helpers *CredentialHelpers
+	// StaleAfter is the age of the last push after which a tag is stale; zero disables the check.
+	StaleAfter time.Duration
+	// Now returns the current time. It can be replaced in tests.
+	Now func() time.Time
+	// HubURL is the Docker Hub API queried for the push time of docker.io images.
+	HubURL string
+
+	mu     sync.Mutex
+	tags   map[string][]string
+	tokens map[string]string
+}
+
+// NewTagChecker creates a TagChecker with DefaultTagCheckTimeout and the given credentials.
+func NewTagChecker(credentials map[string]Credentials, staleAfter time.Duration) *TagChecker {
+	return &TagChecker{
+		Client:      &http.Client{Timeout: DefaultTagCheckTimeout},
+		Credentials: credentials,
+		StaleAfter:  staleAfter,
+		Now:         time.Now,
+		HubURL:      dockerHubAPIURL,
+	}
+}
+
+// Check reports the freshness of tag in repository on the registry host. Docker Hub images may be
+// given with the docker.io host and a repository without namespace.
+func (c *TagChecker) Check(ctx context.Context, host, repository, tag string) TagFreshness {
+	host, repository = registryEndpoint(host, repository)
+
+	exists, err := c.tagExists(ctx, host, repository, tag)
+	if err != nil {
+		return TagFreshness{Status: TagStatusUnknown, Error: err.Error()}
+	}
+	if !exists {
+		return TagFreshness{Status: TagStatusMissing}
+	}
+
+	result := TagFreshness{Status: TagStatusCurrent}
+	var problems []string
+	tags, err := c.repositoryTags(ctx, host, repository)
+	if err != nil {
+		problems = append(problems, err.Error())
+	} else if latest := newerSemverTag(tag, tags); latest != "" {
+		result.Status = TagStatusOutdated
+		result.LatestTag = latest
+	}
+
+	pushed, err := c.lastPushed(ctx, host, repository, tag)
+	switch {
+	case err != nil:
+		problems = append(problems, err.Error())
+	case !pushed.IsZero():
+		result.LastPushed = pushed.UTC().Format(time.RFC3339)
+		result.Stale = c.StaleAfter > 0 && c.now().Sub(pushed) > c.StaleAfter
+	}
+	result.Error = strings.Join(problems, "; ")
+	return result
+}
+
+// registryEndpoint returns the API host and repository path of an image: Docker Hub images are
+// served by registry-1.docker.io, with official images under library/.
+func registryEndpoint(host, repository string) (apiHost, repoPath string) {
+	host = strings.ToLower(host)
+	switch host {
+	case "", "docker.io", "index.docker.io", "registry-1.docker.io":
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+		return "registry-1.docker.io", repository
+	default:
+		return host, repository
+	}
+}
+
+// isDockerHub reports whether host is Docker Hub's registry endpoint.
+func isDockerHub(host string) bool {
+	return host == "registry-1.docker.io"
+}
+
+// newerSemverTag returns the highest tag of tags that is a semver version greater than current
+// with the same pre-release suffix, or "" if there is none or current is not a semver version.
+func newerSemverTag(current string, tags []string) string {
+	currentVersion, err := semver.NewVersion(current)
+	if err != nil {
+		return ""
+	}
+	var latest *semver.Version
+	latestTag := ""
+	for _, tag := range tags {
+		version, err := semver.NewVersion(tag)
+		if err != nil || version.Prerelease() != currentVersion.Prerelease() || !version.GreaterThan(currentVersion) {
+			continue
+		}
+		if latest == nil || version.GreaterThan(latest) {
+			latest = version
+			latestTag = tag
+		}
+	}
+	return latestTag
+}
+
+// tagExists reports whether the registry has a manifest for tag.
+func (c *TagChecker) tagExists(ctx context.Context, host, repository, tag string) (bool, error) {
+	resp, err := c.do(ctx, http.MethodHead, host, repository, "https://"+host+"/v2/"+repository+"/manifests/"+url.PathEscape(tag), manifestMediaTypes)
+	if err != nil {
+		return false, err
+	}
+	closeBody(resp)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status %s checking %s/%s:%s", resp.Status, host, repository, tag)
+	}
+}
+
+// repositoryTags returns the tags of a repository, following the pagination of /tags/list.
+func (c *TagChecker) repositoryTags(ctx context.Context, host, repository string) ([]string, error) {
+	key := host + "/" + repository
+	c.mu.Lock()
+	cached, ok := c.tags[key]
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	var tags []string
+	next := "https://" + host + "/v2/" + repository + "/tags/list?n=1000"
+	for page := 0; next != "" && page < maxTagPages; page++ {
+		resp, err := c.do(ctx, http.MethodGet, host, repository, next, nil)
+		if err != nil {
+			return nil, err
+		}
+		var list struct {
+			Tags []string `json:"tags"`
+		}
+		err = decodeResponse(resp, &list)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", key, err)
+		}
+		tags = append(tags, list.Tags...)
+		next = nextPage(resp, next)
+	}
+
+	c.mu.Lock()
+	if c.tags == nil {
+		c.tags = make(map[string][]string)
+	}
+	c.tags[key] = tags
+	c.mu.Unlock()
+	return tags, nil
+}
+
+// nextPage returns the URL of the next page named by the Link header of resp, or "".
+func nextPage(resp *http.Response, current string) string {
+	link := resp.Header.Get("Link")
+	if !strings.Contains(link, `rel="next"`) {
+		return ""
+	}
+	start, end := strings.Index(link, "<"), strings.Index(link, ">")
+	if start < 0 || end <= start {
+		return ""
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return ""
+	}
+	next, err := base.Parse(link[start+1 : end])
+	if err != nil {
+		return ""
+	}
+	return next.String()
+}
+
+// lastPushed returns when tag was last pushed: the push time recorded by Docker Hub, or the
+// creation time in the image config for other registries. A zero time means it is not known.
+func (c *TagChecker) lastPushed(ctx context.Context, host, repository, tag string) (time.Time, error) {
+	if isDockerHub(host) {
+		return c.dockerHubLastPushed(ctx, repository, tag)
+	}
+	return c.imageCreated(ctx, host, repository, tag)
+}
+
+// dockerHubLastPushed asks the Docker Hub API when tag was last pushed.
+func (c *TagChecker) dockerHubLastPushed(ctx context.Context, repository, tag string) (time.Time, error) {
+	target := strings.TrimSuffix(c.HubURL, "/") + "/v2/repositories/" + repository + "/tags/" + url.PathEscape(tag)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, http.NoBody)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid Docker Hub URL %s: %w", target, err)
+	}
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("request to %s failed: %w", target, err)
+	}
+	var info struct {
+		TagLastPushed time.Time `json:"tag_last_pushed"`
+	}
+	if err := decodeResponse(resp, &info); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get push time of %s:%s from Docker Hub: %w", repository, tag, err)
+	}
+	return info.TagLastPushed, nil
+}
+
+// imageCreated returns the creation time in the config of the image tagged tag. For an image
+// index, the linux/amd64 image (or the first one) is used.
+func (c *TagChecker) imageCreated(ctx context.Context, host, repository, tag string) (time.Time, error) {
+	type descriptor struct {
+		MediaType string `json:"mediaType"`
+		Digest    string `json:"digest"`
+		Platform  *struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		} `json:"platform"`
+	}
+	var manifest struct {
+		Config    descriptor   `json:"config"`
+		Manifests []descriptor `json:"manifests"`
+	}
+	getManifest := func(reference string) error {
+		target := "https://" + host + "/v2/" + repository + "/manifests/" + url.PathEscape(reference)
+		resp, err := c.do(ctx, http.MethodGet, host, repository, target, manifestMediaTypes)
+		if err != nil {
+			return err
+		}
+		return decodeResponse(resp, &manifest)
+	}
+
+	if err := getManifest(tag); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get manifest of %s/%s:%s: %w", host, repository, tag, err)
+	}
+	if len(manifest.Manifests) > 0 {
+		chosen := manifest.Manifests[0]
+		for _, m := range manifest.Manifests {
+			if m.Platform != nil && m.Platform.OS == "linux" && m.Platform.Architecture == "amd64" {
+				chosen = m
+				break
+			}
+		}
+		manifest.Manifests = nil
+		if err := getManifest(chosen.Digest); err != nil {
+			return time.Time{}, fmt.Errorf("failed to get manifest %s of %s/%s: %w", chosen.Digest, host, repository, err)
+		}
+	}
+	if manifest.Config.Digest == "" {
+		return time.Time{}, nil
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, host, repository, "https://"+host+"/v2/"+repository+"/blobs/"+manifest.Config.Digest, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var config struct {
+		Created time.Time `json:"created"`
+	}
+	if err := decodeResponse(resp, &config); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get image config of %s/%s:%s: %w", host, repository, tag, err)
+	}
+	return config.Created, nil
+}
+
+// do sends a request to a registry, authenticating for pulls from repository when the registry
+// answers 401: with basic auth, or with a token from the bearer realm of its challenge. Tokens are
+// cached per repository.
+func (c *TagChecker) do(ctx context.Context, method, host, repository, target string, accept []string) (*http.Response, error) {
+	tokenKey := host + "/" + repository
+	c.mu.Lock()
+	token := c.tokens[tokenKey]
+	c.mu.Unlock()
+
+	send := func(authorize func(*http.Request)) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, target, http.NoBody)
+		if err != nil {
+			return nil, fmt.Errorf("invalid registry URL %s: %w", target, err)
+		}
+		if len(accept) > 0 {
+			req.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+		if authorize != nil {
+			authorize(req)
+		}
+		resp, err := c.client().Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("request to %s failed: %w", target, err)
+		}
+		return resp, nil
+	}
+	bearer := func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+
+	var authorize func(*http.Request)
+	if token != "" {
+		authorize = bearer
+	}
+	resp, err := send(authorize)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	closeBody(resp)
+
+	creds, hasCreds := c.Helpers.Resolve(ctx, c.Credentials, host)
+	if hasCreds && creds.External {
+		hasCreds = false
+	}
+	scheme, params := parseChallenge(challenge)
+	switch {
+	case strings.EqualFold(scheme, "bearer"):
+		token, err = c.fetchToken(ctx, params, repository, creds, hasCreds)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		if c.tokens == nil {
+			c.tokens = make(map[string]string)
+		}
+		c.tokens[tokenKey] = token
+		c.mu.Unlock()
+		return send(bearer)
+	case strings.EqualFold(scheme, "basic") && hasCreds:
+		return send(func(req *http.Request) { req.SetBasicAuth(creds.Username, creds.Password) })
+	default:
+		return nil, fmt.Errorf("%s requires authentication and no credentials were found", host)
+	}
+}
+
+// fetchToken requests a pull token for repository from the realm of a bearer challenge, with
+// basic auth when credentials are available and anonymously otherwise.
+func (c *TagChecker) fetchToken(ctx context.Context, params map[string]string, repository string, creds Credentials, hasCreds bool) (string, error) {
+	return requestToken(ctx, c.client(), params, repository, "pull", creds, hasCreds)
+}
+
+// requestToken requests a token granting actions (e.g. "pull" or "pull,push") on repository from
+// the realm of a bearer challenge, with basic auth when credentials are available and anonymously
+// otherwise.
+func requestToken(ctx context.Context, client *http.Client, params map[string]string, repository, actions string, creds Credentials, hasCreds bool) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid bearer realm %q", params["realm"])
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", "repository:"+repository+":"+actions)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), http.NoBody)
+	if err != nil {
+		return "", fmt.Errorf("invalid token URL %s: %w", realm, err)
+	}
+	if hasCreds {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request to %s failed: %w", realm.Host, err)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := decodeResponse(resp, &body); err != nil {
+		return "", fmt.Errorf("failed to get registry token for %s: %w", repository, err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("no registry token returned for %s", repository)
+}
+
+// decodeResponse decodes the JSON body of a successful response into v and closes the body.
+func decodeResponse(resp *http.Response, v interface{}) error {
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// client returns the HTTP client of c.
+func (c *TagChecker) client() *http.Client {
+	if c.Client == nil {
+		return http.DefaultClient
+	}
+	return c.Client
+}
+
+// now returns the current time of c.
+func (c *TagChecker) now() time.Time {
+	if c.Now == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
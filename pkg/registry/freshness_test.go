@@ -0,0 +1,106 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTagRegistry starts a TLS registry serving library/app with tags 1.0.0 to 1.2.0 (listed on
+// two pages) behind an anonymous bearer token, and returns it with its host.
+func newTagRegistry(t *testing.T) (server *httptest.Server, host string) {
+	t.Helper()
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			assert.Equal(t, "repository:team/app:pull", r.URL.Query().Get("scope"))
+			_, _ = w.Write([]byte(`{"token": "pull-token"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer pull-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="https://`+r.Host+`/token",service="test-registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/team/app/manifests/1.1.0":
+			_, _ = w.Write([]byte(`{"manifests": [
+				{"digest": "sha256:arm", "platform": {"os": "linux", "architecture": "arm64"}},
+				{"digest": "sha256:amd", "platform": {"os": "linux", "architecture": "amd64"}}
+			]}`))
+		case "/v2/team/app/manifests/sha256:amd":
+			_, _ = w.Write([]byte(`{"config": {"digest": "sha256:config"}}`))
+		case "/v2/team/app/blobs/sha256:config":
+			_, _ = w.Write([]byte(`{"created": "2024-01-15T10:00:00Z"}`))
+		case "/v2/team/app/tags/list":
+			if r.URL.Query().Get("last") == "" {
+				w.Header().Set("Link", `</v2/team/app/tags/list?n=1000&last=1.1.0>; rel="next"`)
+				_, _ = w.Write([]byte(`{"tags": ["1.0.0", "1.1.0", "1.1.0-alpine"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"tags": ["1.2.0", "1.3.0-rc.1", "1.2.0-alpine", "latest"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, strings.TrimPrefix(server.URL, "https://")
+}
+
+func TestTagCheckerCheck(t *testing.T) {
+	server, host := newTagRegistry(t)
+	checker := NewTagChecker(nil, 180*24*time.Hour)
+	checker.Client = server.Client()
+	checker.Now = func() time.Time { return time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC) }
+
+	assert.Equal(t, TagFreshness{
+		Status:     TagStatusOutdated,
+		LatestTag:  "1.2.0",
+		LastPushed: "2024-01-15T10:00:00Z",
+		Stale:      true,
+	}, checker.Check(context.Background(), host, "team/app", "1.1.0"))
+
+	assert.Equal(t, TagFreshness{Status: TagStatusMissing}, checker.Check(context.Background(), host, "team/app", "0.9.0"))
+
+	unknown := checker.Check(context.Background(), "127.0.0.1:1", "team/app", "1.1.0")
+	assert.Equal(t, TagStatusUnknown, unknown.Status)
+	assert.NotEmpty(t, unknown.Error)
+}
+
+func TestNewerSemverTag(t *testing.T) {
+	tags := []string{"1.0.0", "1.2.0", "v1.10.1", "1.11.0-rc.1", "1.3.0-alpine", "1.9.0-alpine", "latest", "stable"}
+
+	assert.Equal(t, "v1.10.1", newerSemverTag("1.2.0", tags))
+	assert.Equal(t, "1.9.0-alpine", newerSemverTag("1.3.0-alpine", tags), "only tags with the same suffix are compared")
+	assert.Empty(t, newerSemverTag("v1.10.1", tags))
+	assert.Empty(t, newerSemverTag("latest", tags))
+}
+
+func TestRegistryEndpoint(t *testing.T) {
+	host, repository := registryEndpoint("docker.io", "nginx")
+	assert.Equal(t, "registry-1.docker.io", host)
+	assert.Equal(t, "library/nginx", repository)
+
+	host, repository = registryEndpoint("Quay.io", "prometheus/node-exporter")
+	assert.Equal(t, "quay.io", host)
+	assert.Equal(t, "prometheus/node-exporter", repository)
+}
+
+func TestDockerHubLastPushed(t *testing.T) {
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v2/repositories/library/nginx/tags/1.27", r.URL.Path)
+		_, _ = w.Write([]byte(`{"name": "1.27", "tag_last_pushed": "2025-06-01T08:30:00.123456Z"}`))
+	}))
+	t.Cleanup(hub.Close)
+	checker := NewTagChecker(nil, 0)
+	checker.HubURL = hub.URL
+
+	pushed, err := checker.lastPushed(context.Background(), "registry-1.docker.io", "library/nginx", "1.27")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 6, 1, 8, 30, 0, 123456000, time.UTC), pushed)
+}
@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RegGroup routes several source registries to one target registry, so that different groups of
+// sources can be relocated to entirely different targets (e.g. docker.io and quay.io to one Harbor
+// instance, gcr.io and registry.k8s.io to another).
+type RegGroup struct {
+	// Name identifies the group in error messages and generated configs
+	Name string `yaml:"name,omitempty"`
+	// Target is the target registry of every source in the group, either a bare registry host
+	// (e.g. harbor-a.example.com), whose paths then come from the path strategy, or a registry
+	// and path prefix (e.g. harbor-a.example.com/mirror)
+	Target string `yaml:"target"`
+	// Sources are the source registries routed to Target
+	Sources []string `yaml:"sources"`
+	// Description provides optional documentation about this group
+	Description string `yaml:"description,omitempty"`
+	// TagTransform is an optional template rewriting image tags for every source in the group
+	TagTransform string `yaml:"tagTransform,omitempty"`
+}
+
+// label returns how the group is referred to in error messages
+func (g *RegGroup) label(index int) string {
+	if g.Name != "" {
+		return fmt.Sprintf("group %q", g.Name)
+	}
+	return fmt.Sprintf("group at index %d", index)
+}
+
+// validateRegGroups validates the groups of a registries section. seenSources holds the sources
+// of the section's mappings; a source may only be routed once across mappings and groups.
+func validateRegGroups(groups []RegGroup, seenSources map[string]bool, path string) error {
+	for i := range groups {
+		group := &groups[i]
+		if group.Target == "" {
+			return fmt.Errorf("empty target registry in %s in config file '%s'", group.label(i), path)
+		}
+		if len(group.Sources) == 0 {
+			return fmt.Errorf("%s has no sources in config file '%s'", group.label(i), path)
+		}
+		for _, source := range group.Sources {
+			if source == "" {
+				return fmt.Errorf("empty source registry in %s in config file '%s'", group.label(i), path)
+			}
+			if seenSources[source] {
+				return WrapDuplicateRegistryKey(path, source)
+			}
+			seenSources[source] = true
+			if len(source) > MaxKeyLength {
+				return WrapKeyTooLong(path, source, len(source), MaxKeyLength)
+			}
+			if !isValidDomain(source) {
+				return fmt.Errorf("invalid source registry domain '%s' in config file '%s'", source, path)
+			}
+		}
+		if err := validateGroupTarget(group, path); err != nil {
+			return fmt.Errorf("invalid target in %s: %w", group.label(i), err)
+		}
+		if group.TagTransform != "" {
+			if err := ValidateTagTransform(group.TagTransform); err != nil {
+				return fmt.Errorf("invalid tagTransform in %s in config file '%s': %w", group.label(i), path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateGroupTarget validates a group target, which unlike a mapping target may be a bare
+// registry host with an optional port
+func validateGroupTarget(group *RegGroup, path string) error {
+	source := strings.Join(group.Sources, ",")
+	if strings.Contains(group.Target, "/") {
+		return validateMappingValue(source, group.Target, path)
+	}
+	if len(group.Target) > MaxValueLength {
+		return WrapValueTooLong(path, source, group.Target, len(group.Target), MaxValueLength)
+	}
+	host, port, hasPort := strings.Cut(group.Target, ":")
+	if hasPort {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return WrapInvalidPortNumber(path, source, group.Target, port)
+		}
+	}
+	if host != "localhost" && !isValidDomain(host) {
+		return fmt.Errorf("invalid target registry '%s' in config file '%s'", group.Target, path)
+	}
+	return nil
+}
+
+// groupMappings expands groups into one mapping per source
+func groupMappings(groups []RegGroup) []Mapping {
+	var entries []Mapping
+	for _, group := range groups {
+		for _, source := range group.Sources {
+			entries = append(entries, Mapping{
+				Source:       source,
+				Target:       group.Target,
+				TagTransform: group.TagTransform,
+			})
+		}
+	}
+	return entries
+}
+
+// withoutSources returns groups with the given sources removed, dropping groups left without sources
+func withoutSources(groups []RegGroup, sources map[string]bool) []RegGroup {
+	if len(sources) == 0 {
+		return groups
+	}
+	kept := make([]RegGroup, 0, len(groups))
+	for _, group := range groups {
+		remaining := make([]string, 0, len(group.Sources))
+		for _, source := range group.Sources {
+			if !sources[source] {
+				remaining = append(remaining, source)
+			}
+		}
+		if len(remaining) == 0 {
+			continue
+		}
+		group.Sources = remaining
+		kept = append(kept, group)
+	}
+	return kept
+}
@@ -0,0 +1,119 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const groupsConfig = `registries:
+  defaultTarget: harbor-a.example.com/default
+  mappings:
+    - source: ghcr.io
+      target: harbor-a.example.com/github
+  groups:
+    - name: public
+      target: harbor-a.example.com
+      sources: [docker.io, quay.io]
+    - name: google
+      target: harbor-b.example.com/google
+      tagTransform: "mirror-{{ .Tag }}"
+      sources: [gcr.io, registry.k8s.io]
+profiles:
+  prod:
+    groups:
+      - name: prod
+        target: harbor-prod.example.com:8443
+        sources: [quay.io, ghcr.io]
+`
+
+func TestLoadGroups(t *testing.T) {
+	config, err := loadProfilesConfig(t, groupsConfig)
+	require.NoError(t, err)
+	assert.Equal(t, []Mapping{
+		{Source: "ghcr.io", Target: "harbor-a.example.com/github"},
+		{Source: "docker.io", Target: "harbor-a.example.com"},
+		{Source: "quay.io", Target: "harbor-a.example.com"},
+		{Source: "gcr.io", Target: "harbor-b.example.com/google", TagTransform: "mirror-{{ .Tag }}"},
+		{Source: "registry.k8s.io", Target: "harbor-b.example.com/google", TagTransform: "mirror-{{ .Tag }}"},
+	}, config.ToMappings().Entries)
+	assert.Equal(t, "harbor-b.example.com/google", config.ToMappings().GetTargetRegistry("registry.k8s.io"))
+}
+
+func TestLoadGroupsOnlyConfig(t *testing.T) {
+	config, err := loadProfilesConfig(t, `registries:
+  strictMode: true
+  groups:
+    - target: harbor-a.example.com
+      sources: [docker.io]
+`)
+	require.NoError(t, err)
+	assert.Empty(t, config.Registries.Mappings)
+	assert.Equal(t, []Mapping{{Source: "docker.io", Target: "harbor-a.example.com"}}, config.ToMappings().Entries)
+}
+
+func TestApplyProfileGroups(t *testing.T) {
+	config, err := loadProfilesConfig(t, groupsConfig)
+	require.NoError(t, err)
+
+	require.NoError(t, config.ApplyProfile("prod"))
+	assert.Equal(t, []Mapping{
+		{Source: "docker.io", Target: "harbor-a.example.com"},
+		{Source: "gcr.io", Target: "harbor-b.example.com/google", TagTransform: "mirror-{{ .Tag }}"},
+		{Source: "registry.k8s.io", Target: "harbor-b.example.com/google", TagTransform: "mirror-{{ .Tag }}"},
+		{Source: "quay.io", Target: "harbor-prod.example.com:8443"},
+		{Source: "ghcr.io", Target: "harbor-prod.example.com:8443"},
+	}, config.ToMappings().Entries)
+}
+
+func TestLoadInvalidGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		groups  string
+		wantErr string
+	}{
+		{
+			name:    "missing target",
+			groups:  "    - name: public\n      sources: [docker.io]\n",
+			wantErr: `empty target registry in group "public"`,
+		},
+		{
+			name:    "no sources",
+			groups:  "    - target: harbor-a.example.com\n",
+			wantErr: "group at index 0 has no sources",
+		},
+		{
+			name:    "source already mapped",
+			groups:  "    - target: harbor-a.example.com\n      sources: [ghcr.io]\n",
+			wantErr: "duplicate registry key",
+		},
+		{
+			name:    "source in two groups",
+			groups:  "    - target: harbor-a.example.com\n      sources: [docker.io]\n    - target: harbor-b.example.com\n      sources: [docker.io]\n",
+			wantErr: "duplicate registry key",
+		},
+		{
+			name:    "invalid source",
+			groups:  "    - target: harbor-a.example.com\n      sources: [not_a_domain]\n",
+			wantErr: "invalid source registry domain",
+		},
+		{
+			name:    "invalid target port",
+			groups:  "    - target: harbor-a.example.com:99999\n      sources: [docker.io]\n",
+			wantErr: "invalid target in group at index 0",
+		},
+		{
+			name:    "invalid tag transform",
+			groups:  "    - name: public\n      target: harbor-a.example.com\n      tagTransform: \"{{ .Nope\"\n      sources: [docker.io]\n",
+			wantErr: `invalid tagTransform in group "public"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadProfilesConfig(t, "registries:\n  mappings:\n    - source: ghcr.io\n      target: harbor-a.example.com/github\n  groups:\n"+tt.groups)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
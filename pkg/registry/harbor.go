@@ -0,0 +1,219 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+)
+
+// Harbor output formats accepted by RenderHarborProjects.
+const (
+	HarborFormatTerraform = "terraform"
+	HarborFormatAPIJSON   = "api-json"
+)
+
+// HarborProxyProject describes a Harbor proxy-cache project and the upstream registry endpoint it
+// pulls through to.
+type HarborProxyProject struct {
+	// Project is the Harbor project name, i.e. the first path component of rewritten images.
+	Project string `json:"project"`
+	// SourceRegistry is the upstream registry the project caches.
+	SourceRegistry string `json:"sourceRegistry"`
+	// EndpointType is the Harbor API registry type (e.g. docker-hub, quay, docker-registry).
+	EndpointType string `json:"endpointType"`
+	// ProviderName is the goharbor/harbor Terraform provider_name for the endpoint.
+	ProviderName string `json:"providerName"`
+	// EndpointURL is the upstream URL Harbor pulls from.
+	EndpointURL string `json:"endpointUrl"`
+	// MappingTarget is the registry mapping target that routes images into the project.
+	MappingTarget string `json:"mappingTarget"`
+}
+
+// harborEndpoint holds the Harbor API type, Terraform provider name and URL for a well-known upstream.
+type harborEndpoint struct {
+	endpointType string
+	providerName string
+	url          string
+}
+
+var knownHarborEndpoints = map[string]harborEndpoint{
+	DockerHubRegistry: {endpointType: "docker-hub", providerName: "docker-hub", url: "https://hub.docker.com"},
+	"quay.io":         {endpointType: "quay", providerName: "quay", url: "https://quay.io"},
+	"ghcr.io":         {endpointType: "github-ghcr", providerName: "github", url: "https://ghcr.io"},
+	"gcr.io":          {endpointType: "google-gcr", providerName: "google", url: "https://gcr.io"},
+}
+
+// harborEndpointFor returns the endpoint settings for an upstream registry, falling back to a generic
+// Docker registry endpoint.
+func harborEndpointFor(source string) harborEndpoint {
+	if endpoint, ok := knownHarborEndpoints[source]; ok {
+		return endpoint
+	}
+	if strings.HasSuffix(source, ".gcr.io") {
+		return harborEndpoint{endpointType: "google-gcr", providerName: "google", url: "https://" + source}
+	}
+	return harborEndpoint{endpointType: "docker-registry", providerName: "docker-registry", url: "https://" + source}
+}
+
+// PlanHarborProxyProjects returns one proxy-cache project per source registry on harborHost, named so
+// that images rewritten by the prefix-source-registry strategy land in it: a mapping target of
+// harborHost/PROJECT uses PROJECT, and an unmapped source (or a mapping to the bare host) uses the
+// source registry name. Unmapped sources get harborHost/PROJECT as their suggested mapping target.
+// Sources mapped to a different host are returned in skipped. Sources that resolve to the same
+// project are merged, keeping the first.
+func PlanHarborProxyProjects(sources []string, mappings *Mappings, harborHost string) (projects []HarborProxyProject, skipped []string) {
+	harborHost = strings.TrimSuffix(harborHost, "/")
+	seenSources := make(map[string]bool, len(sources))
+	seenProjects := make(map[string]bool, len(sources))
+
+	for _, raw := range sources {
+		source := strings.ToLower(strings.TrimSpace(raw))
+		if image.NormalizeRegistry(source) == DockerHubRegistry {
+			source = DockerHubRegistry
+		}
+		if source == "" || seenSources[source] {
+			continue
+		}
+		seenSources[source] = true
+
+		project := image.SanitizeRegistryForPath(source)
+		mappingTarget := harborHost + "/" + project
+		if target := mappings.GetTargetRegistry(source); target != "" {
+			host, path, _ := strings.Cut(target, "/")
+			if host != harborHost {
+				skipped = append(skipped, source)
+				continue
+			}
+			if path != "" {
+				project, _, _ = strings.Cut(path, "/")
+			}
+			mappingTarget = target
+		}
+		if seenProjects[project] {
+			continue
+		}
+		seenProjects[project] = true
+
+		endpoint := harborEndpointFor(source)
+		projects = append(projects, HarborProxyProject{
+			Project:        project,
+			SourceRegistry: source,
+			EndpointType:   endpoint.endpointType,
+			ProviderName:   endpoint.providerName,
+			EndpointURL:    endpoint.url,
+			MappingTarget:  mappingTarget,
+		})
+	}
+
+	slices.SortFunc(projects, func(a, b HarborProxyProject) int { return strings.Compare(a.Project, b.Project) })
+	return projects, skipped
+}
+
+// RenderHarborProjects renders the projects for the Harbor instance at harborURL in the given format.
+func RenderHarborProjects(format, harborURL string, projects []HarborProxyProject) ([]byte, error) {
+	switch format {
+	case HarborFormatTerraform:
+		return renderHarborTerraform(harborURL, projects), nil
+	case HarborFormatAPIJSON:
+		return renderHarborAPIJSON(harborURL, projects)
+	default:
+		return nil, fmt.Errorf("unsupported Harbor output format %q (expected %s or %s)", format, HarborFormatTerraform, HarborFormatAPIJSON)
+	}
+}
+
+// terraformIdentifier turns a project name into a Terraform resource name.
+func terraformIdentifier(name string) string {
+	identifier := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if identifier == "" || (identifier[0] >= '0' && identifier[0] <= '9') {
+		identifier = "p_" + identifier
+	}
+	return identifier
+}
+
+func renderHarborTerraform(harborURL string, projects []HarborProxyProject) []byte {
+	var b strings.Builder
+	b.WriteString("# Harbor proxy-cache projects generated by irr.\n")
+	b.WriteString("# Each project pulls through to one upstream registry; mapping targets are noted per project.\n\n")
+	b.WriteString("terraform {\n  required_providers {\n    harbor = {\n      source = \"goharbor/harbor\"\n    }\n  }\n}\n\n")
+	fmt.Fprintf(&b, "provider \"harbor\" {\n  url = %q\n}\n", harborURL)
+
+	for _, project := range projects {
+		id := terraformIdentifier(project.Project)
+		fmt.Fprintf(&b, "\n# %s -> %s\n", project.SourceRegistry, project.MappingTarget)
+		fmt.Fprintf(&b, "resource \"harbor_registry\" %q {\n", id)
+		fmt.Fprintf(&b, "  provider_name = %q\n", project.ProviderName)
+		fmt.Fprintf(&b, "  name          = %q\n", project.SourceRegistry)
+		fmt.Fprintf(&b, "  endpoint_url  = %q\n", project.EndpointURL)
+		b.WriteString("}\n\n")
+		fmt.Fprintf(&b, "resource \"harbor_project\" %q {\n", id)
+		fmt.Fprintf(&b, "  name        = %q\n", project.Project)
+		fmt.Fprintf(&b, "  registry_id = harbor_registry.%s.registry_id\n", id)
+		b.WriteString("  public      = true\n")
+		b.WriteString("}\n")
+	}
+	return []byte(b.String())
+}
+
+// harborAPIRegistry is the body of POST /api/v2.0/registries.
+type harborAPIRegistry struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	URL      string `json:"url"`
+	Insecure bool   `json:"insecure"`
+}
+
+// harborAPIProject is the body of POST /api/v2.0/projects. RegistryName identifies the registry
+// endpoint whose ID must be filled into registry_id once the endpoint has been created.
+type harborAPIProject struct {
+	ProjectName  string            `json:"project_name"`
+	RegistryName string            `json:"registry_name"`
+	RegistryID   *int64            `json:"registry_id"`
+	Metadata     map[string]string `json:"metadata"`
+}
+
+// harborAPIMapping is the registry mapping that routes a source registry into its project.
+type harborAPIMapping struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+func renderHarborAPIJSON(harborURL string, projects []HarborProxyProject) ([]byte, error) {
+	doc := struct {
+		HarborURL  string              `json:"harborUrl"`
+		Registries []harborAPIRegistry `json:"registries"`
+		Projects   []harborAPIProject  `json:"projects"`
+		Mappings   []harborAPIMapping  `json:"mappings"`
+	}{
+		HarborURL:  harborURL,
+		Registries: make([]harborAPIRegistry, 0, len(projects)),
+		Projects:   make([]harborAPIProject, 0, len(projects)),
+		Mappings:   make([]harborAPIMapping, 0, len(projects)),
+	}
+	for _, project := range projects {
+		doc.Registries = append(doc.Registries, harborAPIRegistry{
+			Name: project.SourceRegistry,
+			Type: project.EndpointType,
+			URL:  project.EndpointURL,
+		})
+		doc.Projects = append(doc.Projects, harborAPIProject{
+			ProjectName:  project.Project,
+			RegistryName: project.SourceRegistry,
+			Metadata:     map[string]string{"public": "true"},
+		})
+		doc.Mappings = append(doc.Mappings, harborAPIMapping{Source: project.SourceRegistry, Target: project.MappingTarget})
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Harbor API payloads: %w", err)
+	}
+	return append(data, '\n'), nil
+}
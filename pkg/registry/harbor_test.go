@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanHarborProxyProjects(t *testing.T) {
+	mappings := &Mappings{Entries: []Mapping{
+		{Source: "quay.io", Target: "harbor.local/quay"},
+		{Source: "ghcr.io", Target: "other.local/github"},
+		{Source: "registry.k8s.io", Target: "harbor.local"},
+	}}
+
+	projects, skipped := PlanHarborProxyProjects(
+		[]string{"index.docker.io", "docker.io", "quay.io", "ghcr.io", "registry.k8s.io", "localhost:5000"},
+		mappings, "harbor.local")
+
+	assert.Equal(t, []string{"ghcr.io"}, skipped)
+	assert.Equal(t, []HarborProxyProject{
+		{Project: "docker.io", SourceRegistry: "docker.io", EndpointType: "docker-hub", ProviderName: "docker-hub",
+			EndpointURL: "https://hub.docker.com", MappingTarget: "harbor.local/docker.io"},
+		{Project: "localhost", SourceRegistry: "localhost:5000", EndpointType: "docker-registry", ProviderName: "docker-registry",
+			EndpointURL: "https://localhost:5000", MappingTarget: "harbor.local/localhost"},
+		{Project: "quay", SourceRegistry: "quay.io", EndpointType: "quay", ProviderName: "quay",
+			EndpointURL: "https://quay.io", MappingTarget: "harbor.local/quay"},
+		{Project: "registry.k8s.io", SourceRegistry: "registry.k8s.io", EndpointType: "docker-registry", ProviderName: "docker-registry",
+			EndpointURL: "https://registry.k8s.io", MappingTarget: "harbor.local"},
+	}, projects)
+}
+
+func TestRenderHarborProjects(t *testing.T) {
+	projects, _ := PlanHarborProxyProjects([]string{"docker.io"}, nil, "harbor.local")
+
+	t.Run("terraform", func(t *testing.T) {
+		data, err := RenderHarborProjects(HarborFormatTerraform, "https://harbor.local", projects)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `url = "https://harbor.local"`)
+		assert.Contains(t, string(data), `resource "harbor_registry" "docker_io" {
+  provider_name = "docker-hub"
+  name          = "docker.io"
+  endpoint_url  = "https://hub.docker.com"
+}`)
+		assert.Contains(t, string(data), `resource "harbor_project" "docker_io" {
+  name        = "docker.io"
+  registry_id = harbor_registry.docker_io.registry_id
+  public      = true
+}`)
+	})
+
+	t.Run("api-json", func(t *testing.T) {
+		data, err := RenderHarborProjects(HarborFormatAPIJSON, "https://harbor.local", projects)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+  "harborUrl": "https://harbor.local",
+  "registries": [{"name": "docker.io", "type": "docker-hub", "url": "https://hub.docker.com", "insecure": false}],
+  "projects": [{"project_name": "docker.io", "registry_name": "docker.io", "registry_id": null, "metadata": {"public": "true"}}],
+  "mappings": [{"source": "docker.io", "target": "harbor.local/docker.io"}]
+}`, string(data))
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := RenderHarborProjects("helm", "https://harbor.local", projects)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/lucas-albers-lz4/irr/pkg/limits"
+)
+
+// LimitsConfig is the limits section of a registry config. It bounds the values analyzed for each
+// chart like the --max-values-size, --max-values-depth and --max-image-patterns flags, which take
+// precedence. Unset fields keep the default limits.
+type LimitsConfig struct {
+	// MaxValuesSize is the maximum size of a values document, in bytes or with a Ki, Mi or Gi suffix
+	MaxValuesSize string `yaml:"maxValuesSize,omitempty"`
+	// MaxDepth is the maximum nesting depth of maps and lists in values
+	MaxDepth int `yaml:"maxDepth,omitempty"`
+	// MaxImagePatterns is the maximum number of image patterns detected in a chart
+	MaxImagePatterns int `yaml:"maxImagePatterns,omitempty"`
+}
+
+// Limits returns the limits of the section. Unset fields are zero, meaning the default limit.
+func (c LimitsConfig) Limits() (limits.Limits, error) {
+	var l limits.Limits
+	if c.MaxValuesSize != "" {
+		size, err := limits.ParseSize(c.MaxValuesSize)
+		if err != nil {
+			return limits.Limits{}, fmt.Errorf("maxValuesSize: %w", err)
+		}
+		l.MaxValuesSize = size
+	}
+	if c.MaxDepth < 0 {
+		return limits.Limits{}, errors.New("maxDepth must be positive")
+	}
+	if c.MaxImagePatterns < 0 {
+		return limits.Limits{}, errors.New("maxImagePatterns must be positive")
+	}
+	l.MaxDepth = c.MaxDepth
+	l.MaxImagePatterns = c.MaxImagePatterns
+	return l, nil
+}
+
+// Merge returns c with the fields that other sets replaced by those of other.
+func (c LimitsConfig) Merge(other LimitsConfig) LimitsConfig {
+	if other.MaxValuesSize != "" {
+		c.MaxValuesSize = other.MaxValuesSize
+	}
+	if other.MaxDepth != 0 {
+		c.MaxDepth = other.MaxDepth
+	}
+	if other.MaxImagePatterns != 0 {
+		c.MaxImagePatterns = other.MaxImagePatterns
+	}
+	return c
+}
+
+// settings returns the fields c sets by their YAML key, formatted for merge conflicts.
+func (c LimitsConfig) settings() map[string]string {
+	settings := make(map[string]string)
+	if c.MaxValuesSize != "" {
+		settings["maxValuesSize"] = c.MaxValuesSize
+	}
+	if c.MaxDepth != 0 {
+		settings["maxDepth"] = strconv.Itoa(c.MaxDepth)
+	}
+	if c.MaxImagePatterns != 0 {
+		settings["maxImagePatterns"] = strconv.Itoa(c.MaxImagePatterns)
+	}
+	return settings
+}
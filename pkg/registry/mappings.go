@@ -36,8 +36,9 @@ const (
 
 // Mapping represents a single source to target registry mapping
 type Mapping struct {
-	Source string `yaml:"source"`
-	Target string `yaml:"target"`
+	Source       string `yaml:"source"`
+	Target       string `yaml:"target"`
+	TagTransform string `yaml:"tagTransform,omitempty"`
 }
 
 // Mappings holds a collection of registry mappings
@@ -75,6 +76,16 @@ func LoadMappings(fs afero.Fs, path string, skipCWDRestriction bool) (*Mappings,
 
 	log.Debug("LoadMappings: Attempting to parse file content:\n%s", string(data))
 
+	// Upgrade older versions and legacy formats to the current structured format
+	data, err = currentConfigData(data, path)
+	if err != nil {
+		var versionErr *ErrUnsupportedConfigVersion
+		if errors.As(err, &versionErr) {
+			return nil, err
+		}
+		return nil, WrapMappingFileParse(path, err)
+	}
+
 	// Parse as structured format
 	var config Config
 	if err := yaml.Unmarshal(data, &config); err != nil {
@@ -82,6 +93,10 @@ func LoadMappings(fs afero.Fs, path string, skipCWDRestriction bool) (*Mappings,
 		return nil, WrapMappingFileParse(path, err)
 	}
 
+	if err := expandConfigEnv(&config, path, os.LookupEnv); err != nil {
+		return nil, err
+	}
+
 	// Validate the parsed config
 	if err := validateStructuredConfig(&config, path); err != nil {
 		log.Debug("LoadMappings: Structured config parsed but failed validation: %v", err)
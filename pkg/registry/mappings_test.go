@@ -108,6 +108,17 @@ func TestLoadMappings(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid mappings file (legacy format)",
+			path: "/tmp/valid-legacy.yaml",
+			wantMappings: &Mappings{
+				Entries: []Mapping{
+					{Source: "quay.io", Target: "my-registry.example.com/quay-mirror"},
+					{Source: "docker.io", Target: "my-registry.example.com/docker-mirror"},
+				},
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -138,7 +149,7 @@ registries:
 `
 				err := afero.WriteFile(fs, tt.path, []byte(content), fileutil.ReadWriteUserPermission)
 				require.NoError(t, err)
-			case "valid mappings file (legacy format)": // Assuming this test case exists or will be added
+			case "valid mappings file (legacy format)":
 				content := `
 quay.io: my-registry.example.com/quay-mirror
 docker.io: my-registry.example.com/docker-mirror
@@ -0,0 +1,244 @@
+package registry
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/strictness"
+	"github.com/spf13/afero"
+)
+
+// ConfigConflict is a setting that two merged registry configs define differently. The later
+// config's value wins.
+type ConfigConflict struct {
+	// Setting names the setting (e.g. "mapping docker.io" or "defaultTarget")
+	Setting string
+	// Earlier and Later are the conflicting values; both are empty for a redefined profile
+	Earlier string
+	Later   string
+	// EarlierSource and LaterSource name the configs the values come from
+	EarlierSource string
+	LaterSource   string
+}
+
+// String describes the conflict.
+func (c ConfigConflict) String() string {
+	if c.Earlier == "" && c.Later == "" {
+		return fmt.Sprintf("%s: redefined in %s, replacing the definition from %s", c.Setting, c.LaterSource, c.EarlierSource)
+	}
+	return fmt.Sprintf("%s: %q from %s overrides %q from %s", c.Setting, c.Later, c.LaterSource, c.Earlier, c.EarlierSource)
+}
+
+// ConfigFiles expands paths into the registry config files to merge: files are kept as given and
+// directories are replaced by their *.yaml and *.yml files, sorted by name. Subdirectories are not
+// searched.
+func ConfigFiles(fs afero.Fs, paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		info, err := fs.Stat(path)
+		if err != nil || !info.IsDir() {
+			// Missing files are reported when they are loaded
+			files = append(files, path)
+			continue
+		}
+		entries, err := afero.ReadDir(fs, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read registry config directory '%s': %w", path, err)
+		}
+		var found []string
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				found = append(found, filepath.Join(path, entry.Name()))
+			}
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("registry config directory '%s' contains no .yaml or .yml files", path)
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// MergeConfigs merges registry configs in order, sources naming the file of each. Each config is
+// layered over the merge of those before it the way a profile is applied (see ApplyProfile):
+// mappings replace earlier mappings with the same source, groups take over their sources, and
+// defaultTarget and defaultRegistry replace the earlier ones when set. Profiles and the TLS
+// settings of a registry host replace earlier ones with the same name or host, policy actions,
+// limits and encryption settings set later replace earlier ones, relocation exceptions are combined, strictMode and compatibility
+// flags are enabled if any config enables them, and the last version set is kept.
+// Settings that a later config changes are returned as conflicts. The configs are not modified.
+func MergeConfigs(configs []*Config, sources []string) (*Config, []ConfigConflict) {
+	merged := &Config{}
+	var conflicts []ConfigConflict
+	origins := make(map[string]string)
+	for i, config := range configs {
+		if config == nil {
+			continue
+		}
+		source := fmt.Sprintf("config %d", i+1)
+		if i < len(sources) {
+			source = sources[i]
+		}
+		record := func(setting, earlier, later string) {
+			if earlierSource, ok := origins[setting]; ok && earlier != later {
+				conflicts = append(conflicts, ConfigConflict{
+					Setting: setting, Earlier: earlier, Later: later, EarlierSource: earlierSource, LaterSource: source,
+				})
+			}
+			origins[setting] = source
+		}
+
+		targets := sourceTargets(merged.Registries)
+		for _, mapping := range config.Registries.Mappings {
+			record("mapping "+mapping.Source, targets[mapping.Source], describeTarget(mapping.Target, mapping.TagTransform))
+		}
+		for _, group := range config.Registries.Groups {
+			for _, groupSource := range group.Sources {
+				record("mapping "+groupSource, targets[groupSource], describeTarget(group.Target, group.TagTransform))
+			}
+		}
+		if config.Registries.DefaultTarget != "" {
+			record("defaultTarget", merged.Registries.DefaultTarget, config.Registries.DefaultTarget)
+		}
+		if config.Registries.DefaultRegistry != "" {
+			record("defaultRegistry", merged.Registries.DefaultRegistry, config.Registries.DefaultRegistry)
+		}
+		for _, name := range config.ProfileNames() {
+			setting := "profile " + name
+			if earlier, exists := merged.Profiles[name]; exists && !reflect.DeepEqual(earlier, config.Profiles[name]) {
+				conflicts = append(conflicts, ConfigConflict{Setting: setting, EarlierSource: origins[setting], LaterSource: source})
+			}
+			origins[setting] = source
+		}
+		for _, condition := range strictness.Conditions() {
+			if action := policyAction(config.Policy, condition); action != "" {
+				record("policy "+string(condition), string(policyAction(merged.Policy, condition)), string(action))
+			}
+		}
+		for _, host := range slices.Sorted(maps.Keys(config.TLS)) {
+			record("tls "+host, merged.TLS[host].String(), config.TLS[host].String())
+		}
+		if config.Limits != nil {
+			earlier := map[string]string{}
+			if merged.Limits != nil {
+				earlier = merged.Limits.settings()
+			}
+			settings := config.Limits.settings()
+			for _, name := range slices.Sorted(maps.Keys(settings)) {
+				record("limits "+name, earlier[name], settings[name])
+			}
+		}
+		if config.Encryption != nil {
+			earlier := map[string]string{}
+			if merged.Encryption != nil {
+				earlier = merged.Encryption.settings()
+			}
+			settings := config.Encryption.settings()
+			for _, name := range slices.Sorted(maps.Keys(settings)) {
+				record("encryption "+name, earlier[name], settings[name])
+			}
+		}
+
+		merged.Registries = cloneRegConfig(merged.Registries)
+		merged.Registries.layer(config.Registries)
+		if len(config.Profiles) > 0 {
+			if merged.Profiles == nil {
+				merged.Profiles = make(map[string]RegConfig)
+			}
+			maps.Copy(merged.Profiles, config.Profiles)
+		}
+		if len(config.TLS) > 0 {
+			tlsConfigs := maps.Clone(merged.TLS)
+			if tlsConfigs == nil {
+				tlsConfigs = make(map[string]TLSConfig)
+			}
+			maps.Copy(tlsConfigs, config.TLS)
+			merged.TLS = tlsConfigs
+		}
+		merged.Exceptions = append(slices.Clip(merged.Exceptions), config.Exceptions...)
+		if config.Policy != nil {
+			policy := strictness.Policy{}
+			if merged.Policy != nil {
+				policy = *merged.Policy
+			}
+			policy = policy.Merge(*config.Policy)
+			merged.Policy = &policy
+		}
+		if config.Limits != nil {
+			limitsConfig := LimitsConfig{}
+			if merged.Limits != nil {
+				limitsConfig = *merged.Limits
+			}
+			limitsConfig = limitsConfig.Merge(*config.Limits)
+			merged.Limits = &limitsConfig
+		}
+		if config.Encryption != nil {
+			encryptionConfig := EncryptionConfig{}
+			if merged.Encryption != nil {
+				encryptionConfig = *merged.Encryption
+			}
+			encryptionConfig = encryptionConfig.Merge(*config.Encryption)
+			merged.Encryption = &encryptionConfig
+		}
+		if config.Version != "" {
+			merged.Version = config.Version
+		}
+		merged.Compatibility.IgnoreEmptyFields = merged.Compatibility.IgnoreEmptyFields || config.Compatibility.IgnoreEmptyFields
+	}
+	return merged, conflicts
+}
+
+// sourceTargets returns the target, as described by describeTarget, of each source registry
+// mapped by r.
+func sourceTargets(r RegConfig) map[string]string {
+	targets := make(map[string]string)
+	for _, mapping := range r.Mappings {
+		targets[mapping.Source] = describeTarget(mapping.Target, mapping.TagTransform)
+	}
+	for _, group := range r.Groups {
+		for _, source := range group.Sources {
+			targets[source] = describeTarget(group.Target, group.TagTransform)
+		}
+	}
+	return targets
+}
+
+// describeTarget returns a target registry with its tag transform, if any.
+func describeTarget(target, tagTransform string) string {
+	if tagTransform == "" {
+		return target
+	}
+	return target + " (tagTransform " + tagTransform + ")"
+}
+
+// policyAction returns the action policy sets for condition, or "" if it leaves it to the strict
+// mode level.
+func policyAction(policy *strictness.Policy, condition strictness.Condition) strictness.Action {
+	if policy == nil {
+		return ""
+	}
+	const unset = strictness.Action("unset")
+	defaults := strictness.Policy{TemplateExpressions: unset, UnparseableImages: unset, UnmappedRegistries: unset, EmptyRepositories: unset, MappingConflicts: unset, MutableReferences: unset}
+	if action := defaults.Merge(*policy).ActionFor(condition); action != unset {
+		return action
+	}
+	return ""
+}
+
+// cloneRegConfig returns a copy of r whose slices can be changed without affecting r.
+func cloneRegConfig(r RegConfig) RegConfig {
+	r.Mappings = slices.Clone(r.Mappings)
+	r.Groups = slices.Clone(r.Groups)
+	return r
+}
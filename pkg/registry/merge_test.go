@@ -0,0 +1,109 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/strictness"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFiles(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, name := range []string{"/configs/20-cluster.yml", "/configs/10-base.yaml", "/configs/README.md", "/configs/nested/ignored.yaml", "/extra.yaml", "/empty/notes.txt"} {
+		require.NoError(t, afero.WriteFile(fs, name, []byte("registries: {}\n"), 0o644))
+	}
+
+	files, err := ConfigFiles(fs, []string{"/configs", "/extra.yaml", " ", "/missing.yaml"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/configs/10-base.yaml", "/configs/20-cluster.yml", "/extra.yaml", "/missing.yaml"}, files)
+
+	_, err = ConfigFiles(fs, []string{"/empty"})
+	assert.ErrorContains(t, err, "contains no .yaml or .yml files")
+}
+
+func TestMergeConfigs(t *testing.T) {
+	base := &Config{
+		Version: "1.0",
+		Registries: RegConfig{
+			DefaultTarget: "harbor.local/default",
+			Mappings: []RegMapping{
+				{Source: "docker.io", Target: "harbor.local/docker", Enabled: true},
+				{Source: "quay.io", Target: "harbor.local/quay", Enabled: true},
+			},
+			Groups: []RegGroup{{Name: "github", Target: "harbor.local/github", Sources: []string{"ghcr.io", "pkg.github.com"}}},
+		},
+		Profiles:   map[string]RegConfig{"prod": {DefaultTarget: "harbor.prod.local/default"}},
+		Policy:     &strictness.Policy{UnmappedRegistries: strictness.ActionWarn},
+		TLS:        map[string]TLSConfig{"harbor.local": {CAFile: "harbor-ca.pem"}},
+		Exceptions: []RelocationException{{Image: "registry.vendor.com/**", Reason: "licensed"}},
+		Limits:     &LimitsConfig{MaxValuesSize: "8Mi", MaxDepth: 50},
+		Encryption: &EncryptionConfig{Format: "sops", Recipients: []string{"age1base"}},
+	}
+	cluster := &Config{
+		Registries: RegConfig{
+			StrictMode: true,
+			Mappings: []RegMapping{
+				{Source: "docker.io", Target: "mirror.cluster.local/docker", Enabled: true},
+				{Source: "quay.io", Target: "harbor.local/quay", Enabled: true},
+				{Source: "ghcr.io", Target: "mirror.cluster.local/github", Enabled: true},
+			},
+		},
+		Profiles: map[string]RegConfig{
+			"prod":    {DefaultTarget: "mirror.prod.local/default"},
+			"staging": {DefaultTarget: "mirror.staging.local/default"},
+		},
+		Policy: &strictness.Policy{UnmappedRegistries: strictness.ActionError, EmptyRepositories: strictness.ActionIgnore},
+		TLS: map[string]TLSConfig{
+			"harbor.local":         {InsecureSkipTLSVerify: true},
+			"mirror.cluster.local": {CAFile: "cluster-ca.pem"},
+		},
+		Exceptions: []RelocationException{{Path: "enterprise.*.image"}},
+		Limits:     &LimitsConfig{MaxValuesSize: "32Mi", MaxImagePatterns: 500},
+		Encryption: &EncryptionConfig{Recipients: []string{"age1cluster"}},
+	}
+
+	merged, conflicts := MergeConfigs([]*Config{base, cluster}, []string{"base.yaml", "cluster.yaml"})
+
+	assert.Equal(t, "1.0", merged.Version)
+	assert.Equal(t, RegConfig{
+		DefaultTarget: "harbor.local/default",
+		StrictMode:    true,
+		Mappings: []RegMapping{
+			{Source: "docker.io", Target: "mirror.cluster.local/docker", Enabled: true},
+			{Source: "quay.io", Target: "harbor.local/quay", Enabled: true},
+			{Source: "ghcr.io", Target: "mirror.cluster.local/github", Enabled: true},
+		},
+		Groups: []RegGroup{{Name: "github", Target: "harbor.local/github", Sources: []string{"pkg.github.com"}}},
+	}, merged.Registries)
+	assert.Equal(t, "mirror.prod.local/default", merged.Profiles["prod"].DefaultTarget)
+	assert.Contains(t, merged.Profiles, "staging")
+	assert.Equal(t, strictness.Policy{UnmappedRegistries: strictness.ActionError, EmptyRepositories: strictness.ActionIgnore}, *merged.Policy)
+	assert.Equal(t, map[string]TLSConfig{
+		"harbor.local":         {InsecureSkipTLSVerify: true},
+		"mirror.cluster.local": {CAFile: "cluster-ca.pem"},
+	}, merged.TLS)
+	assert.Equal(t, []RelocationException{
+		{Image: "registry.vendor.com/**", Reason: "licensed"},
+		{Path: "enterprise.*.image"},
+	}, merged.Exceptions)
+	assert.Equal(t, LimitsConfig{MaxValuesSize: "32Mi", MaxDepth: 50, MaxImagePatterns: 500}, *merged.Limits)
+	assert.Equal(t, EncryptionConfig{Format: "sops", Recipients: []string{"age1cluster"}}, *merged.Encryption)
+
+	assert.ElementsMatch(t, []ConfigConflict{
+		{Setting: "mapping docker.io", Earlier: "harbor.local/docker", Later: "mirror.cluster.local/docker", EarlierSource: "base.yaml", LaterSource: "cluster.yaml"},
+		{Setting: "mapping ghcr.io", Earlier: "harbor.local/github", Later: "mirror.cluster.local/github", EarlierSource: "base.yaml", LaterSource: "cluster.yaml"},
+		{Setting: "profile prod", EarlierSource: "base.yaml", LaterSource: "cluster.yaml"},
+		{Setting: "policy unmappedRegistries", Earlier: "warn", Later: "error", EarlierSource: "base.yaml", LaterSource: "cluster.yaml"},
+		{Setting: "limits maxValuesSize", Earlier: "8Mi", Later: "32Mi", EarlierSource: "base.yaml", LaterSource: "cluster.yaml"},
+		{Setting: "encryption recipients", Earlier: "age1base", Later: "age1cluster", EarlierSource: "base.yaml", LaterSource: "cluster.yaml"},
+		{Setting: "tls harbor.local", Earlier: "caFile harbor-ca.pem", Later: "insecureSkipTLSVerify", EarlierSource: "base.yaml", LaterSource: "cluster.yaml"},
+	}, conflicts, "identical mappings and newly set settings are not conflicts")
+	assert.Equal(t, `mapping docker.io: "mirror.cluster.local/docker" from cluster.yaml overrides "harbor.local/docker" from base.yaml`, conflicts[0].String())
+
+	assert.Len(t, base.Registries.Groups[0].Sources, 2, "the merged configs are not modified")
+	assert.Equal(t, "harbor.prod.local/default", base.Profiles["prod"].DefaultTarget)
+	assert.Len(t, base.TLS, 1)
+	assert.Len(t, base.Exceptions, 1)
+}
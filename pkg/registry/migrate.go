@@ -0,0 +1,230 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/log"
+	yamlv3 "gopkg.in/yaml.v3"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ConfigFormatLegacyMap is the unversioned format mapping each source registry to its target
+	// at the top level (docker.io: harbor.example.com/docker) or below registry_mappings
+	ConfigFormatLegacyMap = "legacy-map"
+	// ConfigFormatLegacyList is the unversioned format listing the mappings at the top level,
+	// without the registries section
+	ConfigFormatLegacyList = "legacy-list"
+
+	// unversionedConfigVersion is the version of structured configs that do not declare one
+	unversionedConfigVersion = "1.0"
+
+	// migratedConfigIndent is the indentation of migrated config files
+	migratedConfigIndent = 2
+)
+
+// configVersionPattern matches config versions: a major version with an optional minor version
+// and "v" prefix
+var configVersionPattern = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+)?$`)
+
+// structuredConfigKeys are the top-level keys of the structured format
+var structuredConfigKeys = []string{"registries", "version", "compatibility", "profiles", "policy", "tls"}
+
+// configMigration upgrades a config document from one version to the next
+type configMigration struct {
+	to      string
+	migrate func(doc map[string]interface{}) (map[string]interface{}, error)
+}
+
+// configMigrations are the upgrade steps by the version or legacy format they start from.
+// Following them from any supported version leads to DefaultConfigVersion, so a new format
+// version adds the step from the previous one and bumps DefaultConfigVersion.
+var configMigrations = map[string]configMigration{
+	ConfigFormatLegacyMap:  {to: "1.0", migrate: migrateLegacyMap},
+	ConfigFormatLegacyList: {to: "1.0", migrate: migrateLegacyList},
+}
+
+// IsLegacyConfigFormat reports whether version is one of the unversioned legacy formats.
+func IsLegacyConfigFormat(version string) bool {
+	return version == ConfigFormatLegacyMap || version == ConfigFormatLegacyList
+}
+
+// DetectConfigVersion returns the version of the registry config in data: the declared version
+// (normalized, e.g. "1" and "v1" are "1.0"), the implied version of structured configs without one, or
+// ConfigFormatLegacyMap/ConfigFormatLegacyList. source names the file in errors.
+func DetectConfigVersion(data []byte, source string) (string, error) {
+	doc, err := parseConfigDocument(data)
+	if err != nil {
+		return "", err
+	}
+	return detectDocumentVersion(doc, source)
+}
+
+// MigrateConfigData upgrades the registry config in data to DefaultConfigVersion and returns
+// the upgraded YAML and the version it was upgraded from. migrated is nil if data already is
+// in the current version. source names the file in errors.
+func MigrateConfigData(data []byte, source string) (migrated []byte, from string, err error) {
+	doc, err := parseConfigDocument(data)
+	if err != nil {
+		return nil, "", err
+	}
+	from, err = detectDocumentVersion(doc, source)
+	if err != nil {
+		return nil, "", err
+	}
+	if from == DefaultConfigVersion {
+		return nil, from, nil
+	}
+
+	for version := from; version != DefaultConfigVersion; {
+		step, ok := configMigrations[version]
+		if !ok {
+			return nil, from, fmt.Errorf("no migration from config version %s in config file '%s'", version, source)
+		}
+		if doc, err = step.migrate(doc); err != nil {
+			return nil, from, fmt.Errorf("failed to migrate config file '%s' from %s to %s: %w", source, version, step.to, err)
+		}
+		version = step.to
+	}
+	doc["version"] = DefaultConfigVersion
+
+	// Round-trip through Config, so the upgraded file is written in the field order and with
+	// the omissions of files irr generates
+	docJSON, err := json.Marshal(doc)
+	if err != nil {
+		return nil, from, fmt.Errorf("failed to encode migrated config file '%s': %w", source, err)
+	}
+	var config Config
+	if err := yaml.Unmarshal(docJSON, &config); err != nil {
+		return nil, from, fmt.Errorf("failed to decode migrated config file '%s': %w", source, err)
+	}
+	var buf bytes.Buffer
+	encoder := yamlv3.NewEncoder(&buf)
+	encoder.SetIndent(migratedConfigIndent)
+	if err := encoder.Encode(config); err != nil {
+		return nil, from, fmt.Errorf("failed to marshal migrated config file '%s': %w", source, err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, from, fmt.Errorf("failed to marshal migrated config file '%s': %w", source, err)
+	}
+	return buf.Bytes(), from, nil
+}
+
+// currentConfigData returns data upgraded in memory to DefaultConfigVersion, warning that
+// older files should be migrated with 'irr config migrate'
+func currentConfigData(data []byte, source string) ([]byte, error) {
+	migrated, from, err := MigrateConfigData(data, source)
+	if err != nil {
+		return nil, err
+	}
+	if migrated == nil {
+		return data, nil
+	}
+	log.Warn("Registry config file uses an older format; upgrade it with 'irr config migrate'",
+		"file", source, "format", from, "version", DefaultConfigVersion)
+	return migrated, nil
+}
+
+// parseConfigDocument parses data as a generic YAML document
+func parseConfigDocument(data []byte) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+	return doc, nil
+}
+
+// detectDocumentVersion implements DetectConfigVersion for a parsed document
+func detectDocumentVersion(doc map[string]interface{}, source string) (string, error) {
+	if declared, ok := doc["version"]; ok && declared != nil && declared != "" {
+		version := fmt.Sprint(declared)
+		if !configVersionPattern.MatchString(version) {
+			return "", WrapUnsupportedConfigVersion(source, version)
+		}
+		version = strings.TrimPrefix(version, "v")
+		if !strings.Contains(version, ".") {
+			version += ".0"
+		}
+		if _, ok := configMigrations[version]; !ok && version != DefaultConfigVersion {
+			return "", WrapUnsupportedConfigVersion(source, version)
+		}
+		return version, nil
+	}
+
+	if slices.ContainsFunc(structuredConfigKeys, func(key string) bool { _, ok := doc[key]; return ok }) {
+		return unversionedConfigVersion, nil
+	}
+	if _, ok := doc["registry_mappings"]; ok {
+		return ConfigFormatLegacyMap, nil
+	}
+	if mappings, ok := doc["mappings"].([]interface{}); ok && mappings != nil {
+		return ConfigFormatLegacyList, nil
+	}
+	if len(doc) > 0 && isLegacyMap(doc) {
+		return ConfigFormatLegacyMap, nil
+	}
+	return unversionedConfigVersion, nil
+}
+
+// isLegacyMap reports whether all keys of doc are registries mapped to a string target
+func isLegacyMap(doc map[string]interface{}) bool {
+	for source, target := range doc {
+		if _, ok := target.(string); !ok || !isValidDomain(source) {
+			return false
+		}
+	}
+	return true
+}
+
+// migrateLegacyMap turns source: target pairs, at the top level or below registry_mappings,
+// into the mappings of the registries section
+func migrateLegacyMap(doc map[string]interface{}) (map[string]interface{}, error) {
+	pairs := doc
+	if nested, ok := doc["registry_mappings"]; ok {
+		if pairs, ok = nested.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("registry_mappings must map source registries to targets")
+		}
+	}
+	mappings := make([]interface{}, 0, len(pairs))
+	for _, source := range slices.Sorted(maps.Keys(pairs)) {
+		target, ok := pairs[source].(string)
+		if !ok {
+			return nil, fmt.Errorf("target of source registry %q must be a string", source)
+		}
+		mappings = append(mappings, map[string]interface{}{"source": source, "target": target, "enabled": true})
+	}
+	return map[string]interface{}{"registries": map[string]interface{}{"mappings": mappings}}, nil
+}
+
+// migrateLegacyList moves the top-level mappings list, and the defaultTarget and strictMode
+// settings next to it, into the registries section
+func migrateLegacyList(doc map[string]interface{}) (map[string]interface{}, error) {
+	registries := map[string]interface{}{}
+	for _, key := range []string{"mappings", "defaultTarget", "strictMode"} {
+		if value, ok := doc[key]; ok {
+			registries[key] = value
+			delete(doc, key)
+		}
+	}
+	for _, item := range registries["mappings"].([]interface{}) {
+		mapping, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("mappings must be a list of source and target pairs")
+		}
+		// Legacy mappings had no enabled flag; they were all active
+		if _, ok := mapping["enabled"]; !ok {
+			mapping["enabled"] = true
+		}
+	}
+	doc["registries"] = registries
+	return doc, nil
+}
@@ -0,0 +1,110 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectConfigVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "declared", content: "version: \"1.0\"\nregistries:\n  mappings: []\n", want: "1.0"},
+		{name: "major only", content: "version: \"1\"\nregistries: {}\n", want: "1.0"},
+		{name: "number", content: "version: 1.0\nregistries: {}\n", want: "1.0"},
+		{name: "v prefix", content: "version: v1\nregistries: {}\n", want: "1.0"},
+		{name: "unversioned structured", content: "registries:\n  defaultTarget: harbor.local/default\n", want: "1.0"},
+		{name: "legacy top-level map", content: "docker.io: harbor.local/docker\nquay.io: harbor.local/quay\n", want: ConfigFormatLegacyMap},
+		{name: "legacy registry_mappings", content: "registry_mappings:\n  docker.io: harbor.local/docker\n", want: ConfigFormatLegacyMap},
+		{name: "legacy mappings list", content: "mappings:\n  - source: docker.io\n    target: harbor.local/docker\n", want: ConfigFormatLegacyList},
+		{name: "unknown keys", content: "exclude: [docker.io]\n", want: "1.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := DetectConfigVersion([]byte(tt.content), "config.yaml")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, version)
+		})
+	}
+
+	for _, version := range []string{"2.0", "3", "0.9", "latest"} {
+		_, err := DetectConfigVersion([]byte("version: \""+version+"\"\nregistries: {}\n"), "config.yaml")
+		var versionErr *ErrUnsupportedConfigVersion
+		require.ErrorAs(t, err, &versionErr, version)
+		assert.Contains(t, err.Error(), "config.yaml")
+		assert.Contains(t, err.Error(), "newer config versions need a newer irr")
+	}
+}
+
+func TestMigrateConfigData(t *testing.T) {
+	t.Run("legacy map", func(t *testing.T) {
+		migrated, from, err := MigrateConfigData([]byte("quay.io: harbor.local/quay\ndocker.io: harbor.local/docker\n"), "config.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, ConfigFormatLegacyMap, from)
+		assert.Equal(t, `registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+      enabled: true
+    - source: quay.io
+      target: harbor.local/quay
+      enabled: true
+version: "1.0"
+`, string(migrated))
+
+		config, err := ParseConfig(migrated, "config.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, DefaultConfigVersion, config.Version)
+		assert.Len(t, config.Registries.Mappings, 2)
+	})
+
+	t.Run("legacy list", func(t *testing.T) {
+		migrated, from, err := MigrateConfigData([]byte(`mappings:
+  - source: docker.io
+    target: harbor.local/docker
+  - source: quay.io
+    target: harbor.local/quay
+    enabled: false
+defaultTarget: harbor.local/default
+`), "config.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, ConfigFormatLegacyList, from)
+		assert.Equal(t, `registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+      enabled: true
+    - source: quay.io
+      target: harbor.local/quay
+  defaultTarget: harbor.local/default
+version: "1.0"
+`, string(migrated), "explicitly disabled mappings stay disabled")
+	})
+
+	t.Run("current version", func(t *testing.T) {
+		migrated, from, err := MigrateConfigData([]byte(profilesConfig), "config.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, DefaultConfigVersion, from)
+		assert.Nil(t, migrated)
+	})
+
+	t.Run("invalid legacy target", func(t *testing.T) {
+		_, _, err := MigrateConfigData([]byte("registry_mappings:\n  docker.io: [harbor.local]\n"), "config.yaml")
+		assert.ErrorContains(t, err, `failed to migrate config file 'config.yaml' from legacy-map to 1.0: target of source registry "docker.io" must be a string`)
+	})
+}
+
+func TestParseConfigMigratesLegacyFormats(t *testing.T) {
+	config, err := ParseConfig([]byte("registry_mappings:\n  docker.io: harbor.local/docker\n"), "config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, []RegMapping{{Source: "docker.io", Target: "harbor.local/docker", Enabled: true}}, config.Registries.Mappings)
+
+	_, err = ParseConfig([]byte("version: \"2.0\"\nregistries:\n  mappings: []\n"), "config.yaml")
+	var versionErr *ErrUnsupportedConfigVersion
+	require.ErrorAs(t, err, &versionErr)
+	assert.Equal(t, "2.0", versionErr.Version)
+}
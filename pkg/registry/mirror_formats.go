@@ -0,0 +1,306 @@
+package registry
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+	"gopkg.in/yaml.v3"
+)
+
+// Mirroring tool configuration formats accepted by ParseMirrorConfig and RenderMirrorConfig.
+const (
+	// MirrorFormatSkopeoSync is the YAML source file of `skopeo sync --src yaml`
+	MirrorFormatSkopeoSync = "skopeo-sync"
+	// MirrorFormatZarf is a zarf.yaml package definition
+	MirrorFormatZarf = "zarf"
+	// MirrorFormatHauler is a Hauler manifest of Images resources
+	MirrorFormatHauler = "hauler"
+)
+
+// MirrorImportFormats lists the formats mappings can be imported from.
+var MirrorImportFormats = []string{MirrorFormatSkopeoSync, MirrorFormatZarf, MirrorFormatHauler}
+
+// MirrorExportFormats lists the formats mappings can be exported to.
+var MirrorExportFormats = []string{MirrorFormatSkopeoSync}
+
+// ErrMultipleMirrorTargets is returned by RenderMirrorConfig when the mappings route to more than
+// one target and none was selected; skopeo sync copies to a single destination.
+var ErrMultipleMirrorTargets = errors.New("mappings have more than one target; select one")
+
+// MirrorSource is a source registry of a mirroring configuration and the repositories it mirrors.
+type MirrorSource struct {
+	// Registry is the source registry host, with docker.io for Docker Hub.
+	Registry string
+	// Repositories are the repositories mirrored from the registry, in file order.
+	Repositories []string
+}
+
+// MirrorImage is an image listed in an exported mirroring configuration, with the tags and digests
+// to copy from its repository: its own tag or digest, followed by those of its cosign signatures
+// and attestations (see ImageSignatures) so they stay verifiable in the target registry.
+type MirrorImage struct {
+	// Registry is the source registry host of the image.
+	Registry string
+	// Repository is the repository of the image in the registry.
+	Repository string
+	// References are the tags and digests copied from the repository.
+	References []string
+}
+
+// ParseMirrorConfig returns the source registries of a mirroring tool configuration in the given
+// format. Registries are returned in the order they first appear (sorted for skopeo-sync, whose
+// registries are map keys).
+func ParseMirrorConfig(format string, data []byte) ([]MirrorSource, error) {
+	switch format {
+	case MirrorFormatSkopeoSync:
+		return parseSkopeoSync(data)
+	case MirrorFormatZarf:
+		return parseZarfPackage(data)
+	case MirrorFormatHauler:
+		return parseHaulerManifest(data)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q (expected one of %s)", format, strings.Join(MirrorImportFormats, ", "))
+	}
+}
+
+// ImportMirrorMappings maps every source registry to target. With scoped, each source gets its
+// own path below target (TARGET/SOURCE), as `skopeo sync --scoped` lays out the destination.
+func ImportMirrorMappings(sources []MirrorSource, target string, scoped bool) []Mapping {
+	target = strings.TrimSuffix(target, "/")
+	mappings := make([]Mapping, 0, len(sources))
+	for _, source := range sources {
+		mappingTarget := target
+		if scoped {
+			mappingTarget = target + "/" + source.Registry
+		}
+		mappings = append(mappings, Mapping{Source: source.Registry, Target: mappingTarget})
+	}
+	return mappings
+}
+
+// RenderMirrorConfig renders the mappings to target as a mirroring tool configuration. An empty
+// target selects the single target of the mappings, and fails with ErrMultipleMirrorTargets if
+// there is more than one. The images from the source registries of target are listed below their
+// registry; images from other registries are left out.
+func RenderMirrorConfig(format string, mappings *Mappings, target string, images []MirrorImage) ([]byte, error) {
+	if format != MirrorFormatSkopeoSync {
+		return nil, fmt.Errorf("unsupported export format %q (expected one of %s)", format, strings.Join(MirrorExportFormats, ", "))
+	}
+	if mappings == nil || len(mappings.Entries) == 0 {
+		return nil, errors.New("no registry mappings to export")
+	}
+
+	target = strings.TrimSuffix(target, "/")
+	if target == "" {
+		targets := mappingTargets(mappings)
+		if len(targets) > 1 {
+			return nil, fmt.Errorf("%w: %s", ErrMultipleMirrorTargets, strings.Join(targets, ", "))
+		}
+		target = targets[0]
+	}
+	var sources []string
+	for _, mapping := range mappings.Entries {
+		if strings.TrimSuffix(mapping.Target, "/") == target && !slices.Contains(sources, mapping.Source) {
+			sources = append(sources, mapping.Source)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no registry mappings target %s", target)
+	}
+	return renderSkopeoSync(sources, target, images), nil
+}
+
+// mappingTargets returns the distinct targets of mappings, sorted
+func mappingTargets(mappings *Mappings) []string {
+	var targets []string
+	for _, mapping := range mappings.Entries {
+		target := strings.TrimSuffix(mapping.Target, "/")
+		if !slices.Contains(targets, target) {
+			targets = append(targets, target)
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// canonicalMirrorRegistry lowercases a registry host and folds the Docker Hub aliases into docker.io
+func canonicalMirrorRegistry(registry string) string {
+	registry = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(registry), "/"))
+	if image.NormalizeRegistry(registry) == DockerHubRegistry {
+		return DockerHubRegistry
+	}
+	return registry
+}
+
+// skopeoSyncRegistry is a registry entry of a skopeo sync YAML file. Credentials and TLS settings
+// are not needed for mappings and are ignored.
+type skopeoSyncRegistry struct {
+	Images           map[string]interface{} `yaml:"images"`
+	ImagesByTagRegex map[string]interface{} `yaml:"images-by-tag-regex"`
+	ImagesBySemver   map[string]interface{} `yaml:"images-by-semver"`
+}
+
+func parseSkopeoSync(data []byte) ([]MirrorSource, error) {
+	var registries map[string]skopeoSyncRegistry
+	if err := yaml.Unmarshal(data, &registries); err != nil {
+		return nil, fmt.Errorf("failed to parse skopeo sync file: %w", err)
+	}
+	hosts := make([]string, 0, len(registries))
+	for host := range registries {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	var sources []MirrorSource
+	for _, host := range hosts {
+		entry := registries[host]
+		var repositories []string
+		for _, images := range []map[string]interface{}{entry.Images, entry.ImagesByTagRegex, entry.ImagesBySemver} {
+			for repository := range images {
+				if !slices.Contains(repositories, repository) {
+					repositories = append(repositories, repository)
+				}
+			}
+		}
+		sort.Strings(repositories)
+		sources = addMirrorSource(sources, canonicalMirrorRegistry(host), repositories...)
+	}
+	return sources, nil
+}
+
+// zarfPackage is the part of a zarf.yaml package definition that lists images
+type zarfPackage struct {
+	Kind       string `yaml:"kind"`
+	Components []struct {
+		Images []string `yaml:"images"`
+	} `yaml:"components"`
+}
+
+func parseZarfPackage(data []byte) ([]MirrorSource, error) {
+	var pkg zarfPackage
+	if err := yaml.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse zarf package: %w", err)
+	}
+	if pkg.Kind != "" && pkg.Kind != "ZarfPackageConfig" && pkg.Kind != "ZarfInitConfig" {
+		return nil, fmt.Errorf("unexpected zarf package kind %q", pkg.Kind)
+	}
+	var images []string
+	for _, component := range pkg.Components {
+		images = append(images, component.Images...)
+	}
+	return mirrorSourcesFromImages(images)
+}
+
+// haulerManifest is a Hauler resource; only Images resources list images
+type haulerManifest struct {
+	Kind string `yaml:"kind"`
+	Spec struct {
+		Images []struct {
+			Name string `yaml:"name"`
+		} `yaml:"images"`
+	} `yaml:"spec"`
+}
+
+func parseHaulerManifest(data []byte) ([]MirrorSource, error) {
+	var images []string
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var manifest haulerManifest
+		err := decoder.Decode(&manifest)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse hauler manifest: %w", err)
+		}
+		if manifest.Kind != "Images" {
+			continue
+		}
+		for _, img := range manifest.Spec.Images {
+			images = append(images, img.Name)
+		}
+	}
+	return mirrorSourcesFromImages(images)
+}
+
+// mirrorSourcesFromImages groups image references by registry
+func mirrorSourcesFromImages(images []string) ([]MirrorSource, error) {
+	var sources []MirrorSource
+	for _, raw := range images {
+		ref, err := image.ParseImageReference(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image reference %q: %w", raw, err)
+		}
+		sources = addMirrorSource(sources, canonicalMirrorRegistry(ref.Registry), ref.Repository)
+	}
+	return sources, nil
+}
+
+// addMirrorSource adds the repositories to the source for registry, appending a new source if needed
+func addMirrorSource(sources []MirrorSource, registry string, repositories ...string) []MirrorSource {
+	i := slices.IndexFunc(sources, func(source MirrorSource) bool { return source.Registry == registry })
+	if i < 0 {
+		sources = append(sources, MirrorSource{Registry: registry})
+		i = len(sources) - 1
+	}
+	for _, repository := range repositories {
+		if repository != "" && !slices.Contains(sources[i].Repositories, repository) {
+			sources[i].Repositories = append(sources[i].Repositories, repository)
+		}
+	}
+	return sources
+}
+
+// renderSkopeoSync renders a skopeo sync YAML file for the sources. irr mappings only know
+// registries, so a registry without images gets an empty images list to be filled in. A target
+// with a path receives repositories directly below it; a bare registry host receives them below
+// the source registry name, which is what `skopeo sync --scoped` does.
+func renderSkopeoSync(sources []string, target string, images []MirrorImage) []byte {
+	var b strings.Builder
+	b.WriteString("# skopeo sync source file generated by irr.\n")
+	if len(images) == 0 {
+		b.WriteString("# List the repositories (and optionally tags) to mirror under images, then run:\n")
+	} else {
+		b.WriteString("# Images are listed with their cosign signatures and attestations. Review them, then run:\n")
+	}
+	if strings.Contains(target, "/") {
+		fmt.Fprintf(&b, "#   skopeo sync --src yaml --dest docker skopeo-sync.yaml %s\n", target)
+	} else {
+		fmt.Fprintf(&b, "#   skopeo sync --src yaml --dest docker --scoped skopeo-sync.yaml %s\n", target)
+	}
+	for _, source := range sources {
+		var repositories []string
+		references := make(map[string][]string)
+		for _, img := range images {
+			if canonicalMirrorRegistry(img.Registry) != canonicalMirrorRegistry(source) {
+				continue
+			}
+			if _, seen := references[img.Repository]; !seen {
+				repositories = append(repositories, img.Repository)
+			}
+			for _, reference := range img.References {
+				if !slices.Contains(references[img.Repository], reference) {
+					references[img.Repository] = append(references[img.Repository], reference)
+				}
+			}
+		}
+		if len(repositories) == 0 {
+			fmt.Fprintf(&b, "%s:\n  images: {}\n", source)
+			continue
+		}
+		fmt.Fprintf(&b, "%s:\n  images:\n", source)
+		for _, repository := range repositories {
+			fmt.Fprintf(&b, "    %s:\n", repository)
+			for _, reference := range references[repository] {
+				fmt.Fprintf(&b, "      - %q\n", reference)
+			}
+		}
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,152 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseMirrorConfig(t *testing.T) {
+	t.Run("skopeo-sync", func(t *testing.T) {
+		data := []byte(`quay.io:
+  images:
+    prometheus/node-exporter: [v1.7.0]
+  tls-verify: true
+index.docker.io:
+  images:
+    library/nginx: []
+  images-by-tag-regex:
+    bitnami/redis: ^7\.
+`)
+		sources, err := ParseMirrorConfig(MirrorFormatSkopeoSync, data)
+		require.NoError(t, err)
+		assert.Equal(t, []MirrorSource{
+			{Registry: "docker.io", Repositories: []string{"bitnami/redis", "library/nginx"}},
+			{Registry: "quay.io", Repositories: []string{"prometheus/node-exporter"}},
+		}, sources)
+	})
+
+	t.Run("zarf", func(t *testing.T) {
+		data := []byte(`kind: ZarfPackageConfig
+metadata:
+  name: podinfo
+components:
+  - name: podinfo
+    images:
+      - ghcr.io/stefanprodan/podinfo:6.4.0
+      - nginx:1.25
+  - name: tools
+    images:
+      - ghcr.io/org/tools@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef
+`)
+		sources, err := ParseMirrorConfig(MirrorFormatZarf, data)
+		require.NoError(t, err)
+		assert.Equal(t, []MirrorSource{
+			{Registry: "ghcr.io", Repositories: []string{"stefanprodan/podinfo", "org/tools"}},
+			{Registry: "docker.io", Repositories: []string{"library/nginx"}},
+		}, sources)
+
+		_, err = ParseMirrorConfig(MirrorFormatZarf, []byte("kind: Deployment\n"))
+		assert.ErrorContains(t, err, "unexpected zarf package kind")
+	})
+
+	t.Run("hauler", func(t *testing.T) {
+		data := []byte(`apiVersion: content.hauler.cattle.io/v1
+kind: Images
+spec:
+  images:
+    - name: registry.k8s.io/pause:3.9
+---
+apiVersion: content.hauler.cattle.io/v1
+kind: Charts
+spec:
+  charts:
+    - name: rancher
+      repoURL: https://releases.rancher.com/server-charts/stable
+---
+apiVersion: content.hauler.cattle.io/v1
+kind: Images
+spec:
+  images:
+    - name: docker.io/rancher/rancher:v2.8.0
+`)
+		sources, err := ParseMirrorConfig(MirrorFormatHauler, data)
+		require.NoError(t, err)
+		assert.Equal(t, []MirrorSource{
+			{Registry: "registry.k8s.io", Repositories: []string{"pause"}},
+			{Registry: "docker.io", Repositories: []string{"rancher/rancher"}},
+		}, sources)
+	})
+
+	_, err := ParseMirrorConfig("crane", nil)
+	assert.ErrorContains(t, err, "unsupported import format")
+}
+
+func TestImportMirrorMappings(t *testing.T) {
+	sources := []MirrorSource{{Registry: "docker.io"}, {Registry: "quay.io"}}
+	assert.Equal(t, []Mapping{
+		{Source: "docker.io", Target: "harbor.local/mirror"},
+		{Source: "quay.io", Target: "harbor.local/mirror"},
+	}, ImportMirrorMappings(sources, "harbor.local/mirror/", false))
+	assert.Equal(t, []Mapping{
+		{Source: "docker.io", Target: "harbor.local/docker.io"},
+		{Source: "quay.io", Target: "harbor.local/quay.io"},
+	}, ImportMirrorMappings(sources, "harbor.local", true))
+}
+
+func TestRenderMirrorConfig(t *testing.T) {
+	mappings := &Mappings{Entries: []Mapping{
+		{Source: "docker.io", Target: "harbor.local/mirror"},
+		{Source: "quay.io", Target: "harbor.local/mirror"},
+		{Source: "ghcr.io", Target: "other.local"},
+	}}
+
+	data, err := RenderMirrorConfig(MirrorFormatSkopeoSync, mappings, "harbor.local/mirror", nil)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "skopeo sync --src yaml --dest docker skopeo-sync.yaml harbor.local/mirror\n")
+	assert.Contains(t, string(data), "docker.io:\n  images: {}\nquay.io:\n  images: {}\n")
+	assert.NotContains(t, string(data), "ghcr.io")
+
+	sources, err := ParseMirrorConfig(MirrorFormatSkopeoSync, data)
+	require.NoError(t, err)
+	assert.Equal(t, []MirrorSource{{Registry: "docker.io"}, {Registry: "quay.io"}}, sources, "exported files can be imported again")
+
+	data, err = RenderMirrorConfig(MirrorFormatSkopeoSync, mappings, "other.local", nil)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "--scoped skopeo-sync.yaml other.local\n")
+
+	data, err = RenderMirrorConfig(MirrorFormatSkopeoSync, mappings, "harbor.local/mirror", []MirrorImage{
+		{Registry: "index.docker.io", Repository: "library/nginx", References: []string{"1.27", "sha256-abc.sig", "sha256:att"}},
+		{Registry: "docker.io", Repository: "library/nginx", References: []string{"1.27", "1.26"}},
+		{Registry: "ghcr.io", Repository: "org/app", References: []string{"1.0.0"}},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `docker.io:
+  images:
+    library/nginx:
+      - "1.27"
+      - "sha256-abc.sig"
+      - "sha256:att"
+      - "1.26"
+quay.io:
+  images: {}
+`)
+	assert.NotContains(t, string(data), "org/app", "images of registries mapped to other targets are left out")
+	var synced map[string]struct {
+		Images map[string][]string `yaml:"images"`
+	}
+	require.NoError(t, yaml.Unmarshal(data, &synced))
+	assert.Equal(t, []string{"1.27", "sha256-abc.sig", "sha256:att", "1.26"}, synced["docker.io"].Images["library/nginx"])
+
+	_, err = RenderMirrorConfig(MirrorFormatSkopeoSync, mappings, "", nil)
+	require.ErrorIs(t, err, ErrMultipleMirrorTargets)
+	assert.ErrorContains(t, err, "harbor.local/mirror, other.local")
+
+	_, err = RenderMirrorConfig(MirrorFormatSkopeoSync, mappings, "missing.local", nil)
+	assert.ErrorContains(t, err, "no registry mappings target missing.local")
+
+	_, err = RenderMirrorConfig(MirrorFormatZarf, mappings, "", nil)
+	assert.ErrorContains(t, err, "unsupported export format")
+}
@@ -0,0 +1,137 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ErrManifestNotFound is returned by ImagePlatforms when the registry has no manifest for the image.
+var ErrManifestNotFound = errors.New("manifest not found")
+
+// maxPlatformParts is the number of parts of os/architecture/variant
+const maxPlatformParts = 3
+
+// ParsePlatforms checks that each of specs is a platform in the os/architecture[/variant] form
+// (e.g. linux/arm64 or linux/arm/v7) and returns them lowercased, without duplicates.
+func ParsePlatforms(specs []string) ([]string, error) {
+	var platforms []string
+	for _, spec := range specs {
+		platform := strings.ToLower(strings.TrimSpace(spec))
+		parts := strings.Split(platform, "/")
+		if len(parts) < 2 || len(parts) > maxPlatformParts || slices.Contains(parts, "") {
+			return nil, fmt.Errorf("invalid platform %q: expected os/architecture[/variant], e.g. linux/arm64", spec)
+		}
+		if !slices.Contains(platforms, platform) {
+			platforms = append(platforms, platform)
+		}
+	}
+	return platforms, nil
+}
+
+// ImagePlatforms returns the platforms (os/architecture[/variant]) of the image tagged or pinned
+// by reference in repository on the registry host: those of the entries of an image index, or the
+// platform in the config of a single-platform image. Index entries without a platform and
+// attestation manifests (unknown/unknown) are skipped. ErrManifestNotFound is returned when the
+// registry does not have the image.
+func (c *TagChecker) ImagePlatforms(ctx context.Context, host, repository, reference string) ([]string, error) {
+	host, repository = registryEndpoint(host, repository)
+
+	type platform struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+		Variant      string `json:"variant"`
+	}
+	var manifest struct {
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+		Manifests []struct {
+			Platform *platform `json:"platform"`
+		} `json:"manifests"`
+	}
+	target := "https://" + host + "/v2/" + repository + "/manifests/" + url.PathEscape(reference)
+	resp, err := c.do(ctx, http.MethodGet, host, repository, target, manifestMediaTypes)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		closeBody(resp)
+		return nil, fmt.Errorf("%s/%s:%s: %w", host, repository, reference, ErrManifestNotFound)
+	}
+	if err := decodeResponse(resp, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to get manifest of %s/%s:%s: %w", host, repository, reference, err)
+	}
+
+	format := func(p platform) string {
+		name := strings.ToLower(p.OS + "/" + p.Architecture)
+		if p.Variant != "" {
+			name += "/" + strings.ToLower(p.Variant)
+		}
+		return name
+	}
+	var platforms []string
+	if len(manifest.Manifests) > 0 {
+		for _, entry := range manifest.Manifests {
+			if entry.Platform == nil || entry.Platform.OS == "unknown" || entry.Platform.OS == "" {
+				continue
+			}
+			if name := format(*entry.Platform); !slices.Contains(platforms, name) {
+				platforms = append(platforms, name)
+			}
+		}
+		return platforms, nil
+	}
+	if manifest.Config.Digest == "" {
+		return nil, nil
+	}
+
+	resp, err = c.do(ctx, http.MethodGet, host, repository, "https://"+host+"/v2/"+repository+"/blobs/"+manifest.Config.Digest, nil)
+	if err != nil {
+		return nil, err
+	}
+	var config platform
+	if err := decodeResponse(resp, &config); err != nil {
+		return nil, fmt.Errorf("failed to get image config of %s/%s:%s: %w", host, repository, reference, err)
+	}
+	if config.OS == "" {
+		return nil, nil
+	}
+	return []string{format(config)}, nil
+}
+
+// MissingPlatforms returns the platforms of required that none of available provides. A required
+// platform without a variant is provided by any variant of its os and architecture, and
+// linux/arm64 is the same as linux/arm64/v8.
+func MissingPlatforms(required, available []string) []string {
+	var missing []string
+	for _, want := range required {
+		provided := slices.ContainsFunc(available, func(have string) bool {
+			return platformProvides(canonicalPlatform(have), canonicalPlatform(want))
+		})
+		if !provided {
+			missing = append(missing, want)
+		}
+	}
+	return missing
+}
+
+// canonicalPlatform drops the variant of arm64/v8, which registries list either way
+func canonicalPlatform(platform string) string {
+	if trimmed, ok := strings.CutSuffix(platform, "/arm64/v8"); ok {
+		return trimmed + "/arm64"
+	}
+	return platform
+}
+
+// platformProvides reports whether an image for platform have runs on platform want
+func platformProvides(have, want string) bool {
+	if have == want {
+		return true
+	}
+	return strings.Count(want, "/") == 1 && strings.HasPrefix(have, want+"/")
+}
@@ -0,0 +1,65 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlatforms(t *testing.T) {
+	platforms, err := ParsePlatforms([]string{"linux/amd64", " Linux/ARM64 ", "linux/arm/v7", "linux/amd64"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"linux/amd64", "linux/arm64", "linux/arm/v7"}, platforms)
+
+	for _, invalid := range []string{"amd64", "linux/", "linux/arm/v7/extra"} {
+		_, err := ParsePlatforms([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestTagCheckerImagePlatforms(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/team/app/manifests/1.0":
+			_, _ = w.Write([]byte(`{"manifests": [
+				{"digest": "sha256:amd", "platform": {"os": "linux", "architecture": "amd64"}},
+				{"digest": "sha256:arm", "platform": {"os": "linux", "architecture": "arm64", "variant": "v8"}},
+				{"digest": "sha256:att", "platform": {"os": "unknown", "architecture": "unknown"}}
+			]}`))
+		case "/v2/team/app/manifests/1.0-amd64":
+			_, _ = w.Write([]byte(`{"config": {"digest": "sha256:config"}}`))
+		case "/v2/team/app/blobs/sha256:config":
+			_, _ = w.Write([]byte(`{"os": "linux", "architecture": "amd64"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	host := strings.TrimPrefix(server.URL, "https://")
+	checker := NewTagChecker(nil, DefaultStaleAfter)
+	checker.Client = server.Client()
+
+	platforms, err := checker.ImagePlatforms(context.Background(), host, "team/app", "1.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"linux/amd64", "linux/arm64/v8"}, platforms)
+
+	platforms, err = checker.ImagePlatforms(context.Background(), host, "team/app", "1.0-amd64")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"linux/amd64"}, platforms)
+
+	_, err = checker.ImagePlatforms(context.Background(), host, "team/app", "2.0")
+	assert.ErrorIs(t, err, ErrManifestNotFound)
+}
+
+func TestMissingPlatforms(t *testing.T) {
+	available := []string{"linux/amd64", "linux/arm64/v8", "linux/arm/v7"}
+
+	assert.Empty(t, MissingPlatforms([]string{"linux/amd64", "linux/arm64", "linux/arm"}, available))
+	assert.Empty(t, MissingPlatforms([]string{"linux/arm64/v8"}, []string{"linux/arm64"}))
+	assert.Equal(t, []string{"linux/arm/v6", "linux/s390x"}, MissingPlatforms([]string{"linux/arm/v6", "linux/s390x"}, available))
+}
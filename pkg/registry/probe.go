@@ -0,0 +1,312 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/spf13/afero"
+)
+
+// Target registry probe statuses reported in ProbeResult.Status.
+const (
+	// ProbeStatusReachable means GET /v2/ succeeded without credentials
+	ProbeStatusReachable = "reachable"
+	// ProbeStatusCredentialsFound means the registry requires authentication and credentials for
+	// it were found but not checked
+	ProbeStatusCredentialsFound = "credentials-found"
+	// ProbeStatusAuthenticated means the registry accepted the credentials found for it
+	ProbeStatusAuthenticated = "authenticated"
+	// ProbeStatusNoCredentials means the registry requires authentication and no credentials were found
+	ProbeStatusNoCredentials = "no-credentials"
+	// ProbeStatusAuthFailed means the registry rejected the credentials found for it
+	ProbeStatusAuthFailed = "auth-failed"
+	// ProbeStatusUnreachable means the registry could not be reached or did not answer as a registry
+	ProbeStatusUnreachable = "unreachable"
+)
+
+// DefaultProbeTimeout bounds each request made while probing a target registry.
+const DefaultProbeTimeout = 5 * time.Second
+
+// ProbeResult is the outcome of probing one target registry host.
+type ProbeResult struct {
+	Host   string
+	Status string
+	// Err explains an unreachable registry or failed authentication
+	Err error
+}
+
+// OK reports whether pulls from the registry are expected to work.
+func (r ProbeResult) OK() bool {
+	switch r.Status {
+	case ProbeStatusReachable, ProbeStatusCredentialsFound, ProbeStatusAuthenticated:
+		return true
+	default:
+		return false
+	}
+}
+
+// Credentials are the registry credentials found in a Docker-style config file. External is set
+// when they are held by a credential helper and cannot be read or checked without running it;
+// Helper then names the helper and ServerURL the key the credentials are stored under.
+type Credentials struct {
+	Username  string
+	Password  string
+	External  bool
+	Helper    string
+	ServerURL string
+}
+
+// Prober checks that target registries answer on the registry API base endpoint (GET /v2/) and,
+// when they require authentication, that credentials for them are available.
+type Prober struct {
+	// Client sends the probe requests; its Timeout should bound each request.
+	Client *http.Client
+	// Credentials holds the credentials found for each registry host.
+	Credentials map[string]Credentials
+	// Helpers resolves credentials held by credential helpers and cloud providers; may be nil.
+	Helpers *CredentialHelpers
+	// CheckAuth sends the credentials to registries requiring authentication to verify them.
+	CheckAuth bool
+}
+
+// NewProber creates a Prober with DefaultProbeTimeout and the given credentials.
+func NewProber(credentials map[string]Credentials, checkAuth bool) *Prober {
+	return &Prober{
+		Client:      &http.Client{Timeout: DefaultProbeTimeout},
+		Credentials: credentials,
+		CheckAuth:   checkAuth,
+	}
+}
+
+// ProbeAll probes every host concurrently and returns the results in the order of hosts.
+func (p *Prober) ProbeAll(ctx context.Context, hosts []string) []ProbeResult {
+	results := make([]ProbeResult, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = p.Probe(ctx, host)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Probe checks a single registry host.
+func (p *Prober) Probe(ctx context.Context, host string) ProbeResult {
+	result := ProbeResult{Host: host}
+	resp, err := p.get(ctx, "https://"+host+"/v2/", nil)
+	if err != nil {
+		result.Status = ProbeStatusUnreachable
+		result.Err = err
+		return result
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	closeBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		result.Status = ProbeStatusReachable
+		return result
+	case http.StatusUnauthorized:
+	default:
+		result.Status = ProbeStatusUnreachable
+		result.Err = fmt.Errorf("unexpected status %s from /v2/; the host does not look like a registry", resp.Status)
+		return result
+	}
+
+	creds, ok := p.Helpers.Resolve(ctx, p.Credentials, host)
+	switch {
+	case !ok:
+		result.Status = ProbeStatusNoCredentials
+		result.Err = errors.New("registry requires authentication and no credentials were found")
+	case !p.CheckAuth || creds.External:
+		result.Status = ProbeStatusCredentialsFound
+	default:
+		result.Err = p.checkCredentials(ctx, host, challenge, creds)
+		result.Status = ProbeStatusAuthenticated
+		if result.Err != nil {
+			result.Status = ProbeStatusAuthFailed
+		}
+	}
+	return result
+}
+
+// checkCredentials authenticates against a registry with the scheme of its WWW-Authenticate
+// challenge: basic auth on /v2/ itself, or a token request to the bearer realm.
+func (p *Prober) checkCredentials(ctx context.Context, host, challenge string, creds Credentials) error {
+	scheme, params := parseChallenge(challenge)
+	authURL := "https://" + host + "/v2/"
+	if strings.EqualFold(scheme, "bearer") {
+		realm, err := url.Parse(params["realm"])
+		if err != nil || params["realm"] == "" {
+			return fmt.Errorf("invalid bearer challenge %q", challenge)
+		}
+		if service := params["service"]; service != "" {
+			query := realm.Query()
+			query.Set("service", service)
+			realm.RawQuery = query.Encode()
+		}
+		authURL = realm.String()
+	}
+
+	resp, err := p.get(ctx, authURL, &creds)
+	if err != nil {
+		return err
+	}
+	closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("credentials rejected: %s from %s", resp.Status, authURL)
+	}
+	return nil
+}
+
+// get sends a GET request, with basic auth when creds is set.
+func (p *Prober) get(ctx context.Context, target string, creds *Credentials) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("invalid probe URL %s: %w", target, err)
+	}
+	if creds != nil {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", target, err)
+	}
+	return resp, nil
+}
+
+// closeBody drains and closes a response body so the connection can be reused.
+func closeBody(resp *http.Response) {
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		log.Debug("Failed to read probe response body", "error", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		log.Debug("Failed to close probe response body", "error", err)
+	}
+}
+
+// parseChallenge splits a WWW-Authenticate header such as
+// `Bearer realm="https://auth.example.com/token",service="registry"` into its scheme and parameters.
+func parseChallenge(challenge string) (scheme string, params map[string]string) {
+	params = make(map[string]string)
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	for _, part := range strings.Split(rest, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			continue
+		}
+		params[strings.ToLower(key)] = strings.Trim(value, `"`)
+	}
+	return scheme, params
+}
+
+// TargetHosts returns the distinct registry hosts of targets (registry URLs with an optional
+// repository path, such as harbor.example.com/dockerhub), sorted.
+func TargetHosts(targets []string) []string {
+	seen := make(map[string]bool)
+	var hosts []string
+	for _, target := range targets {
+		host := registryHost(target)
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// registryHost returns the host of a registry URL or credentials key, without scheme or path.
+func registryHost(target string) string {
+	target = strings.TrimSpace(target)
+	if i := strings.Index(target, "://"); i >= 0 {
+		target = target[i+3:]
+	}
+	host, _, _ := strings.Cut(target, "/")
+	return strings.ToLower(host)
+}
+
+// dockerConfigFile is the part of a Docker-style config.json (also written by `helm registry
+// login`) that holds registry credentials.
+type dockerConfigFile struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// LoadCredentials reads the registry credentials of Docker-style config files, keyed by registry
+// host. Missing files are skipped; when several files have credentials for a host, the first wins.
+// Hosts listed in credHelpers, and hosts of auths entries without inline credentials when a
+// credsStore is configured, are returned as External with the helper holding their credentials.
+func LoadCredentials(fs afero.Fs, paths ...string) (map[string]Credentials, error) {
+	credentials := make(map[string]Credentials)
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		data, err := afero.ReadFile(fs, path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read registry credentials file %s: %w", path, err)
+		}
+		var config dockerConfigFile
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse registry credentials file %s: %w", path, err)
+		}
+
+		add := func(host string, creds Credentials) {
+			if _, exists := credentials[host]; !exists && host != "" {
+				credentials[host] = creds
+			}
+		}
+		for key, auth := range config.Auths {
+			creds := Credentials{Username: auth.Username, Password: auth.Password}
+			if auth.Auth != "" {
+				decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+				if err != nil {
+					return nil, fmt.Errorf("invalid auth for %s in registry credentials file %s: %w", key, path, err)
+				}
+				creds.Username, creds.Password, _ = strings.Cut(string(decoded), ":")
+			}
+			if creds.Username == "" && creds.Password == "" {
+				if config.CredsStore == "" {
+					continue
+				}
+				creds.External = true
+				creds.Helper = config.CredsStore
+				creds.ServerURL = key
+			}
+			add(registryHost(key), creds)
+		}
+		for key, helper := range config.CredHelpers {
+			add(registryHost(key), Credentials{External: true, Helper: helper, ServerURL: key})
+		}
+	}
+	return credentials, nil
+}
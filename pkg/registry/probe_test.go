@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRegistry starts a TLS registry that requires user:secret, with a bearer token realm
+// when bearer is set, and returns it with its host.
+func newTestRegistry(t *testing.T, bearer bool) (server *httptest.Server, host string) {
+	t.Helper()
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		authorized := ok && user == "user" && pass == "secret"
+		switch {
+		case r.URL.Path == "/token" && authorized:
+			assert.Equal(t, "test-registry", r.URL.Query().Get("service"))
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/v2/" && authorized && !bearer:
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/v2/" && bearer:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="https://`+r.Host+`/token",service="test-registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, strings.TrimPrefix(server.URL, "https://")
+}
+
+func TestProberProbe(t *testing.T) {
+	open := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(open.Close)
+	openHost := strings.TrimPrefix(open.URL, "https://")
+	basic, basicHost := newTestRegistry(t, false)
+	_, bearerHost := newTestRegistry(t, true)
+
+	probe := func(host string, creds map[string]Credentials, checkAuth bool) ProbeResult {
+		prober := NewProber(creds, checkAuth)
+		prober.Client = basic.Client()
+		return prober.Probe(context.Background(), host)
+	}
+	valid := Credentials{Username: "user", Password: "secret"}
+	invalid := Credentials{Username: "user", Password: "wrong"}
+
+	result := probe(openHost, nil, true)
+	assert.Equal(t, ProbeStatusReachable, result.Status)
+	assert.True(t, result.OK())
+
+	result = probe(basicHost, nil, true)
+	assert.Equal(t, ProbeStatusNoCredentials, result.Status)
+	assert.False(t, result.OK())
+
+	assert.Equal(t, ProbeStatusCredentialsFound, probe(basicHost, map[string]Credentials{basicHost: invalid}, false).Status,
+		"credentials are not sent without the auth check")
+	assert.Equal(t, ProbeStatusAuthenticated, probe(basicHost, map[string]Credentials{basicHost: valid}, true).Status)
+	assert.Equal(t, ProbeStatusAuthFailed, probe(basicHost, map[string]Credentials{basicHost: invalid}, true).Status)
+	assert.Equal(t, ProbeStatusAuthenticated, probe(bearerHost, map[string]Credentials{bearerHost: valid}, true).Status)
+	assert.Equal(t, ProbeStatusCredentialsFound, probe(bearerHost, map[string]Credentials{bearerHost: {External: true}}, true).Status)
+
+	result = probe("127.0.0.1:1", nil, false)
+	assert.Equal(t, ProbeStatusUnreachable, result.Status)
+	require.Error(t, result.Err)
+
+	prober := NewProber(nil, false)
+	prober.Client = basic.Client()
+	results := prober.ProbeAll(context.Background(), []string{openHost, basicHost})
+	require.Len(t, results, 2)
+	assert.Equal(t, openHost, results[0].Host)
+	assert.Equal(t, ProbeStatusNoCredentials, results[1].Status)
+}
+
+func TestTargetHosts(t *testing.T) {
+	assert.Equal(t, []string{"ecr.example.com", "harbor.example.com:8443"}, TargetHosts([]string{
+		"harbor.example.com:8443/dockerhub",
+		"https://ecr.example.com",
+		"Harbor.example.com:8443/quay",
+		"",
+	}))
+}
+
+func TestLoadCredentials(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/helm/config.json", []byte(`{
+  "auths": {"harbor.example.com": {"auth": "dXNlcjpzZWNyZXQ="}}
+}`), 0o600))
+	require.NoError(t, afero.WriteFile(fs, "/docker/config.json", []byte(`{
+  "auths": {
+    "harbor.example.com": {"username": "other", "password": "other"},
+    "https://index.docker.io/v1/": {},
+    "ecr.example.com": {"username": "AWS", "password": "token"}
+  },
+  "credsStore": "desktop",
+  "credHelpers": {"123456789.dkr.ecr.us-east-1.amazonaws.com": "ecr-login"}
+}`), 0o600))
+
+	credentials, err := LoadCredentials(fs, "/helm/config.json", "/docker/config.json", "/missing/config.json")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Credentials{
+		"harbor.example.com":                        {Username: "user", Password: "secret"},
+		"index.docker.io":                           {External: true, Helper: "desktop", ServerURL: "https://index.docker.io/v1/"},
+		"ecr.example.com":                           {Username: "AWS", Password: "token"},
+		"123456789.dkr.ecr.us-east-1.amazonaws.com": {External: true, Helper: "ecr-login", ServerURL: "123456789.dkr.ecr.us-east-1.amazonaws.com"},
+	}, credentials)
+
+	require.NoError(t, afero.WriteFile(fs, "/bad/config.json", []byte("{"), 0o600))
+	_, err = LoadCredentials(fs, "/bad/config.json")
+	assert.Error(t, err)
+}
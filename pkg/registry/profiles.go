@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ProfileNames returns the names of the profiles defined in the config, sorted.
+func (c *Config) ProfileNames() []string {
+	return slices.Sorted(maps.Keys(c.Profiles))
+}
+
+// ApplyProfile layers the named profile over the top-level registries section. Profile mappings
+// replace top-level mappings with the same source and are otherwise appended; profile groups are
+// appended to the top-level groups and take over their sources from the top-level mappings and
+// groups. A profile's defaultTarget replaces the top-level one when set, and strictMode is enabled
+// if either enables it. An empty name leaves the config unchanged.
+func (c *Config) ApplyProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		available := "none defined"
+		if len(c.Profiles) > 0 {
+			available = strings.Join(c.ProfileNames(), ", ")
+		}
+		return fmt.Errorf("profile %q not found in registry config (available: %s)", name, available)
+	}
+	c.Registries.layer(profile)
+	return nil
+}
+
+// layer applies overlay over r, with the precedence described for ApplyProfile.
+func (r *RegConfig) layer(overlay RegConfig) {
+	merged := make([]RegMapping, 0, len(r.Mappings)+len(overlay.Mappings))
+	indexBySource := make(map[string]int, len(r.Mappings))
+	for _, mapping := range r.Mappings {
+		indexBySource[mapping.Source] = len(merged)
+		merged = append(merged, mapping)
+	}
+	groupSources := make(map[string]bool)
+	for _, group := range overlay.Groups {
+		for _, source := range group.Sources {
+			groupSources[source] = true
+		}
+	}
+	overlaySources := maps.Clone(groupSources)
+	for _, mapping := range overlay.Mappings {
+		overlaySources[mapping.Source] = true
+		if i, exists := indexBySource[mapping.Source]; exists {
+			merged[i] = mapping
+			continue
+		}
+		indexBySource[mapping.Source] = len(merged)
+		merged = append(merged, mapping)
+	}
+
+	// An overlay group takes over its sources from the mappings of r as well
+	r.Mappings = slices.DeleteFunc(merged, func(mapping RegMapping) bool {
+		return groupSources[mapping.Source]
+	})
+	r.Groups = append(withoutSources(r.Groups, overlaySources), overlay.Groups...)
+	if overlay.DefaultTarget != "" {
+		r.DefaultTarget = overlay.DefaultTarget
+	}
+	if overlay.DefaultRegistry != "" {
+		r.DefaultRegistry = overlay.DefaultRegistry
+	}
+	r.StrictMode = r.StrictMode || overlay.StrictMode
+}
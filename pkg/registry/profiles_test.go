@@ -0,0 +1,111 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const profilesConfig = `version: "1.0"
+registries:
+  defaultTarget: harbor.dev.local/default
+  mappings:
+    - source: docker.io
+      target: harbor.dev.local/docker
+    - source: quay.io
+      target: harbor.dev.local/quay
+profiles:
+  prod:
+    defaultTarget: harbor.prod.local/default
+    strictMode: true
+    mappings:
+      - source: docker.io
+        target: harbor.prod.local/docker
+      - source: ghcr.io
+        target: harbor.prod.local/github
+  staging:
+    defaultTarget: harbor.staging.local/default
+`
+
+func loadProfilesConfig(t *testing.T, content string) (*Config, error) {
+	t.Helper()
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/tmp/profiles.yaml", []byte(content), 0o644))
+	return LoadStructuredConfig(fs, "/tmp/profiles.yaml", true)
+}
+
+func TestApplyProfile(t *testing.T) {
+	t.Run("prod", func(t *testing.T) {
+		config, err := loadProfilesConfig(t, profilesConfig)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"prod", "staging"}, config.ProfileNames())
+
+		require.NoError(t, config.ApplyProfile("prod"))
+		assert.Equal(t, "harbor.prod.local/default", config.Registries.DefaultTarget)
+		assert.True(t, config.Registries.StrictMode)
+		assert.Equal(t, []Mapping{
+			{Source: "docker.io", Target: "harbor.prod.local/docker"},
+			{Source: "quay.io", Target: "harbor.dev.local/quay"},
+			{Source: "ghcr.io", Target: "harbor.prod.local/github"},
+		}, config.ToMappings().Entries)
+	})
+
+	t.Run("profile without mappings", func(t *testing.T) {
+		config, err := loadProfilesConfig(t, profilesConfig)
+		require.NoError(t, err)
+
+		require.NoError(t, config.ApplyProfile("staging"))
+		assert.Equal(t, "harbor.staging.local/default", config.Registries.DefaultTarget)
+		assert.False(t, config.Registries.StrictMode)
+		assert.Len(t, config.ToMappings().Entries, 2)
+	})
+
+	t.Run("no profile selected", func(t *testing.T) {
+		config, err := loadProfilesConfig(t, profilesConfig)
+		require.NoError(t, err)
+
+		require.NoError(t, config.ApplyProfile(""))
+		assert.Equal(t, "harbor.dev.local/default", config.Registries.DefaultTarget)
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		config, err := loadProfilesConfig(t, profilesConfig)
+		require.NoError(t, err)
+
+		err = config.ApplyProfile("qa")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `profile "qa" not found`)
+		assert.Contains(t, err.Error(), "available: prod, staging")
+	})
+}
+
+func TestLoadProfilesOnlyConfig(t *testing.T) {
+	config, err := loadProfilesConfig(t, `registries:
+  strictMode: true
+profiles:
+  prod:
+    mappings:
+      - source: docker.io
+        target: harbor.prod.local/docker
+`)
+	require.NoError(t, err)
+	require.NoError(t, config.ApplyProfile("prod"))
+	assert.Equal(t, []Mapping{{Source: "docker.io", Target: "harbor.prod.local/docker"}}, config.ToMappings().Entries)
+}
+
+func TestLoadInvalidProfile(t *testing.T) {
+	_, err := loadProfilesConfig(t, `registries:
+  mappings:
+    - source: docker.io
+      target: harbor.local/docker
+profiles:
+  prod:
+    mappings:
+      - source: docker.io
+        target: ""
+`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid profile "prod"`)
+}
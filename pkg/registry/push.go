@@ -0,0 +1,201 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPushTimeout bounds each request made while pushing to a registry.
+const DefaultPushTimeout = 60 * time.Second
+
+// maxPushErrorBodySize limits how much of an error response body is included in push errors
+const maxPushErrorBodySize = 512
+
+// Pusher uploads blobs and manifests to registries with the OCI distribution API, authenticating
+// with the credentials found for each registry. Tokens are cached per repository.
+type Pusher struct {
+	// Client sends the registry requests; its Timeout should bound each request.
+	Client *http.Client
+	// Credentials holds the credentials found for each registry host.
+	Credentials map[string]Credentials
+	// Helpers resolves credentials held by credential helpers and cloud providers; may be nil.
+	Helpers *CredentialHelpers
+	// PlainHTTP sends requests over HTTP instead of HTTPS, for local registries.
+	PlainHTTP bool
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// NewPusher creates a Pusher with DefaultPushTimeout and the given credentials.
+func NewPusher(credentials map[string]Credentials) *Pusher {
+	return &Pusher{
+		Client:      &http.Client{Timeout: DefaultPushTimeout},
+		Credentials: credentials,
+	}
+}
+
+// Digest returns the sha256 digest of data as used by registries, e.g. sha256:44136f...
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// PushBlob uploads data as a blob of repository on the registry host, unless the repository
+// already has it, and returns its digest.
+func (p *Pusher) PushBlob(ctx context.Context, host, repository string, data []byte) (string, error) {
+	host, repository = registryEndpoint(host, repository)
+	digest := Digest(data)
+	base := p.scheme() + "://" + host + "/v2/" + repository
+
+	resp, err := p.do(ctx, http.MethodHead, host, repository, base+"/blobs/"+digest, "", nil)
+	if err != nil {
+		return "", err
+	}
+	closeBody(resp)
+	if resp.StatusCode == http.StatusOK {
+		return digest, nil
+	}
+
+	resp, err = p.do(ctx, http.MethodPost, host, repository, base+"/blobs/uploads/", "", nil)
+	if err != nil {
+		return "", err
+	}
+	if err := pushStatus(resp, http.StatusAccepted, "start blob upload to "+host+"/"+repository); err != nil {
+		return "", err
+	}
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.Header.Get("Location") == "" {
+		return "", fmt.Errorf("registry %s returned no valid upload location for %s", host, repository)
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+
+	resp, err = p.do(ctx, http.MethodPut, host, repository, location.String(), "application/octet-stream", data)
+	if err != nil {
+		return "", err
+	}
+	if err := pushStatus(resp, http.StatusCreated, "upload blob "+digest+" to "+host+"/"+repository); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+// PushManifest uploads a manifest of mediaType to repository on the registry host under
+// reference, a tag, and returns its digest.
+func (p *Pusher) PushManifest(ctx context.Context, host, repository, reference, mediaType string, data []byte) (string, error) {
+	host, repository = registryEndpoint(host, repository)
+	target := p.scheme() + "://" + host + "/v2/" + repository + "/manifests/" + url.PathEscape(reference)
+	resp, err := p.do(ctx, http.MethodPut, host, repository, target, mediaType, data)
+	if err != nil {
+		return "", err
+	}
+	if err := pushStatus(resp, http.StatusCreated, "push manifest to "+host+"/"+repository+":"+reference); err != nil {
+		return "", err
+	}
+	return Digest(data), nil
+}
+
+// do sends a request to a registry, authenticating for pushes to repository when the registry
+// answers 401: with basic auth, or with a token from the bearer realm of its challenge.
+func (p *Pusher) do(ctx context.Context, method, host, repository, target, contentType string, body []byte) (*http.Response, error) {
+	tokenKey := host + "/" + repository
+	p.mu.Lock()
+	token := p.tokens[tokenKey]
+	p.mu.Unlock()
+
+	send := func(authorize func(*http.Request)) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid registry URL %s: %w", target, err)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if authorize != nil {
+			authorize(req)
+		}
+		resp, err := p.client().Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("request to %s failed: %w", target, err)
+		}
+		return resp, nil
+	}
+	bearer := func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+
+	var authorize func(*http.Request)
+	if token != "" {
+		authorize = bearer
+	}
+	resp, err := send(authorize)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	closeBody(resp)
+
+	creds, hasCreds := p.Helpers.Resolve(ctx, p.Credentials, host)
+	if hasCreds && creds.External {
+		hasCreds = false
+	}
+	scheme, params := parseChallenge(challenge)
+	switch {
+	case strings.EqualFold(scheme, "bearer"):
+		token, err = requestToken(ctx, p.client(), params, repository, "pull,push", creds, hasCreds)
+		if err != nil {
+			return nil, err
+		}
+		p.mu.Lock()
+		if p.tokens == nil {
+			p.tokens = make(map[string]string)
+		}
+		p.tokens[tokenKey] = token
+		p.mu.Unlock()
+		return send(bearer)
+	case strings.EqualFold(scheme, "basic") && hasCreds:
+		return send(func(req *http.Request) { req.SetBasicAuth(creds.Username, creds.Password) })
+	default:
+		return nil, fmt.Errorf("%s requires authentication and no credentials were found", host)
+	}
+}
+
+// pushStatus closes resp and fails unless it has the status expected for a push step, including
+// the registry's error message if there is one.
+func pushStatus(resp *http.Response, expected int, step string) error {
+	if resp.StatusCode == expected {
+		closeBody(resp)
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxPushErrorBodySize))
+	closeBody(resp)
+	if message := strings.TrimSpace(string(body)); message != "" {
+		return fmt.Errorf("failed to %s: %s: %s", step, resp.Status, message)
+	}
+	return fmt.Errorf("failed to %s: %s", step, resp.Status)
+}
+
+// scheme returns the URL scheme requests of p are sent with.
+func (p *Pusher) scheme() string {
+	if p.PlainHTTP {
+		return "http"
+	}
+	return "https"
+}
+
+// client returns the HTTP client of p.
+func (p *Pusher) client() *http.Client {
+	if p.Client == nil {
+		return http.DefaultClient
+	}
+	return p.Client
+}
@@ -0,0 +1,94 @@
+package registry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPushRegistry starts a TLS registry accepting pushes to team/overrides with a token issued
+// for the ci user, and returns it with its host and the blobs and manifests pushed to it.
+func newPushRegistry(t *testing.T) (server *httptest.Server, host string, blobs, manifests map[string]string) {
+	t.Helper()
+	var mu sync.Mutex
+	blobs, manifests = map[string]string{"sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a": "{}"}, map[string]string{}
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/token" {
+			assert.True(t, strings.HasSuffix(r.URL.Query().Get("scope"), ":pull,push"), "tokens are requested for pushes")
+			if user, password, _ := r.BasicAuth(); user != "ci" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"token": "push-token"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer push-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="https://`+r.Host+`/token",service="test-registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method == http.MethodHead && strings.HasPrefix(r.URL.Path, "/v2/team/overrides/blobs/"):
+			if _, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/team/overrides/blobs/")]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/team/overrides/blobs/uploads/":
+			w.Header().Set("Location", "/v2/team/overrides/blobs/uploads/session-1?state=abc")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodPut && r.URL.Path == "/v2/team/overrides/blobs/uploads/session-1":
+			assert.Equal(t, "abc", r.URL.Query().Get("state"))
+			blobs[r.URL.Query().Get("digest")] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/v2/team/overrides/manifests/"):
+			assert.Equal(t, "application/vnd.oci.image.manifest.v1+json", r.Header.Get("Content-Type"))
+			manifests[strings.TrimPrefix(r.URL.Path, "/v2/team/overrides/manifests/")] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.Error(w, `{"errors":[{"code":"DENIED"}]}`, http.StatusForbidden)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, strings.TrimPrefix(server.URL, "https://"), blobs, manifests
+}
+
+func TestPusherPush(t *testing.T) {
+	server, host, blobs, manifests := newPushRegistry(t)
+	pusher := NewPusher(map[string]Credentials{host: {Username: "ci", Password: "secret"}})
+	pusher.Client = server.Client()
+	ctx := context.Background()
+
+	digest, err := pusher.PushBlob(ctx, host, "team/overrides", []byte("{}"))
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a", digest, "existing blobs are not uploaded again")
+
+	digest, err = pusher.PushBlob(ctx, host, "team/overrides", []byte("image: {}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, Digest([]byte("image: {}\n")), digest)
+	assert.Equal(t, "image: {}\n", blobs[digest])
+
+	_, err = pusher.PushManifest(ctx, host, "team/overrides", "web", "application/vnd.oci.image.manifest.v1+json", []byte(`{"schemaVersion":2}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"web": `{"schemaVersion":2}`}, manifests)
+
+	_, err = pusher.PushManifest(ctx, host, "team/other", "web", "application/vnd.oci.image.manifest.v1+json", nil)
+	assert.ErrorContains(t, err, "403 Forbidden")
+}
+
+func TestPusherNoCredentials(t *testing.T) {
+	server, host, _, _ := newPushRegistry(t)
+	pusher := NewPusher(nil)
+	pusher.Client = server.Client()
+
+	_, err := pusher.PushBlob(context.Background(), host, "team/overrides", []byte("image: {}\n"))
+	assert.ErrorContains(t, err, "failed to get registry token")
+}
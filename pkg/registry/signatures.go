@@ -0,0 +1,134 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Artifact types of the cosign signatures and attestations attached to an image as OCI referrers.
+const (
+	// cosignSignatureArtifactType is a signature stored by cosign as a referrer
+	cosignSignatureArtifactType = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	// cosignAttestationArtifactType is an attestation stored by cosign as a referrer
+	cosignAttestationArtifactType = "application/vnd.dev.cosign.attestation.v1+json"
+	// sigstoreBundleArtifactType is a Sigstore bundle, which cosign stores for signatures and
+	// attestations alike
+	sigstoreBundleArtifactType = "application/vnd.dev.sigstore.bundle"
+	// inTotoArtifactType is an in-toto attestation
+	inTotoArtifactType = "application/vnd.in-toto+json"
+)
+
+// referrersMediaType is the media type of the image index listing the referrers of a manifest.
+const referrersMediaType = "application/vnd.oci.image.index.v1+json"
+
+// ImageSignatures lists the cosign signatures and attestations of an image in its source
+// registry. Each artifact is a tag or a digest in the repository of the image, so it can be copied
+// along with the image: the sha256-<digest>.sig and .att tags cosign stores them under by default,
+// and the digests of OCI referrers of the image.
+type ImageSignatures struct {
+	// Digest is the digest of the manifest the artifacts refer to
+	Digest string `json:"digest,omitempty" yaml:"digest,omitempty"`
+	// Signatures are the tags and digests of the signatures of the image
+	Signatures []string `json:"signatures,omitempty" yaml:"signatures,omitempty"`
+	// Attestations are the tags and digests of the attestations of the image
+	Attestations []string `json:"attestations,omitempty" yaml:"attestations,omitempty"`
+	// Error explains why the registry could not be checked, or only partly
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Signed reports whether any signature or attestation was found.
+func (s ImageSignatures) Signed() bool {
+	return len(s.Signatures) > 0 || len(s.Attestations) > 0
+}
+
+// Artifacts returns the signatures followed by the attestations.
+func (s ImageSignatures) Artifacts() []string {
+	return append(slices.Clone(s.Signatures), s.Attestations...)
+}
+
+// Signatures finds the cosign signatures and attestations of the image reference (a tag or a
+// sha256 digest) in repository on the registry host. It looks for the tags cosign derives from the
+// image digest, then asks the OCI referrers API, falling back to the referrers tag of registries
+// that do not implement it. Registry errors are recorded, not returned.
+func (c *TagChecker) Signatures(ctx context.Context, host, repository, reference string) ImageSignatures {
+	digest := reference
+	if !strings.HasPrefix(reference, "sha256:") {
+		var err error
+		digest, err = c.ImageDigest(ctx, host, repository, reference)
+		if err != nil {
+			return ImageSignatures{Error: err.Error()}
+		}
+	}
+	host, repository = registryEndpoint(host, repository)
+	result := ImageSignatures{Digest: digest}
+	digestTag := strings.Replace(digest, ":", "-", 1)
+
+	var problems []string
+	for _, artifact := range []struct {
+		tag  string
+		list *[]string
+	}{
+		{digestTag + ".sig", &result.Signatures},
+		{digestTag + ".att", &result.Attestations},
+	} {
+		exists, err := c.tagExists(ctx, host, repository, artifact.tag)
+		if err != nil {
+			problems = append(problems, err.Error())
+		} else if exists {
+			*artifact.list = append(*artifact.list, artifact.tag)
+		}
+	}
+
+	referrers, err := c.referrers(ctx, host, repository, digest)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	for _, referrer := range referrers {
+		switch {
+		case referrer.ArtifactType == cosignSignatureArtifactType,
+			strings.HasPrefix(referrer.ArtifactType, sigstoreBundleArtifactType):
+			result.Signatures = append(result.Signatures, referrer.Digest)
+		case referrer.ArtifactType == cosignAttestationArtifactType, referrer.ArtifactType == inTotoArtifactType:
+			result.Attestations = append(result.Attestations, referrer.Digest)
+		}
+	}
+	result.Error = strings.Join(problems, "; ")
+	return result
+}
+
+// referrer is a descriptor of the image index listing the referrers of a manifest.
+type referrer struct {
+	Digest       string `json:"digest"`
+	ArtifactType string `json:"artifactType"`
+}
+
+// referrers returns the referrers of the manifest digest in repository. Registries without the
+// referrers API answer 404; their referrers are read from the sha256-<digest> tag of the fallback
+// tag schema, and none are returned when that tag does not exist either.
+func (c *TagChecker) referrers(ctx context.Context, host, repository, digest string) ([]referrer, error) {
+	var index struct {
+		Manifests []referrer `json:"manifests"`
+	}
+	for _, target := range []string{
+		"https://" + host + "/v2/" + repository + "/referrers/" + digest,
+		"https://" + host + "/v2/" + repository + "/manifests/" + url.PathEscape(strings.Replace(digest, ":", "-", 1)),
+	} {
+		resp, err := c.do(ctx, http.MethodGet, host, repository, target, []string{referrersMediaType})
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			closeBody(resp)
+			continue
+		}
+		if err := decodeResponse(resp, &index); err != nil {
+			return nil, fmt.Errorf("failed to list the referrers of %s/%s@%s: %w", host, repository, digest, err)
+		}
+		return index.Manifests, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSignedRegistry starts a TLS registry with a team/app image signed with cosign's tag
+// scheme and the referrers API, a team/legacy image whose referrers are only found through the
+// fallback tag schema, and an unsigned team/plain image.
+func newSignedRegistry(t *testing.T) (server *httptest.Server, host string) {
+	t.Helper()
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/team/app/manifests/1.0.0":
+			w.Header().Set("Docker-Content-Digest", "sha256:app")
+		case "/v2/team/legacy/manifests/2.0.0":
+			w.Header().Set("Docker-Content-Digest", "sha256:legacy")
+		case "/v2/team/plain/manifests/1.0.0":
+			w.Header().Set("Docker-Content-Digest", "sha256:plain")
+		case "/v2/team/app/manifests/sha256-app.sig":
+		case "/v2/team/app/referrers/sha256:app":
+			assert.Equal(t, referrersMediaType, r.Header.Get("Accept"))
+			_, _ = w.Write([]byte(`{"manifests": [
+				{"digest": "sha256:sig", "artifactType": "application/vnd.dev.cosign.artifact.sig.v1+json"},
+				{"digest": "sha256:provenance", "artifactType": "application/vnd.in-toto+json"},
+				{"digest": "sha256:sbom", "artifactType": "application/spdx+json"}
+			]}`))
+		case "/v2/team/legacy/manifests/sha256-legacy":
+			_, _ = w.Write([]byte(`{"manifests": [
+				{"digest": "sha256:bundle", "artifactType": "application/vnd.dev.sigstore.bundle.v0.3+json"}
+			]}`))
+		case "/v2/team/plain/referrers/sha256:plain":
+			_, _ = w.Write([]byte(`{"manifests": []}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, strings.TrimPrefix(server.URL, "https://")
+}
+
+func TestTagCheckerSignatures(t *testing.T) {
+	server, host := newSignedRegistry(t)
+	checker := NewTagChecker(nil, 0)
+	checker.Client = server.Client()
+	ctx := context.Background()
+
+	signatures := checker.Signatures(ctx, host, "team/app", "1.0.0")
+	assert.Equal(t, ImageSignatures{
+		Digest:       "sha256:app",
+		Signatures:   []string{"sha256-app.sig", "sha256:sig"},
+		Attestations: []string{"sha256:provenance"},
+	}, signatures)
+	assert.True(t, signatures.Signed())
+	assert.Equal(t, []string{"sha256-app.sig", "sha256:sig", "sha256:provenance"}, signatures.Artifacts())
+
+	assert.Equal(t, ImageSignatures{Digest: "sha256:app", Signatures: []string{"sha256-app.sig", "sha256:sig"}, Attestations: []string{"sha256:provenance"}},
+		checker.Signatures(ctx, host, "team/app", "sha256:app"), "images pinned by digest are not resolved")
+
+	assert.Equal(t, ImageSignatures{Digest: "sha256:legacy", Signatures: []string{"sha256:bundle"}},
+		checker.Signatures(ctx, host, "team/legacy", "2.0.0"), "referrers are read from the fallback tag without the referrers API")
+
+	plain := checker.Signatures(ctx, host, "team/plain", "1.0.0")
+	assert.Equal(t, ImageSignatures{Digest: "sha256:plain"}, plain)
+	assert.False(t, plain.Signed())
+
+	missing := checker.Signatures(ctx, host, "team/app", "9.9.9")
+	require.NotEmpty(t, missing.Error)
+	assert.Contains(t, missing.Error, ErrManifestNotFound.Error())
+	assert.False(t, missing.Signed())
+}
@@ -0,0 +1,130 @@
+// Package registry provides functionality for mapping container registry names.
+package registry
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/lucas-albers-lz4/irr/pkg/image"
+)
+
+// TagTransformData is the data available to a mapping's tagTransform template.
+type TagTransformData struct {
+	// Tag is the image tag after defaults have been applied (may be empty for digest-only images)
+	Tag string
+	// Digest is the image digest, if any
+	Digest string
+	// Repository is the original repository path (e.g., library/nginx)
+	Repository string
+}
+
+// validTag is the grammar of image tags in the OCI distribution spec
+var validTag = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]{0,127}$`)
+
+// parseTagTransform parses a tagTransform template, failing on unknown fields at execution time.
+func parseTagTransform(tmpl string) (*template.Template, error) {
+	parsed, err := template.New("tagTransform").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tagTransform template %q: %w", tmpl, err)
+	}
+	return parsed, nil
+}
+
+// ValidateTagTransform checks that tmpl parses and renders with sample data.
+func ValidateTagTransform(tmpl string) error {
+	_, err := TransformTag(tmpl, TagTransformData{Tag: "1.0.0", Digest: "sha256:" + strings.Repeat("0", 64), Repository: "library/sample"})
+	return err
+}
+
+// TransformTag renders a tagTransform template (e.g. "mirror-{{ .Tag }}") and returns the new tag.
+// Surrounding whitespace is trimmed; an empty result, or one that is not a valid image tag, is an error.
+func TransformTag(tmpl string, data TagTransformData) (string, error) {
+	parsed, err := parseTagTransform(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := parsed.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to apply tagTransform %q: %w", tmpl, err)
+	}
+	tag := strings.TrimSpace(buf.String())
+	if tag == "" {
+		return "", fmt.Errorf("tagTransform %q produced an empty tag for %s", tmpl, data.Repository)
+	}
+	if !validTag.MatchString(tag) {
+		return "", fmt.Errorf("tagTransform %q produced %q for %s, which is not a valid image tag", tmpl, tag, data.Repository)
+	}
+	return tag, nil
+}
+
+// TagTransformUsesDigest reports whether a tagTransform template reads .Digest, and so can render
+// a tag for images pinned by digest only. Templates that do not parse use nothing.
+func TagTransformUsesDigest(tmpl string) bool {
+	parsed, err := parseTagTransform(tmpl)
+	if err != nil {
+		return false
+	}
+	return nodeReadsField(parsed.Root, "Digest")
+}
+
+// nodeReadsField reports whether node, or any node below it, reads the field name of the data.
+func nodeReadsField(node parse.Node, name string) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, child := range n.Nodes {
+			if nodeReadsField(child, name) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return nodeReadsField(n.Pipe, name)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, command := range n.Cmds {
+			if nodeReadsField(command, name) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if nodeReadsField(arg, name) {
+				return true
+			}
+		}
+	case *parse.IfNode:
+		return nodeReadsField(n.Pipe, name) || nodeReadsField(n.List, name) || nodeReadsField(n.ElseList, name)
+	case *parse.RangeNode:
+		return nodeReadsField(n.Pipe, name) || nodeReadsField(n.List, name) || nodeReadsField(n.ElseList, name)
+	case *parse.WithNode:
+		return nodeReadsField(n.Pipe, name) || nodeReadsField(n.List, name) || nodeReadsField(n.ElseList, name)
+	case *parse.FieldNode:
+		return len(n.Ident) > 0 && n.Ident[0] == name
+	case *parse.VariableNode:
+		return len(n.Ident) > 1 && n.Ident[0] == "$" && n.Ident[1] == name
+	}
+	return false
+}
+
+// GetTagTransform returns the tagTransform template of the mapping for a source registry,
+// or an empty string if the registry has no mapping or the mapping has no transform.
+func (m *Mappings) GetTagTransform(source string) string {
+	if m == nil {
+		return ""
+	}
+	normalizedSource := image.NormalizeRegistry(strings.TrimSpace(source))
+	for _, mapping := range m.Entries {
+		if image.NormalizeRegistry(strings.TrimSpace(mapping.Source)) == normalizedSource {
+			return strings.TrimSpace(mapping.TagTransform)
+		}
+	}
+	return ""
+}
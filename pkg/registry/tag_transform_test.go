@@ -0,0 +1,102 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/fileutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformTag(t *testing.T) {
+	data := TagTransformData{Tag: "1.25", Digest: "sha256:abc", Repository: "library/nginx"}
+
+	tests := []struct {
+		name          string
+		tmpl          string
+		want          string
+		errorContains string
+	}{
+		{name: "prefix", tmpl: "mirror-{{ .Tag }}", want: "mirror-1.25"},
+		{name: "repository and tag", tmpl: "{{ .Repository | printf \"%.7s\" }}-{{ .Tag }}", want: "library-1.25"},
+		{name: "pin latest", tmpl: `{{ if eq .Tag "latest" }}1.0.0{{ else }}{{ .Tag }}{{ end }}`, want: "1.25"},
+		{name: "surrounding whitespace trimmed", tmpl: " {{ .Tag }} ", want: "1.25"},
+		{name: "parse error", tmpl: "{{ .Tag ", errorContains: "invalid tagTransform template"},
+		{name: "unknown field", tmpl: "{{ .Version }}", errorContains: "failed to apply tagTransform"},
+		{name: "empty result", tmpl: "{{ .Tag | printf \"%.0s\" }}", errorContains: "produced an empty tag"},
+		{name: "invalid tag", tmpl: "{{ .Tag }}@{{ .Digest }}", errorContains: "not a valid image tag"},
+		{name: "leading dot", tmpl: ".{{ .Tag }}", errorContains: "not a valid image tag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TransformTag(tt.tmpl, data)
+			if tt.errorContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTagTransformUsesDigest(t *testing.T) {
+	assert.True(t, TagTransformUsesDigest("{{ slice .Digest 7 19 }}"))
+	assert.True(t, TagTransformUsesDigest(`{{ if .Tag }}{{ .Tag }}{{ else }}{{ printf "%.19s" $.Digest }}{{ end }}`))
+	assert.False(t, TagTransformUsesDigest("mirror-{{ .Tag }}"))
+	assert.False(t, TagTransformUsesDigest("{{ .Tag "), "templates that do not parse use nothing")
+}
+
+func TestGetTagTransform(t *testing.T) {
+	mappings := &Mappings{Entries: []Mapping{
+		{Source: "docker.io", Target: "harbor.local/dockerhub", TagTransform: "mirror-{{ .Tag }}"},
+		{Source: "quay.io", Target: "harbor.local/quay"},
+	}}
+
+	assert.Equal(t, "mirror-{{ .Tag }}", mappings.GetTagTransform("docker.io"))
+	assert.Equal(t, "mirror-{{ .Tag }}", mappings.GetTagTransform("index.docker.io"), "source registries are normalized")
+	assert.Empty(t, mappings.GetTagTransform("quay.io"))
+	assert.Empty(t, mappings.GetTagTransform("ghcr.io"))
+
+	var nilMappings *Mappings
+	assert.Empty(t, nilMappings.GetTagTransform("docker.io"))
+}
+
+func TestLoadStructuredConfigTagTransform(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, fs.MkdirAll(TestTmpDir, fileutil.ReadWriteExecuteUserReadExecuteOthers))
+
+	validFile := filepath.Join(TestTmpDir, "tag-transform.yaml")
+	validContent := `
+version: "1"
+registries:
+  mappings:
+    - source: docker.io
+      target: harbor.example.com/docker
+      tagTransform: "mirror-{{ .Tag }}"
+`
+	invalidFile := filepath.Join(TestTmpDir, "invalid-tag-transform.yaml")
+	invalidContent := `
+version: "1"
+registries:
+  mappings:
+    - source: docker.io
+      target: harbor.example.com/docker
+      tagTransform: "{{ .Version }}"
+`
+	require.NoError(t, afero.WriteFile(fs, validFile, []byte(validContent), fileutil.ReadWriteUserReadOthers))
+	require.NoError(t, afero.WriteFile(fs, invalidFile, []byte(invalidContent), fileutil.ReadWriteUserReadOthers))
+
+	config, err := LoadStructuredConfig(fs, validFile, true)
+	require.NoError(t, err)
+	require.Len(t, config.Registries.Mappings, 1)
+	assert.Equal(t, "mirror-{{ .Tag }}", config.Registries.Mappings[0].TagTransform)
+	assert.Equal(t, "mirror-{{ .Tag }}", config.ToMappings().GetTagTransform("docker.io"))
+
+	_, err = LoadStructuredConfig(fs, invalidFile, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid tagTransform for source 'docker.io'")
+}
@@ -0,0 +1,147 @@
+package registry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// TLSConfig controls how the TLS certificate of a registry is verified, like Helm's --ca-file and
+// --insecure-skip-tls-verify flags.
+type TLSConfig struct {
+	// CAFile is a PEM bundle of certificate authorities trusted in addition to the system ones
+	CAFile string `yaml:"caFile,omitempty"`
+	// InsecureSkipTLSVerify disables verification of the registry's certificate
+	InsecureSkipTLSVerify bool `yaml:"insecureSkipTLSVerify,omitempty"`
+}
+
+// IsZero reports whether c leaves certificate verification at its default.
+func (c TLSConfig) IsZero() bool {
+	return c.CAFile == "" && !c.InsecureSkipTLSVerify
+}
+
+// String describes the settings of c, e.g. "caFile certs/harbor.pem, insecureSkipTLSVerify".
+func (c TLSConfig) String() string {
+	var settings []string
+	if c.CAFile != "" {
+		settings = append(settings, "caFile "+c.CAFile)
+	}
+	if c.InsecureSkipTLSVerify {
+		settings = append(settings, "insecureSkipTLSVerify")
+	}
+	if len(settings) == 0 {
+		return "default verification"
+	}
+	return strings.Join(settings, ", ")
+}
+
+// TransportOptions configure the HTTP transport shared by all network operations.
+type TransportOptions struct {
+	// Default applies to every host
+	Default TLSConfig
+	// Registries adds settings for individual registry hosts (host or host:port), as set in the
+	// tls section of the registry mappings file. Their CA files are trusted in addition to the
+	// default one, and either setting can disable verification.
+	Registries map[string]TLSConfig
+}
+
+// NewTransport returns the transport for requests to registries and chart repositories. It
+// connects through the proxy set by the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment
+// variables and verifies certificates as opts configure for the host of each request. CA files
+// are read from fs when the transport is created, so a missing or invalid file fails early.
+func NewTransport(fs afero.Fs, opts TransportOptions) (http.RoundTripper, error) {
+	defaultTransport, err := newHTTPTransport(fs, opts.Default.InsecureSkipTLSVerify, opts.Default.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Registries) == 0 {
+		return defaultTransport, nil
+	}
+
+	hosts := make([]string, 0, len(opts.Registries))
+	for host := range opts.Registries {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	transport := &registryTransport{defaultTransport: defaultTransport, registries: make(map[string]http.RoundTripper)}
+	for _, host := range hosts {
+		config := opts.Registries[host]
+		insecure := opts.Default.InsecureSkipTLSVerify || config.InsecureSkipTLSVerify
+		hostTransport, err := newHTTPTransport(fs, insecure, opts.Default.CAFile, config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS settings for registry %s: %w", host, err)
+		}
+		transport.registries[strings.ToLower(host)] = hostTransport
+	}
+	return transport, nil
+}
+
+// NewTLSClientConfig returns the TLS configuration that trusts the system certificate
+// authorities and those in the CA files, or that skips verification when insecure is set.
+func NewTLSClientConfig(fs afero.Fs, insecure bool, caFiles ...string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if insecure {
+		config.InsecureSkipVerify = true //nolint:gosec // requested with --insecure-skip-tls-verify
+		return config, nil
+	}
+
+	var pool *x509.CertPool
+	for _, caFile := range caFiles {
+		if caFile == "" {
+			continue
+		}
+		if pool == nil {
+			systemPool, err := x509.SystemCertPool()
+			if err != nil {
+				systemPool = x509.NewCertPool()
+			}
+			pool = systemPool
+		}
+		data, err := afero.ReadFile(fs, caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file '%s': %w", caFile, err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("CA file '%s' contains no PEM certificates", caFile)
+		}
+	}
+	config.RootCAs = pool
+	return config, nil
+}
+
+// newHTTPTransport returns a transport with the timeouts of http.DefaultTransport, the proxy from
+// the environment and the TLS configuration of NewTLSClientConfig.
+func newHTTPTransport(fs afero.Fs, insecure bool, caFiles ...string) (*http.Transport, error) {
+	tlsConfig, err := NewTLSClientConfig(fs, insecure, caFiles...)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// registryTransport sends requests to hosts with their own TLS settings through their transport
+// and all other requests through the default one.
+type registryTransport struct {
+	defaultTransport http.RoundTripper
+	registries       map[string]http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *registryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Host)
+	if transport, ok := t.registries[host]; ok {
+		return transport.RoundTrip(req)
+	}
+	if transport, ok := t.registries[strings.ToLower(req.URL.Hostname())]; ok {
+		return transport.RoundTrip(req)
+	}
+	return t.defaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,77 @@
+package registry
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	fs := afero.NewMemMapFs()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, afero.WriteFile(fs, "registry-ca.pem", caPEM, 0o644))
+	require.NoError(t, afero.WriteFile(fs, "empty.pem", []byte("not a certificate"), 0o644))
+
+	get := func(t *testing.T, opts TransportOptions) error {
+		t.Helper()
+		transport, err := NewTransport(fs, opts)
+		require.NoError(t, err)
+		client := &http.Client{Transport: transport}
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			closeBody(resp)
+		}
+		return err
+	}
+
+	t.Run("unknown certificate authority", func(t *testing.T) {
+		assert.ErrorContains(t, get(t, TransportOptions{}), "certificate")
+	})
+	t.Run("ca file", func(t *testing.T) {
+		assert.NoError(t, get(t, TransportOptions{Default: TLSConfig{CAFile: "registry-ca.pem"}}))
+	})
+	t.Run("insecure", func(t *testing.T) {
+		assert.NoError(t, get(t, TransportOptions{Default: TLSConfig{InsecureSkipTLSVerify: true}}))
+	})
+	t.Run("per registry", func(t *testing.T) {
+		assert.NoError(t, get(t, TransportOptions{Registries: map[string]TLSConfig{serverURL.Host: {CAFile: "registry-ca.pem"}}}))
+		assert.NoError(t, get(t, TransportOptions{Registries: map[string]TLSConfig{serverURL.Hostname(): {InsecureSkipTLSVerify: true}}}),
+			"settings for a host without port apply to every port")
+		assert.Error(t, get(t, TransportOptions{Registries: map[string]TLSConfig{"harbor.local": {InsecureSkipTLSVerify: true}}}),
+			"other hosts keep the default verification")
+	})
+
+	t.Run("proxy from the environment", func(t *testing.T) {
+		transport, err := NewTransport(fs, TransportOptions{})
+		require.NoError(t, err)
+		httpTransport, ok := transport.(*http.Transport)
+		require.True(t, ok)
+		assert.NotNil(t, httpTransport.Proxy)
+	})
+
+	t.Run("invalid ca files", func(t *testing.T) {
+		_, err := NewTransport(fs, TransportOptions{Default: TLSConfig{CAFile: "missing.pem"}})
+		assert.ErrorContains(t, err, "failed to read CA file 'missing.pem'")
+		_, err = NewTransport(fs, TransportOptions{Registries: map[string]TLSConfig{"harbor.local": {CAFile: "empty.pem"}}})
+		assert.ErrorContains(t, err, "invalid TLS settings for registry harbor.local: CA file 'empty.pem' contains no PEM certificates")
+	})
+}
+
+func TestTLSConfigString(t *testing.T) {
+	assert.Equal(t, "default verification", TLSConfig{}.String())
+	assert.Equal(t, "caFile ca.pem, insecureSkipTLSVerify", TLSConfig{CAFile: "ca.pem", InsecureSkipTLSVerify: true}.String())
+	assert.True(t, TLSConfig{}.IsZero())
+}
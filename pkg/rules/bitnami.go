@@ -12,6 +12,9 @@ import (
 // BitnamiAllowInsecureImagesPath is the Helm values path for Bitnami insecure image bypass.
 const BitnamiAllowInsecureImagesPath = "global.security.allowInsecureImages"
 
+// BitnamiSecurityBypassRuleName is the name of the Bitnami security bypass rule.
+const BitnamiSecurityBypassRuleName = "bitnami-security-bypass"
+
 // BitnamiSecurityBypassPriority is the priority assigned to the Bitnami security bypass rule.
 // Higher numbers mean higher priority.
 const BitnamiSecurityBypassPriority = 100
@@ -26,7 +29,7 @@ type BitnamiSecurityBypassRule struct {
 func NewBitnamiSecurityBypassRule() *BitnamiSecurityBypassRule {
 	return &BitnamiSecurityBypassRule{
 		BaseRule: NewBaseRule(
-			"bitnami-security-bypass",
+			BitnamiSecurityBypassRuleName,
 			"Adds global.security.allowInsecureImages=true to override files for Bitnami charts",
 			[]Parameter{
 				{
@@ -55,6 +58,23 @@ func (r *BitnamiSecurityBypassRule) AppliesTo(ch *chart.Chart) (Detection, bool)
 	return detection, false
 }
 
+// FindBitnamiChart returns the name of the first chart in the tree (the chart itself, then its
+// subcharts depth-first) detected as a Bitnami chart with medium or high confidence.
+func FindBitnamiChart(ch *chart.Chart) (string, bool) {
+	if ch == nil {
+		return "", false
+	}
+	if detectBitnamiChart(ch).Confidence >= ConfidenceMedium {
+		return ch.Name(), true
+	}
+	for _, sub := range ch.Dependencies() {
+		if name, ok := FindBitnamiChart(sub); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
 // BitnamiFallbackHandler provides a fallback mechanism for handling Bitnami charts
 // that fail with exit code 16 and specific error messages
 type BitnamiFallbackHandler struct {
@@ -77,6 +77,29 @@ func TestAppliesTo(t *testing.T) {
 	assert.Equal(t, ConfidenceNone, detectionNon.Confidence)
 }
 
+// TestFindBitnamiChart tests Bitnami detection across a chart and its subcharts.
+func TestFindBitnamiChart(t *testing.T) {
+	postgresql := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:    "postgresql",
+			Home:    "https://bitnami.com",
+			Sources: []string{"https://github.com/bitnami/charts/tree/main/bitnami/postgresql"},
+		},
+	}
+	umbrella := &chart.Chart{Metadata: &chart.Metadata{Name: "platform", Home: "https://example.com"}}
+	umbrella.AddDependency(&chart.Chart{Metadata: &chart.Metadata{Name: "web"}}, postgresql)
+
+	name, found := FindBitnamiChart(umbrella)
+	assert.True(t, found, "Bitnami subchart should be found")
+	assert.Equal(t, "postgresql", name)
+
+	_, found = FindBitnamiChart(&chart.Chart{Metadata: &chart.Metadata{Name: "web"}})
+	assert.False(t, found, "Non-Bitnami chart should not be found")
+
+	_, found = FindBitnamiChart(nil)
+	assert.False(t, found, "Nil chart should not be found")
+}
+
 func TestBitnamiFallbackHandler_ShouldRetryWithSecurityBypass(t *testing.T) {
 	// Test the handler for Bitnami security error detection
 	// See https://github.com/bitnami/charts/issues/30850 for details on the security bypass mechanism
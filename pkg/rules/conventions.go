@@ -0,0 +1,337 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/keys"
+	"github.com/lucas-albers-lz4/irr/pkg/log"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// ImageConvention describes a non-standard image key layout used by a family of charts, such as
+// the operatorImage and relatedImages keys of operator charts, so that their images are found
+// without custom include patterns.
+type ImageConvention struct {
+	// Name identifies the convention; a rules file convention replaces the built-in one of the same name
+	Name string `json:"name" yaml:"name"`
+	// Charts are globs of the chart or subchart names the convention applies to; empty applies to
+	// every chart. Paths of a matching subchart are relative to its values.
+	Charts []string `json:"charts,omitempty" yaml:"charts,omitempty"`
+	// Images are globs of the values paths holding complete image references, such as
+	// relatedImages[].image; [] matches any sequence index
+	Images []string `json:"images,omitempty" yaml:"images,omitempty"`
+	// SplitImages are images whose reference is split across keys of one map
+	SplitImages []SplitImageKeys `json:"splitImages,omitempty" yaml:"splitImages,omitempty"`
+}
+
+// SplitImageKeys names the keys of a map that an image reference is split across, such as
+// imageRegistry, imageRepository and imageTag.
+type SplitImageKeys struct {
+	// Path is a glob of the values path of the map holding the keys; empty for the top level
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Registry is the key holding the registry host; empty when the repository includes it
+	Registry string `json:"registry,omitempty" yaml:"registry,omitempty"`
+	// Repository is the key holding the repository
+	Repository string `json:"repository" yaml:"repository"`
+	// Tag is the key holding the tag
+	Tag string `json:"tag,omitempty" yaml:"tag,omitempty"`
+}
+
+// ImageConventionsFile is the rules file read by --rules-file.
+type ImageConventionsFile struct {
+	ImageConventions []ImageConvention `json:"imageConventions" yaml:"imageConventions"`
+}
+
+// ConventionImage is an image found at a values path described by an image convention.
+type ConventionImage struct {
+	// Convention is the name of the convention that matched
+	Convention string
+	// Path is the values path of the image string, or of the map holding a split image's keys
+	Path string
+	// Value is the image reference, composed from the keys of a split image
+	Value string
+	// Keys maps the image fields (registry, repository, tag) of a split image to the keys below
+	// Path holding them; nil for image strings
+	Keys map[string]string
+}
+
+// DefaultImageConventions is the built-in convention library.
+var DefaultImageConventions = []ImageConvention{
+	{
+		// Charts that keep an image in imageRegistry/imageRepository/imageTag keys of one map
+		Name: "split-image-keys",
+		SplitImages: []SplitImageKeys{
+			{Path: "*", Registry: "imageRegistry", Repository: "imageRepository", Tag: "imageTag"},
+		},
+	},
+	{
+		// Operator charts with the operator and its kube-rbac-proxy sidecar as image strings
+		Name:   "operator-images",
+		Images: []string{"operatorImage", "*.operatorImage", "kubeRbacProxy.image", "*.kubeRbacProxy.image"},
+	},
+	{
+		// OLM-style lists of the images an operator deploys, as strings or {name, image} maps
+		Name:   "related-images",
+		Images: []string{"relatedImages[]", "relatedImages[].image", "*.relatedImages[]", "*.relatedImages[].image"},
+	},
+	{
+		// CloudNativePG passes the operand images to the operator through its config map
+		Name:   "cloudnative-pg",
+		Charts: []string{"cloudnative-pg"},
+		Images: []string{"config.data.POSTGRES_IMAGE_NAME", "config.data.PGBOUNCER_IMAGE_NAME"},
+	},
+	{
+		// Strimzi builds every image from top-level defaults; the repository is the organization
+		// shared by all images, so relocating it relocates them all
+		Name:   "strimzi",
+		Charts: []string{"strimzi-kafka-operator"},
+		SplitImages: []SplitImageKeys{
+			{Registry: "defaultImageRegistry", Repository: "defaultImageRepository", Tag: "defaultImageTag"},
+		},
+	},
+}
+
+// LoadImageConventions reads the image conventions of a rules file.
+func LoadImageConventions(fs afero.Fs, file string) ([]ImageConvention, error) {
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file %s: %w", file, err)
+	}
+	var rulesFile ImageConventionsFile
+	if err := yaml.Unmarshal(data, &rulesFile); err != nil {
+		return nil, fmt.Errorf("failed to parse rules file %s: %w", file, err)
+	}
+	for i := range rulesFile.ImageConventions {
+		if err := rulesFile.ImageConventions[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid image convention %d in rules file %s: %w", i+1, file, err)
+		}
+	}
+	return rulesFile.ImageConventions, nil
+}
+
+// Validate checks that the convention is named, describes at least one image key and has valid globs.
+func (c *ImageConvention) Validate() error {
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(c.Images) == 0 && len(c.SplitImages) == 0 {
+		return fmt.Errorf("convention %s has no images or splitImages", c.Name)
+	}
+	for _, pattern := range c.Charts {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("convention %s: invalid chart glob %q: %w", c.Name, pattern, err)
+		}
+	}
+	for _, pattern := range c.Images {
+		if _, err := path.Match(valuesPathGlob(pattern), ""); err != nil || pattern == "" {
+			return fmt.Errorf("convention %s: invalid image path glob %q", c.Name, pattern)
+		}
+	}
+	for _, split := range c.SplitImages {
+		if split.Repository == "" {
+			return fmt.Errorf("convention %s: splitImages entry at %q has no repository key", c.Name, split.Path)
+		}
+		if _, err := path.Match(valuesPathGlob(split.Path), ""); err != nil {
+			return fmt.Errorf("convention %s: invalid splitImages path glob %q: %w", c.Name, split.Path, err)
+		}
+	}
+	return nil
+}
+
+// MergeImageConventions returns base with the conventions of extra added; a convention in extra
+// replaces the one in base with the same name.
+func MergeImageConventions(base, extra []ImageConvention) []ImageConvention {
+	merged := make([]ImageConvention, 0, len(base)+len(extra))
+	replaced := make(map[string]bool, len(extra))
+	for _, convention := range extra {
+		replaced[convention.Name] = true
+	}
+	for _, convention := range base {
+		if replaced[convention.Name] {
+			log.Debug("Image convention replaced by rules file", "convention", convention.Name)
+			continue
+		}
+		merged = append(merged, convention)
+	}
+	return append(merged, extra...)
+}
+
+// FindConventionImages returns the images in values at the paths described by conventions that
+// apply to ch or its subcharts, sorted by path. When several conventions describe the same path,
+// the first one wins.
+func FindConventionImages(conventions []ImageConvention, ch *chart.Chart, values map[string]interface{}) []ConventionImage {
+	found := make(map[string]ConventionImage)
+	for i := range conventions {
+		convention := &conventions[i]
+		for _, root := range convention.roots(ch) {
+			node := values
+			if root != "" {
+				subchartValues, ok := values[root].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				node = subchartValues
+			}
+			convention.walk(node, root, "", found)
+		}
+	}
+
+	images := make([]ConventionImage, 0, len(found))
+	for _, img := range found {
+		images = append(images, img)
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Path < images[j].Path })
+	return images
+}
+
+// roots returns the values prefixes the convention applies below: "" for the chart itself and the
+// values key of each matching subchart.
+func (c *ImageConvention) roots(ch *chart.Chart) []string {
+	if len(c.Charts) == 0 {
+		return []string{""}
+	}
+	if ch == nil || ch.Metadata == nil {
+		return nil
+	}
+	var roots []string
+	if c.matchesChart(ch.Metadata.Name) {
+		roots = append(roots, "")
+	}
+	for _, dep := range ch.Metadata.Dependencies {
+		if dep == nil {
+			continue
+		}
+		key := dep.Name
+		if dep.Alias != "" {
+			key = dep.Alias
+		}
+		if c.matchesChart(dep.Name) || c.matchesChart(dep.Alias) {
+			roots = append(roots, key)
+		}
+	}
+	return roots
+}
+
+// matchesChart reports whether name matches one of the convention's chart globs.
+func (c *ImageConvention) matchesChart(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, pattern := range c.Charts {
+		if match, err := path.Match(pattern, name); err == nil && match {
+			return true
+		}
+	}
+	return false
+}
+
+// walk records the convention images in node, whose path is fullPath in the chart values and
+// relPath below the convention's root. relPath has [] for its sequence indexes, as the convention
+// globs do, so that matching it does not rewrite the path of every value.
+func (c *ImageConvention) walk(node interface{}, fullPath, relPath string, found map[string]ConventionImage) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for _, split := range c.SplitImages {
+			if img, ok := c.splitImage(v, split, fullPath, relPath); ok {
+				addConventionImage(found, img)
+			}
+		}
+		for key, child := range v {
+			c.walk(child, joinValuesPath(fullPath, key), joinValuesPath(relPath, key), found)
+		}
+	case []interface{}:
+		for i, child := range v {
+			c.walk(child, fmt.Sprintf("%s[%d]", fullPath, i), relPath+"[]", found)
+		}
+	case string:
+		value := strings.TrimSpace(v)
+		if value == "" || strings.Contains(value, "{{") || !c.matchesImagePath(relPath) {
+			return
+		}
+		addConventionImage(found, ConventionImage{Convention: c.Name, Path: fullPath, Value: value})
+	}
+}
+
+// splitImage returns the split image held by the keys of m, if m is at a path the split keys
+// describe and has a repository.
+func (c *ImageConvention) splitImage(m map[string]interface{}, split SplitImageKeys, fullPath, relPath string) (ConventionImage, bool) {
+	if !matchValuesPath(split.Path, relPath) {
+		return ConventionImage{}, false
+	}
+	repository, ok := m[split.Repository].(string)
+	if !ok || strings.TrimSpace(repository) == "" {
+		return ConventionImage{}, false
+	}
+	img := ConventionImage{
+		Convention: c.Name,
+		Path:       fullPath,
+		Keys:       map[string]string{keys.Repository: split.Repository},
+	}
+	value := strings.TrimSpace(repository)
+	if registry, ok := m[split.Registry].(string); ok && split.Registry != "" {
+		img.Keys[keys.Registry] = split.Registry
+		if registry = strings.TrimSpace(registry); registry != "" {
+			value = registry + "/" + value
+		}
+	}
+	if tag, ok := m[split.Tag].(string); ok && split.Tag != "" {
+		img.Keys[keys.Tag] = split.Tag
+		if tag = strings.TrimSpace(tag); tag != "" {
+			separator := ":"
+			if strings.HasPrefix(tag, "sha256:") {
+				separator = "@"
+			}
+			value += separator + tag
+		}
+	}
+	if strings.Contains(value, "{{") {
+		return ConventionImage{}, false
+	}
+	img.Value = value
+	return img, true
+}
+
+// matchesImagePath reports whether relPath matches one of the convention's image path globs.
+func (c *ImageConvention) matchesImagePath(relPath string) bool {
+	for _, pattern := range c.Images {
+		if matchValuesPath(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// addConventionImage records img unless an earlier convention already described its path.
+func addConventionImage(found map[string]ConventionImage, img ConventionImage) {
+	if existing, ok := found[img.Path]; ok {
+		log.Debug("Image path already described by another convention", "path", img.Path, "convention", existing.Convention)
+		return
+	}
+	found[img.Path] = img
+}
+
+// matchValuesPath reports whether the values path valuesPath, whose sequence indexes are [], matches
+// the glob pattern, in which [] stands for any sequence index.
+func matchValuesPath(pattern, valuesPath string) bool {
+	match, err := path.Match(valuesPathGlob(pattern), valuesPath)
+	return err == nil && match
+}
+
+// valuesPathGlob escapes the [] of a convention path glob so that it matches literally.
+func valuesPathGlob(pattern string) string {
+	return strings.ReplaceAll(pattern, "[]", `\[\]`)
+}
+
+// joinValuesPath appends key to the values path prefix.
+func joinValuesPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
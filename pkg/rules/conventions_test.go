@@ -0,0 +1,153 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestFindConventionImages(t *testing.T) {
+	values := map[string]interface{}{
+		"operatorImage": "quay.io/example/operator:v1.2.0",
+		"kubeRbacProxy": map[string]interface{}{"image": "gcr.io/kubebuilder/kube-rbac-proxy:v0.15.0"},
+		"relatedImages": []interface{}{
+			"quay.io/example/agent:v1",
+			map[string]interface{}{"name": "db", "image": "docker.io/library/postgres:16"},
+		},
+		"exporter": map[string]interface{}{
+			"imageRegistry":   "docker.io",
+			"imageRepository": "prom/exporter",
+			"imageTag":        "0.9",
+		},
+		"templated":  map[string]interface{}{"operatorImage": "{{ .Values.image }}"},
+		"notAnImage": map[string]interface{}{"operatorImages": "quay.io/example/ignored:v1"},
+	}
+
+	images := FindConventionImages(DefaultImageConventions, &chart.Chart{Metadata: &chart.Metadata{Name: "example"}}, values)
+
+	assert.Equal(t, []ConventionImage{
+		{Convention: "split-image-keys", Path: "exporter", Value: "docker.io/prom/exporter:0.9",
+			Keys: map[string]string{"registry": "imageRegistry", "repository": "imageRepository", "tag": "imageTag"}},
+		{Convention: "operator-images", Path: "kubeRbacProxy.image", Value: "gcr.io/kubebuilder/kube-rbac-proxy:v0.15.0"},
+		{Convention: "operator-images", Path: "operatorImage", Value: "quay.io/example/operator:v1.2.0"},
+		{Convention: "related-images", Path: "relatedImages[0]", Value: "quay.io/example/agent:v1"},
+		{Convention: "related-images", Path: "relatedImages[1].image", Value: "docker.io/library/postgres:16"},
+	}, images)
+}
+
+func TestFindConventionImages_ChartSpecific(t *testing.T) {
+	strimziValues := map[string]interface{}{
+		"defaultImageRegistry":   "quay.io",
+		"defaultImageRepository": "strimzi",
+		"defaultImageTag":        "0.38.0",
+	}
+
+	t.Run("matching chart", func(t *testing.T) {
+		ch := &chart.Chart{Metadata: &chart.Metadata{Name: "strimzi-kafka-operator"}}
+		images := FindConventionImages(DefaultImageConventions, ch, strimziValues)
+		require.Len(t, images, 1)
+		assert.Equal(t, "", images[0].Path)
+		assert.Equal(t, "quay.io/strimzi:0.38.0", images[0].Value)
+		assert.Equal(t, "defaultImageRepository", images[0].Keys["repository"])
+	})
+
+	t.Run("other chart", func(t *testing.T) {
+		ch := &chart.Chart{Metadata: &chart.Metadata{Name: "kafka"}}
+		assert.Empty(t, FindConventionImages(DefaultImageConventions, ch, strimziValues))
+	})
+
+	t.Run("aliased subchart", func(t *testing.T) {
+		ch := &chart.Chart{Metadata: &chart.Metadata{
+			Name:         "platform",
+			Dependencies: []*chart.Dependency{{Name: "strimzi-kafka-operator", Alias: "kafkaOperator"}},
+		}}
+		images := FindConventionImages(DefaultImageConventions, ch, map[string]interface{}{"kafkaOperator": strimziValues})
+		require.Len(t, images, 1)
+		assert.Equal(t, "kafkaOperator", images[0].Path)
+		assert.Equal(t, "quay.io/strimzi:0.38.0", images[0].Value)
+	})
+}
+
+func TestSplitImage_PartialKeys(t *testing.T) {
+	convention := ImageConvention{
+		Name:        "custom",
+		SplitImages: []SplitImageKeys{{Path: "manager", Registry: "imageRegistry", Repository: "imageRepository", Tag: "imageTag"}},
+	}
+	values := map[string]interface{}{
+		"manager": map[string]interface{}{"imageRepository": "ghcr.io/example/manager", "imageTag": "sha256:abc"},
+		"other":   map[string]interface{}{"imageRepository": "ghcr.io/example/other"},
+	}
+
+	images := FindConventionImages([]ImageConvention{convention}, nil, values)
+
+	require.Len(t, images, 1)
+	assert.Equal(t, "manager", images[0].Path)
+	assert.Equal(t, "ghcr.io/example/manager@sha256:abc", images[0].Value)
+	assert.Equal(t, map[string]string{"repository": "imageRepository", "tag": "imageTag"}, images[0].Keys)
+}
+
+func TestLoadImageConventions(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "rules.yaml", []byte(`imageConventions:
+  - name: my-operator
+    charts: [my-operator*]
+    images: ["manager.operatorImage"]
+    splitImages:
+      - path: sidecar
+        registry: sidecarRegistry
+        repository: sidecarRepository
+        tag: sidecarVersion
+`), 0o600))
+
+	conventions, err := LoadImageConventions(fs, "rules.yaml")
+	require.NoError(t, err)
+	require.Len(t, conventions, 1)
+	assert.Equal(t, []string{"my-operator*"}, conventions[0].Charts)
+	assert.Equal(t, "sidecarVersion", conventions[0].SplitImages[0].Tag)
+
+	require.NoError(t, afero.WriteFile(fs, "invalid.yaml", []byte("imageConventions:\n  - name: empty\n"), 0o600))
+	_, err = LoadImageConventions(fs, "invalid.yaml")
+	assert.ErrorContains(t, err, "has no images or splitImages")
+
+	_, err = LoadImageConventions(fs, "missing.yaml")
+	assert.ErrorContains(t, err, "failed to read rules file")
+}
+
+func TestImageConventionValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		convention ImageConvention
+		wantErr    string
+	}{
+		{"valid", ImageConvention{Name: "ok", Images: []string{"a.b[].image"}}, ""},
+		{"no name", ImageConvention{Images: []string{"image"}}, "name is required"},
+		{"bad chart glob", ImageConvention{Name: "bad", Charts: []string{"["}, Images: []string{"image"}}, "invalid chart glob"},
+		{"bad image glob", ImageConvention{Name: "bad", Images: []string{"images[x"}}, "invalid image path glob"},
+		{"split without repository", ImageConvention{Name: "bad", SplitImages: []SplitImageKeys{{Registry: "reg"}}}, "has no repository key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.convention.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestMergeImageConventions(t *testing.T) {
+	base := []ImageConvention{{Name: "a", Images: []string{"x"}}, {Name: "b", Images: []string{"y"}}}
+	extra := []ImageConvention{{Name: "b", Images: []string{"z"}}, {Name: "c", Images: []string{"w"}}}
+
+	merged := MergeImageConventions(base, extra)
+
+	require.Len(t, merged, 3)
+	assert.Equal(t, "a", merged[0].Name)
+	assert.Equal(t, []string{"z"}, merged[1].Images)
+	assert.Equal(t, "c", merged[2].Name)
+}
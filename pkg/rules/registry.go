@@ -45,6 +45,21 @@ func (r *Registry) AddRule(rule Rule) {
 	log.Debug("Added rule '%s' to registry", rule.Name())
 }
 
+// RemoveRule removes the rule with the given name and reports whether it was registered
+func (r *Registry) RemoveRule(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, rule := range r.rules {
+		if rule.Name() == name {
+			r.rules = append(r.rules[:i], r.rules[i+1:]...)
+			log.Debug("Removed rule '%s' from registry", name)
+			return true
+		}
+	}
+	return false
+}
+
 // GetRules returns all registered rules
 func (r *Registry) GetRules() []Rule {
 	r.mu.RLock()
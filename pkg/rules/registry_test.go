@@ -48,6 +48,15 @@ func TestRegistry_GetRules(t *testing.T) {
 	assert.True(t, foundCustomRule, "Registry should contain the custom rule")
 }
 
+func TestRegistry_RemoveRule(t *testing.T) {
+	registry := NewRegistry()
+	count := len(registry.GetRules())
+
+	assert.True(t, registry.RemoveRule(BitnamiSecurityBypassRuleName), "Bitnami rule should be removed")
+	assert.Len(t, registry.GetRules(), count-1, "Registry should have one rule fewer")
+	assert.False(t, registry.RemoveRule(BitnamiSecurityBypassRuleName), "Removing an unknown rule should report false")
+}
+
 func TestRegistry_IsEnabled(t *testing.T) {
 	// Create a new registry
 	registry := NewRegistry()
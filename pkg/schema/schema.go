@@ -0,0 +1,238 @@
+// Package schema generates JSON Schemas (draft 2020-12) from Go types. Properties are named the
+// way encoding/json or gopkg.in/yaml.v3 serialize the struct fields, so the schemas of irr's
+// output and configuration files always describe the structs those files are written from.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect of generated schemas
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Encoding is the serialization a schema describes
+type Encoding string
+
+const (
+	// JSON names properties as encoding/json does: the json tag, else the field name
+	JSON Encoding = "json"
+	// YAML names properties as gopkg.in/yaml.v3 does: the yaml tag, else the lowercased field name
+	YAML Encoding = "yaml"
+)
+
+// Options configure Generate
+type Options struct {
+	// Title and Description annotate the schema
+	Title       string
+	Description string
+	// Comment is recorded as $comment, e.g. the version of the program the schema belongs to
+	Comment string
+	// Encoding selects how properties are named; JSON by default
+	Encoding Encoding
+	// RequireFields marks fields without omitempty as required, for documents that are written
+	// from the structs and therefore always contain them
+	RequireFields bool
+}
+
+// Schema is a JSON Schema document or subschema. It marshals with sorted keys.
+type Schema map[string]interface{}
+
+// JSON returns the indented JSON of s.
+func (s Schema) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// Generate returns the schema of the values of v's type. Named struct types are defined once in
+// $defs and referenced, so recursive types are supported.
+func Generate(v interface{}, opts Options) Schema {
+	if opts.Encoding == "" {
+		opts.Encoding = JSON
+	}
+	g := &generator{opts: opts, defs: Schema{}, names: make(map[reflect.Type]string)}
+	root := g.schemaOf(reflect.TypeOf(v))
+
+	root["$schema"] = Draft
+	if opts.Title != "" {
+		root["title"] = opts.Title
+	}
+	if opts.Description != "" {
+		root["description"] = opts.Description
+	}
+	if opts.Comment != "" {
+		root["$comment"] = opts.Comment
+	}
+	if len(g.defs) > 0 {
+		root["$defs"] = g.defs
+	}
+	return root
+}
+
+// generator collects the definitions of the named struct types of a schema
+type generator struct {
+	opts  Options
+	defs  Schema
+	names map[reflect.Type]string
+}
+
+// schemaOf returns the schema of the values of t
+func (g *generator) schemaOf(t reflect.Type) Schema {
+	if t == nil {
+		return Schema{}
+	}
+	switch t {
+	case timeType:
+		return Schema{"type": "string", "format": "date-time"}
+	case durationType:
+		if g.opts.Encoding == YAML {
+			return Schema{"type": "string"} // yaml.v3 writes durations as text, e.g. 1m30s
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schemaOf(t.Elem())
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Schema{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return Schema{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string"} // byte slices are written base64-encoded
+		}
+		return Schema{"type": "array", "items": g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return Schema{"$ref": "#/$defs/" + g.define(t)}
+	default:
+		// Interfaces hold any value
+		return Schema{}
+	}
+}
+
+// define adds the schema of the named struct type t to $defs, if not already there, and returns
+// its name
+func (g *generator) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := g.defs[name]; taken {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	g.names[t] = name
+	g.defs[name] = Schema{} // placeholder, so references from within t resolve to name
+	g.defs[name] = g.structSchema(t)
+	return name
+}
+
+// structSchema returns the object schema of the struct type t
+func (g *generator) structSchema(t reflect.Type) Schema {
+	properties := Schema{}
+	var required []string
+	g.addFields(t, properties, &required)
+	schema := Schema{"type": "object", "properties": properties, "additionalProperties": false}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// addFields adds the serialized fields of the struct type t, including those of inlined structs,
+// to properties
+func (g *generator) addFields(t reflect.Type, properties Schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitempty, inline, ok := g.fieldName(field)
+		if !ok {
+			continue
+		}
+		if inline {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			g.addFields(embedded, properties, required)
+			continue
+		}
+
+		fieldSchema := g.schemaOf(field.Type)
+		if !omitempty && g.nullable(field.Type) {
+			fieldSchema = Schema{"anyOf": []Schema{fieldSchema, {"type": "null"}}}
+		}
+		properties[name] = fieldSchema
+		if g.opts.RequireFields && !omitempty && !slices.Contains(*required, name) {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// fieldName returns the property name of field and whether it is omitted when empty or inlined
+// into its parent. ok is false for fields that are not serialized.
+func (g *generator) fieldName(field reflect.StructField) (name string, omitempty, inline, ok bool) {
+	tag := field.Tag.Get(string(g.opts.Encoding))
+	if tag == "-" {
+		return "", false, false, false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	optionList := strings.Split(options, ",")
+	omitempty = slices.Contains(optionList, "omitempty") || slices.Contains(optionList, "omitzero")
+
+	embeddedStruct := field.Anonymous && (field.Type.Kind() == reflect.Struct ||
+		(field.Type.Kind() == reflect.Pointer && field.Type.Elem().Kind() == reflect.Struct))
+	if g.opts.Encoding == YAML {
+		inline = slices.Contains(optionList, "inline")
+	} else {
+		inline = embeddedStruct && name == ""
+	}
+	if inline {
+		return "", false, true, true
+	}
+	if !field.IsExported() {
+		return "", false, false, false
+	}
+	if name == "" {
+		name = field.Name
+		if g.opts.Encoding == YAML {
+			name = strings.ToLower(name)
+		}
+	}
+	return name, omitempty, false, true
+}
+
+// nullable reports whether a zero value of t is written as null: nil pointers, and in JSON also
+// nil slices and maps, which yaml.v3 writes as empty collections
+func (g *generator) nullable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return true
+	case reflect.Slice, reflect.Map:
+		return g.opts.Encoding == JSON
+	default:
+		return false
+	}
+}
@@ -0,0 +1,131 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type testMetadata struct {
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+type testImage struct {
+	testMetadata `yaml:",inline"`
+	Repository   string        `json:"repository" yaml:"repository"`
+	Tag          string        `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Path         string        // untagged: "Path" in JSON, "path" in YAML
+	Paths        []string      `json:"paths" yaml:"paths"`
+	Parent       *testImage    `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Pushed       time.Time     `json:"pushed" yaml:"pushed"`
+	Timeout      time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Extra        interface{}   `json:"extra,omitempty" yaml:"extra,omitempty"`
+	Ignored      string        `json:"-" yaml:"-"`
+	internal     string
+}
+
+type testReport struct {
+	Images   []testImage `json:"images" yaml:"images"`
+	Count    uint        `json:"count" yaml:"count"`
+	Warnings *string     `json:"warnings" yaml:"warnings"`
+}
+
+func TestGenerate(t *testing.T) {
+	schema := Generate(testReport{}, Options{Title: "Report", Comment: "irr 1.0.0", RequireFields: true})
+
+	assert.Equal(t, Draft, schema["$schema"])
+	assert.Equal(t, "Report", schema["title"])
+	assert.Equal(t, "irr 1.0.0", schema["$comment"])
+	assert.Equal(t, "#/$defs/testReport", schema["$ref"])
+
+	defs, ok := schema["$defs"].(Schema)
+	require.True(t, ok)
+	report := defs["testReport"].(Schema)
+	assert.Equal(t, []string{"images", "count", "warnings"}, report["required"])
+	assert.Equal(t, false, report["additionalProperties"])
+	properties := report["properties"].(Schema)
+	assert.Equal(t, Schema{"anyOf": []Schema{{"type": "array", "items": Schema{"$ref": "#/$defs/testImage"}}, {"type": "null"}}},
+		properties["images"], "nil slices are written as null in JSON")
+	assert.Equal(t, Schema{"type": "integer", "minimum": 0}, properties["count"])
+
+	image := defs["testImage"].(Schema)
+	imageProperties := image["properties"].(Schema)
+	assert.ElementsMatch(t, []string{"labels", "repository", "tag", "Path", "paths", "parent", "pushed", "timeout", "extra"},
+		keys(imageProperties), "embedded structs are inlined; ignored and unexported fields are left out")
+	assert.Equal(t, Schema{"$ref": "#/$defs/testImage"}, imageProperties["parent"], "recursive types are referenced")
+	assert.Equal(t, Schema{"type": "string", "format": "date-time"}, imageProperties["pushed"])
+	assert.Equal(t, Schema{"type": "integer"}, imageProperties["timeout"])
+	assert.Equal(t, Schema{}, imageProperties["extra"])
+	assert.Equal(t, []string{"repository", "Path", "paths", "pushed"}, image["required"])
+}
+
+func TestGenerateYAML(t *testing.T) {
+	schema := Generate(testImage{}, Options{Encoding: YAML, RequireFields: true})
+	image := schema["$defs"].(Schema)["testImage"].(Schema)
+	properties := image["properties"].(Schema)
+	assert.ElementsMatch(t, []string{"labels", "repository", "tag", "path", "paths", "parent", "pushed", "timeout", "extra"},
+		keys(properties), "inline structs are flattened and untagged fields lowercased")
+	assert.Equal(t, Schema{"type": "array", "items": Schema{"type": "string"}}, properties["paths"], "yaml.v3 writes nil slices as []")
+	assert.Equal(t, Schema{"type": "string"}, properties["timeout"])
+}
+
+func TestGenerateValidatesDocuments(t *testing.T) {
+	warning := "deprecated"
+	report := testReport{
+		Images: []testImage{{Repository: "library/nginx", Tag: "1.25", Path: "image", Parent: &testImage{Repository: "library/nginx"}}},
+		Count:  1,
+	}
+	validate := func(t *testing.T, opts Options, document []byte, unmarshal func([]byte, interface{}) error) error {
+		t.Helper()
+		schemaJSON, err := Generate(testReport{}, opts).JSON()
+		require.NoError(t, err)
+		parsed, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaJSON))
+		require.NoError(t, err)
+		compiler := jsonschema.NewCompiler()
+		require.NoError(t, compiler.AddResource("file:///report.schema.json", parsed))
+		validator, err := compiler.Compile("file:///report.schema.json")
+		require.NoError(t, err)
+
+		var instance interface{}
+		require.NoError(t, unmarshal(document, &instance))
+		instanceJSON, err := json.Marshal(instance)
+		require.NoError(t, err)
+		instance, err = jsonschema.UnmarshalJSON(bytes.NewReader(instanceJSON))
+		require.NoError(t, err)
+		return validator.Validate(instance)
+	}
+
+	for _, encoding := range []Encoding{JSON, YAML} {
+		t.Run(string(encoding), func(t *testing.T) {
+			opts := Options{Encoding: encoding, RequireFields: true}
+			marshal, unmarshal := json.Marshal, json.Unmarshal
+			if encoding == YAML {
+				marshal, unmarshal = yaml.Marshal, yaml.Unmarshal
+			}
+			for _, r := range []testReport{report, {Warnings: &warning}} {
+				document, err := marshal(r)
+				require.NoError(t, err)
+				assert.NoError(t, validate(t, opts, document, unmarshal), string(document))
+			}
+
+			document := []byte(`{"images": [], "count": 1, "warnings": null, "unknown": true}`)
+			assert.Error(t, validate(t, opts, document, json.Unmarshal), "unknown properties are rejected")
+			document = []byte(`{"images": [], "warnings": null}`)
+			assert.Error(t, validate(t, opts, document, json.Unmarshal), "required properties must be present")
+		})
+	}
+}
+
+func keys(s Schema) []string {
+	result := make([]string, 0, len(s))
+	for key := range s {
+		result = append(result, key)
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+// Package selector implements the --select syntax that limits override generation and inspection
+// to part of a chart: selected subcharts, values paths or releases.
+package selector
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Kind is what a selector matches against.
+type Kind string
+
+// Supported selector kinds.
+const (
+	// KindSubchart matches values under a subchart's top-level key (its name or alias).
+	KindSubchart Kind = "subchart"
+	// KindPath matches values paths with a glob pattern, like --include-pattern.
+	KindPath Kind = "path"
+	// KindRelease matches every value of the named release.
+	KindRelease Kind = "release"
+)
+
+// ErrInvalidSelector is returned for selectors that are not KIND=PATTERN with a supported kind
+// and a valid glob pattern.
+var ErrInvalidSelector = errors.New("invalid selector")
+
+// Selector selects the values whose subchart, path or release matches Pattern.
+type Selector struct {
+	Kind    Kind
+	Pattern string
+}
+
+// String formats the selector the way it is written on the command line.
+func (s Selector) String() string {
+	return string(s.Kind) + "=" + s.Pattern
+}
+
+// Parse parses a selector written as KIND=PATTERN, e.g. "subchart=postgresql" or "path=ingress.*".
+// Patterns are globs; subchart and release patterns match the whole name.
+func Parse(expr string) (Selector, error) {
+	kind, pattern, ok := strings.Cut(strings.TrimSpace(expr), "=")
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	pattern = strings.TrimSpace(pattern)
+	if !ok || pattern == "" {
+		return Selector{}, fmt.Errorf("%w %q: expected KIND=PATTERN", ErrInvalidSelector, expr)
+	}
+
+	selector := Selector{Kind: Kind(kind), Pattern: pattern}
+	switch selector.Kind {
+	case KindSubchart, KindPath, KindRelease:
+	default:
+		return Selector{}, fmt.Errorf("%w %q: unknown kind %q (supported: %s, %s, %s)",
+			ErrInvalidSelector, expr, kind, KindSubchart, KindPath, KindRelease)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return Selector{}, fmt.Errorf("%w %q: %w", ErrInvalidSelector, expr, err)
+	}
+	return selector, nil
+}
+
+// Set is a list of selectors that are OR-ed together.
+type Set []Selector
+
+// ParseAll parses each expression into a Set.
+func ParseAll(exprs []string) (Set, error) {
+	set := make(Set, 0, len(exprs))
+	for _, expr := range exprs {
+		selector, err := Parse(expr)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, selector)
+	}
+	return set, nil
+}
+
+// Matches reports whether the value at valuePath of release (empty outside of a release) is
+// selected. An empty set selects everything.
+func (s Set) Matches(release, valuePath string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, selector := range s {
+		if selector.matches(release, valuePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// String lists the selectors, comma separated.
+func (s Set) String() string {
+	exprs := make([]string, 0, len(s))
+	for _, selector := range s {
+		exprs = append(exprs, selector.String())
+	}
+	return strings.Join(exprs, ", ")
+}
+
+func (s Selector) matches(release, valuePath string) bool {
+	var subject string
+	switch s.Kind {
+	case KindSubchart:
+		subject = topLevelKey(valuePath)
+	case KindPath:
+		subject = valuePath
+	case KindRelease:
+		subject = release
+	}
+	if subject == "" {
+		return false
+	}
+	// Patterns were validated by Parse, so errors only come from hand-built selectors
+	match, err := filepath.Match(s.Pattern, subject)
+	return err == nil && match
+}
+
+// topLevelKey returns the first key of a dot-notation values path, e.g. "postgresql" for
+// "postgresql.image.repository" or "sidecars" for "sidecars[0].image".
+func topLevelKey(valuePath string) string {
+	if i := strings.IndexAny(valuePath, ".["); i >= 0 {
+		return valuePath[:i]
+	}
+	return valuePath
+}
@@ -0,0 +1,65 @@
+package selector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    Selector
+		wantErr bool
+	}{
+		{name: "subchart", expr: "subchart=postgresql", want: Selector{Kind: KindSubchart, Pattern: "postgresql"}},
+		{name: "path glob", expr: "path=ingress.*", want: Selector{Kind: KindPath, Pattern: "ingress.*"}},
+		{name: "release with spaces", expr: " Release = web-* ", want: Selector{Kind: KindRelease, Pattern: "web-*"}},
+		{name: "missing equals", expr: "postgresql", wantErr: true},
+		{name: "empty pattern", expr: "subchart=", wantErr: true},
+		{name: "unknown kind", expr: "chart=postgresql", wantErr: true},
+		{name: "bad glob", expr: "path=ingress.[", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.expr)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidSelector)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSetMatches(t *testing.T) {
+	set, err := ParseAll([]string{"subchart=postgresql", "path=ingress.*", "release=web-*"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		release   string
+		valuePath string
+		want      bool
+	}{
+		{name: "subchart key", valuePath: "postgresql.image", want: true},
+		{name: "subchart nested", valuePath: "postgresql.metrics.image.repository", want: true},
+		{name: "subchart prefix is not a match", valuePath: "postgresql-ha.image"},
+		{name: "path glob", valuePath: "ingress.controller.image", want: true},
+		{name: "path not selected", valuePath: "redis.image"},
+		{name: "release selects every path", release: "web-frontend", valuePath: "redis.image", want: true},
+		{name: "other release", release: "api", valuePath: "redis.image"},
+		{name: "sequence under subchart key", valuePath: "postgresql[0].image", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, set.Matches(tt.release, tt.valuePath))
+		})
+	}
+
+	assert.True(t, Set{}.Matches("", "anything"))
+	assert.Equal(t, "subchart=postgresql, path=ingress.*, release=web-*", set.String())
+}
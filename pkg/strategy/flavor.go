@@ -0,0 +1,145 @@
+package strategy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TargetFlavor names a target registry provider whose repository naming rules are enforced
+// on generated paths (--target-flavor).
+type TargetFlavor string
+
+// Supported target flavors.
+const (
+	// FlavorGeneric applies no provider-specific rules.
+	FlavorGeneric TargetFlavor = "generic"
+	// FlavorECR is Amazon Elastic Container Registry.
+	FlavorECR TargetFlavor = "ecr"
+	// FlavorGCR is Google Container Registry and Artifact Registry.
+	FlavorGCR TargetFlavor = "gcr"
+	// FlavorACR is Azure Container Registry.
+	FlavorACR TargetFlavor = "acr"
+	// FlavorHarbor is a Harbor registry, where the first path component is the project.
+	FlavorHarbor TargetFlavor = "harbor"
+)
+
+const (
+	// ecrMaxRepositoryLength is the maximum length of an ECR repository name.
+	ecrMaxRepositoryLength = 256
+	// gcrMinDepth is the number of path components in gcr.io/PROJECT/IMAGE.
+	gcrMinDepth = 2
+	// artifactRegistryMinDepth is the number of path components in LOCATION-docker.pkg.dev/PROJECT/REPOSITORY/IMAGE.
+	artifactRegistryMinDepth = 3
+	// harborMinDepth is the number of path components in PROJECT/REPOSITORY.
+	harborMinDepth = 2
+)
+
+// pathComponentPattern is the repository path component grammar shared by the Docker distribution
+// spec and all supported providers: lowercase alphanumerics joined by '.', '_', '__' or dashes.
+var pathComponentPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+
+// TargetFlavors lists the supported target flavor names.
+func TargetFlavors() []string {
+	return []string{string(FlavorGeneric), string(FlavorECR), string(FlavorGCR), string(FlavorACR), string(FlavorHarbor)}
+}
+
+// ParseTargetFlavor returns the flavor with the given name; an empty name is FlavorGeneric.
+func ParseTargetFlavor(name string) (TargetFlavor, error) {
+	flavor := TargetFlavor(strings.ToLower(strings.TrimSpace(name)))
+	if flavor == "" {
+		return FlavorGeneric, nil
+	}
+	for _, known := range TargetFlavors() {
+		if string(flavor) == known {
+			return flavor, nil
+		}
+	}
+	return "", fmt.Errorf("unknown target flavor %q (expected one of: %s)", name, strings.Join(TargetFlavors(), ", "))
+}
+
+// TargetPathError reports a generated repository path that breaks the target flavor's naming rules.
+type TargetPathError struct {
+	Flavor TargetFlavor
+	Path   string
+	Reason string
+	Hint   string
+}
+
+func (e *TargetPathError) Error() string {
+	msg := fmt.Sprintf("repository path %q is not valid for %s: %s", e.Path, e.Flavor, e.Reason)
+	if e.Hint != "" {
+		msg += "; " + e.Hint
+	}
+	return msg
+}
+
+// AdjustRepositoryPath rewrites repoPath, the repository path under the registry host, where the
+// flavor allows it. Currently this lowercases paths for flavors that only accept lowercase names,
+// which fixes upper-case target prefixes from registry mappings. The second result reports whether
+// the path was changed.
+func (f TargetFlavor) AdjustRepositoryPath(repoPath string) (string, bool) {
+	if f == FlavorGeneric || f == "" {
+		return repoPath, false
+	}
+	lower := strings.ToLower(repoPath)
+	return lower, lower != repoPath
+}
+
+// ValidateRepositoryPath checks repoPath, the repository path under registry host host, against
+// the flavor's naming rules. It returns a *TargetPathError describing how to fix a violation.
+func (f TargetFlavor) ValidateRepositoryPath(host, repoPath string) error {
+	if f == FlavorGeneric || f == "" {
+		return nil
+	}
+	newErr := func(reason, hint string) error {
+		return &TargetPathError{Flavor: f, Path: repoPath, Reason: reason, Hint: hint}
+	}
+
+	components := strings.Split(repoPath, "/")
+	for _, component := range components {
+		if !pathComponentPattern.MatchString(component) {
+			return newErr(fmt.Sprintf("component %q must be lowercase alphanumerics separated by '.', '_' or '-'", component),
+				"rename the target prefix in --target-registry or the registry mappings")
+		}
+	}
+
+	switch f {
+	case FlavorECR:
+		if len(repoPath) > ecrMaxRepositoryLength {
+			return newErr(fmt.Sprintf("%d characters exceeds the ECR limit of %d", len(repoPath), ecrMaxRepositoryLength),
+				"use a shorter target prefix or map the source registry to a shorter path")
+		}
+	case FlavorGCR:
+		minDepth, layout := gcrMinDepth, "gcr.io/PROJECT/IMAGE"
+		if strings.HasSuffix(host, ".pkg.dev") {
+			minDepth, layout = artifactRegistryMinDepth, "LOCATION-docker.pkg.dev/PROJECT/REPOSITORY/IMAGE"
+		}
+		if len(components) < minDepth {
+			return newErr(fmt.Sprintf("expected at least %d path components (%s)", minDepth, layout),
+				"include the project in the target, e.g. --target-registry "+strings.Join(strings.Split(layout, "/")[:minDepth], "/"))
+		}
+	case FlavorHarbor:
+		if len(components) < harborMinDepth {
+			return newErr("Harbor repositories must be inside a project (PROJECT/REPOSITORY)",
+				"set the project in the target, e.g. --target-registry "+host+"/PROJECT")
+		}
+	}
+	// ACR only requires the lowercase grammar checked above
+	return nil
+}
+
+// RequiredResource returns the resource that must exist in the target registry before images can be
+// pushed to repoPath: the repository for ECR and the project for Harbor. kind is empty when the
+// flavor creates repositories on push.
+func (f TargetFlavor) RequiredResource(repoPath string) (kind, name string) {
+	switch f {
+	case FlavorECR:
+		return "repository", repoPath
+	case FlavorHarbor:
+		project, _, _ := strings.Cut(repoPath, "/")
+		return "project", project
+	default:
+		return "", ""
+	}
+}
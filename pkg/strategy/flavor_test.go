@@ -0,0 +1,80 @@
+package strategy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTargetFlavor(t *testing.T) {
+	flavor, err := ParseTargetFlavor("")
+	require.NoError(t, err)
+	assert.Equal(t, FlavorGeneric, flavor)
+
+	flavor, err = ParseTargetFlavor(" ECR ")
+	require.NoError(t, err)
+	assert.Equal(t, FlavorECR, flavor)
+
+	_, err = ParseTargetFlavor("quay")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "generic, ecr, gcr, acr, harbor")
+}
+
+func TestTargetFlavor_ValidateRepositoryPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		flavor    TargetFlavor
+		host      string
+		repoPath  string
+		wantError string
+	}{
+		{name: "generic accepts anything", flavor: FlavorGeneric, host: "registry.local", repoPath: "Team/nginx"},
+		{name: "acr lowercase", flavor: FlavorACR, host: "myacr.azurecr.io", repoPath: "docker.io/library/nginx"},
+		{name: "acr uppercase", flavor: FlavorACR, host: "myacr.azurecr.io", repoPath: "Team/nginx", wantError: `component "Team"`},
+		{name: "ecr", flavor: FlavorECR, host: "1234.dkr.ecr.us-east-1.amazonaws.com", repoPath: "mirror/docker.io/bitnami/redis"},
+		{name: "ecr too long", flavor: FlavorECR, host: "1234.dkr.ecr.us-east-1.amazonaws.com", repoPath: "mirror/" + strings.Repeat("a", 256), wantError: "exceeds the ECR limit of 256"},
+		{name: "gcr with project", flavor: FlavorGCR, host: "gcr.io", repoPath: "my-project/nginx"},
+		{name: "gcr without project", flavor: FlavorGCR, host: "gcr.io", repoPath: "nginx", wantError: "--target-registry gcr.io/PROJECT"},
+		{name: "artifact registry", flavor: FlavorGCR, host: "europe-docker.pkg.dev", repoPath: "my-project/mirror/nginx"},
+		{name: "artifact registry without repository", flavor: FlavorGCR, host: "europe-docker.pkg.dev", repoPath: "my-project/nginx", wantError: "at least 3 path components"},
+		{name: "harbor project", flavor: FlavorHarbor, host: "harbor.local", repoPath: "dockerhub/library/nginx"},
+		{name: "harbor without project", flavor: FlavorHarbor, host: "harbor.local", repoPath: "docker.io-library-nginx", wantError: "--target-registry harbor.local/PROJECT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.flavor.ValidateRepositoryPath(tt.host, tt.repoPath)
+			if tt.wantError == "" {
+				require.NoError(t, err)
+				return
+			}
+			var pathErr *TargetPathError
+			require.ErrorAs(t, err, &pathErr)
+			assert.Contains(t, err.Error(), tt.wantError)
+		})
+	}
+}
+
+func TestTargetFlavor_AdjustRepositoryPath(t *testing.T) {
+	adjusted, changed := FlavorACR.AdjustRepositoryPath("Team/nginx")
+	assert.True(t, changed)
+	assert.Equal(t, "team/nginx", adjusted)
+
+	adjusted, changed = FlavorGeneric.AdjustRepositoryPath("Team/nginx")
+	assert.False(t, changed)
+	assert.Equal(t, "Team/nginx", adjusted)
+}
+
+func TestTargetFlavor_RequiredResource(t *testing.T) {
+	kind, name := FlavorECR.RequiredResource("mirror/nginx")
+	assert.Equal(t, "repository", kind)
+	assert.Equal(t, "mirror/nginx", name)
+
+	kind, name = FlavorHarbor.RequiredResource("dockerhub/library/nginx")
+	assert.Equal(t, "project", kind)
+	assert.Equal(t, "dockerhub", name)
+
+	kind, _ = FlavorGCR.RequiredResource("my-project/nginx")
+	assert.Empty(t, kind)
+}
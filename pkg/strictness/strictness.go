@@ -0,0 +1,241 @@
+// Package strictness defines the strict mode levels and the per-condition policy that decide which
+// problems found while generating overrides are errors, which are warnings and which are ignored.
+package strictness
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+)
+
+// Level is a strict mode level selected with --strict-mode. Each level sets a default Action for
+// every Condition.
+type Level string
+
+// Supported strict mode levels.
+const (
+	// LevelOff ignores all conditions but mapping conflicts, which are warnings (the default, and
+	// the behavior without --strict).
+	LevelOff Level = "off"
+	// LevelWarn reports every condition as a warning.
+	LevelWarn Level = "warn"
+	// LevelUnsupported fails on structures irr cannot relocate (template expressions, unparseable
+	// images and empty repositories) and warns about unmapped registries and mapping conflicts.
+	LevelUnsupported Level = "unsupported"
+	// LevelAll fails on every condition (the behavior of --strict).
+	LevelAll Level = "all"
+)
+
+// Action is what happens when a condition is found.
+type Action string
+
+// Supported actions.
+const (
+	ActionIgnore Action = "ignore"
+	ActionWarn   Action = "warn"
+	ActionError  Action = "error"
+)
+
+// Condition is a problem the strict mode policy can act on.
+type Condition string
+
+// Conditions checked by the strict mode policy. The values are the keys of the config file's policy block.
+const (
+	// TemplateExpressions are image values containing Helm template expressions ({{ ... }}).
+	TemplateExpressions Condition = "templateExpressions"
+	// UnparseableImages are image values that are not valid image references.
+	UnparseableImages Condition = "unparseableImages"
+	// UnmappedRegistries are source registries without a registry mapping.
+	UnmappedRegistries Condition = "unmappedRegistries"
+	// EmptyRepositories are image maps whose repository is empty.
+	EmptyRepositories Condition = "emptyRepositories"
+	// MappingConflicts are registry mappings, or a target registry, relocating images into their own
+	// or another source registry, and source registries mapped more than once.
+	MappingConflicts Condition = "mappingConflicts"
+	// MutableReferences are relocated images referenced by a tag that may be moved, such as latest
+	// or a branch name, rather than by a digest or a full version. No level acts on them; they are
+	// enabled with --require-immutable-refs or the config file's policy block.
+	MutableReferences Condition = "mutableReferences"
+)
+
+// Levels lists the supported strict mode level names.
+func Levels() []string {
+	return []string{string(LevelOff), string(LevelWarn), string(LevelUnsupported), string(LevelAll)}
+}
+
+// Conditions lists all conditions in the order they are checked and reported.
+func Conditions() []Condition {
+	return []Condition{MappingConflicts, TemplateExpressions, UnparseableImages, EmptyRepositories, UnmappedRegistries, MutableReferences}
+}
+
+// ParseLevel returns the level with the given name; an empty name is LevelOff.
+func ParseLevel(name string) (Level, error) {
+	level := Level(strings.ToLower(strings.TrimSpace(name)))
+	if level == "" {
+		return LevelOff, nil
+	}
+	for _, known := range Levels() {
+		if string(level) == known {
+			return level, nil
+		}
+	}
+	return "", fmt.Errorf("unknown strict mode %q (expected one of: %s)", name, strings.Join(Levels(), ", "))
+}
+
+// Policy sets the Action for each Condition. An empty field leaves the action to the strict mode level.
+type Policy struct {
+	TemplateExpressions Action `yaml:"templateExpressions,omitempty" json:"templateExpressions,omitempty"`
+	UnparseableImages   Action `yaml:"unparseableImages,omitempty" json:"unparseableImages,omitempty"`
+	UnmappedRegistries  Action `yaml:"unmappedRegistries,omitempty" json:"unmappedRegistries,omitempty"`
+	EmptyRepositories   Action `yaml:"emptyRepositories,omitempty" json:"emptyRepositories,omitempty"`
+	MappingConflicts    Action `yaml:"mappingConflicts,omitempty" json:"mappingConflicts,omitempty"`
+	MutableReferences   Action `yaml:"mutableReferences,omitempty" json:"mutableReferences,omitempty"`
+}
+
+// Policy returns the default policy of the level. MutableReferences is left unset, so it is ignored.
+func (l Level) Policy() Policy {
+	switch l {
+	case LevelWarn:
+		return Policy{TemplateExpressions: ActionWarn, UnparseableImages: ActionWarn, UnmappedRegistries: ActionWarn, EmptyRepositories: ActionWarn, MappingConflicts: ActionWarn}
+	case LevelUnsupported:
+		return Policy{TemplateExpressions: ActionError, UnparseableImages: ActionError, UnmappedRegistries: ActionWarn, EmptyRepositories: ActionError, MappingConflicts: ActionWarn}
+	case LevelAll:
+		return Policy{TemplateExpressions: ActionError, UnparseableImages: ActionError, UnmappedRegistries: ActionError, EmptyRepositories: ActionError, MappingConflicts: ActionError}
+	default:
+		return Policy{TemplateExpressions: ActionIgnore, UnparseableImages: ActionIgnore, UnmappedRegistries: ActionIgnore, EmptyRepositories: ActionIgnore, MappingConflicts: ActionWarn}
+	}
+}
+
+// ActionFor returns the action for condition c, ActionIgnore if unset.
+func (p Policy) ActionFor(c Condition) Action {
+	var action Action
+	switch c {
+	case TemplateExpressions:
+		action = p.TemplateExpressions
+	case UnparseableImages:
+		action = p.UnparseableImages
+	case UnmappedRegistries:
+		action = p.UnmappedRegistries
+	case EmptyRepositories:
+		action = p.EmptyRepositories
+	case MappingConflicts:
+		action = p.MappingConflicts
+	case MutableReferences:
+		action = p.MutableReferences
+	}
+	if action == "" {
+		return ActionIgnore
+	}
+	return action
+}
+
+// Merge returns p with the actions set in overrides replacing its own.
+func (p Policy) Merge(overrides Policy) Policy {
+	if overrides.TemplateExpressions != "" {
+		p.TemplateExpressions = overrides.TemplateExpressions
+	}
+	if overrides.UnparseableImages != "" {
+		p.UnparseableImages = overrides.UnparseableImages
+	}
+	if overrides.UnmappedRegistries != "" {
+		p.UnmappedRegistries = overrides.UnmappedRegistries
+	}
+	if overrides.EmptyRepositories != "" {
+		p.EmptyRepositories = overrides.EmptyRepositories
+	}
+	if overrides.MappingConflicts != "" {
+		p.MappingConflicts = overrides.MappingConflicts
+	}
+	if overrides.MutableReferences != "" {
+		p.MutableReferences = overrides.MutableReferences
+	}
+	return p
+}
+
+// Validate checks that every action set in the policy is known.
+func (p Policy) Validate() error {
+	for _, c := range Conditions() {
+		switch p.ActionFor(c) {
+		case ActionIgnore, ActionWarn, ActionError:
+		default:
+			return fmt.Errorf("invalid action %q for policy condition %s (expected ignore, warn or error)", p.ActionFor(c), c)
+		}
+	}
+	return nil
+}
+
+// WarningCode returns the code of warnings recorded for the condition.
+func (c Condition) WarningCode() string {
+	switch c {
+	case TemplateExpressions:
+		return "template-expression"
+	case UnparseableImages:
+		return "unparseable-image"
+	case UnmappedRegistries:
+		return "unmapped-registry"
+	case EmptyRepositories:
+		return "empty-repository"
+	case MappingConflicts:
+		return "mapping-conflict"
+	case MutableReferences:
+		return "mutable-reference"
+	default:
+		return string(c)
+	}
+}
+
+// ExitCode returns the exit code used when the condition fails the run.
+func (c Condition) ExitCode() int {
+	switch c {
+	case TemplateExpressions:
+		return exitcodes.ExitUnsupportedStructure
+	case UnparseableImages:
+		return exitcodes.ExitImageProcessingError
+	case UnmappedRegistries:
+		return exitcodes.ExitRegistryDetectionError
+	case EmptyRepositories:
+		return exitcodes.ExitEmptyRepositoryError
+	case MappingConflicts:
+		return exitcodes.ExitInputConfigurationError
+	case MutableReferences:
+		return exitcodes.ExitMutableReferenceError
+	default:
+		return exitcodes.ExitGeneralRuntimeError
+	}
+}
+
+// description returns the text used in violation messages.
+func (c Condition) description() string {
+	switch c {
+	case TemplateExpressions:
+		return "unsupported structure found: template expressions in image values"
+	case UnparseableImages:
+		return "image values that are not valid image references"
+	case UnmappedRegistries:
+		return "no mapping found for registries"
+	case EmptyRepositories:
+		return "images with an empty repository"
+	case MappingConflicts:
+		return "registry mappings pointing images back at source registries"
+	case MutableReferences:
+		return "relocated images referenced by mutable tags"
+	default:
+		return string(c)
+	}
+}
+
+// ViolationError reports a condition whose policy action is ActionError.
+type ViolationError struct {
+	Condition Condition
+	// Items are the values paths (or registries, for UnmappedRegistries, and conflicts, for
+	// MappingConflicts) the condition was found at.
+	Items []string
+}
+
+func (e *ViolationError) Error() string {
+	return fmt.Sprintf("strict mode violation (%s): %s: %s", e.Condition, e.Condition.description(), strings.Join(e.Items, ", "))
+}
+
+// ExitCode returns the exit code of the violated condition, implementing exitcodes.Coder.
+func (e *ViolationError) ExitCode() int { return e.Condition.ExitCode() }
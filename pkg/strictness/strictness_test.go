@@ -0,0 +1,86 @@
+package strictness
+
+import (
+	"testing"
+
+	"github.com/lucas-albers-lz4/irr/pkg/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("")
+	require.NoError(t, err)
+	assert.Equal(t, LevelOff, level)
+
+	level, err = ParseLevel(" Unsupported ")
+	require.NoError(t, err)
+	assert.Equal(t, LevelUnsupported, level)
+
+	_, err = ParseLevel("strict")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "off, warn, unsupported, all")
+}
+
+func TestLevelPolicy(t *testing.T) {
+	tests := []struct {
+		level Level
+		want  map[Condition]Action
+	}{
+		{level: LevelOff, want: map[Condition]Action{
+			TemplateExpressions: ActionIgnore, UnparseableImages: ActionIgnore, EmptyRepositories: ActionIgnore, UnmappedRegistries: ActionIgnore, MappingConflicts: ActionWarn, MutableReferences: ActionIgnore,
+		}},
+		{level: LevelWarn, want: map[Condition]Action{
+			TemplateExpressions: ActionWarn, UnparseableImages: ActionWarn, EmptyRepositories: ActionWarn, UnmappedRegistries: ActionWarn, MappingConflicts: ActionWarn, MutableReferences: ActionIgnore,
+		}},
+		{level: LevelUnsupported, want: map[Condition]Action{
+			TemplateExpressions: ActionError, UnparseableImages: ActionError, EmptyRepositories: ActionError, UnmappedRegistries: ActionWarn, MappingConflicts: ActionWarn, MutableReferences: ActionIgnore,
+		}},
+		{level: LevelAll, want: map[Condition]Action{
+			TemplateExpressions: ActionError, UnparseableImages: ActionError, EmptyRepositories: ActionError, UnmappedRegistries: ActionError, MappingConflicts: ActionError, MutableReferences: ActionIgnore,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.level), func(t *testing.T) {
+			policy := tt.level.Policy()
+			for _, c := range Conditions() {
+				assert.Equal(t, tt.want[c], policy.ActionFor(c), "condition %s", c)
+			}
+		})
+	}
+}
+
+func TestPolicyMerge(t *testing.T) {
+	policy := LevelUnsupported.Policy().Merge(Policy{TemplateExpressions: ActionWarn, UnmappedRegistries: ActionError})
+	assert.Equal(t, ActionWarn, policy.ActionFor(TemplateExpressions))
+	assert.Equal(t, ActionError, policy.ActionFor(UnparseableImages))
+	assert.Equal(t, ActionError, policy.ActionFor(UnmappedRegistries))
+	assert.Equal(t, ActionIgnore, policy.ActionFor(MutableReferences))
+
+	policy = policy.Merge(Policy{MutableReferences: ActionWarn})
+	assert.Equal(t, ActionWarn, policy.ActionFor(MutableReferences))
+}
+
+func TestPolicyValidate(t *testing.T) {
+	require.NoError(t, Policy{UnparseableImages: ActionWarn}.Validate())
+
+	err := Policy{EmptyRepositories: "fail"}.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid action "fail" for policy condition emptyRepositories`)
+}
+
+func TestConditionExitCodesAreDistinct(t *testing.T) {
+	seen := make(map[int]Condition)
+	for _, c := range Conditions() {
+		code := c.ExitCode()
+		assert.NotEqual(t, exitcodes.ExitGeneralRuntimeError, code, "condition %s", c)
+		other, duplicate := seen[code]
+		assert.False(t, duplicate, "conditions %s and %s share exit code %d", c, other, code)
+		seen[code] = c
+	}
+}
+
+func TestViolationError(t *testing.T) {
+	err := &ViolationError{Condition: TemplateExpressions, Items: []string{"image", "sidecar.image"}}
+	assert.Equal(t, "strict mode violation (templateExpressions): unsupported structure found: template expressions in image values: image, sidecar.image", err.Error())
+}
@@ -0,0 +1,69 @@
+package timing
+
+import (
+	"time"
+)
+
+// Profile is the JSON report of a run written by --profile-output.
+type Profile struct {
+	// Command is the command that ran, e.g. "irr override"
+	Command string `json:"command"`
+	// Start is when the run started
+	Start time.Time `json:"start"`
+	// TotalMs is the wall time of the run in milliseconds
+	TotalMs float64 `json:"totalMs"`
+	// Stages aggregates the spans per stage, in the order the stages first started
+	Stages []ProfileStage `json:"stages"`
+	// Spans lists every recorded span, ordered by start time
+	Spans []ProfileSpan `json:"spans"`
+}
+
+// ProfileStage is the aggregate of one stage in a Profile.
+type ProfileStage struct {
+	Name    string  `json:"name"`
+	Count   int     `json:"count"`
+	TotalMs float64 `json:"totalMs"`
+	MaxMs   float64 `json:"maxMs"`
+}
+
+// ProfileSpan is a single span in a Profile. OffsetMs is its start relative to the run.
+type ProfileSpan struct {
+	Name       string  `json:"name"`
+	Detail     string  `json:"detail,omitempty"`
+	OffsetMs   float64 `json:"offsetMs"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// NewProfile builds the profile of a run of command from start to end out of the recorded spans.
+func NewProfile(command string, start, end time.Time) *Profile {
+	recorded := Spans()
+	profile := &Profile{
+		Command: command,
+		Start:   start,
+		TotalMs: milliseconds(end.Sub(start)),
+		Stages:  []ProfileStage{},
+		Spans:   make([]ProfileSpan, 0, len(recorded)),
+	}
+	for _, summary := range Summarize(recorded) {
+		profile.Stages = append(profile.Stages, ProfileStage{
+			Name:    summary.Name,
+			Count:   summary.Count,
+			TotalMs: milliseconds(summary.Total),
+			MaxMs:   milliseconds(summary.Max),
+		})
+	}
+	for _, span := range recorded {
+		profile.Spans = append(profile.Spans, ProfileSpan{
+			Name:       span.Name,
+			Detail:     span.Detail,
+			OffsetMs:   milliseconds(span.Start.Sub(start)),
+			DurationMs: milliseconds(span.Duration),
+		})
+	}
+	return profile
+}
+
+// milliseconds converts a duration to fractional milliseconds, rounded to microseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / float64(time.Millisecond/time.Microsecond)
+}
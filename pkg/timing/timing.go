@@ -0,0 +1,122 @@
+// Package timing measures how long the stages of a run take (chart load, analysis, override
+// generation, validation). Every span is logged at debug level when it ends; when recording is
+// enabled, spans are also kept so a profile of the whole run can be written at exit.
+package timing
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/lucas-albers-lz4/irr/pkg/log"
+)
+
+// Names of the spans recorded by irr.
+const (
+	// SpanChartLoad covers loading a chart and its values
+	SpanChartLoad = "chart load"
+	// SpanAnalysis covers detecting the images of a loaded chart
+	SpanAnalysis = "analysis"
+	// SpanGeneration covers generating overrides for the detected images
+	SpanGeneration = "generation"
+	// SpanValidation covers rendering a chart with helm template to validate overrides
+	SpanValidation = "validation"
+)
+
+// Span is a completed, timed stage of a run.
+type Span struct {
+	// Name is the stage, e.g. SpanChartLoad
+	Name string
+	// Detail identifies what the stage worked on, e.g. the chart path; may be empty
+	Detail string
+	// Start is when the stage started
+	Start time.Time
+	// Duration is how long the stage took
+	Duration time.Duration
+}
+
+// StageSummary aggregates the spans of one stage.
+type StageSummary struct {
+	Name     string
+	Count    int
+	Total    time.Duration
+	Max      time.Duration
+	Earliest time.Time
+}
+
+var (
+	mu        sync.Mutex
+	recording bool
+	spans     []Span
+
+	// now is replaced in tests
+	now = time.Now
+)
+
+// Start starts a span and returns the function that ends it. detail identifies what the stage
+// works on and may be empty. Spans may be started concurrently, e.g. by override workers.
+//
+//	defer timing.Start(timing.SpanAnalysis, chartPath)()
+func Start(name, detail string) (end func()) {
+	start := now()
+	return func() {
+		span := Span{Name: name, Detail: detail, Start: start, Duration: now().Sub(start)}
+		log.Debug("Timing span", "span", name, "detail", detail, "duration", span.Duration.String())
+
+		mu.Lock()
+		defer mu.Unlock()
+		if recording {
+			spans = append(spans, span)
+		}
+	}
+}
+
+// EnableRecording keeps the spans that end from now on, so they can be read with Spans.
+func EnableRecording() {
+	mu.Lock()
+	defer mu.Unlock()
+	recording = true
+}
+
+// Reset stops recording and discards the recorded spans.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	recording = false
+	spans = nil
+}
+
+// Spans returns the recorded spans, ordered by start time.
+func Spans() []Span {
+	mu.Lock()
+	result := append([]Span(nil), spans...)
+	mu.Unlock()
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Start.Before(result[j].Start) })
+	return result
+}
+
+// Summarize aggregates spans by stage, ordered by when each stage first started.
+func Summarize(spans []Span) []StageSummary {
+	var summaries []StageSummary
+	index := make(map[string]int)
+	for _, span := range spans {
+		i, ok := index[span.Name]
+		if !ok {
+			i = len(summaries)
+			index[span.Name] = i
+			summaries = append(summaries, StageSummary{Name: span.Name, Earliest: span.Start})
+		}
+		summary := &summaries[i]
+		summary.Count++
+		summary.Total += span.Duration
+		if span.Duration > summary.Max {
+			summary.Max = span.Duration
+		}
+		if span.Start.Before(summary.Earliest) {
+			summary.Earliest = span.Start
+		}
+	}
+	sort.SliceStable(summaries, func(i, j int) bool { return summaries[i].Earliest.Before(summaries[j].Earliest) })
+	return summaries
+}
@@ -0,0 +1,79 @@
+package timing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withClock replaces the clock with one that advances by step on every reading
+func withClock(t *testing.T, start time.Time, step time.Duration) {
+	t.Helper()
+	current := start
+	original := now
+	now = func() time.Time {
+		reading := current
+		current = current.Add(step)
+		return reading
+	}
+	t.Cleanup(func() {
+		now = original
+		Reset()
+	})
+}
+
+func TestStart(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	withClock(t, base, 10*time.Millisecond)
+
+	Start(SpanChartLoad, "ignored")()
+	assert.Empty(t, Spans(), "spans are only kept while recording")
+
+	EnableRecording()
+	endAnalysis := Start(SpanAnalysis, "./nginx")
+	endGeneration := Start(SpanGeneration, "")
+	endGeneration()
+	endAnalysis()
+
+	spans := Spans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, Span{Name: SpanAnalysis, Detail: "./nginx", Start: base.Add(20 * time.Millisecond), Duration: 30 * time.Millisecond}, spans[0])
+	assert.Equal(t, Span{Name: SpanGeneration, Start: base.Add(30 * time.Millisecond), Duration: 10 * time.Millisecond}, spans[1])
+
+	Reset()
+	Start(SpanValidation, "")()
+	assert.Empty(t, Spans())
+}
+
+func TestSummarize(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	spans := []Span{
+		{Name: SpanAnalysis, Start: base.Add(time.Second), Duration: 2 * time.Second},
+		{Name: SpanChartLoad, Start: base, Duration: time.Second},
+		{Name: SpanAnalysis, Start: base.Add(4 * time.Second), Duration: 3 * time.Second},
+	}
+
+	assert.Equal(t, []StageSummary{
+		{Name: SpanChartLoad, Count: 1, Total: time.Second, Max: time.Second, Earliest: base},
+		{Name: SpanAnalysis, Count: 2, Total: 5 * time.Second, Max: 3 * time.Second, Earliest: base.Add(time.Second)},
+	}, Summarize(spans))
+	assert.Nil(t, Summarize(nil))
+}
+
+func TestNewProfile(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	withClock(t, base.Add(5*time.Millisecond), 1500*time.Microsecond)
+	EnableRecording()
+	Start(SpanChartLoad, "./nginx")()
+
+	profile := NewProfile("irr override", base, base.Add(20*time.Millisecond))
+	assert.Equal(t, &Profile{
+		Command: "irr override",
+		Start:   base,
+		TotalMs: 20,
+		Stages:  []ProfileStage{{Name: SpanChartLoad, Count: 1, TotalMs: 1.5, MaxMs: 1.5}},
+		Spans:   []ProfileSpan{{Name: SpanChartLoad, Detail: "./nginx", OffsetMs: 5, DurationMs: 1.5}},
+	}, profile)
+}
@@ -1236,6 +1236,28 @@ func (h *TestHarness) RunIrrCommand(args ...string) (stdout, stderr string, exit
 	return output, errOut, exitCode
 }
 
+// AssertJSONLogLines asserts that every non-empty line of stderr is a JSON log record, as with
+// --json-logs-only, and returns the records.
+func (h *TestHarness) AssertJSONLogLines(stderr string) []map[string]interface{} {
+	h.t.Helper()
+	var records []map[string]interface{}
+	for _, line := range strings.Split(stderr, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			h.t.Errorf("stderr line is not a JSON log record: %q", line)
+			continue
+		}
+		if _, ok := record["level"]; !ok {
+			h.t.Errorf("stderr JSON line has no log level: %q", line)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
 // ExecuteAnalysisOnly runs irr inspect and returns the parsed analysis result.
 // TODO: Implement the actual command execution and parsing logic.
 func (h *TestHarness) ExecuteAnalysisOnly(chartPath string, extraArgs ...string) (*analysis.ChartAnalysis, error) {
@@ -111,8 +111,8 @@ func TestParentChart(t *testing.T) {
 
 	// Example assertion for a child chart image (e.g., 'child' alias, image from docker.io)
 	assert.Contains(t, overrideContent, "child:", "Should contain child alias section")
-	// Assuming child.image path
-	assert.Contains(t, overrideContent, "repository: docker.io/library/nginx", "child.image.repository should be docker.io/library/nginx")
+	// child.image sets a registry-qualified repository without a registry key, which the override keeps
+	assert.Contains(t, overrideContent, "repository: test.registry.io/docker.io/library/nginx", "child.image.repository should include the target registry")
 
 	// Example assertion for an image from quay.io (e.g., in 'another-child' alias)
 	// Assuming another-child.monitoring.image is from quay.io/prometheus/node-exporter
@@ -0,0 +1,71 @@
+//go:build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestQuietMode(t *testing.T) {
+	h := NewTestHarness(t)
+	defer h.Cleanup()
+	setupMinimalTestChart(t, h)
+
+	stdout, stderr, err := h.ExecuteIRRWithStderr(map[string]string{"LOG_LEVEL": "debug"}, false,
+		"inspect", "--chart-path", h.chartPath, "--output-format", "json", "--quiet")
+	require.NoError(t, err, "stderr: %s", stderr)
+
+	var analysis map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &analysis), "stdout must be the JSON document only: %s", stdout)
+	assert.Empty(t, stderr, "--quiet logs errors only")
+
+	_, stderr, err = h.ExecuteIRRWithStderr(nil, false, "inspect", "--chart-path", h.chartPath, "--quiet", "--debug")
+	require.Error(t, err)
+	assert.Contains(t, stderr, "--quiet cannot be combined with --debug or --log-level")
+}
+
+func TestOverrideQuietMode(t *testing.T) {
+	h := NewTestHarness(t)
+	defer h.Cleanup()
+	setupMinimalTestChart(t, h)
+
+	stdout, stderr, err := h.ExecuteIRRWithStderr(map[string]string{"LOG_LEVEL": "debug"}, false,
+		"override", "--chart-path", h.chartPath,
+		"--target-registry", "test.registry.io", "--source-registries", "docker.io",
+		"--dry-run", "--quiet")
+	require.NoError(t, err, "stderr: %s", stderr)
+
+	var overrides map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(stdout), &overrides), "stdout must be the YAML document only: %s", stdout)
+	assert.Contains(t, overrides, "image")
+	assert.Empty(t, stderr, "--quiet logs errors only")
+}
+
+func TestJSONLogsOnlyMode(t *testing.T) {
+	h := NewTestHarness(t)
+	defer h.Cleanup()
+	setupMinimalTestChart(t, h)
+
+	stdout, stderr, err := h.ExecuteIRRWithStderr(map[string]string{"LOG_FORMAT": "text"}, false,
+		"override", "--chart-path", h.chartPath,
+		"--target-registry", "test.registry.io", "--source-registries", "docker.io",
+		"--dry-run", "--json-logs-only")
+	require.NoError(t, err, "stderr: %s", stderr)
+
+	var overrides map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(stdout), &overrides), "stdout must be the YAML document only: %s", stdout)
+	assert.Contains(t, overrides, "image")
+	records := h.AssertJSONLogLines(stderr)
+	assert.NotEmpty(t, records, "info logs are kept, as JSON despite LOG_FORMAT=text")
+
+	_, stderr, err = h.ExecuteIRRWithStderr(nil, false,
+		"override", "--chart-path", h.chartPath, "--target-registry", "test.registry.io", "--source-registries", "docker.io",
+		"--dry-run", "--json-logs-only", "--log-format", "text")
+	require.Error(t, err)
+	assert.Contains(t, stderr, "--json-logs-only cannot be combined with --log-format text")
+}
@@ -41,8 +41,9 @@ func TestOverrideFallbackTriggeredAndSucceeds(t *testing.T) {
 	t.Logf("Stderr output: %s", stderr)
 
 	// Verify the content contains expected overrides
-	assert.Contains(t, stdout, "registry: my-target-registry.com", "Output should include the target registry")
-	assert.Contains(t, stdout, "repository: docker.io/library/nginx", "Output should include the image repository")
+	// The chart keeps the registry in image.repository, so the override does too
+	assert.Contains(t, stdout, "repository: my-target-registry.com/docker.io/library/nginx", "Output should include the target registry in the image repository")
+	assert.NotContains(t, stdout, "registry: my-target-registry.com", "Output should not add a registry key the chart does not use")
 	assert.Contains(t, stdout, "tag: latest", "Output should include the image tag")
 }
 
@@ -134,14 +135,11 @@ func TestOverrideParentChart(t *testing.T) {
 	parentAppImageMapTyped, ok := parentAppImageMap.(map[string]interface{})
 	require.True(t, ok, "parentAppImage override should be a map")
 
-	parentAppImageRegistry, ok := parentAppImageMapTyped["registry"].(string)
-	assert.True(t, ok, "parentAppImage map should have registry string")
-	assert.Equal(t, "test-registry.io/my-project", parentAppImageRegistry, "Parent app image registry override incorrect")
-
+	// parentAppImage has a registry-qualified repository and no registry key, so the override keeps that layout
+	assert.NotContains(t, parentAppImageMapTyped, "registry", "parentAppImage override should not add a registry key")
 	parentAppImageRepo, ok := parentAppImageMapTyped["repository"].(string)
 	assert.True(t, ok, "parentAppImage map should have repository string")
-	// Path strategy preserves registry with dots
-	assert.Contains(t, parentAppImageRepo, "docker.io/parent/app", "Parent app image repo override incorrect")
+	assert.Equal(t, "test-registry.io/my-project/docker.io/parent/app", parentAppImageRepo, "Parent app image repo override incorrect")
 
 	parentAppImageTag, ok := parentAppImageMapTyped["tag"].(string)
 	assert.True(t, ok, "parentAppImage map should have tag string")
@@ -154,14 +152,12 @@ func TestOverrideParentChart(t *testing.T) {
 	childImageMapTyped, ok := childImageMap.(map[string]interface{})
 	require.True(t, ok, "child.image override should be a map")
 
-	childImageRegistry, ok := childImageMapTyped["registry"].(string)
-	assert.True(t, ok, "child.image map should have registry string")
-	assert.Equal(t, "test-registry.io/my-project", childImageRegistry, "Child image registry override incorrect")
-
+	// The child chart's image has a registry-qualified repository and no registry key
+	assert.NotContains(t, childImageMapTyped, "registry", "child.image override should not add a registry key")
 	childImageRepo, ok := childImageMapTyped["repository"].(string)
 	assert.True(t, ok, "child.image map should have repository string")
 	// Path strategy preserves registry with dots and adds library/
-	assert.Contains(t, childImageRepo, "docker.io/library/nginx", "Child image repo override incorrect")
+	assert.Equal(t, "test-registry.io/my-project/docker.io/library/nginx", childImageRepo, "Child image repo override incorrect")
 
 	childImageTag, ok := childImageMapTyped["tag"].(string)
 	assert.True(t, ok, "child.image map should have tag string")
@@ -637,8 +633,9 @@ func TestOverridePluginMode_SingleReleaseNamespace(t *testing.T) {
 	require.NoError(t, err, "override (plugin mode) should succeed. Stderr: %s", stderr)
 
 	// 4. Assert output contains expected registry/repo/tag
-	assert.Contains(t, stdout, "registry: my-target-registry.com", "Output should include the target registry")
-	assert.Contains(t, stdout, "repository: docker.io/library/nginx", "Output should include the image repository")
+	// The chart keeps the registry in image.repository, so the override does too
+	assert.Contains(t, stdout, "repository: my-target-registry.com/docker.io/library/nginx", "Output should include the target registry in the image repository")
+	assert.NotContains(t, stdout, "registry: my-target-registry.com", "Output should not add a registry key the chart does not use")
 	assert.Contains(t, stdout, "tag: latest", "Output should include the image tag")
 }
 
@@ -840,8 +837,7 @@ registries:
 		"--log-level", "debug",
 	)
 	require.NoError(t, err, "override (plugin mode) with mapping file should succeed. Stderr: %s", stderr)
-	assert.Contains(t, stdout, "registry: custom.registry.io", "Output should use registry from mapping file")
-	assert.Contains(t, stdout, "repository: mirror/library/nginx", "Output should use mirror path from mapping file")
+	assert.Contains(t, stdout, "repository: custom.registry.io/mirror/library/nginx", "Output should use registry and mirror path from mapping file")
 	assert.NotContains(t, stdout, "should-not-be-used.io", "Output should not use CLI target registry when mapping file is present")
 }
 
@@ -57,7 +57,7 @@ compatibility:
 quay.io: registry.example.com/quay
 `,
 			shouldSucceed: true,
-			expectedText:  "test.registry.io",
+			expectedText:  "repository: docker/library/nginx", // registry.example.com/docker, split into registry and repository
 		},
 		{
 			name: "malformed YAML format",